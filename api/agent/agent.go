@@ -3,19 +3,23 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"path/filepath"
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	dockerdriver "github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/audit"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
@@ -94,6 +98,78 @@ type Agent interface {
 	AddCallListener(fnext.CallListener)
 }
 
+// NetworkPoolProvider is implemented by an Agent which runs containers with a
+// driver that supports changing its set of networks at runtime.
+type NetworkPoolProvider interface {
+	// NetworkPool returns the network pool of the driver, or nil if the driver
+	// does not support it.
+	NetworkPool() drivers.NetworkPool
+}
+
+// Drainer is implemented by an Agent which can be drained before it is closed.
+type Drainer interface {
+	// Drain stops the agent from accepting new calls, which fail with
+	// models.ErrAgentDraining, and waits for the calls in flight to complete.
+	// Once ctx is done, the calls still in flight are cancelled, Drain waits for
+	// them to end (which uploads their logs) and returns ctx.Err().
+	Drain(ctx context.Context) error
+}
+
+// IdleTimeouts are the settings of an agent on idle containers and connections which may be
+// changed as it runs.
+type IdleTimeouts struct {
+	// FreezeIdle is the delay between a container being last used and being frozen, see
+	// EnvFreezeIdle
+	FreezeIdle time.Duration
+	// WebSocketIdle is the time a WebSocket connection to a function may go without frames
+	// before it is closed, see EnvWebSocketIdleTimeout
+	WebSocketIdle time.Duration
+}
+
+// IdleTimeoutsSetter is implemented by an Agent whose idle timeouts may be changed as it runs.
+type IdleTimeoutsSetter interface {
+	// SetIdleTimeouts changes the idle timeouts of the containers and connections of the
+	// agent, including the ones already running.
+	SetIdleTimeouts(IdleTimeouts)
+}
+
+// Provisioner is implemented by an Agent which can launch the hot containers of a function
+// ahead of its calls.
+type Provisioner interface {
+	// Provision launches the min ready hot containers of fn of app in the background, and
+	// keeps them warm as if fn had just been called.
+	Provision(ctx context.Context, app *models.App, fn *models.Fn) error
+}
+
+// DriverPinger is implemented by an Agent whose driver can check that its daemon is reachable.
+type DriverPinger interface {
+	// PingDriver returns the error reaching the daemon of the driver, nil if the driver has
+	// no daemon.
+	PingDriver(ctx context.Context) error
+}
+
+// CallStatusProvider is implemented by an Agent which runs detached calls in the
+// background, a detached call is only stored once it ended.
+type CallStatusProvider interface {
+	// DetachedCall returns the detached call with the given id while it is in flight.
+	DetachedCall(fnID, callID string) (*models.Call, bool)
+}
+
+// BackpressureProvider is implemented by an Agent which can describe its load when it
+// rejects a call, see models.BackpressureError.
+type BackpressureProvider interface {
+	// Backpressure returns the load of the agent for the function of a call it rejected,
+	// false if unknown.
+	Backpressure(call Call) (models.Backpressure, bool)
+}
+
+// StatusProvider is implemented by an Agent which can describe its capacity, its hot
+// containers and its image cache.
+type StatusProvider interface {
+	// NodeStatus returns the current status of the agent, nil if unknown.
+	NodeStatus(ctx context.Context) *models.NodeStatus
+}
+
 type agent struct {
 	cfg           Config
 	da            CallHandler
@@ -105,11 +181,20 @@ type agent struct {
 	evictor Evictor
 	// track usage
 	resources ResourceTracker
+	// track usage per app
+	appQuotas *appQuotas
 
 	// used to track running calls / safe shutdown
 	shutWg   *common.WaitGroup
 	shutonce sync.Once
 
+	// used to track calls in flight / drain
+	callWg    *common.WaitGroup
+	callsLock sync.Mutex // protects calls
+	calls     map[string]context.CancelFunc
+	detached  map[string]*models.Call // detached calls in flight, protected by callsLock
+	inFlight  uint64                  // calls admitted and not yet done, see EnvMaxInFlightCalls
+
 	// TODO(reed): shoot this fucking thing
 	callOverrider CallOverrider
 
@@ -118,6 +203,20 @@ type agent struct {
 
 	// deferred actions to call at end of initialisation
 	onStartup []func()
+
+	// true if the driver pulls images lazily
+	lazyPull bool
+
+	// audit records of calls are written to this if set, see EnvAuditURL
+	auditSink audit.Sink
+	// the callers of calls are the requests of these are forwarded for, see EnvTrustedProxies
+	trustedProxies audit.TrustedProxies
+
+	// the IdleTimeouts of the agent once changed by SetIdleTimeouts
+	idleTimeouts atomic.Value
+
+	// the output of containers is streamed to the tails of their functions, see LogTailer
+	logTail *logTail
 }
 
 // Option configures an agent at startup
@@ -140,9 +239,13 @@ func New(da CallHandler, options ...Option) Agent {
 	}
 
 	a.shutWg = common.NewWaitGroup()
+	a.callWg = common.NewWaitGroup()
+	a.calls = make(map[string]context.CancelFunc)
+	a.detached = make(map[string]*models.Call)
 	a.da = da
 	a.slotMgr = NewSlotQueueMgr()
 	a.evictor = NewEvictor()
+	a.logTail = newLogTail()
 
 	// Allow overriding config
 	for _, option := range options {
@@ -162,7 +265,38 @@ func New(da CallHandler, options ...Option) Agent {
 		a.driver = d
 	}
 
+	if d, ok := a.driver.(*dockerdriver.DockerDriver); ok {
+		a.lazyPull = d.IsLazyPull()
+		if a.cfg.MaxFsSize > 0 && !d.Capabilities().StorageOpt {
+			logrus.WithField("max_fs_size_mb", a.cfg.MaxFsSize).Warn("docker storage driver does not support the size option, the file system size of functions is not limited")
+		}
+	}
+
+	if a.auditSink == nil && a.cfg.AuditURL != "" {
+		sink, err := audit.New(a.cfg.AuditURL)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create audit sink")
+		}
+		a.auditSink = sink
+	}
+	if a.auditSink != nil {
+		a.auditSink = audit.NewBufferedSink(a.auditSink, int(a.cfg.AuditBufferSize))
+	}
+	trustedProxies, err := audit.ParseTrustedProxies(a.cfg.TrustedProxies)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid trusted proxies")
+	}
+	a.trustedProxies = trustedProxies
+
 	a.resources = NewResourceTracker(&a.cfg)
+	a.appQuotas = newAppQuotas(&a.cfg)
+
+	if (a.cfg.MemoryOvercommit > 100 || a.cfg.EvictPausedFree > 0) && a.cfg.MemoryPressurePoll > 0 {
+		if !a.shutWg.AddSession(1) {
+			logrus.Fatal("cannot start agent, unable to add session")
+		}
+		go a.watchMemoryPressure()
+	}
 
 	for _, sup := range a.onStartup {
 		sup()
@@ -245,9 +379,59 @@ func NewDockerDriver(cfg *Config) (drivers.Driver, error) {
 		ImageCleanMaxSize:    cfg.ImageCleanMaxSize,
 		ImageCleanExemptTags: cfg.ImageCleanExemptTags,
 		ImageEnableVolume:    cfg.ImageEnableVolume,
+		RegistryMirrors:      cfg.DockerRegistryMirrors,
+		PullRetryAttempts:    cfg.PullRetryAttempts,
+		PullRetryBackoff:     cfg.PullRetryBackoff,
+		PullRetryMaxBackoff:  cfg.PullRetryMaxBackoff,
+		PullRetryJitter:      cfg.PullRetryJitter,
+		PullRetryStatusCodes: cfg.PullRetryStatusCodes,
+		SyslogRetryAttempts:  cfg.SyslogRetryAttempts,
+		SyslogRetryBackoff:   cfg.SyslogRetryBackoff,
+		LazyPull:             cfg.LazyPull,
+		LazyPullSnapshotter:  cfg.LazyPullSnapshotter,
+		EnableSnapshots:      cfg.EnableSnapshots,
+		DockerAPIVersion:     cfg.DockerAPIVersion,
+		DockerMaxIdleConns:   cfg.DockerMaxIdleConns,
+		Runtime:              cfg.DockerRuntime,
+		PreForkMaxSize:       cfg.PreForkPoolMaxSize,
+		PreForkHighWatermark: cfg.PreForkHighWatermark,
+		PreForkLowWatermark:  cfg.PreForkLowWatermark,
+		PreForkScaleInterval: cfg.PreForkScaleInterval,
+		EnableNoNewPrivs:     !cfg.DisableNoNewPrivs,
+		AllowedSysctls:       cfg.DockerAllowedSysctls,
+		CgroupVersion:        cfg.CgroupVersion,
+		CPUPinningThreshold:  cfg.CPUPinningThreshold,
 	})
 }
 
+// SetIdleTimeouts implements IdleTimeoutsSetter
+func (a *agent) SetIdleTimeouts(t IdleTimeouts) {
+	a.idleTimeouts.Store(t)
+	logrus.WithFields(logrus.Fields{"freeze_idle": t.FreezeIdle, "websocket_idle_timeout": t.WebSocketIdle}).Info("agent idle timeouts changed")
+}
+
+// getIdleTimeouts returns the IdleTimeouts of the agent, the ones of its config until they are set
+func (a *agent) getIdleTimeouts() IdleTimeouts {
+	if t, ok := a.idleTimeouts.Load().(IdleTimeouts); ok {
+		return t
+	}
+	return IdleTimeouts{FreezeIdle: a.cfg.FreezeIdle, WebSocketIdle: a.cfg.WebSocketIdleTimeout}
+}
+
+// PingDriver implements DriverPinger
+func (a *agent) PingDriver(ctx context.Context) error {
+	if p, ok := a.driver.(models.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// NetworkPool implements NetworkPoolProvider
+func (a *agent) NetworkPool() drivers.NetworkPool {
+	pool, _ := a.driver.(drivers.NetworkPool)
+	return pool
+}
+
 func (a *agent) Close() error {
 	var err error
 
@@ -259,11 +443,57 @@ func (a *agent) Close() error {
 		if a.driver != nil {
 			err = a.driver.Close()
 		}
+		// calls have ended, flush their audit records
+		if a.auditSink != nil {
+			if aerr := a.auditSink.Close(); aerr != nil && err == nil {
+				err = aerr
+			}
+		}
 	})
 
 	return err
 }
 
+// Drain implements Drainer
+func (a *agent) Drain(ctx context.Context) error {
+	logrus.Info("draining agent")
+
+	drained := a.callWg.CloseGroupNB()
+	select {
+	case <-drained:
+		logrus.Info("agent drained")
+		return nil
+	case <-ctx.Done():
+	}
+
+	a.callsLock.Lock()
+	logrus.WithField("calls", len(a.calls)).Warn("agent drain timed out, cancelling calls in flight")
+	for _, cancel := range a.calls {
+		cancel()
+	}
+	a.callsLock.Unlock()
+
+	<-drained
+	return ctx.Err()
+}
+
+// trackCall returns a context of ctx for a call in flight that is cancelled if a drain
+// times out, and a func to stop tracking the call once done.
+func (a *agent) trackCall(ctx context.Context, call *call) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	a.callsLock.Lock()
+	a.calls[call.ID] = cancel
+	a.callsLock.Unlock()
+
+	return ctx, func() {
+		a.callsLock.Lock()
+		delete(a.calls, call.ID)
+		a.callsLock.Unlock()
+		cancel()
+	}
+}
+
 func (a *agent) Submit(callI Call) error {
 	call := callI.(*call)
 	ctx, span := trace.StartSpan(call.req.Context(), "agent_submit")
@@ -271,14 +501,60 @@ func (a *agent) Submit(callI Call) error {
 
 	statsCalls(ctx)
 
+	release, err := a.admit(ctx, call)
+	if err != nil {
+		return err
+	}
+
+	if call.Type == models.TypeDetached {
+		return a.submitDetached(ctx, call, release)
+	}
+	defer release()
+
+	err = a.submit(ctx, call)
+	return err
+}
+
+// admit checks that the agent can take call, the returned func must be called once the
+// call is done.
+func (a *agent) admit(ctx context.Context, call *call) (func(), error) {
+	// async calls reserved before a drain already hold their session of callWg
+	if !call.drainHeld && !a.callWg.AddSession(1) {
+		statsTooBusy(ctx)
+		return nil, models.ErrAgentDraining
+	}
+
 	if !a.shutWg.AddSession(1) {
+		a.callWg.DoneSession()
 		statsTooBusy(ctx)
-		return models.ErrCallTimeoutServerBusy
+		return nil, models.ErrCallTimeoutServerBusy
 	}
-	defer a.shutWg.DoneSession()
 
-	err := a.submit(ctx, call)
-	return err
+	inFlight := atomic.AddUint64(&a.inFlight, 1)
+	if max := a.cfg.MaxInFlightCalls; max != 0 && inFlight > max {
+		atomic.AddUint64(&a.inFlight, ^uint64(0))
+		a.shutWg.DoneSession()
+		a.callWg.DoneSession()
+		statsTooBusy(ctx)
+		common.Logger(ctx).WithField("in_flight", inFlight-1).Debug("too many calls in flight, rejecting call")
+		return nil, models.ErrAgentOverloaded
+	}
+
+	release, err := a.appQuotas.acquire(call.AppID, (call.Memory+uint64(call.TmpFsSize))*Mem1MB, uint64(call.CPUs))
+	if err != nil {
+		atomic.AddUint64(&a.inFlight, ^uint64(0))
+		a.shutWg.DoneSession()
+		a.callWg.DoneSession()
+		common.Logger(ctx).WithField("app_id", call.AppID).Debug("app quota exceeded, rejecting call")
+		return nil, err
+	}
+
+	return func() {
+		release()
+		atomic.AddUint64(&a.inFlight, ^uint64(0))
+		a.shutWg.DoneSession()
+		a.callWg.DoneSession()
+	}, nil
 }
 
 func (a *agent) startStateTrackers(ctx context.Context, call *call) {
@@ -295,9 +571,14 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 	a.startStateTrackers(ctx, call)
 	defer a.endStateTrackers(ctx, call)
 
-	slot, err := a.getSlot(ctx, call)
+	// a drain that times out cancels callCtx, the call is still ended with ctx so
+	// that its status and logs get stored.
+	callCtx, untrack := a.trackCall(ctx, call)
+	defer untrack()
+
+	slot, err := a.getSlot(callCtx, call)
 	if err != nil {
-		return a.handleCallEnd(ctx, call, slot, err, false)
+		return a.handleCallEnd(ctx, call, slot, drainError(ctx, err), false)
 	}
 
 	err = call.Start(ctx)
@@ -309,12 +590,25 @@ func (a *agent) submit(ctx context.Context, call *call) error {
 	statsStartRun(ctx)
 
 	// We are about to execute the function, set container Exec Deadline (call.Timeout)
-	slotCtx, cancel := context.WithTimeout(ctx, time.Duration(call.Timeout)*time.Second)
+	timeout := time.Duration(call.Timeout) * time.Second
+	if call.isWebSocket {
+		timeout = a.cfg.WebSocketMaxDuration
+	}
+	slotCtx, cancel := context.WithTimeout(callCtx, timeout)
 	defer cancel()
 
 	// Pass this error (nil or otherwise) to end directly, to store status, etc.
 	err = slot.exec(slotCtx, call)
-	return a.handleCallEnd(ctx, call, slot, err, true)
+	return a.handleCallEnd(ctx, call, slot, drainError(ctx, err), true)
+}
+
+// drainError returns models.ErrAgentDraining if err is from a call cancelled by a
+// drain rather than by its caller.
+func drainError(ctx context.Context, err error) error {
+	if err == context.Canceled && ctx.Err() == nil {
+		return models.ErrAgentDraining
+	}
+	return err
 }
 
 func (a *agent) handleCallEnd(ctx context.Context, call *call, slot Slot, err error, isStarted bool) error {
@@ -359,10 +653,14 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 		// and also make sure we have call.Timeout inside the container. Total time
 		// to run an async becomes 2 * call.Timeout.
 		// *) for sync, there's no slot deadline, the timeout is controlled by http-client
-		// context (or runner gRPC context)
+		// context (or runner gRPC context), unless a slot wait timeout is set
 		tmp, cancel := context.WithTimeout(ctx, time.Duration(call.Timeout)*time.Second)
 		ctx = tmp
 		defer cancel()
+	} else if wait := call.slotWaitTimeout(&a.cfg); wait > 0 {
+		tmp, cancel := context.WithTimeout(ctx, wait)
+		ctx = tmp
+		defer cancel()
 	}
 
 	ctx, span := trace.StartSpan(ctx, "agent_get_slot")
@@ -371,17 +669,14 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 	// For hot requests, we use a long lived slot queue, which we use to manage hot containers
 	var isNew bool
 
-	if call.slotHashId == "" {
-		call.slotHashId = getSlotQueueKey(call)
-	}
-
-	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId)
+	isNew = a.setupSlots(call)
+	call.slots.recordCall(time.Now())
 	call.requestState.UpdateState(ctx, RequestStateWait, call.slots)
 
 	// setup slot caller with a ctx that gets cancelled once waitHot() is completed.
 	// This allows runHot() to detect if original caller has been serviced by
 	// another container or if original caller was disconnected.
-	caller := &slotCaller{}
+	caller := &slotCaller{priority: call.priority()}
 	{
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
@@ -397,6 +692,59 @@ func (a *agent) getSlot(ctx context.Context, call *call) (Slot, error) {
 	return s, err
 }
 
+// setupSlots sets the slot queue of a call up with the settings of its function, it returns
+// true if the slot queue is new.
+func (a *agent) setupSlots(call *call) bool {
+	if call.slotHashId == "" {
+		call.slotHashId = getSlotQueueKey(call)
+	}
+
+	var isNew bool
+	call.slots, isNew = a.slotMgr.getSlotQueue(call.slotHashId)
+	call.slots.setMinReady(call.MinReady)
+	call.slots.setMaxContainers(a.maxContainers(call))
+	call.slots.setScalePolicy(time.Duration(call.IdleTimeout)*time.Second, call.ScalePolicy)
+	a.slotMgr.setCurrentSlotQueue(call.FnID, call.slots)
+	return isNew
+}
+
+// Provision implements Provisioner
+func (a *agent) Provision(ctx context.Context, app *models.App, fn *models.Fn) error {
+	if fn.GetMinReady() == 0 {
+		return nil
+	}
+	select {
+	case <-a.shutWg.Closer():
+		return models.ErrCallTimeoutServerBusy
+	default:
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		return err
+	}
+	callI, err := a.GetCall(FromHTTPFnRequest(app, fn, req.WithContext(ctx)), WithWriter(ioutil.Discard))
+	if err != nil {
+		return err
+	}
+	call := callI.(*call)
+
+	// no call waits for the containers, they are queued for the calls to come and evictable
+	done := make(chan struct{})
+	close(done)
+	caller := &slotCaller{priority: models.PriorityLow, done: done}
+	if a.setupSlots(call) {
+		go a.hotLauncher(ctx, call, caller)
+		return nil
+	}
+	// wake the launcher of the slot queue up to launch the containers now
+	select {
+	case call.slots.signaller <- caller:
+	default:
+	}
+	return nil
+}
+
 // hotLauncher is spawned in a go routine for each slot queue to monitor stats and launch hot
 // containers if needed. Upon shutdown or activity timeout, hotLauncher exits and during exit,
 // it destroys the slot queue.
@@ -419,23 +767,38 @@ func (a *agent) hotLauncher(ctx context.Context, call *call, caller *slotCaller)
 	ctx, span := trace.StartSpan(ctx, "agent_hot_launcher")
 	defer span.End()
 
+	launchCtx, cancel := context.WithTimeout(ctx, timeout)
 	for {
-		ctx, cancel := context.WithTimeout(ctx, timeout)
-		a.checkLaunch(ctx, call, *caller)
+		a.checkLaunch(launchCtx, call, *caller)
+		a.checkMinReady(launchCtx, call, *caller)
+
+		// poll to replace min ready containers that went away, or to follow
+		// the schedule of a scale policy keeping containers warm.
+		var minReadyPoll <-chan time.Time
+		if call.slots.getMinReady() > 0 || call.slots.getScalePolicy().KeepsWarm() {
+			minReadyPoll = time.After(a.cfg.HotPoll)
+		}
 
 		select {
 		case <-a.shutWg.Closer(): // server shutdown
 			cancel()
 			return
-		case <-ctx.Done(): // timed out
+		case <-launchCtx.Done(): // timed out
 			cancel()
-			if a.slotMgr.deleteSlotQueue(call.slots) {
+			// let the min ready containers idle out if there have been no calls since,
+			// a scale policy keeping containers warm applies regardless of calls.
+			call.slots.expireMinReady(timeout)
+			if !call.slots.getScalePolicy().KeepsWarm() && a.slotMgr.deleteSlotQueue(call.slots) {
 				logger.Debug("Hot function launcher timed out")
 				return
 			}
 		case caller = <-call.slots.signaller:
 			cancel()
+		case <-minReadyPoll:
+			// polling is not activity, keep the launcher timeout running
+			continue
 		}
+		launchCtx, cancel = context.WithTimeout(ctx, timeout)
 	}
 }
 
@@ -454,6 +817,14 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 	if !isNewContainerNeeded(&curStats) {
 		return
 	}
+	if isMaxContainersReached(&curStats, call.slots.getMaxContainers()) {
+		// waiters get a slot once a container of the function frees up, for
+		// non-blocking mode the function is out of capacity.
+		if isNB {
+			tryNotify(caller.notify, models.ErrCallTimeoutServerBusy)
+		}
+		return
+	}
 
 	state := NewContainerState()
 	state.UpdateState(ctx, ContainerStateWait, call.slots)
@@ -480,15 +851,23 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 	//
 	// Non-blocking mode only applies to cpu+mem, and if isNewContainerNeeded decided that we do not
 	// need to start a new container, then waiters will wait.
+	//
+	// Blocked requests are served in the priority order of their callers. A blocked request is
+	// cancelled once we stop waiting for it, so that it does not hold back requests of a lower
+	// priority.
+	resCtx, resCancel := context.WithCancel(ctx)
+	defer resCancel()
+
 	select {
-	case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, isNB):
+	case tok = <-a.resources.GetResourceToken(resCtx, mem, call.CPUs, caller.priority, isNB):
 	case <-time.After(a.cfg.HotPoll):
 		// Request routines are polling us with this a.cfg.HotPoll frequency. We can use this
 		// same timer to assume that we waited for cpu/mem long enough. Let's try to evict an
 		// idle container. We do this by submitting a non-blocking request and evicting required
 		// amount of resources.
+		resCancel()
 		select {
-		case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, true):
+		case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, caller.priority, true):
 		case <-ctx.Done(): // timeout
 		case <-a.shutWg.Closer(): // server shutdown
 		}
@@ -537,6 +916,90 @@ func (a *agent) checkLaunch(ctx context.Context, call *call, caller slotCaller)
 	}
 }
 
+// maxContainers returns the maximum number of hot containers for the function of a call, the
+// agent wide maximum applies unless the function sets a lower one. 0 means unlimited.
+func (a *agent) maxContainers(call *call) uint64 {
+	max := a.cfg.MaxFnContainers
+	if call.MaxContainers > 0 && (max == 0 || call.MaxContainers < max) {
+		max = call.MaxContainers
+	}
+	return max
+}
+
+// freezeDelay returns how long an idle hot container runs before it is paused.
+func (a *agent) freezeDelay(policy *models.ScalePolicy) time.Duration {
+	if policy != nil {
+		switch {
+		case policy.FreezeDelay == models.FreezeDelayDisabled:
+			return MaxMsDisabled
+		case policy.FreezeDelay > 0:
+			return time.Duration(policy.FreezeDelay) * time.Millisecond
+		}
+	}
+	return a.getIdleTimeouts().FreezeIdle
+}
+
+// minFreezeRecheck is the least time between the checks of the call rate of a function with
+// adaptive freeze, so that a short or zero freeze delay doesn't spin on them
+const minFreezeRecheck = time.Second
+
+// freezeRecheck returns the time until the next check of the call rate of an idle container
+// that was not paused
+func freezeRecheck(freezeDelay time.Duration) time.Duration {
+	if freezeDelay < minFreezeRecheck {
+		return minFreezeRecheck
+	}
+	return freezeDelay
+}
+
+// skipFreeze returns true if an idle hot container of slots should stay unpaused, as its
+// function uses adaptive freeze and is called often enough that pausing would only add
+// latency to its next call.
+func (a *agent) skipFreeze(slots *slotQueue) bool {
+	policy := slots.getScalePolicy()
+	if !a.cfg.FreezeAdaptive && (policy == nil || !policy.AdaptiveFreeze) {
+		return false
+	}
+	return slots.callRate(time.Now()) > a.cfg.FreezeAdaptiveRate
+}
+
+// checkMinReady launches a hot container if fewer than the min ready containers of the function
+// are around. Unlike checkLaunch, it only uses free capacity and never evicts other containers.
+func (a *agent) checkMinReady(ctx context.Context, call *call, caller slotCaller) {
+	curStats := call.slots.getStats()
+	if !isMinReadyNeeded(&curStats, call.slots.getMinReady()) ||
+		isMaxContainersReached(&curStats, call.slots.getMaxContainers()) {
+		return
+	}
+
+	state := NewContainerState()
+	state.UpdateState(ctx, ContainerStateWait, call.slots)
+
+	mem := call.Memory + uint64(call.TmpFsSize)
+
+	// containers kept warm yield to calls waiting for resources
+	var tok ResourceToken
+	select {
+	case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, models.PriorityLow, true):
+	case <-ctx.Done(): // timeout
+	case <-a.shutWg.Closer(): // server shutdown
+	}
+
+	if tok != nil {
+		if tok.Error() == nil && a.shutWg.AddSession(1) {
+			go func() {
+				// NOTE: runHot will not inherit the timeout from ctx (ignore timings)
+				a.runHot(ctx, caller, call, tok, state)
+				a.shutWg.DoneSession()
+			}()
+			return
+		}
+		tok.Close()
+	}
+
+	state.UpdateState(ctx, ContainerStateDone, call.slots)
+}
+
 // waitHot pings and waits for a hot container from the slot queue
 func (a *agent) waitHot(ctx context.Context, call *call, caller *slotCaller) (Slot, error) {
 	ctx, span := trace.StartSpan(ctx, "agent_wait_hot")
@@ -545,7 +1008,7 @@ func (a *agent) waitHot(ctx context.Context, call *call, caller *slotCaller) (Sl
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel() // shut down dequeuer if we grab a slot
 
-	ch := call.slots.startDequeuer(ctx)
+	ch := call.slots.startDequeuer(ctx, caller.priority)
 
 	// 1) if we can get a slot immediately, grab it.
 	// 2) if we don't, send a signaller every x msecs until we do.
@@ -587,6 +1050,7 @@ type hotSlot struct {
 	done          chan struct{} // signal we are done with slot
 	container     *container    // TODO mask this
 	cfg           *Config
+	webSocketIdle time.Duration // see IdleTimeouts, as of the start of the call
 	fatalErr      error
 	containerSpan trace.SpanContext
 }
@@ -606,6 +1070,20 @@ func (s *hotSlot) trySetError(err error) {
 	}
 }
 
+// errCallCanceled is the fatal error of a slot whose call was cancelled while the container
+// was processing it, eg. the client disconnected. The container is stopped gracefully.
+var errCallCanceled = errors.New("call cancelled while running, terminating container")
+
+// canceled handles the call of the slot being cancelled before the container is done with it.
+// Unless configured to only free the slot, the container is shut down as it may still be busy
+// with the cancelled call.
+func (s *hotSlot) canceled() error {
+	if !s.cfg.CancelFreeSlot {
+		s.trySetError(errCallCanceled)
+	}
+	return context.Canceled
+}
+
 func (s *hotSlot) exec(ctx context.Context, call *call) error {
 	ctx, span := trace.StartSpan(ctx, "agent_hot_exec")
 	defer span.End()
@@ -623,6 +1101,10 @@ func (s *hotSlot) exec(ctx context.Context, call *call) error {
 	})
 
 	call.req = call.req.WithContext(ctx) // TODO this is funny biz reed is bad
+	call.imageDigest = s.container.imageDigest
+	if call.isWebSocket {
+		return s.dispatchWebSocket(ctx, call)
+	}
 	return s.dispatch(ctx, call)
 }
 
@@ -633,6 +1115,7 @@ var removeHeaders = map[string]bool{
 	"transfer-encoding": true,
 	"te":                true,
 	"upgrade":           true,
+	"http2-settings":    true,
 	"authorization":     true,
 }
 
@@ -646,6 +1129,12 @@ func createUDSRequest(ctx context.Context, call *call) *http.Request {
 	// it properly and close connections at the end, e.g. when using UDS.
 	req = req.WithContext(ctx)
 
+	// bodies of a known length, which HTTP/2 requests carry without chunking, are sent with
+	// it rather than chunked, streaming the body as the client's flow control allows
+	if call.req.ContentLength > 0 {
+		req.ContentLength = call.req.ContentLength
+	}
+
 	req.Header = make(http.Header)
 	for k, vs := range call.req.Header {
 		if !removeHeaders[strings.ToLower(k)] {
@@ -671,17 +1160,23 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	// TODO it's possible we can get rid of this (after getting rid of logs API) - may need for call id/debug mode still
 	// TODO there's a timeout race for swapping this back if the container doesn't get killed for timing out, and don't you forget it
-	swapBack := s.container.swap(call.stderr, &call.Stats)
+	swapBack := s.container.swap(call)
 	defer swapBack()
 
 	resp, err := s.container.udsClient.Do(createUDSRequest(ctx, call))
 	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return s.canceled()
+		}
 		// IMPORTANT: Container contract: If http-uds errors/timeout, container cannot continue
 		s.trySetError(err)
 		// first filter out timeouts
 		if ctx.Err() == context.DeadlineExceeded {
 			return context.DeadlineExceeded
 		}
+		if errors.Is(err, models.ErrRequestContentTooBig) {
+			return models.ErrRequestContentTooBig
+		}
 		return models.ErrFunctionResponse
 	}
 	defer resp.Body.Close()
@@ -690,17 +1185,18 @@ func (s *hotSlot) dispatch(ctx context.Context, call *call) error {
 
 	ioErrChan := make(chan error, 1)
 	go func() {
-		ioErrChan <- s.writeResp(ctx, s.cfg.MaxResponseSize, resp, call.respWriter)
+		ioErrChan <- s.writeResp(ctx, call.maxResponseSize(s.cfg), resp, call.respWriter)
 	}()
 
 	select {
 	case ioErr := <-ioErrChan:
 		return ioErr
 	case <-ctx.Done():
-		if ctx.Err() == context.DeadlineExceeded {
-			// IMPORTANT: Container contract: If http-uds timeout, container cannot continue
-			s.trySetError(ctx.Err())
+		if ctx.Err() == context.Canceled {
+			return s.canceled()
 		}
+		// IMPORTANT: Container contract: If http-uds timeout, container cannot continue
+		s.trySetError(ctx.Err())
 		return ctx.Err()
 	}
 }
@@ -882,32 +1378,47 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		return
 	}
 
-	needsPull, err := cookie.ValidateImage(ctx)
+	phases := newColdStartPhases(ctx)
+
+	phaseCtx := phases.start(coldStartImagePull)
+	needsPull, err := cookie.ValidateImage(phaseCtx)
 	if needsPull {
-		pullCtx, pullCancel := context.WithTimeout(ctx, a.cfg.HotPullTimeout)
+		pullCtx, pullCancel := context.WithTimeout(phaseCtx, a.cfg.HotPullTimeout)
 		err = cookie.PullImage(pullCtx)
 		pullCancel()
 		if err != nil && pullCtx.Err() == context.DeadlineExceeded {
 			err = models.ErrDockerPullTimeout
 		}
 		if tryQueueErr(err, errQueue) == nil {
-			needsPull, err = cookie.ValidateImage(ctx) // uses original ctx timeout
+			needsPull, err = cookie.ValidateImage(phaseCtx) // uses original ctx timeout
 			if needsPull {
 				// Image must have removed by image cleaner, manual intervention, etc.
 				err = models.ErrCallTimeoutServerBusy
 			}
 		}
 	}
+	phases.end(err)
+	if timer, ok := cookie.(drivers.RegistryAuthTimer); ok {
+		phases.split(coldStartImagePull, coldStartRegistryAuth, timer.RegistryAuthDuration())
+	}
 	if tryQueueErr(err, errQueue) != nil {
 		return
 	}
 
-	err = cookie.CreateContainer(ctx)
+	phaseCtx = phases.start(coldStartContainerCreate)
+	err = cookie.CreateContainer(phaseCtx)
+	phases.end(err)
 	if tryQueueErr(err, errQueue) != nil {
 		return
 	}
 
-	waiter, err := cookie.Run(ctx)
+	if digester, ok := cookie.(drivers.ImageDigester); ok {
+		container.imageDigest = digester.ImageDigest()
+	}
+
+	phaseCtx = phases.start(coldStartContainerStart)
+	waiter, err := cookie.Run(phaseCtx)
+	phases.end(err)
 	if tryQueueErr(err, errQueue) != nil {
 		return
 	}
@@ -920,11 +1431,15 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		// Notice how we do not distinguish between agent-shutdown, eviction, ctx.Done, etc. This is
 		// because monitoring go-routine may pick these events earlier and cancel the ctx.
 		initStart := time.Now()
+		phases.start(coldStartUDSInit)
+		defer phases.end(nil)
 
 		// INIT BARRIER HERE. Wait for the initialization go-routine signal
 		select {
 		case <-initialized:
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "initialized")
+			phases.end(nil)
+			phases.record(logger)
 		case <-a.shutWg.Closer(): // agent shutdown
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "canceled")
 			return
@@ -934,42 +1449,38 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 		case <-evictor.C: // eviction
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "canceled")
 			return
-		case <-time.After(a.cfg.HotStartTimeout):
+		case <-time.After(a.hotStartTimeout()):
 			statsContainerUDSInitLatency(ctx, initStart, time.Now(), "timedout")
+			phases.end(models.ErrContainerInitTimeout)
 			tryQueueErr(models.ErrContainerInitTimeout, errQueue)
 			return
 		}
 
-		for {
-			// Below we are rather defensive and poll on evictor/ctx
-			// to reduce the likelyhood of attempting to queue a hotSlot when these
-			// two cases occur.
-			select {
-			case <-ctx.Done():
-				return
-			case <-evictor.C: // eviction
-				return
-			default:
-			}
-
-			slot := &hotSlot{
-				done:          make(chan struct{}),
-				container:     container,
-				cfg:           &a.cfg,
-				containerSpan: trace.FromContext(ctx).SpanContext(),
+		// snapshot the initialized container before it processes any requests
+		if snap, ok := cookie.(drivers.Snapshotter); ok {
+			if err := snap.Snapshot(ctx); err != nil {
+				logger.WithError(err).Info("cannot snapshot hot container")
 			}
-			if !a.runHotReq(ctx, call, state, logger, cookie, slot, evictor) {
-				return
-			}
-			// wait for this call to finish
-			// NOTE do NOT select with shutdown / other channels. slot handles this.
-			<-slot.done
+		}
 
-			if slot.fatalErr != nil {
-				logger.WithError(slot.fatalErr).Info("hot function terminating")
-				return
-			}
+		// A container that can serve calls concurrently has one slot loop per
+		// call it can serve, any of them exiting shuts down the container.
+		hc := newHotContainerSlots(container.concurrency, state, evictor, call.slots)
+		var wg sync.WaitGroup
+		for i := uint64(0); i < container.concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					// a recycled container lets the calls of its other slots end
+					if !hc.isDraining() {
+						cancel()
+					}
+				}()
+				a.runHotSlots(ctx, call, logger, cookie, container, hc)
+			}()
 		}
+		wg.Wait()
 	}()
 
 	runRes := waiter.Wait(ctx)
@@ -978,6 +1489,79 @@ func (a *agent) runHot(ctx context.Context, caller slotCaller, call *call, tok R
 	}
 }
 
+// runHotSlots queues slots of a hot container one after another, each one waiting for the
+// call consuming the previous one to finish, until the container should shutdown.
+func (a *agent) runHotSlots(ctx context.Context, call *call, logger logrus.FieldLogger, cookie drivers.Cookie, container *container, hc *hotContainerSlots) {
+	for {
+		// Below we are rather defensive and poll on evictor/ctx
+		// to reduce the likelyhood of attempting to queue a hotSlot when these
+		// two cases occur.
+		select {
+		case <-ctx.Done():
+			return
+		case <-hc.evictor.C: // eviction
+			return
+		case <-hc.drain: // container recycled
+			return
+		default:
+		}
+
+		slot := &hotSlot{
+			done:          make(chan struct{}),
+			container:     container,
+			cfg:           &a.cfg,
+			webSocketIdle: a.getIdleTimeouts().WebSocketIdle,
+			containerSpan: trace.FromContext(ctx).SpanContext(),
+		}
+		if !a.runHotReq(ctx, call, logger, cookie, slot, hc) {
+			return
+		}
+		// wait for this call to finish
+		// NOTE do NOT select with shutdown / other channels. slot handles this.
+		<-slot.done
+		hc.release()
+		a.checkRecycle(ctx, call, logger, container, hc)
+
+		if slot.fatalErr != nil {
+			logger.WithError(slot.fatalErr).Info("hot function terminating")
+			if slot.fatalErr == errCallCanceled {
+				a.terminateHot(ctx, logger, cookie)
+			}
+			return
+		}
+	}
+}
+
+// terminateHot sends SIGTERM to a hot container and waits for it to exit for the cancel grace
+// period, the container is killed once it is shut down if it is still running by then.
+func (a *agent) terminateHot(ctx context.Context, logger logrus.FieldLogger, cookie drivers.Cookie) {
+	term, ok := cookie.(drivers.Terminator)
+	if !ok || a.cfg.CancelGracePeriod <= 0 {
+		return
+	}
+	if err := term.Terminate(ctx); err != nil {
+		logger.WithError(err).Info("cannot terminate hot container")
+		return
+	}
+
+	// ctx is cancelled once the container exits
+	timer := time.NewTimer(a.cfg.CancelGracePeriod)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// hotStartTimeout returns the time a hot container has to become available for requests after
+// it is started. Lazily pulled images fetch their content while the container starts.
+func (a *agent) hotStartTimeout() time.Duration {
+	if a.lazyPull {
+		return a.cfg.LazyPullStartTimeout
+	}
+	return a.cfg.HotStartTimeout
+}
+
 //checkSocketDestination verifies that the socket file created by the FDK is valid and permitted - notably verifying that any symlinks are relative to the socket dir
 func checkSocketDestination(filename string) error {
 	finfo, err := os.Lstat(filename)
@@ -1074,13 +1658,21 @@ func inotifyAwait(ctx context.Context, iofsDir string, udsWait chan error) {
 // runHotReq enqueues a free slot to slot queue manager and watches various timers and the consumer until
 // the slot is consumed. A return value of false means, the container should shutdown and no subsequent
 // calls should be made to this function.
-func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState, logger logrus.FieldLogger, cookie drivers.Cookie, slot *hotSlot, evictor *EvictToken) bool {
+func (a *agent) runHotReq(ctx context.Context, call *call, logger logrus.FieldLogger, cookie drivers.Cookie, slot *hotSlot, hc *hotContainerSlots) bool {
 
 	var err error
 	isFrozen := false
-
-	freezeTimer := time.NewTimer(a.cfg.FreezeIdle)
-	idleTimer := time.NewTimer(time.Duration(call.IdleTimeout) * time.Second)
+	isRetiring := false
+	state := hc.state
+	evictor := hc.evictor
+
+	idleTimeout := call.slots.getIdleTimeout()
+	freezeDelay := a.freezeDelay(call.slots.getScalePolicy())
+	freezeTimer := time.NewTimer(freezeDelay)
+	idleTimer := time.NewTimer(idleTimeout)
+	if !hc.canFreeze() {
+		freezeTimer.Stop()
+	}
 
 	defer func() {
 		evictor.SetEvictable(false)
@@ -1092,8 +1684,7 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 		}
 	}()
 
-	evictor.SetEvictable(true)
-	state.UpdateState(ctx, ContainerStateIdle, call.slots)
+	hc.idle(ctx)
 
 	s := call.slots.queueSlot(slot)
 
@@ -1101,9 +1692,25 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 		select {
 		case <-s.trigger: // slot already consumed
 		case <-ctx.Done(): // container shutdown
+		case <-hc.drain: // container recycled
 		case <-a.shutWg.Closer(): // agent shutdown
 		case <-idleTimer.C:
+			// other slots of the container may have served calls in the meantime
+			if remaining := hc.idleRemaining(idleTimeout); remaining > 0 {
+				idleTimer.Reset(remaining)
+				continue
+			}
+			// keep the min ready containers of the function alive
+			if !call.slots.tryRetire() {
+				idleTimer.Reset(idleTimeout)
+				continue
+			}
+			isRetiring = true
 		case <-freezeTimer.C:
+			if !isFrozen && a.skipFreeze(call.slots) {
+				freezeTimer.Reset(freezeRecheck(freezeDelay))
+				continue
+			}
 			if !isFrozen {
 				ctx, cancel := context.WithTimeout(ctx, pauseTimeout)
 				err = cookie.Freeze(ctx)
@@ -1112,6 +1719,7 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 					return false
 				}
 				isFrozen = true
+				evictor.SetPaused(true)
 				state.UpdateState(ctx, ContainerStatePaused, call.slots)
 			}
 			continue
@@ -1127,8 +1735,15 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 	// otherwise continue processing the request
 	if call.slots.acquireSlot(s) {
 		slot.Close()
+		if isRetiring {
+			state.UpdateState(ctx, ContainerStateDone, call.slots)
+			call.slots.retired()
+		}
 		return false
 	}
+	if isRetiring {
+		call.slots.retired()
+	}
 
 	// In case, timer/acquireSlot failure landed us here, make
 	// sure to unfreeze.
@@ -1140,16 +1755,17 @@ func (a *agent) runHotReq(ctx context.Context, call *call, state ContainerState,
 			return false
 		}
 		isFrozen = false
+		evictor.SetPaused(false)
 	}
 
-	state.UpdateState(ctx, ContainerStateBusy, call.slots)
+	hc.acquire(ctx)
 	return true
 }
 
 // container implements drivers.ContainerTask container is the execution of a
 // single container, which may run multiple functions [consecutively]. the id
 // and stderr can be swapped out by new calls in the container.  input and
-// output must be copied in and out. stdout is sent to stderr.
+// output must be copied in and out. stdout is sent to stderr, see logStdout.
 type container struct {
 	id         string // contrived
 	image      string
@@ -1160,18 +1776,29 @@ type container struct {
 	fsSize     uint64
 	tmpFsSize  uint64
 	disableNet bool
+	sysctls    map[string]string
 	iofs       iofs
 	logCfg     drivers.LoggerConfig
 	close      func()
 	dockerAuth dockerdriver.Auther
 
+	// digest of the image the container runs, if the driver provides it
+	imageDigest string
+
 	stderr io.Writer
+	// logStdout and logStderr are the writers of the streams of the container, to stderr
+	logStdout io.Writer
+	logStderr io.Writer
 
-	udsClient http.Client
+	udsClient   http.Client
+	concurrency uint64
 
-	// swapMu protects the stats swapping
+	// swapMu protects the stats swapping and memUsage
 	swapMu sync.Mutex
 	stats  *drivers.Stats
+
+	// memory used by the container in bytes, as last sampled
+	memUsage uint64
 }
 
 // newHotContainer creates a container that can be used for multiple sequential events
@@ -1194,6 +1821,8 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 
 	inotifyAwait(ctx, iofs.AgentPath(), udsWait)
 
+	concurrency := callConcurrency(ctx, call, cfg.MaxHotConcurrency)
+
 	// IMPORTANT: we are not operating on a TTY allocated container. This means, stderr and stdout are multiplexed
 	// from the same stream internally via docker using a multiplexing protocol. Therefore, stderr/stdout *BOTH*
 	// have to be read or *BOTH* blocked consistently. In other words, we cannot block one and continue
@@ -1220,6 +1849,29 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 		bufs = append(bufs, buf1)
 	}
 
+	// both streams are written to stderr, as json entries of their stream if structured
+	var logStdout, logStderr io.Writer = stderr, stderr
+	var entryWriters []*entryWriter
+	if _, ok := stderr.(common.NoopReadWriteCloser); !ok && cfg.StructuredLogs {
+		entryWriters = []*entryWriter{
+			newEntryWriter(stderr, models.LogStreamStdout),
+			newEntryWriter(stderr, models.LogStreamStderr),
+		}
+		logStdout, logStderr = entryWriters[0], entryWriters[1]
+	}
+
+	// the lines of both streams are redacted before they are encoded or written anywhere
+	var redactWriters []*redactWriter
+	if _, ok := stderr.(common.NoopReadWriteCloser); !ok {
+		if redactor := callRedactor(ctx, call, cfg); redactor != nil {
+			redactWriters = []*redactWriter{
+				newRedactWriter(logStdout, redactor),
+				newRedactWriter(logStderr, redactor),
+			}
+			logStdout, logStderr = redactWriters[0], redactWriters[1]
+		}
+	}
+
 	return &container{
 		id:         id, // XXX we could just let docker generate ids...
 		image:      call.Image,
@@ -1230,6 +1882,7 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 		fsSize:     cfg.MaxFsSize,
 		tmpFsSize:  uint64(call.TmpFsSize),
 		disableNet: call.disableNet,
+		sysctls:    callSysctls(ctx, call),
 		iofs:       iofs,
 		dockerAuth: call.dockerAuth,
 		logCfg: drivers.LoggerConfig{
@@ -1238,12 +1891,17 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 				{Name: "app_id", Value: call.AppID},
 				{Name: "fn_id", Value: call.FnID},
 			},
+			Format:     call.SyslogFormat,
+			TLS:        syslogTLS(cfg),
+			BufferSize: cfg.SyslogBufferSize,
 		},
-		stderr: stderr,
+		stderr:    stderr,
+		logStdout: logStdout,
+		logStderr: logStderr,
 		udsClient: http.Client{
 			Transport: &http.Transport{
-				MaxIdleConns:        1,
-				MaxIdleConnsPerHost: 1,
+				MaxIdleConns:        int(concurrency),
+				MaxIdleConnsPerHost: int(concurrency),
 				// XXX(reed): other settings ?
 				IdleConnTimeout: 1 * time.Second,
 				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
@@ -1252,7 +1910,14 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 				},
 			},
 		},
+		concurrency: concurrency,
 		close: func() {
+			for _, w := range redactWriters {
+				w.Close()
+			}
+			for _, w := range entryWriters {
+				w.Close()
+			}
 			stderr.Close()
 			for _, b := range bufs {
 				bufPool.Put(b)
@@ -1264,7 +1929,15 @@ func newHotContainer(ctx context.Context, call *call, cfg *Config, id string, ud
 	}
 }
 
-func (c *container) swap(stderr io.Writer, cs *drivers.Stats) func() {
+func (c *container) swap(call *call) func() {
+	// calls served concurrently cannot be told apart in the container stderr or
+	// stats, these stay with the container instead of being handed to each call.
+	if c.concurrency > 1 {
+		call.LogUnavailable = models.LogUnavailableConcurrent
+		return func() {}
+	}
+	stderr, cs := call.stderr, &call.Stats
+
 	// if they aren't using a ghost writer, the logs are disabled, we can skip swapping
 	gw, ok := c.stderr.(common.GhostWriter)
 	var ostderr io.Writer
@@ -1289,7 +1962,7 @@ func (c *container) swap(stderr io.Writer, cs *drivers.Stats) func() {
 func (c *container) Id() string                         { return c.id }
 func (c *container) Command() string                    { return "" }
 func (c *container) Input() io.Reader                   { return common.NoopReadWriteCloser{} }
-func (c *container) Logger() (io.Writer, io.Writer)     { return c.stderr, c.stderr }
+func (c *container) Logger() (io.Writer, io.Writer)     { return c.logStdout, c.logStderr }
 func (c *container) Volumes() [][2]string               { return nil }
 func (c *container) WorkDir() string                    { return "" }
 func (c *container) Close()                             { c.close() }
@@ -1305,6 +1978,57 @@ func (c *container) UDSAgentPath() string               { return c.iofs.AgentPat
 func (c *container) UDSDockerPath() string              { return c.iofs.DockerPath() }
 func (c *container) UDSDockerDest() string              { return iofsDockerMountDest }
 func (c *container) DisableNet() bool                   { return c.disableNet }
+func (c *container) Sysctls() map[string]string         { return c.sysctls }
+
+// callSysctls returns the kernel parameters requested by the function of a call
+func callSysctls(ctx context.Context, call *call) map[string]string {
+	raw, ok := call.Annotations.Get(models.FnSysctlsAnnotation)
+	if !ok {
+		return nil
+	}
+
+	var sysctls map[string]string
+	err := json.Unmarshal(raw, &sysctls)
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithField("fn_id", call.FnID).Error("invalid sysctls annotation, ignoring")
+		return nil
+	}
+	return sysctls
+}
+
+// callRedactor returns the redactor of the logs of a call, of the secrets and patterns of its
+// log redaction annotation and of the patterns of the agent, nil if there are none
+func callRedactor(ctx context.Context, call *call, cfg *Config) *models.LogRedactor {
+	patterns := strings.Fields(cfg.LogRedactPatterns)
+	var secrets []string
+	redaction, err := models.LogRedactionFromAnnotations(call.Annotations)
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithField("fn_id", call.FnID).Error("invalid log redaction annotation, redacting the patterns of the agent only")
+	} else if redaction != nil {
+		for _, k := range redaction.SecretKeys {
+			secrets = append(secrets, call.Config[k])
+		}
+		patterns = append(patterns, redaction.Patterns...)
+	}
+
+	// the patterns of the agent and annotation were validated, see NewConfig and App.Validate
+	redactor, _ := models.NewLogRedactor(secrets, patterns)
+	return redactor
+}
+
+// syslogTLS returns the certificates of the connections to tcp+tls syslog URLs, nil if none
+// are configured
+func syslogTLS(cfg *Config) *drivers.LoggerTLSConfig {
+	if cfg.SyslogTLSCACert == "" && cfg.SyslogTLSCert == "" && !cfg.SyslogTLSSkipVerify {
+		return nil
+	}
+	return &drivers.LoggerTLSConfig{
+		CACert:     cfg.SyslogTLSCACert,
+		Cert:       cfg.SyslogTLSCert,
+		Key:        cfg.SyslogTLSKey,
+		SkipVerify: cfg.SyslogTLSSkipVerify,
+	}
+}
 
 // WriteStat publishes each metric in the specified Stats structure as a histogram metric
 func (c *container) WriteStat(ctx context.Context, stat drivers.Stat) {
@@ -1318,9 +2042,19 @@ func (c *container) WriteStat(ctx context.Context, stat drivers.Stat) {
 	if c.stats != nil {
 		*(c.stats) = append(*(c.stats), stat)
 	}
+	if mem, ok := stat.Metrics["mem_usage"]; ok {
+		c.memUsage = mem
+	}
 	c.swapMu.Unlock()
 }
 
+// memoryUsage returns the memory used by the container in bytes, as last sampled.
+func (c *container) memoryUsage() uint64 {
+	c.swapMu.Lock()
+	defer c.swapMu.Unlock()
+	return c.memUsage
+}
+
 // assert we implement this at compile time
 var _ dockerdriver.Auther = new(container)
 
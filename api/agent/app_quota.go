@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// appQuotas limits the calls each app has in flight on the agent, and the memory and
+// cpu reserved by those calls, so that a single app of a multi-tenant deployment cannot
+// take over the runners it shares with other apps. A zero limit is unlimited.
+type appQuotas struct {
+	maxCalls  uint64
+	maxMemory uint64 // bytes
+	maxCPU    uint64 // milli cpus
+
+	lock sync.Mutex // protects apps
+	apps map[string]*appUsage
+}
+
+// appUsage is the quota use of the calls in flight of an app
+type appUsage struct {
+	calls  uint64
+	memory uint64
+	cpu    uint64
+}
+
+func newAppQuotas(cfg *Config) *appQuotas {
+	return &appQuotas{
+		maxCalls:  cfg.MaxAppCalls,
+		maxMemory: cfg.MaxAppMemory,
+		maxCPU:    cfg.MaxAppCPU,
+		apps:      make(map[string]*appUsage),
+	}
+}
+
+func (q *appQuotas) isEnabled() bool {
+	return q.maxCalls != 0 || q.maxMemory != 0 || q.maxCPU != 0
+}
+
+// acquire reserves a call of the app using memory (in bytes) and cpu (in milli cpus),
+// returning models.ErrAppQuotaExceeded if it would exceed any of the app quotas. The
+// returned func releases the reservation once the call is done.
+func (q *appQuotas) acquire(appID string, memory, cpu uint64) (func(), error) {
+	if !q.isEnabled() {
+		return func() {}, nil
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	usage, ok := q.apps[appID]
+	if !ok {
+		usage = &appUsage{}
+	}
+
+	if q.maxCalls != 0 && usage.calls+1 > q.maxCalls {
+		return nil, models.ErrAppQuotaExceeded
+	}
+	if q.maxMemory != 0 && usage.memory+memory > q.maxMemory {
+		return nil, models.ErrAppQuotaExceeded
+	}
+	if q.maxCPU != 0 && usage.cpu+cpu > q.maxCPU {
+		return nil, models.ErrAppQuotaExceeded
+	}
+
+	usage.calls++
+	usage.memory += memory
+	usage.cpu += cpu
+	q.apps[appID] = usage
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { q.release(appID, memory, cpu) })
+	}, nil
+}
+
+func (q *appQuotas) release(appID string, memory, cpu uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	usage, ok := q.apps[appID]
+	if !ok {
+		return
+	}
+
+	usage.calls--
+	usage.memory -= memory
+	usage.cpu -= cpu
+	if usage.calls == 0 {
+		delete(q.apps, appID)
+	}
+}
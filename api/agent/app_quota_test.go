@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAppQuotasDisabled(t *testing.T) {
+	q := newAppQuotas(&Config{})
+
+	for i := 0; i < 100; i++ {
+		if _, err := q.acquire("app", 1024*Mem1MB, 1000); err != nil {
+			t.Fatalf("disabled quotas should not reject calls: %v", err)
+		}
+	}
+}
+
+func TestAppQuotas(t *testing.T) {
+	q := newAppQuotas(&Config{MaxAppCalls: 2, MaxAppMemory: 256 * Mem1MB, MaxAppCPU: 1000})
+
+	release1, err := q.acquire("app1", 128*Mem1MB, 0)
+	if err != nil {
+		t.Fatalf("first call should be admitted: %v", err)
+	}
+	release2, err := q.acquire("app1", 64*Mem1MB, 500)
+	if err != nil {
+		t.Fatalf("second call should be admitted: %v", err)
+	}
+	if _, err := q.acquire("app1", 0, 0); err != models.ErrAppQuotaExceeded {
+		t.Fatalf("third call should exceed the calls quota, got: %v", err)
+	}
+
+	// other apps have their own quotas
+	release3, err := q.acquire("app2", 256*Mem1MB, 1000)
+	if err != nil {
+		t.Fatalf("call of another app should be admitted: %v", err)
+	}
+
+	release2()
+	release2() // releasing twice is harmless
+	if _, err := q.acquire("app1", 192*Mem1MB, 0); err != models.ErrAppQuotaExceeded {
+		t.Fatalf("call should exceed the memory quota, got: %v", err)
+	}
+	if _, err := q.acquire("app2", 0, 1); err != models.ErrAppQuotaExceeded {
+		t.Fatalf("call should exceed the cpu quota, got: %v", err)
+	}
+
+	release1()
+	release3()
+	if len(q.apps) != 0 {
+		t.Fatalf("apps without calls in flight should not be tracked: %#v", q.apps)
+	}
+}
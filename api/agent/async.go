@@ -25,6 +25,9 @@ func (a *agent) asyncDequeue(dqda DequeueDataAccess) {
 		case <-a.shutWg.Closer():
 			a.shutWg.DoneSession()
 			return
+		case <-a.callWg.Closer(): // draining
+			a.shutWg.DoneSession()
+			return
 		case <-a.resources.WaitAsyncResource(ctx):
 			// TODO we _could_ return a token here to reserve the ram so that there's
 			// not a race between here and Submit but we're single threaded
@@ -32,15 +35,28 @@ func (a *agent) asyncDequeue(dqda DequeueDataAccess) {
 			// out of RAM so..
 		}
 
+		// the call being reserved holds off drains until it is run, see EnvDrainFinishAsync
+		held := a.cfg.DrainFinishAsync && a.callWg.AddSession(1)
+		draining := a.callWg.Closer()
+		if held {
+			draining = nil
+		}
+
 		// we think we can get a cookie now, so go get a cookie
 		select {
 		case <-a.shutWg.Closer():
+			if held {
+				a.callWg.DoneSession()
+			}
+			a.shutWg.DoneSession()
+			return
+		case <-draining:
 			a.shutWg.DoneSession()
 			return
 		case model, ok := <-a.asyncChew(ctx, dqda):
 			if ok {
 				go func(model *models.Call) {
-					a.asyncRun(ctx, model)
+					a.asyncRun(ctx, model, held)
 					a.shutWg.DoneSession()
 				}(model)
 
@@ -48,6 +64,8 @@ func (a *agent) asyncDequeue(dqda DequeueDataAccess) {
 				if !a.shutWg.AddSession(1) {
 					return
 				}
+			} else if held {
+				a.callWg.DoneSession()
 			}
 		}
 	}
@@ -76,11 +94,19 @@ func (a *agent) asyncChew(ctx context.Context, dqda DequeueDataAccess) <-chan *m
 	return ch
 }
 
-func (a *agent) asyncRun(ctx context.Context, model *models.Call) {
+// asyncRun runs a call reserved from the queue, held is true if the call holds a session of
+// callWg, which is then released once the call is done
+func (a *agent) asyncRun(ctx context.Context, model *models.Call, held bool) {
 	// IMPORTANT: get a context that has a child span but NO timeout (Submit imposes timeout)
 	// TODO this is a 'FollowsFrom'
 	ctx = common.BackgroundContext(ctx)
 
+	// logs of the call carry the request id of the request that enqueued it
+	if model.RequestID != "" {
+		ctx = common.WithRequestID(ctx, model.RequestID)
+		ctx, _ = common.LoggerWithFields(ctx, logrus.Fields{common.RequestIDContextKey: model.RequestID})
+	}
+
 	// since async doesn't come in through the normal request path,
 	// we've gotta add tags here for stats to come out properly.
 	appKey, err := tag.NewKey("app_id")
@@ -106,8 +132,12 @@ func (a *agent) asyncRun(ctx context.Context, model *models.Call) {
 	call, err := a.GetCall(
 		FromModel(model),
 		WithContext(ctx), // NOTE: order is important
+		withDrainHeld(held),
 	)
 	if err != nil {
+		if held {
+			a.callWg.DoneSession()
+		}
 		logrus.WithError(err).Error("error getting async call")
 		return
 	}
@@ -121,3 +151,11 @@ func (a *agent) asyncRun(ctx context.Context, model *models.Call) {
 		logrus.WithFields(logrus.Fields{"id": id}).WithError(err).Error("error running async call")
 	}
 }
+
+// withDrainHeld marks a call reserved from the queue as holding a session of callWg
+func withDrainHeld(held bool) CallOpt {
+	return func(c *call) error {
+		c.drainHeld = held
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// WithAuditSink writes an audit record of every call to sink, in place of the sink
+// configured with EnvAuditURL. The agent closes the sink when it is closed.
+func WithAuditSink(sink audit.Sink) Option {
+	return func(a *agent) error {
+		a.auditSink = sink
+		return nil
+	}
+}
+
+// writeAudit emits the audit record of an ended call, a failure to do so is only logged.
+func (c *call) writeAudit(ctx context.Context) {
+	if c.auditSink == nil {
+		return
+	}
+	if err := c.auditSink.Write(ctx, newAuditRecord(c)); err != nil {
+		common.Logger(ctx).WithError(err).Error("error writing audit record")
+	}
+}
+
+func newAuditRecord(c *call) *audit.Record {
+	rec := &audit.Record{
+		CallID:      c.ID,
+		AppID:       c.AppID,
+		AppName:     c.AppName,
+		FnID:        c.FnID,
+		FnRevision:  c.FnRevision,
+		TriggerID:   c.TriggerID,
+		Image:       c.Image,
+		ImageDigest: c.imageDigest,
+		Type:        c.Type,
+		Method:      c.Method,
+		URL:         c.URL,
+		Status:      c.Status,
+		Error:       c.Error,
+		CreatedAt:   c.CreatedAt,
+		StartedAt:   c.StartedAt,
+		CompletedAt: c.CompletedAt,
+		Resources: audit.Resources{
+			Memory: c.Memory,
+			CPUs:   uint64(c.CPUs),
+			Peak:   peakMetrics(c.Call),
+		},
+	}
+
+	if c.req != nil {
+		rec.Caller = audit.Caller{
+			Address:  c.trustedProxies.CallerAddress(c.req),
+			Identity: c.req.Header.Get(audit.CallerIdentityHeader),
+		}
+	}
+
+	created, started, completed := time.Time(c.CreatedAt), time.Time(c.StartedAt), time.Time(c.CompletedAt)
+	if !created.IsZero() && !completed.IsZero() {
+		rec.Latencies.Total = msecs(completed.Sub(created))
+	}
+	if !started.IsZero() {
+		if !created.IsZero() {
+			rec.Latencies.Scheduling = msecs(started.Sub(created))
+		}
+		if !completed.IsZero() {
+			rec.Latencies.Execution = msecs(completed.Sub(started))
+		}
+	}
+	return rec
+}
+
+// peakMetrics returns the highest sample of each container metric of a call
+func peakMetrics(call *models.Call) map[string]uint64 {
+	if len(call.Stats) == 0 {
+		return nil
+	}
+	peak := make(map[string]uint64)
+	for _, stat := range call.Stats {
+		for k, v := range stat.Metrics {
+			if v > peak[k] {
+				peak[k] = v
+			}
+		}
+	}
+	return peak
+}
+
+func msecs(d time.Duration) int64 {
+	if d < 0 {
+		return 0
+	}
+	return int64(d / time.Millisecond)
+}
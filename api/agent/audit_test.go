@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestAuditRecord(t *testing.T) {
+	created := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	req, err := http.NewRequest("POST", "http://localhost/invoke/fn_id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set(audit.CallerIdentityHeader, "alice")
+
+	c := &call{
+		Call: &models.Call{
+			ID:          "call_id",
+			AppID:       "app_id",
+			FnID:        "fn_id",
+			Image:       "fnproject/fn-test-utils",
+			Type:        models.TypeSync,
+			Status:      "error",
+			Error:       "boom",
+			Memory:      128,
+			CreatedAt:   common.DateTime(created),
+			StartedAt:   common.DateTime(created.Add(250 * time.Millisecond)),
+			CompletedAt: common.DateTime(created.Add(time.Second)),
+			Stats: drivers.Stats{
+				{Metrics: map[string]uint64{"mem_usage": 10, "cpu_total": 5}},
+				{Metrics: map[string]uint64{"mem_usage": 30, "cpu_total": 2}},
+			},
+		},
+		req:         req,
+		imageDigest: "sha256:abc",
+	}
+
+	rec := newAuditRecord(c)
+	if rec.CallID != "call_id" || rec.ImageDigest != "sha256:abc" || rec.Status != "error" || rec.Error != "boom" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	if rec.Caller.Address != "10.0.0.1" || rec.Caller.Identity != "alice" {
+		t.Fatalf("unexpected caller %+v", rec.Caller)
+	}
+	if rec.Latencies.Scheduling != 250 || rec.Latencies.Execution != 750 || rec.Latencies.Total != 1000 {
+		t.Fatalf("unexpected latencies %+v", rec.Latencies)
+	}
+	if rec.Resources.Memory != 128 || rec.Resources.Peak["mem_usage"] != 30 || rec.Resources.Peak["cpu_total"] != 5 {
+		t.Fatalf("unexpected resources %+v", rec.Resources)
+	}
+
+	// only trusted proxies forward requests
+	req.Header.Set("X-Forwarded-For", "192.168.1.1, 10.0.0.2")
+	if rec := newAuditRecord(c); rec.Caller.Address != "10.0.0.1" {
+		t.Fatalf("expected the remote address, got %s", rec.Caller.Address)
+	}
+	c.trustedProxies, _ = audit.ParseTrustedProxies("10.0.0.0/24")
+	if rec := newAuditRecord(c); rec.Caller.Address != "192.168.1.1" {
+		t.Fatalf("expected the forwarded address, got %s", rec.Caller.Address)
+	}
+}
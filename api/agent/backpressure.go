@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// maxRetryAfter bounds the suggested delay before retrying a rejected call
+const maxRetryAfter = 15 * time.Second
+
+// Backpressure implements BackpressureProvider
+func (a *agent) Backpressure(c Call) (models.Backpressure, bool) {
+	call, ok := c.(*call)
+	if !ok {
+		return models.Backpressure{}, false
+	}
+
+	slots := call.slots
+	if slots == nil {
+		// rejected before waiting for a slot, eg. app quota exceeded
+		slots = a.slotMgr.getCurrentSlotQueue(call.FnID)
+	}
+
+	bp := models.Backpressure{
+		InFlight:    atomic.LoadUint64(&a.inFlight),
+		MaxInFlight: a.cfg.MaxInFlightCalls,
+	}
+	var containers uint64
+	if slots != nil {
+		stats := slots.getStats()
+		bp.QueueDepth = stats.requestStates[RequestStateWait]
+		containers = stats.containerStates[ContainerStateStart] +
+			stats.containerStates[ContainerStateIdle] +
+			stats.containerStates[ContainerStatePaused] +
+			stats.containerStates[ContainerStateBusy]
+	}
+	if containers == 0 {
+		containers = 1
+	}
+
+	// a retry likely waits behind the calls queued per container, as long as the rejected
+	// call was allowed to wait for each of them
+	wait := call.slotWaitTimeout(&a.cfg)
+	if wait <= 0 {
+		wait = time.Second
+	} else if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	retryAfter := maxRetryAfter
+	if queued := bp.QueueDepth / containers; queued < uint64(maxRetryAfter/wait) {
+		retryAfter = wait * time.Duration(1+queued)
+		if retryAfter > maxRetryAfter {
+			retryAfter = maxRetryAfter
+		}
+	}
+
+	bp.RetryAfter = int64((retryAfter + time.Second - 1) / time.Second)
+	return bp, true
+}
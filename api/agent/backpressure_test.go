@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestBackpressure(t *testing.T) {
+	a := &agent{cfg: Config{SlotWaitTimeout: 2 * time.Second}, slotMgr: NewSlotQueueMgr()}
+
+	slots := NewSlotQueue("fn")
+	slots.enterContainerState(ContainerStateBusy)
+	slots.enterContainerState(ContainerStateBusy)
+	for i := 0; i < 4; i++ {
+		slots.enterRequestState(RequestStateWait)
+	}
+	c := &call{Call: &models.Call{FnID: "fn"}, slots: slots}
+
+	bp, ok := a.Backpressure(c)
+	if !ok {
+		t.Fatal("expected backpressure")
+	}
+	// 2 calls queued per container, each waited for up to 2s
+	if bp.QueueDepth != 4 || bp.RetryAfter != 6 {
+		t.Fatalf("unexpected backpressure %+v", bp)
+	}
+
+	// per fn timeout overrides the agent one
+	c.SlotWaitTimeout = 500
+	if bp, _ = a.Backpressure(c); bp.RetryAfter != 2 {
+		t.Fatalf("expected retry after 2s, got %+v", bp)
+	}
+
+	// suggested delay is bounded
+	for i := 0; i < 100; i++ {
+		slots.enterRequestState(RequestStateWait)
+	}
+	if bp, _ = a.Backpressure(c); bp.RetryAfter != int64(maxRetryAfter/time.Second) {
+		t.Fatalf("expected retry after %v, got %+v", maxRetryAfter, bp)
+	}
+
+	// no slot queue yet, eg. rejected before waiting
+	bp, ok = a.Backpressure(&call{Call: &models.Call{FnID: "other"}})
+	if !ok || bp.QueueDepth != 0 || bp.RetryAfter != 2 {
+		t.Fatalf("unexpected backpressure %+v", bp)
+	}
+}
+
+func TestAdmitMaxInFlight(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{MaxInFlightCalls: 2}
+	a := &agent{
+		cfg:       cfg,
+		slotMgr:   NewSlotQueueMgr(),
+		appQuotas: newAppQuotas(&cfg),
+		shutWg:    common.NewWaitGroup(),
+		callWg:    common.NewWaitGroup(),
+	}
+	c := &call{Call: &models.Call{AppID: "app", FnID: "fn"}}
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := a.admit(ctx, c)
+		if err != nil {
+			t.Fatalf("call %d: expected call to be admitted, got %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	if _, err := a.admit(ctx, c); err != models.ErrAgentOverloaded {
+		t.Fatalf("expected call to be rejected with %v, got %v", models.ErrAgentOverloaded, err)
+	}
+	if bp, _ := a.Backpressure(c); bp.InFlight != 2 || bp.MaxInFlight != 2 {
+		t.Fatalf("unexpected backpressure %+v", bp)
+	}
+
+	releases[0]()
+	release, err := a.admit(ctx, c)
+	if err != nil {
+		t.Fatalf("expected call to be admitted once another is done, got %v", err)
+	}
+	release()
+	releases[1]()
+	if a.inFlight != 0 {
+		t.Fatalf("expected no calls in flight, got %d", a.inFlight)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/audit"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
@@ -52,41 +53,70 @@ type CallOpt func(c *call) error
 // FromHTTPFnRequest Sets up a call from an http trigger request
 func FromHTTPFnRequest(app *models.App, fn *models.Fn, req *http.Request) CallOpt {
 	return func(c *call) error {
-		id := id.New().String()
+		c.Call = NewCallModel(app, fn, req)
+		c.req = req
+		return nil
+	}
+}
 
-		var syslogURL string
-		if app.SyslogURL != nil {
-			syslogURL = *app.SyslogURL
-		}
+// NewCallModel builds the model of a sync call of fn for req, callers that enqueue calls
+// instead of running them set its Type and Payload, see FromModel
+func NewCallModel(app *models.App, fn *models.Fn, req *http.Request) *models.Call {
+	id := id.New().String()
 
-		c.Call = &models.Call{
-			ID:    id,
-			Image: fn.Image,
-			// Delay: 0,
-			Type: models.TypeSync,
-			// Payload: TODO,
-			Priority:    new(int32), // TODO this is crucial, apparently
-			Timeout:     fn.Timeout,
-			IdleTimeout: fn.IdleTimeout,
-			TmpFsSize:   0, // TODO clean up this
-			Memory:      fn.Memory,
-			CPUs:        0, // TODO clean up this
-			Config:      buildConfig(app, fn),
-			// TODO - this wasn't really the intention here (that annotations would naturally cascade
-			// but seems to be necessary for some runner behaviour
-			Annotations: app.Annotations.MergeChange(fn.Annotations),
-			Headers:     req.Header,
-			CreatedAt:   common.DateTime(time.Now()),
-			URL:         reqURL(req),
-			Method:      req.Method,
-			AppID:       app.ID,
-			AppName:     app.Name,
-			FnID:        fn.ID,
-			SyslogURL:   syslogURL,
-		}
+	fn, canary := routeCanary(fn)
 
-		c.req = req
-		return nil
+	var syslogURL string
+	if app.SyslogURL != nil {
+		syslogURL = *app.SyslogURL
+	}
+
+	annotations := app.Annotations.MergeChange(fn.Annotations)
+
+	// annotations are validated when apps and fns are stored, fall back to the default
+	priority, _ := models.PriorityFromAnnotations(annotations)
+	maxRequestSize, maxResponseSize, _ := models.SizeLimitsFromAnnotations(app.Annotations)
+	maxLogSize, _ := models.MaxLogSizeFromAnnotations(app.Annotations)
+	syslogFormat, _ := models.SyslogFormatFromAnnotations(app.Annotations)
+	slotWaitTimeout, _ := models.SlotWaitTimeoutFromAnnotations(fn.Annotations)
+
+	return &models.Call{
+		ID:    id,
+		Image: fn.Image,
+		// Delay: 0,
+		Type: models.TypeSync,
+		// Payload: TODO,
+		Priority:        &priority,
+		Timeout:         fn.Timeout,
+		IdleTimeout:     fn.IdleTimeout,
+		MinReady:        fn.GetMinReady(),
+		MaxContainers:   fn.GetMaxContainers(),
+		ScalePolicy:     fn.ScalePolicy,
+		RetryPolicy:     fn.RetryPolicy.Clone(),
+		RecyclePolicy:   fn.RecyclePolicy.Clone(),
+		SlotWaitTimeout: uint64(slotWaitTimeout / time.Millisecond),
+		MaxRequestSize:  maxRequestSize,
+		MaxResponseSize: maxResponseSize,
+		MaxLogSize:      maxLogSize,
+		TmpFsSize:       0, // TODO clean up this
+		Memory:          fn.Memory,
+		CPUs:            0, // TODO clean up this
+		Config:          buildConfig(app, fn),
+		// TODO - this wasn't really the intention here (that annotations would naturally cascade
+		// but seems to be necessary for some runner behaviour
+		Annotations:  annotations,
+		Headers:      req.Header,
+		CreatedAt:    common.DateTime(time.Now()),
+		URL:          reqURL(req),
+		Method:       req.Method,
+		AppID:        app.ID,
+		AppName:      app.Name,
+		FnID:         fn.ID,
+		FnRevision:   fn.Revision,
+		Canary:       canary,
+		SyslogURL:    syslogURL,
+		SyslogFormat: syslogFormat,
+		RequestID:    common.RequestIDFromContext(req.Context()),
 	}
 }
 
@@ -176,6 +206,15 @@ func WithWriter(w io.Writer) CallOpt {
 	}
 }
 
+// WithWebSocket marks a call as a WebSocket upgrade, the connection of the request is proxied
+// to the container for its lifetime. The call writer must implement http.Hijacker.
+func WithWebSocket() CallOpt {
+	return func(c *call) error {
+		c.isWebSocket = true
+		return nil
+	}
+}
+
 // WithLogger sets stderr to the provided one
 func WithLogger(w io.ReadWriteCloser) CallOpt {
 	return func(c *call) error {
@@ -253,21 +292,31 @@ func (a *agent) GetCall(opts ...CallOpt) (Call, error) {
 		return nil, models.ErrCallResourceTooBig
 	}
 
+	if err := c.limitRequest(&a.cfg); err != nil {
+		return nil, err
+	}
+
 	if c.Call.Config == nil {
 		c.Call.Config = make(models.Config)
 	}
 	c.Call.Config["FN_LISTENER"] = "unix:" + filepath.Join(iofsDockerMountDest, udsFilename)
 	c.Call.Config["FN_FORMAT"] = "http-stream" // TODO: remove this after fdk's forget what it means
+	if shared, _ := models.SharedPoolFromAnnotations(c.Annotations); shared {
+		delete(c.Call.Config, sharedPoolUnsetConfig)
+	}
 	// TODO we could set type here too, for now, or anything else not based in fn/app/trigger config
 
 	setupCtx(&c)
 
 	c.handler = a.da
 	c.ct = a
+	c.auditSink = a.auditSink
+	c.trustedProxies = a.trustedProxies
+	c.logTail = a.logTail
 	if c.stderr == nil {
 		// TODO(reed): is line writer is vulnerable to attack?
 		// XXX(reed): forcing this as default is not great / configuring it isn't great either. reconsider.
-		c.stderr = setupLogger(c.req.Context(), a.cfg.MaxLogSize, !a.cfg.DisableDebugUserLogs, c.Call)
+		c.stderr = setupLogger(c.req.Context(), c.maxLogSize(&a.cfg), !a.cfg.DisableDebugUserLogs, c.Call, c.logTail)
 	}
 	if c.respWriter == nil {
 		// send function output to logs if no writer given (async...)
@@ -296,7 +345,16 @@ type call struct {
 	requestState RequestState
 	slotHashId   string
 	disableNet   bool
+	isWebSocket  bool          // request upgrades to a WebSocket proxied to the container
+	result       *resultWriter // response of a detached call, stored once it ends
 	dockerAuth   docker.Auther // pull config function
+	auditSink    audit.Sink    // audit record is written once the call ends
+	logTail      *logTail      // the output of the call is streamed to the tails of its fn
+	imageDigest  string        // digest of the image of the container the call ran in
+	drainHeld    bool          // call was admitted before a drain, see EnvDrainFinishAsync
+
+	// proxies the caller of the audit record of the call may be forwarded by
+	trustedProxies audit.TrustedProxies
 
 	// amount of time attributed to user-code execution
 	userExecTime *time.Duration
@@ -346,6 +404,63 @@ func (c *call) GetUserExecutionTime() *time.Duration {
 
 func (c *call) Model() *models.Call { return c.Call }
 
+// slotWaitTimeout returns the time the call may wait for a hot container, 0 if unlimited.
+func (c *call) slotWaitTimeout(cfg *Config) time.Duration {
+	if c.SlotWaitTimeout > 0 {
+		return time.Duration(c.SlotWaitTimeout) * time.Millisecond
+	}
+	return cfg.SlotWaitTimeout
+}
+
+// maxRequestSize returns the maximum size of the request body of the call, 0 if unlimited.
+func (c *call) maxRequestSize(cfg *Config) uint64 {
+	return minSizeLimit(c.MaxRequestSize, cfg.MaxRequestSize)
+}
+
+// maxResponseSize returns the maximum size of the response body of the call, 0 if unlimited.
+func (c *call) maxResponseSize(cfg *Config) uint64 {
+	return minSizeLimit(c.MaxResponseSize, cfg.MaxResponseSize)
+}
+
+// minSizeLimit returns the limit of an app clamped to the limit of the runner, apps may only
+// lower it. 0 is unlimited.
+func minSizeLimit(app, runner uint64) uint64 {
+	if app > 0 && (runner == 0 || app < runner) {
+		return app
+	}
+	return runner
+}
+
+// maxLogSize returns the maximum size of the log captured for the call.
+func (c *call) maxLogSize(cfg *Config) uint64 {
+	if c.MaxLogSize > 0 {
+		return c.MaxLogSize
+	}
+	return cfg.MaxLogSize
+}
+
+// limitRequest rejects calls with a request body known to be too large upfront, otherwise the
+// body is cut off at the limit and reading past it fails with models.ErrRequestContentTooBig.
+func (c *call) limitRequest(cfg *Config) error {
+	max := c.maxRequestSize(cfg)
+	if max == 0 || c.req.Body == nil {
+		return nil
+	}
+	if c.req.ContentLength > 0 && uint64(c.req.ContentLength) > max {
+		return models.ErrRequestContentTooBig
+	}
+	c.req.Body = common.NewClampReadCloser(c.req.Body, max, models.ErrRequestContentTooBig)
+	return nil
+}
+
+// priority returns the priority class of the call, see models.PriorityLow etc.
+func (c *call) priority() int32 {
+	if c.Priority == nil {
+		return models.PriorityNormal
+	}
+	return clampPriority(*c.Priority)
+}
+
 func (c *call) Start(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "agent_call_start")
 	defer span.End()
@@ -389,14 +504,46 @@ func (c *call) End(ctx context.Context, errIn error) error {
 		c.Status = "success"
 	case context.DeadlineExceeded:
 		c.Status = "timeout"
+	case context.Canceled:
+		c.Status = "cancelled"
+		c.Error = "client_request"
+		// the client is gone, the call is still recorded
+		ctx = common.BackgroundContext(ctx)
 	default:
 		c.Status = "error"
 		c.Error = errIn.Error()
 	}
 
+	if c.Canary != "" {
+		statsCanaryCall(ctx, c.Call)
+	}
+
 	// ensure stats histogram is reasonably bounded
 	c.Call.Stats = drivers.Decimate(240, c.Call.Stats)
 
+	if d, ok := c.stderr.(interface{ Dropped() int64 }); ok {
+		c.LogDropped = d.Dropped()
+	}
+	if l, ok := c.stderr.(interface{ Len() int }); ok {
+		c.LogSize = int64(l.Len())
+	}
+
+	// store the result first, it is available once the call shows as ended
+	if c.result != nil && errIn == nil {
+		if rh, ok := c.handler.(ResultHandler); ok {
+			if err := rh.StoreResult(ctx, c.Model(), &c.result.body); err != nil {
+				common.Logger(ctx).WithError(err).Error("error storing call result")
+				// the caller can't get the result, so the call failed for them
+				c.Status = "error"
+				c.Error = models.ErrCallResultStore.Error()
+			}
+		}
+	}
+
+	if c.Type == models.TypeAsync && errIn != nil {
+		c.retryOrDeadLetter(ctx)
+	}
+
 	if err := c.handler.Finish(ctx, c.Model(), c.stderr, c.Type == models.TypeAsync); err != nil {
 		common.Logger(ctx).WithError(err).Error("error finalizing call on datastore/mq")
 		// note: Not returning err here since the job could have already finished successfully.
@@ -405,12 +552,63 @@ func (c *call) End(ctx context.Context, errIn error) error {
 	// NOTE call this after InsertLog or the buffer will get reset
 	c.stderr.Close()
 
+	c.writeAudit(ctx)
+
 	if err := c.ct.fireAfterCall(ctx, c.Model()); err != nil {
 		return err
 	}
 	return errIn // original error, important for use in sync call returns
 }
 
+// retryOrDeadLetter enqueues a retry of a failed async call, or dead letters it once the
+// retry policy of its fn is exhausted or its retry can't be enqueued. Calls of fns without
+// a retry policy are left alone.
+func (c *call) retryOrDeadLetter(ctx context.Context) {
+	policy := c.RetryPolicy
+	if policy.IsEmpty() {
+		return
+	}
+
+	attempt := c.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	if policy.ShouldRetry(attempt) {
+		eqda, ok := c.handler.(EnqueueDataAccess)
+		if !ok {
+			return
+		}
+
+		retry := *c.Model()
+		retry.ID = id.New().String()
+		retry.Attempt = attempt + 1
+		retry.RetryOf = c.ID
+		retry.Status = "queued"
+		retry.Error = ""
+		retry.Stats = nil
+		retry.Delay = policy.Delay(attempt)
+		retry.CreatedAt = common.DateTime(time.Now())
+		retry.StartedAt = common.DateTime(time.Time{})
+		retry.CompletedAt = common.DateTime(time.Time{})
+
+		err := eqda.Enqueue(ctx, &retry)
+		if err == nil {
+			return
+		}
+		common.Logger(ctx).WithError(err).Error("error enqueueing retry of call")
+		// the call won't be retried, record why and keep it as a dead letter instead
+		c.Status = "error"
+		c.Error = fmt.Sprintf("%v: %v", models.ErrCallRetryEnqueue, err)
+	}
+
+	if dlh, ok := c.handler.(DeadLetterHandler); ok {
+		if err := dlh.DeadLetter(ctx, c.Model()); err != nil {
+			common.Logger(ctx).WithError(err).Error("error dead lettering call")
+		}
+	}
+}
+
 func GetCallLatencies(c *call) (time.Duration, time.Duration) {
 	var schedDuration time.Duration
 	var execDuration time.Duration
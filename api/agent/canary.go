@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"math/rand"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// routeCanary returns the fn serving a call of fn, the candidate of its canary for the
+// weight of the canary, and the role of the revision serving the call in the canary, if
+// fn has one.
+func routeCanary(fn *models.Fn) (*models.Fn, string) {
+	if fn.Canary.IsEmpty() {
+		return fn, ""
+	}
+	if !fn.Canary.Routes(rand.Int31n(100)) {
+		return fn, models.CanaryStable
+	}
+	return fn.CandidateFn(), models.CanaryCandidate
+}
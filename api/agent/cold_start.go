@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// Phases of the cold start of a hot container
+const (
+	coldStartRegistryAuth    = "registry_auth"
+	coldStartImagePull       = "image_pull"
+	coldStartContainerCreate = "container_create"
+	coldStartContainerStart  = "container_start"
+	coldStartUDSInit         = "uds_init"
+)
+
+type phaseDuration struct {
+	phase string
+	dur   time.Duration
+}
+
+// coldStartPhases times the phases of the cold start of a hot container, one after the
+// other. Each phase is traced in its own span, and the time spent in each is recorded once
+// the container is initialized, so that operators can see where cold start time goes.
+type coldStartPhases struct {
+	ctx       context.Context
+	span      *trace.Span
+	phase     string
+	begin     time.Time
+	durations []phaseDuration
+}
+
+func newColdStartPhases(ctx context.Context) *coldStartPhases {
+	return &coldStartPhases{ctx: ctx}
+}
+
+// start begins phase, returning the context to run it with.
+func (p *coldStartPhases) start(phase string) context.Context {
+	ctx, span := trace.StartSpan(p.ctx, "agent_cold_start_"+phase)
+	p.span = span
+	p.phase = phase
+	p.begin = time.Now()
+	return ctx
+}
+
+// end ends the current phase, err is the error it failed with if any. It does nothing once
+// the phase ended, so that it may be deferred.
+func (p *coldStartPhases) end(err error) {
+	if p.span == nil {
+		return
+	}
+	if err != nil {
+		p.span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	p.span.End()
+	p.span = nil
+	p.durations = append(p.durations, phaseDuration{phase: p.phase, dur: time.Since(p.begin)})
+}
+
+// split accounts dur of an ended phase to sub instead, eg. registry auth done while pulling
+// the image.
+func (p *coldStartPhases) split(phase, sub string, dur time.Duration) {
+	if dur <= 0 {
+		return
+	}
+	for i := range p.durations {
+		if p.durations[i].phase == phase && p.durations[i].dur >= dur {
+			p.durations[i].dur -= dur
+			p.durations = append(p.durations, phaseDuration{phase: sub, dur: dur})
+			return
+		}
+	}
+}
+
+// record records the time spent in each phase.
+func (p *coldStartPhases) record(logger logrus.FieldLogger) {
+	fields := make(logrus.Fields, len(p.durations))
+	for _, d := range p.durations {
+		statsColdStartPhase(p.ctx, d.phase, d.dur)
+		fields[d.phase+"_ms"] = int64(d.dur / time.Millisecond)
+	}
+	logger.WithFields(fields).Debug("hot container cold started")
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestColdStartPhases(t *testing.T) {
+	phases := newColdStartPhases(context.Background())
+
+	for _, phase := range []string{coldStartImagePull, coldStartContainerCreate} {
+		if ctx := phases.start(phase); ctx == nil {
+			t.Fatal("expected a context for the phase")
+		}
+		time.Sleep(5 * time.Millisecond)
+		phases.end(nil)
+	}
+	phases.start(coldStartContainerStart)
+	phases.end(errors.New("boom"))
+	// ending an ended phase, eg. deferred, does nothing
+	phases.end(nil)
+
+	pull := phases.durations[0].dur
+	phases.split(coldStartImagePull, coldStartRegistryAuth, 2*time.Millisecond)
+	// more than the phase took is ignored
+	phases.split(coldStartContainerCreate, coldStartRegistryAuth, time.Hour)
+
+	expected := []string{coldStartImagePull, coldStartContainerCreate, coldStartContainerStart, coldStartRegistryAuth}
+	if len(phases.durations) != len(expected) {
+		t.Fatalf("expected phases %v, got %+v", expected, phases.durations)
+	}
+	for i, phase := range expected {
+		if phases.durations[i].phase != phase {
+			t.Fatalf("expected phases %v, got %+v", expected, phases.durations)
+		}
+	}
+	if phases.durations[0].dur != pull-2*time.Millisecond || phases.durations[3].dur != 2*time.Millisecond {
+		t.Fatalf("registry auth should be split out of image pull, got %+v", phases.durations)
+	}
+	if phases.durations[1].dur < 5*time.Millisecond {
+		t.Fatalf("unexpected container create time %+v", phases.durations)
+	}
+}
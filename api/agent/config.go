@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 // Config specifies various settings for an agent
@@ -16,12 +18,15 @@ type Config struct {
 	DockerNetworks          string        `json:"docker_networks"`
 	DockerLoadFile          string        `json:"docker_load_file"`
 	FreezeIdle              time.Duration `json:"freeze_idle_msecs"`
+	FreezeAdaptive          bool          `json:"freeze_adaptive"`
+	FreezeAdaptiveRate      uint64        `json:"freeze_adaptive_calls_per_min"`
 	HotPoll                 time.Duration `json:"hot_poll_msecs"`
 	HotLauncherTimeout      time.Duration `json:"hot_launcher_timeout_msecs"`
 	HotPullTimeout          time.Duration `json:"hot_pull_timeout_msecs"`
 	HotStartTimeout         time.Duration `json:"hot_start_timeout_msecs"`
 	AsyncChewPoll           time.Duration `json:"async_chew_poll_msecs"`
 	DetachedHeadRoom        time.Duration `json:"detached_head_room_msecs"`
+	MaxRequestSize          uint64        `json:"max_request_size_bytes"`
 	MaxResponseSize         uint64        `json:"max_response_size_bytes"`
 	MaxLogSize              uint64        `json:"max_log_size_bytes"`
 	MaxTotalCPU             uint64        `json:"max_total_cpu_mcpus"`
@@ -32,10 +37,26 @@ type Config struct {
 	PreForkCmd              string        `json:"pre_fork_pool_cmd"`
 	PreForkUseOnce          uint64        `json:"pre_fork_use_once"`
 	PreForkNetworks         string        `json:"pre_fork_networks"`
+	PreForkPoolMaxSize      uint64        `json:"pre_fork_pool_max_size"`
+	PreForkHighWatermark    uint64        `json:"pre_fork_pool_high_watermark_pct"`
+	PreForkLowWatermark     uint64        `json:"pre_fork_pool_low_watermark_pct"`
+	PreForkScaleInterval    time.Duration `json:"pre_fork_pool_scale_interval_msecs"`
 	EnableNBResourceTracker bool          `json:"enable_nb_resource_tracker"`
 	MaxTmpFsInodes          uint64        `json:"max_tmpfs_inodes"`
 	DisableReadOnlyRootFs   bool          `json:"disable_readonly_rootfs"`
+	DisableNoNewPrivs       bool          `json:"disable_no_new_privileges"`
+	DockerAllowedSysctls    string        `json:"docker_allowed_sysctls"`
+	CgroupVersion           uint64        `json:"cgroup_version"`
 	DisableDebugUserLogs    bool          `json:"disable_debug_user_logs"`
+	StructuredLogs          bool          `json:"structured_logs"`
+	LogRedactPatterns       string        `json:"log_redact_patterns"`
+	SyslogTLSCACert         string        `json:"syslog_tls_ca_cert"`
+	SyslogTLSCert           string        `json:"syslog_tls_cert"`
+	SyslogTLSKey            string        `json:"syslog_tls_key"`
+	SyslogTLSSkipVerify     bool          `json:"syslog_tls_skip_verify"`
+	SyslogBufferSize        uint64        `json:"syslog_buffer_size_bytes"`
+	SyslogRetryAttempts     uint64        `json:"syslog_retry_attempts"`
+	SyslogRetryBackoff      time.Duration `json:"syslog_retry_backoff_msecs"`
 	IOFSEnableTmpfs         bool          `json:"iofs_enable_tmpfs"`
 	IOFSAgentPath           string        `json:"iofs_path"`
 	IOFSMountRoot           string        `json:"iofs_mount_root"`
@@ -43,6 +64,39 @@ type Config struct {
 	ImageCleanMaxSize       uint64        `json:"image_clean_max_size"`
 	ImageCleanExemptTags    string        `json:"image_clean_exempt_tags"`
 	ImageEnableVolume       bool          `json:"image_enable_volume"`
+	DockerRegistryMirrors   string        `json:"docker_registry_mirrors"`
+	PullRetryAttempts       uint64        `json:"pull_retry_attempts"`
+	PullRetryBackoff        time.Duration `json:"pull_retry_backoff_msecs"`
+	PullRetryMaxBackoff     time.Duration `json:"pull_retry_max_backoff_msecs"`
+	PullRetryJitter         uint64        `json:"pull_retry_jitter_pct"`
+	PullRetryStatusCodes    string        `json:"pull_retry_status_codes"`
+	LazyPull                bool          `json:"lazy_pull"`
+	LazyPullSnapshotter     string        `json:"lazy_pull_snapshotter"`
+	LazyPullStartTimeout    time.Duration `json:"lazy_pull_start_timeout_msecs"`
+	EnableSnapshots         bool          `json:"enable_snapshots"`
+	DockerAPIVersion        string        `json:"docker_api_version"`
+	DockerMaxIdleConns      uint64        `json:"docker_max_idle_conns"`
+	DockerRuntime           string        `json:"docker_runtime"`
+	MaxHotConcurrency       uint64        `json:"max_hot_concurrency"`
+	MaxFnContainers         uint64        `json:"max_fn_containers"`
+	MaxAppCalls             uint64        `json:"max_app_calls"`
+	MaxInFlightCalls        uint64        `json:"max_in_flight_calls"`
+	MaxAppMemory            uint64        `json:"max_app_memory_bytes"`
+	MaxAppCPU               uint64        `json:"max_app_cpu_mcpus"`
+	MemoryOvercommit        uint64        `json:"memory_overcommit_pct"`
+	MemoryPressureFree      uint64        `json:"memory_pressure_free_pct"`
+	MemoryPressurePoll      time.Duration `json:"memory_pressure_poll_msecs"`
+	EvictPausedFree         uint64        `json:"evict_paused_free_pct"`
+	CPUPinningThreshold     uint64        `json:"cpu_pinning_threshold_mcpus"`
+	CancelFreeSlot          bool          `json:"cancel_free_slot"`
+	DrainFinishAsync        bool          `json:"drain_finish_async"`
+	CancelGracePeriod       time.Duration `json:"cancel_grace_period_msecs"`
+	WebSocketIdleTimeout    time.Duration `json:"websocket_idle_timeout_msecs"`
+	WebSocketMaxDuration    time.Duration `json:"websocket_max_duration_msecs"`
+	SlotWaitTimeout         time.Duration `json:"slot_wait_timeout_msecs"`
+	AuditURL                string        `json:"audit_url"`
+	AuditBufferSize         uint64        `json:"audit_buffer_size"`
+	TrustedProxies          string        `json:"trusted_proxies"`
 }
 
 const (
@@ -56,10 +110,86 @@ const (
 	EnvImageEnableVolume = "FN_IMAGE_ENABLE_VOLUME"
 	// EnvDockerNetworks is a comma separated list of networks to attach to each container started
 	EnvDockerNetworks = "FN_DOCKER_NETWORKS"
+	// EnvDockerRegistryMirrors is a whitespace separated list of upstream=mirror registry pairs, images from
+	// an upstream registry are pulled through its mirror instead
+	EnvDockerRegistryMirrors = "FN_DOCKER_REGISTRY_MIRRORS"
+	// EnvPullRetryAttempts is the maximum number of attempts made to pull an image, 1 disables retries
+	EnvPullRetryAttempts = "FN_DOCKER_PULL_RETRY_ATTEMPTS"
+	// EnvPullRetryBackoff is the delay before the first pull retry, doubled on each subsequent retry
+	EnvPullRetryBackoff = "FN_DOCKER_PULL_RETRY_BACKOFF_MSECS"
+	// EnvPullRetryMaxBackoff is the upper bound of the delay between pull retries
+	EnvPullRetryMaxBackoff = "FN_DOCKER_PULL_RETRY_MAX_BACKOFF_MSECS"
+	// EnvPullRetryJitter is the percentage of the delay between pull retries that is randomized
+	EnvPullRetryJitter = "FN_DOCKER_PULL_RETRY_JITTER_PCT"
+	// EnvPullRetryStatusCodes is a whitespace separated list of docker API status codes for which a pull is retried
+	EnvPullRetryStatusCodes = "FN_DOCKER_PULL_RETRY_STATUS_CODES"
+	// EnvDockerAPIVersion pins the docker remote API version, by default the version is negotiated with the daemon
+	EnvDockerAPIVersion = "FN_DOCKER_API_VERSION"
+	// EnvDockerMaxIdleConns is the number of idle connections to the docker daemon kept open for reuse
+	EnvDockerMaxIdleConns = "FN_DOCKER_MAX_IDLE_CONNS"
+	// EnvDockerRuntime is the OCI runtime to run containers with (eg. runsc), if the daemon provides it
+	EnvDockerRuntime = "FN_DOCKER_RUNTIME"
 	// EnvDockerLoadFile is a file location for a file that contains a tarball of a docker image to load on startup
 	EnvDockerLoadFile = "FN_DOCKER_LOAD_FILE"
 	// EnvFreezeIdle is the delay between a container being last used and being frozen
 	EnvFreezeIdle = "FN_FREEZE_IDLE_MSECS"
+	// EnvFreezeAdaptive skips pausing idle containers of any function called more often than EnvFreezeAdaptiveRate,
+	// functions may opt into it with their scale policy otherwise
+	EnvFreezeAdaptive = "FN_FREEZE_ADAPTIVE"
+	// EnvFreezeAdaptiveRate is the number of calls per minute above which idle containers of a function with adaptive
+	// freeze are not paused
+	EnvFreezeAdaptiveRate = "FN_FREEZE_ADAPTIVE_CALLS_PER_MIN"
+	// EnvMaxHotConcurrency is the maximum number of concurrent calls a function may ask a single hot container to serve
+	EnvMaxHotConcurrency = "FN_MAX_HOT_CONCURRENCY"
+	// EnvMaxFnContainers is the maximum number of hot containers a function may run at once, functions may set a lower maximum
+	EnvMaxFnContainers = "FN_MAX_FN_CONTAINERS"
+	// EnvMaxAppCalls is the maximum number of calls of an app in flight at once, further calls are rejected with 429
+	EnvMaxAppCalls = "FN_MAX_APP_CALLS"
+	// EnvMaxInFlightCalls is the maximum number of calls in flight on the agent at once, further calls are rejected with 503
+	EnvMaxInFlightCalls = "FN_MAX_IN_FLIGHT_CALLS"
+	// EnvMaxAppMemory is the maximum memory that will be reserved across the calls in flight of an app
+	EnvMaxAppMemory = "FN_MAX_APP_MEMORY_BYTES"
+	// EnvMaxAppCPU is the maximum CPU that will be reserved across the calls in flight of an app
+	EnvMaxAppCPU = "FN_MAX_APP_CPU_MCPUS"
+	// EnvMemoryOvercommit is the percentage of the memory available to functions that may be reserved by containers,
+	// eg. 150 reserves up to 1.5 times the memory. Values of 100 or less disable overcommit
+	EnvMemoryOvercommit = "FN_MEMORY_OVERCOMMIT_PCT"
+	// EnvMemoryPressureFree is the percentage of host memory below which free memory is considered under pressure when
+	// overcommit is enabled, idle containers are then evicted until free memory is back above it
+	EnvMemoryPressureFree = "FN_MEMORY_PRESSURE_FREE_PCT"
+	// EnvMemoryPressurePoll is the interval to check free memory of the host for pressure when overcommit is enabled
+	// or paused containers are evicted under pressure
+	EnvMemoryPressurePoll = "FN_MEMORY_PRESSURE_POLL_MSECS"
+	// EnvEvictPausedFree is the percentage of host memory below which free memory makes paused containers be evicted,
+	// the ones paused the longest first, rather than waiting for their idle timeout. 0 disables it
+	EnvEvictPausedFree = "FN_EVICT_PAUSED_FREE_PCT"
+	// EnvCPUPinningThreshold enables cpu pinning, functions asking for at least this many milli cpus are given
+	// dedicated cpus and memory of the NUMA node of those cpus
+	EnvCPUPinningThreshold = "FN_CPU_PINNING_THRESHOLD_MCPUS"
+	// EnvCancelFreeSlot makes calls cancelled by their client only free their slot, leaving the container to finish
+	// processing them. By default the container is sent SIGTERM and killed after EnvCancelGracePeriod
+	EnvCancelFreeSlot = "FN_CANCEL_FREE_SLOT"
+	// EnvDrainFinishAsync makes the agent run the async calls it is reserving from the queue when it is drained, rather
+	// than leave them reserved until the queue hands them out again. Drains then wait up to EnvAsyncChewPoll for them
+	EnvDrainFinishAsync = "FN_DRAIN_FINISH_ASYNC"
+	// EnvCancelGracePeriod is the time a container has to exit after SIGTERM once a call it serves is cancelled
+	EnvCancelGracePeriod = "FN_CANCEL_GRACE_PERIOD_MSECS"
+	// EnvWebSocketIdleTimeout is the time a WebSocket connection to a function may go without frames before it is closed
+	EnvWebSocketIdleTimeout = "FN_WEBSOCKET_IDLE_TIMEOUT_MSECS"
+	// EnvWebSocketMaxDuration is the maximum lifetime of a WebSocket connection to a function, it replaces the
+	// function timeout for WebSocket calls
+	EnvWebSocketMaxDuration = "FN_WEBSOCKET_MAX_DURATION_MSECS"
+	// EnvSlotWaitTimeout is the time a sync call may wait for a hot container to run it before it is rejected with 503,
+	// functions may override it. By default calls wait until their client gives up
+	EnvSlotWaitTimeout = "FN_SLOT_WAIT_TIMEOUT_MSECS"
+	// EnvAuditURL is the url of a sink an audit record of every call is written to, eg. file:///var/log/fn/audit.log,
+	// https://audit.example.com/records or kafka-rest://kafka-rest:8082/fn-audit
+	EnvAuditURL = "FN_AUDIT_URL"
+	// EnvAuditBufferSize is the number of audit records pending a write to the sink, further records are dropped
+	EnvAuditBufferSize = "FN_AUDIT_BUFFER_SIZE"
+	// EnvTrustedProxies is a comma separated list of the IP addresses and CIDR networks of the proxies in front of fn,
+	// the X-Forwarded-For and Fn-Caller-Identity headers of their requests are recorded in audit records
+	EnvTrustedProxies = "FN_TRUSTED_PROXIES"
 	// EnvHotPoll is the interval to ping for a slot manager thread to check if a container should be
 	// launched for a given function
 	EnvHotPoll = "FN_HOT_POLL_MSECS"
@@ -71,7 +201,10 @@ const (
 	EnvHotStartTimeout = "FN_HOT_START_TIMEOUT_MSECS"
 	// EnvAsyncChewPoll is the interval to poll the queue that contains async function invocations
 	EnvAsyncChewPoll = "FN_ASYNC_CHEW_POLL_MSECS"
-	// EnvMaxResponseSize is the maximum number of bytes that a function may return from an invocation
+	// EnvMaxRequestSize is the maximum number of bytes of the request body of an invocation, apps may lower it.
+	// It is the limit of the API requests of the server too, see server.EnvMaxRequestSize
+	EnvMaxRequestSize = "FN_MAX_REQUEST_SIZE"
+	// EnvMaxResponseSize is the maximum number of bytes that a function may return from an invocation, apps may lower it
 	EnvMaxResponseSize = "FN_MAX_RESPONSE_SIZE"
 	// EnvMaxLogSize is the maximum size that a function's log may reach
 	EnvMaxLogSize = "FN_MAX_LOG_SIZE_BYTES"
@@ -91,6 +224,25 @@ const (
 	EnvPreForkUseOnce = "FN_EXPERIMENTAL_PREFORK_USE_ONCE"
 	// EnvPreForkNetworks is the equivalent of EnvDockerNetworks but for pre-fork pool containers
 	EnvPreForkNetworks = "FN_EXPERIMENTAL_PREFORK_NETWORKS"
+	// EnvPreForkPoolMaxSize is the size up to which the pre-fork pool grows under load, the pool is fixed size if unset
+	EnvPreForkPoolMaxSize = "FN_EXPERIMENTAL_PREFORK_POOL_MAX_SIZE"
+	// EnvPreForkHighWatermark is the pre-fork pool utilization percentage at which the pool grows
+	EnvPreForkHighWatermark = "FN_EXPERIMENTAL_PREFORK_POOL_HIGH_WATERMARK_PCT"
+	// EnvPreForkLowWatermark is the pre-fork pool utilization percentage at which the pool shrinks
+	EnvPreForkLowWatermark = "FN_EXPERIMENTAL_PREFORK_POOL_LOW_WATERMARK_PCT"
+	// EnvPreForkScaleInterval is how often the pre-fork pool utilization is checked for resizing
+	EnvPreForkScaleInterval = "FN_EXPERIMENTAL_PREFORK_POOL_SCALE_INTERVAL_MSECS"
+	// EnvLazyPull enables lazy image pulling, the docker daemon must use the containerd image store
+	// with a lazy pulling snapshotter such as stargz or soci, otherwise this setting is ignored
+	EnvLazyPull = "FN_EXPERIMENTAL_LAZY_PULL"
+	// EnvLazyPullSnapshotter is the name of the docker daemon snapshotter which pulls images lazily
+	EnvLazyPullSnapshotter = "FN_EXPERIMENTAL_LAZY_PULL_SNAPSHOTTER"
+	// EnvLazyPullStartTimeout replaces EnvHotStartTimeout when lazy pulling is enabled, since image
+	// content is fetched while the container starts
+	EnvLazyPullStartTimeout = "FN_EXPERIMENTAL_LAZY_PULL_START_TIMEOUT_MSECS"
+	// EnvEnableSnapshots commits the first initialized container of a function to an image and creates
+	// subsequent containers from that image, this requires a writable root fs to have any effect
+	EnvEnableSnapshots = "FN_EXPERIMENTAL_CONTAINER_SNAPSHOTS"
 	// EnvEnableNBResourceTracker makes every request to the resource tracker non-blocking, meaning the resources are either
 	// available or it will return an error immediately
 	EnvEnableNBResourceTracker = "FN_ENABLE_NB_RESOURCE_TRACKER"
@@ -98,8 +250,36 @@ const (
 	EnvMaxTmpFsInodes = "FN_MAX_TMPFS_INODES"
 	// EnvDisableReadOnlyRootFs makes the root fs for a container have rw permissions, by default it is read only
 	EnvDisableReadOnlyRootFs = "FN_DISABLE_READONLY_ROOTFS"
+	// EnvDisableNoNewPrivs allows container processes to gain privileges, by default containers run with no-new-privileges
+	EnvDisableNoNewPrivs = "FN_DISABLE_NO_NEW_PRIVILEGES"
+	// EnvDockerAllowedSysctls is a whitespace separated list of kernel parameters functions may set, a trailing '*' allows a prefix
+	EnvDockerAllowedSysctls = "FN_DOCKER_ALLOWED_SYSCTLS"
+	// EnvCgroupVersion overrides detection of the cgroup version (1 or 2) of the docker host
+	EnvCgroupVersion = "FN_CGROUP_VERSION"
 	// EnvDisableDebugUserLogs disables user function logs being logged at level debug. wise to enable for production.
 	EnvDisableDebugUserLogs = "FN_DISABLE_DEBUG_USER_LOGS"
+	// EnvStructuredLogs captures the logs of calls as a json models.LogEntry per line, with the time and stream
+	// of each line, rather than as the raw output of their containers
+	EnvStructuredLogs = "FN_STRUCTURED_LOGS"
+	// EnvLogRedactPatterns is a whitespace separated list of patterns scrubbed from the logs of all calls, names of
+	// models.LogRedactionPatterns or regular expressions, in addition to those of models.LogRedactionAnnotation
+	EnvLogRedactPatterns = "FN_LOG_REDACT_PATTERNS"
+	// EnvSyslogTLSCACert is the path on the docker host of the CA certificate tcp+tls syslog URLs are verified against
+	EnvSyslogTLSCACert = "FN_SYSLOG_TLS_CA_CERT"
+	// EnvSyslogTLSCert is the path on the docker host of the client certificate sent to tcp+tls syslog URLs
+	EnvSyslogTLSCert = "FN_SYSLOG_TLS_CERT"
+	// EnvSyslogTLSKey is the path on the docker host of the key of the client certificate, see EnvSyslogTLSCert
+	EnvSyslogTLSKey = "FN_SYSLOG_TLS_KEY"
+	// EnvSyslogTLSSkipVerify disables the verification of the certificates of tcp+tls syslog URLs
+	EnvSyslogTLSSkipVerify = "FN_SYSLOG_TLS_SKIP_VERIFY"
+	// EnvSyslogBufferSize is the number of bytes of log lines the docker daemon buffers for each container while its
+	// syslog URL is slow or reconnecting, rather than blocking the container. Lines are not buffered if 0, the default
+	EnvSyslogBufferSize = "FN_SYSLOG_BUFFER_SIZE"
+	// EnvSyslogRetryAttempts is the maximum number of attempts made to start a container while its syslog URL is
+	// unreachable, 1 disables retries
+	EnvSyslogRetryAttempts = "FN_SYSLOG_RETRY_ATTEMPTS"
+	// EnvSyslogRetryBackoff is the delay before the first retry to start a container, doubled on each subsequent retry
+	EnvSyslogRetryBackoff = "FN_SYSLOG_RETRY_BACKOFF_MSECS"
 
 	// EnvIOFSEnableTmpfs enables creating a per-container tmpfs mount for the IOFS
 	EnvIOFSEnableTmpfs = "FN_IOFS_TMPFS"
@@ -134,21 +314,32 @@ const (
 func NewConfig() (*Config, error) {
 
 	cfg := &Config{
-		MinDockerVersion: "17.10.0-ce",
-		MaxLogSize:       1 * 1024 * 1024,
-		PreForkImage:     "busybox",
-		PreForkCmd:       "tail -f /dev/null",
+		MinDockerVersion:     "17.10.0-ce",
+		MaxLogSize:           1 * 1024 * 1024,
+		PreForkImage:         "busybox",
+		PreForkCmd:           "tail -f /dev/null",
+		PreForkHighWatermark: 80,
+		PreForkLowWatermark:  20,
+		PullRetryAttempts:    1,
+		SyslogRetryAttempts:  1,
+		PullRetryJitter:      20,
+		MaxHotConcurrency:    100,
+		MemoryPressureFree:   10,
+		FreezeAdaptiveRate:   60,
 	}
 
 	var err error
 
 	err = setEnvMsecs(err, EnvFreezeIdle, &cfg.FreezeIdle, 50*time.Millisecond)
+	err = setEnvBool(err, EnvFreezeAdaptive, &cfg.FreezeAdaptive)
+	err = setEnvUint(err, EnvFreezeAdaptiveRate, &cfg.FreezeAdaptiveRate)
 	err = setEnvMsecs(err, EnvHotPoll, &cfg.HotPoll, DefaultHotPoll)
 	err = setEnvMsecs(err, EnvHotLauncherTimeout, &cfg.HotLauncherTimeout, time.Duration(60)*time.Minute)
 	err = setEnvMsecs(err, EnvHotPullTimeout, &cfg.HotPullTimeout, time.Duration(10)*time.Minute)
 	err = setEnvMsecs(err, EnvHotStartTimeout, &cfg.HotStartTimeout, time.Duration(5)*time.Second)
 	err = setEnvMsecs(err, EnvAsyncChewPoll, &cfg.AsyncChewPoll, time.Duration(60)*time.Second)
 	err = setEnvMsecs(err, EnvDetachedHeadroom, &cfg.DetachedHeadRoom, time.Duration(360)*time.Second)
+	err = setEnvUint(err, EnvMaxRequestSize, &cfg.MaxRequestSize)
 	err = setEnvUint(err, EnvMaxResponseSize, &cfg.MaxResponseSize)
 	err = setEnvUint(err, EnvMaxLogSize, &cfg.MaxLogSize)
 	err = setEnvUint(err, EnvMaxTotalCPU, &cfg.MaxTotalCPU)
@@ -159,6 +350,10 @@ func NewConfig() (*Config, error) {
 	err = setEnvStr(err, EnvPreForkCmd, &cfg.PreForkCmd)
 	err = setEnvUint(err, EnvPreForkUseOnce, &cfg.PreForkUseOnce)
 	err = setEnvStr(err, EnvPreForkNetworks, &cfg.PreForkNetworks)
+	err = setEnvUint(err, EnvPreForkPoolMaxSize, &cfg.PreForkPoolMaxSize)
+	err = setEnvUint(err, EnvPreForkHighWatermark, &cfg.PreForkHighWatermark)
+	err = setEnvUint(err, EnvPreForkLowWatermark, &cfg.PreForkLowWatermark)
+	err = setEnvMsecs(err, EnvPreForkScaleInterval, &cfg.PreForkScaleInterval, time.Duration(1)*time.Second)
 	err = setEnvStr(err, EnvContainerLabelTag, &cfg.ContainerLabelTag)
 	err = setEnvStr(err, EnvDockerNetworks, &cfg.DockerNetworks)
 	err = setEnvStr(err, EnvDockerLoadFile, &cfg.DockerLoadFile)
@@ -169,14 +364,74 @@ func NewConfig() (*Config, error) {
 	err = setEnvBool(err, EnvIOFSEnableTmpfs, &cfg.IOFSEnableTmpfs)
 	err = setEnvBool(err, EnvEnableNBResourceTracker, &cfg.EnableNBResourceTracker)
 	err = setEnvBool(err, EnvDisableReadOnlyRootFs, &cfg.DisableReadOnlyRootFs)
+	err = setEnvBool(err, EnvDisableNoNewPrivs, &cfg.DisableNoNewPrivs)
+	err = setEnvStr(err, EnvDockerAllowedSysctls, &cfg.DockerAllowedSysctls)
+	err = setEnvUint(err, EnvCgroupVersion, &cfg.CgroupVersion)
 	err = setEnvBool(err, EnvDisableDebugUserLogs, &cfg.DisableDebugUserLogs)
+	err = setEnvBool(err, EnvStructuredLogs, &cfg.StructuredLogs)
+	err = setEnvStr(err, EnvLogRedactPatterns, &cfg.LogRedactPatterns)
+	err = setEnvStr(err, EnvSyslogTLSCACert, &cfg.SyslogTLSCACert)
+	err = setEnvStr(err, EnvSyslogTLSCert, &cfg.SyslogTLSCert)
+	err = setEnvStr(err, EnvSyslogTLSKey, &cfg.SyslogTLSKey)
+	err = setEnvBool(err, EnvSyslogTLSSkipVerify, &cfg.SyslogTLSSkipVerify)
+	err = setEnvUint(err, EnvSyslogBufferSize, &cfg.SyslogBufferSize)
+	err = setEnvUint(err, EnvSyslogRetryAttempts, &cfg.SyslogRetryAttempts)
+	err = setEnvMsecs(err, EnvSyslogRetryBackoff, &cfg.SyslogRetryBackoff, 500*time.Millisecond)
 	err = setEnvUint(err, EnvImageCleanMaxSize, &cfg.ImageCleanMaxSize)
 	err = setEnvStr(err, EnvImageCleanExemptTags, &cfg.ImageCleanExemptTags)
 	err = setEnvBool(err, EnvImageEnableVolume, &cfg.ImageEnableVolume)
+	err = setEnvStr(err, EnvDockerRegistryMirrors, &cfg.DockerRegistryMirrors)
+	err = setEnvUint(err, EnvPullRetryAttempts, &cfg.PullRetryAttempts)
+	err = setEnvMsecs(err, EnvPullRetryBackoff, &cfg.PullRetryBackoff, 500*time.Millisecond)
+	err = setEnvMsecs(err, EnvPullRetryMaxBackoff, &cfg.PullRetryMaxBackoff, 10*time.Second)
+	err = setEnvUint(err, EnvPullRetryJitter, &cfg.PullRetryJitter)
+	err = setEnvStr(err, EnvPullRetryStatusCodes, &cfg.PullRetryStatusCodes)
+	err = setEnvBool(err, EnvLazyPull, &cfg.LazyPull)
+	err = setEnvStr(err, EnvLazyPullSnapshotter, &cfg.LazyPullSnapshotter)
+	err = setEnvMsecs(err, EnvLazyPullStartTimeout, &cfg.LazyPullStartTimeout, time.Duration(60)*time.Second)
+	err = setEnvBool(err, EnvEnableSnapshots, &cfg.EnableSnapshots)
+	err = setEnvStr(err, EnvDockerAPIVersion, &cfg.DockerAPIVersion)
+	err = setEnvUint(err, EnvDockerMaxIdleConns, &cfg.DockerMaxIdleConns)
+	err = setEnvStr(err, EnvDockerRuntime, &cfg.DockerRuntime)
+	err = setEnvUint(err, EnvMaxHotConcurrency, &cfg.MaxHotConcurrency)
+	err = setEnvUint(err, EnvMaxFnContainers, &cfg.MaxFnContainers)
+	err = setEnvUint(err, EnvMaxAppCalls, &cfg.MaxAppCalls)
+	err = setEnvUint(err, EnvMaxInFlightCalls, &cfg.MaxInFlightCalls)
+	err = setEnvUint(err, EnvMaxAppMemory, &cfg.MaxAppMemory)
+	err = setEnvUint(err, EnvMaxAppCPU, &cfg.MaxAppCPU)
+	err = setEnvUint(err, EnvMemoryOvercommit, &cfg.MemoryOvercommit)
+	err = setEnvUint(err, EnvMemoryPressureFree, &cfg.MemoryPressureFree)
+	err = setEnvMsecs(err, EnvMemoryPressurePoll, &cfg.MemoryPressurePoll, time.Duration(1)*time.Second)
+	err = setEnvUint(err, EnvEvictPausedFree, &cfg.EvictPausedFree)
+	err = setEnvUint(err, EnvCPUPinningThreshold, &cfg.CPUPinningThreshold)
+	err = setEnvBool(err, EnvCancelFreeSlot, &cfg.CancelFreeSlot)
+	err = setEnvBool(err, EnvDrainFinishAsync, &cfg.DrainFinishAsync)
+	err = setEnvMsecs(err, EnvCancelGracePeriod, &cfg.CancelGracePeriod, time.Duration(1)*time.Second)
+	err = setEnvMsecs(err, EnvWebSocketIdleTimeout, &cfg.WebSocketIdleTimeout, time.Duration(60)*time.Second)
+	err = setEnvMsecs(err, EnvWebSocketMaxDuration, &cfg.WebSocketMaxDuration, time.Duration(60)*time.Minute)
+	err = setEnvMsecs(err, EnvSlotWaitTimeout, &cfg.SlotWaitTimeout, 0)
+	err = setEnvStr(err, EnvAuditURL, &cfg.AuditURL)
+	err = setEnvUint(err, EnvAuditBufferSize, &cfg.AuditBufferSize)
+	err = setEnvStr(err, EnvTrustedProxies, &cfg.TrustedProxies)
 	if err != nil {
 		return cfg, err
 	}
 
+	if cfg.MemoryPressureFree > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvMemoryPressureFree, cfg.MemoryPressureFree)
+	}
+	if cfg.EvictPausedFree > 100 {
+		return cfg, fmt.Errorf("error invalid %s %v > 100", EnvEvictPausedFree, cfg.EvictPausedFree)
+	}
+
+	if _, err := models.NewLogRedactor(nil, strings.Fields(cfg.LogRedactPatterns)); err != nil {
+		return cfg, fmt.Errorf("error invalid %s, %v", EnvLogRedactPatterns, err)
+	}
+
+	if (cfg.SyslogTLSCert == "") != (cfg.SyslogTLSKey == "") {
+		return cfg, fmt.Errorf("error %s and %s must be set together", EnvSyslogTLSCert, EnvSyslogTLSKey)
+	}
+
 	if cfg.MaxLogSize > math.MaxInt64 {
 		// for safety during uint64 to int conversions in Write()/Read(), etc.
 		return cfg, fmt.Errorf("error invalid %s %v > %v", EnvMaxLogSize, cfg.MaxLogSize, math.MaxInt64)
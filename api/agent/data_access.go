@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"io"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
@@ -18,6 +20,8 @@ type ReadDataAccess interface {
 	GetAppByID(ctx context.Context, appID string) (*models.App, error)
 	GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*models.Trigger, error)
 	GetFnByID(ctx context.Context, fnId string) (*models.Fn, error)
+	// GetDomainsByHostname abstracts querying the datastore for the domains of a hostname.
+	GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error)
 }
 
 //DequeueDataAccess abstracts an underlying dequeue for async runners
@@ -46,6 +50,20 @@ type CallHandler interface {
 	Finish(ctx context.Context, mCall *models.Call, stderr io.Reader, async bool) error
 }
 
+// ResultHandler is implemented by a CallHandler which can store the response of detached
+// calls.
+type ResultHandler interface {
+	// StoreResult stores the response payload of a detached call before it is finished.
+	StoreResult(ctx context.Context, mCall *models.Call, result io.Reader) error
+}
+
+// DeadLetterHandler is implemented by a CallHandler which can keep async calls that failed
+// their last attempt for later inspection and replay.
+type DeadLetterHandler interface {
+	// DeadLetter stores a call which exhausted the retry policy of its fn.
+	DeadLetter(ctx context.Context, mCall *models.Call) error
+}
+
 // DataAccess is currently
 type DataAccess interface {
 	ReadDataAccess
@@ -53,12 +71,22 @@ type DataAccess interface {
 	CallHandler
 }
 
-// CachedDataAccess wraps a DataAccess and caches the results of GetApp.
+// watchedCacheTTL is how long results are cached for when the datastore notifies its
+// changes, which evict them before
+const watchedCacheTTL = time.Minute
+
+// CachedDataAccess wraps a DataAccess and caches the results of GetApp. If the DataAccess is
+// a models.ChangeWatcher, fns and triggers are cached too and the results are evicted as
+// they change.
 type cachedDataAccess struct {
+	// generation counts the changes, results read before a change are not cached after it
+	generation uint64
+
 	ReadDataAccess
 
 	cache        *cache.Cache
 	singleflight singleflight.SingleFlight
+	watched      bool
 }
 
 func NewCachedDataAccess(da ReadDataAccess) ReadDataAccess {
@@ -66,9 +94,72 @@ func NewCachedDataAccess(da ReadDataAccess) ReadDataAccess {
 		ReadDataAccess: da,
 		cache:          cache.New(5*time.Second, 1*time.Minute),
 	}
+	if w, ok := da.(models.ChangeWatcher); ok {
+		ctx := context.Background()
+		changes, err := w.WatchChanges(ctx)
+		if err != nil {
+			common.Logger(ctx).WithError(err).Error("failed to watch datastore changes, caching for a short time instead")
+			return cda
+		}
+		cda.cache = cache.New(watchedCacheTTL, 1*time.Minute)
+		cda.watched = true
+		go cda.evict(changes)
+	}
 	return cda
 }
 
+// evict removes the cached results of the changes
+func (da *cachedDataAccess) evict(changes <-chan *models.Change) {
+	for c := range changes {
+		atomic.AddUint64(&da.generation, 1)
+		switch c.Kind {
+		case models.ChangeApp:
+			da.cache.Delete(appIDCacheKey(c.ID))
+			// the triggers of the fns of deleted apps are not called
+			if c.Deleted {
+				da.evictPrefix(triggerCacheKeyPrefix)
+			}
+		case models.ChangeFn:
+			da.cache.Delete(fnCacheKey(c.ID))
+			if c.Deleted {
+				da.evictPrefix(triggerCacheKeyPrefix)
+			}
+		case models.ChangeTrigger:
+			// triggers are cached by source
+			da.evictPrefix(triggerCacheKeyPrefix)
+		case models.ChangeDomain:
+			da.evictPrefix(domainsCacheKeyPrefix)
+		default:
+			da.cache.Flush()
+		}
+	}
+}
+
+func (da *cachedDataAccess) evictPrefix(prefix string) {
+	for key := range da.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			da.cache.Delete(key)
+		}
+	}
+}
+
+// load reads the result of key with f, caching it unless it changed meanwhile
+func (da *cachedDataAccess) load(key string, f func() (interface{}, error)) (interface{}, error) {
+	if v, ok := da.cache.Get(key); ok {
+		return v, nil
+	}
+
+	generation := atomic.LoadUint64(&da.generation)
+	v, err := da.singleflight.Do(key, f)
+	if err != nil {
+		return nil, err
+	}
+	if atomic.LoadUint64(&da.generation) == generation {
+		da.cache.Set(key, v, cache.DefaultExpiration)
+	}
+	return v, nil
+}
+
 func appIDCacheKey(appID string) string {
 	return "a:" + appID
 }
@@ -78,28 +169,86 @@ func (da *cachedDataAccess) GetAppID(ctx context.Context, appName string) (strin
 }
 
 func (da *cachedDataAccess) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
-	key := appIDCacheKey(appID)
-	app, ok := da.cache.Get(key)
-	if ok {
-		return app.(*models.App), nil
+	app, err := da.load(appIDCacheKey(appID), func() (interface{}, error) {
+		return da.ReadDataAccess.GetAppByID(ctx, appID)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return app.(*models.App), nil
+}
 
-	resp, err := da.singleflight.Do(key,
-		func() (interface{}, error) {
-			return da.ReadDataAccess.GetAppByID(ctx, appID)
-		})
+func fnCacheKey(fnID string) string {
+	return "f:" + fnID
+}
 
+// GetFnByID caches fns only if their changes evict them
+func (da *cachedDataAccess) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	if !da.watched {
+		return da.ReadDataAccess.GetFnByID(ctx, fnID)
+	}
+	fn, err := da.load(fnCacheKey(fnID), func() (interface{}, error) {
+		return da.ReadDataAccess.GetFnByID(ctx, fnID)
+	})
 	if err != nil {
 		return nil, err
 	}
-	app = resp.(*models.App)
-	da.cache.Set(key, app, cache.DefaultExpiration)
-	return app.(*models.App), nil
+	return fn.(*models.Fn), nil
+}
+
+const triggerCacheKeyPrefix = "t:"
+
+func triggerCacheKey(appID, triggerType, source string) string {
+	return triggerCacheKeyPrefix + appID + "/" + triggerType + "/" + source
+}
+
+// GetTriggerBySource caches triggers only if their changes evict them
+func (da *cachedDataAccess) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	if !da.watched {
+		return da.ReadDataAccess.GetTriggerBySource(ctx, appID, triggerType, source)
+	}
+	trigger, err := da.load(triggerCacheKey(appID, triggerType, source), func() (interface{}, error) {
+		return da.ReadDataAccess.GetTriggerBySource(ctx, appID, triggerType, source)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trigger.(*models.Trigger), nil
+}
+
+const domainsCacheKeyPrefix = "d:"
+
+func domainsCacheKey(hostname string) string {
+	return domainsCacheKeyPrefix + hostname
+}
+
+// GetDomainsByHostname caches the domains of hostnames, including hostnames without domains
+// since most requests are to the hostname of the server itself.
+func (da *cachedDataAccess) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	domains, err := da.load(domainsCacheKey(hostname), func() (interface{}, error) {
+		return da.ReadDataAccess.GetDomainsByHostname(ctx, hostname)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return domains.([]*models.Domain), nil
 }
 
 type directDataAccess struct {
-	mq models.MessageQueue
-	ls models.LogStore
+	mq  models.MessageQueue
+	ls  models.LogStore
+	dlq models.MessageQueue
+}
+
+// DirectDataAccessOption configures the CallHandler returned by NewDirectCallDataAccess
+type DirectDataAccessOption func(*directDataAccess)
+
+// WithDeadLetterQueue pushes dead lettered calls to the given queue instead of storing
+// them in the log store.
+func WithDeadLetterQueue(dlq models.MessageQueue) DirectDataAccessOption {
+	return func(da *directDataAccess) {
+		da.dlq = dlq
+	}
 }
 
 type directDequeue struct {
@@ -132,11 +281,14 @@ func (da *directEnequeue) Enqueue(ctx context.Context, mCall *models.Call) error
 	// TODO: Insert a call in the datastore with the 'queued' state
 }
 
-func NewDirectCallDataAccess(ls models.LogStore, mq models.MessageQueue) CallHandler {
+func NewDirectCallDataAccess(ls models.LogStore, mq models.MessageQueue, opts ...DirectDataAccessOption) CallHandler {
 	da := &directDataAccess{
 		mq: mq,
 		ls: ls,
 	}
+	for _, opt := range opts {
+		opt(da)
+	}
 	return da
 }
 
@@ -166,9 +318,11 @@ func (da *directDataAccess) Finish(ctx context.Context, mCall *models.Call, stde
 		// note: Not returning err here since the job could have already finished successfully.
 	}
 
-	if err := da.ls.InsertLog(ctx, mCall, stderr); err != nil {
-		common.Logger(ctx).WithError(err).Error("error uploading log")
-		// note: Not returning err here since the job could have already finished successfully.
+	if mCall.LogUnavailable == "" {
+		if err := da.ls.InsertLog(ctx, mCall, stderr); err != nil {
+			common.Logger(ctx).WithError(err).Error("error uploading log")
+			// note: Not returning err here since the job could have already finished successfully.
+		}
 	}
 
 	if async {
@@ -179,6 +333,20 @@ func (da *directDataAccess) Finish(ctx context.Context, mCall *models.Call, stde
 	return nil
 }
 
+func (da *directDataAccess) StoreResult(ctx context.Context, mCall *models.Call, result io.Reader) error {
+	return da.ls.InsertResult(ctx, mCall, result)
+}
+
+func (da *directDataAccess) DeadLetter(ctx context.Context, mCall *models.Call) error {
+	if da.dlq != nil {
+		dead := *mCall
+		dead.Delay = 0
+		_, err := da.dlq.Push(ctx, &dead)
+		return err
+	}
+	return da.ls.InsertDeadLetter(ctx, mCall)
+}
+
 type noAsyncEnqueueAccess struct{}
 
 func (noAsyncEnqueueAccess) Enqueue(ctx context.Context, mCall *models.Call) error {
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCachedDataAccessEvictsChanges(t *testing.T) {
+	ctx := context.Background()
+	ds := kv.New(kv.NewMemory())
+	da := NewCachedDataAccess(ds)
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := ds.InsertFn(ctx, &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/hello",
+		ResourceConfig: models.ResourceConfig{Memory: 128, Timeout: 30, IdleTimeout: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := ds.InsertTrigger(ctx, &models.Trigger{AppID: app.ID, FnID: fn.ID, Name: "mytrigger", Type: "http", Source: "/hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := da.GetFnByID(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := da.GetTriggerBySource(ctx, app.ID, "http", "/hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes are seen before the cached results expire
+	eventually := func(what string, f func() bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the cache to see the %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if _, err := ds.UpdateFn(ctx, &models.Fn{ID: fn.ID, Image: "fnproject/hello:2"}); err != nil {
+		t.Fatal(err)
+	}
+	eventually("updated fn", func() bool {
+		fn, err := da.GetFnByID(ctx, fn.ID)
+		return err == nil && fn.Image == "fnproject/hello:2"
+	})
+
+	if err := ds.RemoveTrigger(ctx, trigger.ID); err != nil {
+		t.Fatal(err)
+	}
+	eventually("removed trigger", func() bool {
+		_, err := da.GetTriggerBySource(ctx, app.ID, "http", "/hello")
+		return err == models.ErrTriggerNotFound
+	})
+}
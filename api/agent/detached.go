@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// resultWriter collects the response of a detached call, which is stored as the result of
+// the call once it ends.
+type resultWriter struct {
+	headers http.Header
+	status  int
+	body    bytes.Buffer
+}
+
+var _ http.ResponseWriter = new(resultWriter)
+
+func (w *resultWriter) Header() http.Header         { return w.headers }
+func (w *resultWriter) WriteHeader(code int)        { w.status = code }
+func (w *resultWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// submitDetached reads the request of a detached call and runs the call in the background,
+// release is called once the call ends. The caller is acknowledged as soon as the call is
+// handed off, the call can then be looked up with DetachedCall until it is stored.
+func (a *agent) submitDetached(ctx context.Context, call *call, release func()) error {
+	// the request is gone once the caller is acknowledged, keep its body
+	if call.req.Body != nil {
+		body, err := ioutil.ReadAll(call.req.Body)
+		call.req.Body.Close()
+		if err != nil {
+			release()
+			return err
+		}
+		call.req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	call.result = &resultWriter{headers: make(http.Header), status: http.StatusOK}
+	call.respWriter = call.result
+
+	a.callsLock.Lock()
+	a.detached[call.ID] = &models.Call{
+		ID:        call.ID,
+		AppID:     call.AppID,
+		FnID:      call.FnID,
+		CreatedAt: call.CreatedAt,
+		Status:    "running",
+	}
+	a.callsLock.Unlock()
+
+	ctx = common.BackgroundContext(ctx)
+	go func() {
+		defer release()
+		defer func() {
+			a.callsLock.Lock()
+			delete(a.detached, call.ID)
+			a.callsLock.Unlock()
+		}()
+
+		if err := a.submit(ctx, call); err != nil {
+			common.Logger(ctx).WithError(err).Debug("detached call failed")
+		}
+	}()
+	return nil
+}
+
+// DetachedCall implements CallStatusProvider
+func (a *agent) DetachedCall(fnID, callID string) (*models.Call, bool) {
+	a.callsLock.Lock()
+	defer a.callsLock.Unlock()
+
+	c, ok := a.detached[callID]
+	if !ok || c.FnID != fnID {
+		return nil, false
+	}
+	cp := *c
+	return &cp, true
+}
@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	CgroupV1 = 1
+	CgroupV2 = 2
+
+	// cgroupRoot is where the cgroup hierarchy is mounted on the host
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// cpuPeriod is the CFS period used to translate milli cpus into a quota, in usecs
+	cpuPeriod = 100000
+)
+
+// detectCgroupVersion returns CgroupV2 if the cgroup hierarchy mounted at root is
+// the unified hierarchy, CgroupV1 otherwise. The docker daemon is assumed to run
+// on the same host as the agent.
+func detectCgroupVersion(root string) int {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return CgroupV2
+	}
+	return CgroupV1
+}
+
+// cgroupResources are the container resource settings for a cgroup version
+type cgroupResources struct {
+	Memory       int64
+	MemorySwap   int64
+	KernelMemory int64
+	CPUQuota     int64
+	CPUPeriod    int64
+}
+
+// translateResources maps a memory limit in bytes and a cpu limit in milli cpus onto
+// docker resource settings. With cgroup v1 swap is disabled by setting memory+swap to
+// the memory limit, and kernel memory is limited separately. With cgroup v2 memory and
+// swap map onto memory.max and memory.swap.max, which docker sets from the same values,
+// while kernel memory is accounted in memory.max and cannot be limited separately.
+// In both versions the cpu quota and period map onto cpu.cfs_quota_us/cpu.cfs_period_us
+// or cpu.max respectively.
+func translateResources(version int, memory, cpus uint64) cgroupResources {
+	var res cgroupResources
+
+	if memory != 0 {
+		res.Memory = int64(memory)
+		res.MemorySwap = int64(memory) // disables swap
+		if version != CgroupV2 {
+			res.KernelMemory = int64(memory)
+		}
+	}
+
+	// eg: cpus of 8000 means CPUQuota of 8 * 100000 usecs in 100000 usec period,
+	// which is approx 8 CPUS in CFS world.
+	if cpus != 0 {
+		res.CPUQuota = int64(cpus * cpuPeriod / 1000)
+		res.CPUPeriod = cpuPeriod
+	}
+	return res
+}
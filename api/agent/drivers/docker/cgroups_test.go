@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestDetectCgroupVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if v := detectCgroupVersion(dir); v != CgroupV1 {
+		t.Fatalf("expected cgroup v1 without cgroup.controllers, got %d", v)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu memory pids"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := detectCgroupVersion(dir); v != CgroupV2 {
+		t.Fatalf("expected cgroup v2 with cgroup.controllers, got %d", v)
+	}
+}
+
+func TestTranslateResources(t *testing.T) {
+	const mem = 128 * 1024 * 1024
+
+	v1 := translateResources(CgroupV1, mem, 1500)
+	if v1.Memory != mem || v1.MemorySwap != mem || v1.KernelMemory != mem {
+		t.Fatalf("unexpected cgroup v1 memory settings %+v", v1)
+	}
+	if v1.CPUQuota != 150000 || v1.CPUPeriod != 100000 {
+		t.Fatalf("unexpected cgroup v1 cpu settings %+v", v1)
+	}
+
+	v2 := translateResources(CgroupV2, mem, 1500)
+	if v2.Memory != mem || v2.MemorySwap != mem || v2.KernelMemory != 0 {
+		t.Fatalf("unexpected cgroup v2 memory settings %+v", v2)
+	}
+	if v2.CPUQuota != 150000 || v2.CPUPeriod != 100000 {
+		t.Fatalf("unexpected cgroup v2 cpu settings %+v", v2)
+	}
+
+	none := translateResources(CgroupV2, 0, 0)
+	if none != (cgroupResources{}) {
+		t.Fatalf("expected no limits, got %+v", none)
+	}
+}
+
+func TestCherryPickCgroupV2(t *testing.T) {
+	var ds docker.Stats
+	ds.CPUStats.OnlineCPUs = 2
+	ds.CPUStats.SystemCPUUsage = 2000
+	ds.CPUStats.CPUUsage.TotalUsage = 500
+	ds.PreCPUStats.SystemCPUUsage = 1000
+
+	stat := cherryPick(&ds)
+	if stat.Metrics["cpu_total"] != 100 {
+		t.Fatalf("expected cpu_total from online cpus, got %d", stat.Metrics["cpu_total"])
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
@@ -42,6 +44,15 @@ type cookie struct {
 
 	// contains created container if CreateContainer() is called
 	container *docker.Container
+
+	// snapshot the container was created from if applicable
+	snapshot *snapshot
+
+	// dedicated cpus of the container if applicable
+	pinnedCPUs []int
+
+	// time spent authenticating with the registry if PullImage() is called
+	authDuration time.Duration
 }
 
 func (c *cookie) configureImage(log logrus.FieldLogger) {
@@ -71,15 +82,39 @@ func (c *cookie) configureLogger(log logrus.FieldLogger) {
 		return
 	}
 
+	format := conf.Format
+	if format == "" {
+		format = "rfc5424"
+	}
 	c.opts.HostConfig.LogConfig = docker.LogConfig{
 		Type: "syslog",
 		Config: map[string]string{
 			"syslog-address":  conf.URL,
 			"syslog-facility": "user",
-			"syslog-format":   "rfc5424",
+			"syslog-format":   format,
 		},
 	}
 
+	// See: https://docs.docker.com/config/containers/logging/syslog/#options
+	if tls := conf.TLS; tls != nil && strings.HasPrefix(conf.URL, "tcp+tls://") {
+		if tls.CACert != "" {
+			c.opts.HostConfig.LogConfig.Config["syslog-tls-ca-cert"] = tls.CACert
+		}
+		if tls.Cert != "" {
+			c.opts.HostConfig.LogConfig.Config["syslog-tls-cert"] = tls.Cert
+			c.opts.HostConfig.LogConfig.Config["syslog-tls-key"] = tls.Key
+		}
+		if tls.SkipVerify {
+			c.opts.HostConfig.LogConfig.Config["syslog-tls-skip-verify"] = "true"
+		}
+	}
+
+	// the lines are buffered by the daemon while the driver reconnects to the sink
+	if conf.BufferSize > 0 {
+		c.opts.HostConfig.LogConfig.Config["mode"] = "non-blocking"
+		c.opts.HostConfig.LogConfig.Config["max-buffer-size"] = strconv.FormatUint(conf.BufferSize, 10)
+	}
+
 	tags := make([]string, 0, len(conf.Tags))
 	for _, pair := range conf.Tags {
 		tags = append(tags, fmt.Sprintf("%s=%s", pair.Name, pair.Value))
@@ -94,11 +129,11 @@ func (c *cookie) configureMem(log logrus.FieldLogger) {
 		return
 	}
 
-	mem := int64(c.task.Memory())
+	res := translateResources(c.drv.caps.CgroupVersion, c.task.Memory(), 0)
 
-	c.opts.Config.Memory = mem
-	c.opts.Config.MemorySwap = mem // disables swap
-	c.opts.Config.KernelMemory = mem
+	c.opts.Config.Memory = res.Memory
+	c.opts.Config.MemorySwap = res.MemorySwap
+	c.opts.Config.KernelMemory = res.KernelMemory
 }
 
 func (c *cookie) configureFsSize(log logrus.FieldLogger) {
@@ -106,6 +141,11 @@ func (c *cookie) configureFsSize(log logrus.FieldLogger) {
 		return
 	}
 
+	// the agent warns once at startup if the limit cannot be applied
+	if !c.drv.caps.StorageOpt {
+		return
+	}
+
 	// If defined, impose file system size limit. In MB units.
 	if c.opts.HostConfig.StorageOpt == nil {
 		c.opts.HostConfig.StorageOpt = make(map[string]string)
@@ -116,6 +156,44 @@ func (c *cookie) configureFsSize(log logrus.FieldLogger) {
 	c.opts.HostConfig.StorageOpt["size"] = opt
 }
 
+func (c *cookie) configureRuntime(log logrus.FieldLogger) {
+	runtime := c.drv.conf.Runtime
+	if runtime == "" || !c.drv.caps.Runtimes[runtime] {
+		return
+	}
+
+	log.WithFields(logrus.Fields{"runtime": runtime, "call_id": c.task.Id()}).Debug("setting runtime")
+	c.opts.HostConfig.Runtime = runtime
+}
+
+func (c *cookie) configureSecurity(log logrus.FieldLogger) {
+	if !c.drv.conf.EnableNoNewPrivs {
+		return
+	}
+
+	c.opts.HostConfig.SecurityOpt = append(c.opts.HostConfig.SecurityOpt, "no-new-privileges")
+}
+
+func (c *cookie) configureSysctls(log logrus.FieldLogger) {
+	sysctls := c.task.Sysctls()
+	if len(sysctls) == 0 {
+		return
+	}
+
+	for name, value := range sysctls {
+		if !c.drv.sysctls.isAllowed(name) {
+			log.WithFields(logrus.Fields{"sysctl": name, "call_id": c.task.Id()}).Warn("sysctl is not allowed, ignoring")
+			continue
+		}
+
+		if c.opts.HostConfig.Sysctls == nil {
+			c.opts.HostConfig.Sysctls = make(map[string]string)
+		}
+		log.WithFields(logrus.Fields{"sysctl": name, "value": value, "call_id": c.task.Id()}).Debug("setting sysctl")
+		c.opts.HostConfig.Sysctls[name] = value
+	}
+}
+
 func (c *cookie) configureTmpFs(log logrus.FieldLogger) {
 	// if RO Root is NOT enabled and TmpFsSize does not have any limit, then we do not need
 	// any tmpfs in the container since function can freely write whereever it wants.
@@ -171,20 +249,32 @@ func (c *cookie) configureVolumes(log logrus.FieldLogger) {
 }
 
 func (c *cookie) configureCPU(log logrus.FieldLogger) {
-	// Translate milli cpus into CPUQuota & CPUPeriod (see Linux cGroups CFS cgroup v1 documentation)
-	// eg: task.CPUQuota() of 8000 means CPUQuota of 8 * 100000 usecs in 100000 usec period,
-	// which is approx 8 CPUS in CFS world.
+	// Translate milli cpus into CPUQuota & CPUPeriod, see translateResources.
 	// Also see docker run options --cpu-quota and --cpu-period
 	if c.task.CPUs() == 0 {
 		return
 	}
 
-	quota := int64(c.task.CPUs() * 100)
-	period := int64(100000)
+	res := translateResources(c.drv.caps.CgroupVersion, 0, c.task.CPUs())
+	quota := res.CPUQuota
+	period := res.CPUPeriod
 
 	log.WithFields(logrus.Fields{"quota": quota, "period": period, "call_id": c.task.Id()}).Debug("setting CPU")
 	c.opts.HostConfig.CPUQuota = quota
 	c.opts.HostConfig.CPUPeriod = period
+
+	// Functions asking for enough cpu get cpus of their own, and their memory on the
+	// NUMA node of those cpus. See docker run options --cpuset-cpus and --cpuset-mems
+	cpus, node := c.drv.pinner.pin(c.task.CPUs())
+	if len(cpus) == 0 {
+		return
+	}
+	c.pinnedCPUs = cpus
+	c.opts.HostConfig.CPUSetCPUs = formatCPUList(cpus)
+	if node >= 0 {
+		c.opts.HostConfig.CPUSetMEMs = strconv.Itoa(node)
+	}
+	log.WithFields(logrus.Fields{"cpuset_cpus": c.opts.HostConfig.CPUSetCPUs, "cpuset_mems": c.opts.HostConfig.CPUSetMEMs, "call_id": c.task.Id()}).Debug("setting CPU pinning")
 }
 
 func (c *cookie) configureWorkDir(log logrus.FieldLogger) {
@@ -288,6 +378,9 @@ func (c *cookie) Close(ctx context.Context) error {
 	if c.image != nil && c.drv.imgCache != nil {
 		c.drv.imgCache.MarkFree(c.image)
 	}
+
+	c.drv.pinner.unpin(c.pinnedCPUs)
+	c.pinnedCPUs = nil
 	return err
 }
 
@@ -325,6 +418,19 @@ func (c *cookie) Unfreeze(ctx context.Context) error {
 	return err
 }
 
+// implements drivers.Terminator
+func (c *cookie) Terminate(ctx context.Context) error {
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Terminate"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker kill sigterm")
+
+	err := c.drv.docker.KillContainer(docker.KillContainerOptions{
+		ID: c.task.Id(), Signal: docker.SIGTERM, Context: ctx})
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error terminating container")
+	}
+	return err
+}
+
 func (c *cookie) authImage(ctx context.Context) (*docker.AuthConfiguration, error) {
 	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "AuthImage"})
 	log.WithFields(logrus.Fields{"call_id": c.task.Id()}).Debug("docker auth image")
@@ -335,7 +441,9 @@ func (c *cookie) authImage(ctx context.Context) (*docker.AuthConfiguration, erro
 
 	if task, ok := c.task.(Auther); ok {
 		_, span := trace.StartSpan(ctx, "docker_auth")
+		start := time.Now()
 		authConfig, err := task.DockerAuth(ctx, c.task.Image())
+		c.authDuration += time.Since(start)
 		span.End()
 		if err != nil {
 			return nil, err
@@ -389,6 +497,19 @@ func (c *cookie) ValidateImage(ctx context.Context) (bool, error) {
 	return false, err
 }
 
+// implements drivers.ImageDigester
+func (c *cookie) ImageDigest() string {
+	if c.image == nil {
+		return ""
+	}
+	return c.image.ID
+}
+
+// implements drivers.RegistryAuthTimer
+func (c *cookie) RegistryAuthDuration() time.Duration {
+	return c.authDuration
+}
+
 // implements Cookie
 func (c *cookie) PullImage(ctx context.Context) error {
 	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "PullImage"})
@@ -396,17 +517,38 @@ func (c *cookie) PullImage(ctx context.Context) error {
 		return nil
 	}
 
-	cfg, err := c.authImage(ctx)
-	if err != nil {
-		return err
-	}
+	var cfg *docker.AuthConfiguration
+	var err error
 
 	repo := path.Join(c.imgReg, c.imgRepo)
+	pullRepo := repo
+
+	// If the registry is mirrored, pull from the mirror with the credentials configured
+	// for the mirror. The image is tagged back to its original name after the pull.
+	mirror := findRegistryMirror(c.imgReg, c.drv.mirrors)
+	if mirror != "" {
+		pullRepo = path.Join(mirror, c.imgRepo)
+		cfg = findRegistryConfig(mirror, c.drv.auths)
+	} else {
+		cfg, err = c.authImage(ctx)
+		if err != nil {
+			return err
+		}
+	}
 
-	log = common.Logger(ctx).WithFields(logrus.Fields{"registry": cfg.ServerAddress, "username": cfg.Username})
-	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("docker pull")
+	log = common.Logger(ctx).WithFields(logrus.Fields{"registry": cfg.ServerAddress, "username": cfg.Username, "mirror": mirror})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image(), "lazy": c.drv.lazyPull}).Debug("docker pull")
 
-	err = c.drv.docker.PullImage(docker.PullImageOptions{Repository: repo, Tag: c.imgTag, Context: ctx}, *cfg)
+	err = c.drv.retry.do(ctx, func() error {
+		err := c.drv.docker.PullImage(docker.PullImageOptions{Repository: pullRepo, Tag: c.imgTag, Context: ctx}, *cfg)
+		if err != nil {
+			log.WithError(err).Debug("docker pull attempt failed")
+		}
+		return err
+	})
+	if err == nil && pullRepo != repo {
+		err = c.drv.docker.TagImage(pullRepo+":"+c.imgTag, docker.TagImageOptions{Repo: repo, Tag: c.imgTag, Force: true, Context: ctx})
+	}
 	if err != nil {
 		log.WithError(err).Info("Failed to pull image")
 
@@ -444,6 +586,31 @@ func (c *cookie) CreateContainer(ctx context.Context) error {
 	createOptions := c.opts
 	createOptions.Context = ctx
 
+	// If there's a snapshot of an initialized container for this task, create the
+	// container from the snapshot instead.
+	if c.drv.snapshots != nil {
+		current, stale := c.drv.snapshots.get(c.snapshotKey(), c.image.ID)
+		if stale != nil {
+			go removeSnapshotImage(common.BackgroundContext(ctx), c.drv, stale)
+		}
+		if current != nil {
+			config := *createOptions.Config
+			config.Image = current.image
+			createOptions.Config = &config
+
+			c.container, err = c.drv.docker.CreateContainer(createOptions)
+			if err == nil {
+				c.snapshot = current
+				return nil
+			}
+
+			// snapshot may have been removed by image cleaner, fallback to the task image
+			log.WithError(err).WithField("snapshot", current.image).Info("Cannot CreateContainer from snapshot")
+			c.drv.snapshots.remove(c.snapshotKey(), current)
+			createOptions.Config = c.opts.Config
+		}
+	}
+
 	c.container, err = c.drv.docker.CreateContainer(createOptions)
 
 	// IMPORTANT: The return code 503 here is controversial. Here we treat disk pressure as a temporary
@@ -464,6 +631,54 @@ func (c *cookie) CreateContainer(ctx context.Context) error {
 	return nil
 }
 
+// snapshotKey returns the key of the container snapshot for the task
+func (c *cookie) snapshotKey() string {
+	return snapshotKey(c.task.Image(), c.task.EnvVars())
+}
+
+// implements drivers.Snapshotter
+func (c *cookie) Snapshot(ctx context.Context) error {
+	if c.drv.snapshots == nil || c.container == nil || c.snapshot != nil {
+		return nil
+	}
+
+	key := c.snapshotKey()
+	if !c.drv.snapshots.reserve(key) {
+		return nil
+	}
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "Snapshot"})
+	log.WithFields(logrus.Fields{"call_id": c.task.Id(), "image": c.task.Image()}).Debug("docker commit container")
+
+	var snap *snapshot
+	defer func() {
+		// a snapshot under a new image or config replaces the previous one of the fn
+		old := c.drv.snapshots.release(key, c.task.EnvVars()["FN_FN_ID"], snap)
+		if old != nil {
+			go removeSnapshotImage(common.BackgroundContext(ctx), c.drv, old)
+		}
+	}()
+
+	// docker pauses the container during commit
+	_, err := c.drv.docker.CommitContainer(docker.CommitContainerOptions{
+		Container:  c.task.Id(),
+		Repository: snapshotRepository,
+		Tag:        key,
+		Changes:    []string{fmt.Sprintf("LABEL %s=%s", FnSnapshotBaseLabel, c.image.ID)},
+		Context:    ctx,
+	})
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"call_id": c.task.Id()}).Error("error committing container")
+		return err
+	}
+
+	snap = &snapshot{
+		image:  snapshotRepository + ":" + key,
+		baseID: c.image.ID,
+	}
+	return nil
+}
+
 // removes docker err formatting: 'API Error (code) {"message":"..."}'
 func dockerMsg(derr *docker.Error) string {
 	// derr.Message is a JSON response from docker, which has a "message" field we want to extract if possible.
@@ -481,3 +696,7 @@ func dockerMsg(derr *docker.Error) string {
 }
 
 var _ drivers.Cookie = &cookie{}
+var _ drivers.Terminator = &cookie{}
+var _ drivers.Snapshotter = &cookie{}
+var _ drivers.ImageDigester = &cookie{}
+var _ drivers.RegistryAuthTimer = &cookie{}
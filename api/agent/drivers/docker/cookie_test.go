@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestCookieLogger(t *testing.T) {
+	drv := &DockerDriver{
+		network: NewDockerNetworks(drivers.Config{}),
+	}
+	tlsCfg := &drivers.LoggerTLSConfig{CACert: "/etc/fn/ca.pem", Cert: "/etc/fn/cert.pem", Key: "/etc/fn/key.pem"}
+
+	for i, test := range []struct {
+		cfg      drivers.LoggerConfig
+		expected map[string]string
+	}{
+		{
+			drivers.LoggerConfig{URL: "udp://logs:514"},
+			map[string]string{"syslog-address": "udp://logs:514", "syslog-facility": "user", "syslog-format": "rfc5424"},
+		},
+		{
+			drivers.LoggerConfig{URL: "tcp://logs:514", Format: "rfc3164", TLS: tlsCfg, BufferSize: 1048576},
+			map[string]string{"syslog-address": "tcp://logs:514", "syslog-facility": "user", "syslog-format": "rfc3164",
+				"mode": "non-blocking", "max-buffer-size": "1048576"},
+		},
+		{
+			drivers.LoggerConfig{URL: "tcp+tls://logs:6514", TLS: tlsCfg},
+			map[string]string{"syslog-address": "tcp+tls://logs:6514", "syslog-facility": "user", "syslog-format": "rfc5424",
+				"syslog-tls-ca-cert": "/etc/fn/ca.pem", "syslog-tls-cert": "/etc/fn/cert.pem", "syslog-tls-key": "/etc/fn/key.pem"},
+		},
+		{
+			drivers.LoggerConfig{URL: "tcp+tls://logs:6514", TLS: &drivers.LoggerTLSConfig{SkipVerify: true}},
+			map[string]string{"syslog-address": "tcp+tls://logs:6514", "syslog-facility": "user", "syslog-format": "rfc5424",
+				"syslog-tls-skip-verify": "true"},
+		},
+	} {
+		ctx := context.Background()
+		task := &taskDockerTest{id: "test-docker-logger", logCfg: test.cfg}
+		cookie, err := drv.CreateCookie(ctx, task)
+		if err != nil {
+			t.Fatalf("Test %d: couldn't create task cookie: %v", i, err)
+		}
+
+		opts := cookie.ContainerOptions().(docker.CreateContainerOptions)
+		cookie.Close(ctx)
+		config := opts.HostConfig.LogConfig.Config
+		if opts.HostConfig.LogConfig.Type != "syslog" || len(config) != len(test.expected) {
+			t.Fatalf("Test %d: expected syslog options %v, got %s %v", i, test.expected, opts.HostConfig.LogConfig.Type, config)
+		}
+		for k, v := range test.expected {
+			if config[k] != v {
+				t.Fatalf("Test %d: expected %s=%s, got %q", i, k, v, config[k])
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sysfsNodes is where the kernel lists the NUMA nodes of the host
+const sysfsNodes = "/sys/devices/system/node"
+
+// numaNode is a NUMA node of the host and the cpus of it pinned to containers
+type numaNode struct {
+	id     int // -1 if the NUMA topology is unknown
+	cpus   []int
+	pinned map[int]bool
+}
+
+// cpuPinner assigns dedicated cpus to the containers of functions asking for at least
+// threshold milli cpus. The cpus of a container are taken from a single NUMA node if
+// possible, so that its memory can be kept on the node local to them. Containers that
+// cannot be given dedicated cpus run unpinned.
+type cpuPinner struct {
+	threshold uint64
+
+	lock  sync.Mutex // protects nodes
+	nodes []*numaNode
+}
+
+// newCPUPinner returns a pinner for the NUMA topology of the host, nil if threshold is 0
+func newCPUPinner(threshold uint64) *cpuPinner {
+	if threshold == 0 {
+		return nil
+	}
+
+	nodes, err := loadNUMANodes(sysfsNodes)
+	if err != nil || len(nodes) == 0 {
+		logrus.WithError(err).Warn("cannot read NUMA topology, cpus are pinned without NUMA locality")
+
+		cpus := make([]int, runtime.NumCPU())
+		for i := range cpus {
+			cpus[i] = i
+		}
+		nodes = []*numaNode{{id: -1, cpus: cpus}}
+	}
+
+	for _, node := range nodes {
+		node.pinned = make(map[int]bool)
+		logrus.WithFields(logrus.Fields{"node": node.id, "cpus": formatCPUList(node.cpus)}).Info("cpu pinning enabled")
+	}
+	return &cpuPinner{threshold: threshold, nodes: nodes}
+}
+
+// loadNUMANodes reads the cpus of each NUMA node listed in dir
+func loadNUMANodes(dir string) ([]*numaNode, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*numaNode
+	for _, path := range paths {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(path, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUList(string(b))
+		if err != nil {
+			return nil, err
+		}
+		if len(cpus) > 0 {
+			nodes = append(nodes, &numaNode{id: id, cpus: cpus})
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	return nodes, nil
+}
+
+// pin assigns dedicated cpus for milliCPUs to a container, returning the cpus and the
+// NUMA node (-1 if unknown) they belong to, or nil if the container should run unpinned.
+func (p *cpuPinner) pin(milliCPUs uint64) ([]int, int) {
+	if p == nil || milliCPUs < p.threshold {
+		return nil, -1
+	}
+	count := int((milliCPUs + 999) / 1000)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// the node with the most free cpus that fits the container
+	var best *numaNode
+	bestFree := 0
+	for _, node := range p.nodes {
+		free := len(node.cpus) - len(node.pinned)
+		if free >= count && free > bestFree {
+			best, bestFree = node, free
+		}
+	}
+	if best == nil {
+		return nil, -1
+	}
+
+	cpus := make([]int, 0, count)
+	for _, cpu := range best.cpus {
+		if len(cpus) == count {
+			break
+		}
+		if !best.pinned[cpu] {
+			best.pinned[cpu] = true
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, best.id
+}
+
+// unpin frees cpus assigned by pin
+func (p *cpuPinner) unpin(cpus []int) {
+	if p == nil || len(cpus) == 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, node := range p.nodes {
+		for _, cpu := range cpus {
+			delete(node.pinned, cpu)
+		}
+	}
+}
+
+// parseCPUList parses a kernel cpu list, eg. "0-3,8,10-11"
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q", list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpu list %q", list)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// formatCPUList formats cpus for docker's --cpuset-cpus, eg. "0,1,2"
+func formatCPUList(cpus []int) string {
+	list := make([]string, len(cpus))
+	for i, cpu := range cpus {
+		list[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(list, ",")
+}
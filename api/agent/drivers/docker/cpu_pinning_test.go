@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8,10-11\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cpus, []int{0, 1, 2, 3, 8, 10, 11}) {
+		t.Fatalf("unexpected cpus %v", cpus)
+	}
+	if formatCPUList(cpus) != "0,1,2,3,8,10,11" {
+		t.Fatalf("unexpected cpu list %s", formatCPUList(cpus))
+	}
+
+	for _, list := range []string{"a", "3-1", "1-b"} {
+		if _, err := parseCPUList(list); err == nil {
+			t.Fatalf("cpu list %q should be invalid", list)
+		}
+	}
+}
+
+func TestLoadNUMANodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "numa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for node, list := range map[string]string{"node0": "0-1", "node1": "2-3"} {
+		os.MkdirAll(filepath.Join(dir, node), 0755)
+		if err := ioutil.WriteFile(filepath.Join(dir, node, "cpulist"), []byte(list), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes, err := loadNUMANodes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].id != 0 || nodes[1].id != 1 || !reflect.DeepEqual(nodes[1].cpus, []int{2, 3}) {
+		t.Fatalf("unexpected nodes %+v %+v", nodes[0], nodes[1])
+	}
+}
+
+func newTestCPUPinner() *cpuPinner {
+	return &cpuPinner{
+		threshold: 1000,
+		nodes: []*numaNode{
+			{id: 0, cpus: []int{0, 1, 2}, pinned: make(map[int]bool)},
+			{id: 1, cpus: []int{3, 4, 5}, pinned: make(map[int]bool)},
+		},
+	}
+}
+
+func TestCPUPinner(t *testing.T) {
+	p := newTestCPUPinner()
+
+	if cpus, _ := p.pin(500); cpus != nil {
+		t.Fatalf("containers below the threshold should not be pinned, got %v", cpus)
+	}
+
+	cpus1, node1 := p.pin(1500)
+	if len(cpus1) != 2 || node1 != 0 {
+		t.Fatalf("expected 2 cpus of node 0, got %v of node %d", cpus1, node1)
+	}
+
+	// node 1 has the most free cpus now
+	cpus2, node2 := p.pin(2000)
+	if !reflect.DeepEqual(cpus2, []int{3, 4}) || node2 != 1 {
+		t.Fatalf("expected cpus 3,4 of node 1, got %v of node %d", cpus2, node2)
+	}
+
+	// no single node has enough free cpus
+	if cpus, _ := p.pin(2000); cpus != nil {
+		t.Fatalf("container should run unpinned, got %v", cpus)
+	}
+
+	p.unpin(cpus1)
+	cpus3, node3 := p.pin(3000)
+	if !reflect.DeepEqual(cpus3, []int{0, 1, 2}) || node3 != 0 {
+		t.Fatalf("expected cpus 0,1,2 of node 0, got %v of node %d", cpus3, node3)
+	}
+
+	var disabled *cpuPinner
+	if cpus, _ := disabled.pin(8000); cpus != nil {
+		t.Fatalf("disabled pinner should not pin, got %v", cpus)
+	}
+}
+
+func TestCookieCPUPinning(t *testing.T) {
+	drv := &DockerDriver{
+		network: NewDockerNetworks(drivers.Config{}),
+		pinner:  newTestCPUPinner(),
+	}
+
+	ctx := context.Background()
+	task := &taskDockerTest{
+		id:   "test-docker-cpu-pinning",
+		cpus: 2000,
+	}
+
+	cookie, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatalf("Couldn't create task cookie: %v", err)
+	}
+
+	opts := cookie.ContainerOptions().(docker.CreateContainerOptions)
+	if opts.HostConfig.CPUSetCPUs != "0,1" || opts.HostConfig.CPUSetMEMs != "0" {
+		t.Fatalf("expected cpus 0,1 and memory of node 0, got %q and %q", opts.HostConfig.CPUSetCPUs, opts.HostConfig.CPUSetMEMs)
+	}
+
+	cookie.Close(ctx)
+	if len(drv.pinner.nodes[0].pinned) != 0 {
+		t.Fatalf("closed cookie should unpin its cpus, pinned %v", drv.pinner.nodes[0].pinned)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
@@ -60,12 +61,33 @@ type DockerDriver struct {
 	docker   dockerClient // retries on *docker.Client, restricts ad hoc *docker.Client usage / retries
 	hostname string
 	auths    map[string]driverAuthConfig
+	mirrors  map[string]string
+	retry    *pullRetryPolicy
 	pool     DockerPool
 	network  *DockerNetworks
 
 	instanceId string
 
 	imgCache ImageCacher
+
+	// true if the docker daemon pulls images lazily
+	lazyPull bool
+
+	// container snapshots, nil if disabled
+	snapshots *snapshotStore
+
+	// features supported by the docker daemon
+	caps DaemonCapabilities
+
+	// kernel parameters functions may set
+	sysctls *sysctlAllowlist
+
+	// dedicated cpus of containers, nil if disabled
+	pinner *cpuPinner
+
+	// protects containerListeners
+	listenersLock      sync.RWMutex
+	containerListeners []drivers.ContainerListener
 }
 
 // NewDocker implements drivers.Driver
@@ -89,14 +111,29 @@ func NewDocker(conf drivers.Config) *DockerDriver {
 		logrus.WithError(err).Fatal("couldn't initialize registry")
 	}
 
+	mirrors, err := parseRegistryMirrors(conf.RegistryMirrors)
+	if err != nil {
+		logrus.WithError(err).Fatal("couldn't initialize registry mirrors")
+	}
+
+	retry, err := newPullRetryPolicy(conf)
+	if err != nil {
+		logrus.WithError(err).Fatal("couldn't initialize pull retry policy")
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	client, apiVersion := newClient(ctx, conf)
 	driver := &DockerDriver{
 		cancel:     cancel,
 		conf:       conf,
-		docker:     newClient(ctx),
+		docker:     client,
 		hostname:   hostname,
 		auths:      auths,
+		mirrors:    mirrors,
+		retry:      retry,
 		network:    NewDockerNetworks(conf),
+		sysctls:    newSysctlAllowlist(conf.AllowedSysctls),
+		pinner:     newCPUPinner(conf.CPUPinningThreshold),
 		instanceId: instanceId,
 		imgCache:   createImageCache(conf),
 	}
@@ -106,9 +143,21 @@ func NewDocker(conf drivers.Config) *DockerDriver {
 		logrus.WithError(err).Fatal("docker version error")
 	}
 
+	driver.caps, err = checkCapabilities(ctx, driver, apiVersion)
+	if err != nil {
+		logrus.WithError(err).Fatal("docker capabilities error")
+	}
+
+	driver.lazyPull = checkLazyPull(ctx, driver)
+
+	if conf.EnableSnapshots {
+		driver.snapshots = newSnapshotStore()
+	}
+
 	// start the cleanup jobs as early as possible
 	go func() {
 		killLeakedContainers(ctx, driver)
+		cleanSnapshots(ctx, driver)
 		runImageStats(ctx, driver)
 		syncImageCleaner(ctx, driver)
 		runImageCleaner(ctx, driver)
@@ -334,6 +383,37 @@ func checkDockerVersion(ctx context.Context, driver *DockerDriver) error {
 	return nil
 }
 
+// checkCapabilities detects docker daemon features and validates the driver config against them
+func checkCapabilities(ctx context.Context, driver *DockerDriver, apiVersion string) (DaemonCapabilities, error) {
+	info, err := driver.docker.Info(ctx)
+	if err != nil {
+		return DaemonCapabilities{}, err
+	}
+
+	caps := detectCapabilities(apiVersion, info)
+	switch driver.conf.CgroupVersion {
+	case CgroupV1, CgroupV2:
+		caps.CgroupVersion = int(driver.conf.CgroupVersion)
+	case 0:
+		caps.CgroupVersion = detectCgroupVersion(cgroupRoot)
+	default:
+		return caps, fmt.Errorf("invalid cgroup version %d", driver.conf.CgroupVersion)
+	}
+
+	log := common.Logger(ctx).WithFields(logrus.Fields{"api_version": caps.APIVersion, "storage_driver": info.Driver, "storage_opt": caps.StorageOpt, "cgroup_version": caps.CgroupVersion})
+	log.Info("docker daemon capabilities")
+
+	if driver.conf.Runtime != "" && !caps.Runtimes[driver.conf.Runtime] {
+		log.WithField("runtime", driver.conf.Runtime).Error("docker runtime is not available, using default runtime")
+	}
+	return caps, nil
+}
+
+// Capabilities returns the docker daemon features detected at startup
+func (drv *DockerDriver) Capabilities() DaemonCapabilities {
+	return drv.caps
+}
+
 func loadDockerImages(ctx context.Context, driver *DockerDriver) error {
 	if driver.conf.DockerLoadFile == "" {
 		return nil
@@ -345,6 +425,66 @@ func loadDockerImages(ctx context.Context, driver *DockerDriver) error {
 	return driver.docker.LoadImages(ctx, driver.conf.DockerLoadFile)
 }
 
+// IsLazyPull returns true if images are pulled lazily, in which case image
+// content is fetched on demand after the container is started.
+func (drv *DockerDriver) IsLazyPull() bool {
+	return drv.lazyPull
+}
+
+// Networks implements drivers.NetworkPool
+func (drv *DockerDriver) Networks() map[string]uint64 {
+	return drv.network.Networks()
+}
+
+// AddNetwork implements drivers.NetworkPool
+func (drv *DockerDriver) AddNetwork(ctx context.Context, id string) error {
+	_, err := drv.docker.NetworkInfo(ctx, id)
+	if err != nil {
+		if _, ok := err.(*docker.NoSuchNetwork); ok {
+			return ErrNetworkUnknown
+		}
+		return err
+	}
+
+	err = drv.network.AddNetwork(id)
+	if err == nil {
+		common.Logger(ctx).WithField("network", id).Info("docker network added to pool")
+	}
+	return err
+}
+
+// RemoveNetwork implements drivers.NetworkPool
+func (drv *DockerDriver) RemoveNetwork(ctx context.Context, id string) error {
+	err := drv.network.RemoveNetwork(id)
+	if err == nil {
+		common.Logger(ctx).WithField("network", id).Info("docker network removed from pool")
+	}
+	return err
+}
+
+// CachedImages implements drivers.ImageCache
+func (drv *DockerDriver) CachedImages() ([]drivers.ImageCacheEntry, uint64, bool) {
+	if drv.imgCache == nil {
+		return nil, 0, false
+	}
+
+	busy, idle := drv.imgCache.List()
+	images := make([]drivers.ImageCacheEntry, 0, len(busy)+len(idle))
+	for _, img := range busy {
+		images = append(images, drivers.ImageCacheEntry{ID: img.ID, Tags: img.RepoTags, Size: img.Size, InUse: true})
+	}
+	for _, img := range idle {
+		images = append(images, drivers.ImageCacheEntry{ID: img.ID, Tags: img.RepoTags, Size: img.Size})
+	}
+	return images, drv.imgCache.GetStats().MaxImgTotalSize, true
+}
+
+// Ping checks that the docker daemon is reachable
+func (drv *DockerDriver) Ping(ctx context.Context) error {
+	_, err := drv.docker.Info(ctx)
+	return err
+}
+
 func (drv *DockerDriver) Close() error {
 	var err error
 	if drv.pool != nil {
@@ -400,6 +540,9 @@ func (drv *DockerDriver) CreateCookie(ctx context.Context, task drivers.Containe
 	cookie.configureEnv(log)
 	cookie.configureCPU(log)
 	cookie.configureFsSize(log)
+	cookie.configureRuntime(log)
+	cookie.configureSecurity(log)
+	cookie.configureSysctls(log)
 	cookie.configureTmpFs(log)
 	cookie.configureVolumes(log)
 	cookie.configureWorkDir(log)
@@ -408,9 +551,35 @@ func (drv *DockerDriver) CreateCookie(ctx context.Context, task drivers.Containe
 	cookie.configureHostname(log)
 	cookie.configureImage(log)
 
+	err := drv.fireBeforeContainerCreate(ctx, &cookie.opts)
+	if err != nil {
+		cookie.Close(ctx)
+		return nil, err
+	}
+
 	return cookie, nil
 }
 
+// AddContainerListener implements drivers.ContainerListenerAdder
+func (drv *DockerDriver) AddContainerListener(listener drivers.ContainerListener) {
+	drv.listenersLock.Lock()
+	drv.containerListeners = append(drv.containerListeners, listener)
+	drv.listenersLock.Unlock()
+}
+
+func (drv *DockerDriver) fireBeforeContainerCreate(ctx context.Context, opts *docker.CreateContainerOptions) error {
+	drv.listenersLock.RLock()
+	defer drv.listenersLock.RUnlock()
+
+	for _, l := range drv.containerListeners {
+		err := l.BeforeContainerCreate(ctx, opts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Run executes the docker container. If task runs, drivers.RunResult will be returned. If something fails outside the task (ie: Docker), it will return error.
 // The docker driver will attempt to cast the task to a Auther. If that succeeds, private image support is available. See the Auther interface for how to implement this.
 func (drv *DockerDriver) run(ctx context.Context, container string, task drivers.ContainerTask) (drivers.WaitResult, error) {
@@ -470,7 +639,7 @@ func (drv *DockerDriver) run(ctx context.Context, container string, task drivers
 	stopSignal := make(chan struct{})
 	go drv.collectStats(ctx, stopSignal, container, task)
 
-	err = drv.docker.StartContainerWithContext(container, nil, ctx)
+	err = drv.startContainer(ctx, container)
 	if err != nil && ctx.Err() == nil {
 		if isSyslogError(err) {
 			// syslog error is a func error
@@ -490,6 +659,26 @@ func (drv *DockerDriver) run(ctx context.Context, container string, task drivers
 	}, nil
 }
 
+// startContainer starts the container, retrying while its syslog is unavailable up to
+// SyslogRetryAttempts times, the delay between attempts doubling from SyslogRetryBackoff
+func (drv *DockerDriver) startContainer(ctx context.Context, container string) error {
+	backoff := drv.conf.SyslogRetryBackoff
+	for attempt := uint64(1); ; attempt++ {
+		err := drv.docker.StartContainerWithContext(container, nil, ctx)
+		if err == nil || !isSyslogError(err) || attempt >= drv.conf.SyslogRetryAttempts {
+			return err
+		}
+
+		common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"container": container, "attempt": attempt}).Warn("syslog unavailable, retrying container start")
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
 // isSyslogError checks if the error message is what docker syslog plugin returns
 // when not able to connect to syslog
 func isSyslogError(err error) bool {
@@ -572,6 +761,10 @@ func cherryPick(ds *docker.Stats) drivers.Stat {
 	// do we want users to see as a % of system?
 	systemDelta := float64(ds.CPUStats.SystemCPUUsage - ds.PreCPUStats.SystemCPUUsage)
 	cores := float64(len(ds.CPUStats.CPUUsage.PercpuUsage))
+	if cores == 0 {
+		// per cpu usage is not reported with cgroup v2
+		cores = float64(ds.CPUStats.OnlineCPUs)
+	}
 	var cpuUser, cpuKernel, cpuTotal float64
 	if systemDelta > 0 {
 		// TODO we could leave these in docker format and let hud/viz tools do this instead of us... like net is, could do same for mem, too. thoughts?
@@ -665,6 +858,8 @@ func (w *waitResult) wait(ctx context.Context) (status string, err error) {
 }
 
 var _ drivers.Driver = &DockerDriver{}
+var _ drivers.NetworkPool = &DockerDriver{}
+var _ drivers.ContainerListenerAdder = &DockerDriver{}
 
 func init() {
 	drivers.Register("docker", func(config drivers.Config) (drivers.Driver, error) {
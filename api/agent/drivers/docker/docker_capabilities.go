@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// maxDockerAPIVersion is the highest docker remote API version the driver negotiates
+	maxDockerAPIVersion = "1.38"
+
+	// defaultMaxIdleConns is the default number of idle connections kept open to the docker daemon
+	defaultMaxIdleConns = 32
+)
+
+var (
+	// storage drivers that support the size storage option. overlay2 supports it only on
+	// xfs backing file systems, see detectCapabilities.
+	storageOptDrivers = map[string]bool{
+		"devicemapper":  true,
+		"btrfs":         true,
+		"zfs":           true,
+		"windowsfilter": true,
+	}
+)
+
+// DaemonCapabilities describes features of the docker daemon which are detected at
+// startup. Features that are not supported by the daemon are disabled gracefully.
+type DaemonCapabilities struct {
+	// APIVersion is the negotiated docker remote API version
+	APIVersion string
+	// StorageOpt is true if the storage driver supports container file system size limits
+	StorageOpt bool
+	// Runtimes are the OCI runtimes available to run containers
+	Runtimes map[string]bool
+	// CgroupVersion is the cgroup version containers run with, CgroupV1 or CgroupV2
+	CgroupVersion int
+}
+
+// negotiateAPIVersion returns the highest API version supported by both the docker
+// daemon and the driver, given the version env returned by the daemon.
+func negotiateAPIVersion(env *docker.Env) (string, error) {
+	max, err := docker.NewAPIVersion(maxDockerAPIVersion)
+	if err != nil {
+		return "", err
+	}
+
+	daemon, err := docker.NewAPIVersion(env.Get("ApiVersion"))
+	if err != nil {
+		return "", fmt.Errorf("invalid docker daemon api version %q: %v", env.Get("ApiVersion"), err)
+	}
+
+	if min := env.Get("MinAPIVersion"); min != "" {
+		daemonMin, err := docker.NewAPIVersion(min)
+		if err != nil {
+			return "", fmt.Errorf("invalid docker daemon min api version %q: %v", min, err)
+		}
+		if max.LessThan(daemonMin) {
+			return "", fmt.Errorf("docker daemon requires api version %s or newer, driver supports up to %s", daemonMin, max)
+		}
+	}
+
+	if daemon.LessThan(max) {
+		return daemon.String(), nil
+	}
+	return max.String(), nil
+}
+
+// configureConnPool enables keep-alives on the docker client transport and keeps up to
+// maxIdle connections open to the daemon, rather than dialing for each API call.
+func configureConnPool(client *docker.Client, maxIdle uint64) {
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+
+	tr, ok := client.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+
+	tr.DisableKeepAlives = false
+	tr.MaxIdleConns = int(maxIdle)
+	tr.MaxIdleConnsPerHost = int(maxIdle)
+}
+
+// detectCapabilities inspects docker info for features the driver depends on
+func detectCapabilities(apiVersion string, info *docker.DockerInfo) DaemonCapabilities {
+	caps := DaemonCapabilities{
+		APIVersion: apiVersion,
+		StorageOpt: storageOptDrivers[info.Driver],
+		Runtimes:   make(map[string]bool),
+	}
+
+	// docker only reports the backing file system, creating a container with a size
+	// option still fails if the xfs file system is not mounted with pquota
+	if info.Driver == "overlay2" {
+		for _, status := range info.DriverStatus {
+			if status[0] == "Backing Filesystem" && status[1] == "xfs" {
+				caps.StorageOpt = true
+			}
+		}
+	}
+
+	for name := range info.Runtimes {
+		caps.Runtimes[name] = true
+	}
+	return caps
+}
@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestNegotiateAPIVersion(t *testing.T) {
+	for _, tc := range []struct {
+		daemon, min string
+		expected    string
+		err         bool
+	}{
+		{"1.25", "1.12", "1.25", false},
+		{"1.40", "1.12", maxDockerAPIVersion, false},
+		{maxDockerAPIVersion, "", maxDockerAPIVersion, false},
+		{"1.45", "1.40", "", true},
+		{"bogus", "", "", true},
+	} {
+		env := &docker.Env{}
+		env.Set("ApiVersion", tc.daemon)
+		if tc.min != "" {
+			env.Set("MinAPIVersion", tc.min)
+		}
+
+		version, err := negotiateAPIVersion(env)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("expected error for daemon=%s min=%s, got version %s", tc.daemon, tc.min, version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for daemon=%s min=%s: %v", tc.daemon, tc.min, err)
+		}
+		if version != tc.expected {
+			t.Fatalf("expected version %s for daemon=%s, got %s", tc.expected, tc.daemon, version)
+		}
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	caps := detectCapabilities("1.38", &docker.DockerInfo{
+		Driver:       "overlay2",
+		DriverStatus: [][2]string{{"Backing Filesystem", "extfs"}},
+		Runtimes:     map[string]docker.Runtime{"runc": {Path: "docker-runc"}},
+	})
+	if caps.APIVersion != "1.38" {
+		t.Fatalf("unexpected api version %s", caps.APIVersion)
+	}
+	if caps.StorageOpt {
+		t.Fatalf("overlay2 on extfs should not support storage opt")
+	}
+	if !caps.Runtimes["runc"] || caps.Runtimes["runsc"] {
+		t.Fatalf("unexpected runtimes %v", caps.Runtimes)
+	}
+
+	caps = detectCapabilities("1.38", &docker.DockerInfo{
+		Driver:       "overlay2",
+		DriverStatus: [][2]string{{"Backing Filesystem", "xfs"}},
+	})
+	if !caps.StorageOpt {
+		t.Fatalf("overlay2 on xfs should support storage opt")
+	}
+
+	caps = detectCapabilities("1.38", &docker.DockerInfo{Driver: "devicemapper"})
+	if !caps.StorageOpt {
+		t.Fatalf("devicemapper should support storage opt")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/sirupsen/logrus"
@@ -31,15 +32,18 @@ type dockerClient interface {
 	StartContainerWithContext(id string, hostConfig *docker.HostConfig, ctx context.Context) error
 	KillContainer(opts docker.KillContainerOptions) error
 	CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error)
+	CommitContainer(opts docker.CommitContainerOptions) (*docker.Image, error)
 	RemoveContainer(opts docker.RemoveContainerOptions) error
 	PauseContainer(id string, ctx context.Context) error
 	UnpauseContainer(id string, ctx context.Context) error
 	PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error
+	TagImage(name string, opts docker.TagImageOptions) error
 	InspectImage(ctx context.Context, name string) (*docker.Image, error)
 	ListImages(opts docker.ListImagesOptions) ([]docker.APIImages, error)
 	RemoveImage(id string, opts docker.RemoveImageOptions) error
 	Stats(opts docker.StatsOptions) error
 	Info(ctx context.Context) (*docker.DockerInfo, error)
+	NetworkInfo(ctx context.Context, id string) (*docker.Network, error)
 	LoadImages(ctx context.Context, filePath string) error
 	ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error)
 	AddEventListener(ctx context.Context) (chan *docker.APIEvents, error)
@@ -47,9 +51,7 @@ type dockerClient interface {
 }
 
 // TODO: switch to github.com/docker/engine-api
-func newClient(ctx context.Context) dockerClient {
-	// TODO this was much easier, don't need special settings at the moment
-	// docker, err := docker.NewClient(conf.Docker)
+func newClient(ctx context.Context, conf drivers.Config) (dockerClient, string) {
 	client, err := docker.NewClientFromEnv()
 	if err != nil {
 		logrus.WithError(err).Fatal("couldn't create docker client")
@@ -59,9 +61,30 @@ func newClient(ctx context.Context) dockerClient {
 		logrus.WithError(err).Fatal("couldn't connect to docker daemon")
 	}
 
+	// pin the api version, either as configured or negotiated with the daemon
+	apiVersion := conf.DockerAPIVersion
+	if apiVersion == "" {
+		env, err := client.Version()
+		if err != nil {
+			logrus.WithError(err).Fatal("couldn't get docker daemon version")
+		}
+		apiVersion, err = negotiateAPIVersion(env)
+		if err != nil {
+			logrus.WithError(err).Fatal("couldn't negotiate docker api version")
+		}
+	}
+
+	client, err = docker.NewVersionedClientFromEnv(apiVersion)
+	if err != nil {
+		logrus.WithError(err).Fatal("couldn't create docker client")
+	}
+	configureConnPool(client, conf.DockerMaxIdleConns)
+
+	logrus.WithField("api_version", apiVersion).Info("docker client created")
+
 	wrap := &dockerWrap{docker: client}
 	go wrap.listenEventLoop(ctx)
-	return wrap
+	return wrap, apiVersion
 }
 
 type dockerWrap struct {
@@ -82,6 +105,8 @@ var (
 
 	dockerEventsMeasure = common.MakeMeasure("docker_events", "docker events", "")
 
+	dockerPullRetryMeasure = common.MakeMeasure("docker_pull_retries", "docker image pull retry counts", "")
+
 	imageCleanerBusyImgCount = common.MakeMeasure("image_cleaner_busy_img_count", "image cleaner busy image count", "")
 	imageCleanerBusyImgSize  = common.MakeMeasure("image_cleaner_busy_img_size", "image cleaner busy image total size", "By")
 	imageCleanerIdleImgCount = common.MakeMeasure("image_cleaner_idle_img_count", "image cleaner idle image count", "")
@@ -89,6 +114,10 @@ var (
 	imageCleanerMaxImgSize   = common.MakeMeasure("image_cleaner_max_img_size", "image cleaner image max size", "By")
 
 	dockerInstanceId = common.MakeMeasure("docker_instance_id", "docker instance id", "")
+
+	preForkPoolSize  = common.MakeMeasure("prefork_pool_size", "prefork pool container count", "")
+	preForkPoolInUse = common.MakeMeasure("prefork_pool_inuse", "prefork pool containers in use", "")
+	preForkPoolFree  = common.MakeMeasure("prefork_pool_free", "prefork pool containers ready for use", "")
 )
 
 func RecordInstanceId(ctx context.Context, id string) {
@@ -98,6 +127,16 @@ func RecordInstanceId(ctx context.Context, id string) {
 	stats.Record(ctx, dockerInstanceId.M(hid))
 }
 
+func RecordPullRetry(ctx context.Context) {
+	stats.Record(ctx, dockerPullRetryMeasure.M(0))
+}
+
+func RecordPreForkPoolStats(ctx context.Context, sample DockerPoolStats) {
+	stats.Record(ctx, preForkPoolSize.M(int64(sample.size)))
+	stats.Record(ctx, preForkPoolInUse.M(int64(sample.inuse)))
+	stats.Record(ctx, preForkPoolFree.M(int64(sample.free)))
+}
+
 func RecordImageCleanerStats(ctx context.Context, sample *ImageCacherStats) {
 	stats.Record(ctx, imageCleanerBusyImgCount.M(int64(sample.BusyImgCount)))
 	stats.Record(ctx, imageCleanerBusyImgSize.M(int64(sample.BusyImgTotalSize)))
@@ -237,12 +276,16 @@ func RegisterViews(tagKeys []string, latencyDist []float64) {
 		common.CreateViewWithTags(dockerExitMeasure, view.Count(), exitTags),
 		common.CreateViewWithTags(dockerLatencyMeasure, view.Distribution(latencyDist...), defaultTags),
 		common.CreateViewWithTags(dockerEventsMeasure, view.Count(), eventTags),
+		common.CreateViewWithTags(dockerPullRetryMeasure, view.Count(), emptyTags),
 		common.CreateViewWithTags(imageCleanerBusyImgCount, view.LastValue(), emptyTags),
 		common.CreateViewWithTags(imageCleanerBusyImgSize, view.LastValue(), emptyTags),
 		common.CreateViewWithTags(imageCleanerIdleImgCount, view.LastValue(), emptyTags),
 		common.CreateViewWithTags(imageCleanerIdleImgSize, view.LastValue(), emptyTags),
 		common.CreateViewWithTags(imageCleanerMaxImgSize, view.LastValue(), emptyTags),
 		common.CreateViewWithTags(dockerInstanceId, view.LastValue(), emptyTags),
+		common.CreateViewWithTags(preForkPoolSize, view.LastValue(), emptyTags),
+		common.CreateViewWithTags(preForkPoolInUse, view.LastValue(), emptyTags),
+		common.CreateViewWithTags(preForkPoolFree, view.LastValue(), emptyTags),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -308,6 +351,13 @@ func (d *dockerWrap) Info(ctx context.Context) (info *docker.DockerInfo, err err
 	return info, err
 }
 
+func (d *dockerWrap) NetworkInfo(ctx context.Context, id string) (network *docker.Network, err error) {
+	_, closer := makeTracker(ctx, "docker_network_info")
+	defer func() { closer(err) }()
+	network, err = d.docker.NetworkInfo(id)
+	return network, err
+}
+
 func (d *dockerWrap) AttachToContainerNonBlocking(ctx context.Context, opts docker.AttachToContainerOptions) (w docker.CloseWaiter, err error) {
 	_, closer := makeTracker(ctx, "docker_attach_container")
 	defer func() { closer(err) }()
@@ -339,6 +389,13 @@ func (d *dockerWrap) CreateContainer(opts docker.CreateContainerOptions) (c *doc
 	return c, err
 }
 
+func (d *dockerWrap) CommitContainer(opts docker.CommitContainerOptions) (img *docker.Image, err error) {
+	_, closer := makeTracker(opts.Context, "docker_commit_container")
+	defer func() { closer(err) }()
+	img, err = d.docker.CommitContainer(opts)
+	return img, err
+}
+
 func (d *dockerWrap) KillContainer(opts docker.KillContainerOptions) (err error) {
 	_, closer := makeTracker(opts.Context, "docker_kill_container")
 	defer func() { closer(err) }()
@@ -353,6 +410,13 @@ func (d *dockerWrap) PullImage(opts docker.PullImageOptions, auth docker.AuthCon
 	return err
 }
 
+func (d *dockerWrap) TagImage(name string, opts docker.TagImageOptions) (err error) {
+	_, closer := makeTracker(opts.Context, "docker_tag_image")
+	defer func() { closer(err) }()
+	err = d.docker.TagImage(name, opts)
+	return err
+}
+
 func (d *dockerWrap) RemoveImage(image string, opts docker.RemoveImageOptions) (err error) {
 	_, closer := makeTracker(opts.Context, "docker_remove_image")
 	defer func() { closer(err) }()
@@ -1,22 +1,35 @@
 package docker
 
 import (
+	"errors"
 	"math"
+	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/models"
+)
+
+var (
+	ErrNetworkExists   = models.NewAPIError(http.StatusConflict, errors.New("docker network already in pool"))
+	ErrNetworkNotFound = models.NewAPIError(http.StatusNotFound, errors.New("docker network not in pool"))
+	ErrNetworkUnknown  = models.NewAPIError(http.StatusBadRequest, errors.New("docker network does not exist"))
 )
 
 type DockerNetworks struct {
-	// protects networks map
+	// protects networks and draining maps
 	networksLock sync.Mutex
 	networks     map[string]uint64
+	// networks removed from the pool with the number of containers still running on them,
+	// until the last one is freed
+	draining map[string]uint64
 }
 
 func NewDockerNetworks(conf drivers.Config) *DockerNetworks {
 	obj := &DockerNetworks{
 		networks: make(map[string]uint64),
+		draining: make(map[string]uint64),
 	}
 
 	// Record DockerNetworks both in wait-list and in network allocations
@@ -29,6 +42,9 @@ func NewDockerNetworks(conf drivers.Config) *DockerNetworks {
 
 // pick least used network
 func (n *DockerNetworks) AllocNetwork() string {
+	n.networksLock.Lock()
+	defer n.networksLock.Unlock()
+
 	if len(n.networks) == 0 {
 		return ""
 	}
@@ -36,7 +52,6 @@ func (n *DockerNetworks) AllocNetwork() string {
 	var id string
 	min := uint64(math.MaxUint64)
 
-	n.networksLock.Lock()
 	for key, val := range n.networks {
 		if val < min {
 			id = key
@@ -44,7 +59,6 @@ func (n *DockerNetworks) AllocNetwork() string {
 		}
 	}
 	n.networks[id]++
-	n.networksLock.Unlock()
 
 	return id
 }
@@ -52,8 +66,57 @@ func (n *DockerNetworks) AllocNetwork() string {
 // unregister network
 func (n *DockerNetworks) FreeNetwork(id string) {
 	n.networksLock.Lock()
-	if count, ok := n.networks[id]; ok {
+	if count, ok := n.networks[id]; ok && count > 0 {
 		n.networks[id] = count - 1
+	} else if count, ok := n.draining[id]; ok {
+		if count <= 1 {
+			delete(n.draining, id)
+		} else {
+			n.draining[id] = count - 1
+		}
 	}
 	n.networksLock.Unlock()
 }
+
+// AddNetwork adds a network to the set of networks containers are allocated to. A network
+// removed while containers still run on it is added back with them.
+func (n *DockerNetworks) AddNetwork(id string) error {
+	n.networksLock.Lock()
+	defer n.networksLock.Unlock()
+
+	if _, ok := n.networks[id]; ok {
+		return ErrNetworkExists
+	}
+	n.networks[id] = n.draining[id]
+	delete(n.draining, id)
+	return nil
+}
+
+// RemoveNetwork stops allocating containers to a network. Containers already
+// running on the network are not affected, they are counted until freed.
+func (n *DockerNetworks) RemoveNetwork(id string) error {
+	n.networksLock.Lock()
+	defer n.networksLock.Unlock()
+
+	count, ok := n.networks[id]
+	if !ok {
+		return ErrNetworkNotFound
+	}
+	delete(n.networks, id)
+	if count > 0 {
+		n.draining[id] = count
+	}
+	return nil
+}
+
+// Networks returns the networks in the pool with the number of containers allocated to each
+func (n *DockerNetworks) Networks() map[string]uint64 {
+	n.networksLock.Lock()
+	defer n.networksLock.Unlock()
+
+	networks := make(map[string]uint64, len(n.networks))
+	for key, val := range n.networks {
+		networks[key] = val
+	}
+	return networks
+}
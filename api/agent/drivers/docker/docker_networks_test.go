@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+)
+
+func TestDockerNetworksReAdd(t *testing.T) {
+	n := NewDockerNetworks(drivers.Config{DockerNetworks: "net1 net2"})
+
+	// net1 and net2 get a container each, then net1 another
+	for i := 0; i < 3; i++ {
+		n.AllocNetwork()
+	}
+	busy := "net1"
+	if n.Networks()["net2"] == 2 {
+		busy = "net2"
+	}
+
+	// the containers of a network removed and added back are still counted
+	if err := n.RemoveNetwork(busy); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AddNetwork(busy); err != nil {
+		t.Fatal(err)
+	}
+	if count := n.Networks()[busy]; count != 2 {
+		t.Fatalf("expected the 2 containers of %s counted once added back, got %d", busy, count)
+	}
+	for i := 0; i < 3; i++ {
+		n.FreeNetwork(busy)
+	}
+	if count := n.Networks()[busy]; count != 0 {
+		t.Fatalf("expected the count of %s not to go below 0, got %d", busy, count)
+	}
+
+	// containers freed once their network is removed are not counted when it is added back
+	n.AllocNetwork()
+	n.AllocNetwork()
+	counts := n.Networks()
+	if err := n.RemoveNetwork("net1"); err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(0); i < counts["net1"]; i++ {
+		n.FreeNetwork("net1")
+	}
+	if err := n.AddNetwork("net1"); err != nil {
+		t.Fatal(err)
+	}
+	if count := n.Networks()["net1"]; count != 0 {
+		t.Fatalf("expected no container on net1 once added back, got %d", count)
+	}
+	if id := n.AllocNetwork(); id != "net1" {
+		t.Fatalf("expected the least used net1 allocated, got %s", id)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/common"
@@ -28,7 +29,8 @@ import (
 // where pool buddy provides already creates namespaces. These are currently
 // network and user namespaces, but perhaps can be extended to also use pid and ipc.
 // (see docker.go Prepare() on how this is currently being used.)
-// Currently the pool is a set size and it does not grow on demand.
+// If a max pool size is configured, the pool grows on demand when utilization
+// reaches the high watermark and shrinks back when it drops below the low watermark.
 
 var (
 	ErrorPoolEmpty = errors.New("docker pre fork pool empty")
@@ -46,6 +48,15 @@ const (
 	LimitBurst  = 20
 )
 
+const (
+	// default utilization percentages at which the pool grows and shrinks
+	DefaultHighWatermark = 80
+	DefaultLowWatermark  = 20
+
+	// default interval at which the pool utilization is checked
+	DefaultScaleInterval = time.Duration(1 * time.Second)
+)
+
 type poolTask struct {
 	id      string
 	image   string
@@ -87,11 +98,29 @@ type dockerPool struct {
 	cancel    func()
 	wg        sync.WaitGroup
 	isRecycle bool
+
+	// pool containers by id, with the func to stop their nanny
+	tasks    map[string]func()
+	seq      uint64
+	driver   *DockerDriver
+	image    string
+	cmd      string
+	networks []string
+	pullGate chan struct{}
+
+	// autoscaling settings, the pool is fixed size if minSize == maxSize
+	minSize       int
+	maxSize       int
+	highWatermark uint64
+	lowWatermark  uint64
+	scaleInterval time.Duration
+	growCh        chan struct{}
 }
 
 type DockerPoolStats struct {
 	inuse int
 	free  int
+	size  int
 }
 
 type DockerPool interface {
@@ -122,38 +151,172 @@ func NewDockerPool(conf drivers.Config, driver *DockerDriver) DockerPool {
 	log.Error("WARNING: Experimental Prefork Docker Pool Enabled")
 
 	pool := &dockerPool{
-		inuse:   make(map[string]dockerPoolItem, conf.PreForkPoolSize),
-		free:    make([]dockerPoolItem, 0, conf.PreForkPoolSize),
-		limiter: rate.NewLimiter(LimitPerSec, LimitBurst),
-		cancel:  cancel,
+		inuse:         make(map[string]dockerPoolItem, conf.PreForkPoolSize),
+		free:          make([]dockerPoolItem, 0, conf.PreForkPoolSize),
+		limiter:       rate.NewLimiter(LimitPerSec, LimitBurst),
+		cancel:        cancel,
+		tasks:         make(map[string]func(), conf.PreForkPoolSize),
+		driver:        driver,
+		image:         conf.PreForkImage,
+		cmd:           conf.PreForkCmd,
+		networks:      strings.Fields(conf.PreForkNetworks),
+		pullGate:      make(chan struct{}, 1),
+		minSize:       int(conf.PreForkPoolSize),
+		maxSize:       int(conf.PreForkMaxSize),
+		highWatermark: conf.PreForkHighWatermark,
+		lowWatermark:  conf.PreForkLowWatermark,
+		scaleInterval: conf.PreForkScaleInterval,
+		growCh:        make(chan struct{}, 1),
 	}
 
 	if conf.PreForkUseOnce != 0 {
 		pool.isRecycle = true
 	}
 
-	networks := strings.Fields(conf.PreForkNetworks)
-	if len(networks) == 0 {
-		networks = append(networks, "")
+	if len(pool.networks) == 0 {
+		pool.networks = append(pool.networks, "")
+	}
+	if pool.maxSize < pool.minSize {
+		pool.maxSize = pool.minSize
+	}
+	if pool.highWatermark == 0 || pool.highWatermark > 100 {
+		pool.highWatermark = DefaultHighWatermark
+	}
+	if pool.lowWatermark >= pool.highWatermark {
+		pool.lowWatermark = DefaultLowWatermark
+		if pool.lowWatermark >= pool.highWatermark {
+			pool.lowWatermark = 0
+		}
+	}
+	if pool.scaleInterval == 0 {
+		pool.scaleInterval = DefaultScaleInterval
 	}
 
-	pool.wg.Add(1 + int(conf.PreForkPoolSize))
-	pullGate := make(chan struct{}, 1)
+	for i := 0; i < pool.minSize; i++ {
+		pool.spawn(ctx)
+	}
 
-	for i := 0; i < int(conf.PreForkPoolSize); i++ {
+	if pool.maxSize > pool.minSize {
+		log.WithFields(logrus.Fields{"min": pool.minSize, "max": pool.maxSize, "high": pool.highWatermark, "low": pool.lowWatermark}).Info("prefork pool autoscaling enabled")
+		pool.wg.Add(1)
+		go pool.autoscale(ctx)
+	}
+
+	pool.wg.Add(1)
+	go pool.prepareImage(ctx, driver, conf.PreForkImage, pool.pullGate)
+	return pool
+}
+
+// spawn adds a container to the pool
+func (pool *dockerPool) spawn(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	pool.lock.Lock()
+	task := &poolTask{
+		id:      fmt.Sprintf("%d_prefork_%s", pool.seq, id.New().String()),
+		image:   pool.image,
+		cmd:     pool.cmd,
+		netMode: pool.networks[pool.seq%uint64(len(pool.networks))],
+	}
+	pool.seq++
+	pool.tasks[task.Id()] = cancel
+	pool.lock.Unlock()
+
+	pool.wg.Add(1)
+	go pool.nannyContainer(ctx, pool.driver, task, pool.pullGate)
+}
+
+// shrink removes a free container from the pool, returns false if there is none
+func (pool *dockerPool) shrink() bool {
+	pool.lock.Lock()
+	if len(pool.free) == 0 {
+		pool.lock.Unlock()
+		return false
+	}
+
+	// the oldest free container has been idle the longest
+	item := pool.free[0]
+	pool.free = pool.free[1:]
+	cancel := pool.tasks[item.id]
+	delete(pool.tasks, item.id)
+	pool.lock.Unlock()
 
-		task := &poolTask{
-			id:      fmt.Sprintf("%d_prefork_%s", i, id.New().String()),
-			image:   conf.PreForkImage,
-			cmd:     conf.PreForkCmd,
-			netMode: networks[i%len(networks)],
+	if cancel != nil {
+		cancel()
+	}
+	return true
+}
+
+// poolScaleTarget returns the size the pool should have given its current size and the
+// number of containers in use. The pool grows by half its size when utilization reaches
+// the high watermark and shrinks by one container when it falls to the low watermark.
+func poolScaleTarget(size, inuse, minSize, maxSize int, high, low uint64) int {
+	if size < minSize {
+		return minSize
+	}
+	if size > maxSize {
+		return maxSize
+	}
+
+	var util uint64
+	if size > 0 {
+		util = uint64(inuse * 100 / size)
+	} else {
+		util = 100
+	}
+
+	if util >= high && size < maxSize {
+		step := size / 2
+		if step < 1 {
+			step = 1
+		}
+		if size+step > maxSize {
+			return maxSize
+		}
+		return size + step
+	}
+	if util <= low && size > minSize {
+		return size - 1
+	}
+	return size
+}
+
+// autoscale periodically resizes the pool between its min and max size based on utilization
+func (pool *dockerPool) autoscale(ctx context.Context) {
+	defer pool.wg.Done()
+
+	ticker := time.NewTicker(pool.scaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-pool.growCh:
 		}
+		pool.scale(ctx)
+	}
+}
 
-		go pool.nannyContainer(ctx, driver, task, pullGate)
+func (pool *dockerPool) scale(ctx context.Context) {
+	stats := pool.Usage()
+	target := poolScaleTarget(stats.size, stats.inuse, pool.minSize, pool.maxSize, pool.highWatermark, pool.lowWatermark)
+
+	if target != stats.size {
+		common.Logger(ctx).WithFields(logrus.Fields{"size": stats.size, "inuse": stats.inuse, "target": target}).Debug("prefork pool scaling")
 	}
 
-	go pool.prepareImage(ctx, driver, conf.PreForkImage, pullGate)
-	return pool
+	for i := stats.size; i < target; i++ {
+		pool.spawn(ctx)
+	}
+	for i := stats.size; i > target; i-- {
+		if !pool.shrink() {
+			break
+		}
+	}
+
+	RecordPreForkPoolStats(ctx, pool.Usage())
 }
 
 func (pool *dockerPool) Close() error {
@@ -366,8 +529,12 @@ func (pool *dockerPool) AllocPoolId() (string, error) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
-	// We currently do not grow the pool if we run out of pre-forked containers
 	if len(pool.free) == 0 {
+		// signal the autoscaler to grow the pool, the caller falls back to a new namespace
+		select {
+		case pool.growCh <- struct{}{}:
+		default:
+		}
 		return "", ErrorPoolEmpty
 	}
 
@@ -404,6 +571,7 @@ func (pool *dockerPool) Usage() DockerPoolStats {
 
 	stats.inuse = len(pool.inuse)
 	stats.free = len(pool.free)
+	stats.size = len(pool.tasks)
 
 	pool.lock.Unlock()
 	return stats
@@ -146,3 +146,26 @@ func TestRunnerDockerPoolFaulty(t *testing.T) {
 		t.Fatalf("pool shutdown timeout stats=%+v", stats)
 	}
 }
+
+func TestDockerPoolScaleTarget(t *testing.T) {
+	for _, tc := range []struct {
+		size, inuse, min, max int
+		expected              int
+	}{
+		{2, 0, 2, 2, 2},     // fixed size pool
+		{2, 2, 2, 2, 2},     // fixed size pool exhausted
+		{0, 0, 2, 10, 2},    // below min
+		{2, 2, 2, 10, 3},    // exhausted, grow by one
+		{4, 4, 2, 10, 6},    // exhausted, grow by half
+		{8, 7, 2, 10, 10},   // grow is capped at max
+		{6, 3, 2, 10, 6},    // between watermarks
+		{6, 1, 2, 10, 5},    // below low watermark, shrink by one
+		{2, 0, 2, 10, 2},    // shrink is capped at min
+		{12, 12, 2, 10, 10}, // above max
+	} {
+		target := poolScaleTarget(tc.size, tc.inuse, tc.min, tc.max, DefaultHighWatermark, DefaultLowWatermark)
+		if target != tc.expected {
+			t.Fatalf("size=%d inuse=%d min=%d max=%d expected target %d, got %d", tc.size, tc.inuse, tc.min, tc.max, tc.expected, target)
+		}
+	}
+}
@@ -18,10 +18,12 @@ type taskDockerTest struct {
 	id         string
 	cmd        string
 	disableNet bool
+	sysctls    map[string]string
+	cpus       uint64
 	input      io.Reader
 	output     io.Writer
 	errors     io.Writer
-	logURL     string
+	logCfg     drivers.LoggerConfig
 }
 
 func (f *taskDockerTest) Command() string                         { return f.cmd }
@@ -33,7 +35,7 @@ func (f *taskDockerTest) Logger() (stdout, stderr io.Writer)      { return f.out
 func (f *taskDockerTest) WriteStat(context.Context, drivers.Stat) { /* TODO */ }
 func (f *taskDockerTest) Volumes() [][2]string                    { return [][2]string{} }
 func (f *taskDockerTest) Memory() uint64                          { return 256 * 1024 * 1024 }
-func (f *taskDockerTest) CPUs() uint64                            { return 0 }
+func (f *taskDockerTest) CPUs() uint64                            { return f.cpus }
 func (f *taskDockerTest) FsSize() uint64                          { return 0 }
 func (f *taskDockerTest) TmpFsSize() uint64                       { return 0 }
 func (f *taskDockerTest) WorkDir() string                         { return "" }
@@ -41,12 +43,15 @@ func (f *taskDockerTest) Close()                                  {}
 func (f *taskDockerTest) Input() io.Reader                        { return f.input }
 func (f *taskDockerTest) Extensions() map[string]string           { return nil }
 func (f *taskDockerTest) LoggerConfig() drivers.LoggerConfig {
-	return drivers.LoggerConfig{URL: f.logURL}
+	return f.logCfg
 }
 func (f *taskDockerTest) UDSAgentPath() string  { return "" }
 func (f *taskDockerTest) UDSDockerPath() string { return "" }
 func (f *taskDockerTest) UDSDockerDest() string { return "" }
 func (f *taskDockerTest) DisableNet() bool      { return f.disableNet }
+func (f *taskDockerTest) Sysctls() map[string]string {
+	return f.sysctls
+}
 
 func createTask(id string) *taskDockerTest {
 	return &taskDockerTest{
@@ -539,7 +544,7 @@ func TestRunnerDockerInvalidSyslog(t *testing.T) {
 	defer dkr.Close()
 
 	task := createTask("test-docker-no-net")
-	task.logURL = "tcp://invalid:9999"
+	task.logCfg.URL = "tcp://invalid:9999"
 
 	cookie, err := dkr.CreateCookie(ctx, task)
 	if err != nil {
@@ -560,3 +565,88 @@ func TestRunnerDockerInvalidSyslog(t *testing.T) {
 	}
 
 }
+
+// startClient fails to start containers with err the first fails times
+type startClient struct {
+	dockerWrap
+	err    error
+	fails  int
+	starts int
+}
+
+func (c *startClient) StartContainerWithContext(id string, hostConfig *docker.HostConfig, ctx context.Context) error {
+	c.starts++
+	if c.starts <= c.fails {
+		return c.err
+	}
+	return nil
+}
+
+func TestStartContainerSyslogRetry(t *testing.T) {
+	ctx := context.Background()
+	syslogErr := &docker.Error{Status: 500, Message: "failed to initialize logging driver: dial tcp: connection refused"}
+
+	for i, test := range []struct {
+		attempts uint64
+		err      error
+		fails    int
+		starts   int
+		ok       bool
+	}{
+		{1, syslogErr, 1, 1, false},
+		{3, syslogErr, 2, 3, true},
+		{3, syslogErr, 5, 3, false},
+		{3, &docker.Error{Status: 500, Message: "no such image"}, 1, 1, false},
+	} {
+		client := &startClient{err: test.err, fails: test.fails}
+		drv := &DockerDriver{
+			conf:   drivers.Config{SyslogRetryAttempts: test.attempts, SyslogRetryBackoff: time.Millisecond},
+			docker: client,
+		}
+		err := drv.startContainer(ctx, "test-syslog-retry")
+		if (err == nil) != test.ok {
+			t.Fatalf("Test %d: expected success %v, got %v", i, test.ok, err)
+		}
+		if client.starts != test.starts {
+			t.Fatalf("Test %d: expected %d starts, got %d", i, test.starts, client.starts)
+		}
+	}
+}
+
+type testContainerListener struct {
+	err error
+}
+
+func (l *testContainerListener) BeforeContainerCreate(ctx context.Context, opts interface{}) error {
+	if l.err != nil {
+		return l.err
+	}
+	dopts := opts.(*docker.CreateContainerOptions)
+	dopts.HostConfig.CgroupParent = "/fn"
+	return nil
+}
+
+func TestContainerListener(t *testing.T) {
+	drv := &DockerDriver{network: NewDockerNetworks(drivers.Config{})}
+	drv.AddContainerListener(&testContainerListener{})
+
+	ctx := context.Background()
+	task := &taskDockerTest{id: "test-docker-listener"}
+
+	cookie, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatalf("Couldn't create task cookie: %v", err)
+	}
+	defer cookie.Close(ctx)
+
+	opts := cookie.ContainerOptions().(docker.CreateContainerOptions)
+	if opts.HostConfig.CgroupParent != "/fn" {
+		t.Fatalf("container listener changes not applied, cgroup parent=%q", opts.HostConfig.CgroupParent)
+	}
+
+	drv.AddContainerListener(&testContainerListener{err: io.ErrUnexpectedEOF})
+	_, err = drv.CreateCookie(ctx, task)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected container listener error, got %v", err)
+	}
+}
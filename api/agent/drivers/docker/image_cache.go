@@ -2,6 +2,7 @@ package docker
 
 import (
 	"container/list"
+	"sort"
 	"sync"
 )
 
@@ -55,6 +56,10 @@ type ImageCacher interface {
 
 	// Stats Monitoring
 	GetStats() *ImageCacherStats
+
+	// List returns the in-use images and the images in LRU cache, the most
+	// recently used first.
+	List() (busy []*CachedImage, idle []*CachedImage)
 }
 
 type imageCacher struct {
@@ -79,6 +84,7 @@ type imageCacher struct {
 	// reference count of images that are in-use
 	busySize uint64
 	busyRef  map[string]uint64
+	busyImgs map[string]*CachedImage
 }
 
 func NewImageCache(exemptTags []string, maxSize uint64) ImageCacher {
@@ -89,6 +95,7 @@ func NewImageCache(exemptTags []string, maxSize uint64) ImageCacher {
 		lruList:       list.New(),
 		lruMap:        make(map[string]*list.Element),
 		busyRef:       make(map[string]uint64),
+		busyImgs:      make(map[string]*CachedImage),
 	}
 
 	for _, tag := range exemptTags {
@@ -139,6 +146,7 @@ func (c *imageCacher) addBusyLocked(img *CachedImage) bool {
 	}
 
 	c.busyRef[img.ID] = 1
+	c.busyImgs[img.ID] = img
 	c.busySize += img.Size
 	return true
 }
@@ -152,6 +160,7 @@ func (c *imageCacher) rmBusyLocked(img *CachedImage) bool {
 			return false
 		}
 		delete(c.busyRef, img.ID)
+		delete(c.busyImgs, img.ID)
 		c.busySize -= img.Size
 		return true
 	}
@@ -190,6 +199,23 @@ func (c *imageCacher) GetStats() *ImageCacherStats {
 	return stats
 }
 
+func (c *imageCacher) List() ([]*CachedImage, []*CachedImage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	busy := make([]*CachedImage, 0, len(c.busyImgs))
+	for _, img := range c.busyImgs {
+		busy = append(busy, img)
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].ID < busy[j].ID })
+
+	idle := make([]*CachedImage, 0, c.lruList.Len())
+	for ee := c.lruList.Front(); ee != nil; ee = ee.Next() {
+		idle = append(idle, ee.Value.(*CachedImage))
+	}
+	return busy, idle
+}
+
 func (c *imageCacher) GetNotifier() <-chan struct{} {
 	return c.notifier
 }
@@ -250,3 +250,32 @@ func TestImageCacherBasic4(t *testing.T) {
 		t.Fatalf("cache %+v should Pop()?", inner)
 	}
 }
+
+func TestImageCacherList(t *testing.T) {
+	obj := NewImageCache([]string{"exempt"}, 100)
+
+	img1 := &CachedImage{ID: "img1", Size: 10}
+	img2 := &CachedImage{ID: "img2", Size: 20}
+	img3 := &CachedImage{ID: "img3", Size: 30}
+
+	obj.Update(img1)
+	obj.Update(img2)
+	obj.Update(&CachedImage{ID: "img4", RepoTags: []string{"exempt"}, Size: 40})
+	obj.MarkBusy(img3)
+	obj.MarkBusy(img3)
+
+	busy, idle := obj.List()
+	if len(busy) != 1 || busy[0] != img3 {
+		t.Fatalf("expected busy img3, got %+v", busy)
+	}
+	if len(idle) != 2 || idle[0] != img2 || idle[1] != img1 {
+		t.Fatalf("expected idle img2 then img1, got %+v", idle)
+	}
+
+	obj.MarkFree(img3)
+	obj.MarkFree(img3)
+	busy, idle = obj.List()
+	if len(busy) != 0 || len(idle) != 3 || idle[0] != img3 {
+		t.Fatalf("expected img3 to be the most recently used, got %+v %+v", busy, idle)
+	}
+}
@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// containerdSnapshotterType is reported in docker info driver status when the
+	// daemon uses the containerd image store, which is required for lazy pulling.
+	containerdSnapshotterType = "io.containerd.snapshotter.v1"
+
+	defaultLazyPullSnapshotter = "stargz"
+)
+
+// checkLazyPull verifies that the docker daemon stores images with a lazy pulling
+// snapshotter (eg. stargz or soci). Lazily pulled images are fetched on demand
+// by the snapshotter while the container runs, which means docker pull returns
+// after fetching only the image manifest and layer tables of contents. If the
+// daemon is not configured accordingly, lazy pulling is disabled.
+func checkLazyPull(ctx context.Context, driver *DockerDriver) bool {
+	if !driver.conf.LazyPull {
+		return false
+	}
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "checkLazyPull"})
+
+	snapshotter := driver.conf.LazyPullSnapshotter
+	if snapshotter == "" {
+		snapshotter = defaultLazyPullSnapshotter
+	}
+
+	info, err := driver.docker.Info(ctx)
+	if err != nil {
+		log.WithError(err).Error("cannot inspect docker daemon, disabling lazy pull")
+		return false
+	}
+
+	if !isLazyPullSnapshotter(info, snapshotter) {
+		log.WithFields(logrus.Fields{"storage_driver": info.Driver, "snapshotter": snapshotter}).Error("docker daemon is not configured with lazy pull snapshotter, disabling lazy pull")
+		return false
+	}
+
+	log.WithField("snapshotter", snapshotter).Info("lazy image pulling enabled")
+	return true
+}
+
+// isLazyPullSnapshotter returns true if the docker daemon uses the containerd
+// image store backed by the given snapshotter.
+func isLazyPullSnapshotter(info *docker.DockerInfo, snapshotter string) bool {
+	if info.Driver != snapshotter {
+		return false
+	}
+	for _, status := range info.DriverStatus {
+		if status[0] == "driver-type" && status[1] == containerdSnapshotterType {
+			return true
+		}
+	}
+	return false
+}
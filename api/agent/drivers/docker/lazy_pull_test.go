@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestLazyPullSnapshotter(t *testing.T) {
+	info := &docker.DockerInfo{
+		Driver:       "stargz",
+		DriverStatus: [][2]string{{"driver-type", "io.containerd.snapshotter.v1"}},
+	}
+	if !isLazyPullSnapshotter(info, "stargz") {
+		t.Fatalf("stargz containerd snapshotter should enable lazy pull")
+	}
+	if isLazyPullSnapshotter(info, "soci") {
+		t.Fatalf("mismatched snapshotter should not enable lazy pull")
+	}
+
+	info = &docker.DockerInfo{
+		Driver:       "stargz",
+		DriverStatus: [][2]string{{"Backing Filesystem", "extfs"}},
+	}
+	if isLazyPullSnapshotter(info, "stargz") {
+		t.Fatalf("graph driver should not enable lazy pull")
+	}
+
+	info = &docker.DockerInfo{
+		Driver:       "overlayfs",
+		DriverStatus: [][2]string{{"driver-type", "io.containerd.snapshotter.v1"}},
+	}
+	if isLazyPullSnapshotter(info, "stargz") {
+		t.Fatalf("overlayfs snapshotter should not enable lazy pull")
+	}
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fsouza/go-dockerclient"
+)
+
+var (
+	defaultPullRetryStatusCodes = []int{429, 500, 502, 503, 504}
+)
+
+// pullRetryPolicy determines how many times and how often a failed image
+// pull is retried before the error is reflected back to the caller.
+type pullRetryPolicy struct {
+	attempts    uint64
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	jitter      float64
+	statusCodes map[int]bool
+	rng         *rand.Rand
+}
+
+func newPullRetryPolicy(conf drivers.Config) (*pullRetryPolicy, error) {
+	policy := &pullRetryPolicy{
+		attempts:    conf.PullRetryAttempts,
+		backoff:     conf.PullRetryBackoff,
+		maxBackoff:  conf.PullRetryMaxBackoff,
+		statusCodes: make(map[int]bool),
+		rng:         common.NewRNG(time.Now().UnixNano()),
+	}
+
+	if policy.attempts == 0 {
+		policy.attempts = 1
+	}
+	if conf.PullRetryJitter > 100 {
+		return nil, fmt.Errorf("invalid pull retry jitter %d, must be a percentage between 0 and 100", conf.PullRetryJitter)
+	}
+	policy.jitter = float64(conf.PullRetryJitter) / 100
+
+	codes := strings.Fields(conf.PullRetryStatusCodes)
+	if len(codes) == 0 {
+		for _, code := range defaultPullRetryStatusCodes {
+			policy.statusCodes[code] = true
+		}
+	}
+	for _, tmp := range codes {
+		code, err := strconv.Atoi(tmp)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid pull retry status code %q", tmp)
+		}
+		policy.statusCodes[code] = true
+	}
+
+	return policy, nil
+}
+
+// isRetryable returns true if a pull failing with err should be attempted again.
+// Errors without a docker status (network failures, errors in the pull progress
+// stream) are considered transient.
+func (p *pullRetryPolicy) isRetryable(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	if dErr, ok := err.(*docker.Error); ok {
+		return p.statusCodes[dErr.Status]
+	}
+	return true
+}
+
+// delay returns the time to wait before the given retry (starting from 1),
+// doubling the backoff on each retry up to the max backoff.
+func (p *pullRetryPolicy) delay(retry uint64) time.Duration {
+	d := p.backoff
+	for i := uint64(1); i < retry && (p.maxBackoff == 0 || d < p.maxBackoff); i++ {
+		d *= 2
+	}
+	if p.maxBackoff != 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	if p.jitter > 0 {
+		d -= time.Duration(p.jitter * p.rng.Float64() * float64(d))
+	}
+	return d
+}
+
+// do runs pull until it succeeds, returns an error that is not retryable or
+// the attempts are exhausted. The last error is returned.
+func (p *pullRetryPolicy) do(ctx context.Context, pull func() error) error {
+	var err error
+	for attempt := uint64(1); ; attempt++ {
+		err = pull()
+		if err == nil || attempt >= p.attempts || !p.isRetryable(err) {
+			return err
+		}
+
+		RecordPullRetry(ctx)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.delay(attempt)):
+		}
+	}
+}
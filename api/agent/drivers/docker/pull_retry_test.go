@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestPullRetryPolicyConfig(t *testing.T) {
+	_, err := newPullRetryPolicy(drivers.Config{PullRetryJitter: 101})
+	if err == nil {
+		t.Fatalf("jitter over 100 should fail")
+	}
+
+	_, err = newPullRetryPolicy(drivers.Config{PullRetryStatusCodes: "500 abc"})
+	if err == nil {
+		t.Fatalf("invalid status code should fail")
+	}
+
+	policy, err := newPullRetryPolicy(drivers.Config{PullRetryStatusCodes: "404"})
+	if err != nil {
+		t.Fatalf("policy config failed: %s", err)
+	}
+	if !policy.isRetryable(&docker.Error{Status: 404}) {
+		t.Fatalf("404 should be retryable when configured")
+	}
+	if policy.isRetryable(&docker.Error{Status: 500}) {
+		t.Fatalf("500 should not be retryable when not configured")
+	}
+	if policy.isRetryable(context.DeadlineExceeded) {
+		t.Fatalf("context errors should never be retryable")
+	}
+	if !policy.isRetryable(errors.New("connection reset")) {
+		t.Fatalf("non docker errors should be retryable")
+	}
+}
+
+func TestPullRetryPolicyDelay(t *testing.T) {
+	policy, err := newPullRetryPolicy(drivers.Config{
+		PullRetryBackoff:    100 * time.Millisecond,
+		PullRetryMaxBackoff: 500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("policy config failed: %s", err)
+	}
+
+	for retry, exp := range []time.Duration{100, 200, 400, 500, 500} {
+		exp *= time.Millisecond
+		if d := policy.delay(uint64(retry + 1)); d != exp {
+			t.Fatalf("retry %d delay %v != %v", retry+1, d, exp)
+		}
+	}
+
+	policy.jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := policy.delay(1)
+		if d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+}
+
+func TestPullRetryPolicyDo(t *testing.T) {
+	policy, err := newPullRetryPolicy(drivers.Config{
+		PullRetryAttempts: 3,
+		PullRetryBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("policy config failed: %s", err)
+	}
+
+	ctx := context.Background()
+
+	var count int
+	err = policy.do(ctx, func() error {
+		count++
+		return &docker.Error{Status: 503}
+	})
+	if err == nil || count != 3 {
+		t.Fatalf("expected 3 failed attempts, got %d err=%v", count, err)
+	}
+
+	count = 0
+	err = policy.do(ctx, func() error {
+		count++
+		if count < 2 {
+			return &docker.Error{Status: 502}
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("expected success on attempt 2, got %d err=%v", count, err)
+	}
+
+	count = 0
+	err = policy.do(ctx, func() error {
+		count++
+		return &docker.Error{Status: 401}
+	})
+	if err == nil || count != 1 {
+		t.Fatalf("expected no retries for 401, got %d err=%v", count, err)
+	}
+}
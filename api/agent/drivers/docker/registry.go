@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"fmt"
 	"net/url"
 	"os"
 	"strings"
@@ -11,8 +12,52 @@ import (
 
 var (
 	defaultPrivateRegistries = []string{"hub.docker.com", "index.docker.io"}
+	dockerHubRegistries      = []string{"docker.io", "index.docker.io", "registry-1.docker.io", "hub.docker.com"}
 )
 
+// dockerHubRegistry is the key used in registry mirror config for images
+// that do not specify a registry in their name
+const dockerHubRegistry = "docker.io"
+
+// parseRegistryMirrors parses whitespace separated upstream=mirror pairs, eg.
+// "docker.io=mirror.local:5000 quay.io=quay-cache.local". Any docker hub alias
+// used as the upstream is normalized to docker.io.
+func parseRegistryMirrors(conf string) (map[string]string, error) {
+	mirrors := make(map[string]string)
+
+	for _, pair := range strings.Fields(conf) {
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+			return nil, fmt.Errorf("invalid registry mirror %q, expected upstream=mirror", pair)
+		}
+
+		upstream := tokens[0]
+		for _, reg := range dockerHubRegistries {
+			if upstream == reg {
+				upstream = dockerHubRegistry
+				break
+			}
+		}
+		mirrors[upstream] = strings.TrimSuffix(tokens[1], "/")
+	}
+	return mirrors, nil
+}
+
+// findRegistryMirror returns the mirror configured for an image registry as
+// returned by drivers.ParseImage, or an empty string if there isn't one.
+func findRegistryMirror(reg string, mirrors map[string]string) string {
+	if reg == "" {
+		reg = dockerHubRegistry
+	}
+	for _, hub := range dockerHubRegistries {
+		if reg == hub {
+			reg = dockerHubRegistry
+			break
+		}
+	}
+	return mirrors[reg]
+}
+
 func registryFromEnv() (map[string]driverAuthConfig, error) {
 	var auths *docker.AuthConfigurations
 	var err error
@@ -111,3 +111,40 @@ func TestRegistryEnv(t *testing.T) {
 	}
 
 }
+
+func TestRegistryMirrors(t *testing.T) {
+	mirrors, err := parseRegistryMirrors("index.docker.io=mirror.local:5000/ quay.io=quay-cache.local")
+	if err != nil {
+		t.Fatalf("parsing mirrors failed: %s", err)
+	}
+
+	res := findRegistryMirror("", mirrors)
+	if res != "mirror.local:5000" {
+		t.Fatalf("empty registry should pickup docker hub mirror %v", res)
+	}
+
+	res = findRegistryMirror("docker.io", mirrors)
+	if res != "mirror.local:5000" {
+		t.Fatalf("docker.io registry should pickup docker hub mirror %v", res)
+	}
+
+	res = findRegistryMirror("quay.io", mirrors)
+	if res != "quay-cache.local" {
+		t.Fatalf("quay.io registry should pickup quay-cache.local mirror %v", res)
+	}
+
+	res = findRegistryMirror("my.registry.com", mirrors)
+	if res != "" {
+		t.Fatalf("my.registry.com registry should not be mirrored %v", res)
+	}
+
+	_, err = parseRegistryMirrors("docker.io")
+	if err == nil {
+		t.Fatalf("mirror without upstream=mirror pair should fail")
+	}
+
+	_, err = parseRegistryMirrors("docker.io=")
+	if err == nil {
+		t.Fatalf("mirror with empty mirror should fail")
+	}
+}
@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// snapshotRepository is the repository all container snapshot images are committed to
+	snapshotRepository = "fn-snapshot"
+
+	// FnSnapshotBaseLabel records the id of the image a snapshot was committed from
+	FnSnapshotBaseLabel = "fn-snapshot-base"
+)
+
+// snapshot is an image committed from an initialized container
+type snapshot struct {
+	// image reference of the committed snapshot
+	image string
+	// id of the image the snapshotted container was created from
+	baseID string
+}
+
+// snapshotStore keeps track of container snapshots per image and configuration. A
+// container created from a snapshot skips the bootstrap work the function does on
+// its filesystem during initialization. Only file system changes are captured,
+// which means snapshots are only useful if the root file system is writable.
+//
+// The key of the latest snapshot of each function is tracked as well, a deploy of
+// a new image or config changes the key and leaves the previous snapshot unused.
+type snapshotStore struct {
+	lock      sync.Mutex
+	snapshots map[string]*snapshot
+	pending   map[string]bool
+	fns       map[string]string
+}
+
+func newSnapshotStore() *snapshotStore {
+	return &snapshotStore{
+		snapshots: make(map[string]*snapshot),
+		pending:   make(map[string]bool),
+		fns:       make(map[string]string),
+	}
+}
+
+// snapshotKey returns the key of the snapshot for a task image and environment
+func snapshotKey(image string, env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(image))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(env[k]))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// get returns the snapshot for key if it was committed from the image with
+// baseID. A snapshot of a different base image is stale and is returned as
+// such, so that the caller can remove it.
+func (s *snapshotStore) get(key, baseID string) (current, stale *snapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snap, ok := s.snapshots[key]
+	if !ok {
+		return nil, nil
+	}
+	if snap.baseID != baseID {
+		delete(s.snapshots, key)
+		return nil, snap
+	}
+	return snap, nil
+}
+
+// reserve marks a snapshot for key as in progress, returns false if a snapshot
+// already exists or is being committed.
+func (s *snapshotStore) reserve(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pending[key] {
+		return false
+	}
+	if _, ok := s.snapshots[key]; ok {
+		return false
+	}
+	s.pending[key] = true
+	return true
+}
+
+// release completes a reservation, recording the snapshot if it is not nil as the
+// latest snapshot of fnID. The previous snapshot of fnID is forgotten and returned
+// if its key differs and no other function uses it, so that the caller can remove it.
+func (s *snapshotStore) release(key, fnID string, snap *snapshot) (old *snapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.pending, key)
+	if snap == nil {
+		return nil
+	}
+	s.snapshots[key] = snap
+
+	if fnID == "" {
+		return nil
+	}
+	prev, ok := s.fns[fnID]
+	s.fns[fnID] = key
+	if !ok || prev == key {
+		return nil
+	}
+	for _, k := range s.fns {
+		if k == prev {
+			return nil
+		}
+	}
+	old = s.snapshots[prev]
+	delete(s.snapshots, prev)
+	return old
+}
+
+// remove forgets the snapshot for key if it is still the given snapshot
+func (s *snapshotStore) remove(key string, snap *snapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.snapshots[key] == snap {
+		delete(s.snapshots, key)
+	}
+}
+
+// removeSnapshotImage removes a snapshot image from docker
+func removeSnapshotImage(ctx context.Context, driver *DockerDriver, snap *snapshot) {
+	const removeImgTimeout = time.Duration(60 * time.Second)
+
+	ctx, cancel := context.WithTimeout(ctx, removeImgTimeout)
+	defer cancel()
+
+	err := driver.docker.RemoveImage(snap.image, docker.RemoveImageOptions{Context: ctx})
+	if err != nil && err != docker.ErrNoSuchImage {
+		common.Logger(ctx).WithError(err).WithField("image", snap.image).Error("Removing snapshot image failed")
+	}
+}
+
+// cleanSnapshots removes snapshot images left over by previous agent runs. Snapshot
+// state is not persisted, so these images would never be used again. This operation
+// is executed once and if it fails, it will not retry the procedure.
+func cleanSnapshots(ctx context.Context, driver *DockerDriver) {
+	if driver.snapshots == nil {
+		return
+	}
+
+	const imageListTimeout = time.Duration(60 * time.Second)
+
+	ctx, log := common.LoggerWithFields(ctx, logrus.Fields{"stack": "cleanSnapshots"})
+	limiter := rate.NewLimiter(2.0, 1)
+
+	var images []docker.APIImages
+	for limiter.Wait(ctx) == nil {
+		var err error
+		ctx, cancel := context.WithTimeout(ctx, imageListTimeout)
+		images, err = driver.docker.ListImages(docker.ListImagesOptions{
+			Filters: map[string][]string{
+				"reference": []string{snapshotRepository},
+			},
+			Context: ctx,
+		})
+		cancel()
+		if err == nil {
+			break
+		}
+
+		log.WithError(err).Error("ListImages error, will retry...")
+	}
+
+	for _, img := range images {
+		for _, tag := range img.RepoTags {
+			log.WithField("image", tag).Info("Removing stale snapshot image")
+			removeSnapshotImage(ctx, driver, &snapshot{image: tag})
+		}
+	}
+}
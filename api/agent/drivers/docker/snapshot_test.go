@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"testing"
+)
+
+func TestSnapshotKey(t *testing.T) {
+	k1 := snapshotKey("busybox", map[string]string{"A": "1", "B": "2"})
+	k2 := snapshotKey("busybox", map[string]string{"B": "2", "A": "1"})
+	if k1 != k2 {
+		t.Fatalf("snapshot key should not depend on env order %s != %s", k1, k2)
+	}
+
+	k3 := snapshotKey("busybox", map[string]string{"A": "1", "B": "3"})
+	if k1 == k3 {
+		t.Fatalf("snapshot key should depend on env values")
+	}
+
+	k4 := snapshotKey("busybox:1.0", map[string]string{"A": "1", "B": "2"})
+	if k1 == k4 {
+		t.Fatalf("snapshot key should depend on image")
+	}
+}
+
+func TestSnapshotStore(t *testing.T) {
+	store := newSnapshotStore()
+
+	if !store.reserve("key") {
+		t.Fatalf("reserve should succeed on empty store")
+	}
+	if store.reserve("key") {
+		t.Fatalf("reserve should fail while snapshot is pending")
+	}
+
+	snap := &snapshot{image: "fn-snapshot:key", baseID: "base1"}
+	store.release("key", "fn1", snap)
+
+	if store.reserve("key") {
+		t.Fatalf("reserve should fail if snapshot exists")
+	}
+
+	current, stale := store.get("key", "base1")
+	if current != snap || stale != nil {
+		t.Fatalf("expected current snapshot, got current=%v stale=%v", current, stale)
+	}
+
+	current, stale = store.get("key", "base2")
+	if current != nil || stale != snap {
+		t.Fatalf("expected stale snapshot for new base image, got current=%v stale=%v", current, stale)
+	}
+
+	current, stale = store.get("key", "base1")
+	if current != nil || stale != nil {
+		t.Fatalf("stale snapshot should be forgotten, got current=%v stale=%v", current, stale)
+	}
+
+	if !store.reserve("key") {
+		t.Fatalf("reserve should succeed after stale snapshot is dropped")
+	}
+	store.release("key", "fn1", nil)
+	if !store.reserve("key") {
+		t.Fatalf("reserve should succeed after failed snapshot")
+	}
+	store.release("key", "fn1", snap)
+
+	store.remove("key", &snapshot{image: "other"})
+	if current, _ := store.get("key", "base1"); current != snap {
+		t.Fatalf("remove of another snapshot should not forget current snapshot")
+	}
+	store.remove("key", snap)
+	if current, _ := store.get("key", "base1"); current != nil {
+		t.Fatalf("remove should forget snapshot")
+	}
+}
+
+func TestSnapshotStoreReplacesFnSnapshot(t *testing.T) {
+	store := newSnapshotStore()
+	env := map[string]string{"FN_FN_ID": "fn1", "A": "1"}
+
+	oldKey := snapshotKey("busybox:1.0", env)
+	if !store.reserve(oldKey) {
+		t.Fatalf("reserve should succeed on empty store")
+	}
+	oldSnap := &snapshot{image: snapshotRepository + ":" + oldKey, baseID: "base1"}
+	if old := store.release(oldKey, "fn1", oldSnap); old != nil {
+		t.Fatalf("first snapshot of a fn should not replace anything, got %v", old)
+	}
+
+	// another fn with the same image doesn't replace the snapshot of fn1
+	otherKey := snapshotKey("busybox:1.0", map[string]string{"FN_FN_ID": "fn2", "A": "1"})
+	store.reserve(otherKey)
+	otherSnap := &snapshot{image: snapshotRepository + ":" + otherKey, baseID: "base1"}
+	if old := store.release(otherKey, "fn2", otherSnap); old != nil {
+		t.Fatalf("snapshot of another fn should not replace anything, got %v", old)
+	}
+
+	// fn1 is deployed with a new image tag
+	newKey := snapshotKey("busybox:1.1", env)
+	if !store.reserve(newKey) {
+		t.Fatalf("reserve should succeed for new image tag")
+	}
+	newSnap := &snapshot{image: snapshotRepository + ":" + newKey, baseID: "base2"}
+	if old := store.release(newKey, "fn1", newSnap); old != oldSnap {
+		t.Fatalf("expected previous snapshot to be removed, got %v", old)
+	}
+
+	if current, stale := store.get(oldKey, "base1"); current != nil || stale != nil {
+		t.Fatalf("previous snapshot should be forgotten, got current=%v stale=%v", current, stale)
+	}
+	if current, _ := store.get(newKey, "base2"); current != newSnap {
+		t.Fatalf("expected new snapshot, got %v", current)
+	}
+	if current, _ := store.get(otherKey, "base1"); current != otherSnap {
+		t.Fatalf("snapshot of another fn should be kept, got %v", current)
+	}
+
+	// a failed snapshot keeps the current one
+	lastKey := snapshotKey("busybox:1.2", env)
+	store.reserve(lastKey)
+	if old := store.release(lastKey, "fn1", nil); old != nil {
+		t.Fatalf("failed snapshot should not replace anything, got %v", old)
+	}
+	if current, _ := store.get(newKey, "base2"); current != newSnap {
+		t.Fatalf("failed snapshot should keep current snapshot, got %v", current)
+	}
+}
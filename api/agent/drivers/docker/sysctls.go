@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"strings"
+)
+
+// sysctlAllowlist holds the kernel parameters functions may set in their containers.
+// Entries are either exact parameter names or prefixes ending with '*', eg. 'net.ipv4.*'
+type sysctlAllowlist struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+// newSysctlAllowlist parses a whitespace separated list of allowed kernel parameters
+func newSysctlAllowlist(conf string) *sysctlAllowlist {
+	allow := &sysctlAllowlist{
+		names: make(map[string]bool),
+	}
+
+	for _, entry := range strings.Fields(conf) {
+		if strings.HasSuffix(entry, "*") {
+			allow.prefixes = append(allow.prefixes, strings.TrimSuffix(entry, "*"))
+		} else {
+			allow.names[entry] = true
+		}
+	}
+	return allow
+}
+
+func (a *sysctlAllowlist) isAllowed(name string) bool {
+	if a == nil {
+		return false
+	}
+	if a.names[name] {
+		return true
+	}
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestSysctlAllowlist(t *testing.T) {
+	allow := newSysctlAllowlist("net.core.somaxconn  net.ipv4.tcp_*")
+
+	for name, expected := range map[string]bool{
+		"net.core.somaxconn":       true,
+		"net.ipv4.tcp_fin_timeout": true,
+		"net.core.rmem_max":        false,
+		"net.ipv4.ip_forward":      false,
+		"kernel.shmmax":            false,
+	} {
+		if allow.isAllowed(name) != expected {
+			t.Fatalf("sysctl %s expected allowed=%v", name, expected)
+		}
+	}
+
+	var empty *sysctlAllowlist
+	if empty.isAllowed("net.core.somaxconn") {
+		t.Fatalf("nil allowlist should not allow any sysctl")
+	}
+}
+
+func TestCookieSecurity(t *testing.T) {
+	drv := &DockerDriver{
+		conf:    drivers.Config{EnableNoNewPrivs: true},
+		network: NewDockerNetworks(drivers.Config{}),
+		sysctls: newSysctlAllowlist("net.core.somaxconn"),
+	}
+
+	ctx := context.Background()
+	task := &taskDockerTest{
+		id:      "test-docker-security",
+		sysctls: map[string]string{"net.core.somaxconn": "4096", "kernel.shmmax": "1"},
+	}
+
+	cookie, err := drv.CreateCookie(ctx, task)
+	if err != nil {
+		t.Fatalf("Couldn't create task cookie: %v", err)
+	}
+	defer cookie.Close(ctx)
+
+	opts := cookie.ContainerOptions().(docker.CreateContainerOptions)
+	if len(opts.HostConfig.SecurityOpt) != 1 || opts.HostConfig.SecurityOpt[0] != "no-new-privileges" {
+		t.Fatalf("expected no-new-privileges security option, got %v", opts.HostConfig.SecurityOpt)
+	}
+	if len(opts.HostConfig.Sysctls) != 1 || opts.HostConfig.Sysctls["net.core.somaxconn"] != "4096" {
+		t.Fatalf("expected only allowed sysctls, got %v", opts.HostConfig.Sysctls)
+	}
+}
@@ -59,6 +59,80 @@ type Cookie interface {
 	ContainerOptions() interface{}
 }
 
+// Snapshotter may be implemented by a Cookie which is able to snapshot its
+// container once the container is initialized, so that subsequent containers
+// for the same task can be created from the snapshot.
+type Snapshotter interface {
+	// Snapshot records the state of an initialized container. It should be
+	// called at most once, after the container signals readiness and before
+	// it processes any requests.
+	Snapshot(ctx context.Context) error
+}
+
+// Terminator may be implemented by a Cookie which is able to ask its container to
+// stop gracefully, giving it a chance to clean up before it is removed.
+type Terminator interface {
+	// Terminate sends SIGTERM to the container. Closing the cookie still kills the
+	// container if it did not exit by then.
+	Terminate(ctx context.Context) error
+}
+
+// ImageDigester may be implemented by a Cookie which knows the content digest of the
+// image its container runs, once the image has been validated.
+type ImageDigester interface {
+	ImageDigest() string
+}
+
+// RegistryAuthTimer may be implemented by a Cookie which authenticates with the registry
+// of its image while pulling it, to tell how long that took of PullImage.
+type RegistryAuthTimer interface {
+	RegistryAuthDuration() time.Duration
+}
+
+// ContainerListener is invoked by a Driver before it creates a container, it has
+// the method set of fnext.ContainerListener which cannot be imported here.
+type ContainerListener interface {
+	BeforeContainerCreate(ctx context.Context, opts interface{}) error
+}
+
+// ContainerListenerAdder may be implemented by a Driver which invokes
+// ContainerListener hooks before it creates containers.
+type ContainerListenerAdder interface {
+	AddContainerListener(listener ContainerListener)
+}
+
+// NetworkPool may be implemented by a Driver which allocates containers across a
+// set of networks, allowing the set to be changed without restarting the driver.
+type NetworkPool interface {
+	// Networks returns the networks in the pool with the number of containers
+	// currently allocated to each.
+	Networks() map[string]uint64
+
+	// AddNetwork adds an existing network to the pool.
+	AddNetwork(ctx context.Context, id string) error
+
+	// RemoveNetwork removes a network from the pool. Containers already
+	// allocated to the network are not affected.
+	RemoveNetwork(ctx context.Context, id string) error
+}
+
+// ImageCacheEntry is an image kept in the image cache of a Driver.
+type ImageCacheEntry struct {
+	ID    string
+	Tags  []string
+	Size  uint64
+	InUse bool
+}
+
+// ImageCache may be implemented by a Driver which keeps the images of functions in a
+// cache bounded in size, evicting the least recently used images no container uses.
+type ImageCache interface {
+	// CachedImages returns the images in the cache, in use first then the most recently
+	// used first, with the size in bytes the cache is bounded to. It returns false if
+	// the cache is disabled.
+	CachedImages() (images []ImageCacheEntry, maxSize uint64, ok bool)
+}
+
 type WaitResult interface {
 	// Wait may be called to await the result of a container's execution. If the
 	// provided context is canceled and the container does not return first, the
@@ -103,6 +177,30 @@ type LoggerConfig struct {
 
 	// Log Tag Pairs
 	Tags []LoggerTag
+
+	// Format of the syslog messages, rfc5424 if empty, see models.SyslogFormats
+	Format string
+
+	// TLS configures the connection to a tcp+tls URL, its certificates are verified against
+	// the system roots if nil
+	TLS *LoggerTLSConfig
+
+	// BufferSize is the number of bytes of log lines buffered while the sink is slow or
+	// reconnecting, instead of blocking the container writing them. Lines are dropped once
+	// it is full. Lines are not buffered if 0.
+	BufferSize uint64
+}
+
+// LoggerTLSConfig holds the paths, on the host of the containers, of the certificates of the
+// TLS connection to a log sink
+type LoggerTLSConfig struct {
+	// CACert is the CA certificate the certificate of the sink is verified against
+	CACert string
+	// Cert and Key are the client certificate and its key, for sinks authenticating clients
+	Cert string
+	Key  string
+	// SkipVerify disables the verification of the certificate of the sink
+	SkipVerify bool
 }
 
 // The ContainerTask interface guides container execution across a wide variety of
@@ -178,6 +276,10 @@ type ContainerTask interface {
 
 	// Returns true if network is disabled.
 	DisableNet() bool
+
+	// Sysctls returns the namespaced kernel parameters to set in the container,
+	// drivers may ignore parameters that are not allowed.
+	Sysctls() map[string]string
 }
 
 // Stat is a bucket of stats from a driver at a point in time for a certain task.
@@ -260,6 +362,29 @@ type Config struct {
 	ImageCleanMaxSize    uint64 `json:"image_clean_max_size"`
 	ImageCleanExemptTags string `json:"image_clean_exempt_tags"`
 	ImageEnableVolume    bool   `json:"image_enable_volume"`
+	RegistryMirrors      string `json:"registry_mirrors"`
+
+	PullRetryAttempts    uint64        `json:"pull_retry_attempts"`
+	PullRetryBackoff     time.Duration `json:"pull_retry_backoff"`
+	PullRetryMaxBackoff  time.Duration `json:"pull_retry_max_backoff"`
+	PullRetryJitter      uint64        `json:"pull_retry_jitter"`
+	PullRetryStatusCodes string        `json:"pull_retry_status_codes"`
+	SyslogRetryAttempts  uint64        `json:"syslog_retry_attempts"`
+	SyslogRetryBackoff   time.Duration `json:"syslog_retry_backoff"`
+	LazyPull             bool          `json:"lazy_pull"`
+	LazyPullSnapshotter  string        `json:"lazy_pull_snapshotter"`
+	EnableSnapshots      bool          `json:"enable_snapshots"`
+	DockerAPIVersion     string        `json:"docker_api_version"`
+	DockerMaxIdleConns   uint64        `json:"docker_max_idle_conns"`
+	Runtime              string        `json:"runtime"`
+	PreForkMaxSize       uint64        `json:"pre_fork_max_size"`
+	PreForkHighWatermark uint64        `json:"pre_fork_high_watermark"`
+	PreForkLowWatermark  uint64        `json:"pre_fork_low_watermark"`
+	PreForkScaleInterval time.Duration `json:"pre_fork_scale_interval"`
+	EnableNoNewPrivs     bool          `json:"enable_no_new_privileges"`
+	AllowedSysctls       string        `json:"allowed_sysctls"`
+	CgroupVersion        uint64        `json:"cgroup_version"`
+	CPUPinningThreshold  uint64        `json:"cpu_pinning_threshold"`
 }
 
 func average(samples []Stat) (Stat, bool) {
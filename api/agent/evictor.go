@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fnproject/fn/api/id"
 
@@ -26,6 +28,7 @@ type tokenKey struct {
 type EvictToken struct {
 	key       tokenKey
 	evictable uint32
+	pausedAt  int64 // unix nanos, 0 if not paused
 	C         chan struct{}
 	DoneChan  chan struct{}
 }
@@ -42,6 +45,15 @@ type Evictor interface {
 	// and returns a slice of channels for evictions performed. The callers
 	// can wait on these channel to ensure evictions are completed.
 	PerformEviction(slotId string, mem, cpu uint64) []chan struct{}
+
+	// PerformReclaim evicts containers until at least mem is reclaimed, or evicts
+	// every evictable container if that is not enough. Unlike PerformEviction, it
+	// evicts what it can. Returns a slice of channels for evictions performed.
+	PerformReclaim(mem uint64) []chan struct{}
+
+	// PerformReclaimPaused is like PerformReclaim, but only evicts paused containers,
+	// the ones paused the longest first.
+	PerformReclaimPaused(mem uint64) []chan struct{}
 }
 
 type evictor struct {
@@ -76,6 +88,16 @@ func (token *EvictToken) SetEvictable(isEvictable bool) {
 	atomic.StoreUint32(&token.evictable, val)
 }
 
+// SetPaused records whether the container of the token is paused, and since when.
+func (token *EvictToken) SetPaused(isPaused bool) {
+	val := int64(0)
+	if isPaused {
+		val = time.Now().UnixNano()
+	}
+
+	atomic.StoreInt64(&token.pausedAt, val)
+}
+
 func (tok *EvictToken) isEligible() bool {
 	// if no resource limits are in place, then this
 	// function is not eligible.
@@ -182,29 +204,50 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 
 	// If we can satisfy the need, then let's commit/perform eviction
 	if isSatisfied {
+		notifyChans, completionChans = e.evictLocked(keys)
+	}
 
-		notifyChans = make([]chan struct{}, 0, len(keys))
-		completionChans = make([]chan struct{}, 0, len(keys))
+	e.lock.Unlock()
+
+	for _, ch := range notifyChans {
+		close(ch)
+	}
 
-		idx := 0
-		for _, id := range keys {
+	return completionChans
+}
 
-			// do not initialize idx, we continue where we left off
-			// since keys are in order from above.
-			for ; idx < len(e.slots); idx++ {
-				if id == e.slots[idx].id {
-					e.slots = append(e.slots[:idx], e.slots[idx+1:]...)
-					break
-				}
-			}
+func (e *evictor) PerformReclaim(mem uint64) []chan struct{} {
+	var notifyChans []chan struct{}
+	var completionChans []chan struct{}
 
-			notifyChans = append(notifyChans, e.tokens[id].C)
-			completionChans = append(completionChans, e.tokens[id].DoneChan)
+	if mem == 0 {
+		return completionChans
+	}
 
-			delete(e.tokens, id)
+	totalMemory := uint64(0)
+	var keys []string
+
+	e.lock.Lock()
+
+	for _, val := range e.slots {
+		// containers without memory limits reclaim nothing we know of
+		if val.memory == 0 {
+			continue
+		}
+		if atomic.LoadUint32(&e.tokens[val.id].evictable) == 0 {
+			continue
+		}
+
+		totalMemory += val.memory
+		keys = append(keys, val.id)
+
+		if totalMemory >= mem {
+			break
 		}
 	}
 
+	notifyChans, completionChans = e.evictLocked(keys)
+
 	e.lock.Unlock()
 
 	for _, ch := range notifyChans {
@@ -213,3 +256,89 @@ func (e *evictor) PerformEviction(slotId string, mem, cpu uint64) []chan struct{
 
 	return completionChans
 }
+
+func (e *evictor) PerformReclaimPaused(mem uint64) []chan struct{} {
+	var notifyChans []chan struct{}
+	var completionChans []chan struct{}
+
+	if mem == 0 {
+		return completionChans
+	}
+
+	type candidate struct {
+		idx      int
+		pausedAt int64
+	}
+	var candidates []candidate
+
+	e.lock.Lock()
+
+	for idx, val := range e.slots {
+		if val.memory == 0 {
+			continue
+		}
+		token := e.tokens[val.id]
+		if atomic.LoadUint32(&token.evictable) == 0 {
+			continue
+		}
+		pausedAt := atomic.LoadInt64(&token.pausedAt)
+		if pausedAt == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{idx: idx, pausedAt: pausedAt})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].pausedAt < candidates[j].pausedAt })
+
+	totalMemory := uint64(0)
+	n := 0
+	for n < len(candidates) && totalMemory < mem {
+		totalMemory += e.slots[candidates[n].idx].memory
+		n++
+	}
+
+	// evictLocked expects keys in the order of slots
+	candidates = candidates[:n]
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].idx < candidates[j].idx })
+	keys := make([]string, 0, n)
+	for _, c := range candidates {
+		keys = append(keys, e.slots[c.idx].id)
+	}
+
+	notifyChans, completionChans = e.evictLocked(keys)
+
+	e.lock.Unlock()
+
+	for _, ch := range notifyChans {
+		close(ch)
+	}
+
+	return completionChans
+}
+
+// evictLocked removes the tokens of keys from tracking and returns their eviction
+// and completion channels, the caller must close eviction channels once unlocked.
+func (e *evictor) evictLocked(keys []string) ([]chan struct{}, []chan struct{}) {
+	notifyChans := make([]chan struct{}, 0, len(keys))
+	completionChans := make([]chan struct{}, 0, len(keys))
+
+	idx := 0
+	for _, id := range keys {
+
+		// do not initialize idx, we continue where we left off
+		// since keys are in order from above.
+		for ; idx < len(e.slots); idx++ {
+			if id == e.slots[idx].id {
+				e.slots = append(e.slots[:idx], e.slots[idx+1:]...)
+				break
+			}
+		}
+
+		notifyChans = append(notifyChans, e.tokens[id].C)
+		completionChans = append(completionChans, e.tokens[id].DoneChan)
+
+		delete(e.tokens, id)
+	}
+
+	return notifyChans, completionChans
+}
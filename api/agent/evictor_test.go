@@ -2,6 +2,7 @@ package agent
 
 import (
 	"testing"
+	"time"
 )
 
 func getACall(slot string, mem, cpu int) (string, uint64, uint64) {
@@ -141,3 +142,97 @@ func TestEvictorSimple03(t *testing.T) {
 	evictor.DeleteEvictToken(token2)
 	evictor.DeleteEvictToken(token3)
 }
+
+func TestEvictorReclaim(t *testing.T) {
+	evictor := NewEvictor()
+
+	token0 := evictor.CreateEvictToken("slot0", 64, 100)
+	token1 := evictor.CreateEvictToken("slot1", 0, 100)
+	token2 := evictor.CreateEvictToken("slot2", 128, 100)
+	token3 := evictor.CreateEvictToken("slot3", 128, 100)
+
+	token1.SetEvictable(true)
+	token2.SetEvictable(true)
+	token3.SetEvictable(true)
+
+	if len(evictor.PerformReclaim(0)) > 0 {
+		t.Fatalf("We should not evict to reclaim nothing")
+	}
+
+	// not enough to reclaim, but evict what we can
+	if len(evictor.PerformReclaim(512)) != 2 {
+		t.Fatalf("We should be able to evict")
+	}
+
+	// busy containers and containers without memory limits are not evicted
+	if token0.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+	if token1.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+	if !token2.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+	if !token3.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+
+	evictor.DeleteEvictToken(token0)
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+	evictor.DeleteEvictToken(token3)
+}
+
+func TestEvictorReclaimPaused(t *testing.T) {
+	evictor := NewEvictor()
+
+	token0 := evictor.CreateEvictToken("slot0", 64, 100)
+	token1 := evictor.CreateEvictToken("slot1", 128, 100)
+	token2 := evictor.CreateEvictToken("slot2", 128, 100)
+	token3 := evictor.CreateEvictToken("slot3", 128, 100)
+
+	token0.SetEvictable(true)
+	token1.SetEvictable(true)
+	token2.SetEvictable(true)
+	token3.SetEvictable(true)
+
+	// paused in reverse order of registration, token0 is idle but not paused
+	token3.SetPaused(true)
+	time.Sleep(time.Millisecond)
+	token2.SetPaused(true)
+	time.Sleep(time.Millisecond)
+	token1.SetPaused(true)
+
+	if len(evictor.PerformReclaimPaused(0)) > 0 {
+		t.Fatalf("We should not evict to reclaim nothing")
+	}
+
+	// the containers paused the longest go first
+	if len(evictor.PerformReclaimPaused(200)) != 2 {
+		t.Fatalf("We should be able to evict")
+	}
+	if token1.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+	if !token2.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+	if !token3.isEvicted() {
+		t.Fatalf("should be evicted")
+	}
+
+	// unpaused containers are not evicted
+	token1.SetPaused(false)
+	if len(evictor.PerformReclaimPaused(512)) > 0 {
+		t.Fatalf("We should not be able to evict")
+	}
+	if token0.isEvicted() || token1.isEvicted() {
+		t.Fatalf("should not be evicted")
+	}
+
+	evictor.DeleteEvictToken(token0)
+	evictor.DeleteEvictToken(token1)
+	evictor.DeleteEvictToken(token2)
+	evictor.DeleteEvictToken(token3)
+}
@@ -3,9 +3,11 @@ package agent
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
@@ -20,12 +22,13 @@ var (
 // setupLogger returns a ReadWriteCloser that may have:
 // * [always] writes bytes to a size limited buffer, that can be read from using io.Reader
 // * [always] writes bytes per line to stderr as DEBUG
+// * [tail != nil] publishes lines to the tails of the fn of the call, while it has any
 //
 // To prevent write failures from failing the call or any other writes,
 // multiWriteCloser ignores errors. Close will flush the line writers
 // appropriately.  The returned io.ReadWriteCloser is not safe for use after
 // calling Close.
-func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call) io.ReadWriteCloser {
+func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call, tail *logTail) io.ReadWriteCloser {
 	lbuf := bufPool.Get().(*bytes.Buffer)
 	dbuf := logPool.Get().(*bytes.Buffer)
 
@@ -39,10 +42,11 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 	}
 
 	// we don't need to log per line to db, but we do need to limit it
-	limitw := &nopCloser{newLimitWriter(int(maxSize), dbuf)}
+	limit := newLimitWriter(int(maxSize), dbuf)
+	limitw := &nopCloser{limit}
 
 	// order matters, in that closer should be last and limit should be next to last
-	mw := make(multiWriteCloser, 0, 3)
+	mw := make(multiWriteCloser, 0, 4)
 
 	if debug {
 		// accumulate all line writers, wrap in same line writer (to re-use buffer)
@@ -54,8 +58,12 @@ func setupLogger(ctx context.Context, maxSize uint64, debug bool, c *models.Call
 		mw = append(mw, linew)
 	}
 
+	if tail != nil {
+		mw = append(mw, newTailWriter(tail, c.FnID, c.ID))
+	}
+
 	mw = append(mw, limitw, &fCloser{close})
-	return &rwc{mw, dbuf}
+	return &rwc{mw, dbuf, limit}
 }
 
 // implements io.ReadWriteCloser, fmt.Stringer and Bytes()
@@ -67,11 +75,17 @@ type rwc struct {
 	// buffer is not embedded since it would bypass calls to WriteCloser.Write
 	// in cases such as WriteString and ReadFrom
 	b *bytes.Buffer
+
+	limit *limitDiscardWriter
 }
 
 func (r *rwc) Read(b []byte) (int, error) { return r.b.Read(b) }
 func (r *rwc) String() string             { return r.b.String() }
 func (r *rwc) Bytes() []byte              { return r.b.Bytes() }
+func (r *rwc) Len() int                   { return r.b.Len() }
+
+// Dropped returns the number of bytes dropped once the log reached its maximum size
+func (r *rwc) Dropped() int64 { return r.limit.dropped }
 
 // implements passthrough Write & closure call in Close
 type fCloser struct {
@@ -175,26 +189,112 @@ func (li *lineWriter) Close() error {
 	return err
 }
 
+// entryWriter writes each line written to it to w as a json models.LogEntry of stream, in
+// a single Write per line. Close must be called to flush the last line if it has no newline.
+type entryWriter struct {
+	b      bytes.Buffer
+	w      io.Writer
+	stream string
+}
+
+func newEntryWriter(w io.Writer, stream string) *entryWriter {
+	return &entryWriter{w: w, stream: stream}
+}
+
+func (e *entryWriter) Write(p []byte) (int, error) {
+	e.b.Write(p)
+	for {
+		i := bytes.IndexByte(e.b.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		e.writeEntry(e.b.Next(i + 1)[:i])
+	}
+}
+
+func (e *entryWriter) writeEntry(line []byte) {
+	b, err := json.Marshal(models.NewLogEntry(time.Now(), e.stream, line))
+	if err != nil {
+		return
+	}
+	e.w.Write(append(b, '\n'))
+}
+
+func (e *entryWriter) Close() error {
+	if e.b.Len() > 0 {
+		e.writeEntry(e.b.Bytes())
+		e.b.Reset()
+	}
+	return nil
+}
+
+// maxRedactLine is the size lines are split at before they are redacted, secrets across the
+// split of longer lines are not redacted
+const maxRedactLine = 64 << 10
+
+// redactWriter writes each line written to it to w with its secrets redacted, in a single
+// Write per line. Close must be called to flush the last line if it has no newline.
+//
+// Only the output read by the agent is redacted, the output docker sends to the syslog URL of
+// an app goes straight from the container to the syslog server.
+type redactWriter struct {
+	b bytes.Buffer
+	w io.Writer
+	r *models.LogRedactor
+}
+
+func newRedactWriter(w io.Writer, r *models.LogRedactor) *redactWriter {
+	return &redactWriter{w: w, r: r}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	r.b.Write(p)
+	for {
+		b := r.b.Bytes()
+		i := bytes.IndexByte(b, '\n')
+		switch {
+		case i >= 0 && i < maxRedactLine:
+			r.w.Write(r.r.Redact(r.b.Next(i + 1)))
+		case len(b) >= maxRedactLine:
+			r.w.Write(r.r.Redact(r.b.Next(maxRedactLine)))
+		default:
+			return len(p), nil
+		}
+	}
+}
+
+func (r *redactWriter) Close() error {
+	if r.b.Len() > 0 {
+		r.w.Write(r.r.Redact(r.b.Bytes()))
+		r.b.Reset()
+	}
+	return nil
+}
+
 // io.Writer that allows limiting bytes written to w
 // TODO change to use clamp writer, this is dupe code
 type limitDiscardWriter struct {
 	n, max int
+	// dropped is the number of bytes cut off
+	dropped int64
 	io.Writer
 }
 
-func newLimitWriter(max int, w io.Writer) io.Writer {
+func newLimitWriter(max int, w io.Writer) *limitDiscardWriter {
 	return &limitDiscardWriter{max: max, Writer: w}
 }
 
 func (l *limitDiscardWriter) Write(b []byte) (int, error) {
 	inpLen := len(b)
 	if l.n >= l.max {
+		l.dropped += int64(inpLen)
 		return inpLen, nil
 	}
 
 	if l.n+inpLen >= l.max {
 		// cut off to prevent gigantic line attack
 		b = b[:l.max-l.n]
+		l.dropped += int64(inpLen - len(b))
 	}
 
 	n, err := l.Writer.Write(b)
@@ -737,6 +737,126 @@ func (m *RunnerStatus) GetIsNetworkDisabled() bool {
 	return false
 }
 
+// Status of the node the runner runs on, as served by its /status endpoint
+type RunnerNodeStatus struct {
+	ModelsNodeStatusJson string   `protobuf:"bytes,1,opt,name=models_node_status_json,json=modelsNodeStatusJson,proto3" json:"models_node_status_json,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RunnerNodeStatus) Reset()         { *m = RunnerNodeStatus{} }
+func (m *RunnerNodeStatus) String() string { return proto.CompactTextString(m) }
+func (*RunnerNodeStatus) ProtoMessage()    {}
+func (*RunnerNodeStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{9}
+}
+
+func (m *RunnerNodeStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RunnerNodeStatus.Unmarshal(m, b)
+}
+func (m *RunnerNodeStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RunnerNodeStatus.Marshal(b, m, deterministic)
+}
+func (m *RunnerNodeStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RunnerNodeStatus.Merge(m, src)
+}
+func (m *RunnerNodeStatus) XXX_Size() int {
+	return xxx_messageInfo_RunnerNodeStatus.Size(m)
+}
+func (m *RunnerNodeStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_RunnerNodeStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RunnerNodeStatus proto.InternalMessageInfo
+
+func (m *RunnerNodeStatus) GetModelsNodeStatusJson() string {
+	if m != nil {
+		return m.ModelsNodeStatusJson
+	}
+	return ""
+}
+
+// Request to stream the output of the containers of a function
+type TailLogsRequest struct {
+	FnId                 string   `protobuf:"bytes,1,opt,name=fn_id,json=fnId,proto3" json:"fn_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TailLogsRequest) Reset()         { *m = TailLogsRequest{} }
+func (m *TailLogsRequest) String() string { return proto.CompactTextString(m) }
+func (*TailLogsRequest) ProtoMessage()    {}
+func (*TailLogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{10}
+}
+
+func (m *TailLogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TailLogsRequest.Unmarshal(m, b)
+}
+func (m *TailLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TailLogsRequest.Marshal(b, m, deterministic)
+}
+func (m *TailLogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TailLogsRequest.Merge(m, src)
+}
+func (m *TailLogsRequest) XXX_Size() int {
+	return xxx_messageInfo_TailLogsRequest.Size(m)
+}
+func (m *TailLogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TailLogsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TailLogsRequest proto.InternalMessageInfo
+
+func (m *TailLogsRequest) GetFnId() string {
+	if m != nil {
+		return m.FnId
+	}
+	return ""
+}
+
+// Line of the output of a container of a tailed function
+type LogTailLine struct {
+	ModelsLogLineJson    string   `protobuf:"bytes,1,opt,name=models_log_line_json,json=modelsLogLineJson,proto3" json:"models_log_line_json,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogTailLine) Reset()         { *m = LogTailLine{} }
+func (m *LogTailLine) String() string { return proto.CompactTextString(m) }
+func (*LogTailLine) ProtoMessage()    {}
+func (*LogTailLine) Descriptor() ([]byte, []int) {
+	return fileDescriptor_48eceea7e2abc593, []int{11}
+}
+
+func (m *LogTailLine) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogTailLine.Unmarshal(m, b)
+}
+func (m *LogTailLine) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogTailLine.Marshal(b, m, deterministic)
+}
+func (m *LogTailLine) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogTailLine.Merge(m, src)
+}
+func (m *LogTailLine) XXX_Size() int {
+	return xxx_messageInfo_LogTailLine.Size(m)
+}
+func (m *LogTailLine) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogTailLine.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogTailLine proto.InternalMessageInfo
+
+func (m *LogTailLine) GetModelsLogLineJson() string {
+	if m != nil {
+		return m.ModelsLogLineJson
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*TryCall)(nil), "TryCall")
 	proto.RegisterMapType((map[string]string)(nil), "TryCall.ExtensionsEntry")
@@ -748,63 +868,73 @@ func init() {
 	proto.RegisterType((*ClientMsg)(nil), "ClientMsg")
 	proto.RegisterType((*RunnerMsg)(nil), "RunnerMsg")
 	proto.RegisterType((*RunnerStatus)(nil), "RunnerStatus")
+	proto.RegisterType((*RunnerNodeStatus)(nil), "RunnerNodeStatus")
+	proto.RegisterType((*TailLogsRequest)(nil), "TailLogsRequest")
+	proto.RegisterType((*LogTailLine)(nil), "LogTailLine")
 }
 
 func init() { proto.RegisterFile("runner.proto", fileDescriptor_48eceea7e2abc593) }
 
 var fileDescriptor_48eceea7e2abc593 = []byte{
-	// 805 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x95, 0x6f, 0x6f, 0xe3, 0x44,
-	0x10, 0xc6, 0xeb, 0xfc, 0x71, 0x9c, 0xb1, 0xdb, 0xa6, 0x2b, 0x74, 0xb2, 0xc2, 0x49, 0x58, 0x06,
-	0x24, 0x0b, 0x4e, 0x2e, 0x14, 0x90, 0x4e, 0x48, 0x20, 0x41, 0xdb, 0x93, 0x41, 0x3a, 0x40, 0x5b,
-	0xe0, 0x6d, 0xb4, 0xf5, 0x4e, 0x13, 0x53, 0xc7, 0x1b, 0x76, 0xd7, 0xe5, 0xf2, 0x51, 0x90, 0xf8,
-	0x4c, 0xbc, 0xe2, 0x03, 0xa1, 0x5d, 0x3b, 0x4e, 0x68, 0x20, 0xc7, 0xbd, 0xf3, 0x3c, 0xcf, 0xec,
-	0xce, 0xcc, 0xee, 0xcf, 0x5a, 0x08, 0x64, 0x5d, 0x55, 0x28, 0xd3, 0x95, 0x14, 0x5a, 0x4c, 0xdf,
-	0x9e, 0x0b, 0x31, 0x2f, 0xf1, 0xdc, 0x46, 0xb7, 0xf5, 0xdd, 0x39, 0x2e, 0x57, 0x7a, 0xdd, 0x98,
-	0xf1, 0x9f, 0x0e, 0x8c, 0x7e, 0x94, 0xeb, 0x4b, 0x56, 0x96, 0x24, 0x81, 0xc9, 0x52, 0x70, 0x2c,
-	0xd5, 0x2c, 0x67, 0x65, 0x39, 0xfb, 0x45, 0x89, 0x2a, 0x74, 0x22, 0x27, 0x19, 0xd3, 0x93, 0x46,
-	0x37, 0x59, 0xdf, 0x2a, 0x51, 0x91, 0x08, 0x02, 0x55, 0x0a, 0x3d, 0x5b, 0x30, 0xb5, 0x98, 0x15,
-	0x3c, 0xec, 0xd9, 0x2c, 0x30, 0x5a, 0xc6, 0xd4, 0xe2, 0x1b, 0x4e, 0x9e, 0x03, 0xe0, 0x2b, 0x8d,
-	0x95, 0x2a, 0x44, 0xa5, 0xc2, 0x7e, 0xd4, 0x4f, 0xfc, 0x8b, 0x30, 0x6d, 0x2b, 0xa5, 0xd7, 0x9d,
-	0x75, 0x5d, 0x69, 0xb9, 0xa6, 0x3b, 0xb9, 0xd3, 0x2f, 0xe0, 0xf4, 0x91, 0x4d, 0x26, 0xd0, 0xbf,
-	0xc7, 0x75, 0xdb, 0x8b, 0xf9, 0x24, 0x6f, 0xc1, 0xf0, 0x81, 0x95, 0x35, 0xb6, 0x95, 0x9b, 0xe0,
-	0xf3, 0xde, 0x73, 0x27, 0xfe, 0x18, 0xc6, 0x57, 0x4c, 0xb3, 0x17, 0x92, 0x2d, 0x91, 0x10, 0x18,
-	0x70, 0xa6, 0x99, 0x5d, 0x19, 0x50, 0xfb, 0x6d, 0x36, 0x43, 0x71, 0x67, 0x17, 0x7a, 0xd4, 0x7c,
-	0xc6, 0x9f, 0x02, 0x64, 0x5a, 0xaf, 0x32, 0x64, 0x1c, 0xe5, 0xff, 0x2d, 0x16, 0xff, 0x0c, 0x81,
-	0x59, 0x45, 0x51, 0xad, 0x5e, 0xa2, 0x66, 0xe4, 0x1d, 0xf0, 0x95, 0x66, 0xba, 0x56, 0xb3, 0x5c,
-	0x70, 0xb4, 0xeb, 0x87, 0x14, 0x1a, 0xe9, 0x52, 0x70, 0x24, 0xef, 0xc3, 0x68, 0x61, 0x4b, 0xa8,
-	0xb0, 0x67, 0xcf, 0xc3, 0x4f, 0xb7, 0x65, 0xe9, 0xc6, 0x8b, 0xbf, 0x84, 0x53, 0x73, 0x46, 0x14,
-	0x55, 0x5d, 0xea, 0x1b, 0xcd, 0xa4, 0x26, 0xef, 0xc2, 0x60, 0xa1, 0xf5, 0x2a, 0xe4, 0x91, 0x93,
-	0xf8, 0x17, 0xc7, 0xe9, 0x6e, 0xdd, 0xec, 0x88, 0x5a, 0xf3, 0x6b, 0x17, 0x06, 0x4b, 0xd4, 0x2c,
-	0xfe, 0xab, 0x07, 0x81, 0xd9, 0xe0, 0x45, 0x51, 0x15, 0x6a, 0x81, 0x9c, 0x84, 0x30, 0x52, 0x75,
-	0x9e, 0xa3, 0x52, 0xb6, 0x29, 0x8f, 0x6e, 0x42, 0xe3, 0x70, 0xd4, 0xac, 0x28, 0x55, 0x3b, 0xda,
-	0x26, 0x24, 0x4f, 0x61, 0x8c, 0x52, 0x0a, 0x69, 0x1a, 0x0f, 0xfb, 0x76, 0x94, 0xad, 0x40, 0xa6,
-	0xe0, 0xd9, 0xe0, 0x46, 0xcb, 0x70, 0x60, 0x17, 0x76, 0xb1, 0x59, 0x99, 0x4b, 0x64, 0x1a, 0xf9,
-	0x57, 0x3a, 0x1c, 0x5a, 0x73, 0x2b, 0x18, 0x57, 0x99, 0x91, 0xac, 0xeb, 0x36, 0x6e, 0x27, 0x90,
-	0x08, 0xfc, 0x5c, 0x2c, 0x57, 0x25, 0x36, 0xfe, 0xc8, 0xfa, 0xbb, 0x12, 0x79, 0x06, 0x67, 0x2a,
-	0x5f, 0x20, 0xaf, 0x4b, 0x94, 0x57, 0xb5, 0x64, 0xba, 0x10, 0x55, 0xe8, 0x45, 0x4e, 0xd2, 0xa7,
-	0xfb, 0x86, 0xc9, 0xc6, 0x57, 0x98, 0xd7, 0x26, 0xe8, 0xb2, 0xc7, 0x4d, 0xf6, 0x9e, 0xd1, 0xcd,
-	0xfc, 0x93, 0x42, 0x19, 0x82, 0x3d, 0xa9, 0xad, 0x10, 0xdf, 0xc0, 0xf8, 0xb2, 0x2c, 0xb0, 0xd2,
-	0x2f, 0xd5, 0x9c, 0x3c, 0x85, 0xbe, 0x96, 0x0d, 0x23, 0xfe, 0x85, 0xb7, 0xc1, 0x3a, 0x3b, 0xa2,
-	0x46, 0x26, 0x51, 0x4b, 0x5d, 0xcf, 0xda, 0x90, 0x76, 0x3c, 0x9a, 0xbb, 0x32, 0x8e, 0xb9, 0xab,
-	0x5b, 0xc1, 0xd7, 0xf1, 0xef, 0x0e, 0x8c, 0xa9, 0xfd, 0x57, 0xcd, 0xae, 0x9f, 0x41, 0x20, 0xed,
-	0xad, 0xcf, 0xec, 0x91, 0xb4, 0xdb, 0x4f, 0xd2, 0x47, 0x38, 0x64, 0x47, 0xd4, 0x97, 0x3b, 0x74,
-	0xbc, 0xb6, 0x1c, 0xf9, 0x10, 0xbc, 0xbb, 0x96, 0x06, 0x7b, 0x99, 0x86, 0xa1, 0x5d, 0x44, 0xb2,
-	0x23, 0xda, 0x25, 0x74, 0xbd, 0xfd, 0x31, 0x80, 0xa0, 0xe9, 0xed, 0xc6, 0x32, 0x4c, 0x9e, 0x80,
-	0xcb, 0x72, 0x5d, 0x3c, 0x34, 0xff, 0xc1, 0x90, 0xb6, 0x91, 0xd1, 0xef, 0x58, 0x51, 0xb6, 0x7b,
-	0x7b, 0xb4, 0x8d, 0xc8, 0x09, 0xf4, 0x0a, 0xde, 0xf2, 0xd1, 0x2b, 0xf8, 0x2e, 0x6d, 0xc3, 0x03,
-	0xb4, 0xb9, 0x87, 0x68, 0x1b, 0x1d, 0xa2, 0xcd, 0x3b, 0x48, 0xdb, 0xf8, 0x35, 0xb4, 0xc1, 0x3e,
-	0x6d, 0x4f, 0xc0, 0xcd, 0x99, 0xa1, 0x2a, 0xf4, 0x9b, 0xc9, 0x9a, 0x88, 0x7c, 0x00, 0x13, 0x89,
-	0xbf, 0xd6, 0xa8, 0xb4, 0xa2, 0x98, 0x63, 0xf1, 0x80, 0x3c, 0x0c, 0x22, 0x27, 0x19, 0xd0, 0x3d,
-	0x9d, 0x24, 0x70, 0xba, 0xd1, 0x32, 0x56, 0x71, 0x73, 0x4c, 0xc7, 0x36, 0xf5, 0xb1, 0x4c, 0x62,
-	0x08, 0xee, 0x79, 0xbd, 0x5c, 0xa9, 0xef, 0xab, 0xab, 0x42, 0xdd, 0x87, 0x27, 0x36, 0xed, 0x1f,
-	0xda, 0xbf, 0xf3, 0x7f, 0xfa, 0x46, 0xfc, 0x4f, 0xfe, 0x8b, 0xff, 0x67, 0x70, 0x56, 0xa8, 0xef,
-	0x50, 0xff, 0x26, 0xe4, 0xfd, 0x55, 0xa1, 0xd8, 0xad, 0xe9, 0xf5, 0xcc, 0x0e, 0xbe, 0x6f, 0x5c,
-	0xcc, 0xe1, 0xa4, 0xa1, 0xe3, 0x07, 0xf3, 0x8e, 0xe4, 0xa2, 0x24, 0xef, 0x81, 0x7b, 0x5d, 0xcd,
-	0xd9, 0x1c, 0x09, 0xa4, 0xdd, 0xaf, 0x32, 0x85, 0xb4, 0x03, 0x3c, 0x71, 0x3e, 0x72, 0xc8, 0x39,
-	0xb8, 0x1b, 0x9e, 0xd2, 0xe6, 0x61, 0x4a, 0x37, 0x0f, 0x53, 0x7a, 0x6d, 0x1e, 0xa6, 0xe9, 0x71,
-	0xba, 0x8b, 0xdd, 0xad, 0x6b, 0xed, 0x4f, 0xfe, 0x0e, 0x00, 0x00, 0xff, 0xff, 0xc9, 0x05, 0xb1,
-	0xfd, 0xd5, 0x06, 0x00, 0x00,
+	// 921 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x6d, 0x6f, 0x1b, 0x45,
+	0x10, 0xce, 0xf9, 0xdd, 0xe3, 0x4b, 0x6c, 0x2f, 0x55, 0x39, 0x99, 0x4a, 0x58, 0xc7, 0x8b, 0x2c,
+	0x88, 0x2e, 0x25, 0x50, 0xa9, 0xaa, 0x44, 0x25, 0x48, 0x52, 0x1c, 0x94, 0x16, 0xb4, 0x29, 0x7c,
+	0xb5, 0x36, 0xb7, 0x63, 0xfb, 0xc8, 0xf9, 0xd6, 0xec, 0xee, 0x85, 0xfa, 0xa7, 0x20, 0xf1, 0x9b,
+	0x90, 0x90, 0xf8, 0x41, 0x68, 0x77, 0xcf, 0x67, 0x37, 0xa6, 0x09, 0x7c, 0xbb, 0x79, 0x9e, 0x79,
+	0xdb, 0xd9, 0x67, 0x6f, 0xc0, 0x97, 0x79, 0x96, 0xa1, 0x8c, 0x96, 0x52, 0x68, 0x31, 0xf8, 0x60,
+	0x26, 0xc4, 0x2c, 0xc5, 0x23, 0x6b, 0x5d, 0xe5, 0xd3, 0x23, 0x5c, 0x2c, 0xf5, 0xca, 0x91, 0xe1,
+	0x9f, 0x1e, 0x34, 0x5f, 0xcb, 0xd5, 0x09, 0x4b, 0x53, 0x32, 0x82, 0xde, 0x42, 0x70, 0x4c, 0xd5,
+	0x24, 0x66, 0x69, 0x3a, 0xf9, 0x45, 0x89, 0x2c, 0xf0, 0x86, 0xde, 0xa8, 0x4d, 0x0f, 0x1c, 0x6e,
+	0xbc, 0xbe, 0x57, 0x22, 0x23, 0x43, 0xf0, 0x55, 0x2a, 0xf4, 0x64, 0xce, 0xd4, 0x7c, 0x92, 0xf0,
+	0xa0, 0x62, 0xbd, 0xc0, 0x60, 0x63, 0xa6, 0xe6, 0xe7, 0x9c, 0x3c, 0x05, 0xc0, 0x37, 0x1a, 0x33,
+	0x95, 0x88, 0x4c, 0x05, 0xd5, 0x61, 0x75, 0xd4, 0x39, 0x0e, 0xa2, 0xa2, 0x52, 0x74, 0x56, 0x52,
+	0x67, 0x99, 0x96, 0x2b, 0xba, 0xe5, 0x3b, 0xf8, 0x1a, 0xba, 0xb7, 0x68, 0xd2, 0x83, 0xea, 0x35,
+	0xae, 0x8a, 0x5e, 0xcc, 0x27, 0x79, 0x00, 0xf5, 0x1b, 0x96, 0xe6, 0x58, 0x54, 0x76, 0xc6, 0xb3,
+	0xca, 0x53, 0x2f, 0xfc, 0x02, 0xda, 0xa7, 0x4c, 0xb3, 0x17, 0x92, 0x2d, 0x90, 0x10, 0xa8, 0x71,
+	0xa6, 0x99, 0x8d, 0xf4, 0xa9, 0xfd, 0x36, 0xc9, 0x50, 0x4c, 0x6d, 0x60, 0x8b, 0x9a, 0xcf, 0xf0,
+	0x2b, 0x80, 0xb1, 0xd6, 0xcb, 0x31, 0x32, 0x8e, 0xf2, 0xbf, 0x16, 0x0b, 0x7f, 0x06, 0xdf, 0x44,
+	0x51, 0x54, 0xcb, 0x97, 0xa8, 0x19, 0xf9, 0x10, 0x3a, 0x4a, 0x33, 0x9d, 0xab, 0x49, 0x2c, 0x38,
+	0xda, 0xf8, 0x3a, 0x05, 0x07, 0x9d, 0x08, 0x8e, 0xe4, 0x13, 0x68, 0xce, 0x6d, 0x09, 0x15, 0x54,
+	0xec, 0x3c, 0x3a, 0xd1, 0xa6, 0x2c, 0x5d, 0x73, 0xe1, 0x73, 0xe8, 0x9a, 0x19, 0x51, 0x54, 0x79,
+	0xaa, 0x2f, 0x35, 0x93, 0x9a, 0x7c, 0x04, 0xb5, 0xb9, 0xd6, 0xcb, 0x80, 0x0f, 0xbd, 0x51, 0xe7,
+	0x78, 0x3f, 0xda, 0xae, 0x3b, 0xde, 0xa3, 0x96, 0xfc, 0xb6, 0x01, 0xb5, 0x05, 0x6a, 0x16, 0xfe,
+	0x5d, 0x01, 0xdf, 0x24, 0x78, 0x91, 0x64, 0x89, 0x9a, 0x23, 0x27, 0x01, 0x34, 0x55, 0x1e, 0xc7,
+	0xa8, 0x94, 0x6d, 0xaa, 0x45, 0xd7, 0xa6, 0x61, 0x38, 0x6a, 0x96, 0xa4, 0xaa, 0x38, 0xda, 0xda,
+	0x24, 0x8f, 0xa0, 0x8d, 0x52, 0x0a, 0x69, 0x1a, 0x0f, 0xaa, 0xf6, 0x28, 0x1b, 0x80, 0x0c, 0xa0,
+	0x65, 0x8d, 0x4b, 0x2d, 0x83, 0x9a, 0x0d, 0x2c, 0x6d, 0x13, 0x19, 0x4b, 0x64, 0x1a, 0xf9, 0x37,
+	0x3a, 0xa8, 0x5b, 0x72, 0x03, 0x18, 0x56, 0x99, 0x23, 0x59, 0xb6, 0xe1, 0xd8, 0x12, 0x20, 0x43,
+	0xe8, 0xc4, 0x62, 0xb1, 0x4c, 0xd1, 0xf1, 0x4d, 0xcb, 0x6f, 0x43, 0xe4, 0x10, 0xfa, 0x2a, 0x9e,
+	0x23, 0xcf, 0x53, 0x94, 0xa7, 0xb9, 0x64, 0x3a, 0x11, 0x59, 0xd0, 0x1a, 0x7a, 0xa3, 0x2a, 0xdd,
+	0x25, 0x8c, 0x37, 0xbe, 0xc1, 0x38, 0x37, 0x46, 0xe9, 0xdd, 0x76, 0xde, 0x3b, 0x44, 0x79, 0xe6,
+	0x9f, 0x14, 0xca, 0x00, 0xec, 0xa4, 0x36, 0x40, 0x78, 0x09, 0xed, 0x93, 0x34, 0xc1, 0x4c, 0xbf,
+	0x54, 0x33, 0xf2, 0x08, 0xaa, 0x5a, 0x3a, 0x8d, 0x74, 0x8e, 0x5b, 0x6b, 0x59, 0x8f, 0xf7, 0xa8,
+	0x81, 0xc9, 0xb0, 0x50, 0x5d, 0xc5, 0xd2, 0x10, 0x95, 0x7a, 0x34, 0x77, 0x65, 0x18, 0x73, 0x57,
+	0x57, 0x82, 0xaf, 0xc2, 0xdf, 0x3d, 0x68, 0x53, 0xfb, 0x56, 0x4d, 0xd6, 0x27, 0xe0, 0x4b, 0x7b,
+	0xeb, 0x13, 0x3b, 0x92, 0x22, 0x7d, 0x2f, 0xba, 0x25, 0x87, 0xf1, 0x1e, 0xed, 0xc8, 0x8d, 0x79,
+	0x7f, 0x39, 0xf2, 0x39, 0xb4, 0xa6, 0x85, 0x1a, 0x82, 0x6a, 0xa1, 0xa1, 0x6d, 0x89, 0x8c, 0xf7,
+	0x68, 0xe9, 0x50, 0xf6, 0xf6, 0x47, 0x0d, 0x7c, 0xd7, 0xdb, 0xa5, 0xd5, 0x30, 0x79, 0x08, 0x0d,
+	0x16, 0xeb, 0xe4, 0xc6, 0xbd, 0x83, 0x3a, 0x2d, 0x2c, 0x83, 0x4f, 0x59, 0x92, 0x16, 0xb9, 0x5b,
+	0xb4, 0xb0, 0xc8, 0x01, 0x54, 0x12, 0x5e, 0xe8, 0xa3, 0x92, 0xf0, 0x6d, 0xb5, 0xd5, 0xef, 0x50,
+	0x5b, 0xe3, 0x2e, 0xb5, 0x35, 0xef, 0x52, 0x5b, 0xeb, 0x4e, 0xb5, 0xb5, 0xef, 0x51, 0x1b, 0xec,
+	0xaa, 0xed, 0x21, 0x34, 0x62, 0x66, 0x54, 0x15, 0x74, 0xdc, 0xc9, 0x9c, 0x45, 0x3e, 0x83, 0x9e,
+	0xc4, 0x5f, 0x73, 0x54, 0x5a, 0x51, 0x8c, 0x31, 0xb9, 0x41, 0x1e, 0xf8, 0x43, 0x6f, 0x54, 0xa3,
+	0x3b, 0x38, 0x19, 0x41, 0x77, 0x8d, 0x8d, 0x59, 0xc6, 0xcd, 0x98, 0xf6, 0xad, 0xeb, 0x6d, 0x98,
+	0x84, 0xe0, 0x5f, 0xf3, 0x7c, 0xb1, 0x54, 0x3f, 0x64, 0xa7, 0x89, 0xba, 0x0e, 0x0e, 0xac, 0xdb,
+	0x5b, 0xd8, 0xbf, 0xeb, 0xbf, 0xfb, 0xbf, 0xf4, 0xdf, 0x7b, 0x97, 0xfe, 0x0f, 0xa1, 0x9f, 0xa8,
+	0x57, 0xa8, 0x7f, 0x13, 0xf2, 0xfa, 0x34, 0x51, 0xec, 0xca, 0xf4, 0xda, 0xb7, 0x07, 0xdf, 0x25,
+	0xc2, 0x73, 0xe8, 0x39, 0x75, 0xbc, 0x12, 0x1c, 0x0b, 0x85, 0x3c, 0x81, 0xf7, 0x8b, 0x05, 0x92,
+	0x09, 0x8e, 0x93, 0xe2, 0x77, 0xb8, 0xb5, 0x47, 0x1e, 0x38, 0x7a, 0x13, 0x62, 0xb6, 0x49, 0xf8,
+	0x29, 0x74, 0x5f, 0xb3, 0x24, 0xbd, 0x10, 0x33, 0x45, 0xdd, 0x4c, 0xc8, 0x7b, 0x50, 0x9f, 0x66,
+	0x66, 0xb3, 0xb8, 0xb8, 0xda, 0x34, 0x3b, 0xe7, 0xe1, 0x73, 0xe8, 0x5c, 0x88, 0x99, 0x75, 0x4d,
+	0x32, 0x24, 0x47, 0x50, 0xa4, 0x9b, 0xa4, 0x62, 0x36, 0x49, 0x93, 0x0c, 0xb7, 0x4b, 0xf5, 0x1d,
+	0x77, 0x21, 0x66, 0xc6, 0xd9, 0xd4, 0x39, 0xfe, 0xcb, 0x83, 0x03, 0xd7, 0xf3, 0x8f, 0x66, 0xf7,
+	0xc5, 0x22, 0x25, 0x1f, 0x43, 0xe3, 0x2c, 0x9b, 0xb1, 0x19, 0x12, 0x88, 0xca, 0xe7, 0x3d, 0x80,
+	0xa8, 0x7c, 0x94, 0x23, 0xef, 0xb1, 0x47, 0x8e, 0xa0, 0xb1, 0x7e, 0x03, 0x91, 0x5b, 0xa6, 0xd1,
+	0x7a, 0x99, 0x46, 0x67, 0x66, 0x99, 0x0e, 0xf6, 0xa3, 0xb7, 0x9e, 0xca, 0x33, 0xd8, 0xff, 0x0e,
+	0xf5, 0xd6, 0x64, 0xde, 0x15, 0xd7, 0x8f, 0x76, 0x86, 0x78, 0x08, 0xad, 0xf5, 0x34, 0x48, 0x2f,
+	0xba, 0x35, 0x98, 0x81, 0x1f, 0x6d, 0x8d, 0xe0, 0xb1, 0x77, 0xd5, 0xb0, 0x09, 0xbf, 0xfc, 0x67,
+	0x00, 0xbf, 0xcf, 0x5c, 0x9e, 0xf3, 0x07, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -822,6 +952,10 @@ type RunnerProtocolClient interface {
 	Engage(ctx context.Context, opts ...grpc.CallOption) (RunnerProtocol_EngageClient, error)
 	// Rather than rely on Prometheus for this, expose status that's specific to the runner lifecycle through this.
 	Status(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RunnerStatus, error)
+	// Node status of the runner, aggregated by load balancers for their own /status.
+	GetNodeStatus(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RunnerNodeStatus, error)
+	// Streams the output of the containers of a function until the client cancels.
+	TailLogs(ctx context.Context, in *TailLogsRequest, opts ...grpc.CallOption) (RunnerProtocol_TailLogsClient, error)
 }
 
 type runnerProtocolClient struct {
@@ -872,11 +1006,56 @@ func (c *runnerProtocolClient) Status(ctx context.Context, in *empty.Empty, opts
 	return out, nil
 }
 
+func (c *runnerProtocolClient) GetNodeStatus(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RunnerNodeStatus, error) {
+	out := new(RunnerNodeStatus)
+	err := c.cc.Invoke(ctx, "/RunnerProtocol/GetNodeStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runnerProtocolClient) TailLogs(ctx context.Context, in *TailLogsRequest, opts ...grpc.CallOption) (RunnerProtocol_TailLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RunnerProtocol_serviceDesc.Streams[1], "/RunnerProtocol/TailLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &runnerProtocolTailLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RunnerProtocol_TailLogsClient interface {
+	Recv() (*LogTailLine, error)
+	grpc.ClientStream
+}
+
+type runnerProtocolTailLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *runnerProtocolTailLogsClient) Recv() (*LogTailLine, error) {
+	m := new(LogTailLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RunnerProtocolServer is the server API for RunnerProtocol service.
 type RunnerProtocolServer interface {
 	Engage(RunnerProtocol_EngageServer) error
 	// Rather than rely on Prometheus for this, expose status that's specific to the runner lifecycle through this.
 	Status(context.Context, *empty.Empty) (*RunnerStatus, error)
+	// Node status of the runner, aggregated by load balancers for their own /status.
+	GetNodeStatus(context.Context, *empty.Empty) (*RunnerNodeStatus, error)
+	// Streams the output of the containers of a function until the client cancels.
+	TailLogs(*TailLogsRequest, RunnerProtocol_TailLogsServer) error
 }
 
 func RegisterRunnerProtocolServer(s *grpc.Server, srv RunnerProtocolServer) {
@@ -927,6 +1106,45 @@ func _RunnerProtocol_Status_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RunnerProtocol_GetNodeStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RunnerProtocolServer).GetNodeStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/RunnerProtocol/GetNodeStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RunnerProtocolServer).GetNodeStatus(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RunnerProtocol_TailLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RunnerProtocolServer).TailLogs(m, &runnerProtocolTailLogsServer{stream})
+}
+
+type RunnerProtocol_TailLogsServer interface {
+	Send(*LogTailLine) error
+	grpc.ServerStream
+}
+
+type runnerProtocolTailLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *runnerProtocolTailLogsServer) Send(m *LogTailLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _RunnerProtocol_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "RunnerProtocol",
 	HandlerType: (*RunnerProtocolServer)(nil),
@@ -935,6 +1153,10 @@ var _RunnerProtocol_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Status",
 			Handler:    _RunnerProtocol_Status_Handler,
 		},
+		{
+			MethodName: "GetNodeStatus",
+			Handler:    _RunnerProtocol_GetNodeStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -943,6 +1165,11 @@ var _RunnerProtocol_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "TailLogs",
+			Handler:       _RunnerProtocol_TailLogs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "runner.proto",
 }
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// hotContainerSlots tracks the slots of a single hot container. A function that declares
+// call concurrency has several slots of the same container queued or busy at once, so the
+// container state and its evictability are derived from all of them: the container is idle
+// only when none of its slots are busy, and it idles out only when none of its slots have
+// served a call for the idle timeout.
+type hotContainerSlots struct {
+	concurrency uint64
+	state       ContainerState
+	evictor     *EvictToken
+	slots       *slotQueue
+	started     time.Time
+
+	lock       sync.Mutex // protects busy, lastActive, calls and recycling
+	busy       uint64
+	lastActive time.Time
+	calls      uint64
+	recycling  bool
+
+	// closed once the container stops taking calls, see startDrain
+	drain     chan struct{}
+	drainOnce sync.Once
+}
+
+func newHotContainerSlots(concurrency uint64, state ContainerState, evictor *EvictToken, slots *slotQueue) *hotContainerSlots {
+	return &hotContainerSlots{
+		concurrency: concurrency,
+		state:       state,
+		evictor:     evictor,
+		slots:       slots,
+		started:     time.Now(),
+		drain:       make(chan struct{}),
+	}
+}
+
+// canFreeze returns true if the container may be paused while a slot is idle. With
+// concurrent slots another slot could be handed a call while the container is paused,
+// so freezing is only done for containers serving one call at a time.
+func (h *hotContainerSlots) canFreeze() bool {
+	return h.concurrency <= 1
+}
+
+// idle marks the container idle and evictable if none of its slots are busy.
+func (h *hotContainerSlots) idle(ctx context.Context) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.busy == 0 {
+		h.evictor.SetEvictable(true)
+		h.state.UpdateState(ctx, ContainerStateIdle, h.slots)
+	}
+}
+
+// acquire marks a slot of the container busy.
+func (h *hotContainerSlots) acquire(ctx context.Context) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.busy++
+	h.evictor.SetEvictable(false)
+	h.state.UpdateState(ctx, ContainerStateBusy, h.slots)
+}
+
+// release marks a busy slot of the container done.
+func (h *hotContainerSlots) release() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.busy > 0 {
+		h.busy--
+	}
+	h.calls++
+	h.lastActive = time.Now()
+}
+
+// tryRecycle returns the reason the container is recycled as per policy given it uses mem
+// bytes of memory, or an empty string if it is not or another slot already recycles it.
+func (h *hotContainerSlots) tryRecycle(policy *models.RecyclePolicy, mem uint64) string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.recycling {
+		return ""
+	}
+	reason := policy.ShouldRecycle(h.calls, h.started, time.Now(), mem)
+	h.recycling = reason != ""
+	return reason
+}
+
+// startDrain stops all slots of the container from taking calls, the container shuts down
+// once the calls they are serving end.
+func (h *hotContainerSlots) startDrain() {
+	h.drainOnce.Do(func() { close(h.drain) })
+}
+
+// isDraining returns true once startDrain was called.
+func (h *hotContainerSlots) isDraining() bool {
+	select {
+	case <-h.drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// idleRemaining returns how much longer the container has to stay idle before it
+// reaches the idle timeout, taking the calls served by any of its slots into account.
+func (h *hotContainerSlots) idleRemaining(timeout time.Duration) time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.busy > 0 {
+		return timeout
+	}
+	return timeout - time.Since(h.lastActive)
+}
+
+// callConcurrency returns the number of calls a hot container of the function of a call
+// may serve at the same time, capped at max.
+func callConcurrency(ctx context.Context, call *call, max uint64) uint64 {
+	raw, ok := call.Annotations.Get(models.FnConcurrencyAnnotation)
+	if !ok {
+		return 1
+	}
+
+	var concurrency uint64
+	err := json.Unmarshal(raw, &concurrency)
+	if err != nil || concurrency == 0 {
+		common.Logger(ctx).WithError(err).WithField("fn_id", call.FnID).Error("invalid concurrency annotation, ignoring")
+		return 1
+	}
+	if max > 0 && concurrency > max {
+		return max
+	}
+	return concurrency
+}
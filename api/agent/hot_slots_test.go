@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestCallConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	mkCall := func(value interface{}) *call {
+		annotations := models.EmptyAnnotations()
+		if value != nil {
+			var err error
+			annotations, err = annotations.With(models.FnConcurrencyAnnotation, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &call{Call: &models.Call{FnID: "fn", Annotations: annotations}}
+	}
+
+	for i, test := range []struct {
+		value    interface{}
+		max      uint64
+		expected uint64
+	}{
+		{nil, 100, 1},
+		{4, 100, 4},
+		{4, 0, 4},
+		{400, 100, 100},
+		{0, 100, 1},
+		{-2, 100, 1},
+		{"four", 100, 1},
+	} {
+		res := callConcurrency(ctx, mkCall(test.value), test.max)
+		if res != test.expected {
+			t.Errorf("test %d: expected concurrency %d got %d", i, test.expected, res)
+		}
+	}
+}
+
+func TestHotContainerSlots(t *testing.T) {
+	ctx := context.Background()
+	slots := NewSlotQueue("test")
+	evictor := NewEvictor().CreateEvictToken(slots.key, 128, 100)
+	hc := newHotContainerSlots(2, NewContainerState(), evictor, slots)
+
+	if hc.canFreeze() {
+		t.Fatal("concurrent container should not be frozen")
+	}
+
+	hc.idle(ctx)
+	if atomic.LoadUint32(&evictor.evictable) != 1 {
+		t.Fatal("idle container should be evictable")
+	}
+	if hc.idleRemaining(time.Minute) > 0 {
+		t.Fatal("container that never served calls should be past its idle timeout")
+	}
+
+	hc.acquire(ctx)
+	hc.idle(ctx) // another slot of the container queued
+	if atomic.LoadUint32(&evictor.evictable) == 1 {
+		t.Fatal("container with a busy slot should not be evictable")
+	}
+	if stats := slots.getStats(); stats.containerStates[ContainerStateBusy] != 1 {
+		t.Fatalf("container with a busy slot should be busy: %#v", stats)
+	}
+	if hc.idleRemaining(time.Minute) != time.Minute {
+		t.Fatal("container with a busy slot should not idle out")
+	}
+
+	hc.release()
+	if hc.idleRemaining(time.Minute) <= 0 {
+		t.Fatal("container that just served a call should not idle out")
+	}
+	hc.idle(ctx)
+	if atomic.LoadUint32(&evictor.evictable) != 1 {
+		t.Fatal("container without busy slots should be evictable")
+	}
+	if stats := slots.getStats(); stats.containerStates[ContainerStateIdle] != 1 {
+		t.Fatalf("container without busy slots should be idle: %#v", stats)
+	}
+}
+
+func TestHotContainerSlotsRecycle(t *testing.T) {
+	ctx := context.Background()
+	slots := NewSlotQueue("test")
+	evictor := NewEvictor().CreateEvictToken(slots.key, 128, 100)
+	hc := newHotContainerSlots(2, NewContainerState(), evictor, slots)
+	policy := &models.RecyclePolicy{MaxCalls: 2}
+
+	hc.acquire(ctx)
+	hc.release()
+	if reason := hc.tryRecycle(policy, 0); reason != "" {
+		t.Fatalf("container should not be recycled after one call, got %q", reason)
+	}
+
+	hc.acquire(ctx)
+	hc.release()
+	if reason := hc.tryRecycle(policy, 0); reason != "max_calls" {
+		t.Fatalf("container should be recycled after two calls, got %q", reason)
+	}
+	if reason := hc.tryRecycle(policy, 0); reason != "" {
+		t.Fatalf("container should only be recycled once, got %q", reason)
+	}
+
+	if hc.isDraining() {
+		t.Fatal("container should take calls until it drains")
+	}
+	hc.startDrain()
+	hc.startDrain()
+	if !hc.isDraining() {
+		t.Fatal("container should be draining")
+	}
+}
+
+func TestReplacementState(t *testing.T) {
+	ctx := context.Background()
+	slots := NewSlotQueue("test")
+	state := newReplacementState()
+
+	state.UpdateState(ctx, ContainerStateWait, slots)
+	state.UpdateState(ctx, ContainerStateStart, slots)
+	select {
+	case <-state.ready:
+		t.Fatal("starting replacement should not be ready")
+	default:
+	}
+
+	state.UpdateState(ctx, ContainerStateIdle, slots)
+	select {
+	case <-state.ready:
+	default:
+		t.Fatal("idle replacement should be ready")
+	}
+	if stats := slots.getStats(); stats.containerStates[ContainerStateIdle] != 1 {
+		t.Fatalf("replacement state should be tracked by its slot queue: %#v", stats)
+	}
+	state.UpdateState(ctx, ContainerStateDone, slots)
+}
@@ -167,6 +167,18 @@ func (cl *client) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error
 	return &fn, nil
 }
 
+func (cl *client) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	ctx, span := trace.StartSpan(ctx, "hybrid_client_get_domains_by_hostname")
+	defer span.End()
+
+	var d models.DomainList
+	err := cl.do(ctx, nil, &d, "GET", map[string]string{"hostname": hostname}, "domains")
+	if err != nil {
+		return nil, err
+	}
+	return d.Items, nil
+}
+
 type httpErr struct {
 	code int
 	error
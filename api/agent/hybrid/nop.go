@@ -25,6 +25,12 @@ func (cl *nopDataStore) GetFnByID(ctx context.Context, fnId string) (*models.Fn,
 	return nil, errors.New("should not call GetFnByID on a NOP data store")
 }
 
+func (cl *nopDataStore) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	ctx, span := trace.StartSpan(ctx, "nop_datastore_get_domains_by_hostname")
+	defer span.End()
+	return nil, errors.New("should not call GetDomainsByHostname on a NOP data store")
+}
+
 func NewNopDataStore() (agent.DataAccess, error) {
 	return &nopDataStore{}, nil
 }
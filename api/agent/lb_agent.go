@@ -231,7 +231,12 @@ func (a *lbAgent) placeDetachCall(ctx context.Context, call *call) error {
 }
 
 func (a *lbAgent) placeCall(ctx context.Context, call *call) error {
-	err := a.placer.PlaceCall(ctx, a.rp, call)
+	var err error
+	if delay := call.hedgeAfter(); delay > 0 {
+		err = a.placeHedgedCall(ctx, call, delay)
+	} else {
+		err = a.placer.PlaceCall(ctx, a.rp, call)
+	}
 	return a.handleCallEnd(ctx, call, err, true)
 }
 
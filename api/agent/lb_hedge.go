@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+// errHedgeLost is returned to the writes of an attempt once another attempt of its call
+// has responded
+var errHedgeLost = errors.New("another attempt of the hedged call responded first")
+
+// hedge races the attempts of a hedged call. The first attempt to respond claims the
+// response writer of the call and cancels the other attempts.
+type hedge struct {
+	w http.ResponseWriter
+
+	lock     sync.Mutex // protects attempts and winner
+	attempts []*hedgedCall
+	winner   *hedgedCall
+}
+
+// hedgedCall is an attempt of a hedged call, it holds the headers of its response until
+// it claims the response writer of the call.
+type hedgedCall struct {
+	*call
+	ctx     context.Context
+	cancel  context.CancelFunc
+	hedge   *hedge
+	headers http.Header
+
+	// amount of time user execution inside container, only the winner's is accounted
+	userExecTime *time.Duration
+}
+
+type hedgeResult struct {
+	attempt *hedgedCall
+	err     error
+}
+
+// hedgeAfter returns how long the call waits for a response before it is hedged, 0 if the
+// fn of the call did not opt into hedging.
+func (c *call) hedgeAfter() time.Duration {
+	delay, err := models.HedgeAfterFromAnnotations(c.Annotations)
+	if err != nil {
+		return 0
+	}
+	return delay
+}
+
+// newAttempt adds an attempt to the hedge, nil if an attempt already responded.
+func (h *hedge) newAttempt(ctx context.Context, c *call) *hedgedCall {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.winner != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	attempt := &hedgedCall{
+		call:    c,
+		ctx:     ctx,
+		cancel:  cancel,
+		hedge:   h,
+		headers: make(http.Header),
+	}
+	h.attempts = append(h.attempts, attempt)
+	return attempt
+}
+
+// claim makes attempt the winner if no other attempt responded yet, cancelling the others.
+// Returns true if attempt is the winner.
+func (h *hedge) claim(attempt *hedgedCall) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.winner == nil {
+		h.winner = attempt
+		for key, vals := range attempt.headers {
+			h.w.Header()[key] = vals
+		}
+		for _, other := range h.attempts {
+			if other != attempt {
+				other.cancel()
+			}
+		}
+	}
+	return h.winner == attempt
+}
+
+func (h *hedge) isWinner(attempt *hedgedCall) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.winner == attempt
+}
+
+func (c *hedgedCall) ResponseWriter() http.ResponseWriter {
+	return c
+}
+
+func (c *hedgedCall) Header() http.Header {
+	return c.headers
+}
+
+func (c *hedgedCall) WriteHeader(statusCode int) {
+	if c.hedge.claim(c) {
+		c.hedge.w.WriteHeader(statusCode)
+	}
+}
+
+func (c *hedgedCall) Write(data []byte) (int, error) {
+	if !c.hedge.claim(c) {
+		return 0, errHedgeLost
+	}
+	return c.hedge.w.Write(data)
+}
+
+func (c *hedgedCall) AddUserExecutionTime(dur time.Duration) {
+	if c.userExecTime == nil {
+		c.userExecTime = new(time.Duration)
+	}
+	*c.userExecTime += dur
+}
+
+func (c *hedgedCall) GetUserExecutionTime() *time.Duration {
+	return c.userExecTime
+}
+
+// placeHedgedCall places the call, and places a second attempt of it if the first has not
+// responded after delay, eg. because it is waiting for a runner with a free slot. The
+// attempt which responds first is used, the other is cancelled. An attempt failing without
+// responding gives way to the attempts still running.
+func (a *lbAgent) placeHedgedCall(ctx context.Context, call *call, delay time.Duration) error {
+	h := &hedge{w: call.ResponseWriter()}
+	results := make(chan hedgeResult, 2)
+
+	start := func() bool {
+		attempt := h.newAttempt(ctx, call)
+		if attempt == nil {
+			return false
+		}
+		go func() {
+			err := a.placer.PlaceCall(attempt.ctx, a.rp, attempt)
+			results <- hedgeResult{attempt: attempt, err: err}
+		}()
+		return true
+	}
+
+	start()
+	pending := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedgeC := timer.C
+
+	var result *hedgeResult
+	for pending > 0 {
+		select {
+		case <-hedgeC:
+			hedgeC = nil
+			if start() {
+				pending++
+				statsLBAgentHedged(ctx)
+				common.Logger(ctx).WithField("hedge_after", delay).Debug("hedging call")
+			}
+		case res := <-results:
+			pending--
+			res.attempt.cancel()
+			if result != nil {
+				continue // cancelled loser
+			}
+			if res.err != nil && pending > 0 && !h.isWinner(res.attempt) {
+				continue
+			}
+			if h.claim(res.attempt) {
+				result = &res
+			}
+		}
+	}
+
+	if result.attempt != h.attempts[0] {
+		statsLBAgentHedgeWon(ctx)
+	}
+	if execTime := result.attempt.GetUserExecutionTime(); execTime != nil {
+		call.AddUserExecutionTime(*execTime)
+	}
+	return result.err
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+// hedgePlacer responds to the nth attempt of a call after delays[n], or fails it with errs[n]
+type hedgePlacer struct {
+	delays    []time.Duration
+	errs      []error
+	attempts  int32
+	cancelled int32
+}
+
+func (p *hedgePlacer) GetPlacerConfig() pool.PlacerConfig {
+	return pool.NewPlacerConfig()
+}
+
+func (p *hedgePlacer) PlaceCall(ctx context.Context, rp pool.RunnerPool, call pool.RunnerCall) error {
+	n := atomic.AddInt32(&p.attempts, 1) - 1
+
+	select {
+	case <-time.After(p.delays[n]):
+	case <-ctx.Done():
+		atomic.AddInt32(&p.cancelled, 1)
+		return ctx.Err()
+	}
+	if p.errs != nil && p.errs[n] != nil {
+		return p.errs[n]
+	}
+
+	w := call.ResponseWriter()
+	w.Header().Set("Attempt", strconv.Itoa(int(n)))
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("hello"))
+	return err
+}
+
+func hedgeTestCall(t *testing.T) (*call, *httptest.ResponseRecorder) {
+	annotations, err := models.EmptyAnnotations().With(models.FnHedgeAfterAnnotation, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	return &call{
+		Call:       &models.Call{Type: models.TypeSync, Annotations: annotations},
+		req:        httptest.NewRequest("POST", "/invoke/fn", nil),
+		respWriter: rec,
+	}, rec
+}
+
+func TestHedgedCall(t *testing.T) {
+	for i, test := range []struct {
+		delays    []time.Duration
+		errs      []error
+		attempts  int32
+		cancelled int32
+		winner    string
+		err       error
+	}{
+		// responds before the hedge delay
+		{[]time.Duration{0}, nil, 1, 0, "0", nil},
+		// slow first attempt loses to the hedge
+		{[]time.Duration{time.Second, 0}, nil, 2, 1, "1", nil},
+		// slow hedge loses to the first attempt
+		{[]time.Duration{40 * time.Millisecond, time.Second}, nil, 2, 1, "0", nil},
+		// failing first attempt gives way to the hedge
+		{[]time.Duration{40 * time.Millisecond, 60 * time.Millisecond}, []error{models.ErrCallTimeoutServerBusy, nil}, 2, 0, "1", nil},
+		// all attempts failing return the error of the last one
+		{[]time.Duration{40 * time.Millisecond, 60 * time.Millisecond}, []error{models.ErrCallTimeout, models.ErrCallTimeoutServerBusy}, 2, 0, "", models.ErrCallTimeoutServerBusy},
+	} {
+		placer := &hedgePlacer{delays: test.delays, errs: test.errs}
+		a := &lbAgent{placer: placer}
+		c, rec := hedgeTestCall(t)
+
+		err := a.placeHedgedCall(context.Background(), c, c.hedgeAfter())
+		if err != test.err {
+			t.Fatalf("test %d: expected error %v, got %v", i, test.err, err)
+		}
+		if placer.attempts != test.attempts || placer.cancelled != test.cancelled {
+			t.Fatalf("test %d: expected %d attempts with %d cancelled, got %d with %d cancelled",
+				i, test.attempts, test.cancelled, placer.attempts, placer.cancelled)
+		}
+		if rec.Header().Get("Attempt") != test.winner {
+			t.Fatalf("test %d: expected response of attempt %q, got %q", i, test.winner, rec.Header().Get("Attempt"))
+		}
+		if test.winner != "" && rec.Body.String() != "hello" {
+			t.Fatalf("test %d: unexpected body %q", i, rec.Body.String())
+		}
+	}
+}
+
+func TestHedgeAfter(t *testing.T) {
+	c, _ := hedgeTestCall(t)
+	if delay := c.hedgeAfter(); delay != 20*time.Millisecond {
+		t.Fatalf("expected hedge after 20ms, got %v", delay)
+	}
+
+	c.Annotations = models.EmptyAnnotations()
+	if delay := c.hedgeAfter(); delay != 0 {
+		t.Fatalf("expected no hedging, got %v", delay)
+	}
+}
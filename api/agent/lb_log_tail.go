@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+// tailRetryDelay is the delay before the output of a runner is tailed again once its stream
+// failed, eg. as the runner restarts
+const tailRetryDelay = time.Second
+
+// runnerLogTailer tails the output of the containers of the runners of a pool
+type runnerLogTailer struct {
+	rp pool.RunnerPool
+}
+
+// NewRunnerLogTailer returns a LogTailer streaming the output of the containers of the
+// runners of rp, eg. for API nodes to tail the functions their load balancers run. Nothing
+// is tailed unless rp is a runnerpool.RunnerLister.
+func NewRunnerLogTailer(rp pool.RunnerPool) LogTailer {
+	return &runnerLogTailer{rp: rp}
+}
+
+// TailLogs implements LogTailer
+func (t *runnerLogTailer) TailLogs(fnID string) (<-chan *models.LogLine, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan *models.LogLine, tailBuffer)
+
+	lister, ok := t.rp.(pool.RunnerLister)
+	if !ok {
+		return lines, cancel
+	}
+	runners, err := lister.ListRunners(ctx)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("Failed to list the runners of the pool")
+		return lines, cancel
+	}
+	for _, r := range runners {
+		if tr, ok := r.(pool.LogTailRunner); ok {
+			go tailRunner(ctx, tr, r.Address(), fnID, lines)
+		}
+	}
+	return lines, cancel
+}
+
+// tailRunner tails the output of the containers of fnID on a runner until ctx is done
+func tailRunner(ctx context.Context, r pool.LogTailRunner, addr, fnID string, lines chan<- *models.LogLine) {
+	log := common.Logger(ctx).WithField("runner_addr", addr).WithField("fn_id", fnID)
+	for {
+		err := r.TailLogs(ctx, fnID, lines)
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			log.WithError(err).Info("Runner does not tail logs")
+			return
+		}
+		log.WithError(err).Warn("Log tail of runner failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tailRetryDelay):
+		}
+	}
+}
+
+// TailLogs implements LogTailer, it tails the runners of the pool
+func (a *lbAgent) TailLogs(fnID string) (<-chan *models.LogLine, func()) {
+	return NewRunnerLogTailer(a.rp).TailLogs(fnID)
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+type tailingRunner struct {
+	mockRunner
+	tail *logTail
+}
+
+func (r *tailingRunner) TailLogs(ctx context.Context, fnID string, lines chan<- *models.LogLine) error {
+	rl, cancel := r.tail.subscribe(fnID)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case l := <-rl:
+			lines <- l
+		}
+	}
+}
+
+func TestRunnerLogTailer(t *testing.T) {
+	tail1, tail2 := newLogTail(), newLogTail()
+	rp := &listingRunnerPool{mockRunnerPool{runners: []pool.Runner{
+		&tailingRunner{mockRunner: mockRunner{addr: "runner1"}, tail: tail1},
+		&tailingRunner{mockRunner: mockRunner{addr: "runner2"}, tail: tail2},
+		&mockRunner{addr: "runner3"},
+	}}}
+	a := &lbAgent{rp: rp}
+
+	lines, cancel := a.TailLogs("fn")
+	waitTailing := func(tail *logTail, tailing bool) {
+		for i := 0; tail.tailing("fn") != tailing; i++ {
+			if i > 100 {
+				t.Fatalf("expected the runner tailing to be %v", tailing)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitTailing(tail1, true)
+	waitTailing(tail2, true)
+
+	newTailWriter(tail1, "fn", "call1").Write([]byte("one\n"))
+	newTailWriter(tail2, "fn", "call2").Write([]byte("two\n"))
+	got := make(map[string]string)
+	for len(got) < 2 {
+		select {
+		case l := <-lines:
+			got[l.CallID] = l.Line
+		case <-time.After(time.Second):
+			t.Fatalf("expected the lines of both runners, got %v", got)
+		}
+	}
+	if got["call1"] != "one" || got["call2"] != "two" {
+		t.Fatalf("unexpected lines %v", got)
+	}
+
+	cancel()
+	waitTailing(tail1, false)
+	waitTailing(tail2, false)
+}
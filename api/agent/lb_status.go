@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+// NodeStatus implements StatusProvider, it sums the status of the runners of the pool and
+// lists the status of each. It returns nil if the pool cannot list its runners.
+func (a *lbAgent) NodeStatus(ctx context.Context) *models.NodeStatus {
+	lister, ok := a.rp.(pool.RunnerLister)
+	if !ok {
+		return nil
+	}
+	runners, err := lister.ListRunners(ctx)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("Failed to list the runners of the pool")
+		return nil
+	}
+
+	statuses := make([]*models.RunnerNodeStatus, len(runners))
+	var wg sync.WaitGroup
+	for i, r := range runners {
+		wg.Add(1)
+		go func(i int, r pool.Runner) {
+			defer wg.Done()
+			statuses[i] = runnerNodeStatus(ctx, r)
+		}(i, r)
+	}
+	wg.Wait()
+
+	status := sumNodeStatus(statuses)
+	select {
+	case <-a.shutWg.Closer():
+		status.Draining = true
+	default:
+	}
+	return status
+}
+
+// runnerNodeStatus fetches the status of a runner, recording why it could not
+func runnerNodeStatus(ctx context.Context, r pool.Runner) *models.RunnerNodeStatus {
+	status := &models.RunnerNodeStatus{Address: r.Address()}
+
+	sr, ok := r.(pool.NodeStatusRunner)
+	if !ok {
+		status.Error = models.ErrNodeStatusUnavailable.Error()
+		return status
+	}
+	nodeStatus, err := sr.NodeStatus(ctx)
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithField("runner_addr", r.Address()).Warn("Failed to fetch the status of a runner")
+		status.Error = err.Error()
+		return status
+	}
+	status.Status = nodeStatus
+	return status
+}
+
+// sumNodeStatus returns the sum of the status of the runners which reported one, a
+// function reports the containers and calls it has on all of them.
+func sumNodeStatus(runners []*models.RunnerNodeStatus) *models.NodeStatus {
+	status := &models.NodeStatus{
+		Functions: []*models.FnStatus{},
+		Runners:   runners,
+	}
+	fns := make(map[string]*models.FnStatus)
+	unlimited := false
+
+	for _, r := range runners {
+		rs := r.Status
+		if rs == nil {
+			continue
+		}
+		status.InFlight += rs.InFlight
+		status.MaxInFlight += rs.MaxInFlight
+		unlimited = unlimited || rs.MaxInFlight == 0
+
+		status.Resources.MemoryTotal += rs.Resources.MemoryTotal
+		status.Resources.MemoryUsed += rs.Resources.MemoryUsed
+		status.Resources.MemoryFree += rs.Resources.MemoryFree
+		status.Resources.CPUTotal += rs.Resources.CPUTotal
+		status.Resources.CPUUsed += rs.Resources.CPUUsed
+		status.Resources.CPUFree += rs.Resources.CPUFree
+
+		for _, rfn := range rs.Functions {
+			fn, ok := fns[rfn.FnID]
+			if !ok {
+				fn = &models.FnStatus{FnID: rfn.FnID, Containers: make(map[string]uint64)}
+				fns[rfn.FnID] = fn
+				status.Functions = append(status.Functions, fn)
+			}
+			for state, n := range rfn.Containers {
+				fn.Containers[state] += n
+			}
+			fn.QueueDepth += rfn.QueueDepth
+			fn.Executing += rfn.Executing
+		}
+	}
+
+	// a single runner without a limit lifts the limit of the pool
+	if unlimited {
+		status.MaxInFlight = 0
+	}
+	sort.Slice(status.Functions, func(i, j int) bool { return status.Functions[i].FnID < status.Functions[j].FnID })
+	return status
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	pool "github.com/fnproject/fn/api/runnerpool"
+)
+
+type statusRunner struct {
+	mockRunner
+	status *models.NodeStatus
+	err    error
+}
+
+func (r *statusRunner) NodeStatus(context.Context) (*models.NodeStatus, error) {
+	return r.status, r.err
+}
+
+type listingRunnerPool struct {
+	mockRunnerPool
+}
+
+func (rp *listingRunnerPool) ListRunners(ctx context.Context) ([]pool.Runner, error) {
+	return rp.runners, nil
+}
+
+func TestLBNodeStatus(t *testing.T) {
+	runners := []pool.Runner{
+		&statusRunner{mockRunner: mockRunner{addr: "runner1"}, status: &models.NodeStatus{
+			InFlight:    2,
+			MaxInFlight: 10,
+			Resources:   models.NodeResources{MemoryTotal: 1024, MemoryUsed: 256, MemoryFree: 768, CPUTotal: 1000},
+			Functions: []*models.FnStatus{
+				{FnID: "fn2", Containers: map[string]uint64{"busy": 1}, Executing: 1},
+				{FnID: "fn1", Containers: map[string]uint64{"idle": 1}},
+			},
+		}},
+		&statusRunner{mockRunner: mockRunner{addr: "runner2"}, status: &models.NodeStatus{
+			InFlight:    1,
+			MaxInFlight: 5,
+			Resources:   models.NodeResources{MemoryTotal: 512, MemoryUsed: 512, CPUTotal: 500},
+			Functions: []*models.FnStatus{
+				{FnID: "fn2", Containers: map[string]uint64{"busy": 1, "idle": 2}, QueueDepth: 3},
+			},
+		}},
+		&statusRunner{mockRunner: mockRunner{addr: "runner3"}, err: errors.New("unreachable")},
+		&mockRunner{addr: "runner4"},
+	}
+	a := &lbAgent{rp: &listingRunnerPool{mockRunnerPool{runners: runners}}, shutWg: common.NewWaitGroup()}
+
+	status := a.NodeStatus(context.Background())
+	if status == nil {
+		t.Fatal("expected the status of the runners")
+	}
+	if status.Draining || status.InFlight != 3 || status.MaxInFlight != 15 {
+		t.Fatalf("unexpected calls in flight %+v", status)
+	}
+	expectedResources := models.NodeResources{MemoryTotal: 1536, MemoryUsed: 768, MemoryFree: 768, CPUTotal: 1500}
+	if status.Resources != expectedResources {
+		t.Fatalf("expected resources %+v, got %+v", expectedResources, status.Resources)
+	}
+
+	if len(status.Functions) != 2 || status.Functions[0].FnID != "fn1" || status.Functions[1].FnID != "fn2" {
+		t.Fatalf("expected the functions of all runners sorted by id, got %+v", status.Functions)
+	}
+	fn2 := status.Functions[1]
+	if fn2.Containers["busy"] != 2 || fn2.Containers["idle"] != 2 || fn2.Executing != 1 || fn2.QueueDepth != 3 {
+		t.Fatalf("expected the containers and calls of fn2 on both runners, got %+v", fn2)
+	}
+
+	if len(status.Runners) != 4 {
+		t.Fatalf("expected the status of each runner, got %+v", status.Runners)
+	}
+	for i, r := range status.Runners {
+		if r.Address != runners[i].Address() {
+			t.Fatalf("Test %d: expected runner %s, got %s", i, runners[i].Address(), r.Address)
+		}
+		if (r.Status == nil) != (i >= 2) || (r.Error == "") != (i < 2) {
+			t.Fatalf("Test %d: unexpected runner status %+v", i, r)
+		}
+	}
+	if status.Runners[2].Error != "unreachable" || status.Runners[3].Error != models.ErrNodeStatusUnavailable.Error() {
+		t.Fatalf("unexpected runner errors %q %q", status.Runners[2].Error, status.Runners[3].Error)
+	}
+
+	// a runner without a limit of calls in flight lifts the limit of the pool
+	runners[1].(*statusRunner).status.MaxInFlight = 0
+	if status := a.NodeStatus(context.Background()); status.MaxInFlight != 0 {
+		t.Fatalf("expected no limit of calls in flight, got %d", status.MaxInFlight)
+	}
+
+	a.shutWg.CloseGroup()
+	if !a.NodeStatus(context.Background()).Draining {
+		t.Fatal("expected the load balancer to be draining once closed")
+	}
+
+	// pools which cannot list their runners have no status
+	a = &lbAgent{rp: &mockRunnerPool{runners: runners}, shutWg: common.NewWaitGroup()}
+	if status := a.NodeStatus(context.Background()); status != nil {
+		t.Fatalf("expected no status, got %+v", status)
+	}
+}
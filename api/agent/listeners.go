@@ -3,8 +3,10 @@ package agent
 import (
 	"context"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
 )
 
 type callTrigger interface {
@@ -16,6 +18,17 @@ func (a *agent) AddCallListener(listener fnext.CallListener) {
 	a.callListeners = append(a.callListeners, listener)
 }
 
+// AddContainerListener implements drivers.ContainerListenerAdder, the listener is
+// ignored if the driver does not support it.
+func (a *agent) AddContainerListener(listener drivers.ContainerListener) {
+	adder, ok := a.driver.(drivers.ContainerListenerAdder)
+	if !ok {
+		logrus.Warn("driver does not support container listeners, ignoring listener")
+		return
+	}
+	adder.AddContainerListener(listener)
+}
+
 func (a *agent) fireBeforeCall(ctx context.Context, call *models.Call) error {
 	return fireBeforeCallFun(a.callListeners, ctx, call)
 }
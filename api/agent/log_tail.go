@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// tailBuffer is the number of lines buffered for each tail, lines are dropped for tails
+	// not read fast enough
+	tailBuffer = 256
+
+	// maxTailLine is the size lines are split at
+	maxTailLine = 64 << 10
+)
+
+// LogTailer is implemented by an Agent which streams the output of the containers it runs
+// during calls, the output of hot containers between calls is not tailed.
+type LogTailer interface {
+	// TailLogs streams the lines written by the containers of fnID, those running and those
+	// started later, until cancel is called. Lines are dropped once lines is full.
+	TailLogs(fnID string) (lines <-chan *models.LogLine, cancel func())
+}
+
+// logTail dispatches the lines of the containers of functions to their tails
+type logTail struct {
+	mu    sync.RWMutex
+	tails map[string]map[chan *models.LogLine]struct{}
+}
+
+func newLogTail() *logTail {
+	return &logTail{tails: make(map[string]map[chan *models.LogLine]struct{})}
+}
+
+func (t *logTail) subscribe(fnID string) (<-chan *models.LogLine, func()) {
+	lines := make(chan *models.LogLine, tailBuffer)
+	t.mu.Lock()
+	if t.tails[fnID] == nil {
+		t.tails[fnID] = make(map[chan *models.LogLine]struct{})
+	}
+	t.tails[fnID][lines] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return lines, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.tails[fnID], lines)
+			if len(t.tails[fnID]) == 0 {
+				delete(t.tails, fnID)
+			}
+			t.mu.Unlock()
+		})
+	}
+}
+
+// tailing returns true if fnID has tails
+func (t *logTail) tailing(fnID string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.tails[fnID]) > 0
+}
+
+func (t *logTail) publish(line *models.LogLine) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for lines := range t.tails[line.FnID] {
+		select {
+		case lines <- line:
+		default:
+		}
+	}
+}
+
+// TailLogs implements LogTailer
+func (a *agent) TailLogs(fnID string) (<-chan *models.LogLine, func()) {
+	return a.logTail.subscribe(fnID)
+}
+
+// tailWriter publishes the lines written to it by a call while its function is tailed, the
+// output written while it is not is discarded
+type tailWriter struct {
+	tail   *logTail
+	fnID   string
+	callID string
+	b      bytes.Buffer
+}
+
+func newTailWriter(tail *logTail, fnID, callID string) *tailWriter {
+	return &tailWriter{tail: tail, fnID: fnID, callID: callID}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	if !w.tail.tailing(w.fnID) {
+		w.b.Reset()
+		return len(p), nil
+	}
+
+	w.b.Write(p)
+	for {
+		b := w.b.Bytes()
+		i := bytes.IndexByte(b, '\n')
+		switch {
+		case i >= 0 && i <= maxTailLine:
+			w.publish(w.b.Next(i + 1)[:i])
+		case len(b) >= maxTailLine:
+			w.publish(w.b.Next(maxTailLine))
+		default:
+			return len(p), nil
+		}
+	}
+}
+
+func (w *tailWriter) publish(line []byte) {
+	w.tail.publish(&models.LogLine{
+		FnID:   w.fnID,
+		CallID: w.callID,
+		Time:   common.DateTime(time.Now()),
+		Line:   string(line),
+	})
+}
+
+// Close publishes the last line if it has no newline
+func (w *tailWriter) Close() error {
+	if w.b.Len() > 0 && w.tail.tailing(w.fnID) {
+		w.publish(w.b.Bytes())
+	}
+	w.b.Reset()
+	return nil
+}
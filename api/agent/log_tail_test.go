@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func readLines(lines <-chan *models.LogLine) []*models.LogLine {
+	var res []*models.LogLine
+	for {
+		select {
+		case l := <-lines:
+			res = append(res, l)
+		default:
+			return res
+		}
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	tail := newLogTail()
+	w := newTailWriter(tail, "fn", "call")
+
+	// output is discarded until the fn is tailed
+	w.Write([]byte("before\npart"))
+	lines, cancel := tail.subscribe("fn")
+	other, cancelOther := tail.subscribe("other_fn")
+	defer cancelOther()
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\nthird"))
+	w.Close()
+
+	got := readLines(lines)
+	var text []string
+	for _, l := range got {
+		if l.FnID != "fn" || l.CallID != "call" {
+			t.Fatalf("expected the lines to be of the call, got %+v", l)
+		}
+		text = append(text, l.Line)
+	}
+	if strings.Join(text, "|") != "first|second|third" {
+		t.Fatalf("expected the lines written once tailed, got %q", text)
+	}
+	if l := readLines(other); len(l) != 0 {
+		t.Fatalf("expected the tail of another fn not to get lines, got %d", len(l))
+	}
+
+	cancel()
+	cancel()
+	if tail.tailing("fn") {
+		t.Fatal("expected the fn not to be tailed once its tail is cancelled")
+	}
+}
+
+func TestTailWriterLongLine(t *testing.T) {
+	tail := newLogTail()
+	lines, cancel := tail.subscribe("fn")
+	defer cancel()
+
+	w := newTailWriter(tail, "fn", "call")
+	w.Write([]byte(strings.Repeat("a", maxTailLine+1) + "\n"))
+	got := readLines(lines)
+	if len(got) != 2 || len(got[0].Line) != maxTailLine || got[1].Line != "a" {
+		t.Fatalf("expected the line to be split at the max line size, got %d lines", len(got))
+	}
+}
+
+func TestTailDropsLines(t *testing.T) {
+	tail := newLogTail()
+	lines, cancel := tail.subscribe("fn")
+	defer cancel()
+
+	w := newTailWriter(tail, "fn", "call")
+	w.Write([]byte(strings.Repeat("line\n", tailBuffer+10)))
+	if got := readLines(lines); len(got) != tailBuffer {
+		t.Fatalf("expected the lines past the buffer to be dropped, got %d", len(got))
+	}
+}
+
+func TestLoggerTail(t *testing.T) {
+	tail := newLogTail()
+	lines, cancel := tail.subscribe("fn")
+	defer cancel()
+
+	call := &models.Call{ID: "call", FnID: "fn"}
+	logger := setupLogger(context.Background(), 1024, false, call, tail)
+	logger.Write([]byte("hello\n"))
+	got := readLines(lines)
+	logger.Close()
+
+	if len(got) != 1 || got[0].Line != "hello" || got[0].CallID != "call" {
+		t.Fatalf("expected the line of the call to be tailed, got %+v", got)
+	}
+}
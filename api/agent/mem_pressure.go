@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// watchMemoryPressure evicts idle hot containers while free memory of the host is below
+// cfg.MemoryPressureFree percent. With memory overcommit, containers reserve more memory
+// than the host has, which holds up as long as idle containers use little of it. Once
+// the host runs low, evicting idle containers beats waking up the OOM killer.
+//
+// Paused containers are evicted first, the ones paused the longest first, while free
+// memory is below cfg.EvictPausedFree percent, with or without overcommit. Paused
+// containers are the least likely to serve a call soon, so they go before the kernel
+// runs out of memory and kills containers running calls.
+func (a *agent) watchMemoryPressure() {
+	defer a.shutWg.DoneSession()
+
+	total, err := checkProcMemTotal()
+	if err != nil {
+		logrus.WithError(err).Error("cannot read host memory, memory pressure eviction disabled")
+		return
+	}
+	var low uint64
+	if a.cfg.MemoryOvercommit > 100 {
+		low = total / 100 * a.cfg.MemoryPressureFree
+	}
+	pausedLow := total / 100 * a.cfg.EvictPausedFree
+
+	ticker := time.NewTicker(a.cfg.MemoryPressurePoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.shutWg.Closer(): // server shutdown
+			return
+		case <-ticker.C:
+		}
+
+		avail, err := checkProcMem()
+		if err != nil {
+			logrus.WithError(err).Error("cannot read available memory")
+			continue
+		}
+
+		var notifyChans []chan struct{}
+		if need := memoryPressureNeed(avail, pausedLow); need > 0 {
+			notifyChans = a.evictor.PerformReclaimPaused(need)
+			if len(notifyChans) > 0 {
+				logrus.WithFields(logrus.Fields{
+					"avail_memory": avail,
+					"low_memory":   pausedLow,
+					"evicted":      len(notifyChans),
+				}).Warn("memory pressure, evicting paused containers")
+			}
+		}
+		// idle containers only go once there are no more paused containers to evict
+		if need := memoryPressureNeed(avail, low); need > 0 && len(notifyChans) == 0 {
+			notifyChans = a.evictor.PerformReclaim(need)
+
+			logrus.WithFields(logrus.Fields{
+				"avail_memory": avail,
+				"low_memory":   low,
+				"evicted":      len(notifyChans),
+			}).Warn("memory pressure, evicting idle containers")
+		}
+
+		// let evicted containers go away before checking again
+		for _, wait := range notifyChans {
+			select {
+			case <-wait:
+			case <-a.shutWg.Closer(): // server shutdown
+				return
+			}
+		}
+	}
+}
+
+// memoryPressureNeed returns the memory in MB to reclaim for avail bytes of free memory
+// to get back to low bytes, 0 if there is no memory pressure.
+func memoryPressureNeed(avail, low uint64) uint64 {
+	if avail >= low {
+		return 0
+	}
+	return (low - avail + Mem1MB - 1) / Mem1MB
+}
@@ -20,6 +20,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/fnproject/fn/api/agent/drivers"
 	"github.com/fnproject/fn/api/agent/grpc"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/id"
@@ -584,6 +585,14 @@ func (pr *pureRunner) GetCall(opts ...CallOpt) (Call, error) {
 	return pr.a.GetCall(opts...)
 }
 
+// NodeStatus implements StatusProvider, it returns nil unless the agent of the runner does.
+func (pr *pureRunner) NodeStatus(ctx context.Context) *models.NodeStatus {
+	if sp, ok := pr.a.(StatusProvider); ok {
+		return sp.NodeStatus(ctx)
+	}
+	return nil
+}
+
 // implements Agent
 func (pr *pureRunner) Submit(Call) error {
 	return errors.New("Submit cannot be called directly in a Pure Runner.")
@@ -606,6 +615,13 @@ func (pr *pureRunner) AddCallListener(cl fnext.CallListener) {
 	pr.a.AddCallListener(cl)
 }
 
+// implements drivers.ContainerListenerAdder
+func (pr *pureRunner) AddContainerListener(cl drivers.ContainerListener) {
+	if adder, ok := pr.a.(drivers.ContainerListenerAdder); ok {
+		adder.AddContainerListener(cl)
+	}
+}
+
 func (pr *pureRunner) saveCallHandle(ch *callHandle) {
 	pr.callHandleLock.Lock()
 	pr.callHandleMap[ch.c.Model().ID] = ch
@@ -978,6 +994,45 @@ func (pr *pureRunner) Status(ctx context.Context, _ *empty.Empty) (*runner.Runne
 	return status, err
 }
 
+// implements RunnerProtocolServer
+func (pr *pureRunner) GetNodeStatus(ctx context.Context, _ *empty.Empty) (*runner.RunnerNodeStatus, error) {
+	status := pr.NodeStatus(ctx)
+	if status == nil {
+		return &runner.RunnerNodeStatus{}, nil
+	}
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	return &runner.RunnerNodeStatus{ModelsNodeStatusJson: string(statusJSON)}, nil
+}
+
+// implements RunnerProtocolServer
+func (pr *pureRunner) TailLogs(req *runner.TailLogsRequest, stream runner.RunnerProtocol_TailLogsServer) error {
+	tailer, ok := pr.a.(LogTailer)
+	if !ok {
+		return status.Error(codes.Unimplemented, "Log tailing is not supported by this runner")
+	}
+	lines, cancel := tailer.TailLogs(req.FnId)
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line := <-lines:
+			lineJSON, err := json.Marshal(line)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(&runner.LogTailLine{ModelsLogLineJson: string(lineJSON)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // BeforeCall called before a function is executed
 func (pr *pureRunner) BeforeCall(ctx context.Context, call *models.Call) error {
 	if call.Type != models.TypeDetached {
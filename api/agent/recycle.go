@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// replacementState tracks the state of a container started to replace a recycled one, ready
+// is closed once the replacement takes calls or fails to start.
+type replacementState struct {
+	ContainerState
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func newReplacementState() *replacementState {
+	return &replacementState{
+		ContainerState: NewContainerState(),
+		ready:          make(chan struct{}),
+	}
+}
+
+func (s *replacementState) UpdateState(ctx context.Context, newState ContainerStateType, slots *slotQueue) {
+	s.ContainerState.UpdateState(ctx, newState, slots)
+	if newState >= ContainerStateIdle {
+		s.readyOnce.Do(func() { close(s.ready) })
+	}
+}
+
+// checkRecycle recycles the hot container once it reaches a limit of the recycle policy of its
+// function. The container keeps taking calls until a replacement is ready, then it drains.
+func (a *agent) checkRecycle(ctx context.Context, call *call, logger logrus.FieldLogger, container *container, hc *hotContainerSlots) {
+	if call.RecyclePolicy.IsEmpty() {
+		return
+	}
+	reason := hc.tryRecycle(call.RecyclePolicy, container.memoryUsage())
+	if reason == "" {
+		return
+	}
+
+	logger.WithField("recycle_reason", reason).Info("recycling hot container")
+	statsContainerRecycled(ctx, reason)
+	go func() {
+		a.replaceHot(ctx, call, logger)
+		hc.startDrain()
+	}()
+}
+
+// replaceHot starts a hot container for the function of call and waits for it to take calls.
+// The replacement only uses free capacity, if there is none the recycled container drains
+// first and calls start a new container once it is gone. The replacement may briefly exceed
+// the max containers of the function, as the recycled container still counts towards it.
+func (a *agent) replaceHot(ctx context.Context, call *call, logger logrus.FieldLogger) {
+	mem := call.Memory + uint64(call.TmpFsSize)
+
+	var tok ResourceToken
+	select {
+	case tok = <-a.resources.GetResourceToken(ctx, mem, call.CPUs, models.PriorityLow, true):
+	case <-ctx.Done(): // container shutdown
+		return
+	case <-a.shutWg.Closer(): // agent shutdown
+		return
+	}
+	if tok.Error() != nil {
+		logger.WithError(tok.Error()).Info("cannot start replacement of recycled hot container")
+		tok.Close()
+		return
+	}
+
+	state := newReplacementState()
+	state.UpdateState(ctx, ContainerStateWait, call.slots)
+	if !a.shutWg.AddSession(1) {
+		state.UpdateState(ctx, ContainerStateDone, call.slots)
+		tok.Close()
+		return
+	}
+	go func() {
+		a.runHot(ctx, slotCaller{}, call, tok, state)
+		a.shutWg.DoneSession()
+	}()
+
+	select {
+	case <-state.ready:
+	case <-ctx.Done(): // container shutdown
+	case <-a.shutWg.Closer(): // agent shutdown
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -52,9 +53,10 @@ type ResourceTracker interface {
 	// the channel will never receive anything. If it is not possible to fulfill this resource, the channel
 	// will never receive anything (use IsResourcePossible). If a resource token is available for the provided
 	// resource parameters, it will otherwise be sent once on the returned channel. The channel is never closed.
-	// if isNB is set, resource check is done and error token is returned without blocking.
+	// if isNB is set, resource check is done and error token is returned without blocking. Blocked
+	// requests are served in priority order, see models.PriorityLow, PriorityNormal and PriorityHigh.
 	// Memory is expected to be provided in MB units.
-	GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, isNB bool) <-chan ResourceToken
+	GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, priority int32, isNB bool) <-chan ResourceToken
 
 	// IsResourcePossible returns whether it's possible to fulfill the requested resources on this
 	// machine. It must be called before GetResourceToken or GetResourceToken may hang.
@@ -80,6 +82,8 @@ type resourceTracker struct {
 	cpuUsed uint64
 	// cpu in use in which agent stops dequeuing async jobs
 	cpuAsyncHWMark uint64
+	// waiters is the number of blocked requests per priority
+	waiters [models.PriorityHigh + 1]uint64
 }
 
 func NewResourceTracker(cfg *Config) ResourceTracker {
@@ -133,6 +137,26 @@ func (a *resourceTracker) isResourceAvailableLocked(memory uint64, cpuQuota mode
 	return availMem >= memory && availCPU >= uint64(cpuQuota)
 }
 
+// isOutrankedLocked returns true if requests of a higher priority are blocked.
+func (a *resourceTracker) isOutrankedLocked(priority int32) bool {
+	for p := priority + 1; p <= models.PriorityHigh; p++ {
+		if a.waiters[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutrankingLocked returns true if requests of a lower priority are blocked.
+func (a *resourceTracker) isOutrankingLocked(priority int32) bool {
+	for p := models.PriorityLow; p < priority; p++ {
+		if a.waiters[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *resourceTracker) GetUtilization() ResourceUtilization {
 	var util ResourceUtilization
 
@@ -226,7 +250,7 @@ func (a *resourceTracker) getResourceTokenNBChan(ctx context.Context, memory uin
 
 // the received token should be passed directly to launch (unconditionally), launch
 // will close this token (i.e. the receiver should not call Close)
-func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, isNB bool) <-chan ResourceToken {
+func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, cpuQuota models.MilliCPUs, priority int32, isNB bool) <-chan ResourceToken {
 	if isNB {
 		return a.getResourceTokenNBChan(ctx, memory, cpuQuota)
 	}
@@ -240,6 +264,7 @@ func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, c
 
 	c := a.cond
 	isWaiting := false
+	priority = clampPriority(priority)
 
 	memory = memory * Mem1MB
 
@@ -263,18 +288,29 @@ func (a *resourceTracker) GetResourceToken(ctx context.Context, memory uint64, c
 		c.L.Lock()
 
 		isWaiting = true
-		for !a.isResourceAvailableLocked(memory, cpuQuota) && ctx.Err() == nil {
+		a.waiters[priority] += 1
+		for (!a.isResourceAvailableLocked(memory, cpuQuota) || a.isOutrankedLocked(priority)) && ctx.Err() == nil {
 			c.Wait()
 		}
 		isWaiting = false
+		a.waiters[priority] -= 1
+
+		// requests of a lower priority may go ahead once we are no longer waiting
+		isOutranking := a.isOutrankingLocked(priority)
 
 		if ctx.Err() != nil {
 			c.L.Unlock()
+			if isOutranking {
+				c.Broadcast()
+			}
 			return
 		}
 
 		t := a.allocResourcesLocked(memory, cpuQuota)
 		c.L.Unlock()
+		if isOutranking {
+			c.Broadcast()
+		}
 
 		select {
 		case ch <- t:
@@ -328,6 +364,17 @@ func (a *resourceTracker) WaitAsyncResource(ctx context.Context) chan struct{} {
 	return ch
 }
 
+// clampPriority returns priority within the range of priority classes.
+func clampPriority(priority int32) int32 {
+	if priority < models.PriorityLow {
+		return models.PriorityLow
+	}
+	if priority > models.PriorityHigh {
+		return models.PriorityHigh
+	}
+	return priority
+}
+
 func minUint64(a, b uint64) uint64 {
 	if a <= b {
 		return a
@@ -449,6 +496,17 @@ func (a *resourceTracker) initializeMemory(cfg *Config) {
 		availMemory = minUint64(cfg.MaxTotalMemory, availMemory)
 	}
 
+	// with overcommit, idle containers are kept beyond the available memory and evicted
+	// under memory pressure (see watchMemoryPressure)
+	if cfg != nil && cfg.MemoryOvercommit > 100 {
+		logrus.WithFields(logrus.Fields{
+			"avail_memory":      availMemory,
+			"memory_overcommit": cfg.MemoryOvercommit,
+		}).Info("memory overcommit enabled")
+
+		availMemory = availMemory * cfg.MemoryOvercommit / 100
+	}
+
 	a.ramTotal = availMemory
 	a.ramAsyncHWMark = availMemory * 8 / 10
 
@@ -477,7 +535,11 @@ func getMemoryHeadRoom(usableMemory uint64, cfg *Config) (uint64, error) {
 	// instead of estimate below.
 	// if pre-fork pool is enabled, add 1 MB per pool-item
 	if cfg != nil && cfg.PreForkPoolSize != 0 {
-		headRoom += Mem1MB * cfg.PreForkPoolSize
+		poolSize := cfg.PreForkPoolSize
+		if cfg.PreForkPoolMaxSize > poolSize {
+			poolSize = cfg.PreForkPoolMaxSize
+		}
+		headRoom += Mem1MB * poolSize
 	}
 
 	// TODO: improve these calculations.
@@ -503,6 +565,11 @@ func readString(fileName string) (string, error) {
 }
 
 func checkCgroupMem() (uint64, error) {
+	// cgroup v2 unified hierarchy
+	if value, err := readString("/sys/fs/cgroup/memory.max"); err == nil {
+		return parseCgroupV2Mem(value)
+	}
+
 	value, err := readString("/sys/fs/cgroup/memory/memory.limit_in_bytes")
 	if err != nil {
 		return 0, err
@@ -510,8 +577,42 @@ func checkCgroupMem() (uint64, error) {
 	return strconv.ParseUint(value, 10, 64)
 }
 
+// parseCgroupV2Mem parses memory.max, which is either a limit in bytes or 'max'
+func parseCgroupV2Mem(value string) (uint64, error) {
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// parseCgroupV2CPU parses cpu.max, which is formatted as '$QUOTA $PERIOD' where
+// quota may be 'max', into milli cpus. Returns 0 if there is no limit.
+func parseCgroupV2CPU(value string) uint64 {
+	fields := strings.Fields(value)
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+
+	quota, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		logrus.Warn("Cannot parse cpu.max quota", err)
+		return 0
+	}
+	period, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || period == 0 {
+		logrus.Warn("Cannot parse cpu.max period", err)
+		return 0
+	}
+	return quota * 1000 / period
+}
+
 func checkCgroupCPU() uint64 {
 
+	// cgroup v2 unified hierarchy
+	if value, err := readString("/sys/fs/cgroup/cpu.max"); err == nil {
+		return parseCgroupV2CPU(value)
+	}
+
 	periodStr, err := readString("/sys/fs/cgroup/cpu/cpu.cfs_period_us")
 	if err != nil {
 		return 0
@@ -542,7 +643,17 @@ func checkCgroupCPU() uint64 {
 
 var errCantReadMemInfo = errors.New("Didn't find MemAvailable in /proc/meminfo, kernel is probably < 3.14")
 
+// checkProcMem returns the memory available on the host in bytes
 func checkProcMem() (uint64, error) {
+	return checkProcMemInfo("MemAvailable", errCantReadMemInfo)
+}
+
+// checkProcMemTotal returns the total memory of the host in bytes
+func checkProcMemTotal() (uint64, error) {
+	return checkProcMemInfo("MemTotal", errors.New("Didn't find MemTotal in /proc/meminfo"))
+}
+
+func checkProcMemInfo(field string, errNotFound error) (uint64, error) {
 	f, err := os.Open("/proc/meminfo")
 	if err != nil {
 		return 0, err
@@ -552,7 +663,7 @@ func checkProcMem() (uint64, error) {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		b := scanner.Text()
-		if !strings.HasPrefix(b, "MemAvailable") {
+		if !strings.HasPrefix(b, field+":") {
 			continue
 		}
 
@@ -560,12 +671,12 @@ func checkProcMem() (uint64, error) {
 		// MemAvailable: 1234567890 kB
 		tri := strings.Fields(b)
 		if len(tri) != 3 {
-			return 0, fmt.Errorf("MemAvailable line has unexpected format: %v", b)
+			return 0, fmt.Errorf("%s line has unexpected format: %v", field, b)
 		}
 
 		c, err := strconv.ParseUint(tri[1], 10, 64)
 		if err != nil {
-			return 0, fmt.Errorf("Could not parse MemAvailable: %v", b)
+			return 0, fmt.Errorf("Could not parse %s: %v", field, b)
 		}
 		switch tri[2] { // convert units to bytes
 		case "kB":
@@ -573,12 +684,12 @@ func checkProcMem() (uint64, error) {
 		case "MB":
 			c *= 1024 * 1024
 		default:
-			return 0, fmt.Errorf("Unexpected units for MemAvailable in /proc/meminfo, need kB or MB, got: %v", tri[2])
+			return 0, fmt.Errorf("Unexpected units for %s in /proc/meminfo, need kB or MB, got: %v", field, tri[2])
 		}
 		return c, nil
 	}
 
-	return 0, errCantReadMemInfo
+	return 0, errNotFound
 }
 
 func checkProcCPU() (uint64, error) {
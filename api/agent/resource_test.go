@@ -3,8 +3,11 @@ package agent
 import (
 	"context"
 	"errors"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 func setTrackerTestVals(tr *resourceTracker, vals *trackerVals) {
@@ -163,7 +166,7 @@ func TestResourceGetSimple(t *testing.T) {
 
 	// ask for 4GB and 10 CPU
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := trI.GetResourceToken(ctx, 4*1024, 1000, false)
+	ch := trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, false)
 	defer cancel()
 
 	_, err := fetchToken(ch)
@@ -182,7 +185,7 @@ func TestResourceGetSimple(t *testing.T) {
 
 	// ask for another 4GB and 10 CPU
 	ctx, cancel = context.WithCancel(context.Background())
-	ch = trI.GetResourceToken(ctx, 4*1024, 1000, false)
+	ch = trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, false)
 	defer cancel()
 
 	_, err = fetchToken(ch)
@@ -226,7 +229,7 @@ func TestResourceGetSimpleNB(t *testing.T) {
 
 	// ask for 4GB and 10 CPU
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	ch := trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, true)
 	defer cancel()
 
 	tok := <-ch
@@ -238,14 +241,14 @@ func TestResourceGetSimpleNB(t *testing.T) {
 	vals.setDefaults()
 	setTrackerTestVals(tr, &vals)
 
-	tok1 := <-trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	tok1 := <-trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, true)
 	if tok1.Error() != nil {
 		t.Fatalf("empty system should hand out token")
 	}
 
 	// ask for another 4GB and 10 CPU
 	ctx, cancel = context.WithCancel(context.Background())
-	ch = trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	ch = trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, true)
 	defer cancel()
 
 	tok = <-ch
@@ -256,7 +259,7 @@ func TestResourceGetSimpleNB(t *testing.T) {
 	// close means, giant token resources released
 	tok1.Close()
 
-	tok = <-trI.GetResourceToken(ctx, 4*1024, 1000, true)
+	tok = <-trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityNormal, true)
 	if tok.Error() != nil {
 		t.Fatalf("empty system should hand out token")
 	}
@@ -272,3 +275,135 @@ func TestResourceGetSimpleNB(t *testing.T) {
 		t.Fatalf("faulty state CPU %#v", vals)
 	}
 }
+
+func TestResourceGetPriority(t *testing.T) {
+
+	var vals trackerVals
+	trI := NewResourceTracker(nil)
+	tr := trI.(*resourceTracker)
+
+	vals.setDefaults()
+
+	// let's make it like CPU and MEM are 100% full
+	vals.mu = vals.mt
+	vals.cu = vals.ct
+
+	setTrackerTestVals(tr, &vals)
+
+	// ask for 4GB and 10 CPU, at low and high priority
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chLow := trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityLow, false)
+	chHigh := trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityHigh, false)
+
+	if _, err := fetchToken(chLow); err == nil {
+		t.Fatalf("full system should not hand out token")
+	}
+	if _, err := fetchToken(chHigh); err == nil {
+		t.Fatalf("full system should not hand out token")
+	}
+
+	// reset back, there is room for one of them
+	vals.setDefaults()
+	setTrackerTestVals(tr, &vals)
+
+	tok, err := fetchToken(chHigh)
+	if err != nil {
+		t.Fatalf("empty system should hand out token to high priority")
+	}
+	if _, err := fetchToken(chLow); err == nil {
+		t.Fatalf("full system should not hand out token")
+	}
+
+	tok.Close()
+
+	tok, err = fetchToken(chLow)
+	if err != nil {
+		t.Fatalf("empty system should hand out token to low priority")
+	}
+	tok.Close()
+
+	// a cancelled high priority request should not hold back low priority ones
+	vals.mu = vals.mt
+	vals.cu = vals.ct
+	setTrackerTestVals(tr, &vals)
+
+	ctxHigh, cancelHigh := context.WithCancel(context.Background())
+	chLow = trI.GetResourceToken(ctx, 4*1024, 1000, models.PriorityLow, false)
+	chHigh = trI.GetResourceToken(ctxHigh, 4*1024, 1000, models.PriorityHigh, false)
+
+	if _, err := fetchToken(chHigh); err == nil {
+		t.Fatalf("full system should not hand out token")
+	}
+	cancelHigh()
+
+	vals.setDefaults()
+	setTrackerTestVals(tr, &vals)
+
+	tok, err = fetchToken(chLow)
+	if err != nil {
+		t.Fatalf("empty system should hand out token to low priority")
+	}
+	tok.Close()
+
+	// POOLS should all be empty now
+	getTrackerTestVals(tr, &vals)
+	if vals.mu != 0 {
+		t.Fatalf("faulty state MEM %#v", vals)
+	}
+	if vals.cu != 0 {
+		t.Fatalf("faulty state CPU %#v", vals)
+	}
+}
+
+func TestResourceOvercommit(t *testing.T) {
+	tr := NewResourceTracker(&Config{MaxTotalMemory: 256 * Mem1MB, MaxTotalCPU: 1000}).(*resourceTracker)
+	if tr.ramTotal != 256*Mem1MB {
+		t.Fatalf("without overcommit expected %d memory, got %d", 256*Mem1MB, tr.ramTotal)
+	}
+
+	tr = NewResourceTracker(&Config{MaxTotalMemory: 256 * Mem1MB, MaxTotalCPU: 1000, MemoryOvercommit: 150}).(*resourceTracker)
+	if tr.ramTotal != 384*Mem1MB {
+		t.Fatalf("with overcommit expected %d memory, got %d", 384*Mem1MB, tr.ramTotal)
+	}
+	if !tr.IsResourcePossible(384, 1000) {
+		t.Fatal("overcommitted memory should be possible to reserve")
+	}
+}
+
+func TestMemoryPressureNeed(t *testing.T) {
+	if need := memoryPressureNeed(2*Mem1GB, 1*Mem1GB); need != 0 {
+		t.Fatalf("no pressure expected, got %d", need)
+	}
+	if need := memoryPressureNeed(1*Mem1GB-Mem1MB, 1*Mem1GB); need != 1 {
+		t.Fatalf("expected to reclaim 1MB, got %d", need)
+	}
+	if need := memoryPressureNeed(1*Mem1GB-Mem1MB-1, 1*Mem1GB); need != 2 {
+		t.Fatalf("expected to reclaim 2MB, got %d", need)
+	}
+}
+
+func TestResourceCgroupV2Parse(t *testing.T) {
+	mem, err := parseCgroupV2Mem("max")
+	if err != nil || mem != math.MaxUint64 {
+		t.Fatalf("unlimited memory.max expected max uint64, got %v err=%v", mem, err)
+	}
+	mem, err = parseCgroupV2Mem("536870912")
+	if err != nil || mem != 536870912 {
+		t.Fatalf("memory.max expected 536870912, got %v err=%v", mem, err)
+	}
+	if _, err = parseCgroupV2Mem("bogus"); err == nil {
+		t.Fatal("invalid memory.max should fail")
+	}
+
+	for value, expected := range map[string]uint64{
+		"max 100000":    0,
+		"150000 100000": 1500,
+		"50000 100000":  500,
+		"bogus":         0,
+	} {
+		if cpu := parseCgroupV2CPU(value); cpu != expected {
+			t.Fatalf("cpu.max %q expected %d milli cpus, got %d", value, expected, cpu)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+)
+
+type recordingMQ struct {
+	pushed []*models.Call
+}
+
+func (mq *recordingMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	mq.pushed = append(mq.pushed, call)
+	return call, nil
+}
+
+func (mq *recordingMQ) Reserve(context.Context) (*models.Call, error) { return nil, nil }
+func (mq *recordingMQ) Delete(context.Context, *models.Call) error    { return nil }
+func (mq *recordingMQ) Close() error                                  { return nil }
+
+func failedAsyncCall(handler CallHandler, attempt int32) *call {
+	return &call{
+		Call: &models.Call{
+			ID:          "call_id",
+			FnID:        "fn_id",
+			Type:        models.TypeAsync,
+			Payload:     "payload",
+			Status:      "error",
+			Error:       "boom",
+			Attempt:     attempt,
+			RetryPolicy: &models.RetryPolicy{MaxAttempts: 3, Backoff: 5},
+		},
+		handler: handler,
+	}
+}
+
+func TestAsyncCallRetry(t *testing.T) {
+	ctx := context.Background()
+	mq := new(recordingMQ)
+	ls := logs.NewMock()
+
+	failedAsyncCall(NewDirectCallDataAccess(ls, mq), 0).retryOrDeadLetter(ctx)
+
+	if len(mq.pushed) != 1 {
+		t.Fatalf("expected a retry to be enqueued, got %d", len(mq.pushed))
+	}
+	retry := mq.pushed[0]
+	if retry.ID == "call_id" || retry.RetryOf != "call_id" || retry.Attempt != 2 || retry.Delay != 5 {
+		t.Fatalf("unexpected retry %+v", retry)
+	}
+	if retry.Status != "queued" || retry.Error != "" || retry.Payload != "payload" {
+		t.Fatalf("expected retry to be queued with the call payload, got %+v", retry)
+	}
+
+	dead, err := ls.GetDeadLetters(ctx, &models.CallFilter{FnID: "fn_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead.Items) != 0 {
+		t.Fatalf("expected retried call not to be dead lettered, got %+v", dead.Items)
+	}
+}
+
+func TestAsyncCallDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	mq := new(recordingMQ)
+	ls := logs.NewMock()
+
+	failedAsyncCall(NewDirectCallDataAccess(ls, mq), 3).retryOrDeadLetter(ctx)
+
+	if len(mq.pushed) != 0 {
+		t.Fatalf("expected no retry after the last attempt, got %d", len(mq.pushed))
+	}
+	if _, err := ls.GetDeadLetter(ctx, "fn_id", "call_id"); err != nil {
+		t.Fatalf("expected call to be dead lettered: %v", err)
+	}
+
+	dlq := new(recordingMQ)
+	failedAsyncCall(NewDirectCallDataAccess(logs.NewMock(), mq, WithDeadLetterQueue(dlq)), 3).retryOrDeadLetter(ctx)
+
+	if len(dlq.pushed) != 1 || dlq.pushed[0].ID != "call_id" || len(mq.pushed) != 0 {
+		t.Fatalf("expected call to be pushed to the dead letter queue, got %+v", dlq.pushed)
+	}
+}
+
+func TestAsyncCallWithoutRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	mq := new(recordingMQ)
+	ls := logs.NewMock()
+
+	c := failedAsyncCall(NewDirectCallDataAccess(ls, mq), 1)
+	c.RetryPolicy = nil
+	c.retryOrDeadLetter(ctx)
+
+	if len(mq.pushed) != 0 {
+		t.Fatalf("expected no retry without a retry policy, got %d", len(mq.pushed))
+	}
+	if _, err := ls.GetDeadLetter(ctx, "fn_id", "call_id"); err != models.ErrDeadLetterNotFound {
+		t.Fatalf("expected call not to be dead lettered, got %v", err)
+	}
+}
+
+type failingMQ struct{ recordingMQ }
+
+func (mq *failingMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	return nil, errors.New("queue is down")
+}
+
+func TestAsyncCallRetryEnqueueFailed(t *testing.T) {
+	ctx := context.Background()
+	ls := logs.NewMock()
+
+	c := failedAsyncCall(NewDirectCallDataAccess(ls, new(failingMQ)), 1)
+	c.retryOrDeadLetter(ctx)
+
+	if c.Status != "error" || !strings.HasPrefix(c.Error, models.ErrCallRetryEnqueue.Error()) || !strings.Contains(c.Error, "queue is down") {
+		t.Fatalf("expected call to fail with the enqueue error, got %q %q", c.Status, c.Error)
+	}
+	if _, err := ls.GetDeadLetter(ctx, "fn_id", "call_id"); err != nil {
+		t.Fatalf("expected call which could not be retried to be dead lettered: %v", err)
+	}
+}
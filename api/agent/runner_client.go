@@ -159,6 +159,54 @@ func (r *gRPCRunner) Status(ctx context.Context) (*pool.RunnerStatus, error) {
 	return TranslateGRPCStatusToRunnerStatus(status), err
 }
 
+// implements NodeStatusRunner
+func (r *gRPCRunner) NodeStatus(ctx context.Context) (*models.NodeStatus, error) {
+	if !r.shutWg.AddSession(1) {
+		return nil, ErrorRunnerClosed
+	}
+	defer r.shutWg.DoneSession()
+
+	status, err := r.client.GetNodeStatus(ctx, &pb_empty.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	// the runner has no status to report, eg. its agent is not a StatusProvider
+	if status.ModelsNodeStatusJson == "" {
+		return nil, models.ErrNodeStatusUnavailable
+	}
+
+	var nodeStatus models.NodeStatus
+	if err := json.Unmarshal([]byte(status.ModelsNodeStatusJson), &nodeStatus); err != nil {
+		return nil, err
+	}
+	return &nodeStatus, nil
+}
+
+// implements LogTailRunner. Tails do not hold the runner open, they end once it is closed.
+func (r *gRPCRunner) TailLogs(ctx context.Context, fnID string, lines chan<- *models.LogLine) error {
+	stream, err := r.client.TailLogs(ctx, &pb.TailLogsRequest{FnId: fnID})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		var line models.LogLine
+		if err := json.Unmarshal([]byte(msg.ModelsLogLineJson), &line); err != nil {
+			return err
+		}
+		select {
+		case lines <- &line:
+		default:
+		}
+	}
+}
+
 // implements Runner
 func (r *gRPCRunner) TryExec(ctx context.Context, call pool.RunnerCall) (bool, error) {
 	log := common.Logger(ctx).WithField("runner_addr", r.address)
@@ -9,7 +9,10 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
+
+	"github.com/fnproject/fn/api/models"
 )
 
 //
@@ -26,14 +29,17 @@ type Slot interface {
 
 // slotQueueMgr manages hot container slotQueues
 type slotQueueMgr struct {
-	hMu sync.Mutex // protects hot
-	hot map[string]*slotQueue
+	hMu     sync.Mutex // protects hot and current
+	hot     map[string]*slotQueue
+	current map[string]*slotQueue // fn id to the slot queue of its latest call
 }
 
 // request and container states
 type slotQueueStats struct {
 	requestStates   [RequestStateMax]uint64
 	containerStates [ContainerStateMax]uint64
+	// queued slots, a container serving calls concurrently queues several
+	queuedSlots uint64
 }
 
 type slotToken struct {
@@ -44,8 +50,9 @@ type slotToken struct {
 }
 
 type slotCaller struct {
-	notify chan error      // notification to caller
-	done   <-chan struct{} // caller done
+	notify   chan error      // notification to caller
+	done     <-chan struct{} // caller done
+	priority int32           // priority of the caller
 }
 
 // LIFO queue that exposes input/output channels along
@@ -58,11 +65,35 @@ type slotQueue struct {
 	signaller chan *slotCaller
 	statsLock sync.Mutex // protects stats below
 	stats     slotQueueStats
+
+	// number of dequeuers per priority, protected by cond.L
+	waiters [models.PriorityHigh + 1]uint64
+
+	// number of containers to keep alive, see setMinReady()
+	minReady   uint64
+	minReadyAt int64
+	// maximum number of containers, see setMaxContainers()
+	maxContainers uint64
+	// idle timeout and scale policy, see setScalePolicy()
+	idleTimeout int64
+	scalePolicy atomic.Value
+
+	retireLock sync.Mutex // protects retiring below
+	retiring   uint64
+
+	rateLock  sync.Mutex // protects the call rate below, see recordCall()
+	rateStart time.Time
+	rateCalls uint64
+	ratePrev  uint64
 }
 
+// callRateWindow is the period over which the call rate of a slot queue is measured
+const callRateWindow = time.Minute
+
 func NewSlotQueueMgr() *slotQueueMgr {
 	obj := &slotQueueMgr{
-		hot: make(map[string]*slotQueue),
+		hot:     make(map[string]*slotQueue),
+		current: make(map[string]*slotQueue),
 	}
 	return obj
 }
@@ -89,6 +120,9 @@ func (a *slotQueue) acquireSlot(s *slotToken) bool {
 	for i := len(a.slots) - 1; i >= 0; i-- {
 		if a.slots[i].id == s.id {
 			a.slots = append(a.slots[:i], a.slots[i+1:]...)
+			a.statsLock.Lock()
+			a.stats.queuedSlots -= 1
+			a.statsLock.Unlock()
 			break
 		}
 	}
@@ -99,15 +133,24 @@ func (a *slotQueue) acquireSlot(s *slotToken) bool {
 	return true
 }
 
-func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
+// startDequeuer returns a channel of slots for the caller, while dequeuers of a higher
+// priority are active no slots are handed out to it.
+func (a *slotQueue) startDequeuer(ctx context.Context, priority int32) chan *slotToken {
 
 	isWaiting := false
 	output := make(chan *slotToken)
+	priority = clampPriority(priority)
+
+	a.cond.L.Lock()
+	a.waiters[priority] += 1
+	a.cond.L.Unlock()
 
 	go func() {
 		<-ctx.Done()
 		a.cond.L.Lock()
-		if isWaiting {
+		a.waiters[priority] -= 1
+		// wake up dequeuers of a lower priority, they may go ahead now
+		if isWaiting || a.isOutrankingLocked(priority) {
 			a.cond.Broadcast()
 		}
 		a.cond.L.Unlock()
@@ -118,7 +161,7 @@ func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
 			a.cond.L.Lock()
 
 			isWaiting = true
-			for len(a.slots) <= 0 && (ctx.Err() == nil) {
+			for (len(a.slots) <= 0 || a.isOutrankedLocked(priority)) && (ctx.Err() == nil) {
 				a.cond.Wait()
 			}
 			isWaiting = false
@@ -142,6 +185,26 @@ func (a *slotQueue) startDequeuer(ctx context.Context) chan *slotToken {
 	return output
 }
 
+// isOutrankedLocked returns true if dequeuers of a higher priority are active.
+func (a *slotQueue) isOutrankedLocked(priority int32) bool {
+	for p := priority + 1; p <= models.PriorityHigh; p++ {
+		if a.waiters[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isOutrankingLocked returns true if dequeuers of a lower priority are active.
+func (a *slotQueue) isOutrankingLocked(priority int32) bool {
+	for p := models.PriorityLow; p < priority; p++ {
+		if a.waiters[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *slotQueue) queueSlot(slot Slot) *slotToken {
 
 	token := &slotToken{slot, make(chan struct{}), 0, 0}
@@ -150,6 +213,9 @@ func (a *slotQueue) queueSlot(slot Slot) *slotToken {
 	token.id = a.nextId
 	a.slots = append(a.slots, token)
 	a.nextId += 1
+	a.statsLock.Lock()
+	a.stats.queuedSlots += 1
+	a.statsLock.Unlock()
 	a.cond.L.Unlock()
 
 	a.cond.Broadcast()
@@ -176,6 +242,129 @@ func (a *slotQueue) isIdle() bool {
 	return isIdle
 }
 
+// setMinReady sets the number of containers of the slot queue to keep alive (even when idle)
+// as requested by its latest call.
+func (a *slotQueue) setMinReady(minReady uint64) {
+	atomic.StoreUint64(&a.minReady, minReady)
+	atomic.StoreInt64(&a.minReadyAt, time.Now().UnixNano())
+}
+
+// getMinReady returns the number of containers of the slot queue to keep alive now, the
+// larger of the min ready of its latest call and what its scale policy asks for.
+func (a *slotQueue) getMinReady() uint64 {
+	minReady := atomic.LoadUint64(&a.minReady)
+	if n := a.getScalePolicy().MinReady(time.Now()); n > minReady {
+		minReady = n
+	}
+	return minReady
+}
+
+// expireMinReady stops keeping containers alive if no call requested it within timeout.
+func (a *slotQueue) expireMinReady(timeout time.Duration) {
+	if time.Since(time.Unix(0, atomic.LoadInt64(&a.minReadyAt))) >= timeout {
+		atomic.StoreUint64(&a.minReady, 0)
+	}
+}
+
+// setScalePolicy sets the idle timeout and scale policy of the containers of the slot queue
+// as requested by its latest call, these apply to running containers right away.
+func (a *slotQueue) setScalePolicy(idleTimeout time.Duration, policy *models.ScalePolicy) {
+	atomic.StoreInt64(&a.idleTimeout, int64(idleTimeout))
+	a.scalePolicy.Store(policy)
+}
+
+func (a *slotQueue) getIdleTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&a.idleTimeout))
+}
+
+func (a *slotQueue) getScalePolicy() *models.ScalePolicy {
+	policy, _ := a.scalePolicy.Load().(*models.ScalePolicy)
+	return policy
+}
+
+// recordCall counts a call of the slot queue towards its call rate.
+func (a *slotQueue) recordCall(now time.Time) {
+	a.rateLock.Lock()
+	defer a.rateLock.Unlock()
+
+	a.advanceRateLocked(now)
+	a.rateCalls++
+}
+
+// callRate returns the number of calls of the slot queue over the last callRateWindow,
+// estimated from the calls of the current and previous windows.
+func (a *slotQueue) callRate(now time.Time) uint64 {
+	a.rateLock.Lock()
+	defer a.rateLock.Unlock()
+
+	a.advanceRateLocked(now)
+	// the previous window counts for the part of it still within callRateWindow
+	overlap := callRateWindow - now.Sub(a.rateStart)
+	if overlap < 0 {
+		overlap = 0
+	}
+	return a.rateCalls + uint64(float64(a.ratePrev)*float64(overlap)/float64(callRateWindow))
+}
+
+func (a *slotQueue) advanceRateLocked(now time.Time) {
+	if a.rateStart.IsZero() {
+		a.rateStart = now
+		return
+	}
+	elapsed := now.Sub(a.rateStart)
+	if elapsed < callRateWindow {
+		return
+	}
+	if elapsed < 2*callRateWindow {
+		a.ratePrev = a.rateCalls
+		a.rateStart = a.rateStart.Add(callRateWindow)
+	} else {
+		a.ratePrev = 0
+		a.rateStart = now
+	}
+	a.rateCalls = 0
+}
+
+// setMaxContainers sets the maximum number of containers of the slot queue as requested
+// by its latest call, 0 means unlimited.
+func (a *slotQueue) setMaxContainers(maxContainers uint64) {
+	atomic.StoreUint64(&a.maxContainers, maxContainers)
+}
+
+func (a *slotQueue) getMaxContainers() uint64 {
+	return atomic.LoadUint64(&a.maxContainers)
+}
+
+// tryRetire returns true if an idle container may shutdown, which is the case unless it would
+// leave fewer than the min ready containers of the slot queue running. Containers allowed to
+// shutdown must call retired() once they are in done state.
+func (a *slotQueue) tryRetire() bool {
+	minReady := a.getMinReady()
+
+	a.retireLock.Lock()
+	defer a.retireLock.Unlock()
+
+	if minReady > 0 {
+		cur := a.getStats()
+		running := cur.containerStates[ContainerStateStart] +
+			cur.containerStates[ContainerStateIdle] +
+			cur.containerStates[ContainerStatePaused] +
+			cur.containerStates[ContainerStateBusy]
+		if running <= a.retiring || running-a.retiring <= minReady {
+			return false
+		}
+	}
+
+	a.retiring += 1
+	return true
+}
+
+func (a *slotQueue) retired() {
+	a.retireLock.Lock()
+	a.retiring -= 1
+	a.retireLock.Unlock()
+}
+
 func (a *slotQueue) getStats() slotQueueStats {
 	var out slotQueueStats
 	a.statsLock.Lock()
@@ -187,6 +376,10 @@ func (a *slotQueue) getStats() slotQueueStats {
 func isNewContainerNeeded(cur *slotQueueStats) bool {
 
 	idleWorkers := cur.containerStates[ContainerStateIdle] + cur.containerStates[ContainerStatePaused]
+	// busy containers serving calls concurrently may still have idle slots queued
+	if cur.queuedSlots > idleWorkers {
+		idleWorkers = cur.queuedSlots
+	}
 	starters := cur.containerStates[ContainerStateStart]
 	startWaiters := cur.containerStates[ContainerStateWait]
 
@@ -215,6 +408,26 @@ func isNewContainerNeeded(cur *slotQueueStats) bool {
 	return true
 }
 
+// liveContainers returns the number of containers waiting for resources, starting or running.
+func liveContainers(cur *slotQueueStats) uint64 {
+	return cur.containerStates[ContainerStateWait] +
+		cur.containerStates[ContainerStateStart] +
+		cur.containerStates[ContainerStateIdle] +
+		cur.containerStates[ContainerStatePaused] +
+		cur.containerStates[ContainerStateBusy]
+}
+
+// isMinReadyNeeded returns true if fewer than minReady containers are live.
+func isMinReadyNeeded(cur *slotQueueStats, minReady uint64) bool {
+	return liveContainers(cur) < minReady
+}
+
+// isMaxContainersReached returns true if no more containers may be launched, a
+// maxContainers of 0 means unlimited.
+func isMaxContainersReached(cur *slotQueueStats, maxContainers uint64) bool {
+	return maxContainers > 0 && liveContainers(cur) >= maxContainers
+}
+
 func (a *slotQueue) enterRequestState(reqType RequestStateType) {
 	if reqType > RequestStateNone && reqType < RequestStateMax {
 		a.statsLock.Lock()
@@ -262,6 +475,40 @@ func (a *slotQueueMgr) getSlotQueue(key string) (*slotQueue, bool) {
 	return slots, !ok
 }
 
+// setCurrentSlotQueue records slots as the slot queue of the latest call of a function. A slot
+// queue replaced by another one (eg. the function image changed) stops keeping containers alive,
+// unless it is a shared pool other functions still use.
+func (a *slotQueueMgr) setCurrentSlotQueue(fnID string, slots *slotQueue) {
+	if fnID == "" {
+		return
+	}
+
+	a.hMu.Lock()
+	prev := a.current[fnID]
+	a.current[fnID] = slots
+	if prev != nil && prev != slots {
+		for _, cur := range a.current {
+			if cur == prev {
+				prev = nil
+				break
+			}
+		}
+	}
+	a.hMu.Unlock()
+
+	if prev != nil && prev != slots {
+		prev.setMinReady(0)
+		prev.setScalePolicy(prev.getIdleTimeout(), nil)
+	}
+}
+
+// getCurrentSlotQueue returns the slot queue of the latest call of a function, nil if none.
+func (a *slotQueueMgr) getCurrentSlotQueue(fnID string) *slotQueue {
+	a.hMu.Lock()
+	defer a.hMu.Unlock()
+	return a.current[fnID]
+}
+
 // currently unused. But at some point, we need to age/delete old
 // slotQueues.
 func (a *slotQueueMgr) deleteSlotQueue(slots *slotQueue) bool {
@@ -270,6 +517,11 @@ func (a *slotQueueMgr) deleteSlotQueue(slots *slotQueue) bool {
 	a.hMu.Lock()
 	if slots.isIdle() {
 		delete(a.hot, slots.key)
+		for fnID, cur := range a.current {
+			if cur == slots {
+				delete(a.current, fnID)
+			}
+		}
 		isDeleted = true
 	}
 	a.hMu.Unlock()
@@ -279,8 +531,20 @@ func (a *slotQueueMgr) deleteSlotQueue(slots *slotQueue) bool {
 
 var shapool = &sync.Pool{New: func() interface{} { return sha256.New() }}
 
+// sharedPoolUnsetConfig is the config of a function left out of the containers of a shared
+// pool, which serve several functions
+const sharedPoolUnsetConfig = "FN_FN_ID"
+
+// sharedPoolAnnotations are the annotations shaping hot containers, functions sharing a pool
+// of hot containers must agree on them
+var sharedPoolAnnotations = []string{models.FnConcurrencyAnnotation, models.FnSysctlsAnnotation, models.LogRedactionAnnotation}
+
 // TODO do better; once we have app+fn versions this function
 // can be simply app+fn ids & version
+//
+// Functions opting into a shared pool (see models.SharedPoolAnnotation) are keyed by the
+// content of their config rather than by their id, so that functions of an app with the same
+// image and config get the same key.
 func getSlotQueueKey(call *call) string {
 	// return a sha256 hash of a (hopefully) unique string of all the config
 	// values, to make map lookups quicker [than the giant unique string]
@@ -293,7 +557,12 @@ func getSlotQueueKey(call *call) string {
 	hash.Write(unsafeBytes("\x00"))
 	hash.Write(unsafeBytes(call.SyslogURL))
 	hash.Write(unsafeBytes("\x00"))
-	hash.Write(unsafeBytes(call.FnID))
+	hash.Write(unsafeBytes(call.SyslogFormat))
+	hash.Write(unsafeBytes("\x00"))
+	shared, _ := models.SharedPoolFromAnnotations(call.Annotations)
+	if !shared {
+		hash.Write(unsafeBytes(call.FnID))
+	}
 	hash.Write(unsafeBytes("\x00"))
 	hash.Write(unsafeBytes(call.Image))
 	hash.Write(unsafeBytes("\x00"))
@@ -303,9 +572,6 @@ func getSlotQueueKey(call *call) string {
 	binary.LittleEndian.PutUint32(byt[:4], uint32(call.Timeout))
 	hash.Write(byt[:4])
 
-	binary.LittleEndian.PutUint32(byt[:4], uint32(call.IdleTimeout))
-	hash.Write(byt[:4])
-
 	binary.LittleEndian.PutUint32(byt[:4], uint32(call.TmpFsSize))
 	hash.Write(byt[:4])
 
@@ -327,6 +593,9 @@ func getSlotQueueKey(call *call) string {
 	}
 
 	for _, k := range keys {
+		if shared && k == sharedPoolUnsetConfig {
+			continue
+		}
 		hash.Write(unsafeBytes(k))
 		hash.Write(unsafeBytes("\x00"))
 		hash.Write(unsafeBytes(call.Config[k]))
@@ -336,6 +605,17 @@ func getSlotQueueKey(call *call) string {
 	// we need to additionally delimit config and annotations to eliminate overlap bug
 	hash.Write(unsafeBytes("\x00"))
 
+	if shared {
+		for _, k := range sharedPoolAnnotations {
+			hash.Write(unsafeBytes(k))
+			hash.Write(unsafeBytes("\x00"))
+			v, _ := call.Annotations.Get(k)
+			hash.Write(v)
+			hash.Write(unsafeBytes("\x00"))
+		}
+		return hashSum(hash)
+	}
+
 	keys = keys[:0] // clear keys
 	for k := range call.Annotations {
 		i := sort.SearchStrings(keys, k)
@@ -352,8 +632,12 @@ func getSlotQueueKey(call *call) string {
 		hash.Write(unsafeBytes("\x00"))
 	}
 
+	return hashSum(hash)
+}
+
+func hashSum(h hash.Hash) string {
 	var buf [sha256.Size]byte
-	hash.Sum(buf[:0])
+	h.Sum(buf[:0])
 	return string(buf[:])
 }
 
@@ -46,7 +46,7 @@ func checkGetTokenId(t *testing.T, a *slotQueue, dur time.Duration, id uint64) e
 	ctx, cancel := context.WithTimeout(context.Background(), dur)
 	defer cancel()
 
-	outChan := a.startDequeuer(ctx)
+	outChan := a.startDequeuer(ctx, models.PriorityNormal)
 
 	for {
 		select {
@@ -158,6 +158,52 @@ func TestSlotQueueBasic2(t *testing.T) {
 	}
 }
 
+func TestSlotQueuePriority(t *testing.T) {
+
+	obj := NewSlotQueue("test-priority")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctxHigh, cancelHigh := context.WithCancel(context.Background())
+	defer cancelHigh()
+
+	chLow := obj.startDequeuer(ctx, models.PriorityLow)
+	chHigh := obj.startDequeuer(ctxHigh, models.PriorityHigh)
+
+	obj.queueSlot(NewTestSlot(1))
+
+	select {
+	case z := <-chLow:
+		t.Fatalf("Low priority should not get a slot while high priority waits: %#v", z)
+	case z := <-chHigh:
+		if !obj.acquireSlot(z) {
+			t.Fatalf("Should acquire slot %#v", z)
+		}
+	case <-time.After(time.Duration(500) * time.Millisecond):
+		t.Fatalf("High priority should get a slot")
+	}
+
+	obj.queueSlot(NewTestSlot(2))
+
+	select {
+	case z := <-chLow:
+		t.Fatalf("Low priority should not get a slot while high priority waits: %#v", z)
+	case <-time.After(time.Duration(100) * time.Millisecond):
+	}
+
+	// high priority caller is done, low priority may go ahead
+	cancelHigh()
+
+	select {
+	case z := <-chLow:
+		if !obj.acquireSlot(z) {
+			t.Fatalf("Should acquire slot %#v", z)
+		}
+	case <-time.After(time.Duration(500) * time.Millisecond):
+		t.Fatalf("Low priority should get a slot")
+	}
+}
+
 func statsHelperSet(reqW, reqE, conW, conS, conI, conB uint64) slotQueueStats {
 	return slotQueueStats{
 		requestStates:   [RequestStateMax]uint64{0, reqW, reqE, 0},
@@ -204,6 +250,13 @@ func TestSlotNewContainerLogic1(t *testing.T) {
 	if !isNewContainerNeeded(&cur) {
 		t.Fatalf("Should need a new container cur: %#v", cur)
 	}
+
+	// CASE: busy containers with idle concurrent slots (slots >= requests)
+	cur = statsHelperSet(4, 0, 0, 0, 0, 2)
+	cur.queuedSlots = 6
+	if isNewContainerNeeded(&cur) {
+		t.Fatalf("Should not need a new container cur: %#v", cur)
+	}
 }
 
 func TestSlotQueueBasic3(t *testing.T) {
@@ -307,3 +360,258 @@ func BenchmarkSlotKey(b *testing.B) {
 		_ = getSlotQueueKey(call)
 	}
 }
+
+func TestSlotQueueMinReady(t *testing.T) {
+	ctx := context.Background()
+	obj := NewSlotQueue("test-min-ready")
+
+	// CASE: without min ready, idle containers always shutdown
+	if !obj.tryRetire() {
+		t.Fatalf("Should be able to retire a container without min ready")
+	}
+	obj.retired()
+
+	obj.setMinReady(2)
+
+	cur := obj.getStats()
+	if !isMinReadyNeeded(&cur, obj.getMinReady()) {
+		t.Fatalf("Should need a new container cur: %#v", cur)
+	}
+
+	states := make([]ContainerState, 3)
+	for i := range states {
+		states[i] = NewContainerState()
+		states[i].UpdateState(ctx, ContainerStateIdle, obj)
+	}
+
+	cur = obj.getStats()
+	if isMinReadyNeeded(&cur, obj.getMinReady()) {
+		t.Fatalf("Should not need a new container cur: %#v", cur)
+	}
+
+	// CASE: only the containers above min ready may shutdown
+	if !obj.tryRetire() {
+		t.Fatalf("Should be able to retire a container above min ready")
+	}
+	if obj.tryRetire() {
+		t.Fatalf("Should not be able to retire a min ready container")
+	}
+	states[0].UpdateState(ctx, ContainerStateDone, obj)
+	obj.retired()
+	if obj.tryRetire() {
+		t.Fatalf("Should not be able to retire a min ready container")
+	}
+
+	// CASE: min ready expires without calls
+	obj.expireMinReady(time.Hour)
+	if obj.getMinReady() != 2 {
+		t.Fatalf("Min ready should not expire")
+	}
+	obj.expireMinReady(0)
+	if obj.getMinReady() != 0 {
+		t.Fatalf("Min ready should expire")
+	}
+	if !obj.tryRetire() {
+		t.Fatalf("Should be able to retire a container once min ready expired")
+	}
+	obj.retired()
+}
+
+func TestSlotMaxContainersLogic(t *testing.T) {
+
+	var cur slotQueueStats
+
+	// CASE: unlimited
+	cur = statsHelperSet(10, 0, 0, 5, 0, 5)
+	if isMaxContainersReached(&cur, 0) {
+		t.Fatalf("Should not reach max containers cur: %#v", cur)
+	}
+
+	// CASE: starting and running containers below max
+	if isMaxContainersReached(&cur, 11) {
+		t.Fatalf("Should not reach max containers cur: %#v", cur)
+	}
+
+	// CASE: starting and running containers at max
+	if !isMaxContainersReached(&cur, 10) {
+		t.Fatalf("Should reach max containers cur: %#v", cur)
+	}
+}
+
+func TestSlotQueueScalePolicy(t *testing.T) {
+	mgr := NewSlotQueueMgr()
+	obj, _ := mgr.getSlotQueue("test-policy-1")
+
+	obj.setMinReady(0)
+	obj.setScalePolicy(10*time.Second, &models.ScalePolicy{NeverScaleToZero: true})
+	mgr.setCurrentSlotQueue("fn", obj)
+
+	if obj.getIdleTimeout() != 10*time.Second {
+		t.Fatalf("Unexpected idle timeout %v", obj.getIdleTimeout())
+	}
+	if obj.getMinReady() != 1 {
+		t.Fatalf("Policy should keep a container alive")
+	}
+
+	// CASE: policy applies regardless of calls
+	obj.expireMinReady(0)
+	if obj.getMinReady() != 1 {
+		t.Fatalf("Policy should keep a container alive without calls")
+	}
+
+	// CASE: function changed, the previous slot queue stops keeping containers
+	obj2, _ := mgr.getSlotQueue("test-policy-2")
+	obj2.setScalePolicy(10*time.Second, &models.ScalePolicy{NeverScaleToZero: true})
+	mgr.setCurrentSlotQueue("fn", obj2)
+
+	if obj.getMinReady() != 0 || obj.getScalePolicy().KeepsWarm() {
+		t.Fatalf("Replaced slot queue should not keep containers alive")
+	}
+	if obj2.getMinReady() != 1 {
+		t.Fatalf("Current slot queue should keep a container alive")
+	}
+
+	if !mgr.deleteSlotQueue(obj2) {
+		t.Fatalf("Should be able to delete an idle slot queue")
+	}
+	if len(mgr.current) != 0 {
+		t.Fatalf("Deleted slot queue should not be current")
+	}
+}
+
+func TestSlotQueueKeySharedPool(t *testing.T) {
+	newCall := func(appID, fnID string, shared bool, config models.Config) *call {
+		annotations := models.EmptyAnnotations()
+		if shared {
+			annotations, _ = annotations.With(models.SharedPoolAnnotation, true)
+		}
+		annotations, _ = annotations.With("fnproject.io/fn/name", fnID)
+		cfg := models.Config{"FN_FN_ID": fnID, "FN_APP_ID": appID}
+		for k, v := range config {
+			cfg[k] = v
+		}
+		return &call{Call: &models.Call{
+			AppID:       appID,
+			FnID:        fnID,
+			Image:       "fnproject/hello",
+			Memory:      128,
+			Config:      cfg,
+			Annotations: annotations,
+		}}
+	}
+
+	// CASE: fns not opting in never share
+	if getSlotQueueKey(newCall("app", "fn1", false, nil)) == getSlotQueueKey(newCall("app", "fn2", false, nil)) {
+		t.Fatalf("Fns should not share a pool without opting in")
+	}
+
+	// CASE: identical fns of an app opting in share
+	key := getSlotQueueKey(newCall("app", "fn1", true, nil))
+	if key != getSlotQueueKey(newCall("app", "fn2", true, nil)) {
+		t.Fatalf("Identical fns should share a pool")
+	}
+
+	// CASE: fns of another app, with another config or not opting in do not share
+	for _, other := range []*call{
+		newCall("app2", "fn2", true, nil),
+		newCall("app", "fn2", true, models.Config{"VAR": "x"}),
+		newCall("app", "fn1", false, nil),
+	} {
+		if key == getSlotQueueKey(other) {
+			t.Fatalf("Fn %+v should not share the pool", other.Call)
+		}
+	}
+
+	// CASE: fns with containers of another shape do not share
+	other := newCall("app", "fn2", true, nil)
+	other.Annotations, _ = other.Annotations.With(models.FnConcurrencyAnnotation, 4)
+	if key == getSlotQueueKey(other) {
+		t.Fatalf("Fns with another concurrency should not share the pool")
+	}
+}
+
+func TestSlotQueueSharedPoolReplaced(t *testing.T) {
+	mgr := NewSlotQueueMgr()
+	shared, _ := mgr.getSlotQueue("test-shared")
+	shared.setMinReady(1)
+	mgr.setCurrentSlotQueue("fn1", shared)
+	mgr.setCurrentSlotQueue("fn2", shared)
+
+	// CASE: a fn leaving a shared pool leaves it alive for the others
+	own, _ := mgr.getSlotQueue("test-own")
+	mgr.setCurrentSlotQueue("fn1", own)
+	if shared.getMinReady() != 1 {
+		t.Fatalf("Shared pool should keep containers alive while used")
+	}
+
+	// CASE: the last fn leaving it stops it
+	mgr.setCurrentSlotQueue("fn2", own)
+	if shared.getMinReady() != 0 {
+		t.Fatalf("Unused shared pool should not keep containers alive")
+	}
+}
+
+func TestSlotQueueCallRate(t *testing.T) {
+	obj := NewSlotQueue("test-call-rate")
+	now := time.Now()
+
+	if obj.callRate(now) != 0 {
+		t.Fatalf("Should have no calls")
+	}
+	for i := 0; i < 90; i++ {
+		obj.recordCall(now.Add(time.Duration(i) * time.Second / 2))
+	}
+	if rate := obj.callRate(now.Add(45 * time.Second)); rate != 90 {
+		t.Fatalf("Unexpected call rate %d", rate)
+	}
+
+	// CASE: the previous window counts for its part within the last window
+	if rate := obj.callRate(now.Add(90 * time.Second)); rate != 45 {
+		t.Fatalf("Unexpected call rate %d", rate)
+	}
+
+	// CASE: calls older than two windows are forgotten
+	if rate := obj.callRate(now.Add(5 * time.Minute)); rate != 0 {
+		t.Fatalf("Unexpected call rate %d", rate)
+	}
+}
+
+func TestSkipFreeze(t *testing.T) {
+	a := &agent{cfg: Config{FreezeAdaptiveRate: 10}}
+	obj := NewSlotQueue("test-skip-freeze")
+	for i := 0; i < 20; i++ {
+		obj.recordCall(time.Now())
+	}
+
+	// CASE: adaptive freeze is opt in
+	if a.skipFreeze(obj) {
+		t.Fatalf("Should pause without adaptive freeze")
+	}
+
+	obj.setScalePolicy(time.Second, &models.ScalePolicy{AdaptiveFreeze: true})
+	if !a.skipFreeze(obj) {
+		t.Fatalf("Should not pause chatty function")
+	}
+
+	// CASE: runner wide adaptive freeze
+	obj.setScalePolicy(time.Second, nil)
+	a.cfg.FreezeAdaptive = true
+	if !a.skipFreeze(obj) {
+		t.Fatalf("Should not pause chatty function")
+	}
+
+	a.cfg.FreezeAdaptiveRate = 100
+	if a.skipFreeze(obj) {
+		t.Fatalf("Should pause quiet function")
+	}
+}
+
+func TestFreezeRecheck(t *testing.T) {
+	// CASE: a zero freeze delay doesn't spin on the call rate of chatty functions
+	if d := freezeRecheck(0); d != minFreezeRecheck {
+		t.Fatalf("Should recheck after %v, got %v", minFreezeRecheck, d)
+	}
+	if d := freezeRecheck(5 * time.Second); d != 5*time.Second {
+		t.Fatalf("Should recheck after the freeze delay, got %v", d)
+	}
+}
@@ -42,6 +42,11 @@ func (rp *staticRunnerPool) Runners(ctx context.Context, call pool.RunnerCall) (
 	return r, nil
 }
 
+// implements RunnerLister
+func (rp *staticRunnerPool) ListRunners(ctx context.Context) ([]pool.Runner, error) {
+	return rp.Runners(ctx, nil)
+}
+
 func (rp *staticRunnerPool) Shutdown(ctx context.Context) error {
 	var retErr error
 	for _, r := range rp.runners {
@@ -2,10 +2,12 @@ package agent
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
 
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/stats"
@@ -17,6 +19,11 @@ var (
 	containerStateKey    = common.MakeKey("container_state")
 	callStatusKey        = common.MakeKey("call_status")
 	containerUDSStateKey = common.MakeKey("container_uds_state")
+	coldStartPhaseKey    = common.MakeKey("cold_start_phase")
+	recycleReasonKey     = common.MakeKey("recycle_reason")
+	fnIDKey              = common.MakeKey("fn_id")
+	fnRevisionKey        = common.MakeKey("fn_revision")
+	canaryRoleKey        = common.MakeKey("canary_role")
 
 	// tri-state values below: error/true/false
 	statusCallCacheKey    = common.MakeKey("cached")
@@ -72,6 +79,14 @@ func statsLBAgentRunnerExecLatency(ctx context.Context, dur time.Duration) {
 	stats.Record(ctx, runnerExecLatencyMeasure.M(int64(dur/time.Millisecond)))
 }
 
+func statsLBAgentHedged(ctx context.Context) {
+	stats.Record(ctx, hedgedCallsMeasure.M(1))
+}
+
+func statsLBAgentHedgeWon(ctx context.Context) {
+	stats.Record(ctx, hedgeWinsMeasure.M(1))
+}
+
 func statsContainerUDSInitLatency(ctx context.Context, start time.Time, end time.Time, containerUDSState string) {
 	if end.Before(start) {
 		return
@@ -88,6 +103,48 @@ func statsContainerUDSInitLatency(ctx context.Context, start time.Time, end time
 	stats.Record(ctx, containerUDSInitLatencyMeasure.M(int64(dur/time.Millisecond)))
 }
 
+func statsColdStartPhase(ctx context.Context, phase string, dur time.Duration) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(coldStartPhaseKey, phase),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, coldStartLatencyMeasure.M(int64(dur/time.Millisecond)))
+}
+
+func statsContainerRecycled(ctx context.Context, reason string) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(recycleReasonKey, reason),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, containerRecycledMeasure.M(1))
+}
+
+// statsCanaryCall records an ended call of a fn with a canary, split by the revision which
+// served it so that the candidate can be compared to the stable revision.
+func statsCanaryCall(ctx context.Context, call *models.Call) {
+	ctx, err := tag.New(ctx,
+		tag.Upsert(fnIDKey, call.FnID),
+		tag.Upsert(fnRevisionKey, strconv.FormatInt(call.FnRevision, 10)),
+		tag.Upsert(canaryRoleKey, call.Canary),
+		tag.Upsert(callStatusKey, call.Status),
+	)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	stats.Record(ctx, canaryCallsMeasure.M(1))
+	created, completed := time.Time(call.CreatedAt), time.Time(call.CompletedAt)
+	if !created.IsZero() && completed.After(created) {
+		stats.Record(ctx, canaryCallLatencyMeasure.M(int64(completed.Sub(created)/time.Millisecond)))
+	}
+}
+
 func statsContainerEvicted(ctx context.Context, containerState string) {
 	ctx, err := tag.New(ctx,
 		tag.Upsert(containerStateKey, containerState),
@@ -163,8 +220,13 @@ const (
 	errorsMetricName     = "errors"
 	serverBusyMetricName = "server_busy"
 
+	canaryCallsMetricName       = "canary_calls"
+	canaryCallLatencyMetricName = "canary_call_latency"
+
 	containerEvictedMetricName        = "container_evictions"
+	containerRecycledMetricName       = "container_recycles"
 	containerUDSInitLatencyMetricName = "container_uds_init_latency"
+	coldStartLatencyMetricName        = "container_cold_start_latency"
 
 	utilCpuUsedMetricName  = "util_cpu_used"
 	utilCpuAvailMetricName = "util_cpu_avail"
@@ -175,6 +237,8 @@ const (
 	runnerSchedLatencyMetricName = "lb_runner_sched_latency"
 	runnerExecLatencyMetricName  = "lb_runner_exec_latency"
 	callLatencyMetricName        = "lb_call_latency"
+	hedgedCallsMetricName        = "lb_hedged_calls"
+	hedgeWinsMetricName          = "lb_hedge_wins"
 
 	// Reported by Runner
 	statusCallMetricName = "status_call"
@@ -198,8 +262,13 @@ var (
 	utilMemUsedMeasure  = common.MakeMeasure(utilMemUsedMetricName, "agent memory in use", "By")
 	utilMemAvailMeasure = common.MakeMeasure(utilMemAvailMetricName, "agent memory available", "By")
 
+	canaryCallsMeasure       = common.MakeMeasure(canaryCallsMetricName, "calls of fns with a canary ended in agent", "")
+	canaryCallLatencyMeasure = common.MakeMeasure(canaryCallLatencyMetricName, "latency of calls of fns with a canary", "msecs")
+
 	containerEvictedMeasure        = common.MakeMeasure(containerEvictedMetricName, "containers evicted", "")
+	containerRecycledMeasure       = common.MakeMeasure(containerRecycledMetricName, "containers recycled", "")
 	containerUDSInitLatencyMeasure = common.MakeMeasure(containerUDSInitLatencyMetricName, "container UDS Init-Wait Latency", "msecs")
+	coldStartLatencyMeasure        = common.MakeMeasure(coldStartLatencyMetricName, "container Cold Start Latency per phase", "msecs")
 
 	// Reported By LB: How long does a runner scheduler wait for a committed call? eg. wait/launch/pull containers
 	runnerSchedLatencyMeasure = common.MakeMeasure(runnerSchedLatencyMetricName, "Runner Scheduler Latency Reported By LBAgent", "msecs")
@@ -207,6 +276,10 @@ var (
 	runnerExecLatencyMeasure = common.MakeMeasure(runnerExecLatencyMetricName, "Runner Container Execution Latency Reported By LBAgent", "msecs")
 	// Reported By LB: Function total call latency (except function execution inside container)
 	callLatencyMeasure = common.MakeMeasure(callLatencyMetricName, "LB Call Latency Reported By LBAgent", "msecs")
+	// Reported By LB: Calls placed a second time as the first attempt was slow to respond
+	hedgedCallsMeasure = common.MakeMeasure(hedgedCallsMetricName, "Hedged Calls Reported By LBAgent", "")
+	// Reported By LB: Hedged calls where the second attempt responded first
+	hedgeWinsMeasure = common.MakeMeasure(hedgeWinsMetricName, "Hedge Wins Reported By LBAgent", "")
 	// Reported By Runner: Status Call Results
 	statusCallMeasure = common.MakeMeasure(statusCallMetricName, "Status Call Results Reported By Runner", "")
 )
@@ -225,6 +298,8 @@ func RegisterLBAgentViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(runnerSchedLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
 		common.CreateView(runnerExecLatencyMeasure, view.Distribution(latencyDist...), tagKeys),
 		common.CreateView(callLatencyMeasure, view.Distribution(latencyDist...), callLatencyTags),
+		common.CreateView(hedgedCallsMeasure, view.Sum(), tagKeys),
+		common.CreateView(hedgeWinsMeasure, view.Sum(), tagKeys),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -233,6 +308,15 @@ func RegisterLBAgentViews(tagKeys []string, latencyDist []float64) {
 
 // RegisterAgentViews creates and registers all agent views
 func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
+	// add fn revision tags for canary calls
+	canaryTags := make([]string, 0, len(tagKeys)+4)
+	canaryTags = append(canaryTags, "fn_id", "fn_revision", "canary_role", "call_status")
+	for _, key := range tagKeys {
+		if key != "fn_id" && key != "fn_revision" && key != "canary_role" && key != "call_status" {
+			canaryTags = append(canaryTags, key)
+		}
+	}
+
 	err := view.Register(
 		common.CreateView(queuedMeasure, view.Sum(), tagKeys),
 		common.CreateView(callsMeasure, view.Sum(), tagKeys),
@@ -242,6 +326,8 @@ func RegisterAgentViews(tagKeys []string, latencyDist []float64) {
 		common.CreateView(timedoutMeasure, view.Sum(), tagKeys),
 		common.CreateView(errorsMeasure, view.Sum(), tagKeys),
 		common.CreateView(serverBusyMeasure, view.Sum(), tagKeys),
+		common.CreateView(canaryCallsMeasure, view.Sum(), canaryTags),
+		common.CreateView(canaryCallLatencyMeasure, view.Distribution(latencyDist...), canaryTags),
 		common.CreateView(utilCpuUsedMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilCpuAvailMeasure, view.LastValue(), tagKeys),
 		common.CreateView(utilMemUsedMeasure, view.LastValue(), tagKeys),
@@ -345,9 +431,29 @@ func RegisterContainerViews(tagKeys []string, latencyDist []float64) {
 		}
 	}
 
+	// add cold start phase tag for cold start latency
+	coldStartTags := make([]string, 0, len(tagKeys)+1)
+	coldStartTags = append(coldStartTags, "cold_start_phase")
+	for _, key := range tagKeys {
+		if key != "cold_start_phase" {
+			coldStartTags = append(coldStartTags, key)
+		}
+	}
+
+	// add recycle reason tag for recycled containers
+	recycleTags := make([]string, 0, len(tagKeys)+1)
+	recycleTags = append(recycleTags, "recycle_reason")
+	for _, key := range tagKeys {
+		if key != "recycle_reason" {
+			recycleTags = append(recycleTags, key)
+		}
+	}
+
 	err := view.Register(
 		common.CreateView(containerEvictedMeasure, view.Count(), evictTags),
+		common.CreateView(containerRecycledMeasure, view.Count(), recycleTags),
 		common.CreateView(containerUDSInitLatencyMeasure, view.Distribution(latencyDist...), udsInitTags),
+		common.CreateView(coldStartLatencyMeasure, view.Distribution(latencyDist...), coldStartTags),
 	)
 	if err != nil {
 		logrus.WithError(err).Fatal("cannot register view")
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/models"
+)
+
+// NodeStatus implements StatusProvider
+func (a *agent) NodeStatus(ctx context.Context) *models.NodeStatus {
+	status := &models.NodeStatus{
+		InFlight:    atomic.LoadUint64(&a.inFlight),
+		MaxInFlight: a.cfg.MaxInFlightCalls,
+		Functions:   a.slotMgr.fnStatuses(),
+	}
+
+	select {
+	case <-a.callWg.Closer():
+		status.Draining = true
+	default:
+	}
+
+	util := a.resources.GetUtilization()
+	status.Resources = models.NodeResources{
+		MemoryTotal: util.MemUsed + util.MemAvail,
+		MemoryUsed:  util.MemUsed,
+		MemoryFree:  util.MemAvail,
+		CPUTotal:    util.CpuUsed + util.CpuAvail,
+		CPUUsed:     util.CpuUsed,
+		CPUFree:     util.CpuAvail,
+	}
+
+	if cache, ok := a.driver.(drivers.ImageCache); ok {
+		status.ImageCache = imageCacheStatus(cache)
+	}
+	return status
+}
+
+// fnStatuses returns the status of the slot queue of the latest call of each function
+func (a *slotQueueMgr) fnStatuses() []*models.FnStatus {
+	a.hMu.Lock()
+	current := make(map[string]*slotQueue, len(a.current))
+	for fnID, slots := range a.current {
+		current[fnID] = slots
+	}
+	a.hMu.Unlock()
+
+	fns := make([]*models.FnStatus, 0, len(current))
+	for fnID, slots := range current {
+		stats := slots.getStats()
+		fn := &models.FnStatus{
+			FnID:       fnID,
+			Containers: make(map[string]uint64),
+			QueueDepth: stats.requestStates[RequestStateWait],
+			Executing:  stats.requestStates[RequestStateExec],
+		}
+		for state := ContainerStateWait; state < ContainerStateDone; state++ {
+			if n := stats.containerStates[state]; n > 0 {
+				fn.Containers[containerStateKeys[state]] = n
+			}
+		}
+		fns = append(fns, fn)
+	}
+
+	sort.Slice(fns, func(i, j int) bool { return fns[i].FnID < fns[j].FnID })
+	return fns
+}
+
+// imageCacheStatus returns the content of the image cache of a driver, nil if disabled
+func imageCacheStatus(cache drivers.ImageCache) *models.ImageCacheStatus {
+	images, maxSize, ok := cache.CachedImages()
+	if !ok {
+		return nil
+	}
+
+	status := &models.ImageCacheStatus{
+		MaxSize: maxSize,
+		Images:  make([]*models.CachedImageStatus, 0, len(images)),
+	}
+	for _, img := range images {
+		if img.InUse {
+			status.BusySize += img.Size
+		} else {
+			status.IdleSize += img.Size
+		}
+		status.Images = append(status.Images, &models.CachedImageStatus{
+			ID:    img.ID,
+			Tags:  img.Tags,
+			Size:  img.Size,
+			InUse: img.InUse,
+		})
+	}
+	return status
+}
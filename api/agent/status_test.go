@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+type imageCacheDriver struct {
+	drivers.Driver
+	images []drivers.ImageCacheEntry
+}
+
+func (d *imageCacheDriver) CachedImages() ([]drivers.ImageCacheEntry, uint64, bool) {
+	return d.images, 1000, true
+}
+
+func TestNodeStatus(t *testing.T) {
+	tr := NewResourceTracker(&Config{MaxTotalMemory: 256 * Mem1MB, MaxTotalCPU: 1000}).(*resourceTracker)
+	tr.ramUsed = 128 * Mem1MB
+	tr.cpuUsed = 100
+
+	a := &agent{
+		cfg:       Config{MaxInFlightCalls: 10},
+		slotMgr:   NewSlotQueueMgr(),
+		resources: tr,
+		callWg:    common.NewWaitGroup(),
+		driver: &imageCacheDriver{images: []drivers.ImageCacheEntry{
+			{ID: "busy", Tags: []string{"fn:0.0.1"}, Size: 100, InUse: true},
+			{ID: "idle", Size: 50},
+		}},
+		inFlight: 3,
+	}
+
+	pool, _ := a.slotMgr.getSlotQueue("pool")
+	pool.enterContainerState(ContainerStateBusy)
+	pool.enterContainerState(ContainerStateIdle)
+	pool.enterRequestState(RequestStateExec)
+	pool.enterRequestState(RequestStateWait)
+	pool.enterRequestState(RequestStateWait)
+	a.slotMgr.setCurrentSlotQueue("fn2", pool)
+	a.slotMgr.setCurrentSlotQueue("fn1", pool)
+	cold, _ := a.slotMgr.getSlotQueue("cold")
+	cold.enterContainerState(ContainerStateStart)
+	a.slotMgr.setCurrentSlotQueue("fn3", cold)
+
+	status := a.NodeStatus(context.Background())
+	if status.Draining || status.InFlight != 3 || status.MaxInFlight != 10 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	expected := models.NodeResources{
+		MemoryTotal: 256 * Mem1MB, MemoryUsed: 128 * Mem1MB, MemoryFree: 128 * Mem1MB,
+		CPUTotal: 1000, CPUUsed: 100, CPUFree: 900,
+	}
+	if status.Resources != expected {
+		t.Fatalf("expected resources %+v, got %+v", expected, status.Resources)
+	}
+
+	if len(status.Functions) != 3 {
+		t.Fatalf("expected 3 functions, got %+v", status.Functions)
+	}
+	for i, fnID := range []string{"fn1", "fn2"} {
+		fn := status.Functions[i]
+		if fn.FnID != fnID || fn.QueueDepth != 2 || fn.Executing != 1 || len(fn.Containers) != 2 ||
+			fn.Containers["busy"] != 1 || fn.Containers["idle"] != 1 {
+			t.Fatalf("unexpected status of %s: %+v", fnID, fn)
+		}
+	}
+	if fn := status.Functions[2]; fn.FnID != "fn3" || fn.Containers["start"] != 1 || fn.QueueDepth != 0 {
+		t.Fatalf("unexpected status of fn3: %+v", fn)
+	}
+
+	images := status.ImageCache
+	if images == nil || images.MaxSize != 1000 || images.BusySize != 100 || images.IdleSize != 50 || len(images.Images) != 2 {
+		t.Fatalf("unexpected image cache %+v", images)
+	}
+	if img := images.Images[0]; img.ID != "busy" || !img.InUse || img.Tags[0] != "fn:0.0.1" {
+		t.Fatalf("unexpected image %+v", img)
+	}
+
+	a.callWg.CloseGroupNB()
+	if !a.NodeStatus(context.Background()).Draining {
+		t.Fatal("expected a draining agent")
+	}
+}
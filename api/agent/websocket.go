@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"go.opencensus.io/trace"
+)
+
+// dialUDS connects to the unix socket the FDK of the container listens on
+func (c *container) dialUDS(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", filepath.Join(c.iofs.AgentPath(), udsFilename))
+}
+
+// dispatchWebSocket sends the upgrade request of a WebSocket call to the container and, once the
+// container accepts it, proxies frames between the client and the container until either side
+// closes the connection, it stays idle for too long or ctx (the max duration) expires.
+func (s *hotSlot) dispatchWebSocket(ctx context.Context, call *call) error {
+	ctx, span := trace.StartSpan(ctx, "agent_dispatch_websocket")
+	defer span.End()
+
+	hj, ok := call.respWriter.(http.Hijacker)
+	if !ok {
+		return models.ErrWebSocketUnsupported
+	}
+
+	swapBack := s.container.swap(call)
+	defer swapBack()
+
+	backend, err := s.container.dialUDS(ctx)
+	if err != nil {
+		// IMPORTANT: Container contract: If http-uds errors, container cannot continue
+		s.trySetError(err)
+		return models.ErrFunctionResponse
+	}
+	defer backend.Close()
+
+	req := createUDSRequest(ctx, call)
+	req.Method = http.MethodGet
+	req.Body = nil
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	// the handshake is bound to ctx, afterwards the proxy watches ctx
+	stop := closeOnDone(ctx, backend)
+	backendBuf := bufio.NewReader(backend)
+	err = req.Write(backend)
+	var resp *http.Response
+	if err == nil {
+		resp, err = http.ReadResponse(backendBuf, req)
+	}
+	if !stop() || err != nil {
+		if ctx.Err() == context.Canceled {
+			return s.canceled()
+		}
+		s.trySetError(err)
+		if ctx.Err() == context.DeadlineExceeded {
+			return context.DeadlineExceeded
+		}
+		return models.ErrFunctionResponse
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// the function declined the upgrade, its response is a regular one
+		return s.writeResp(ctx, call.maxResponseSize(s.cfg), resp, call.respWriter)
+	}
+
+	client, clientBuf, err := hj.Hijack()
+	if err != nil {
+		s.trySetError(err)
+		return err
+	}
+	defer client.Close()
+
+	if err := resp.Write(client); err != nil {
+		return err
+	}
+
+	common.Logger(ctx).Debug("proxying websocket connection")
+	return proxyWebSocket(ctx, client, clientBuf, backend, backendBuf, s.webSocketIdle)
+}
+
+// closeOnDone closes conn if ctx is done before the returned func is called, which returns
+// false if conn got closed.
+func closeOnDone(ctx context.Context, conn net.Conn) func() bool {
+	done := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			closed <- true
+		case <-done:
+			closed <- false
+		}
+	}()
+	return func() bool {
+		close(done)
+		return !<-closed
+	}
+}
+
+// proxyWebSocket copies data both ways between the client and the container connections
+// until one of them is closed, no data went through for idle or ctx is done. A connection
+// closed by either side or idling out ends the call successfully.
+func proxyWebSocket(ctx context.Context, client net.Conn, clientR io.Reader, backend net.Conn, backendR io.Reader, idle time.Duration) error {
+	var lastActive int64
+	touch := func() { atomic.StoreInt64(&lastActive, time.Now().UnixNano()) }
+	touch()
+
+	done := make(chan struct{})
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			close(done)
+			client.Close()
+			backend.Close()
+		})
+	}
+	defer closeBoth()
+
+	pipe := func(dst io.Writer, src io.Reader) {
+		defer closeBoth()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				touch()
+				if _, werr := dst.Write(buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	go pipe(backend, clientR)
+	go pipe(client, backendR)
+
+	tick := idle / 4
+	if tick <= 0 || tick > time.Second {
+		tick = time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			select {
+			case <-done: // closed by either side first
+				return nil
+			default:
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if idle > 0 && time.Since(time.Unix(0, atomic.LoadInt64(&lastActive))) >= idle {
+				common.Logger(ctx).Debug("closing idle websocket connection")
+				return nil
+			}
+		}
+	}
+}
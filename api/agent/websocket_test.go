@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// echoFDK accepts websocket upgrades on the uds of a container and echoes what it reads
+func echoFDK(t *testing.T, dir string) net.Listener {
+	lsnr, err := net.Listen("unix", filepath.Join(dir, udsFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(lsnr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+		io.Copy(conn, buf)
+	}))
+	return lsnr
+}
+
+func TestDispatchWebSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iofs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lsnr := echoFDK(t, dir)
+	defer lsnr.Close()
+
+	slot := &hotSlot{
+		done:          make(chan struct{}),
+		container:     &container{iofs: &directoryIOFS{agentPath: dir}},
+		cfg:           &Config{},
+		webSocketIdle: time.Minute,
+	}
+
+	errC := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &call{
+			Call:        &models.Call{ID: id.New().String()},
+			req:         r,
+			respWriter:  w,
+			isWebSocket: true,
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		errC <- slot.dispatchWebSocket(ctx, c)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	rd := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the container to switch protocols, got: %d", resp.StatusCode)
+	}
+
+	conn.Write([]byte("hello"))
+	echo := make([]byte, 5)
+	if _, err := io.ReadFull(rd, echo); err != nil || string(echo) != "hello" {
+		t.Fatalf("expected frames to be proxied to the container and back, got: %q %v", echo, err)
+	}
+
+	conn.Close()
+	select {
+	case err := <-errC:
+		if err != nil {
+			t.Fatalf("connection closed by the client should end the call, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call should end once the client closes the connection")
+	}
+	if slot.Error() != nil {
+		t.Fatalf("container should keep running, got: %v", slot.Error())
+	}
+}
+
+func TestProxyWebSocketLimits(t *testing.T) {
+	// idle connections are closed
+	client, clientPeer := net.Pipe()
+	backend, backendPeer := net.Pipe()
+	defer clientPeer.Close()
+	defer backendPeer.Close()
+
+	start := time.Now()
+	err := proxyWebSocket(context.Background(), client, client, backend, backend, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("idle connection should end the call, got: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("idle connection should be closed after the idle timeout")
+	}
+
+	// connections past the max duration are closed
+	client, clientPeer = net.Pipe()
+	backend, backendPeer = net.Pipe()
+	defer clientPeer.Close()
+	defer backendPeer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = proxyWebSocket(ctx, client, client, backend, backend, time.Minute)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("connection past its max duration should time out, got: %v", err)
+	}
+}
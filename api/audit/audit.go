@@ -0,0 +1,115 @@
+// Package audit emits a structured record for every call to a pluggable sink, separate
+// from function logs, for compliance and analytics.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+)
+
+// CallerIdentityHeader is the request header the identity of the caller of a call is read
+// from. It is set by the authentication of fn, or by an authenticating proxy in front of fn
+// among its TrustedProxies, and dropped from the requests of other callers.
+const CallerIdentityHeader = "Fn-Caller-Identity"
+
+// Record describes a call once it ended.
+type Record struct {
+	CallID      string `json:"call_id"`
+	AppID       string `json:"app_id"`
+	AppName     string `json:"app_name,omitempty"`
+	FnID        string `json:"fn_id"`
+	FnRevision  int64  `json:"fn_revision,omitempty"`
+	TriggerID   string `json:"trigger_id,omitempty"`
+	Image       string `json:"image"`
+	ImageDigest string `json:"image_digest,omitempty"`
+	// Type is sync, async or detached
+	Type   string `json:"type"`
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+
+	Caller Caller `json:"caller"`
+
+	// Status is the exit status of the call, one of success, error, timeout or cancelled
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	CreatedAt   common.DateTime `json:"created_at"`
+	StartedAt   common.DateTime `json:"started_at,omitempty"`
+	CompletedAt common.DateTime `json:"completed_at"`
+
+	Latencies Latencies `json:"latencies"`
+	Resources Resources `json:"resources"`
+}
+
+// Caller identifies who made a call.
+type Caller struct {
+	// Address is the remote address of the request, or the address trusted proxies forwarded
+	// it for, see TrustedProxies
+	Address string `json:"address,omitempty"`
+	// Identity is the value of CallerIdentityHeader
+	Identity string `json:"identity,omitempty"`
+}
+
+// Latencies are the durations of the phases of a call, in milliseconds.
+type Latencies struct {
+	// Scheduling is the time from the call being created to it starting in a container,
+	// including queueing, waiting for a slot and starting a container.
+	Scheduling int64 `json:"scheduling_ms"`
+	// Execution is the time from the call starting in a container to it ending.
+	Execution int64 `json:"execution_ms"`
+	// Total is the time from the call being created to it ending.
+	Total int64 `json:"total_ms"`
+}
+
+// Resources are the resources reserved for a call and the peak usage sampled while it ran.
+type Resources struct {
+	// Memory reserved in MB
+	Memory uint64 `json:"memory_mb"`
+	// CPUs reserved in milli cpus, 0 if unlimited
+	CPUs uint64 `json:"cpus_mcpus,omitempty"`
+	// Peak is the highest value of each container metric sampled during the call, eg. mem_usage
+	Peak map[string]uint64 `json:"peak,omitempty"`
+}
+
+// Sink receives the audit records of calls. Writes must be safe for concurrent use.
+type Sink interface {
+	// Write emits a record, errors are logged by the caller and the record dropped.
+	Write(ctx context.Context, rec *Record) error
+	// Close flushes pending records and releases the sink.
+	Close() error
+}
+
+// Provider for audit sink extensions
+type Provider interface {
+	fmt.Stringer
+	// Supports indicates if this provider can handle a specific URL scheme
+	Supports(url *url.URL) bool
+	// New creates a new sink from a given URL
+	New(url *url.URL) (Sink, error)
+}
+
+var providers []Provider
+
+// AddProvider registers a new global audit sink provider
+func AddProvider(p Provider) {
+	providers = append(providers, p)
+}
+
+// New parses the URL and returns the sink of the provider supporting its scheme.
+func New(sinkURL string) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad audit URL %q: %v", sinkURL, err)
+	}
+	logrus.WithFields(logrus.Fields{"audit": u.Scheme}).Debug("selecting audit sink")
+	for _, p := range providers {
+		if p.Supports(u) {
+			return p.New(u)
+		}
+	}
+	return nil, fmt.Errorf("audit sink type not supported %v", u.Scheme)
+}
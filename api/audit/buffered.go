@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBufferSize is the number of records a buffered sink holds before dropping them
+const DefaultBufferSize = 1024
+
+var (
+	// ErrBufferFull is returned when a record is dropped because the sink is falling behind
+	ErrBufferFull = errors.New("audit buffer is full, record dropped")
+	// ErrSinkClosed is returned when a record is written after the sink was closed
+	ErrSinkClosed = errors.New("audit sink is closed")
+)
+
+type bufferedSink struct {
+	sink    Sink
+	records chan *Record
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.RWMutex // protects closed and sending on records
+	closed  bool
+}
+
+// NewBufferedSink writes records to sink from a background goroutine so that calls never
+// wait on it, records are dropped once size records are pending.
+func NewBufferedSink(sink Sink, size int) Sink {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	b := &bufferedSink{
+		sink:    sink,
+		records: make(chan *Record, size),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *bufferedSink) run() {
+	defer close(b.done)
+	ctx := context.Background()
+	for rec := range b.records {
+		if err := b.sink.Write(ctx, rec); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"call_id": rec.CallID}).Error("error writing audit record")
+		}
+	}
+}
+
+func (b *bufferedSink) Write(ctx context.Context, rec *Record) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrSinkClosed
+	}
+	select {
+	case b.records <- rec:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Close waits for pending records to be written before closing the underlying sink.
+func (b *bufferedSink) Close() error {
+	var err error
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		close(b.records)
+		b.mu.Unlock()
+
+		<-b.done
+		err = b.sink.Close()
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	block   chan struct{}
+	records []*Record
+	closed  bool
+}
+
+func (s *recordingSink) Write(ctx context.Context, rec *Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestBufferedSinkFlushesOnClose(t *testing.T) {
+	ctx := context.Background()
+	rs := new(recordingSink)
+	sink := NewBufferedSink(rs, 10)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := sink.Write(ctx, &Record{CallID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs.records) != 3 || rs.records[0].CallID != "a" || rs.records[2].CallID != "c" {
+		t.Fatalf("expected records to be written in order, got %+v", rs.records)
+	}
+	if !rs.closed {
+		t.Fatal("expected underlying sink to be closed")
+	}
+	if err := sink.Write(ctx, &Record{CallID: "d"}); err != ErrSinkClosed {
+		t.Fatalf("expected write after close to fail with %v, got %v", ErrSinkClosed, err)
+	}
+}
+
+func TestBufferedSinkDropsWhenFull(t *testing.T) {
+	ctx := context.Background()
+	rs := &recordingSink{block: make(chan struct{})}
+	sink := NewBufferedSink(rs, 1)
+
+	// the first record may already be taken by the writer, which blocks on it
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = sink.Write(ctx, &Record{CallID: "call"})
+	}
+	if err != ErrBufferFull {
+		t.Fatalf("expected %v once the buffer is full, got %v", ErrBufferFull, err)
+	}
+
+	close(rs.block)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package file is an audit sink appending records to a file as JSON lines, eg.
+// file:///var/log/fn/audit.log
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/fnproject/fn/api/audit"
+)
+
+type fileProvider int
+
+type fileSink struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func (fileProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "file"
+}
+
+func (fileProvider) New(u *url.URL) (audit.Sink, error) {
+	if u.Path == "" {
+		return nil, errors.New("no path provided for file audit sink, eg. file:///var/log/fn/audit.log")
+	}
+	f, err := os.OpenFile(u.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (fileProvider) String() string {
+	return "file"
+}
+
+func (s *fileSink) Write(ctx context.Context, rec *audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(rec)
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+func init() {
+	audit.AddProvider(fileProvider(0))
+}
@@ -0,0 +1,62 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn/api/audit"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	ctx := context.Background()
+	for _, id := range []string{"a", "b"} {
+		// reopening the sink appends to the file
+		sink, err := audit.New("file://" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Write(ctx, &audit.Record{CallID: id, Status: "success"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec audit.Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("expected a json record per line, got %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, rec.CallID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("expected records a and b, got %v", ids)
+	}
+}
+
+func TestFileSinkRequiresPath(t *testing.T) {
+	if _, err := audit.New("file://"); err == nil {
+		t.Fatal("expected a file sink without a path to fail")
+	}
+}
@@ -0,0 +1,76 @@
+// Package kafkarest is an audit sink producing records to a Kafka topic through a Kafka
+// REST proxy (v2 API), eg. kafka-rest://kafka-rest:8082/fn-audit, or kafka-rests:// to
+// reach the proxy over https. Records are keyed by app id.
+package kafkarest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/audit/webhook"
+)
+
+const contentType = "application/vnd.kafka.json.v2+json"
+
+type kafkaRESTProvider int
+
+type kafkaRESTSink struct {
+	url    string
+	client *http.Client
+}
+
+type produceRecord struct {
+	Key   string        `json:"key,omitempty"`
+	Value *audit.Record `json:"value"`
+}
+
+type produceRequest struct {
+	Records []produceRecord `json:"records"`
+}
+
+func (kafkaRESTProvider) Supports(u *url.URL) bool {
+	switch u.Scheme {
+	case "kafka-rest", "kafka-rests":
+		return true
+	}
+	return false
+}
+
+func (kafkaRESTProvider) New(u *url.URL) (audit.Sink, error) {
+	topic := strings.Trim(u.Path, "/")
+	if topic == "" || strings.Contains(topic, "/") {
+		return nil, errors.New("no topic provided for kafka audit sink, eg. kafka-rest://kafka-rest:8082/fn-audit")
+	}
+
+	scheme := "http"
+	if u.Scheme == "kafka-rests" {
+		scheme = "https"
+	}
+	proxy := url.URL{Scheme: scheme, User: u.User, Host: u.Host, Path: "/topics/" + topic}
+	return &kafkaRESTSink{url: proxy.String(), client: &http.Client{Timeout: webhook.Timeout}}, nil
+}
+
+func (kafkaRESTProvider) String() string {
+	return "kafka-rest"
+}
+
+func (s *kafkaRESTSink) Write(ctx context.Context, rec *audit.Record) error {
+	body, err := json.Marshal(produceRequest{Records: []produceRecord{{Key: rec.AppID, Value: rec}}})
+	if err != nil {
+		return err
+	}
+	return webhook.Post(ctx, s.client, s.url, contentType, body)
+}
+
+func (s *kafkaRESTSink) Close() error {
+	return nil
+}
+
+func init() {
+	audit.AddProvider(kafkaRESTProvider(0))
+}
@@ -0,0 +1,46 @@
+package kafkarest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/audit"
+)
+
+func TestKafkaRESTSink(t *testing.T) {
+	var got produceRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/fn-audit" || r.Header.Get("Content-Type") != contentType {
+			t.Errorf("unexpected request to %s with %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := audit.New(strings.Replace(srv.URL, "http://", "kafka-rest://", 1) + "/fn-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	if err := sink.Write(context.Background(), &audit.Record{CallID: "call_id", AppID: "app_id"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Records) != 1 || got.Records[0].Key != "app_id" || got.Records[0].Value.CallID != "call_id" {
+		t.Fatalf("unexpected records produced %+v", got.Records)
+	}
+}
+
+func TestKafkaRESTSinkRequiresTopic(t *testing.T) {
+	for _, u := range []string{"kafka-rest://localhost:8082", "kafka-rest://localhost:8082/a/b"} {
+		if _, err := audit.New(u); err == nil {
+			t.Errorf("expected %s to fail without a single topic", u)
+		}
+	}
+}
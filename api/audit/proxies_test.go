@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8, bad"); err == nil {
+		t.Fatal("expected invalid proxies to be rejected")
+	}
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.1, ::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		proxies   TrustedProxies
+		remote    string
+		forwarded []string
+		expected  string
+	}{
+		{proxies, "203.0.113.5:5000", nil, "203.0.113.5"},
+		// callers can't claim to be forwarded for another address
+		{proxies, "203.0.113.5:5000", []string{"198.51.100.1"}, "203.0.113.5"},
+		{nil, "10.0.0.1:5000", []string{"198.51.100.1"}, "10.0.0.1"},
+		{proxies, "10.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{proxies, "[::1]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		// the addresses the caller set in front of those of the proxies are skipped
+		{proxies, "10.0.0.1:5000", []string{"198.51.100.9, 198.51.100.1, 192.168.1.1"}, "198.51.100.1"},
+		{proxies, "10.0.0.1:5000", []string{"198.51.100.9", "198.51.100.1, 10.1.2.3"}, "198.51.100.1"},
+		{proxies, "10.0.0.1:5000", []string{"garbage"}, "10.0.0.1"},
+	} {
+		req := &http.Request{RemoteAddr: test.remote, Header: http.Header{}}
+		for _, v := range test.forwarded {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if addr := test.proxies.CallerAddress(req); addr != test.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, test.expected, addr)
+		}
+	}
+
+	if !proxies.TrustsRequest(&http.Request{RemoteAddr: "10.1.1.1:80"}) || proxies.TrustsRequest(&http.Request{RemoteAddr: "192.168.1.2:80"}) {
+		t.Fatal("expected only the requests of trusted proxies to be trusted")
+	}
+}
@@ -0,0 +1,415 @@
+// Package trail records every change made through the management API to apps, functions,
+// triggers, schedules, API keys, role bindings, domains and webhooks in an append-only audit
+// trail: who made the change, from where, when, and the fields it changed.
+//
+// Changes are recorded by a datastore wrapping the one of the server, see Wrap, to a Sink.
+// Entries are persisted in the datastore by default, where the management API queries them
+// from, and may be sent to other systems with a custom Sink.
+package trail
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Sink appends the entries of the audit trail. Appends must be safe for concurrent use.
+type Sink interface {
+	// Append records an entry, errors are logged by the caller and the entry dropped.
+	Append(ctx context.Context, entry *models.AuditEntry) error
+}
+
+type datastoreSink struct {
+	ds models.Datastore
+}
+
+// NewDatastoreSink returns a sink persisting entries in ds
+func NewDatastoreSink(ds models.Datastore) Sink {
+	return &datastoreSink{ds: ds}
+}
+
+func (s *datastoreSink) Append(ctx context.Context, entry *models.AuditEntry) error {
+	return s.ds.InsertAuditEntry(ctx, entry)
+}
+
+type multiSink []Sink
+
+// MultiSink returns a sink appending entries to each of sinks, eg. to keep the entries in
+// the datastore while shipping them to another system.
+func MultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+func (m multiSink) Append(ctx context.Context, entry *models.AuditEntry) error {
+	var firstErr error
+	for _, sink := range m {
+		if err := sink.Append(ctx, entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type contextKey string
+
+const callerKey = contextKey("audit_caller")
+
+// WithCaller returns a context with the caller of a request, who the changes made while
+// handling the request are attributed to.
+func WithCaller(ctx context.Context, caller audit.Caller) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// GetCaller returns the caller of the request of ctx, empty if it is unknown.
+func GetCaller(ctx context.Context) audit.Caller {
+	caller, _ := ctx.Value(callerKey).(audit.Caller)
+	return caller
+}
+
+// Wrap returns a datastore that appends an entry to sink for each resource created, updated
+// or deleted through ds, attributed to the caller of the context of the change.
+func Wrap(ds models.Datastore, sink Sink) models.Datastore {
+	return &auditds{Datastore: ds, sink: sink}
+}
+
+type auditds struct {
+	models.Datastore
+	sink Sink
+}
+
+// record appends the entry of a change, a failure to do so is only logged as the change was
+// already made.
+func (a *auditds) record(ctx context.Context, action, resourceType, resourceID, appID string, before, after interface{}) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"action": action, "resource_type": resourceType, "resource_id": resourceID})
+
+	diff, err := models.NewAuditDiff(before, after)
+	if err != nil {
+		log.WithError(err).Error("failed to diff audited resource")
+	}
+
+	caller := GetCaller(ctx)
+	entry := &models.AuditEntry{
+		ID:           id.New().String(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		AppID:        appID,
+		Actor:        caller.Identity,
+		Address:      caller.Address,
+		Diff:         diff,
+		CreatedAt:    common.DateTime(time.Now()),
+	}
+
+	// the change is recorded even if the request was cancelled since
+	if err := a.sink.Append(common.BackgroundContext(ctx), entry); err != nil {
+		log.WithError(err).Error("failed to append audit entry")
+	}
+}
+
+// snapshot returns the JSON of a resource before it is updated, as datastores may update the
+// resources they returned in place.
+func snapshot(resource interface{}, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+func (a *auditds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := a.Datastore.InsertApp(ctx, app)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceApp, app.ID, app.ID, nil, app)
+	}
+	return app, err
+}
+
+func (a *auditds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	before := snapshot(a.Datastore.GetAppByID(ctx, app.ID))
+	app, err := a.Datastore.UpdateApp(ctx, app)
+	if err == nil {
+		a.record(ctx, models.AuditActionUpdate, models.AuditResourceApp, app.ID, app.ID, before, app)
+	}
+	return app, err
+}
+
+// RemoveApp records the removal of live apps, the purge of an app marked deleted was recorded
+// when it was deleted.
+func (a *auditds) RemoveApp(ctx context.Context, appID string) error {
+	before, _ := a.Datastore.GetAppByID(ctx, appID)
+	err := a.Datastore.RemoveApp(ctx, appID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceApp, appID, appID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) SoftDeleteApp(ctx context.Context, appID string) error {
+	before := snapshot(a.Datastore.GetAppByID(ctx, appID))
+	err := a.Datastore.SoftDeleteApp(ctx, appID)
+	if err == nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceApp, appID, appID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	app, err := a.Datastore.RestoreApp(ctx, appID)
+	if err == nil {
+		a.record(ctx, models.AuditActionRestore, models.AuditResourceApp, app.ID, app.ID, nil, app)
+	}
+	return app, err
+}
+
+func (a *auditds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := a.Datastore.InsertFn(ctx, fn)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceFn, fn.ID, fn.AppID, nil, fn)
+	}
+	return fn, err
+}
+
+func (a *auditds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	before := snapshot(a.Datastore.GetFnByID(ctx, fn.ID))
+	fn, err := a.Datastore.UpdateFn(ctx, fn)
+	if err == nil {
+		a.record(ctx, models.AuditActionUpdate, models.AuditResourceFn, fn.ID, fn.AppID, before, fn)
+	}
+	return fn, err
+}
+
+func (a *auditds) RemoveFn(ctx context.Context, fnID string) error {
+	before, _ := a.Datastore.GetFnByID(ctx, fnID)
+	err := a.Datastore.RemoveFn(ctx, fnID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceFn, fnID, before.AppID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) SoftDeleteFn(ctx context.Context, fnID string) error {
+	fn, err := a.Datastore.GetFnByID(ctx, fnID)
+	before := snapshot(fn, err)
+	err = a.Datastore.SoftDeleteFn(ctx, fnID)
+	if err == nil && fn != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceFn, fnID, fn.AppID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := a.Datastore.RestoreFn(ctx, fnID)
+	if err == nil {
+		a.record(ctx, models.AuditActionRestore, models.AuditResourceFn, fn.ID, fn.AppID, nil, fn)
+	}
+	return fn, err
+}
+
+func (a *auditds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := a.Datastore.InsertTrigger(ctx, trigger)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceTrigger, trigger.ID, trigger.AppID, nil, trigger)
+	}
+	return trigger, err
+}
+
+func (a *auditds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	before := snapshot(a.Datastore.GetTriggerByID(ctx, trigger.ID))
+	trigger, err := a.Datastore.UpdateTrigger(ctx, trigger)
+	if err == nil {
+		a.record(ctx, models.AuditActionUpdate, models.AuditResourceTrigger, trigger.ID, trigger.AppID, before, trigger)
+	}
+	return trigger, err
+}
+
+func (a *auditds) RemoveTrigger(ctx context.Context, triggerID string) error {
+	before, _ := a.Datastore.GetTriggerByID(ctx, triggerID)
+	err := a.Datastore.RemoveTrigger(ctx, triggerID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceTrigger, triggerID, before.AppID, before, nil)
+	}
+	return err
+}
+
+// ApplyBatch records the changes of a batch once it was applied, in the order of its items
+func (a *auditds) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	befores := make([]interface{}, len(items))
+	for i, item := range items {
+		if item == nil || item.Action != models.BatchUpdate {
+			continue
+		}
+		switch {
+		case item.App != nil:
+			befores[i] = snapshot(a.Datastore.GetAppByID(ctx, item.App.ID))
+		case item.Fn != nil:
+			befores[i] = snapshot(a.Datastore.GetFnByID(ctx, item.Fn.ID))
+		case item.Trigger != nil:
+			befores[i] = snapshot(a.Datastore.GetTriggerByID(ctx, item.Trigger.ID))
+		}
+	}
+
+	applied, err := a.Datastore.ApplyBatch(ctx, items)
+	if err != nil {
+		return applied, err
+	}
+	for i, item := range applied {
+		resourceType, resourceID, appID, resource := batchAuditResource(item)
+		action, before, after := batchAuditAction(item.Action), befores[i], resource
+		if action == models.AuditActionDelete {
+			// deleted resources are returned as they were before
+			before, after = resource, nil
+		}
+		a.record(ctx, action, resourceType, resourceID, appID, before, after)
+	}
+	return applied, nil
+}
+
+// batchAuditResource returns the type, id and app of the resource of a batch item, and the
+// resource
+func batchAuditResource(item *models.BatchItem) (string, string, string, interface{}) {
+	switch {
+	case item.App != nil:
+		return models.AuditResourceApp, item.App.ID, item.App.ID, item.App
+	case item.Fn != nil:
+		return models.AuditResourceFn, item.Fn.ID, item.Fn.AppID, item.Fn
+	}
+	return models.AuditResourceTrigger, item.Trigger.ID, item.Trigger.AppID, item.Trigger
+}
+
+// batchAuditAction returns the audit action of the action of a batch item
+func batchAuditAction(action string) string {
+	switch action {
+	case models.BatchCreate:
+		return models.AuditActionCreate
+	case models.BatchUpdate:
+		return models.AuditActionUpdate
+	}
+	return models.AuditActionDelete
+}
+
+func (a *auditds) InsertSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	schedule, err := a.Datastore.InsertSchedule(ctx, schedule)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceSchedule, schedule.ID, schedule.AppID, nil, schedule)
+	}
+	return schedule, err
+}
+
+func (a *auditds) UpdateSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	before := snapshot(a.Datastore.GetScheduleByID(ctx, schedule.ID))
+	schedule, err := a.Datastore.UpdateSchedule(ctx, schedule)
+	if err == nil {
+		a.record(ctx, models.AuditActionUpdate, models.AuditResourceSchedule, schedule.ID, schedule.AppID, before, schedule)
+	}
+	return schedule, err
+}
+
+func (a *auditds) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	before, _ := a.Datastore.GetScheduleByID(ctx, scheduleID)
+	err := a.Datastore.RemoveSchedule(ctx, scheduleID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceSchedule, scheduleID, before.AppID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	key, err := a.Datastore.InsertAPIKey(ctx, key)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceAPIKey, key.ID, key.AppID, nil, key)
+	}
+	return key, err
+}
+
+func (a *auditds) RemoveAPIKey(ctx context.Context, keyID string) error {
+	before := a.getAPIKey(ctx, keyID)
+	err := a.Datastore.RemoveAPIKey(ctx, keyID)
+	if err == nil {
+		var appID string
+		if before != nil {
+			appID = before.AppID
+		}
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceAPIKey, keyID, appID, before, nil)
+	}
+	return err
+}
+
+// getAPIKey returns the key with ID keyID, nil if there is none, keys are only listed
+func (a *auditds) getAPIKey(ctx context.Context, keyID string) *models.APIKey {
+	filter := &models.APIKeyFilter{PerPage: 100}
+	for {
+		keys, err := a.Datastore.GetAPIKeys(ctx, filter)
+		if err != nil {
+			return nil
+		}
+		for _, key := range keys.Items {
+			if key.ID == keyID {
+				return key
+			}
+		}
+		if keys.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = keys.NextCursor
+	}
+}
+
+func (a *auditds) InsertRoleBinding(ctx context.Context, binding *models.RoleBinding) (*models.RoleBinding, error) {
+	binding, err := a.Datastore.InsertRoleBinding(ctx, binding)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceRoleBinding, binding.ID, binding.AppID, nil, binding)
+	}
+	return binding, err
+}
+
+func (a *auditds) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	before, _ := a.Datastore.GetRoleBindingByID(ctx, bindingID)
+	err := a.Datastore.RemoveRoleBinding(ctx, bindingID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceRoleBinding, bindingID, before.AppID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	domain, err := a.Datastore.InsertDomain(ctx, domain)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceDomain, domain.ID, domain.AppID, nil, domain)
+	}
+	return domain, err
+}
+
+func (a *auditds) RemoveDomain(ctx context.Context, domainID string) error {
+	before, _ := a.Datastore.GetDomainByID(ctx, domainID)
+	err := a.Datastore.RemoveDomain(ctx, domainID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceDomain, domainID, before.AppID, before, nil)
+	}
+	return err
+}
+
+func (a *auditds) InsertWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	webhook, err := a.Datastore.InsertWebhook(ctx, webhook)
+	if err == nil {
+		a.record(ctx, models.AuditActionCreate, models.AuditResourceWebhook, webhook.ID, webhook.AppID, nil, webhook)
+	}
+	return webhook, err
+}
+
+func (a *auditds) RemoveWebhook(ctx context.Context, webhookID string) error {
+	before, _ := a.Datastore.GetWebhookByID(ctx, webhookID)
+	err := a.Datastore.RemoveWebhook(ctx, webhookID)
+	if err == nil && before != nil {
+		a.record(ctx, models.AuditActionDelete, models.AuditResourceWebhook, webhookID, before.AppID, before, nil)
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package trail
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+type failingSink struct{}
+
+func (failingSink) Append(ctx context.Context, entry *models.AuditEntry) error {
+	return errors.New("sink is down")
+}
+
+func entries(t *testing.T, ds models.Datastore, filter *models.AuditEntryFilter) []*models.AuditEntry {
+	res, err := ds.GetAuditEntries(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Items
+}
+
+func TestRecordChanges(t *testing.T) {
+	inner := datastore.NewMock()
+	ds := Wrap(inner, NewDatastoreSink(inner))
+	ctx := WithCaller(context.Background(), audit.Caller{Address: "10.0.0.1", Identity: "alice"})
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/hello:0.0.1"}
+	fn.SetDefaults()
+	fn, err = ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.UpdateFn(ctx, &models.Fn{ID: fn.ID, Image: "fnproject/hello:0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ds.InsertAPIKey(ctx, &models.APIKey{Name: "ci", AppID: app.ID, Scope: models.APIKeyScopeManage, Hash: "hash", Secret: "fnk_secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.RemoveAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	// failed changes are not recorded
+	if err := ds.RemoveFn(ctx, "missing"); err == nil {
+		t.Fatal("expected removing a missing fn to fail")
+	}
+
+	all := entries(t, ds, &models.AuditEntryFilter{})
+	expected := []struct{ action, resourceType, resourceID string }{
+		{models.AuditActionDelete, models.AuditResourceApp, app.ID},
+		{models.AuditActionDelete, models.AuditResourceAPIKey, key.ID},
+		{models.AuditActionCreate, models.AuditResourceAPIKey, key.ID},
+		{models.AuditActionUpdate, models.AuditResourceFn, fn.ID},
+		{models.AuditActionCreate, models.AuditResourceFn, fn.ID},
+		{models.AuditActionCreate, models.AuditResourceApp, app.ID},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(all))
+	}
+	for i, e := range expected {
+		entry := all[i]
+		if entry.Action != e.action || entry.ResourceType != e.resourceType || entry.ResourceID != e.resourceID {
+			t.Errorf("expected %s of %s %s, got %+v", e.action, e.resourceType, e.resourceID, entry)
+		}
+		if entry.AppID != app.ID || entry.Actor != "alice" || entry.Address != "10.0.0.1" {
+			t.Errorf("expected the change to be attributed to alice on app %s, got %+v", app.ID, entry)
+		}
+	}
+
+	if d := all[3].Diff; len(d) != 2 || d["image"].Old != "fnproject/hello:0.0.1" || d["image"].New != "fnproject/hello:0.0.2" || d["revision"].New == nil {
+		t.Errorf("expected the image and revision of the function to change, got %+v", d)
+	}
+	if d := all[2].Diff; d["secret"].New != models.AuditRedacted || d["name"].New != "ci" {
+		t.Errorf("expected the secret of the key to be redacted, got %+v", d)
+	}
+	if d := all[1].Diff; d["name"].Old != "ci" || d["name"].New != nil {
+		t.Errorf("expected the removed key to be recorded, got %+v", d)
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	inner1, inner2 := datastore.NewMock(), datastore.NewMock()
+	sink := MultiSink(failingSink{}, NewDatastoreSink(inner1), NewDatastoreSink(inner2))
+	ds := Wrap(inner1, sink)
+
+	// changes made by fn itself have no caller
+	if _, err := ds.InsertApp(context.Background(), &models.App{Name: "myapp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inner := range []models.Datastore{inner1, inner2} {
+		all := entries(t, inner, &models.AuditEntryFilter{})
+		if len(all) != 1 || all[0].Actor != "" || all[0].Address != "" {
+			t.Fatalf("expected an entry without caller in each sink, got %+v", all)
+		}
+	}
+}
+
+func TestRecordSoftDeletes(t *testing.T) {
+	inner := datastore.NewMock()
+	ds := Wrap(inner, NewDatastoreSink(inner))
+	ctx := WithCaller(context.Background(), audit.Caller{Identity: "alice"})
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/hello:0.0.1"}
+	fn.SetDefaults()
+	fn, err = ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.SoftDeleteFn(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.RestoreFn(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.SoftDeleteApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	// purging a deleted app was recorded when it was deleted
+	if err := ds.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	all := entries(t, ds, &models.AuditEntryFilter{})
+	expected := []struct{ action, resourceType string }{
+		{models.AuditActionDelete, models.AuditResourceApp},
+		{models.AuditActionRestore, models.AuditResourceFn},
+		{models.AuditActionDelete, models.AuditResourceFn},
+		{models.AuditActionCreate, models.AuditResourceFn},
+		{models.AuditActionCreate, models.AuditResourceApp},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), all)
+	}
+	for i, e := range expected {
+		if all[i].Action != e.action || all[i].ResourceType != e.resourceType {
+			t.Errorf("expected %s of %s, got %+v", e.action, e.resourceType, all[i])
+		}
+	}
+	if d := all[2].Diff; d["name"].Old != "myfn" || d["deleted_at"].Old != nil {
+		t.Errorf("expected the fn to be recorded as it was before it was deleted, got %+v", d)
+	}
+	if d := all[1].Diff; d["name"].New != "myfn" || d["deleted_at"].New != nil {
+		t.Errorf("expected the restored fn to be recorded, got %+v", d)
+	}
+}
+
+func TestRecordBatch(t *testing.T) {
+	inner := datastore.NewMock()
+	ds := Wrap(inner, NewDatastoreSink(inner))
+	ctx := WithCaller(context.Background(), audit.Caller{Identity: "alice"})
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/hello:0.0.1"}
+	fn.SetDefaults()
+	_, err = ds.ApplyBatch(ctx, []*models.BatchItem{
+		{Action: models.BatchUpdate, App: &models.App{ID: app.ID, Config: models.Config{"LEVEL": "debug"}}},
+		{Action: models.BatchCreate, Fn: fn},
+		{Action: models.BatchDelete, App: &models.App{ID: app.ID}, SoftDelete: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// batches that are not applied record nothing
+	_, err = ds.ApplyBatch(ctx, []*models.BatchItem{
+		{Action: models.BatchCreate, App: &models.App{Name: "otherapp"}},
+		{Action: models.BatchUpdate, App: &models.App{ID: app.ID, Config: models.Config{"LEVEL": "info"}}},
+	})
+	if _, ok := err.(*models.BatchError); !ok {
+		t.Fatalf("expected the update of the deleted app to fail the batch, got %v", err)
+	}
+
+	all := entries(t, ds, &models.AuditEntryFilter{})
+	expected := []struct{ action, resourceType string }{
+		{models.AuditActionDelete, models.AuditResourceApp},
+		{models.AuditActionCreate, models.AuditResourceFn},
+		{models.AuditActionUpdate, models.AuditResourceApp},
+		{models.AuditActionCreate, models.AuditResourceApp},
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), all)
+	}
+	for i, e := range expected {
+		if all[i].Action != e.action || all[i].ResourceType != e.resourceType || all[i].Actor != "alice" {
+			t.Errorf("expected %s of %s by alice, got %+v", e.action, e.resourceType, all[i])
+		}
+	}
+	if d := all[2].Diff; d["config"].New.(map[string]interface{})["LEVEL"] != "debug" {
+		t.Errorf("expected the config update to be recorded, got %+v", d)
+	}
+	if d := all[0].Diff; d["name"].Old != "myapp" || d["config"].Old.(map[string]interface{})["LEVEL"] != "debug" {
+		t.Errorf("expected the app to be recorded as it was before it was deleted, got %+v", d)
+	}
+}
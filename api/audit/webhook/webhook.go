@@ -0,0 +1,82 @@
+// Package webhook is an audit sink posting each record as JSON to an HTTP endpoint, eg.
+// https://audit.example.com/records
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fnproject/fn/api/audit"
+)
+
+// Timeout bounds each request to the endpoint
+const Timeout = 10 * time.Second
+
+type webhookProvider int
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (webhookProvider) Supports(u *url.URL) bool {
+	switch u.Scheme {
+	case "http", "https":
+		return true
+	}
+	return false
+}
+
+func (webhookProvider) New(u *url.URL) (audit.Sink, error) {
+	return &webhookSink{url: u.String(), client: &http.Client{Timeout: Timeout}}, nil
+}
+
+func (webhookProvider) String() string {
+	return "webhook"
+}
+
+func (s *webhookSink) Write(ctx context.Context, rec *audit.Record) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return Post(ctx, s.client, s.url, "application/json", body)
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
+
+// Post sends body to url, failing on any status other than 2xx.
+func Post(ctx context.Context, client *http.Client, url, contentType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body so that the connection is reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func init() {
+	audit.AddProvider(webhookProvider(0))
+}
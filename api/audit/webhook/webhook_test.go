@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/audit"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var got []audit.Record
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		var rec audit.Record
+		if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+			t.Error(err)
+		}
+		got = append(got, rec)
+		if rec.CallID == "rejected" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := audit.New(srv.URL + "/records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ctx := context.Background()
+	if err := sink.Write(ctx, &audit.Record{CallID: "accepted", FnID: "fn_id"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, &audit.Record{CallID: "rejected"}); err == nil {
+		t.Fatal("expected a non 2xx response to fail the write")
+	}
+
+	if len(got) != 2 || got[0].CallID != "accepted" || got[0].FnID != "fn_id" {
+		t.Fatalf("unexpected records received %+v", got)
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -41,6 +42,25 @@ func LoggerWithFields(ctx context.Context, fields logrus.Fields) (context.Contex
 	return ctx, l
 }
 
+// WithWriteTracking returns a child context of ctx recording whether writes to the datastore
+// were made with it, so that the reads following them are not served by stale replicas.
+func WithWriteTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey("writes"), new(int32))
+}
+
+// MarkWrite records that a write to the datastore was made with ctx, if it tracks writes.
+func MarkWrite(ctx context.Context) {
+	if w, ok := ctx.Value(contextKey("writes")).(*int32); ok {
+		atomic.StoreInt32(w, 1)
+	}
+}
+
+// HasWritten returns whether a write to the datastore was made with ctx.
+func HasWritten(ctx context.Context) bool {
+	w, ok := ctx.Value(contextKey("writes")).(*int32)
+	return ok && atomic.LoadInt32(w) == 1
+}
+
 // contextWithNoDeadline is an implementation of context.Context which delegates
 // Value() to its parent, but it has no deadline and it is never cancelled, just
 // like a context.Background().
@@ -67,7 +67,13 @@ func (g *clampReadCloser) Close() error {
 
 func (g *clampReadCloser) Read(p []byte) (int, error) {
 	if g.remaining <= 0 {
-		return 0, g.overflowErr
+		// reading exactly up to the limit is fine, only fail if there is more
+		var probe [1]byte
+		n, err := g.r.Read(probe[:])
+		if n > 0 {
+			return 0, g.overflowErr
+		}
+		return 0, err
 	}
 	if int64(len(p)) > g.remaining {
 		p = p[0:g.remaining]
@@ -0,0 +1,50 @@
+// Package redispool connects the stores kept in redis, configured with redis://host:port/prefix
+// URLs, to their server.
+package redispool
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// New returns a pool of connections to the server of u, failing if it cannot connect to it.
+// The path of u is the prefix of the keys of the store, not the database of the server.
+func New(u *url.URL) (*redis.Pool, error) {
+	server := *u
+	server.Path, server.RawPath = "", ""
+	pool := &redis.Pool{
+		MaxIdle:     512,
+		MaxActive:   512,
+		Wait:        true,
+		IdleTimeout: 300 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(server.String())
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	// Force a connection so we can fail in case of error.
+	conn := pool.Get()
+	defer conn.Close()
+	if err := conn.Err(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Prefix returns the prefix of the keys of the store of u, its path without slashes and
+// followed by a colon, empty if u has no path.
+func Prefix(u *url.URL) string {
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + ":"
+}
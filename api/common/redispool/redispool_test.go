@@ -0,0 +1,27 @@
+package redispool
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPrefix(t *testing.T) {
+	for i, test := range []struct {
+		url    string
+		prefix string
+	}{
+		{"redis://localhost:6379", ""},
+		{"redis://localhost:6379/", ""},
+		{"redis://localhost:6379/fn", "fn:"},
+		{"redis://localhost:6379/fn/", "fn:"},
+		{"redis://localhost:6379/fn/prod", "fn/prod:"},
+	} {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefix := Prefix(u); prefix != test.prefix {
+			t.Errorf("Test %d: expected prefix %q, got %q", i, test.prefix, prefix)
+		}
+	}
+}
@@ -2,16 +2,40 @@ package common
 
 import (
 	"context"
+	"strings"
 
 	"github.com/fnproject/fn/api/id"
 )
 
-// FnRequestID returns the passed value if that is not empty otherwise it generates a new unique ID
+// MaxRequestIDLength is the longest request ID adopted from a caller
+const MaxRequestIDLength = 128
+
+// FnRequestID returns the passed value if that is a valid request ID, see SanitizeRequestID,
+// otherwise it generates a new unique ID
 func FnRequestID(ridFound string) string {
-	if ridFound == "" {
-		return id.New().String()
+	if rid := SanitizeRequestID(ridFound); rid != "" {
+		return rid
+	}
+	return id.New().String()
+}
+
+// SanitizeRequestID returns a request ID supplied by a caller without surrounding spaces, or
+// an empty string if it is longer than MaxRequestIDLength or has characters other than
+// letters, digits and -_.:/+=@, so that it can be safely logged and sent on
+func SanitizeRequestID(rid string) string {
+	rid = strings.TrimSpace(rid)
+	if len(rid) > MaxRequestIDLength {
+		return ""
+	}
+	for _, r := range rid {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_.:/+=@", r):
+		default:
+			return ""
+		}
 	}
-	return ridFound
+	return rid
 }
 
 //RequestIDFromContext extract the request id from the context
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFnRequestID(t *testing.T) {
+	for rid, expected := range map[string]string{
+		"abc-123":                  "abc-123",
+		" 7f2c:req/1+a=b@svc_x.y ": "7f2c:req/1+a=b@svc_x.y",
+		"with space":               "",
+		"newline\nforged=log":      "",
+		"<script>":                 "",
+		strings.Repeat("a", 129):   "",
+		strings.Repeat("a", 128):   strings.Repeat("a", 128),
+	} {
+		if sanitized := SanitizeRequestID(rid); sanitized != expected {
+			t.Errorf("expected %q sanitized as %q, got %q", rid, expected, sanitized)
+		}
+		generated := FnRequestID(rid)
+		if expected != "" && generated != expected {
+			t.Errorf("expected %q adopted, got %q", rid, generated)
+		}
+		if expected == "" && (generated == "" || generated == rid) {
+			t.Errorf("expected an id generated for %q, got %q", rid, generated)
+		}
+	}
+}
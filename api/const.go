@@ -9,10 +9,22 @@ const (
 
 	// TriggerID is the url path parameter for trigger id
 	TriggerID string = "trigger_id"
+	// ScheduleID is the url path parameter for schedule id
+	ScheduleID string = "schedule_id"
+	// APIKeyID is the url path parameter for api key id
+	APIKeyID string = "key_id"
+	// RoleBindingID is the url path parameter for role binding id
+	RoleBindingID string = "binding_id"
+	// DomainID is the url path parameter for domain id
+	DomainID string = "domain_id"
+	// WebhookID is the url path parameter for webhook id
+	WebhookID string = "webhook_id"
 	// CallID is the url path parameter for call id
 	CallID string = "call_id"
 	// FnID is the url path parameter for fn id
 	FnID string = "fn_id"
+	// FnRevision is the url path parameter for fn revision
+	FnRevision string = "revision"
 	// TriggerSource is the triggers source parameter
 	TriggerSource string = "trigger_source"
 
@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/garyburd/redigo/redis"
+)
+
+// DefaultChannel is the redis channel of the changes if the bus URL has no path
+const DefaultChannel = "fn-datastore-changes"
+
+// redisResubscribeDelay is the delay before resubscribing after the connection of a redis bus
+// is lost
+const redisResubscribeDelay = time.Second
+
+// Bus carries the changes of a datastore to the caches of all the nodes using it
+type Bus interface {
+	// Publish sends c to the subscribers of the bus, including the ones of this node
+	Publish(ctx context.Context, c *models.Change) error
+	// Subscribe sends the changes published from now on, until ctx is done. A change of kind
+	// models.ChangeAll is sent when changes may have been missed.
+	Subscribe(ctx context.Context) (<-chan *models.Change, error)
+	// Close closes the bus and the channels of its subscribers
+	Close() error
+}
+
+// NewBus returns the bus of u, the bus of the nodes subscribed to the same redis channel for a
+// redis[s]://[:password@]host:port[/channel] URL, or the bus of this node only if u is empty
+func NewBus(u string) (Bus, error) {
+	if u == "" {
+		return NewMemoryBus(), nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "redis", "rediss":
+		return NewRedisBus(parsed)
+	}
+	return nil, fmt.Errorf("no datastore cache bus for %s, expected a redis URL", parsed.Scheme)
+}
+
+// memoryBus carries changes to the subscribers of this node only
+type memoryBus struct {
+	mu   sync.Mutex
+	subs map[chan *models.Change]context.Context
+}
+
+// NewMemoryBus returns a bus carrying changes to the subscribers of this node only, for a
+// single node or in tests
+func NewMemoryBus() Bus {
+	return &memoryBus{subs: make(map[chan *models.Change]context.Context)}
+}
+
+func (b *memoryBus) Publish(ctx context.Context, c *models.Change) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub, subCtx := range b.subs {
+		select {
+		case sub <- c:
+		case <-subCtx.Done():
+			delete(b.subs, sub)
+			close(sub)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context) (<-chan *models.Change, error) {
+	sub := make(chan *models.Change)
+	b.mu.Lock()
+	b.subs[sub] = ctx
+	b.mu.Unlock()
+	return sub, nil
+}
+
+func (b *memoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub)
+	}
+	return nil
+}
+
+// watcherBus carries the changes of a datastore notifying its changes itself
+type watcherBus struct {
+	models.ChangeWatcher
+}
+
+func (watcherBus) Publish(context.Context, *models.Change) error {
+	return nil
+}
+
+func (b watcherBus) Subscribe(ctx context.Context) (<-chan *models.Change, error) {
+	return b.WatchChanges(ctx)
+}
+
+func (watcherBus) Close() error {
+	return nil
+}
+
+// redisBus carries changes through a redis channel
+type redisBus struct {
+	pool    *redis.Pool
+	dial    func() (redis.Conn, error)
+	channel string
+}
+
+// NewRedisBus returns the bus of the redis channel of u, see NewBus
+func NewRedisBus(u *url.URL) (Bus, error) {
+	// the path of the URL is the channel, not the database of the server
+	server := *u
+	server.Path, server.RawPath = "", ""
+	dial := func() (redis.Conn, error) {
+		return redis.DialURL(server.String())
+	}
+	pool := &redis.Pool{
+		MaxIdle:     16,
+		MaxActive:   64,
+		Wait:        true,
+		IdleTimeout: 300 * time.Second,
+		Dial:        dial,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	// Force a connection so we can fail in case of error.
+	conn := pool.Get()
+	defer conn.Close()
+	if err := conn.Err(); err != nil {
+		return nil, err
+	}
+
+	channel := strings.Trim(u.Path, "/")
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &redisBus{pool: pool, dial: dial, channel: channel}, nil
+}
+
+func (b *redisBus) Publish(ctx context.Context, c *models.Change) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PUBLISH", b.channel, data)
+	return err
+}
+
+func (b *redisBus) Subscribe(ctx context.Context) (<-chan *models.Change, error) {
+	psc, done, err := b.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan *models.Change)
+	go func() {
+		defer close(changes)
+		for {
+			b.receive(ctx, psc, done, changes)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// the changes published until subscribed again are missed
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(redisResubscribeDelay):
+				}
+				psc, done, err = b.subscribe(ctx)
+				if err == nil {
+					break
+				}
+				common.Logger(ctx).WithError(err).Error("failed to subscribe to the datastore changes again")
+			}
+			select {
+			case changes <- &models.Change{Kind: models.ChangeAll}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// subscribe dials a connection subscribed to the channel, closed once ctx or the returned
+// channel is
+func (b *redisBus) subscribe(ctx context.Context) (*redis.PubSubConn, chan struct{}, error) {
+	// subscribed connections can't be returned to the pool, and closing them is the only way
+	// to interrupt their receiving
+	conn, err := b.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(b.channel); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	// changes published once the subscription is confirmed are received
+	switch v := psc.Receive().(type) {
+	case error:
+		conn.Close()
+		return nil, nil, v
+	case redis.Subscription:
+	default:
+		conn.Close()
+		return nil, nil, fmt.Errorf("unexpected reply %v to subscribe", v)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+	return psc, done, nil
+}
+
+// receive sends the changes received by psc until its connection fails, closing done then
+func (b *redisBus) receive(ctx context.Context, psc *redis.PubSubConn, done chan struct{}, changes chan<- *models.Change) {
+	defer close(done)
+	for {
+		switch v := psc.Receive().(type) {
+		case error:
+			if ctx.Err() == nil {
+				common.Logger(ctx).WithError(v).Error("datastore changes subscription interrupted")
+			}
+			return
+		case redis.Message:
+			var c models.Change
+			if err := json.Unmarshal(v.Data, &c); err != nil {
+				common.Logger(ctx).WithError(err).Error("invalid datastore change")
+				continue
+			}
+			select {
+			case changes <- &c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (b *redisBus) Close() error {
+	return b.pool.Close()
+}
@@ -0,0 +1,408 @@
+// Package cache caches the hot lookups of a datastore, the apps by name and ID, the fns by
+// ID and the triggers by source that each invoke makes, so that nodes serving invokes do not
+// query the db for each of them.
+//
+// The changes made through the cache are published on a Bus, which evicts the results they
+// change from the caches of all the nodes subscribed to it and notifies them to the watchers
+// of the datastore, see models.ChangeWatcher. Results are cached for a TTL, changes made to
+// the db without going through the cache of a node subscribed to the bus are seen once it
+// expires.
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/common/singleflight"
+	"github.com/fnproject/fn/api/models"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	appIDKeyPrefix   = "app-id:"
+	appKeyPrefix     = "app:"
+	fnKeyPrefix      = "fn:"
+	triggerKeyPrefix = "trigger:"
+)
+
+// Datastore caches the lookups of the datastore it wraps
+type Datastore struct {
+	// generation counts the changes, results read before a change are not cached after it
+	generation uint64
+
+	models.Datastore
+
+	cache        *cache.Cache
+	singleflight singleflight.SingleFlight
+	bus          Bus
+	cancel       context.CancelFunc
+
+	mu       sync.Mutex
+	watchers map[chan *models.Change]context.Context
+}
+
+var _ models.ChangeWatcher = new(Datastore)
+
+// New returns a cache of the lookups of ds for ttl, whose changes are published on bus. The
+// changes of datastores which are models.ChangeWatcher are watched instead if bus is nil.
+func New(ctx context.Context, ds models.Datastore, ttl time.Duration, bus Bus) (*Datastore, error) {
+	if bus == nil {
+		if w, ok := ds.(models.ChangeWatcher); ok {
+			bus = &watcherBus{w}
+		} else {
+			bus = NewMemoryBus()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	changes, err := bus.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	d := &Datastore{
+		Datastore: ds,
+		cache:     cache.New(ttl, time.Minute),
+		bus:       bus,
+		cancel:    cancel,
+		watchers:  make(map[chan *models.Change]context.Context),
+	}
+	go d.dispatch(changes)
+	return d, nil
+}
+
+// Wrap returns the cache of New, which is a models.LogStore if ds is one
+func Wrap(ctx context.Context, ds models.Datastore, ttl time.Duration, bus Bus) (models.Datastore, error) {
+	d, err := New(ctx, ds, ttl, bus)
+	if err != nil {
+		return nil, err
+	}
+	if ls, ok := ds.(models.LogStore); ok {
+		return &logDatastore{Datastore: d, LogStore: ls}, nil
+	}
+	return d, nil
+}
+
+// logDatastore is the cache of a datastore which is also its logstore
+type logDatastore struct {
+	*Datastore
+	models.LogStore
+}
+
+// Close closes the datastore, which is the logstore too
+func (d *logDatastore) Close() error {
+	return d.Datastore.Close()
+}
+
+// dispatch evicts the results of the changes of the bus and notifies them to the watchers
+func (d *Datastore) dispatch(changes <-chan *models.Change) {
+	for c := range changes {
+		d.evict(c)
+
+		d.mu.Lock()
+		for w, ctx := range d.watchers {
+			select {
+			case w <- c:
+			case <-ctx.Done():
+				delete(d.watchers, w)
+				close(w)
+			}
+		}
+		d.mu.Unlock()
+	}
+
+	// the bus is closed, so is the datastore
+	d.mu.Lock()
+	for w := range d.watchers {
+		delete(d.watchers, w)
+		close(w)
+	}
+	d.mu.Unlock()
+}
+
+// WatchChanges implements models.ChangeWatcher, with the changes of the bus
+func (d *Datastore) WatchChanges(ctx context.Context) (<-chan *models.Change, error) {
+	w := make(chan *models.Change)
+	d.mu.Lock()
+	d.watchers[w] = ctx
+	d.mu.Unlock()
+	return w, nil
+}
+
+// evict removes the cached results of c
+func (d *Datastore) evict(c *models.Change) {
+	atomic.AddUint64(&d.generation, 1)
+	switch c.Kind {
+	case models.ChangeApp:
+		d.cache.Delete(appKeyPrefix + c.ID)
+		for key, item := range d.cache.Items() {
+			if strings.HasPrefix(key, appIDKeyPrefix) && item.Object == c.ID {
+				d.cache.Delete(key)
+			}
+		}
+		// the fns and triggers of apps go with them
+		if c.Deleted {
+			d.evictPrefix(fnKeyPrefix)
+			d.evictPrefix(triggerKeyPrefix)
+		}
+	case models.ChangeFn:
+		d.cache.Delete(fnKeyPrefix + c.ID)
+		if c.Deleted {
+			d.evictPrefix(triggerKeyPrefix)
+		}
+	case models.ChangeTrigger:
+		// triggers are cached by source
+		d.evictPrefix(triggerKeyPrefix)
+	case models.ChangeDomain:
+		// domains are not cached, only notified to the watchers
+	default:
+		d.cache.Flush()
+	}
+}
+
+func (d *Datastore) evictPrefix(prefix string) {
+	for key := range d.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			d.cache.Delete(key)
+		}
+	}
+}
+
+// changed evicts the results of c right away, for the node making the change to read it
+// back, and publishes c to the other nodes. Changes made in a transaction are evicted again
+// and published once committed, the results read meanwhile being those before the change.
+func (d *Datastore) changed(ctx context.Context, c *models.Change) {
+	d.evict(c)
+	models.AfterCommit(ctx, func() {
+		if models.InTransaction(ctx) {
+			d.evict(c)
+		}
+		if err := d.bus.Publish(ctx, c); err != nil {
+			common.Logger(ctx).WithError(err).WithField("kind", c.Kind).Error("failed to publish datastore change, other nodes see it once their cache expires")
+		}
+	})
+}
+
+// load reads the result of key with f, caching it unless it changed meanwhile. The reads made
+// in a transaction, which may see its uncommitted changes, are neither cached nor shared.
+func (d *Datastore) load(ctx context.Context, key string, f func() (interface{}, error)) (interface{}, error) {
+	if models.InTransaction(ctx) {
+		return f()
+	}
+	if v, ok := d.cache.Get(key); ok {
+		return v, nil
+	}
+
+	generation := atomic.LoadUint64(&d.generation)
+	v, err := d.singleflight.Do(key, f)
+	if err != nil {
+		return nil, err
+	}
+	if atomic.LoadUint64(&d.generation) == generation {
+		d.cache.Set(key, v, cache.DefaultExpiration)
+	}
+	return v, nil
+}
+
+// GetAppID implements models.Datastore
+func (d *Datastore) GetAppID(ctx context.Context, appName string) (string, error) {
+	id, err := d.load(ctx, appIDKeyPrefix+appName, func() (interface{}, error) {
+		return d.Datastore.GetAppID(ctx, appName)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id.(string), nil
+}
+
+// GetAppByID implements models.Datastore
+func (d *Datastore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	app, err := d.load(ctx, appKeyPrefix+appID, func() (interface{}, error) {
+		return d.Datastore.GetAppByID(ctx, appID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return app.(*models.App), nil
+}
+
+// GetFnByID implements models.Datastore
+func (d *Datastore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := d.load(ctx, fnKeyPrefix+fnID, func() (interface{}, error) {
+		return d.Datastore.GetFnByID(ctx, fnID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fn.(*models.Fn), nil
+}
+
+// GetTriggerBySource implements models.Datastore
+func (d *Datastore) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	trigger, err := d.load(ctx, triggerKeyPrefix+appID+"/"+triggerType+"/"+source, func() (interface{}, error) {
+		return d.Datastore.GetTriggerBySource(ctx, appID, triggerType, source)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trigger.(*models.Trigger), nil
+}
+
+// InsertApp implements models.Datastore
+func (d *Datastore) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := d.Datastore.InsertApp(ctx, app)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeApp, ID: app.ID})
+	}
+	return app, err
+}
+
+// UpdateApp implements models.Datastore
+func (d *Datastore) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := d.Datastore.UpdateApp(ctx, app)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeApp, ID: app.ID})
+	}
+	return app, err
+}
+
+// RemoveApp implements models.Datastore
+func (d *Datastore) RemoveApp(ctx context.Context, appID string) error {
+	err := d.Datastore.RemoveApp(ctx, appID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeApp, ID: appID, Deleted: true})
+	}
+	return err
+}
+
+// SoftDeleteApp implements models.Datastore
+func (d *Datastore) SoftDeleteApp(ctx context.Context, appID string) error {
+	err := d.Datastore.SoftDeleteApp(ctx, appID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeApp, ID: appID, Deleted: true})
+	}
+	return err
+}
+
+// RestoreApp implements models.Datastore
+func (d *Datastore) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	app, err := d.Datastore.RestoreApp(ctx, appID)
+	if err == nil {
+		// the fns of the app are restored with it
+		d.changed(ctx, &models.Change{Kind: models.ChangeAll})
+	}
+	return app, err
+}
+
+// InsertFn implements models.Datastore
+func (d *Datastore) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := d.Datastore.InsertFn(ctx, fn)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeFn, ID: fn.ID})
+	}
+	return fn, err
+}
+
+// UpdateFn implements models.Datastore
+func (d *Datastore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := d.Datastore.UpdateFn(ctx, fn)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeFn, ID: fn.ID})
+	}
+	return fn, err
+}
+
+// RemoveFn implements models.Datastore
+func (d *Datastore) RemoveFn(ctx context.Context, fnID string) error {
+	err := d.Datastore.RemoveFn(ctx, fnID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeFn, ID: fnID, Deleted: true})
+	}
+	return err
+}
+
+// SoftDeleteFn implements models.Datastore
+func (d *Datastore) SoftDeleteFn(ctx context.Context, fnID string) error {
+	err := d.Datastore.SoftDeleteFn(ctx, fnID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeFn, ID: fnID, Deleted: true})
+	}
+	return err
+}
+
+// RestoreFn implements models.Datastore
+func (d *Datastore) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := d.Datastore.RestoreFn(ctx, fnID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeFn, ID: fnID})
+	}
+	return fn, err
+}
+
+// InsertTrigger implements models.Datastore
+func (d *Datastore) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := d.Datastore.InsertTrigger(ctx, trigger)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeTrigger, ID: trigger.ID})
+	}
+	return trigger, err
+}
+
+// UpdateTrigger implements models.Datastore
+func (d *Datastore) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := d.Datastore.UpdateTrigger(ctx, trigger)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeTrigger, ID: trigger.ID})
+	}
+	return trigger, err
+}
+
+// RemoveTrigger implements models.Datastore
+func (d *Datastore) RemoveTrigger(ctx context.Context, triggerID string) error {
+	err := d.Datastore.RemoveTrigger(ctx, triggerID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeTrigger, ID: triggerID, Deleted: true})
+	}
+	return err
+}
+
+// InsertDomain implements models.Datastore
+func (d *Datastore) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	domain, err := d.Datastore.InsertDomain(ctx, domain)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeDomain, ID: domain.ID})
+	}
+	return domain, err
+}
+
+// RemoveDomain implements models.Datastore
+func (d *Datastore) RemoveDomain(ctx context.Context, domainID string) error {
+	err := d.Datastore.RemoveDomain(ctx, domainID)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeDomain, ID: domainID, Deleted: true})
+	}
+	return err
+}
+
+// ApplyBatch implements models.Datastore
+func (d *Datastore) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	items, err := d.Datastore.ApplyBatch(ctx, items)
+	if err == nil {
+		d.changed(ctx, &models.Change{Kind: models.ChangeAll})
+	}
+	return items, err
+}
+
+// Close stops the cache, closing its bus and the datastore
+func (d *Datastore) Close() error {
+	d.cancel()
+	err := d.bus.Close()
+	if errDS := d.Datastore.Close(); err == nil {
+		err = errDS
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		ds, err := Wrap(context.Background(), datastore.NewMock(), time.Minute, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ds
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+// testInvalidation checks that the changes made through the cache of a node are seen by the
+// cache of another, the buses of both nodes being connected
+func testInvalidation(t *testing.T, bus1, bus2 Bus) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := datastore.NewMock()
+	node1, err := New(ctx, ds, time.Hour, bus1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node1.Close()
+	node2, err := New(ctx, ds, time.Hour, bus2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := node2.WatchChanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() *models.Change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return nil
+		}
+	}
+
+	app, err := node1.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || c.ID != app.ID {
+		t.Fatalf("expected the app to be changed, got %+v", c)
+	}
+	if id, err := node2.GetAppID(ctx, "myapp"); err != nil || id != app.ID {
+		t.Fatalf("expected app %s, got %s %v", app.ID, id, err)
+	}
+	if _, err := node2.GetAppByID(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := *app
+	updated.Config = models.Config{"foo": "bar"}
+	if _, err := node1.UpdateApp(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || c.ID != app.ID {
+		t.Fatalf("expected the app to be changed, got %+v", c)
+	}
+	got, err := node2.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Config["foo"] != "bar" {
+		t.Fatalf("expected the cache of the other node to be evicted, got config %v", got.Config)
+	}
+
+	if err := node1.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || !c.Deleted {
+		t.Fatalf("expected the app to be deleted, got %+v", c)
+	}
+	if _, err := node2.GetAppID(ctx, "myapp"); err != models.ErrAppsNotFound {
+		t.Fatalf("expected the removed app not to be found, got %v", err)
+	}
+}
+
+func TestMemoryBusInvalidation(t *testing.T) {
+	bus := NewMemoryBus()
+	testInvalidation(t, bus, bus)
+}
+
+func TestRedisBusInvalidation(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("no redis to test with, set REDIS_URL")
+	}
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/fn-test-changes"
+
+	bus1, err := NewRedisBus(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus2, err := NewRedisBus(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testInvalidation(t, bus1, bus2)
+}
@@ -0,0 +1,464 @@
+// Package cassandra implements the datastore over Cassandra or Scylla, see the kv package, for
+// function catalogs too large for a single database. Datastore URLs are of the form
+// cassandra://[user:password@]host:port[,host:port...]/keyspace[?options], scylla:// being
+// the same, with the options:
+//
+//   - consistency: the consistency level of reads and writes, local_quorum by default
+//   - serial_consistency: the consistency level of the locks of transactions, serial or
+//     local_serial, local_serial by default
+//   - local_dc: the data center queries are sent to first, the nearest to the node
+//
+// The keyspace must exist, with the replication of the data centers of the deployment. The
+// tables of the datastore are created in it.
+//
+// The keys of each kind of resource are a partition, and the index keys scoped by an app or a
+// host are a partition per app or host, so that listing them reads a single partition.
+// Transactions lock the partitions they touch with lightweight transactions, then check the
+// keys they read and apply their changes in a logged batch. Views are not isolated from the
+// batches being applied. Nodes of several data centers changing the datastore concurrently
+// require a serial_consistency of serial, and a consistency of quorum for their reads to see
+// the changes of one another.
+//
+// Cassandra does not notify changes, watches poll the revisions of the partitions watched.
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gocql/gocql"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// lockTTL is the time a lock of a node which stopped is held for
+	lockTTL = 30 * time.Second
+	// lockWait is the time a transaction waits for a locked partition before conflicting
+	lockWait = 5 * time.Second
+	// lockBackoff is the time a transaction waits for at most before trying to lock a locked
+	// partition again
+	lockBackoff = 20 * time.Millisecond
+	// watchPoll is the interval watches check for changes at
+	watchPoll = time.Second
+)
+
+// revision is a static column, the revision of the partition, which is also the timestamp of
+// its last batch so that the changes of transactions apply in order whatever the clocks of
+// the nodes
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS kv (
+		partition text,
+		key text,
+		value blob,
+		version bigint,
+		revision bigint static,
+		PRIMARY KEY (partition, key)
+	)`,
+	`CREATE TABLE IF NOT EXISTS kv_locks (
+		partition text PRIMARY KEY,
+		owner text
+	)`,
+}
+
+type cassandraKV struct {
+	session *gocql.Session
+}
+
+var _ kv.KV = new(cassandraKV)
+
+// New connects to the cluster of u, creating the tables of the datastore
+func New(ctx context.Context, u *url.URL) (*kv.Store, error) {
+	c, err := newKV(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return kv.Open(ctx, c)
+}
+
+func newKV(ctx context.Context, u *url.URL) (*cassandraKV, error) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"url": common.MaskPassword(u)})
+
+	cluster := gocql.NewCluster(strings.Split(u.Host, ",")...)
+	cluster.Keyspace = strings.Trim(u.Path, "/")
+	if cluster.Keyspace == "" {
+		return nil, errors.New("the keyspace of the cassandra datastore is missing from its URL")
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		cluster.Authenticator = gocql.PasswordAuthenticator{Username: u.User.Username(), Password: password}
+	}
+
+	q := u.Query()
+	var err error
+	cluster.Consistency, err = gocql.ParseConsistencyWrapper(getOr(q, "consistency", "local_quorum"))
+	if err != nil {
+		return nil, err
+	}
+	if err := cluster.SerialConsistency.UnmarshalText([]byte(strings.ToUpper(getOr(q, "serial_consistency", "local_serial")))); err != nil {
+		return nil, err
+	}
+	if dc := q.Get("local_dc"); dc != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(dc))
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		log.WithError(err).Error("couldn't connect to cassandra")
+		return nil, err
+	}
+	for _, stmt := range schema {
+		if err := session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+			session.Close()
+			log.WithError(err).Error("couldn't create the tables of the datastore")
+			return nil, err
+		}
+	}
+
+	log.WithFields(logrus.Fields{"keyspace": cluster.Keyspace}).Info("datastore dialed")
+	return &cassandraKV{session: session}, nil
+}
+
+func getOr(q url.Values, key, def string) string {
+	if v := q.Get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// partition returns the partition of key and its key within it. Keys are partitioned by their
+// kind, or by their kind and scope for the sub resources of a resource and the index keys of
+// an app or a host, so that each prefix the kv package lists is a partition.
+func partition(key string) (string, string) {
+	n := 2
+	if strings.HasPrefix(key, "index/") {
+		n = 3
+	}
+	segs := strings.SplitN(key, "/", n+1)
+	if len(segs) <= n {
+		n = len(segs) - 1
+	}
+	return strings.Join(segs[:n], "/"), strings.Join(segs[n:], "/")
+}
+
+// prefixPartition returns the partition of the keys under prefix, which must be a partition
+func prefixPartition(prefix string) (string, error) {
+	p, rest := partition(prefix)
+	if rest != "" || p+"/" != prefix {
+		return "", fmt.Errorf("cassandra: prefix %q is not a partition", prefix)
+	}
+	return p, nil
+}
+
+func (c *cassandraKV) revision(ctx context.Context, p string) (int64, error) {
+	var revision int64
+	err := c.session.Query(`SELECT revision FROM kv WHERE partition = ? LIMIT 1`, p).WithContext(ctx).Scan(&revision)
+	if err == gocql.ErrNotFound {
+		err = nil
+	}
+	return revision, err
+}
+
+// Get implements kv.KV
+func (c *cassandraKV) Get(ctx context.Context, key string) (*kv.Entry, error) {
+	p, k := partition(key)
+	e := &kv.Entry{Key: key}
+	err := c.session.Query(`SELECT value, version FROM kv WHERE partition = ? AND key = ?`, p, k).
+		WithContext(ctx).Scan(&e.Value, &e.Version)
+	if err == gocql.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// List implements kv.KV, prefix must be a partition
+func (c *cassandraKV) List(ctx context.Context, prefix string) ([]*kv.Entry, int64, error) {
+	p, err := prefixPartition(prefix)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// rows are in key order within a partition, a partition with a revision and no rows has a
+	// single row with a null key
+	iter := c.session.Query(`SELECT revision, key, value, version FROM kv WHERE partition = ?`, p).WithContext(ctx).Iter()
+	var (
+		entries  []*kv.Entry
+		revision int64
+		rev, ver int64
+		key      string
+		value    []byte
+	)
+	for rows := 0; iter.Scan(&rev, &key, &value, &ver); rows++ {
+		// the partition is listed at the revision of the first page, transactions changing
+		// it while the next pages are read conflict
+		if rows == 0 {
+			revision = rev
+		}
+		if key != "" {
+			entries = append(entries, &kv.Entry{Key: prefix + key, Value: value, Version: ver})
+		}
+		key, value = "", nil
+	}
+	if err := iter.Close(); err != nil {
+		return nil, 0, err
+	}
+	return entries, revision, nil
+}
+
+// Commit implements kv.KV, the prefixes of txn must be partitions
+func (c *cassandraKV) Commit(ctx context.Context, txn *kv.Txn) error {
+	written := make(map[string]bool)
+	touched := make(map[string]bool)
+	for k := range txn.Reads {
+		p, _ := partition(k)
+		touched[p] = true
+	}
+	for prefix := range txn.Lists {
+		p, err := prefixPartition(prefix)
+		if err != nil {
+			return err
+		}
+		touched[p] = true
+	}
+	for _, prefix := range txn.DeletePrefixes {
+		p, err := prefixPartition(prefix)
+		if err != nil {
+			return err
+		}
+		written[p] = true
+	}
+	for _, k := range txn.Deletes {
+		p, _ := partition(k)
+		written[p] = true
+	}
+	for k := range txn.Puts {
+		p, _ := partition(k)
+		written[p] = true
+	}
+	if len(written) == 0 {
+		return nil
+	}
+
+	partitions := make([]string, 0, len(touched)+len(written))
+	for p := range written {
+		touched[p] = true
+	}
+	for p := range touched {
+		partitions = append(partitions, p)
+	}
+	// locking in order, transactions don't wait for one another in a cycle
+	sort.Strings(partitions)
+	owner := id.New().String()
+	defer func() {
+		for _, p := range partitions {
+			c.unlock(p, owner)
+		}
+	}()
+	for _, p := range partitions {
+		if err := c.lock(ctx, p, owner); err != nil {
+			return err
+		}
+	}
+
+	// the partitions are locked, nothing changes them until the batch is applied
+	revisions := make(map[string]int64, len(partitions))
+	for _, p := range partitions {
+		rev, err := c.revision(ctx, p)
+		if err != nil {
+			return err
+		}
+		revisions[p] = rev
+	}
+	for prefix, rev := range txn.Lists {
+		p, _ := prefixPartition(prefix)
+		if revisions[p] != rev {
+			return kv.ErrConflict
+		}
+	}
+	for k, version := range txn.Reads {
+		e, err := c.Get(ctx, k)
+		if err != nil {
+			return err
+		}
+		var current int64
+		if e != nil {
+			current = e.Version
+		}
+		if current != version {
+			return kv.ErrConflict
+		}
+	}
+
+	// the revision of the partitions written is after their last revision and in microseconds
+	// as cassandra timestamps
+	revision := time.Now().UnixNano() / int64(time.Microsecond)
+	for p := range written {
+		if revisions[p] >= revision {
+			revision = revisions[p] + 1
+		}
+	}
+
+	batch := c.session.NewBatch(gocql.LoggedBatch).WithContext(ctx).WithTimestamp(revision)
+	for p := range written {
+		batch.Query(`UPDATE kv SET revision = ? WHERE partition = ?`, revision, p)
+	}
+	for _, prefix := range txn.DeletePrefixes {
+		p, _ := prefixPartition(prefix)
+		batch.Query(`DELETE FROM kv WHERE partition = ? AND key >= ''`, p)
+	}
+	for _, k := range txn.Deletes {
+		p, k := partition(k)
+		batch.Query(`DELETE FROM kv WHERE partition = ? AND key = ?`, p, k)
+	}
+	for k, v := range txn.Puts {
+		p, k := partition(k)
+		batch.Query(`INSERT INTO kv (partition, key, value, version) VALUES (?, ?, ?, ?)`, p, k, v, revision)
+	}
+	return c.session.ExecuteBatch(batch)
+}
+
+// lock locks partition p for owner, returning kv.ErrConflict if it stays locked by another
+// owner for lockWait
+func (c *cassandraKV) lock(ctx context.Context, p, owner string) error {
+	ctx, cancel := context.WithTimeout(ctx, lockWait)
+	defer cancel()
+
+	for {
+		applied, err := c.session.Query(`INSERT INTO kv_locks (partition, owner) VALUES (?, ?) IF NOT EXISTS USING TTL ?`,
+			p, owner, int(lockTTL/time.Second)).WithContext(ctx).MapScanCAS(make(map[string]interface{}))
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return kv.ErrConflict
+			}
+			return err
+		}
+		if applied {
+			return nil
+		}
+
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(lockBackoff)))):
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return kv.ErrConflict
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// unlock unlocks partition p if owner holds its lock. Locks are released even if the context
+// of the transaction is done, their TTL is the last resort.
+func (c *cassandraKV) unlock(p, owner string) {
+	_, err := c.session.Query(`DELETE FROM kv_locks WHERE partition = ? IF owner = ?`, p, owner).
+		MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"partition": p}).Error("couldn't unlock a partition of the datastore")
+	}
+}
+
+// Watch implements kv.KV, prefix must be a partition
+func (c *cassandraKV) Watch(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	if _, err := prefixPartition(prefix); err != nil {
+		return nil, err
+	}
+	entries, revision, err := c.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int64, len(entries))
+	for _, e := range entries {
+		versions[e.Key] = e.Version
+	}
+
+	events := make(chan kv.Event)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(watchPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			p, _ := prefixPartition(prefix)
+			rev, err := c.revision(ctx, p)
+			if err != nil {
+				common.Logger(ctx).WithError(err).Info("cassandra watch interrupted")
+				return
+			}
+			if rev == revision {
+				continue
+			}
+			entries, rev, err := c.List(ctx, prefix)
+			if err != nil {
+				common.Logger(ctx).WithError(err).Info("cassandra watch interrupted")
+				return
+			}
+			revision = rev
+
+			var changes []kv.Event
+			seen := make(map[string]bool, len(entries))
+			for _, e := range entries {
+				seen[e.Key] = true
+				if versions[e.Key] != e.Version {
+					versions[e.Key] = e.Version
+					changes = append(changes, kv.Event{Key: e.Key})
+				}
+			}
+			for k := range versions {
+				if !seen[k] {
+					delete(versions, k)
+					changes = append(changes, kv.Event{Key: k, Deleted: true})
+				}
+			}
+			for _, ev := range changes {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Close implements kv.KV
+func (c *cassandraKV) Close() error {
+	c.session.Close()
+	return nil
+}
+
+type cassandraProvider int
+
+func (cassandraProvider) String() string {
+	return "cassandra"
+}
+
+func (cassandraProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "cassandra" || u.Scheme == "scylla"
+}
+
+func (cassandraProvider) New(ctx context.Context, u *url.URL) (models.Datastore, error) {
+	return New(ctx, u)
+}
+
+func init() {
+	datastore.Register(cassandraProvider(0))
+}
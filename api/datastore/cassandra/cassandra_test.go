@@ -0,0 +1,65 @@
+package cassandra
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestPartition(t *testing.T) {
+	for _, c := range []struct {
+		key, partition, rest string
+	}{
+		{"apps/a1", "apps", "a1"},
+		{"fn_revisions/f1/00000000000000000001", "fn_revisions/f1", "00000000000000000001"},
+		{"index/app_names/myapp", "index/app_names", "myapp"},
+		{"index/fn_names/a1/myfn", "index/fn_names/a1", "myfn"},
+		{"index/trigger_names/a1/f1/mytrigger", "index/trigger_names/a1", "f1/mytrigger"},
+		{"apps/", "apps", ""},
+		{"index/trigger_sources/a1/", "index/trigger_sources/a1", ""},
+	} {
+		p, rest := partition(c.key)
+		if p != c.partition || rest != c.rest {
+			t.Errorf("expected %q to be %q in partition %q, got %q in %q", c.key, c.rest, c.partition, rest, p)
+		}
+	}
+
+	for _, prefix := range []string{"", "apps", "index/trigger_names/a1/f1/"} {
+		if _, err := prefixPartition(prefix); err == nil {
+			t.Errorf("expected prefix %q not to be a partition", prefix)
+		}
+	}
+}
+
+// TestDatastore runs against the keyspace of CASSANDRA_URL, e.g. cassandra://localhost:9042/fntest
+func TestDatastore(t *testing.T) {
+	cassandraURL := os.Getenv("CASSANDRA_URL")
+	if cassandraURL == "" {
+		t.Skip("CASSANDRA_URL is not set")
+	}
+	u, err := url.Parse(cassandraURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	f := func(t *testing.T) models.Datastore {
+		c, err := newKV(ctx, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range []string{"kv", "kv_locks"} {
+			if err := c.session.Query("TRUNCATE " + table).WithContext(ctx).Exec(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return datastoreutil.NewValidator(kv.New(c))
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
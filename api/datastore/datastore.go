@@ -12,7 +12,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// New creates a DataStore from the specified URL
+// New creates a DataStore from the specified URL, whose operations are traced and recorded,
+// see RegisterViews
 func New(ctx context.Context, dbURL string) (models.Datastore, error) {
 	log := common.Logger(ctx)
 	u, err := url.Parse(dbURL)
@@ -23,14 +24,25 @@ func New(ctx context.Context, dbURL string) (models.Datastore, error) {
 
 	for _, provider := range providers {
 		if provider.Supports(u) {
-			return provider.New(ctx, u)
+			ds, err := provider.New(ctx, u)
+			if err != nil {
+				return nil, err
+			}
+			return datastoreutil.MetricDS(ds), nil
 		}
 	}
 	return nil, fmt.Errorf("no data store provider found for storage url %s", u)
 }
 
+// Wrap validates the arguments of the operations of ds
 func Wrap(ds models.Datastore) models.Datastore {
-	return datastoreutil.MetricDS(datastoreutil.NewValidator(ds))
+	return datastoreutil.NewValidator(ds)
+}
+
+// RegisterViews registers the views of the latency and errors of the operations of the
+// datastores of New
+func RegisterViews(tagKeys []string, latencyDist []float64) {
+	datastoreutil.RegisterViews(tagKeys, latencyDist)
 }
 
 // Provider is a datastore provider
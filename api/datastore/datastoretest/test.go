@@ -7,13 +7,19 @@ package datastoretest
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -668,6 +674,208 @@ func RunFnsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 			}
 		})
 
+		t.Run("Update function min ready", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			minReady := uint64(2)
+			updated, err := ds.UpdateFn(ctx, &models.Fn{
+				ID:       testFn.ID,
+				MinReady: &minReady,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.GetMinReady() != minReady {
+				t.Fatalf("expected min ready %d but got %d", minReady, updated.GetMinReady())
+			}
+
+			stored, err := ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stored.GetMinReady() != minReady {
+				t.Fatalf("expected stored min ready %d but got %d", minReady, stored.GetMinReady())
+			}
+
+			// setting 0 removes the minimum
+			minReady = 0
+			updated, err = ds.UpdateFn(ctx, &models.Fn{
+				ID:       testFn.ID,
+				MinReady: &minReady,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.MinReady != nil {
+				t.Fatalf("expected min ready to be removed but got %d", *updated.MinReady)
+			}
+		})
+
+		t.Run("Update function keeps revisions", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			if testFn.Revision != 1 {
+				t.Fatalf("expected inserted fn at revision 1 but got %d", testFn.Revision)
+			}
+
+			// changing neither the image nor the config keeps the revision
+			updated, err := ds.UpdateFn(ctx, &models.Fn{
+				ID:             testFn.ID,
+				ResourceConfig: models.ResourceConfig{Memory: 256},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Revision != 1 {
+				t.Fatalf("expected revision 1 but got %d", updated.Revision)
+			}
+
+			updated, err = ds.UpdateFn(ctx, &models.Fn{
+				ID:     testFn.ID,
+				Image:  "fnproject/fn-test-utils:v2",
+				Config: map[string]string{"A": "a"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Revision != 2 {
+				t.Fatalf("expected revision 2 but got %d", updated.Revision)
+			}
+
+			stored, err := ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stored.Revision != 2 {
+				t.Fatalf("expected stored revision 2 but got %d", stored.Revision)
+			}
+
+			rev, err := ds.GetFnRevision(ctx, testFn.ID, 1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rev.Image != testFn.Image || len(rev.Config) != 0 || rev.AppID != testApp.ID {
+				t.Fatalf("unexpected first revision %+v", rev)
+			}
+			if _, err := ds.GetFnRevision(ctx, testFn.ID, 3); err != models.ErrFnRevisionNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrFnRevisionNotFound, err)
+			}
+
+			revs, err := ds.GetFnRevisions(ctx, &models.FnRevisionFilter{FnID: testFn.ID, PerPage: 1})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(revs.Items) != 1 || revs.Items[0].Revision != 2 || revs.Items[0].Config["A"] != "a" {
+				t.Fatalf("expected revision 2 first, got %+v", revs.Items)
+			}
+			revs, err = ds.GetFnRevisions(ctx, &models.FnRevisionFilter{FnID: testFn.ID, PerPage: 1, Cursor: revs.NextCursor})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(revs.Items) != 1 || revs.Items[0].Revision != 1 {
+				t.Fatalf("expected revision 1 next, got %+v", revs.Items)
+			}
+
+			// revisions go with their fn
+			if err := ds.RemoveFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			revs, err = ds.GetFnRevisions(ctx, &models.FnRevisionFilter{FnID: testFn.ID})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(revs.Items) != 0 {
+				t.Fatalf("expected revisions to be removed, got %d", len(revs.Items))
+			}
+		})
+
+		t.Run("Update function canary records candidate revision", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			updated, err := ds.UpdateFn(ctx, &models.Fn{
+				ID:     testFn.ID,
+				Canary: &models.FnCanary{Weight: 10, Image: "fnproject/fn-test-utils:v2"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Revision != 1 || updated.Canary.Revision != 2 {
+				t.Fatalf("expected revision 1 with candidate revision 2 but got %d and %+v", updated.Revision, updated.Canary)
+			}
+
+			rev, err := ds.GetFnRevision(ctx, testFn.ID, 2)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rev.Image != "fnproject/fn-test-utils:v2" {
+				t.Fatalf("unexpected candidate revision %+v", rev)
+			}
+
+			stored, err := ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !stored.Canary.Equals(updated.Canary) {
+				t.Fatalf("expected stored canary %+v but got %+v", updated.Canary, stored.Canary)
+			}
+
+			// promoting the candidate makes it the current revision
+			updated, err = ds.UpdateFn(ctx, stored.PromotePatch())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.Revision != 2 || updated.Image != "fnproject/fn-test-utils:v2" || updated.Canary != nil {
+				t.Fatalf("expected promoted fn at revision 2 but got %+v", updated)
+			}
+			if _, err := ds.GetFnRevision(ctx, testFn.ID, 3); err != models.ErrFnRevisionNotFound {
+				t.Fatalf("expected error `%v`, but it was `%v`", models.ErrFnRevisionNotFound, err)
+			}
+		})
+
+		t.Run("Update function request and response schemas", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			schema := models.RequestSchema(`{"type":"object","required":["name"]}`)
+			if _, err := ds.UpdateFn(ctx, &models.Fn{ID: testFn.ID, RequestSchema: schema, ResponseSchema: models.ResponseSchema(schema)}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			stored, err := ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !stored.RequestSchema.Equals(schema) || !stored.ResponseSchema.Equals(models.ResponseSchema(schema)) {
+				t.Fatalf("expected stored schemas %s but got %s and %s", schema, stored.RequestSchema, stored.ResponseSchema)
+			}
+
+			// empty schemas remove it
+			if _, err := ds.UpdateFn(ctx, &models.Fn{ID: testFn.ID, RequestSchema: models.RequestSchema(`{}`)}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			stored, err = ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stored.RequestSchema != nil {
+				t.Fatalf("expected request schema to be removed but got %s", stored.RequestSchema)
+			}
+
+			_, err = ds.UpdateFn(ctx, &models.Fn{ID: testFn.ID, RequestSchema: models.RequestSchema(`{"type":"text"}`)})
+			if _, ok := err.(models.ErrFnsInvalidRequestSchema); !ok {
+				t.Fatalf("expected invalid request schema error, but it was `%v`", err)
+			}
+		})
+
 		t.Run("basic pagination no functions", func(t *testing.T) {
 			h := NewHarness(t, ctx, ds)
 			defer h.Cleanup()
@@ -1221,6 +1429,1921 @@ func RunTriggerBySourceTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvide
 
 }
 
+func validSchedule(appID, fnID string) *models.Schedule {
+	return &models.Schedule{
+		Name:    fmt.Sprintf("schedule_%09d", rand.Uint32()),
+		AppID:   appID,
+		FnID:    fnID,
+		Cron:    "@hourly",
+		Payload: `{"hello":"world"}`,
+	}
+}
+
+func RunSchedulesTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("schedules", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert and get", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			start := time.Now()
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if schedule.ID == "" {
+				t.Fatalf("expected ID to be set")
+			}
+			next := time.Time(schedule.NextRunAt)
+			if !next.After(start) || next.After(start.Add(time.Hour)) {
+				t.Fatalf("expected next run within the hour, got %s", schedule.NextRunAt)
+			}
+
+			got, err := ds.GetScheduleByID(ctx, schedule.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if !got.Equals(schedule) || !time.Time(got.NextRunAt).Equal(next) {
+				t.Fatalf("expected %#v got %#v", schedule, got)
+			}
+
+			_, err = ds.InsertSchedule(ctx, &models.Schedule{Name: schedule.Name, AppID: testApp.ID, FnID: testFn.ID, Cron: "@daily"})
+			if err != models.ErrScheduleExists {
+				t.Fatalf("expected ErrScheduleExists, got %v", err)
+			}
+		})
+
+		t.Run("get non-existant", func(t *testing.T) {
+			_, err := ds.GetScheduleByID(ctx, "nonexistant")
+			if err != models.ErrScheduleNotFound {
+				t.Fatalf("expected ErrScheduleNotFound, got %v", err)
+			}
+		})
+
+		t.Run("update cron moves next run", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+
+			updated, err := ds.UpdateSchedule(ctx, &models.Schedule{ID: schedule.ID, Cron: "0 0 1 1 *"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if updated.Cron != "0 0 1 1 *" || !time.Time(updated.NextRunAt).After(time.Now().Add(time.Hour)) {
+				t.Fatalf("expected cron and next run to be updated, got %#v", updated)
+			}
+
+			_, err = ds.UpdateSchedule(ctx, &models.Schedule{ID: schedule.ID, Cron: "* *"})
+			if err != models.ErrScheduleInvalidCron {
+				t.Fatalf("expected ErrScheduleInvalidCron, got %v", err)
+			}
+		})
+
+		t.Run("list schedules", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			for i := 0; i < 3; i++ {
+				if _, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID)); err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+			}
+
+			page, err := ds.GetSchedules(ctx, &models.ScheduleFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.NextCursor == "" {
+				t.Fatalf("expected a full first page, got %#v", page)
+			}
+			page, err = ds.GetSchedules(ctx, &models.ScheduleFilter{AppID: testApp.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 {
+				t.Fatalf("expected one schedule on second page, got %#v", page)
+			}
+		})
+
+		t.Run("due schedules advance once", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			due := time.Time(schedule.NextRunAt)
+
+			found := false
+			schedules, err := ds.GetDueSchedules(ctx, due, 1000)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			for _, s := range schedules {
+				found = found || s.ID == schedule.ID
+			}
+			if !found {
+				t.Fatalf("expected schedule to be due at %s", due)
+			}
+
+			next := due.Add(time.Hour)
+			if err := ds.AdvanceSchedule(ctx, schedule.ID, due, next); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if err := ds.AdvanceSchedule(ctx, schedule.ID, due, next); err != models.ErrScheduleNotDue {
+				t.Fatalf("expected ErrScheduleNotDue, got %v", err)
+			}
+
+			got, err := ds.GetScheduleByID(ctx, schedule.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if !time.Time(got.NextRunAt).Equal(next) {
+				t.Fatalf("expected next run at %s, got %s", next, got.NextRunAt)
+			}
+		})
+
+		t.Run("runs", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+
+			for _, id := range []string{"run1", "run2"} {
+				err := ds.InsertScheduleRun(ctx, &models.ScheduleRun{
+					ID:          id,
+					ScheduleID:  schedule.ID,
+					AppID:       testApp.ID,
+					FnID:        testFn.ID,
+					CallID:      "call_" + id,
+					ScheduledAt: schedule.NextRunAt,
+					CreatedAt:   schedule.NextRunAt,
+					Status:      models.ScheduleRunEnqueued,
+				})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+			}
+
+			runs, err := ds.GetScheduleRuns(ctx, &models.ScheduleRunFilter{ScheduleID: schedule.ID, PerPage: 1})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(runs.Items) != 1 || runs.Items[0].ID != "run2" || runs.Items[0].CallID != "call_run2" {
+				t.Fatalf("expected most recent run first, got %#v", runs.Items)
+			}
+			runs, err = ds.GetScheduleRuns(ctx, &models.ScheduleRunFilter{ScheduleID: schedule.ID, PerPage: 1, Cursor: runs.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(runs.Items) != 1 || runs.Items[0].ID != "run1" {
+				t.Fatalf("expected older run on second page, got %#v", runs.Items)
+			}
+
+			if err := ds.RemoveSchedule(ctx, schedule.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			runs, err = ds.GetScheduleRuns(ctx, &models.ScheduleRunFilter{ScheduleID: schedule.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(runs.Items) != 0 {
+				t.Fatalf("expected runs to be removed with their schedule, got %#v", runs.Items)
+			}
+		})
+
+		t.Run("remove function should remove schedules", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if err := ds.RemoveFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetScheduleByID(ctx, schedule.ID); err != models.ErrScheduleNotFound {
+				t.Fatalf("expected ErrScheduleNotFound, got %v", err)
+			}
+		})
+
+		t.Run("leases", func(t *testing.T) {
+			name := fmt.Sprintf("lease_%09d", rand.Uint32())
+
+			ok, err := ds.AcquireLease(ctx, name, "a", time.Minute)
+			if err != nil || !ok {
+				t.Fatalf("expected to acquire free lease, got %v %v", ok, err)
+			}
+			ok, err = ds.AcquireLease(ctx, name, "b", time.Minute)
+			if err != nil || ok {
+				t.Fatalf("expected held lease not to be acquired, got %v %v", ok, err)
+			}
+			ok, err = ds.AcquireLease(ctx, name, "a", -time.Minute)
+			if err != nil || !ok {
+				t.Fatalf("expected holder to renew lease, got %v %v", ok, err)
+			}
+			ok, err = ds.AcquireLease(ctx, name, "b", time.Minute)
+			if err != nil || !ok {
+				t.Fatalf("expected expired lease to be acquired, got %v %v", ok, err)
+			}
+		})
+	})
+}
+
+func RunAPIKeysTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("api keys", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert, get and remove", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			secret, err := models.NewAPIKeySecret()
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := ds.InsertAPIKey(ctx, &models.APIKey{
+				Name:      "deployer",
+				AppID:     testApp.ID,
+				Scope:     models.APIKeyScopeManage,
+				RateLimit: &models.RateLimit{Rate: 2.5, Burst: 10},
+				Hash:      models.HashAPIKeySecret(secret),
+			})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if key.ID == "" || time.Time(key.CreatedAt).IsZero() {
+				t.Fatalf("expected ID and created time to be set, got %+v", key)
+			}
+
+			got, err := ds.GetAPIKeyByHash(ctx, models.HashAPIKeySecret(secret))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if got.ID != key.ID || got.AppID != testApp.ID || got.Scope != models.APIKeyScopeManage {
+				t.Fatalf("expected %#v got %#v", key, got)
+			}
+			if got.RateLimit == nil || *got.RateLimit != (models.RateLimit{Rate: 2.5, Burst: 10}) {
+				t.Fatalf("expected rate limit to be stored, got %+v", got.RateLimit)
+			}
+			if _, err := ds.GetAPIKeyByHash(ctx, models.HashAPIKeySecret(secret+"x")); err != models.ErrAPIKeyNotFound {
+				t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+			}
+
+			if err := ds.RemoveAPIKey(ctx, key.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetAPIKeyByHash(ctx, key.Hash); err != models.ErrAPIKeyNotFound {
+				t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+			}
+			if err := ds.RemoveAPIKey(ctx, key.ID); err != models.ErrAPIKeyNotFound {
+				t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+			}
+		})
+
+		t.Run("insert invalid", func(t *testing.T) {
+			for _, key := range []*models.APIKey{
+				{Name: "key", Scope: models.APIKeyScopeInvoke},
+				{Name: "key", Scope: "admin", Hash: "hash"},
+				{Name: "key", Scope: models.APIKeyScopeInvoke, Hash: "hash", RateLimit: &models.RateLimit{}},
+				{Name: "not a name", Scope: models.APIKeyScopeInvoke, Hash: "hash"},
+				{Name: "key", Scope: models.APIKeyScopeInvoke, Hash: "hash", ID: "id"},
+			} {
+				if _, err := ds.InsertAPIKey(ctx, key); err == nil {
+					t.Fatalf("expected %+v to be invalid", key)
+				}
+			}
+			_, err := ds.InsertAPIKey(ctx, &models.APIKey{Name: "key", AppID: "nonexistant", Scope: models.APIKeyScopeInvoke, Hash: "hash"})
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected ErrAppsNotFound, got %v", err)
+			}
+		})
+
+		t.Run("list and remove app", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			var ids []string
+			for i := 0; i < 3; i++ {
+				key, err := ds.InsertAPIKey(ctx, &models.APIKey{
+					Name:  fmt.Sprintf("key%d", i),
+					AppID: testApp.ID,
+					Scope: models.APIKeyScopeInvoke,
+					Hash:  fmt.Sprintf("hash_%s_%d", testApp.ID, i),
+				})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				ids = append(ids, key.ID)
+			}
+
+			page, err := ds.GetAPIKeys(ctx, &models.APIKeyFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.Items[0].ID != ids[0] || page.Items[1].ID != ids[1] || page.NextCursor == "" {
+				t.Fatalf("expected the first two keys, got %+v", page)
+			}
+			if page.Items[0].Hash == "" {
+				t.Fatalf("expected hash to be stored")
+			}
+			if page.Items[0].RateLimit != nil {
+				t.Fatalf("expected no rate limit, got %+v", page.Items[0].RateLimit)
+			}
+			page, err = ds.GetAPIKeys(ctx, &models.APIKeyFilter{AppID: testApp.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].ID != ids[2] {
+				t.Fatalf("expected the last key, got %+v", page)
+			}
+
+			// keys go with their app
+			if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			page, err = ds.GetAPIKeys(ctx, &models.APIKeyFilter{AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 0 {
+				t.Fatalf("expected keys to be removed, got %d", len(page.Items))
+			}
+		})
+	})
+}
+
+func RunRoleBindingsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("role bindings", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert, get and remove", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			binding, err := ds.InsertRoleBinding(ctx, &models.RoleBinding{
+				Principal: "alice",
+				Role:      models.RoleDeployer,
+				AppID:     testApp.ID,
+			})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if binding.ID == "" || time.Time(binding.CreatedAt).IsZero() {
+				t.Fatalf("expected ID and created time to be set, got %+v", binding)
+			}
+
+			got, err := ds.GetRoleBindingByID(ctx, binding.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if got.Principal != "alice" || got.AppID != testApp.ID || got.Role != models.RoleDeployer {
+				t.Fatalf("expected %#v got %#v", binding, got)
+			}
+
+			_, err = ds.InsertRoleBinding(ctx, &models.RoleBinding{Principal: "alice", Role: models.RoleDeployer, AppID: testApp.ID})
+			if err != models.ErrRoleBindingExists {
+				t.Fatalf("expected ErrRoleBindingExists, got %v", err)
+			}
+			// the same role on all apps is another binding
+			all, err := ds.InsertRoleBinding(ctx, &models.RoleBinding{Principal: "alice", Role: models.RoleDeployer})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+
+			if err := ds.RemoveRoleBinding(ctx, binding.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetRoleBindingByID(ctx, binding.ID); err != models.ErrRoleBindingNotFound {
+				t.Fatalf("expected ErrRoleBindingNotFound, got %v", err)
+			}
+			if err := ds.RemoveRoleBinding(ctx, binding.ID); err != models.ErrRoleBindingNotFound {
+				t.Fatalf("expected ErrRoleBindingNotFound, got %v", err)
+			}
+			if err := ds.RemoveRoleBinding(ctx, all.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+		})
+
+		t.Run("insert invalid", func(t *testing.T) {
+			for _, binding := range []*models.RoleBinding{
+				{Role: models.RoleReader},
+				{Principal: "alice", Role: "owner"},
+				{Principal: "alice", Role: models.RoleReader, ID: "id"},
+			} {
+				if _, err := ds.InsertRoleBinding(ctx, binding); err == nil {
+					t.Fatalf("expected %+v to be invalid", binding)
+				}
+			}
+			_, err := ds.InsertRoleBinding(ctx, &models.RoleBinding{Principal: "alice", Role: models.RoleReader, AppID: "nonexistant"})
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected ErrAppsNotFound, got %v", err)
+			}
+		})
+
+		t.Run("list and remove app", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			var ids []string
+			for _, role := range []string{models.RoleReader, models.RoleInvoker, models.RoleAdmin} {
+				binding, err := ds.InsertRoleBinding(ctx, &models.RoleBinding{
+					Principal: "bob",
+					Role:      role,
+					AppID:     testApp.ID,
+				})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				ids = append(ids, binding.ID)
+			}
+
+			page, err := ds.GetRoleBindings(ctx, &models.RoleBindingFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.Items[0].ID != ids[0] || page.Items[1].ID != ids[1] || page.NextCursor == "" {
+				t.Fatalf("expected the first two bindings, got %+v", page)
+			}
+			page, err = ds.GetRoleBindings(ctx, &models.RoleBindingFilter{AppID: testApp.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].ID != ids[2] {
+				t.Fatalf("expected the last binding, got %+v", page)
+			}
+
+			page, err = ds.GetRoleBindings(ctx, &models.RoleBindingFilter{Principal: "nobody"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 0 {
+				t.Fatalf("expected no bindings of another principal, got %d", len(page.Items))
+			}
+
+			// bindings go with their app
+			if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			page, err = ds.GetRoleBindings(ctx, &models.RoleBindingFilter{Principal: "bob"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 0 {
+				t.Fatalf("expected bindings to be removed, got %d", len(page.Items))
+			}
+		})
+	})
+}
+
+func RunAppUsageTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("app usage", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+		testApp := h.GivenAppInDb(rp.ValidApp())
+
+		usage, err := ds.GetAppUsage(ctx, testApp.ID, "2018-01")
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if usage.AppID != testApp.ID || usage.Period != "2018-01" || usage.Calls != 0 || usage.MBMilliseconds != 0 {
+			t.Fatalf("expected no usage, got %+v", usage)
+		}
+
+		for i := 0; i < 3; i++ {
+			err := ds.AddAppUsage(ctx, &models.AppUsage{AppID: testApp.ID, Period: "2018-01", Calls: 2, MBMilliseconds: 1024 * 500})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+		}
+		if err := ds.AddAppUsage(ctx, &models.AppUsage{AppID: testApp.ID, Period: "2018-02", Calls: 1}); err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+
+		usage, err = ds.GetAppUsage(ctx, testApp.ID, "2018-01")
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if usage.Calls != 6 || usage.MBMilliseconds != 3*1024*500 || usage.GBSeconds != 1.5 {
+			t.Fatalf("expected usage to add up, got %+v", usage)
+		}
+
+		if err := ds.AddAppUsage(ctx, &models.AppUsage{AppID: testApp.ID, Calls: 1}); err != models.ErrMissingUsagePeriod {
+			t.Fatalf("expected ErrMissingUsagePeriod, got %v", err)
+		}
+
+		// usage goes with its app
+		if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		usage, err = ds.GetAppUsage(ctx, testApp.ID, "2018-01")
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if usage.Calls != 0 {
+			t.Fatalf("expected usage to be removed, got %+v", usage)
+		}
+	})
+}
+
+func RunDomainsTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("domains", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert, get and remove", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			domain, err := ds.InsertDomain(ctx, &models.Domain{Hostname: "api.example.com", AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if domain.ID == "" || time.Time(domain.CreatedAt).IsZero() {
+				t.Fatalf("expected ID and created time to be set, got %+v", domain)
+			}
+
+			got, err := ds.GetDomainByID(ctx, domain.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if got.Hostname != "api.example.com" || got.PathPrefix != "" || got.AppID != testApp.ID {
+				t.Fatalf("expected %#v got %#v", domain, got)
+			}
+
+			_, err = ds.InsertDomain(ctx, &models.Domain{Hostname: "api.example.com", AppID: testApp.ID})
+			if err != models.ErrDomainExists {
+				t.Fatalf("expected ErrDomainExists, got %v", err)
+			}
+			// another prefix of the same hostname is another domain
+			prefixed, err := ds.InsertDomain(ctx, &models.Domain{Hostname: "api.example.com", PathPrefix: "/v1", AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			// but other apps may not take paths of the hostname
+			otherApp := h.GivenAppInDb(rp.ValidApp())
+			_, err = ds.InsertDomain(ctx, &models.Domain{Hostname: "api.example.com", PathPrefix: "/v2", AppID: otherApp.ID})
+			if err != models.ErrDomainHostnameTaken {
+				t.Fatalf("expected ErrDomainHostnameTaken, got %v", err)
+			}
+
+			domains, err := ds.GetDomainsByHostname(ctx, "api.example.com")
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(domains) != 2 {
+				t.Fatalf("expected both domains of the hostname, got %d", len(domains))
+			}
+			domains, err = ds.GetDomainsByHostname(ctx, "www.example.com")
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(domains) != 0 {
+				t.Fatalf("expected no domains of another hostname, got %d", len(domains))
+			}
+
+			if err := ds.RemoveDomain(ctx, domain.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetDomainByID(ctx, domain.ID); err != models.ErrDomainNotFound {
+				t.Fatalf("expected ErrDomainNotFound, got %v", err)
+			}
+			if err := ds.RemoveDomain(ctx, domain.ID); err != models.ErrDomainNotFound {
+				t.Fatalf("expected ErrDomainNotFound, got %v", err)
+			}
+			if err := ds.RemoveDomain(ctx, prefixed.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+		})
+
+		t.Run("insert invalid", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			for _, domain := range []*models.Domain{
+				{AppID: testApp.ID},
+				{Hostname: "API.example.com", AppID: testApp.ID},
+				{Hostname: "api..example.com", AppID: testApp.ID},
+				{Hostname: "api.example.com:8080", AppID: testApp.ID},
+				{Hostname: "api.example.com", PathPrefix: "v1", AppID: testApp.ID},
+				{Hostname: "api.example.com", PathPrefix: "/v1/", AppID: testApp.ID},
+				{Hostname: "api.example.com"},
+				{Hostname: "api.example.com", AppID: testApp.ID, ID: "id"},
+			} {
+				if _, err := ds.InsertDomain(ctx, domain); err == nil {
+					t.Fatalf("expected %+v to be invalid", domain)
+				}
+			}
+			_, err := ds.InsertDomain(ctx, &models.Domain{Hostname: "api.example.com", AppID: "nonexistant"})
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected ErrAppsNotFound, got %v", err)
+			}
+		})
+
+		t.Run("list and remove app", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			var ids []string
+			for _, hostname := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+				domain, err := ds.InsertDomain(ctx, &models.Domain{Hostname: hostname, AppID: testApp.ID})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				ids = append(ids, domain.ID)
+			}
+
+			page, err := ds.GetDomains(ctx, &models.DomainFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.Items[0].ID != ids[0] || page.Items[1].ID != ids[1] || page.NextCursor == "" {
+				t.Fatalf("expected the first two domains, got %+v", page)
+			}
+			page, err = ds.GetDomains(ctx, &models.DomainFilter{AppID: testApp.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].ID != ids[2] {
+				t.Fatalf("expected the last domain, got %+v", page)
+			}
+
+			// domains go with their app
+			if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			page, err = ds.GetDomains(ctx, &models.DomainFilter{AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 0 {
+				t.Fatalf("expected domains to be removed, got %d", len(page.Items))
+			}
+		})
+	})
+}
+
+func RunWebhooksTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("webhooks", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert, get and remove", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+
+			webhook, err := ds.InsertWebhook(ctx, &models.Webhook{
+				URL:    "https://hooks.example.com/fn",
+				Events: models.WebhookEvents{models.EventFnCreated, models.EventFnDeleted},
+				Secret: "0123456789abcdef",
+			})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if webhook.ID == "" || time.Time(webhook.CreatedAt).IsZero() {
+				t.Fatalf("expected ID and created time to be set, got %+v", webhook)
+			}
+
+			got, err := ds.GetWebhookByID(ctx, webhook.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if got.URL != webhook.URL || got.AppID != "" || got.Secret != webhook.Secret ||
+				len(got.Events) != 2 || got.Events[1] != models.EventFnDeleted {
+				t.Fatalf("expected %#v got %#v", webhook, got)
+			}
+
+			all, err := ds.InsertWebhook(ctx, &models.Webhook{URL: "http://hooks.example.com/all", Secret: "0123456789abcdef"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			got, err = ds.GetWebhookByID(ctx, all.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(got.Events) != 0 {
+				t.Fatalf("expected no events, got %v", got.Events)
+			}
+
+			if err := ds.RemoveWebhook(ctx, webhook.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetWebhookByID(ctx, webhook.ID); err != models.ErrWebhookNotFound {
+				t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+			}
+			if err := ds.RemoveWebhook(ctx, webhook.ID); err != models.ErrWebhookNotFound {
+				t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+			}
+			if err := ds.RemoveWebhook(ctx, all.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+		})
+
+		t.Run("insert invalid", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			for _, webhook := range []*models.Webhook{
+				{Secret: "0123456789abcdef"},
+				{URL: "hooks.example.com/fn", Secret: "0123456789abcdef"},
+				{URL: "ftp://hooks.example.com/fn", Secret: "0123456789abcdef"},
+				{URL: "https://hooks.example.com/fn", Events: models.WebhookEvents{"fn.invoked"}, Secret: "0123456789abcdef"},
+				{URL: "https://hooks.example.com/fn", Events: models.WebhookEvents{models.EventFnCreated, models.EventFnCreated}, Secret: "0123456789abcdef"},
+				{URL: "https://hooks.example.com/fn", Secret: "short"},
+				{URL: "https://hooks.example.com/fn", AppID: testApp.ID, Secret: "0123456789abcdef", ID: "id"},
+			} {
+				if _, err := ds.InsertWebhook(ctx, webhook); err == nil {
+					t.Fatalf("expected %+v to be invalid", webhook)
+				}
+			}
+			_, err := ds.InsertWebhook(ctx, &models.Webhook{URL: "https://hooks.example.com/fn", AppID: "nonexistant", Secret: "0123456789abcdef"})
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected ErrAppsNotFound, got %v", err)
+			}
+		})
+
+		t.Run("deliveries and remove app", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			webhook, err := ds.InsertWebhook(ctx, &models.Webhook{URL: "https://hooks.example.com/fn", AppID: testApp.ID, Secret: "0123456789abcdef"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+
+			var ids []string
+			for i := 0; i < 3; i++ {
+				delivery := &models.WebhookDelivery{
+					ID:          id.New().String(),
+					WebhookID:   webhook.ID,
+					EventID:     id.New().String(),
+					Event:       models.EventAppUpdated,
+					Status:      models.WebhookFailed,
+					Attempts:    5,
+					StatusCode:  503,
+					Error:       "webhook responded with 503 Service Unavailable",
+					CreatedAt:   common.DateTime(time.Now()),
+					CompletedAt: common.DateTime(time.Now()),
+				}
+				if err := ds.InsertWebhookDelivery(ctx, delivery); err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				ids = append(ids, delivery.ID)
+			}
+			err = ds.InsertWebhookDelivery(ctx, &models.WebhookDelivery{ID: id.New().String(), WebhookID: "nonexistant"})
+			if err != models.ErrWebhookNotFound {
+				t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+			}
+
+			page, err := ds.GetWebhookDeliveries(ctx, &models.WebhookDeliveryFilter{WebhookID: webhook.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.Items[0].ID != ids[2] || page.Items[1].ID != ids[1] || page.NextCursor == "" {
+				t.Fatalf("expected the two most recent deliveries, got %+v", page)
+			}
+			if d := page.Items[0]; d.Status != models.WebhookFailed || d.Attempts != 5 || d.StatusCode != 503 || d.Error == "" {
+				t.Fatalf("unexpected delivery %+v", d)
+			}
+			page, err = ds.GetWebhookDeliveries(ctx, &models.WebhookDeliveryFilter{WebhookID: webhook.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].ID != ids[0] {
+				t.Fatalf("expected the first delivery, got %+v", page)
+			}
+
+			page2, err := ds.GetWebhooks(ctx, &models.WebhookFilter{AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page2.Items) != 1 || page2.Items[0].ID != webhook.ID {
+				t.Fatalf("expected the webhook of the app, got %+v", page2)
+			}
+
+			// webhooks restricted to an app go with it, along with their deliveries
+			if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetWebhookByID(ctx, webhook.ID); err != models.ErrWebhookNotFound {
+				t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+			}
+			page, err = ds.GetWebhookDeliveries(ctx, &models.WebhookDeliveryFilter{WebhookID: webhook.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 0 {
+				t.Fatalf("expected deliveries to be removed, got %d", len(page.Items))
+			}
+		})
+	})
+}
+
+func RunAuditTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("audit", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("insert invalid", func(t *testing.T) {
+			for _, entry := range []*models.AuditEntry{
+				nil,
+				{Action: models.AuditActionCreate, ResourceType: models.AuditResourceApp, ResourceID: "app_id"},
+				{ID: id.New().String(), ResourceType: models.AuditResourceApp, ResourceID: "app_id"},
+				{ID: id.New().String(), Action: models.AuditActionCreate, ResourceID: "app_id"},
+				{ID: id.New().String(), Action: models.AuditActionCreate, ResourceType: models.AuditResourceApp},
+			} {
+				if err := ds.InsertAuditEntry(ctx, entry); err == nil {
+					t.Fatalf("expected %+v to be invalid", entry)
+				}
+			}
+		})
+
+		t.Run("insert and query", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			start := time.Now().Add(-time.Minute)
+			var ids []string
+			for _, entry := range []*models.AuditEntry{
+				{Action: models.AuditActionCreate, ResourceType: models.AuditResourceApp, ResourceID: testApp.ID, AppID: testApp.ID, Actor: "alice",
+					Diff: models.AuditDiff{"name": {New: testApp.Name}}},
+				{Action: models.AuditActionCreate, ResourceType: models.AuditResourceFn, ResourceID: "fn_id", AppID: testApp.ID, Actor: "bob", Address: "10.0.0.1"},
+				{Action: models.AuditActionUpdate, ResourceType: models.AuditResourceFn, ResourceID: "fn_id", AppID: testApp.ID, Actor: "alice",
+					Diff: models.AuditDiff{"memory": {Old: 128.0, New: 256.0}}},
+				{Action: models.AuditActionCreate, ResourceType: models.AuditResourceAPIKey, ResourceID: "key_id", Actor: "alice"},
+			} {
+				entry.ID = id.New().String()
+				entry.CreatedAt = common.DateTime(time.Now())
+				if err := ds.InsertAuditEntry(ctx, entry); err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				ids = append(ids, entry.ID)
+			}
+
+			all, err := ds.GetAuditEntries(ctx, &models.AuditEntryFilter{})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(all.Items) != 4 || all.Items[0].ID != ids[3] || all.Items[3].ID != ids[0] {
+				t.Fatalf("expected all entries most recent first, got %+v", all.Items)
+			}
+			if d := all.Items[1].Diff["memory"]; d.Old != 128.0 || d.New != 256.0 {
+				t.Fatalf("expected the diff to be kept, got %+v", all.Items[1].Diff)
+			}
+			if all.Items[3].Diff["name"].New != testApp.Name || all.Items[2].Diff != nil || all.Items[2].Address != "10.0.0.1" {
+				t.Fatalf("unexpected entries %+v %+v", all.Items[3], all.Items[2])
+			}
+
+			page, err := ds.GetAuditEntries(ctx, &models.AuditEntryFilter{AppID: testApp.ID, PerPage: 2})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 2 || page.Items[0].ID != ids[2] || page.NextCursor == "" {
+				t.Fatalf("expected the two most recent entries of the app, got %+v", page.Items)
+			}
+			page, err = ds.GetAuditEntries(ctx, &models.AuditEntryFilter{AppID: testApp.ID, PerPage: 2, Cursor: page.NextCursor})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].ID != ids[0] {
+				t.Fatalf("expected the first entry of the app, got %+v", page.Items)
+			}
+
+			for _, test := range []struct {
+				filter   models.AuditEntryFilter
+				expected []string
+			}{
+				{models.AuditEntryFilter{ResourceType: models.AuditResourceFn}, []string{ids[2], ids[1]}},
+				{models.AuditEntryFilter{ResourceType: models.AuditResourceFn, ResourceID: "fn_id", Actor: "alice"}, []string{ids[2]}},
+				{models.AuditEntryFilter{Actor: "carol"}, nil},
+				{models.AuditEntryFilter{FromTime: common.DateTime(start)}, ids},
+				{models.AuditEntryFilter{ToTime: common.DateTime(start)}, nil},
+			} {
+				res, err := ds.GetAuditEntries(ctx, &test.filter)
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				if len(res.Items) != len(test.expected) {
+					t.Fatalf("expected %d entries for %+v, got %+v", len(test.expected), test.filter, res.Items)
+				}
+			}
+
+			// entries outlive the resources they refer to
+			if err := ds.RemoveApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			page, err = ds.GetAuditEntries(ctx, &models.AuditEntryFilter{AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(page.Items) != 3 {
+				t.Fatalf("expected the entries of the app to be kept, got %d", len(page.Items))
+			}
+		})
+	})
+}
+
+func RunSoftDeleteTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("soft delete", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("apps and their fns", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			deletedFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			testTrigger := h.GivenTriggerInDb(rp.ValidTrigger(testApp.ID, testFn.ID))
+
+			if err := ds.SoftDeleteFn(ctx, deletedFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			// fns are restored with the app they were deleted at the same time as
+			time.Sleep(10 * time.Millisecond)
+			if err := ds.SoftDeleteApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if err := ds.SoftDeleteApp(ctx, testApp.ID); err != models.ErrAppsNotFound {
+				t.Fatalf("expected deleting a deleted app to fail with %s, got %v", models.ErrAppsNotFound, err)
+			}
+
+			if _, err := ds.GetAppByID(ctx, testApp.ID); err != models.ErrAppsNotFound {
+				t.Fatalf("expected deleted app to be hidden, got %v", err)
+			}
+			if _, err := ds.GetAppID(ctx, testApp.Name); err != models.ErrAppsNotFound {
+				t.Fatalf("expected deleted app to be hidden, got %v", err)
+			}
+			if _, err := ds.UpdateApp(ctx, &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}}); err != models.ErrAppsNotFound {
+				t.Fatalf("expected deleted app not to be updated, got %v", err)
+			}
+			if _, err := ds.GetFnByID(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected fn of deleted app to be hidden, got %v", err)
+			}
+			if _, err := ds.InsertFn(ctx, rp.ValidFn(testApp.ID)); err != models.ErrAppsNotFound {
+				t.Fatalf("expected fns not to be added to a deleted app, got %v", err)
+			}
+			if _, err := ds.InsertApp(ctx, &models.App{Name: testApp.Name}); err != models.ErrAppsAlreadyExists {
+				t.Fatalf("expected the name of the deleted app to be taken, got %v", err)
+			}
+
+			apps, err := ds.GetApps(ctx, &models.AppFilter{Name: testApp.Name, PerPage: 100})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(apps.Items) != 0 {
+				t.Fatalf("expected deleted app not to be listed, got %+v", apps.Items)
+			}
+			apps, err = ds.GetApps(ctx, &models.AppFilter{Name: testApp.Name, PerPage: 100, Deleted: true})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(apps.Items) != 1 || apps.Items[0].ID != testApp.ID || apps.Items[0].DeletedAt == nil {
+				t.Fatalf("expected deleted app to be listed as deleted, got %+v", apps.Items)
+			}
+
+			fns, err := ds.GetFns(ctx, &models.FnFilter{AppID: testApp.ID, Deleted: true})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(fns.Items) != 2 {
+				t.Fatalf("expected the fns of the app to be listed as deleted, got %+v", fns.Items)
+			}
+			if _, err := ds.RestoreFn(ctx, testFn.ID); err != models.ErrAppsNotFound {
+				t.Fatalf("expected fn of deleted app not to be restored, got %v", err)
+			}
+
+			app, err := ds.RestoreApp(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if app.ID != testApp.ID || app.DeletedAt != nil {
+				t.Fatalf("expected app to be restored, got %+v", app)
+			}
+			if _, err := ds.RestoreApp(ctx, testApp.ID); err != models.ErrAppsNotFound {
+				t.Fatalf("expected restoring a live app to fail with %s, got %v", models.ErrAppsNotFound, err)
+			}
+			if _, err := ds.GetFnByID(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected fn to be restored with its app, got %s", err)
+			}
+			if _, err := ds.GetTriggerByID(ctx, testTrigger.ID); err != nil {
+				t.Fatalf("expected trigger to be kept, got %s", err)
+			}
+			if _, err := ds.GetFnByID(ctx, deletedFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected fn deleted before its app to remain deleted, got %v", err)
+			}
+		})
+
+		t.Run("fns", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			if err := ds.SoftDeleteFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if err := ds.SoftDeleteFn(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected deleting a deleted fn to fail with %s, got %v", models.ErrFnsNotFound, err)
+			}
+			if _, err := ds.GetFnByID(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected deleted fn to be hidden, got %v", err)
+			}
+			if _, err := ds.UpdateFn(ctx, &models.Fn{ID: testFn.ID, Image: "fnproject/hello"}); err != models.ErrFnsNotFound {
+				t.Fatalf("expected deleted fn not to be updated, got %v", err)
+			}
+			fns, err := ds.GetFns(ctx, &models.FnFilter{AppID: testApp.ID})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(fns.Items) != 0 {
+				t.Fatalf("expected deleted fn not to be listed, got %+v", fns.Items)
+			}
+			fn, err := ds.GetDeletedFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if fn.AppID != testApp.ID || fn.DeletedAt == nil {
+				t.Fatalf("expected the deleted fn, got %+v", fn)
+			}
+
+			fn, err = ds.RestoreFn(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if fn.ID != testFn.ID || fn.DeletedAt != nil {
+				t.Fatalf("expected fn to be restored, got %+v", fn)
+			}
+			if _, err := ds.GetDeletedFnByID(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected restored fn not to be deleted, got %v", err)
+			}
+			if _, err := ds.RestoreFn(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected restoring a live fn to fail with %s, got %v", models.ErrFnsNotFound, err)
+			}
+
+			// deleted fns are purged by removing them
+			if err := ds.SoftDeleteFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if err := ds.RemoveFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetDeletedFnByID(ctx, testFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected fn to be purged, got %v", err)
+			}
+		})
+
+		t.Run("deleted fns are not triggered", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			testTrigger := h.GivenTriggerInDb(rp.ValidTrigger(testApp.ID, testFn.ID))
+			schedule, err := ds.InsertSchedule(ctx, validSchedule(testApp.ID, testFn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			due := time.Time(schedule.NextRunAt)
+			isDue := func() bool {
+				schedules, err := ds.GetDueSchedules(ctx, due, 1000)
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				for _, s := range schedules {
+					if s.ID == schedule.ID {
+						return true
+					}
+				}
+				return false
+			}
+
+			if err := ds.SoftDeleteFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetTriggerBySource(ctx, testApp.ID, testTrigger.Type, testTrigger.Source); err != models.ErrTriggerNotFound {
+				t.Fatalf("expected the trigger of a deleted fn not to be found, got %v", err)
+			}
+			if isDue() {
+				t.Fatal("expected the schedule of a deleted fn not to be due")
+			}
+
+			if _, err := ds.RestoreFn(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if trigger, err := ds.GetTriggerBySource(ctx, testApp.ID, testTrigger.Type, testTrigger.Source); err != nil || trigger.ID != testTrigger.ID {
+				t.Fatalf("expected the trigger of a restored fn, got %v %v", trigger, err)
+			}
+			if !isDue() {
+				t.Fatal("expected the schedule of a restored fn to be due")
+			}
+
+			// the fns of deleted apps are deleted with them
+			if err := ds.SoftDeleteApp(ctx, testApp.ID); err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, err := ds.GetTriggerBySource(ctx, testApp.ID, testTrigger.Type, testTrigger.Source); err != models.ErrTriggerNotFound {
+				t.Fatalf("expected the trigger of a deleted app not to be found, got %v", err)
+			}
+			if isDue() {
+				t.Fatal("expected the schedule of a deleted app not to be due")
+			}
+		})
+	})
+}
+
+func RunBatchTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("batch", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("applied", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			removedFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			deletedFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			testTrigger := h.GivenTriggerInDb(rp.ValidTrigger(testApp.ID, testFn.ID))
+
+			newApp := rp.ValidApp()
+			newFn := rp.ValidFn(testApp.ID)
+			newTrigger := rp.ValidTrigger(testApp.ID, testFn.ID)
+			applied, err := ds.ApplyBatch(ctx, []*models.BatchItem{
+				{Action: models.BatchCreate, App: newApp},
+				{Action: models.BatchUpdate, App: &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}}},
+				{Action: models.BatchCreate, Fn: newFn},
+				{Action: models.BatchUpdate, Fn: &models.Fn{ID: testFn.ID, Image: "fnproject/fn-test-utils:2"}},
+				{Action: models.BatchDelete, Fn: &models.Fn{ID: removedFn.ID}},
+				{Action: models.BatchDelete, Fn: &models.Fn{ID: deletedFn.ID}, SoftDelete: true},
+				{Action: models.BatchCreate, Trigger: newTrigger},
+				{Action: models.BatchDelete, Trigger: &models.Trigger{ID: testTrigger.ID}},
+			})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(applied) != 8 {
+				t.Fatalf("expected an applied item for each item, got %d", len(applied))
+			}
+			h.AppForDeletion(applied[0].App)
+
+			if applied[0].App.ID == "" || applied[0].App.Name != newApp.Name {
+				t.Fatalf("expected the created app, got %+v", applied[0].App)
+			}
+			if applied[1].App.Config["A"] != "1" {
+				t.Fatalf("expected the updated app, got %+v", applied[1].App)
+			}
+			if applied[2].Fn.ID == "" || applied[2].Fn.Name != newFn.Name {
+				t.Fatalf("expected the created fn, got %+v", applied[2].Fn)
+			}
+			if applied[3].Fn.Image != "fnproject/fn-test-utils:2" {
+				t.Fatalf("expected the updated fn, got %+v", applied[3].Fn)
+			}
+			if applied[4].Fn.ID != removedFn.ID || applied[4].Fn.Name != removedFn.Name {
+				t.Fatalf("expected the removed fn as it was, got %+v", applied[4].Fn)
+			}
+			if applied[7].Trigger.ID != testTrigger.ID || applied[7].Trigger.Source != testTrigger.Source {
+				t.Fatalf("expected the removed trigger as it was, got %+v", applied[7].Trigger)
+			}
+
+			if _, err := ds.GetAppID(ctx, newApp.Name); err != nil {
+				t.Fatalf("expected the app to be created, got %s", err)
+			}
+			if _, err := ds.GetFnByID(ctx, applied[2].Fn.ID); err != nil {
+				t.Fatalf("expected the fn to be created, got %s", err)
+			}
+			if _, err := ds.GetFnByID(ctx, removedFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected the fn to be removed, got %v", err)
+			}
+			if _, err := ds.GetDeletedFnByID(ctx, removedFn.ID); err != models.ErrFnsNotFound {
+				t.Fatalf("expected the fn to be removed rather than marked deleted, got %v", err)
+			}
+			if _, err := ds.GetDeletedFnByID(ctx, deletedFn.ID); err != nil {
+				t.Fatalf("expected the fn to be marked deleted, got %s", err)
+			}
+			if _, err := ds.GetTriggerByID(ctx, applied[6].Trigger.ID); err != nil {
+				t.Fatalf("expected the trigger to be created, got %s", err)
+			}
+			if _, err := ds.GetTriggerByID(ctx, testTrigger.ID); err != models.ErrTriggerNotFound {
+				t.Fatalf("expected the trigger to be removed, got %v", err)
+			}
+		})
+
+		t.Run("rolled back", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			newApp := rp.ValidApp()
+			_, err := ds.ApplyBatch(ctx, []*models.BatchItem{
+				{Action: models.BatchCreate, App: newApp},
+				{Action: models.BatchUpdate, App: &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}}},
+				{Action: models.BatchDelete, Fn: &models.Fn{ID: testFn.ID}},
+				{Action: models.BatchCreate, Fn: rp.ValidFn("nonexistent")},
+			})
+			e, ok := err.(*models.BatchError)
+			if !ok || e.Index != 3 || e.Err != models.ErrAppsNotFound {
+				t.Fatalf("expected the fn of a missing app to fail with %s, got %v", models.ErrAppsNotFound, err)
+			}
+
+			if id, err := ds.GetAppID(ctx, newApp.Name); err != models.ErrAppsNotFound {
+				h.AppForDeletion(&models.App{ID: id})
+				t.Fatalf("expected the app not to be created, got %v", err)
+			}
+			app, err := ds.GetAppByID(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, ok := app.Config["A"]; ok {
+				t.Fatalf("expected the app not to be updated, got %+v", app)
+			}
+			if _, err := ds.GetFnByID(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected the fn not to be removed, got %s", err)
+			}
+
+			_, err = ds.ApplyBatch(ctx, []*models.BatchItem{
+				{Action: models.BatchCreate, App: &models.App{Name: testApp.Name}},
+			})
+			if e, ok := err.(*models.BatchError); !ok || e.Index != 0 || e.Err != models.ErrAppsAlreadyExists {
+				t.Fatalf("expected a duplicate app to fail with %s, got %v", models.ErrAppsAlreadyExists, err)
+			}
+		})
+	})
+}
+
+func RunTxTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("tx", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+
+		t.Run("committed", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+
+			var app *models.App
+			var fn *models.Fn
+			var trigger *models.Trigger
+			committed := 0
+			err := ds.InTx(ctx, func(ctx context.Context) error {
+				var err error
+				committed = 0
+				app, err = ds.InsertApp(ctx, rp.ValidApp())
+				if err != nil {
+					return err
+				}
+				h.AppForDeletion(app)
+				// the changes of a transaction are read back in it
+				if _, err := ds.GetAppByID(ctx, app.ID); err != nil {
+					return err
+				}
+				fn, err = ds.InsertFn(ctx, rp.ValidFn(app.ID))
+				if err != nil {
+					return err
+				}
+				models.AfterCommit(ctx, func() { committed++ })
+				// nested transactions join the transaction
+				return ds.InTx(ctx, func(ctx context.Context) error {
+					trigger, err = ds.InsertTrigger(ctx, rp.ValidTrigger(app.ID, fn.ID))
+					return err
+				})
+			})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if committed != 1 {
+				t.Fatalf("expected the side effects to run once committed, ran %d times", committed)
+			}
+
+			if _, err := ds.GetAppByID(ctx, app.ID); err != nil {
+				t.Fatalf("expected the app to be created, got %s", err)
+			}
+			if _, err := ds.GetFnByID(ctx, fn.ID); err != nil {
+				t.Fatalf("expected the fn to be created, got %s", err)
+			}
+			if _, err := ds.GetTriggerByID(ctx, trigger.ID); err != nil {
+				t.Fatalf("expected the trigger to be created, got %s", err)
+			}
+		})
+
+		t.Run("rolled back", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			newApp := rp.ValidApp()
+			committed := false
+			err := ds.InTx(ctx, func(ctx context.Context) error {
+				app, err := ds.InsertApp(ctx, newApp)
+				if err != nil {
+					return err
+				}
+				if _, err := ds.InsertFn(ctx, rp.ValidFn(app.ID)); err != nil {
+					return err
+				}
+				if _, err := ds.UpdateApp(ctx, &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}}); err != nil {
+					return err
+				}
+				if err := ds.RemoveFn(ctx, testFn.ID); err != nil {
+					return err
+				}
+				models.AfterCommit(ctx, func() { committed = true })
+				_, err = ds.InsertFn(ctx, rp.ValidFn("nonexistent"))
+				return err
+			})
+			if err != models.ErrAppsNotFound {
+				t.Fatalf("expected the fn of a missing app to fail with %s, got %v", models.ErrAppsNotFound, err)
+			}
+			if committed {
+				t.Fatal("expected the side effects of a rolled back transaction not to run")
+			}
+
+			if id, err := ds.GetAppID(ctx, newApp.Name); err != models.ErrAppsNotFound {
+				h.AppForDeletion(&models.App{ID: id})
+				t.Fatalf("expected the app not to be created, got %v", err)
+			}
+			app, err := ds.GetAppByID(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, ok := app.Config["A"]; ok {
+				t.Fatalf("expected the app not to be updated, got %+v", app)
+			}
+			if _, err := ds.GetFnByID(ctx, testFn.ID); err != nil {
+				t.Fatalf("expected the fn not to be removed, got %s", err)
+			}
+		})
+	})
+}
+
+func RunIfMatchTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("if-match", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		staleCtx := models.WithIfMatch(ctx, []string{`"stale"`})
+
+		t.Run("apps", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+
+			_, err := ds.UpdateApp(staleCtx, &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}})
+			if err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale update to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+
+			app, err := ds.GetAppByID(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if _, ok := app.Config["A"]; ok {
+				t.Fatalf("expected the app not to be updated, got %+v", app)
+			}
+			etag := models.ETag(app)
+
+			updated, err := ds.UpdateApp(models.WithIfMatch(ctx, []string{etag}), &models.App{ID: testApp.ID, Config: models.Config{"A": "1"}})
+			if err != nil {
+				t.Fatalf("expected a current update to succeed, got %s", err)
+			}
+			app, err = ds.GetAppByID(ctx, testApp.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if models.ETag(app) == etag || models.ETag(app) != models.ETag(updated) {
+				t.Fatalf("expected the ETag of the updated app to change and be returned, got %s then %s", etag, models.ETag(app))
+			}
+
+			if err := ds.SoftDeleteApp(models.WithIfMatch(ctx, []string{etag}), testApp.ID); err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale delete to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+			if err := ds.RemoveApp(models.WithIfMatch(ctx, []string{etag}), testApp.ID); err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale remove to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+			if err := ds.RemoveApp(models.WithIfMatch(ctx, []string{etag, models.ETag(app)}), testApp.ID); err != nil {
+				t.Fatalf("expected a current remove to succeed, got %s", err)
+			}
+		})
+
+		t.Run("fns", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+
+			_, err := ds.UpdateFn(staleCtx, &models.Fn{ID: testFn.ID, Image: "fnproject/fn-test-utils:2"})
+			if err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale update to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+
+			fn, err := ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			etag := models.ETag(fn)
+			updated, err := ds.UpdateFn(models.WithIfMatch(ctx, []string{etag}), &models.Fn{ID: testFn.ID, Image: "fnproject/fn-test-utils:2"})
+			if err != nil {
+				t.Fatalf("expected a current update to succeed, got %s", err)
+			}
+			if updated.Image != "fnproject/fn-test-utils:2" {
+				t.Fatalf("expected the fn to be updated, got %+v", updated)
+			}
+
+			if err := ds.SoftDeleteFn(models.WithIfMatch(ctx, []string{etag}), testFn.ID); err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale delete to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+			if err := ds.RemoveFn(staleCtx, testFn.ID); err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale remove to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+
+			fn, err = ds.GetFnByID(ctx, testFn.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if models.ETag(fn) != models.ETag(updated) {
+				t.Fatalf("expected the ETag of the updated fn to be returned, got %s and %s", models.ETag(updated), models.ETag(fn))
+			}
+			if err := ds.SoftDeleteFn(models.WithIfMatch(ctx, []string{models.ETag(fn)}), testFn.ID); err != nil {
+				t.Fatalf("expected a current delete to succeed, got %s", err)
+			}
+		})
+
+		t.Run("triggers", func(t *testing.T) {
+			h := NewHarness(t, ctx, ds)
+			defer h.Cleanup()
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			testFn := h.GivenFnInDb(rp.ValidFn(testApp.ID))
+			testTrigger := h.GivenTriggerInDb(rp.ValidTrigger(testApp.ID, testFn.ID))
+
+			_, err := ds.UpdateTrigger(staleCtx, &models.Trigger{ID: testTrigger.ID, Source: "/updated"})
+			if err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale update to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+			if err := ds.RemoveTrigger(staleCtx, testTrigger.ID); err != models.ErrPreconditionFailed {
+				t.Fatalf("expected a stale remove to fail with %s, got %v", models.ErrPreconditionFailed, err)
+			}
+
+			trigger, err := ds.GetTriggerByID(ctx, testTrigger.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			updated, err := ds.UpdateTrigger(models.WithIfMatch(ctx, []string{models.ETag(trigger)}), &models.Trigger{ID: testTrigger.ID, Source: "/updated"})
+			if err != nil {
+				t.Fatalf("expected a current update to succeed, got %s", err)
+			}
+			if err := ds.RemoveTrigger(models.WithIfMatch(ctx, []string{models.ETag(updated)}), testTrigger.ID); err != nil {
+				t.Fatalf("expected a current remove to succeed, got %s", err)
+			}
+		})
+	})
+}
+
+func RunPersistHookTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("persist-hook", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		errRejected := models.NewAPIError(http.StatusBadRequest, errors.New("rejected by hook"))
+
+		hooked := func(a models.Annotations) string {
+			v, _ := a.GetString("hooked")
+			return v
+		}
+		var calls []bool
+		hookCtx := models.WithPersistHook(ctx, func(ctx context.Context, resource interface{}, created bool) error {
+			calls = append(calls, created)
+			switch r := resource.(type) {
+			case *models.App:
+				if strings.HasSuffix(r.Name, "_rejected") {
+					return errRejected
+				}
+				var err error
+				r.Annotations, err = r.Annotations.With("hooked", "app")
+				return err
+			case *models.Fn:
+				r.Memory = 256
+			case *models.Trigger:
+				var err error
+				r.Annotations, err = r.Annotations.With("hooked", "trigger")
+				return err
+			}
+			return nil
+		})
+
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+
+		t.Run("apps", func(t *testing.T) {
+			calls = nil
+			app, err := ds.InsertApp(hookCtx, rp.ValidApp())
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			h.AppForDeletion(app)
+			if len(calls) != 1 || !calls[0] {
+				t.Fatalf("expected the hook to run once on create, got %v", calls)
+			}
+			stored, err := ds.GetAppByID(ctx, app.ID)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if !stored.Annotations.Equals(app.Annotations) || hooked(stored.Annotations) != "app" {
+				t.Fatalf("expected the changes of the hook to be persisted and returned, got %v and %v", stored.Annotations, app.Annotations)
+			}
+
+			calls = nil
+			updated, err := ds.UpdateApp(hookCtx, &models.App{ID: app.ID, Config: models.Config{"A": "1"}})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(calls) != 1 || calls[0] {
+				t.Fatalf("expected the hook to run once on update, got %v", calls)
+			}
+			if updated.Config["A"] != "1" || hooked(updated.Annotations) != "app" {
+				t.Fatalf("expected the hook to run on the updated app, got %+v", updated)
+			}
+
+			rejected := rp.ValidApp()
+			rejected.Name += "_rejected"
+			if _, err := ds.InsertApp(hookCtx, rejected); err != errRejected {
+				t.Fatalf("expected the error of the hook, got %v", err)
+			}
+			if _, err := ds.GetAppID(ctx, rejected.Name); err != models.ErrAppsNotFound {
+				t.Fatalf("expected the rejected app not to be inserted, got %v", err)
+			}
+		})
+
+		t.Run("fns and triggers", func(t *testing.T) {
+			testApp := h.GivenAppInDb(rp.ValidApp())
+			fn, err := ds.InsertFn(hookCtx, rp.ValidFn(testApp.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if fn.Memory != 256 {
+				t.Fatalf("expected the hook to change the fn, got %+v", fn)
+			}
+			patch := &models.Fn{ID: fn.ID}
+			patch.Memory = 512
+			updated, err := ds.UpdateFn(hookCtx, patch)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if stored, err := ds.GetFnByID(ctx, fn.ID); err != nil || stored.Memory != 256 || updated.Memory != 256 {
+				t.Fatalf("expected the hook to change the updated fn, got %+v %v", stored, err)
+			}
+
+			trigger, err := ds.InsertTrigger(hookCtx, rp.ValidTrigger(testApp.ID, fn.ID))
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if stored, err := ds.GetTriggerByID(ctx, trigger.ID); err != nil || hooked(stored.Annotations) != "trigger" {
+				t.Fatalf("expected the changes of the hook to be persisted, got %+v %v", stored, err)
+			}
+		})
+	})
+}
+
+func RunListFilterTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("list-filters", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+
+		// the values are unique to this run, datastores may hold the resources of other tests
+		team := rp.ValidApp().Name
+		annotations, err := models.EmptyAnnotations().With("team", team)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prefix := rp.ValidApp().Name + "_"
+		app1 := h.GivenAppInDb(&models.App{Name: prefix + "a", Annotations: annotations, Config: models.Config{"TIER": team}})
+		app2 := h.GivenAppInDb(&models.App{Name: prefix + "b", Config: models.Config{"TIER": team}})
+		h.GivenAppInDb(&models.App{Name: rp.ValidApp().Name, Annotations: annotations})
+
+		t.Run("apps", func(t *testing.T) {
+			for _, test := range []struct {
+				filter   *models.AppFilter
+				expected []*models.App
+			}{
+				{&models.AppFilter{NamePrefix: prefix}, []*models.App{app1, app2}},
+				{&models.AppFilter{Config: []models.KeyFilter{{Key: "TIER", Value: team, HasValue: true}}}, []*models.App{app1, app2}},
+				{&models.AppFilter{NamePrefix: prefix, Annotations: []models.KeyFilter{{Key: "team"}}}, []*models.App{app1}},
+				{&models.AppFilter{NamePrefix: prefix, Annotations: []models.KeyFilter{{Key: "team", Value: "other", HasValue: true}}}, nil},
+				{&models.AppFilter{NamePrefix: prefix + "%"}, nil},
+			} {
+				test.filter.PerPage = 100
+				apps, err := ds.GetApps(ctx, test.filter)
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				if len(apps.Items) != len(test.expected) {
+					t.Fatalf("expected %d apps for %+v, got %+v", len(test.expected), test.filter, apps.Items)
+				}
+				for i, app := range test.expected {
+					if apps.Items[i].ID != app.ID {
+						t.Fatalf("expected app %s for %+v, got %+v", app.Name, test.filter, apps.Items[i])
+					}
+				}
+			}
+
+			// the index follows the changes to the app
+			_, err := ds.UpdateApp(ctx, &models.App{ID: app2.ID, Annotations: annotations, Config: models.Config{"TIER": ""}})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			apps, err := ds.GetApps(ctx, &models.AppFilter{PerPage: 100, Annotations: []models.KeyFilter{{Key: "team", Value: team, HasValue: true}}, Config: []models.KeyFilter{{Key: "TIER"}}})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(apps.Items) != 1 || apps.Items[0].ID != app1.ID {
+				t.Fatalf("expected only the first app to match, got %+v", apps.Items)
+			}
+		})
+
+		t.Run("fns", func(t *testing.T) {
+			fn := rp.ValidFn(app1.ID)
+			fn.Name = "filtered_a"
+			fn.Annotations = annotations
+			fn1 := h.GivenFnInDb(fn)
+			fn = rp.ValidFn(app1.ID)
+			fn.Name = "filtered_b"
+			fn.Config = models.Config{"TIER": team}
+			fn2 := h.GivenFnInDb(fn)
+
+			fns, err := ds.GetFns(ctx, &models.FnFilter{AppID: app1.ID, NamePrefix: "filtered_"})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(fns.Items) != 2 {
+				t.Fatalf("expected both fns to match the prefix, got %+v", fns.Items)
+			}
+			fns, err = ds.GetFns(ctx, &models.FnFilter{AppID: app1.ID, Annotations: []models.KeyFilter{{Key: "team", Value: team, HasValue: true}}})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(fns.Items) != 1 || fns.Items[0].ID != fn1.ID {
+				t.Fatalf("expected the first fn to match its annotation, got %+v", fns.Items)
+			}
+			fns, err = ds.GetFns(ctx, &models.FnFilter{AppID: app1.ID, Config: []models.KeyFilter{{Key: "TIER"}}})
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			if len(fns.Items) != 1 || fns.Items[0].ID != fn2.ID {
+				t.Fatalf("expected the second fn to match its config, got %+v", fns.Items)
+			}
+
+			t.Run("triggers", func(t *testing.T) {
+				trigger := rp.ValidTrigger(app1.ID, fn1.ID)
+				trigger.Annotations = annotations
+				t1 := h.GivenTriggerInDb(trigger)
+				h.GivenTriggerInDb(rp.ValidTrigger(app1.ID, fn1.ID))
+
+				triggers, err := ds.GetTriggers(ctx, &models.TriggerFilter{AppID: app1.ID, Annotations: []models.KeyFilter{{Key: "team"}}})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				if len(triggers.Items) != 1 || triggers.Items[0].ID != t1.ID {
+					t.Fatalf("expected the annotated trigger to match, got %+v", triggers.Items)
+				}
+				triggers, err = ds.GetTriggers(ctx, &models.TriggerFilter{AppID: app1.ID, NamePrefix: "trigger_"})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				if len(triggers.Items) != 2 {
+					t.Fatalf("expected both triggers to match the prefix, got %+v", triggers.Items)
+				}
+
+				if err := ds.RemoveTrigger(ctx, t1.ID); err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				triggers, err = ds.GetTriggers(ctx, &models.TriggerFilter{AppID: app1.ID, Annotations: []models.KeyFilter{{Key: "team"}}})
+				if err != nil {
+					t.Fatalf("expected success, got %s", err)
+				}
+				if len(triggers.Items) != 0 {
+					t.Fatalf("expected the removed trigger not to match, got %+v", triggers.Items)
+				}
+			})
+		})
+	})
+}
+
+func RunSearchTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("search", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+
+		// the words are unique to this run, datastores may hold the resources of other tests
+		word := "s" + strings.TrimPrefix(rp.ValidApp().Name, "app_")
+		annotations, err := models.EmptyAnnotations().With(word+".owner", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app := h.GivenAppInDb(&models.App{Name: word + "-app"})
+		fn := rp.ValidFn(app.ID)
+		fn.Name = "checkout"
+		fn.Annotations = annotations
+		fn1 := h.GivenFnInDb(fn)
+		fn = rp.ValidFn(app.ID)
+		fn.Config = models.Config{strings.ToUpper(word) + "_CHECKOUT": "1"}
+		fn2 := h.GivenFnInDb(fn)
+
+		search := func(filter *models.SearchFilter) []*models.SearchResult {
+			t.Helper()
+			res, err := ds.Search(ctx, filter)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			return res.Items
+		}
+
+		results := search(&models.SearchFilter{Terms: []string{word}})
+		if len(results) != 3 || results[0].ID != app.ID || results[0].Type != models.SearchTypeApp ||
+			results[1].ID != fn1.ID || results[1].Type != models.SearchTypeFn || results[1].AppID != app.ID || results[2].Name != fn2.Name {
+			t.Fatalf("expected the app and its fns to match by name, annotation and config, got %+v", results)
+		}
+
+		results = search(&models.SearchFilter{Terms: []string{word, "checkout"}, PerPage: 1})
+		if len(results) != 1 || results[0].ID != fn1.ID {
+			t.Fatalf("expected the first fn matching all terms, got %+v", results)
+		}
+		res, err := ds.Search(ctx, &models.SearchFilter{Terms: []string{word, "checkout"}, PerPage: 1, Cursor: base64.RawURLEncoding.EncodeToString([]byte(fn1.ID))})
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if len(res.Items) != 1 || res.Items[0].ID != fn2.ID {
+			t.Fatalf("expected the next fn matching all terms, got %+v", res.Items)
+		}
+
+		results = search(&models.SearchFilter{Terms: []string{word}, Type: models.SearchTypeApp, AppID: app.ID})
+		if len(results) != 1 || results[0].ID != app.ID {
+			t.Fatalf("expected only the app to match its type, got %+v", results)
+		}
+
+		// terms match the words they prefix, the words of keys are split at punctuation
+		results = search(&models.SearchFilter{Terms: []string{word[:len(word)-1]}, AppID: app.ID})
+		if len(results) != 3 {
+			t.Fatalf("expected the prefix of a word to match, got %+v", results)
+		}
+		results = search(&models.SearchFilter{Terms: []string{word[1:]}, AppID: app.ID})
+		if len(results) != 0 {
+			t.Fatalf("expected the middle of a word not to match, got %+v", results)
+		}
+		results = search(&models.SearchFilter{Terms: []string{"own", "check"}, AppID: app.ID})
+		if len(results) != 1 || results[0].ID != fn1.ID {
+			t.Fatalf("expected the words of an annotation key to match, got %+v", results)
+		}
+
+		// searches are scoped to a set of apps
+		results = search(&models.SearchFilter{Terms: []string{word}, AppIDs: []string{"other", app.ID}})
+		if len(results) != 3 {
+			t.Fatalf("expected the resources of the apps to match, got %+v", results)
+		}
+		results = search(&models.SearchFilter{Terms: []string{word}, AppIDs: []string{"other"}})
+		if len(results) != 0 {
+			t.Fatalf("expected the resources of other apps not to match, got %+v", results)
+		}
+		results = search(&models.SearchFilter{Terms: []string{word}, AppIDs: []string{}})
+		if len(results) != 0 {
+			t.Fatalf("expected nothing to match no apps, got %+v", results)
+		}
+
+		// the document follows the changes to the fn, and is not searched once it is deleted
+		_, err = ds.UpdateFn(ctx, &models.Fn{ID: fn2.ID, Config: models.Config{strings.ToUpper(word) + "_CHECKOUT": ""}})
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if err := ds.SoftDeleteFn(ctx, fn1.ID); err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		results = search(&models.SearchFilter{Terms: []string{word}})
+		if len(results) != 1 || results[0].ID != app.ID {
+			t.Fatalf("expected only the app to still match, got %+v", results)
+		}
+	})
+}
+
+func RunListOrderTest(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
+	t.Run("list-order", func(t *testing.T) {
+		ds := dsf(t)
+		ctx := rp.DefaultCtx()
+		h := NewHarness(t, ctx, ds)
+		defer h.Cleanup()
+
+		app := h.GivenAppInDb(rp.ValidApp())
+		for _, name := range []string{"b", "c", "a"} {
+			fn := rp.ValidFn(app.ID)
+			fn.Name = name
+			h.GivenFnInDb(fn)
+		}
+
+		order, err := models.ParseListOrder(models.OrderByCreatedAt, models.OrderDesc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the fns created once the list has begun are before its cursor, and not listed
+		var listed []*models.Fn
+		filter := &models.FnFilter{AppID: app.ID, PerPage: 1, Order: order}
+		for {
+			fns, err := ds.GetFns(ctx, filter)
+			if err != nil {
+				t.Fatalf("expected success, got %s", err)
+			}
+			listed = append(listed, fns.Items...)
+			if len(listed) == 1 {
+				fn := rp.ValidFn(app.ID)
+				fn.Name = "d"
+				h.GivenFnInDb(fn)
+			}
+			if fns.NextCursor == "" {
+				break
+			}
+			filter.Cursor = fns.NextCursor
+		}
+		if len(listed) != 3 {
+			t.Fatalf("expected the 3 fns created before the list, got %+v", listed)
+		}
+		for i := 1; i < len(listed); i++ {
+			prev, fn := listed[i-1], listed[i]
+			if !order.Less(prev.CreatedAt.String(), prev.ID, fn.CreatedAt.String(), fn.ID) {
+				t.Fatalf("expected fns ordered by created_at desc, got %+v before %+v", prev, fn)
+			}
+		}
+
+		fns, err := ds.GetFns(ctx, &models.FnFilter{AppID: app.ID, PerPage: 2, Order: models.ListOrder{Desc: true}})
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if len(fns.Items) != 2 || fns.Items[0].Name != "d" || fns.Items[1].Name != "c" {
+			t.Fatalf("expected fns ordered by name desc, got %+v", fns.Items)
+		}
+
+		// a cursor of one order cannot be used with another
+		_, err = ds.GetFns(ctx, &models.FnFilter{AppID: app.ID, PerPage: 2, Order: order, Cursor: fns.NextCursor})
+		if err != models.ErrInvalidListCursor {
+			t.Fatalf("expected %s, got %v", models.ErrInvalidListCursor, err)
+		}
+
+		fn := listed[0]
+		trigger := rp.ValidTrigger(app.ID, fn.ID)
+		trigger.Name = "a"
+		t1 := h.GivenTriggerInDb(trigger)
+		trigger = rp.ValidTrigger(app.ID, fn.ID)
+		trigger.Name = "b"
+		t2 := h.GivenTriggerInDb(trigger)
+
+		// updating the first trigger moves it last in order of updated_at
+		time.Sleep(10 * time.Millisecond)
+		t1.Source = "/updated"
+		if _, err := ds.UpdateTrigger(ctx, t1); err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		triggers, err := ds.GetTriggers(ctx, &models.TriggerFilter{AppID: app.ID, Order: models.ListOrder{Field: models.OrderByUpdatedAt}})
+		if err != nil {
+			t.Fatalf("expected success, got %s", err)
+		}
+		if len(triggers.Items) != 2 || triggers.Items[0].ID != t2.ID || triggers.Items[1].ID != t1.ID {
+			t.Fatalf("expected triggers ordered by updated_at, got %+v", triggers.Items)
+		}
+	})
+}
+
 func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	buf := setLogBuffer()
 	defer func() {
@@ -1233,5 +3356,20 @@ func RunAllTests(t *testing.T, dsf DataStoreFunc, rp ResourceProvider) {
 	RunFnsTest(t, dsf, rp)
 	RunTriggersTest(t, dsf, rp)
 	RunTriggerBySourceTests(t, dsf, rp)
+	RunSchedulesTest(t, dsf, rp)
+	RunAPIKeysTest(t, dsf, rp)
+	RunRoleBindingsTest(t, dsf, rp)
+	RunAppUsageTest(t, dsf, rp)
+	RunDomainsTest(t, dsf, rp)
+	RunWebhooksTest(t, dsf, rp)
+	RunAuditTest(t, dsf, rp)
+	RunSoftDeleteTest(t, dsf, rp)
+	RunBatchTest(t, dsf, rp)
+	RunTxTest(t, dsf, rp)
+	RunIfMatchTest(t, dsf, rp)
+	RunPersistHookTest(t, dsf, rp)
+	RunListFilterTest(t, dsf, rp)
+	RunSearchTest(t, dsf, rp)
+	RunListOrderTest(t, dsf, rp)
 
 }
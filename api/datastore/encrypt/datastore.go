@@ -0,0 +1,335 @@
+package encrypt
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// GetAppByID implements models.Datastore
+func (d *Datastore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	app, err := d.Datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptApp(app)
+}
+
+// GetApps implements models.Datastore. The apps are filtered by config values once decrypted,
+// so pages may have fewer apps than requested.
+func (d *Datastore) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	var values []models.KeyFilter
+	if filter != nil {
+		f := *filter
+		f.Config, values = splitConfigFilters(filter.Config)
+		filter = &f
+	}
+	list, err := d.Datastore.GetApps(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.AppList{NextCursor: list.NextCursor, Items: make([]*models.App, 0, len(list.Items))}
+	for _, app := range list.Items {
+		app, err := d.decryptApp(app)
+		if err != nil {
+			return nil, err
+		}
+		if models.MatchKeyFilters(nil, values, app.Config, nil) {
+			res.Items = append(res.Items, app)
+		}
+	}
+	return res, nil
+}
+
+// InsertApp implements models.Datastore
+func (d *Datastore) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	enc, err := d.encryptApp(app, nil)
+	if err != nil {
+		return nil, err
+	}
+	app, err = d.Datastore.InsertApp(d.withPersistHook(ctx), enc)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptApp(app)
+}
+
+// UpdateApp implements models.Datastore
+func (d *Datastore) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	if app != nil && app.ID != "" {
+		stored, err := d.Datastore.GetAppByID(ctx, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		current, err := d.decryptApp(stored)
+		if err != nil {
+			return nil, err
+		}
+		if ctx, err = ifMatch(ctx, current, stored); err != nil {
+			return nil, err
+		}
+		if app, err = d.encryptApp(app, stored); err != nil {
+			return nil, err
+		}
+	}
+	app, err := d.Datastore.UpdateApp(d.withPersistHook(ctx), app)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptApp(app)
+}
+
+// appIfMatch returns the context of a change of the app appID, see ifMatch
+func (d *Datastore) appIfMatch(ctx context.Context, appID string) (context.Context, error) {
+	if !models.HasIfMatch(ctx) || appID == "" {
+		return ctx, nil
+	}
+	stored, err := d.Datastore.GetAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.decryptApp(stored)
+	if err != nil {
+		return nil, err
+	}
+	return ifMatch(ctx, current, stored)
+}
+
+// RemoveApp implements models.Datastore
+func (d *Datastore) RemoveApp(ctx context.Context, appID string) error {
+	ctx, err := d.appIfMatch(ctx, appID)
+	if err != nil {
+		return err
+	}
+	return d.Datastore.RemoveApp(ctx, appID)
+}
+
+// SoftDeleteApp implements models.Datastore
+func (d *Datastore) SoftDeleteApp(ctx context.Context, appID string) error {
+	ctx, err := d.appIfMatch(ctx, appID)
+	if err != nil {
+		return err
+	}
+	return d.Datastore.SoftDeleteApp(ctx, appID)
+}
+
+// RestoreApp implements models.Datastore
+func (d *Datastore) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	app, err := d.Datastore.RestoreApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptApp(app)
+}
+
+// InsertFn implements models.Datastore
+func (d *Datastore) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	enc, err := d.encryptFn(fn, nil)
+	if err != nil {
+		return nil, err
+	}
+	fn, err = d.Datastore.InsertFn(d.withPersistHook(ctx), enc)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptFn(fn)
+}
+
+// UpdateFn implements models.Datastore
+func (d *Datastore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if fn != nil && fn.ID != "" {
+		stored, err := d.Datastore.GetFnByID(ctx, fn.ID)
+		if err != nil {
+			return nil, err
+		}
+		current, err := d.decryptFn(stored)
+		if err != nil {
+			return nil, err
+		}
+		if ctx, err = ifMatch(ctx, current, stored); err != nil {
+			return nil, err
+		}
+		if fn, err = d.encryptFn(fn, stored); err != nil {
+			return nil, err
+		}
+	}
+	fn, err := d.Datastore.UpdateFn(d.withPersistHook(ctx), fn)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptFn(fn)
+}
+
+// GetFns implements models.Datastore. The fns are filtered by config values once decrypted,
+// so pages may have fewer fns than requested.
+func (d *Datastore) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
+	var values []models.KeyFilter
+	if filter != nil {
+		f := *filter
+		f.Config, values = splitConfigFilters(filter.Config)
+		filter = &f
+	}
+	list, err := d.Datastore.GetFns(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.FnList{NextCursor: list.NextCursor, Items: make([]*models.Fn, 0, len(list.Items))}
+	for _, fn := range list.Items {
+		fn, err := d.decryptFn(fn)
+		if err != nil {
+			return nil, err
+		}
+		if models.MatchKeyFilters(nil, values, fn.Config, nil) {
+			res.Items = append(res.Items, fn)
+		}
+	}
+	return res, nil
+}
+
+// GetFnByID implements models.Datastore
+func (d *Datastore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := d.Datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptFn(fn)
+}
+
+// fnIfMatch returns the context of a change of the fn fnID, see ifMatch
+func (d *Datastore) fnIfMatch(ctx context.Context, fnID string) (context.Context, error) {
+	if !models.HasIfMatch(ctx) || fnID == "" {
+		return ctx, nil
+	}
+	stored, err := d.Datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := d.decryptFn(stored)
+	if err != nil {
+		return nil, err
+	}
+	return ifMatch(ctx, current, stored)
+}
+
+// RemoveFn implements models.Datastore
+func (d *Datastore) RemoveFn(ctx context.Context, fnID string) error {
+	ctx, err := d.fnIfMatch(ctx, fnID)
+	if err != nil {
+		return err
+	}
+	return d.Datastore.RemoveFn(ctx, fnID)
+}
+
+// SoftDeleteFn implements models.Datastore
+func (d *Datastore) SoftDeleteFn(ctx context.Context, fnID string) error {
+	ctx, err := d.fnIfMatch(ctx, fnID)
+	if err != nil {
+		return err
+	}
+	return d.Datastore.SoftDeleteFn(ctx, fnID)
+}
+
+// GetDeletedFnByID implements models.Datastore
+func (d *Datastore) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := d.Datastore.GetDeletedFnByID(ctx, fnID)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptFn(fn)
+}
+
+// RestoreFn implements models.Datastore
+func (d *Datastore) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := d.Datastore.RestoreFn(ctx, fnID)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptFn(fn)
+}
+
+// GetFnRevision implements models.Datastore
+func (d *Datastore) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	rev, err := d.Datastore.GetFnRevision(ctx, fnID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return d.decryptRevision(rev)
+}
+
+// GetFnRevisions implements models.Datastore
+func (d *Datastore) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	list, err := d.Datastore.GetFnRevisions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := *list
+	res.Items = make([]*models.FnRevision, 0, len(list.Items))
+	for _, rev := range list.Items {
+		rev, err := d.decryptRevision(rev)
+		if err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, rev)
+	}
+	return &res, nil
+}
+
+// decryptRevision returns a copy of rev with its config decrypted
+func (d *Datastore) decryptRevision(rev *models.FnRevision) (*models.FnRevision, error) {
+	config, err := d.decryptConfig(rev.Config)
+	if err != nil {
+		return nil, err
+	}
+	res := *rev
+	res.Config = config
+	return &res, nil
+}
+
+// ApplyBatch implements models.Datastore
+func (d *Datastore) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	encrypted := make([]*models.BatchItem, 0, len(items))
+	for i, item := range items {
+		enc, err := d.encryptBatchItem(ctx, item)
+		if err != nil {
+			return nil, &models.BatchError{Index: i, Err: err}
+		}
+		encrypted = append(encrypted, enc)
+	}
+
+	applied, err := d.Datastore.ApplyBatch(d.withPersistHook(ctx), encrypted)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range applied {
+		res := *item
+		if res.App, err = d.decryptApp(item.App); err != nil {
+			return nil, err
+		}
+		if res.Fn, err = d.decryptFn(item.Fn); err != nil {
+			return nil, err
+		}
+		applied[i] = &res
+	}
+	return applied, nil
+}
+
+// encryptBatchItem returns a copy of item with the config of its app or fn encrypted, keeping
+// the unchanged values of the resources updated
+func (d *Datastore) encryptBatchItem(ctx context.Context, item *models.BatchItem) (*models.BatchItem, error) {
+	res := *item
+	var err error
+	switch {
+	case item.App != nil && item.Action == models.BatchCreate:
+		res.App, err = d.encryptApp(item.App, nil)
+	case item.App != nil && item.Action == models.BatchUpdate && item.App.Config != nil:
+		// the apps created by prior items are not found, and have no values to keep
+		stored, _ := d.Datastore.GetAppByID(ctx, item.App.ID)
+		res.App, err = d.encryptApp(item.App, stored)
+	case item.Fn != nil && item.Action == models.BatchCreate:
+		res.Fn, err = d.encryptFn(item.Fn, nil)
+	case item.Fn != nil && item.Action == models.BatchUpdate:
+		stored, _ := d.Datastore.GetFnByID(ctx, item.Fn.ID)
+		res.Fn, err = d.encryptFn(item.Fn, stored)
+	}
+	return &res, err
+}
@@ -0,0 +1,321 @@
+// Package encrypt encrypts the config values of apps and fns, which commonly hold credentials,
+// before they are written to a datastore, and decrypts them as they are read, so that the
+// consumers of the API see them as they set them.
+//
+// Values are encrypted with the first key of a keyring, and decrypted with the key of the
+// keyring they were encrypted with, which the values identify, so that keys can be rotated:
+// a new key is put first while the previous ones remain to decrypt the values encrypted with
+// them, until Reencrypt encrypts those with the new key. Values written before encryption was
+// enabled are read as they are, until Reencrypt encrypts them.
+package encrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// valuePrefix starts the encrypted values, followed by the id of their key, a colon and their
+// ciphertext in base64
+const valuePrefix = "fnenc:v1:"
+
+// Datastore encrypts the config values of the apps and fns of the datastore it wraps
+type Datastore struct {
+	models.Datastore
+	keys []Key
+	byID map[string]Key
+}
+
+// New returns a datastore encrypting the config values of ds with the first of keys, and
+// decrypting them with any of keys.
+func New(ds models.Datastore, keys []Key) (*Datastore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no config encryption key")
+	}
+	d := &Datastore{Datastore: ds, keys: keys, byID: make(map[string]Key, len(keys))}
+	for _, k := range keys {
+		if _, ok := d.byID[k.ID()]; ok {
+			return nil, fmt.Errorf("config encryption key %s is given more than once", k.ID())
+		}
+		d.byID[k.ID()] = k
+	}
+	return d, nil
+}
+
+// Wrap returns the datastore of New, which is a models.LogStore or a models.ChangeWatcher if
+// ds is one
+func Wrap(ds models.Datastore, keys []Key) (models.Datastore, error) {
+	d, err := New(ds, keys)
+	if err != nil {
+		return nil, err
+	}
+	if ls, ok := ds.(models.LogStore); ok {
+		return &logDatastore{Datastore: d, LogStore: ls}, nil
+	}
+	if w, ok := ds.(models.ChangeWatcher); ok {
+		return &watchedDatastore{Datastore: d, ChangeWatcher: w}, nil
+	}
+	return d, nil
+}
+
+// logDatastore encrypts the configs of a datastore which is also its logstore
+type logDatastore struct {
+	*Datastore
+	models.LogStore
+}
+
+// Close closes the datastore, which is the logstore too
+func (d *logDatastore) Close() error {
+	return d.Datastore.Close()
+}
+
+// watchedDatastore encrypts the configs of a datastore notifying its changes
+type watchedDatastore struct {
+	*Datastore
+	models.ChangeWatcher
+}
+
+// encryptValue returns v encrypted with the first key, empty values which remove config keys
+// are left as they are
+func (d *Datastore) encryptValue(v string) (string, error) {
+	if v == "" {
+		return v, nil
+	}
+	key := d.keys[0]
+	ciphertext, err := key.Encrypt([]byte(v))
+	if err != nil {
+		return "", err
+	}
+	return valuePrefix + key.ID() + ":" + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptValue returns v decrypted, values which are not encrypted are returned as they are
+func (d *Datastore) decryptValue(v string) (string, error) {
+	if !strings.HasPrefix(v, valuePrefix) {
+		return v, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(v, valuePrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("invalid encrypted config value")
+	}
+	key, ok := d.byID[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("config value encrypted with unknown key %s", parts[0])
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := key.Decrypt(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt config value with key %s: %v", parts[0], err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptConfig returns the config c encrypted. The values of stored, the config as currently
+// stored, which are unchanged in c are kept, so that updates setting them change nothing.
+func (d *Datastore) encryptConfig(c, stored models.Config) (models.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	res := make(models.Config, len(c))
+	for k, v := range c {
+		if s, ok := stored[k]; ok && s != "" {
+			if plain, err := d.decryptValue(s); err == nil && plain == v {
+				res[k] = s
+				continue
+			}
+		}
+		enc, err := d.encryptValue(v)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = enc
+	}
+	return res, nil
+}
+
+// decryptConfig returns the config c decrypted
+func (d *Datastore) decryptConfig(c models.Config) (models.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	res := make(models.Config, len(c))
+	for k, v := range c {
+		plain, err := d.decryptValue(v)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = plain
+	}
+	return res, nil
+}
+
+// encrypted reports whether all the values of c are encrypted with the first key
+func (d *Datastore) encrypted(c models.Config) bool {
+	prefix := valuePrefix + d.keys[0].ID() + ":"
+	for _, v := range c {
+		if v != "" && !strings.HasPrefix(v, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// encryptApp returns a copy of app with its config encrypted, keeping the unchanged values of
+// stored if not nil
+func (d *Datastore) encryptApp(app, stored *models.App) (*models.App, error) {
+	if app == nil || app.Config == nil {
+		return app, nil
+	}
+	var storedConfig models.Config
+	if stored != nil {
+		storedConfig = stored.Config
+	}
+	config, err := d.encryptConfig(app.Config, storedConfig)
+	if err != nil {
+		return nil, err
+	}
+	res := *app
+	res.Config = config
+	return &res, nil
+}
+
+// decryptApp returns a copy of app with its config decrypted, datastores may return the
+// apps they hold
+func (d *Datastore) decryptApp(app *models.App) (*models.App, error) {
+	if app == nil {
+		return nil, nil
+	}
+	config, err := d.decryptConfig(app.Config)
+	if err != nil {
+		return nil, err
+	}
+	res := *app
+	res.Config = config
+	return &res, nil
+}
+
+// encryptFn returns a copy of fn with its config and the config of its canary encrypted,
+// keeping the unchanged values of stored if not nil
+func (d *Datastore) encryptFn(fn, stored *models.Fn) (*models.Fn, error) {
+	if fn == nil {
+		return nil, nil
+	}
+	var storedConfig, storedCanaryConfig models.Config
+	if stored != nil {
+		storedConfig = stored.Config
+		if stored.Canary != nil {
+			storedCanaryConfig = stored.Canary.Config
+		}
+	}
+	res := *fn
+	var err error
+	res.Config, err = d.encryptConfig(fn.Config, storedConfig)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Canary != nil {
+		canary := *fn.Canary
+		canary.Config, err = d.encryptConfig(fn.Canary.Config, storedCanaryConfig)
+		if err != nil {
+			return nil, err
+		}
+		res.Canary = &canary
+	}
+	return &res, nil
+}
+
+// decryptFn returns a copy of fn with its config and the config of its canary decrypted
+func (d *Datastore) decryptFn(fn *models.Fn) (*models.Fn, error) {
+	if fn == nil {
+		return nil, nil
+	}
+	res := *fn
+	var err error
+	res.Config, err = d.decryptConfig(fn.Config)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Canary != nil {
+		canary := *fn.Canary
+		canary.Config, err = d.decryptConfig(fn.Canary.Config)
+		if err != nil {
+			return nil, err
+		}
+		res.Canary = &canary
+	}
+	return &res, nil
+}
+
+// ifMatch checks the If-Match precondition of ctx, if it has one, against the resource as
+// read through the datastore, returning the context checking it against the resource as
+// stored, whose ETag the wrapped datastore computes
+func ifMatch(ctx context.Context, decrypted, stored interface{}) (context.Context, error) {
+	if !models.HasIfMatch(ctx) {
+		return ctx, nil
+	}
+	if err := models.CheckIfMatch(ctx, decrypted); err != nil {
+		return nil, err
+	}
+	return models.WithIfMatch(ctx, []string{models.ETag(stored)}), nil
+}
+
+// splitConfigFilters returns the config filters the wrapped datastore matches, on keys only,
+// and the filters on values, matched once the values are decrypted
+func splitConfigFilters(filters []models.KeyFilter) (keys, values []models.KeyFilter) {
+	for _, f := range filters {
+		if f.HasValue {
+			values = append(values, f)
+		}
+		keys = append(keys, models.KeyFilter{Key: f.Key})
+	}
+	return keys, values
+}
+
+// withPersistHook returns ctx with its persist hook, if it has one, run on the apps and fns
+// with their config decrypted, the values it changes being encrypted again
+func (d *Datastore) withPersistHook(ctx context.Context) context.Context {
+	hook := models.PersistHookFrom(ctx)
+	if hook == nil {
+		return ctx
+	}
+	return models.WithPersistHook(ctx, func(ctx context.Context, resource interface{}, created bool) error {
+		switch r := resource.(type) {
+		case *models.App:
+			app, err := d.decryptApp(r)
+			if err != nil {
+				return err
+			}
+			if err := hook(ctx, app, created); err != nil {
+				return err
+			}
+			enc, err := d.encryptApp(app, r)
+			if err != nil {
+				return err
+			}
+			*r = *enc
+		case *models.Fn:
+			fn, err := d.decryptFn(r)
+			if err != nil {
+				return err
+			}
+			if err := hook(ctx, fn, created); err != nil {
+				return err
+			}
+			enc, err := d.encryptFn(fn, r)
+			if err != nil {
+				return err
+			}
+			*r = *enc
+		default:
+			return hook(ctx, resource, created)
+		}
+		return nil
+	})
+}
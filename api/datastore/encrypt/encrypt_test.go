@@ -0,0 +1,235 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/models"
+)
+
+// testKey returns a file key written in dir, encoded in hex
+func testKey(t *testing.T, dir, name string) Key {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(b)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewKey("file://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestDatastore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := testKey(t, dir, "key")
+
+	f := func(t *testing.T) models.Datastore {
+		ds, err := Wrap(datastore.NewMock(), []Key{key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ds
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "encrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old, current := testKey(t, dir, "old"), testKey(t, dir, "current")
+
+	raw := datastore.NewMock()
+	plainApp, err := raw.InsertApp(ctx, &models.App{Name: "plain", Config: models.Config{"TOKEN": "plain"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := New(raw, []Key{old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp", Config: models.Config{"TOKEN": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Config["TOKEN"] != "secret" {
+		t.Fatalf("expected the config to be returned decrypted, got %v", app.Config)
+	}
+	fn := &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/myfn", Config: models.Config{"PASSWORD": "hunter2"}}
+	fn.SetDefaults()
+	fn, err = ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := raw.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := stored.Config["TOKEN"]; !strings.HasPrefix(v, valuePrefix+old.ID()+":") || strings.Contains(v, "secret") {
+		t.Fatalf("expected the config to be stored encrypted with the old key, got %v", v)
+	}
+
+	// updates leaving a value unchanged keep its ciphertext
+	updated, err := ds.UpdateApp(ctx, &models.App{ID: app.ID, Config: models.Config{"TOKEN": "secret", "LEVEL": "info"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Config["TOKEN"] != "secret" || updated.Config["LEVEL"] != "info" {
+		t.Fatalf("unexpected config %v", updated.Config)
+	}
+	again, err := raw.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Config["TOKEN"] != stored.Config["TOKEN"] {
+		t.Fatal("expected the unchanged value to keep its ciphertext")
+	}
+
+	// the If-Match of the decrypted app is checked
+	if _, err := ds.UpdateApp(models.WithIfMatch(ctx, []string{models.ETag(updated)}), &models.App{ID: app.ID, Config: models.Config{"LEVEL": "debug"}}); err != nil {
+		t.Fatalf("expected the ETag of the decrypted app to match, got %v", err)
+	}
+	if _, err := ds.UpdateApp(models.WithIfMatch(ctx, []string{models.ETag(updated)}), &models.App{ID: app.ID, Config: models.Config{"LEVEL": "info"}}); err != models.ErrPreconditionFailed {
+		t.Fatalf("expected a stale ETag to fail, got %v", err)
+	}
+
+	// config values are filtered once decrypted
+	apps, err := ds.GetApps(ctx, &models.AppFilter{PerPage: 10, Config: []models.KeyFilter{{Key: "TOKEN", Value: "secret", HasValue: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps.Items) != 1 || apps.Items[0].ID != app.ID {
+		t.Fatalf("expected the app to match its decrypted config, got %+v", apps.Items)
+	}
+
+	// the new key reads the values of the old key until they are re-encrypted
+	rotated, err := New(raw, []Key{current, old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rotated.GetFnByID(ctx, fn.ID)
+	if err != nil || got.Config["PASSWORD"] != "hunter2" {
+		t.Fatalf("expected the fn to be read with the old key, got %+v %v", got, err)
+	}
+	apps2, fns2, err := rotated.Reencrypt(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apps2 != 2 || fns2 != 1 {
+		t.Fatalf("expected 2 apps and 1 fn to be re-encrypted, got %d and %d", apps2, fns2)
+	}
+	if apps2, fns2, err = rotated.Reencrypt(ctx); err != nil || apps2 != 0 || fns2 != 0 {
+		t.Fatalf("expected nothing left to re-encrypt, got %d, %d, %v", apps2, fns2, err)
+	}
+	for _, id := range []string{app.ID, plainApp.ID} {
+		stored, err := raw.GetAppByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rotated.encrypted(stored.Config) {
+			t.Fatalf("expected the app to be re-encrypted with the current key, got %v", stored.Config)
+		}
+	}
+
+	// the current key alone reads the re-encrypted values
+	only, err := New(raw, []Key{current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := only.GetAppByID(ctx, plainApp.ID)
+	if err != nil || plain.Config["TOKEN"] != "plain" {
+		t.Fatalf("expected the plaintext app to be re-encrypted, got %+v %v", plain, err)
+	}
+	if _, err := New(raw, []Key{old, old}); err == nil {
+		t.Fatal("expected a key given twice to fail")
+	}
+}
+
+// fakeKMS wraps data keys by reversing them
+type fakeKMS struct {
+	decrypts int
+}
+
+func reversed(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := range b {
+		res[len(b)-1-i] = b[i]
+	}
+	return res
+}
+
+func (f *fakeKMS) GenerateDataKey(in *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{KeyId: in.KeyId, Plaintext: key, CiphertextBlob: reversed(key)}, nil
+}
+
+func (f *fakeKMS) Decrypt(in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	f.decrypts++
+	if len(in.CiphertextBlob) != 32 {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{Plaintext: reversed(in.CiphertextBlob)}, nil
+}
+
+func TestKMSKey(t *testing.T) {
+	client := &fakeKMS{}
+	k1, err := newKMSKey("alias/fn", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := k1.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("secret")) {
+		t.Fatal("expected the value to be encrypted")
+	}
+
+	// another node, with another data key, unwraps the data key of the value once
+	k2, err := newKMSKey("alias/fn", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k1.ID() != k2.ID() {
+		t.Fatalf("expected the ids of a KMS key to be the same, got %s and %s", k1.ID(), k2.ID())
+	}
+	for i := 0; i < 2; i++ {
+		plaintext, err := k2.Decrypt(ciphertext)
+		if err != nil || string(plaintext) != "secret" {
+			t.Fatalf("expected the value to be decrypted, got %q %v", plaintext, err)
+		}
+	}
+	if client.decrypts != 1 {
+		t.Fatalf("expected the data key to be unwrapped once, got %d", client.decrypts)
+	}
+	if _, err := k2.Decrypt(ciphertext[:1]); err == nil {
+		t.Fatal("expected a truncated value to fail")
+	}
+}
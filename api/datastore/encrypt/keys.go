@@ -0,0 +1,226 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// Key encrypts and decrypts config values
+type Key interface {
+	// ID identifies the key in the values it encrypts, it may not contain colons
+	ID() string
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// NewKey returns the key of u, which is either file:///path of a file holding a 32 byte
+// AES-256 key, raw or encoded in hex or base64, or awskms:key-id[?region=region] of an AWS
+// KMS key, by id, ARN or alias/name, which wraps the AES-256 keys encrypting values.
+func NewKey(u string) (Key, error) {
+	switch {
+	case strings.HasPrefix(u, "file://"):
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileKey(parsed.Path)
+	case strings.HasPrefix(u, "awskms:"):
+		keyID, query := strings.TrimPrefix(strings.TrimPrefix(u, "awskms:"), "//"), ""
+		if i := strings.Index(keyID, "?"); i >= 0 {
+			keyID, query = keyID[:i], keyID[i+1:]
+		}
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		return NewKMSKey(keyID, values.Get("region"))
+	}
+	return nil, fmt.Errorf("no config encryption key for %s, expected a file:// or awskms: key", u)
+}
+
+// aesKey encrypts values with AES-256-GCM, the nonce preceding the ciphertext
+type aesKey struct {
+	aead cipher.AEAD
+}
+
+func newAESKey(key []byte) (*aesKey, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid config encryption key of %d bytes, expected 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKey{aead: aead}, nil
+}
+
+func (k *aesKey) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k *aesKey) open(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < k.aead.NonceSize() {
+		return nil, errors.New("config value ciphertext too short")
+	}
+	nonce := ciphertext[:k.aead.NonceSize()]
+	return k.aead.Open(nil, nonce, ciphertext[k.aead.NonceSize():], nil)
+}
+
+// fileKey is an AES-256 key read from a file
+type fileKey struct {
+	*aesKey
+	id string
+}
+
+// NewFileKey returns the key of the file at path, see NewKey. Its id is derived from the key.
+func NewFileKey(path string) (Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := data
+	if len(key) != 32 {
+		text := strings.TrimSpace(string(data))
+		if b, err := hex.DecodeString(text); err == nil {
+			key = b
+		} else if b, err := base64.StdEncoding.DecodeString(text); err == nil {
+			key = b
+		}
+	}
+	k, err := newAESKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	sum := sha256.Sum256(key)
+	return &fileKey{aesKey: k, id: "file-" + hex.EncodeToString(sum[:4])}, nil
+}
+
+func (k *fileKey) ID() string                                { return k.id }
+func (k *fileKey) Encrypt(plaintext []byte) ([]byte, error)  { return k.seal(plaintext) }
+func (k *fileKey) Decrypt(ciphertext []byte) ([]byte, error) { return k.open(ciphertext) }
+
+// kmsAPI is the part of the KMS client the keys use
+type kmsAPI interface {
+	GenerateDataKey(*kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(*kms.DecryptInput) (*kms.DecryptOutput, error)
+}
+
+// kmsKey encrypts values with a data key generated by KMS, the data key wrapped by KMS
+// preceding each of them. The data keys of the values decrypted are unwrapped once.
+type kmsKey struct {
+	id      string
+	client  kmsAPI
+	wrapped []byte
+	dataKey *aesKey
+
+	mu       sync.Mutex
+	dataKeys map[string]*aesKey // by wrapped data key
+}
+
+// NewKMSKey returns the key of the AWS KMS key keyID, in region or in the region of the AWS
+// config if empty. Its id is derived from keyID, the data key is generated once.
+func NewKMSKey(keyID, region string) (Key, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	return newKMSKey(keyID, kms.New(sess))
+}
+
+func newKMSKey(keyID string, client kmsAPI) (*kmsKey, error) {
+	out, err := client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(keyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := newAESKey(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(keyID))
+	return &kmsKey{
+		id:       "kms-" + hex.EncodeToString(sum[:4]),
+		client:   client,
+		wrapped:  out.CiphertextBlob,
+		dataKey:  dataKey,
+		dataKeys: map[string]*aesKey{string(out.CiphertextBlob): dataKey},
+	}, nil
+}
+
+func (k *kmsKey) ID() string { return k.id }
+
+func (k *kmsKey) Encrypt(plaintext []byte) ([]byte, error) {
+	sealed, err := k.dataKey.seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 2, 2+len(k.wrapped)+len(sealed))
+	binary.BigEndian.PutUint16(out, uint16(len(k.wrapped)))
+	out = append(out, k.wrapped...)
+	return append(out, sealed...), nil
+}
+
+func (k *kmsKey) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2 || len(ciphertext) < 2+int(binary.BigEndian.Uint16(ciphertext)) {
+		return nil, errors.New("config value ciphertext too short")
+	}
+	n := 2 + int(binary.BigEndian.Uint16(ciphertext))
+	dataKey, err := k.unwrap(ciphertext[2:n])
+	if err != nil {
+		return nil, err
+	}
+	return dataKey.open(ciphertext[n:])
+}
+
+// unwrap returns the data key wrapped by KMS
+func (k *kmsKey) unwrap(wrapped []byte) (*aesKey, error) {
+	k.mu.Lock()
+	dataKey, ok := k.dataKeys[string(wrapped)]
+	k.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	out, err := k.client.Decrypt(&kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err = newAESKey(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	k.dataKeys[string(wrapped)] = dataKey
+	k.mu.Unlock()
+	return dataKey, nil
+}
@@ -0,0 +1,112 @@
+package encrypt
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// reencryptPageSize is the number of apps or fns read at once by Reencrypt
+const reencryptPageSize = 100
+
+// reencryptAttempts is the number of times Reencrypt tries to update a resource changed
+// concurrently
+const reencryptAttempts = 3
+
+// Reencrypt encrypts with the first key the config values of the live apps and fns which are
+// not, either not encrypted or encrypted with a previous key, returning the number of apps and
+// fns updated. Resources changed concurrently are read again. The configs of the revisions of
+// fns and of their canaries keep their keys, which must stay in the keyring to read them.
+func (d *Datastore) Reencrypt(ctx context.Context) (apps, fns int, err error) {
+	appFilter := &models.AppFilter{PerPage: reencryptPageSize}
+	for {
+		list, err := d.Datastore.GetApps(ctx, appFilter)
+		if err != nil {
+			return apps, fns, err
+		}
+		for _, app := range list.Items {
+			updated, err := d.reencryptApp(ctx, app)
+			if err != nil {
+				return apps, fns, err
+			}
+			if updated {
+				apps++
+			}
+
+			fnFilter := &models.FnFilter{AppID: app.ID, PerPage: reencryptPageSize}
+			for {
+				fnList, err := d.Datastore.GetFns(ctx, fnFilter)
+				if err != nil {
+					return apps, fns, err
+				}
+				for _, fn := range fnList.Items {
+					updated, err := d.reencryptFn(ctx, fn)
+					if err != nil {
+						return apps, fns, err
+					}
+					if updated {
+						fns++
+					}
+				}
+				if fnList.NextCursor == "" {
+					break
+				}
+				fnFilter.Cursor = fnList.NextCursor
+			}
+		}
+		if list.NextCursor == "" {
+			return apps, fns, nil
+		}
+		appFilter.Cursor = list.NextCursor
+	}
+}
+
+// reencryptApp encrypts the config of app, as stored, with the first key if it is not
+func (d *Datastore) reencryptApp(ctx context.Context, app *models.App) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		if d.encrypted(app.Config) {
+			return false, nil
+		}
+		config, err := d.reencryptConfig(app.Config)
+		if err != nil {
+			return false, err
+		}
+		_, err = d.Datastore.UpdateApp(models.WithIfMatch(ctx, []string{models.ETag(app)}), &models.App{ID: app.ID, Config: config})
+		if err != models.ErrPreconditionFailed || attempt == reencryptAttempts {
+			return err == nil, err
+		}
+		if app, err = d.Datastore.GetAppByID(ctx, app.ID); err != nil {
+			return false, err
+		}
+	}
+}
+
+// reencryptFn encrypts the config of fn, as stored, with the first key if it is not
+func (d *Datastore) reencryptFn(ctx context.Context, fn *models.Fn) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		if d.encrypted(fn.Config) {
+			return false, nil
+		}
+		config, err := d.reencryptConfig(fn.Config)
+		if err != nil {
+			return false, err
+		}
+		_, err = d.Datastore.UpdateFn(models.WithIfMatch(ctx, []string{models.ETag(fn)}), &models.Fn{ID: fn.ID, Config: config})
+		if err != models.ErrPreconditionFailed || attempt == reencryptAttempts {
+			return err == nil, err
+		}
+		if fn, err = d.Datastore.GetFnByID(ctx, fn.ID); err != nil {
+			return false, err
+		}
+	}
+}
+
+// reencryptConfig returns the stored config c with all of its values encrypted with the
+// first key
+func (d *Datastore) reencryptConfig(c models.Config) (models.Config, error) {
+	plain, err := d.decryptConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return d.encryptConfig(plain, nil)
+}
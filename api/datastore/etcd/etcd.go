@@ -0,0 +1,182 @@
+// Package etcd implements the datastore over etcd, see the kv package. Datastore URLs are of
+// the form etcd://[user:password@]host:port[,host:port...][/prefix], the keys of the
+// datastore being under prefix, fn by default.
+//
+// Removing an app removes its resources in a single transaction, etcd must be run with a
+// --max-txn-ops large enough for the apps it stores.
+package etcd
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPrefix is the prefix of the keys of the datastore if the URL has no path
+	DefaultPrefix = "fn"
+
+	dialTimeout = 5 * time.Second
+)
+
+type etcdKV struct {
+	client *clientv3.Client
+	prefix string
+}
+
+var _ kv.KV = new(etcdKV)
+
+// New connects to the etcd cluster of u
+func New(ctx context.Context, u *url.URL) (*kv.Store, error) {
+	e, err := newKV(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return kv.Open(ctx, e)
+}
+
+func newKV(ctx context.Context, u *url.URL) (*etcdKV, error) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"url": common.MaskPassword(u)})
+
+	cfg := clientv3.Config{
+		Endpoints:   strings.Split(u.Host, ","),
+		DialTimeout: dialTimeout,
+	}
+	if u.User != nil {
+		cfg.Username = u.User.Username()
+		cfg.Password, _ = u.User.Password()
+	}
+	client, err := clientv3.New(cfg)
+	if err != nil {
+		log.WithError(err).Error("couldn't connect to etcd")
+		return nil, err
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	log.WithFields(logrus.Fields{"prefix": prefix}).Info("datastore dialed")
+	return &etcdKV{client: client, prefix: prefix + "/"}, nil
+}
+
+// Get implements kv.KV
+func (e *etcdKV) Get(ctx context.Context, key string) (*kv.Entry, error) {
+	resp, err := e.client.Get(ctx, e.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return e.entry(resp.Kvs[0]), nil
+}
+
+// List implements kv.KV
+func (e *etcdKV) List(ctx context.Context, prefix string) ([]*kv.Entry, int64, error) {
+	resp, err := e.client.Get(ctx, e.prefix+prefix, clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]*kv.Entry, len(resp.Kvs))
+	for i, v := range resp.Kvs {
+		entries[i] = e.entry(v)
+	}
+	return entries, resp.Header.Revision, nil
+}
+
+func (e *etcdKV) entry(v *mvccpb.KeyValue) *kv.Entry {
+	return &kv.Entry{
+		Key:     strings.TrimPrefix(string(v.Key), e.prefix),
+		Value:   v.Value,
+		Version: v.ModRevision,
+	}
+}
+
+// Commit implements kv.KV
+func (e *etcdKV) Commit(ctx context.Context, txn *kv.Txn) error {
+	var cmps []clientv3.Cmp
+	for k, version := range txn.Reads {
+		// absent keys have a revision of 0
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(e.prefix+k), "=", version))
+	}
+	for prefix, revision := range txn.Lists {
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(e.prefix+prefix), "<", revision+1).WithPrefix())
+	}
+
+	var ops []clientv3.Op
+	for _, prefix := range txn.DeletePrefixes {
+		ops = append(ops, clientv3.OpDelete(e.prefix+prefix, clientv3.WithPrefix()))
+	}
+	for _, k := range txn.Deletes {
+		ops = append(ops, clientv3.OpDelete(e.prefix+k))
+	}
+	for k, v := range txn.Puts {
+		ops = append(ops, clientv3.OpPut(e.prefix+k, string(v)))
+	}
+
+	resp, err := e.client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return kv.ErrConflict
+	}
+	return nil
+}
+
+// Watch implements kv.KV
+func (e *etcdKV) Watch(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	watch := e.client.Watch(clientv3.WithRequireLeader(ctx), e.prefix+prefix, clientv3.WithPrefix())
+	events := make(chan kv.Event)
+	go func() {
+		defer close(events)
+		for resp := range watch {
+			if err := resp.Err(); err != nil {
+				common.Logger(ctx).WithError(err).Info("etcd watch interrupted")
+				return
+			}
+			for _, ev := range resp.Events {
+				select {
+				case events <- kv.Event{Key: strings.TrimPrefix(string(ev.Kv.Key), e.prefix), Deleted: ev.Type == mvccpb.DELETE}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Close implements kv.KV
+func (e *etcdKV) Close() error {
+	return e.client.Close()
+}
+
+type etcdProvider int
+
+func (etcdProvider) String() string {
+	return "etcd"
+}
+
+func (etcdProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "etcd"
+}
+
+func (etcdProvider) New(ctx context.Context, u *url.URL) (models.Datastore, error) {
+	return New(ctx, u)
+}
+
+func init() {
+	datastore.Register(etcdProvider(0))
+}
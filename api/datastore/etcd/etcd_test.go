@@ -0,0 +1,38 @@
+package etcd
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+)
+
+// TestDatastore runs against the etcd cluster of ETCD_URL, e.g. etcd://localhost:2379/fntest
+func TestDatastore(t *testing.T) {
+	etcdURL := os.Getenv("ETCD_URL")
+	if etcdURL == "" {
+		t.Skip("ETCD_URL is not set")
+	}
+	u, err := url.Parse(etcdURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	f := func(t *testing.T) models.Datastore {
+		e, err := newKV(ctx, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Commit(ctx, &kv.Txn{DeletePrefixes: []string{""}}); err != nil {
+			t.Fatal(err)
+		}
+		return datastoreutil.NewValidator(kv.New(e))
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
@@ -2,121 +2,387 @@ package datastoreutil
 
 import (
 	"context"
-
-	"go.opencensus.io/trace"
+	"time"
 
 	"github.com/fnproject/fn/api/models"
 )
 
+// MetricDS returns a datastore tracing the operations of ds and recording their latency and
+// errors, see RegisterViews. It is a models.LogStore or a models.ChangeWatcher if ds is one.
 func MetricDS(ds models.Datastore) models.Datastore {
-	return &metricds{ds}
+	m := &metricds{ds}
+	if ls, ok := ds.(models.LogStore); ok {
+		return &logMetricds{metricds: m, LogStore: ls}
+	}
+	if w, ok := ds.(models.ChangeWatcher); ok {
+		return &watchedMetricds{metricds: m, ChangeWatcher: w}
+	}
+	return m
 }
 
 type metricds struct {
 	ds models.Datastore
 }
 
+// logMetricds records the operations of a datastore which is also its logstore
+type logMetricds struct {
+	*metricds
+	models.LogStore
+}
+
+// Close closes the datastore, which is the logstore too
+func (m *logMetricds) Close() error {
+	return m.metricds.Close()
+}
+
+// watchedMetricds records the operations of a datastore notifying its changes
+type watchedMetricds struct {
+	*metricds
+	models.ChangeWatcher
+}
+
 func (m *metricds) GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*models.Trigger, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_trigger_by_source")
-	defer span.End()
-	return m.ds.GetTriggerBySource(ctx, appId, triggerType, source)
+	ctx, op := startOp(ctx, "ds_get_trigger_by_source")
+	res, err := m.ds.GetTriggerBySource(ctx, appId, triggerType, source)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetAppID(ctx context.Context, appName string) (string, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_app_id")
-	defer span.End()
-	return m.ds.GetAppID(ctx, appName)
+	ctx, op := startOp(ctx, "ds_get_app_id")
+	res, err := m.ds.GetAppID(ctx, appName)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_app_by_id")
-	defer span.End()
-	return m.ds.GetAppByID(ctx, appID)
+	ctx, op := startOp(ctx, "ds_get_app_by_id")
+	res, err := m.ds.GetAppByID(ctx, appID)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_apps")
-	defer span.End()
-	return m.ds.GetApps(ctx, filter)
+	ctx, op := startOp(ctx, "ds_get_apps")
+	res, err := m.ds.GetApps(ctx, filter)
+	return res, op.end(err)
 }
 
 func (m *metricds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_insert_app")
-	defer span.End()
-	return m.ds.InsertApp(ctx, app)
+	ctx, op := startOp(ctx, "ds_insert_app")
+	res, err := m.ds.InsertApp(ctx, app)
+	return res, op.end(err)
 }
 
 func (m *metricds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_update_app")
-	defer span.End()
-	return m.ds.UpdateApp(ctx, app)
+	ctx, op := startOp(ctx, "ds_update_app")
+	res, err := m.ds.UpdateApp(ctx, app)
+	return res, op.end(err)
 }
 
 func (m *metricds) RemoveApp(ctx context.Context, appID string) error {
-	ctx, span := trace.StartSpan(ctx, "ds_remove_app")
-	defer span.End()
-	return m.ds.RemoveApp(ctx, appID)
+	ctx, op := startOp(ctx, "ds_remove_app")
+	return op.end(m.ds.RemoveApp(ctx, appID))
 }
 
-func (m *metricds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_insert_trigger")
-	defer span.End()
-	return m.ds.InsertTrigger(ctx, trigger)
+func (m *metricds) SoftDeleteApp(ctx context.Context, appID string) error {
+	ctx, op := startOp(ctx, "ds_soft_delete_app")
+	return op.end(m.ds.SoftDeleteApp(ctx, appID))
+}
 
+func (m *metricds) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	ctx, op := startOp(ctx, "ds_restore_app")
+	res, err := m.ds.RestoreApp(ctx, appID)
+	return res, op.end(err)
+}
+
+func (m *metricds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	ctx, op := startOp(ctx, "ds_insert_trigger")
+	res, err := m.ds.InsertTrigger(ctx, trigger)
+	return res, op.end(err)
 }
 
 func (m *metricds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_update_trigger")
-	defer span.End()
-	return m.ds.UpdateTrigger(ctx, trigger)
+	ctx, op := startOp(ctx, "ds_update_trigger")
+	res, err := m.ds.UpdateTrigger(ctx, trigger)
+	return res, op.end(err)
 }
 
 func (m *metricds) RemoveTrigger(ctx context.Context, triggerID string) error {
-	ctx, span := trace.StartSpan(ctx, "ds_remove_trigger")
-	defer span.End()
-	return m.ds.RemoveTrigger(ctx, triggerID)
+	ctx, op := startOp(ctx, "ds_remove_trigger")
+	return op.end(m.ds.RemoveTrigger(ctx, triggerID))
 }
 
 func (m *metricds) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_trigger_by_id")
-	defer span.End()
-	return m.ds.GetTriggerByID(ctx, triggerID)
+	ctx, op := startOp(ctx, "ds_get_trigger_by_id")
+	res, err := m.ds.GetTriggerByID(ctx, triggerID)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_triggers")
-	defer span.End()
-	return m.ds.GetTriggers(ctx, filter)
+	ctx, op := startOp(ctx, "ds_get_triggers")
+	res, err := m.ds.GetTriggers(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	ctx, op := startOp(ctx, "ds_apply_batch")
+	res, err := m.ds.ApplyBatch(ctx, items)
+	return res, op.end(err)
+}
+
+func (m *metricds) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, op := startOp(ctx, "ds_in_tx")
+	return op.end(m.ds.InTx(ctx, f))
 }
 
 func (m *metricds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_insert_func")
-	defer span.End()
-	return m.ds.InsertFn(ctx, fn)
+	ctx, op := startOp(ctx, "ds_insert_func")
+	res, err := m.ds.InsertFn(ctx, fn)
+	return res, op.end(err)
 }
 
 func (m *metricds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_insert_func")
-	defer span.End()
-	return m.ds.UpdateFn(ctx, fn)
+	ctx, op := startOp(ctx, "ds_insert_func")
+	res, err := m.ds.UpdateFn(ctx, fn)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_funcs")
-	defer span.End()
-	return m.ds.GetFns(ctx, filter)
+	ctx, op := startOp(ctx, "ds_get_funcs")
+	res, err := m.ds.GetFns(ctx, filter)
+	return res, op.end(err)
 }
 
 func (m *metricds) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
-	ctx, span := trace.StartSpan(ctx, "ds_get_func")
-	defer span.End()
-	return m.ds.GetFnByID(ctx, fnID)
+	ctx, op := startOp(ctx, "ds_get_func")
+	res, err := m.ds.GetFnByID(ctx, fnID)
+	return res, op.end(err)
 }
 
 func (m *metricds) RemoveFn(ctx context.Context, fnID string) error {
-	ctx, span := trace.StartSpan(ctx, "ds_remove_func")
-	defer span.End()
-	return m.ds.RemoveFn(ctx, fnID)
+	ctx, op := startOp(ctx, "ds_remove_func")
+	return op.end(m.ds.RemoveFn(ctx, fnID))
+}
+
+func (m *metricds) SoftDeleteFn(ctx context.Context, fnID string) error {
+	ctx, op := startOp(ctx, "ds_soft_delete_func")
+	return op.end(m.ds.SoftDeleteFn(ctx, fnID))
+}
+
+func (m *metricds) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	ctx, op := startOp(ctx, "ds_get_deleted_func")
+	res, err := m.ds.GetDeletedFnByID(ctx, fnID)
+	return res, op.end(err)
+}
+
+func (m *metricds) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	ctx, op := startOp(ctx, "ds_restore_func")
+	res, err := m.ds.RestoreFn(ctx, fnID)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	ctx, op := startOp(ctx, "ds_get_func_revision")
+	res, err := m.ds.GetFnRevision(ctx, fnID, revision)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	ctx, op := startOp(ctx, "ds_get_func_revisions")
+	res, err := m.ds.GetFnRevisions(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) InsertSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	ctx, op := startOp(ctx, "ds_insert_schedule")
+	res, err := m.ds.InsertSchedule(ctx, schedule)
+	return res, op.end(err)
+}
+
+func (m *metricds) UpdateSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	ctx, op := startOp(ctx, "ds_update_schedule")
+	res, err := m.ds.UpdateSchedule(ctx, schedule)
+	return res, op.end(err)
+}
+
+func (m *metricds) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	ctx, op := startOp(ctx, "ds_remove_schedule")
+	return op.end(m.ds.RemoveSchedule(ctx, scheduleID))
+}
+
+func (m *metricds) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	ctx, op := startOp(ctx, "ds_get_schedule_by_id")
+	res, err := m.ds.GetScheduleByID(ctx, scheduleID)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetSchedules(ctx context.Context, filter *models.ScheduleFilter) (*models.ScheduleList, error) {
+	ctx, op := startOp(ctx, "ds_get_schedules")
+	res, err := m.ds.GetSchedules(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetDueSchedules(ctx context.Context, t time.Time, limit int) ([]*models.Schedule, error) {
+	ctx, op := startOp(ctx, "ds_get_due_schedules")
+	res, err := m.ds.GetDueSchedules(ctx, t, limit)
+	return res, op.end(err)
+}
+
+func (m *metricds) AdvanceSchedule(ctx context.Context, scheduleID string, due, next time.Time) error {
+	ctx, op := startOp(ctx, "ds_advance_schedule")
+	return op.end(m.ds.AdvanceSchedule(ctx, scheduleID, due, next))
+}
+
+func (m *metricds) InsertScheduleRun(ctx context.Context, run *models.ScheduleRun) error {
+	ctx, op := startOp(ctx, "ds_insert_schedule_run")
+	return op.end(m.ds.InsertScheduleRun(ctx, run))
+}
+
+func (m *metricds) GetScheduleRuns(ctx context.Context, filter *models.ScheduleRunFilter) (*models.ScheduleRunList, error) {
+	ctx, op := startOp(ctx, "ds_get_schedule_runs")
+	res, err := m.ds.GetScheduleRuns(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ctx, op := startOp(ctx, "ds_insert_api_key")
+	res, err := m.ds.InsertAPIKey(ctx, key)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	ctx, op := startOp(ctx, "ds_get_api_keys")
+	res, err := m.ds.GetAPIKeys(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, op := startOp(ctx, "ds_get_api_key_by_hash")
+	res, err := m.ds.GetAPIKeyByHash(ctx, hash)
+	return res, op.end(err)
+}
+
+func (m *metricds) RemoveAPIKey(ctx context.Context, keyID string) error {
+	ctx, op := startOp(ctx, "ds_remove_api_key")
+	return op.end(m.ds.RemoveAPIKey(ctx, keyID))
+}
+
+func (m *metricds) InsertRoleBinding(ctx context.Context, binding *models.RoleBinding) (*models.RoleBinding, error) {
+	ctx, op := startOp(ctx, "ds_insert_role_binding")
+	res, err := m.ds.InsertRoleBinding(ctx, binding)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetRoleBindingByID(ctx context.Context, bindingID string) (*models.RoleBinding, error) {
+	ctx, op := startOp(ctx, "ds_get_role_binding_by_id")
+	res, err := m.ds.GetRoleBindingByID(ctx, bindingID)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	ctx, op := startOp(ctx, "ds_get_role_bindings")
+	res, err := m.ds.GetRoleBindings(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	ctx, op := startOp(ctx, "ds_remove_role_binding")
+	return op.end(m.ds.RemoveRoleBinding(ctx, bindingID))
+}
+
+func (m *metricds) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	ctx, op := startOp(ctx, "ds_insert_domain")
+	res, err := m.ds.InsertDomain(ctx, domain)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetDomainByID(ctx context.Context, domainID string) (*models.Domain, error) {
+	ctx, op := startOp(ctx, "ds_get_domain_by_id")
+	res, err := m.ds.GetDomainByID(ctx, domainID)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetDomains(ctx context.Context, filter *models.DomainFilter) (*models.DomainList, error) {
+	ctx, op := startOp(ctx, "ds_get_domains")
+	res, err := m.ds.GetDomains(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	ctx, op := startOp(ctx, "ds_get_domains_by_hostname")
+	res, err := m.ds.GetDomainsByHostname(ctx, hostname)
+	return res, op.end(err)
+}
+
+func (m *metricds) RemoveDomain(ctx context.Context, domainID string) error {
+	ctx, op := startOp(ctx, "ds_remove_domain")
+	return op.end(m.ds.RemoveDomain(ctx, domainID))
+}
+
+func (m *metricds) InsertWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	ctx, op := startOp(ctx, "ds_insert_webhook")
+	res, err := m.ds.InsertWebhook(ctx, webhook)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetWebhookByID(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	ctx, op := startOp(ctx, "ds_get_webhook_by_id")
+	res, err := m.ds.GetWebhookByID(ctx, webhookID)
+	return res, op.end(err)
+}
+
+func (m *metricds) GetWebhooks(ctx context.Context, filter *models.WebhookFilter) (*models.WebhookList, error) {
+	ctx, op := startOp(ctx, "ds_get_webhooks")
+	res, err := m.ds.GetWebhooks(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) RemoveWebhook(ctx context.Context, webhookID string) error {
+	ctx, op := startOp(ctx, "ds_remove_webhook")
+	return op.end(m.ds.RemoveWebhook(ctx, webhookID))
+}
+
+func (m *metricds) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, op := startOp(ctx, "ds_insert_webhook_delivery")
+	return op.end(m.ds.InsertWebhookDelivery(ctx, delivery))
+}
+
+func (m *metricds) GetWebhookDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryList, error) {
+	ctx, op := startOp(ctx, "ds_get_webhook_deliveries")
+	res, err := m.ds.GetWebhookDeliveries(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, op := startOp(ctx, "ds_insert_audit_entry")
+	return op.end(m.ds.InsertAuditEntry(ctx, entry))
+}
+
+func (m *metricds) GetAuditEntries(ctx context.Context, filter *models.AuditEntryFilter) (*models.AuditEntryList, error) {
+	ctx, op := startOp(ctx, "ds_get_audit_entries")
+	res, err := m.ds.GetAuditEntries(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) Search(ctx context.Context, filter *models.SearchFilter) (*models.SearchResultList, error) {
+	ctx, op := startOp(ctx, "ds_search")
+	res, err := m.ds.Search(ctx, filter)
+	return res, op.end(err)
+}
+
+func (m *metricds) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	ctx, op := startOp(ctx, "ds_add_app_usage")
+	return op.end(m.ds.AddAppUsage(ctx, usage))
+}
+
+func (m *metricds) GetAppUsage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	ctx, op := startOp(ctx, "ds_get_app_usage")
+	res, err := m.ds.GetAppUsage(ctx, appID, period)
+	return res, op.end(err)
+}
+
+func (m *metricds) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	ctx, op := startOp(ctx, "ds_acquire_lease")
+	res, err := m.ds.AcquireLease(ctx, name, holder, ttl)
+	return res, op.end(err)
 }
 
 // Close calls Close on the underlying Datastore
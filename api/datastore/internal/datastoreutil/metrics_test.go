@@ -0,0 +1,50 @@
+package datastoreutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"go.opencensus.io/stats/view"
+)
+
+// notFoundDatastore fails all of its lookups of apps
+type notFoundDatastore struct {
+	models.Datastore
+}
+
+func (notFoundDatastore) GetAppByID(context.Context, string) (*models.App, error) {
+	return nil, models.ErrAppsNotFound
+}
+
+func TestMetricDS(t *testing.T) {
+	RegisterViews(nil, []float64{1, 10, 100})
+	defer view.Unregister(view.Find(opLatencyMeasure.Name()), view.Find(opErrorsMeasure.Name()))
+
+	ds := MetricDS(notFoundDatastore{})
+	for i := 0; i < 2; i++ {
+		if _, err := ds.GetAppByID(context.Background(), "app"); err != models.ErrAppsNotFound {
+			t.Fatalf("expected the error of the datastore, got %v", err)
+		}
+	}
+
+	rows, err := view.RetrieveData(opLatencyMeasure.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Tags[0].Value != "get_app_by_id" || rows[0].Data.(*view.DistributionData).Count != 2 {
+		t.Fatalf("expected the latency of 2 get_app_by_id operations, got %+v", rows)
+	}
+	rows, err = view.RetrieveData(opErrorsMeasure.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || len(rows[0].Tags) != 2 || rows[0].Data.(*view.CountData).Value != 2 {
+		t.Fatalf("expected 2 errors of get_app_by_id, got %+v", rows)
+	}
+	for _, tag := range rows[0].Tags {
+		if tag.Key == errorKey && tag.Value != "404" {
+			t.Fatalf("expected the errors to be tagged with their status code, got %s", tag.Value)
+		}
+	}
+}
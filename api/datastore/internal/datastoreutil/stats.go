@@ -0,0 +1,68 @@
+package datastoreutil
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+var (
+	opKey    = common.MakeKey("ds_op")
+	errorKey = common.MakeKey("ds_error")
+
+	opLatencyMeasure = common.MakeMeasure("ds_latency", "Latency of the datastore operations", stats.UnitMilliseconds)
+	opErrorsMeasure  = common.MakeMeasure("ds_errors", "Number of the datastore operations which failed", stats.UnitDimensionless)
+)
+
+// RegisterViews registers the views of the latency of the datastore operations, tagged by
+// ds_op, and of their errors, tagged by ds_op and ds_error, the status code of the API errors
+// or internal for the others
+func RegisterViews(tagKeys []string, latencyDist []float64) {
+	latencyTags := append(append([]string{}, tagKeys...), opKey.Name())
+	errorTags := append(append([]string{}, latencyTags...), errorKey.Name())
+	err := view.Register(
+		common.CreateView(opLatencyMeasure, view.Distribution(latencyDist...), latencyTags),
+		common.CreateView(opErrorsMeasure, view.Count(), errorTags),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+// op is a datastore operation in progress
+type op struct {
+	ctx   context.Context
+	name  string
+	span  *trace.Span
+	start time.Time
+}
+
+// startOp starts the operation name, in a span of its own
+func startOp(ctx context.Context, name string) (context.Context, *op) {
+	ctx, span := trace.StartSpan(ctx, name)
+	return ctx, &op{ctx: ctx, name: strings.TrimPrefix(name, "ds_"), span: span, start: time.Now()}
+}
+
+// end records the latency of the operation and its error, if any, which it returns
+func (o *op) end(err error) error {
+	o.span.End()
+	mutators := []tag.Mutator{tag.Upsert(opKey, o.name)}
+	stats.RecordWithTags(o.ctx, mutators, opLatencyMeasure.M(int64(time.Since(o.start)/time.Millisecond)))
+	if err != nil {
+		kind := "internal"
+		if models.IsAPIError(err) {
+			kind = strconv.Itoa(models.GetAPIErrorCode(err))
+		}
+		stats.RecordWithTags(o.ctx, append(mutators, tag.Upsert(errorKey, kind)), opErrorsMeasure.M(1))
+	}
+	return err
+}
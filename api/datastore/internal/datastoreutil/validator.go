@@ -33,19 +33,23 @@ func (v *validator) GetAppByID(ctx context.Context, appID string) (*models.App,
 
 // app and app.Name will never be nil/empty.
 func (v *validator) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
-	if app == nil {
-		return nil, models.ErrDatastoreEmptyApp
-	}
-	if app.ID != "" {
-		return nil, models.ErrAppIDProvided
-	}
-	if err := app.Validate(); err != nil {
+	if err := validateNewApp(app); err != nil {
 		return nil, err
 	}
 
 	return v.Datastore.InsertApp(ctx, app)
 }
 
+func validateNewApp(app *models.App) error {
+	if app == nil {
+		return models.ErrDatastoreEmptyApp
+	}
+	if app.ID != "" {
+		return models.ErrAppIDProvided
+	}
+	return app.Validate()
+}
+
 // app and app.Name will never be nil/empty.
 func (v *validator) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
 	if app == nil {
@@ -67,20 +71,92 @@ func (v *validator) RemoveApp(ctx context.Context, appID string) error {
 	return v.Datastore.RemoveApp(ctx, appID)
 }
 
+func (v *validator) SoftDeleteApp(ctx context.Context, appID string) error {
+	if appID == "" {
+		return models.ErrAppsMissingID
+	}
+
+	return v.Datastore.SoftDeleteApp(ctx, appID)
+}
+
+func (v *validator) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	if appID == "" {
+		return nil, models.ErrAppsMissingID
+	}
+
+	return v.Datastore.RestoreApp(ctx, appID)
+}
+
 func (v *validator) InsertTrigger(ctx context.Context, t *models.Trigger) (*models.Trigger, error) {
+	if err := validateNewTrigger(t); err != nil {
+		return nil, err
+	}
 
+	return v.Datastore.InsertTrigger(ctx, t)
+}
+
+func validateNewTrigger(t *models.Trigger) error {
 	if t.ID != "" {
-		return nil, models.ErrTriggerIDProvided
+		return models.ErrTriggerIDProvided
 	}
 
 	if !time.Time(t.CreatedAt).IsZero() {
-		return nil, models.ErrCreatedAtProvided
+		return models.ErrCreatedAtProvided
 	}
 	if !time.Time(t.UpdatedAt).IsZero() {
-		return nil, models.ErrUpdatedAtProvided
+		return models.ErrUpdatedAtProvided
+	}
+	return nil
+}
+
+// ApplyBatch checks the items as the methods applying them one at a time do
+func (v *validator) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	for i, item := range items {
+		if err := validateBatchItem(item); err != nil {
+			return nil, &models.BatchError{Index: i, Err: err}
+		}
+	}
+	return v.Datastore.ApplyBatch(ctx, items)
+}
+
+func validateBatchItem(item *models.BatchItem) error {
+	if item == nil || item.Kind() == "" {
+		return models.ErrBatchInvalidItem("batch items must carry a single app, fn or trigger")
 	}
 
-	return v.Datastore.InsertTrigger(ctx, t)
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return validateNewApp(item.App)
+		case models.BatchUpdate, models.BatchDelete:
+			if item.App.ID == "" {
+				return models.ErrAppsMissingID
+			}
+			return nil
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return validateNewFn(item.Fn)
+		case models.BatchUpdate, models.BatchDelete:
+			if item.Fn.ID == "" {
+				return models.ErrDatastoreEmptyFnID
+			}
+			return nil
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return validateNewTrigger(item.Trigger)
+		case models.BatchUpdate, models.BatchDelete:
+			if item.Trigger.ID == "" {
+				return models.ErrMissingID
+			}
+			return nil
+		}
+	}
+	return models.ErrBatchInvalidItem("batch item has invalid action " + item.Action)
 }
 
 func (v *validator) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
@@ -100,19 +176,26 @@ func (v *validator) RemoveTrigger(ctx context.Context, triggerID string) error {
 }
 
 func (v *validator) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	if err := validateNewFn(fn); err != nil {
+		return nil, err
+	}
+	return v.Datastore.InsertFn(ctx, fn)
+}
+
+func validateNewFn(fn *models.Fn) error {
 	if fn == nil {
-		return nil, models.ErrDatastoreEmptyFn
+		return models.ErrDatastoreEmptyFn
 	}
 	if fn.ID != "" {
-		return nil, models.ErrFnsIDProvided
+		return models.ErrFnsIDProvided
 	}
 	if fn.AppID == "" {
-		return nil, models.ErrFnsMissingAppID
+		return models.ErrFnsMissingAppID
 	}
 	if fn.Name == "" {
-		return nil, models.ErrFnsMissingName
+		return models.ErrFnsMissingName
 	}
-	return v.Datastore.InsertFn(ctx, fn)
+	return nil
 }
 
 func (v *validator) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
@@ -138,3 +221,278 @@ func (v *validator) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return v.Datastore.RemoveFn(ctx, fnID)
 }
+
+func (v *validator) SoftDeleteFn(ctx context.Context, fnID string) error {
+	if fnID == "" {
+		return models.ErrDatastoreEmptyFnID
+	}
+	return v.Datastore.SoftDeleteFn(ctx, fnID)
+}
+
+func (v *validator) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	if fnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	return v.Datastore.GetDeletedFnByID(ctx, fnID)
+}
+
+func (v *validator) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	if fnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	return v.Datastore.RestoreFn(ctx, fnID)
+}
+
+func (v *validator) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	if fnID == "" {
+		return nil, models.ErrDatastoreEmptyFnID
+	}
+	if revision < 1 {
+		return nil, models.ErrFnRevisionInvalid
+	}
+	return v.Datastore.GetFnRevision(ctx, fnID, revision)
+}
+
+func (v *validator) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	if filter.FnID == "" {
+		return nil, models.ErrFnRevisionMissingFnID
+	}
+
+	return v.Datastore.GetFnRevisions(ctx, filter)
+}
+
+func (v *validator) InsertSchedule(ctx context.Context, s *models.Schedule) (*models.Schedule, error) {
+	if s == nil {
+		return nil, models.ErrDatastoreEmptySchedule
+	}
+	if s.ID != "" {
+		return nil, models.ErrScheduleIDProvided
+	}
+
+	if !time.Time(s.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+	if !time.Time(s.UpdatedAt).IsZero() {
+		return nil, models.ErrUpdatedAtProvided
+	}
+
+	return v.Datastore.InsertSchedule(ctx, s)
+}
+
+func (v *validator) UpdateSchedule(ctx context.Context, s *models.Schedule) (*models.Schedule, error) {
+	if s == nil {
+		return nil, models.ErrDatastoreEmptySchedule
+	}
+	if s.ID == "" {
+		return nil, models.ErrMissingID
+	}
+
+	return v.Datastore.UpdateSchedule(ctx, s)
+}
+
+func (v *validator) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	if scheduleID == "" {
+		return models.ErrMissingID
+	}
+
+	return v.Datastore.RemoveSchedule(ctx, scheduleID)
+}
+
+func (v *validator) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	if scheduleID == "" {
+		return nil, models.ErrMissingID
+	}
+
+	return v.Datastore.GetScheduleByID(ctx, scheduleID)
+}
+
+func (v *validator) GetSchedules(ctx context.Context, filter *models.ScheduleFilter) (*models.ScheduleList, error) {
+	if filter.AppID == "" {
+		return nil, models.ErrScheduleMissingAppID
+	}
+
+	return v.Datastore.GetSchedules(ctx, filter)
+}
+
+func (v *validator) GetScheduleRuns(ctx context.Context, filter *models.ScheduleRunFilter) (*models.ScheduleRunList, error) {
+	if filter.ScheduleID == "" {
+		return nil, models.ErrScheduleRunMissingScheduleID
+	}
+
+	return v.Datastore.GetScheduleRuns(ctx, filter)
+}
+
+func (v *validator) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	if key == nil {
+		return nil, models.ErrDatastoreEmptyAPIKey
+	}
+	if key.ID != "" {
+		return nil, models.ErrAPIKeyIDProvided
+	}
+	if !time.Time(key.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+
+	return v.Datastore.InsertAPIKey(ctx, key)
+}
+
+func (v *validator) RemoveAPIKey(ctx context.Context, keyID string) error {
+	if keyID == "" {
+		return models.ErrMissingID
+	}
+
+	return v.Datastore.RemoveAPIKey(ctx, keyID)
+}
+
+func (v *validator) InsertRoleBinding(ctx context.Context, binding *models.RoleBinding) (*models.RoleBinding, error) {
+	if binding == nil {
+		return nil, models.ErrDatastoreEmptyRoleBinding
+	}
+	if binding.ID != "" {
+		return nil, models.ErrRoleBindingIDProvided
+	}
+	if !time.Time(binding.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+
+	return v.Datastore.InsertRoleBinding(ctx, binding)
+}
+
+func (v *validator) GetRoleBindingByID(ctx context.Context, bindingID string) (*models.RoleBinding, error) {
+	if bindingID == "" {
+		return nil, models.ErrMissingID
+	}
+
+	return v.Datastore.GetRoleBindingByID(ctx, bindingID)
+}
+
+func (v *validator) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	if bindingID == "" {
+		return models.ErrMissingID
+	}
+
+	return v.Datastore.RemoveRoleBinding(ctx, bindingID)
+}
+
+func (v *validator) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	if usage.AppID == "" {
+		return models.ErrMissingAppID
+	}
+	if usage.Period == "" {
+		return models.ErrMissingUsagePeriod
+	}
+
+	return v.Datastore.AddAppUsage(ctx, usage)
+}
+
+func (v *validator) GetAppUsage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	if appID == "" {
+		return nil, models.ErrMissingAppID
+	}
+	if period == "" {
+		return nil, models.ErrMissingUsagePeriod
+	}
+
+	return v.Datastore.GetAppUsage(ctx, appID, period)
+}
+
+func (v *validator) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	if domain == nil {
+		return nil, models.ErrDatastoreEmptyDomain
+	}
+	if domain.ID != "" {
+		return nil, models.ErrDomainIDProvided
+	}
+	if !time.Time(domain.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+
+	return v.Datastore.InsertDomain(ctx, domain)
+}
+
+func (v *validator) GetDomainByID(ctx context.Context, domainID string) (*models.Domain, error) {
+	if domainID == "" {
+		return nil, models.ErrMissingID
+	}
+
+	return v.Datastore.GetDomainByID(ctx, domainID)
+}
+
+func (v *validator) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	if hostname == "" {
+		return nil, models.ErrDomainMissingHostname
+	}
+
+	return v.Datastore.GetDomainsByHostname(ctx, hostname)
+}
+
+func (v *validator) RemoveDomain(ctx context.Context, domainID string) error {
+	if domainID == "" {
+		return models.ErrMissingID
+	}
+
+	return v.Datastore.RemoveDomain(ctx, domainID)
+}
+
+func (v *validator) InsertWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	if webhook == nil {
+		return nil, models.ErrDatastoreEmptyWebhook
+	}
+	if webhook.ID != "" {
+		return nil, models.ErrWebhookIDProvided
+	}
+	if !time.Time(webhook.CreatedAt).IsZero() {
+		return nil, models.ErrCreatedAtProvided
+	}
+
+	return v.Datastore.InsertWebhook(ctx, webhook)
+}
+
+func (v *validator) GetWebhookByID(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	if webhookID == "" {
+		return nil, models.ErrMissingID
+	}
+
+	return v.Datastore.GetWebhookByID(ctx, webhookID)
+}
+
+func (v *validator) RemoveWebhook(ctx context.Context, webhookID string) error {
+	if webhookID == "" {
+		return models.ErrMissingID
+	}
+
+	return v.Datastore.RemoveWebhook(ctx, webhookID)
+}
+
+func (v *validator) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID == "" {
+		return models.ErrMissingID
+	}
+	if delivery.WebhookID == "" {
+		return models.ErrWebhookDeliveryMissingWebhookID
+	}
+
+	return v.Datastore.InsertWebhookDelivery(ctx, delivery)
+}
+
+func (v *validator) GetWebhookDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryList, error) {
+	if filter.WebhookID == "" {
+		return nil, models.ErrWebhookDeliveryMissingWebhookID
+	}
+
+	return v.Datastore.GetWebhookDeliveries(ctx, filter)
+}
+
+func (v *validator) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	if entry == nil {
+		return models.ErrDatastoreEmptyAuditEntry
+	}
+	if entry.ID == "" {
+		return models.ErrMissingID
+	}
+	if entry.Action == "" || entry.ResourceType == "" || entry.ResourceID == "" {
+		return models.ErrAuditEntryInvalid
+	}
+
+	return v.Datastore.InsertAuditEntry(ctx, entry)
+}
@@ -0,0 +1,173 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// annotatedPrefixes are the prefixes of the resources whose annotations are indexed
+var annotatedPrefixes = []string{appsPrefix, fnsPrefix, triggersPrefix}
+
+// annotationIndexVersionKey is set once the annotations of the resources stored before the
+// index was maintained are indexed, to the version of the index
+const annotationIndexVersionKey = "index/versions/annotations"
+
+// annotationIndexVersion is the version of the annotation index, which is indexed again
+// when it changes
+const annotationIndexVersion = 1
+
+// annotationsPrefix is the prefix of the index keys of the resources under resourcePrefix
+// having the annotation name, followed by their ID
+func annotationsPrefix(resourcePrefix, name string) string {
+	return "index/annotations/" + resourcePrefix + esc(name) + "/"
+}
+
+// annotationIndex is the value of an index key of an annotation of a resource
+type annotationIndex struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// annotated is the part of the apps, fns and triggers which is indexed
+type annotated struct {
+	Annotations models.Annotations `json:"annotations"`
+}
+
+// annotatedPrefix returns the prefix of key if it is the key of a resource whose annotations
+// are indexed
+func annotatedPrefix(key string) (string, bool) {
+	for _, p := range annotatedPrefixes {
+		if strings.HasPrefix(key, p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// annotationValues returns the indexed values of annotations by name
+func annotationValues(annotations models.Annotations) map[string]string {
+	values := make(map[string]string, len(annotations))
+	for _, k := range models.ResourceKeys(nil, annotations) {
+		values[k.Name] = k.Value
+	}
+	return values
+}
+
+// reindex updates the index keys of the annotations of the resource of key, if it is one, as
+// its value changes to value, or as it is removed if value is nil
+func (t *tx) reindex(key string, value []byte) error {
+	prefix, ok := annotatedPrefix(key)
+	if !ok {
+		return nil
+	}
+	id := strings.TrimPrefix(key, prefix)
+
+	var old, changed annotated
+	if _, err := t.get(key, &old); err != nil {
+		return err
+	}
+	if value != nil {
+		if err := json.Unmarshal(value, &changed); err != nil {
+			return err
+		}
+	}
+	oldValues, values := annotationValues(old.Annotations), annotationValues(changed.Annotations)
+	for name := range oldValues {
+		if _, ok := values[name]; !ok {
+			t.del(annotationsPrefix(prefix, name) + id)
+		}
+	}
+	for name, v := range values {
+		if ov, ok := oldValues[name]; ok && ov == v {
+			continue
+		}
+		if err := t.put(annotationsPrefix(prefix, name)+id, &annotationIndex{ID: id, Value: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotated returns the values of the resources under prefix which have the annotations of
+// filters, looked up in their index rather than listing all the resources
+func (t *tx) annotated(prefix string, filters []models.KeyFilter) ([][]byte, error) {
+	var ids map[string]bool
+	for _, f := range filters {
+		values, err := t.list(annotationsPrefix(prefix, f.Key))
+		if err != nil {
+			return nil, err
+		}
+		matched := make(map[string]bool, len(values))
+		for _, v := range values {
+			var idx annotationIndex
+			if err := json.Unmarshal(v, &idx); err != nil {
+				return nil, err
+			}
+			if (!f.HasValue || idx.Value == f.Value) && (ids == nil || ids[idx.ID]) {
+				matched[idx.ID] = true
+			}
+		}
+		ids = matched
+		if len(ids) == 0 {
+			break
+		}
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	res := make([][]byte, 0, len(sorted))
+	for _, id := range sorted {
+		var v json.RawMessage
+		ok, err := t.get(prefix+id, &v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, v)
+		}
+	}
+	return res, nil
+}
+
+// indexAnnotations indexes the annotations of the resources stored before the index was
+// maintained, once for all the nodes sharing the KV. Nodes of previous versions, which do not
+// maintain the index, must not change resources afterwards.
+func (s *Store) indexAnnotations(ctx context.Context) error {
+	return s.update(ctx, func(t *tx) error {
+		var version int
+		if _, err := t.get(annotationIndexVersionKey, &version); err != nil {
+			return err
+		}
+		if version >= annotationIndexVersion {
+			return nil
+		}
+		for _, prefix := range annotatedPrefixes {
+			values, err := t.list(prefix)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				var r struct {
+					ID          string             `json:"id"`
+					Annotations models.Annotations `json:"annotations"`
+				}
+				if err := json.Unmarshal(v, &r); err != nil {
+					return err
+				}
+				for name, value := range annotationValues(r.Annotations) {
+					if err := t.put(annotationsPrefix(prefix, name)+r.ID, &annotationIndex{ID: r.ID, Value: value}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return t.put(annotationIndexVersionKey, annotationIndexVersion)
+	})
+}
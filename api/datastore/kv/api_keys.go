@@ -0,0 +1,163 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// apiKeyRecord is the stored form of an API key, which keeps its hash
+type apiKeyRecord struct {
+	*models.APIKey
+	Hash string `json:"hash"`
+}
+
+func decodeAPIKey(b []byte) (*models.APIKey, error) {
+	r := apiKeyRecord{APIKey: new(models.APIKey)}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	r.APIKey.Hash = r.Hash
+	return r.APIKey, nil
+}
+
+func (t *tx) apiKeys() ([]*models.APIKey, error) {
+	values, err := t.list(apiKeysPrefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*models.APIKey, 0, len(values))
+	for _, v := range values {
+		k, err := decodeAPIKey(v)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (t *tx) getAPIKey(keyID string) (*models.APIKey, error) {
+	var raw json.RawMessage
+	ok, err := t.get(apiKeyKey(keyID), &raw)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	return decodeAPIKey(raw)
+}
+
+func (t *tx) removeAPIKey(key *models.APIKey) {
+	t.del(apiKeyKey(key.ID))
+	t.del(apiKeyHashKey(key.Hash))
+}
+
+// InsertAPIKey implements models.Datastore
+func (s *Store) InsertAPIKey(ctx context.Context, newKey *models.APIKey) (*models.APIKey, error) {
+	key := *newKey
+	key.ID = id.New().String()
+	key.CreatedAt = common.DateTime(time.Now())
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.update(ctx, func(t *tx) error {
+		if key.AppID != "" {
+			if _, err := t.getLiveApp(key.AppID); err != nil {
+				return err
+			}
+		}
+		// the secret itself is never stored
+		stored := key
+		stored.Secret = ""
+		if err := t.put(apiKeyHashKey(key.Hash), key.ID); err != nil {
+			return err
+		}
+		return t.put(apiKeyKey(key.ID), &apiKeyRecord{APIKey: &stored, Hash: key.Hash})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeys implements models.Datastore
+func (s *Store) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var keys []*models.APIKey
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		keys, err = t.apiKeys()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are listed by ID
+	res := []*models.APIKey{}
+	for _, k := range keys {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || k.AppID == filter.AppID) && (cursor == "" || k.ID > cursor) {
+			res = append(res, k)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.APIKeyList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// GetAPIKeyByHash implements models.Datastore
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key *models.APIKey
+	err := s.view(ctx, func(t *tx) error {
+		var keyID string
+		ok, err := t.get(apiKeyHashKey(hash), &keyID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrAPIKeyNotFound
+		}
+		key, err = t.getAPIKey(keyID)
+		return err
+	})
+	return key, err
+}
+
+// RemoveAPIKey implements models.Datastore
+func (s *Store) RemoveAPIKey(ctx context.Context, keyID string) error {
+	return s.update(ctx, func(t *tx) error {
+		key, err := t.getAPIKey(keyID)
+		if err != nil {
+			return err
+		}
+		t.removeAPIKey(key)
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package kv
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// appUsageRecord is the stored form of the usage of an app, which keeps its compute usage
+type appUsageRecord struct {
+	Calls          int64 `json:"calls"`
+	MBMilliseconds int64 `json:"mb_ms"`
+}
+
+// AddAppUsage implements models.Datastore
+func (s *Store) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	key := appUsageKey(usage.AppID, usage.Period)
+	return s.update(ctx, func(t *tx) error {
+		var r appUsageRecord
+		if _, err := t.get(key, &r); err != nil {
+			return err
+		}
+		r.Calls += usage.Calls
+		r.MBMilliseconds += usage.MBMilliseconds
+		return t.put(key, &r)
+	})
+}
+
+// GetAppUsage implements models.Datastore
+func (s *Store) GetAppUsage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	var r appUsageRecord
+	err := s.view(ctx, func(t *tx) error {
+		_, err := t.get(appUsageKey(appID, period), &r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.AppUsage{
+		AppID:          appID,
+		Period:         period,
+		Calls:          r.Calls,
+		MBMilliseconds: r.MBMilliseconds,
+	}
+	usage.SetGBSeconds()
+	return usage, nil
+}
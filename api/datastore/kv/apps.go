@@ -0,0 +1,365 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// decodeCursor decodes the cursor of a list in order, which is nil if there is none
+func decodeCursor(order models.ListOrder, cursor string) (*models.ListCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	c, err := order.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// after returns true if the resource with value and id follows cursor in order, or if there
+// is no cursor
+func after(order models.ListOrder, cursor *models.ListCursor, value, id string) bool {
+	return cursor == nil || order.Less(cursor.Value, cursor.ID, value, id)
+}
+
+func (t *tx) getApp(appID string) (*models.App, error) {
+	var app models.App
+	ok, err := t.get(appKey(appID), &app)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrAppsNotFound
+	}
+	return &app, nil
+}
+
+// getLiveApp returns an app which is not deleted
+func (t *tx) getLiveApp(appID string) (*models.App, error) {
+	app, err := t.getApp(appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.DeletedAt != nil {
+		return nil, models.ErrAppsNotFound
+	}
+	return app, nil
+}
+
+func (t *tx) apps() ([]*models.App, error) {
+	values, err := t.list(appsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return decodeApps(values)
+}
+
+// annotatedApps returns the apps with the annotations of filters, all of them without filters
+func (t *tx) annotatedApps(filters []models.KeyFilter) ([]*models.App, error) {
+	if len(filters) == 0 {
+		return t.apps()
+	}
+	values, err := t.annotated(appsPrefix, filters)
+	if err != nil {
+		return nil, err
+	}
+	return decodeApps(values)
+}
+
+func decodeApps(values [][]byte) ([]*models.App, error) {
+	apps := make([]*models.App, 0, len(values))
+	for _, v := range values {
+		var app models.App
+		if err := json.Unmarshal(v, &app); err != nil {
+			return nil, err
+		}
+		apps = append(apps, &app)
+	}
+	return apps, nil
+}
+
+// GetAppID implements models.Datastore
+func (s *Store) GetAppID(ctx context.Context, appName string) (string, error) {
+	var appID string
+	err := s.view(ctx, func(t *tx) error {
+		ok, err := t.get(appNameKey(appName), &appID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrAppsNotFound
+		}
+		_, err = t.getLiveApp(appID)
+		return err
+	})
+	return appID, err
+}
+
+// GetAppByID implements models.Datastore
+func (s *Store) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	var app *models.App
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		app, err = t.getLiveApp(appID)
+		return err
+	})
+	return app, err
+}
+
+// GetApps implements models.Datastore
+func (s *Store) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
+	order := filter.Order
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*models.App
+	err = s.view(ctx, func(t *tx) error {
+		all, err = t.annotatedApps(filter.Annotations)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
+
+	apps := []*models.App{}
+	for _, a := range all {
+		if filter.PerPage > 0 && len(apps) == filter.PerPage {
+			break
+		}
+		if after(order, cursor, order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID) &&
+			(filter.Name == "" || filter.Name == a.Name) &&
+			(a.DeletedAt != nil) == filter.Deleted &&
+			strings.HasPrefix(a.Name, filter.NamePrefix) &&
+			models.MatchKeyFilters(filter.Annotations, filter.Config, a.Config, a.Annotations) {
+			apps = append(apps, a)
+		}
+	}
+
+	var nextCursor string
+	if len(apps) > 0 && len(apps) == filter.PerPage {
+		last := apps[len(apps)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
+	}
+
+	return &models.AppList{
+		NextCursor: nextCursor,
+		Items:      apps,
+	}, nil
+}
+
+// InsertApp implements models.Datastore
+func (s *Store) InsertApp(ctx context.Context, newApp *models.App) (*models.App, error) {
+	app := newApp.Clone()
+	app.ID = id.New().String()
+	app.CreatedAt = common.DateTime(time.Now())
+	app.UpdatedAt = app.CreatedAt
+
+	var res *models.App
+	err := s.update(ctx, func(t *tx) error {
+		// the hook runs again on the app as inserted if the transaction is retried
+		res = app.Clone()
+		if err := models.RunPersistHook(ctx, res, true); err != nil {
+			return err
+		}
+		var appID string
+		ok, err := t.get(appNameKey(res.Name), &appID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return models.ErrAppsAlreadyExists
+		}
+		if err := t.put(appNameKey(res.Name), res.ID); err != nil {
+			return err
+		}
+		return t.put(appKey(res.ID), res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateApp implements models.Datastore
+func (s *Store) UpdateApp(ctx context.Context, patch *models.App) (*models.App, error) {
+	var app *models.App
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		app, err = t.getLiveApp(patch.ID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, app); err != nil {
+			return err
+		}
+		if patch.Name != "" && patch.Name != app.Name {
+			return models.ErrAppsNameImmutable
+		}
+		app.Update(patch)
+		if err := app.Validate(); err != nil {
+			return err
+		}
+		if err := models.RunPersistHook(ctx, app, false); err != nil {
+			return err
+		}
+		return t.put(appKey(app.ID), app)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// RemoveApp implements models.Datastore, removing the resources of the app along with it
+func (s *Store) RemoveApp(ctx context.Context, appID string) error {
+	return s.update(ctx, func(t *tx) error {
+		app, err := t.getApp(appID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, app); err != nil {
+			return err
+		}
+		t.del(appKey(appID))
+		t.del(appNameKey(app.Name))
+
+		fns, err := t.fns()
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			if fn.AppID == appID {
+				if err := t.removeFn(fn); err != nil {
+					return err
+				}
+			}
+		}
+		t.delPrefix(fnNamesPrefix(appID))
+		t.delPrefix(triggerNamesPrefix(appID))
+		t.delPrefix(triggerSourcesPrefix(appID))
+		t.delPrefix(scheduleNamesPrefix(appID))
+		t.delPrefix(appUsageKeyPrefix(appID))
+
+		keys, err := t.apiKeys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if k.AppID == appID {
+				t.removeAPIKey(k)
+			}
+		}
+		bindings, err := t.roleBindings()
+		if err != nil {
+			return err
+		}
+		for _, b := range bindings {
+			if b.AppID == appID {
+				t.removeRoleBinding(b)
+			}
+		}
+		domains, err := t.domains()
+		if err != nil {
+			return err
+		}
+		for _, d := range domains {
+			if d.AppID == appID {
+				// hostnames are mapped to a single app
+				t.del(domainKey(d.ID))
+				t.del(domainPathKey(d.Hostname, d.PathPrefix))
+				t.del(domainHostKey(d.Hostname))
+			}
+		}
+		webhooks, err := t.webhooks()
+		if err != nil {
+			return err
+		}
+		for _, w := range webhooks {
+			if w.AppID == appID {
+				t.removeWebhook(w.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// SoftDeleteApp implements models.Datastore
+func (s *Store) SoftDeleteApp(ctx context.Context, appID string) error {
+	return s.update(ctx, func(t *tx) error {
+		app, err := t.getLiveApp(appID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, app); err != nil {
+			return err
+		}
+		// the fns of the app are marked with its time, to be restored with it
+		deletedAt := common.DateTime(time.Now())
+		app.DeletedAt = &deletedAt
+		if err := t.put(appKey(appID), app); err != nil {
+			return err
+		}
+
+		fns, err := t.fns()
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			if fn.AppID == appID && fn.DeletedAt == nil {
+				fn.DeletedAt = &deletedAt
+				if err := t.put(fnKey(fn.ID), fn); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// RestoreApp implements models.Datastore
+func (s *Store) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	var app *models.App
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		app, err = t.getApp(appID)
+		if err != nil {
+			return err
+		}
+		if app.DeletedAt == nil {
+			return models.ErrAppsNotFound
+		}
+
+		fns, err := t.fns()
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			if fn.AppID == appID && fn.DeletedAt != nil && time.Time(*fn.DeletedAt).Equal(time.Time(*app.DeletedAt)) {
+				fn.DeletedAt = nil
+				if err := t.put(fnKey(fn.ID), fn); err != nil {
+					return err
+				}
+			}
+		}
+		app.DeletedAt = nil
+		return t.put(appKey(appID), app)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// InsertAuditEntry implements models.Datastore
+func (s *Store) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return s.update(ctx, func(t *tx) error {
+		return t.put(auditKey(entry.ID), entry)
+	})
+}
+
+// GetAuditEntries implements models.Datastore
+func (s *Store) GetAuditEntries(ctx context.Context, filter *models.AuditEntryFilter) (*models.AuditEntryList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var values [][]byte
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		values, err = t.list(auditPrefix)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// entries are listed by ID, most recent first
+	from, to := time.Time(filter.FromTime), time.Time(filter.ToTime)
+	res := []*models.AuditEntry{}
+	for i := len(values) - 1; i >= 0; i-- {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		var e models.AuditEntry
+		if err := json.Unmarshal(values[i], &e); err != nil {
+			return nil, err
+		}
+		created := time.Time(e.CreatedAt)
+		if (cursor == "" || e.ID < cursor) &&
+			(filter.AppID == "" || e.AppID == filter.AppID) &&
+			(filter.ResourceType == "" || e.ResourceType == filter.ResourceType) &&
+			(filter.ResourceID == "" || e.ResourceID == filter.ResourceID) &&
+			(filter.Actor == "" || e.Actor == filter.Actor) &&
+			(from.IsZero() || created.After(from)) &&
+			(to.IsZero() || created.Before(to)) {
+			res = append(res, &e)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.AuditEntryList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
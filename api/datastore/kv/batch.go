@@ -0,0 +1,81 @@
+package kv
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// ApplyBatch implements models.Datastore, applying the items in a single transaction
+func (s *Store) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	var applied []*models.BatchItem
+	err := s.update(ctx, func(t *tx) error {
+		ctx := context.WithValue(ctx, txKey{}, t)
+		applied = make([]*models.BatchItem, 0, len(items))
+		for i, item := range items {
+			res, err := s.applyBatchItem(ctx, item)
+			if err != nil {
+				return &models.BatchError{Index: i, Err: err}
+			}
+			applied = append(applied, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (s *Store) applyBatchItem(ctx context.Context, item *models.BatchItem) (*models.BatchItem, error) {
+	res := &models.BatchItem{Action: item.Action, SoftDelete: item.SoftDelete}
+	var err error
+
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.App, err = s.InsertApp(ctx, item.App)
+		case models.BatchUpdate:
+			res.App, err = s.UpdateApp(ctx, item.App)
+		case models.BatchDelete:
+			res.App, err = s.GetAppByID(ctx, item.App.ID)
+			if err == nil && item.SoftDelete {
+				err = s.SoftDeleteApp(ctx, item.App.ID)
+			} else if err == nil {
+				err = s.RemoveApp(ctx, item.App.ID)
+			}
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Fn, err = s.InsertFn(ctx, item.Fn)
+		case models.BatchUpdate:
+			res.Fn, err = s.UpdateFn(ctx, item.Fn)
+		case models.BatchDelete:
+			res.Fn, err = s.GetFnByID(ctx, item.Fn.ID)
+			if err == nil && item.SoftDelete {
+				err = s.SoftDeleteFn(ctx, item.Fn.ID)
+			} else if err == nil {
+				err = s.RemoveFn(ctx, item.Fn.ID)
+			}
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Trigger, err = s.InsertTrigger(ctx, item.Trigger)
+		case models.BatchUpdate:
+			res.Trigger, err = s.UpdateTrigger(ctx, item.Trigger)
+		case models.BatchDelete:
+			res.Trigger, err = s.GetTriggerByID(ctx, item.Trigger.ID)
+			if err == nil {
+				err = s.RemoveTrigger(ctx, item.Trigger.ID)
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,207 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func domainPathsPrefix(hostname string) string {
+	return domainPathKey(hostname, "")
+}
+
+func (t *tx) getDomain(domainID string) (*models.Domain, error) {
+	var d models.Domain
+	ok, err := t.get(domainKey(domainID), &d)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrDomainNotFound
+	}
+	return &d, nil
+}
+
+func (t *tx) domains() ([]*models.Domain, error) {
+	values, err := t.list(domainsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	domains := make([]*models.Domain, 0, len(values))
+	for _, v := range values {
+		var d models.Domain
+		if err := json.Unmarshal(v, &d); err != nil {
+			return nil, err
+		}
+		domains = append(domains, &d)
+	}
+	return domains, nil
+}
+
+// InsertDomain implements models.Datastore
+func (s *Store) InsertDomain(ctx context.Context, newDomain *models.Domain) (*models.Domain, error) {
+	d := *newDomain
+	d.ID = id.New().String()
+	d.CreatedAt = common.DateTime(time.Now())
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.update(ctx, func(t *tx) error {
+		if _, err := t.getLiveApp(d.AppID); err != nil {
+			return err
+		}
+		var domainID string
+		ok, err := t.get(domainPathKey(d.Hostname, d.PathPrefix), &domainID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return models.ErrDomainExists
+		}
+		// the domains of a hostname all route to the same app
+		var appID string
+		ok, err = t.get(domainHostKey(d.Hostname), &appID)
+		if err != nil {
+			return err
+		}
+		if ok && appID != d.AppID {
+			return models.ErrDomainHostnameTaken
+		}
+
+		if err := t.put(domainHostKey(d.Hostname), d.AppID); err != nil {
+			return err
+		}
+		if err := t.put(domainPathKey(d.Hostname, d.PathPrefix), d.ID); err != nil {
+			return err
+		}
+		return t.put(domainKey(d.ID), &d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetDomainByID implements models.Datastore
+func (s *Store) GetDomainByID(ctx context.Context, domainID string) (*models.Domain, error) {
+	var d *models.Domain
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		d, err = t.getDomain(domainID)
+		return err
+	})
+	return d, err
+}
+
+// GetDomains implements models.Datastore
+func (s *Store) GetDomains(ctx context.Context, filter *models.DomainFilter) (*models.DomainList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var domains []*models.Domain
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		domains, err = t.domains()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// domains are listed by ID
+	res := []*models.Domain{}
+	for _, d := range domains {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || d.AppID == filter.AppID) &&
+			(filter.Hostname == "" || d.Hostname == filter.Hostname) &&
+			(cursor == "" || d.ID > cursor) {
+			res = append(res, d)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.DomainList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// GetDomainsByHostname implements models.Datastore
+func (s *Store) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	res := []*models.Domain{}
+	err := s.view(ctx, func(t *tx) error {
+		values, err := t.list(domainPathsPrefix(hostname))
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			var domainID string
+			if err := json.Unmarshal(v, &domainID); err != nil {
+				return err
+			}
+			d, err := t.getDomain(domainID)
+			if err == models.ErrDomainNotFound {
+				// removed since it was listed
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			res = append(res, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// RemoveDomain implements models.Datastore
+func (s *Store) RemoveDomain(ctx context.Context, domainID string) error {
+	return s.update(ctx, func(t *tx) error {
+		d, err := t.getDomain(domainID)
+		if err != nil {
+			return err
+		}
+		t.del(domainKey(d.ID))
+		t.del(domainPathKey(d.Hostname, d.PathPrefix))
+
+		// the hostname is released with its last domain, it is written otherwise so that
+		// removing the other domains meanwhile conflicts
+		others, err := t.list(domainPathsPrefix(d.Hostname))
+		if err != nil {
+			return err
+		}
+		var appID string
+		if _, err := t.get(domainHostKey(d.Hostname), &appID); err != nil {
+			return err
+		}
+		if len(others) == 0 {
+			t.del(domainHostKey(d.Hostname))
+			return nil
+		}
+		return t.put(domainHostKey(d.Hostname), appID)
+	})
+}
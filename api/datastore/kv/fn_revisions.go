@@ -0,0 +1,121 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) fnRevisions(fnID string) ([]*models.FnRevision, error) {
+	values, err := t.list(fnRevisionsKeyPrefix(fnID))
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]*models.FnRevision, 0, len(values))
+	for _, v := range values {
+		var r models.FnRevision
+		if err := json.Unmarshal(v, &r); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &r)
+	}
+	return revisions, nil
+}
+
+// recordFnRevisions numbers and records the revisions of fn left to the datastore, see
+// models.Fn.Update
+func (t *tx) recordFnRevisions(fn *models.Fn) error {
+	revisions, err := t.fnRevisions(fn.ID)
+	if err != nil {
+		return err
+	}
+	// revisions are listed in order
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	if fn.Revision == 0 {
+		fn.Revision = next
+		next++
+		if err := t.put(fnRevisionKey(fn.ID, fn.Revision), fn.CurrentRevision()); err != nil {
+			return err
+		}
+	}
+	if !fn.Canary.IsEmpty() && fn.Canary.Revision == 0 {
+		fn.Canary.Revision = next
+		if err := t.put(fnRevisionKey(fn.ID, fn.Canary.Revision), fn.CandidateRevision()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFnRevision implements models.Datastore
+func (s *Store) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	var r models.FnRevision
+	err := s.view(ctx, func(t *tx) error {
+		ok, err := t.get(fnRevisionKey(fnID, revision), &r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrFnRevisionNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetFnRevisions implements models.Datastore
+func (s *Store) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	var cursor int64
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor, err = strconv.ParseInt(string(s), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var revisions []*models.FnRevision
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		revisions, err = t.fnRevisions(filter.FnID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// most recent first
+	res := []*models.FnRevision{}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if r := revisions[i]; filter.Cursor == "" || r.Revision < cursor {
+			res = append(res, r)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(strconv.FormatInt(res[len(res)-1].Revision, 10))
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.FnRevisionList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
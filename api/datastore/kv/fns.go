@@ -0,0 +1,303 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) getFn(fnID string) (*models.Fn, error) {
+	var fn models.Fn
+	ok, err := t.get(fnKey(fnID), &fn)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrFnsNotFound
+	}
+	return &fn, nil
+}
+
+// getLiveFn returns a fn which is not deleted
+func (t *tx) getLiveFn(fnID string) (*models.Fn, error) {
+	fn, err := t.getFn(fnID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil {
+		return nil, models.ErrFnsNotFound
+	}
+	return fn, nil
+}
+
+func (t *tx) fns() ([]*models.Fn, error) {
+	values, err := t.list(fnsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFns(values)
+}
+
+// annotatedFns returns the fns with the annotations of filters, all of them without filters
+func (t *tx) annotatedFns(filters []models.KeyFilter) ([]*models.Fn, error) {
+	if len(filters) == 0 {
+		return t.fns()
+	}
+	values, err := t.annotated(fnsPrefix, filters)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFns(values)
+}
+
+func decodeFns(values [][]byte) ([]*models.Fn, error) {
+	fns := make([]*models.Fn, 0, len(values))
+	for _, v := range values {
+		var fn models.Fn
+		if err := json.Unmarshal(v, &fn); err != nil {
+			return nil, err
+		}
+		fns = append(fns, &fn)
+	}
+	return fns, nil
+}
+
+// removeFn removes fn along with its triggers, schedules and revisions
+func (t *tx) removeFn(fn *models.Fn) error {
+	t.del(fnKey(fn.ID))
+	t.del(fnNameKey(fn.AppID, fn.Name))
+	t.delPrefix(fnRevisionsKeyPrefix(fn.ID))
+
+	triggers, err := t.triggers()
+	if err != nil {
+		return err
+	}
+	for _, trigger := range triggers {
+		if trigger.FnID == fn.ID {
+			t.removeTrigger(trigger)
+		}
+	}
+	schedules, err := t.schedules()
+	if err != nil {
+		return err
+	}
+	for _, s := range schedules {
+		if s.FnID == fn.ID {
+			t.removeSchedule(s)
+		}
+	}
+	return nil
+}
+
+// InsertFn implements models.Datastore
+func (s *Store) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn, error) {
+	fn := newFn.Clone()
+	fn.ID = id.New().String()
+	fn.CreatedAt = common.DateTime(time.Now())
+	fn.UpdatedAt = fn.CreatedAt
+	fn.Revision = 0
+	if !fn.Canary.IsEmpty() {
+		fn.Canary.Revision = 0
+	}
+	if err := fn.Validate(); err != nil {
+		return nil, err
+	}
+
+	var res *models.Fn
+	err := s.update(ctx, func(t *tx) error {
+		// the hook runs again and the revisions are numbered again if the transaction is retried
+		res = fn.Clone()
+		if err := models.RunPersistHook(ctx, res, true); err != nil {
+			return err
+		}
+		if _, err := t.getLiveApp(res.AppID); err != nil {
+			return err
+		}
+		var fnID string
+		ok, err := t.get(fnNameKey(res.AppID, res.Name), &fnID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return models.ErrFnsExists
+		}
+
+		if err := t.recordFnRevisions(res); err != nil {
+			return err
+		}
+		if err := t.put(fnNameKey(res.AppID, res.Name), res.ID); err != nil {
+			return err
+		}
+		return t.put(fnKey(res.ID), res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateFn implements models.Datastore
+func (s *Store) UpdateFn(ctx context.Context, patch *models.Fn) (*models.Fn, error) {
+	var fn *models.Fn
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		fn, err = t.getLiveFn(patch.ID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, fn); err != nil {
+			return err
+		}
+		fn.Update(patch)
+		if err := fn.Validate(); err != nil {
+			return err
+		}
+		if err := models.RunPersistHook(ctx, fn, false); err != nil {
+			return err
+		}
+		if err := t.recordFnRevisions(fn); err != nil {
+			return err
+		}
+		return t.put(fnKey(fn.ID), fn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// GetFns implements models.Datastore
+func (s *Store) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
+	order := filter.Order
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*models.Fn
+	err = s.view(ctx, func(t *tx) error {
+		all, err = t.annotatedFns(filter.Annotations)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
+
+	fns := []*models.Fn{}
+	for _, f := range all {
+		if filter.PerPage > 0 && len(fns) == filter.PerPage {
+			break
+		}
+		if after(order, cursor, order.Value(f.Name, f.CreatedAt, f.UpdatedAt), f.ID) &&
+			(filter.AppID == "" || filter.AppID == f.AppID) &&
+			(filter.Name == "" || filter.Name == f.Name) &&
+			strings.HasPrefix(f.Name, filter.NamePrefix) &&
+			models.MatchKeyFilters(filter.Annotations, filter.Config, f.Config, f.Annotations) &&
+			(f.DeletedAt != nil) == filter.Deleted {
+			fns = append(fns, f)
+		}
+	}
+
+	var nextCursor string
+	if len(fns) > 0 && len(fns) == filter.PerPage {
+		last := fns[len(fns)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
+	}
+
+	return &models.FnList{
+		NextCursor: nextCursor,
+		Items:      fns,
+	}, nil
+}
+
+// GetFnByID implements models.Datastore
+func (s *Store) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn *models.Fn
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		fn, err = t.getLiveFn(fnID)
+		return err
+	})
+	return fn, err
+}
+
+// RemoveFn implements models.Datastore
+func (s *Store) RemoveFn(ctx context.Context, fnID string) error {
+	return s.update(ctx, func(t *tx) error {
+		fn, err := t.getFn(fnID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, fn); err != nil {
+			return err
+		}
+		return t.removeFn(fn)
+	})
+}
+
+// SoftDeleteFn implements models.Datastore
+func (s *Store) SoftDeleteFn(ctx context.Context, fnID string) error {
+	return s.update(ctx, func(t *tx) error {
+		fn, err := t.getLiveFn(fnID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, fn); err != nil {
+			return err
+		}
+		deletedAt := common.DateTime(time.Now())
+		fn.DeletedAt = &deletedAt
+		return t.put(fnKey(fnID), fn)
+	})
+}
+
+// GetDeletedFnByID implements models.Datastore
+func (s *Store) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn *models.Fn
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		fn, err = t.getFn(fnID)
+		if err != nil {
+			return err
+		}
+		if fn.DeletedAt == nil {
+			return models.ErrFnsNotFound
+		}
+		return nil
+	})
+	return fn, err
+}
+
+// RestoreFn implements models.Datastore
+func (s *Store) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn *models.Fn
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		fn, err = t.getFn(fnID)
+		if err != nil {
+			return err
+		}
+		if fn.DeletedAt == nil {
+			return models.ErrFnsNotFound
+		}
+		if _, err := t.getLiveApp(fn.AppID); err != nil {
+			return err
+		}
+		fn.DeletedAt = nil
+		return t.put(fnKey(fnID), fn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
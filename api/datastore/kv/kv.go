@@ -0,0 +1,62 @@
+// Package kv implements the datastore over an ordered key value store with optimistic
+// transactions, such as etcd. Each resource is a JSON value under a key of its kind and ID,
+// and the unique names and sources of resources are index keys, so that conflicting changes
+// of concurrent nodes fail their transaction and are retried. The annotations of apps, fns
+// and triggers are index keys too, so that the lists filtering on them read the resources
+// having them only.
+package kv
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrConflict is returned by KV.Commit when a key a transaction read was changed since
+var ErrConflict = errors.New("kv: transaction conflict")
+
+// Entry is a key and its value
+type Entry struct {
+	Key   string
+	Value []byte
+	// Version changes whenever the value of the key changes, it is 0 for absent keys
+	Version int64
+}
+
+// Txn is a set of changes committed atomically, if the keys read hold their versions
+type Txn struct {
+	// Reads are the versions of the keys read, 0 for keys which were absent
+	Reads map[string]int64
+	// Lists are the revisions of the KV the prefixes were listed at, the transaction
+	// conflicts if a key under them was set since
+	Lists map[string]int64
+	// Puts are the values to set by key, none of them is under DeletePrefixes
+	Puts map[string][]byte
+	// Deletes are the keys to remove, none of them is in Puts
+	Deletes []string
+	// DeletePrefixes removes all the keys under each prefix
+	DeletePrefixes []string
+}
+
+// Event is a change of a key
+type Event struct {
+	Key     string
+	Deleted bool
+}
+
+// KV is an ordered key value store
+type KV interface {
+	// Get returns the entry of key, nil if it is absent.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// List returns the entries of the keys under prefix, in key order, and the revision of
+	// the KV they were listed at.
+	List(ctx context.Context, prefix string) ([]*Entry, int64, error)
+	// Commit applies the changes of txn atomically, or returns ErrConflict if any of the keys
+	// it read or listed changed since.
+	Commit(ctx context.Context, txn *Txn) error
+	// Watch sends the changes of the keys under prefix from now on until ctx is done. The
+	// channel is closed if changes may have been missed, the caller watches again.
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+
+	io.Closer
+}
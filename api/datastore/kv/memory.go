@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// watchBuffer is the number of events a watcher of a Memory store may fall behind by before
+// its channel is closed
+const watchBuffer = 1024
+
+// Memory is a KV in the memory of the process, for tests and single node development
+type Memory struct {
+	lock     sync.Mutex
+	revision int64
+	entries  map[string]*Entry
+	watchers map[*memoryWatcher]struct{}
+}
+
+type memoryWatcher struct {
+	prefix string
+	ch     chan Event
+}
+
+// NewMemory returns an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{
+		entries:  make(map[string]*Entry),
+		watchers: make(map[*memoryWatcher]struct{}),
+	}
+}
+
+// Get implements KV
+func (m *Memory) Get(ctx context.Context, key string) (*Entry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	cl := *e
+	return &cl, nil
+}
+
+// List implements KV
+func (m *Memory) List(ctx context.Context, prefix string) ([]*Entry, int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var res []*Entry
+	for k, e := range m.entries {
+		if strings.HasPrefix(k, prefix) {
+			cl := *e
+			res = append(res, &cl)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res, m.revision, nil
+}
+
+// Commit implements KV
+func (m *Memory) Commit(ctx context.Context, txn *Txn) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for k, version := range txn.Reads {
+		var current int64
+		if e, ok := m.entries[k]; ok {
+			current = e.Version
+		}
+		if current != version {
+			return ErrConflict
+		}
+	}
+	for prefix, revision := range txn.Lists {
+		for k, e := range m.entries {
+			if strings.HasPrefix(k, prefix) && e.Version > revision {
+				return ErrConflict
+			}
+		}
+	}
+
+	m.revision++
+	var events []Event
+	for _, prefix := range txn.DeletePrefixes {
+		for k := range m.entries {
+			if strings.HasPrefix(k, prefix) {
+				delete(m.entries, k)
+				events = append(events, Event{Key: k, Deleted: true})
+			}
+		}
+	}
+	for _, k := range txn.Deletes {
+		if _, ok := m.entries[k]; ok {
+			delete(m.entries, k)
+			events = append(events, Event{Key: k, Deleted: true})
+		}
+	}
+	for k, v := range txn.Puts {
+		m.entries[k] = &Entry{Key: k, Value: v, Version: m.revision}
+		events = append(events, Event{Key: k})
+	}
+
+	for w := range m.watchers {
+		for _, ev := range events {
+			if !strings.HasPrefix(ev.Key, w.prefix) {
+				continue
+			}
+			select {
+			case w.ch <- ev:
+			default:
+				// the watcher missed events, it watches again
+				close(w.ch)
+				delete(m.watchers, w)
+			}
+			if _, ok := m.watchers[w]; !ok {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Watch implements KV
+func (m *Memory) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	w := &memoryWatcher{prefix: prefix, ch: make(chan Event, watchBuffer)}
+	m.lock.Lock()
+	m.watchers[w] = struct{}{}
+	m.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if _, ok := m.watchers[w]; ok {
+			close(w.ch)
+			delete(m.watchers, w)
+		}
+	}()
+	return w.ch, nil
+}
+
+// Close implements KV
+func (m *Memory) Close() error {
+	return nil
+}
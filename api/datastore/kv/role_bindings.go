@@ -0,0 +1,148 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) getRoleBinding(bindingID string) (*models.RoleBinding, error) {
+	var b models.RoleBinding
+	ok, err := t.get(roleBindingKey(bindingID), &b)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrRoleBindingNotFound
+	}
+	return &b, nil
+}
+
+func (t *tx) roleBindings() ([]*models.RoleBinding, error) {
+	values, err := t.list(roleBindingsPrefix)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]*models.RoleBinding, 0, len(values))
+	for _, v := range values {
+		var b models.RoleBinding
+		if err := json.Unmarshal(v, &b); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, &b)
+	}
+	return bindings, nil
+}
+
+func (t *tx) removeRoleBinding(b *models.RoleBinding) {
+	t.del(roleBindingKey(b.ID))
+	t.del(roleBindingIndexKey(b))
+}
+
+// InsertRoleBinding implements models.Datastore
+func (s *Store) InsertRoleBinding(ctx context.Context, newBinding *models.RoleBinding) (*models.RoleBinding, error) {
+	b := *newBinding
+	b.ID = id.New().String()
+	b.CreatedAt = common.DateTime(time.Now())
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.update(ctx, func(t *tx) error {
+		if b.AppID != "" {
+			if _, err := t.getLiveApp(b.AppID); err != nil {
+				return err
+			}
+		}
+		var bindingID string
+		ok, err := t.get(roleBindingIndexKey(&b), &bindingID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return models.ErrRoleBindingExists
+		}
+		if err := t.put(roleBindingIndexKey(&b), b.ID); err != nil {
+			return err
+		}
+		return t.put(roleBindingKey(b.ID), &b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetRoleBindingByID implements models.Datastore
+func (s *Store) GetRoleBindingByID(ctx context.Context, bindingID string) (*models.RoleBinding, error) {
+	var b *models.RoleBinding
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		b, err = t.getRoleBinding(bindingID)
+		return err
+	})
+	return b, err
+}
+
+// GetRoleBindings implements models.Datastore
+func (s *Store) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var bindings []*models.RoleBinding
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		bindings, err = t.roleBindings()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// bindings are listed by ID
+	res := []*models.RoleBinding{}
+	for _, b := range bindings {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || b.AppID == filter.AppID) &&
+			(filter.Principal == "" || b.Principal == filter.Principal) &&
+			(cursor == "" || b.ID > cursor) {
+			res = append(res, b)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.RoleBindingList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// RemoveRoleBinding implements models.Datastore
+func (s *Store) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	return s.update(ctx, func(t *tx) error {
+		b, err := t.getRoleBinding(bindingID)
+		if err != nil {
+			return err
+		}
+		t.removeRoleBinding(b)
+		return nil
+	})
+}
@@ -0,0 +1,300 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) getSchedule(scheduleID string) (*models.Schedule, error) {
+	var schedule models.Schedule
+	ok, err := t.get(scheduleKey(scheduleID), &schedule)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrScheduleNotFound
+	}
+	return &schedule, nil
+}
+
+func (t *tx) schedules() ([]*models.Schedule, error) {
+	values, err := t.list(schedulesPrefix)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]*models.Schedule, 0, len(values))
+	for _, v := range values {
+		var schedule models.Schedule
+		if err := json.Unmarshal(v, &schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+	return schedules, nil
+}
+
+// putSchedule stores schedule and its name, failing if the name is taken
+func (t *tx) putSchedule(schedule *models.Schedule) error {
+	fn, err := t.getLiveFn(schedule.FnID)
+	if err != nil {
+		return err
+	}
+	if fn.AppID != schedule.AppID {
+		return models.ErrScheduleFnIDNotSameApp
+	}
+
+	var scheduleID string
+	ok, err := t.get(scheduleNameKey(schedule.AppID, schedule.Name), &scheduleID)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return models.ErrScheduleExists
+	}
+	if err := t.put(scheduleNameKey(schedule.AppID, schedule.Name), schedule.ID); err != nil {
+		return err
+	}
+	return t.put(scheduleKey(schedule.ID), schedule)
+}
+
+// removeSchedule removes schedule along with its runs
+func (t *tx) removeSchedule(schedule *models.Schedule) {
+	t.del(scheduleKey(schedule.ID))
+	t.del(scheduleNameKey(schedule.AppID, schedule.Name))
+	t.delPrefix(scheduleRunsKeyPrefix(schedule.ID))
+}
+
+// InsertSchedule implements models.Datastore
+func (s *Store) InsertSchedule(ctx context.Context, newSchedule *models.Schedule) (*models.Schedule, error) {
+	schedule := newSchedule.Clone()
+	schedule.CreatedAt = common.DateTime(time.Now())
+	schedule.UpdatedAt = schedule.CreatedAt
+	schedule.ID = id.New().String()
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	next, err := schedule.NextRun(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule.NextRunAt = common.DateTime(next)
+
+	err = s.update(ctx, func(t *tx) error {
+		if _, err := t.getLiveApp(schedule.AppID); err != nil {
+			return err
+		}
+		return t.putSchedule(schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule implements models.Datastore
+func (s *Store) UpdateSchedule(ctx context.Context, patch *models.Schedule) (*models.Schedule, error) {
+	var schedule *models.Schedule
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		schedule, err = t.getSchedule(patch.ID)
+		if err != nil {
+			return err
+		}
+		t.del(scheduleNameKey(schedule.AppID, schedule.Name))
+		schedule.Update(patch)
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+		return t.putSchedule(schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// RemoveSchedule implements models.Datastore
+func (s *Store) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	return s.update(ctx, func(t *tx) error {
+		schedule, err := t.getSchedule(scheduleID)
+		if err != nil {
+			return err
+		}
+		t.removeSchedule(schedule)
+		return nil
+	})
+}
+
+// GetScheduleByID implements models.Datastore
+func (s *Store) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	var schedule *models.Schedule
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		schedule, err = t.getSchedule(scheduleID)
+		return err
+	})
+	return schedule, err
+}
+
+// GetSchedules implements models.Datastore
+func (s *Store) GetSchedules(ctx context.Context, filter *models.ScheduleFilter) (*models.ScheduleList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var schedules []*models.Schedule
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		schedules, err = t.schedules()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+
+	res := []*models.Schedule{}
+	for _, sc := range schedules {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.Cursor == "" || sc.Name > cursor) &&
+			sc.AppID == filter.AppID &&
+			(filter.FnID == "" || filter.FnID == sc.FnID) &&
+			(filter.Name == "" || filter.Name == sc.Name) {
+			res = append(res, sc)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].Name)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.ScheduleList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// GetDueSchedules implements models.Datastore
+func (s *Store) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]*models.Schedule, error) {
+	var due []*models.Schedule
+	err := s.view(ctx, func(t *tx) error {
+		schedules, err := t.schedules()
+		if err != nil {
+			return err
+		}
+		for _, sc := range schedules {
+			if time.Time(sc.NextRunAt).After(now) {
+				continue
+			}
+			// the schedules of deleted fns don't fire until the fns are restored
+			if _, err := t.getLiveFn(sc.FnID); err == models.ErrFnsNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			due = append(due, sc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return time.Time(due[i].NextRunAt).Before(time.Time(due[j].NextRunAt))
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// AdvanceSchedule implements models.Datastore
+func (s *Store) AdvanceSchedule(ctx context.Context, scheduleID string, due, next time.Time) error {
+	return s.update(ctx, func(t *tx) error {
+		schedule, err := t.getSchedule(scheduleID)
+		if err == models.ErrScheduleNotFound {
+			return models.ErrScheduleNotDue
+		}
+		if err != nil {
+			return err
+		}
+		// times are compared as they are stored
+		if schedule.NextRunAt.String() != common.DateTime(due).String() {
+			return models.ErrScheduleNotDue
+		}
+		schedule.NextRunAt = common.DateTime(next)
+		return t.put(scheduleKey(scheduleID), schedule)
+	})
+}
+
+// InsertScheduleRun implements models.Datastore
+func (s *Store) InsertScheduleRun(ctx context.Context, run *models.ScheduleRun) error {
+	return s.update(ctx, func(t *tx) error {
+		return t.put(scheduleRunsKeyPrefix(run.ScheduleID)+run.ID, run)
+	})
+}
+
+// GetScheduleRuns implements models.Datastore
+func (s *Store) GetScheduleRuns(ctx context.Context, filter *models.ScheduleRunFilter) (*models.ScheduleRunList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var values [][]byte
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		values, err = t.list(scheduleRunsKeyPrefix(filter.ScheduleID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// runs are listed by ID, most recent first
+	res := []*models.ScheduleRun{}
+	for i := len(values) - 1; i >= 0; i-- {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		var r models.ScheduleRun
+		if err := json.Unmarshal(values[i], &r); err != nil {
+			return nil, err
+		}
+		if filter.Cursor == "" || r.ID < cursor {
+			res = append(res, &r)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.ScheduleRunList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
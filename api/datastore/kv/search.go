@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Search implements models.Datastore
+func (s *Store) Search(ctx context.Context, filter *models.SearchFilter) (*models.SearchResultList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var results []*models.SearchResult
+	err := s.view(ctx, func(t *tx) error {
+		if filter.Type == "" || filter.Type == models.SearchTypeApp {
+			apps, err := t.apps()
+			if err != nil {
+				return err
+			}
+			for _, a := range apps {
+				if a.DeletedAt == nil && models.MatchSearch(filter.Terms, models.SearchText(a.Name, a.Config, a.Annotations)) {
+					results = append(results, &models.SearchResult{Type: models.SearchTypeApp, ID: a.ID, AppID: a.ID, Name: a.Name})
+				}
+			}
+		}
+		if filter.Type == "" || filter.Type == models.SearchTypeFn {
+			fns, err := t.fns()
+			if err != nil {
+				return err
+			}
+			for _, f := range fns {
+				if f.DeletedAt == nil && models.MatchSearch(filter.Terms, models.SearchText(f.Name, f.Config, f.Annotations)) {
+					results = append(results, &models.SearchResult{Type: models.SearchTypeFn, ID: f.ID, AppID: f.AppID, Name: f.Name})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	res := []*models.SearchResult{}
+	for _, r := range results {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if r.ID > cursor && filter.SearchesApp(r.AppID) {
+			res = append(res, r)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.SearchResultList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
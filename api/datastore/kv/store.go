@@ -0,0 +1,440 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+const (
+	// maxCommitAttempts is the number of times a transaction is run when it conflicts with
+	// the transactions of other nodes
+	maxCommitAttempts = 10
+	// commitBackoff is the time conflicting transactions wait for at most before running
+	// again, times the number of attempts
+	commitBackoff = 10 * time.Millisecond
+)
+
+// Prefixes of the keys of each kind of resource, followed by their ID
+const (
+	appsPrefix              = "apps/"
+	fnsPrefix               = "fns/"
+	triggersPrefix          = "triggers/"
+	schedulesPrefix         = "schedules/"
+	scheduleRunsPrefix      = "schedule_runs/"
+	fnRevisionsPrefix       = "fn_revisions/"
+	apiKeysPrefix           = "api_keys/"
+	roleBindingsPrefix      = "role_bindings/"
+	appUsagePrefix          = "app_usage/"
+	domainsPrefix           = "domains/"
+	webhooksPrefix          = "webhooks/"
+	webhookDeliveriesPrefix = "webhook_deliveries/"
+	auditPrefix             = "audit/"
+	leasesPrefix            = "leases/"
+)
+
+// Store is a models.Datastore over a KV
+type Store struct {
+	kv KV
+}
+
+var _ models.Datastore = new(Store)
+var _ models.ChangeWatcher = new(Store)
+
+// New returns the datastore of kv
+func New(kv KV) *Store {
+	return &Store{kv: kv}
+}
+
+// Open returns the datastore of kv, indexing the resources stored by previous versions
+func Open(ctx context.Context, kv KV) (*Store, error) {
+	s := New(kv)
+	if err := s.indexAnnotations(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// esc escapes the names and sources of resources in keys, so that they have no slashes
+func esc(s string) string {
+	return url.PathEscape(s)
+}
+
+func appKey(id string) string                { return appsPrefix + id }
+func appNameKey(name string) string          { return "index/app_names/" + esc(name) }
+func fnKey(id string) string                 { return fnsPrefix + id }
+func fnNamesPrefix(appID string) string      { return "index/fn_names/" + appID + "/" }
+func fnNameKey(appID, name string) string    { return fnNamesPrefix(appID) + esc(name) }
+func triggerKey(id string) string            { return triggersPrefix + id }
+func triggerNamesPrefix(appID string) string { return "index/trigger_names/" + appID + "/" }
+func triggerNameKey(appID, fnID, name string) string {
+	return triggerNamesPrefix(appID) + fnID + "/" + esc(name)
+}
+func triggerSourcesPrefix(appID string) string { return "index/trigger_sources/" + appID + "/" }
+func triggerSourceKey(appID, triggerType, source string) string {
+	return triggerSourcesPrefix(appID) + esc(triggerType) + "/" + esc(source)
+}
+func scheduleKey(id string) string              { return schedulesPrefix + id }
+func scheduleNamesPrefix(appID string) string   { return "index/schedule_names/" + appID + "/" }
+func scheduleNameKey(appID, name string) string { return scheduleNamesPrefix(appID) + esc(name) }
+func scheduleRunsKeyPrefix(scheduleID string) string {
+	return scheduleRunsPrefix + scheduleID + "/"
+}
+func fnRevisionsKeyPrefix(fnID string) string { return fnRevisionsPrefix + fnID + "/" }
+func fnRevisionKey(fnID string, revision int64) string {
+	// revisions are padded to list them in order
+	return fmt.Sprintf("%s%020d", fnRevisionsKeyPrefix(fnID), revision)
+}
+func apiKeyKey(id string) string       { return apiKeysPrefix + id }
+func apiKeyHashKey(hash string) string { return "index/api_key_hashes/" + esc(hash) }
+func roleBindingKey(id string) string  { return roleBindingsPrefix + id }
+func roleBindingIndexKey(b *models.RoleBinding) string {
+	return "index/role_bindings/" + esc(b.Principal) + "/" + b.AppID + "/" + esc(b.Role)
+}
+func appUsageKeyPrefix(appID string) string   { return appUsagePrefix + appID + "/" }
+func appUsageKey(appID, period string) string { return appUsageKeyPrefix(appID) + esc(period) }
+func domainKey(id string) string              { return domainsPrefix + id }
+func domainHostKey(hostname string) string    { return "index/domain_hosts/" + esc(hostname) }
+func domainPathKey(hostname, pathPrefix string) string {
+	return "index/domain_paths/" + esc(hostname) + "/" + esc(pathPrefix)
+}
+func webhookKey(id string) string { return webhooksPrefix + id }
+func webhookDeliveriesKeyPrefix(webhookID string) string {
+	return webhookDeliveriesPrefix + webhookID + "/"
+}
+func auditKey(id string) string   { return auditPrefix + id }
+func leaseKey(name string) string { return leasesPrefix + esc(name) }
+
+// tx reads keys for a transaction, recording their versions, and buffers its changes. Reads
+// see the changes of the transaction.
+type tx struct {
+	ctx      context.Context
+	kv       KV
+	reads    map[string]int64
+	lists    map[string]int64
+	puts     map[string][]byte
+	deleted  map[string]bool
+	prefixes []string
+	// listed are the versions of the keys listed, which are read by the transaction if it
+	// changes them
+	listed map[string]int64
+	// err is the error of a change, which fails the transaction
+	err error
+}
+
+func (s *Store) newTx(ctx context.Context) *tx {
+	return &tx{
+		ctx:     ctx,
+		kv:      s.kv,
+		reads:   make(map[string]int64),
+		lists:   make(map[string]int64),
+		puts:    make(map[string][]byte),
+		deleted: make(map[string]bool),
+		listed:  make(map[string]int64),
+	}
+}
+
+// txKey is the key of the transaction of a context, the operations of a batch share it
+type txKey struct{}
+
+// update runs f in a transaction, committing its changes. f runs again if the keys it read
+// were changed by another transaction before the changes are committed. f runs in the
+// transaction of ctx if it has one, which is committed by its owner.
+func (s *Store) update(ctx context.Context, f func(t *tx) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		return f(t)
+	}
+	for attempt := 1; ; attempt++ {
+		t := s.newTx(ctx)
+		if err := f(t); err != nil {
+			return err
+		}
+		if t.err != nil {
+			return t.err
+		}
+		txn := t.txn()
+		if len(txn.Puts) == 0 && len(txn.Deletes) == 0 && len(txn.DeletePrefixes) == 0 {
+			return nil
+		}
+		err := s.kv.Commit(ctx, txn)
+		if err != ErrConflict || attempt == maxCommitAttempts {
+			return err
+		}
+		common.Logger(ctx).WithError(err).Debug("retrying conflicting datastore transaction")
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(attempt) * int64(commitBackoff)))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// view runs f with reads only, in the transaction of ctx if it has one
+func (s *Store) view(ctx context.Context, f func(t *tx) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok {
+		return f(t)
+	}
+	return f(s.newTx(ctx))
+}
+
+// InTx implements models.Datastore, the operations made with the context passed to f
+// sharing a transaction as the items of a batch do
+func (s *Store) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return f(ctx)
+	}
+	var scope *models.TxScope
+	err := s.update(ctx, func(t *tx) error {
+		// the side effects of an attempt which conflicted are dropped along with its changes
+		var txCtx context.Context
+		txCtx, scope = models.WithTxScope(ctx)
+		return f(context.WithValue(txCtx, txKey{}, t))
+	})
+	if err != nil {
+		return err
+	}
+	scope.Committed()
+	return nil
+}
+
+// txn returns the changes of the transaction
+func (t *tx) txn() *Txn {
+	txn := &Txn{
+		Reads:          t.reads,
+		Lists:          t.lists,
+		Puts:           t.puts,
+		DeletePrefixes: t.prefixes,
+	}
+	for k := range t.deleted {
+		txn.Deletes = append(txn.Deletes, k)
+	}
+	sort.Strings(txn.Deletes)
+	return txn
+}
+
+func (t *tx) isDeleted(key string) bool {
+	if t.deleted[key] {
+		return true
+	}
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tx) read(key string, version int64) {
+	if _, ok := t.reads[key]; !ok {
+		t.reads[key] = version
+	}
+}
+
+// get decodes the value of key into v, returning false if key is absent
+func (t *tx) get(key string, v interface{}) (bool, error) {
+	if b, ok := t.puts[key]; ok {
+		return true, json.Unmarshal(b, v)
+	}
+	if t.isDeleted(key) {
+		return false, nil
+	}
+	e, err := t.kv.Get(t.ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if e == nil {
+		t.read(key, 0)
+		return false, nil
+	}
+	t.read(key, e.Version)
+	return true, json.Unmarshal(e.Value, v)
+}
+
+// list returns the values of the keys under prefix, in key order. Keys added under prefix by
+// other transactions conflict with the transaction, as do changes of the keys it changes.
+func (t *tx) list(prefix string) ([][]byte, error) {
+	entries, revision, err := t.kv.List(t.ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.lists[prefix]; !ok {
+		t.lists[prefix] = revision
+	}
+	values := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		if _, ok := t.listed[e.Key]; !ok {
+			t.listed[e.Key] = e.Version
+		}
+		if !t.isDeleted(e.Key) {
+			values[e.Key] = e.Value
+		}
+	}
+	for k, v := range t.puts {
+		if strings.HasPrefix(k, prefix) {
+			values[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([][]byte, len(keys))
+	for i, k := range keys {
+		res[i] = values[k]
+	}
+	return res, nil
+}
+
+// changed records the version of key as read if it was listed
+func (t *tx) changed(key string) {
+	if version, ok := t.listed[key]; ok {
+		t.read(key, version)
+	}
+}
+
+func (t *tx) put(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := t.reindex(key, b); err != nil {
+		return err
+	}
+	t.changed(key)
+	t.puts[key] = b
+	delete(t.deleted, key)
+	return nil
+}
+
+func (t *tx) del(key string) {
+	if err := t.reindex(key, nil); err != nil && t.err == nil {
+		t.err = err
+	}
+	t.changed(key)
+	delete(t.puts, key)
+	t.deleted[key] = true
+}
+
+func (t *tx) delPrefix(prefix string) {
+	for k := range t.puts {
+		if strings.HasPrefix(k, prefix) {
+			delete(t.puts, k)
+		}
+	}
+	t.prefixes = append(t.prefixes, prefix)
+}
+
+type lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcquireLease implements models.Datastore
+func (s *Store) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	acquired := false
+	err := s.update(ctx, func(t *tx) error {
+		now := time.Now()
+		var l lease
+		ok, err := t.get(leaseKey(name), &l)
+		if err != nil {
+			return err
+		}
+		if ok && l.Holder != holder && l.ExpiresAt.After(now) {
+			acquired = false
+			return nil
+		}
+		acquired = true
+		return t.put(leaseKey(name), &lease{Holder: holder, ExpiresAt: now.Add(ttl)})
+	})
+	return acquired, err
+}
+
+// watchedPrefixes are the prefixes of the keys of the resources whose changes are watched,
+// by kind of change
+var watchedPrefixes = map[string]string{
+	models.ChangeApp:     appsPrefix,
+	models.ChangeFn:      fnsPrefix,
+	models.ChangeTrigger: triggersPrefix,
+	models.ChangeDomain:  domainsPrefix,
+}
+
+// watchRetryDelay is the time before a watch which was interrupted is started again
+const watchRetryDelay = time.Second
+
+// WatchChanges implements models.ChangeWatcher. A models.ChangeAll change is sent whenever
+// the watch of the KV is interrupted, since changes may have been missed.
+func (s *Store) WatchChanges(ctx context.Context) (<-chan *models.Change, error) {
+	changes := make(chan *models.Change)
+	var wg sync.WaitGroup
+	for kind, prefix := range watchedPrefixes {
+		events, err := s.kv.Watch(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func(kind, prefix string, events <-chan Event) {
+			defer wg.Done()
+			s.watch(ctx, kind, prefix, events, changes)
+		}(kind, prefix, events)
+	}
+	go func() {
+		wg.Wait()
+		close(changes)
+	}()
+	return changes, nil
+}
+
+func (s *Store) watch(ctx context.Context, kind, prefix string, events <-chan Event, changes chan<- *models.Change) {
+	send := func(c *models.Change) bool {
+		select {
+		case changes <- c:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		for ev := range events {
+			if !send(&models.Change{Kind: kind, ID: strings.TrimPrefix(ev.Key, prefix), Deleted: ev.Deleted}) {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		for {
+			select {
+			case <-time.After(watchRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+			var err error
+			events, err = s.kv.Watch(ctx, prefix)
+			if err == nil {
+				break
+			}
+			common.Logger(ctx).WithError(err).Error("failed to watch datastore changes")
+		}
+		if !send(&models.Change{Kind: models.ChangeAll}) {
+			return
+		}
+	}
+}
+
+// Close implements models.Datastore
+func (s *Store) Close() error {
+	return s.kv.Close()
+}
@@ -0,0 +1,124 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		return datastoreutil.NewValidator(New(NewMemory()))
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+func TestWatchChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := New(NewMemory())
+	changes, err := ds.WatchChanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() *models.Change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return nil
+		}
+	}
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || c.ID != app.ID || c.Deleted {
+		t.Fatalf("expected the app to be changed, got %+v", c)
+	}
+
+	if err := ds.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || c.ID != app.ID || !c.Deleted {
+		t.Fatalf("expected the app to be deleted, got %+v", c)
+	}
+
+	// changes are sent until the watch is cancelled
+	cancel()
+	for range changes {
+	}
+}
+
+func TestAnnotationIndex(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+
+	// resources stored by a previous version are not indexed
+	app := &models.App{ID: "app1", Name: "myapp"}
+	app.Annotations, _ = models.EmptyAnnotations().With("team", "blue")
+	b, err := json.Marshal(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mem.Commit(ctx, &Txn{Puts: map[string][]byte{appKey(app.ID): b, appNameKey(app.Name): []byte(`"app1"`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := Open(ctx, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blue := &models.AppFilter{PerPage: 10, Annotations: []models.KeyFilter{{Key: "team", Value: "blue", HasValue: true}}}
+	expectApps := func(filter *models.AppFilter, expected ...string) {
+		t.Helper()
+		apps, err := ds.GetApps(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, a := range apps.Items {
+			names = append(names, a.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Fatalf("expected apps %v, got %v", expected, names)
+		}
+	}
+	expectApps(blue, "myapp")
+
+	// the index follows the changes of the annotations
+	other := &models.App{Name: "other"}
+	other.Annotations, _ = models.EmptyAnnotations().With("team", "blue")
+	if other, err = ds.InsertApp(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	expectApps(blue, "myapp", "other")
+	patch := &models.App{ID: app.ID}
+	patch.Annotations, _ = models.EmptyAnnotations().With("team", "red")
+	if _, err := ds.UpdateApp(ctx, patch); err != nil {
+		t.Fatal(err)
+	}
+	expectApps(blue, "other")
+	expectApps(&models.AppFilter{PerPage: 10, Annotations: []models.KeyFilter{{Key: "team"}}}, "myapp", "other")
+	if err := ds.RemoveApp(ctx, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectApps(blue)
+	entries, _, err := mem.List(ctx, "index/annotations/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the index keys of the removed app to be removed, got %d keys", len(entries))
+	}
+}
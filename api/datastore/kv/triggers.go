@@ -0,0 +1,264 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) getTrigger(triggerID string) (*models.Trigger, error) {
+	var trigger models.Trigger
+	ok, err := t.get(triggerKey(triggerID), &trigger)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrTriggerNotFound
+	}
+	return &trigger, nil
+}
+
+func (t *tx) triggers() ([]*models.Trigger, error) {
+	values, err := t.list(triggersPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTriggers(values)
+}
+
+// annotatedTriggers returns the triggers with the annotations of filters, all of them without filters
+func (t *tx) annotatedTriggers(filters []models.KeyFilter) ([]*models.Trigger, error) {
+	if len(filters) == 0 {
+		return t.triggers()
+	}
+	values, err := t.annotated(triggersPrefix, filters)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTriggers(values)
+}
+
+func decodeTriggers(values [][]byte) ([]*models.Trigger, error) {
+	triggers := make([]*models.Trigger, 0, len(values))
+	for _, v := range values {
+		var trigger models.Trigger
+		if err := json.Unmarshal(v, &trigger); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, &trigger)
+	}
+	return triggers, nil
+}
+
+// putTrigger stores trigger and its index keys, failing if its name or source are taken
+func (t *tx) putTrigger(trigger *models.Trigger) error {
+	var triggerID string
+	ok, err := t.get(triggerNameKey(trigger.AppID, trigger.FnID, trigger.Name), &triggerID)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return models.ErrTriggerExists
+	}
+	ok, err = t.get(triggerSourceKey(trigger.AppID, trigger.Type, trigger.Source), &triggerID)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return models.ErrTriggerSourceExists
+	}
+
+	if err := t.put(triggerNameKey(trigger.AppID, trigger.FnID, trigger.Name), trigger.ID); err != nil {
+		return err
+	}
+	if err := t.put(triggerSourceKey(trigger.AppID, trigger.Type, trigger.Source), trigger.ID); err != nil {
+		return err
+	}
+	return t.put(triggerKey(trigger.ID), trigger)
+}
+
+func (t *tx) removeTrigger(trigger *models.Trigger) {
+	t.del(triggerKey(trigger.ID))
+	t.del(triggerNameKey(trigger.AppID, trigger.FnID, trigger.Name))
+	t.del(triggerSourceKey(trigger.AppID, trigger.Type, trigger.Source))
+}
+
+// InsertTrigger implements models.Datastore
+func (s *Store) InsertTrigger(ctx context.Context, newTrigger *models.Trigger) (*models.Trigger, error) {
+	trigger := newTrigger.Clone()
+	trigger.CreatedAt = common.DateTime(time.Now())
+	trigger.UpdatedAt = trigger.CreatedAt
+	trigger.ID = id.New().String()
+	if err := trigger.Validate(); err != nil {
+		return nil, err
+	}
+
+	var res *models.Trigger
+	err := s.update(ctx, func(t *tx) error {
+		// the hook runs again on the trigger as inserted if the transaction is retried
+		res = trigger.Clone()
+		if err := models.RunPersistHook(ctx, res, true); err != nil {
+			return err
+		}
+		if _, err := t.getLiveApp(res.AppID); err != nil {
+			return err
+		}
+		fn, err := t.getLiveFn(res.FnID)
+		if err != nil {
+			return err
+		}
+		if fn.AppID != res.AppID {
+			return models.ErrTriggerFnIDNotSameApp
+		}
+		return t.putTrigger(res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateTrigger implements models.Datastore
+func (s *Store) UpdateTrigger(ctx context.Context, patch *models.Trigger) (*models.Trigger, error) {
+	var trigger *models.Trigger
+	err := s.update(ctx, func(t *tx) error {
+		var err error
+		trigger, err = t.getTrigger(patch.ID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, trigger); err != nil {
+			return err
+		}
+		// the index keys are moved if the patch changes the name or source of the trigger
+		t.removeTrigger(trigger)
+		trigger.Update(patch)
+		if err := trigger.Validate(); err != nil {
+			return err
+		}
+		if err := models.RunPersistHook(ctx, trigger, false); err != nil {
+			return err
+		}
+		return t.putTrigger(trigger)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
+
+// RemoveTrigger implements models.Datastore
+func (s *Store) RemoveTrigger(ctx context.Context, triggerID string) error {
+	return s.update(ctx, func(t *tx) error {
+		trigger, err := t.getTrigger(triggerID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckIfMatch(ctx, trigger); err != nil {
+			return err
+		}
+		t.removeTrigger(trigger)
+		return nil
+	})
+}
+
+// GetTriggerByID implements models.Datastore
+func (s *Store) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
+	var trigger *models.Trigger
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		trigger, err = t.getTrigger(triggerID)
+		if err != nil {
+			return err
+		}
+		// the triggers of deleted fns are not called until the fns are restored
+		if _, err := t.getLiveFn(trigger.FnID); err == models.ErrFnsNotFound {
+			return models.ErrTriggerNotFound
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	return trigger, err
+}
+
+// GetTriggerBySource implements models.Datastore
+func (s *Store) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	var trigger *models.Trigger
+	err := s.view(ctx, func(t *tx) error {
+		var triggerID string
+		ok, err := t.get(triggerSourceKey(appID, triggerType, source), &triggerID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrTriggerNotFound
+		}
+		trigger, err = t.getTrigger(triggerID)
+		if err != nil {
+			return err
+		}
+		// the triggers of deleted fns are not called until the fns are restored
+		if _, err := t.getLiveFn(trigger.FnID); err == models.ErrFnsNotFound {
+			return models.ErrTriggerNotFound
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+	return trigger, err
+}
+
+// GetTriggers implements models.Datastore
+func (s *Store) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
+	order := filter.Order
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*models.Trigger
+	err = s.view(ctx, func(t *tx) error {
+		all, err = t.annotatedTriggers(filter.Annotations)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
+
+	res := []*models.Trigger{}
+	for _, t := range all {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if after(order, cursor, order.Value(t.Name, t.CreatedAt, t.UpdatedAt), t.ID) &&
+			t.AppID == filter.AppID &&
+			(filter.FnID == "" || filter.FnID == t.FnID) &&
+			(filter.Name == "" || filter.Name == t.Name) &&
+			strings.HasPrefix(t.Name, filter.NamePrefix) &&
+			models.MatchKeyFilters(filter.Annotations, nil, nil, t.Annotations) {
+			res = append(res, t)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := res[len(res)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
+	}
+
+	return &models.TriggerList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
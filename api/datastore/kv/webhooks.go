@@ -0,0 +1,193 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+func (t *tx) getWebhook(webhookID string) (*models.Webhook, error) {
+	var w models.Webhook
+	ok, err := t.get(webhookKey(webhookID), &w)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrWebhookNotFound
+	}
+	return &w, nil
+}
+
+func (t *tx) webhooks() ([]*models.Webhook, error) {
+	values, err := t.list(webhooksPrefix)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]*models.Webhook, 0, len(values))
+	for _, v := range values {
+		var w models.Webhook
+		if err := json.Unmarshal(v, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, nil
+}
+
+// removeWebhook removes a webhook along with its deliveries
+func (t *tx) removeWebhook(webhookID string) {
+	t.del(webhookKey(webhookID))
+	t.delPrefix(webhookDeliveriesKeyPrefix(webhookID))
+}
+
+// InsertWebhook implements models.Datastore
+func (s *Store) InsertWebhook(ctx context.Context, newWebhook *models.Webhook) (*models.Webhook, error) {
+	w := *newWebhook
+	w.ID = id.New().String()
+	w.CreatedAt = common.DateTime(time.Now())
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.update(ctx, func(t *tx) error {
+		if w.AppID != "" {
+			if _, err := t.getLiveApp(w.AppID); err != nil {
+				return err
+			}
+		}
+		return t.put(webhookKey(w.ID), &w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetWebhookByID implements models.Datastore
+func (s *Store) GetWebhookByID(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	var w *models.Webhook
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		w, err = t.getWebhook(webhookID)
+		return err
+	})
+	return w, err
+}
+
+// GetWebhooks implements models.Datastore
+func (s *Store) GetWebhooks(ctx context.Context, filter *models.WebhookFilter) (*models.WebhookList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var webhooks []*models.Webhook
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		webhooks, err = t.webhooks()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// webhooks are listed by ID
+	res := []*models.Webhook{}
+	for _, w := range webhooks {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || w.AppID == filter.AppID) && (cursor == "" || w.ID > cursor) {
+			res = append(res, w)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.WebhookList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+// RemoveWebhook implements models.Datastore
+func (s *Store) RemoveWebhook(ctx context.Context, webhookID string) error {
+	return s.update(ctx, func(t *tx) error {
+		if _, err := t.getWebhook(webhookID); err != nil {
+			return err
+		}
+		t.removeWebhook(webhookID)
+		return nil
+	})
+}
+
+// InsertWebhookDelivery implements models.Datastore
+func (s *Store) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.update(ctx, func(t *tx) error {
+		if _, err := t.getWebhook(delivery.WebhookID); err != nil {
+			return err
+		}
+		return t.put(webhookDeliveriesKeyPrefix(delivery.WebhookID)+delivery.ID, delivery)
+	})
+}
+
+// GetWebhookDeliveries implements models.Datastore
+func (s *Store) GetWebhookDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var values [][]byte
+	err := s.view(ctx, func(t *tx) error {
+		var err error
+		values, err = t.list(webhookDeliveriesKeyPrefix(filter.WebhookID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// deliveries are listed by ID, most recent first
+	res := []*models.WebhookDelivery{}
+	for i := len(values) - 1; i >= 0; i-- {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		var d models.WebhookDelivery
+		if err := json.Unmarshal(values[i], &d); err != nil {
+			return nil, err
+		}
+		if cursor == "" || d.ID < cursor {
+			res = append(res, &d)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.WebhookDeliveryList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
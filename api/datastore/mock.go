@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
@@ -12,13 +14,27 @@ import (
 	"github.com/fnproject/fn/api/id"
 	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
-	"github.com/sirupsen/logrus"
 )
 
 type mock struct {
-	Apps     []*models.App
-	Fns      []*models.Fn
-	Triggers []*models.Trigger
+	Apps         []*models.App
+	Fns          []*models.Fn
+	Triggers     []*models.Trigger
+	Schedules    []*models.Schedule
+	ScheduleRuns []*models.ScheduleRun
+	FnRevisions  []*models.FnRevision
+	APIKeys      []*models.APIKey
+	RoleBindings []*models.RoleBinding
+	AppUsage     []*models.AppUsage
+	Domains      []*models.Domain
+	Leases       map[string]mockLease
+
+	// webhooks are used by deliveries in the background
+	hooksLock         sync.Mutex
+	Webhooks          []*models.Webhook
+	WebhookDeliveries []*models.WebhookDelivery
+
+	AuditEntries []*models.AuditEntry
 
 	models.LogStore
 }
@@ -31,7 +47,7 @@ var _ models.Datastore = &mock{}
 
 func (m *mock) GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*models.Trigger, error) {
 	for _, t := range m.Triggers {
-		if t.AppID == appId && t.Type == triggerType && t.Source == source {
+		if t.AppID == appId && t.Type == triggerType && t.Source == source && m.liveFn(t.FnID) {
 			return t, nil
 		}
 	}
@@ -39,6 +55,16 @@ func (m *mock) GetTriggerBySource(ctx context.Context, appId string, triggerType
 	return nil, models.ErrTriggerNotFound
 }
 
+// liveFn returns true if fnID is a fn which is not deleted
+func (m *mock) liveFn(fnID string) bool {
+	for _, f := range m.Fns {
+		if f.ID == fnID {
+			return f.DeletedAt == nil
+		}
+	}
+	return false
+}
+
 // args helps break tests less if we change stuff
 func NewMockInit(args ...interface{}) models.Datastore {
 	var mocker mock
@@ -50,18 +76,21 @@ func NewMockInit(args ...interface{}) models.Datastore {
 			mocker.Fns = x
 		case []*models.Trigger:
 			mocker.Triggers = x
+		case []*models.Schedule:
+			mocker.Schedules = x
 
 		default:
 			panic("not accounted for data type sent to mock init. add it")
 		}
 	}
+	mocker.Leases = make(map[string]mockLease)
 	mocker.LogStore = logs.NewMock()
 	return datastoreutil.NewValidator(&mocker)
 }
 
 func (m *mock) GetAppID(ctx context.Context, appName string) (string, error) {
 	for _, a := range m.Apps {
-		if a.Name == appName {
+		if a.Name == appName && a.DeletedAt == nil {
 			return a.ID, nil
 		}
 	}
@@ -71,7 +100,7 @@ func (m *mock) GetAppID(ctx context.Context, appName string) (string, error) {
 
 func (m *mock) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
 	for _, a := range m.Apps {
-		if a.ID == appID {
+		if a.ID == appID && a.DeletedAt == nil {
 			return a.Clone(), nil
 		}
 	}
@@ -79,24 +108,35 @@ func (m *mock) GetAppByID(ctx context.Context, appID string) (*models.App, error
 	return nil, models.ErrAppsNotFound
 }
 
-type sortA []*models.App
+// after returns true if the resource with value and id follows cursor in order, or if there
+// is no cursor
+func after(order models.ListOrder, cursor *models.ListCursor, value, id string) bool {
+	return cursor == nil || order.Less(cursor.Value, cursor.ID, value, id)
+}
 
-func (s sortA) Len() int           { return len(s) }
-func (s sortA) Less(i, j int) bool { return strings.Compare(s[i].Name, s[j].Name) < 0 }
-func (s sortA) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+// decodeCursor decodes the cursor of a list in order, which is nil if there is none
+func decodeCursor(order models.ListOrder, cursor string) (*models.ListCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	c, err := order.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
 
 func (m *mock) GetApps(ctx context.Context, filter *models.AppFilter) (*models.AppList, error) {
 	// sort them all first for cursoring (this is for testing, n is small & mock is not concurrent..)
-	sort.Sort(sortA(m.Apps))
+	order := filter.Order
+	sort.Slice(m.Apps, func(i, j int) bool {
+		a, b := m.Apps[i], m.Apps[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
 
-	var cursor string
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return nil, err
-		}
-		logrus.Error(s)
-		cursor = string(s)
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
 	}
 
 	apps := []*models.App{}
@@ -104,18 +144,24 @@ func (m *mock) GetApps(ctx context.Context, filter *models.AppFilter) (*models.A
 		if len(apps) == filter.PerPage {
 			break
 		}
-		if strings.Compare(cursor, a.Name) < 0 {
+		if after(order, cursor, order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID) {
 			if filter.Name != "" && filter.Name != a.Name {
 				continue
 			}
+			if (a.DeletedAt != nil) != filter.Deleted {
+				continue
+			}
+			if !strings.HasPrefix(a.Name, filter.NamePrefix) || !models.MatchKeyFilters(filter.Annotations, filter.Config, a.Config, a.Annotations) {
+				continue
+			}
 			apps = append(apps, a.Clone())
 		}
 	}
 
 	var nextCursor string
 	if len(apps) > 0 && len(apps) == filter.PerPage {
-		last := []byte(apps[len(apps)-1].Name)
-		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := apps[len(apps)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	return &models.AppList{
@@ -125,16 +171,19 @@ func (m *mock) GetApps(ctx context.Context, filter *models.AppFilter) (*models.A
 }
 
 func (m *mock) InsertApp(ctx context.Context, newApp *models.App) (*models.App, error) {
-	for _, a := range m.Apps {
-		if newApp.Name == a.Name {
-			return nil, models.ErrAppsAlreadyExists
-		}
-	}
-
 	app := newApp.Clone()
 	app.CreatedAt = common.DateTime(time.Now())
 	app.UpdatedAt = app.CreatedAt
 	app.ID = id.New().String()
+	if err := models.RunPersistHook(ctx, app, true); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Apps {
+		if app.Name == a.Name {
+			return nil, models.ErrAppsAlreadyExists
+		}
+	}
 
 	m.Apps = append(m.Apps, app)
 	return app.Clone(), nil
@@ -144,7 +193,10 @@ func (m *mock) UpdateApp(ctx context.Context, app *models.App) (*models.App, err
 
 	appID := app.ID
 	for idx, a := range m.Apps {
-		if a.ID == appID {
+		if a.ID == appID && a.DeletedAt == nil {
+			if err := models.CheckIfMatch(ctx, a); err != nil {
+				return nil, err
+			}
 			if app.Name != "" && app.Name != a.Name {
 				return nil, models.ErrAppsNameImmutable
 			}
@@ -154,6 +206,9 @@ func (m *mock) UpdateApp(ctx context.Context, app *models.App) (*models.App, err
 			if err != nil {
 				return nil, err
 			}
+			if err := models.RunPersistHook(ctx, c, false); err != nil {
+				return nil, err
+			}
 			m.Apps[idx] = c
 			return c.Clone(), nil
 		}
@@ -166,6 +221,9 @@ func (m *mock) UpdateApp(ctx context.Context, app *models.App) (*models.App, err
 func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 	for i, a := range m.Apps {
 		if a.ID == appID {
+			if err := models.CheckIfMatch(ctx, a); err != nil {
+				return err
+			}
 			var newFns []*models.Fn
 			var newTriggers []*models.Trigger
 			newApps := append(m.Apps[0:i], m.Apps[i+1:]...)
@@ -185,6 +243,19 @@ func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 			m.Apps = newApps
 			m.Triggers = newTriggers
 			m.Fns = newFns
+			m.removeSchedules(func(s *models.Schedule) bool { return s.AppID == appID })
+			m.removeFnRevisions(func(r *models.FnRevision) bool { return r.AppID == appID })
+			m.removeAPIKeys(func(k *models.APIKey) bool { return k.AppID == appID })
+			m.removeRoleBindings(func(b *models.RoleBinding) bool { return b.AppID == appID })
+			var usage []*models.AppUsage
+			for _, u := range m.AppUsage {
+				if u.AppID != appID {
+					usage = append(usage, u)
+				}
+			}
+			m.AppUsage = usage
+			m.removeDomains(func(d *models.Domain) bool { return d.AppID == appID })
+			m.removeWebhooks(func(w *models.Webhook) bool { return w.AppID == appID })
 			return nil
 
 		}
@@ -193,6 +264,43 @@ func (m *mock) RemoveApp(ctx context.Context, appID string) error {
 	return models.ErrAppsNotFound
 }
 
+func (m *mock) SoftDeleteApp(ctx context.Context, appID string) error {
+	for _, a := range m.Apps {
+		if a.ID == appID && a.DeletedAt == nil {
+			if err := models.CheckIfMatch(ctx, a); err != nil {
+				return err
+			}
+			// the fns of the app are marked with its time, to be restored with it
+			deletedAt := common.DateTime(time.Now())
+			a.DeletedAt = &deletedAt
+			for _, f := range m.Fns {
+				if f.AppID == appID && f.DeletedAt == nil {
+					f.DeletedAt = &deletedAt
+				}
+			}
+			return nil
+		}
+	}
+
+	return models.ErrAppsNotFound
+}
+
+func (m *mock) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	for _, a := range m.Apps {
+		if a.ID == appID && a.DeletedAt != nil {
+			for _, f := range m.Fns {
+				if f.AppID == appID && f.DeletedAt != nil && time.Time(*f.DeletedAt).Equal(time.Time(*a.DeletedAt)) {
+					f.DeletedAt = nil
+				}
+			}
+			a.DeletedAt = nil
+			return a.Clone(), nil
+		}
+	}
+
+	return nil, models.ErrAppsNotFound
+}
+
 func (m *mock) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
 	_, err := m.GetAppByID(ctx, fn.AppID)
 	if err != nil {
@@ -210,11 +318,20 @@ func (m *mock) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error)
 	cl.ID = id.New().String()
 	cl.CreatedAt = common.DateTime(time.Now())
 	cl.UpdatedAt = cl.CreatedAt
+	cl.Revision = 0
+	if !cl.Canary.IsEmpty() {
+		cl.Canary.Revision = 0
+	}
 	err = fn.Validate()
 	if err != nil {
 		return nil, err
 	}
+	err = models.RunPersistHook(ctx, cl, true)
+	if err != nil {
+		return nil, err
+	}
 
+	m.recordFnRevisions(cl)
 	m.Fns = append(m.Fns, cl)
 
 	return cl.Clone(), nil
@@ -223,13 +340,20 @@ func (m *mock) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error)
 func (m *mock) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
 	// update if exists
 	for _, f := range m.Fns {
-		if f.ID == fn.ID {
+		if f.ID == fn.ID && f.DeletedAt == nil {
+			if err := models.CheckIfMatch(ctx, f); err != nil {
+				return nil, err
+			}
 			clone := f.Clone()
 			clone.Update(fn)
 			err := clone.Validate()
 			if err != nil {
 				return nil, err
 			}
+			if err := models.RunPersistHook(ctx, clone, false); err != nil {
+				return nil, err
+			}
+			m.recordFnRevisions(clone)
 			*f = *clone
 			return f, nil
 		}
@@ -238,25 +362,19 @@ func (m *mock) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error)
 	return nil, models.ErrFnsNotFound
 }
 
-type sortF []*models.Fn
-
-func (s sortF) Len() int           { return len(s) }
-func (s sortF) Less(i, j int) bool { return strings.Compare(s[i].Name, s[j].Name) < 0 }
-func (s sortF) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-
 func (m *mock) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnList, error) {
 	// sort them all first for cursoring (this is for testing, n is small & mock is not concurrent..)
-	sort.Sort(sortF(m.Fns))
+	order := filter.Order
+	sort.Slice(m.Fns, func(i, j int) bool {
+		a, b := m.Fns[i], m.Fns[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
 
 	funcs := []*models.Fn{}
 
-	var cursor string
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return nil, err
-		}
-		cursor = string(s)
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, f := range m.Fns {
@@ -264,17 +382,20 @@ func (m *mock) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnL
 			break
 		}
 
-		if strings.Compare(cursor, f.Name) < 0 &&
+		if after(order, cursor, order.Value(f.Name, f.CreatedAt, f.UpdatedAt), f.ID) &&
 			(filter.AppID == "" || filter.AppID == f.AppID) &&
-			(filter.Name == "" || filter.Name == f.Name) {
+			(filter.Name == "" || filter.Name == f.Name) &&
+			strings.HasPrefix(f.Name, filter.NamePrefix) &&
+			models.MatchKeyFilters(filter.Annotations, filter.Config, f.Config, f.Annotations) &&
+			(f.DeletedAt != nil) == filter.Deleted {
 			funcs = append(funcs, f)
 		}
 	}
 
 	var nextCursor string
 	if len(funcs) > 0 && len(funcs) == filter.PerPage {
-		last := []byte(funcs[len(funcs)-1].Name)
-		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := funcs[len(funcs)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	return &models.FnList{
@@ -285,7 +406,46 @@ func (m *mock) GetFns(ctx context.Context, filter *models.FnFilter) (*models.FnL
 
 func (m *mock) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
 	for _, f := range m.Fns {
-		if f.ID == fnID {
+		if f.ID == fnID && f.DeletedAt == nil {
+			return f, nil
+		}
+	}
+
+	return nil, models.ErrFnsNotFound
+}
+
+func (m *mock) SoftDeleteFn(ctx context.Context, fnID string) error {
+	for _, f := range m.Fns {
+		if f.ID == fnID && f.DeletedAt == nil {
+			if err := models.CheckIfMatch(ctx, f); err != nil {
+				return err
+			}
+			deletedAt := common.DateTime(time.Now())
+			f.DeletedAt = &deletedAt
+			return nil
+		}
+	}
+
+	return models.ErrFnsNotFound
+}
+
+func (m *mock) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	for _, f := range m.Fns {
+		if f.ID == fnID && f.DeletedAt != nil {
+			return f, nil
+		}
+	}
+
+	return nil, models.ErrFnsNotFound
+}
+
+func (m *mock) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	for _, f := range m.Fns {
+		if f.ID == fnID && f.DeletedAt != nil {
+			if _, err := m.GetAppByID(ctx, f.AppID); err != nil {
+				return nil, err
+			}
+			f.DeletedAt = nil
 			return f, nil
 		}
 	}
@@ -296,6 +456,9 @@ func (m *mock) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
 func (m *mock) RemoveFn(ctx context.Context, fnID string) error {
 	for i, f := range m.Fns {
 		if f.ID == fnID {
+			if err := models.CheckIfMatch(ctx, f); err != nil {
+				return err
+			}
 			m.Fns = append(m.Fns[:i], m.Fns[i+1:]...)
 			var newTriggers []*models.Trigger
 			for _, t := range m.Triggers {
@@ -305,6 +468,8 @@ func (m *mock) RemoveFn(ctx context.Context, fnID string) error {
 			}
 
 			m.Triggers = newTriggers
+			m.removeSchedules(func(s *models.Schedule) bool { return s.FnID == fnID })
+			m.removeFnRevisions(func(r *models.FnRevision) bool { return r.FnID == fnID })
 			return nil
 		}
 	}
@@ -312,6 +477,88 @@ func (m *mock) RemoveFn(ctx context.Context, fnID string) error {
 	return models.ErrFnsNotFound
 }
 
+// recordFnRevisions numbers and records the revisions of fn left to the datastore, see
+// models.Fn.Update
+func (m *mock) recordFnRevisions(fn *models.Fn) {
+	var next int64 = 1
+	for _, r := range m.FnRevisions {
+		if r.FnID == fn.ID && r.Revision >= next {
+			next = r.Revision + 1
+		}
+	}
+
+	if fn.Revision == 0 {
+		fn.Revision = next
+		next++
+		m.FnRevisions = append(m.FnRevisions, fn.CurrentRevision())
+	}
+	if !fn.Canary.IsEmpty() && fn.Canary.Revision == 0 {
+		fn.Canary.Revision = next
+		m.FnRevisions = append(m.FnRevisions, fn.CandidateRevision())
+	}
+}
+
+// removeFnRevisions removes the fn revisions matched by f
+func (m *mock) removeFnRevisions(f func(*models.FnRevision) bool) {
+	var newRevisions []*models.FnRevision
+	for _, r := range m.FnRevisions {
+		if !f(r) {
+			newRevisions = append(newRevisions, r)
+		}
+	}
+	m.FnRevisions = newRevisions
+}
+
+func (m *mock) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	for _, r := range m.FnRevisions {
+		if r.FnID == fnID && r.Revision == revision {
+			cl := *r
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrFnRevisionNotFound
+}
+
+func (m *mock) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	var cursor int64
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor, err = strconv.ParseInt(string(s), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	revisions := make([]*models.FnRevision, len(m.FnRevisions))
+	copy(revisions, m.FnRevisions)
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+
+	res := []*models.FnRevision{}
+	for _, r := range revisions {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if r.FnID == filter.FnID && (filter.Cursor == "" || r.Revision < cursor) {
+			cl := *r
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(strconv.FormatInt(res[len(res)-1].Revision, 10))
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.FnRevisionList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
 func (m *mock) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
 	_, err := m.GetAppByID(ctx, trigger.AppID)
 	if err != nil {
@@ -350,6 +597,10 @@ func (m *mock) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*mod
 	if err != nil {
 		return nil, err
 	}
+	err = models.RunPersistHook(ctx, cl, true)
+	if err != nil {
+		return nil, err
+	}
 	m.Triggers = append(m.Triggers, cl)
 	return cl.Clone(), nil
 }
@@ -357,12 +608,18 @@ func (m *mock) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*mod
 func (m *mock) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
 	for _, t := range m.Triggers {
 		if t.ID == trigger.ID {
+			if err := models.CheckIfMatch(ctx, t); err != nil {
+				return nil, err
+			}
 			cl := t.Clone()
 			cl.Update(trigger)
 			err := cl.Validate()
 			if err != nil {
 				return nil, err
 			}
+			if err := models.RunPersistHook(ctx, cl, false); err != nil {
+				return nil, err
+			}
 			*t = *cl
 			return cl.Clone(), nil
 		}
@@ -388,22 +645,16 @@ func (m *mock) GetTriggerByID(ctx context.Context, triggerId string) (*models.Tr
 	return nil, models.ErrTriggerNotFound
 }
 
-type sortT []*models.Trigger
-
-func (s sortT) Len() int           { return len(s) }
-func (s sortT) Less(i, j int) bool { return strings.Compare(s[i].Name, s[j].Name) < 0 }
-func (s sortT) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-
 func (m *mock) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*models.TriggerList, error) {
-	sort.Sort(sortT(m.Triggers))
+	order := filter.Order
+	sort.Slice(m.Triggers, func(i, j int) bool {
+		a, b := m.Triggers[i], m.Triggers[j]
+		return order.Less(order.Value(a.Name, a.CreatedAt, a.UpdatedAt), a.ID, order.Value(b.Name, b.CreatedAt, b.UpdatedAt), b.ID)
+	})
 
-	var cursor string
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return nil, err
-		}
-		cursor = string(s)
+	cursor, err := decodeCursor(order, filter.Cursor)
+	if err != nil {
+		return nil, err
 	}
 
 	res := []*models.Trigger{}
@@ -413,7 +664,7 @@ func (m *mock) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*
 		}
 
 		matched := true
-		if filter.Cursor != "" && t.Name <= cursor {
+		if !after(order, cursor, order.Value(t.Name, t.CreatedAt, t.UpdatedAt), t.ID) {
 			matched = false
 		}
 
@@ -428,6 +679,10 @@ func (m *mock) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*
 			matched = false
 		}
 
+		if !strings.HasPrefix(t.Name, filter.NamePrefix) || !models.MatchKeyFilters(filter.Annotations, nil, nil, t.Annotations) {
+			matched = false
+		}
+
 		if matched {
 			res = append(res, t)
 		}
@@ -435,8 +690,8 @@ func (m *mock) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*
 
 	var nextCursor string
 	if len(res) > 0 && len(res) == filter.PerPage {
-		last := []byte(res[len(res)-1].Name)
-		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := res[len(res)-1]
+		nextCursor = order.EncodeCursor(order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	return &models.TriggerList{
@@ -448,6 +703,9 @@ func (m *mock) GetTriggers(ctx context.Context, filter *models.TriggerFilter) (*
 func (m *mock) RemoveTrigger(ctx context.Context, triggerID string) error {
 	for i, t := range m.Triggers {
 		if t.ID == triggerID {
+			if err := models.CheckIfMatch(ctx, t); err != nil {
+				return err
+			}
 			m.Triggers = append(m.Triggers[:i], m.Triggers[i+1:]...)
 			return nil
 		}
@@ -455,6 +713,956 @@ func (m *mock) RemoveTrigger(ctx context.Context, triggerID string) error {
 	return models.ErrTriggerNotFound
 }
 
+func (m *mock) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	state := m.snapshot()
+	applied := make([]*models.BatchItem, 0, len(items))
+	for i, item := range items {
+		res, err := m.applyBatchItem(ctx, item)
+		if err != nil {
+			m.restore(state)
+			return nil, &models.BatchError{Index: i, Err: err}
+		}
+		applied = append(applied, res)
+	}
+	return applied, nil
+}
+
+// mockTxKey is the key of the contexts of the operations made in a transaction of the mock
+type mockTxKey struct{}
+
+func (m *mock) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	if ctx.Value(mockTxKey{}) != nil {
+		return f(ctx)
+	}
+	state := m.snapshot()
+	ctx, scope := models.WithTxScope(ctx)
+	if err := f(context.WithValue(ctx, mockTxKey{}, true)); err != nil {
+		m.restore(state)
+		return err
+	}
+	scope.Committed()
+	return nil
+}
+
+func (m *mock) applyBatchItem(ctx context.Context, item *models.BatchItem) (*models.BatchItem, error) {
+	res := &models.BatchItem{Action: item.Action, SoftDelete: item.SoftDelete}
+	var err error
+
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.App, err = m.InsertApp(ctx, item.App)
+		case models.BatchUpdate:
+			res.App, err = m.UpdateApp(ctx, item.App)
+		case models.BatchDelete:
+			res.App, err = m.GetAppByID(ctx, item.App.ID)
+			if err == nil && item.SoftDelete {
+				err = m.SoftDeleteApp(ctx, item.App.ID)
+			} else if err == nil {
+				err = m.RemoveApp(ctx, item.App.ID)
+			}
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Fn, err = m.InsertFn(ctx, item.Fn)
+		case models.BatchUpdate:
+			res.Fn, err = m.UpdateFn(ctx, item.Fn)
+		case models.BatchDelete:
+			res.Fn, err = m.GetFnByID(ctx, item.Fn.ID)
+			if err == nil {
+				res.Fn = res.Fn.Clone()
+				if item.SoftDelete {
+					err = m.SoftDeleteFn(ctx, item.Fn.ID)
+				} else {
+					err = m.RemoveFn(ctx, item.Fn.ID)
+				}
+			}
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Trigger, err = m.InsertTrigger(ctx, item.Trigger)
+		case models.BatchUpdate:
+			res.Trigger, err = m.UpdateTrigger(ctx, item.Trigger)
+		case models.BatchDelete:
+			res.Trigger, err = m.GetTriggerByID(ctx, item.Trigger.ID)
+			if err == nil {
+				err = m.RemoveTrigger(ctx, item.Trigger.ID)
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// mockState is a copy of the resources a batch may change, to roll it back
+type mockState struct {
+	apps              []*models.App
+	fns               []*models.Fn
+	triggers          []*models.Trigger
+	schedules         []*models.Schedule
+	scheduleRuns      []*models.ScheduleRun
+	fnRevisions       []*models.FnRevision
+	apiKeys           []*models.APIKey
+	roleBindings      []*models.RoleBinding
+	appUsage          []*models.AppUsage
+	domains           []*models.Domain
+	webhooks          []*models.Webhook
+	webhookDeliveries []*models.WebhookDelivery
+}
+
+// snapshot copies the resources a batch may change, apps, fns and triggers are updated in
+// place so they are cloned, the others are only ever removed.
+func (m *mock) snapshot() *mockState {
+	s := &mockState{
+		schedules:    append([]*models.Schedule(nil), m.Schedules...),
+		scheduleRuns: append([]*models.ScheduleRun(nil), m.ScheduleRuns...),
+		fnRevisions:  append([]*models.FnRevision(nil), m.FnRevisions...),
+		apiKeys:      append([]*models.APIKey(nil), m.APIKeys...),
+		roleBindings: append([]*models.RoleBinding(nil), m.RoleBindings...),
+		appUsage:     append([]*models.AppUsage(nil), m.AppUsage...),
+		domains:      append([]*models.Domain(nil), m.Domains...),
+	}
+	for _, a := range m.Apps {
+		s.apps = append(s.apps, a.Clone())
+	}
+	for _, f := range m.Fns {
+		s.fns = append(s.fns, f.Clone())
+	}
+	for _, t := range m.Triggers {
+		s.triggers = append(s.triggers, t.Clone())
+	}
+
+	m.hooksLock.Lock()
+	s.webhooks = append([]*models.Webhook(nil), m.Webhooks...)
+	s.webhookDeliveries = append([]*models.WebhookDelivery(nil), m.WebhookDeliveries...)
+	m.hooksLock.Unlock()
+	return s
+}
+
+func (m *mock) restore(s *mockState) {
+	m.Apps = s.apps
+	m.Fns = s.fns
+	m.Triggers = s.triggers
+	m.Schedules = s.schedules
+	m.ScheduleRuns = s.scheduleRuns
+	m.FnRevisions = s.fnRevisions
+	m.APIKeys = s.apiKeys
+	m.RoleBindings = s.roleBindings
+	m.AppUsage = s.appUsage
+	m.Domains = s.domains
+
+	m.hooksLock.Lock()
+	m.Webhooks = s.webhooks
+	m.WebhookDeliveries = s.webhookDeliveries
+	m.hooksLock.Unlock()
+}
+
+type mockLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// removeSchedules removes the schedules matched by f, and their runs
+func (m *mock) removeSchedules(f func(*models.Schedule) bool) {
+	var newSchedules []*models.Schedule
+	removed := make(map[string]bool)
+	for _, s := range m.Schedules {
+		if f(s) {
+			removed[s.ID] = true
+		} else {
+			newSchedules = append(newSchedules, s)
+		}
+	}
+
+	var newRuns []*models.ScheduleRun
+	for _, r := range m.ScheduleRuns {
+		if !removed[r.ScheduleID] {
+			newRuns = append(newRuns, r)
+		}
+	}
+	m.Schedules = newSchedules
+	m.ScheduleRuns = newRuns
+}
+
+func (m *mock) InsertSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	_, err := m.GetAppByID(ctx, schedule.AppID)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := m.GetFnByID(ctx, schedule.FnID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.AppID != schedule.AppID {
+		return nil, models.ErrScheduleFnIDNotSameApp
+	}
+
+	for _, s := range m.Schedules {
+		if s.AppID == schedule.AppID && s.Name == schedule.Name {
+			return nil, models.ErrScheduleExists
+		}
+	}
+
+	cl := schedule.Clone()
+	cl.CreatedAt = common.DateTime(time.Now())
+	cl.UpdatedAt = cl.CreatedAt
+	cl.ID = id.New().String()
+
+	err = cl.Validate()
+	if err != nil {
+		return nil, err
+	}
+	next, err := cl.NextRun(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	cl.NextRunAt = common.DateTime(next)
+
+	m.Schedules = append(m.Schedules, cl)
+	return cl.Clone(), nil
+}
+
+func (m *mock) UpdateSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	for _, s := range m.Schedules {
+		if s.ID == schedule.ID {
+			cl := s.Clone()
+			cl.Update(schedule)
+			err := cl.Validate()
+			if err != nil {
+				return nil, err
+			}
+			fn, err := m.GetFnByID(ctx, cl.FnID)
+			if err != nil {
+				return nil, err
+			}
+			if fn.AppID != cl.AppID {
+				return nil, models.ErrScheduleFnIDNotSameApp
+			}
+			for _, o := range m.Schedules {
+				if o.ID != cl.ID && o.AppID == cl.AppID && o.Name == cl.Name {
+					return nil, models.ErrScheduleExists
+				}
+			}
+			*s = *cl
+			return cl.Clone(), nil
+		}
+	}
+	return nil, models.ErrScheduleNotFound
+}
+
+func (m *mock) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	for _, s := range m.Schedules {
+		if s.ID == scheduleID {
+			m.removeSchedules(func(s *models.Schedule) bool { return s.ID == scheduleID })
+			return nil
+		}
+	}
+	return models.ErrScheduleNotFound
+}
+
+func (m *mock) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	for _, s := range m.Schedules {
+		if s.ID == scheduleID {
+			return s.Clone(), nil
+		}
+	}
+	return nil, models.ErrScheduleNotFound
+}
+
+type sortS []*models.Schedule
+
+func (s sortS) Len() int           { return len(s) }
+func (s sortS) Less(i, j int) bool { return strings.Compare(s[i].Name, s[j].Name) < 0 }
+func (s sortS) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (m *mock) GetSchedules(ctx context.Context, filter *models.ScheduleFilter) (*models.ScheduleList, error) {
+	sort.Sort(sortS(m.Schedules))
+
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	res := []*models.Schedule{}
+	for _, s := range m.Schedules {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+
+		if (filter.Cursor == "" || s.Name > cursor) &&
+			s.AppID == filter.AppID &&
+			(filter.FnID == "" || filter.FnID == s.FnID) &&
+			(filter.Name == "" || filter.Name == s.Name) {
+			res = append(res, s.Clone())
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].Name)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.ScheduleList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) GetDueSchedules(ctx context.Context, t time.Time, limit int) ([]*models.Schedule, error) {
+	var due []*models.Schedule
+	for _, s := range m.Schedules {
+		if !time.Time(s.NextRunAt).After(t) && m.liveFn(s.FnID) {
+			due = append(due, s.Clone())
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return time.Time(due[i].NextRunAt).Before(time.Time(due[j].NextRunAt))
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (m *mock) AdvanceSchedule(ctx context.Context, scheduleID string, due, next time.Time) error {
+	for _, s := range m.Schedules {
+		if s.ID == scheduleID && time.Time(s.NextRunAt).Equal(due) {
+			s.NextRunAt = common.DateTime(next)
+			return nil
+		}
+	}
+	return models.ErrScheduleNotDue
+}
+
+func (m *mock) InsertScheduleRun(ctx context.Context, run *models.ScheduleRun) error {
+	cl := *run
+	m.ScheduleRuns = append(m.ScheduleRuns, &cl)
+	return nil
+}
+
+func (m *mock) GetScheduleRuns(ctx context.Context, filter *models.ScheduleRunFilter) (*models.ScheduleRunList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	runs := make([]*models.ScheduleRun, len(m.ScheduleRuns))
+	copy(runs, m.ScheduleRuns)
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+
+	res := []*models.ScheduleRun{}
+	for _, r := range runs {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if r.ScheduleID == filter.ScheduleID && (filter.Cursor == "" || r.ID < cursor) {
+			cl := *r
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.ScheduleRunList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) InsertAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	if key.AppID != "" {
+		if _, err := m.GetAppByID(ctx, key.AppID); err != nil {
+			return nil, err
+		}
+	}
+
+	cl := *key
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	if err := cl.Validate(); err != nil {
+		return nil, err
+	}
+	m.APIKeys = append(m.APIKeys, &cl)
+	res := cl
+	return &res, nil
+}
+
+func (m *mock) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	keys := make([]*models.APIKey, len(m.APIKeys))
+	copy(keys, m.APIKeys)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	res := []*models.APIKey{}
+	for _, k := range keys {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || k.AppID == filter.AppID) && (cursor == "" || k.ID > cursor) {
+			cl := *k
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.APIKeyList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	for _, k := range m.APIKeys {
+		if k.Hash == hash {
+			cl := *k
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrAPIKeyNotFound
+}
+
+func (m *mock) RemoveAPIKey(ctx context.Context, keyID string) error {
+	for _, k := range m.APIKeys {
+		if k.ID == keyID {
+			m.removeAPIKeys(func(k *models.APIKey) bool { return k.ID == keyID })
+			return nil
+		}
+	}
+	return models.ErrAPIKeyNotFound
+}
+
+// removeAPIKeys removes the API keys matched by f
+func (m *mock) removeAPIKeys(f func(*models.APIKey) bool) {
+	var newKeys []*models.APIKey
+	for _, k := range m.APIKeys {
+		if !f(k) {
+			newKeys = append(newKeys, k)
+		}
+	}
+	m.APIKeys = newKeys
+}
+
+func (m *mock) InsertRoleBinding(ctx context.Context, binding *models.RoleBinding) (*models.RoleBinding, error) {
+	if binding.AppID != "" {
+		if _, err := m.GetAppByID(ctx, binding.AppID); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range m.RoleBindings {
+		if b.Principal == binding.Principal && b.AppID == binding.AppID && b.Role == binding.Role {
+			return nil, models.ErrRoleBindingExists
+		}
+	}
+
+	cl := *binding
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	if err := cl.Validate(); err != nil {
+		return nil, err
+	}
+	m.RoleBindings = append(m.RoleBindings, &cl)
+	res := cl
+	return &res, nil
+}
+
+func (m *mock) GetRoleBindingByID(ctx context.Context, bindingID string) (*models.RoleBinding, error) {
+	for _, b := range m.RoleBindings {
+		if b.ID == bindingID {
+			cl := *b
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrRoleBindingNotFound
+}
+
+func (m *mock) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	bindings := make([]*models.RoleBinding, len(m.RoleBindings))
+	copy(bindings, m.RoleBindings)
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].ID < bindings[j].ID })
+
+	res := []*models.RoleBinding{}
+	for _, b := range bindings {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || b.AppID == filter.AppID) &&
+			(filter.Principal == "" || b.Principal == filter.Principal) &&
+			(cursor == "" || b.ID > cursor) {
+			cl := *b
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.RoleBindingList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	for _, b := range m.RoleBindings {
+		if b.ID == bindingID {
+			m.removeRoleBindings(func(b *models.RoleBinding) bool { return b.ID == bindingID })
+			return nil
+		}
+	}
+	return models.ErrRoleBindingNotFound
+}
+
+// removeRoleBindings removes the role bindings matched by f
+func (m *mock) removeRoleBindings(f func(*models.RoleBinding) bool) {
+	var newBindings []*models.RoleBinding
+	for _, b := range m.RoleBindings {
+		if !f(b) {
+			newBindings = append(newBindings, b)
+		}
+	}
+	m.RoleBindings = newBindings
+}
+
+func (m *mock) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	for _, u := range m.AppUsage {
+		if u.AppID == usage.AppID && u.Period == usage.Period {
+			u.Calls += usage.Calls
+			u.MBMilliseconds += usage.MBMilliseconds
+			return nil
+		}
+	}
+	m.AppUsage = append(m.AppUsage, &models.AppUsage{
+		AppID:          usage.AppID,
+		Period:         usage.Period,
+		Calls:          usage.Calls,
+		MBMilliseconds: usage.MBMilliseconds,
+	})
+	return nil
+}
+
+func (m *mock) GetAppUsage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	usage := models.AppUsage{AppID: appID, Period: period}
+	for _, u := range m.AppUsage {
+		if u.AppID == appID && u.Period == period {
+			usage = *u
+		}
+	}
+	usage.SetGBSeconds()
+	return &usage, nil
+}
+
+func (m *mock) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	if _, err := m.GetAppByID(ctx, domain.AppID); err != nil {
+		return nil, err
+	}
+	for _, d := range m.Domains {
+		if d.Hostname == domain.Hostname && d.PathPrefix == domain.PathPrefix {
+			return nil, models.ErrDomainExists
+		}
+		if d.Hostname == domain.Hostname && d.AppID != domain.AppID {
+			return nil, models.ErrDomainHostnameTaken
+		}
+	}
+
+	cl := *domain
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	if err := cl.Validate(); err != nil {
+		return nil, err
+	}
+	m.Domains = append(m.Domains, &cl)
+	res := cl
+	return &res, nil
+}
+
+func (m *mock) GetDomainByID(ctx context.Context, domainID string) (*models.Domain, error) {
+	for _, d := range m.Domains {
+		if d.ID == domainID {
+			cl := *d
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrDomainNotFound
+}
+
+func (m *mock) GetDomains(ctx context.Context, filter *models.DomainFilter) (*models.DomainList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	domains := make([]*models.Domain, len(m.Domains))
+	copy(domains, m.Domains)
+	sort.Slice(domains, func(i, j int) bool { return domains[i].ID < domains[j].ID })
+
+	res := []*models.Domain{}
+	for _, d := range domains {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || d.AppID == filter.AppID) &&
+			(filter.Hostname == "" || d.Hostname == filter.Hostname) &&
+			(cursor == "" || d.ID > cursor) {
+			cl := *d
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.DomainList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	res, err := m.GetDomains(ctx, &models.DomainFilter{Hostname: hostname})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (m *mock) RemoveDomain(ctx context.Context, domainID string) error {
+	for _, d := range m.Domains {
+		if d.ID == domainID {
+			m.removeDomains(func(d *models.Domain) bool { return d.ID == domainID })
+			return nil
+		}
+	}
+	return models.ErrDomainNotFound
+}
+
+// removeDomains removes the domains matched by f
+func (m *mock) removeDomains(f func(*models.Domain) bool) {
+	var newDomains []*models.Domain
+	for _, d := range m.Domains {
+		if !f(d) {
+			newDomains = append(newDomains, d)
+		}
+	}
+	m.Domains = newDomains
+}
+
+func (m *mock) InsertWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	if webhook.AppID != "" {
+		if _, err := m.GetAppByID(ctx, webhook.AppID); err != nil {
+			return nil, err
+		}
+	}
+
+	cl := *webhook
+	cl.ID = id.New().String()
+	cl.CreatedAt = common.DateTime(time.Now())
+	if err := cl.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.hooksLock.Lock()
+	m.Webhooks = append(m.Webhooks, &cl)
+	m.hooksLock.Unlock()
+	res := cl
+	return &res, nil
+}
+
+func (m *mock) GetWebhookByID(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	m.hooksLock.Lock()
+	defer m.hooksLock.Unlock()
+
+	for _, w := range m.Webhooks {
+		if w.ID == webhookID {
+			cl := *w
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrWebhookNotFound
+}
+
+func (m *mock) GetWebhooks(ctx context.Context, filter *models.WebhookFilter) (*models.WebhookList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	m.hooksLock.Lock()
+	webhooks := make([]*models.Webhook, len(m.Webhooks))
+	copy(webhooks, m.Webhooks)
+	m.hooksLock.Unlock()
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+
+	res := []*models.Webhook{}
+	for _, w := range webhooks {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if (filter.AppID == "" || w.AppID == filter.AppID) && (cursor == "" || w.ID > cursor) {
+			cl := *w
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.WebhookList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) RemoveWebhook(ctx context.Context, webhookID string) error {
+	if _, err := m.GetWebhookByID(ctx, webhookID); err != nil {
+		return err
+	}
+	m.removeWebhooks(func(w *models.Webhook) bool { return w.ID == webhookID })
+	return nil
+}
+
+// removeWebhooks removes the webhooks matched by f along with their deliveries
+func (m *mock) removeWebhooks(f func(*models.Webhook) bool) {
+	m.hooksLock.Lock()
+	defer m.hooksLock.Unlock()
+
+	removed := make(map[string]bool)
+	var newWebhooks []*models.Webhook
+	for _, w := range m.Webhooks {
+		if f(w) {
+			removed[w.ID] = true
+		} else {
+			newWebhooks = append(newWebhooks, w)
+		}
+	}
+	m.Webhooks = newWebhooks
+
+	var newDeliveries []*models.WebhookDelivery
+	for _, d := range m.WebhookDeliveries {
+		if !removed[d.WebhookID] {
+			newDeliveries = append(newDeliveries, d)
+		}
+	}
+	m.WebhookDeliveries = newDeliveries
+}
+
+func (m *mock) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if _, err := m.GetWebhookByID(ctx, delivery.WebhookID); err != nil {
+		return err
+	}
+
+	cl := *delivery
+	m.hooksLock.Lock()
+	m.WebhookDeliveries = append(m.WebhookDeliveries, &cl)
+	m.hooksLock.Unlock()
+	return nil
+}
+
+func (m *mock) GetWebhookDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	m.hooksLock.Lock()
+	deliveries := make([]*models.WebhookDelivery, len(m.WebhookDeliveries))
+	copy(deliveries, m.WebhookDeliveries)
+	m.hooksLock.Unlock()
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+
+	res := []*models.WebhookDelivery{}
+	for _, d := range deliveries {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if d.WebhookID == filter.WebhookID && (cursor == "" || d.ID < cursor) {
+			cl := *d
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.WebhookDeliveryList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	cl := *entry
+	m.AuditEntries = append(m.AuditEntries, &cl)
+	return nil
+}
+
+func (m *mock) GetAuditEntries(ctx context.Context, filter *models.AuditEntryFilter) (*models.AuditEntryList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	entries := make([]*models.AuditEntry, len(m.AuditEntries))
+	copy(entries, m.AuditEntries)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+
+	from, to := time.Time(filter.FromTime), time.Time(filter.ToTime)
+	res := []*models.AuditEntry{}
+	for _, e := range entries {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		created := time.Time(e.CreatedAt)
+		if (cursor == "" || e.ID < cursor) &&
+			(filter.AppID == "" || e.AppID == filter.AppID) &&
+			(filter.ResourceType == "" || e.ResourceType == filter.ResourceType) &&
+			(filter.ResourceID == "" || e.ResourceID == filter.ResourceID) &&
+			(filter.Actor == "" || e.Actor == filter.Actor) &&
+			(from.IsZero() || created.After(from)) &&
+			(to.IsZero() || created.Before(to)) {
+			cl := *e
+			res = append(res, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.AuditEntryList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) Search(ctx context.Context, filter *models.SearchFilter) (*models.SearchResultList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	var results []*models.SearchResult
+	if filter.Type == "" || filter.Type == models.SearchTypeApp {
+		for _, a := range m.Apps {
+			if a.DeletedAt == nil && models.MatchSearch(filter.Terms, models.SearchText(a.Name, a.Config, a.Annotations)) {
+				results = append(results, &models.SearchResult{Type: models.SearchTypeApp, ID: a.ID, AppID: a.ID, Name: a.Name})
+			}
+		}
+	}
+	if filter.Type == "" || filter.Type == models.SearchTypeFn {
+		for _, f := range m.Fns {
+			if f.DeletedAt == nil && models.MatchSearch(filter.Terms, models.SearchText(f.Name, f.Config, f.Annotations)) {
+				results = append(results, &models.SearchResult{Type: models.SearchTypeFn, ID: f.ID, AppID: f.AppID, Name: f.Name})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	res := []*models.SearchResult{}
+	for _, r := range results {
+		if filter.PerPage > 0 && len(res) == filter.PerPage {
+			break
+		}
+		if r.ID > cursor && filter.SearchesApp(r.AppID) {
+			res = append(res, r)
+		}
+	}
+
+	var nextCursor string
+	if len(res) > 0 && len(res) == filter.PerPage {
+		last := []byte(res[len(res)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.SearchResultList{
+		NextCursor: nextCursor,
+		Items:      res,
+	}, nil
+}
+
+func (m *mock) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	if l, ok := m.Leases[name]; ok && l.holder != holder && l.expiresAt.After(now) {
+		return false, nil
+	}
+	m.Leases[name] = mockLease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
 func (m *mock) Close() error {
 	return nil
 }
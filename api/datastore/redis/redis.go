@@ -0,0 +1,353 @@
+// Package redis implements the datastore over a redis server, see the kv package, for CI and
+// edge deployments where running a SQL database is overkill. Datastore URLs are of the form
+// redis[s]://[:password@]host:port[/prefix][?persist=aof|rdb], the keys of the datastore
+// being under prefix, fn by default.
+//
+// Values are hashes holding their version, and the keys of the datastore are members of a
+// sorted set that prefixes are listed from in order. Transactions are lua scripts checking
+// the versions of the keys read before applying their changes, which are published to the
+// watchers of the datastore. The keys of the datastore are not declared to the scripts, a
+// single redis server is required rather than a cluster.
+//
+// Redis keeps its data in memory, the datastore is lost on restarts unless the server
+// persists it. persist configures the server to do so, with an append only file for aof or
+// with periodic snapshots for rdb.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+	"github.com/garyburd/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPrefix is the prefix of the keys of the datastore if the URL has no path
+const DefaultPrefix = "fn"
+
+// listScript returns the revision of the datastore followed by the key, value and version of
+// each key under ARGV[1]. KEYS[1] is the prefix of the datastore.
+var listScript = redis.NewScript(1, `
+local p = KEYS[1]
+local res = {tonumber(redis.call("GET", p .. "rev") or 0)}
+for _, k in ipairs(redis.call("ZRANGEBYLEX", p .. "keys", "[" .. ARGV[1], "[" .. ARGV[1] .. "\255")) do
+	local e = redis.call("HMGET", p .. "v:" .. k, "value", "version")
+	table.insert(res, k)
+	table.insert(res, e[1])
+	table.insert(res, tonumber(e[2]))
+end
+return res
+`)
+
+// commitScript applies the transaction encoded in ARGV[1], the values of its puts following
+// as key value pairs. It returns 0 if a key read or listed changed since, 1 once applied.
+// KEYS[1] is the prefix of the datastore.
+var commitScript = redis.NewScript(1, `
+local p = KEYS[1]
+local txn = cjson.decode(ARGV[1])
+
+local function version(k)
+	return tonumber(redis.call("HGET", p .. "v:" .. k, "version") or 0)
+end
+local function keys(prefix)
+	return redis.call("ZRANGEBYLEX", p .. "keys", "[" .. prefix, "[" .. prefix .. "\255")
+end
+
+for k, v in pairs(txn.reads or {}) do
+	if version(k) ~= v then
+		return 0
+	end
+end
+for prefix, rev in pairs(txn.lists or {}) do
+	for _, k in ipairs(keys(prefix)) do
+		if version(k) > rev then
+			return 0
+		end
+	end
+end
+
+local rev = redis.call("INCR", p .. "rev")
+local events = {}
+local function del(k)
+	if redis.call("ZREM", p .. "keys", k) == 1 then
+		redis.call("DEL", p .. "v:" .. k)
+		table.insert(events, {key = k, deleted = true})
+	end
+end
+for _, prefix in ipairs(txn.delete_prefixes or {}) do
+	for _, k in ipairs(keys(prefix)) do
+		del(k)
+	end
+end
+for _, k in ipairs(txn.deletes or {}) do
+	del(k)
+end
+for i = 2, #ARGV, 2 do
+	redis.call("HMSET", p .. "v:" .. ARGV[i], "value", ARGV[i + 1], "version", rev)
+	redis.call("ZADD", p .. "keys", 0, ARGV[i])
+	table.insert(events, {key = ARGV[i], deleted = false})
+end
+
+for _, ev in ipairs(events) do
+	redis.call("PUBLISH", p .. "events", cjson.encode(ev))
+end
+return 1
+`)
+
+// txn is the encoding of a kv.Txn for commitScript, but for its puts
+type txn struct {
+	Reads          map[string]int64 `json:"reads,omitempty"`
+	Lists          map[string]int64 `json:"lists,omitempty"`
+	Deletes        []string         `json:"deletes,omitempty"`
+	DeletePrefixes []string         `json:"delete_prefixes,omitempty"`
+}
+
+// event is a change published by commitScript
+type event struct {
+	Key     string `json:"key"`
+	Deleted bool   `json:"deleted"`
+}
+
+type redisKV struct {
+	pool   *redis.Pool
+	dial   func() (redis.Conn, error)
+	prefix string
+}
+
+var _ kv.KV = new(redisKV)
+
+// New connects to the redis server of u
+func New(ctx context.Context, u *url.URL) (*kv.Store, error) {
+	r, err := newKV(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return kv.Open(ctx, r)
+}
+
+func newKV(ctx context.Context, u *url.URL) (*redisKV, error) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"url": common.MaskPassword(u)})
+
+	// the path of the URL is the prefix of the keys, not the database of the server
+	server := *u
+	server.Path, server.RawPath, server.RawQuery = "", "", ""
+	dial := func() (redis.Conn, error) {
+		return redis.DialURL(server.String())
+	}
+	pool := &redis.Pool{
+		MaxIdle:     512,
+		MaxActive:   512,
+		Wait:        true,
+		IdleTimeout: 300 * time.Second,
+		Dial:        dial,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	// Force a connection so we can fail in case of error.
+	conn := pool.Get()
+	defer conn.Close()
+	if err := conn.Err(); err != nil {
+		log.WithError(err).Error("couldn't connect to redis")
+		return nil, err
+	}
+	if err := persist(conn, u.Query().Get("persist")); err != nil {
+		log.WithError(err).Error("couldn't configure the persistence of redis")
+		return nil, err
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	log.WithFields(logrus.Fields{"prefix": prefix}).Info("datastore dialed")
+	return &redisKV{pool: pool, dial: dial, prefix: prefix + "/"}, nil
+}
+
+// persist configures the redis server to persist its data with mode, leaving it as is if mode
+// is empty
+func persist(conn redis.Conn, mode string) error {
+	var err error
+	switch mode {
+	case "":
+	case "aof":
+		_, err = conn.Do("CONFIG", "SET", "appendonly", "yes")
+	case "rdb":
+		// the default snapshot points of redis
+		_, err = conn.Do("CONFIG", "SET", "save", "3600 1 300 100 60 10000")
+	default:
+		err = fmt.Errorf("invalid persist mode %q, expected aof or rdb", mode)
+	}
+	return err
+}
+
+// Get implements kv.KV
+func (r *redisKV) Get(ctx context.Context, key string) (*kv.Entry, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("HMGET", r.prefix+"v:"+key, "value", "version"))
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+	e := &kv.Entry{Key: key}
+	if _, err := redis.Scan(values, &e.Value, &e.Version); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// List implements kv.KV
+func (r *redisKV) List(ctx context.Context, prefix string) ([]*kv.Entry, int64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Values(listScript.Do(conn, r.prefix, prefix))
+	if err != nil {
+		return nil, 0, err
+	}
+	var revision int64
+	values, err = redis.Scan(values, &revision)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]*kv.Entry, 0, len(values)/3)
+	for len(values) > 0 {
+		var e kv.Entry
+		values, err = redis.Scan(values, &e.Key, &e.Value, &e.Version)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, revision, nil
+}
+
+// Commit implements kv.KV
+func (r *redisKV) Commit(ctx context.Context, t *kv.Txn) error {
+	b, err := json.Marshal(&txn{
+		Reads:          t.Reads,
+		Lists:          t.Lists,
+		Deletes:        t.Deletes,
+		DeletePrefixes: t.DeletePrefixes,
+	})
+	if err != nil {
+		return err
+	}
+	args := []interface{}{r.prefix, b}
+	for k, v := range t.Puts {
+		args = append(args, k, v)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	applied, err := redis.Bool(commitScript.Do(conn, args...))
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return kv.ErrConflict
+	}
+	return nil
+}
+
+// Watch implements kv.KV
+func (r *redisKV) Watch(ctx context.Context, prefix string) (<-chan kv.Event, error) {
+	// subscribed connections can't be returned to the pool, and closing them is the only way
+	// to interrupt their receiving
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(r.prefix + "events"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// events published once the subscription is confirmed are received
+	switch v := psc.Receive().(type) {
+	case error:
+		conn.Close()
+		return nil, v
+	case redis.Subscription:
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("unexpected reply %v to subscribe", v)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	events := make(chan kv.Event)
+	go func() {
+		defer close(events)
+		defer close(done)
+		for {
+			switch v := psc.Receive().(type) {
+			case error:
+				if ctx.Err() == nil {
+					common.Logger(ctx).WithError(v).Info("redis watch interrupted")
+				}
+				return
+			case redis.Message:
+				var ev event
+				if err := json.Unmarshal(v.Data, &ev); err != nil {
+					common.Logger(ctx).WithError(err).Error("invalid redis datastore event")
+					return
+				}
+				if !strings.HasPrefix(ev.Key, prefix) {
+					continue
+				}
+				select {
+				case events <- kv.Event{Key: ev.Key, Deleted: ev.Deleted}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Close implements kv.KV
+func (r *redisKV) Close() error {
+	return r.pool.Close()
+}
+
+type redisProvider int
+
+func (redisProvider) String() string {
+	return "redis"
+}
+
+func (redisProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "redis" || u.Scheme == "rediss"
+}
+
+func (redisProvider) New(ctx context.Context, u *url.URL) (models.Datastore, error) {
+	return New(ctx, u)
+}
+
+func init() {
+	datastore.Register(redisProvider(0))
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/datastore/kv"
+	"github.com/fnproject/fn/api/models"
+)
+
+// emptyStore connects to the redis server of REDIS_URL, e.g. redis://localhost:6379/fntest,
+// removing the keys of the datastore
+func emptyStore(t *testing.T) *kv.Store {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL is not set")
+	}
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	r, err := newKV(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit(ctx, &kv.Txn{DeletePrefixes: []string{""}}); err != nil {
+		t.Fatal(err)
+	}
+	return kv.New(r)
+}
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		return datastoreutil.NewValidator(emptyStore(t))
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+func TestWatchChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := emptyStore(t)
+	changes, err := ds.WatchChanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		if c.Kind != models.ChangeApp || c.ID != app.ID || c.Deleted {
+			t.Fatalf("expected the app to be changed, got %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a change")
+	}
+
+	// changes are sent until the watch is cancelled
+	cancel()
+	for range changes {
+	}
+}
@@ -0,0 +1,362 @@
+// Package replica keeps a region-local replica of the apps, fns, triggers and domains of a
+// datastore in another region, the primary, for the lookups each invoke makes not to cross
+// regions.
+//
+// Mutations are written through to the primary, and applied to the replica of the node
+// making them once committed. The resources are replicated asynchronously from the primary,
+// listing all of them at an interval and, if the primary is a models.ChangeWatcher, as they
+// change. Each version replicated is applied unless it conflicts with the replica: versions
+// with an updated_at older than the one replicated, and versions read before the resource was
+// removed, are dropped.
+//
+// Lookups missing from the replica, of the resources created since it was last replicated,
+// fall back to the primary, as do the reads made in transactions. Domains are looked up in
+// the replica only once it is replicated, since most hostnames have none.
+package replica
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Datastore replicates the lookups of invokes of the primary datastore it wraps
+type Datastore struct {
+	models.Datastore
+
+	store    *store
+	interval time.Duration
+	resync   chan struct{}
+	cancel   context.CancelFunc
+
+	mu       sync.Mutex
+	watchers map[chan *models.Change]context.Context
+}
+
+// New returns a replica of primary, which lists all of its resources every interval and
+// watches their changes if primary is a models.ChangeWatcher, until the replica is closed.
+func New(ctx context.Context, primary models.Datastore, interval time.Duration) *Datastore {
+	d := newDatastore(primary, interval)
+	ctx, d.cancel = context.WithCancel(ctx)
+	go d.syncLoop(ctx)
+	if w, ok := primary.(models.ChangeWatcher); ok {
+		go d.watch(ctx, w)
+	}
+	return d
+}
+
+// newDatastore returns a replica of primary which is not replicated until it is synced
+func newDatastore(primary models.Datastore, interval time.Duration) *Datastore {
+	return &Datastore{
+		Datastore: primary,
+		store:     newStore(),
+		interval:  interval,
+		resync:    make(chan struct{}, 1),
+		cancel:    func() {},
+		watchers:  make(map[chan *models.Change]context.Context),
+	}
+}
+
+// Wrap returns the replica of New, which is a models.LogStore or a models.ChangeWatcher if
+// primary is one. The changes are notified to its watchers once applied to the replica.
+func Wrap(ctx context.Context, primary models.Datastore, interval time.Duration) models.Datastore {
+	d := New(ctx, primary, interval)
+	if ls, ok := primary.(models.LogStore); ok {
+		return &logDatastore{Datastore: d, LogStore: ls}
+	}
+	if _, ok := primary.(models.ChangeWatcher); ok {
+		return &watchedDatastore{d}
+	}
+	return d
+}
+
+// logDatastore is the replica of a datastore which is also its logstore
+type logDatastore struct {
+	*Datastore
+	models.LogStore
+}
+
+// Close closes the datastore, which is the logstore too
+func (d *logDatastore) Close() error {
+	return d.Datastore.Close()
+}
+
+// watchedDatastore is the replica of a datastore notifying its changes
+type watchedDatastore struct {
+	*Datastore
+}
+
+var _ models.ChangeWatcher = new(watchedDatastore)
+
+// WatchChanges implements models.ChangeWatcher, with the changes of the primary once they are
+// applied to the replica
+func (d *watchedDatastore) WatchChanges(ctx context.Context) (<-chan *models.Change, error) {
+	w := make(chan *models.Change)
+	d.mu.Lock()
+	d.watchers[w] = ctx
+	d.mu.Unlock()
+	return w, nil
+}
+
+// requestSync lists the resources of the primary again without waiting for the interval, for
+// the changes of many resources
+func (d *Datastore) requestSync() {
+	select {
+	case d.resync <- struct{}{}:
+	default:
+	}
+}
+
+// GetAppID implements models.Datastore
+func (d *Datastore) GetAppID(ctx context.Context, appName string) (string, error) {
+	if !models.InTransaction(ctx) {
+		if id, ok := d.store.appID(appName); ok {
+			return id, nil
+		}
+	}
+	return d.Datastore.GetAppID(ctx, appName)
+}
+
+// GetAppByID implements models.Datastore
+func (d *Datastore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	if models.InTransaction(ctx) {
+		return d.Datastore.GetAppByID(ctx, appID)
+	}
+	if app, ok := d.store.app(appID); ok {
+		return app, nil
+	}
+	readAt := time.Now()
+	app, err := d.Datastore.GetAppByID(ctx, appID)
+	if err == nil {
+		d.store.putApp(app, readAt)
+	}
+	return app, err
+}
+
+// GetFnByID implements models.Datastore
+func (d *Datastore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	if models.InTransaction(ctx) {
+		return d.Datastore.GetFnByID(ctx, fnID)
+	}
+	if fn, ok := d.store.fn(fnID); ok {
+		return fn, nil
+	}
+	readAt := time.Now()
+	fn, err := d.Datastore.GetFnByID(ctx, fnID)
+	if err == nil {
+		d.store.putFn(fn, readAt)
+	}
+	return fn, err
+}
+
+// GetTriggerBySource implements models.Datastore
+func (d *Datastore) GetTriggerBySource(ctx context.Context, appID string, triggerType, source string) (*models.Trigger, error) {
+	if models.InTransaction(ctx) {
+		return d.Datastore.GetTriggerBySource(ctx, appID, triggerType, source)
+	}
+	if trigger, ok := d.store.triggerBySource(appID, triggerType, source); ok {
+		return trigger, nil
+	}
+	readAt := time.Now()
+	trigger, err := d.Datastore.GetTriggerBySource(ctx, appID, triggerType, source)
+	if err == nil {
+		d.store.putTrigger(trigger, readAt)
+	}
+	return trigger, err
+}
+
+// GetDomainsByHostname implements models.Datastore
+func (d *Datastore) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	if models.InTransaction(ctx) || !d.store.isSynced() {
+		return d.Datastore.GetDomainsByHostname(ctx, hostname)
+	}
+	return d.store.domainsByHostname(hostname), nil
+}
+
+// InsertApp implements models.Datastore
+func (d *Datastore) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	readAt := time.Now()
+	app, err := d.Datastore.InsertApp(ctx, app)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putApp(app, readAt) })
+	}
+	return app, err
+}
+
+// UpdateApp implements models.Datastore
+func (d *Datastore) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	readAt := time.Now()
+	app, err := d.Datastore.UpdateApp(ctx, app)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putApp(app, readAt) })
+	}
+	return app, err
+}
+
+// RemoveApp implements models.Datastore
+func (d *Datastore) RemoveApp(ctx context.Context, appID string) error {
+	err := d.Datastore.RemoveApp(ctx, appID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeApp, appID) })
+	}
+	return err
+}
+
+// SoftDeleteApp implements models.Datastore
+func (d *Datastore) SoftDeleteApp(ctx context.Context, appID string) error {
+	err := d.Datastore.SoftDeleteApp(ctx, appID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeApp, appID) })
+	}
+	return err
+}
+
+// RestoreApp implements models.Datastore
+func (d *Datastore) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	readAt := time.Now()
+	app, err := d.Datastore.RestoreApp(ctx, appID)
+	if err == nil {
+		models.AfterCommit(ctx, func() {
+			d.store.putApp(app, readAt)
+			// the fns of the app are restored with it
+			d.requestSync()
+		})
+	}
+	return app, err
+}
+
+// InsertFn implements models.Datastore
+func (d *Datastore) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	readAt := time.Now()
+	fn, err := d.Datastore.InsertFn(ctx, fn)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putFn(fn, readAt) })
+	}
+	return fn, err
+}
+
+// UpdateFn implements models.Datastore
+func (d *Datastore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	readAt := time.Now()
+	fn, err := d.Datastore.UpdateFn(ctx, fn)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putFn(fn, readAt) })
+	}
+	return fn, err
+}
+
+// RemoveFn implements models.Datastore
+func (d *Datastore) RemoveFn(ctx context.Context, fnID string) error {
+	err := d.Datastore.RemoveFn(ctx, fnID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeFn, fnID) })
+	}
+	return err
+}
+
+// SoftDeleteFn implements models.Datastore
+func (d *Datastore) SoftDeleteFn(ctx context.Context, fnID string) error {
+	err := d.Datastore.SoftDeleteFn(ctx, fnID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeFn, fnID) })
+	}
+	return err
+}
+
+// RestoreFn implements models.Datastore
+func (d *Datastore) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	readAt := time.Now()
+	fn, err := d.Datastore.RestoreFn(ctx, fnID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putFn(fn, readAt) })
+	}
+	return fn, err
+}
+
+// InsertTrigger implements models.Datastore
+func (d *Datastore) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	readAt := time.Now()
+	trigger, err := d.Datastore.InsertTrigger(ctx, trigger)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putTrigger(trigger, readAt) })
+	}
+	return trigger, err
+}
+
+// UpdateTrigger implements models.Datastore
+func (d *Datastore) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	readAt := time.Now()
+	trigger, err := d.Datastore.UpdateTrigger(ctx, trigger)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putTrigger(trigger, readAt) })
+	}
+	return trigger, err
+}
+
+// RemoveTrigger implements models.Datastore
+func (d *Datastore) RemoveTrigger(ctx context.Context, triggerID string) error {
+	err := d.Datastore.RemoveTrigger(ctx, triggerID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeTrigger, triggerID) })
+	}
+	return err
+}
+
+// InsertDomain implements models.Datastore
+func (d *Datastore) InsertDomain(ctx context.Context, domain *models.Domain) (*models.Domain, error) {
+	readAt := time.Now()
+	domain, err := d.Datastore.InsertDomain(ctx, domain)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.putDomain(domain, readAt) })
+	}
+	return domain, err
+}
+
+// RemoveDomain implements models.Datastore
+func (d *Datastore) RemoveDomain(ctx context.Context, domainID string) error {
+	err := d.Datastore.RemoveDomain(ctx, domainID)
+	if err == nil {
+		models.AfterCommit(ctx, func() { d.store.remove(models.ChangeDomain, domainID) })
+	}
+	return err
+}
+
+// ApplyBatch implements models.Datastore
+func (d *Datastore) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	readAt := time.Now()
+	applied, err := d.Datastore.ApplyBatch(ctx, items)
+	if err == nil {
+		models.AfterCommit(ctx, func() {
+			for _, item := range applied {
+				d.applyBatchItem(item, readAt)
+			}
+		})
+	}
+	return applied, err
+}
+
+func (d *Datastore) applyBatchItem(item *models.BatchItem, readAt time.Time) {
+	deleted := item.Action == models.BatchDelete
+	switch {
+	case item.App != nil && deleted:
+		d.store.remove(models.ChangeApp, item.App.ID)
+	case item.App != nil:
+		d.store.putApp(item.App, readAt)
+	case item.Fn != nil && deleted:
+		d.store.remove(models.ChangeFn, item.Fn.ID)
+	case item.Fn != nil:
+		d.store.putFn(item.Fn, readAt)
+	case item.Trigger != nil && deleted:
+		d.store.remove(models.ChangeTrigger, item.Trigger.ID)
+	case item.Trigger != nil:
+		d.store.putTrigger(item.Trigger, readAt)
+	}
+}
+
+// Close stops replicating and closes the primary
+func (d *Datastore) Close() error {
+	d.cancel()
+	return d.Datastore.Close()
+}
@@ -0,0 +1,195 @@
+package replica
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/cache"
+	"github.com/fnproject/fn/api/datastore/datastoretest"
+	"github.com/fnproject/fn/api/models"
+)
+
+// the mock is not safe for concurrent use, the replicas of the tests are synced as they go
+// rather than in the background
+
+func TestDatastore(t *testing.T) {
+	f := func(t *testing.T) models.Datastore {
+		return newDatastore(datastore.NewMock(), time.Hour)
+	}
+	datastoretest.RunAllTests(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	primary := datastore.NewMock()
+	d := newDatastore(primary, time.Hour)
+	sync := func() {
+		if err := d.sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// made in the primary by the nodes of another region
+	app, err := primary.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{AppID: app.ID, Name: "myfn", Image: "fnproject/hello"}
+	fn.SetDefaults()
+	fn, err = primary.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := primary.InsertTrigger(ctx, &models.Trigger{AppID: app.ID, FnID: fn.ID, Name: "mytrigger", Type: "http", Source: "/hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync()
+	if _, ok := d.store.triggerBySource(app.ID, "http", "/hello"); !ok {
+		t.Fatal("expected the trigger to be replicated")
+	}
+	if id, ok := d.store.appID("myapp"); !ok || id != app.ID {
+		t.Fatalf("expected the app to be replicated, got %q", id)
+	}
+	if _, ok := d.store.fn(fn.ID); !ok {
+		t.Fatal("expected the fn to be replicated")
+	}
+	if got, err := d.GetTriggerBySource(ctx, app.ID, "http", "/hello"); err != nil || got.ID != trigger.ID {
+		t.Fatalf("expected trigger %s, got %v %v", trigger.ID, got, err)
+	}
+
+	updated := app.Clone()
+	updated.Config = models.Config{"foo": "bar"}
+	if _, err := primary.UpdateApp(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	sync()
+	if got, _ := d.store.app(app.ID); got.Config["foo"] != "bar" {
+		t.Fatalf("expected the update of the app to be replicated, got config %v", got.Config)
+	}
+
+	if err := primary.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	sync()
+	if _, ok := d.store.app(app.ID); ok {
+		t.Fatal("expected the removal of the app to be replicated")
+	}
+	if _, ok := d.store.fn(fn.ID); ok {
+		t.Fatal("expected the fn to be removed with its app")
+	}
+	if _, ok := d.store.triggerBySource(app.ID, "http", "/hello"); ok {
+		t.Fatal("expected the trigger to be removed with its app")
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the nodes of the primary region notify their changes on a bus
+	ds := datastore.NewMock()
+	bus := cache.NewMemoryBus()
+	primary, err := cache.New(ctx, ds, time.Minute, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := cache.New(ctx, ds, time.Minute, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDatastore(primary, time.Hour)
+	go d.watch(ctx, primary)
+	changes, err := (&watchedDatastore{d}).WatchChanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() *models.Change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return nil
+		}
+	}
+
+	app, err := other.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || c.ID != app.ID {
+		t.Fatalf("expected the app to be changed, got %+v", c)
+	}
+	if _, ok := d.store.app(app.ID); !ok {
+		t.Fatal("expected the app to be replicated before its change is notified")
+	}
+
+	if err := other.RemoveApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c := next(); c.Kind != models.ChangeApp || !c.Deleted {
+		t.Fatalf("expected the app to be deleted, got %+v", c)
+	}
+	if _, ok := d.store.app(app.ID); ok {
+		t.Fatal("expected the app to be removed before its change is notified")
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	primary := datastore.NewMock()
+	d := newDatastore(primary, time.Hour)
+
+	app, err := d.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.GetAppByID(ctx, app.ID); err != nil {
+		t.Fatalf("expected the app to be written to the primary, got %v", err)
+	}
+	if _, ok := d.store.app(app.ID); !ok {
+		t.Fatal("expected the app to be replicated as it is inserted")
+	}
+
+	if err := d.SoftDeleteApp(ctx, app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.GetAppByID(ctx, app.ID); err != models.ErrAppsNotFound {
+		t.Fatalf("expected the deleted app not to be found, got %v", err)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	s := newStore()
+	readAt := time.Now()
+	older := &models.App{ID: "app", Name: "myapp", UpdatedAt: common.DateTime(readAt.Add(-time.Minute))}
+	newer := &models.App{ID: "app", Name: "myapp", UpdatedAt: common.DateTime(readAt)}
+
+	if !s.putApp(newer, readAt) {
+		t.Fatal("expected the app to be replicated")
+	}
+	if s.putApp(older, readAt) {
+		t.Fatal("expected a version older than the one replicated to conflict")
+	}
+	if got, _ := s.app("app"); got != newer {
+		t.Fatal("expected the newer version to be kept")
+	}
+
+	s.remove(models.ChangeApp, "app")
+	if s.putApp(newer, readAt) {
+		t.Fatal("expected a version read before the removal to conflict")
+	}
+	if !s.putApp(newer, time.Now()) {
+		t.Fatal("expected a version read after the removal to be replicated")
+	}
+
+	s.prune(time.Now(), map[resource]bool{})
+	if _, ok := s.app("app"); ok {
+		t.Fatal("expected the app no longer in the primary to be pruned")
+	}
+}
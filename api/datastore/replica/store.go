@@ -0,0 +1,261 @@
+package replica
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// tombstoneTTL is how long the removals of resources are remembered for, for the versions
+// read before them not to bring the resources back
+const tombstoneTTL = 5 * time.Minute
+
+// store is the in-memory replica of the apps, fns, triggers and domains of the primary
+// datastore. Each version of a resource is applied with the time it was read at, versions
+// read before the resource was removed and versions older than the one replicated, by their
+// updated_at, are conflicts which are not applied.
+type store struct {
+	mu sync.RWMutex
+
+	apps     map[string]*models.App
+	fns      map[string]*models.Fn
+	triggers map[string]*models.Trigger
+	domains  map[string]*models.Domain
+
+	// appIDs are the ids of the apps by name, triggerIDs the ids of the triggers by source
+	appIDs     map[string]string
+	triggerIDs map[string]string
+
+	// stored are the times the resources were last stored at
+	stored map[resource]time.Time
+	// removed are the times the resources were removed at
+	removed map[resource]time.Time
+
+	// synced is set once all the resources are replicated
+	synced bool
+}
+
+func newStore() *store {
+	return &store{
+		apps:       make(map[string]*models.App),
+		fns:        make(map[string]*models.Fn),
+		triggers:   make(map[string]*models.Trigger),
+		domains:    make(map[string]*models.Domain),
+		appIDs:     make(map[string]string),
+		triggerIDs: make(map[string]string),
+		stored:     make(map[resource]time.Time),
+		removed:    make(map[resource]time.Time),
+	}
+}
+
+// resource identifies a resource by its kind, one of the kinds of models.Change, and its id
+type resource struct {
+	kind, id string
+}
+
+func triggerSourceKey(appID, triggerType, source string) string {
+	return appID + "/" + triggerType + "/" + source
+}
+
+// conflicts returns true if the version of the resource of key updated at updatedAt,
+// read at readAt, must not be applied over the replicated one updated at current, if any
+func (s *store) conflicts(key resource, readAt, updatedAt time.Time, current *time.Time) bool {
+	if removedAt, ok := s.removed[key]; ok {
+		if !readAt.After(removedAt) {
+			return true
+		}
+		delete(s.removed, key)
+	}
+	return current != nil && current.After(updatedAt)
+}
+
+func (s *store) putApp(app *models.App, readAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := resource{models.ChangeApp, app.ID}
+	cur, ok := s.apps[app.ID]
+	var current *time.Time
+	if ok {
+		t := time.Time(cur.UpdatedAt)
+		current = &t
+	}
+	if s.conflicts(key, readAt, time.Time(app.UpdatedAt), current) {
+		return false
+	}
+	if ok {
+		delete(s.appIDs, cur.Name)
+	}
+	s.apps[app.ID] = app
+	s.appIDs[app.Name] = app.ID
+	s.stored[key] = time.Now()
+	return true
+}
+
+func (s *store) putFn(fn *models.Fn, readAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := resource{models.ChangeFn, fn.ID}
+	var current *time.Time
+	if cur, ok := s.fns[fn.ID]; ok {
+		t := time.Time(cur.UpdatedAt)
+		current = &t
+	}
+	if s.conflicts(key, readAt, time.Time(fn.UpdatedAt), current) {
+		return false
+	}
+	s.fns[fn.ID] = fn
+	s.stored[key] = time.Now()
+	return true
+}
+
+func (s *store) putTrigger(trigger *models.Trigger, readAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := resource{models.ChangeTrigger, trigger.ID}
+	cur, ok := s.triggers[trigger.ID]
+	var current *time.Time
+	if ok {
+		t := time.Time(cur.UpdatedAt)
+		current = &t
+	}
+	if s.conflicts(key, readAt, time.Time(trigger.UpdatedAt), current) {
+		return false
+	}
+	if ok {
+		delete(s.triggerIDs, triggerSourceKey(cur.AppID, cur.Type, cur.Source))
+	}
+	s.triggers[trigger.ID] = trigger
+	s.triggerIDs[triggerSourceKey(trigger.AppID, trigger.Type, trigger.Source)] = trigger.ID
+	s.stored[key] = time.Now()
+	return true
+}
+
+// putDomain stores domain, which cannot be updated and so has no conflicting version
+func (s *store) putDomain(domain *models.Domain, readAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := resource{models.ChangeDomain, domain.ID}
+	if s.conflicts(key, readAt, time.Time(domain.CreatedAt), nil) {
+		return false
+	}
+	s.domains[domain.ID] = domain
+	s.stored[key] = time.Now()
+	return true
+}
+
+// remove removes the resource of kind and id, along with the fns, triggers and domains of
+// the apps and the triggers of the fns removed
+func (s *store) remove(kind, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(resource{kind, id}, time.Now())
+}
+
+func (s *store) removeLocked(key resource, removedAt time.Time) {
+	s.removed[key] = removedAt
+	delete(s.stored, key)
+
+	id := key.id
+	switch key.kind {
+	case models.ChangeApp:
+		if app, ok := s.apps[id]; ok {
+			delete(s.appIDs, app.Name)
+			delete(s.apps, id)
+		}
+		for fnID, fn := range s.fns {
+			if fn.AppID == id {
+				s.removeLocked(resource{models.ChangeFn, fnID}, removedAt)
+			}
+		}
+		for domainID, d := range s.domains {
+			if d.AppID == id {
+				s.removeLocked(resource{models.ChangeDomain, domainID}, removedAt)
+			}
+		}
+	case models.ChangeFn:
+		delete(s.fns, id)
+		for triggerID, t := range s.triggers {
+			if t.FnID == id {
+				s.removeLocked(resource{models.ChangeTrigger, triggerID}, removedAt)
+			}
+		}
+	case models.ChangeTrigger:
+		if t, ok := s.triggers[id]; ok {
+			delete(s.triggerIDs, triggerSourceKey(t.AppID, t.Type, t.Source))
+			delete(s.triggers, id)
+		}
+	case models.ChangeDomain:
+		delete(s.domains, id)
+	}
+}
+
+// prune removes the resources stored before since which are not in seen, as they were
+// removed from the primary, and forgets the removals older than tombstoneTTL
+func (s *store) prune(since time.Time, seen map[resource]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, storedAt := range s.stored {
+		if !seen[key] && storedAt.Before(since) {
+			s.removeLocked(key, now)
+		}
+	}
+	for key, removedAt := range s.removed {
+		if now.Sub(removedAt) > tombstoneTTL {
+			delete(s.removed, key)
+		}
+	}
+	s.synced = true
+}
+
+func (s *store) isSynced() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.synced
+}
+
+func (s *store) appID(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.appIDs[name]
+	return id, ok
+}
+
+func (s *store) app(id string) (*models.App, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	app, ok := s.apps[id]
+	return app, ok
+}
+
+func (s *store) fn(id string) (*models.Fn, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn, ok := s.fns[id]
+	return fn, ok
+}
+
+func (s *store) triggerBySource(appID, triggerType, source string) (*models.Trigger, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.triggerIDs[triggerSourceKey(appID, triggerType, source)]
+	if !ok {
+		return nil, false
+	}
+	return s.triggers[id], true
+}
+
+func (s *store) domainsByHostname(hostname string) []*models.Domain {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	domains := []*models.Domain{}
+	for _, d := range s.domains {
+		if d.Hostname == hostname {
+			domains = append(domains, d)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].ID < domains[j].ID })
+	return domains
+}
@@ -0,0 +1,217 @@
+package replica
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// syncPageSize is the number of resources listed at once from the primary
+const syncPageSize = 100
+
+// syncLoop lists all the resources of the primary every interval, and as requested, until ctx
+// is done
+func (d *Datastore) syncLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if err := d.sync(ctx); err != nil && ctx.Err() == nil {
+			common.Logger(ctx).WithError(err).Error("cannot replicate the primary datastore")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.resync:
+		}
+	}
+}
+
+// syncStats counts the resources replicated by a sync and the versions conflicting with the
+// replica which were dropped
+type syncStats struct {
+	resources, conflicts int
+}
+
+func (st *syncStats) add(applied bool) {
+	st.resources++
+	if !applied {
+		st.conflicts++
+	}
+}
+
+// sync replicates all the resources of the primary, removing those which are no longer in it
+func (d *Datastore) sync(ctx context.Context) error {
+	start := time.Now()
+	seen := make(map[resource]bool)
+	var stats syncStats
+
+	filter := &models.AppFilter{PerPage: syncPageSize}
+	for {
+		apps, err := d.Datastore.GetApps(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps.Items {
+			seen[resource{models.ChangeApp, app.ID}] = true
+			stats.add(d.store.putApp(app, start))
+			if err := d.syncApp(ctx, app.ID, start, seen, &stats); err != nil {
+				return err
+			}
+		}
+		if apps.NextCursor == "" {
+			break
+		}
+		filter.Cursor = apps.NextCursor
+	}
+	d.store.prune(start, seen)
+
+	common.Logger(ctx).WithFields(logrus.Fields{
+		"resources":   stats.resources,
+		"conflicts":   stats.conflicts,
+		"duration_ms": int64(time.Since(start) / time.Millisecond),
+	}).Debug("replicated the primary datastore")
+	return nil
+}
+
+// syncApp replicates the fns, triggers and domains of the app of appID
+func (d *Datastore) syncApp(ctx context.Context, appID string, start time.Time, seen map[resource]bool, stats *syncStats) error {
+	fnFilter := &models.FnFilter{AppID: appID, PerPage: syncPageSize}
+	for {
+		fns, err := d.Datastore.GetFns(ctx, fnFilter)
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns.Items {
+			seen[resource{models.ChangeFn, fn.ID}] = true
+			stats.add(d.store.putFn(fn, start))
+		}
+		if fns.NextCursor == "" {
+			break
+		}
+		fnFilter.Cursor = fns.NextCursor
+	}
+
+	triggerFilter := &models.TriggerFilter{AppID: appID, PerPage: syncPageSize}
+	for {
+		triggers, err := d.Datastore.GetTriggers(ctx, triggerFilter)
+		if err != nil {
+			return err
+		}
+		for _, t := range triggers.Items {
+			seen[resource{models.ChangeTrigger, t.ID}] = true
+			stats.add(d.store.putTrigger(t, start))
+		}
+		if triggers.NextCursor == "" {
+			break
+		}
+		triggerFilter.Cursor = triggers.NextCursor
+	}
+
+	domainFilter := &models.DomainFilter{AppID: appID, PerPage: syncPageSize}
+	for {
+		domains, err := d.Datastore.GetDomains(ctx, domainFilter)
+		if err != nil {
+			return err
+		}
+		for _, dom := range domains.Items {
+			seen[resource{models.ChangeDomain, dom.ID}] = true
+			stats.add(d.store.putDomain(dom, start))
+		}
+		if domains.NextCursor == "" {
+			break
+		}
+		domainFilter.Cursor = domains.NextCursor
+	}
+	return nil
+}
+
+// watch applies the changes of the primary to the replica and notifies them to the watchers
+// of the replica, until ctx is done
+func (d *Datastore) watch(ctx context.Context, w models.ChangeWatcher) {
+	changes, err := w.WatchChanges(ctx)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("cannot watch the changes of the primary datastore, they are replicated at an interval")
+		return
+	}
+	for c := range changes {
+		d.apply(ctx, c)
+
+		d.mu.Lock()
+		for w, wctx := range d.watchers {
+			select {
+			case w <- c:
+			case <-wctx.Done():
+				delete(d.watchers, w)
+				close(w)
+			}
+		}
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	for w := range d.watchers {
+		delete(d.watchers, w)
+		close(w)
+	}
+	d.mu.Unlock()
+}
+
+// apply replicates the resource of c as it is now in the primary. Resources which are not
+// found were removed since.
+func (d *Datastore) apply(ctx context.Context, c *models.Change) {
+	readAt := time.Now()
+	var err error
+	switch c.Kind {
+	case models.ChangeApp:
+		var app *models.App
+		if !c.Deleted {
+			if app, err = d.Datastore.GetAppByID(ctx, c.ID); err == nil {
+				d.store.putApp(app, readAt)
+				return
+			}
+		}
+	case models.ChangeFn:
+		var fn *models.Fn
+		if !c.Deleted {
+			if fn, err = d.Datastore.GetFnByID(ctx, c.ID); err == nil {
+				d.store.putFn(fn, readAt)
+				return
+			}
+		}
+	case models.ChangeTrigger:
+		var t *models.Trigger
+		if !c.Deleted {
+			if t, err = d.Datastore.GetTriggerByID(ctx, c.ID); err == nil {
+				d.store.putTrigger(t, readAt)
+				return
+			}
+		}
+	case models.ChangeDomain:
+		var dom *models.Domain
+		if !c.Deleted {
+			if dom, err = d.Datastore.GetDomainByID(ctx, c.ID); err == nil {
+				d.store.putDomain(dom, readAt)
+				return
+			}
+		}
+	default:
+		d.requestSync()
+		return
+	}
+
+	if err != nil && !isNotFound(err) {
+		// the next sync replicates it
+		common.Logger(ctx).WithError(err).WithField("kind", c.Kind).Error("cannot replicate datastore change")
+		return
+	}
+	d.store.remove(c.Kind, c.ID)
+}
+
+func isNotFound(err error) bool {
+	return models.IsAPIError(err) && models.GetAPIErrorCode(err) == http.StatusNotFound
+}
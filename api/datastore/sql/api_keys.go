@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const apiKeySelector = `SELECT id,name,app_id,scope,rate_limit,hash,created_at FROM api_keys`
+
+func (ds *SQLStore) InsertAPIKey(ctx context.Context, newKey *models.APIKey) (*models.APIKey, error) {
+	key := *newKey
+	key.ID = id.New().String()
+	key.CreatedAt = common.DateTime(time.Now())
+
+	err := key.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.tx(ctx, func(tx *sqlx.Tx) error {
+		if key.AppID != "" {
+			query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+			r := tx.QueryRowContext(ctx, query, key.AppID)
+			if err := r.Scan(new(int)); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrAppsNotFound
+				}
+				return err
+			}
+		}
+
+		query := tx.Rebind(`INSERT INTO api_keys (
+			id,
+			name,
+			app_id,
+			scope,
+			rate_limit,
+			hash,
+			created_at
+		)
+		VALUES (
+			:id,
+			:name,
+			:app_id,
+			:scope,
+			:rate_limit,
+			:hash,
+			:created_at
+		);`)
+
+		_, err := tx.NamedExecContext(ctx, query, &key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (ds *SQLStore) GetAPIKeys(ctx context.Context, filter *models.APIKeyFilter) (*models.APIKeyList, error) {
+	res := &models.APIKeyList{Items: []*models.APIKey{}}
+	if filter == nil {
+		filter = new(models.APIKeyFilter)
+	}
+
+	var b bytes.Buffer
+	var args []interface{}
+	fmt.Fprintf(&b, `1=1`)
+	if filter.AppID != "" {
+		fmt.Fprintf(&b, ` AND app_id = ?`)
+		args = append(args, filter.AppID)
+	}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id > ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", apiKeySelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.StructScan(&key); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &key)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	query := ds.db.Rebind(apiKeySelector + ` WHERE hash=?`)
+	err := ds.reader(ctx).QueryRowxContext(ctx, query, hash).StructScan(&key)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAPIKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (ds *SQLStore) RemoveAPIKey(ctx context.Context, keyID string) error {
+	query := ds.db.Rebind(`DELETE FROM api_keys WHERE id=?`)
+	res, err := ds.writer(ctx).ExecContext(ctx, query, keyID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *SQLStore) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	add := func() error {
+		return ds.tx(ctx, func(tx *sqlx.Tx) error {
+			query := tx.Rebind(`UPDATE app_usage SET calls=calls+?, mb_ms=mb_ms+? WHERE app_id=? AND period=?`)
+			res, err := tx.ExecContext(ctx, query, usage.Calls, usage.MBMilliseconds, usage.AppID, usage.Period)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil || n > 0 {
+				return err
+			}
+
+			query = tx.Rebind(`INSERT INTO app_usage (app_id, period, calls, mb_ms) VALUES (:app_id, :period, :calls, :mb_ms)`)
+			_, err = tx.NamedExecContext(ctx, query, usage)
+			return err
+		})
+	}
+
+	err := add()
+	if err != nil && ds.helper.IsDuplicateKeyError(err) {
+		// another node inserted the usage of the period first, add to it
+		err = add()
+	}
+	return err
+}
+
+func (ds *SQLStore) GetAppUsage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	usage := models.AppUsage{AppID: appID, Period: period}
+	query := ds.db.Rebind(`SELECT app_id,period,calls,mb_ms FROM app_usage WHERE app_id=? AND period=?`)
+	err := ds.reader(ctx).QueryRowxContext(ctx, query, appID, period).StructScan(&usage)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	usage.SetGBSeconds()
+	return &usage, nil
+}
@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+const auditEntrySelector = `SELECT id,action,resource_type,resource_id,app_id,actor,address,diff,created_at FROM audit_entries`
+
+func (ds *SQLStore) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	query := ds.db.Rebind(`INSERT INTO audit_entries (
+		id,
+		action,
+		resource_type,
+		resource_id,
+		app_id,
+		actor,
+		address,
+		diff,
+		created_at
+	)
+	VALUES (
+		:id,
+		:action,
+		:resource_type,
+		:resource_id,
+		:app_id,
+		:actor,
+		:address,
+		:diff,
+		:created_at
+	);`)
+
+	_, err := ds.writer(ctx).NamedExecContext(ctx, query, entry)
+	return err
+}
+
+func (ds *SQLStore) GetAuditEntries(ctx context.Context, filter *models.AuditEntryFilter) (*models.AuditEntryList, error) {
+	res := &models.AuditEntryList{Items: []*models.AuditEntry{}}
+
+	var b bytes.Buffer
+	var args []interface{}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = where(&b, args, "id<?", string(s))
+	}
+	args = where(&b, args, "app_id=?", filter.AppID)
+	args = where(&b, args, "resource_type=?", filter.ResourceType)
+	args = where(&b, args, "resource_id=?", filter.ResourceID)
+	args = where(&b, args, "actor=?", filter.Actor)
+	if !time.Time(filter.ToTime).IsZero() {
+		args = where(&b, args, "created_at<?", filter.ToTime.String())
+	}
+	if !time.Time(filter.FromTime).IsZero() {
+		args = where(&b, args, "created_at>?", filter.FromTime.String())
+	}
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s %s", auditEntrySelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.StructScan(&entry); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &entry)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
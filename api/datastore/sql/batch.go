@@ -0,0 +1,130 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *SQLStore) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	applied := make([]*models.BatchItem, 0, len(items))
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		for i, item := range items {
+			res, err := ds.applyBatchItem(ctx, tx, item)
+			if err != nil {
+				return &models.BatchError{Index: i, Err: err}
+			}
+			applied = append(applied, res)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (ds *SQLStore) applyBatchItem(ctx context.Context, tx *sqlx.Tx, item *models.BatchItem) (*models.BatchItem, error) {
+	res := &models.BatchItem{Action: item.Action, SoftDelete: item.SoftDelete}
+	var err error
+
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.App, err = ds.insertApp(ctx, tx, item.App)
+		case models.BatchUpdate:
+			res.App, err = updateApp(ctx, tx, item.App)
+		case models.BatchDelete:
+			res.App, err = deleteBatchApp(ctx, tx, item.App.ID, item.SoftDelete)
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Fn, err = ds.insertFn(ctx, tx, item.Fn)
+		case models.BatchUpdate:
+			res.Fn, err = updateFn(ctx, tx, item.Fn)
+		case models.BatchDelete:
+			res.Fn, err = deleteBatchFn(ctx, tx, item.Fn.ID, item.SoftDelete)
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			res.Trigger, err = ds.insertTrigger(ctx, tx, item.Trigger)
+		case models.BatchUpdate:
+			res.Trigger, err = updateTrigger(ctx, tx, item.Trigger)
+		case models.BatchDelete:
+			res.Trigger, err = deleteBatchTrigger(ctx, tx, item.Trigger.ID)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// deleteBatchApp deletes a live app, returning it as it was before
+func deleteBatchApp(ctx context.Context, tx *sqlx.Tx, appID string, soft bool) (*models.App, error) {
+	var app models.App
+	err := tx.QueryRowxContext(ctx, tx.Rebind(appIDSelector), appID).StructScan(&app)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAppsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if soft {
+		err = softDeleteApp(ctx, tx, appID)
+	} else {
+		err = removeApp(ctx, tx, appID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// deleteBatchFn deletes a live fn, returning it as it was before
+func deleteBatchFn(ctx context.Context, tx *sqlx.Tx, fnID string, soft bool) (*models.Fn, error) {
+	var fn models.Fn
+	err := tx.QueryRowxContext(ctx, tx.Rebind(fnIDSelector), fnID).StructScan(&fn)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrFnsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if soft {
+		err = softDeleteFn(ctx, tx, fnID)
+	} else {
+		err = removeFn(ctx, tx, fnID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+// deleteBatchTrigger removes a trigger, returning it as it was before
+func deleteBatchTrigger(ctx context.Context, tx *sqlx.Tx, triggerID string) (*models.Trigger, error) {
+	var trigger models.Trigger
+	err := tx.QueryRowxContext(ctx, tx.Rebind(triggerIDSelector), triggerID).StructScan(&trigger)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrTriggerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = removeTrigger(ctx, tx, triggerID)
+	if err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
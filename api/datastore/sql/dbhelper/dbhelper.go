@@ -3,9 +3,11 @@ package dbhelper
 
 import (
 	"fmt"
+	"net/url"
+	"time"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
-	"net/url"
 )
 
 var sqlHelpers []Helper
@@ -27,10 +29,87 @@ type Helper interface {
 	PostCreate(db *sqlx.DB) (*sqlx.DB, error)
 	// CheckTableExists checks if a table exists in the DB
 	CheckTableExists(tx *sqlx.Tx, table string) (bool, error)
+	// CheckIndexExists checks if an index of a table exists in the DB
+	CheckIndexExists(tx *sqlx.Tx, table, index string) (bool, error)
 	// IsDuplicateKeyError determines if an error indicates if the prior error was caused by a duplicate key insert
 	IsDuplicateKeyError(err error) bool
 }
 
+// FullTextSearcher is implemented by the helpers of dbs with full-text search. The search
+// documents of a datastore are otherwise a plain table, whose text is matched with LIKE.
+type FullTextSearcher interface {
+	// SearchTableStatements returns the statements creating the search_documents table, if it
+	// does not exist, with the full-text index of its body column
+	SearchTableStatements() []string
+	// SearchCondition returns the condition on the search_documents table matching the rows
+	// whose body has all of terms, words of lower case letters and digits, with its args
+	SearchCondition(terms []string) (string, []interface{})
+}
+
+// StatementTimeouter is implemented by the helpers of dbs which can cancel the statements
+// running for too long
+type StatementTimeouter interface {
+	// WithStatementTimeout returns a copy of url whose connections cancel the statements
+	// running for longer than timeout
+	WithStatementTimeout(url *url.URL, timeout time.Duration) *url.URL
+}
+
+// TLS modes of the connections to dbs, named after the sslmode of postgres
+const (
+	// TLSDisable connects in plain text
+	TLSDisable = "disable"
+	// TLSRequire encrypts connections without verifying the certificate of the db
+	TLSRequire = "require"
+	// TLSVerifyCA encrypts connections to dbs whose certificate is signed by the CA
+	TLSVerifyCA = "verify-ca"
+	// TLSVerifyFull encrypts connections to dbs whose certificate is signed by the CA and
+	// matches their host name
+	TLSVerifyFull = "verify-full"
+)
+
+// TLS configures the encryption of the connections to a db. The files are read again by new
+// connections, certificates are renewed by replacing them.
+type TLS struct {
+	// Mode is one of the TLS modes
+	Mode string
+	// CAFile is the PEM bundle of the CAs verifying the db, the CAs of the system if empty
+	CAFile string
+	// CertFile and KeyFile are the PEM certificate and key of the client, if the db
+	// authenticates clients by certificate
+	CertFile string
+	KeyFile  string
+}
+
+// TLSConfigurer is implemented by the helpers of dbs which can encrypt their connections
+type TLSConfigurer interface {
+	// WithTLS returns a copy of url whose connections are encrypted as configured by tls
+	WithTLS(url *url.URL, tls *TLS) (*url.URL, error)
+}
+
+// TokenAuthenticator is implemented by the helpers of dbs which can authenticate with short
+// lived tokens as passwords, as managed dbs with IAM authentication do
+type TokenAuthenticator interface {
+	// Endpoint returns the host:port of the db of url, which tokens are issued for
+	Endpoint(url *url.URL) (string, error)
+	// WithToken returns a copy of url authenticating with token
+	WithToken(url *url.URL, token string) *url.URL
+	// Encrypted returns whether the TLS parameters of url encrypt all its connections, which
+	// tokens are only sent over
+	Encrypted(url *url.URL) bool
+}
+
+// WriteLocker is implemented by the helpers of embedded dbs which lock the whole db for each
+// write. The writes of the datastores of such a db are made one at a time, rather than
+// failing as it is locked by another.
+type WriteLocker interface {
+	// WithLocking returns a copy of url whose connections use journalMode and synchronous,
+	// unless empty, and wait for up to busyTimeout for the db to be unlocked, unless zero.
+	// The settings of url take precedence.
+	WithLocking(url *url.URL, journalMode, synchronous string, busyTimeout time.Duration) *url.URL
+	// WriteLockKey returns the key of the db of url, the same for all the urls of a db
+	WriteLockKey(url *url.URL) string
+}
+
 // GetHelper returns a helper for a specific driver
 func GetHelper(driverName string) (Helper, bool) {
 	for _, helper := range sqlHelpers {
@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const domainSelector = `SELECT id,hostname,path_prefix,app_id,created_at FROM domains`
+
+func (ds *SQLStore) InsertDomain(ctx context.Context, newDomain *models.Domain) (*models.Domain, error) {
+	domain := *newDomain
+	domain.ID = id.New().String()
+	domain.CreatedAt = common.DateTime(time.Now())
+
+	err := domain.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+		r := tx.QueryRowContext(ctx, query, domain.AppID)
+		if err := r.Scan(new(int)); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrAppsNotFound
+			}
+			return err
+		}
+
+		// the paths of a hostname can't be taken over by other apps
+		query = tx.Rebind(`SELECT 1 FROM domains WHERE hostname=? AND app_id<>?`)
+		r = tx.QueryRowContext(ctx, query, domain.Hostname, domain.AppID)
+		if err := r.Scan(new(int)); err != sql.ErrNoRows {
+			if err == nil {
+				return models.ErrDomainHostnameTaken
+			}
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO domains (
+			id,
+			hostname,
+			path_prefix,
+			app_id,
+			created_at
+		)
+		VALUES (
+			:id,
+			:hostname,
+			:path_prefix,
+			:app_id,
+			:created_at
+		);`)
+
+		_, err := tx.NamedExecContext(ctx, query, &domain)
+		return err
+	})
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrDomainExists
+		}
+		return nil, err
+	}
+
+	return &domain, nil
+}
+
+func (ds *SQLStore) GetDomainByID(ctx context.Context, domainID string) (*models.Domain, error) {
+	var domain models.Domain
+	query := ds.db.Rebind(domainSelector + ` WHERE id=?`)
+	err := ds.reader(ctx).QueryRowxContext(ctx, query, domainID).StructScan(&domain)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrDomainNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+func (ds *SQLStore) GetDomains(ctx context.Context, filter *models.DomainFilter) (*models.DomainList, error) {
+	res := &models.DomainList{Items: []*models.Domain{}}
+	if filter == nil {
+		filter = new(models.DomainFilter)
+	}
+
+	var b bytes.Buffer
+	var args []interface{}
+	fmt.Fprintf(&b, `1=1`)
+	if filter.AppID != "" {
+		fmt.Fprintf(&b, ` AND app_id = ?`)
+		args = append(args, filter.AppID)
+	}
+	if filter.Hostname != "" {
+		fmt.Fprintf(&b, ` AND hostname = ?`)
+		args = append(args, filter.Hostname)
+	}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id > ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", domainSelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var domain models.Domain
+		if err := rows.StructScan(&domain); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &domain)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) GetDomainsByHostname(ctx context.Context, hostname string) ([]*models.Domain, error) {
+	res, err := ds.GetDomains(ctx, &models.DomainFilter{Hostname: hostname})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (ds *SQLStore) RemoveDomain(ctx context.Context, domainID string) error {
+	query := ds.db.Rebind(`DELETE FROM domains WHERE id=?`)
+	res, err := ds.writer(ctx).ExecContext(ctx, query, domainID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrDomainNotFound
+	}
+	return nil
+}
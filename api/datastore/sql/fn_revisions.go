@@ -0,0 +1,132 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const fnRevisionSelector = `SELECT fn_id,revision,app_id,image,config,created_at FROM fn_revisions`
+
+// recordFnRevisions numbers and records the revisions of fn left to the datastore, its
+// current revision and the candidate of its canary, see models.Fn.Update.
+func recordFnRevisions(ctx context.Context, tx *sqlx.Tx, fn *models.Fn) error {
+	newCandidate := !fn.Canary.IsEmpty() && fn.Canary.Revision == 0
+	if fn.Revision != 0 && !newCandidate {
+		return nil
+	}
+
+	var last sql.NullInt64
+	query := tx.Rebind(`SELECT MAX(revision) FROM fn_revisions WHERE fn_id=?`)
+	if err := tx.QueryRowContext(ctx, query, fn.ID).Scan(&last); err != nil {
+		return err
+	}
+	next := last.Int64 + 1
+
+	if fn.Revision == 0 {
+		fn.Revision = next
+		next++
+		if err := insertFnRevision(ctx, tx, fn.CurrentRevision()); err != nil {
+			return err
+		}
+	}
+	if newCandidate {
+		fn.Canary.Revision = next
+		return insertFnRevision(ctx, tx, fn.CandidateRevision())
+	}
+	return nil
+}
+
+// insertFnRevision records a revision of a fn, in the transaction changing the fn
+func insertFnRevision(ctx context.Context, tx *sqlx.Tx, rev *models.FnRevision) error {
+	query := tx.Rebind(`INSERT INTO fn_revisions (
+		fn_id,
+		revision,
+		app_id,
+		image,
+		config,
+		created_at
+	)
+	VALUES (
+		:fn_id,
+		:revision,
+		:app_id,
+		:image,
+		:config,
+		:created_at
+	);`)
+
+	_, err := tx.NamedExecContext(ctx, query, rev)
+	return err
+}
+
+func (ds *SQLStore) GetFnRevision(ctx context.Context, fnID string, revision int64) (*models.FnRevision, error) {
+	query := ds.db.Rebind(fnRevisionSelector + ` WHERE fn_id=? AND revision=?`)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, fnID, revision)
+
+	var rev models.FnRevision
+	err := row.StructScan(&rev)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrFnRevisionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (ds *SQLStore) GetFnRevisions(ctx context.Context, filter *models.FnRevisionFilter) (*models.FnRevisionList, error) {
+	res := &models.FnRevisionList{Items: []*models.FnRevision{}}
+
+	var b bytes.Buffer
+	args := []interface{}{filter.FnID}
+	fmt.Fprintf(&b, `fn_id = ?`)
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor, err := strconv.ParseInt(string(s), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND revision < ?`)
+		args = append(args, cursor)
+	}
+	fmt.Fprintf(&b, ` ORDER BY revision DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", fnRevisionSelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rev models.FnRevision
+		if err := rows.StructScan(&rev); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &rev)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(strconv.FormatInt(res.Items[len(res.Items)-1].Revision, 10))
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,192 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds/rdsutils"
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvDBIAMAuth authenticates to managed dbs with short lived IAM tokens rather than the
+	// password of the URL, with aws for RDS or gcp for Cloud SQL. Tokens are issued for each
+	// new connection, which must be encrypted: the URL is rejected unless EnvDBTLSMode or its
+	// TLS parameters, tls for mysql or sslmode for postgres, encrypt all connections.
+	EnvDBIAMAuth = "FN_DS_DB_IAM_AUTH"
+	// EnvDBIAMRegion is the region of the RDS db, the region of the AWS env by default
+	EnvDBIAMRegion = "FN_DS_DB_IAM_REGION"
+
+	// gcpMetadataHost is the GCE metadata server issuing the tokens of the service account
+	// of the instance, which GCE_METADATA_HOST overrides
+	gcpMetadataHost   = "metadata.google.internal"
+	gcpTokenPath      = "/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpTokenLeeway    = time.Minute
+	gcpTokenTimeout   = 10 * time.Second
+	gcpMetadataFlavor = "Google"
+)
+
+// tokenSource issues the tokens authenticating user to the db at endpoint
+type tokenSource interface {
+	Token(ctx context.Context, endpoint, user string) (string, error)
+}
+
+func newTokenSource(auth string) (tokenSource, error) {
+	switch auth {
+	case "aws":
+		config := aws.NewConfig()
+		if region := os.Getenv(EnvDBIAMRegion); region != "" {
+			config = config.WithRegion(region)
+		}
+		sess, err := session.NewSession(config)
+		if err != nil {
+			return nil, err
+		}
+		region := aws.StringValue(sess.Config.Region)
+		if region == "" {
+			return nil, fmt.Errorf("no region for the RDS db, set %s or AWS_REGION", EnvDBIAMRegion)
+		}
+		return &rdsTokens{region: region, creds: sess.Config.Credentials}, nil
+	case "gcp":
+		host := os.Getenv("GCE_METADATA_HOST")
+		if host == "" {
+			host = gcpMetadataHost
+		}
+		return &cloudSQLTokens{
+			url:    "http://" + host + gcpTokenPath,
+			client: &http.Client{Timeout: gcpTokenTimeout},
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid %s=%s, expected aws or gcp", EnvDBIAMAuth, auth)
+}
+
+// rdsTokens signs RDS tokens with the credentials of the AWS env, which are valid for 15 minutes
+type rdsTokens struct {
+	region string
+	creds  *credentials.Credentials
+}
+
+func (r *rdsTokens) Token(ctx context.Context, endpoint, user string) (string, error) {
+	return rdsutils.BuildAuthToken(endpoint, r.region, user, r.creds)
+}
+
+// cloudSQLTokens gets the OAuth2 access tokens of the service account of the instance from
+// the metadata server, caching them until they are about to expire. Cloud SQL takes them as
+// the passwords of the IAM users of the service account.
+type cloudSQLTokens struct {
+	url    string
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (c *cloudSQLTokens) Token(ctx context.Context, endpoint, user string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(gcpTokenLeeway).Before(c.expiry) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", gcpMetadataFlavor)
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot get a Cloud SQL token: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot get a Cloud SQL token: metadata server returned %s", resp.Status)
+	}
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("cannot get a Cloud SQL token: %s", err)
+	}
+	c.token = t.AccessToken
+	c.expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// tokenConnector opens the connections of a db with the dsn of the time, the password of
+// previous connections being expired
+type tokenConnector struct {
+	driver driver.Driver
+	dsn    func(ctx context.Context) (string, error)
+}
+
+var _ driver.Connector = new(tokenConnector)
+
+func (c *tokenConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.dsn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c *tokenConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// connectorWithTokens returns the connector of the db of u, authenticating its connections with
+// the IAM tokens of auth
+func connectorWithTokens(ctx context.Context, helper dbhelper.Helper, u *url.URL, auth string, log logrus.FieldLogger) (driver.Connector, error) {
+	a, ok := helper.(dbhelper.TokenAuthenticator)
+	if !ok {
+		return nil, fmt.Errorf("the %s db can't authenticate with IAM tokens", u.Scheme)
+	}
+	if !a.Encrypted(u) {
+		return nil, fmt.Errorf("the connections to the %s db must be encrypted to authenticate with IAM tokens, set %s or the TLS parameters of the URL", u.Scheme, EnvDBTLSMode)
+	}
+	endpoint, err := a.Endpoint(u)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := newTokenSource(auth)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User.Username()
+	dsn := func(ctx context.Context) (string, error) {
+		token, err := tokens.Token(ctx, endpoint, user)
+		if err != nil {
+			return "", err
+		}
+		return helper.PreConnect(a.WithToken(u, token))
+	}
+
+	// database/sql only hands out the driver of a scheme with a db, which also checks that
+	// tokens can be issued
+	uri, err := dsn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(u.Scheme, uri)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	log.WithFields(logrus.Fields{"iam_auth": auth, "endpoint": endpoint, "user": user}).Info("db connections authenticated with IAM tokens")
+	return &tokenConnector{driver: drv, dsn: dsn}, nil
+}
@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/fnproject/fn/api/datastore/sql/migrations"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvDBMigrateDryRun prints the statements migrating the schema of the db rather than
+	// executing them, fn exits once printed
+	EnvDBMigrateDryRun = "FN_DS_DB_MIGRATE_DRY_RUN"
+	// EnvDBMigrateDownTo migrates the schema of the db down to the version, for the fn
+	// releases of that version to run against it once fn is rolled back, fn exits once migrated.
+	// sqlite dbs, which can't drop columns, can't be migrated down past the migrations adding some.
+	EnvDBMigrateDownTo = "FN_DS_DB_MIGRATE_DOWN_TO"
+)
+
+// ErrMigrateOnly is returned by the datastore once the schema of its db is migrated, or
+// printed, as EnvDBMigrateDryRun or EnvDBMigrateDownTo request
+var ErrMigrateOnly = errors.New("datastore migrations only were requested, " + EnvDBMigrateDryRun + " or " + EnvDBMigrateDownTo + " is set")
+
+// migrateOnly runs the migrations requested by EnvDBMigrateDryRun or EnvDBMigrateDownTo, if
+// any, reporting whether it did
+func (ds *SQLStore) migrateOnly(ctx context.Context, log logrus.FieldLogger) (bool, error) {
+	dryRun, _ := strconv.ParseBool(os.Getenv(EnvDBMigrateDryRun))
+	downTo := os.Getenv(EnvDBMigrateDownTo)
+	if !dryRun && downTo == "" {
+		return false, nil
+	}
+
+	up, target := true, latestVersion(migrations.Migrations)
+	if downTo != "" {
+		version, err := strconv.ParseInt(downTo, 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("cannot parse invalid %s=%s", EnvDBMigrateDownTo, downTo)
+		}
+		up, target = false, version
+	}
+
+	if dryRun {
+		return true, ds.printMigrations(ctx, os.Stdout, up, target, log)
+	}
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		if err := migratex.CheckVersion(ctx, tx, migrations.Migrations); err != nil {
+			return err
+		}
+		return migratex.DownTo(ctx, tx, migrations.Migrations, target)
+	})
+	if err != nil {
+		return true, err
+	}
+	log.WithFields(logrus.Fields{"version": target}).Info("datastore schema migrated down")
+	return true, nil
+}
+
+// printMigrations prints to w the statements which would migrate the db up, or down, to target
+func (ds *SQLStore) printMigrations(ctx context.Context, w io.Writer, up bool, target int64, log logrus.FieldLogger) error {
+	var stmts []migratex.Statement
+	err := ds.Tx(func(tx *sqlx.Tx) error {
+		if err := migratex.CheckVersion(ctx, tx, migrations.Migrations); err != nil {
+			return err
+		}
+		// new dbs are created with the latest schema rather than migrated, see runMigrations
+		exists, err := ds.helper.CheckTableExists(tx, "apps")
+		if err != nil || exists {
+			return err
+		}
+		for _, t := range append(tables[:], searchTables(ds.helper)...) {
+			stmts = append(stmts, migratex.Statement{Version: target, Up: true, Query: t})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if stmts == nil {
+		stmts, err = migratex.DryRun(ctx, ds.db, migrations.Migrations, up, target)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, s := range stmts {
+		dir := "up"
+		if !s.Up {
+			dir = "down"
+		}
+		if _, err := fmt.Fprintf(w, "-- migration %d %s\n%s;\n\n", s.Version, dir, strings.TrimRight(strings.TrimSpace(s.Query), ";")); err != nil {
+			return err
+		}
+	}
+	log.WithFields(logrus.Fields{"statements": len(stmts), "version": target}).Info("datastore migrations printed, nothing executed")
+	return nil
+}
@@ -0,0 +1,123 @@
+package migratex
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Statement is a statement that a migration executes
+type Statement struct {
+	Version int64
+	Up      bool
+	Query   string
+}
+
+// DryRun returns the statements of the migrations which would migrate db up, or down, to
+// target, without executing them. Migrations still run their queries, against the schema the
+// db has before the migrations, so the statements of the migrations inspecting it may differ
+// from the ones they execute once the previous migrations are applied.
+func DryRun(ctx context.Context, db *sqlx.DB, migs []Migration, up bool, target int64) ([]Statement, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var stmts []Statement
+	err = conn.Raw(func(dc interface{}) error {
+		rec := &recordingConn{Conn: dc.(driver.Conn)}
+		rdb := sqlx.NewDb(sql.OpenDB(&borrowedConnector{conn: rec, driver: db.Driver()}), db.DriverName())
+		rdb.SetMaxOpenConns(1)
+		defer rdb.Close()
+
+		// nothing is executed, the transaction is only there for the migrations
+		tx, err := rdb.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		curVersion, dirty, err := Version(ctx, tx)
+		if dirty {
+			return dirtyErr(curVersion)
+		}
+		if err != nil {
+			return err
+		}
+		for _, m := range pending(migs, curVersion, up, target) {
+			rec.queries = nil
+			if up {
+				err = m.Up(ctx, tx)
+			} else {
+				err = m.Down(ctx, tx)
+			}
+			if err != nil {
+				version := m.Version()
+				if !up {
+					version--
+				}
+				return migrateErr(version, up, err)
+			}
+			for _, q := range rec.queries {
+				stmts = append(stmts, Statement{Version: m.Version(), Up: up, Query: q})
+			}
+		}
+		return nil
+	})
+	return stmts, err
+}
+
+// recordingConn records the statements executed on its connection rather than executing them,
+// passing its queries through
+type recordingConn struct {
+	driver.Conn
+	queries []string
+}
+
+var (
+	_ driver.ExecerContext  = new(recordingConn)
+	_ driver.QueryerContext = new(recordingConn)
+	_ driver.ConnBeginTx    = new(recordingConn)
+)
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries = append(c.queries, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	// database/sql prepares the query instead
+	return nil, driver.ErrSkip
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// Close leaves the connection to the db it is borrowed from
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+// borrowedConnector hands out a connection borrowed from another db
+type borrowedConnector struct {
+	conn   driver.Conn
+	driver driver.Driver
+}
+
+func (c *borrowedConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c *borrowedConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -78,14 +78,23 @@ func (m MigFields) Version() int64                              { return m.Versi
 // TODO instance must have `multiStatements` set to true ?
 
 func Up(ctx context.Context, tx *sqlx.Tx, migs []Migration) error {
-	return migrate(ctx, tx, migs, true)
+	return migrate(ctx, tx, migs, true, latest(migs))
 }
 
 func Down(ctx context.Context, tx *sqlx.Tx, migs []Migration) error {
-	return migrate(ctx, tx, migs, false)
+	return migrate(ctx, tx, migs, false, NilVersion)
 }
 
-func migrate(ctx context.Context, tx *sqlx.Tx, migs []Migration, up bool) error {
+// DownTo runs the down migrations of the versions after version, leaving the db at version
+// so that the older fn of that version can run against it
+func DownTo(ctx context.Context, tx *sqlx.Tx, migs []Migration, version int64) error {
+	if version < NilVersion || version > latest(migs) {
+		return fmt.Errorf("cannot migrate down to unknown version %v", version)
+	}
+	return migrate(ctx, tx, migs, false, version)
+}
+
+func migrate(ctx context.Context, tx *sqlx.Tx, migs []Migration, up bool, target int64) error {
 	curVersion, dirty, err := Version(ctx, tx)
 	if dirty {
 		return dirtyErr(curVersion)
@@ -99,31 +108,69 @@ func migrate(ctx context.Context, tx *sqlx.Tx, migs []Migration, up bool) error
 	// so that we can make as much progress as possible if we hit an error.
 	// not sure it makes much difference either way where we lock.
 
+	for _, m := range pending(migs, curVersion, up, target) {
+		// do each individually, for large migrations it's better to checkpoint
+		// than to try to do them all in one big go.
+		// XXX(reed): we could more gracefully handle concurrent databases trying to
+		// run migrations here by handling error and feeding back the version.
+		// get something working mode for now...
+		err := run(ctx, tx, m, up)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pending returns the migrations to run, in order, to migrate a db from curVersion to target
+func pending(migs []Migration, curVersion int64, up bool, target int64) []Migration {
+	migs = append([]Migration(nil), migs...)
 	if up {
 		sort.Sort(sorted(migs))
 	} else {
 		sort.Sort(sort.Reverse(sorted(migs)))
 	}
+	var res []Migration
 	for _, m := range migs {
 		// skip over migrations we have run
 		mVersion := m.Version()
-		if (up && curVersion < mVersion) || (!up && curVersion >= mVersion) {
-
-			// do each individually, for large migrations it's better to checkpoint
-			// than to try to do them all in one big go.
-			// XXX(reed): we could more gracefully handle concurrent databases trying to
-			// run migrations here by handling error and feeding back the version.
-			// get something working mode for now...
-			err := run(ctx, tx, m, up)
-			if err != nil {
-				return err
-			}
+		if (up && curVersion < mVersion && mVersion <= target) || (!up && curVersion >= mVersion && mVersion > target) {
+			res = append(res, m)
 		}
 	}
+	return res
+}
 
+func latest(migs []Migration) int64 {
+	highest := int64(NilVersion)
+	for _, m := range migs {
+		if m.Version() > highest {
+			highest = m.Version()
+		}
+	}
+	return highest
+}
+
+// CheckVersion returns an ErrNewerSchema if the schema of the db is newer than the latest of
+// migs, as it is once a newer fn migrated it. Running against it would corrupt its data.
+func CheckVersion(ctx context.Context, tx *sqlx.Tx, migs []Migration) error {
+	curVersion, _, err := Version(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if latest := latest(migs); curVersion > latest {
+		return ErrNewerSchema(fmt.Sprintf("database schema version %v is newer than the latest known version %v, "+
+			"upgrade or migrate the database down with the newer version", curVersion, latest))
+	}
 	return nil
 }
 
+// ErrNewerSchema is returned when the schema of a db is newer than the migrations known.
+type ErrNewerSchema string
+
+func (e ErrNewerSchema) Error() string { return string(e) }
+
 func withLock(ctx context.Context, tx *sqlx.Tx, f func(*sqlx.Tx) error) error {
 	err := lock(ctx, tx)
 	if err != nil {
@@ -98,3 +98,84 @@ func TestMigrateUp(t *testing.T) {
 		t.Fatalf("migration check failed: %v", err)
 	}
 }
+
+func TestMigrateDownToAndDryRun(t *testing.T) {
+	bar := &MigFields{
+		VersionFunc: func() int64 { return 2 },
+		UpFunc: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "CREATE TABLE bar (baz bigint NOT NULL PRIMARY KEY)")
+			return err
+		},
+		DownFunc: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "DROP TABLE bar")
+			return err
+		},
+	}
+	migs := []Migration{new(tm), bar}
+
+	db, err := sqlx.Open("sqlite3", "file:downto?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	version := func() int64 {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Commit()
+		v, _, err := Version(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tx := func(f func(*sqlx.Tx) error) error {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Commit()
+		return f(tx)
+	}
+
+	stmts, err := DryRun(ctx, db, migs, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || stmts[1].Version != 2 || stmts[1].Query != "CREATE TABLE bar (baz bigint NOT NULL PRIMARY KEY)" {
+		t.Fatalf("expected the statements of both migrations, got %+v", stmts)
+	}
+	if v := version(); v != NilVersion {
+		t.Fatalf("expected a dry run to leave the db as is, got version %v", v)
+	}
+
+	if err := tx(func(tx *sqlx.Tx) error { return Up(ctx, tx, migs) }); err != nil {
+		t.Fatal(err)
+	}
+	stmts, err = DryRun(ctx, db, migs, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || stmts[0].Up || stmts[0].Query != "DROP TABLE bar" {
+		t.Fatalf("expected the statement dropping bar, got %+v", stmts)
+	}
+
+	if err := tx(func(tx *sqlx.Tx) error { return DownTo(ctx, tx, migs, 1) }); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(); v != 1 {
+		t.Fatalf("expected the db to be migrated down to version 1, got %v", v)
+	}
+
+	// the fn knowing of the first migration only must not run against the db
+	if err := tx(func(tx *sqlx.Tx) error { return Up(ctx, tx, migs) }); err != nil {
+		t.Fatal(err)
+	}
+	err = tx(func(tx *sqlx.Tx) error { return CheckVersion(ctx, tx, migs[:1]) })
+	if _, ok := err.(ErrNewerSchema); !ok {
+		t.Fatalf("expected a newer schema error, got %v", err)
+	}
+}
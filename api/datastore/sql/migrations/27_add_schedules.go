@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up27(ctx context.Context, tx *sqlx.Tx) error {
+	createQueries := []string{`CREATE TABLE IF NOT EXISTS schedules (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	cron varchar(256) NOT NULL,
+	payload text,
+	next_run_at varchar(256) NOT NULL,
+	annotations text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL,
+	CONSTRAINT schedules_name_app_id_unique UNIQUE (app_id, name)
+);`,
+		`CREATE TABLE IF NOT EXISTS schedule_runs (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	schedule_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	call_id varchar(256),
+	scheduled_at varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	error text
+);`,
+		`CREATE TABLE IF NOT EXISTS leases (
+	name varchar(256) NOT NULL PRIMARY KEY,
+	holder varchar(256) NOT NULL,
+	expires_at varchar(256) NOT NULL
+);`,
+	}
+	for _, q := range createQueries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func down27(ctx context.Context, tx *sqlx.Tx) error {
+	for _, table := range []string{"leases", "schedule_runs", "schedules"} {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE "+table+";"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(27),
+		UpFunc:      up27,
+		DownFunc:    down27,
+	})
+}
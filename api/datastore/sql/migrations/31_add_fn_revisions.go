@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up31(ctx context.Context, tx *sqlx.Tx) error {
+	queries := []string{
+		"ALTER TABLE fns ADD revision int NOT NULL DEFAULT 0;",
+		"ALTER TABLE calls ADD fn_revision int NOT NULL DEFAULT 0;",
+		`CREATE TABLE IF NOT EXISTS fn_revisions (
+	fn_id varchar(256) NOT NULL,
+	revision int NOT NULL,
+	app_id varchar(256) NOT NULL,
+	image varchar(256) NOT NULL,
+	config text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	PRIMARY KEY (fn_id, revision)
+);`,
+		// existing fns start at their first revision, so that they can be rolled back to it
+		"UPDATE fns SET revision = 1;",
+		`INSERT INTO fn_revisions (fn_id, revision, app_id, image, config, created_at)
+	SELECT id, revision, app_id, image, config, updated_at FROM fns;`,
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func down31(ctx context.Context, tx *sqlx.Tx) error {
+	queries := []string{
+		"DROP TABLE fn_revisions;",
+		"ALTER TABLE calls DROP COLUMN fn_revision;",
+		"ALTER TABLE fns DROP COLUMN revision;",
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(31),
+		UpFunc:      up31,
+		DownFunc:    down31,
+	})
+}
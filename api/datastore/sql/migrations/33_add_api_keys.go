@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up33(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS api_keys (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	scope varchar(256) NOT NULL,
+	hash varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT api_keys_hash_unique UNIQUE (hash)
+);`)
+	return err
+}
+
+func down33(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE api_keys;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(33),
+		UpFunc:      up33,
+		DownFunc:    down33,
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up36(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS app_usage (
+	app_id varchar(256) NOT NULL,
+	period varchar(16) NOT NULL,
+	calls bigint NOT NULL,
+	mb_ms bigint NOT NULL,
+	PRIMARY KEY (app_id, period)
+);`)
+	return err
+}
+
+func down36(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE app_usage;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(36),
+		UpFunc:      up36,
+		DownFunc:    down36,
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up37(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS domains (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	hostname varchar(256) NOT NULL,
+	path_prefix varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT domains_hostname_path_prefix_unique UNIQUE (hostname, path_prefix)
+);`)
+	return err
+}
+
+func down37(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE domains;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(37),
+		UpFunc:      up37,
+		DownFunc:    down37,
+	})
+}
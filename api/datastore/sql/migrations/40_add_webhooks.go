@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up40(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS webhooks (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	url varchar(2048) NOT NULL,
+	events text NOT NULL,
+	app_id varchar(256) NOT NULL,
+	secret varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	webhook_id varchar(256) NOT NULL,
+	event_id varchar(256) NOT NULL,
+	event varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	attempts int NOT NULL,
+	status_code int NOT NULL,
+	error text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL
+);`)
+	return err
+}
+
+func down40(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE webhook_deliveries;")
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DROP TABLE webhooks;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(40),
+		UpFunc:      up40,
+		DownFunc:    down40,
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+func up41(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS audit_entries (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	action varchar(256) NOT NULL,
+	resource_type varchar(256) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	actor varchar(256) NOT NULL,
+	address varchar(256) NOT NULL,
+	diff text NOT NULL,
+	created_at varchar(256) NOT NULL
+);`)
+	return err
+}
+
+func down41(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE audit_entries;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(41),
+		UpFunc:      up41,
+		DownFunc:    down41,
+	})
+}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// keyedResource is the resource whose config and annotations are indexed in resource_keys
+type keyedResource struct {
+	ID          string             `db:"id"`
+	AppID       string             `db:"app_id"`
+	Config      models.Config      `db:"config"`
+	Annotations models.Annotations `db:"annotations"`
+}
+
+func up43(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS resource_keys (
+	resource_type varchar(16) NOT NULL,
+	field varchar(16) NOT NULL,
+	name varchar(256) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	value text NOT NULL,
+	PRIMARY KEY (resource_type, field, name, resource_id)
+);`)
+	if err != nil {
+		return err
+	}
+
+	// existing apps, fns and triggers are indexed as they are
+	selectors := map[string]string{
+		"app":     "SELECT id, id AS app_id, config, annotations FROM apps;",
+		"fn":      "SELECT id, app_id, config, annotations FROM fns;",
+		"trigger": "SELECT id, app_id, annotations FROM triggers;",
+	}
+	for resourceType, selector := range selectors {
+		rows, err := tx.QueryxContext(ctx, selector)
+		if err != nil {
+			return err
+		}
+
+		var res []*keyedResource
+		for rows.Next() {
+			var r keyedResource
+			err := rows.StructScan(&r)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			res = append(res, &r)
+		}
+		err = rows.Close()
+		if err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// the rows must be closed before the keys are inserted
+		query := tx.Rebind(`INSERT INTO resource_keys (resource_type, field, name, resource_id, app_id, value) VALUES (?, ?, ?, ?, ?, ?);`)
+		for _, r := range res {
+			for _, k := range models.ResourceKeys(r.Config, r.Annotations) {
+				_, err := tx.ExecContext(ctx, query, resourceType, k.Field, k.Name, r.ID, r.AppID, k.Value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func down43(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE resource_keys;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(43),
+		UpFunc:      up43,
+		DownFunc:    down43,
+	})
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// searchedResource is the resource whose text is indexed in search_documents
+type searchedResource struct {
+	ID          string             `db:"id"`
+	AppID       string             `db:"app_id"`
+	Name        string             `db:"name"`
+	Config      models.Config      `db:"config"`
+	Annotations models.Annotations `db:"annotations"`
+}
+
+func up44(ctx context.Context, tx *sqlx.Tx) error {
+	// dbs with full-text search create the table with its index
+	statements := []string{`CREATE TABLE IF NOT EXISTS search_documents (
+	resource_type varchar(16) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	body text NOT NULL,
+	PRIMARY KEY (resource_type, resource_id)
+);`}
+	if helper, ok := dbhelper.GetHelper(tx.DriverName()); ok {
+		if s, ok := helper.(dbhelper.FullTextSearcher); ok {
+			statements = s.SearchTableStatements()
+		}
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	selectors := map[string]string{
+		models.SearchTypeApp: "SELECT id, id AS app_id, name, config, annotations FROM apps;",
+		models.SearchTypeFn:  "SELECT id, app_id, name, config, annotations FROM fns;",
+	}
+	for resourceType, selector := range selectors {
+		rows, err := tx.QueryxContext(ctx, selector)
+		if err != nil {
+			return err
+		}
+
+		var res []*searchedResource
+		for rows.Next() {
+			var r searchedResource
+			err := rows.StructScan(&r)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			res = append(res, &r)
+		}
+		err = rows.Close()
+		if err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		query := tx.Rebind(`INSERT INTO search_documents (resource_type, resource_id, app_id, name, body) VALUES (?, ?, ?, ?, ?);`)
+		for _, r := range res {
+			_, err := tx.ExecContext(ctx, query, resourceType, r.ID, r.AppID, r.Name, models.SearchText(r.Name, r.Config, r.Annotations))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func down44(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE search_documents;")
+	return err
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(44),
+		UpFunc:      up44,
+		DownFunc:    down44,
+	})
+}
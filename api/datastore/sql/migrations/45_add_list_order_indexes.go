@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+// listOrderIndexes are the indexes of the lists of apps, fns and triggers ordered by name and
+// time, by table and name
+var listOrderIndexes = [][3]string{
+	{"apps", "apps_created_at_id", "created_at, id"},
+	{"apps", "apps_updated_at_id", "updated_at, id"},
+	{"fns", "fns_app_id_created_at_id", "app_id, created_at, id"},
+	{"fns", "fns_app_id_updated_at_id", "app_id, updated_at, id"},
+	{"triggers", "triggers_app_id_name", "app_id, name"},
+	{"triggers", "triggers_app_id_created_at_id", "app_id, created_at, id"},
+	{"triggers", "triggers_app_id_updated_at_id", "app_id, updated_at, id"},
+}
+
+func up45(ctx context.Context, tx *sqlx.Tx) error {
+	for _, idx := range listOrderIndexes {
+		/* #nosec */
+		_, err := tx.ExecContext(ctx, "CREATE INDEX "+idx[1]+" ON "+idx[0]+" ("+idx[2]+");")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func down45(ctx context.Context, tx *sqlx.Tx) error {
+	for _, idx := range listOrderIndexes {
+		query := "DROP INDEX " + idx[1] + ";"
+		if tx.DriverName() == "mysql" {
+			query = "DROP INDEX " + idx[1] + " ON " + idx[0] + ";"
+		}
+		/* #nosec */
+		_, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(45),
+		UpFunc:      up45,
+		DownFunc:    down45,
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/jmoiron/sqlx"
+)
+
+// results are the response bodies of functions, which need not be text. sqlite3 keeps the
+// blobs inserted in a text column as they are.
+func up48(ctx context.Context, tx *sqlx.Tx) error {
+	switch tx.DriverName() {
+	case "mysql":
+		_, err := tx.ExecContext(ctx, "ALTER TABLE results MODIFY result longblob NOT NULL;")
+		return err
+	case "postgres", "pgx":
+		_, err := tx.ExecContext(ctx, "ALTER TABLE results ALTER COLUMN result TYPE bytea USING convert_to(result, 'UTF8');")
+		return err
+	}
+	return nil
+}
+
+func down48(ctx context.Context, tx *sqlx.Tx) error {
+	switch tx.DriverName() {
+	case "mysql":
+		_, err := tx.ExecContext(ctx, "ALTER TABLE results MODIFY result text NOT NULL;")
+		return err
+	case "postgres", "pgx":
+		_, err := tx.ExecContext(ctx, "ALTER TABLE results ALTER COLUMN result TYPE text USING convert_from(result, 'UTF8');")
+		return err
+	}
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(48),
+		UpFunc:      up48,
+		DownFunc:    down48,
+	})
+}
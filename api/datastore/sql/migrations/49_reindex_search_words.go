@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/datastore/sql/migratex"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// the bodies of search documents become the words of the names and keys of resources, which
+// the terms of searches match by prefix whether the db has full-text search or not
+func up49(ctx context.Context, tx *sqlx.Tx) error {
+	selectors := map[string]string{
+		models.SearchTypeApp: "SELECT id, id AS app_id, name, config, annotations FROM apps;",
+		models.SearchTypeFn:  "SELECT id, app_id, name, config, annotations FROM fns;",
+	}
+	for resourceType, selector := range selectors {
+		rows, err := tx.QueryxContext(ctx, selector)
+		if err != nil {
+			return err
+		}
+
+		var res []*searchedResource
+		for rows.Next() {
+			var r searchedResource
+			err := rows.StructScan(&r)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			res = append(res, &r)
+		}
+		err = rows.Close()
+		if err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		query := tx.Rebind(`UPDATE search_documents SET body=? WHERE resource_type=? AND resource_id=?;`)
+		for _, r := range res {
+			_, err := tx.ExecContext(ctx, query, models.SearchText(r.Name, r.Config, r.Annotations), resourceType, r.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// the words of the bodies still match the searches of earlier versions
+func down49(ctx context.Context, tx *sqlx.Tx) error {
+	return nil
+}
+
+func init() {
+	Migrations = append(Migrations, &migratex.MigFields{
+		VersionFunc: vfunc(49),
+		UpFunc:      up49,
+		DownFunc:    down49,
+	})
+}
@@ -1,8 +1,12 @@
 package mysql
 
 import (
+	"fmt"
+	"net"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
 	"github.com/go-sql-driver/mysql"
@@ -41,6 +45,23 @@ func (mysqlHelper) CheckTableExists(tx *sqlx.Tx, table string) (bool, error) {
 	return exists, nil
 }
 
+func (mysqlHelper) CheckIndexExists(tx *sqlx.Tx, table, index string) (bool, error) {
+	query := tx.Rebind(`SELECT count(*)
+	FROM information_schema.STATISTICS
+	WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`)
+
+	row := tx.QueryRow(query, table, index)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	exists := count > 0
+	return exists, nil
+}
+
 func (mysqlHelper) String() string {
 	return "mysql"
 }
@@ -55,6 +76,63 @@ func (mysqlHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+// WithStatementTimeout sets the max_execution_time of the sessions, which the mysql driver
+// sets for the parameters it does not know. MySQL 5.7.8 and later only time out SELECTs.
+func (mysqlHelper) WithStatementTimeout(u *url.URL, timeout time.Duration) *url.URL {
+	cl := *u
+	q := cl.Query()
+	q.Set("max_execution_time", strconv.FormatInt(int64(timeout/time.Millisecond), 10))
+	cl.RawQuery = q.Encode()
+	return &cl
+}
+
+// Endpoint implements dbhelper.TokenAuthenticator
+func (mysqlHelper) Endpoint(u *url.URL) (string, error) {
+	host, port, err := dbAddr(u)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// WithToken sets the password of the URL to token, which mysql gets in clear text from the
+// authentication plugins of managed dbs. Connections must be encrypted with TLS.
+func (mysqlHelper) WithToken(u *url.URL, token string) *url.URL {
+	cl := *u
+	cl.User = url.UserPassword(u.User.Username(), token)
+	q := cl.Query()
+	q.Set("allowCleartextPasswords", "true")
+	cl.RawQuery = q.Encode()
+	return &cl
+}
+
+// Encrypted implements dbhelper.TokenAuthenticator, the preferred tls mode falls back to
+// unencrypted connections
+func (mysqlHelper) Encrypted(u *url.URL) bool {
+	switch u.Query().Get("tls") {
+	case "", "false", "preferred":
+		return false
+	}
+	return true
+}
+
+// dbAddr returns the host and port of the db of u, whose host is of the form tcp(host:port)
+func dbAddr(u *url.URL) (host, port string, err error) {
+	addr := u.Host
+	if i := strings.Index(addr, "("); i >= 0 && strings.HasSuffix(addr, ")") {
+		addr = addr[i+1 : len(addr)-1]
+	}
+	host, port, err = net.SplitHostPort(addr)
+	if err != nil {
+		// no port
+		host, port = addr, "3306"
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("no db host in %s", u.Host)
+	}
+	return host, port, nil
+}
+
 func init() {
 	dbhelper.Register(mysqlHelper(0))
 }
@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/go-sql-driver/mysql"
+)
+
+// tlsConfigs counts the TLS configs registered to the driver, naming them
+var tlsConfigs uint32
+
+// WithTLS registers a TLS config to the mysql driver and sets the tls parameter of the URL to
+// it. The files are read again on each handshake.
+func (mysqlHelper) WithTLS(u *url.URL, t *dbhelper.TLS) (*url.URL, error) {
+	cl := *u
+	q := cl.Query()
+	if t.Mode == dbhelper.TLSDisable {
+		q.Set("tls", "false")
+		cl.RawQuery = q.Encode()
+		return &cl, nil
+	}
+
+	conf, err := newTLSConfig(u, t)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("fn-%d", atomic.AddUint32(&tlsConfigs, 1))
+	if err := mysql.RegisterTLSConfig(name, conf); err != nil {
+		return nil, err
+	}
+	q.Set("tls", name)
+	cl.RawQuery = q.Encode()
+	return &cl, nil
+}
+
+// newTLSConfig returns the TLS config of t for the db of u. The certificate of the db is
+// verified by the config rather than by crypto/tls, which could only verify it against CAs
+// loaded once.
+func newTLSConfig(u *url.URL, t *dbhelper.TLS) (*tls.Config, error) {
+	conf := &tls.Config{InsecureSkipVerify: true}
+	if t.CertFile != "" {
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load the db client certificate: %s", err)
+			}
+			return &cert, nil
+		}
+	}
+	if t.Mode == dbhelper.TLSRequire {
+		return conf, nil
+	}
+
+	var host string
+	if t.Mode == dbhelper.TLSVerifyFull {
+		var err error
+		host, _, err = dbAddr(u)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verify(rawCerts, t.CAFile, host)
+	}
+	return conf, nil
+}
+
+// verify verifies the certificate chain of a db against the CAs of caFile, the CAs of the
+// system if empty, and against host if not empty
+func verify(rawCerts [][]byte, caFile, host string) error {
+	if len(rawCerts) == 0 {
+		return errors.New("the db presented no certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	opts := x509.VerifyOptions{DNSName: host, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("cannot read the db CA: %s", err)
+		}
+		opts.Roots = x509.NewCertPool()
+		if !opts.Roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate in the db CA %s", caFile)
+		}
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
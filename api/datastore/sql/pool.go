@@ -0,0 +1,156 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	// EnvDBMaxOpenConns is the maximum number of connections to each db, unlimited by default
+	EnvDBMaxOpenConns = "FN_DS_DB_MAX_OPEN_CONNS"
+	// EnvDBMaxIdleConns is the maximum number of idle connections to each db, 256 by default
+	EnvDBMaxIdleConns = "FN_DS_DB_MAX_IDLE_CONNS"
+	// EnvDBConnMaxLifetime is the number of seconds connections are reused for, forever by
+	// default. Managed dbs closing connections on their side require it.
+	EnvDBConnMaxLifetime = "FN_DS_DB_CONN_MAX_LIFETIME"
+	// EnvDBStatementTimeout is the number of seconds the db cancels statements after, for the
+	// dbs supporting it, none by default
+	EnvDBStatementTimeout = "FN_DS_DB_STATEMENT_TIMEOUT"
+
+	defaultMaxIdleConns = 256
+
+	// poolStatsInterval is the interval the stats of the connection pools are recorded at
+	poolStatsInterval = 10 * time.Second
+)
+
+var (
+	poolKey = common.MakeKey("db_pool")
+
+	poolOpenMeasure         = common.MakeMeasure("db_pool_open_connections", "Number of connections to the db, in use or idle", stats.UnitDimensionless)
+	poolInUseMeasure        = common.MakeMeasure("db_pool_in_use_connections", "Number of connections to the db in use", stats.UnitDimensionless)
+	poolIdleMeasure         = common.MakeMeasure("db_pool_idle_connections", "Number of idle connections to the db", stats.UnitDimensionless)
+	poolWaitCountMeasure    = common.MakeMeasure("db_pool_wait_count", "Total number of times a connection to the db was waited for", stats.UnitDimensionless)
+	poolWaitDurationMeasure = common.MakeMeasure("db_pool_wait_duration", "Total time waited for connections to the db", stats.UnitMilliseconds)
+)
+
+// RegisterViews registers views for the connection pools of the sql datastore, tagged by
+// db_pool, primary or replica_<i> for the replicas of EnvDBReadURL
+func RegisterViews(tagKeys []string) {
+	keys := append(append([]string{}, tagKeys...), poolKey.Name())
+	err := view.Register(
+		common.CreateView(poolOpenMeasure, view.LastValue(), keys),
+		common.CreateView(poolInUseMeasure, view.LastValue(), keys),
+		common.CreateView(poolIdleMeasure, view.LastValue(), keys),
+		common.CreateView(poolWaitCountMeasure, view.LastValue(), keys),
+		common.CreateView(poolWaitDurationMeasure, view.LastValue(), keys),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+func getEnvInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("cannot parse invalid %s=%s", key, v)
+	}
+	return i, nil
+}
+
+// configurePool configures the connection pool of db from the env
+func configurePool(db *sqlx.DB, log logrus.FieldLogger) error {
+	maxOpen, err := getEnvInt(EnvDBMaxOpenConns, 0)
+	if err != nil {
+		return err
+	}
+	maxIdle, err := getEnvInt(EnvDBMaxIdleConns, defaultMaxIdleConns)
+	if err != nil {
+		return err
+	}
+	maxLifetime, err := getEnvInt(EnvDBConnMaxLifetime, 0)
+	if err != nil {
+		return err
+	}
+
+	// idle connections are at most the open ones, as database/sql does
+	if maxOpen > 0 && maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(time.Duration(maxLifetime) * time.Second)
+	log.WithFields(logrus.Fields{
+		"max_open_connections": maxOpen,
+		"max_idle_connections": maxIdle,
+		"conn_max_lifetime":    maxLifetime,
+	}).Info("db connection pool configured")
+	return nil
+}
+
+// statementTimeout returns u with the statement timeout of the env, if any and if the db of
+// helper supports it
+func statementTimeout(helper dbhelper.Helper, u *url.URL, log logrus.FieldLogger) (*url.URL, error) {
+	timeout, err := getEnvInt(EnvDBStatementTimeout, 0)
+	if err != nil || timeout == 0 {
+		return u, err
+	}
+	t, ok := helper.(dbhelper.StatementTimeouter)
+	if !ok {
+		log.Warnf("%s is ignored, the %s db can't time out statements", EnvDBStatementTimeout, u.Scheme)
+		return u, nil
+	}
+	return t.WithStatementTimeout(u, time.Duration(timeout)*time.Second), nil
+}
+
+// recordPoolStats records the stats of the connection pools of ds until it is closed
+func (ds *SQLStore) recordPoolStats() {
+	pools := map[string]*sqlx.DB{"primary": ds.db}
+	for i, replica := range ds.replicas {
+		pools[fmt.Sprintf("replica_%d", i)] = replica
+	}
+	ctxs := make(map[string]context.Context, len(pools))
+	for name := range pools {
+		ctx, err := tag.New(context.Background(), tag.Upsert(poolKey, name))
+		if err != nil {
+			logrus.WithError(err).Fatal("cannot add tag")
+		}
+		ctxs[name] = ctx
+	}
+
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+	for {
+		for name, db := range pools {
+			s := db.Stats()
+			stats.Record(ctxs[name],
+				poolOpenMeasure.M(int64(s.OpenConnections)),
+				poolInUseMeasure.M(int64(s.InUse)),
+				poolIdleMeasure.M(int64(s.Idle)),
+				poolWaitCountMeasure.M(s.WaitCount),
+				poolWaitDurationMeasure.M(int64(s.WaitDuration/time.Millisecond)),
+			)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ds.closed:
+			return
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package postgres
 
 import (
+	"fmt"
 	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"net"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type postgresHelper int
@@ -44,6 +49,23 @@ func (postgresHelper) CheckTableExists(tx *sqlx.Tx, table string) (bool, error)
 	return exists, nil
 }
 
+func (postgresHelper) CheckIndexExists(tx *sqlx.Tx, table, index string) (bool, error) {
+	query := tx.Rebind(`SELECT count(*)
+	FROM pg_indexes
+	WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?`)
+
+	row := tx.QueryRow(query, table, index)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	exists := count > 0
+	return exists, nil
+}
+
 func (postgresHelper) String() string {
 	return "postgres"
 }
@@ -58,6 +80,85 @@ func (postgresHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+func (postgresHelper) SearchTableStatements() []string {
+	return []string{
+		`CREATE TABLE IF NOT EXISTS search_documents (
+	resource_type varchar(16) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	body text NOT NULL,
+	PRIMARY KEY (resource_type, resource_id)
+);`,
+		`CREATE INDEX IF NOT EXISTS search_documents_body ON search_documents USING GIN (to_tsvector('simple', body));`,
+	}
+}
+
+func (postgresHelper) SearchCondition(terms []string) (string, []interface{}) {
+	// terms match the words they prefix, like with LIKE on the words of bodies
+	prefixes := make([]string, len(terms))
+	for i, t := range terms {
+		prefixes[i] = t + ":*"
+	}
+	return `to_tsvector('simple', body) @@ to_tsquery('simple', ?)`, []interface{}{strings.Join(prefixes, " & ")}
+}
+
+// WithStatementTimeout sets the statement_timeout of the connections, which pq passes on to
+// postgres as the connection parameters it does not know
+func (postgresHelper) WithStatementTimeout(u *url.URL, timeout time.Duration) *url.URL {
+	cl := *u
+	q := cl.Query()
+	q.Set("statement_timeout", strconv.FormatInt(int64(timeout/time.Millisecond), 10))
+	cl.RawQuery = q.Encode()
+	return &cl
+}
+
+// WithTLS sets the ssl parameters of the connections, whose files pq reads again on each
+// connection. Note that pq verifies the CA of the db in require mode if a CA is set.
+func (postgresHelper) WithTLS(u *url.URL, t *dbhelper.TLS) (*url.URL, error) {
+	cl := *u
+	q := cl.Query()
+	q.Set("sslmode", t.Mode)
+	if t.CAFile != "" {
+		q.Set("sslrootcert", t.CAFile)
+	}
+	if t.CertFile != "" {
+		q.Set("sslcert", t.CertFile)
+		q.Set("sslkey", t.KeyFile)
+	}
+	cl.RawQuery = q.Encode()
+	return &cl, nil
+}
+
+// Endpoint implements dbhelper.TokenAuthenticator
+func (postgresHelper) Endpoint(u *url.URL) (string, error) {
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("no db host in %s", u.Host)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "5432"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// WithToken sets the password of the URL to token
+func (postgresHelper) WithToken(u *url.URL, token string) *url.URL {
+	cl := *u
+	cl.User = url.UserPassword(u.User.Username(), token)
+	return &cl
+}
+
+// Encrypted implements dbhelper.TokenAuthenticator, the sslmode must be set to require or a
+// verifying mode rather than left to the default of the driver
+func (postgresHelper) Encrypted(u *url.URL) bool {
+	switch u.Query().Get("sslmode") {
+	case dbhelper.TLSRequire, dbhelper.TLSVerifyCA, dbhelper.TLSVerifyFull:
+		return true
+	}
+	return false
+}
+
 func init() {
 	dbhelper.Register(postgresHelper(0))
 }
@@ -0,0 +1,68 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// the types of the resources whose keys are indexed in resource_keys
+const (
+	resourceTypeApp     = "app"
+	resourceTypeFn      = "fn"
+	resourceTypeTrigger = "trigger"
+)
+
+// updateResourceKeys replaces the indexed keys of a resource, old, with its keys as changed,
+// in the transaction changing the resource. The keys of a new resource are indexed with no
+// old keys.
+func updateResourceKeys(ctx context.Context, tx *sqlx.Tx, resourceType, resourceID, appID string, old, new []models.ResourceKey) error {
+	for _, k := range old {
+		query := tx.Rebind(`DELETE FROM resource_keys WHERE resource_type=? AND field=? AND name=? AND resource_id=?`)
+		_, err := tx.ExecContext(ctx, query, resourceType, k.Field, k.Name, resourceID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, k := range new {
+		query := tx.Rebind(`INSERT INTO resource_keys (resource_type, field, name, resource_id, app_id, value) VALUES (?, ?, ?, ?, ?, ?)`)
+		_, err := tx.ExecContext(ctx, query, resourceType, k.Field, k.Name, resourceID, appID, k.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// whereKeyFilters restricts a query to the resources with the keys of filters in field,
+// looked up in the index of resource_keys. It must follow the calls to where.
+func whereKeyFilters(b *bytes.Buffer, args []interface{}, resourceType, field string, filters []models.KeyFilter) []interface{} {
+	for _, f := range filters {
+		if b.Len() == 0 {
+			fmt.Fprintf(b, `WHERE `)
+		} else {
+			fmt.Fprintf(b, ` AND `)
+		}
+		fmt.Fprintf(b, `id IN (SELECT resource_id FROM resource_keys WHERE resource_type=? AND field=? AND name=?`)
+		args = append(args, resourceType, field, f.Key)
+		if f.HasValue {
+			fmt.Fprintf(b, ` AND value=?`)
+			args = append(args, f.Value)
+		}
+		fmt.Fprintf(b, `)`)
+	}
+	return args
+}
+
+// namePrefixPattern returns the LIKE pattern of the names starting with prefix, escaped with !
+func namePrefixPattern(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return r.Replace(prefix) + "%"
+}
@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const roleBindingSelector = `SELECT id,principal,role,app_id,created_at FROM role_bindings`
+
+func (ds *SQLStore) InsertRoleBinding(ctx context.Context, newBinding *models.RoleBinding) (*models.RoleBinding, error) {
+	binding := *newBinding
+	binding.ID = id.New().String()
+	binding.CreatedAt = common.DateTime(time.Now())
+
+	err := binding.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.tx(ctx, func(tx *sqlx.Tx) error {
+		if binding.AppID != "" {
+			query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+			r := tx.QueryRowContext(ctx, query, binding.AppID)
+			if err := r.Scan(new(int)); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrAppsNotFound
+				}
+				return err
+			}
+		}
+
+		query := tx.Rebind(`INSERT INTO role_bindings (
+			id,
+			principal,
+			role,
+			app_id,
+			created_at
+		)
+		VALUES (
+			:id,
+			:principal,
+			:role,
+			:app_id,
+			:created_at
+		);`)
+
+		_, err := tx.NamedExecContext(ctx, query, &binding)
+		return err
+	})
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrRoleBindingExists
+		}
+		return nil, err
+	}
+
+	return &binding, nil
+}
+
+func (ds *SQLStore) GetRoleBindingByID(ctx context.Context, bindingID string) (*models.RoleBinding, error) {
+	var binding models.RoleBinding
+	query := ds.db.Rebind(roleBindingSelector + ` WHERE id=?`)
+	err := ds.reader(ctx).QueryRowxContext(ctx, query, bindingID).StructScan(&binding)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrRoleBindingNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &binding, nil
+}
+
+func (ds *SQLStore) GetRoleBindings(ctx context.Context, filter *models.RoleBindingFilter) (*models.RoleBindingList, error) {
+	res := &models.RoleBindingList{Items: []*models.RoleBinding{}}
+	if filter == nil {
+		filter = new(models.RoleBindingFilter)
+	}
+
+	var b bytes.Buffer
+	var args []interface{}
+	fmt.Fprintf(&b, `1=1`)
+	if filter.AppID != "" {
+		fmt.Fprintf(&b, ` AND app_id = ?`)
+		args = append(args, filter.AppID)
+	}
+	if filter.Principal != "" {
+		fmt.Fprintf(&b, ` AND principal = ?`)
+		args = append(args, filter.Principal)
+	}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id > ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", roleBindingSelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var binding models.RoleBinding
+		if err := rows.StructScan(&binding); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &binding)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveRoleBinding(ctx context.Context, bindingID string) error {
+	query := ds.db.Rebind(`DELETE FROM role_bindings WHERE id=?`)
+	res, err := ds.writer(ctx).ExecContext(ctx, query, bindingID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrRoleBindingNotFound
+	}
+	return nil
+}
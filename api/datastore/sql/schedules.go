@@ -0,0 +1,427 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	scheduleSelector   = `SELECT id,name,app_id,fn_id,cron,payload,next_run_at,annotations,created_at,updated_at FROM schedules`
+	scheduleIDSelector = scheduleSelector + ` WHERE id=?`
+
+	scheduleRunSelector = `SELECT id,schedule_id,app_id,fn_id,call_id,scheduled_at,created_at,status,error FROM schedule_runs`
+)
+
+// times the scheduler compares are stored in UTC, so that they sort as strings
+func utcDateTime(t time.Time) common.DateTime {
+	return common.DateTime(t.UTC())
+}
+
+func (ds *SQLStore) InsertSchedule(ctx context.Context, newSchedule *models.Schedule) (*models.Schedule, error) {
+	schedule := newSchedule.Clone()
+
+	schedule.CreatedAt = common.DateTime(time.Now())
+	schedule.UpdatedAt = schedule.CreatedAt
+	schedule.ID = id.New().String()
+
+	err := schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
+	next, err := schedule.NextRun(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule.NextRunAt = utcDateTime(next)
+
+	err = ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+		r := tx.QueryRowContext(ctx, query, schedule.AppID)
+		if err := r.Scan(new(int)); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrAppsNotFound
+			}
+			return err
+		}
+
+		query = tx.Rebind(`SELECT app_id FROM fns WHERE id=? AND deleted_at IS NULL`)
+		r = tx.QueryRowContext(ctx, query, schedule.FnID)
+		var appID string
+		if err := r.Scan(&appID); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrFnsNotFound
+			}
+			return err
+		}
+		if appID != schedule.AppID {
+			return models.ErrScheduleFnIDNotSameApp
+		}
+
+		query = tx.Rebind(`INSERT INTO schedules (
+			id,
+			name,
+			app_id,
+			fn_id,
+			cron,
+			payload,
+			next_run_at,
+			annotations,
+			created_at,
+			updated_at
+		)
+		VALUES (
+			:id,
+			:name,
+			:app_id,
+			:fn_id,
+			:cron,
+			:payload,
+			:next_run_at,
+			:annotations,
+			:created_at,
+			:updated_at
+		);`)
+
+		_, err = tx.NamedExecContext(ctx, query, schedule)
+		return err
+	})
+
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrScheduleExists
+		}
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (ds *SQLStore) UpdateSchedule(ctx context.Context, schedule *models.Schedule) (*models.Schedule, error) {
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var dst models.Schedule
+		query := tx.Rebind(scheduleIDSelector)
+		row := tx.QueryRowxContext(ctx, query, schedule.ID)
+		err := row.StructScan(&dst)
+
+		if err == sql.ErrNoRows {
+			return models.ErrScheduleNotFound
+		} else if err != nil {
+			return err
+		}
+
+		dst.Update(schedule)
+		err = dst.Validate()
+		if err != nil {
+			return err
+		}
+		dst.NextRunAt = utcDateTime(time.Time(dst.NextRunAt))
+		schedule = &dst // set for query & to return
+
+		query = tx.Rebind(`SELECT app_id FROM fns WHERE id=? AND deleted_at IS NULL`)
+		r := tx.QueryRowContext(ctx, query, schedule.FnID)
+		var appID string
+		if err := r.Scan(&appID); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrFnsNotFound
+			}
+			return err
+		}
+		if appID != schedule.AppID {
+			return models.ErrScheduleFnIDNotSameApp
+		}
+
+		query = tx.Rebind(`UPDATE schedules SET
+			name = :name,
+			fn_id = :fn_id,
+			cron = :cron,
+			payload = :payload,
+			next_run_at = :next_run_at,
+			annotations = :annotations,
+			updated_at = :updated_at
+			WHERE id = :id;`)
+		_, err = tx.NamedExecContext(ctx, query, schedule)
+		return err
+	})
+
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return nil, models.ErrScheduleExists
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (ds *SQLStore) RemoveSchedule(ctx context.Context, scheduleID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM schedules WHERE id=?`), scheduleID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrScheduleNotFound
+		}
+
+		_, err = tx.ExecContext(ctx, tx.Rebind(`DELETE FROM schedule_runs WHERE schedule_id=?`), scheduleID)
+		return err
+	})
+}
+
+func (ds *SQLStore) GetScheduleByID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	var schedule models.Schedule
+	query := ds.db.Rebind(scheduleIDSelector)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, scheduleID)
+
+	err := row.StructScan(&schedule)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrScheduleNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func buildFilterScheduleQuery(filter *models.ScheduleFilter) (string, []interface{}, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	fmt.Fprintf(&b, `app_id = ?`)
+	args = append(args, filter.AppID)
+
+	if filter.FnID != "" {
+		fmt.Fprintf(&b, ` AND fn_id = ?`)
+		args = append(args, filter.FnID)
+	}
+
+	if filter.Name != "" {
+		fmt.Fprintf(&b, ` AND name = ?`)
+		args = append(args, filter.Name)
+	}
+
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+
+		fmt.Fprintf(&b, ` AND name > ?`)
+		args = append(args, string(s))
+	}
+
+	fmt.Fprintf(&b, ` ORDER BY name ASC`)
+
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	return b.String(), args, nil
+}
+
+func (ds *SQLStore) GetSchedules(ctx context.Context, filter *models.ScheduleFilter) (*models.ScheduleList, error) {
+	res := &models.ScheduleList{Items: []*models.Schedule{}}
+	if filter == nil {
+		filter = new(models.ScheduleFilter)
+	}
+
+	filterQuery, args, err := buildFilterScheduleQuery(filter)
+	if err != nil {
+		return res, err
+	}
+
+	/* #nosec */
+	query := fmt.Sprintf("%s WHERE %s", scheduleSelector, filterQuery)
+	query = ds.db.Rebind(query)
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := rows.StructScan(&schedule); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &schedule)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].Name)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) GetDueSchedules(ctx context.Context, t time.Time, limit int) ([]*models.Schedule, error) {
+	query := ds.db.Rebind(scheduleSelector + ` WHERE next_run_at <= ? AND fn_id IN (SELECT id FROM fns WHERE deleted_at IS NULL) ORDER BY next_run_at ASC LIMIT ?`)
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, utcDateTime(t), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*models.Schedule{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := rows.StructScan(&schedule); err != nil {
+			return nil, err
+		}
+		res = append(res, &schedule)
+	}
+	return res, rows.Err()
+}
+
+func (ds *SQLStore) AdvanceSchedule(ctx context.Context, scheduleID string, due, next time.Time) error {
+	query := ds.db.Rebind(`UPDATE schedules SET next_run_at=? WHERE id=? AND next_run_at=?`)
+	res, err := ds.writer(ctx).ExecContext(ctx, query, utcDateTime(next), scheduleID, utcDateTime(due))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrScheduleNotDue
+	}
+	return nil
+}
+
+func (ds *SQLStore) InsertScheduleRun(ctx context.Context, run *models.ScheduleRun) error {
+	query := ds.db.Rebind(`INSERT INTO schedule_runs (
+		id,
+		schedule_id,
+		app_id,
+		fn_id,
+		call_id,
+		scheduled_at,
+		created_at,
+		status,
+		error
+	)
+	VALUES (
+		:id,
+		:schedule_id,
+		:app_id,
+		:fn_id,
+		:call_id,
+		:scheduled_at,
+		:created_at,
+		:status,
+		:error
+	);`)
+
+	_, err := ds.writer(ctx).NamedExecContext(ctx, query, run)
+	return err
+}
+
+func (ds *SQLStore) GetScheduleRuns(ctx context.Context, filter *models.ScheduleRunFilter) (*models.ScheduleRunList, error) {
+	res := &models.ScheduleRunList{Items: []*models.ScheduleRun{}}
+
+	var b bytes.Buffer
+	args := []interface{}{filter.ScheduleID}
+	fmt.Fprintf(&b, `schedule_id = ?`)
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id < ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", scheduleRunSelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var run models.ScheduleRun
+		if err := rows.StructScan(&run); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &run)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiresAt := utcDateTime(now.Add(ttl))
+
+	var acquired bool
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var current struct {
+			Holder    string          `db:"holder"`
+			ExpiresAt common.DateTime `db:"expires_at"`
+		}
+		query := tx.Rebind(`SELECT holder, expires_at FROM leases WHERE name=?`)
+		err := tx.QueryRowxContext(ctx, query, name).StructScan(&current)
+		if err == sql.ErrNoRows {
+			query = tx.Rebind(`INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)`)
+			_, err = tx.ExecContext(ctx, query, name, holder, expiresAt)
+			acquired = err == nil
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if current.Holder != holder && time.Time(current.ExpiresAt).After(now) {
+			return nil // held by another node
+		}
+
+		// only take over the lease as it was read, another node may be doing the same
+		query = tx.Rebind(`UPDATE leases SET holder=?, expires_at=? WHERE name=? AND holder=? AND expires_at=?`)
+		res, err := tx.ExecContext(ctx, query, holder, expiresAt, name, current.Holder, utcDateTime(time.Time(current.ExpiresAt)))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		acquired = n == 1
+		return err
+	})
+
+	if err != nil {
+		if ds.helper.IsDuplicateKeyError(err) {
+			return false, nil // another node inserted the lease first
+		}
+		return false, err
+	}
+	return acquired, nil
+}
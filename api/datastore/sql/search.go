@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// searchDocumentsTable is the table of the search documents of dbs without full-text search
+const searchDocumentsTable = `CREATE TABLE IF NOT EXISTS search_documents (
+	resource_type varchar(16) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	name varchar(256) NOT NULL,
+	body text NOT NULL,
+	PRIMARY KEY (resource_type, resource_id)
+);`
+
+// searchTables returns the statements creating the table of search documents of the db of helper
+func searchTables(helper dbhelper.Helper) []string {
+	if s, ok := helper.(dbhelper.FullTextSearcher); ok {
+		return s.SearchTableStatements()
+	}
+	return []string{searchDocumentsTable}
+}
+
+// updateSearchDocument replaces the search document of a resource with the text of its name,
+// config and annotations, in the transaction changing the resource
+func updateSearchDocument(ctx context.Context, tx *sqlx.Tx, resourceType, resourceID, appID, name string, config models.Config, annotations models.Annotations) error {
+	query := tx.Rebind(`DELETE FROM search_documents WHERE resource_type=? AND resource_id=?`)
+	_, err := tx.ExecContext(ctx, query, resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`INSERT INTO search_documents (resource_type, resource_id, app_id, name, body) VALUES (?, ?, ?, ?, ?)`)
+	_, err = tx.ExecContext(ctx, query, resourceType, resourceID, appID, name, models.SearchText(name, config, annotations))
+	return err
+}
+
+// Search matches the bodies of the search documents with the full-text search of the db if it
+// has one, and with LIKE otherwise, both matching the words the terms prefix
+func (ds *SQLStore) Search(ctx context.Context, filter *models.SearchFilter) (*models.SearchResultList, error) {
+	res := &models.SearchResultList{Items: []*models.SearchResult{}}
+
+	var b bytes.Buffer
+	var args []interface{}
+	if s, ok := ds.helper.(dbhelper.FullTextSearcher); ok {
+		cond, condArgs := s.SearchCondition(filter.Terms)
+		fmt.Fprintf(&b, `WHERE %s`, cond)
+		args = append(args, condArgs...)
+	} else {
+		for i, t := range filter.Terms {
+			if i == 0 {
+				fmt.Fprintf(&b, `WHERE `)
+			} else {
+				fmt.Fprintf(&b, ` AND `)
+			}
+			// bodies are words separated by spaces, terms match the words they prefix like with
+			// full-text search. Terms are letters and digits, which LIKE matches as they are.
+			fmt.Fprintf(&b, `(body LIKE ? OR body LIKE ?)`)
+			args = append(args, t+"%", "% "+t+"%")
+		}
+	}
+
+	if filter.Type != "" {
+		fmt.Fprintf(&b, ` AND resource_type=?`)
+		args = append(args, filter.Type)
+	}
+	if filter.AppID != "" {
+		fmt.Fprintf(&b, ` AND app_id=?`)
+		args = append(args, filter.AppID)
+	}
+	if filter.AppIDs != nil {
+		if len(filter.AppIDs) == 0 {
+			return res, nil
+		}
+		fmt.Fprintf(&b, ` AND app_id IN (?%s)`, strings.Repeat(", ?", len(filter.AppIDs)-1))
+		for _, id := range filter.AppIDs {
+			args = append(args, id)
+		}
+	}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND resource_id>?`)
+		args = append(args, string(s))
+	}
+
+	// the documents of deleted resources are kept to be restored with them
+	fmt.Fprintf(&b, ` AND (resource_type=? AND resource_id IN (SELECT id FROM apps WHERE deleted_at IS NULL)`)
+	fmt.Fprintf(&b, ` OR resource_type=? AND resource_id IN (SELECT id FROM fns WHERE deleted_at IS NULL))`)
+	args = append(args, models.SearchTypeApp, models.SearchTypeFn)
+
+	fmt.Fprintf(&b, ` ORDER BY resource_id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("SELECT resource_type, resource_id, app_id, name FROM search_documents %s", b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result models.SearchResult
+		if err := rows.StructScan(&result); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &result)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
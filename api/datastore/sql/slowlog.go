@@ -0,0 +1,258 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+)
+
+// EnvDBSlowQueryThreshold is the number of milliseconds the statements taking longer are
+// logged after, with their literals removed, none are by default
+const EnvDBSlowQueryThreshold = "FN_DS_DB_SLOW_QUERY_THRESHOLD"
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteral  = regexp.MustCompile(`([^\w$.])\d+(?:\.\d+)?`)
+	sqlWhitespaces = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeStatement returns query on a single line with its string and number literals
+// replaced with ?, so that the values of the statements not using arguments are not logged
+func sanitizeStatement(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = numberLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(sqlWhitespaces.ReplaceAllString(query, " "))
+}
+
+// dsnConnector opens the connections of a db with a dsn, as sql.Open does
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+var _ driver.Connector = new(dsnConnector)
+
+// newDSNConnector returns the connector of the db of the driver registered as name with dsn
+func newDSNConnector(name, dsn string) (driver.Connector, error) {
+	// database/sql only hands out the driver of a name with a db, which opens no connection
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &dsnConnector{driver: drv, dsn: dsn}, nil
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// slowConnector logs the statements of the connections of a connector taking longer than
+// its threshold
+type slowConnector struct {
+	driver.Connector
+	threshold time.Duration
+}
+
+// logSlowStatements returns c logging its statements taking longer than EnvDBSlowQueryThreshold,
+// or c as it is if it is not set
+func logSlowStatements(c driver.Connector, log logrus.FieldLogger) (driver.Connector, error) {
+	threshold, err := getEnvInt(EnvDBSlowQueryThreshold, 0)
+	if err != nil || threshold == 0 {
+		return c, err
+	}
+	log.WithFields(logrus.Fields{"slow_query_threshold": threshold}).Info("db statements taking longer are logged")
+	return &slowConnector{Connector: c, threshold: time.Duration(threshold) * time.Millisecond}, nil
+}
+
+func (c *slowConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+// observeStatement logs query if it took longer than threshold since start. Queries are timed
+// until their rows are returned, not read.
+func observeStatement(ctx context.Context, threshold time.Duration, query string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	if elapsed < threshold || err == driver.ErrSkip {
+		return
+	}
+	log := common.Logger(ctx).WithFields(logrus.Fields{
+		"statement":   sanitizeStatement(query),
+		"duration_ms": int64(elapsed / time.Millisecond),
+	})
+	if err != nil {
+		log = log.WithError(err)
+	}
+	log.Warn("slow db statement")
+}
+
+// slowConn logs its statements taking longer than threshold, passing the optional interfaces
+// of database/sql through to its connection
+type slowConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+var (
+	_ driver.ExecerContext      = new(slowConn)
+	_ driver.QueryerContext     = new(slowConn)
+	_ driver.ConnBeginTx        = new(slowConn)
+	_ driver.ConnPrepareContext = new(slowConn)
+	_ driver.SessionResetter    = new(slowConn)
+	_ driver.NamedValueChecker  = new(slowConn)
+	_ driver.Pinger             = new(slowConn)
+)
+
+func (c *slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	observeStatement(ctx, c.threshold, query, start, err)
+	return res, err
+}
+
+func (c *slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	observeStatement(ctx, c.threshold, query, start, err)
+	return rows, err
+}
+
+func (c *slowConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slowStmt{Stmt: stmt, conn: c, query: query, threshold: c.threshold}, nil
+}
+
+func (c *slowConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *slowConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *slowConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowConn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func (c *slowConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// slowStmt logs the executions of a prepared statement taking longer than threshold, the
+// drivers which cannot execute statements with arguments directly preparing them
+type slowStmt struct {
+	driver.Stmt
+	conn      *slowConn
+	query     string
+	threshold time.Duration
+}
+
+var (
+	_ driver.StmtExecContext  = new(slowStmt)
+	_ driver.StmtQueryContext = new(slowStmt)
+)
+
+func (s *slowStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	observeStatement(ctx, s.threshold, s.query, start, err)
+	return res, err
+}
+
+func (s *slowStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	observeStatement(ctx, s.threshold, s.query, start, err)
+	return rows, err
+}
+
+// CheckNamedValue checks v as the statement does, or else as its connection does, since
+// database/sql does not check the values of statements with their connection
+func (s *slowStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return s.conn.CheckNamedValue(v)
+}
+
+// namedValues returns the values of args, for the statements of drivers predating contexts
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
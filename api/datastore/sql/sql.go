@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	sqldriver "database/sql/driver"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fnproject/fn/api/common"
@@ -43,7 +46,8 @@ var tables = [...]string{
 	annotations text NOT NULL,
 	syslog_url text,
 	created_at varchar(256),
-	updated_at varchar(256)
+	updated_at varchar(256),
+	deleted_at varchar(256)
 );`,
 
 	`CREATE TABLE IF NOT EXISTS calls (
@@ -54,6 +58,11 @@ var tables = [...]string{
 	id varchar(256) NOT NULL,
 	app_id varchar(256),
 	fn_id varchar(256),
+	fn_revision int NOT NULL DEFAULT 0,
+	request_id varchar(256) NOT NULL DEFAULT '',
+	log_dropped bigint NOT NULL DEFAULT 0,
+	log_size bigint NOT NULL DEFAULT 0,
+	log_unavailable varchar(256) NOT NULL DEFAULT '',
 	stats text,
 	error text,
 	PRIMARY KEY (id)
@@ -79,6 +88,46 @@ var tables = [...]string{
 	log text NOT NULL
 );`,
 
+	`CREATE TABLE IF NOT EXISTS dead_letters (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256),
+	fn_id varchar(256),
+	created_at varchar(256) NOT NULL,
+	data text NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS schedules (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	cron varchar(256) NOT NULL,
+	payload text,
+	next_run_at varchar(256) NOT NULL,
+	annotations text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	updated_at varchar(256) NOT NULL,
+	CONSTRAINT schedules_name_app_id_unique UNIQUE (app_id, name)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS schedule_runs (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	schedule_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	fn_id varchar(256) NOT NULL,
+	call_id varchar(256),
+	scheduled_at varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	error text
+);`,
+
+	`CREATE TABLE IF NOT EXISTS leases (
+	name varchar(256) NOT NULL PRIMARY KEY,
+	holder varchar(256) NOT NULL,
+	expires_at varchar(256) NOT NULL
+);`,
+
 	`CREATE TABLE IF NOT EXISTS fns (
 	id varchar(256) NOT NULL PRIMARY KEY,
 	name varchar(256) NOT NULL,
@@ -87,28 +136,147 @@ var tables = [...]string{
 	memory int NOT NULL,
 	timeout int NOT NULL,
 	idle_timeout int NOT NULL,
+	min_ready int,
+	max_containers int,
+	scale_policy text,
+	retry_policy text,
+	recycle_policy text,
+	request_schema text,
+	response_schema text,
 	config text NOT NULL,
 	annotations text NOT NULL,
+	revision int NOT NULL DEFAULT 0,
+	canary text,
 	created_at varchar(256) NOT NULL,
 	updated_at varchar(256) NOT NULL,
+	deleted_at varchar(256),
     CONSTRAINT name_app_id_unique UNIQUE (app_id, name)
 );`,
+
+	`CREATE TABLE IF NOT EXISTS fn_revisions (
+	fn_id varchar(256) NOT NULL,
+	revision int NOT NULL,
+	app_id varchar(256) NOT NULL,
+	image varchar(256) NOT NULL,
+	config text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	PRIMARY KEY (fn_id, revision)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS api_keys (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	name varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	scope varchar(256) NOT NULL,
+	rate_limit text,
+	hash varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT api_keys_hash_unique UNIQUE (hash)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS role_bindings (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	principal varchar(256) NOT NULL,
+	role varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT role_bindings_principal_app_id_role_unique UNIQUE (principal, app_id, role)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS app_usage (
+	app_id varchar(256) NOT NULL,
+	period varchar(16) NOT NULL,
+	calls bigint NOT NULL,
+	mb_ms bigint NOT NULL,
+	PRIMARY KEY (app_id, period)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS domains (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	hostname varchar(256) NOT NULL,
+	path_prefix varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL,
+	CONSTRAINT domains_hostname_path_prefix_unique UNIQUE (hostname, path_prefix)
+);`,
+
+	`CREATE TABLE IF NOT EXISTS webhooks (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	url varchar(2048) NOT NULL,
+	events text NOT NULL,
+	app_id varchar(256) NOT NULL,
+	secret varchar(256) NOT NULL,
+	created_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	webhook_id varchar(256) NOT NULL,
+	event_id varchar(256) NOT NULL,
+	event varchar(256) NOT NULL,
+	status varchar(256) NOT NULL,
+	attempts int NOT NULL,
+	status_code int NOT NULL,
+	error text NOT NULL,
+	created_at varchar(256) NOT NULL,
+	completed_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS audit_entries (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	action varchar(256) NOT NULL,
+	resource_type varchar(256) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	actor varchar(256) NOT NULL,
+	address varchar(256) NOT NULL,
+	diff text NOT NULL,
+	created_at varchar(256) NOT NULL
+);`,
+
+	`CREATE TABLE IF NOT EXISTS resource_keys (
+	resource_type varchar(16) NOT NULL,
+	field varchar(16) NOT NULL,
+	name varchar(256) NOT NULL,
+	resource_id varchar(256) NOT NULL,
+	app_id varchar(256) NOT NULL,
+	value text NOT NULL,
+	PRIMARY KEY (resource_type, field, name, resource_id)
+);`,
+}
+
+// listIndexes are the indexes of the orders of the lists of apps, fns and triggers, by table
+// and name. The lists of fns and triggers are of an app.
+var listIndexes = [...]struct{ table, name, columns string }{
+	{"apps", "apps_created_at_id", "created_at, id"},
+	{"apps", "apps_updated_at_id", "updated_at, id"},
+	{"fns", "fns_app_id_created_at_id", "app_id, created_at, id"},
+	{"fns", "fns_app_id_updated_at_id", "app_id, updated_at, id"},
+	{"triggers", "triggers_app_id_name", "app_id, name"},
+	{"triggers", "triggers_app_id_created_at_id", "app_id, created_at, id"},
+	{"triggers", "triggers_app_id_updated_at_id", "app_id, updated_at, id"},
 }
 
 const (
-	callSelector      = `SELECT id, created_at, started_at, completed_at, status, app_id, fn_id, stats, error FROM calls`
-	appIDSelector     = `SELECT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps WHERE id=?`
-	ensureAppSelector = `SELECT id FROM apps WHERE name=?`
+	callSelector      = `SELECT id, created_at, started_at, completed_at, status, app_id, fn_id, fn_revision, request_id, log_dropped, log_size, log_unavailable, stats, error FROM calls`
+	appSelector       = `SELECT id, name, config, annotations, syslog_url, created_at, updated_at, deleted_at FROM apps`
+	appIDSelector     = appSelector + ` WHERE id=? AND deleted_at IS NULL`
+	ensureAppSelector = `SELECT id FROM apps WHERE name=? AND deleted_at IS NULL`
 
-	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,config,annotations,created_at,updated_at FROM fns`
-	fnIDSelector = fnSelector + ` WHERE id=?`
+	fnSelector   = `SELECT id,name,app_id,image,memory,timeout,idle_timeout,min_ready,max_containers,scale_policy,retry_policy,recycle_policy,request_schema,response_schema,config,annotations,revision,canary,created_at,updated_at,deleted_at FROM fns`
+	fnIDSelector = fnSelector + ` WHERE id=? AND deleted_at IS NULL`
 
 	triggerSelector   = `SELECT id,name,app_id,fn_id,type,source,annotations,created_at,updated_at FROM triggers`
 	triggerIDSelector = triggerSelector + ` WHERE id=?`
 
-	triggerIDSourceSelector = triggerSelector + ` WHERE app_id=? AND type=? AND source=?`
+	// the triggers of deleted fns are not called until the fns are restored
+	triggerIDSourceSelector = triggerSelector + ` WHERE app_id=? AND type=? AND source=? AND fn_id IN (SELECT id FROM fns WHERE deleted_at IS NULL)`
 
 	EnvDBPingMaxRetries = "FN_DS_DB_PING_MAX_RETRIES"
+
+	// EnvDBReadURL is a comma separated list of URLs of read replicas of the db, of the same
+	// scheme. Reads are spread across them, but those of requests which made writes.
+	EnvDBReadURL = "FN_DS_DB_READ_URL"
 )
 
 var ( // compiler will yell nice things about our upbringing as a child
@@ -119,10 +287,36 @@ var ( // compiler will yell nice things about our upbringing as a child
 type SQLStore struct {
 	helper dbhelper.Helper
 	db     *sqlx.DB
+
+	// replicas serve the reads outside of transactions, if any
+	replicas []*sqlx.DB
+	next     uint32
+
+	// writes queues the writes of the dbs locked as a whole by each write, nil for the others
+	writes *writeQueue
+
+	closed chan struct{}
 }
 
 type sqlDsProvider int
 
+// resultsTable returns the statement creating the results table, whose results are binary
+func resultsTable(driverName string) string {
+	resultType := "blob"
+	switch driverName {
+	case "mysql":
+		resultType = "longblob"
+	case "postgres", "pgx":
+		resultType = "bytea"
+	}
+	return `CREATE TABLE IF NOT EXISTS results (
+	id varchar(256) NOT NULL PRIMARY KEY,
+	app_id varchar(256),
+	fn_id varchar(256),
+	result ` + resultType + ` NOT NULL
+);`
+}
+
 // New will open the db specified by url, create any tables necessary
 // and return a models.Datastore safe for concurrent usage.
 func New(ctx context.Context, u *url.URL) (*SQLStore, error) {
@@ -168,40 +362,21 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 		return nil, fmt.Errorf("DB helper '%s' is not supported", driver)
 	}
 
-	uri, err := helper.PreConnect(url)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialise db helper %s : %s", driver, err)
-	}
-
-	// NOTE: DO NOT LOG THE URL AND ITS PASSWORD! See common.MaskPassword (should be above)
-	log.Info("Connecting to DB")
-
-	sqldb, err := sql.Open(driver, uri)
-	if err != nil {
-		log.WithError(err).Error("couldn't open db")
-		return nil, err
-	}
-
-	db := sqlx.NewDb(sqldb, driver)
-
-	// force a connection and test that it worked
-	err = pingWithRetry(ctx, db)
+	db, err := connect(ctx, helper, url, log)
 	if err != nil {
-		log.WithError(err).Error("couldn't ping db")
 		return nil, err
 	}
+	sdb := &SQLStore{db: db, helper: helper, closed: make(chan struct{}), writes: acquireWriteQueue(helper, url)}
 
-	maxIdleConns := 256 // TODO we need to strip this out of the URL probably
-	db.SetMaxIdleConns(maxIdleConns)
-	log.WithFields(logrus.Fields{"max_idle_connections": maxIdleConns, "datastore": driver}).Info("datastore dialed")
-
-	db, err = helper.PostCreate(db)
-	if err != nil {
-		log.WithError(err).Error("couldn't initialize db")
-		return nil, err
+	if done, err := sdb.migrateOnly(ctx, log); done || err != nil {
+		db.Close()
+		sdb.releaseWrites()
+		if err != nil {
+			log.WithError(err).Error("error running migrations")
+			return nil, err
+		}
+		return nil, ErrMigrateOnly
 	}
-	sdb := &SQLStore{db: db, helper: helper}
 
 	// NOTE: runMigrations happens before we create all the tables, so that it
 	// can detect whether the db did not exist and insert the latest version of
@@ -221,23 +396,125 @@ func newDS(ctx context.Context, url *url.URL) (*SQLStore, error) {
 			return err
 		}
 
-		for _, v := range tables {
+		for _, v := range append(append(tables[:], resultsTable(tx.DriverName())), searchTables(helper)...) {
 			_, err = tx.ExecContext(ctx, v)
 			if err != nil {
 				log.WithError(err).Error("error creating tables")
 				return err
 			}
 		}
+
+		// not every db creates indexes if they do not exist, so they are checked for first
+		for _, idx := range listIndexes {
+			exists, err := helper.CheckIndexExists(tx, idx.table, idx.name)
+			if err != nil {
+				log.WithError(err).Error("error checking indexes")
+				return err
+			}
+			if exists {
+				continue
+			}
+			/* #nosec */
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX %s ON %s (%s);", idx.name, idx.table, idx.columns))
+			if err != nil {
+				log.WithError(err).Error("error creating indexes")
+				return err
+			}
+		}
 		return nil
 	})
 
 	if err != nil {
+		sdb.releaseWrites()
 		return nil, err
 	}
 
+	if readURLs := os.Getenv(EnvDBReadURL); readURLs != "" {
+		sdb.replicas, err = connectReplicas(ctx, helper, driver, readURLs)
+		if err != nil {
+			sdb.releaseWrites()
+			return nil, err
+		}
+	}
+
+	go sdb.recordPoolStats()
 	return sdb, nil
 }
 
+// connect opens the db of u and checks it can be reached
+func connect(ctx context.Context, helper dbhelper.Helper, u *url.URL, log logrus.FieldLogger) (*sqlx.DB, error) {
+	driver := u.Scheme
+	u, err := statementTimeout(helper, u, log)
+	if err != nil {
+		return nil, err
+	}
+	u, err = withTLS(helper, u, log)
+	if err != nil {
+		return nil, err
+	}
+	u, err = withLocking(helper, u, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: DO NOT LOG THE URL AND ITS PASSWORD! See common.MaskPassword (should be above)
+	log.Info("Connecting to DB")
+
+	var connector sqldriver.Connector
+	if auth := os.Getenv(EnvDBIAMAuth); auth != "" {
+		connector, err = connectorWithTokens(ctx, helper, u, auth, log)
+	} else {
+		uri, err := helper.PreConnect(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialise db helper %s : %s", driver, err)
+		}
+		connector, err = newDSNConnector(driver, uri)
+	}
+	if err == nil {
+		connector, err = logSlowStatements(connector, log)
+	}
+	if err != nil {
+		log.WithError(err).Error("couldn't open db")
+		return nil, err
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), driver)
+	if err := configurePool(db, log); err != nil {
+		return nil, err
+	}
+
+	// force a connection and test that it worked
+	err = pingWithRetry(ctx, db)
+	if err != nil {
+		log.WithError(err).Error("couldn't ping db")
+		return nil, err
+	}
+	log.WithFields(logrus.Fields{"datastore": driver}).Info("datastore dialed")
+
+	return helper.PostCreate(db)
+}
+
+// connectReplicas connects to the read replicas of readURLs, which must be dbs of driver
+func connectReplicas(ctx context.Context, helper dbhelper.Helper, driver, readURLs string) ([]*sqlx.DB, error) {
+	var replicas []*sqlx.DB
+	for _, r := range strings.Split(readURLs, ",") {
+		u, err := url.Parse(strings.TrimSpace(r))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", EnvDBReadURL, err)
+		}
+		if u.Scheme != driver {
+			return nil, fmt.Errorf("invalid %s: read replicas must be %s dbs", EnvDBReadURL, driver)
+		}
+		log := common.Logger(ctx).WithFields(logrus.Fields{"url": common.MaskPassword(u), "replica": true})
+		replica, err := connect(ctx, helper, u, log)
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
 func pingWithRetry(ctx context.Context, db *sqlx.DB) (err error) {
 
 	attempts := int64(10)
@@ -282,6 +559,11 @@ func (ds *SQLStore) runMigrations(ctx context.Context, tx *sqlx.Tx, migrations [
 		return migratex.SetVersion(ctx, tx, latestVersion(migrations), false)
 	}
 
+	// refuse to run against the schema of a newer fn, which must be migrated down first
+	if err := migratex.CheckVersion(ctx, tx, migrations); err != nil {
+		return err
+	}
+
 	// run any migrations needed to get to latest, if any
 	return migratex.Up(ctx, tx, migrations)
 }
@@ -329,6 +611,96 @@ func (ds *SQLStore) clear() error {
 
 		query = tx.Rebind(`DELETE FROM logs`)
 		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM results`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM dead_letters`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM schedules`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM schedule_runs`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM leases`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM fn_revisions`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM api_keys`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM role_bindings`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM app_usage`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM domains`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM webhooks`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM webhook_deliveries`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM audit_entries`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM resource_keys`)
+		_, err = tx.Exec(query)
+		if err != nil {
+			return err
+		}
+
+		query = tx.Rebind(`DELETE FROM search_documents`)
+		_, err = tx.Exec(query)
 		return err
 	})
 }
@@ -336,7 +708,7 @@ func (ds *SQLStore) clear() error {
 func (ds *SQLStore) GetAppID(ctx context.Context, appName string) (string, error) {
 	var app models.App
 	query := ds.db.Rebind(ensureAppSelector)
-	row := ds.db.QueryRowxContext(ctx, query, appName)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, appName)
 
 	err := row.StructScan(&app)
 	if err == sql.ErrNoRows {
@@ -350,6 +722,20 @@ func (ds *SQLStore) GetAppID(ctx context.Context, appName string) (string, error
 }
 
 func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.App, error) {
+	var app *models.App
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		app, err = ds.insertApp(ctx, tx, newApp)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func (ds *SQLStore) insertApp(ctx context.Context, tx *sqlx.Tx, newApp *models.App) (*models.App, error) {
 	app := newApp.Clone()
 	app.CreatedAt = common.DateTime(time.Now())
 	app.UpdatedAt = app.CreatedAt
@@ -360,7 +746,12 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 		app.Config = map[string]string{}
 	}
 
-	query := ds.db.Rebind(`INSERT INTO apps (
+	err := models.RunPersistHook(ctx, app, true)
+	if err != nil {
+		return nil, err
+	}
+
+	query := tx.Rebind(`INSERT INTO apps (
 		id,
 		name,
 		config,
@@ -378,7 +769,7 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 		:created_at,
 		:updated_at
 	);`)
-	_, err := ds.db.NamedExecContext(ctx, query, app)
+	_, err = tx.NamedExecContext(ctx, query, app)
 	if err != nil {
 		if ds.helper.IsDuplicateKeyError(err) {
 			return nil, models.ErrAppsAlreadyExists
@@ -386,93 +777,221 @@ func (ds *SQLStore) InsertApp(ctx context.Context, newApp *models.App) (*models.
 		return nil, err
 	}
 
-	return app, nil
-}
-
-func (ds *SQLStore) UpdateApp(ctx context.Context, newapp *models.App) (*models.App, error) {
-	var app models.App
+	err = updateResourceKeys(ctx, tx, resourceTypeApp, app.ID, app.ID, nil, models.ResourceKeys(app.Config, app.Annotations))
+	if err != nil {
+		return nil, err
+	}
+	err = updateSearchDocument(ctx, tx, models.SearchTypeApp, app.ID, app.ID, app.Name, app.Config, app.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
 
-	err := ds.Tx(func(tx *sqlx.Tx) error {
-		// NOTE: must query whole object since we're returning app, Update logic
-		// must only modify modifiable fields (as seen here). need to fix brittle..
+func (ds *SQLStore) UpdateApp(ctx context.Context, newapp *models.App) (*models.App, error) {
+	var app *models.App
 
-		query := tx.Rebind(appIDSelector)
-		row := tx.QueryRowxContext(ctx, query, newapp.ID)
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		app, err = updateApp(ctx, tx, newapp)
+		return err
+	})
 
-		err := row.StructScan(&app)
-		if err == sql.ErrNoRows {
-			return models.ErrAppsNotFound
-		}
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		if newapp.Name != "" && app.Name != newapp.Name {
-			return models.ErrAppsNameImmutable
-		}
-		app.Update(newapp)
-		err = app.Validate()
-		if err != nil {
-			return err
-		}
+	return app, nil
+}
 
-		query = tx.Rebind(`UPDATE apps SET config=:config, annotations=:annotations, syslog_url=:syslog_url, updated_at=:updated_at WHERE name=:name`)
-		res, err := tx.NamedExecContext(ctx, query, app)
-		if err != nil {
-			return err
-		}
+func updateApp(ctx context.Context, tx *sqlx.Tx, newapp *models.App) (*models.App, error) {
+	var app models.App
 
-		if n, err := res.RowsAffected(); err != nil {
-			return err
-		} else if n == 0 {
-			// inside of the transaction, we are querying for the app, so we know that it exists
-			return nil
-		}
-		return nil
-	})
+	// NOTE: must query whole object since we're returning app, Update logic
+	// must only modify modifiable fields (as seen here). need to fix brittle..
+
+	query := tx.Rebind(appIDSelector)
+	row := tx.QueryRowxContext(ctx, query, newapp.ID)
+
+	err := row.StructScan(&app)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAppsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	err = models.CheckIfMatch(ctx, &app)
 	if err != nil {
 		return nil, err
 	}
 
+	if newapp.Name != "" && app.Name != newapp.Name {
+		return nil, models.ErrAppsNameImmutable
+	}
+	oldKeys := models.ResourceKeys(app.Config, app.Annotations)
+	app.Update(newapp)
+	err = app.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = models.RunPersistHook(ctx, &app, false)
+	if err != nil {
+		return nil, err
+	}
+
+	query = tx.Rebind(`UPDATE apps SET config=:config, annotations=:annotations, syslog_url=:syslog_url, updated_at=:updated_at WHERE name=:name`)
+	_, err = tx.NamedExecContext(ctx, query, app)
+	if err != nil {
+		return nil, err
+	}
+	err = updateResourceKeys(ctx, tx, resourceTypeApp, app.ID, app.ID, oldKeys, models.ResourceKeys(app.Config, app.Annotations))
+	if err != nil {
+		return nil, err
+	}
+	err = updateSearchDocument(ctx, tx, models.SearchTypeApp, app.ID, app.ID, app.Name, app.Config, app.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	// inside of the transaction, we are querying for the app, so we know that it exists
 	return &app, nil
 }
 
+// checkIfMatch checks the resource of id selected by query into dest against the If-Match
+// precondition of ctx, if it has one, see models.WithIfMatch
+func checkIfMatch(ctx context.Context, tx *sqlx.Tx, query, id string, dest interface{}, notFound error) error {
+	if !models.HasIfMatch(ctx) {
+		return nil
+	}
+	err := tx.QueryRowxContext(ctx, tx.Rebind(query), id).StructScan(dest)
+	if err == sql.ErrNoRows {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	return models.CheckIfMatch(ctx, dest)
+}
+
 func (ds *SQLStore) RemoveApp(ctx context.Context, appID string) error {
-	return ds.Tx(func(tx *sqlx.Tx) error {
-		res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM apps WHERE id=?`), appID)
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		return removeApp(ctx, tx, appID)
+	})
+}
+
+func removeApp(ctx context.Context, tx *sqlx.Tx, appID string) error {
+	err := checkIfMatch(ctx, tx, appSelector+` WHERE id=?`, appID, new(models.App), models.ErrAppsNotFound)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM apps WHERE id=?`), appID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrAppsNotFound
+	}
+
+	deletes := []string{
+		`DELETE FROM logs WHERE app_id=?`,
+		`DELETE FROM results WHERE app_id=?`,
+		`DELETE FROM dead_letters WHERE app_id=?`,
+		`DELETE FROM calls WHERE app_id=?`,
+		`DELETE FROM fns WHERE app_id=?`,
+		`DELETE FROM fn_revisions WHERE app_id=?`,
+		`DELETE FROM triggers WHERE app_id=?`,
+		`DELETE FROM schedules WHERE app_id=?`,
+		`DELETE FROM schedule_runs WHERE app_id=?`,
+		`DELETE FROM api_keys WHERE app_id=?`,
+		`DELETE FROM role_bindings WHERE app_id=?`,
+		`DELETE FROM app_usage WHERE app_id=?`,
+		`DELETE FROM domains WHERE app_id=?`,
+		`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE app_id=?)`,
+		`DELETE FROM webhooks WHERE app_id=?`,
+		`DELETE FROM resource_keys WHERE app_id=?`,
+		`DELETE FROM search_documents WHERE app_id=?`,
+	}
+	for _, stmt := range deletes {
+		_, err := tx.ExecContext(ctx, tx.Rebind(stmt), appID)
 		if err != nil {
 			return err
 		}
-		n, err := res.RowsAffected()
+	}
+
+	return nil
+}
+
+func (ds *SQLStore) SoftDeleteApp(ctx context.Context, appID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		return softDeleteApp(ctx, tx, appID)
+	})
+}
+
+func softDeleteApp(ctx context.Context, tx *sqlx.Tx, appID string) error {
+	// the fns of the app are marked with its time, to be restored with it
+	err := checkIfMatch(ctx, tx, appIDSelector, appID, new(models.App), models.ErrAppsNotFound)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := common.DateTime(time.Now())
+	query := tx.Rebind(`UPDATE apps SET deleted_at=? WHERE id=? AND deleted_at IS NULL`)
+	res, err := tx.ExecContext(ctx, query, deletedAt, appID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrAppsNotFound
+	}
+
+	query = tx.Rebind(`UPDATE fns SET deleted_at=? WHERE app_id=? AND deleted_at IS NULL`)
+	_, err = tx.ExecContext(ctx, query, deletedAt, appID)
+	return err
+}
+
+func (ds *SQLStore) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	var app models.App
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(appSelector + ` WHERE id=? AND deleted_at IS NOT NULL`)
+		err := tx.QueryRowxContext(ctx, query, appID).StructScan(&app)
+		if err == sql.ErrNoRows {
+			return models.ErrAppsNotFound
+		}
 		if err != nil {
 			return err
 		}
-		if n == 0 {
-			return models.ErrAppsNotFound
-		}
 
-		deletes := []string{
-			`DELETE FROM logs WHERE app_id=?`,
-			`DELETE FROM calls WHERE app_id=?`,
-			`DELETE FROM fns WHERE app_id=?`,
-			`DELETE FROM triggers WHERE app_id=?`,
-		}
-		for _, stmt := range deletes {
-			_, err := tx.ExecContext(ctx, tx.Rebind(stmt), appID)
-			if err != nil {
-				return err
-			}
+		query = tx.Rebind(`UPDATE fns SET deleted_at=NULL WHERE app_id=? AND deleted_at=?`)
+		_, err = tx.ExecContext(ctx, query, appID, *app.DeletedAt)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		query = tx.Rebind(`UPDATE apps SET deleted_at=NULL WHERE id=?`)
+		_, err = tx.ExecContext(ctx, query, appID)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	app.DeletedAt = nil
+	return &app, nil
 }
 
 func (ds *SQLStore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
 	var app models.App
 	query := ds.db.Rebind(appIDSelector)
-	row := ds.db.QueryRowxContext(ctx, query, appID)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, appID)
 
 	err := row.StructScan(&app)
 	if err == sql.ErrNoRows {
@@ -493,8 +1012,8 @@ func (ds *SQLStore) GetApps(ctx context.Context, filter *models.AppFilter) (*mod
 		return nil, err
 	}
 	/* #nosec */
-	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, config, annotations, syslog_url, created_at, updated_at FROM apps %s", query))
-	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	query = ds.db.Rebind(fmt.Sprintf("SELECT DISTINCT id, name, config, annotations, syslog_url, created_at, updated_at, deleted_at FROM apps %s", query))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -513,8 +1032,8 @@ func (ds *SQLStore) GetApps(ctx context.Context, filter *models.AppFilter) (*mod
 	}
 
 	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
-		last := []byte(res.Items[len(res.Items)-1].Name)
-		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := res.Items[len(res.Items)-1]
+		res.NextCursor = filter.Order.EncodeCursor(filter.Order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -524,102 +1043,191 @@ func (ds *SQLStore) GetApps(ctx context.Context, filter *models.AppFilter) (*mod
 }
 
 func (ds *SQLStore) InsertFn(ctx context.Context, newFn *models.Fn) (*models.Fn, error) {
+	var fn *models.Fn
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		fn, err = ds.insertFn(ctx, tx, newFn)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (ds *SQLStore) insertFn(ctx context.Context, tx *sqlx.Tx, newFn *models.Fn) (*models.Fn, error) {
 	fn := newFn.Clone()
 	fn.ID = id.New().String()
 	fn.CreatedAt = common.DateTime(time.Now())
 	fn.UpdatedAt = fn.CreatedAt
+	fn.Revision = 0
+	if !fn.Canary.IsEmpty() {
+		fn.Canary.Revision = 0
+	}
 
 	err := newFn.Validate()
 	if err != nil {
 		return nil, err
 	}
+	err = models.RunPersistHook(ctx, fn, true)
+	if err != nil {
+		return nil, err
+	}
 
-	err = ds.Tx(func(tx *sqlx.Tx) error {
-
-		query := tx.Rebind(`SELECT 1 FROM apps WHERE id=?`)
-		r := tx.QueryRowContext(ctx, query, fn.AppID)
-		if err := r.Scan(new(int)); err != nil {
-			if err == sql.ErrNoRows {
-				return models.ErrAppsNotFound
-			}
+	query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+	r := tx.QueryRowContext(ctx, query, fn.AppID)
+	if err := r.Scan(new(int)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAppsNotFound
 		}
+	}
 
-		query = tx.Rebind(`INSERT INTO fns (
-				id,
-				name,
-				app_id,
-				image,
-				memory,
-				timeout,
-				idle_timeout,
-				config,
-				annotations,
-				created_at,
-				updated_at
-			)
-			VALUES (
-				:id,
-				:name,
-				:app_id,
-				:image,
-				:memory,
-				:timeout,
-				:idle_timeout,
-				:config,
-				:annotations,
-				:created_at,
-				:updated_at
-			);`)
-
-		_, err = tx.NamedExecContext(ctx, query, fn)
-		return err
-	})
+	query = tx.Rebind(`INSERT INTO fns (
+			id,
+			name,
+			app_id,
+			image,
+			memory,
+			timeout,
+			idle_timeout,
+			min_ready,
+			max_containers,
+			scale_policy,
+			retry_policy,
+			recycle_policy,
+			request_schema,
+			response_schema,
+			config,
+			annotations,
+			revision,
+			canary,
+			created_at,
+			updated_at
+		)
+		VALUES (
+			:id,
+			:name,
+			:app_id,
+			:image,
+			:memory,
+			:timeout,
+			:idle_timeout,
+			:min_ready,
+			:max_containers,
+			:scale_policy,
+			:retry_policy,
+			:recycle_policy,
+			:request_schema,
+			:response_schema,
+			:config,
+			:annotations,
+			:revision,
+			:canary,
+			:created_at,
+			:updated_at
+		);`)
 
+	err = recordFnRevisions(ctx, tx, fn)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.NamedExecContext(ctx, query, fn)
 	if err != nil {
 		if ds.helper.IsDuplicateKeyError(err) {
 			return nil, models.ErrFnsExists
 		}
 		return nil, err
 	}
+
+	err = updateResourceKeys(ctx, tx, resourceTypeFn, fn.ID, fn.AppID, nil, models.ResourceKeys(fn.Config, fn.Annotations))
+	if err != nil {
+		return nil, err
+	}
+	err = updateSearchDocument(ctx, tx, models.SearchTypeFn, fn.ID, fn.AppID, fn.Name, fn.Config, fn.Annotations)
+	if err != nil {
+		return nil, err
+	}
 	return fn, nil
 }
 
 func (ds *SQLStore) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
-	err := ds.Tx(func(tx *sqlx.Tx) error {
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		fn, err = updateFn(ctx, tx, fn)
+		return err
+	})
 
-		var dst models.Fn
-		query := tx.Rebind(fnIDSelector)
-		row := tx.QueryRowxContext(ctx, query, fn.ID)
-		err := row.StructScan(&dst)
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
 
-		if err == sql.ErrNoRows {
-			return models.ErrFnsNotFound
-		} else if err != nil {
-			return err
-		}
+func updateFn(ctx context.Context, tx *sqlx.Tx, fn *models.Fn) (*models.Fn, error) {
+	var dst models.Fn
+	query := tx.Rebind(fnIDSelector)
+	row := tx.QueryRowxContext(ctx, query, fn.ID)
+	err := row.StructScan(&dst)
 
-		dst.Update(fn)
-		err = dst.Validate()
-		if err != nil {
-			return err
-		}
-		fn = &dst // set for query & to return
+	if err == sql.ErrNoRows {
+		return nil, models.ErrFnsNotFound
+	} else if err != nil {
+		return nil, err
+	}
 
-		query = tx.Rebind(`UPDATE fns SET
-				name = :name,
-				image = :image,
-				memory = :memory,
-				timeout = :timeout,
-				idle_timeout = :idle_timeout,
-				config = :config,
-				annotations = :annotations,
-				updated_at = :updated_at
-			    WHERE id=:id;`)
+	err = models.CheckIfMatch(ctx, &dst)
+	if err != nil {
+		return nil, err
+	}
 
-		_, err = tx.NamedExecContext(ctx, query, fn)
-		return err
-	})
+	oldKeys := models.ResourceKeys(dst.Config, dst.Annotations)
+	dst.Update(fn)
+	err = dst.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = models.RunPersistHook(ctx, &dst, false)
+	if err != nil {
+		return nil, err
+	}
+	fn = &dst // set for query & to return
 
+	err = recordFnRevisions(ctx, tx, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	query = tx.Rebind(`UPDATE fns SET
+			name = :name,
+			image = :image,
+			memory = :memory,
+			timeout = :timeout,
+			idle_timeout = :idle_timeout,
+			min_ready = :min_ready,
+			max_containers = :max_containers,
+			scale_policy = :scale_policy,
+			retry_policy = :retry_policy,
+			recycle_policy = :recycle_policy,
+			request_schema = :request_schema,
+			response_schema = :response_schema,
+			config = :config,
+			annotations = :annotations,
+			revision = :revision,
+			canary = :canary,
+			updated_at = :updated_at
+		    WHERE id=:id;`)
+
+	_, err = tx.NamedExecContext(ctx, query, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateResourceKeys(ctx, tx, resourceTypeFn, fn.ID, fn.AppID, oldKeys, models.ResourceKeys(fn.Config, fn.Annotations))
+	if err != nil {
+		return nil, err
+	}
+	err = updateSearchDocument(ctx, tx, models.SearchTypeFn, fn.ID, fn.AppID, fn.Name, fn.Config, fn.Annotations)
 	if err != nil {
 		return nil, err
 	}
@@ -640,7 +1248,7 @@ func (ds *SQLStore) GetFns(ctx context.Context, filter *models.FnFilter) (*model
 	/* #nosec */
 	query := fmt.Sprintf("%s %s", fnSelector, filterQuery)
 	query = ds.db.Rebind(query)
-	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return res, nil // no error for empty list
@@ -659,8 +1267,8 @@ func (ds *SQLStore) GetFns(ctx context.Context, filter *models.FnFilter) (*model
 	}
 
 	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
-		last := []byte(res.Items[len(res.Items)-1].Name)
-		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := res.Items[len(res.Items)-1]
+		res.NextCursor = filter.Order.EncodeCursor(filter.Order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -672,9 +1280,8 @@ func (ds *SQLStore) GetFns(ctx context.Context, filter *models.FnFilter) (*model
 }
 
 func (ds *SQLStore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
-	/* #nosec */
-	query := ds.db.Rebind(fmt.Sprintf("%s WHERE id=?", fnSelector))
-	row := ds.db.QueryRowxContext(ctx, query, fnID)
+	query := ds.db.Rebind(fnIDSelector)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, fnID)
 
 	var fn models.Fn
 	err := row.StructScan(&fn)
@@ -686,44 +1293,260 @@ func (ds *SQLStore) GetFnByID(ctx context.Context, fnID string) (*models.Fn, err
 	return &fn, nil
 }
 
-func (ds *SQLStore) RemoveFn(ctx context.Context, fnID string) error {
-	return ds.Tx(func(tx *sqlx.Tx) error {
-		/* #nosec */
-		query := tx.Rebind(fmt.Sprintf("%s WHERE id=?", fnSelector))
-		row := tx.QueryRowxContext(ctx, query, fnID)
+func (ds *SQLStore) SoftDeleteFn(ctx context.Context, fnID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		return softDeleteFn(ctx, tx, fnID)
+	})
+}
 
-		var fn models.Fn
-		err := row.StructScan(&fn)
+func softDeleteFn(ctx context.Context, tx *sqlx.Tx, fnID string) error {
+	err := checkIfMatch(ctx, tx, fnIDSelector, fnID, new(models.Fn), models.ErrFnsNotFound)
+	if err != nil {
+		return err
+	}
+
+	query := tx.Rebind(`UPDATE fns SET deleted_at=? WHERE id=? AND deleted_at IS NULL`)
+	res, err := tx.ExecContext(ctx, query, common.DateTime(time.Now()), fnID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrFnsNotFound
+	}
+	return nil
+}
+
+func (ds *SQLStore) GetDeletedFnByID(ctx context.Context, fnID string) (*models.Fn, error) {
+	query := ds.db.Rebind(fnSelector + ` WHERE id=? AND deleted_at IS NOT NULL`)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, fnID)
+
+	var fn models.Fn
+	err := row.StructScan(&fn)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrFnsNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+func (ds *SQLStore) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	var fn models.Fn
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(fnSelector + ` WHERE id=? AND deleted_at IS NOT NULL`)
+		err := tx.QueryRowxContext(ctx, query, fnID).StructScan(&fn)
 		if err == sql.ErrNoRows {
 			return models.ErrFnsNotFound
 		}
+		if err != nil {
+			return err
+		}
 
-		query = tx.Rebind(`DELETE FROM triggers WHERE fn_id=?`)
-		_, err = tx.ExecContext(ctx, query, fnID)
-
+		query = tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+		err = tx.QueryRowContext(ctx, query, fn.AppID).Scan(new(int))
+		if err == sql.ErrNoRows {
+			return models.ErrAppsNotFound
+		}
 		if err != nil {
 			return err
 		}
 
-		query = tx.Rebind(`DELETE FROM fns WHERE id=?`)
+		query = tx.Rebind(`UPDATE fns SET deleted_at=NULL WHERE id=?`)
 		_, err = tx.ExecContext(ctx, query, fnID)
-
 		return err
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	fn.DeletedAt = nil
+	return &fn, nil
 }
 
-func (ds *SQLStore) Tx(f func(*sqlx.Tx) error) error {
-	tx, err := ds.db.Beginx()
+func (ds *SQLStore) RemoveFn(ctx context.Context, fnID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		return removeFn(ctx, tx, fnID)
+	})
+
+}
+
+func removeFn(ctx context.Context, tx *sqlx.Tx, fnID string) error {
+	/* #nosec */
+	query := tx.Rebind(fmt.Sprintf("%s WHERE id=?", fnSelector))
+	row := tx.QueryRowxContext(ctx, query, fnID)
+
+	var fn models.Fn
+	err := row.StructScan(&fn)
+	if err == sql.ErrNoRows {
+		return models.ErrFnsNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = models.CheckIfMatch(ctx, &fn)
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM resource_keys WHERE resource_type=? AND resource_id IN (SELECT id FROM triggers WHERE fn_id=?)`)
+	_, err = tx.ExecContext(ctx, query, resourceTypeTrigger, fnID)
+
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM triggers WHERE fn_id=?`)
+	_, err = tx.ExecContext(ctx, query, fnID)
+
 	if err != nil {
 		return err
 	}
-	err = f(tx)
+
+	query = tx.Rebind(`DELETE FROM schedules WHERE fn_id=?`)
+	_, err = tx.ExecContext(ctx, query, fnID)
+
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM schedule_runs WHERE fn_id=?`)
+	_, err = tx.ExecContext(ctx, query, fnID)
+
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	return tx.Commit()
+
+	query = tx.Rebind(`DELETE FROM fn_revisions WHERE fn_id=?`)
+	_, err = tx.ExecContext(ctx, query, fnID)
+
+	if err != nil {
+		return err
+	}
+
+	err = updateResourceKeys(ctx, tx, resourceTypeFn, fnID, fn.AppID, models.ResourceKeys(fn.Config, fn.Annotations), nil)
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM search_documents WHERE resource_type=? AND resource_id=?`)
+	_, err = tx.ExecContext(ctx, query, models.SearchTypeFn, fnID)
+
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM fns WHERE id=?`)
+	_, err = tx.ExecContext(ctx, query, fnID)
+
+	return err
+}
+
+// txKey is the key of the transaction of a context, see InTx
+type txKey struct{}
+
+// conn is a db, or the transaction of the operations made with a context
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+}
+
+// InTx implements models.Datastore. An operation failing fails the transaction, postgres
+// aborting the transactions of the statements which failed.
+func (ds *SQLStore) InTx(ctx context.Context, f func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return f(ctx)
+	}
+	common.MarkWrite(ctx)
+	ctx, scope := models.WithTxScope(ctx)
+	err := ds.queueWrite(ctx, func() error {
+		tx, err := ds.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := f(context.WithValue(ctx, txKey{}, tx)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return err
+	}
+	// the functions run after the commit may write to the db
+	scope.Committed()
+	return nil
+}
+
+// queueWrite makes the write f through the queue of the writes of the db, if it has one
+func (ds *SQLStore) queueWrite(ctx context.Context, f func() error) error {
+	if ds.writes == nil {
+		return f()
+	}
+	return ds.writes.do(ctx, f)
+}
+
+// releaseWrites releases the queue of the writes of the db, if it has one
+func (ds *SQLStore) releaseWrites() {
+	if ds.writes != nil {
+		ds.writes.release()
+	}
+}
+
+// tx runs f in a transaction of a write made with ctx, the transaction of ctx if it has one
+func (ds *SQLStore) tx(ctx context.Context, f func(*sqlx.Tx) error) error {
+	common.MarkWrite(ctx)
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return f(tx)
+	}
+	return ds.Tx(f)
+}
+
+// reader returns the db the reads made with ctx go to, the transaction of ctx if it has one,
+// and otherwise a replica unless there are none or a write was made with ctx
+func (ds *SQLStore) reader(ctx context.Context) conn {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	if len(ds.replicas) == 0 || common.HasWritten(ctx) {
+		return ds.db
+	}
+	i := atomic.AddUint32(&ds.next, 1)
+	return ds.replicas[i%uint32(len(ds.replicas))]
+}
+
+// writer returns the db the writes made with ctx go to, the transaction of ctx if it has one
+func (ds *SQLStore) writer(ctx context.Context) conn {
+	common.MarkWrite(ctx)
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	if ds.writes != nil {
+		return &queuedConn{DB: ds.db, writes: ds.writes}
+	}
+	return ds.db
+}
+
+func (ds *SQLStore) Tx(f func(*sqlx.Tx) error) error {
+	return ds.queueWrite(context.Background(), func() error {
+		tx, err := ds.db.Beginx()
+		if err != nil {
+			return err
+		}
+		err = f(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
 }
 
 func (ds *SQLStore) InsertCall(ctx context.Context, call *models.Call) error {
@@ -735,6 +1558,11 @@ func (ds *SQLStore) InsertCall(ctx context.Context, call *models.Call) error {
 		status,
 		app_id,
 		fn_id,
+		fn_revision,
+		request_id,
+		log_dropped,
+		log_size,
+		log_unavailable,
 		stats,
 		error
 	)
@@ -746,130 +1574,308 @@ func (ds *SQLStore) InsertCall(ctx context.Context, call *models.Call) error {
 		:status,
 		:app_id,
 		:fn_id,
+		:fn_revision,
+		:request_id,
+		:log_dropped,
+		:log_size,
+		:log_unavailable,
 		:stats,
 		:error
 	);`)
 
-	_, err := ds.db.NamedExecContext(ctx, query, call)
+	_, err := ds.writer(ctx).NamedExecContext(ctx, query, call)
+	return err
+}
+
+func (ds *SQLStore) GetCall1(ctx context.Context, appID, callID string) (*models.Call, error) {
+	/* #nosec */
+	query := fmt.Sprintf(`%s WHERE id=? AND app_id=?`, callSelector)
+	query = ds.db.Rebind(query)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, callID, appID)
+
+	var call models.Call
+	err := row.StructScan(&call)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCallNotFound
+		}
+		return nil, err
+	}
+	return &call, nil
+}
+
+func (ds *SQLStore) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	/* #nosec */
+	query := fmt.Sprintf(`%s WHERE id=? AND fn_id=?`, callSelector)
+	query = ds.db.Rebind(query)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, callID, fnID)
+
+	var call models.Call
+	err := row.StructScan(&call)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCallNotFound
+		}
+		return nil, err
+	}
+	return &call, nil
+}
+
+func (ds *SQLStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = string(cursor)
+	}
+
+	calls, err := ds.GetCalls1(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	callList := &models.CallList{Items: calls}
+
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return callList, nil
+}
+
+func (ds *SQLStore) GetCalls1(ctx context.Context, filter *models.CallFilter) ([]*models.Call, error) {
+	res := []*models.Call{}
+	query, args := buildFilterCallQuery(filter)
+	/* #nosec */
+	query = fmt.Sprintf("%s %s", callSelector, query)
+	query = ds.db.Rebind(query)
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var call models.Call
+		err := rows.StructScan(&call)
+		if err != nil {
+			continue
+		}
+		res = append(res, &call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveCall(ctx context.Context, fnID, callID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var removed int64
+		for _, table := range []string{"calls", "logs", "results"} {
+			/* #nosec */
+			query := tx.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE id=? AND fn_id=?`, table))
+			res, err := tx.ExecContext(ctx, query, callID, fnID)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			removed += n
+		}
+		if removed == 0 {
+			return models.ErrCallNotFound
+		}
+		return nil
+	})
+}
+
+func (ds *SQLStore) InsertLog(ctx context.Context, call *models.Call, logR io.Reader) error {
+	// coerce this into a string for sql
+	var log string
+	if stringer, ok := logR.(fmt.Stringer); ok {
+		log = stringer.String()
+	} else {
+		// TODO we could optimize for Size / buffer pool, but atm we aren't hitting
+		// this code path anyway (a fallback)
+		var b bytes.Buffer
+		io.Copy(&b, logR)
+		log = b.String()
+	}
+
+	query := ds.db.Rebind(`INSERT INTO logs (id, app_id, fn_id, log) VALUES (?, ?, ?, ?);`)
+	_, err := ds.writer(ctx).ExecContext(ctx, query, call.ID, call.AppID, call.FnID, log)
+	return err
+}
+
+func (ds *SQLStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	query := ds.db.Rebind(`SELECT log FROM logs WHERE id=? AND fn_id=?`)
+	row := ds.reader(ctx).QueryRowContext(ctx, query, callID, fnID)
+
+	var log string
+	err := row.Scan(&log)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCallLogNotFound
+		}
+		return nil, err
+	}
+
+	return strings.NewReader(log), nil
+}
+
+// SearchLog only reads the log if it contains the text matched, its entries are filtered
+// once read
+func (ds *SQLStore) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	if !filter.MatchesStored() {
+		log, err := ds.GetLog(ctx, fnID, callID)
+		if err != nil {
+			return nil, err
+		}
+		return models.FilterLog(log, filter)
+	}
+
+	// logs not containing the text are read as empty, rather than not found
+	query := ds.db.Rebind(`SELECT CASE WHEN LOWER(log) LIKE ? ESCAPE '!' THEN log ELSE '' END FROM logs WHERE id=? AND fn_id=?`)
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	pattern := "%" + r.Replace(strings.ToLower(filter.Match)) + "%"
+	row := ds.reader(ctx).QueryRowContext(ctx, query, pattern, callID, fnID)
+
+	var log string
+	err := row.Scan(&log)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrCallLogNotFound
+		}
+		return nil, err
+	}
+	return models.FilterLog(strings.NewReader(log), filter)
+}
+
+func (ds *SQLStore) InsertResult(ctx context.Context, call *models.Call, resultR io.Reader) error {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, resultR); err != nil {
+		return err
+	}
+
+	query := ds.db.Rebind(`INSERT INTO results (id, app_id, fn_id, result) VALUES (?, ?, ?, ?);`)
+	_, err := ds.writer(ctx).ExecContext(ctx, query, call.ID, call.AppID, call.FnID, b.Bytes())
 	return err
 }
 
-func (ds *SQLStore) GetCall1(ctx context.Context, appID, callID string) (*models.Call, error) {
-	/* #nosec */
-	query := fmt.Sprintf(`%s WHERE id=? AND app_id=?`, callSelector)
-	query = ds.db.Rebind(query)
-	row := ds.db.QueryRowxContext(ctx, query, callID, appID)
+func (ds *SQLStore) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	query := ds.db.Rebind(`SELECT result FROM results WHERE id=? AND fn_id=?`)
+	row := ds.reader(ctx).QueryRowContext(ctx, query, callID, fnID)
 
-	var call models.Call
-	err := row.StructScan(&call)
+	var result []byte
+	err := row.Scan(&result)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, models.ErrCallNotFound
+			return nil, models.ErrCallResultNotFound
 		}
 		return nil, err
 	}
-	return &call, nil
+
+	return bytes.NewReader(result), nil
 }
 
-func (ds *SQLStore) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
-	/* #nosec */
-	query := fmt.Sprintf(`%s WHERE id=? AND fn_id=?`, callSelector)
-	query = ds.db.Rebind(query)
-	row := ds.db.QueryRowxContext(ctx, query, callID, fnID)
+func (ds *SQLStore) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	b, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
 
-	var call models.Call
-	err := row.StructScan(&call)
+	query := ds.db.Rebind(`INSERT INTO dead_letters (id, app_id, fn_id, created_at, data) VALUES (?, ?, ?, ?, ?);`)
+	_, err = ds.writer(ctx).ExecContext(ctx, query, call.ID, call.AppID, call.FnID, call.CompletedAt.String(), string(b))
+	return err
+}
+
+func (ds *SQLStore) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	query := ds.db.Rebind(`SELECT data FROM dead_letters WHERE id=? AND fn_id=?`)
+	row := ds.reader(ctx).QueryRowContext(ctx, query, callID, fnID)
+
+	var b string
+	err := row.Scan(&b)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, models.ErrCallNotFound
+			return nil, models.ErrDeadLetterNotFound
 		}
 		return nil, err
 	}
+
+	var call models.Call
+	if err := json.Unmarshal([]byte(b), &call); err != nil {
+		return nil, err
+	}
 	return &call, nil
 }
 
-func (ds *SQLStore) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+func (ds *SQLStore) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	var b bytes.Buffer
+	var args []interface{}
+
+	args = where(&b, args, "fn_id=?", filter.FnID)
 	if filter.Cursor != "" {
 		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
 		if err != nil {
 			return nil, err
 		}
-		filter.Cursor = string(cursor)
-	}
-
-	calls, err := ds.GetCalls1(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-
-	callList := &models.CallList{Items: calls}
-
-	if len(calls) > 0 && len(calls) == filter.PerPage {
-		last := []byte(calls[len(calls)-1].ID)
-		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+		args = where(&b, args, "id<?", string(cursor))
 	}
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	fmt.Fprintf(&b, ` LIMIT ?`)
+	args = append(args, filter.PerPage)
 
-	return callList, nil
-}
-
-func (ds *SQLStore) GetCalls1(ctx context.Context, filter *models.CallFilter) ([]*models.Call, error) {
-	res := []*models.Call{}
-	query, args := buildFilterCallQuery(filter)
 	/* #nosec */
-	query = fmt.Sprintf("%s %s", callSelector, query)
-	query = ds.db.Rebind(query)
-	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	query := ds.db.Rebind(fmt.Sprintf("SELECT data FROM dead_letters %s", b.String()))
+	rows, err := ds.reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	calls := []*models.Call{}
 	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
 		var call models.Call
-		err := rows.StructScan(&call)
-		if err != nil {
-			continue
+		if err := json.Unmarshal([]byte(raw), &call); err != nil {
+			return nil, err
 		}
-		res = append(res, &call)
+		calls = append(calls, &call)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return res, nil
-}
 
-func (ds *SQLStore) InsertLog(ctx context.Context, call *models.Call, logR io.Reader) error {
-	// coerce this into a string for sql
-	var log string
-	if stringer, ok := logR.(fmt.Stringer); ok {
-		log = stringer.String()
-	} else {
-		// TODO we could optimize for Size / buffer pool, but atm we aren't hitting
-		// this code path anyway (a fallback)
-		var b bytes.Buffer
-		io.Copy(&b, logR)
-		log = b.String()
+	callList := &models.CallList{Items: calls}
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
 	}
-
-	query := ds.db.Rebind(`INSERT INTO logs (id, app_id, fn_id, log) VALUES (?, ?, ?, ?);`)
-	_, err := ds.db.ExecContext(ctx, query, call.ID, call.AppID, call.FnID, log)
-	return err
+	return callList, nil
 }
 
-func (ds *SQLStore) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
-	query := ds.db.Rebind(`SELECT log FROM logs WHERE id=? AND fn_id=?`)
-	row := ds.db.QueryRowContext(ctx, query, callID, fnID)
-
-	var log string
-	err := row.Scan(&log)
+func (ds *SQLStore) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	query := ds.db.Rebind(`DELETE FROM dead_letters WHERE id=? AND fn_id=?`)
+	res, err := ds.writer(ctx).ExecContext(ctx, query, callID, fnID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, models.ErrCallLogNotFound
-		}
-		return nil, err
+		return err
 	}
-
-	return strings.NewReader(log), nil
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrDeadLetterNotFound
+	}
+	return nil
 }
 
 func buildFilterAppQuery(filter *models.AppFilter) (string, []interface{}, error) {
@@ -880,18 +1886,19 @@ func buildFilterAppQuery(filter *models.AppFilter) (string, []interface{}, error
 
 	var b bytes.Buffer
 
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return "", args, err
-		}
-		args = where(&b, args, "name>?", string(s))
-	}
 	if filter.Name != "" {
 		args = where(&b, args, "name=?", filter.Name)
 	}
+	args = where(&b, args, "name LIKE ? ESCAPE '!'", namePrefixPattern(filter.NamePrefix))
+	args = whereKeyFilters(&b, args, resourceTypeApp, models.KeyFieldAnnotations, filter.Annotations)
+	args = whereKeyFilters(&b, args, resourceTypeApp, models.KeyFieldConfig, filter.Config)
+	args, err := whereAfter(&b, args, filter.Order, filter.Cursor)
+	if err != nil {
+		return "", args, err
+	}
+	whereDeleted(&b, filter.Deleted)
 
-	fmt.Fprintf(&b, ` ORDER BY name ASC`) // TODO assert this is indexed
+	orderBy(&b, filter.Order)
 	fmt.Fprintf(&b, ` LIMIT ?`)
 	args = append(args, filter.PerPage)
 	return b.String(), args, nil
@@ -932,18 +1939,19 @@ func buildFilterFnQuery(filter *models.FnFilter) (string, []interface{}, error)
 	// where(fmt.Sprintf("image LIKE '%s%%'"), filter.Image) // TODO needs escaping, prob we want prefix query to ignore tags
 	args = where(&b, args, "app_id=? ", filter.AppID)
 
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return "", args, err
-		}
-		args = where(&b, args, "name>?", string(s))
-	}
 	if filter.Name != "" {
 		args = where(&b, args, "name=?", filter.Name)
 	}
+	args = where(&b, args, "name LIKE ? ESCAPE '!'", namePrefixPattern(filter.NamePrefix))
+	args = whereKeyFilters(&b, args, resourceTypeFn, models.KeyFieldAnnotations, filter.Annotations)
+	args = whereKeyFilters(&b, args, resourceTypeFn, models.KeyFieldConfig, filter.Config)
+	args, err := whereAfter(&b, args, filter.Order, filter.Cursor)
+	if err != nil {
+		return "", args, err
+	}
+	whereDeleted(&b, filter.Deleted)
 
-	fmt.Fprintf(&b, ` ORDER BY name ASC`)
+	orderBy(&b, filter.Order)
 	if filter.PerPage > 0 {
 		fmt.Fprintf(&b, ` LIMIT ?`)
 		args = append(args, filter.PerPage)
@@ -951,6 +1959,65 @@ func buildFilterFnQuery(filter *models.FnFilter) (string, []interface{}, error)
 	return b.String(), args, nil
 }
 
+// whereDeleted restricts a query built with where to the rows marked deleted, or to the live
+// ones. It must follow the calls to where.
+func whereDeleted(b *bytes.Buffer, deleted bool) {
+	cond := "deleted_at IS NULL"
+	if deleted {
+		cond = "deleted_at IS NOT NULL"
+	}
+	if b.Len() == 0 {
+		fmt.Fprintf(b, `WHERE %s`, cond)
+	} else {
+		fmt.Fprintf(b, ` AND %s`, cond)
+	}
+}
+
+// whereAfter restricts a query to the rows following cursor in order, if any. Rows ordered
+// by time are ordered by id after it, as the index of each time column is. It must follow
+// the calls to where.
+func whereAfter(b *bytes.Buffer, args []interface{}, order models.ListOrder, cursor string) ([]interface{}, error) {
+	if cursor == "" {
+		return args, nil
+	}
+	c, err := order.DecodeCursor(cursor)
+	if err != nil {
+		return args, err
+	}
+
+	op := ">"
+	if order.Desc {
+		op = "<"
+	}
+	if b.Len() == 0 {
+		fmt.Fprintf(b, `WHERE `)
+	} else {
+		fmt.Fprintf(b, ` AND `)
+	}
+	if order.ByName() {
+		/* #nosec */
+		fmt.Fprintf(b, `name%s?`, op)
+		return append(args, c.Value), nil
+	}
+	/* #nosec */
+	fmt.Fprintf(b, `(%[1]s%[2]s? OR (%[1]s=? AND id%[2]s?))`, order.Field, op)
+	return append(args, c.Value, c.Value, c.ID), nil
+}
+
+// orderBy orders a query by the field of order, checked by models.ParseListOrder
+func orderBy(b *bytes.Buffer, order models.ListOrder) {
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+	if order.ByName() {
+		fmt.Fprintf(b, ` ORDER BY name %s`, direction)
+		return
+	}
+	/* #nosec */
+	fmt.Fprintf(b, ` ORDER BY %[1]s %[2]s, id %[2]s`, order.Field, direction)
+}
+
 func where(b *bytes.Buffer, args []interface{}, colOp string, val interface{}) []interface{} {
 	if val == nil {
 		return args
@@ -977,6 +2044,21 @@ func where(b *bytes.Buffer, args []interface{}, colOp string, val interface{}) [
 }
 
 func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigger) (*models.Trigger, error) {
+	var trigger *models.Trigger
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		trigger, err = ds.insertTrigger(ctx, tx, newTrigger)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return trigger, err
+}
+
+func (ds *SQLStore) insertTrigger(ctx context.Context, tx *sqlx.Tx, newTrigger *models.Trigger) (*models.Trigger, error) {
 
 	trigger := newTrigger.Clone()
 
@@ -988,68 +2070,68 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 	if err != nil {
 		return nil, err
 	}
+	err = models.RunPersistHook(ctx, trigger, true)
+	if err != nil {
+		return nil, err
+	}
 
-	err = ds.Tx(func(tx *sqlx.Tx) error {
-		query := tx.Rebind(`SELECT 1 FROM apps WHERE id=?`)
-		r := tx.QueryRowContext(ctx, query, trigger.AppID)
-		if err := r.Scan(new(int)); err != nil {
-			if err == sql.ErrNoRows {
-				return models.ErrAppsNotFound
-			} else if err != nil {
-				return err
-			}
-		}
-
-		query = tx.Rebind(`SELECT app_id FROM fns WHERE id=?`)
-		r = tx.QueryRowContext(ctx, query, trigger.FnID)
-		var app_id string
-		if err := r.Scan(&app_id); err != nil {
-			if err == sql.ErrNoRows {
-				return models.ErrFnsNotFound
-			} else if err != nil {
-				return err
-			}
-		}
-		if app_id != trigger.AppID {
-			return models.ErrTriggerFnIDNotSameApp
+	query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+	r := tx.QueryRowContext(ctx, query, trigger.AppID)
+	if err := r.Scan(new(int)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrAppsNotFound
+		} else if err != nil {
+			return nil, err
 		}
+	}
 
-		query = tx.Rebind(`SELECT 1 FROM triggers WHERE app_id=? AND type=? and source=?`)
-		r = tx.QueryRowContext(ctx, query, trigger.AppID, trigger.Type, trigger.Source)
-		err := r.Scan(new(int))
-		if err == nil {
-			return models.ErrTriggerSourceExists
-		} else if err != sql.ErrNoRows {
-			return err
+	query = tx.Rebind(`SELECT app_id FROM fns WHERE id=? AND deleted_at IS NULL`)
+	r = tx.QueryRowContext(ctx, query, trigger.FnID)
+	var app_id string
+	if err := r.Scan(&app_id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrFnsNotFound
+		} else if err != nil {
+			return nil, err
 		}
+	}
+	if app_id != trigger.AppID {
+		return nil, models.ErrTriggerFnIDNotSameApp
+	}
 
-		query = tx.Rebind(`INSERT INTO triggers (
-			id,
-			name,
-		  	app_id,
-			fn_id,
-			created_at,
-			updated_at,
-			type,
-		  	source,
-		  	annotations
-		)
-		VALUES (
-			:id,
-			:name,
-			:app_id,
-			:fn_id,
-			:created_at,
-			:updated_at,
-			:type,
-			:source,
-			:annotations
-		);`)
+	query = tx.Rebind(`SELECT 1 FROM triggers WHERE app_id=? AND type=? and source=?`)
+	r = tx.QueryRowContext(ctx, query, trigger.AppID, trigger.Type, trigger.Source)
+	err = r.Scan(new(int))
+	if err == nil {
+		return nil, models.ErrTriggerSourceExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
 
-		_, err = tx.NamedExecContext(ctx, query, trigger)
-		return err
-	})
+	query = tx.Rebind(`INSERT INTO triggers (
+		id,
+		name,
+	  	app_id,
+		fn_id,
+		created_at,
+		updated_at,
+		type,
+	  	source,
+	  	annotations
+	)
+	VALUES (
+		:id,
+		:name,
+		:app_id,
+		:fn_id,
+		:created_at,
+		:updated_at,
+		:type,
+		:source,
+		:annotations
+	);`)
 
+	_, err = tx.NamedExecContext(ctx, query, trigger)
 	if err != nil {
 		if ds.helper.IsDuplicateKeyError(err) {
 			return nil, models.ErrTriggerExists
@@ -1057,41 +2139,68 @@ func (ds *SQLStore) InsertTrigger(ctx context.Context, newTrigger *models.Trigge
 		return nil, err
 	}
 
-	return trigger, err
+	err = updateResourceKeys(ctx, tx, resourceTypeTrigger, trigger.ID, trigger.AppID, nil, models.ResourceKeys(nil, trigger.Annotations))
+	if err != nil {
+		return nil, err
+	}
+	return trigger, nil
 }
 
 func (ds *SQLStore) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
-	err := ds.Tx(func(tx *sqlx.Tx) error {
+	err := ds.tx(ctx, func(tx *sqlx.Tx) error {
+		var err error
+		trigger, err = updateTrigger(ctx, tx, trigger)
+		return err
+	})
 
-		var dst models.Trigger
-		query := tx.Rebind(triggerIDSelector)
-		row := tx.QueryRowxContext(ctx, query, trigger.ID)
-		err := row.StructScan(&dst)
+	if err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
 
-		if err != nil && err != sql.ErrNoRows {
-			return err
-		} else if err == sql.ErrNoRows {
-			return models.ErrTriggerNotFound
-		}
+func updateTrigger(ctx context.Context, tx *sqlx.Tx, trigger *models.Trigger) (*models.Trigger, error) {
+	var dst models.Trigger
+	query := tx.Rebind(triggerIDSelector)
+	row := tx.QueryRowxContext(ctx, query, trigger.ID)
+	err := row.StructScan(&dst)
 
-		dst.Update(trigger)
-		err = dst.Validate()
-		if err != nil {
-			return err
-		}
-		trigger = &dst // set for query & to return
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	} else if err == sql.ErrNoRows {
+		return nil, models.ErrTriggerNotFound
+	}
 
-		query = tx.Rebind(`UPDATE triggers SET
-			name = :name,
-			fn_id = :fn_id,
-			updated_at = :updated_at,
-			source = :source,
-			annotations = :annotations
-			WHERE id = :id;`)
-		_, err = tx.NamedExecContext(ctx, query, trigger)
-		return err
-	})
+	err = models.CheckIfMatch(ctx, &dst)
+	if err != nil {
+		return nil, err
+	}
+
+	oldKeys := models.ResourceKeys(nil, dst.Annotations)
+	dst.Update(trigger)
+	err = dst.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = models.RunPersistHook(ctx, &dst, false)
+	if err != nil {
+		return nil, err
+	}
+	trigger = &dst // set for query & to return
+
+	query = tx.Rebind(`UPDATE triggers SET
+		name = :name,
+		fn_id = :fn_id,
+		updated_at = :updated_at,
+		source = :source,
+		annotations = :annotations
+		WHERE id = :id;`)
+	_, err = tx.NamedExecContext(ctx, query, trigger)
+	if err != nil {
+		return nil, err
+	}
 
+	err = updateResourceKeys(ctx, tx, resourceTypeTrigger, trigger.ID, trigger.AppID, oldKeys, models.ResourceKeys(nil, trigger.Annotations))
 	if err != nil {
 		return nil, err
 	}
@@ -1102,7 +2211,7 @@ func (ds *SQLStore) GetTrigger(ctx context.Context, appId, fnId, triggerName str
 	var trigger models.Trigger
 	/* #nosec */
 	query := ds.db.Rebind(fmt.Sprintf("%s WHERE name=? AND app_id=? AND fn_id=?", fnSelector))
-	row := ds.db.QueryRowxContext(ctx, query, triggerName, appId, fnId)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, triggerName, appId, fnId)
 
 	err := row.StructScan(&trigger)
 	if err == sql.ErrNoRows {
@@ -1116,8 +2225,25 @@ func (ds *SQLStore) GetTrigger(ctx context.Context, appId, fnId, triggerName str
 }
 
 func (ds *SQLStore) RemoveTrigger(ctx context.Context, triggerId string) error {
-	query := ds.db.Rebind(`DELETE FROM triggers WHERE id = ?;`)
-	res, err := ds.db.ExecContext(ctx, query, triggerId)
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		return removeTrigger(ctx, tx, triggerId)
+	})
+}
+
+func removeTrigger(ctx context.Context, tx *sqlx.Tx, triggerId string) error {
+	err := checkIfMatch(ctx, tx, triggerIDSelector, triggerId, new(models.Trigger), models.ErrTriggerNotFound)
+	if err != nil {
+		return err
+	}
+
+	query := tx.Rebind(`DELETE FROM resource_keys WHERE resource_type=? AND resource_id=?`)
+	_, err = tx.ExecContext(ctx, query, resourceTypeTrigger, triggerId)
+	if err != nil {
+		return err
+	}
+
+	query = tx.Rebind(`DELETE FROM triggers WHERE id = ?;`)
+	res, err := tx.ExecContext(ctx, query, triggerId)
 	if err != nil {
 		return err
 	}
@@ -1137,7 +2263,7 @@ func (ds *SQLStore) RemoveTrigger(ctx context.Context, triggerId string) error {
 func (ds *SQLStore) GetTriggerByID(ctx context.Context, triggerID string) (*models.Trigger, error) {
 	var trigger models.Trigger
 	query := ds.db.Rebind(triggerIDSelector)
-	row := ds.db.QueryRowxContext(ctx, query, triggerID)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, triggerID)
 
 	err := row.StructScan(&trigger)
 	if err == sql.ErrNoRows {
@@ -1166,17 +2292,19 @@ func buildFilterTriggerQuery(filter *models.TriggerFilter) (string, []interface{
 		args = append(args, filter.Name)
 	}
 
-	if filter.Cursor != "" {
-		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
-		if err != nil {
-			return "", nil, err
-		}
+	if filter.NamePrefix != "" {
+		fmt.Fprintf(&b, ` AND name LIKE ? ESCAPE '!'`)
+		args = append(args, namePrefixPattern(filter.NamePrefix))
+	}
+
+	args = whereKeyFilters(&b, args, resourceTypeTrigger, models.KeyFieldAnnotations, filter.Annotations)
 
-		fmt.Fprintf(&b, ` AND name > ?`)
-		args = append(args, string(s))
+	args, err := whereAfter(&b, args, filter.Order, filter.Cursor)
+	if err != nil {
+		return "", nil, err
 	}
 
-	fmt.Fprintf(&b, ` ORDER BY name ASC`)
+	orderBy(&b, filter.Order)
 
 	if filter.PerPage > 0 {
 		fmt.Fprintf(&b, ` LIMIT ?`)
@@ -1200,7 +2328,7 @@ func (ds *SQLStore) GetTriggers(ctx context.Context, filter *models.TriggerFilte
 	/* #nosec */
 	query := fmt.Sprintf("%s WHERE %s", triggerSelector, filterQuery)
 	query = ds.db.Rebind(query)
-	rows, err := ds.db.QueryxContext(ctx, query, args...)
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return res, nil // no error for empty list
@@ -1219,8 +2347,8 @@ func (ds *SQLStore) GetTriggers(ctx context.Context, filter *models.TriggerFilte
 	}
 
 	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
-		last := []byte(res.Items[len(res.Items)-1].Name)
-		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+		last := res.Items[len(res.Items)-1]
+		res.NextCursor = filter.Order.EncodeCursor(filter.Order.Value(last.Name, last.CreatedAt, last.UpdatedAt), last.ID)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -1236,7 +2364,7 @@ func (ds *SQLStore) GetTriggerBySource(ctx context.Context, appId string, trigge
 	var trigger models.Trigger
 
 	query := ds.db.Rebind(triggerIDSourceSelector)
-	row := ds.db.QueryRowxContext(ctx, query, appId, triggerType, source)
+	row := ds.reader(ctx).QueryRowxContext(ctx, query, appId, triggerType, source)
 
 	err := row.StructScan(&trigger)
 	if err == sql.ErrNoRows {
@@ -1249,6 +2377,11 @@ func (ds *SQLStore) GetTriggerBySource(ctx context.Context, appId string, trigge
 
 // Close closes the database, releasing any open resources.
 func (ds *SQLStore) Close() error {
+	close(ds.closed)
+	for _, replica := range ds.replicas {
+		replica.Close()
+	}
+	ds.releaseWrites()
 	return ds.db.Close()
 }
 
@@ -1,13 +1,24 @@
 package sql
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/datastore/datastoretest"
 	"github.com/fnproject/fn/api/datastore/internal/datastoreutil"
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
 	"github.com/fnproject/fn/api/datastore/sql/migratex"
 	"github.com/fnproject/fn/api/datastore/sql/migrations"
 	_ "github.com/fnproject/fn/api/datastore/sql/mysql"
@@ -16,6 +27,7 @@ import (
 	logstoretest "github.com/fnproject/fn/api/logs/testing"
 	"github.com/fnproject/fn/api/models"
 	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
 )
 
 // since New with fresh dbs skips all migrations:
@@ -164,3 +176,422 @@ func TestClose(t *testing.T) {
 		t.Fatalf("Failed to close datastore: %v", err)
 	}
 }
+
+func TestReadReplicas(t *testing.T) {
+	ctx := context.Background()
+	defer os.RemoveAll("sqlite_test_dir")
+	defer os.RemoveAll("sqlite_replica_dir")
+	os.RemoveAll("sqlite_test_dir")
+	os.RemoveAll("sqlite_replica_dir")
+
+	// the replica is a db of its own, so reads it serves miss the writes to the primary
+	replicaURL, err := url.Parse("sqlite3://sqlite_replica_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica, err := newDS(ctx, replicaURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	os.Setenv(EnvDBReadURL, replicaURL.String())
+	defer os.Unsetenv(EnvDBReadURL)
+	u, err := url.Parse("sqlite3://sqlite_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := newDS(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetAppByID(ctx, app.ID); err != models.ErrAppsNotFound {
+		t.Fatalf("expected the app to be read from the replica, got %v", err)
+	}
+
+	ctx = common.WithWriteTracking(ctx)
+	if _, err := ds.GetAppByID(ctx, app.ID); err != models.ErrAppsNotFound {
+		t.Fatalf("expected the app to be read from the replica before a write, got %v", err)
+	}
+	if _, err := ds.UpdateApp(ctx, &models.App{ID: app.ID, Config: models.Config{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ds.GetAppByID(ctx, app.ID)
+	if err != nil {
+		t.Fatalf("expected the app to be read from the primary after a write, got %v", err)
+	}
+	if got.Config["k"] != "v" {
+		t.Fatalf("expected the update to be read, got %+v", got)
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	os.Setenv(EnvDBMaxOpenConns, "8")
+	defer os.Unsetenv(EnvDBMaxOpenConns)
+	if err := configurePool(db, logrus.StandardLogger()); err != nil {
+		t.Fatal(err)
+	}
+	if max := db.Stats().MaxOpenConnections; max != 8 {
+		t.Fatalf("expected at most 8 open connections, got %d", max)
+	}
+
+	os.Setenv(EnvDBMaxOpenConns, "-1")
+	if err := configurePool(db, logrus.StandardLogger()); err == nil {
+		t.Fatal("expected a negative number of connections to be invalid")
+	}
+}
+
+// likeHelper hides the full-text search of a db, so that it is searched with LIKE like mysql
+type likeHelper struct {
+	dbhelper.Helper
+}
+
+func TestSearchLike(t *testing.T) {
+	ctx := context.Background()
+	defer os.RemoveAll("sqlite_like_dir")
+	u, err := url.Parse("sqlite3://sqlite_like_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := func(t *testing.T) models.Datastore {
+		os.RemoveAll("sqlite_like_dir")
+		ds, err := newDS(ctx, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds.helper = likeHelper{ds.helper}
+		return datastoreutil.NewValidator(ds)
+	}
+	// the searches with LIKE match the same words as the full-text searches of TestDatastore
+	datastoretest.RunSearchTest(t, f, datastoretest.NewBasicResourceProvider())
+}
+
+func TestSQLiteWrites(t *testing.T) {
+	ctx := context.Background()
+	defer os.RemoveAll("sqlite_writes_dir")
+	os.RemoveAll("sqlite_writes_dir")
+	u, err := url.Parse("sqlite3://sqlite_writes_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(EnvDBSQLiteJournalMode, "wal")
+	defer os.Unsetenv(EnvDBSQLiteJournalMode)
+	os.Setenv(EnvDBSQLiteBusyTimeout, "1000")
+	defer os.Unsetenv(EnvDBSQLiteBusyTimeout)
+
+	// the datastore and the logstore of a node commonly share their db
+	ds, err := newDS(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := newDS(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.writes == nil || ds.writes != ls.writes {
+		t.Fatal("expected the writes to the db to share a queue")
+	}
+
+	var mode string
+	if err := ds.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Fatalf("expected the db to be in WAL mode, got %s", mode)
+	}
+	if ds.db.Stats().MaxOpenConnections == 1 {
+		t.Fatal("expected the reads of a db in WAL mode not to share a single connection")
+	}
+
+	errs := make(chan error)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			if i%2 == 0 {
+				_, err := ds.InsertApp(ctx, &models.App{Name: fmt.Sprintf("app%d", i)})
+				errs <- err
+				return
+			}
+			errs <- ls.InsertCall(ctx, &models.Call{ID: fmt.Sprintf("call%d", i), AppID: "app", FnID: "fn"})
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected concurrent writes to succeed, got %v", err)
+		}
+	}
+
+	ds.Close()
+	ls.Close()
+	writeQueuesLock.Lock()
+	defer writeQueuesLock.Unlock()
+	if _, ok := writeQueues["sqlite_writes_dir"]; ok {
+		t.Fatal("expected the queue to be stopped with the datastores of its db")
+	}
+}
+
+func TestTLSFromEnv(t *testing.T) {
+	defer os.Unsetenv(EnvDBTLSMode)
+	defer os.Unsetenv(EnvDBTLSCA)
+
+	conf, err := tlsFromEnv()
+	if err != nil || conf != nil {
+		t.Fatalf("expected no TLS config, got %v %v", conf, err)
+	}
+
+	ca, err := ioutil.TempFile("", "ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(ca.Name())
+	ca.Close()
+	os.Setenv(EnvDBTLSCA, ca.Name())
+	conf, err = tlsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Mode != dbhelper.TLSVerifyFull || conf.CAFile != ca.Name() {
+		t.Fatalf("expected connections verified by %s, got %+v", ca.Name(), conf)
+	}
+
+	os.Setenv(EnvDBTLSMode, "verify-some")
+	if _, err := tlsFromEnv(); err == nil {
+		t.Fatal("expected an invalid TLS mode to fail")
+	}
+
+	os.Setenv(EnvDBTLSMode, dbhelper.TLSRequire)
+	os.Setenv(EnvDBTLSCA, ca.Name()+".missing")
+	if _, err := tlsFromEnv(); err == nil {
+		t.Fatal("expected a missing CA to fail")
+	}
+}
+
+func TestCloudSQLTokens(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		requests++
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600,"token_type":"Bearer"}`, requests)
+	}))
+	defer srv.Close()
+
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	defer os.Unsetenv("GCE_METADATA_HOST")
+	tokens, err := newTokenSource("gcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := tokens.Token(context.Background(), "db:5432", "fn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token-1" {
+			t.Fatalf("expected the cached token-1, got %s", token)
+		}
+	}
+
+	// tokens about to expire are renewed
+	tokens.(*cloudSQLTokens).expiry = time.Now()
+	token, err := tokens.Token(context.Background(), "db:5432", "fn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token-2" {
+		t.Fatalf("expected a new token-2, got %s", token)
+	}
+}
+
+func TestTokenConnector(t *testing.T) {
+	f, err := ioutil.TempFile("", "tokens.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	db, err := sqlx.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dsns int
+	conn := &tokenConnector{driver: db.Driver(), dsn: func(context.Context) (string, error) {
+		dsns++
+		return f.Name(), nil
+	}}
+	db.Close()
+
+	tdb := sql.OpenDB(conn)
+	defer tdb.Close()
+	tdb.SetMaxIdleConns(0)
+	for i := 0; i < 3; i++ {
+		if err := tdb.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dsns != 3 {
+		t.Fatalf("expected a dsn for each of the 3 connections, got %d", dsns)
+	}
+}
+
+func TestTokensRequireTLS(t *testing.T) {
+	for i, test := range []struct {
+		url       string
+		encrypted bool
+	}{
+		{"postgres://fn@db:5432/fn", false},
+		{"postgres://fn@db:5432/fn?sslmode=prefer", false},
+		{"postgres://fn@db:5432/fn?sslmode=require", true},
+		{"postgres://fn@db:5432/fn?sslmode=verify-full", true},
+		{"mysql://fn@db:3306/fn", false},
+		{"mysql://fn@db:3306/fn?tls=preferred", false},
+		{"mysql://fn@db:3306/fn?tls=true", true},
+	} {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		helper, ok := dbhelper.GetHelper(u.Scheme)
+		if !ok {
+			t.Fatalf("Test %d: no helper of %s", i, u.Scheme)
+		}
+		// tokens can't be issued without an IAM provider, the URL is rejected before
+		_, err = connectorWithTokens(context.Background(), helper, u, "none", logrus.New())
+		if err == nil || strings.Contains(err.Error(), "encrypted") == test.encrypted {
+			t.Fatalf("Test %d: expected %s to be rejected for encryption %v, got %v", i, test.url, !test.encrypted, err)
+		}
+	}
+}
+
+func TestSlowStatements(t *testing.T) {
+	f, err := ioutil.TempFile("", "slow.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+
+	connector, err := newDSNConnector("sqlite3", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every statement is slow
+	db := sqlx.NewDb(sql.OpenDB(&slowConnector{Connector: connector, threshold: time.Nanosecond}), "sqlite3")
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE secrets (name text, value text)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO secrets (name, value) VALUES ('token', 'hunter2'), (?, ?)", "password", "swordfish"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRowxContext(ctx, db.Rebind("SELECT count(*) FROM secrets WHERE length(value) > 4 AND name != ?"), "other").Scan(&n); err != nil || n != 2 {
+		t.Fatalf("expected 2 secrets, got %d %v", n, err)
+	}
+
+	logs := buf.String()
+	for _, expected := range []string{
+		"INSERT INTO secrets (name, value) VALUES (?, ?), (?, ?)",
+		"SELECT count(*) FROM secrets WHERE length(value) > ? AND name != ?",
+	} {
+		if !strings.Contains(logs, expected) {
+			t.Fatalf("expected the statement %q to be logged, got %s", expected, logs)
+		}
+	}
+	for _, secret := range []string{"hunter2", "swordfish"} {
+		if strings.Contains(logs, secret) {
+			t.Fatalf("expected %s not to be logged, got %s", secret, logs)
+		}
+	}
+}
+
+func TestMigrateOnly(t *testing.T) {
+	f, err := ioutil.TempFile("", "migrate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	u, err := url.Parse("sqlite3://" + f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	ds, err := newDS(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds.Close()
+
+	db, err := sqlx.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	version := func() int64 {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Commit()
+		v, _, err := migratex.Version(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	latest := latestVersion(migrations.Migrations)
+
+	os.Setenv(EnvDBMigrateDownTo, strconv.FormatInt(latest-1, 10))
+	defer os.Unsetenv(EnvDBMigrateDownTo)
+	os.Setenv(EnvDBMigrateDryRun, "true")
+	defer os.Unsetenv(EnvDBMigrateDryRun)
+	if _, err := newDS(ctx, u); err != ErrMigrateOnly {
+		t.Fatalf("expected the datastore to print its migrations only, got %v", err)
+	}
+	if v := version(); v != latest {
+		t.Fatalf("expected a dry run to leave the db at %d, got %d", latest, v)
+	}
+
+	os.Unsetenv(EnvDBMigrateDownTo)
+	os.Unsetenv(EnvDBMigrateDryRun)
+
+	// the datastore refuses to run against the schema of a newer fn
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migratex.SetVersion(ctx, tx, latest+1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = newDS(ctx, u)
+	if _, ok := err.(migratex.ErrNewerSchema); !ok {
+		t.Fatalf("expected a newer schema error, got %v", err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
 	"github.com/jmoiron/sqlx"
@@ -32,11 +34,47 @@ func (sqliteHelper) PreConnect(url *url.URL) (string, error) {
 	return strings.TrimPrefix(url.String(), url.Scheme+"://"), nil
 }
 
+// PostCreate uses a single connection to dbs not in WAL mode, whose readers block writers
 func (sqliteHelper) PostCreate(db *sqlx.DB) (*sqlx.DB, error) {
-	db.SetMaxOpenConns(1)
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(mode, "wal") {
+		db.SetMaxOpenConns(1)
+	}
 	return db, nil
 }
 
+// setDefault sets the param key of q, unless it or one of its aliases is set
+func setDefault(q url.Values, value string, key string, aliases ...string) {
+	if value == "" {
+		return
+	}
+	for _, k := range append(aliases, key) {
+		if _, ok := q[k]; ok {
+			return
+		}
+	}
+	q.Set(key, value)
+}
+
+func (sqliteHelper) WithLocking(u *url.URL, journalMode, synchronous string, busyTimeout time.Duration) *url.URL {
+	q := u.Query()
+	setDefault(q, journalMode, "_journal_mode", "_journal")
+	setDefault(q, synchronous, "_synchronous", "_sync")
+	if busyTimeout > 0 {
+		setDefault(q, strconv.FormatInt(int64(busyTimeout/time.Millisecond), 10), "_busy_timeout", "_timeout")
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return &c
+}
+
+func (sqliteHelper) WriteLockKey(u *url.URL) string {
+	return filepath.Clean(u.Host + u.Path)
+}
+
 func (sqliteHelper) CheckTableExists(tx *sqlx.Tx, table string) (bool, error) {
 	query := tx.Rebind(`SELECT count(*)
 		FROM sqlite_master
@@ -54,6 +92,23 @@ func (sqliteHelper) CheckTableExists(tx *sqlx.Tx, table string) (bool, error) {
 	return exists, nil
 }
 
+func (sqliteHelper) CheckIndexExists(tx *sqlx.Tx, table, index string) (bool, error) {
+	query := tx.Rebind(`SELECT count(*)
+		FROM sqlite_master
+		WHERE type = 'index' AND tbl_name = ? AND name = ?`)
+
+	row := tx.QueryRow(query, table, index)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	exists := count > 0
+	return exists, nil
+}
+
 func (sqliteHelper) String() string {
 	return "sqlite"
 }
@@ -68,6 +123,21 @@ func (sqliteHelper) IsDuplicateKeyError(err error) bool {
 	return false
 }
 
+func (sqliteHelper) SearchTableStatements() []string {
+	return []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_documents USING fts4(resource_type, resource_id, app_id, name, body);`,
+	}
+}
+
+func (sqliteHelper) SearchCondition(terms []string) (string, []interface{}) {
+	// a trailing * matches the words starting with the term
+	prefixes := make([]string, len(terms))
+	for i, t := range terms {
+		prefixes[i] = t + "*"
+	}
+	return `body MATCH ?`, []interface{}{strings.Join(prefixes, " ")}
+}
+
 func init() {
 	dbhelper.Register(sqliteHelper(0))
 }
@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvDBTLSMode is the TLS mode of the connections to the db, one of disable, require,
+	// verify-ca or verify-full. It is verify-full if any of the files below is set, the TLS
+	// parameters of the URL are used otherwise.
+	EnvDBTLSMode = "FN_DS_DB_TLS_MODE"
+	// EnvDBTLSCA is the PEM bundle of the CAs verifying the db, the CAs of the system by default
+	EnvDBTLSCA = "FN_DS_DB_TLS_CA"
+	// EnvDBTLSCert is the PEM certificate of fn, for dbs authenticating clients by certificate
+	EnvDBTLSCert = "FN_DS_DB_TLS_CERT"
+	// EnvDBTLSKey is the PEM key of EnvDBTLSCert
+	EnvDBTLSKey = "FN_DS_DB_TLS_KEY"
+)
+
+// tlsFromEnv returns the TLS configuration of the env, nil if there is none
+func tlsFromEnv() (*dbhelper.TLS, error) {
+	t := &dbhelper.TLS{
+		Mode:     os.Getenv(EnvDBTLSMode),
+		CAFile:   os.Getenv(EnvDBTLSCA),
+		CertFile: os.Getenv(EnvDBTLSCert),
+		KeyFile:  os.Getenv(EnvDBTLSKey),
+	}
+	if t.Mode == "" {
+		if t.CAFile == "" && t.CertFile == "" && t.KeyFile == "" {
+			return nil, nil
+		}
+		t.Mode = dbhelper.TLSVerifyFull
+	}
+
+	switch t.Mode {
+	case dbhelper.TLSDisable, dbhelper.TLSRequire, dbhelper.TLSVerifyCA, dbhelper.TLSVerifyFull:
+	default:
+		return nil, fmt.Errorf("invalid %s=%s, expected disable, require, verify-ca or verify-full", EnvDBTLSMode, t.Mode)
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", EnvDBTLSCert, EnvDBTLSKey)
+	}
+	// the files are read by each new connection, fail now rather than on first use
+	for _, f := range []string{t.CAFile, t.CertFile, t.KeyFile} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return nil, fmt.Errorf("cannot read db TLS file: %s", err)
+		}
+	}
+	return t, nil
+}
+
+// withTLS returns u with the TLS configuration of the env, if any and if the db of helper
+// supports it
+func withTLS(helper dbhelper.Helper, u *url.URL, log logrus.FieldLogger) (*url.URL, error) {
+	t, err := tlsFromEnv()
+	if err != nil || t == nil {
+		return u, err
+	}
+	c, ok := helper.(dbhelper.TLSConfigurer)
+	if !ok {
+		log.Warnf("%s is ignored, the %s db has no TLS connections", EnvDBTLSMode, u.Scheme)
+		return u, nil
+	}
+	log.WithFields(logrus.Fields{"tls_mode": t.Mode, "tls_client_cert": t.CertFile != ""}).Info("db connections configured for TLS")
+	return c.WithTLS(u, t)
+}
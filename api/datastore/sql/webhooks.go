@@ -0,0 +1,243 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/jmoiron/sqlx"
+)
+
+const webhookSelector = `SELECT id,url,events,app_id,secret,created_at FROM webhooks`
+
+const webhookDeliverySelector = `SELECT id,webhook_id,event_id,event,status,attempts,status_code,error,created_at,completed_at FROM webhook_deliveries`
+
+func (ds *SQLStore) InsertWebhook(ctx context.Context, newWebhook *models.Webhook) (*models.Webhook, error) {
+	webhook := *newWebhook
+	webhook.ID = id.New().String()
+	webhook.CreatedAt = common.DateTime(time.Now())
+
+	err := webhook.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ds.tx(ctx, func(tx *sqlx.Tx) error {
+		if webhook.AppID != "" {
+			query := tx.Rebind(`SELECT 1 FROM apps WHERE id=? AND deleted_at IS NULL`)
+			r := tx.QueryRowContext(ctx, query, webhook.AppID)
+			if err := r.Scan(new(int)); err != nil {
+				if err == sql.ErrNoRows {
+					return models.ErrAppsNotFound
+				}
+				return err
+			}
+		}
+
+		query := tx.Rebind(`INSERT INTO webhooks (
+			id,
+			url,
+			events,
+			app_id,
+			secret,
+			created_at
+		)
+		VALUES (
+			:id,
+			:url,
+			:events,
+			:app_id,
+			:secret,
+			:created_at
+		);`)
+
+		_, err := tx.NamedExecContext(ctx, query, &webhook)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (ds *SQLStore) GetWebhookByID(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	var webhook models.Webhook
+	query := ds.db.Rebind(webhookSelector + ` WHERE id=?`)
+	err := ds.reader(ctx).QueryRowxContext(ctx, query, webhookID).StructScan(&webhook)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (ds *SQLStore) GetWebhooks(ctx context.Context, filter *models.WebhookFilter) (*models.WebhookList, error) {
+	res := &models.WebhookList{Items: []*models.Webhook{}}
+	if filter == nil {
+		filter = new(models.WebhookFilter)
+	}
+
+	var b bytes.Buffer
+	var args []interface{}
+	fmt.Fprintf(&b, `1=1`)
+	if filter.AppID != "" {
+		fmt.Fprintf(&b, ` AND app_id = ?`)
+		args = append(args, filter.AppID)
+	}
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id > ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id ASC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", webhookSelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.StructScan(&webhook); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &webhook)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ds *SQLStore) RemoveWebhook(ctx context.Context, webhookID string) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`DELETE FROM webhooks WHERE id=?`)
+		res, err := tx.ExecContext(ctx, query, webhookID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return models.ErrWebhookNotFound
+		}
+
+		query = tx.Rebind(`DELETE FROM webhook_deliveries WHERE webhook_id=?`)
+		_, err = tx.ExecContext(ctx, query, webhookID)
+		return err
+	})
+}
+
+func (ds *SQLStore) InsertWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return ds.tx(ctx, func(tx *sqlx.Tx) error {
+		query := tx.Rebind(`SELECT 1 FROM webhooks WHERE id=?`)
+		r := tx.QueryRowContext(ctx, query, delivery.WebhookID)
+		if err := r.Scan(new(int)); err != nil {
+			if err == sql.ErrNoRows {
+				return models.ErrWebhookNotFound
+			}
+			return err
+		}
+
+		query = tx.Rebind(`INSERT INTO webhook_deliveries (
+			id,
+			webhook_id,
+			event_id,
+			event,
+			status,
+			attempts,
+			status_code,
+			error,
+			created_at,
+			completed_at
+		)
+		VALUES (
+			:id,
+			:webhook_id,
+			:event_id,
+			:event,
+			:status,
+			:attempts,
+			:status_code,
+			:error,
+			:created_at,
+			:completed_at
+		);`)
+
+		_, err := tx.NamedExecContext(ctx, query, delivery)
+		return err
+	})
+}
+
+func (ds *SQLStore) GetWebhookDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryList, error) {
+	res := &models.WebhookDeliveryList{Items: []*models.WebhookDelivery{}}
+
+	var b bytes.Buffer
+	args := []interface{}{filter.WebhookID}
+	fmt.Fprintf(&b, `webhook_id = ?`)
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, ` AND id < ?`)
+		args = append(args, string(s))
+	}
+	fmt.Fprintf(&b, ` ORDER BY id DESC`)
+	if filter.PerPage > 0 {
+		fmt.Fprintf(&b, ` LIMIT ?`)
+		args = append(args, filter.PerPage)
+	}
+
+	/* #nosec */
+	query := ds.db.Rebind(fmt.Sprintf("%s WHERE %s", webhookDeliverySelector, b.String()))
+	rows, err := ds.reader(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.StructScan(&delivery); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, &delivery)
+	}
+
+	if len(res.Items) > 0 && len(res.Items) == filter.PerPage {
+		last := []byte(res.Items[len(res.Items)-1].ID)
+		res.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
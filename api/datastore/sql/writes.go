@@ -0,0 +1,148 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore/sql/dbhelper"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvDBSQLiteJournalMode is the journal mode of sqlite dbs, WAL letting reads go on while
+	// a write is made, the default of sqlite otherwise
+	EnvDBSQLiteJournalMode = "FN_DS_DB_SQLITE_JOURNAL_MODE"
+	// EnvDBSQLiteSynchronous is the synchronous setting of sqlite dbs, one of OFF, NORMAL,
+	// FULL or EXTRA, NORMAL in WAL mode and FULL otherwise by default
+	EnvDBSQLiteSynchronous = "FN_DS_DB_SQLITE_SYNCHRONOUS"
+	// EnvDBSQLiteBusyTimeout is the number of milliseconds sqlite waits for the db to be
+	// unlocked by the writes of other processes, 5000 by default
+	EnvDBSQLiteBusyTimeout = "FN_DS_DB_SQLITE_BUSY_TIMEOUT"
+)
+
+// withLocking returns u with the locking settings of the env, if the db of helper locks the
+// whole db for each write
+func withLocking(helper dbhelper.Helper, u *url.URL, log logrus.FieldLogger) (*url.URL, error) {
+	journalMode := os.Getenv(EnvDBSQLiteJournalMode)
+	synchronous := os.Getenv(EnvDBSQLiteSynchronous)
+	busyTimeout, err := getEnvInt(EnvDBSQLiteBusyTimeout, 0)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := helper.(dbhelper.WriteLocker)
+	if !ok {
+		if journalMode != "" || synchronous != "" || busyTimeout != 0 {
+			log.Warnf("the sqlite settings are ignored by the %s db", u.Scheme)
+		}
+		return u, nil
+	}
+	if journalMode != "" || synchronous != "" || busyTimeout != 0 {
+		log.WithFields(logrus.Fields{
+			"journal_mode": journalMode,
+			"synchronous":  synchronous,
+			"busy_timeout": busyTimeout,
+		}).Info("db locking configured")
+	}
+	return l.WithLocking(u, strings.ToUpper(journalMode), strings.ToUpper(synchronous), time.Duration(busyTimeout)*time.Millisecond), nil
+}
+
+// writeQueue makes the writes to a db one at a time in a goroutine of its own, for the dbs
+// locked as a whole by each write. The datastores of a db in a process share its queue.
+type writeQueue struct {
+	key    string
+	refs   int
+	writes chan func()
+	done   chan struct{}
+}
+
+var (
+	writeQueuesLock sync.Mutex
+	writeQueues     = make(map[string]*writeQueue)
+)
+
+// acquireWriteQueue returns the queue of the writes to the db of u, if the db of helper is
+// locked by each write, nil otherwise. It must be released once the db is closed.
+func acquireWriteQueue(helper dbhelper.Helper, u *url.URL) *writeQueue {
+	l, ok := helper.(dbhelper.WriteLocker)
+	if !ok {
+		return nil
+	}
+	key := l.WriteLockKey(u)
+
+	writeQueuesLock.Lock()
+	defer writeQueuesLock.Unlock()
+	q, ok := writeQueues[key]
+	if !ok {
+		q = &writeQueue{key: key, writes: make(chan func()), done: make(chan struct{})}
+		writeQueues[key] = q
+		go q.run()
+	}
+	q.refs++
+	return q
+}
+
+// release stops the queue once all the datastores of its db released it
+func (q *writeQueue) release() {
+	writeQueuesLock.Lock()
+	defer writeQueuesLock.Unlock()
+	q.refs--
+	if q.refs == 0 {
+		delete(writeQueues, q.key)
+		close(q.done)
+	}
+}
+
+func (q *writeQueue) run() {
+	for {
+		select {
+		case write := <-q.writes:
+			write()
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// do makes the write f once the writes queued before are made, unless ctx is done first
+func (q *writeQueue) do(ctx context.Context, f func() error) error {
+	res := make(chan error, 1)
+	select {
+	case q.writes <- func() { res <- f() }:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-res
+}
+
+// queuedConn makes the statements run with a db, outside of transactions, through the queue
+// of its writes. Queries are not expected to write.
+type queuedConn struct {
+	*sqlx.DB
+	writes *writeQueue
+}
+
+func (c *queuedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := c.writes.do(ctx, func() error {
+		var err error
+		res, err = c.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (c *queuedConn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := c.writes.do(ctx, func() error {
+		var err error
+		res, err = c.DB.NamedExecContext(ctx, query, arg)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,244 @@
+// Package janitor removes the calls of functions, with their logs and results, from the log
+// store once they expire by the log retention of their app, see models.LogRetention. The
+// calls of apps and functions marked deleted expire as those of live ones do.
+//
+// Any number of nodes may run a Janitor against the same datastore and log store, a
+// datastore lease elects the one that removes.
+package janitor
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LeaseName is the name of the datastore lease held by the janitor that removes
+	LeaseName = "janitor"
+
+	// DefaultInterval is the default interval expired calls are polled at
+	DefaultInterval = time.Hour
+	// DefaultLeaseTTL is the default time a janitor remains leader for after it last polled
+	DefaultLeaseTTL = 3 * time.Hour
+	// DefaultPageSize is the default number of apps, functions and calls listed at once
+	DefaultPageSize = 100
+)
+
+// Janitor removes expired calls, see the package documentation
+type Janitor struct {
+	ds     models.Datastore
+	ls     models.LogStore
+	holder string
+	// retention is that of the apps without a log retention annotation, if any
+	retention *models.LogRetention
+	interval  time.Duration
+	leaseTTL  time.Duration
+	pageSize  int
+}
+
+// Option configures a Janitor
+type Option func(*Janitor)
+
+// WithDefaultRetention sets the log retention of the apps without a log retention annotation,
+// their calls are kept otherwise
+func WithDefaultRetention(retention *models.LogRetention) Option {
+	return func(j *Janitor) {
+		j.retention = retention
+	}
+}
+
+// WithInterval sets the interval expired calls are polled at
+func WithInterval(d time.Duration) Option {
+	return func(j *Janitor) {
+		j.interval = d
+	}
+}
+
+// WithLeaseTTL sets the time a janitor remains leader for after it last polled, it must
+// exceed the poll interval
+func WithLeaseTTL(d time.Duration) Option {
+	return func(j *Janitor) {
+		j.leaseTTL = d
+	}
+}
+
+// WithPageSize sets the number of apps, functions and calls listed at once
+func WithPageSize(n int) Option {
+	return func(j *Janitor) {
+		j.pageSize = n
+	}
+}
+
+// New creates a janitor removing the expired calls of the functions of ds from ls
+func New(ds models.Datastore, ls models.LogStore, opts ...Option) *Janitor {
+	j := &Janitor{
+		ds:       ds,
+		ls:       ls,
+		holder:   id.New().String(),
+		interval: DefaultInterval,
+		leaseTTL: DefaultLeaseTTL,
+		pageSize: DefaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Run polls for expired calls until ctx is done
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Tick(ctx, time.Now()); err != nil {
+			common.Logger(ctx).WithError(err).Error("error removing expired calls")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick removes the calls expired at now if this janitor holds the janitor lease
+func (j *Janitor) Tick(ctx context.Context, now time.Time) error {
+	leader, err := j.ds.AcquireLease(ctx, LeaseName, j.holder, j.leaseTTL)
+	if err != nil || !leader {
+		return err
+	}
+
+	for _, deleted := range []bool{false, true} {
+		if err := j.cleanApps(ctx, deleted, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanApps removes the expired calls of the live apps, or of those marked deleted
+func (j *Janitor) cleanApps(ctx context.Context, deleted bool, now time.Time) error {
+	filter := &models.AppFilter{PerPage: j.pageSize, Deleted: deleted}
+	for {
+		apps, err := j.ds.GetApps(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps.Items {
+			retention, err := models.LogRetentionFromAnnotations(app.Annotations)
+			if err != nil {
+				common.Logger(ctx).WithError(err).WithField("app_id", app.ID).Error("invalid log retention")
+				continue
+			}
+			if retention == nil {
+				retention = j.retention
+			}
+			if retention == nil || (retention.Days == 0 && retention.Bytes == 0) {
+				continue
+			}
+			if err := j.cleanApp(ctx, app.ID, retention, now); err != nil {
+				return err
+			}
+		}
+		if apps.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
+
+// cleanApp removes the expired calls of the functions of appID, live or marked deleted
+func (j *Janitor) cleanApp(ctx context.Context, appID string, retention *models.LogRetention, now time.Time) error {
+	for _, deleted := range []bool{false, true} {
+		if err := j.cleanFns(ctx, appID, deleted, retention, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *Janitor) cleanFns(ctx context.Context, appID string, deleted bool, retention *models.LogRetention, now time.Time) error {
+	filter := &models.FnFilter{AppID: appID, PerPage: j.pageSize, Deleted: deleted}
+	for {
+		fns, err := j.ds.GetFns(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns.Items {
+			if err := j.cleanFn(ctx, fn.ID, retention, now); err != nil {
+				return err
+			}
+		}
+		if fns.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = fns.NextCursor
+	}
+}
+
+// cleanFn removes the calls of fnID created before the expiry of retention, and those past
+// the most recent ones whose logs fit in its bytes. Logs are measured by the size recorded on
+// their calls, only those of calls recorded without one are read.
+func (j *Janitor) cleanFn(ctx context.Context, fnID string, retention *models.LogRetention, now time.Time) error {
+	expiry := retention.Expiry(now)
+	filter := &models.CallFilter{FnID: fnID, PerPage: j.pageSize}
+	if retention.Bytes == 0 {
+		// only the expired calls are listed
+		filter.ToTime = common.DateTime(expiry)
+	}
+
+	var size int64
+	for {
+		calls, err := j.ls.GetCalls(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, call := range calls.Items {
+			remove := !expiry.IsZero() && time.Time(call.CreatedAt).Before(expiry)
+			if !remove && retention.Bytes > 0 && size <= retention.Bytes {
+				n, err := j.logSize(ctx, call)
+				if err != nil {
+					return err
+				}
+				size += n
+			}
+			if !remove && (retention.Bytes == 0 || size <= retention.Bytes) {
+				continue
+			}
+
+			err := j.ls.RemoveCall(ctx, fnID, call.ID)
+			if err != nil && err != models.ErrCallNotFound {
+				common.Logger(ctx).WithError(err).WithFields(logrus.Fields{
+					"fn_id": fnID, "call_id": call.ID,
+				}).Error("error removing expired call")
+			}
+		}
+		if calls.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = calls.NextCursor
+	}
+}
+
+// logSize returns the size of the log of a call, 0 if it has none
+func (j *Janitor) logSize(ctx context.Context, call *models.Call) (int64, error) {
+	if call.LogSize > 0 {
+		return call.LogSize, nil
+	}
+	log, err := j.ls.GetLog(ctx, call.FnID, call.ID)
+	if err == models.ErrCallLogNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(ioutil.Discard, log)
+}
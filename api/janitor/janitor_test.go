@@ -0,0 +1,191 @@
+package janitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+)
+
+func retentionApp(t *testing.T, appID string, retention map[string]interface{}) *models.App {
+	app := &models.App{ID: appID, Name: appID}
+	if retention != nil {
+		annotations, err := models.EmptyAnnotations().With(models.LogRetentionAnnotation, retention)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.Annotations = annotations
+	}
+	return app
+}
+
+// insertCall inserts a call of fnID created at, with a log
+func insertCall(t *testing.T, ls models.LogStore, fnID string, at time.Time, log string) *models.Call {
+	call := &models.Call{ID: id.New().String(), FnID: fnID, CreatedAt: common.DateTime(at)}
+	ctx := context.Background()
+	if err := ls.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, call, strings.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+	return call
+}
+
+func checkCalls(t *testing.T, ls models.LogStore, kept, removed []*models.Call) {
+	ctx := context.Background()
+	for _, call := range kept {
+		if _, err := ls.GetCall(ctx, call.FnID, call.ID); err != nil {
+			t.Errorf("expected call %s of %s to be kept, got %v", call.ID, call.FnID, err)
+		}
+	}
+	for _, call := range removed {
+		if _, err := ls.GetCall(ctx, call.FnID, call.ID); err != models.ErrCallNotFound {
+			t.Errorf("expected call %s of %s to be removed, got %v", call.ID, call.FnID, err)
+		}
+		if _, err := ls.GetLog(ctx, call.FnID, call.ID); err != models.ErrCallLogNotFound {
+			t.Errorf("expected the log of call %s of %s to be removed, got %v", call.ID, call.FnID, err)
+		}
+	}
+}
+
+func TestJanitorRemovesExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	apps := []*models.App{
+		retentionApp(t, "days_app", map[string]interface{}{"days": 1}),
+		retentionApp(t, "bytes_app", map[string]interface{}{"bytes": 10}),
+		retentionApp(t, "kept_app", nil),
+	}
+	fns := []*models.Fn{
+		{ID: "days_fn", AppID: "days_app", Name: "fn"},
+		{ID: "bytes_fn", AppID: "bytes_app", Name: "fn"},
+		{ID: "kept_fn", AppID: "kept_app", Name: "fn"},
+	}
+	ds := datastore.NewMockInit(apps, fns)
+	ls := logs.NewMock()
+
+	expiredCall := insertCall(t, ls, "days_fn", now.Add(-49*time.Hour), "old\n")
+	recentCall := insertCall(t, ls, "days_fn", now.Add(-time.Hour), "recent\n")
+	oldestCall := insertCall(t, ls, "bytes_fn", now.Add(-3*time.Hour), "line1\n")
+	olderCall := insertCall(t, ls, "bytes_fn", now.Add(-2*time.Hour), "line2\n")
+	newestCall := insertCall(t, ls, "bytes_fn", now.Add(-time.Hour), "line3\n")
+	keptCall := insertCall(t, ls, "kept_fn", now.Add(-49*time.Hour), "old\n")
+
+	j := New(ds, ls, WithPageSize(1))
+	if err := j.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls,
+		[]*models.Call{recentCall, newestCall, keptCall},
+		[]*models.Call{expiredCall, olderCall, oldestCall})
+}
+
+func TestJanitorDefaultRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	ds := datastore.NewMockInit(
+		[]*models.App{retentionApp(t, "app", nil)},
+		[]*models.Fn{{ID: "fn", AppID: "app", Name: "fn"}})
+	ls := logs.NewMock()
+	expiredCall := insertCall(t, ls, "fn", now.Add(-8*24*time.Hour), "old\n")
+	recentCall := insertCall(t, ls, "fn", now.Add(-time.Hour), "recent\n")
+
+	j := New(ds, ls, WithDefaultRetention(&models.LogRetention{Days: 7}))
+	if err := j.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls, []*models.Call{recentCall}, []*models.Call{expiredCall})
+}
+
+func TestJanitorRequiresLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	ds := datastore.NewMockInit(
+		[]*models.App{retentionApp(t, "app", map[string]interface{}{"days": 1})},
+		[]*models.Fn{{ID: "fn", AppID: "app", Name: "fn"}})
+	ls := logs.NewMock()
+	expiredCall := insertCall(t, ls, "fn", now.Add(-49*time.Hour), "old\n")
+
+	leader := New(ds, ls)
+	follower := New(ds, ls)
+	if ok, err := ds.AcquireLease(ctx, LeaseName, leader.holder, DefaultLeaseTTL); err != nil || !ok {
+		t.Fatalf("expected the leader to acquire the lease, got %v %v", ok, err)
+	}
+
+	if err := follower.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls, []*models.Call{expiredCall}, nil)
+
+	if err := leader.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls, nil, []*models.Call{expiredCall})
+}
+
+func TestJanitorRemovesExpiredOfDeleted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	ds := datastore.NewMockInit(
+		[]*models.App{
+			retentionApp(t, "deleted_app", map[string]interface{}{"days": 1}),
+			retentionApp(t, "app", map[string]interface{}{"days": 1}),
+		},
+		[]*models.Fn{
+			{ID: "fn_of_deleted_app", AppID: "deleted_app", Name: "fn"},
+			{ID: "deleted_fn", AppID: "app", Name: "fn"},
+		})
+	if err := ds.SoftDeleteApp(ctx, "deleted_app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.SoftDeleteFn(ctx, "deleted_fn"); err != nil {
+		t.Fatal(err)
+	}
+	ls := logs.NewMock()
+	expiredOfApp := insertCall(t, ls, "fn_of_deleted_app", now.Add(-49*time.Hour), "old\n")
+	expiredOfFn := insertCall(t, ls, "deleted_fn", now.Add(-49*time.Hour), "old\n")
+	recentCall := insertCall(t, ls, "deleted_fn", now.Add(-time.Hour), "recent\n")
+
+	j := New(ds, ls, WithPageSize(1))
+	if err := j.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls, []*models.Call{recentCall}, []*models.Call{expiredOfApp, expiredOfFn})
+}
+
+func TestJanitorRecordedLogSize(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	ds := datastore.NewMockInit(
+		[]*models.App{retentionApp(t, "app", map[string]interface{}{"bytes": 10})},
+		[]*models.Fn{{ID: "fn", AppID: "app", Name: "fn"}})
+	ls := logs.NewMock()
+	olderCall := insertCall(t, ls, "fn", now.Add(-2*time.Hour), "line1\n")
+
+	// the size recorded on the call is that of its log, the stored log isn't read
+	newestCall := &models.Call{ID: id.New().String(), FnID: "fn", CreatedAt: common.DateTime(now.Add(-time.Hour)), LogSize: 8}
+	if err := ls.InsertCall(ctx, newestCall); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, newestCall, strings.NewReader("x\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	j := New(ds, ls)
+	if err := j.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	checkCalls(t, ls, []*models.Call{newestCall}, []*models.Call{olderCall})
+}
@@ -0,0 +1,788 @@
+// Package jsonschema validates JSON documents against JSON Schemas (draft-07), e.g. the
+// request bodies of functions against their models.Fn RequestSchema. Only local references
+// ("#/definitions/...") are supported, formats are not checked and patterns are Go regular
+// expressions.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxErrors is the most errors reported by the validation of a document
+const MaxErrors = 20
+
+// Error is a part of a document that does not match its schema
+type Error struct {
+	// Path is the JSON pointer of the invalid value in the document, empty for the document
+	Path string
+	// Message describes why the value is invalid
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	// always is set for the true and false schemas
+	always *bool
+
+	ref    string
+	target *Schema
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+
+	multipleOf       *float64
+	maximum          *float64
+	exclusiveMaximum *float64
+	minimum          *float64
+	exclusiveMinimum *float64
+
+	maxLength int
+	minLength int
+	pattern   *regexp.Regexp
+
+	items           *Schema
+	itemsList       []*Schema
+	additionalItems *Schema
+	maxItems        int
+	minItems        int
+	uniqueItems     bool
+	contains        *Schema
+
+	maxProperties        int
+	minProperties        int
+	required             []string
+	properties           map[string]*Schema
+	patternProperties    []patternProperty
+	additionalProperties *Schema
+	propertyNames        *Schema
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+
+	ifSchema   *Schema
+	thenSchema *Schema
+	elseSchema *Schema
+}
+
+type patternProperty struct {
+	pattern *regexp.Regexp
+	schema  *Schema
+}
+
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// Compile parses and checks a JSON Schema
+func Compile(raw []byte) (*Schema, error) {
+	doc, err := decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	c := &compiler{root: doc, schemas: make(map[string]*Schema)}
+	s, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkLoops(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate returns the errors of a JSON document that does not match the schema, up to
+// MaxErrors, or nil if it does.
+func (s *Schema) Validate(data []byte) []Error {
+	v, err := decode(data)
+	if err != nil {
+		return []Error{{Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	var errs []Error
+	s.validate(v, "", &errs)
+	if len(errs) > MaxErrors {
+		errs = errs[:MaxErrors]
+	}
+	return errs
+}
+
+// decode parses a JSON document, keeping numbers as json.Number
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the document")
+	}
+	return v, nil
+}
+
+type compiler struct {
+	root    interface{}
+	schemas map[string]*Schema // by JSON pointer
+}
+
+func (c *compiler) compile(v interface{}, ptr string) (*Schema, error) {
+	if s, ok := c.schemas[ptr]; ok {
+		return s, nil
+	}
+	s := &Schema{maxLength: -1, minLength: -1, maxItems: -1, minItems: -1, maxProperties: -1, minProperties: -1}
+	c.schemas[ptr] = s
+
+	switch v := v.(type) {
+	case bool:
+		s.always = &v
+		return s, nil
+	case map[string]interface{}:
+		return s, c.compileObject(s, v, ptr)
+	}
+	return nil, fmt.Errorf("%s: a schema must be an object or a boolean", pointerOrRoot(ptr))
+}
+
+func (c *compiler) compileObject(s *Schema, m map[string]interface{}, ptr string) error {
+	// other keywords are ignored beside references
+	if ref, ok := m["$ref"]; ok {
+		r, ok := ref.(string)
+		if !ok {
+			return keywordError(ptr, "$ref", "must be a string")
+		}
+		target, err := c.resolve(r)
+		if err != nil {
+			return keywordError(ptr, "$ref", err.Error())
+		}
+		s.ref = r
+		s.target = target
+		return nil
+	}
+
+	var err error
+	sub := func(keyword string) *Schema {
+		v, ok := m[keyword]
+		if !ok || err != nil {
+			return nil
+		}
+		var s *Schema
+		s, err = c.compile(v, ptr+"/"+escape(keyword))
+		return s
+	}
+	subList := func(keyword string) []*Schema {
+		v, ok := m[keyword]
+		if !ok || err != nil {
+			return nil
+		}
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			err = keywordError(ptr, keyword, "must be a non-empty array of schemas")
+			return nil
+		}
+		schemas := make([]*Schema, len(list))
+		for i, v := range list {
+			if schemas[i], err = c.compile(v, ptr+"/"+keyword+"/"+strconv.Itoa(i)); err != nil {
+				return nil
+			}
+		}
+		return schemas
+	}
+	number := func(keyword string) *float64 {
+		v, ok := m[keyword]
+		if !ok || err != nil {
+			return nil
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			err = keywordError(ptr, keyword, "must be a number")
+			return nil
+		}
+		return &f
+	}
+	count := func(keyword string) int {
+		v, ok := m[keyword]
+		if !ok || err != nil {
+			return -1
+		}
+		f, ok := toNumber(v)
+		if !ok || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+			err = keywordError(ptr, keyword, "must be a non-negative integer")
+			return -1
+		}
+		return int(f)
+	}
+	pattern := func(keyword string, v interface{}) *regexp.Regexp {
+		p, ok := v.(string)
+		if !ok {
+			err = keywordError(ptr, keyword, "must be a string")
+			return nil
+		}
+		re, rerr := regexp.Compile(p)
+		if rerr != nil {
+			err = keywordError(ptr, keyword, "must be a valid regular expression")
+			return nil
+		}
+		return re
+	}
+
+	if t, ok := m["type"]; ok {
+		switch t := t.(type) {
+		case string:
+			s.types = []string{t}
+		case []interface{}:
+			for _, t := range t {
+				name, _ := t.(string)
+				s.types = append(s.types, name)
+			}
+		}
+		if len(s.types) == 0 {
+			return keywordError(ptr, "type", "must be a type or an array of types")
+		}
+		for _, t := range s.types {
+			if !schemaTypes[t] {
+				return keywordError(ptr, "type", fmt.Sprintf("unknown type %q", t))
+			}
+		}
+	}
+	if v, ok := m["enum"]; ok {
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return keywordError(ptr, "enum", "must be a non-empty array")
+		}
+		s.enum = list
+	}
+	if v, ok := m["const"]; ok {
+		s.constant, s.hasConst = v, true
+	}
+
+	s.multipleOf = number("multipleOf")
+	if s.multipleOf != nil && *s.multipleOf <= 0 {
+		return keywordError(ptr, "multipleOf", "must be greater than 0")
+	}
+	s.maximum = number("maximum")
+	s.exclusiveMaximum = number("exclusiveMaximum")
+	s.minimum = number("minimum")
+	s.exclusiveMinimum = number("exclusiveMinimum")
+
+	s.maxLength = count("maxLength")
+	s.minLength = count("minLength")
+	if v, ok := m["pattern"]; ok && err == nil {
+		s.pattern = pattern("pattern", v)
+	}
+
+	if v, ok := m["items"]; ok && err == nil {
+		if _, ok := v.([]interface{}); ok {
+			s.itemsList = subList("items")
+		} else {
+			s.items = sub("items")
+		}
+	}
+	s.additionalItems = sub("additionalItems")
+	s.maxItems = count("maxItems")
+	s.minItems = count("minItems")
+	if v, ok := m["uniqueItems"]; ok && err == nil {
+		if s.uniqueItems, ok = v.(bool); !ok {
+			return keywordError(ptr, "uniqueItems", "must be a boolean")
+		}
+	}
+	s.contains = sub("contains")
+
+	s.maxProperties = count("maxProperties")
+	s.minProperties = count("minProperties")
+	if v, ok := m["required"]; ok && err == nil {
+		list, ok := v.([]interface{})
+		if !ok {
+			return keywordError(ptr, "required", "must be an array of strings")
+		}
+		for _, name := range list {
+			name, ok := name.(string)
+			if !ok {
+				return keywordError(ptr, "required", "must be an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if v, ok := m["properties"]; ok && err == nil {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return keywordError(ptr, "properties", "must be an object of schemas")
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, v := range props {
+			if s.properties[name], err = c.compile(v, ptr+"/properties/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := m["patternProperties"]; ok && err == nil {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return keywordError(ptr, "patternProperties", "must be an object of schemas")
+		}
+		for p, v := range props {
+			re := pattern("patternProperties", p)
+			if err != nil {
+				return err
+			}
+			schema, err := c.compile(v, ptr+"/patternProperties/"+escape(p))
+			if err != nil {
+				return err
+			}
+			s.patternProperties = append(s.patternProperties, patternProperty{re, schema})
+		}
+		// report errors in the same order every time
+		sort.Slice(s.patternProperties, func(i, j int) bool {
+			return s.patternProperties[i].pattern.String() < s.patternProperties[j].pattern.String()
+		})
+	}
+	s.additionalProperties = sub("additionalProperties")
+	s.propertyNames = sub("propertyNames")
+
+	s.allOf = subList("allOf")
+	s.anyOf = subList("anyOf")
+	s.oneOf = subList("oneOf")
+	s.not = sub("not")
+
+	s.ifSchema = sub("if")
+	s.thenSchema = sub("then")
+	s.elseSchema = sub("else")
+	return err
+}
+
+// resolve returns the schema of a local reference
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, errors.New("only local references are supported")
+	}
+	ptr, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := c.schemas[ptr]; ok {
+		return s, nil
+	}
+	if ptr != "" && ptr[0] != '/' {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+
+	v := c.root
+	if ptr != "" {
+		for _, token := range strings.Split(ptr[1:], "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			next, ok := child(v, token)
+			if !ok {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			v = next
+		}
+	}
+	return c.compile(v, ptr)
+}
+
+// checkLoops rejects schemas that apply to a value schemas applying to the same value in a
+// loop, through references and in-place keywords like allOf, as validating would never end.
+// Loops through properties or items are fine, they apply to ever smaller parts of the value.
+func (c *compiler) checkLoops() error {
+	ptrs := make([]string, 0, len(c.schemas))
+	for ptr := range c.schemas {
+		ptrs = append(ptrs, ptr)
+	}
+	// report the same loop every time
+	sort.Strings(ptrs)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*Schema]int, len(c.schemas))
+	var visit func(s *Schema, ptr string) error
+	visit = func(s *Schema, ptr string) error {
+		switch state[s] {
+		case visiting:
+			return fmt.Errorf("%s applies to the same value in a loop", pointerOrRoot(ptr))
+		case visited:
+			return nil
+		}
+		state[s] = visiting
+		for _, sub := range s.inPlace() {
+			if err := visit(sub, ptr); err != nil {
+				return err
+			}
+		}
+		state[s] = visited
+		return nil
+	}
+	for _, ptr := range ptrs {
+		if err := visit(c.schemas[ptr], ptr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inPlace returns the schemas the schema applies to the value it validates itself
+func (s *Schema) inPlace() []*Schema {
+	var subs []*Schema
+	if s.target != nil {
+		subs = append(subs, s.target)
+	}
+	subs = append(subs, s.allOf...)
+	subs = append(subs, s.anyOf...)
+	subs = append(subs, s.oneOf...)
+	for _, sub := range []*Schema{s.not, s.ifSchema, s.thenSchema, s.elseSchema} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func child(v interface{}, token string) (interface{}, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		v, ok := x[token]
+		return v, ok
+	case []interface{}:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(x) {
+			return nil, false
+		}
+		return x[i], true
+	}
+	return nil, false
+}
+
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func pointerOrRoot(ptr string) string {
+	if ptr == "" {
+		return "schema"
+	}
+	return ptr
+}
+
+func keywordError(ptr, keyword, msg string) error {
+	return fmt.Errorf("%s/%s %s", ptr, keyword, msg)
+}
+
+func (s *Schema) validate(v interface{}, path string, errs *[]Error) {
+	if len(*errs) > MaxErrors {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			fail("no value is allowed")
+		}
+		return
+	}
+	if s.target != nil {
+		s.target.validate(v, path, errs)
+		return
+	}
+
+	if len(s.types) > 0 && !hasType(v, s.types) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))
+		// the other keywords would report the same error
+		return
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if equal(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of the values of the enum")
+		}
+	}
+	if s.hasConst && !equal(v, s.constant) {
+		fail("must be the constant value")
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		s.validateNumber(v, fail)
+	case string:
+		s.validateString(v, fail)
+	case []interface{}:
+		s.validateArray(v, path, errs, fail)
+	case map[string]interface{}:
+		s.validateObject(v, path, errs, fail)
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, errs)
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.valid(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("must match at least one of the schemas of anyOf")
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(v) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("must match exactly one of the schemas of oneOf, matched %d", matched)
+		}
+	}
+	if s.not != nil && s.not.valid(v) {
+		fail("must not match the schema of not")
+	}
+	if s.ifSchema != nil {
+		if s.ifSchema.valid(v) {
+			if s.thenSchema != nil {
+				s.thenSchema.validate(v, path, errs)
+			}
+		} else if s.elseSchema != nil {
+			s.elseSchema.validate(v, path, errs)
+		}
+	}
+}
+
+// valid returns true if v matches the schema
+func (s *Schema) valid(v interface{}) bool {
+	var errs []Error
+	s.validate(v, "", &errs)
+	return len(errs) == 0
+}
+
+func (s *Schema) validateNumber(n json.Number, fail func(string, ...interface{})) {
+	f, _ := n.Float64()
+	if s.multipleOf != nil {
+		q := f / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("must be a multiple of %v", *s.multipleOf)
+		}
+	}
+	if s.maximum != nil && f > *s.maximum {
+		fail("must be at most %v", *s.maximum)
+	}
+	if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+		fail("must be less than %v", *s.exclusiveMaximum)
+	}
+	if s.minimum != nil && f < *s.minimum {
+		fail("must be at least %v", *s.minimum)
+	}
+	if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+		fail("must be greater than %v", *s.exclusiveMinimum)
+	}
+}
+
+func (s *Schema) validateString(str string, fail func(string, ...interface{})) {
+	n := utf8.RuneCountInString(str)
+	if s.maxLength >= 0 && n > s.maxLength {
+		fail("must be at most %d characters long", s.maxLength)
+	}
+	if s.minLength >= 0 && n < s.minLength {
+		fail("must be at least %d characters long", s.minLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		fail("must match the pattern %q", s.pattern.String())
+	}
+}
+
+func (s *Schema) validateArray(a []interface{}, path string, errs *[]Error, fail func(string, ...interface{})) {
+	if s.maxItems >= 0 && len(a) > s.maxItems {
+		fail("must have at most %d items", s.maxItems)
+	}
+	if s.minItems >= 0 && len(a) < s.minItems {
+		fail("must have at least %d items", s.minItems)
+	}
+	if s.uniqueItems {
+	unique:
+		for i := range a {
+			for j := i + 1; j < len(a); j++ {
+				if equal(a[i], a[j]) {
+					fail("items %d and %d must not be equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+	if s.contains != nil {
+		found := false
+		for _, item := range a {
+			if s.contains.valid(item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must contain an item matching the schema of contains")
+		}
+	}
+
+	for i, item := range a {
+		var sub *Schema
+		switch {
+		case s.items != nil:
+			sub = s.items
+		case i < len(s.itemsList):
+			sub = s.itemsList[i]
+		case s.itemsList != nil:
+			sub = s.additionalItems
+		}
+		if sub != nil {
+			sub.validate(item, path+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateObject(o map[string]interface{}, path string, errs *[]Error, fail func(string, ...interface{})) {
+	if s.maxProperties >= 0 && len(o) > s.maxProperties {
+		fail("must have at most %d properties", s.maxProperties)
+	}
+	if s.minProperties >= 0 && len(o) < s.minProperties {
+		fail("must have at least %d properties", s.minProperties)
+	}
+	for _, name := range s.required {
+		if _, ok := o[name]; !ok {
+			*errs = append(*errs, Error{Path: path + "/" + escape(name), Message: "is required"})
+		}
+	}
+
+	// report errors in the same order every time
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, p := o[name], path+"/"+escape(name)
+		if s.propertyNames != nil && !s.propertyNames.valid(name) {
+			*errs = append(*errs, Error{Path: p, Message: "property name must match the schema of propertyNames"})
+		}
+		matched := false
+		if sub, ok := s.properties[name]; ok {
+			matched = true
+			sub.validate(v, p, errs)
+		}
+		for _, pp := range s.patternProperties {
+			if pp.pattern.MatchString(name) {
+				matched = true
+				pp.schema.validate(v, p, errs)
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+				*errs = append(*errs, Error{Path: p, Message: "is not an allowed property"})
+				continue
+			}
+			s.additionalProperties.validate(v, p, errs)
+		}
+	}
+}
+
+func hasType(v interface{}, types []string) bool {
+	t := typeOf(v)
+	for _, want := range types {
+		if want == t || (want == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// equal compares JSON values, numbers by value
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		fa, _ := toNumber(a)
+		fb, ok := toNumber(b)
+		return ok && fa == fb
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
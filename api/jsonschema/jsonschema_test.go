@@ -0,0 +1,120 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	for i, test := range []struct {
+		schema string
+		valid  bool
+	}{
+		{`{}`, true},
+		{`true`, true},
+		{`{"type": "object", "properties": {"a": {"type": ["string", "null"]}}, "required": ["a"]}`, true},
+		{`{"definitions": {"node": {"type": "object", "properties": {"next": {"$ref": "#/definitions/node"}}}}, "$ref": "#/definitions/node"}`, true},
+		{`{"items": [{"type": "string"}], "additionalItems": false}`, true},
+		{`{"type": "text"}`, false},
+		{`{"minLength": -1}`, false},
+		{`{"pattern": "("}`, false},
+		{`{"enum": []}`, false},
+		{`{"properties": {"a": 1}}`, false},
+		{`{"$ref": "http://example.com/schema"}`, false},
+		{`{"$ref": "#/definitions/missing"}`, false},
+		{`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}, "$ref": "#/definitions/a"}`, false},
+		// loops not going through properties or items never end validating
+		{`{"allOf": [{"$ref": "#"}]}`, false},
+		{`{"anyOf": [{"type": "string"}, {"not": {"$ref": "#"}}]}`, false},
+		{`{"definitions": {"a": {"if": {"$ref": "#/definitions/b"}}, "b": {"oneOf": [{"$ref": "#/definitions/a"}]}}, "$ref": "#/definitions/a"}`, false},
+		{`{"properties": {"a": {"allOf": [{"$ref": "#"}]}}, "items": {"$ref": "#"}}`, true},
+		{`{"type": "object"`, false},
+		{`"object"`, false},
+	} {
+		_, err := Compile([]byte(test.schema))
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid schema, got: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected invalid schema", i)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 5, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+			"kind": {"enum": ["a", "b"]},
+			"next": {"$ref": "#"}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		doc  string
+		errs []Error
+	}{
+		{`{"name": "bob"}`, nil},
+		{`{"name": "bob", "age": 42, "tags": ["a", "b"], "kind": "a", "next": {"name": "al"}}`, nil},
+		{`[]`, []Error{{"", "expected object, got array"}}},
+		{`{}`, []Error{{"/name", "is required"}}},
+		{`{"name": "Bob"}`, []Error{{"/name", `must match the pattern "^[a-z]+$"`}}},
+		{`{"name": "bob", "age": 1.5}`, []Error{{"/age", "expected integer, got number"}}},
+		{`{"name": "bob", "age": 150}`, []Error{{"/age", "must be less than 150"}}},
+		{`{"name": "bob", "tags": ["a", "a"]}`, []Error{{"/tags", "items 0 and 1 must not be equal"}}},
+		{`{"name": "bob", "tags": [1]}`, []Error{{"/tags/0", "expected string, got integer"}}},
+		{`{"name": "bob", "kind": "c"}`, []Error{{"/kind", "must be one of the values of the enum"}}},
+		{`{"name": "bob", "next": {}}`, []Error{{"/next/name", "is required"}}},
+		{`{"name": "bob", "extra": 1}`, []Error{{"/extra", "is not an allowed property"}}},
+		{`{"name": "bob"`, []Error{{"", "invalid JSON: unexpected EOF"}}},
+		{`{"name": "bob"} {}`, []Error{{"", "invalid JSON: unexpected data after the document"}}},
+	} {
+		if errs := schema.Validate([]byte(test.doc)); !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("test %d: expected %v, got %v", i, test.errs, errs)
+		}
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	schema, err := Compile([]byte(`{
+		"oneOf": [{"type": "integer"}, {"type": "number", "multipleOf": 0.5}],
+		"not": {"const": 3},
+		"if": {"minimum": 10}, "then": {"maximum": 20}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, valid := range map[string]bool{
+		`1.5`:  true,
+		`1`:    false, // matches both
+		`1.2`:  false, // matches neither
+		`3`:    false,
+		`25`:   false,
+		`15`:   false, // matches both
+		`15.5`: true,
+	} {
+		if errs := schema.Validate([]byte(doc)); (len(errs) == 0) != valid {
+			t.Errorf("%s: expected valid=%v, got %v", doc, valid, errs)
+		}
+	}
+}
+
+func TestValidateMaxErrors(t *testing.T) {
+	schema, err := Compile([]byte(`{"items": {"type": "string"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := []byte(`[` + strings.Repeat(`1,`, 2*MaxErrors) + `1]`)
+	if errs := schema.Validate(doc); len(errs) != MaxErrors {
+		t.Fatalf("expected %d errors, got %d", MaxErrors, len(errs))
+	}
+}
@@ -0,0 +1,87 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// storageResource is the resource the tokens of the store are for
+	storageResource = "https://storage.azure.com/"
+
+	// tokenExpiryDelta is how long before they expire the tokens are renewed
+	tokenExpiryDelta = time.Minute
+)
+
+// imdsEndpoint is the token endpoint of the instance metadata service, which serves the
+// tokens of the managed identities of the VM or of the pod with workload identity
+var imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// token is an access token of a managed identity
+type token struct {
+	AccessToken string `json:"access_token"`
+	// ExpiresIn is a number of seconds, as a string
+	ExpiresIn string `json:"expires_in"`
+	expiry    time.Time
+}
+
+// managedIdentity fetches the tokens of a managed identity from the instance metadata
+// service, reusing them until they are about to expire
+type managedIdentity struct {
+	client *http.Client
+	// clientID selects a user assigned identity, the system assigned one is used if empty
+	clientID string
+
+	mu  sync.Mutex
+	tok *token
+}
+
+func (m *managedIdentity) token(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tok != nil && time.Now().Add(tokenExpiryDelta).Before(m.tok.expiry) {
+		return m.tok.AccessToken, nil
+	}
+
+	q := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {storageResource},
+	}
+	if m.clientID != "" {
+		q.Set("client_id", m.clientID)
+	}
+	req, err := http.NewRequest(http.MethodGet, imdsEndpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := m.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch managed identity token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("cannot fetch managed identity token: %s %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	var tok token
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("cannot fetch managed identity token: %v", err)
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("cannot fetch managed identity token: no access token in response")
+	}
+	expiresIn, _ := strconv.ParseInt(tok.ExpiresIn, 10, 64)
+	tok.expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	m.tok = &tok
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,497 @@
+// Package azure implements a log store writing to block blobs of Azure Blob Storage, with the
+// key layout of the s3 log store
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+)
+
+const (
+	// key prefixes, those of the s3 store
+	callKeyPrefix    = "c/"
+	logKeyPrefix     = "l/"
+	resultKeyPrefix  = "r/"
+	deadLetterPrefix = "d/"
+)
+
+type store struct {
+	client    *client
+	container string
+	// prefix is prepended to the keys of all the blobs of the store
+	prefix string
+}
+
+type azureStoreProvider int
+
+// decorator around the Reader interface that keeps track of the number of bytes read
+// in order to avoid double buffering and track Reader size
+type countingReader struct {
+	r     io.Reader
+	count int
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.count += n
+	return n, err
+}
+
+func (azureStoreProvider) String() string {
+	return "azblob"
+}
+
+func (azureStoreProvider) Supports(u *url.URL) bool {
+	return u.Scheme == "azblob"
+}
+
+// New returns an azure blob storage log store, the container must exist.
+// url format: azblob://account_name/container_name/prefix?sas=<url encoded sas token>
+// The prefix is optional. The requests are authorized with the shared access signature sas
+// if set, and otherwise with the tokens of the managed identity of the VM or pod, the user
+// assigned identity of client_id if set. endpoint overrides the url of the blob service of
+// the account, eg for emulators.
+func (azureStoreProvider) New(ctx context.Context, u *url.URL) (models.LogStore, error) {
+	account := u.Host
+	strs := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	containerName := strs[0]
+	if account == "" || containerName == "" {
+		return nil, errors.New("must provide account and container name in azure blob url. e.g. azblob://my_account/my_container")
+	}
+	var prefix string
+	if len(strs) == 2 {
+		prefix = strings.Trim(strs[1], "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+	}
+	q := u.Query()
+	endpoint := strings.TrimSuffix(q.Get("endpoint"), "/")
+	if endpoint == "" {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+
+	httpClient := &http.Client{}
+	c := &client{
+		http:      httpClient,
+		endpoint:  endpoint,
+		container: containerName,
+	}
+	if sas := q.Get("sas"); sas != "" {
+		values, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sas token in azure blob url: %v", err)
+		}
+		c.sas = values
+	} else {
+		c.identity = &managedIdentity{client: httpClient, clientID: q.Get("client_id")}
+	}
+	store := &store{
+		client:    c,
+		container: containerName,
+		prefix:    prefix,
+	}
+
+	logrus.WithFields(logrus.Fields{"account": account, "container": containerName, "prefix": prefix, "endpoint": endpoint, "sas": c.sas != nil}).Info("checking azure blob container")
+	if err := c.checkContainer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check container %s: %v", containerName, err)
+	}
+	return store, nil
+}
+
+// BinaryLogs implements compress.Binary, objects hold any bytes
+func (s *store) BinaryLogs() bool {
+	return true
+}
+
+func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "azure_insert_log")
+	defer span.End()
+
+	// wrap original reader in a decorator to keep track of read bytes without buffering
+	cr := &countingReader{r: callLog}
+
+	objectName := s.logKey(call.FnID, call.ID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Uploading log")
+	err := s.client.upload(ctx, objectName, "text/plain", cr)
+	if err != nil {
+		return fmt.Errorf("failed to write log, %v", err)
+	}
+
+	stats.Record(ctx, uploadSizeMeasure.M(int64(cr.count)))
+	return nil
+}
+
+func (s *store) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_log")
+	defer span.End()
+
+	objectName := s.logKey(fnID, callID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Downloading log")
+
+	b, err := s.client.download(ctx, objectName)
+	if err == errBlobNotFound {
+		return nil, models.ErrCallLogNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log, %v", err)
+	}
+
+	stats.Record(ctx, downloadSizeMeasure.M(int64(len(b))))
+	return bytes.NewReader(b), nil
+}
+
+// SearchLog filters the log once downloaded, objects can't be searched
+func (s *store) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	log, err := s.GetLog(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	return models.FilterLog(log, filter)
+}
+
+func (s *store) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "azure_insert_result")
+	defer span.End()
+
+	cr := &countingReader{r: result}
+
+	objectName := s.resultKey(call.FnID, call.ID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Uploading result")
+	err := s.client.upload(ctx, objectName, "application/octet-stream", cr)
+	if err != nil {
+		return fmt.Errorf("failed to write result, %v", err)
+	}
+
+	stats.Record(ctx, uploadSizeMeasure.M(int64(cr.count)))
+	return nil
+}
+
+func (s *store) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_result")
+	defer span.End()
+
+	objectName := s.resultKey(fnID, callID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Downloading result")
+
+	b, err := s.client.download(ctx, objectName)
+	if err == errBlobNotFound {
+		return nil, models.ErrCallResultNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result, %v", err)
+	}
+
+	stats.Record(ctx, downloadSizeMeasure.M(int64(len(b))))
+	return bytes.NewReader(b), nil
+}
+
+func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
+	ctx, span := trace.StartSpan(ctx, "azure_insert_call")
+	defer span.End()
+
+	byts, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+
+	objectName := s.callKey(call.AppID, call.ID)
+	if call.FnID != "" {
+		objectName = s.callKey(call.FnID, call.ID)
+	}
+
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Uploading call")
+	err = s.client.upload(ctx, objectName, "text/plain", bytes.NewReader(byts))
+	if err != nil {
+		return fmt.Errorf("failed to insert call, %v", err)
+	}
+
+	return nil
+}
+
+// GetCall returns a call at a certain id
+func (s *store) GetCall(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_call")
+	defer span.End()
+
+	objectName := s.callKey(fnID, callID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Downloading call")
+
+	return s.getCallByKey(ctx, objectName)
+}
+
+func (s *store) getCallByKey(ctx context.Context, key string) (*models.Call, error) {
+	b, err := s.client.download(ctx, key)
+	if err == errBlobNotFound {
+		return nil, models.ErrCallNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log, %v", err)
+	}
+
+	var call models.Call
+	err = json.Unmarshal(b, &call)
+	if err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+func flipCursor(oid string) string {
+	if oid == "" {
+		return ""
+	}
+
+	return id.EncodeDescending(oid)
+}
+
+func (s *store) callKeyFlipped(fnID, id string) string {
+	return s.prefix + callKeyPrefix + fnID + "/" + id
+}
+
+func (s *store) callKey(fnID, id string) string {
+	return s.prefix + callKeyPrefix + fnID + "/" + flipCursor(id)
+}
+
+func (s *store) logKey(fnID, callID string) string {
+	return s.prefix + logKeyPrefix + fnID + "/" + callID
+}
+
+func (s *store) resultKey(fnID, callID string) string {
+	return s.prefix + resultKeyPrefix + fnID + "/" + callID
+}
+
+// deadLetterKey sorts the dead letters of a fn most recent first, like callKey
+func (s *store) deadLetterKey(fnID, callID string) string {
+	return s.prefix + deadLetterPrefix + fnID + "/" + flipCursor(callID)
+}
+
+func (s *store) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	ctx, span := trace.StartSpan(ctx, "azure_insert_dead_letter")
+	defer span.End()
+
+	byts, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+
+	objectName := s.deadLetterKey(call.FnID, call.ID)
+	logrus.WithFields(logrus.Fields{"container": s.container, "key": objectName}).Debug("Uploading dead letter")
+	err = s.client.upload(ctx, objectName, "text/plain", bytes.NewReader(byts))
+	if err != nil {
+		return fmt.Errorf("failed to insert dead letter, %v", err)
+	}
+
+	return nil
+}
+
+func (s *store) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_dead_letter")
+	defer span.End()
+
+	call, err := s.getCallByKey(ctx, s.deadLetterKey(fnID, callID))
+	if err == models.ErrCallNotFound {
+		return nil, models.ErrDeadLetterNotFound
+	}
+	return call, err
+}
+
+// GetDeadLetters returns the dead letters of filter.FnID, most recent first.
+// NOTE: this relies on call ids being lexicographically sortable, see GetCalls
+func (s *store) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_dead_letters")
+	defer span.End()
+
+	var marker string
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		marker = s.deadLetterKey(filter.FnID, string(cursor))
+	}
+
+	keys, err := s.client.list(ctx, s.prefix+deadLetterPrefix+filter.FnID+"/", marker, filter.PerPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %v", err)
+	}
+
+	calls := make([]*models.Call, 0, len(keys))
+	for _, key := range keys {
+		call, err := s.getCallByKey(ctx, key)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"key": key}).Error("error filling dead letter object")
+			continue
+		}
+		calls = append(calls, call)
+	}
+
+	callList := &models.CallList{Items: calls}
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return callList, nil
+}
+
+func (s *store) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "azure_remove_dead_letter")
+	defer span.End()
+
+	err := s.client.remove(ctx, s.deadLetterKey(fnID, callID))
+	if err == errBlobNotFound {
+		return models.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove dead letter, %v", err)
+	}
+	return nil
+}
+
+func (s *store) RemoveCall(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "azure_remove_call")
+	defer span.End()
+
+	var found bool
+	for _, key := range []string{s.callKey(fnID, callID), s.logKey(fnID, callID), s.resultKey(fnID, callID)} {
+		err := s.client.remove(ctx, key)
+		if err == errBlobNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove call, %v", err)
+		}
+		found = true
+	}
+	if !found {
+		return models.ErrCallNotFound
+	}
+	return nil
+}
+
+// GetCalls returns a list of calls that satisfy the given CallFilter. If no
+// calls exist, an empty list and a nil error are returned.
+// NOTE: this relies on call ids being lexicographically sortable and <= 16 byte
+func (s *store) GetCalls(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	ctx, span := trace.StartSpan(ctx, "azure_get_calls")
+	defer span.End()
+
+	if filter.FnID == "" {
+		return nil, errors.New("azure blob store does not support listing across all fns")
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = string(cursor)
+	}
+
+	// the keys are those of the s3 store, listed in lexicographic order, which is the
+	// reverse of the order of the ids
+	var key string
+	if filter.Cursor != "" {
+		key = s.callKey(filter.FnID, filter.Cursor)
+	} else if t := time.Time(filter.ToTime); !t.IsZero() {
+		// get a fake id that has the most significant bits set to the to_time (first 48 bits)
+		key = s.callKey(filter.FnID, id.NewWithTime(t).String())
+	}
+
+	// prefix prevents leaving bounds of fn keys
+	prefix := s.callKey(filter.FnID, "")
+
+	keys, err := s.client.list(ctx, prefix, key, filter.PerPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %v", err)
+	}
+
+	calls := make([]*models.Call, 0, len(keys))
+	for _, k := range keys {
+		// extract the fn and id from the key to lookup the object, this also
+		// validates we aren't reading strangely keyed blobs from the container.
+		fields := strings.Split(strings.TrimPrefix(k, s.prefix), "/")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid key in calls: %v", k)
+		}
+		fnID, id := fields[1], fields[2]
+
+		// the id here is already reverse encoded, keep it that way.
+		call, err := s.getCallByKey(ctx, s.callKeyFlipped(fnID, id))
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"fn_id": fnID, "id": id}).Error("error filling call object")
+			continue
+		}
+
+		// ensure: from_time < created_at < to_time
+		fromTime := time.Time(filter.FromTime).Truncate(time.Millisecond)
+		if !fromTime.IsZero() && !fromTime.Before(time.Time(call.CreatedAt)) {
+			// NOTE could break, ids and created_at aren't necessarily in perfect order
+			continue
+		}
+
+		toTime := time.Time(filter.ToTime).Truncate(time.Millisecond)
+		if !toTime.IsZero() && !time.Time(call.CreatedAt).Before(toTime) {
+			continue
+		}
+
+		calls = append(calls, call)
+	}
+
+	callList := &models.CallList{Items: calls}
+
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return callList, nil
+}
+
+func (s *store) Close() error {
+	return nil
+}
+
+const (
+	uploadSizeMetricName   = "azure_log_upload_size"
+	downloadSizeMetricName = "azure_log_download_size"
+)
+
+var (
+	uploadSizeMeasure   = common.MakeMeasure(uploadSizeMetricName, "uploaded log size", "byte")
+	downloadSizeMeasure = common.MakeMeasure(downloadSizeMetricName, "downloaded log size", "byte")
+)
+
+// RegisterViews registers views for azure blob measures
+func RegisterViews(tagKeys []string, dist []float64) {
+	err := view.Register(
+		common.CreateView(uploadSizeMeasure, view.Distribution(dist...), tagKeys),
+		common.CreateView(downloadSizeMeasure, view.Distribution(dist...), tagKeys),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
+
+func init() {
+	logs.Register(azureStoreProvider(0))
+}
@@ -0,0 +1,236 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+// fakeBlobService serves the requests of the blob service api made by the store, for a
+// container
+type fakeBlobService struct {
+	container string
+	// authorized returns whether a request is authorized
+	authorized func(r *http.Request) bool
+
+	mu     sync.Mutex
+	blobs  map[string][]byte
+	blocks map[string][]byte
+}
+
+func newFakeBlobService(authorized func(r *http.Request) bool) (*fakeBlobService, *httptest.Server) {
+	f := &fakeBlobService{
+		container:  "logs",
+		authorized: authorized,
+		blobs:      make(map[string][]byte),
+		blocks:     make(map[string][]byte),
+	}
+	return f, httptest.NewServer(f)
+}
+
+func (f *fakeBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) || r.Header.Get("x-ms-version") == "" {
+		http.Error(w, "<Error><Code>AuthenticationFailed</Code></Error>", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := r.URL.Query()
+	containerPath := "/" + f.container
+	if r.URL.Path == containerPath && q.Get("restype") == "container" {
+		if q.Get("comp") == "list" {
+			f.list(w, q)
+		}
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, containerPath+"/") {
+		http.Error(w, "<Error><Code>ContainerNotFound</Code></Error>", http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, containerPath+"/")
+	b, _ := ioutil.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPut && q.Get("comp") == "block":
+		f.blocks[name+"/"+q.Get("blockid")] = b
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(b, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var blob []byte
+		for _, id := range list.Latest {
+			blob = append(blob, f.blocks[name+"/"+id]...)
+		}
+		f.blobs[name] = blob
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			http.Error(w, "<Error><Code>MissingRequiredHeader</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		f.blobs[name] = b
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet || r.Method == http.MethodDelete:
+		blob, ok := f.blobs[name]
+		if !ok {
+			http.Error(w, "<Error><Code>BlobNotFound</Code></Error>", http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.blobs, name)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Write(blob)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// list serves pages of 2 blobs at most, for the store to follow the markers
+func (f *fakeBlobService) list(w http.ResponseWriter, q url.Values) {
+	var names []string
+	for name := range f.blobs {
+		if strings.HasPrefix(name, q.Get("prefix")) && name > q.Get("marker") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	type blob struct {
+		Name string `xml:"Name"`
+	}
+	var page struct {
+		XMLName    xml.Name `xml:"EnumerationResults"`
+		Blobs      []blob   `xml:"Blobs>Blob"`
+		NextMarker string   `xml:"NextMarker"`
+	}
+	for i, name := range names {
+		if i == 2 {
+			page.NextMarker = names[i-1]
+			break
+		}
+		page.Blobs = append(page.Blobs, blob{name})
+	}
+	xml.NewEncoder(w).Encode(&page)
+}
+
+func newStore(t *testing.T, rawurl string) models.LogStore {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := azureStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create azure blob log store: %v", err)
+	}
+	return ls
+}
+
+func TestAzure(t *testing.T) {
+	f, srv := newFakeBlobService(func(r *http.Request) bool {
+		return r.URL.Query().Get("sig") == "s+ig/=="
+	})
+	defer srv.Close()
+
+	sas := url.QueryEscape("sv=2019-12-12&sp=rwdl&sig=" + url.QueryEscape("s+ig/=="))
+	ls := newStore(t, "azblob://account/logs/fn/logs?sas="+sas+"&endpoint="+url.QueryEscape(srv.URL))
+	logTesting.Test(t, ls)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.blobs) == 0 {
+		t.Fatal("expected blobs to be written to the container")
+	}
+	for name := range f.blobs {
+		if !strings.HasPrefix(name, "fn/logs/") {
+			t.Fatalf("expected the keys to start with the prefix, got %s", name)
+		}
+	}
+}
+
+func TestLargeLog(t *testing.T) {
+	f, srv := newFakeBlobService(func(r *http.Request) bool { return r.URL.Query().Get("sig") != "" })
+	defer srv.Close()
+	ls := newStore(t, "azblob://account/logs?sas=sig%3Dsecret&endpoint="+url.QueryEscape(srv.URL))
+
+	ctx := context.Background()
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	log := bytes.Repeat([]byte("0123456789abcdef"), blockSize/16*2+1)
+	if err := ls.InsertLog(ctx, call, bytes.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	blocks := len(f.blocks)
+	f.mu.Unlock()
+	if blocks != 3 {
+		t.Fatalf("expected the log to be staged in 3 blocks, got %d", blocks)
+	}
+
+	r, err := ls.GetLog(ctx, call.FnID, call.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, log) {
+		t.Fatalf("expected the log to be read back, got %d bytes of %d", len(got), len(log))
+	}
+}
+
+func TestMissingContainer(t *testing.T) {
+	_, srv := newFakeBlobService(func(r *http.Request) bool { return true })
+	defer srv.Close()
+	u, err := url.Parse("azblob://account/other?sas=sig%3Dsecret&endpoint=" + url.QueryEscape(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := azureStoreProvider(0).New(context.Background(), u); err == nil {
+		t.Fatal("expected a missing container to fail")
+	}
+}
+
+func TestManagedIdentity(t *testing.T) {
+	var tokenRequests int
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		q := r.URL.Query()
+		if r.Header.Get("Metadata") != "true" || q.Get("resource") != storageResource || q.Get("client_id") != "myidentity" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"managed","expires_in":"3600","token_type":"Bearer"}`))
+	}))
+	defer imds.Close()
+	defer func(endpoint string) { imdsEndpoint = endpoint }(imdsEndpoint)
+	imdsEndpoint = imds.URL
+
+	_, srv := newFakeBlobService(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer managed"
+	})
+	defer srv.Close()
+	ls := newStore(t, "azblob://account/logs?client_id=myidentity&endpoint="+url.QueryEscape(srv.URL))
+	logTesting.Test(t, ls)
+	if tokenRequests != 1 {
+		t.Fatalf("expected the token to be fetched once and reused, got %d requests", tokenRequests)
+	}
+}
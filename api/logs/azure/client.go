@@ -0,0 +1,264 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// apiVersion is the version of the blob service api used, the first supporting tokens of
+	// managed identities is 2017-11-09
+	apiVersion = "2019-12-12"
+
+	// blockSize is the size of the blocks the blobs larger than it are staged in
+	blockSize = 4 << 20
+
+	// listPageSize is the number of blobs listed at once
+	listPageSize = 1000
+)
+
+// errBlobNotFound is returned for the blobs which do not exist
+var errBlobNotFound = errors.New("blob not found")
+
+// client makes the requests of the blob service api used by the store, see
+// https://docs.microsoft.com/en-us/rest/api/storageservices/blob-service-rest-api
+type client struct {
+	http *http.Client
+	// endpoint is the url of the blob service of the storage account
+	endpoint  string
+	container string
+	// sas is the shared access signature authorizing the requests, if not empty, otherwise
+	// they are authorized by the tokens of identity
+	sas      url.Values
+	identity *managedIdentity
+}
+
+func (c *client) containerURL() string {
+	return c.endpoint + "/" + url.PathEscape(c.container)
+}
+
+func (c *client) blobURL(name string) string {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return c.containerURL() + "/" + strings.Join(segments, "/")
+}
+
+func (c *client) do(ctx context.Context, method, u string, q url.Values, header http.Header, body []byte) (*http.Response, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	for k, v := range c.sas {
+		q[k] = v
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-version", apiVersion)
+	if c.sas == nil {
+		tok, err := c.identity.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errBlobNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("azure blob %s: %s %s", resp.Status, e.Code, strings.TrimSpace(e.Message))
+		}
+		return nil, fmt.Errorf("azure blob %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// checkContainer returns an error unless the container exists and is accessible
+func (c *client) checkContainer(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, c.containerURL(), url.Values{"restype": {"container"}}, nil, nil)
+	if err == errBlobNotFound {
+		return fmt.Errorf("azure blob container %s does not exist", c.container)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// upload writes r to the block blob of name, overwriting it if it exists. Blobs larger than
+// blockSize are staged in blocks, committed once all are.
+func (c *client) upload(ctx context.Context, name, contentType string, r io.Reader) error {
+	buf := make([]byte, blockSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return c.putBlob(ctx, name, contentType, buf[:n])
+	}
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for n > 0 {
+		// the ids of the blocks of a blob must have the same length
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(ids))))
+		if err := c.putBlock(ctx, name, id, buf[:n]); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+	return c.putBlockList(ctx, name, contentType, ids)
+}
+
+func (c *client) putBlob(ctx context.Context, name, contentType string, b []byte) error {
+	header := http.Header{}
+	header.Set("x-ms-blob-type", "BlockBlob")
+	header.Set("Content-Type", contentType)
+	resp, err := c.do(ctx, http.MethodPut, c.blobURL(name), nil, header, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *client) putBlock(ctx context.Context, name, id string, b []byte) error {
+	q := url.Values{"comp": {"block"}, "blockid": {id}}
+	resp, err := c.do(ctx, http.MethodPut, c.blobURL(name), q, nil, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *client) putBlockList(ctx context.Context, name, contentType string, ids []string) error {
+	var list struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}
+	list.Latest = ids
+	b, err := xml.Marshal(&list)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("x-ms-blob-content-type", contentType)
+	header.Set("Content-Type", "application/xml")
+	resp, err := c.do(ctx, http.MethodPut, c.blobURL(name), url.Values{"comp": {"blocklist"}}, header, append([]byte(xml.Header), b...))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// download returns the content of the blob of name, errBlobNotFound if it does not exist
+func (c *client) download(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.blobURL(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	if resp.ContentLength > 0 {
+		buf.Grow(int(resp.ContentLength))
+	}
+	_, err = buf.ReadFrom(resp.Body)
+	return buf.Bytes(), err
+}
+
+// remove deletes the blob of name, errBlobNotFound if it does not exist
+func (c *client) remove(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.blobURL(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// list returns the names of up to max blobs starting with prefix, in lexicographic order,
+// after marker if not empty. Listings cannot start after a name, the blobs up to marker are
+// listed and skipped.
+func (c *client) list(ctx context.Context, prefix, marker string, max int) ([]string, error) {
+	q := url.Values{
+		"restype":    {"container"},
+		"comp":       {"list"},
+		"prefix":     {prefix},
+		"maxresults": {strconv.Itoa(listPageSize)},
+	}
+
+	var names []string
+	for len(names) < max {
+		resp, err := c.do(ctx, http.MethodGet, c.containerURL(), q, nil, nil)
+		if err == errBlobNotFound {
+			return nil, fmt.Errorf("azure blob container %s does not exist", c.container)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range page.Blobs {
+			if b.Name > marker && len(names) < max {
+				names = append(names, b.Name)
+			}
+		}
+		if page.NextMarker == "" {
+			break
+		}
+		q.Set("marker", page.NextMarker)
+	}
+	return names, nil
+}
@@ -0,0 +1,220 @@
+// Package compress wraps a log store to compress the logs of calls before they are
+// stored, and to decompress them when they are read. Compressed logs start with a header
+// naming their algorithm, and are base64 encoded unless the store keeps logs as bytes,
+// see Binary. Logs stored before compression was enabled have no header and are read as
+// they are.
+//
+// Compression suits the stores keeping logs whole, sql, s3, gcs and azure. Stores working
+// on the lines of logs, elasticsearch in line mode and loki, can't search compressed logs.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithms
+const (
+	None = "none"
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+const (
+	// header starts the compressed logs, it is followed by the algorithm, the encoding of
+	// text stores and a newline, eg. "fn-compressed-log gzip base64\n"
+	header = "fn-compressed-log "
+
+	// encodingBase64 is the encoding of the compressed logs of text stores
+	encodingBase64 = "base64"
+)
+
+// Binary is implemented by the log stores which keep logs as bytes, the compressed logs of
+// the other stores are base64 encoded for them to hold the logs as text.
+type Binary interface {
+	BinaryLogs() bool
+}
+
+var (
+	// zstdDecoder decompresses the logs compressed with zstd, whatever the algorithm of the
+	// store is now
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+type store struct {
+	models.LogStore
+	algorithm string
+	level     int
+	// text is true unless the store keeps logs as bytes
+	text bool
+	zenc *zstd.Encoder
+}
+
+// New returns ls compressing logs with algorithm at level, ls if algorithm is none or
+// empty. Level 0 is the default level of the algorithm.
+func New(ls models.LogStore, algorithm string, level int) (models.LogStore, error) {
+	s := &store{LogStore: ls, algorithm: algorithm, level: level, text: true}
+	if b, ok := ls.(Binary); ok && b.BinaryLogs() {
+		s.text = false
+	}
+
+	switch algorithm {
+	case "", None:
+		return ls, nil
+	case Gzip:
+		if level == 0 {
+			s.level = gzip.DefaultCompression
+		}
+		if s.level != gzip.DefaultCompression && (s.level < gzip.BestSpeed || s.level > gzip.BestCompression) {
+			return nil, fmt.Errorf("invalid gzip log compression level %d, must be between 1 and 9", level)
+		}
+	case Zstd:
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("invalid zstd log compression level %d, must be between 1 and 22", level)
+		}
+		zlevel := zstd.SpeedDefault
+		if level != 0 {
+			zlevel = zstd.EncoderLevelFromZstd(level)
+		}
+		zenc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zlevel))
+		if err != nil {
+			return nil, err
+		}
+		s.zenc = zenc
+	default:
+		return nil, fmt.Errorf("invalid log compression %q, must be %s, %s or %s", algorithm, None, Gzip, Zstd)
+	}
+	return s, nil
+}
+
+func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	log, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return fmt.Errorf("failed to read log, %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header + s.algorithm)
+	if s.text {
+		buf.WriteString(" " + encodingBase64)
+	}
+	buf.WriteByte('\n')
+
+	var w io.Writer = &buf
+	var enc io.WriteCloser
+	if s.text {
+		enc = base64.NewEncoder(base64.StdEncoding, &buf)
+		w = enc
+	}
+
+	switch s.algorithm {
+	case Gzip:
+		zw, err := gzip.NewWriterLevel(w, s.level)
+		if err != nil {
+			return err
+		}
+		if _, err := zw.Write(log); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	case Zstd:
+		if _, err := w.Write(s.zenc.EncodeAll(log, nil)); err != nil {
+			return err
+		}
+	}
+
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	}
+	return s.LogStore.InsertLog(ctx, call, &buf)
+}
+
+func (s *store) GetLog(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	r, err := s.LogStore.GetLog(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	log, err := decompress(b)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(log), nil
+}
+
+// SearchLog filters the log once decompressed, the log stored can't be searched
+func (s *store) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	log, err := s.GetLog(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	return models.FilterLog(log, filter)
+}
+
+// decompress returns the log compressed in b, b if it has no header
+func decompress(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, []byte(header)) {
+		return b, nil
+	}
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return nil, errors.New("failed to decompress log, its header has no end")
+	}
+	fields := strings.Fields(string(b[len(header):i]))
+	b = b[i+1:]
+
+	if len(fields) == 2 && fields[1] == encodingBase64 {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+		n, err := base64.StdEncoding.Decode(decoded, b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress log, %v", err)
+		}
+		b = decoded[:n]
+	} else if len(fields) != 1 {
+		return nil, fmt.Errorf("failed to decompress log, invalid header %q", strings.Join(fields, " "))
+	}
+
+	var log []byte
+	var err error
+	switch fields[0] {
+	case Gzip:
+		var zr *gzip.Reader
+		zr, err = gzip.NewReader(bytes.NewReader(b))
+		if err == nil {
+			log, err = ioutil.ReadAll(zr)
+		}
+	case Zstd:
+		zstdDecoderOnce.Do(func() {
+			zstdDecoder, zstdDecoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		})
+		if err = zstdDecoderErr; err == nil {
+			log, err = zstdDecoder.DecodeAll(b, nil)
+		}
+	default:
+		err = fmt.Errorf("unknown algorithm %q", fields[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress log, %v", err)
+	}
+	return log, nil
+}
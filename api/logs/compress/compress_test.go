@@ -0,0 +1,187 @@
+package compress
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+// binaryStore is a log store keeping logs as bytes
+type binaryStore struct {
+	models.LogStore
+}
+
+func (binaryStore) BinaryLogs() bool { return true }
+
+func TestCompress(t *testing.T) {
+	for _, algorithm := range []string{Gzip, Zstd} {
+		ls, err := New(logs.NewMock(), algorithm, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logTesting.Test(t, ls)
+	}
+}
+
+func TestCompressStored(t *testing.T) {
+	ctx := context.Background()
+	log := strings.Repeat("the same line, over and over\n", 100)
+
+	for i, test := range []struct {
+		algorithm string
+		level     int
+		binary    bool
+		header    string
+	}{
+		{Gzip, 9, false, "fn-compressed-log gzip base64\n"},
+		{Gzip, 0, true, "fn-compressed-log gzip\n"},
+		{Zstd, 19, false, "fn-compressed-log zstd base64\n"},
+		{Zstd, 0, true, "fn-compressed-log zstd\n"},
+	} {
+		var inner models.LogStore = logs.NewMock()
+		if test.binary {
+			inner = binaryStore{inner}
+		}
+		ls, err := New(inner, test.algorithm, test.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+		if err := ls.InsertLog(ctx, call, strings.NewReader(log)); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := inner.GetLog(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(stored), test.header) || len(stored) >= len(log) {
+			t.Fatalf("Test %d: expected the log stored compressed, got %d bytes %q", i, len(stored), stored)
+		}
+		body := stored[len(test.header):]
+		if _, err := base64.StdEncoding.DecodeString(string(body)); (err == nil) == test.binary {
+			t.Fatalf("Test %d: expected the log to be base64 encoded only for text stores, got %q", i, body)
+		}
+
+		r, err = ls.GetLog(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != log {
+			t.Fatalf("Test %d: expected the log decompressed, got %q", i, got)
+		}
+	}
+}
+
+func TestCompressReadsOtherAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	inner := logs.NewMock()
+	gz, err := New(inner, Gzip, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst, err := New(inner, Zstd, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// logs stored with gzip are read once the algorithm is zstd, and once compression is off
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	if err := gz.InsertLog(ctx, call, strings.NewReader("gzipped\n")); err != nil {
+		t.Fatal(err)
+	}
+	for _, ls := range []models.LogStore{zst, mustNew(t, inner, Gzip)} {
+		r, err := ls.GetLog(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ioutil.ReadAll(r); string(got) != "gzipped\n" {
+			t.Fatalf("expected the gzipped log, got %q", got)
+		}
+	}
+}
+
+func mustNew(t *testing.T, ls models.LogStore, algorithm string) models.LogStore {
+	s, err := New(ls, algorithm, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCompressReadsUncompressed(t *testing.T) {
+	ctx := context.Background()
+	inner := logs.NewMock()
+	ls, err := New(inner, Gzip, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stored before compression was enabled, including one looking like base64 gzip
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	for _, log := range []string{"plain log\n", "H4sI is not gzip\n"} {
+		if err := inner.InsertLog(ctx, call, strings.NewReader(log)); err != nil {
+			t.Fatal(err)
+		}
+		r, err := ls.GetLog(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != log {
+			t.Fatalf("expected the uncompressed log as it is, got %q", got)
+		}
+	}
+
+	// a log marked compressed which isn't fails to be read
+	if err := inner.InsertLog(ctx, call, strings.NewReader("fn-compressed-log gzip base64\nnot gzip")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.GetLog(ctx, call.FnID, call.ID); err == nil {
+		t.Fatal("expected a corrupt compressed log to fail to be read")
+	}
+}
+
+func TestNew(t *testing.T) {
+	inner := logs.NewMock()
+	for _, algorithm := range []string{"", None} {
+		ls, err := New(inner, algorithm, 0)
+		if err != nil || ls != inner {
+			t.Fatalf("expected %q to leave the log store as it is, got %v", algorithm, err)
+		}
+	}
+
+	for i, test := range []struct {
+		algorithm string
+		level     int
+	}{
+		{Gzip, 10},
+		{Gzip, -2},
+		{Zstd, 23},
+		{Zstd, -1},
+		{"lz4", 0},
+	} {
+		if _, err := New(inner, test.algorithm, test.level); err == nil {
+			t.Fatalf("Test %d: expected %s at level %d to be invalid", i, test.algorithm, test.level)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errNotFound is returned for the documents and indices which do not exist
+var errNotFound = errors.New("not found")
+
+// client makes the requests of the REST API of Elasticsearch 7+ and OpenSearch used by the
+// store
+type client struct {
+	http     *http.Client
+	endpoint string
+	user     *url.Userinfo
+}
+
+// esError is the error of a request
+type esError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+func (c *client) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.user != nil {
+		password, _ := c.user.Password()
+		req.SetBasicAuth(c.user.Username(), password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error esError `json:"error"`
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) == nil && e.Error.Type != "" {
+			return &e.Error
+		}
+		return fmt.Errorf("elasticsearch %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (e *esError) Error() string {
+	return fmt.Sprintf("elasticsearch %s: %s", e.Type, e.Reason)
+}
+
+func (c *client) doJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	return c.do(ctx, method, path, "application/json", body, out)
+}
+
+// createIndex creates the index of name with mappings, unless it exists
+func (c *client) createIndex(ctx context.Context, name string, mappings interface{}) error {
+	err := c.doJSON(ctx, http.MethodPut, "/"+url.PathEscape(name), map[string]interface{}{"mappings": mappings}, nil)
+	if e, ok := err.(*esError); ok && e.Type == "resource_already_exists_exception" {
+		return nil
+	}
+	return err
+}
+
+// index writes doc as the document of id in index, overwriting it if it exists
+func (c *client) index(ctx context.Context, index, id, refresh string, doc interface{}) error {
+	path := "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id) + "?refresh=" + url.QueryEscape(refresh)
+	return c.doJSON(ctx, http.MethodPut, path, doc, nil)
+}
+
+// bulkIndex writes the documents of docs by id in index, see index
+func (c *client) bulkIndex(ctx context.Context, index, refresh string, ids []string, docs []interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i, doc := range docs {
+		action := map[string]interface{}{"index": map[string]string{"_index": index, "_id": ids[i]}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	var res struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error *esError `json:"error"`
+		} `json:"items"`
+	}
+	err := c.do(ctx, http.MethodPost, "/_bulk?refresh="+url.QueryEscape(refresh), "application/x-ndjson", &buf, &res)
+	if err != nil {
+		return err
+	}
+	if res.Errors {
+		for _, item := range res.Items {
+			for _, r := range item {
+				if r.Error != nil {
+					return r.Error
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// get reads the source of the document of id in index into out, errNotFound if it does not
+// exist. Gets are realtime, they read the documents written since the last refresh.
+func (c *client) get(ctx context.Context, index, id string, out interface{}) error {
+	var res struct {
+		Found  bool            `json:"found"`
+		Source json.RawMessage `json:"_source"`
+	}
+	err := c.doJSON(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, &res)
+	if err != nil {
+		return err
+	}
+	if !res.Found {
+		return errNotFound
+	}
+	return json.Unmarshal(res.Source, out)
+}
+
+// remove deletes the document of id in index, errNotFound if it does not exist
+func (c *client) remove(ctx context.Context, index, id, refresh string) error {
+	path := "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id) + "?refresh=" + url.QueryEscape(refresh)
+	return c.doJSON(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// deleteByQuery deletes the documents of index matching query, returning how many were.
+// Only refreshes of true or false are supported, any other than false refreshes.
+func (c *client) deleteByQuery(ctx context.Context, index, refresh string, query map[string]interface{}) (int, error) {
+	if refresh != "false" {
+		refresh = "true"
+	}
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	path := "/" + url.PathEscape(index) + "/_delete_by_query?refresh=" + refresh
+	if err := c.doJSON(ctx, http.MethodPost, path, query, &res); err != nil {
+		return 0, err
+	}
+	return res.Deleted, nil
+}
+
+// searchHit is a document matching a search
+type searchHit struct {
+	Source json.RawMessage `json:"_source"`
+	Sort   []interface{}   `json:"sort"`
+}
+
+// search returns the documents of index matching query, in the order of sort
+func (c *client) search(ctx context.Context, index string, query map[string]interface{}) ([]searchHit, error) {
+	var res struct {
+		Hits struct {
+			Hits []searchHit `json:"hits"`
+		} `json:"hits"`
+	}
+	err := c.doJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", query, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res.Hits.Hits, nil
+}
@@ -0,0 +1,294 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+// fakeES serves the requests made by the store, searching with the term, match_phrase and range filters,
+// and the sort on a single field, the store uses
+type fakeES struct {
+	mu      sync.Mutex
+	indices map[string]map[string]map[string]interface{}
+}
+
+func newFakeES() (*fakeES, *httptest.Server) {
+	f := &fakeES{indices: make(map[string]map[string]map[string]interface{})}
+	return f, httptest.NewServer(f)
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "_bulk":
+		f.bulk(w, r)
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		if _, ok := f.indices[parts[0]]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"resource_already_exists_exception","reason":"exists"}}`))
+			return
+		}
+		f.indices[parts[0]] = make(map[string]map[string]interface{})
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(parts) == 2 && parts[1] == "_search":
+		f.search(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "_delete_by_query":
+		f.deleteByQuery(w, r, parts[0])
+	case len(parts) == 3 && parts[1] == "_doc":
+		docs, ok := f.indices[parts[0]]
+		if !ok {
+			http.Error(w, `{"error":{"type":"index_not_found_exception","reason":"no index"}}`, http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var doc map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&doc)
+			docs[parts[2]] = doc
+			w.Write([]byte(`{"result":"created"}`))
+		case http.MethodGet:
+			doc, ok := docs[parts[2]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"found":false}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"found": true, "_source": doc})
+		case http.MethodDelete:
+			if _, ok := docs[parts[2]]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"result":"not_found"}`))
+				return
+			}
+			delete(docs, parts[2])
+			w.Write([]byte(`{"result":"deleted"}`))
+		}
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeES) bulk(w http.ResponseWriter, r *http.Request) {
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+				ID    string `json:"_id"`
+			} `json:"index"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		var doc map[string]interface{}
+		json.Unmarshal(scanner.Bytes(), &doc)
+		f.indices[action.Index.Index][action.Index.ID] = doc
+	}
+	w.Write([]byte(`{"errors":false}`))
+}
+
+func (f *fakeES) search(w http.ResponseWriter, r *http.Request, index string) {
+	var query struct {
+		Size  int `json:"size"`
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Sort        []map[string]string `json:"sort"`
+		SearchAfter []interface{}       `json:"search_after"`
+	}
+	json.NewDecoder(r.Body).Decode(&query)
+	var field, order string
+	for field, order = range query.Sort[0] {
+	}
+
+	var hits []map[string]interface{}
+	for _, doc := range f.indices[index] {
+		if matches(doc, query.Query.Bool.Filter) {
+			hits = append(hits, doc)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		less := compare(hits[i][field], hits[j][field]) < 0
+		if order == "desc" {
+			return !less
+		}
+		return less
+	})
+	res := []map[string]interface{}{}
+	for _, doc := range hits {
+		if len(query.SearchAfter) > 0 {
+			c := compare(doc[field], query.SearchAfter[0])
+			if (order == "asc" && c <= 0) || (order == "desc" && c >= 0) {
+				continue
+			}
+		}
+		if len(res) == query.Size {
+			break
+		}
+		res = append(res, map[string]interface{}{"_source": doc, "sort": []interface{}{doc[field]}})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"hits": map[string]interface{}{"hits": res}})
+}
+
+func (f *fakeES) deleteByQuery(w http.ResponseWriter, r *http.Request, index string) {
+	var query struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	json.NewDecoder(r.Body).Decode(&query)
+	var deleted int
+	for id, doc := range f.indices[index] {
+		if matches(doc, query.Query.Bool.Filter) {
+			delete(f.indices[index], id)
+			deleted++
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]int{"deleted": deleted})
+}
+
+func matches(doc map[string]interface{}, filters []map[string]map[string]interface{}) bool {
+	for _, filter := range filters {
+		for field, v := range filter["term"] {
+			if doc[field] != v {
+				return false
+			}
+		}
+		for field, v := range filter["match_phrase"] {
+			if !strings.Contains(strings.ToLower(doc[field].(string)), strings.ToLower(v.(string))) {
+				return false
+			}
+		}
+		for field, bounds := range filter["range"] {
+			for op, bound := range bounds.(map[string]interface{}) {
+				c := compare(doc[field], bound)
+				if (op == "lt" && c >= 0) || (op == "gt" && c <= 0) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case float64:
+		switch {
+		case a < b.(float64):
+			return -1
+		case a > b.(float64):
+			return 1
+		}
+	}
+	return 0
+}
+
+func newStore(t *testing.T, rawurl string) models.LogStore {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := esStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create elasticsearch log store: %v", err)
+	}
+	return ls
+}
+
+func TestElasticsearch(t *testing.T) {
+	_, srv := newFakeES()
+	defer srv.Close()
+	logTesting.Test(t, newStore(t, "elasticsearch://"+strings.TrimPrefix(srv.URL, "http://")+"/fn"))
+}
+
+func TestLineMode(t *testing.T) {
+	f, srv := newFakeES()
+	defer srv.Close()
+	ls := newStore(t, "opensearch://"+strings.TrimPrefix(srv.URL, "http://")+"/fn?mode=line")
+	logTesting.Test(t, ls)
+
+	ctx := context.Background()
+	call := &models.Call{ID: id.New().String(), AppID: id.New().String(), FnID: id.New().String()}
+	log := "first line\nsecond line\n"
+	if err := ls.InsertLog(ctx, call, strings.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	var lines []string
+	for _, doc := range f.indices["fn-logs"] {
+		if doc["call_id"] == call.ID {
+			if doc["app_id"] != call.AppID || doc["fn_id"] != call.FnID {
+				t.Errorf("expected the lines to have the app and fn of the call, got %v", doc)
+			}
+			lines = append(lines, doc["message"].(string))
+		}
+	}
+	f.mu.Unlock()
+	if len(lines) != 3 {
+		t.Fatalf("expected a document per line, got %q", lines)
+	}
+
+	r, err := ls.GetLog(ctx, call.FnID, call.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != log {
+		t.Fatalf("expected the log to be read back, got %q", got)
+	}
+}
+
+func TestInvalidMode(t *testing.T) {
+	u, err := url.Parse("elasticsearch://localhost:9200/fn?mode=word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := esStoreProvider(0).New(context.Background(), u); err == nil {
+		t.Fatal("expected an invalid mode to fail")
+	}
+}
+
+func TestElasticsearchServer(t *testing.T) {
+	es := os.Getenv("ELASTICSEARCH_URL")
+	if es == "" {
+		t.Skip("no elasticsearch specified in url, skipping")
+		return
+	}
+	u, err := url.Parse(es)
+	if err != nil {
+		t.Fatalf("failed to parse url: %v", err)
+	}
+	q := u.Query()
+	// the calls are listed as soon as they are inserted
+	q.Set("refresh", "wait_for")
+	u.RawQuery = q.Encode()
+	logTesting.Test(t, newStore(t, u.String()))
+}
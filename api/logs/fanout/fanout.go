@@ -0,0 +1,207 @@
+// Package fanout wraps a log store, the primary, to write the calls, logs and results it
+// stores to secondary log stores too, e.g. to archive logs in s3 while searching them in
+// loki. Reads are served by the primary.
+//
+// Writes to the primary are made as calls complete, and once they succeed are queued to each
+// secondary, which writes them in the background. A secondary failing, or falling behind
+// until its queue is full, has the writes it misses dropped and logged, it neither fails nor
+// slows down the calls.
+package fanout
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+// DefaultQueueSize is the number of writes queued to each secondary by default
+const DefaultQueueSize = 1024
+
+// writeTimeout bounds each write to a secondary, for a secondary hanging not to stall its
+// queue
+const writeTimeout = 30 * time.Second
+
+type write struct {
+	ctx context.Context
+	op  string
+	fn  func(ctx context.Context, ls models.LogStore) error
+}
+
+type secondary struct {
+	models.LogStore
+	index  int
+	writes chan *write
+}
+
+type store struct {
+	models.LogStore
+	secondaries []*secondary
+	wg          sync.WaitGroup
+}
+
+// New returns primary writing to secondaries too, each queuing up to queueSize writes, or
+// DefaultQueueSize if queueSize is not positive. It returns primary if there are no
+// secondaries. Closing it closes primary and secondaries, once the writes queued are made.
+func New(primary models.LogStore, secondaries []models.LogStore, queueSize int) models.LogStore {
+	if len(secondaries) == 0 {
+		return primary
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	s := &store{LogStore: primary}
+	for i, ls := range secondaries {
+		sec := &secondary{LogStore: ls, index: i, writes: make(chan *write, queueSize)}
+		s.secondaries = append(s.secondaries, sec)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sec.run()
+		}()
+	}
+	return s
+}
+
+func (s *secondary) run() {
+	for w := range s.writes {
+		ctx, cancel := context.WithTimeout(w.ctx, writeTimeout)
+		err := w.fn(ctx, s.LogStore)
+		cancel()
+		if err != nil {
+			stats.Record(ctx, failedWritesMeasure.M(1))
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"secondary": s.index, "op": w.op}).Warn("Failed to write to secondary log store")
+		}
+	}
+}
+
+// fanOut queues the write to every secondary, dropping it for those whose queue is full. The
+// writes are made in a context of their own, the one of the call being done by then.
+func (s *store) fanOut(ctx context.Context, op string, fn func(ctx context.Context, ls models.LogStore) error) {
+	w := &write{ctx: common.WithLogger(context.Background(), common.Logger(ctx)), op: op, fn: fn}
+	for _, sec := range s.secondaries {
+		select {
+		case sec.writes <- w:
+		default:
+			stats.Record(ctx, droppedWritesMeasure.M(1))
+			common.Logger(ctx).WithFields(logrus.Fields{"secondary": sec.index, "op": op}).Warn("Secondary log store queue full, dropping write")
+		}
+	}
+}
+
+func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	b, err := ioutil.ReadAll(callLog)
+	if err != nil {
+		return err
+	}
+	if err := s.LogStore.InsertLog(ctx, call, bytes.NewReader(b)); err != nil {
+		return err
+	}
+	c := *call
+	s.fanOut(ctx, "insert_log", func(ctx context.Context, ls models.LogStore) error {
+		return ls.InsertLog(ctx, &c, bytes.NewReader(b))
+	})
+	return nil
+}
+
+func (s *store) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	b, err := ioutil.ReadAll(result)
+	if err != nil {
+		return err
+	}
+	if err := s.LogStore.InsertResult(ctx, call, bytes.NewReader(b)); err != nil {
+		return err
+	}
+	c := *call
+	s.fanOut(ctx, "insert_result", func(ctx context.Context, ls models.LogStore) error {
+		return ls.InsertResult(ctx, &c, bytes.NewReader(b))
+	})
+	return nil
+}
+
+func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
+	if err := s.LogStore.InsertCall(ctx, call); err != nil {
+		return err
+	}
+	c := *call
+	s.fanOut(ctx, "insert_call", func(ctx context.Context, ls models.LogStore) error {
+		return ls.InsertCall(ctx, &c)
+	})
+	return nil
+}
+
+func (s *store) RemoveCall(ctx context.Context, fnID, callID string) error {
+	if err := s.LogStore.RemoveCall(ctx, fnID, callID); err != nil {
+		return err
+	}
+	s.fanOut(ctx, "remove_call", func(ctx context.Context, ls models.LogStore) error {
+		return ls.RemoveCall(ctx, fnID, callID)
+	})
+	return nil
+}
+
+func (s *store) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	if err := s.LogStore.InsertDeadLetter(ctx, call); err != nil {
+		return err
+	}
+	c := *call
+	s.fanOut(ctx, "insert_dead_letter", func(ctx context.Context, ls models.LogStore) error {
+		return ls.InsertDeadLetter(ctx, &c)
+	})
+	return nil
+}
+
+func (s *store) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	if err := s.LogStore.RemoveDeadLetter(ctx, fnID, callID); err != nil {
+		return err
+	}
+	s.fanOut(ctx, "remove_dead_letter", func(ctx context.Context, ls models.LogStore) error {
+		return ls.RemoveDeadLetter(ctx, fnID, callID)
+	})
+	return nil
+}
+
+// Close waits for the writes queued to the secondaries, then closes them and the primary
+func (s *store) Close() error {
+	for _, sec := range s.secondaries {
+		close(sec.writes)
+	}
+	s.wg.Wait()
+
+	err := s.LogStore.Close()
+	for _, sec := range s.secondaries {
+		if cerr := sec.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+const (
+	droppedWritesMetricName = "fanout_log_dropped_writes"
+	failedWritesMetricName  = "fanout_log_failed_writes"
+)
+
+var (
+	droppedWritesMeasure = common.MakeMeasure(droppedWritesMetricName, "writes to secondary log stores dropped with their queue full", "")
+	failedWritesMeasure  = common.MakeMeasure(failedWritesMetricName, "writes to secondary log stores failed", "")
+)
+
+// RegisterViews registers views for fanout measures
+func RegisterViews(tagKeys []string) {
+	err := view.Register(
+		common.CreateView(droppedWritesMeasure, view.Count(), tagKeys),
+		common.CreateView(failedWritesMeasure, view.Count(), tagKeys),
+	)
+	if err != nil {
+		logrus.WithError(err).Fatal("cannot create view")
+	}
+}
@@ -0,0 +1,126 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+	"github.com/fnproject/fn/api/models"
+)
+
+// blockingStore fails or blocks the logs inserted into it, counting them
+type blockingStore struct {
+	models.LogStore
+	unblock chan struct{}
+	fail    bool
+
+	mu       sync.Mutex
+	inserted int
+}
+
+func (s *blockingStore) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
+	if s.unblock != nil {
+		<-s.unblock
+	}
+	s.mu.Lock()
+	s.inserted++
+	s.mu.Unlock()
+	if s.fail {
+		return errors.New("sink down")
+	}
+	return s.LogStore.InsertLog(ctx, call, callLog)
+}
+
+func TestFanout(t *testing.T) {
+	ls := New(logs.NewMock(), []models.LogStore{logs.NewMock()}, 0)
+	logTesting.Test(t, ls)
+}
+
+func TestFanoutWritesSecondaries(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := logs.NewMock(), logs.NewMock()
+	ls := New(primary, []models.LogStore{secondary}, 0)
+
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	if err := ls.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, call, strings.NewReader("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	// close waits for the writes queued
+	if err := ls.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, store := range []models.LogStore{primary, secondary} {
+		if _, err := store.GetCall(ctx, call.FnID, call.ID); err != nil {
+			t.Fatalf("expected the call written, got %v", err)
+		}
+		r, err := store.GetLog(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "hello\n" {
+			t.Fatalf("expected the log written, got %q", b)
+		}
+	}
+}
+
+func TestFanoutIsolatesSecondaries(t *testing.T) {
+	ctx := context.Background()
+	slow := &blockingStore{LogStore: logs.NewMock(), unblock: make(chan struct{})}
+	failing := &blockingStore{LogStore: logs.NewMock(), fail: true}
+	ls := New(logs.NewMock(), []models.LogStore{slow, failing}, 1)
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 5; i++ {
+			call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+			if err := ls.InsertLog(ctx, call, strings.NewReader("hello\n")); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected the secondaries not to fail the writes, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a slow secondary not to block the writes")
+	}
+
+	close(slow.unblock)
+	if err := ls.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the slow secondary takes a write, queues another and drops the rest
+	if slow.inserted < 1 || slow.inserted > 2 {
+		t.Fatalf("expected the writes beyond the queue of the slow secondary dropped, got %d", slow.inserted)
+	}
+	if failing.inserted == 0 {
+		t.Fatal("expected the failing secondary to be written")
+	}
+}
+
+func TestNewNoSecondaries(t *testing.T) {
+	primary := logs.NewMock()
+	if ls := New(primary, nil, 0); ls != primary {
+		t.Fatal("expected the primary without secondaries")
+	}
+}
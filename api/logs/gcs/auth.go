@@ -0,0 +1,222 @@
+package gcs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// storageScope is the oauth scope of the tokens of the store
+	storageScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+	// tokenExpiryDelta is how long before they expire the tokens are renewed
+	tokenExpiryDelta = time.Minute
+
+	// defaultMetadataHost is the host of the metadata server of GCE and GKE, which serves the
+	// tokens of the service account bound to the node or, with workload identity, to the pod
+	defaultMetadataHost = "metadata.google.internal"
+	// envMetadataHost overrides the metadata host, the variable the google client libraries use
+	envMetadataHost = "GCE_METADATA_HOST"
+	// envCredentials is the path of the service account key file used by default, the variable
+	// the google client libraries use
+	envCredentials = "GOOGLE_APPLICATION_CREDENTIALS"
+)
+
+// token is an oauth access token
+type token struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	expiry      time.Time
+}
+
+// tokenSource fetches the tokens authorizing the requests of the store
+type tokenSource interface {
+	token(ctx context.Context) (*token, error)
+}
+
+// newTokenSource returns the token source of credentials, the path of a service account key
+// file, "none" for no authorization, eg with emulators, or empty for the key file of
+// GOOGLE_APPLICATION_CREDENTIALS if set, and otherwise the service account of the metadata
+// server, bound to the pod with workload identity
+func newTokenSource(client *http.Client, credentials string) (tokenSource, error) {
+	if credentials == "" {
+		credentials = os.Getenv(envCredentials)
+	}
+	switch credentials {
+	case "none":
+		return nil, nil
+	case "":
+		host := os.Getenv(envMetadataHost)
+		if host == "" {
+			host = defaultMetadataHost
+		}
+		return &cachedTokenSource{src: &metadataTokenSource{client: client, host: host}}, nil
+	}
+	key, err := readServiceAccountKey(credentials)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedTokenSource{src: &serviceAccountTokenSource{client: client, key: key}}, nil
+}
+
+// cachedTokenSource reuses the tokens of src until they are about to expire
+type cachedTokenSource struct {
+	src tokenSource
+
+	mu  sync.Mutex
+	tok *token
+}
+
+func (c *cachedTokenSource) token(ctx context.Context) (*token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tok != nil && time.Now().Add(tokenExpiryDelta).Before(c.tok.expiry) {
+		return c.tok, nil
+	}
+	tok, err := c.src.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.tok = tok
+	return tok, nil
+}
+
+// metadataTokenSource fetches the tokens of the service account of the metadata server
+type metadataTokenSource struct {
+	client *http.Client
+	host   string
+}
+
+func (m *metadataTokenSource) token(ctx context.Context) (*token, error) {
+	u := "http://" + m.host + "/computeMetadata/v1/instance/service-accounts/default/token?scopes=" + url.QueryEscape(storageScope)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return doTokenRequest(m.client, req.WithContext(ctx))
+}
+
+// serviceAccountKey is the part of a service account key file used to fetch tokens
+type serviceAccountKey struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+func readServiceAccountKey(path string) (*serviceAccountKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read gcs credentials: %v", err)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(b, &key); err != nil {
+		return nil, fmt.Errorf("invalid gcs credentials %s: %v", path, err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.TokenURI == "" {
+		return nil, fmt.Errorf("invalid gcs credentials %s: not a service account key", path)
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid gcs credentials %s: no private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gcs credentials %s: %v", path, err)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid gcs credentials %s: not an RSA key", path)
+	}
+	key.key = rsaKey
+	return &key, nil
+}
+
+// serviceAccountTokenSource exchanges assertions signed with the key of a service account for
+// tokens, see https://developers.google.com/identity/protocols/oauth2/service-account
+type serviceAccountTokenSource struct {
+	client *http.Client
+	key    *serviceAccountKey
+}
+
+func (s *serviceAccountTokenSource) token(ctx context.Context) (*token, error) {
+	assertion, err := s.assertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequest(http.MethodPost, s.key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(s.client, req.WithContext(ctx))
+}
+
+// assertion returns the JWT asserting the identity of the service account, signed with its key
+func (s *serviceAccountTokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.key.ClientEmail,
+		"scope": storageScope,
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	h := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key.key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func doTokenRequest(client *http.Client, req *http.Request) (*token, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch gcs token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("cannot fetch gcs token: %s %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	var tok token
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("cannot fetch gcs token: %v", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("cannot fetch gcs token: no access token in response")
+	}
+	tok.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return &tok, nil
+}
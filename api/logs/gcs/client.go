@@ -0,0 +1,169 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultEndpoint is the endpoint of the JSON API of GCS
+const defaultEndpoint = "https://storage.googleapis.com"
+
+// errObjectNotFound is returned for the objects which do not exist
+var errObjectNotFound = errors.New("object not found")
+
+// client makes the requests of the JSON API of GCS used by the store, see
+// https://cloud.google.com/storage/docs/json_api
+type client struct {
+	http     *http.Client
+	endpoint string
+	bucket   string
+	tokens   tokenSource
+}
+
+func (c *client) objectURL(name string) string {
+	return c.endpoint + "/storage/v1/b/" + url.PathEscape(c.bucket) + "/o/" + url.PathEscape(name)
+}
+
+func (c *client) do(ctx context.Context, method, u, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tokens != nil {
+		tok, err := c.tokens.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errObjectNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(b, &e) == nil && e.Error.Message != "" {
+			return nil, fmt.Errorf("gcs %s: %s", resp.Status, e.Error.Message)
+		}
+		return nil, fmt.Errorf("gcs %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// checkBucket returns an error unless the bucket exists and is accessible
+func (c *client) checkBucket(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, c.endpoint+"/storage/v1/b/"+url.PathEscape(c.bucket), "", nil)
+	if err == errObjectNotFound {
+		return fmt.Errorf("gcs bucket %s does not exist", c.bucket)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// upload writes body to the object of name, overwriting it if it exists
+func (c *client) upload(ctx context.Context, name, contentType string, body io.Reader) error {
+	u := c.endpoint + "/upload/storage/v1/b/" + url.PathEscape(c.bucket) + "/o?uploadType=media&name=" + url.QueryEscape(name)
+	resp, err := c.do(ctx, http.MethodPost, u, contentType, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// download returns the content of the object of name, errObjectNotFound if it does not exist
+func (c *client) download(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.objectURL(name)+"?alt=media", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	if resp.ContentLength > 0 {
+		buf.Grow(int(resp.ContentLength))
+	}
+	_, err = buf.ReadFrom(resp.Body)
+	return buf.Bytes(), err
+}
+
+// remove deletes the object of name, errObjectNotFound if it does not exist
+func (c *client) remove(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.objectURL(name), "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// list returns the names of up to max objects starting with prefix, in lexicographic order,
+// after marker if not empty
+func (c *client) list(ctx context.Context, prefix, marker string, max int) ([]string, error) {
+	q := url.Values{
+		"prefix":     {prefix},
+		"maxResults": {strconv.Itoa(max + 1)},
+		"fields":     {"items(name),nextPageToken"},
+	}
+	if marker != "" {
+		// startOffset is inclusive, unlike the markers of s3 the keys are compatible with
+		q.Set("startOffset", marker)
+	}
+
+	var names []string
+	for len(names) < max {
+		resp, err := c.do(ctx, http.MethodGet, c.endpoint+"/storage/v1/b/"+url.PathEscape(c.bucket)+"/o?"+q.Encode(), "", nil)
+		if err == errObjectNotFound {
+			return nil, fmt.Errorf("gcs bucket %s does not exist", c.bucket)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if item.Name != marker && len(names) < max {
+				names = append(names, item.Name)
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+	return names, nil
+}
@@ -0,0 +1,228 @@
+package gcs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	logTesting "github.com/fnproject/fn/api/logs/testing"
+)
+
+// fakeGCS serves the requests of the JSON API of GCS made by the store, for a bucket
+type fakeGCS struct {
+	bucket string
+	// token is the token the requests must be authorized with, if not empty
+	token string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		http.Error(w, `{"error":{"message":"unauthorized"}}`, http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucketPath := "/storage/v1/b/" + f.bucket
+	path := r.URL.EscapedPath()
+	switch {
+	case r.Method == http.MethodPost && path == "/upload"+bucketPath+"/o":
+		b, _ := ioutil.ReadAll(r.Body)
+		f.objects[r.URL.Query().Get("name")] = b
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && path == bucketPath:
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && path == bucketPath+"/o":
+		f.list(w, r.URL.Query())
+	case strings.HasPrefix(path, bucketPath+"/o/"):
+		name, _ := url.PathUnescape(strings.TrimPrefix(path, bucketPath+"/o/"))
+		b, ok := f.objects[name]
+		if !ok {
+			http.Error(w, `{"error":{"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.objects, name)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write(b)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+// list serves pages of 2 objects at most, for the store to follow the page tokens
+func (f *fakeGCS) list(w http.ResponseWriter, q url.Values) {
+	var names []string
+	for name := range f.objects {
+		if strings.HasPrefix(name, q.Get("prefix")) && name >= q.Get("startOffset") && name > q.Get("pageToken") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	max, _ := strconv.Atoi(q.Get("maxResults"))
+	if max > 2 {
+		max = 2
+	}
+	var page struct {
+		Items         []map[string]string `json:"items"`
+		NextPageToken string              `json:"nextPageToken,omitempty"`
+	}
+	for i, name := range names {
+		if i == max {
+			page.NextPageToken = names[i-1]
+			break
+		}
+		page.Items = append(page.Items, map[string]string{"name": name})
+	}
+	json.NewEncoder(w).Encode(&page)
+}
+
+func newFakeGCS(token string) (*fakeGCS, *httptest.Server) {
+	f := &fakeGCS{bucket: "logs", token: token, objects: make(map[string][]byte)}
+	return f, httptest.NewServer(f)
+}
+
+func TestGCS(t *testing.T) {
+	f, srv := newFakeGCS("")
+	defer srv.Close()
+	u, err := url.Parse("gcs://logs/fn/logs?credentials=none&endpoint=" + url.QueryEscape(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := gcsStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create gcs log store: %v", err)
+	}
+	logTesting.Test(t, ls)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.objects) == 0 {
+		t.Fatal("expected objects to be written to the bucket")
+	}
+	for name := range f.objects {
+		if !strings.HasPrefix(name, "fn/logs/") {
+			t.Fatalf("expected the keys to start with the prefix, got %s", name)
+		}
+	}
+}
+
+func TestMissingBucket(t *testing.T) {
+	_, srv := newFakeGCS("")
+	defer srv.Close()
+	u, err := url.Parse("gcs://other?credentials=none&endpoint=" + url.QueryEscape(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gcsStoreProvider(0).New(context.Background(), u); err == nil {
+		t.Fatal("expected a missing bucket to fail")
+	}
+}
+
+func TestServiceAccountCredentials(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokenRequests int
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, "invalid assertion", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+			http.Error(w, "invalid signature", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"secret","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer tokens.Close()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "fn@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokens.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "gcs_credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key.json")
+	if err := ioutil.WriteFile(path, keyFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, srv := newFakeGCS("secret")
+	defer srv.Close()
+	u, err := url.Parse("gcs://logs?credentials=" + url.QueryEscape(path) + "&endpoint=" + url.QueryEscape(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := gcsStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create gcs log store: %v", err)
+	}
+	logTesting.Test(t, ls)
+	if tokenRequests != 1 {
+		t.Fatalf("expected the token to be fetched once and reused, got %d requests", tokenRequests)
+	}
+}
+
+func TestMetadataCredentials(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"workload","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer metadata.Close()
+	os.Setenv(envMetadataHost, strings.TrimPrefix(metadata.URL, "http://"))
+	defer os.Unsetenv(envMetadataHost)
+	os.Unsetenv(envCredentials)
+
+	_, srv := newFakeGCS("workload")
+	defer srv.Close()
+	u, err := url.Parse("gcs://logs?endpoint=" + url.QueryEscape(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gcsStoreProvider(0).New(context.Background(), u); err != nil {
+		t.Fatalf("expected the token of the metadata server to be used, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package kafkarest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+// fakeProxy records the records produced to each topic
+type fakeProxy struct {
+	mu      sync.Mutex
+	records map[string][]json.RawMessage
+	keys    map[string][]string
+}
+
+func newFakeProxy() (*fakeProxy, *httptest.Server) {
+	p := &fakeProxy{records: make(map[string][]json.RawMessage), keys: make(map[string][]string)}
+	return p, httptest.NewServer(p)
+}
+
+func (p *fakeProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/topics/") || r.Header.Get("Content-Type") != contentType {
+		http.Error(w, `{"error_code":40401,"message":"unexpected request"}`, http.StatusNotFound)
+		return
+	}
+	topic := strings.TrimPrefix(r.URL.Path, "/topics/")
+	var req struct {
+		Records []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if topic == "full" {
+		w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"record too large"}]}`))
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, rec := range req.Records {
+		p.records[topic] = append(p.records[topic], rec.Value)
+		p.keys[topic] = append(p.keys[topic], rec.Key)
+	}
+	w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+}
+
+func newStore(t *testing.T, rawurl string) models.LogStore {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := kafkaRESTStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create kafka log store: %v", err)
+	}
+	return ls
+}
+
+func testCall() *models.Call {
+	return &models.Call{ID: id.New().String(), AppID: id.New().String(), FnID: id.New().String()}
+}
+
+func TestKafkaREST(t *testing.T) {
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	ls := newStore(t, strings.Replace(srv.URL, "http://", "kafka-rest://", 1))
+
+	ctx := context.Background()
+	call := testCall()
+	if err := ls.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, call, strings.NewReader("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.records["fn-calls"]) != 1 || len(p.records["fn-logs"]) != 1 {
+		t.Fatalf("expected a call and a log to be produced, got %v", p.records)
+	}
+	var c models.Call
+	if err := json.Unmarshal(p.records["fn-calls"][0], &c); err != nil || c.ID != call.ID {
+		t.Fatalf("expected the call to be produced, got %s", p.records["fn-calls"][0])
+	}
+	var l logRecord
+	if err := json.Unmarshal(p.records["fn-logs"][0], &l); err != nil {
+		t.Fatal(err)
+	}
+	if l.CallID != call.ID || l.AppID != call.AppID || l.FnID != call.FnID || l.Log != "hello\n" {
+		t.Fatalf("expected the log of the call to be produced, got %+v", l)
+	}
+	for topic, keys := range p.keys {
+		if keys[0] != call.AppID {
+			t.Fatalf("expected the records of %s to be keyed by app, got %s", topic, keys[0])
+		}
+	}
+}
+
+func TestKafkaRESTOptions(t *testing.T) {
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	ls := newStore(t, strings.Replace(srv.URL, "http://", "kafka-rest://", 1)+"?logs=app-logs&calls=-&key=fn")
+
+	ctx := context.Background()
+	call := testCall()
+	if err := ls.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, call, strings.NewReader("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.records) != 1 || len(p.records["app-logs"]) != 1 {
+		t.Fatalf("expected only the log to be produced, got %v", p.records)
+	}
+	if p.keys["app-logs"][0] != call.FnID {
+		t.Fatalf("expected the log to be keyed by fn, got %s", p.keys["app-logs"][0])
+	}
+}
+
+func TestKafkaRESTRecordError(t *testing.T) {
+	_, srv := newFakeProxy()
+	defer srv.Close()
+	ls := newStore(t, strings.Replace(srv.URL, "http://", "kafka-rest://", 1)+"?logs=full")
+	if err := ls.InsertLog(context.Background(), testCall(), strings.NewReader("hello\n")); err == nil {
+		t.Fatal("expected the error of the record to fail the insert")
+	}
+}
+
+func TestKafkaRESTReads(t *testing.T) {
+	ls := newStore(t, "kafka-rest://localhost:8082")
+	ctx := context.Background()
+	if _, err := ls.GetLog(ctx, "fn", "call"); err != models.ErrCallLogNotFound {
+		t.Fatalf("expected the log not to be found, got %v", err)
+	}
+	if _, err := ls.GetCall(ctx, "fn", "call"); err != models.ErrCallNotFound {
+		t.Fatalf("expected the call not to be found, got %v", err)
+	}
+	calls, err := ls.GetCalls(ctx, &models.CallFilter{FnID: "fn"})
+	if err != nil || len(calls.Items) != 0 {
+		t.Fatalf("expected no calls to be listed, got %v %v", calls, err)
+	}
+}
+
+func TestKafkaRESTInvalidKey(t *testing.T) {
+	u, err := url.Parse("kafka-rest://localhost:8082?key=image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kafkaRESTStoreProvider(0).New(context.Background(), u); err == nil {
+		t.Fatal("expected an invalid key to fail")
+	}
+}
@@ -0,0 +1,242 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+var (
+	matcher    = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	lineFilter = regexp.MustCompile(`\|~ ("(?:[^"\\]|\\.)*")`)
+)
+
+// fakeLoki serves the push and query_range requests of the store, the queries matching the
+// streams with all their label matchers
+type fakeLoki struct {
+	// authorized returns whether a request is authorized
+	authorized func(r *http.Request) bool
+
+	mu      sync.Mutex
+	streams []stream
+}
+
+func newFakeLoki(authorized func(r *http.Request) bool) (*fakeLoki, *httptest.Server) {
+	f := &fakeLoki{authorized: authorized}
+	return f, httptest.NewServer(f)
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/loki/api/v1/push":
+		var req struct {
+			Streams []stream `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.streams = append(f.streams, req.Streams...)
+		w.WriteHeader(http.StatusNoContent)
+	case "/loki/api/v1/query_range":
+		f.query(w, r.URL.Query())
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+func (f *fakeLoki) query(w http.ResponseWriter, q url.Values) {
+	start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if q.Get("direction") != "forward" {
+		http.Error(w, "unexpected direction", http.StatusBadRequest)
+		return
+	}
+
+	var filter *regexp.Regexp
+	if m := lineFilter.FindStringSubmatch(q.Get("query")); m != nil {
+		expr, err := strconv.Unquote(m[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter = regexp.MustCompile(expr)
+	}
+
+	var values [][2]string
+	for _, s := range f.streams {
+		matches := true
+		for _, m := range matcher.FindAllStringSubmatch(q.Get("query"), -1) {
+			matches = matches && s.Stream[m[1]] == m[2]
+		}
+		if !matches {
+			continue
+		}
+		for _, v := range s.Values {
+			if ts, _ := strconv.ParseInt(v[0], 10, 64); ts >= start && (filter == nil || filter.MatchString(v[1])) {
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i][0] < values[j][0] })
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	res := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "streams",
+			"result":     []stream{{Values: values}},
+		},
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func newStore(t *testing.T, rawurl string) models.LogStore {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := lokiStoreProvider(0).New(context.Background(), u)
+	if err != nil {
+		t.Fatalf("failed to create loki log store: %v", err)
+	}
+	return ls
+}
+
+func readLog(t *testing.T, ls models.LogStore, fnID, callID string) string {
+	r, err := ls.GetLog(context.Background(), fnID, callID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLoki(t *testing.T) {
+	f, srv := newFakeLoki(func(r *http.Request) bool {
+		user, password, _ := r.BasicAuth()
+		return user == "fn" && password == "secret" && r.Header.Get("X-Scope-OrgID") == "tenant"
+	})
+	defer srv.Close()
+	ls := newStore(t, "loki://fn:secret@"+strings.TrimPrefix(srv.URL, "http://")+"?tenant=tenant")
+
+	ctx := context.Background()
+	call := &models.Call{ID: id.New().String(), AppID: id.New().String(), FnID: id.New().String()}
+	other := &models.Call{ID: id.New().String(), AppID: call.AppID, FnID: call.FnID}
+	log := "first line\n\nthird line"
+	if err := ls.InsertLog(ctx, call, strings.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ls.InsertLog(ctx, other, strings.NewReader("other\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	labels := f.streams[0].Stream
+	f.mu.Unlock()
+	if labels["app_id"] != call.AppID || labels["fn_id"] != call.FnID || labels["call_id"] != call.ID {
+		t.Fatalf("expected the stream to be labeled with the call, got %v", labels)
+	}
+
+	if got := readLog(t, ls, call.FnID, call.ID); got != log {
+		t.Fatalf("expected the log to be read back, got %q", got)
+	}
+	if _, err := ls.GetLog(ctx, id.New().String(), call.ID); err != models.ErrCallLogNotFound {
+		t.Fatalf("expected the log of another fn not to be found, got %v", err)
+	}
+}
+
+func TestLokiLongLog(t *testing.T) {
+	f, srv := newFakeLoki(func(r *http.Request) bool { return true })
+	defer srv.Close()
+	ls := newStore(t, "loki://"+strings.TrimPrefix(srv.URL, "http://"))
+
+	var lines []string
+	for i := 0; i < queryLimit*2+1; i++ {
+		lines = append(lines, fmt.Sprintf("line %d\n", i))
+	}
+	log := strings.Join(lines, "")
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	if err := ls.InsertLog(context.Background(), call, strings.NewReader(log)); err != nil {
+		t.Fatal(err)
+	}
+	if got := readLog(t, ls, call.FnID, call.ID); got != log {
+		t.Fatalf("expected the log to be read back across pages, got %d bytes of %d", len(got), len(log))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.streams) != 1 {
+		t.Fatalf("expected the log to be pushed at once, got %d pushes", len(f.streams))
+	}
+}
+
+func TestLokiSearch(t *testing.T) {
+	f, srv := newFakeLoki(func(r *http.Request) bool { return true })
+	defer srv.Close()
+	ls := newStore(t, "loki://"+strings.TrimPrefix(srv.URL, "http://"))
+
+	ctx := context.Background()
+	call := &models.Call{ID: id.New().String(), FnID: id.New().String()}
+	if err := ls.InsertLog(ctx, call, strings.NewReader("Hello world\nbye (world)\nhello again\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ls.SearchLog(ctx, call.FnID, call.ID, &models.LogFilter{Match: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Line != "Hello world" || entries[1].Line != "hello again" {
+		t.Fatalf("expected the lines containing the text, got %+v", entries)
+	}
+	entries, err = ls.SearchLog(ctx, call.FnID, call.ID, &models.LogFilter{Match: "(WORLD)"})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected the text to be matched literally, got %+v %v", entries, err)
+	}
+	entries, err = ls.SearchLog(ctx, call.FnID, call.ID, &models.LogFilter{Match: "missing"})
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected no lines to match, got %+v %v", entries, err)
+	}
+	if _, err := ls.SearchLog(ctx, call.FnID, id.New().String(), &models.LogFilter{Match: "hello"}); err != models.ErrCallLogNotFound {
+		t.Fatalf("expected the log of another call not to be found, got %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.streams) != 1 {
+		t.Fatalf("expected the log to be pushed once, got %d pushes", len(f.streams))
+	}
+}
+
+func TestInvalidLookback(t *testing.T) {
+	u, err := url.Parse("loki://localhost:3100?lookback=week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lokiStoreProvider(0).New(context.Background(), u); err == nil {
+		t.Fatal("expected an invalid lookback to fail")
+	}
+}
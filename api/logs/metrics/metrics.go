@@ -46,6 +46,54 @@ func (m *metricls) GetLog(ctx context.Context, appName, callID string) (io.Reade
 	return m.ls.GetLog(ctx, appName, callID)
 }
 
+func (m *metricls) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "ls_insert_result")
+	defer span.End()
+	return m.ls.InsertResult(ctx, call, result)
+}
+
+func (m *metricls) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_result")
+	defer span.End()
+	return m.ls.GetResult(ctx, fnID, callID)
+}
+
+func (m *metricls) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	ctx, span := trace.StartSpan(ctx, "ls_insert_dead_letter")
+	defer span.End()
+	return m.ls.InsertDeadLetter(ctx, call)
+}
+
+func (m *metricls) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_dead_letter")
+	defer span.End()
+	return m.ls.GetDeadLetter(ctx, fnID, callID)
+}
+
+func (m *metricls) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_get_dead_letters")
+	defer span.End()
+	return m.ls.GetDeadLetters(ctx, filter)
+}
+
+func (m *metricls) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "ls_remove_dead_letter")
+	defer span.End()
+	return m.ls.RemoveDeadLetter(ctx, fnID, callID)
+}
+
+func (m *metricls) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "ls_search_log")
+	defer span.End()
+	return m.ls.SearchLog(ctx, fnID, callID, filter)
+}
+
+func (m *metricls) RemoveCall(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "ls_remove_call")
+	defer span.End()
+	return m.ls.RemoveCall(ctx, fnID, callID)
+}
+
 func (m *metricls) Close() error {
 	return m.ls.Close()
 }
@@ -14,8 +14,11 @@ import (
 )
 
 type mock struct {
-	Logs  map[string][]byte
-	Calls []*models.Call
+	Logs    map[string][]byte
+	Results map[string][]byte
+	Calls   []*models.Call
+	// DeadLetters are kept in insertion order
+	DeadLetters []*models.Call
 }
 
 func NewMock(args ...interface{}) models.LogStore {
@@ -29,6 +32,7 @@ func NewMock(args ...interface{}) models.LogStore {
 		}
 	}
 	mocker.Logs = make(map[string][]byte)
+	mocker.Results = make(map[string][]byte)
 	return &mocker
 }
 
@@ -46,6 +50,28 @@ func (m *mock) GetLog(ctx context.Context, fnID, callID string) (io.Reader, erro
 	return bytes.NewReader(logEntry), nil
 }
 
+func (m *mock) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	log, err := m.GetLog(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	return models.FilterLog(log, filter)
+}
+
+func (m *mock) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	bytes, err := ioutil.ReadAll(result)
+	m.Results[call.ID] = bytes
+	return err
+}
+
+func (m *mock) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	result, ok := m.Results[callID]
+	if !ok {
+		return nil, models.ErrCallResultNotFound
+	}
+	return bytes.NewReader(result), nil
+}
+
 func (m *mock) InsertCall(ctx context.Context, call *models.Call) error {
 	m.Calls = append(m.Calls, call)
 	return nil
@@ -62,6 +88,82 @@ func (m *mock) GetCall(ctx context.Context, fnID, callID string) (*models.Call,
 	return nil, models.ErrCallNotFound
 }
 
+func (m *mock) RemoveCall(ctx context.Context, fnID, callID string) error {
+	calls := m.Calls[:0]
+	for _, c := range m.Calls {
+		if c.ID != callID || c.FnID != fnID {
+			calls = append(calls, c)
+		}
+	}
+	if len(calls) == len(m.Calls) {
+		return models.ErrCallNotFound
+	}
+	m.Calls = calls
+	delete(m.Logs, callID)
+	delete(m.Results, callID)
+	return nil
+}
+
+func (m *mock) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	cl := *call
+	m.DeadLetters = append(m.DeadLetters, &cl)
+	return nil
+}
+
+func (m *mock) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	for _, c := range m.DeadLetters {
+		if c.ID == callID && c.FnID == fnID {
+			cl := *c
+			return &cl, nil
+		}
+	}
+	return nil, models.ErrDeadLetterNotFound
+}
+
+func (m *mock) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	var cursor string
+	if filter.Cursor != "" {
+		s, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = string(s)
+	}
+
+	calls := []*models.Call{}
+	for i := len(m.DeadLetters) - 1; i >= 0; i-- {
+		if filter.PerPage > 0 && len(calls) == filter.PerPage {
+			break
+		}
+		c := m.DeadLetters[i]
+		if c.FnID == filter.FnID && (cursor == "" || strings.Compare(cursor, c.ID) > 0) {
+			cl := *c
+			calls = append(calls, &cl)
+		}
+	}
+
+	var nextCursor string
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		nextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+
+	return &models.CallList{
+		NextCursor: nextCursor,
+		Items:      calls,
+	}, nil
+}
+
+func (m *mock) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	for i, c := range m.DeadLetters {
+		if c.ID == callID && c.FnID == fnID {
+			m.DeadLetters = append(m.DeadLetters[:i], m.DeadLetters[i+1:]...)
+			return nil
+		}
+	}
+	return models.ErrDeadLetterNotFound
+}
+
 type sortC []*models.Call
 
 func (s sortC) Len() int           { return len(s) }
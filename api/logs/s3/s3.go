@@ -40,6 +40,8 @@ const (
 	callKeyPrefix    = "c/"
 	callMarkerPrefix = "m/"
 	logKeyPrefix     = "l/"
+	resultKeyPrefix  = "r/"
+	deadLetterPrefix = "d/"
 )
 
 type store struct {
@@ -136,6 +138,11 @@ func (s3StoreProvider) New(ctx context.Context, u *url.URL) (models.LogStore, er
 	return store, nil
 }
 
+// BinaryLogs implements compress.Binary, objects hold any bytes
+func (s *store) BinaryLogs() bool {
+	return true
+}
+
 func (s *store) InsertLog(ctx context.Context, call *models.Call, callLog io.Reader) error {
 	ctx, span := trace.StartSpan(ctx, "s3_insert_log")
 	defer span.End()
@@ -187,6 +194,64 @@ func (s *store) GetLog(ctx context.Context, appID, callID string) (io.Reader, er
 	return bytes.NewReader(target.Bytes()), nil
 }
 
+// SearchLog filters the log once downloaded, objects can't be searched
+func (s *store) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	log, err := s.GetLog(ctx, fnID, callID)
+	if err != nil {
+		return nil, err
+	}
+	return models.FilterLog(log, filter)
+}
+
+func (s *store) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	ctx, span := trace.StartSpan(ctx, "s3_insert_result")
+	defer span.End()
+
+	cr := &countingReader{r: result}
+
+	objectName := resultKey(call.FnID, call.ID)
+
+	params := &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectName),
+		Body:        cr,
+		ContentType: aws.String("application/octet-stream"),
+	}
+
+	logrus.WithFields(logrus.Fields{"bucket_name": s.bucket, "key": objectName}).Debug("Uploading result")
+	_, err := s.uploader.UploadWithContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to write result, %v", err)
+	}
+
+	stats.Record(ctx, uploadSizeMeasure.M(int64(cr.count)))
+	return nil
+}
+
+func (s *store) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	ctx, span := trace.StartSpan(ctx, "s3_get_result")
+	defer span.End()
+
+	objectName := resultKey(fnID, callID)
+	logrus.WithFields(logrus.Fields{"bucket_name": s.bucket, "key": objectName}).Debug("Downloading result")
+
+	target := &aws.WriteAtBuffer{}
+	size, err := s.downloader.DownloadWithContext(ctx, target, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, models.ErrCallResultNotFound
+		}
+		return nil, fmt.Errorf("failed to read result, %v", err)
+	}
+
+	stats.Record(ctx, downloadSizeMeasure.M(size))
+	return bytes.NewReader(target.Bytes()), nil
+}
+
 func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
 	ctx, span := trace.StartSpan(ctx, "s3_insert_call")
 	defer span.End()
@@ -272,6 +337,159 @@ func logKey(appID, callID string) string {
 	return logKeyPrefix + appID + "/" + callID
 }
 
+func resultKey(fnID, callID string) string {
+	return resultKeyPrefix + fnID + "/" + callID
+}
+
+// deadLetterKey sorts the dead letters of a fn most recent first, like callKey
+func deadLetterKey(fnID, callID string) string {
+	return deadLetterPrefix + fnID + "/" + flipCursor(callID)
+}
+
+func (s *store) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	ctx, span := trace.StartSpan(ctx, "s3_insert_dead_letter")
+	defer span.End()
+
+	byts, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+
+	objectName := deadLetterKey(call.FnID, call.ID)
+	params := &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectName),
+		Body:        bytes.NewReader(byts),
+		ContentType: aws.String("text/plain"),
+	}
+
+	logrus.WithFields(logrus.Fields{"bucket_name": s.bucket, "key": objectName}).Debug("Uploading dead letter")
+	_, err = s.uploader.UploadWithContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to insert dead letter, %v", err)
+	}
+
+	return nil
+}
+
+func (s *store) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	ctx, span := trace.StartSpan(ctx, "s3_get_dead_letter")
+	defer span.End()
+
+	call, err := s.getCallByKey(ctx, deadLetterKey(fnID, callID))
+	if err == models.ErrCallNotFound {
+		return nil, models.ErrDeadLetterNotFound
+	}
+	return call, err
+}
+
+// GetDeadLetters returns the dead letters of filter.FnID, most recent first.
+// NOTE: this relies on call ids being lexicographically sortable, see GetCalls
+func (s *store) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	ctx, span := trace.StartSpan(ctx, "s3_get_dead_letters")
+	defer span.End()
+
+	var marker string
+	if filter.Cursor != "" {
+		cursor, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		marker = deadLetterKey(filter.FnID, string(cursor))
+	}
+
+	input := &s3.ListObjectsInput{
+		Bucket:  aws.String(s.bucket),
+		MaxKeys: aws.Int64(int64(filter.PerPage)),
+		Marker:  aws.String(marker),
+		Prefix:  aws.String(deadLetterPrefix + filter.FnID + "/"),
+	}
+
+	result, err := s.client.ListObjects(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %v", err)
+	}
+
+	calls := make([]*models.Call, 0, len(result.Contents))
+	for _, obj := range result.Contents {
+		if len(calls) == filter.PerPage {
+			break
+		}
+
+		call, err := s.getCallByKey(ctx, *obj.Key)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"key": *obj.Key}).Error("error filling dead letter object")
+			continue
+		}
+		calls = append(calls, call)
+	}
+
+	callList := &models.CallList{Items: calls}
+	if len(calls) > 0 && len(calls) == filter.PerPage {
+		last := []byte(calls[len(calls)-1].ID)
+		callList.NextCursor = base64.RawURLEncoding.EncodeToString(last)
+	}
+	return callList, nil
+}
+
+func (s *store) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "s3_remove_dead_letter")
+	defer span.End()
+
+	// s3 deletes are idempotent, check the dead letter exists first
+	if _, err := s.GetDeadLetter(ctx, fnID, callID); err != nil {
+		return err
+	}
+
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(deadLetterKey(fnID, callID)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove dead letter, %v", err)
+	}
+	return nil
+}
+
+func (s *store) RemoveCall(ctx context.Context, fnID, callID string) error {
+	ctx, span := trace.StartSpan(ctx, "s3_remove_call")
+	defer span.End()
+
+	// s3 deletes are idempotent, check the call or its log exist first
+	keys := []string{callKey(fnID, callID), logKey(fnID, callID), resultKey(fnID, callID)}
+	var found bool
+	for _, key := range keys[:2] {
+		_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove call, %v", err)
+		}
+		found = true
+	}
+	if !found {
+		return models.ErrCallNotFound
+	}
+
+	objects := make([]*s3.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(key)})
+	}
+	logrus.WithFields(logrus.Fields{"bucket_name": s.bucket, "call_id": callID}).Debug("Removing call")
+	_, err := s.client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove call, %v", err)
+	}
+	return nil
+}
+
 // GetCalls1 returns a list of calls that satisfy the given CallFilter. If no
 // calls exist, an empty list and a nil error are returned.
 
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -165,6 +168,152 @@ func Test(t *testing.T, fnl models.LogStore) {
 		}
 	})
 
+	t.Run("call-log-search", func(t *testing.T) {
+		call.ID = id.New().String()
+		now := time.Now()
+		var log bytes.Buffer
+		for i, line := range []string{"starting", `{"level":"error","msg":"100% Failed"}`, "done"} {
+			stream := models.LogStreamStdout
+			if i == 1 {
+				stream = models.LogStreamStderr
+			}
+			b, err := json.Marshal(models.NewLogEntry(now.Add(time.Duration(i)*time.Second), stream, []byte(line)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			log.Write(append(b, '\n'))
+		}
+		if err := fnl.InsertLog(ctx, call, &log); err != nil {
+			t.Fatalf("Test SearchLog: unexpected error inserting log `%v`", err)
+		}
+
+		for i, test := range []struct {
+			filter   models.LogFilter
+			expected []string
+		}{
+			{models.LogFilter{}, []string{"starting", `{"level":"error","msg":"100% Failed"}`, "done"}},
+			{models.LogFilter{Match: "100% FAILED"}, []string{`{"level":"error","msg":"100% Failed"}`}},
+			{models.LogFilter{Match: "0_%"}, nil},
+			{models.LogFilter{Severity: "warn"}, []string{`{"level":"error","msg":"100% Failed"}`}},
+			{models.LogFilter{Severity: "fatal"}, nil},
+			{models.LogFilter{FromTime: common.DateTime(now.Add(time.Second / 2))}, []string{`{"level":"error","msg":"100% Failed"}`, "done"}},
+			{models.LogFilter{ToTime: common.DateTime(now.Add(time.Second / 2)), Match: "start"}, []string{"starting"}},
+		} {
+			entries, err := fnl.SearchLog(ctx, call.FnID, call.ID, &test.filter)
+			if err != nil {
+				t.Fatalf("Test SearchLog %d: unexpected error `%v`", i, err)
+			}
+			var lines []string
+			for _, e := range entries {
+				lines = append(lines, e.Line)
+			}
+			if strings.Join(lines, "|") != strings.Join(test.expected, "|") {
+				t.Fatalf("Test SearchLog %d: expected lines %q, got %q", i, test.expected, lines)
+			}
+		}
+
+		if _, err := fnl.SearchLog(ctx, call.FnID, id.New().String(), &models.LogFilter{Match: "done"}); err != models.ErrCallLogNotFound {
+			t.Fatal("SearchLog should return not found, but got:", err)
+		}
+	})
+
+	t.Run("call-result-insert-get", func(t *testing.T) {
+		call.ID = id.New().String()
+		resultText := "the result"
+		err := fnl.InsertResult(ctx, call, strings.NewReader(resultText))
+		if err != nil {
+			t.Fatalf("Test InsertResult(ctx, call, result): unexpected error during inserting result `%v`", err)
+		}
+		result, err := fnl.GetResult(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatalf("Test GetResult(ctx, call.FnID, call.ID): unexpected error `%v`", err)
+		}
+		var b bytes.Buffer
+		io.Copy(&b, result)
+		if b.String() != resultText {
+			t.Fatalf("Test GetResult(ctx, call.FnID, call.ID): result mismatch. "+
+				"Expected: `%v`. Got `%v`.", resultText, b.String())
+		}
+	})
+
+	t.Run("call-result-binary", func(t *testing.T) {
+		call.ID = id.New().String()
+		resultBytes := []byte{0x1f, 0x8b, 0x00, 0xff, 0xfe, '\n'}
+		if err := fnl.InsertResult(ctx, call, bytes.NewReader(resultBytes)); err != nil {
+			t.Fatalf("Test InsertResult(ctx, call, result): unexpected error during inserting result `%v`", err)
+		}
+		result, err := fnl.GetResult(ctx, call.FnID, call.ID)
+		if err != nil {
+			t.Fatalf("Test GetResult(ctx, call.FnID, call.ID): unexpected error `%v`", err)
+		}
+		if b, _ := ioutil.ReadAll(result); !bytes.Equal(b, resultBytes) {
+			t.Fatalf("Test GetResult(ctx, call.FnID, call.ID): result mismatch. "+
+				"Expected: `%v`. Got `%v`.", resultBytes, b)
+		}
+	})
+
+	t.Run("call-result-not-found", func(t *testing.T) {
+		call.ID = id.New().String()
+		_, err := fnl.GetResult(ctx, call.FnID, call.ID)
+		if err != models.ErrCallResultNotFound {
+			t.Fatal("GetResult should return not found, but got:", err)
+		}
+	})
+
+	t.Run("dead-letters", func(t *testing.T) {
+		fnID := id.New().String()
+		var ids []string
+		for i := 0; i < 3; i++ {
+			dead := &models.Call{
+				ID:          id.New().String(),
+				AppID:       testApp.ID,
+				FnID:        fnID,
+				Status:      "error",
+				Error:       "ya dun goofed",
+				Payload:     `{"attempt":` + strconv.Itoa(i) + `}`,
+				Attempt:     3,
+				CompletedAt: common.DateTime(time.Now()),
+			}
+			if err := fnl.InsertDeadLetter(ctx, dead); err != nil {
+				t.Fatalf("Test InsertDeadLetter(ctx, call): unexpected error `%v`", err)
+			}
+			ids = append(ids, dead.ID)
+		}
+
+		got, err := fnl.GetDeadLetter(ctx, fnID, ids[0])
+		if err != nil {
+			t.Fatalf("Test GetDeadLetter(ctx, fnID, callID): unexpected error `%v`", err)
+		}
+		if got.Payload != `{"attempt":0}` || got.Attempt != 3 || got.Error != "ya dun goofed" {
+			t.Fatalf("Test GetDeadLetter(ctx, fnID, callID): call metadata mismatch %#v", got)
+		}
+
+		page, err := fnl.GetDeadLetters(ctx, &models.CallFilter{FnID: fnID, PerPage: 2})
+		if err != nil {
+			t.Fatalf("Test GetDeadLetters(ctx, filter): unexpected error `%v`", err)
+		}
+		if len(page.Items) != 2 || page.Items[0].ID != ids[2] || page.Items[1].ID != ids[1] {
+			t.Fatalf("Test GetDeadLetters(ctx, filter): expected most recent first, got %#v", page.Items)
+		}
+		page, err = fnl.GetDeadLetters(ctx, &models.CallFilter{FnID: fnID, PerPage: 2, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatalf("Test GetDeadLetters(ctx, filter): unexpected error `%v`", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].ID != ids[0] {
+			t.Fatalf("Test GetDeadLetters(ctx, filter): expected last dead letter on second page, got %#v", page.Items)
+		}
+
+		if err := fnl.RemoveDeadLetter(ctx, fnID, ids[0]); err != nil {
+			t.Fatalf("Test RemoveDeadLetter(ctx, fnID, callID): unexpected error `%v`", err)
+		}
+		if _, err := fnl.GetDeadLetter(ctx, fnID, ids[0]); err != models.ErrDeadLetterNotFound {
+			t.Fatal("GetDeadLetter should return not found after remove, but got:", err)
+		}
+		if err := fnl.RemoveDeadLetter(ctx, fnID, ids[0]); err != models.ErrDeadLetterNotFound {
+			t.Fatal("RemoveDeadLetter should return not found, but got:", err)
+		}
+	})
+
 	call = new(models.Call)
 	call.CreatedAt = common.DateTime(time.Now())
 	call.Status = "error"
@@ -173,6 +322,10 @@ func Test(t *testing.T, fnl models.LogStore) {
 	call.CompletedAt = common.DateTime(time.Now())
 	call.AppID = testApp.ID
 	call.FnID = testFn.ID
+	call.RequestID = "req-" + id.New().String()
+	call.LogDropped = 1024
+	call.LogSize = 4096
+	call.LogUnavailable = models.LogUnavailableConcurrent
 
 	t.Run("call-insert", func(t *testing.T) {
 		call.ID = id.New().String()
@@ -213,5 +366,49 @@ func Test(t *testing.T, fnl models.LogStore) {
 		if call.AppID != newCall.AppID {
 			t.Fatalf("Test GetCall: fn id mismatch `%v` `%v`", call.FnID, newCall.FnID)
 		}
+		if call.RequestID != newCall.RequestID {
+			t.Fatalf("Test GetCall: request id mismatch `%v` `%v`", call.RequestID, newCall.RequestID)
+		}
+		if call.LogDropped != newCall.LogDropped {
+			t.Fatalf("Test GetCall: log dropped mismatch `%v` `%v`", call.LogDropped, newCall.LogDropped)
+		}
+		if call.LogSize != newCall.LogSize {
+			t.Fatalf("Test GetCall: log size mismatch `%v` `%v`", call.LogSize, newCall.LogSize)
+		}
+		if call.LogUnavailable != newCall.LogUnavailable {
+			t.Fatalf("Test GetCall: log unavailable mismatch `%v` `%v`", call.LogUnavailable, newCall.LogUnavailable)
+		}
+	})
+
+	t.Run("call-remove", func(t *testing.T) {
+		call.ID = id.New().String()
+		if err := fnl.InsertCall(ctx, call); err != nil {
+			t.Fatalf("Test RemoveCall: unexpected error `%v`", err)
+		}
+		if err := fnl.InsertLog(ctx, call, strings.NewReader("test")); err != nil {
+			t.Fatalf("Test RemoveCall: unexpected error `%v`", err)
+		}
+		if err := fnl.InsertResult(ctx, call, strings.NewReader("the result")); err != nil {
+			t.Fatalf("Test RemoveCall: unexpected error `%v`", err)
+		}
+
+		if err := fnl.RemoveCall(ctx, id.New().String(), call.ID); err != models.ErrCallNotFound {
+			t.Fatalf("Test RemoveCall: expected the call of another fn not to be found, got `%v`", err)
+		}
+		if err := fnl.RemoveCall(ctx, call.FnID, call.ID); err != nil {
+			t.Fatalf("Test RemoveCall: unexpected error `%v`", err)
+		}
+		if _, err := fnl.GetCall(ctx, call.FnID, call.ID); err != models.ErrCallNotFound {
+			t.Fatalf("Test RemoveCall: expected the call to be removed, got `%v`", err)
+		}
+		if _, err := fnl.GetLog(ctx, call.FnID, call.ID); err != models.ErrCallLogNotFound {
+			t.Fatalf("Test RemoveCall: expected the log to be removed, got `%v`", err)
+		}
+		if _, err := fnl.GetResult(ctx, call.FnID, call.ID); err != models.ErrCallResultNotFound {
+			t.Fatalf("Test RemoveCall: expected the result to be removed, got `%v`", err)
+		}
+		if err := fnl.RemoveCall(ctx, call.FnID, call.ID); err != models.ErrCallNotFound {
+			t.Fatalf("Test RemoveCall: expected the removed call not to be found, got `%v`", err)
+		}
 	})
 }
@@ -37,6 +37,97 @@ func (v *validator) GetLog(ctx context.Context, fnID, callID string) (io.Reader,
 	return v.LogStore.GetLog(ctx, fnID, callID)
 }
 
+// callID or appID will never be empty.
+func (v *validator) InsertResult(ctx context.Context, call *models.Call, result io.Reader) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrMissingFnID
+	}
+	return v.LogStore.InsertResult(ctx, call, result)
+}
+
+// callID or appID will never be empty.
+func (v *validator) GetResult(ctx context.Context, fnID, callID string) (io.Reader, error) {
+	if callID == "" {
+		return nil, models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	return v.LogStore.GetResult(ctx, fnID, callID)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) InsertDeadLetter(ctx context.Context, call *models.Call) error {
+	if call.ID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if call.FnID == "" {
+		return models.ErrMissingFnID
+	}
+	return v.LogStore.InsertDeadLetter(ctx, call)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) GetDeadLetter(ctx context.Context, fnID, callID string) (*models.Call, error) {
+	if callID == "" {
+		return nil, models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	return v.LogStore.GetDeadLetter(ctx, fnID, callID)
+}
+
+// fnID will never be empty.
+func (v *validator) GetDeadLetters(ctx context.Context, filter *models.CallFilter) (*models.CallList, error) {
+	if filter.FnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	return v.LogStore.GetDeadLetters(ctx, filter)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) RemoveDeadLetter(ctx context.Context, fnID, callID string) error {
+	if callID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return models.ErrMissingFnID
+	}
+	return v.LogStore.RemoveDeadLetter(ctx, fnID, callID)
+}
+
+// callID or fnID will never be empty.
+func (v *validator) RemoveCall(ctx context.Context, fnID, callID string) error {
+	if callID == "" {
+		return models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return models.ErrMissingFnID
+	}
+	return v.LogStore.RemoveCall(ctx, fnID, callID)
+}
+
+// callID or fnID will never be empty, filter will never be nil.
+func (v *validator) SearchLog(ctx context.Context, fnID, callID string, filter *models.LogFilter) ([]*models.LogEntry, error) {
+	if callID == "" {
+		return nil, models.ErrDatastoreEmptyCallID
+	}
+	if fnID == "" {
+		return nil, models.ErrMissingFnID
+	}
+	if filter == nil {
+		filter = &models.LogFilter{}
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return v.LogStore.SearchLog(ctx, fnID, callID, filter)
+}
+
 // callID or appID will never be empty.
 func (v *validator) InsertCall(ctx context.Context, call *models.Call) error {
 	if call.ID == "" {
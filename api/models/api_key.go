@@ -0,0 +1,152 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Scopes of API keys, the classes of operations a key allows
+const (
+	// APIKeyScopeInvoke keys may only invoke functions
+	APIKeyScopeInvoke = "invoke"
+	// APIKeyScopeManage keys may manage resources, and invoke functions
+	APIKeyScopeManage = "manage"
+)
+
+// APIKeyPrefix starts the secret of every API key, to tell keys from other bearer tokens
+const APIKeyPrefix = "fnk_"
+
+// APIKey authenticates machine clients, which send its secret as a bearer token. A key
+// allows the operations of its scope, on the resources of its app if it has one.
+type APIKey struct {
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// AppID is the app the key is restricted to, the key is valid for all apps if empty.
+	AppID string `json:"app_id,omitempty" db:"app_id"`
+	Scope string `json:"scope" db:"scope"`
+	// RateLimit limits the calls invoked with the key, calls are not limited if nil.
+	RateLimit *RateLimit `json:"rate_limit,omitempty" db:"rate_limit"`
+	// Hash is the hash of the secret of the key, the secret itself is never stored.
+	Hash string `json:"-" db:"hash"`
+	// Secret is only known when the key is created, it can't be retrieved later on.
+	Secret    string          `json:"secret,omitempty" db:"-"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+var (
+	//ErrDatastoreEmptyAPIKey - no API key given to the datastore
+	ErrDatastoreEmptyAPIKey = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing API key"),
+	}
+	//ErrAPIKeyIDProvided indicates that an API key ID was specified when it shouldn't have been
+	ErrAPIKeyIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for API key creation"),
+	}
+	//ErrAPIKeyMissingName - name not specified on an API key
+	ErrAPIKeyMissingName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing name on API key")}
+	//ErrAPIKeyTooLongName - name exceeds maximum permitted name
+	ErrAPIKeyTooLongName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("API key name must be %v characters or less", MaxAPIKeyName)}
+	//ErrAPIKeyInvalidName - name does not comply with naming spec
+	ErrAPIKeyInvalidName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid name for API key")}
+	//ErrAPIKeyInvalidScope - scope is not one of the API key scopes
+	ErrAPIKeyInvalidScope = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid scope for API key, must be %s or %s", APIKeyScopeInvoke, APIKeyScopeManage)}
+	//ErrAPIKeyMissingHash - the secret of an API key was not hashed before storing it
+	ErrAPIKeyMissingHash = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Missing hash of API key")}
+	//ErrAPIKeyNotFound - API key not found
+	ErrAPIKeyNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("API key not found")}
+	//ErrAPIKeyForbidden - the API key of the request does not allow its operation
+	ErrAPIKeyForbidden = err{
+		code:  http.StatusForbidden,
+		error: errors.New("API key does not allow this operation")}
+)
+
+// NewAPIKeySecret returns a new random secret for an API key.
+func NewAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKeySecret returns the hash of the secret of an API key, keys are looked up by it.
+// Secrets are random and long, so a fast hash is enough to not keep them.
+func HashAPIKeySecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// Validate checks that key has valid data for inserting into a store
+func (k *APIKey) Validate() error {
+	if k.Name == "" {
+		return ErrAPIKeyMissingName
+	}
+	if len(k.Name) > MaxAPIKeyName {
+		return ErrAPIKeyTooLongName
+	}
+	for _, c := range k.Name {
+		if !(unicode.IsLetter(c) || unicode.IsNumber(c) || c == '_' || c == '-') {
+			return ErrAPIKeyInvalidName
+		}
+	}
+
+	if k.Scope != APIKeyScopeInvoke && k.Scope != APIKeyScopeManage {
+		return ErrAPIKeyInvalidScope
+	}
+	if k.RateLimit != nil {
+		if err := k.RateLimit.Validate(); err != nil {
+			return err
+		}
+	}
+	if k.Hash == "" {
+		return ErrAPIKeyMissingHash
+	}
+	return nil
+}
+
+// AllowsScope returns true if the key allows the operations of scope, manage keys allow all.
+func (k *APIKey) AllowsScope(scope string) bool {
+	return k.Scope == APIKeyScopeManage || k.Scope == scope
+}
+
+// AllowsApp returns true if the key allows operations on the resources of app appID.
+func (k *APIKey) AllowsApp(appID string) bool {
+	return k.AppID == "" || k.AppID == appID
+}
+
+// APIKeyFilter is a search criteria on API keys, in creation order
+type APIKeyFilter struct {
+	//AppID searches for the keys restricted to an app
+	AppID string // this is exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// APIKeyList is a container of API keys returned by search, optionally indicating the next page cursor
+type APIKeyList struct {
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Items      []*APIKey `json:"items"`
+}
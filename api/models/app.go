@@ -64,6 +64,8 @@ type App struct {
 	SyslogURL   *string         `json:"syslog_url,omitempty" db:"syslog_url"`
 	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
 	UpdatedAt   common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+	// DeletedAt is set on apps marked deleted, which may be restored until they are purged.
+	DeletedAt *common.DateTime `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 func (a *App) Validate() error {
@@ -84,6 +86,42 @@ func (a *App) Validate() error {
 		return err
 	}
 
+	if _, err := PriorityFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, _, err := SizeLimitsFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := MaxLogSizeFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SharedPoolFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := RateLimitFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := UsageQuotaFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := LogRetentionFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SyslogFormatFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := LogRedactionFromAnnotations(a.Annotations); err != nil {
+		return err
+	}
+
 	if a.SyslogURL != nil && *a.SyslogURL != "" {
 		url, err := url.Parse(strings.TrimSpace(*a.SyslogURL))
 		if err == nil {
@@ -191,9 +229,18 @@ func (e ErrInvalidSyslog) Error() string { return string(e) }
 
 // AppFilter is the filter used for querying apps
 type AppFilter struct {
-	Name    string
-	PerPage int
-	Cursor  string
+	Name string
+	// NamePrefix lists the apps whose name starts with it
+	NamePrefix string
+	PerPage    int
+	Cursor     string
+	// Order is the order of the list and its cursors, by name when zero
+	Order ListOrder
+	// Deleted lists the apps marked deleted in place of the live ones
+	Deleted bool
+	// Annotations and Config list the apps matching all of their key filters
+	Annotations []KeyFilter
+	Config      []KeyFilter
 }
 
 type AppList struct {
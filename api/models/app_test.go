@@ -38,6 +38,13 @@ func datetimeGenerator() gopter.Gen {
 	})
 }
 
+func deletedAtGenerator() gopter.Gen {
+	return gen.Time().Map(func(t time.Time) *common.DateTime {
+		d := common.DateTime(t)
+		return &d
+	})
+}
+
 func appFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	fieldGens := make(map[string]gopter.Gen)
 	fieldGens["ID"] = gen.AlphaString()
@@ -49,6 +56,7 @@ func appFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	})
 	fieldGens["CreatedAt"] = datetimeGenerator()
 	fieldGens["UpdatedAt"] = datetimeGenerator()
+	fieldGens["DeletedAt"] = deletedAtGenerator()
 
 	appFieldCount := appReflectType().NumField()
 
@@ -81,6 +89,14 @@ func novelValue(t *testing.T, originalInstance reflect.Value, fieldName string,
 			if !newValue.(Config).Equals(currentValue.(Config)) {
 				break
 			}
+		} else if fieldName == "RequestSchema" {
+			if !newValue.(RequestSchema).Equals(currentValue.(RequestSchema)) {
+				break
+			}
+		} else if fieldName == "ResponseSchema" {
+			if !newValue.(ResponseSchema).Equals(currentValue.(ResponseSchema)) {
+				break
+			}
 		} else {
 			if newValue != currentValue {
 				break
@@ -123,7 +139,8 @@ func TestAppEquality(t *testing.T) {
 			for fieldName, fieldGen := range appFieldGens {
 
 				if fieldName == "CreatedAt" ||
-					fieldName == "UpdatedAt" {
+					fieldName == "UpdatedAt" ||
+					fieldName == "DeletedAt" {
 					continue
 				}
 
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Actions of the changes made to apply a bundle
+const (
+	ApplyCreate    = "create"
+	ApplyUpdate    = "update"
+	ApplyDelete    = "delete"
+	ApplyUnchanged = "unchanged"
+)
+
+// Kinds of the objects changed to apply a bundle
+const (
+	ApplyKindApp     = "app"
+	ApplyKindFn      = "fn"
+	ApplyKindTrigger = "trigger"
+)
+
+var (
+	ErrApplyMissingApp = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing app in bundle"),
+	}
+)
+
+// ErrApplyDuplicateName is returned for bundles declaring two fns, or two triggers, with the
+// same name.
+type ErrApplyDuplicateName string
+
+func (e ErrApplyDuplicateName) Code() int     { return http.StatusBadRequest }
+func (e ErrApplyDuplicateName) Error() string { return string(e) }
+
+// ApplyBundle declares the desired state of an app: the app, all of its fns and all of their
+// triggers. Objects are matched to existing ones by name, fns and triggers of the app missing
+// from the bundle are deleted.
+type ApplyBundle struct {
+	App *App       `json:"app"`
+	Fns []*ApplyFn `json:"fns,omitempty"`
+}
+
+// ApplyFn is a fn of a bundle along with its triggers. App ids and fn ids are set when the
+// bundle is applied.
+type ApplyFn struct {
+	Fn
+	Triggers []*Trigger `json:"triggers,omitempty"`
+}
+
+// ApplyChange is a change made, or to be made on a dry run, to apply a bundle.
+type ApplyChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	Action string `json:"action"`
+	// Fn is the name of the fn of a trigger
+	Fn string `json:"fn,omitempty"`
+}
+
+// ApplyReport lists the changes made to apply a bundle, in the order they were made.
+type ApplyReport struct {
+	App     *App          `json:"app"`
+	Changes []ApplyChange `json:"changes"`
+	DryRun  bool          `json:"dry_run,omitempty"`
+}
+
+// Validate checks that the bundle declares an app and that fn and trigger names are unique,
+// objects are validated as they are applied.
+func (b *ApplyBundle) Validate() error {
+	if b.App == nil {
+		return ErrApplyMissingApp
+	}
+	if b.App.Name == "" {
+		return ErrMissingName
+	}
+
+	fns := make(map[string]bool, len(b.Fns))
+	triggers := make(map[string]bool)
+	for _, fn := range b.Fns {
+		if fn == nil || fn.Name == "" {
+			return ErrFnsMissingName
+		}
+		if fns[fn.Name] {
+			return ErrApplyDuplicateName(fmt.Sprintf("fn %s is declared more than once", fn.Name))
+		}
+		fns[fn.Name] = true
+
+		for _, t := range fn.Triggers {
+			if t == nil || t.Name == "" {
+				return ErrTriggerMissingName
+			}
+			if triggers[t.Name] {
+				return ErrApplyDuplicateName(fmt.Sprintf("trigger %s is declared more than once", t.Name))
+			}
+			triggers[t.Name] = true
+		}
+	}
+	return nil
+}
+
+// configPatch returns the config patch turning c into desired, removed keys are set empty.
+func configPatch(c, desired Config) Config {
+	patch := make(Config, len(desired))
+	for k := range c {
+		if _, ok := desired[k]; !ok {
+			patch[k] = ""
+		}
+	}
+	for k, v := range desired {
+		patch[k] = v
+	}
+	return patch
+}
+
+// annotationsPatch returns the annotations change turning m into desired, removed keys are
+// set empty, see MergeChange.
+func annotationsPatch(m, desired Annotations) Annotations {
+	patch := make(Annotations, len(desired))
+	for k := range m {
+		if _, ok := desired[k]; !ok {
+			empty := annotationValue(`""`)
+			patch[k] = &empty
+		}
+	}
+	for k, v := range desired {
+		patch[k] = v
+	}
+	return patch
+}
+
+// ReplacePatch returns the patch which, passed to Update, turns the app into desired.
+func (a *App) ReplacePatch(desired *App) *App {
+	patch := &App{
+		ID:          a.ID,
+		Name:        a.Name,
+		Config:      configPatch(a.Config, desired.Config),
+		Annotations: annotationsPatch(a.Annotations, desired.Annotations),
+		SyslogURL:   desired.SyslogURL,
+	}
+	if patch.SyslogURL == nil {
+		patch.SyslogURL = new(string)
+	}
+	return patch
+}
+
+// ReplacePatch returns the patch which, passed to Update, turns the fn into desired, leaving
+// its id, name and app unchanged.
+func (f *Fn) ReplacePatch(desired *Fn) *Fn {
+	patch := desired.Clone()
+	patch.ID = f.ID
+	patch.Name = f.Name
+	patch.AppID = f.AppID
+	patch.CreatedAt = f.CreatedAt
+	patch.UpdatedAt = f.UpdatedAt
+	patch.Config = configPatch(f.Config, desired.Config)
+	patch.Annotations = annotationsPatch(f.Annotations, desired.Annotations)
+
+	var zero uint64
+	if patch.MinReady == nil {
+		patch.MinReady = &zero
+	}
+	if patch.MaxContainers == nil {
+		patch.MaxContainers = &zero
+	}
+	if patch.ScalePolicy == nil {
+		patch.ScalePolicy = &ScalePolicy{}
+	}
+	if patch.RetryPolicy == nil {
+		patch.RetryPolicy = &RetryPolicy{}
+	}
+	if patch.RecyclePolicy == nil {
+		patch.RecyclePolicy = &RecyclePolicy{}
+	}
+	if patch.RequestSchema == nil {
+		patch.RequestSchema = RequestSchema("{}")
+	}
+	if patch.ResponseSchema == nil {
+		patch.ResponseSchema = ResponseSchema("{}")
+	}
+	return patch
+}
+
+// ReplacePatch returns the patch which, passed to Update, turns the trigger into desired. The
+// type of a trigger cannot be updated.
+func (t *Trigger) ReplacePatch(desired *Trigger) *Trigger {
+	patch := desired.Clone()
+	patch.ID = t.ID
+	patch.AppID = t.AppID
+	patch.Type = t.Type
+	patch.CreatedAt = t.CreatedAt
+	patch.UpdatedAt = t.UpdatedAt
+	patch.Annotations = annotationsPatch(t.Annotations, desired.Annotations)
+	return patch
+}
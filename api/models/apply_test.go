@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestApplyBundleValidate(t *testing.T) {
+	for i, test := range []struct {
+		bundle *ApplyBundle
+		err    error
+	}{
+		{&ApplyBundle{}, ErrApplyMissingApp},
+		{&ApplyBundle{App: &App{}}, ErrMissingName},
+		{&ApplyBundle{App: &App{Name: "app"}}, nil},
+		{&ApplyBundle{App: &App{Name: "app"}, Fns: []*ApplyFn{{}}}, ErrFnsMissingName},
+		{&ApplyBundle{App: &App{Name: "app"}, Fns: []*ApplyFn{{Fn: Fn{Name: "fn"}}, {Fn: Fn{Name: "fn"}}}}, ErrApplyDuplicateName("")},
+		{&ApplyBundle{App: &App{Name: "app"}, Fns: []*ApplyFn{
+			{Fn: Fn{Name: "fn"}, Triggers: []*Trigger{{}}},
+		}}, ErrTriggerMissingName},
+		{&ApplyBundle{App: &App{Name: "app"}, Fns: []*ApplyFn{
+			{Fn: Fn{Name: "fn"}, Triggers: []*Trigger{{Name: "t"}}},
+			{Fn: Fn{Name: "fn2"}, Triggers: []*Trigger{{Name: "t"}}},
+		}}, ErrApplyDuplicateName("")},
+	} {
+		err := test.bundle.Validate()
+		if _, dup := test.err.(ErrApplyDuplicateName); dup {
+			if _, ok := err.(ErrApplyDuplicateName); !ok {
+				t.Errorf("test %d: expected duplicate name error, got %v", i, err)
+			}
+			continue
+		}
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestReplacePatch(t *testing.T) {
+	minReady := uint64(2)
+	annotations, _ := EmptyAnnotations().With("a", "b")
+	fn := &Fn{
+		ID:          "fn",
+		Name:        "fn",
+		AppID:       "app",
+		Image:       "image",
+		MinReady:    &minReady,
+		ScalePolicy: &ScalePolicy{FreezeDelay: 10},
+		Config:      Config{"A": "a", "B": "b"},
+		Annotations: annotations,
+	}
+	fn.SetDefaults()
+
+	desired := &Fn{Name: "fn", Image: "image2", Config: Config{"B": "c"}}
+	desired.SetDefaults()
+
+	updated := fn.Clone()
+	updated.Update(fn.ReplacePatch(desired))
+	if updated.ID != "fn" || updated.AppID != "app" || updated.Image != "image2" {
+		t.Fatalf("unexpected fn %+v", updated)
+	}
+	if updated.MinReady != nil || updated.ScalePolicy != nil || len(updated.Annotations) != 0 {
+		t.Fatalf("expected unset fields to be removed, got %+v", updated)
+	}
+	if !updated.Config.Equals(Config{"B": "c"}) {
+		t.Fatalf("unexpected config %v", updated.Config)
+	}
+
+	// replacing with the same fn changes nothing
+	same := fn.Clone()
+	same.Update(fn.ReplacePatch(fn))
+	if !same.Equals(fn) {
+		t.Fatalf("expected fn to be unchanged, got %+v", same)
+	}
+
+	syslog := "tcp://logs"
+	app := &App{ID: "app", Name: "app", SyslogURL: &syslog, Config: Config{"A": "a"}}
+	updatedApp := app.Clone()
+	updatedApp.Update(app.ReplacePatch(&App{Name: "app"}))
+	if updatedApp.SyslogURL != nil || len(updatedApp.Config) != 0 {
+		t.Fatalf("expected app fields to be removed, got %+v", updatedApp)
+	}
+
+	trigger := &Trigger{ID: "t", Name: "t", AppID: "app", FnID: "fn", Type: "http", Source: "/a"}
+	updatedTrigger := trigger.Clone()
+	updatedTrigger.Update(trigger.ReplacePatch(&Trigger{Name: "t", FnID: "fn2", Type: "http", Source: "/b"}))
+	if updatedTrigger.ID != "t" || updatedTrigger.FnID != "fn2" || updatedTrigger.Source != "/b" {
+		t.Fatalf("unexpected trigger %+v", updatedTrigger)
+	}
+}
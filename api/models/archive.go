@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// ArchiveVersion is the version of the archives exported, archives of other versions cannot
+// be imported
+const ArchiveVersion = 1
+
+// Strategies of imports for the resources of an archive which exist already
+const (
+	// ImportSkip leaves existing resources as they are
+	ImportSkip = "skip"
+	// ImportOverwrite replaces existing resources with the ones of the archive
+	ImportOverwrite = "overwrite"
+)
+
+// ImportSkipped is the action of the changes of imports leaving existing resources as they are
+const ImportSkipped = "skipped"
+
+var (
+	ErrArchiveVersion = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Unsupported archive version, the supported version is %d", ArchiveVersion),
+	}
+	ErrImportInvalidStrategy = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid import strategy, expected %s or %s", ImportSkip, ImportOverwrite),
+	}
+	ErrArchiveMissingID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing id of an app, fn or trigger of the archive"),
+	}
+)
+
+// ErrArchiveInvalidResource is returned for archives with duplicate resources, or with
+// resources referring to ones missing from the archive.
+type ErrArchiveInvalidResource string
+
+func (e ErrArchiveInvalidResource) Code() int     { return http.StatusBadRequest }
+func (e ErrArchiveInvalidResource) Error() string { return string(e) }
+
+// Archive is a portable copy of apps, with their fns, their triggers and optionally their
+// calls, exported to back them up or to promote them to another cluster. Imported resources
+// are matched to existing ones by name, ids only refer to resources within the archive.
+type Archive struct {
+	Version   int             `json:"version"`
+	CreatedAt common.DateTime `json:"created_at"`
+	Apps      []*App          `json:"apps"`
+	Fns       []*Fn           `json:"fns"`
+	Triggers  []*Trigger      `json:"triggers"`
+	Calls     []*Call         `json:"calls,omitempty"`
+}
+
+// ImportReport lists the changes made to import an archive, in the order they were made.
+type ImportReport struct {
+	Strategy string        `json:"strategy"`
+	Changes  []ApplyChange `json:"changes"`
+	// Calls is the number of calls imported, calls which exist already are skipped
+	Calls int `json:"calls"`
+}
+
+// Validate checks that the resources of the archive are named, unique and refer to resources
+// of the archive, resources are validated as they are imported.
+func (a *Archive) Validate() error {
+	if a.Version != ArchiveVersion {
+		return ErrArchiveVersion
+	}
+
+	apps := make(map[string]bool, len(a.Apps))
+	appNames := make(map[string]bool, len(a.Apps))
+	for _, app := range a.Apps {
+		if app == nil || app.Name == "" {
+			return ErrMissingName
+		}
+		if app.ID == "" {
+			return ErrArchiveMissingID
+		}
+		if apps[app.ID] || appNames[app.Name] {
+			return ErrArchiveInvalidResource(fmt.Sprintf("app %s is archived more than once", app.Name))
+		}
+		apps[app.ID], appNames[app.Name] = true, true
+	}
+
+	fns := make(map[string]string, len(a.Fns)) // app ids by fn id
+	fnNames := make(map[string]bool, len(a.Fns))
+	for _, fn := range a.Fns {
+		if fn == nil || fn.Name == "" {
+			return ErrFnsMissingName
+		}
+		if fn.ID == "" {
+			return ErrArchiveMissingID
+		}
+		if !apps[fn.AppID] {
+			return ErrArchiveInvalidResource(fmt.Sprintf("app %s of fn %s is missing from the archive", fn.AppID, fn.Name))
+		}
+		if _, ok := fns[fn.ID]; ok || fnNames[fn.AppID+"/"+fn.Name] {
+			return ErrArchiveInvalidResource(fmt.Sprintf("fn %s is archived more than once", fn.Name))
+		}
+		fns[fn.ID], fnNames[fn.AppID+"/"+fn.Name] = fn.AppID, true
+	}
+
+	triggerNames := make(map[string]bool, len(a.Triggers))
+	for _, t := range a.Triggers {
+		if t == nil || t.Name == "" {
+			return ErrTriggerMissingName
+		}
+		if appID, ok := fns[t.FnID]; !ok || appID != t.AppID {
+			return ErrArchiveInvalidResource(fmt.Sprintf("fn %s of trigger %s is missing from the archive", t.FnID, t.Name))
+		}
+		if triggerNames[t.AppID+"/"+t.Name] {
+			return ErrArchiveInvalidResource(fmt.Sprintf("trigger %s is archived more than once", t.Name))
+		}
+		triggerNames[t.AppID+"/"+t.Name] = true
+	}
+
+	for _, call := range a.Calls {
+		if call == nil || call.ID == "" {
+			return ErrArchiveMissingID
+		}
+		if _, ok := fns[call.FnID]; !ok {
+			return ErrArchiveInvalidResource(fmt.Sprintf("fn %s of call %s is missing from the archive", call.FnID, call.ID))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Actions recorded in the audit trail
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	// AuditActionRestore is recorded when an app or function marked deleted is restored
+	AuditActionRestore = "restore"
+)
+
+// Types of the resources of the management API recorded in the audit trail
+const (
+	AuditResourceApp         = "app"
+	AuditResourceFn          = "fn"
+	AuditResourceTrigger     = "trigger"
+	AuditResourceSchedule    = "schedule"
+	AuditResourceAPIKey      = "apikey"
+	AuditResourceRoleBinding = "rolebinding"
+	AuditResourceDomain      = "domain"
+	AuditResourceWebhook     = "webhook"
+)
+
+// AuditRedacted replaces the values of secrets in the diffs of audit entries
+const AuditRedacted = "[REDACTED]"
+
+// fields of resources which are left out of diffs, or which values are redacted
+var (
+	auditIgnoredFields  = map[string]bool{"updated_at": true}
+	auditRedactedFields = map[string]bool{"secret": true}
+)
+
+// AuditEntry records a change made to a resource through the management API. Entries are
+// only ever appended, they are kept when the resource or its app is deleted.
+type AuditEntry struct {
+	ID string `json:"id" db:"id"`
+	// Action is create, update or delete
+	Action       string `json:"action" db:"action"`
+	ResourceType string `json:"resource_type" db:"resource_type"`
+	ResourceID   string `json:"resource_id" db:"resource_id"`
+	// AppID is the app of the resource, if any
+	AppID string `json:"app_id,omitempty" db:"app_id"`
+	// Actor is who made the change, the subject of the identity the request was authenticated
+	// with, or the identity set by a proxy in front of fn. It is empty for unauthenticated
+	// requests and for the changes fn makes by itself.
+	Actor string `json:"actor,omitempty" db:"actor"`
+	// Address is the client address the change was requested from
+	Address string `json:"address,omitempty" db:"address"`
+	// Diff holds the fields of the resource the change set, changed or unset
+	Diff      AuditDiff       `json:"diff,omitempty" db:"diff"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+// AuditDiff maps the JSON fields of a resource to their value before and after a change
+type AuditDiff map[string]AuditChange
+
+// AuditChange is the value of a field before and after a change, Old is nil when the field
+// was set and New is nil when the field was unset.
+type AuditChange struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+var (
+	//ErrDatastoreEmptyAuditEntry - no audit entry given to the datastore
+	ErrDatastoreEmptyAuditEntry = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing audit entry"),
+	}
+	//ErrAuditEntryInvalid - the action or resource of an audit entry are missing
+	ErrAuditEntryInvalid = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Audit entries must have an action, a resource type and a resource ID"),
+	}
+)
+
+// NewAuditDiff returns the fields which differ between the JSON representations of a
+// resource before and after a change, either of which may be nil. Secrets are redacted.
+func NewAuditDiff(before, after interface{}) (AuditDiff, error) {
+	prev, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	next, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(AuditDiff)
+	for k, v := range prev {
+		if !reflect.DeepEqual(v, next[k]) {
+			diff[k] = AuditChange{Old: v, New: next[k]}
+		}
+	}
+	for k, v := range next {
+		if _, ok := prev[k]; !ok {
+			diff[k] = AuditChange{New: v}
+		}
+	}
+
+	for k, change := range diff {
+		if auditIgnoredFields[k] {
+			delete(diff, k)
+		} else if auditRedactedFields[k] {
+			diff[k] = AuditChange{Old: redact(change.Old), New: redact(change.New)}
+		}
+	}
+	return diff, nil
+}
+
+func auditFields(resource interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v := reflect.ValueOf(resource); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return fields, nil
+	}
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(b, &fields)
+}
+
+func redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return AuditRedacted
+}
+
+// Value implements sql.Valuer, returning a string
+func (d AuditDiff) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(map[string]AuditChange(d))
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (d *AuditDiff) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("audit diff invalid db format: %T %T value, err: %v", value, v, v)
+	}
+	if len(b) == 0 {
+		*d = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*map[string]AuditChange)(d))
+}
+
+// AuditEntryFilter is a search criteria on audit entries, most recent first
+type AuditEntryFilter struct {
+	AppID        string // this is exact match
+	ResourceType string // this is exact match
+	ResourceID   string // this is exact match
+	Actor        string // this is exact match
+	FromTime     common.DateTime
+	ToTime       common.DateTime
+
+	Cursor  string
+	PerPage int
+}
+
+// AuditEntryList is a container of audit entries returned by search, optionally indicating the next page cursor
+type AuditEntryList struct {
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Items      []*AuditEntry `json:"items"`
+}
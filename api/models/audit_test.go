@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+func TestNewAuditDiff(t *testing.T) {
+	before := &Fn{ID: "fn_id", Name: "myfn", Image: "fnproject/hello:0.0.1", Config: Config{"A": "1"}}
+	after := &Fn{ID: "fn_id", Name: "myfn", Image: "fnproject/hello:0.0.2", Config: Config{"A": "1", "B": "2"}}
+
+	diff, err := NewAuditDiff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 2 {
+		t.Fatalf("expected the image and config to differ, got %+v", diff)
+	}
+	if d := diff["image"]; d.Old != "fnproject/hello:0.0.1" || d.New != "fnproject/hello:0.0.2" {
+		t.Errorf("unexpected image diff %+v", d)
+	}
+	if d := diff["config"]; d.New.(map[string]interface{})["B"] != "2" {
+		t.Errorf("unexpected config diff %+v", d)
+	}
+
+	diff, err = NewAuditDiff(nil, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff["name"]; d.Old != nil || d.New != "myfn" {
+		t.Errorf("expected the name to be set on creation, got %+v", d)
+	}
+
+	var deleted *Fn
+	diff, err = NewAuditDiff(before, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff["id"]; d.Old != "fn_id" || d.New != nil {
+		t.Errorf("expected the id to be unset on deletion, got %+v", d)
+	}
+}
+
+func TestNewAuditDiffSecretsAndTimestamps(t *testing.T) {
+	diff, err := NewAuditDiff(nil, &Webhook{URL: "https://hooks.example.com", Secret: "whsec_0123456789abcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff["secret"]; d.New != AuditRedacted {
+		t.Errorf("expected the secret to be redacted, got %+v", d)
+	}
+
+	app := &App{Name: "myapp"}
+	updated := &App{Name: "myapp", UpdatedAt: common.DateTime(time.Now())}
+	diff, err = NewAuditDiff(app, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 0 {
+		t.Errorf("expected updated_at to be left out, got %+v", diff)
+	}
+}
+
+func TestAuditDiffValueScan(t *testing.T) {
+	v, err := AuditDiff(nil).Value()
+	if err != nil || v != "" {
+		t.Fatalf("expected an empty diff to be stored empty, got %q %v", v, err)
+	}
+
+	v, err = AuditDiff{"memory": {Old: 128.0, New: 256.0}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diff AuditDiff
+	if err := diff.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if d := diff["memory"]; d.Old != 128.0 || d.New != 256.0 {
+		t.Fatalf("expected diff to round trip, got %+v", diff)
+	}
+
+	if err := diff.Scan(""); err != nil || diff != nil {
+		t.Fatalf("expected empty diff, got %v %v", diff, err)
+	}
+	if err := diff.Scan(42); err == nil {
+		t.Fatal("expected an error scanning an int")
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+// Backpressure describes the load of a server rejecting a call, so that load balancers and
+// clients can back off or route the call elsewhere.
+type Backpressure struct {
+	// QueueDepth is the number of calls of the same function waiting for a hot container
+	QueueDepth uint64 `json:"queue_depth"`
+	// RetryAfter is the suggested delay in seconds before retrying the call
+	RetryAfter int64 `json:"retry_after"`
+	// InFlight is the number of calls in flight on the server
+	InFlight uint64 `json:"in_flight"`
+	// MaxInFlight is the most calls the server takes in flight at once, 0 if unlimited
+	MaxInFlight uint64 `json:"max_in_flight,omitempty"`
+}
+
+// BackpressureError is an APIError for a rejected call, along with the backpressure of the
+// server which rejected it.
+type BackpressureError struct {
+	APIError
+	Backpressure Backpressure
+}
+
+// NewBackpressureError attaches backpressure to an APIError rejecting a call
+func NewBackpressureError(err APIError, bp Backpressure) *BackpressureError {
+	return &BackpressureError{APIError: err, Backpressure: bp}
+}
+
+// RootError implements APIErrorWrapper
+func (e *BackpressureError) RootError() error {
+	return e.APIError
+}
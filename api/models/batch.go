@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Actions of the items of batches
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchItems is the largest number of items of a batch
+const MaxBatchItems = 100
+
+var (
+	ErrBatchMissingItems = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing items in batch"),
+	}
+	ErrBatchTooManyItems = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Too many items in batch, the maximum is %d", MaxBatchItems),
+	}
+	ErrBatchNotApplied = err{
+		code:  http.StatusFailedDependency,
+		error: errors.New("Not applied as another item of the batch failed"),
+	}
+)
+
+// ErrBatchInvalidItem is returned for batches with an item of an unknown action, or without
+// exactly one resource of the kind of the batch.
+type ErrBatchInvalidItem string
+
+func (e ErrBatchInvalidItem) Code() int     { return http.StatusBadRequest }
+func (e ErrBatchInvalidItem) Error() string { return string(e) }
+
+// Batch is a list of changes to apps, fns or triggers made in order in a single datastore
+// transaction: either all of them are made or none is.
+type Batch struct {
+	Items []*BatchItem `json:"items"`
+}
+
+// BatchItem is a change of a batch, the creation, update or deletion of the app, fn or
+// trigger it carries. Updates and deletions identify their resource by id.
+type BatchItem struct {
+	Action  string   `json:"action"`
+	App     *App     `json:"app,omitempty"`
+	Fn      *Fn      `json:"fn,omitempty"`
+	Trigger *Trigger `json:"trigger,omitempty"`
+
+	// SoftDelete marks deleted apps and fns deleted rather than removing them, see
+	// Datastore.SoftDeleteApp
+	SoftDelete bool `json:"-"`
+}
+
+// BatchResult reports the outcome of each item of a batch, in the order of the items.
+type BatchResult struct {
+	Applied bool               `json:"applied"`
+	Items   []*BatchItemResult `json:"items"`
+}
+
+// BatchItemResult is the outcome of an item of a batch: the status its own request would have
+// been answered with, along with the resource created or updated, or the error. Items of a
+// batch that was not applied as another item failed have the status of ErrBatchNotApplied.
+type BatchItemResult struct {
+	Status  int      `json:"status"`
+	Error   *Error   `json:"error,omitempty"`
+	App     *App     `json:"app,omitempty"`
+	Fn      *Fn      `json:"fn,omitempty"`
+	Trigger *Trigger `json:"trigger,omitempty"`
+}
+
+// BatchError is returned by datastores for batches with an item that failed, none of the
+// items of the batch is applied.
+type BatchError struct {
+	// Index is the index of the item that failed in the batch
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %s", e.Index, e.Err)
+}
+
+// Validate checks that the batch has between one and MaxBatchItems items, and that each item
+// has a known action and exactly one resource, of the kind of the batch, one of the apply
+// kinds. The resources are validated as they are applied.
+func (b *Batch) Validate(kind string) error {
+	if len(b.Items) == 0 {
+		return ErrBatchMissingItems
+	}
+	if len(b.Items) > MaxBatchItems {
+		return ErrBatchTooManyItems
+	}
+
+	for i, item := range b.Items {
+		if item == nil {
+			return ErrBatchInvalidItem(fmt.Sprintf("item %d is empty", i))
+		}
+		switch item.Action {
+		case BatchCreate, BatchUpdate, BatchDelete:
+		default:
+			return ErrBatchInvalidItem(fmt.Sprintf("item %d has invalid action %q, valid actions are create, update and delete", i, item.Action))
+		}
+		if item.Kind() != kind {
+			return ErrBatchInvalidItem(fmt.Sprintf("item %d must carry a single %s", i, kind))
+		}
+	}
+	return nil
+}
+
+// Kind returns the apply kind of the resource of an item, empty if it carries none or more
+// than one.
+func (i *BatchItem) Kind() string {
+	kind, n := "", 0
+	if i.App != nil {
+		kind, n = ApplyKindApp, n+1
+	}
+	if i.Fn != nil {
+		kind, n = ApplyKindFn, n+1
+	}
+	if i.Trigger != nil {
+		kind, n = ApplyKindTrigger, n+1
+	}
+	if n != 1 {
+		return ""
+	}
+	return kind
+}
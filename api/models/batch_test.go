@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestBatchValidate(t *testing.T) {
+	tooMany := &Batch{}
+	for i := 0; i <= MaxBatchItems; i++ {
+		tooMany.Items = append(tooMany.Items, &BatchItem{Action: BatchCreate, App: &App{}})
+	}
+
+	for i, test := range []struct {
+		batch *Batch
+		err   error
+	}{
+		{&Batch{}, ErrBatchMissingItems},
+		{tooMany, ErrBatchTooManyItems},
+		{&Batch{Items: []*BatchItem{{Action: BatchCreate, App: &App{Name: "app"}}}}, nil},
+		{&Batch{Items: []*BatchItem{{Action: BatchDelete, App: &App{ID: "app"}}}}, nil},
+		{&Batch{Items: []*BatchItem{nil}}, ErrBatchInvalidItem("")},
+		{&Batch{Items: []*BatchItem{{Action: "upsert", App: &App{}}}}, ErrBatchInvalidItem("")},
+		{&Batch{Items: []*BatchItem{{Action: BatchCreate}}}, ErrBatchInvalidItem("")},
+		{&Batch{Items: []*BatchItem{{Action: BatchCreate, Fn: &Fn{}}}}, ErrBatchInvalidItem("")},
+		{&Batch{Items: []*BatchItem{{Action: BatchCreate, App: &App{}, Fn: &Fn{}}}}, ErrBatchInvalidItem("")},
+	} {
+		err := test.batch.Validate(ApplyKindApp)
+		if _, invalid := test.err.(ErrBatchInvalidItem); invalid {
+			if _, ok := err.(ErrBatchInvalidItem); !ok {
+				t.Errorf("test %d: expected invalid item error, got %v", i, err)
+			}
+			continue
+		}
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+}
@@ -18,6 +18,10 @@ const (
 	TypeDetached = "detached"
 )
 
+// LogUnavailableConcurrent is why calls served by containers running several calls at once,
+// see FnConcurrencyAnnotation, have no log: their output can't be told apart.
+const LogUnavailableConcurrent = "concurrent"
+
 var possibleStatuses = [...]string{"delayed", "queued", "running", "success", "error", "cancelled"}
 
 // Call is a representation of a specific invocation of a fn.
@@ -83,8 +87,9 @@ type Call struct {
 	// Method of the http request used to make this call.
 	Method string `json:"method,omitempty" db:"-"`
 
-	// Priority of the call. Higher has more priority. 3 levels from 0-2. Calls
-	// at same priority are processed in FIFO order.
+	// Priority of the call. Higher has more priority. 3 levels from 0-2, see
+	// PriorityLow, PriorityNormal and PriorityHigh. Calls at same priority are
+	// processed in FIFO order.
 	Priority *int32 `json:"priority,omitempty" db:"-"`
 
 	// Maximum runtime in seconds.
@@ -93,6 +98,39 @@ type Call struct {
 	// Hot function idle timeout in seconds before termination.
 	IdleTimeout int32 `json:"idle_timeout,omitempty" db:"-"`
 
+	// Number of hot containers to keep alive for the fn of the call.
+	MinReady uint64 `json:"min_ready,omitempty" db:"-"`
+
+	// Maximum number of hot containers running the fn of the call at once.
+	MaxContainers uint64 `json:"max_containers,omitempty" db:"-"`
+
+	// Scale policy of the hot containers of the fn of the call.
+	ScalePolicy *ScalePolicy `json:"scale_policy,omitempty" db:"-"`
+
+	// Retry policy of the fn of the call, for async calls.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty" db:"-"`
+
+	// Recycle policy of the hot containers of the fn of the call.
+	RecyclePolicy *RecyclePolicy `json:"recycle_policy,omitempty" db:"-"`
+
+	// Attempt of an async call, counting from 1. Each retry of a call is a new call.
+	Attempt int32 `json:"attempt,omitempty" db:"-"`
+
+	// ID of the call this call is a retry of, if any.
+	RetryOf string `json:"retry_of,omitempty" db:"-"`
+
+	// Time in milliseconds the call may wait for a hot container, 0 uses the runner default.
+	SlotWaitTimeout uint64 `json:"slot_wait_timeout_ms,omitempty" db:"-"`
+
+	// Maximum size in bytes of the request body of the call, 0 uses the runner default.
+	MaxRequestSize uint64 `json:"max_request_size,omitempty" db:"-"`
+
+	// Maximum size in bytes of the response body of the call, 0 uses the runner default.
+	MaxResponseSize uint64 `json:"max_response_size,omitempty" db:"-"`
+
+	// Maximum size in bytes of the log captured for the call, 0 uses the runner default.
+	MaxLogSize uint64 `json:"max_log_size,omitempty" db:"-"`
+
 	// Tmpfs size in megabytes.
 	TmpFsSize uint32 `json:"tmpfs_size,omitempty" db:"-"`
 
@@ -116,6 +154,9 @@ type Call struct {
 	// SyslogURL is a syslog URL to send all logs to.
 	SyslogURL string `json:"syslog_url,omitempty" db:"-"`
 
+	// SyslogFormat is the format of the messages sent to SyslogURL, see SyslogFormatAnnotation.
+	SyslogFormat string `json:"syslog_format,omitempty" db:"-"`
+
 	// Time when call completed, whether it was successul or failed. Always in UTC.
 	CompletedAt common.DateTime `json:"completed_at,omitempty" db:"completed_at"`
 
@@ -128,6 +169,18 @@ type Call struct {
 	// Stats is a list of metrics from this call's execution, possibly empty.
 	Stats drivers.Stats `json:"stats,omitempty" db:"stats"`
 
+	// LogDropped is the number of bytes of output dropped from the log of the call once it
+	// reached its maximum size, the log ends with a truncation notice if it is not 0.
+	LogDropped int64 `json:"log_dropped,omitempty" db:"log_dropped"`
+
+	// LogSize is the size in bytes of the log of the call, before it is compressed by the log
+	// store if it is.
+	LogSize int64 `json:"log_size,omitempty" db:"log_size"`
+
+	// LogUnavailable is why no log is stored for the call, if none is, eg.
+	// LogUnavailableConcurrent.
+	LogUnavailable string `json:"log_unavailable,omitempty" db:"log_unavailable"`
+
 	// Error is the reason why the call failed, it is only non-empty if
 	// status is equal to "error".
 	Error string `json:"error,omitempty" db:"error"`
@@ -143,6 +196,17 @@ type Call struct {
 
 	// Fn this call belongs to.
 	FnID string `json:"fn_id" db:"fn_id"`
+
+	// Revision of the fn which served this call, see FnRevision.
+	FnRevision int64 `json:"fn_revision,omitempty" db:"fn_revision"`
+
+	// Role of the revision which served this call if the fn has a canary, CanaryStable or
+	// CanaryCandidate.
+	Canary string `json:"canary,omitempty" db:"-"`
+
+	// Correlation ID of the request which made this call, the X-Request-ID of the caller if
+	// it is valid or one generated by the server, see common.FnRequestID.
+	RequestID string `json:"request_id,omitempty" db:"request_id"`
 }
 
 type CallFilter struct {
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TriggerCORSAnnotation is the annotation of HTTP triggers declaring the cross-origin requests
+// browsers may make to them, e.g. {"allow_origins": ["https://example.com"]}. The server then
+// answers the preflight requests of the trigger itself and sets the CORS headers of its responses.
+const TriggerCORSAnnotation = "fnproject.io/trigger/cors"
+
+// DefaultCORSMethods are the methods allowed when a CORS configuration doesn't list any
+var DefaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORS is the cross-origin resource sharing configuration of an HTTP trigger. "*" allows any
+// origin, method or header.
+type CORS struct {
+	AllowOrigins []string `json:"allow_origins"`
+	// AllowMethods defaults to DefaultCORSMethods
+	AllowMethods []string `json:"allow_methods,omitempty"`
+	// AllowHeaders are the request headers allowed besides the CORS-safelisted ones
+	AllowHeaders []string `json:"allow_headers,omitempty"`
+	// ExposeHeaders are the response headers scripts may read besides the CORS-safelisted ones
+	ExposeHeaders    []string `json:"expose_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	// MaxAge is the number of seconds browsers may cache preflight responses for
+	MaxAge int `json:"max_age,omitempty"`
+}
+
+var (
+	ErrInvalidCORS = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid CORS configuration, allow_origins must list origins such as https://example.com or *, and allow_credentials can't be set with any origin allowed"),
+	}
+)
+
+// CORSFromAnnotations returns the CORS configuration set in trigger annotations, nil if unset.
+func CORSFromAnnotations(annotations Annotations) (*CORS, error) {
+	raw, ok := annotations.Get(TriggerCORSAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var cors CORS
+	if err := json.Unmarshal(raw, &cors); err != nil {
+		return nil, ErrInvalidCORS
+	}
+	if err := cors.Validate(); err != nil {
+		return nil, err
+	}
+	return &cors, nil
+}
+
+// Validate checks the origins, methods and headers of a CORS configuration
+func (c *CORS) Validate() error {
+	if len(c.AllowOrigins) == 0 || c.MaxAge < 0 {
+		return ErrInvalidCORS
+	}
+	for _, o := range c.AllowOrigins {
+		if o == "*" {
+			if c.AllowCredentials {
+				return ErrInvalidCORS
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return ErrInvalidCORS
+		}
+	}
+	for _, list := range [][]string{c.AllowMethods, c.AllowHeaders, c.ExposeHeaders} {
+		for _, v := range list {
+			if v == "" || strings.ContainsAny(v, ", \t") {
+				return ErrInvalidCORS
+			}
+		}
+	}
+	return nil
+}
+
+// AllowsOrigin returns true if origin may make requests to the trigger
+func (c *CORS) AllowsOrigin(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, o := range c.AllowOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMethod returns true if method may be used in cross-origin requests
+func (c *CORS) AllowsMethod(method string) bool {
+	methods := c.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	for _, m := range methods {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsHeader returns true if header may be sent in cross-origin requests
+func (c *CORS) AllowsHeader(header string) bool {
+	for _, h := range c.AllowHeaders {
+		if h == "*" || strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestCORSFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		valid      bool
+	}{
+		{map[string]interface{}{"allow_origins": []string{"https://example.com", "http://localhost:8080"}}, true},
+		{map[string]interface{}{"allow_origins": []string{"*"}, "allow_methods": []string{"*"}, "max_age": 60}, true},
+		{map[string]interface{}{"allow_origins": []string{"https://example.com"}, "allow_credentials": true}, true},
+		{map[string]interface{}{"allow_origins": []string{"*"}, "allow_credentials": true}, false},
+		{map[string]interface{}{"allow_origins": []string{}}, false},
+		{map[string]interface{}{"allow_origins": []string{"example.com"}}, false},
+		{map[string]interface{}{"allow_origins": []string{"https://example.com/path"}}, false},
+		{map[string]interface{}{"allow_origins": []string{"*"}, "allow_headers": []string{"X-A, X-B"}}, false},
+		{map[string]interface{}{"allow_origins": []string{"*"}, "max_age": -1}, false},
+		{"*", false},
+	} {
+		annotations, err := EmptyAnnotations().With(TriggerCORSAnnotation, test.annotation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cors, err := CORSFromAnnotations(annotations)
+		if test.valid && (err != nil || cors == nil) {
+			t.Errorf("test %d: expected valid CORS configuration, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidCORS {
+			t.Errorf("test %d: expected invalid CORS configuration error, got: %v", i, err)
+		}
+	}
+
+	if cors, err := CORSFromAnnotations(EmptyAnnotations()); cors != nil || err != nil {
+		t.Errorf("expected no CORS configuration by default, got %v %v", cors, err)
+	}
+}
+
+func TestCORSAllows(t *testing.T) {
+	cors := &CORS{AllowOrigins: []string{"https://example.com"}, AllowHeaders: []string{"X-Token"}}
+	if !cors.AllowsOrigin("https://EXAMPLE.com") || cors.AllowsOrigin("https://example.org") {
+		t.Error("expected only listed origins to be allowed")
+	}
+	if !cors.AllowsMethod("POST") || cors.AllowsMethod("DELETE") {
+		t.Error("expected the default methods to be allowed")
+	}
+	if !cors.AllowsHeader("x-token") || cors.AllowsHeader("X-Other") {
+		t.Error("expected only listed headers to be allowed")
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"io"
+	"time"
 )
 
 type Datastore interface {
@@ -29,10 +30,18 @@ type Datastore interface {
 	// Returns ErrAppsNotFound if an App is not found.
 	UpdateApp(ctx context.Context, app *App) (*App, error)
 
-	// RemoveApp removes the App named appName. Returns ErrDatastoreEmptyAppName if appName is empty.
+	// RemoveApp removes the App named appName, deleted or not. Returns ErrDatastoreEmptyAppName if appName is empty.
 	// Returns ErrAppsNotFound if an App is not found.
 	RemoveApp(ctx context.Context, appID string) error
 
+	// SoftDeleteApp marks an App and its functions deleted, hiding them from the other methods
+	// until they are restored or removed. Returns ErrAppsNotFound if no live App is found.
+	SoftDeleteApp(ctx context.Context, appID string) error
+
+	// RestoreApp clears the deletion of an App and of the functions deleted along with it.
+	// Returns ErrAppsNotFound if the App is not marked deleted.
+	RestoreApp(ctx context.Context, appID string) (*App, error)
+
 	// InsertFn inserts a new function if one does not exist, applying any defaults necessary,
 	InsertFn(ctx context.Context, fn *Fn) (*Fn, error)
 
@@ -47,10 +56,30 @@ type Datastore interface {
 	// Returns ErrFnsNotFound if a fn is not found.
 	GetFnByID(ctx context.Context, fnID string) (*Fn, error)
 
-	// RemoveFn removes a function. Returns ErrDatastoreEmptyFnID if fnID is empty.
+	// RemoveFn removes a function, deleted or not. Returns ErrDatastoreEmptyFnID if fnID is empty.
 	// Returns ErrFnsNotFound if a func is not found.
 	RemoveFn(ctx context.Context, fnID string) error
 
+	// SoftDeleteFn marks a function deleted, hiding it from the other methods until it is
+	// restored or removed. Returns ErrFnsNotFound if no live function is found.
+	SoftDeleteFn(ctx context.Context, fnID string) error
+
+	// GetDeletedFnByID returns a function marked deleted by ID.
+	// Returns ErrFnsNotFound if the function is not marked deleted.
+	GetDeletedFnByID(ctx context.Context, fnID string) (*Fn, error)
+
+	// RestoreFn clears the deletion of a function. Returns ErrFnsNotFound if the function is
+	// not marked deleted, and ErrAppsNotFound if its App is, it must be restored first.
+	RestoreFn(ctx context.Context, fnID string) (*Fn, error)
+
+	// GetFnRevision returns a revision of a function.
+	// Returns ErrFnRevisionNotFound if the revision is not found.
+	GetFnRevision(ctx context.Context, fnID string, revision int64) (*FnRevision, error)
+
+	// GetFnRevisions returns the revisions of a function, most recent first.
+	// Returns ErrFnRevisionMissingFnID if no FnID set in the filter
+	GetFnRevisions(ctx context.Context, filter *FnRevisionFilter) (*FnRevisionList, error)
+
 	// InsertTrigger inserts a trigger. Returns ErrDatastoreEmptyTrigger when trigger is nil, and specific errors for each field
 	// Returns ErrTriggerAlreadyExists if the exact apiID, fnID, source, type combination already exists
 	InsertTrigger(ctx context.Context, trigger *Trigger) (*Trigger, error)
@@ -73,6 +102,180 @@ type Datastore interface {
 	// GetTriggerBySource loads a trigger by type and source ID - this is only needed when the data store is also used for agent read access
 	GetTriggerBySource(ctx context.Context, appId string, triggerType, source string) (*Trigger, error)
 
+	// ApplyBatch creates, updates and deletes the apps, functions and triggers of the items of
+	// a batch, in order and atomically. It returns the items as applied, with the resources as
+	// created or updated, and as they were before they were deleted. If an item fails none is
+	// applied, and a *BatchError of the item is returned.
+	ApplyBatch(ctx context.Context, items []*BatchItem) ([]*BatchItem, error)
+
+	// InTx runs f in a transaction, the operations made with the context passed to f being
+	// committed together if f returns nil, and rolled back otherwise. InTx within f joins the
+	// transaction. f may run again should the transaction conflict with another, and the side
+	// effects of the operations are deferred until committed, see AfterCommit.
+	InTx(ctx context.Context, f func(ctx context.Context) error) error
+
+	// InsertSchedule inserts a schedule, setting when it runs next.
+	// Returns ErrScheduleExists if a schedule with the same name exists on the app.
+	InsertSchedule(ctx context.Context, schedule *Schedule) (*Schedule, error)
+
+	// UpdateSchedule updates a schedule that exists under the same id.
+	// Returns ErrScheduleNotFound if the schedule is not found.
+	UpdateSchedule(ctx context.Context, schedule *Schedule) (*Schedule, error)
+
+	// RemoveSchedule removes a schedule and its run history.
+	// Returns ErrScheduleNotFound if the schedule is not found.
+	RemoveSchedule(ctx context.Context, scheduleID string) error
+
+	// GetScheduleByID gets a schedule by its id.
+	// Returns ErrScheduleNotFound when no matching schedule is found
+	GetScheduleByID(ctx context.Context, scheduleID string) (*Schedule, error)
+
+	// GetSchedules gets a list of schedules that match the specified filter
+	// Return ErrScheduleMissingAppID if no AppID set in the filter
+	GetSchedules(ctx context.Context, filter *ScheduleFilter) (*ScheduleList, error)
+
+	// GetDueSchedules returns up to limit schedules of any app which are due to run at t,
+	// the longest due first.
+	GetDueSchedules(ctx context.Context, t time.Time, limit int) ([]*Schedule, error)
+
+	// AdvanceSchedule moves the next run of a schedule from due to next.
+	// Returns ErrScheduleNotDue if the schedule was removed or its next run is no longer due,
+	// so that a run is only advanced once.
+	AdvanceSchedule(ctx context.Context, scheduleID string, due, next time.Time) error
+
+	// InsertScheduleRun records a run of a schedule.
+	InsertScheduleRun(ctx context.Context, run *ScheduleRun) error
+
+	// GetScheduleRuns gets the runs of a schedule, most recent first.
+	// Returns ErrScheduleRunMissingScheduleID if no ScheduleID set in the filter
+	GetScheduleRuns(ctx context.Context, filter *ScheduleRunFilter) (*ScheduleRunList, error)
+
+	// InsertAPIKey inserts an API key, the hash of its secret must be set.
+	// Returns ErrAppsNotFound if the key is restricted to an app that does not exist.
+	InsertAPIKey(ctx context.Context, key *APIKey) (*APIKey, error)
+
+	// GetAPIKeys gets a list of API keys that match the specified filter, in creation order
+	GetAPIKeys(ctx context.Context, filter *APIKeyFilter) (*APIKeyList, error)
+
+	// GetAPIKeyByHash gets the API key with the hash of a secret.
+	// Returns ErrAPIKeyNotFound when no matching key is found
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+
+	// RemoveAPIKey removes an API key, revoking it.
+	// Returns ErrAPIKeyNotFound if the key is not found.
+	RemoveAPIKey(ctx context.Context, keyID string) error
+
+	// InsertRoleBinding binds a role to a principal.
+	// Returns ErrAppsNotFound if the role is bound on an app that does not exist, and
+	// ErrRoleBindingExists if the principal already has the role on the same apps.
+	InsertRoleBinding(ctx context.Context, binding *RoleBinding) (*RoleBinding, error)
+
+	// GetRoleBindingByID gets a role binding by its ID.
+	// Returns ErrRoleBindingNotFound when no matching binding is found
+	GetRoleBindingByID(ctx context.Context, bindingID string) (*RoleBinding, error)
+
+	// GetRoleBindings gets a list of role bindings that match the specified filter, in creation order
+	GetRoleBindings(ctx context.Context, filter *RoleBindingFilter) (*RoleBindingList, error)
+
+	// RemoveRoleBinding removes a role binding.
+	// Returns ErrRoleBindingNotFound if the binding is not found.
+	RemoveRoleBinding(ctx context.Context, bindingID string) error
+
+	// AddAppUsage adds the calls and compute usage of usage to the usage of its app over its
+	// period.
+	AddAppUsage(ctx context.Context, usage *AppUsage) error
+
+	// GetAppUsage gets the usage of an app over a period, zero if it was not used.
+	GetAppUsage(ctx context.Context, appID, period string) (*AppUsage, error)
+
+	// InsertDomain maps a domain to an app.
+	// Returns ErrAppsNotFound if the app does not exist, ErrDomainExists if another domain
+	// has the same hostname and path prefix, and ErrDomainHostnameTaken if the hostname is
+	// mapped to another app.
+	InsertDomain(ctx context.Context, domain *Domain) (*Domain, error)
+
+	// GetDomainByID gets a domain by its ID.
+	// Returns ErrDomainNotFound when no matching domain is found
+	GetDomainByID(ctx context.Context, domainID string) (*Domain, error)
+
+	// GetDomains gets a list of domains that match the specified filter, in creation order
+	GetDomains(ctx context.Context, filter *DomainFilter) (*DomainList, error)
+
+	// GetDomainsByHostname gets all the domains of a hostname, with any path prefix.
+	GetDomainsByHostname(ctx context.Context, hostname string) ([]*Domain, error)
+
+	// RemoveDomain removes a domain.
+	// Returns ErrDomainNotFound if the domain is not found.
+	RemoveDomain(ctx context.Context, domainID string) error
+
+	// InsertWebhook registers a webhook, its secret must be set.
+	// Returns ErrAppsNotFound if the webhook is restricted to an app that does not exist.
+	InsertWebhook(ctx context.Context, webhook *Webhook) (*Webhook, error)
+
+	// GetWebhookByID gets a webhook by its ID.
+	// Returns ErrWebhookNotFound when no matching webhook is found
+	GetWebhookByID(ctx context.Context, webhookID string) (*Webhook, error)
+
+	// GetWebhooks gets a list of webhooks that match the specified filter, in creation order
+	GetWebhooks(ctx context.Context, filter *WebhookFilter) (*WebhookList, error)
+
+	// RemoveWebhook removes a webhook along with its deliveries.
+	// Returns ErrWebhookNotFound if the webhook is not found.
+	RemoveWebhook(ctx context.Context, webhookID string) error
+
+	// InsertWebhookDelivery records the delivery of an event to a webhook.
+	// Returns ErrWebhookNotFound if the webhook was removed.
+	InsertWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// GetWebhookDeliveries gets the deliveries of a webhook, most recent first.
+	// Returns ErrWebhookDeliveryMissingWebhookID if no WebhookID set in the filter
+	GetWebhookDeliveries(ctx context.Context, filter *WebhookDeliveryFilter) (*WebhookDeliveryList, error)
+
+	// InsertAuditEntry appends an entry to the audit trail, the caller sets its ID. Entries
+	// are never updated nor removed.
+	InsertAuditEntry(ctx context.Context, entry *AuditEntry) error
+
+	// GetAuditEntries gets the audit entries that match the specified filter, most recent first
+	GetAuditEntries(ctx context.Context, filter *AuditEntryFilter) (*AuditEntryList, error)
+
+	// Search gets the apps and fns that match the specified search, in id order. Deleted
+	// resources are not searched.
+	Search(ctx context.Context, filter *SearchFilter) (*SearchResultList, error)
+
+	// AcquireLease acquires or renews the lease with the given name for holder until ttl
+	// from now, returning false if another holder has it. This is used to elect a leader
+	// among nodes sharing the datastore.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
 	// implements io.Closer to shutdown
 	io.Closer
 }
+
+// Kinds of the resources of changes
+const (
+	ChangeApp     = "app"
+	ChangeFn      = "fn"
+	ChangeTrigger = "trigger"
+	ChangeDomain  = "domain"
+	// ChangeAll is sent when changes may have been missed, all the resources may have changed
+	ChangeAll = "all"
+)
+
+// Change is a change of a resource of a datastore
+type Change struct {
+	// Kind is the kind of the resource, ChangeApp, ChangeFn, ChangeTrigger, ChangeDomain or ChangeAll
+	Kind string
+	// ID of the resource
+	ID string
+	// Deleted is set if the resource was removed
+	Deleted bool
+}
+
+// ChangeWatcher is implemented by datastores which notify the changes of their apps, fns,
+// triggers and domains, so that nodes caching them can drop them as soon as they change
+// instead of polling.
+type ChangeWatcher interface {
+	// WatchChanges sends the changes made to the datastore by any node from now on, until
+	// ctx is done.
+	WatchChanges(ctx context.Context) (<-chan *Change, error)
+}
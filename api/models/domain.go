@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Domain maps a hostname, or the paths of a hostname under a prefix, to an app. Requests to
+// the domain are routed to the HTTP triggers of the app, the path after the prefix being the
+// source of the trigger, e.g. https://api.example.com/v1/hello goes to the /hello trigger of
+// the app of the api.example.com domain with the /v1 path prefix.
+type Domain struct {
+	ID       string `json:"id" db:"id"`
+	Hostname string `json:"hostname" db:"hostname"`
+	// PathPrefix restricts the domain to the paths under it, all the paths of the hostname if empty
+	PathPrefix string          `json:"path_prefix,omitempty" db:"path_prefix"`
+	AppID      string          `json:"app_id" db:"app_id"`
+	CreatedAt  common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+var (
+	//ErrDatastoreEmptyDomain - no domain given to the datastore
+	ErrDatastoreEmptyDomain = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing domain"),
+	}
+	//ErrDomainIDProvided indicates that a domain ID was specified when it shouldn't have been
+	ErrDomainIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for domain creation"),
+	}
+	//ErrDomainMissingHostname - hostname not specified on a domain
+	ErrDomainMissingHostname = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing hostname on domain")}
+	//ErrDomainInvalidHostname - hostname is not a valid DNS name
+	ErrDomainInvalidHostname = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid hostname, must be a lowercase DNS name of %v characters or less", MaxHostname)}
+	//ErrDomainInvalidPathPrefix - path prefix is not a path
+	ErrDomainInvalidPathPrefix = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid path prefix, must start with / and not end with /, and be %v characters or less", MaxPathPrefix)}
+	//ErrDomainMissingAppID - no app specified on a domain
+	ErrDomainMissingAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing App ID on domain")}
+	//ErrDomainExists - another domain has the same hostname and path prefix
+	ErrDomainExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Domain with the same hostname and path prefix exists")}
+	//ErrDomainHostnameTaken - the hostname is mapped to another app
+	ErrDomainHostnameTaken = err{
+		code:  http.StatusConflict,
+		error: errors.New("Hostname is mapped to another app")}
+	//ErrDomainHostnameReserved - the hostname is one of the server
+	ErrDomainHostnameReserved = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Hostname is a hostname of the server")}
+	//ErrDomainNotFound - domain not found
+	ErrDomainNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Domain not found")}
+)
+
+// Validate checks that domain has valid data for inserting into a store
+func (d *Domain) Validate() error {
+	if d.Hostname == "" {
+		return ErrDomainMissingHostname
+	}
+	if !validHostname(d.Hostname) {
+		return ErrDomainInvalidHostname
+	}
+	if d.PathPrefix != "" && (len(d.PathPrefix) > MaxPathPrefix || !strings.HasPrefix(d.PathPrefix, "/") || strings.HasSuffix(d.PathPrefix, "/")) {
+		return ErrDomainInvalidPathPrefix
+	}
+	if d.AppID == "" {
+		return ErrDomainMissingAppID
+	}
+	return nil
+}
+
+func validHostname(hostname string) bool {
+	if len(hostname) > MaxHostname {
+		return false
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Match returns the path of a request to the domain relative to its prefix, false if the
+// path isn't under the prefix.
+func (d *Domain) Match(path string) (string, bool) {
+	if !strings.HasPrefix(path, d.PathPrefix) {
+		return "", false
+	}
+	rest := path[len(d.PathPrefix):]
+	if rest == "" {
+		return "/", true
+	}
+	if !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+// DomainFilter is a search criteria on domains, in creation order
+type DomainFilter struct {
+	//AppID searches for the domains of an app
+	AppID string // this is exact match
+	//Hostname searches for the domains of a hostname
+	Hostname string // this is exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// DomainList is a container of domains returned by search, optionally indicating the next page cursor
+type DomainList struct {
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Items      []*Domain `json:"items"`
+}
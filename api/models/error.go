@@ -8,9 +8,15 @@ import (
 
 // TODO we can put constants all in this file too
 const (
-	maxAppName     = 30
-	maxFnName      = 30
-	MaxTriggerName = 30
+	maxAppName      = 30
+	maxFnName       = 30
+	MaxTriggerName  = 30
+	MaxScheduleName = 30
+	MaxAPIKeyName   = 30
+	MaxPrincipal    = 256
+	MaxHostname     = 253
+	MaxPathPrefix   = 256
+	MaxWebhookURL   = 2048
 )
 
 var (
@@ -114,6 +120,22 @@ var (
 		code:  http.StatusNotFound,
 		error: errors.New("Call log not found"),
 	}
+	ErrCallResultNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Call result not found"),
+	}
+	ErrCallResultStore = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Call result could not be stored"),
+	}
+	ErrCallRetryEnqueue = err{
+		code:  http.StatusInternalServerError,
+		error: errors.New("Call retry could not be enqueued"),
+	}
+	ErrDeadLetterNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Dead letter not found"),
+	}
 	ErrPathNotFound = err{
 		code:  http.StatusNotFound,
 		error: errors.New("Path not found"),
@@ -142,6 +164,26 @@ var (
 		code:  http.StatusTooManyRequests,
 		error: errors.New("Too many requests submitted"),
 	}
+	ErrAgentDraining = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Server is draining and does not accept new calls, retry on another server"),
+	}
+	ErrAgentOverloaded = err{
+		code:  http.StatusServiceUnavailable,
+		error: errors.New("Too many calls in flight on this server, retry on another server"),
+	}
+	ErrNodeStatusUnavailable = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("The status of this server is not available"),
+	}
+	ErrAppQuotaExceeded = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many calls of this app in flight, app quota exceeded"),
+	}
+	ErrWebSocketUnsupported = err{
+		code:  http.StatusNotImplemented,
+		error: errors.New("WebSocket calls are not supported by this server"),
+	}
 	ErrAsyncUnsupported = err{
 		code:  http.StatusBadRequest,
 		error: errors.New("Async functions are not supported on this server"),
@@ -160,6 +202,22 @@ var (
 		code:  http.StatusInternalServerError,
 		error: errors.New("Unable to service the request for the reservation period"),
 	}
+	ErrMissingBearerToken = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Missing bearer token in Authorization header"),
+	}
+	ErrInvalidBearerToken = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Invalid bearer token"),
+	}
+	ErrInvalidIdempotencyKey = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid Idempotency-Key, it must have at most 128 letters, digits or -_.:/+=@ characters"),
+	}
+	ErrIdempotentCallInProgress = err{
+		code:  http.StatusConflict,
+		error: errors.New("A call with this Idempotency-Key is in progress"),
+	}
 
 	// func errors
 
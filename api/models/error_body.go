@@ -3,6 +3,11 @@ package models
 type Error struct {
 	Message string `json:"message,omitempty"`
 	Fields  string `json:"fields,omitempty"`
+	// Backpressure is set on errors of calls rejected by a busy server
+	Backpressure *Backpressure `json:"backpressure,omitempty"`
+	// Errors are set on errors of calls whose request body does not match the request schema
+	// of their function
+	Errors []ValidationError `json:"errors,omitempty"`
 }
 
 // Validate validates this error body
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrPreconditionFailed = err{
+		code:  http.StatusPreconditionFailed,
+		error: errors.New("Resource has changed, its ETag does not match If-Match"),
+	}
+)
+
+type ifMatchKey struct{}
+
+// ETag returns the strong entity tag of an app, fn or trigger, a quoted hash of its JSON. Any
+// change to the resource changes its ETag.
+func ETag(resource interface{}) string {
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return ""
+	}
+	h := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// WithIfMatch returns a context making the updates and deletions of apps, fns and triggers
+// made with it conditional: datastores fail them with ErrPreconditionFailed unless the
+// current ETag of the resource is one of etags.
+func WithIfMatch(ctx context.Context, etags []string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, etags)
+}
+
+// HasIfMatch returns whether the changes made with ctx are conditional, see WithIfMatch
+func HasIfMatch(ctx context.Context) bool {
+	_, ok := ctx.Value(ifMatchKey{}).([]string)
+	return ok
+}
+
+// CheckIfMatch returns ErrPreconditionFailed if the changes made with ctx are conditional and
+// the ETag of resource, as currently stored, is not one of those of ctx.
+func CheckIfMatch(ctx context.Context, resource interface{}) error {
+	etags, ok := ctx.Value(ifMatchKey{}).([]string)
+	if !ok {
+		return nil
+	}
+	etag := ETag(resource)
+	for _, e := range etags {
+		if e == etag {
+			return nil
+		}
+	}
+	return ErrPreconditionFailed
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	app := &App{ID: "app", Name: "myapp"}
+	etag := ETag(app)
+	if etag != ETag(&App{ID: "app", Name: "myapp"}) {
+		t.Fatalf("expected equal apps to have the same ETag")
+	}
+	if etag == ETag(&App{ID: "app", Name: "myapp", Config: Config{"A": "1"}}) {
+		t.Fatalf("expected a changed app to have another ETag")
+	}
+
+	ctx := context.Background()
+	for i, test := range []struct {
+		ctx context.Context
+		err error
+	}{
+		{ctx, nil},
+		{WithIfMatch(ctx, []string{etag}), nil},
+		{WithIfMatch(ctx, []string{`"other"`, etag}), nil},
+		{WithIfMatch(ctx, []string{`"other"`}), ErrPreconditionFailed},
+		{WithIfMatch(ctx, []string{"W/" + etag}), ErrPreconditionFailed},
+		{WithIfMatch(ctx, []string{}), ErrPreconditionFailed},
+	} {
+		if err := CheckIfMatch(test.ctx, app); err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+}
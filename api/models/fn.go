@@ -17,6 +17,7 @@ var (
 	MaxMemory      uint64 = 8 * 1024 // 8GB
 	MaxTimeout     int32  = 300      // 5m
 	MaxIdleTimeout int32  = 3600     // 1h
+	MaxMinReady    uint64 = 32
 
 	DefaultTimeout     int32  = 30  // seconds
 	DefaultIdleTimeout int32  = 30  // seconds
@@ -66,6 +67,14 @@ var (
 		code:  http.StatusBadRequest,
 		error: fmt.Errorf("idle_timeout value is out of range, must be between 0 and %d", MaxIdleTimeout),
 	}
+	ErrFnsInvalidMinReady = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("min_ready value is out of range, must be between 0 and %d", MaxMinReady),
+	}
+	ErrFnsInvalidMaxContainers = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("max_containers value must not be less than min_ready"),
+	}
 	ErrFnsNotFound = err{
 		code:  http.StatusNotFound,
 		error: errors.New("Fn not found"),
@@ -79,6 +88,21 @@ var (
 // FnInvokeEndpointAnnotation is the annotation that exposes the fn invoke endpoint For want of a better place to put this it's here
 const FnInvokeEndpointAnnotation = "fnproject.io/fn/invokeEndpoint"
 
+// FnSysctlsAnnotation is the annotation holding a JSON object of kernel parameters to set in
+// the containers of a function, parameters not allowed by the agent are ignored.
+const FnSysctlsAnnotation = "fnproject.io/fn/sysctls"
+
+// FnConcurrencyAnnotation is the annotation holding the number of calls a single container of a
+// function can serve at the same time, functions without it are sent one call at a time. The
+// output of calls served at the same time can't be told apart: their calls have no log, no
+// stats and are not tailed, see LogUnavailableConcurrent. The output of the containers is
+// logged by the agent in debug, as between calls.
+const FnConcurrencyAnnotation = "fnproject.io/fn/concurrency"
+
+// FnStreamingAnnotation is the annotation set to true on functions that stream their response, the
+// response is then sent to the client as the function writes it instead of once the call completes.
+const FnStreamingAnnotation = "fnproject.io/fn/streaming"
+
 // Fn contains information about a function configuration.
 type Fn struct {
 	// ID is the generated resource id.
@@ -92,14 +116,37 @@ type Fn struct {
 	Image string `json:"image" db:"image"`
 	// ResourceConfig specifies resource constraints.
 	ResourceConfig // embed (TODO or not?)
+	// MinReady is the number of hot containers kept alive for this fn once it has been called.
+	MinReady *uint64 `json:"min_ready,omitempty" db:"min_ready"`
+	// MaxContainers is the maximum number of hot containers running this fn at once on an agent.
+	MaxContainers *uint64 `json:"max_containers,omitempty" db:"max_containers"`
+	// ScalePolicy tunes how the hot containers of this fn scale down when idle.
+	ScalePolicy *ScalePolicy `json:"scale_policy,omitempty" db:"scale_policy"`
+	// RetryPolicy sets how failed async calls of this fn are retried and dead lettered.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty" db:"retry_policy"`
+	// RecyclePolicy sets when the hot containers of this fn are replaced by new ones.
+	RecyclePolicy *RecyclePolicy `json:"recycle_policy,omitempty" db:"recycle_policy"`
+	// RequestSchema is a JSON Schema the request bodies of the calls of this fn must match.
+	RequestSchema RequestSchema `json:"request_schema,omitempty" db:"request_schema"`
+	// ResponseSchema is a JSON Schema describing the response bodies of this fn, it is not
+	// enforced.
+	ResponseSchema ResponseSchema `json:"response_schema,omitempty" db:"response_schema"`
 	// Config is the configuration passed to a function at execution time.
 	Config Config `json:"config" db:"config"`
 	// Annotations allow additional configuration of a function, these are not passed to the function.
 	Annotations Annotations `json:"annotations,omitempty" db:"annotations"`
+	// Revision is the current revision of the image and config of this function, see
+	// FnRevision. It is set by the datastore.
+	Revision int64 `json:"revision,omitempty" db:"revision"`
+	// Canary routes a share of the calls of this function to a candidate revision.
+	Canary *FnCanary `json:"canary,omitempty" db:"canary"`
 	// CreatedAt is the UTC timestamp when this function was created.
 	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
 	// UpdatedAt is the UTC timestamp of the last time this func was modified.
 	UpdatedAt common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+	// DeletedAt is set on functions marked deleted, which may be restored until they are
+	// purged. The functions of a deleted app are deleted along with it.
+	DeletedAt *common.DateTime `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // ResourceConfig specified resource constraints imposed on a function execution.
@@ -177,6 +224,59 @@ func (f *Fn) Validate() error {
 		return ErrInvalidMemory
 	}
 
+	if f.MinReady != nil && *f.MinReady > MaxMinReady {
+		return ErrFnsInvalidMinReady
+	}
+
+	// 0 max_containers is unlimited
+	if f.MaxContainers != nil && *f.MaxContainers > 0 && *f.MaxContainers < f.GetMinReady() {
+		return ErrFnsInvalidMaxContainers
+	}
+
+	if err := f.ScalePolicy.Validate(); err != nil {
+		return err
+	}
+
+	if err := f.RetryPolicy.Validate(); err != nil {
+		return err
+	}
+
+	if err := f.RecyclePolicy.Validate(f.Memory); err != nil {
+		return err
+	}
+
+	if err := f.RequestSchema.Validate(); err != nil {
+		return err
+	}
+
+	if err := f.ResponseSchema.Validate(); err != nil {
+		return err
+	}
+
+	if err := f.Canary.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := PriorityFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := ResponseCacheFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SlotWaitTimeoutFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := HedgeAfterFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := SharedPoolFromAnnotations(f.Annotations); err != nil {
+		return err
+	}
+
 	return f.Annotations.Validate()
 }
 
@@ -184,6 +284,21 @@ func (f *Fn) Clone() *Fn {
 	clone := new(Fn)
 	*clone = *f // shallow copy
 
+	if f.MinReady != nil {
+		minReady := *f.MinReady
+		clone.MinReady = &minReady
+	}
+	if f.MaxContainers != nil {
+		maxContainers := *f.MaxContainers
+		clone.MaxContainers = &maxContainers
+	}
+	clone.ScalePolicy = f.ScalePolicy.Clone()
+	clone.RetryPolicy = f.RetryPolicy.Clone()
+	clone.RecyclePolicy = f.RecyclePolicy.Clone()
+	clone.RequestSchema = f.RequestSchema.Clone()
+	clone.ResponseSchema = f.ResponseSchema.Clone()
+	clone.Canary = f.Canary.Clone()
+
 	// now deep copy the maps
 	if f.Config != nil {
 		clone.Config = make(Config, len(f.Config))
@@ -213,6 +328,14 @@ func (f1 *Fn) Equals(f2 *Fn) bool {
 	eq = eq && f1.Memory == f2.Memory
 	eq = eq && f1.Timeout == f2.Timeout
 	eq = eq && f1.IdleTimeout == f2.IdleTimeout
+	eq = eq && f1.GetMinReady() == f2.GetMinReady()
+	eq = eq && f1.GetMaxContainers() == f2.GetMaxContainers()
+	eq = eq && f1.ScalePolicy.Equals(f2.ScalePolicy)
+	eq = eq && f1.RetryPolicy.Equals(f2.RetryPolicy)
+	eq = eq && f1.RecyclePolicy.Equals(f2.RecyclePolicy)
+	eq = eq && f1.RequestSchema.Equals(f2.RequestSchema)
+	eq = eq && f1.ResponseSchema.Equals(f2.ResponseSchema)
+	eq = eq && f1.Canary.Equals(f2.Canary)
 	eq = eq && f1.Config.Equals(f2.Config)
 	eq = eq && f1.Annotations.Equals(f2.Annotations)
 	// NOTE: datastore tests are not very fun to write with timestamp checks,
@@ -234,6 +357,14 @@ func (f1 *Fn) EqualsWithAnnotationSubset(f2 *Fn) bool {
 	eq = eq && f1.Memory == f2.Memory
 	eq = eq && f1.Timeout == f2.Timeout
 	eq = eq && f1.IdleTimeout == f2.IdleTimeout
+	eq = eq && f1.GetMinReady() == f2.GetMinReady()
+	eq = eq && f1.GetMaxContainers() == f2.GetMaxContainers()
+	eq = eq && f1.ScalePolicy.Equals(f2.ScalePolicy)
+	eq = eq && f1.RetryPolicy.Equals(f2.RetryPolicy)
+	eq = eq && f1.RecyclePolicy.Equals(f2.RecyclePolicy)
+	eq = eq && f1.RequestSchema.Equals(f2.RequestSchema)
+	eq = eq && f1.ResponseSchema.Equals(f2.ResponseSchema)
+	eq = eq && f1.Canary.Equals(f2.Canary)
 	eq = eq && f1.Config.Equals(f2.Config)
 	eq = eq && f1.Annotations.Subset(f2.Annotations)
 	// NOTE: datastore tests are not very fun to write with timestamp checks,
@@ -243,9 +374,28 @@ func (f1 *Fn) EqualsWithAnnotationSubset(f2 *Fn) bool {
 	return eq
 }
 
+// GetMinReady returns the number of hot containers to keep alive for the fn, 0 if unset.
+func (f *Fn) GetMinReady() uint64 {
+	if f.MinReady == nil {
+		return 0
+	}
+	return *f.MinReady
+}
+
+// GetMaxContainers returns the maximum number of hot containers of the fn, 0 if unlimited.
+func (f *Fn) GetMaxContainers() uint64 {
+	if f.MaxContainers == nil {
+		return 0
+	}
+	return *f.MaxContainers
+}
+
 // Update updates fields in f with non-zero field values from new, and sets
 // updated_at if any of the fields change. 0-length slice Header values, and
-// empty-string Config values trigger removal of map entry.
+// empty-string Config values trigger removal of map entry. If the image or
+// config change, the revision is reset for the datastore to record a new one,
+// unless they change to those of the candidate of a canary the patch ends,
+// which is then promoted. The revision of patch is ignored.
 func (f *Fn) Update(patch *Fn) {
 	original := f.Clone()
 
@@ -262,6 +412,57 @@ func (f *Fn) Update(patch *Fn) {
 	if patch.IdleTimeout != 0 {
 		f.IdleTimeout = patch.IdleTimeout
 	}
+	if patch.MinReady != nil {
+		if *patch.MinReady == 0 {
+			f.MinReady = nil // hides it from json
+		} else {
+			minReady := *patch.MinReady
+			f.MinReady = &minReady
+		}
+	}
+	if patch.MaxContainers != nil {
+		if *patch.MaxContainers == 0 {
+			f.MaxContainers = nil // hides it from json
+		} else {
+			maxContainers := *patch.MaxContainers
+			f.MaxContainers = &maxContainers
+		}
+	}
+	if patch.ScalePolicy != nil {
+		if patch.ScalePolicy.IsEmpty() {
+			f.ScalePolicy = nil // hides it from json
+		} else {
+			f.ScalePolicy = patch.ScalePolicy.Clone()
+		}
+	}
+	if patch.RetryPolicy != nil {
+		if patch.RetryPolicy.IsEmpty() {
+			f.RetryPolicy = nil // hides it from json
+		} else {
+			f.RetryPolicy = patch.RetryPolicy.Clone()
+		}
+	}
+	if patch.RecyclePolicy != nil {
+		if patch.RecyclePolicy.IsEmpty() {
+			f.RecyclePolicy = nil // hides it from json
+		} else {
+			f.RecyclePolicy = patch.RecyclePolicy.Clone()
+		}
+	}
+	if patch.RequestSchema != nil {
+		if patch.RequestSchema.IsEmpty() {
+			f.RequestSchema = nil // hides it from json
+		} else {
+			f.RequestSchema = patch.RequestSchema.Clone()
+		}
+	}
+	if patch.ResponseSchema != nil {
+		if patch.ResponseSchema.IsEmpty() {
+			f.ResponseSchema = nil // hides it from json
+		} else {
+			f.ResponseSchema = patch.ResponseSchema.Clone()
+		}
+	}
 	if patch.Config != nil {
 		if f.Config == nil {
 			f.Config = make(Config)
@@ -277,16 +478,40 @@ func (f *Fn) Update(patch *Fn) {
 
 	f.Annotations = f.Annotations.MergeChange(patch.Annotations)
 
+	if patch.Canary != nil {
+		if patch.Canary.IsEmpty() {
+			f.Canary = nil // hides it from json
+		} else {
+			f.Canary = patch.Canary.Clone()
+		}
+	}
+
+	if f.Image != original.Image || !f.Config.Equals(original.Config) {
+		f.Revision = 0
+		if c := original.Canary; f.Canary.IsEmpty() && !c.IsEmpty() && c.Revision > 0 &&
+			c.Image == f.Image && c.Config.Equals(f.Config) {
+			f.Revision = c.Revision
+		}
+	}
 	if !f.Equals(original) {
 		f.UpdatedAt = common.DateTime(time.Now())
 	}
 }
 
 type FnFilter struct {
-	AppID   string // this is exact match
-	Name    string //exact match
-	Cursor  string
-	PerPage int
+	AppID string // this is exact match
+	Name  string //exact match
+	// NamePrefix lists the functions whose name starts with it
+	NamePrefix string
+	Cursor     string
+	PerPage    int
+	// Order is the order of the list and its cursors, by name when zero
+	Order ListOrder
+	// Deleted lists the functions marked deleted in place of the live ones
+	Deleted bool
+	// Annotations and Config list the functions matching all of their key filters
+	Annotations []KeyFilter
+	Config      []KeyFilter
 }
 
 type FnList struct {
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Roles of the revisions of a function with a canary, see Call.Canary
+const (
+	// CanaryStable is the role of the current revision of a function
+	CanaryStable = "stable"
+	// CanaryCandidate is the role of the revision tried by a canary
+	CanaryCandidate = "candidate"
+)
+
+var (
+	ErrFnsNoCanary = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Fn has no canary"),
+	}
+	ErrFnsInvalidCanaryWeight = ErrFnsInvalidCanary("weight value is out of range, must be between 1 and 99")
+)
+
+// FnCanary routes a share of the calls of a function to a candidate revision, to try it on
+// live calls before promoting it to the current revision of the function or aborting it.
+// The image and config of the candidate are kept along, so that calls need no lookup of
+// the revision.
+type FnCanary struct {
+	// Revision is the candidate revision, the datastore records a new revision for a
+	// candidate without one.
+	Revision int64 `json:"revision,omitempty"`
+	// Weight is the percentage of calls routed to the candidate, from 1 to 99.
+	Weight int32  `json:"weight"`
+	Image  string `json:"image"`
+	Config Config `json:"config,omitempty"`
+}
+
+var _ APIError = ErrFnsInvalidCanary("")
+
+// ErrFnsInvalidCanary is returned for canaries that fail validation
+type ErrFnsInvalidCanary string
+
+func (e ErrFnsInvalidCanary) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidCanary) Error() string { return string(e) }
+
+// IsEmpty returns true if the canary routes no calls.
+func (c *FnCanary) IsEmpty() bool {
+	return c == nil || c.Weight == 0
+}
+
+// Routes returns true if a call drawn n, in [0, 100), is routed to the candidate.
+func (c *FnCanary) Routes(n int32) bool {
+	return !c.IsEmpty() && n < c.Weight
+}
+
+// Validate validates all field values, returning the first error, if any.
+func (c *FnCanary) Validate() error {
+	if c.IsEmpty() {
+		return nil
+	}
+
+	if c.Weight < 1 || c.Weight > 99 {
+		return ErrFnsInvalidCanaryWeight
+	}
+	if c.Revision < 0 {
+		return ErrFnsInvalidCanary("revision must not be negative")
+	}
+	if c.Image == "" {
+		return ErrFnsInvalidCanary("Missing image of canary candidate")
+	}
+	return nil
+}
+
+// Equals returns true if both canaries are the same, nil and empty canaries are equal.
+func (c1 *FnCanary) Equals(c2 *FnCanary) bool {
+	if c1.IsEmpty() || c2.IsEmpty() {
+		return c1.IsEmpty() && c2.IsEmpty()
+	}
+	return c1.Revision == c2.Revision &&
+		c1.Weight == c2.Weight &&
+		c1.Image == c2.Image &&
+		c1.Config.Equals(c2.Config)
+}
+
+// Clone returns a copy of the canary.
+func (c *FnCanary) Clone() *FnCanary {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	if c.Config != nil {
+		clone.Config = make(Config, len(c.Config))
+		for k, v := range c.Config {
+			clone.Config[k] = v
+		}
+	}
+	return &clone
+}
+
+// Value implements sql.Valuer, returning a string
+func (c FnCanary) Value() (driver.Value, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(c)
+	return driver.Value(b.String()), err
+}
+
+// Scan implements sql.Scanner
+func (c *FnCanary) Scan(value interface{}) error {
+	if value == nil {
+		*c = FnCanary{}
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		var b []byte
+		switch x := bv.(type) {
+		case []byte:
+			b = x
+		case string:
+			b = []byte(x)
+		}
+
+		if len(b) > 0 {
+			return json.Unmarshal(b, c)
+		}
+
+		*c = FnCanary{}
+		return nil
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("canary invalid db format: %T %T value, err: %v", value, bv, err)
+}
+
+// CandidateRevision returns the candidate revision of the canary of the function.
+func (f *Fn) CandidateRevision() *FnRevision {
+	return f.CandidateFn().CurrentRevision()
+}
+
+// CandidateFn returns the function as it serves the calls routed to the candidate of its
+// canary.
+func (f *Fn) CandidateFn() *Fn {
+	candidate := f.Clone()
+	candidate.Image = f.Canary.Image
+	candidate.Config = make(Config, len(f.Canary.Config))
+	for k, v := range f.Canary.Config {
+		candidate.Config[k] = v
+	}
+	candidate.Revision = f.Canary.Revision
+	candidate.Canary = nil
+	return candidate
+}
+
+// PromotePatch returns the patch which, passed to Update, makes the candidate of the canary
+// the current revision of the function and ends the canary.
+func (f *Fn) PromotePatch() *Fn {
+	return &Fn{
+		ID:     f.ID,
+		Image:  f.Canary.Image,
+		Config: configPatch(f.Config, f.Canary.Config),
+		Canary: &FnCanary{},
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestFnCanaryPromote(t *testing.T) {
+	fn := &Fn{ID: "fn", AppID: "app", Image: "img:1", Config: Config{"A": "1"}, Revision: 1}
+
+	fn.Update(&Fn{Canary: &FnCanary{Weight: 10, Image: "img:2", Config: Config{"A": "2"}}})
+	if fn.Revision != 1 || fn.Canary == nil || fn.Canary.Weight != 10 {
+		t.Fatalf("expected a canary on revision 1, got %+v", fn)
+	}
+	fn.Canary.Revision = 2
+
+	candidate := fn.CandidateFn()
+	if candidate.Revision != 2 || candidate.Image != "img:2" || candidate.Config["A"] != "2" || candidate.Canary != nil {
+		t.Fatalf("unexpected candidate %+v", candidate)
+	}
+	if fn.Image != "img:1" || fn.Config["A"] != "1" {
+		t.Fatalf("expected the candidate to leave the fn alone, got %+v", fn)
+	}
+
+	fn.Update(fn.PromotePatch())
+	if fn.Revision != 2 || fn.Canary != nil || fn.Image != "img:2" || !fn.Config.Equals(Config{"A": "2"}) {
+		t.Fatalf("expected the candidate to be promoted to the current revision, got %+v", fn)
+	}
+}
+
+func TestFnCanaryValidate(t *testing.T) {
+	for _, tc := range []struct {
+		canary *FnCanary
+		valid  bool
+	}{
+		{nil, true},
+		{&FnCanary{}, true},
+		{&FnCanary{Weight: 50, Image: "img"}, true},
+		{&FnCanary{Weight: 100, Image: "img"}, false},
+		{&FnCanary{Weight: -1, Image: "img"}, false},
+		{&FnCanary{Weight: 50}, false},
+		{&FnCanary{Weight: 50, Image: "img", Revision: -1}, false},
+	} {
+		if err := tc.canary.Validate(); (err == nil) != tc.valid {
+			t.Errorf("canary %+v: expected valid %v, got %v", tc.canary, tc.valid, err)
+		}
+	}
+
+	c := &FnCanary{Weight: 10, Image: "img"}
+	if !c.Routes(9) || c.Routes(10) || (*FnCanary)(nil).Routes(0) {
+		t.Fatal("expected a canary to route calls drawn under its weight")
+	}
+}
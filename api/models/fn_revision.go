@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+var (
+	ErrFnRevisionNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Fn revision not found"),
+	}
+	ErrFnRevisionMissingFnID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing Fn ID on Fn revision filter"),
+	}
+	ErrFnRevisionInvalid = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Fn revision must be a positive integer"),
+	}
+)
+
+// FnRevision is an immutable record of the image and config of a function. A new revision is
+// kept every time either of them changes, so that the function can be rolled back to it.
+type FnRevision struct {
+	FnID  string `json:"fn_id" db:"fn_id"`
+	AppID string `json:"app_id" db:"app_id"`
+	// Revision counts the changes of the function from 1, the first revision being the one
+	// the function was created with.
+	Revision int64  `json:"revision" db:"revision"`
+	Image    string `json:"image" db:"image"`
+	Config   Config `json:"config" db:"config"`
+	// CreatedAt is the UTC timestamp when the function was changed to this revision.
+	CreatedAt common.DateTime `json:"created_at" db:"created_at"`
+}
+
+// FnRevisionFilter is a search criteria on the revisions of a function, most recent first
+type FnRevisionFilter struct {
+	//FnID is the function of the revisions - mandatory
+	FnID string
+
+	Cursor  string
+	PerPage int
+}
+
+// FnRevisionList is a container of fn revisions returned by search, optionally indicating the next page cursor
+type FnRevisionList struct {
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Items      []*FnRevision `json:"items"`
+}
+
+// CurrentRevision returns the revision of the function as it is now.
+func (f *Fn) CurrentRevision() *FnRevision {
+	rev := &FnRevision{
+		FnID:      f.ID,
+		AppID:     f.AppID,
+		Revision:  f.Revision,
+		Image:     f.Image,
+		Config:    make(Config, len(f.Config)),
+		CreatedAt: f.UpdatedAt,
+	}
+	for k, v := range f.Config {
+		rev.Config[k] = v
+	}
+	return rev
+}
+
+// RollbackPatch returns the patch which, passed to Update, restores the image and config of
+// the function to those of rev. Rolling back makes a new revision, history is never rewritten.
+func (f *Fn) RollbackPatch(rev *FnRevision) *Fn {
+	return &Fn{
+		ID:     f.ID,
+		Image:  rev.Image,
+		Config: configPatch(f.Config, rev.Config),
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestFnRevisionRollback(t *testing.T) {
+	fn := &Fn{ID: "fn", AppID: "app", Image: "img:1", Config: Config{"A": "1"}, Revision: 1}
+	first := fn.CurrentRevision()
+
+	fn.Update(&Fn{ResourceConfig: ResourceConfig{Memory: 256}})
+	if fn.Revision != 1 {
+		t.Fatalf("expected a memory change to keep revision 1, got %d", fn.Revision)
+	}
+
+	fn.Update(&Fn{Image: "img:2", Config: Config{"A": "", "B": "2"}, Revision: 7})
+	if fn.Revision != 0 {
+		t.Fatalf("expected an image and config change to leave the revision to the datastore, got %d", fn.Revision)
+	}
+	fn.Revision = 2
+
+	fn.Config["C"] = "mutated"
+	if _, ok := first.Config["C"]; ok {
+		t.Fatal("expected revision config to be a copy")
+	}
+	delete(fn.Config, "C")
+
+	fn.Update(fn.RollbackPatch(first))
+	if fn.Revision != 0 || fn.Image != "img:1" || !fn.Config.Equals(Config{"A": "1"}) {
+		t.Fatalf("expected rollback to restore revision 1 as a new revision, got %+v", fn)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -40,9 +41,35 @@ func fnFieldGenerators(t *testing.T) map[string]gopter.Gen {
 	fieldGens["Image"] = gen.AlphaString()
 	fieldGens["Config"] = configGenerator()
 	fieldGens["ResourceConfig"] = resourceConfigGenerator(t)
+	fieldGens["MinReady"] = gen.UInt64().Map(func(n uint64) *uint64 {
+		return &n
+	})
+	fieldGens["MaxContainers"] = gen.UInt64().Map(func(n uint64) *uint64 {
+		return &n
+	})
+	fieldGens["ScalePolicy"] = gen.Int32Range(0, MaxFreezeDelay).Map(func(n int32) *ScalePolicy {
+		return &ScalePolicy{FreezeDelay: n}
+	})
+	fieldGens["RetryPolicy"] = gen.Int32().Map(func(n int32) *RetryPolicy {
+		return &RetryPolicy{MaxAttempts: n}
+	})
+	fieldGens["RecyclePolicy"] = gen.UInt64().Map(func(n uint64) *RecyclePolicy {
+		return &RecyclePolicy{MaxCalls: n}
+	})
+	fieldGens["RequestSchema"] = gen.IntRange(0, 100).Map(func(n int) RequestSchema {
+		return RequestSchema(fmt.Sprintf(`{"maxLength": %d}`, n))
+	})
+	fieldGens["ResponseSchema"] = gen.IntRange(0, 100).Map(func(n int) ResponseSchema {
+		return ResponseSchema(fmt.Sprintf(`{"minLength": %d}`, n))
+	})
 	fieldGens["Annotations"] = annotationGenerator()
+	fieldGens["Revision"] = gen.Int64()
+	fieldGens["Canary"] = gen.Int64().Map(func(n int64) *FnCanary {
+		return &FnCanary{Revision: n, Weight: 50, Image: "img"}
+	})
 	fieldGens["CreatedAt"] = datetimeGenerator()
 	fieldGens["UpdatedAt"] = datetimeGenerator()
+	fieldGens["DeletedAt"] = deletedAtGenerator()
 
 	fnFieldCount := fnReflectType().NumField()
 
@@ -82,7 +109,9 @@ func TestFnEquality(t *testing.T) {
 			for fieldName, fieldGen := range fnFieldGens {
 
 				if fieldName == "CreatedAt" ||
-					fieldName == "UpdatedAt" {
+					fieldName == "UpdatedAt" ||
+					fieldName == "DeletedAt" ||
+					fieldName == "Revision" {
 					continue
 				}
 
@@ -106,3 +135,23 @@ func TestFnEquality(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestFnValidateMaxContainers(t *testing.T) {
+	n := func(v uint64) *uint64 { return &v }
+	for i, test := range []struct {
+		minReady, maxContainers *uint64
+		err                     error
+	}{
+		{nil, nil, nil},
+		{n(2), nil, nil},
+		{n(2), n(0), nil},
+		{n(2), n(2), nil},
+		{n(2), n(1), ErrFnsInvalidMaxContainers},
+	} {
+		fn := &Fn{ID: "fn_id", AppID: "app_id", Name: "myfn", Image: "fnproject/fn-test-utils", MinReady: test.minReady, MaxContainers: test.maxContainers}
+		fn.SetDefaults()
+		if err := fn.Validate(); err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+}
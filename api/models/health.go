@@ -0,0 +1,32 @@
+package models
+
+import "context"
+
+// Pinger is implemented by the backends of a node which can check that they are reachable,
+// without changing their state, to probe the readiness of the node
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Statuses of the health of a node and of its dependencies
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+	HealthDraining    = "draining"
+)
+
+// DependencyHealth is the result of probing a dependency of a node. A node is not ready
+// while a required dependency is unavailable. Why a probe failed is logged by the node, not
+// disclosed to the unauthenticated clients of its health.
+type DependencyHealth struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+}
+
+// NodeHealth is the readiness or liveness of a node, with the health of its dependencies
+type NodeHealth struct {
+	Status       string              `json:"status"`
+	Dependencies []*DependencyHealth `json:"dependencies,omitempty"`
+}
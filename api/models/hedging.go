@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// FnHedgeAfterAnnotation is the fn annotation opting the fn into request hedging. It holds
+// the time in milliseconds a sync call of the fn may wait to be placed on a runner before
+// the load balancer dispatches a second attempt of it, the first attempt to respond wins.
+// Only for functions whose calls are safe to run twice.
+const FnHedgeAfterAnnotation = "fnproject.io/fn/hedgeAfterMs"
+
+var ErrInvalidHedgeAfter = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid hedge after annotation, must be a positive number of milliseconds"),
+}
+
+// HedgeAfterFromAnnotations returns the hedging delay set in fn annotations, 0 if hedging is off.
+func HedgeAfterFromAnnotations(annotations Annotations) (time.Duration, error) {
+	raw, ok := annotations.Get(FnHedgeAfterAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var msecs uint64
+	if err := json.Unmarshal(raw, &msecs); err != nil || msecs == 0 {
+		return 0, ErrInvalidHedgeAfter
+	}
+	return time.Duration(msecs) * time.Millisecond, nil
+}
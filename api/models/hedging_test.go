@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHedgeAfterFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		value    interface{}
+		expected time.Duration
+		valid    bool
+	}{
+		{nil, 0, true},
+		{250, 250 * time.Millisecond, true},
+		{0, 0, false},
+		{-1, 0, false},
+		{"1s", 0, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.value != nil {
+			annotations, err = annotations.With(FnHedgeAfterAnnotation, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		delay, err := HedgeAfterFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid hedge after, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidHedgeAfter {
+			t.Errorf("test %d: expected %v, got: %v", i, ErrInvalidHedgeAfter, err)
+		}
+		if delay != test.expected {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, delay)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "context"
+
+type persistHookKey struct{}
+
+// PersistHook is run by the datastores in the operations creating and updating apps, fns and
+// triggers, once the resource to persist is validated and before it is persisted. resource is
+// the *App, *Fn or *Trigger to persist, which the hook may change, created whether it is
+// created or updated. An error fails the operation, nothing being persisted.
+type PersistHook func(ctx context.Context, resource interface{}, created bool) error
+
+// WithPersistHook returns a context making the datastores run hook in the operations made
+// with it, see PersistHook
+func WithPersistHook(ctx context.Context, hook PersistHook) context.Context {
+	return context.WithValue(ctx, persistHookKey{}, hook)
+}
+
+// PersistHookFrom returns the hook of ctx, nil if it has none
+func PersistHookFrom(ctx context.Context) PersistHook {
+	hook, _ := ctx.Value(persistHookKey{}).(PersistHook)
+	return hook
+}
+
+// RunPersistHook runs the hook of ctx, if it has one, on resource, validating it again once
+// the hook changed it
+func RunPersistHook(ctx context.Context, resource interface{}, created bool) error {
+	hook := PersistHookFrom(ctx)
+	if hook == nil {
+		return nil
+	}
+	if err := hook(ctx, resource, created); err != nil {
+		return err
+	}
+	if v, ok := resource.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Fields of the keys of resources, which key filters apply to
+const (
+	KeyFieldAnnotations = "annotations"
+	KeyFieldConfig      = "config"
+)
+
+const (
+	// MaxKeyFilters is the largest number of key filters of a list
+	MaxKeyFilters = 10
+
+	// maxResourceKeyBytes is the length of the longest key which may be filtered on, longer
+	// keys are not indexed
+	maxResourceKeyBytes = 256
+)
+
+var (
+	ErrInvalidKeyFilter = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid key filter, filters are a key of at most %d bytes, or key=value", maxResourceKeyBytes),
+	}
+	ErrTooManyKeyFilters = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Too many key filters, the maximum is %d", MaxKeyFilters),
+	}
+)
+
+// KeyFilter matches the resources with a key in their annotations or config, with a value if
+// HasValue. The values of annotations which are JSON strings match their text, others their
+// JSON.
+type KeyFilter struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// ParseKeyFilters parses the key filters of a list, of the form key or key=value
+func ParseKeyFilters(filters []string) ([]KeyFilter, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	if len(filters) > MaxKeyFilters {
+		return nil, ErrTooManyKeyFilters
+	}
+
+	res := make([]KeyFilter, 0, len(filters))
+	for _, f := range filters {
+		var filter KeyFilter
+		i := strings.Index(f, "=")
+		if i < 0 {
+			filter.Key = f
+		} else {
+			filter = KeyFilter{Key: f[:i], Value: f[i+1:], HasValue: true}
+		}
+		if filter.Key == "" || len(filter.Key) > maxResourceKeyBytes {
+			return nil, ErrInvalidKeyFilter
+		}
+		res = append(res, filter)
+	}
+	return res, nil
+}
+
+// ResourceKey is a key of the annotations or config of a resource, with its value as key
+// filters match it. Datastores index the keys of resources to filter lists on them.
+type ResourceKey struct {
+	Field string
+	Name  string
+	Value string
+}
+
+// ResourceKeys returns the keys of the config and annotations of a resource, in order
+func ResourceKeys(config Config, annotations Annotations) []ResourceKey {
+	var keys []ResourceKey
+	for k, v := range config {
+		if len(k) <= maxResourceKeyBytes {
+			keys = append(keys, ResourceKey{Field: KeyFieldConfig, Name: k, Value: v})
+		}
+	}
+	for k, v := range annotations {
+		if len(k) <= maxResourceKeyBytes {
+			keys = append(keys, ResourceKey{Field: KeyFieldAnnotations, Name: k, Value: annotationKeyValue(*v)})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Field != keys[j].Field {
+			return keys[i].Field < keys[j].Field
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
+// annotationKeyValue returns the value of an annotation as key filters match it
+func annotationKeyValue(value []byte) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value) // annotations are stored compacted
+}
+
+// MatchKeyFilters returns true if the config and annotations of a resource match all of the
+// filters of the field of each
+func MatchKeyFilters(annotationFilters, configFilters []KeyFilter, config Config, annotations Annotations) bool {
+	for _, f := range annotationFilters {
+		value, ok := annotations.Get(f.Key)
+		if !ok || (f.HasValue && annotationKeyValue(value) != f.Value) {
+			return false
+		}
+	}
+	for _, f := range configFilters {
+		value, ok := config[f.Key]
+		if !ok || (f.HasValue && value != f.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyFilters(t *testing.T) {
+	for i, test := range []struct {
+		filters  []string
+		expected []KeyFilter
+		err      error
+	}{
+		{nil, nil, nil},
+		{[]string{"team"}, []KeyFilter{{Key: "team"}}, nil},
+		{[]string{"team=a=b", "tier="}, []KeyFilter{{Key: "team", Value: "a=b", HasValue: true}, {Key: "tier", HasValue: true}}, nil},
+		{[]string{""}, nil, ErrInvalidKeyFilter},
+		{[]string{"=a"}, nil, ErrInvalidKeyFilter},
+		{[]string{strings.Repeat("k", maxResourceKeyBytes+1)}, nil, ErrInvalidKeyFilter},
+		{make([]string, MaxKeyFilters+1), nil, ErrTooManyKeyFilters},
+	} {
+		filters, err := ParseKeyFilters(test.filters)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(filters, test.expected) {
+			t.Errorf("test %d: expected %+v, got %+v", i, test.expected, filters)
+		}
+	}
+}
+
+func TestMatchKeyFilters(t *testing.T) {
+	annotations, err := EmptyAnnotations().With("team", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations, err = annotations.With("limits", map[string]int{"max": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{"TIER": "prod"}
+
+	keys := ResourceKeys(config, annotations)
+	expected := []ResourceKey{
+		{Field: KeyFieldAnnotations, Name: "limits", Value: `{"max":1}`},
+		{Field: KeyFieldAnnotations, Name: "team", Value: "a"},
+		{Field: KeyFieldConfig, Name: "TIER", Value: "prod"},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected keys %+v, got %+v", expected, keys)
+	}
+
+	for i, test := range []struct {
+		annotations, config []KeyFilter
+		match               bool
+	}{
+		{nil, nil, true},
+		{[]KeyFilter{{Key: "team"}}, nil, true},
+		{[]KeyFilter{{Key: "team", Value: "a", HasValue: true}}, []KeyFilter{{Key: "TIER", Value: "prod", HasValue: true}}, true},
+		{[]KeyFilter{{Key: "limits", Value: `{"max":1}`, HasValue: true}}, nil, true},
+		{[]KeyFilter{{Key: "team", Value: `"a"`, HasValue: true}}, nil, false},
+		{[]KeyFilter{{Key: "TIER"}}, nil, false},
+		{nil, []KeyFilter{{Key: "TIER", Value: "dev", HasValue: true}}, false},
+	} {
+		if match := MatchKeyFilters(test.annotations, test.config, config, annotations); match != test.match {
+			t.Errorf("test %d: expected match %v, got %v", i, test.match, match)
+		}
+	}
+}
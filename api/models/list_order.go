@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Fields which lists of apps, fns and triggers may be ordered by
+const (
+	OrderByName      = "name"
+	OrderByCreatedAt = "created_at"
+	OrderByUpdatedAt = "updated_at"
+)
+
+// Directions of the order of a list
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+var (
+	ErrInvalidListOrder = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid order, lists are ordered by name, created_at or updated_at, asc or desc"),
+	}
+	ErrInvalidListCursor = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid cursor, cursors must be used with the order of the list they were returned by"),
+	}
+)
+
+// ListOrder is the order of a list of apps, fns or triggers, by name ascending when zero.
+// Lists ordered by time are ordered by id after it, so that their cursors stay in place
+// as resources are inserted.
+type ListOrder struct {
+	Field string
+	Desc  bool
+}
+
+// ParseListOrder parses the order of a list, of the field it is ordered by and its direction,
+// either of which may be empty
+func ParseListOrder(orderBy, direction string) (ListOrder, error) {
+	var order ListOrder
+	switch orderBy {
+	case "", OrderByName:
+		order.Field = OrderByName
+	case OrderByCreatedAt, OrderByUpdatedAt:
+		order.Field = orderBy
+	default:
+		return order, ErrInvalidListOrder
+	}
+
+	switch strings.ToLower(direction) {
+	case "", OrderAsc:
+	case OrderDesc:
+		order.Desc = true
+	default:
+		return order, ErrInvalidListOrder
+	}
+	return order, nil
+}
+
+// ByName returns true if the list is ordered by name, whose cursors are the last name listed
+func (o ListOrder) ByName() bool {
+	return o.Field == "" || o.Field == OrderByName
+}
+
+// String returns the order as field:direction
+func (o ListOrder) String() string {
+	field, direction := o.Field, OrderAsc
+	if field == "" {
+		field = OrderByName
+	}
+	if o.Desc {
+		direction = OrderDesc
+	}
+	return field + ":" + direction
+}
+
+// Value returns the value a resource is ordered by, of its name or times. Times are compared
+// as they are formatted.
+func (o ListOrder) Value(name string, createdAt, updatedAt common.DateTime) string {
+	switch o.Field {
+	case OrderByCreatedAt:
+		return createdAt.String()
+	case OrderByUpdatedAt:
+		return updatedAt.String()
+	}
+	return name
+}
+
+// ListCursor is the position of the last resource of a page of an ordered list, after which
+// the next page starts. ID is empty in lists ordered by name.
+type ListCursor struct {
+	Value string
+	ID    string
+}
+
+// EncodeCursor returns the cursor of the next page after the resource with value and id
+func (o ListOrder) EncodeCursor(value, id string) string {
+	if o.ByName() {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(o.String() + "," + value + "," + id))
+}
+
+// DecodeCursor decodes a cursor returned by EncodeCursor with the same order
+func (o ListOrder) DecodeCursor(cursor string) (ListCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ListCursor{}, ErrInvalidListCursor
+	}
+	if o.ByName() {
+		return ListCursor{Value: string(b)}, nil
+	}
+
+	parts := strings.SplitN(string(b), ",", 3)
+	if len(parts) != 3 || parts[0] != o.String() {
+		return ListCursor{}, ErrInvalidListCursor
+	}
+	return ListCursor{Value: parts[1], ID: parts[2]}, nil
+}
+
+// Less returns true if the resource with value a and id aID comes before the one with value
+// b and id bID
+func (o ListOrder) Less(a, aID, b, bID string) bool {
+	if a == b && !o.ByName() {
+		a, b = aID, bID
+	}
+	if o.Desc {
+		return a > b
+	}
+	return a < b
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseListOrder(t *testing.T) {
+	for i, test := range []struct {
+		orderBy, direction string
+		expected           ListOrder
+		err                error
+	}{
+		{"", "", ListOrder{Field: OrderByName}, nil},
+		{"name", "desc", ListOrder{Field: OrderByName, Desc: true}, nil},
+		{"created_at", "ASC", ListOrder{Field: OrderByCreatedAt}, nil},
+		{"updated_at", "DESC", ListOrder{Field: OrderByUpdatedAt, Desc: true}, nil},
+		{"image", "", ListOrder{}, ErrInvalidListOrder},
+		{"name", "up", ListOrder{}, ErrInvalidListOrder},
+	} {
+		order, err := ParseListOrder(test.orderBy, test.direction)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+			continue
+		}
+		if err == nil && order != test.expected {
+			t.Errorf("test %d: expected %+v, got %+v", i, test.expected, order)
+		}
+	}
+}
+
+func TestListOrderCursor(t *testing.T) {
+	// cursors of lists ordered by name are the name listed last, as they always were
+	byName := ListOrder{}
+	cursor := byName.EncodeCursor("app", "id")
+	if cursor != base64.RawURLEncoding.EncodeToString([]byte("app")) {
+		t.Fatalf("expected the cursor of the name, got %s", cursor)
+	}
+	c, err := byName.DecodeCursor(cursor)
+	if err != nil || c != (ListCursor{Value: "app"}) {
+		t.Fatalf("expected the name of the cursor, got %+v, %v", c, err)
+	}
+
+	byTime := ListOrder{Field: OrderByCreatedAt, Desc: true}
+	cursor = byTime.EncodeCursor("2018-01-01T00:00:00.000Z", "id")
+	c, err = byTime.DecodeCursor(cursor)
+	if err != nil || c != (ListCursor{Value: "2018-01-01T00:00:00.000Z", ID: "id"}) {
+		t.Fatalf("expected the time and id of the cursor, got %+v, %v", c, err)
+	}
+
+	for _, order := range []ListOrder{{Field: OrderByCreatedAt}, {Field: OrderByUpdatedAt, Desc: true}} {
+		if _, err := order.DecodeCursor(cursor); err != ErrInvalidListCursor {
+			t.Fatalf("expected %v for the cursor of another order, got %v", ErrInvalidListCursor, err)
+		}
+	}
+	if _, err := byTime.DecodeCursor("!"); err != ErrInvalidListCursor {
+		t.Fatalf("expected %v for an invalid cursor, got %v", ErrInvalidListCursor, err)
+	}
+}
+
+func TestListOrderLess(t *testing.T) {
+	byTime := ListOrder{Field: OrderByCreatedAt}
+	if !byTime.Less("1", "b", "2", "a") || !byTime.Less("1", "a", "1", "b") {
+		t.Fatal("expected times ordered before ids")
+	}
+	byTime.Desc = true
+	if !byTime.Less("2", "a", "1", "b") || !byTime.Less("1", "b", "1", "a") {
+		t.Fatal("expected times and ids ordered desc")
+	}
+	if (ListOrder{}).Less("a", "2", "a", "1") {
+		t.Fatal("expected names not ordered by id")
+	}
+}
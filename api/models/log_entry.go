@@ -0,0 +1,90 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// The streams of the output of a container a log entry may be written to
+const (
+	LogStreamStdout = "stdout"
+	LogStreamStderr = "stderr"
+)
+
+// LogEntry is a line of the log of a call. The logs of calls are stored as a json LogEntry
+// per line when captured structured, and as the raw output of their containers otherwise.
+type LogEntry struct {
+	// Time is when the line was written, zero for lines of raw logs
+	Time common.DateTime `json:"time"`
+	// Stream is the stream the line was written to, empty for lines of raw logs
+	Stream string `json:"stream,omitempty"`
+	// Line is the line, without its newline
+	Line string `json:"line"`
+	// Fields are those of the line if it is a json object
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// NewLogEntry returns the entry of a line written to stream at t, detecting its fields
+func NewLogEntry(t time.Time, stream string, line []byte) *LogEntry {
+	e := &LogEntry{Time: common.DateTime(t), Stream: stream, Line: string(line)}
+	if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]interface{}
+		if json.Unmarshal(trimmed, &fields) == nil {
+			e.Fields = fields
+		}
+	}
+	return e
+}
+
+// ParseLogEntries returns the entries of a log, and whether it was captured structured. The
+// lines of a raw log are returned as entries with no time nor stream, as are the lines of a
+// structured log that are not entries, like the notice of its truncation.
+func ParseLogEntries(log io.Reader) (entries []*LogEntry, structured bool, err error) {
+	r := bufio.NewReader(log)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			e, ok := parseLogEntry(line)
+			if len(entries) == 0 {
+				structured = ok
+			}
+			if !ok || !structured {
+				e = &LogEntry{Line: string(line)}
+			}
+			entries = append(entries, e)
+		}
+		if err == io.EOF {
+			return entries, structured, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+func parseLogEntry(line []byte) (*LogEntry, bool) {
+	if len(line) == 0 || line[0] != '{' {
+		return nil, false
+	}
+	var e LogEntry
+	if json.Unmarshal(line, &e) != nil || e.Stream == "" || time.Time(e.Time).IsZero() {
+		return nil, false
+	}
+	return &e, true
+}
+
+// WriteLogText writes the lines of entries to w, as the raw log they were captured from
+func WriteLogText(w io.Writer, entries []*LogEntry) error {
+	for _, e := range entries {
+		if _, err := io.WriteString(w, e.Line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
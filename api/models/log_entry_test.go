@@ -0,0 +1,76 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLogEntryFields(t *testing.T) {
+	e := NewLogEntry(time.Now(), LogStreamStderr, []byte(`{"level":"info","msg":"hello"}`))
+	if e.Fields["level"] != "info" || e.Fields["msg"] != "hello" {
+		t.Fatalf("expected the fields of the json line, got %v", e.Fields)
+	}
+
+	for _, line := range []string{"hello", `{"truncated":`, `["not", "an", "object"]`} {
+		if e := NewLogEntry(time.Now(), LogStreamStdout, []byte(line)); e.Fields != nil {
+			t.Fatalf("expected no fields for %q, got %v", line, e.Fields)
+		}
+	}
+}
+
+func TestParseLogEntries(t *testing.T) {
+	now := time.Now()
+	var log bytes.Buffer
+	for _, e := range []*LogEntry{
+		NewLogEntry(now, LogStreamStdout, []byte("first")),
+		NewLogEntry(now, LogStreamStderr, []byte(`{"msg":"second"}`)),
+	} {
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Write(append(b, '\n'))
+	}
+	log.WriteString("-----max log size 10 bytes exceeded, truncating log-----\n")
+
+	entries, structured, err := ParseLogEntries(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !structured || len(entries) != 3 {
+		t.Fatalf("expected 3 entries of a structured log, got %d %v", len(entries), structured)
+	}
+	if entries[0].Line != "first" || entries[0].Stream != LogStreamStdout || !time.Time(entries[0].Time).Equal(now.Truncate(time.Millisecond)) {
+		t.Fatalf("expected the first entry, got %+v", entries[0])
+	}
+	if entries[1].Stream != LogStreamStderr || entries[1].Fields["msg"] != "second" {
+		t.Fatalf("expected the second entry with its fields, got %+v", entries[1])
+	}
+	if entries[2].Stream != "" || !strings.HasPrefix(entries[2].Line, "-----max log size") {
+		t.Fatalf("expected the truncation notice as a raw line, got %+v", entries[2])
+	}
+
+	var text bytes.Buffer
+	if err := WriteLogText(&text, entries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text.String(), "first\n{\"msg\":\"second\"}\n-----max") {
+		t.Fatalf("expected the lines of the entries, got %q", text.String())
+	}
+}
+
+func TestParseRawLog(t *testing.T) {
+	entries, structured, err := ParseLogEntries(strings.NewReader("hello\n" + `{"stream":"stdout","time":"2019-01-01T00:00:00.000Z","line":"x"}` + "\nlast"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if structured || len(entries) != 3 {
+		t.Fatalf("expected 3 entries of a raw log, got %d %v", len(entries), structured)
+	}
+	if entries[1].Stream != "" || entries[2].Line != "last" {
+		t.Fatalf("expected the raw lines, got %+v %+v", entries[1], entries[2])
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// LogRedactionAnnotation is the app annotation of the secrets scrubbed from the logs of the
+// calls of the app before they are stored, e.g. {"secret_keys": ["DB_PASSWORD"], "patterns":
+// ["credit_card", "token=\\w+"]}. The secrets are the values of config keys of the fn or its
+// app. Patterns are the names of LogRedactionPatterns or regular expressions.
+//
+// The output of containers docker sends to the syslog URL of the app is not redacted, it
+// doesn't go through fn.
+const LogRedactionAnnotation = "fnproject.io/app/logRedaction"
+
+// LogRedacted replaces the secrets scrubbed from logs
+const LogRedacted = "[REDACTED]"
+
+// LogRedactionPatterns are the patterns of common secrets, by name
+var LogRedactionPatterns = map[string]string{
+	// credit_card matches are only redacted if their digits pass the Luhn check
+	"credit_card":    `\b(?:\d[ -]?){12,18}\d\b`,
+	"bearer_token":   `(?i)\bbearer\s+[a-z0-9._~+/-]+=*`,
+	"jwt":            `\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	"aws_access_key": `\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`,
+}
+
+// logRedactionChecks tell the matches of LogRedactionPatterns that are secrets from the others
+var logRedactionChecks = map[string]func([]byte) bool{
+	"credit_card": luhnValid,
+}
+
+// LogRedaction is the configuration of the redaction of the logs of the calls of an app
+type LogRedaction struct {
+	// SecretKeys are the config keys of the secrets, of the fn or its app
+	SecretKeys []string `json:"secret_keys,omitempty"`
+	// Patterns are the names of LogRedactionPatterns or regular expressions
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+var ErrInvalidLogRedaction = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid log redaction annotation, patterns must be names of known patterns or valid regular expressions"),
+}
+
+// LogRedactionFromAnnotations returns the log redaction set in app annotations, nil if unset.
+func LogRedactionFromAnnotations(annotations Annotations) (*LogRedaction, error) {
+	raw, ok := annotations.Get(LogRedactionAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var redaction LogRedaction
+	if err := json.Unmarshal(raw, &redaction); err != nil {
+		return nil, ErrInvalidLogRedaction
+	}
+	if _, err := compileLogRedactionPatterns(redaction.Patterns); err != nil {
+		return nil, err
+	}
+	return &redaction, nil
+}
+
+type logRedactionPattern struct {
+	re    *regexp.Regexp
+	check func([]byte) bool
+}
+
+func compileLogRedactionPatterns(patterns []string) ([]logRedactionPattern, error) {
+	res := make([]logRedactionPattern, 0, len(patterns))
+	for _, p := range patterns {
+		expr, ok := LogRedactionPatterns[p]
+		if !ok {
+			expr = p
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil || p == "" {
+			return nil, ErrInvalidLogRedaction
+		}
+		res = append(res, logRedactionPattern{re: re, check: logRedactionChecks[p]})
+	}
+	return res, nil
+}
+
+// LogRedactor scrubs secrets from the lines of logs
+type LogRedactor struct {
+	secrets  [][]byte
+	patterns []logRedactionPattern
+}
+
+// NewLogRedactor returns a redactor of secrets and of the matches of patterns, names of
+// LogRedactionPatterns or regular expressions, nil if there is nothing to redact.
+func NewLogRedactor(secrets []string, patterns []string) (*LogRedactor, error) {
+	compiled, err := compileLogRedactionPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	r := &LogRedactor{patterns: compiled}
+	for _, s := range secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, []byte(s))
+		}
+	}
+	if len(r.secrets) == 0 && len(r.patterns) == 0 {
+		return nil, nil
+	}
+	// the longest first, for a secret containing another to be redacted whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	return r, nil
+}
+
+// Redact returns line with its secrets replaced by LogRedacted
+func (r *LogRedactor) Redact(line []byte) []byte {
+	for _, s := range r.secrets {
+		line = bytes.Replace(line, s, []byte(LogRedacted), -1)
+	}
+	for _, p := range r.patterns {
+		line = p.re.ReplaceAllFunc(line, func(m []byte) []byte {
+			if p.check != nil && !p.check(m) {
+				return m
+			}
+			return []byte(LogRedacted)
+		})
+	}
+	return line
+}
+
+// luhnValid returns true if the digits of b pass the Luhn check of card numbers
+func luhnValid(b []byte) bool {
+	var sum, n int
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || b[i] > '9' {
+			continue
+		}
+		d := int(b[i] - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
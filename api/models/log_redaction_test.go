@@ -0,0 +1,59 @@
+package models
+
+import "testing"
+
+func TestLogRedactionFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		valid      bool
+	}{
+		{nil, true},
+		{map[string]interface{}{"secret_keys": []string{"DB_PASSWORD"}}, true},
+		{map[string]interface{}{"patterns": []string{"credit_card", `token=\w+`}}, true},
+		{map[string]interface{}{"patterns": []string{"token=("}}, false},
+		{map[string]interface{}{"patterns": []string{""}}, false},
+		{map[string]interface{}{"secret_keys": "DB_PASSWORD"}, false},
+	} {
+		annotations := EmptyAnnotations()
+		if test.annotation != nil {
+			var err error
+			annotations, err = annotations.With(LogRedactionAnnotation, test.annotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err := LogRedactionFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid log redaction, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidLogRedaction {
+			t.Errorf("test %d: expected invalid log redaction error, got: %v", i, err)
+		}
+	}
+}
+
+func TestLogRedactor(t *testing.T) {
+	r, err := NewLogRedactor([]string{"pass", "password1", ""}, []string{"credit_card", "bearer_token", `sk_live_\w+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for line, expected := range map[string]string{
+		"login with password1":                  "login with [REDACTED]",
+		"pass and password1":                    "[REDACTED] and [REDACTED]",
+		"card 4111 1111 1111 1111 charged":      "card [REDACTED] charged",
+		"order 4111111111111112 shipped":        "order 4111111111111112 shipped",
+		"Authorization: Bearer abc.DEF-123=":    "Authorization: [REDACTED]",
+		"stripe key sk_live_4eC39HqLyjWDarjtT1": "stripe key [REDACTED]",
+		"nothing to see":                        "nothing to see",
+	} {
+		if got := string(r.Redact([]byte(line))); got != expected {
+			t.Errorf("expected %q redacted as %q, got %q", line, expected, got)
+		}
+	}
+
+	if r, err := NewLogRedactor([]string{""}, nil); r != nil || err != nil {
+		t.Fatalf("expected no redactor without secrets nor patterns, got %v %v", r, err)
+	}
+}
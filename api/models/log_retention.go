@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// LogRetentionAnnotation is the app annotation holding the retention of the calls and logs
+// of the app, e.g. {"days": 30, "bytes": 104857600}. The calls and logs of apps are kept
+// regardless of age and size if unset.
+const LogRetentionAnnotation = "fnproject.io/app/logRetention"
+
+// LogRetention bounds how long and how much of the calls of an app, with their logs and
+// results, are kept. A zero bound is unlimited.
+type LogRetention struct {
+	// Days is how many days calls are kept for
+	Days int `json:"days,omitempty"`
+	// Bytes is the most bytes of logs kept for each fn of the app, the calls of a fn are
+	// removed from the oldest once the logs of the more recent ones exceed it
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+var ErrInvalidLogRetention = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid log retention annotation, days and bytes must not be negative"),
+}
+
+// LogRetentionFromAnnotations returns the log retention set in app annotations, nil if unset.
+func LogRetentionFromAnnotations(annotations Annotations) (*LogRetention, error) {
+	raw, ok := annotations.Get(LogRetentionAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var retention LogRetention
+	if err := json.Unmarshal(raw, &retention); err != nil {
+		return nil, ErrInvalidLogRetention
+	}
+	if retention.Days < 0 || retention.Bytes < 0 {
+		return nil, ErrInvalidLogRetention
+	}
+	return &retention, nil
+}
+
+// Expiry returns the time calls created before are expired at now, zero if they do not
+// expire with age.
+func (r *LogRetention) Expiry(now time.Time) time.Time {
+	if r == nil || r.Days == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -r.Days)
+}
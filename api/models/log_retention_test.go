@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogRetentionFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		exp        *LogRetention
+		valid      bool
+	}{
+		{nil, nil, true},
+		{map[string]interface{}{"days": 30}, &LogRetention{Days: 30}, true},
+		{map[string]interface{}{"bytes": 1024}, &LogRetention{Bytes: 1024}, true},
+		{map[string]interface{}{"days": 7, "bytes": 1024}, &LogRetention{Days: 7, Bytes: 1024}, true},
+		{map[string]interface{}{"days": -1}, nil, false},
+		{map[string]interface{}{"bytes": "1GB"}, nil, false},
+		{30, nil, false},
+	} {
+		annotations := EmptyAnnotations()
+		if test.annotation != nil {
+			var err error
+			annotations, err = annotations.With(LogRetentionAnnotation, test.annotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		retention, err := LogRetentionFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid log retention, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidLogRetention {
+			t.Errorf("test %d: expected invalid log retention error, got: %v", i, err)
+		}
+		if (retention == nil) != (test.exp == nil) || (retention != nil && *retention != *test.exp) {
+			t.Errorf("test %d: expected log retention %+v, got %+v", i, test.exp, retention)
+		}
+	}
+}
+
+func TestLogRetentionExpiry(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	if expiry := (&LogRetention{Bytes: 1024}).Expiry(now); !expiry.IsZero() {
+		t.Fatalf("expected calls not to expire with age, got %v", expiry)
+	}
+	if expiry := (&LogRetention{Days: 9}).Expiry(now); !expiry.Equal(time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected calls to expire after 9 days, got %v", expiry)
+	}
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// LogSeverities are the severities of log entries, from the least to the most severe
+var LogSeverities = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// logSeverityAliases are other names of severities, as set by common logging libraries
+var logSeverityAliases = map[string]string{
+	"warning":  "warn",
+	"err":      "error",
+	"critical": "fatal",
+	"panic":    "fatal",
+}
+
+// logSeverityFields are the fields of entries their severity is read from
+var logSeverityFields = []string{"level", "severity", "lvl"}
+
+var ErrInvalidLogSeverity = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid log severity, must be one of trace, debug, info, warn, error or fatal"),
+}
+
+// LogFilter selects the entries of a log. Time and severity are only known for the entries
+// of structured logs, only they match a filter with either set.
+type LogFilter struct {
+	// FromTime and ToTime bound the time of the entries, excluded, if not zero
+	FromTime common.DateTime
+	ToTime   common.DateTime
+	// Match is text the lines of the entries contain, regardless of case, if not empty
+	Match string
+	// Severity is the least severity of the entries, one of LogSeverities, if not empty
+	Severity string
+}
+
+// Validate returns an error if the severity of the filter is unknown
+func (f *LogFilter) Validate() error {
+	if f.Severity != "" && logSeverityRank(f.Severity) < 0 {
+		return ErrInvalidLogSeverity
+	}
+	return nil
+}
+
+// IsZero returns true if the filter selects every entry
+func (f *LogFilter) IsZero() bool {
+	return time.Time(f.FromTime).IsZero() && time.Time(f.ToTime).IsZero() && f.Match == "" && f.Severity == ""
+}
+
+// MatchesStored returns true if the text matched can be matched against the stored logs of
+// the lines it is in, for stores to only read those. It can't if it has characters escaped
+// in the json of structured logs, or non ascii ones whose case stores may fold differently.
+func (f *LogFilter) MatchesStored() bool {
+	if f.Match == "" {
+		return false
+	}
+	for _, r := range f.Match {
+		if r < 0x20 || r >= 0x80 || strings.ContainsRune("\"\\<>&", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if the filter selects e
+func (f *LogFilter) Matches(e *LogEntry) bool {
+	t := time.Time(e.Time)
+	if !time.Time(f.FromTime).IsZero() && (t.IsZero() || !t.After(time.Time(f.FromTime))) {
+		return false
+	}
+	if !time.Time(f.ToTime).IsZero() && (t.IsZero() || !t.Before(time.Time(f.ToTime))) {
+		return false
+	}
+	if f.Match != "" && !strings.Contains(strings.ToLower(e.Line), strings.ToLower(f.Match)) {
+		return false
+	}
+	if f.Severity != "" && logSeverityRank(e.Severity()) < logSeverityRank(f.Severity) {
+		return false
+	}
+	return true
+}
+
+// Severity returns the severity of the entry, read from its fields, empty if unknown
+func (e *LogEntry) Severity() string {
+	for _, field := range logSeverityFields {
+		if s, ok := e.Fields[field].(string); ok && logSeverityRank(s) >= 0 {
+			return normalizeLogSeverity(s)
+		}
+	}
+	return ""
+}
+
+func normalizeLogSeverity(s string) string {
+	s = strings.ToLower(s)
+	if alias, ok := logSeverityAliases[s]; ok {
+		return alias
+	}
+	return s
+}
+
+// logSeverityRank returns the index of s in LogSeverities, -1 if it is not a severity
+func logSeverityRank(s string) int {
+	s = normalizeLogSeverity(s)
+	for i, severity := range LogSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// FilterLog returns the entries of log selected by filter, for log stores that can't
+// filter logs themselves
+func FilterLog(log io.Reader, filter *LogFilter) ([]*LogEntry, error) {
+	entries, _, err := ParseLogEntries(log)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*LogEntry, 0, len(entries))
+	for _, e := range entries {
+		if filter.Matches(e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+func TestLogFilterMatches(t *testing.T) {
+	now := time.Now()
+	warn := NewLogEntry(now, LogStreamStderr, []byte(`{"severity":"WARNING","msg":"Disk low"}`))
+	info := NewLogEntry(now, LogStreamStdout, []byte(`{"level":"info","msg":"ok"}`))
+	plain := NewLogEntry(now, LogStreamStdout, []byte("disk checked"))
+	raw := &LogEntry{Line: "disk raw"}
+
+	for i, test := range []struct {
+		filter   LogFilter
+		expected []*LogEntry
+	}{
+		{LogFilter{}, []*LogEntry{warn, info, plain, raw}},
+		{LogFilter{Match: "DISK"}, []*LogEntry{warn, plain, raw}},
+		{LogFilter{Severity: "info"}, []*LogEntry{warn, info}},
+		{LogFilter{Severity: "warn"}, []*LogEntry{warn}},
+		{LogFilter{Severity: "error"}, nil},
+		{LogFilter{FromTime: common.DateTime(now.Add(-time.Second))}, []*LogEntry{warn, info, plain}},
+		{LogFilter{ToTime: common.DateTime(now)}, nil},
+	} {
+		var got []*LogEntry
+		for _, e := range []*LogEntry{warn, info, plain, raw} {
+			if test.filter.Matches(e) {
+				got = append(got, e)
+			}
+		}
+		if len(got) != len(test.expected) {
+			t.Fatalf("Test %d: expected %d entries, got %d", i, len(test.expected), len(got))
+		}
+		for j := range got {
+			if got[j] != test.expected[j] {
+				t.Fatalf("Test %d: expected %q, got %q", i, test.expected[j].Line, got[j].Line)
+			}
+		}
+	}
+}
+
+func TestLogFilterValidate(t *testing.T) {
+	for _, severity := range []string{"", "debug", "ERROR", "warning", "critical"} {
+		if err := (&LogFilter{Severity: severity}).Validate(); err != nil {
+			t.Fatalf("expected severity %q to be valid, got %v", severity, err)
+		}
+	}
+	if err := (&LogFilter{Severity: "loud"}).Validate(); err != ErrInvalidLogSeverity {
+		t.Fatalf("expected an unknown severity to be invalid, got %v", err)
+	}
+}
+
+func TestLogFilterMatchesStored(t *testing.T) {
+	for match, expected := range map[string]bool{
+		"":          false,
+		"disk low":  true,
+		"100%_done": true,
+		`say "hi"`:  false,
+		"a<b":       false,
+		"tab\tstop": false,
+		"café":      false,
+	} {
+		if got := (&LogFilter{Match: match}).MatchesStored(); got != expected {
+			t.Fatalf("expected %q to be matched against stored logs %v, got %v", match, expected, got)
+		}
+	}
+}
+
+func TestFilterLog(t *testing.T) {
+	entries, err := FilterLog(strings.NewReader("one\ntwo\nthree\n"), &LogFilter{Match: "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Line != "two" || entries[1].Line != "three" {
+		t.Fatalf("expected the lines matched, got %+v", entries)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/fnproject/fn/api/common"
+
+// LogLine is a line of the output, stdout or stderr, of a container of a function written
+// during a call, as streamed to the tails of the function.
+type LogLine struct {
+	FnID   string          `json:"fn_id"`
+	CallID string          `json:"call_id"`
+	Time   common.DateTime `json:"time"`
+	Line   string          `json:"line"`
+}
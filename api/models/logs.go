@@ -14,11 +14,40 @@ type LogStore interface {
 	// cannot be found.
 	GetLog(ctx context.Context, fnID, callID string) (io.Reader, error)
 
+	// SearchLog returns the entries of the log at callID selected by filter, see
+	// LogFilter. Stores narrow the search down themselves where they can. An error will be
+	// returned if the log cannot be found.
+	SearchLog(ctx context.Context, fnID, callID string, filter *LogFilter) ([]*LogEntry, error)
+
+	// InsertResult will insert the response payload of a detached call at callID,
+	// overwriting if it previously existed.
+	InsertResult(ctx context.Context, call *Call, result io.Reader) error
+
+	// GetResult will return the response payload of a detached call at callID, an
+	// error will be returned if the result cannot be found.
+	GetResult(ctx context.Context, fnID, callID string) (io.Reader, error)
+
+	// InsertDeadLetter stores an async call which failed on every attempt allowed by the
+	// retry policy of its fn. The whole call is kept, including its payload, so that it
+	// can be replayed.
+	InsertDeadLetter(ctx context.Context, call *Call) error
+
+	// GetDeadLetter returns the dead lettered call at callID, ErrDeadLetterNotFound is
+	// returned if there is none.
+	GetDeadLetter(ctx context.Context, fnID, callID string) (*Call, error)
+
+	// GetDeadLetters returns the dead lettered calls of filter.FnID, most recent first,
+	// paginated by filter.Cursor and filter.PerPage.
+	GetDeadLetters(ctx context.Context, filter *CallFilter) (*CallList, error)
+
+	// RemoveDeadLetter removes a dead lettered call, e.g. once it is replayed.
+	// ErrDeadLetterNotFound is returned if there is none.
+	RemoveDeadLetter(ctx context.Context, fnID, callID string) error
+
 	// TODO we should probably allow deletion of a range of logs (also calls)?
 	// common cases for deletion will be:
 	// * fn gets nuked
 	// * app gets nuked
-	// * call+logs getting cleaned up periodically
 
 	// InsertCall inserts a call into the datastore, it will error if the call already
 	// exists.
@@ -31,6 +60,10 @@ type LogStore interface {
 	// calls exist, an empty list and a nil error are returned.
 	GetCalls(ctx context.Context, filter *CallFilter) (*CallList, error)
 
+	// RemoveCall removes a call along with its log and result, e.g. once it expired.
+	// ErrCallNotFound is returned if none of them exist.
+	RemoveCall(ctx context.Context, fnID, callID string) error
+
 	// Close will close any underlying connections as needed.
 	// Close is not safe to be called from multiple threads.
 	io.Closer
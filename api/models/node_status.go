@@ -0,0 +1,71 @@
+package models
+
+// NodeStatus describes the capacity of a server running functions, as reported on the
+// admin API.
+type NodeStatus struct {
+	// Draining is true once the server stopped accepting new calls
+	Draining bool `json:"draining"`
+	// InFlight is the number of calls in flight on the server
+	InFlight uint64 `json:"in_flight"`
+	// MaxInFlight is the most calls the server takes in flight at once, 0 if unlimited
+	MaxInFlight uint64 `json:"max_in_flight,omitempty"`
+	// Resources is the memory and CPU of the server reserved by containers
+	Resources NodeResources `json:"resources"`
+	// Functions are the functions with hot containers or calls waiting for one, sorted by id
+	Functions []*FnStatus `json:"functions"`
+	// ImageCache is the content of the image cache of the server, nil if it has none
+	ImageCache *ImageCacheStatus `json:"image_cache,omitempty"`
+	// Runners are the status of each runner of a load balancer, whose own status sums theirs
+	Runners []*RunnerNodeStatus `json:"runners,omitempty"`
+}
+
+// RunnerNodeStatus is the status of a runner behind a load balancer.
+type RunnerNodeStatus struct {
+	Address string `json:"address"`
+	// Status is the status of the runner, nil if it could not be fetched
+	Status *NodeStatus `json:"status,omitempty"`
+	// Error is why the status of the runner could not be fetched
+	Error string `json:"error,omitempty"`
+}
+
+// NodeResources is the memory and CPU of a server, as tracked to place containers.
+type NodeResources struct {
+	MemoryTotal uint64    `json:"memory_total"`
+	MemoryUsed  uint64    `json:"memory_used"`
+	MemoryFree  uint64    `json:"memory_free"`
+	CPUTotal    MilliCPUs `json:"cpu_total"`
+	CPUUsed     MilliCPUs `json:"cpu_used"`
+	CPUFree     MilliCPUs `json:"cpu_free"`
+}
+
+// FnStatus is the hot containers and calls of a function on a server. Functions sharing a
+// pool of hot containers report the containers of the pool.
+type FnStatus struct {
+	FnID string `json:"fn_id"`
+	// Containers is the number of hot containers by state, eg. idle or busy
+	Containers map[string]uint64 `json:"containers"`
+	// QueueDepth is the number of calls waiting for a hot container
+	QueueDepth uint64 `json:"queue_depth"`
+	// Executing is the number of calls running in a hot container
+	Executing uint64 `json:"executing"`
+}
+
+// ImageCacheStatus is the content of the image cache of a server.
+type ImageCacheStatus struct {
+	// MaxSize is the size in bytes the cache evicts unused images beyond
+	MaxSize uint64 `json:"max_size"`
+	// BusySize is the size in bytes of the images used by containers
+	BusySize uint64 `json:"busy_size"`
+	// IdleSize is the size in bytes of the images which may be evicted
+	IdleSize uint64 `json:"idle_size"`
+	// Images are the cached images, in use first then the most recently used first
+	Images []*CachedImageStatus `json:"images"`
+}
+
+// CachedImageStatus is an image in the image cache of a server.
+type CachedImageStatus struct {
+	ID    string   `json:"id"`
+	Tags  []string `json:"tags,omitempty"`
+	Size  uint64   `json:"size"`
+	InUse bool     `json:"in_use"`
+}
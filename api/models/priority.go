@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Priority classes of calls, see Call.Priority. When a runner is saturated, calls waiting for
+// a hot container or for resources to start one are served in priority order.
+const (
+	// PriorityLow is for batch work that yields to other calls
+	PriorityLow int32 = iota
+	// PriorityNormal is the priority of calls of functions that do not set one
+	PriorityNormal
+	// PriorityHigh is for interactive calls that go ahead of other calls
+	PriorityHigh
+)
+
+// PriorityAnnotation is the app or fn annotation holding the priority class of its calls,
+// one of "low", "normal" or "high". A fn annotation takes precedence over the app one.
+const PriorityAnnotation = "fnproject.io/priority"
+
+var priorityClasses = map[string]int32{
+	"low":    PriorityLow,
+	"normal": PriorityNormal,
+	"high":   PriorityHigh,
+}
+
+var ErrInvalidPriority = err{
+	code:  http.StatusBadRequest,
+	error: errors.New(`Invalid priority annotation, must be one of "low", "normal" or "high"`),
+}
+
+// PriorityFromAnnotations returns the priority class set in annotations, PriorityNormal if unset.
+func PriorityFromAnnotations(annotations Annotations) (int32, error) {
+	if _, ok := annotations.Get(PriorityAnnotation); !ok {
+		return PriorityNormal, nil
+	}
+
+	class, err := annotations.GetString(PriorityAnnotation)
+	if err != nil {
+		return PriorityNormal, ErrInvalidPriority
+	}
+	priority, ok := priorityClasses[class]
+	if !ok {
+		return PriorityNormal, ErrInvalidPriority
+	}
+	return priority, nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestPriorityFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		value    interface{}
+		priority int32
+		valid    bool
+	}{
+		{nil, PriorityNormal, true},
+		{"low", PriorityLow, true},
+		{"normal", PriorityNormal, true},
+		{"high", PriorityHigh, true},
+		{"urgent", PriorityNormal, false},
+		{2, PriorityNormal, false},
+	} {
+		annotations := EmptyAnnotations()
+		if test.value != nil {
+			var err error
+			annotations, err = annotations.With(PriorityAnnotation, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		priority, err := PriorityFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid priority, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidPriority {
+			t.Errorf("test %d: expected invalid priority error, got: %v", i, err)
+		}
+		if priority != test.priority {
+			t.Errorf("test %d: expected priority %d, got %d", i, test.priority, priority)
+		}
+	}
+}
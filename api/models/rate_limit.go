@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// AppRateLimitAnnotation is the app annotation holding the rate limit of the calls of the
+// app, e.g. {"rate": 10, "burst": 20}, calls are not limited if unset.
+const AppRateLimitAnnotation = "fnproject.io/app/rateLimit"
+
+// RateLimit limits calls to Rate calls per second on average, with bursts of up to Burst
+// calls. Burst defaults to Rate rounded up.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+var (
+	ErrInvalidRateLimit = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid rate limit, rate must be a positive number of calls per second and burst must not be negative"),
+	}
+	ErrRateLimited = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Too many calls, rate limit exceeded"),
+	}
+)
+
+// Validate checks that the rate limit is valid
+func (l *RateLimit) Validate() error {
+	if l.Rate <= 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) || l.Burst < 0 {
+		return ErrInvalidRateLimit
+	}
+	return nil
+}
+
+// BurstOrDefault returns the burst of the rate limit, defaulting to the rate rounded up
+func (l *RateLimit) BurstOrDefault() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return int(math.Ceil(l.Rate))
+}
+
+// Value implements sql.Valuer, returning a string
+func (l *RateLimit) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (l *RateLimit) Scan(value interface{}) error {
+	if value == nil {
+		*l = RateLimit{}
+		return nil
+	}
+
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("rate limit invalid db format: %T %T value, err: %v", value, v, v)
+	}
+	return json.Unmarshal(b, l)
+}
+
+// RateLimitFromAnnotations returns the rate limit set in app annotations, nil if unset.
+func RateLimitFromAnnotations(annotations Annotations) (*RateLimit, error) {
+	raw, ok := annotations.Get(AppRateLimitAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var limit RateLimit
+	if err := json.Unmarshal(raw, &limit); err != nil {
+		return nil, ErrInvalidRateLimit
+	}
+	if err := limit.Validate(); err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestRateLimitFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   *RateLimit
+		burst      int
+		valid      bool
+	}{
+		{nil, nil, 0, true},
+		{map[string]interface{}{"rate": 10, "burst": 20}, &RateLimit{Rate: 10, Burst: 20}, 20, true},
+		{map[string]interface{}{"rate": 0.5}, &RateLimit{Rate: 0.5}, 1, true},
+		{map[string]interface{}{"rate": 2.5}, &RateLimit{Rate: 2.5}, 3, true},
+		{map[string]interface{}{"rate": 0}, nil, 0, false},
+		{map[string]interface{}{"rate": 10, "burst": -1}, nil, 0, false},
+		{map[string]interface{}{"burst": 10}, nil, 0, false},
+		{"10/s", nil, 0, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.annotation != nil {
+			annotations, err = annotations.With(AppRateLimitAnnotation, test.annotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		limit, err := RateLimitFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid rate limit, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidRateLimit {
+			t.Errorf("test %d: expected invalid rate limit error, got: %v", i, err)
+		}
+		if (limit == nil) != (test.expected == nil) || (limit != nil && (*limit != *test.expected || limit.BurstOrDefault() != test.burst)) {
+			t.Errorf("test %d: expected rate limit %+v, got %+v", i, test.expected, limit)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// MaxRecycleAge is the oldest a recycle policy may let a hot container get, in seconds.
+	MaxRecycleAge int32 = 7 * 24 * 3600 // 1w
+)
+
+// RecyclePolicy sets when the hot containers of a function are replaced by new ones, eg. to
+// protect against functions leaking memory. A replacement container is started before the
+// recycled one stops taking calls.
+type RecyclePolicy struct {
+	// MaxCalls recycles a container once it served this many calls, 0 for no limit.
+	MaxCalls uint64 `json:"max_calls,omitempty"`
+	// MaxAge recycles a container once it has been running for this many seconds, 0 for no
+	// limit.
+	MaxAge int32 `json:"max_age,omitempty"`
+	// MaxMemory recycles a container once its memory usage grows beyond this many MB, 0 for
+	// no limit.
+	MaxMemory uint64 `json:"max_memory_mb,omitempty"`
+}
+
+var _ APIError = ErrFnsInvalidRecyclePolicy("")
+
+// ErrFnsInvalidRecyclePolicy is returned for recycle policies that fail validation
+type ErrFnsInvalidRecyclePolicy string
+
+func (e ErrFnsInvalidRecyclePolicy) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidRecyclePolicy) Error() string { return string(e) }
+
+// IsEmpty returns true if the policy never recycles containers.
+func (p *RecyclePolicy) IsEmpty() bool {
+	return p == nil || (p.MaxCalls == 0 && p.MaxAge == 0 && p.MaxMemory == 0)
+}
+
+// ShouldRecycle returns the reason a container that served calls, started at start and uses
+// mem bytes of memory is recycled, or an empty string if it is not.
+func (p *RecyclePolicy) ShouldRecycle(calls uint64, start, now time.Time, mem uint64) string {
+	switch {
+	case p == nil:
+		return ""
+	case p.MaxCalls > 0 && calls >= p.MaxCalls:
+		return "max_calls"
+	case p.MaxAge > 0 && now.Sub(start) >= time.Duration(p.MaxAge)*time.Second:
+		return "max_age"
+	case p.MaxMemory > 0 && mem > p.MaxMemory*1024*1024:
+		return "max_memory"
+	}
+	return ""
+}
+
+// Validate validates all field values, returning the first error, if any.
+func (p *RecyclePolicy) Validate(memory uint64) error {
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxAge < 0 || p.MaxAge > MaxRecycleAge {
+		return ErrFnsInvalidRecyclePolicy(fmt.Sprintf("max_age value is out of range, must be between 0 and %d", MaxRecycleAge))
+	}
+	if p.MaxMemory > memory {
+		return ErrFnsInvalidRecyclePolicy("max_memory_mb must not be greater than the memory of the function")
+	}
+	return nil
+}
+
+// Equals returns true if both policies are the same, nil and empty policies are equal.
+func (p1 *RecyclePolicy) Equals(p2 *RecyclePolicy) bool {
+	if p1.IsEmpty() || p2.IsEmpty() {
+		return p1.IsEmpty() && p2.IsEmpty()
+	}
+	return *p1 == *p2
+}
+
+// Clone returns a copy of the policy.
+func (p *RecyclePolicy) Clone() *RecyclePolicy {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// Value implements sql.Valuer, returning a string
+func (p RecyclePolicy) Value() (driver.Value, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(p)
+	return driver.Value(b.String()), err
+}
+
+// Scan implements sql.Scanner
+func (p *RecyclePolicy) Scan(value interface{}) error {
+	if value == nil {
+		*p = RecyclePolicy{}
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		var b []byte
+		switch x := bv.(type) {
+		case []byte:
+			b = x
+		case string:
+			b = []byte(x)
+		}
+
+		if len(b) > 0 {
+			return json.Unmarshal(b, p)
+		}
+
+		*p = RecyclePolicy{}
+		return nil
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("recycle policy invalid db format: %T %T value, err: %v", value, bv, err)
+}
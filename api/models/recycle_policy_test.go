@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecyclePolicyValidate(t *testing.T) {
+	for i, test := range []struct {
+		policy *RecyclePolicy
+		valid  bool
+	}{
+		{nil, true},
+		{&RecyclePolicy{}, true},
+		{&RecyclePolicy{MaxCalls: 1000, MaxAge: 3600, MaxMemory: 100}, true},
+		{&RecyclePolicy{MaxAge: MaxRecycleAge}, true},
+		{&RecyclePolicy{MaxMemory: 128}, true},
+		{&RecyclePolicy{MaxAge: -1}, false},
+		{&RecyclePolicy{MaxAge: MaxRecycleAge + 1}, false},
+		{&RecyclePolicy{MaxMemory: 129}, false},
+	} {
+		err := test.policy.Validate(128)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid policy, got: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected invalid policy", i)
+		}
+	}
+}
+
+func TestRecyclePolicyShouldRecycle(t *testing.T) {
+	start := time.Now()
+	policy := &RecyclePolicy{MaxCalls: 10, MaxAge: 60, MaxMemory: 100}
+
+	for i, test := range []struct {
+		policy *RecyclePolicy
+		calls  uint64
+		age    time.Duration
+		mem    uint64
+		reason string
+	}{
+		{nil, 1000, time.Hour, 1 << 30, ""},
+		{&RecyclePolicy{}, 1000, time.Hour, 1 << 30, ""},
+		{policy, 9, 59 * time.Second, 100 * 1024 * 1024, ""},
+		{policy, 10, 0, 0, "max_calls"},
+		{policy, 0, time.Minute, 0, "max_age"},
+		{policy, 0, 0, 100*1024*1024 + 1, "max_memory"},
+	} {
+		reason := test.policy.ShouldRecycle(test.calls, start, start.Add(test.age), test.mem)
+		if reason != test.reason {
+			t.Errorf("test %d: expected recycle reason %q, got %q", i, test.reason, reason)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fnproject/fn/api/jsonschema"
+)
+
+// MaxRequestSchemaSize is the largest request schema of a function, in bytes.
+const MaxRequestSchemaSize = 64 * 1024
+
+// RequestSchema is a JSON Schema the request bodies of the calls of a function must match,
+// calls with other bodies are rejected before running the function. An empty object removes
+// it from a function.
+type RequestSchema json.RawMessage
+
+var _ APIError = ErrFnsInvalidRequestSchema("")
+
+// ErrFnsInvalidRequestSchema is returned for request schemas that fail validation
+type ErrFnsInvalidRequestSchema string
+
+func (e ErrFnsInvalidRequestSchema) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidRequestSchema) Error() string { return "Invalid request schema: " + string(e) }
+
+// ValidationError is a part of a request body which does not match the request schema of the
+// function called.
+type ValidationError struct {
+	// Path is the JSON pointer of the invalid value in the body, empty for the whole body
+	Path string `json:"path"`
+	// Message describes why the value is invalid
+	Message string `json:"message"`
+}
+
+// RequestValidationError is an APIError for a call whose request body does not match the
+// request schema of its function, along with the parts of the body which do not.
+type RequestValidationError struct {
+	Errors []ValidationError
+}
+
+var ErrRequestSchemaMismatch = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Request body does not match the request schema of the function"),
+}
+
+func (e *RequestValidationError) Code() int     { return ErrRequestSchemaMismatch.Code() }
+func (e *RequestValidationError) Error() string { return ErrRequestSchemaMismatch.Error() }
+
+// RootError implements APIErrorWrapper
+func (e *RequestValidationError) RootError() error {
+	if len(e.Errors) == 0 {
+		return ErrRequestSchemaMismatch
+	}
+	return fmt.Errorf("%s: %s", e.Errors[0].Path, e.Errors[0].Message)
+}
+
+// IsEmpty returns true if the schema does not constrain request bodies.
+func (s RequestSchema) IsEmpty() bool {
+	trimmed := bytes.TrimSpace(s)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return true
+	}
+	var m map[string]json.RawMessage
+	return json.Unmarshal(trimmed, &m) == nil && len(m) == 0
+}
+
+// Validate validates the schema, returning the first error, if any.
+func (s RequestSchema) Validate() error {
+	if s.IsEmpty() {
+		return nil
+	}
+	if len(s) > MaxRequestSchemaSize {
+		return ErrFnsInvalidRequestSchema(fmt.Sprintf("must be at most %d bytes", MaxRequestSchemaSize))
+	}
+	if _, err := jsonschema.Compile(s); err != nil {
+		return ErrFnsInvalidRequestSchema(err.Error())
+	}
+	return nil
+}
+
+// Compile returns the compiled schema, nil if it is empty.
+func (s RequestSchema) Compile() (*jsonschema.Schema, error) {
+	if s.IsEmpty() {
+		return nil, nil
+	}
+	return jsonschema.Compile(s)
+}
+
+// Equals returns true if both schemas are the same, nil and empty schemas are equal.
+func (s1 RequestSchema) Equals(s2 RequestSchema) bool {
+	if s1.IsEmpty() || s2.IsEmpty() {
+		return s1.IsEmpty() && s2.IsEmpty()
+	}
+	return bytes.Equal(s1, s2)
+}
+
+// Clone returns a copy of the schema.
+func (s RequestSchema) Clone() RequestSchema {
+	if s == nil {
+		return nil
+	}
+	return append(RequestSchema(nil), s...)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s RequestSchema) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	return s, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *RequestSchema) UnmarshalJSON(data []byte) error {
+	*s = append((*s)[0:0], data...)
+	return nil
+}
+
+// Value implements sql.Valuer, returning a string
+func (s RequestSchema) Value() (driver.Value, error) {
+	if s.IsEmpty() {
+		return nil, nil
+	}
+	return driver.Value(string(s)), nil
+}
+
+// Scan implements sql.Scanner
+func (s *RequestSchema) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		switch x := bv.(type) {
+		case []byte:
+			*s = append(RequestSchema(nil), x...)
+			return nil
+		case string:
+			*s = RequestSchema(x)
+			return nil
+		}
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("request schema invalid db format: %T %T value, err: %v", value, bv, err)
+}
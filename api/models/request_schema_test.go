@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestRequestSchemaValidate(t *testing.T) {
+	for i, test := range []struct {
+		schema RequestSchema
+		valid  bool
+	}{
+		{nil, true},
+		{RequestSchema(`{}`), true},
+		{RequestSchema(`{"type": "object", "required": ["name"]}`), true},
+		{RequestSchema(`{"type": "text"}`), false},
+		{RequestSchema(`"object"`), false},
+		{RequestSchema(`{"type": "object"`), false},
+	} {
+		err := test.schema.Validate()
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid request schema, got: %v", i, err)
+		}
+		if _, ok := err.(ErrFnsInvalidRequestSchema); !test.valid && !ok {
+			t.Errorf("test %d: expected invalid request schema error, got: %v", i, err)
+		}
+	}
+}
+
+func TestRequestSchemaUpdate(t *testing.T) {
+	fn := &Fn{}
+	fn.Update(&Fn{RequestSchema: RequestSchema(`{"type": "array"}`)})
+	if string(fn.RequestSchema) != `{"type": "array"}` {
+		t.Fatalf("expected the request schema to be set, got %s", fn.RequestSchema)
+	}
+	fn.Update(&Fn{})
+	if fn.RequestSchema == nil {
+		t.Fatal("expected the request schema to be kept")
+	}
+	fn.Update(&Fn{RequestSchema: RequestSchema(` { } `)})
+	if fn.RequestSchema != nil {
+		t.Fatalf("expected the request schema to be removed, got %s", fn.RequestSchema)
+	}
+}
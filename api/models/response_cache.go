@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// FnResponseCacheAnnotation is the annotation of idempotent functions whose responses are
+// cached, e.g. {"ttl": 60, "status_codes": [200, 404]}. Calls with the same method, path,
+// query, body and vary_headers are then answered from the cache for ttl seconds without
+// running the function. Responses are not cached if unset.
+const FnResponseCacheAnnotation = "fnproject.io/fn/responseCache"
+
+// ResponseCache configures the caching of the responses of a function
+type ResponseCache struct {
+	// TTL is the number of seconds responses are cached for
+	TTL int `json:"ttl"`
+	// StatusCodes are the status codes of the responses cached, 200 if empty
+	StatusCodes []int `json:"status_codes,omitempty"`
+	// VaryHeaders are the request headers responses depend on
+	VaryHeaders []string `json:"vary_headers,omitempty"`
+}
+
+var (
+	ErrInvalidResponseCache = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid response cache, ttl must be positive and status_codes valid HTTP status codes"),
+	}
+)
+
+// ResponseCacheFromAnnotations returns the response cache configuration set in function
+// annotations, nil if unset.
+func ResponseCacheFromAnnotations(annotations Annotations) (*ResponseCache, error) {
+	raw, ok := annotations.Get(FnResponseCacheAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var cache ResponseCache
+	if err := json.Unmarshal(raw, &cache); err != nil {
+		return nil, ErrInvalidResponseCache
+	}
+	if cache.TTL <= 0 {
+		return nil, ErrInvalidResponseCache
+	}
+	for _, code := range cache.StatusCodes {
+		if code < 100 || code > 599 {
+			return nil, ErrInvalidResponseCache
+		}
+	}
+	for _, h := range cache.VaryHeaders {
+		if strings.TrimSpace(h) == "" {
+			return nil, ErrInvalidResponseCache
+		}
+	}
+	return &cache, nil
+}
+
+// Caches returns true if the responses with status are cached
+func (c *ResponseCache) Caches(status int) bool {
+	if len(c.StatusCodes) == 0 {
+		return status == http.StatusOK
+	}
+	for _, code := range c.StatusCodes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
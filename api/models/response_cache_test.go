@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestResponseCacheFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		valid      bool
+	}{
+		{map[string]interface{}{"ttl": 60}, true},
+		{map[string]interface{}{"ttl": 1, "status_codes": []int{200, 404}, "vary_headers": []string{"Accept"}}, true},
+		{map[string]interface{}{"ttl": 0}, false},
+		{map[string]interface{}{"ttl": -5}, false},
+		{map[string]interface{}{"ttl": 60, "status_codes": []int{42}}, false},
+		{map[string]interface{}{"ttl": 60, "vary_headers": []string{" "}}, false},
+		{"60", false},
+	} {
+		annotations, err := EmptyAnnotations().With(FnResponseCacheAnnotation, test.annotation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache, err := ResponseCacheFromAnnotations(annotations)
+		if test.valid && (err != nil || cache == nil) {
+			t.Errorf("test %d: expected valid response cache, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidResponseCache {
+			t.Errorf("test %d: expected invalid response cache error, got: %v", i, err)
+		}
+	}
+
+	if cache, err := ResponseCacheFromAnnotations(EmptyAnnotations()); cache != nil || err != nil {
+		t.Errorf("expected no response cache by default, got %v %v", cache, err)
+	}
+}
+
+func TestResponseCacheCaches(t *testing.T) {
+	cache := &ResponseCache{TTL: 60}
+	if !cache.Caches(200) || cache.Caches(404) {
+		t.Error("expected only 200 responses to be cached by default")
+	}
+	cache.StatusCodes = []int{200, 404}
+	if !cache.Caches(404) || cache.Caches(500) {
+		t.Error("expected only the configured status codes to be cached")
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+)
+
+// ResponseSchema is a JSON Schema describing the response bodies of a function, it is
+// documented in the OpenAPI document of its app but not enforced. An empty object removes it
+// from a function.
+type ResponseSchema json.RawMessage
+
+var _ APIError = ErrFnsInvalidResponseSchema("")
+
+// ErrFnsInvalidResponseSchema is returned for response schemas that fail validation
+type ErrFnsInvalidResponseSchema string
+
+func (e ErrFnsInvalidResponseSchema) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidResponseSchema) Error() string { return "Invalid response schema: " + string(e) }
+
+// IsEmpty returns true if the schema does not describe response bodies.
+func (s ResponseSchema) IsEmpty() bool { return RequestSchema(s).IsEmpty() }
+
+// Validate validates the schema as a RequestSchema is, returning the first error, if any.
+func (s ResponseSchema) Validate() error {
+	if err, ok := RequestSchema(s).Validate().(ErrFnsInvalidRequestSchema); ok {
+		return ErrFnsInvalidResponseSchema(err)
+	}
+	return nil
+}
+
+// Equals returns true if both schemas are the same, nil and empty schemas are equal.
+func (s1 ResponseSchema) Equals(s2 ResponseSchema) bool {
+	return RequestSchema(s1).Equals(RequestSchema(s2))
+}
+
+// Clone returns a copy of the schema.
+func (s ResponseSchema) Clone() ResponseSchema {
+	return ResponseSchema(RequestSchema(s).Clone())
+}
+
+// MarshalJSON implements json.Marshaler
+func (s ResponseSchema) MarshalJSON() ([]byte, error) { return RequestSchema(s).MarshalJSON() }
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *ResponseSchema) UnmarshalJSON(data []byte) error {
+	return (*RequestSchema)(s).UnmarshalJSON(data)
+}
+
+// Value implements sql.Valuer, returning a string
+func (s ResponseSchema) Value() (driver.Value, error) { return RequestSchema(s).Value() }
+
+// Scan implements sql.Scanner
+func (s *ResponseSchema) Scan(value interface{}) error { return (*RequestSchema)(s).Scan(value) }
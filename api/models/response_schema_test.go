@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestResponseSchemaValidate(t *testing.T) {
+	for i, test := range []struct {
+		schema ResponseSchema
+		valid  bool
+	}{
+		{nil, true},
+		{ResponseSchema(`{}`), true},
+		{ResponseSchema(`{"type": "string"}`), true},
+		{ResponseSchema(`{"type": "text"}`), false},
+		{ResponseSchema(`{"type": "object"`), false},
+	} {
+		err := test.schema.Validate()
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid response schema, got: %v", i, err)
+		}
+		if _, ok := err.(ErrFnsInvalidResponseSchema); !test.valid && !ok {
+			t.Errorf("test %d: expected invalid response schema error, got: %v", i, err)
+		}
+	}
+}
+
+func TestResponseSchemaUpdate(t *testing.T) {
+	fn := &Fn{RequestSchema: RequestSchema(`{"type": "array"}`)}
+	fn.Update(&Fn{ResponseSchema: ResponseSchema(`{"type": "string"}`)})
+	if string(fn.ResponseSchema) != `{"type": "string"}` {
+		t.Fatalf("expected the response schema to be set, got %s", fn.ResponseSchema)
+	}
+	fn.Update(&Fn{RequestSchema: RequestSchema(` { } `)})
+	if fn.RequestSchema != nil || fn.ResponseSchema == nil {
+		t.Fatalf("expected only the request schema to be removed, got %s %s", fn.RequestSchema, fn.ResponseSchema)
+	}
+	fn.Update(&Fn{ResponseSchema: ResponseSchema(`{}`)})
+	if fn.ResponseSchema != nil {
+		t.Fatalf("expected the response schema to be removed, got %s", fn.ResponseSchema)
+	}
+}
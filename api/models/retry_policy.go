@@ -0,0 +1,141 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// MaxRetryAttempts is the most attempts a retry policy may allow for a call.
+	MaxRetryAttempts int32 = 100
+
+	// MaxRetryBackoff is the longest delay before a retry a retry policy may set, in seconds.
+	MaxRetryBackoff int32 = 24 * 3600 // 1d
+)
+
+// RetryPolicy sets how failed async calls of a function are retried. Calls that fail on
+// their last attempt are dead lettered, see LogStore.InsertDeadLetter.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is attempted, including the first. 1 never
+	// retries calls but still dead letters them.
+	MaxAttempts int32 `json:"max_attempts,omitempty"`
+	// Backoff is the delay in seconds before the first retry, it doubles on each further
+	// retry up to MaxBackoff.
+	Backoff int32 `json:"backoff,omitempty"`
+	// MaxBackoff caps the delay in seconds before a retry, 0 for MaxRetryBackoff.
+	MaxBackoff int32 `json:"max_backoff,omitempty"`
+}
+
+var _ APIError = ErrFnsInvalidRetryPolicy("")
+
+// ErrFnsInvalidRetryPolicy is returned for retry policies that fail validation
+type ErrFnsInvalidRetryPolicy string
+
+func (e ErrFnsInvalidRetryPolicy) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidRetryPolicy) Error() string { return string(e) }
+
+// IsEmpty returns true if the policy neither retries nor dead letters calls.
+func (p *RetryPolicy) IsEmpty() bool {
+	return p == nil || (p.MaxAttempts == 0 && p.Backoff == 0 && p.MaxBackoff == 0)
+}
+
+// ShouldRetry returns true if a call failing on the given attempt, counting from 1, is retried.
+func (p *RetryPolicy) ShouldRetry(attempt int32) bool {
+	return p != nil && attempt < p.MaxAttempts
+}
+
+// Delay returns the delay in seconds before retrying a call that failed on the given attempt.
+func (p *RetryPolicy) Delay(attempt int32) int32 {
+	if p == nil || p.Backoff <= 0 {
+		return 0
+	}
+
+	max := p.MaxBackoff
+	if max <= 0 || max > MaxRetryBackoff {
+		max = MaxRetryBackoff
+	}
+	delay := p.Backoff
+	for i := int32(1); i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// Validate validates all field values, returning the first error, if any.
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxRetryAttempts {
+		return ErrFnsInvalidRetryPolicy(fmt.Sprintf("max_attempts value is out of range, must be between 0 and %d", MaxRetryAttempts))
+	}
+	if p.Backoff < 0 || p.Backoff > MaxRetryBackoff {
+		return ErrFnsInvalidRetryPolicy(fmt.Sprintf("backoff value is out of range, must be between 0 and %d", MaxRetryBackoff))
+	}
+	if p.MaxBackoff < 0 || p.MaxBackoff > MaxRetryBackoff {
+		return ErrFnsInvalidRetryPolicy(fmt.Sprintf("max_backoff value is out of range, must be between 0 and %d", MaxRetryBackoff))
+	}
+	if p.MaxBackoff > 0 && p.MaxBackoff < p.Backoff {
+		return ErrFnsInvalidRetryPolicy("max_backoff must not be less than backoff")
+	}
+	return nil
+}
+
+// Equals returns true if both policies are the same, nil and empty policies are equal.
+func (p1 *RetryPolicy) Equals(p2 *RetryPolicy) bool {
+	if p1.IsEmpty() || p2.IsEmpty() {
+		return p1.IsEmpty() && p2.IsEmpty()
+	}
+	return *p1 == *p2
+}
+
+// Clone returns a copy of the policy.
+func (p *RetryPolicy) Clone() *RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// Value implements sql.Valuer, returning a string
+func (p RetryPolicy) Value() (driver.Value, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(p)
+	return driver.Value(b.String()), err
+}
+
+// Scan implements sql.Scanner
+func (p *RetryPolicy) Scan(value interface{}) error {
+	if value == nil {
+		*p = RetryPolicy{}
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		var b []byte
+		switch x := bv.(type) {
+		case []byte:
+			b = x
+		case string:
+			b = []byte(x)
+		}
+
+		if len(b) > 0 {
+			return json.Unmarshal(b, p)
+		}
+
+		*p = RetryPolicy{}
+		return nil
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("retry policy invalid db format: %T %T value, err: %v", value, bv, err)
+}
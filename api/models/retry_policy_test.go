@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestRetryPolicyValidate(t *testing.T) {
+	for i, test := range []struct {
+		policy *RetryPolicy
+		valid  bool
+	}{
+		{nil, true},
+		{&RetryPolicy{}, true},
+		{&RetryPolicy{MaxAttempts: 1}, true},
+		{&RetryPolicy{MaxAttempts: 5, Backoff: 10, MaxBackoff: 60}, true},
+		{&RetryPolicy{MaxAttempts: -1}, false},
+		{&RetryPolicy{MaxAttempts: MaxRetryAttempts + 1}, false},
+		{&RetryPolicy{MaxAttempts: 3, Backoff: -1}, false},
+		{&RetryPolicy{MaxAttempts: 3, Backoff: MaxRetryBackoff + 1}, false},
+		{&RetryPolicy{MaxAttempts: 3, MaxBackoff: MaxRetryBackoff + 1}, false},
+		{&RetryPolicy{MaxAttempts: 3, Backoff: 60, MaxBackoff: 10}, false},
+	} {
+		err := test.policy.Validate()
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid policy, got: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected invalid policy", i)
+		}
+	}
+}
+
+func TestRetryPolicyRetries(t *testing.T) {
+	var none *RetryPolicy
+	if none.ShouldRetry(1) {
+		t.Fatal("expected calls without a policy not to be retried")
+	}
+
+	policy := &RetryPolicy{MaxAttempts: 3, Backoff: 10, MaxBackoff: 30}
+	for attempt, retry := range map[int32]bool{1: true, 2: true, 3: false, 4: false} {
+		if policy.ShouldRetry(attempt) != retry {
+			t.Errorf("expected retry of attempt %d to be %v", attempt, retry)
+		}
+	}
+
+	for attempt, delay := range map[int32]int32{1: 10, 2: 20, 3: 30, 10: 30} {
+		if d := policy.Delay(attempt); d != delay {
+			t.Errorf("expected delay after attempt %d to be %d, got %d", attempt, delay, d)
+		}
+	}
+
+	if d := (&RetryPolicy{MaxAttempts: 3}).Delay(2); d != 0 {
+		t.Errorf("expected no delay without a backoff, got %d", d)
+	}
+	if d := (&RetryPolicy{MaxAttempts: 100, Backoff: 1}).Delay(100); d != MaxRetryBackoff {
+		t.Errorf("expected delay to be capped at %d, got %d", MaxRetryBackoff, d)
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Roles of principals, bound to them on an app or on all apps
+const (
+	// RoleReader may read the resources of apps
+	RoleReader = "reader"
+	// RoleInvoker may invoke the functions of apps
+	RoleInvoker = "invoker"
+	// RoleDeployer may read and change the resources of apps
+	RoleDeployer = "deployer"
+	// RoleAdmin may do anything, including binding roles to principals
+	RoleAdmin = "admin"
+)
+
+// Permissions are the classes of operations a role allows
+const (
+	PermissionRead   = "read"
+	PermissionWrite  = "write"
+	PermissionInvoke = "invoke"
+	PermissionAdmin  = "admin"
+)
+
+var rolePermissions = map[string][]string{
+	RoleReader:   {PermissionRead},
+	RoleInvoker:  {PermissionInvoke},
+	RoleDeployer: {PermissionRead, PermissionWrite},
+	RoleAdmin:    {PermissionRead, PermissionWrite, PermissionInvoke, PermissionAdmin},
+}
+
+// RoleAllows returns true if role allows the operations of permission
+func RoleAllows(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleBinding binds a role to a principal, the subject of the identity of callers, e.g. the
+// subject of their OIDC tokens or "apikey/<id>" for API keys.
+type RoleBinding struct {
+	ID        string `json:"id" db:"id"`
+	Principal string `json:"principal" db:"principal"`
+	Role      string `json:"role" db:"role"`
+	// AppID is the app the role is bound on, the role is bound on all apps if empty.
+	AppID     string          `json:"app_id,omitempty" db:"app_id"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+var (
+	//ErrDatastoreEmptyRoleBinding - no role binding given to the datastore
+	ErrDatastoreEmptyRoleBinding = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing role binding"),
+	}
+	//ErrRoleBindingIDProvided indicates that a role binding ID was specified when it shouldn't have been
+	ErrRoleBindingIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for role binding creation"),
+	}
+	//ErrRoleBindingMissingPrincipal - principal not specified on a role binding
+	ErrRoleBindingMissingPrincipal = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing principal on role binding")}
+	//ErrRoleBindingTooLongPrincipal - principal exceeds maximum permitted length
+	ErrRoleBindingTooLongPrincipal = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Role binding principal must be %v characters or less", MaxPrincipal)}
+	//ErrRoleBindingInvalidRole - role is not one of the roles
+	ErrRoleBindingInvalidRole = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid role, must be one of %s, %s, %s or %s", RoleReader, RoleInvoker, RoleDeployer, RoleAdmin)}
+	//ErrRoleBindingExists - the role is already bound to the principal
+	ErrRoleBindingExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Role is already bound to this principal")}
+	//ErrRoleBindingNotFound - role binding not found
+	ErrRoleBindingNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Role binding not found")}
+	//ErrAccessDenied - the roles of the caller do not allow the operation of its request
+	ErrAccessDenied = err{
+		code:  http.StatusForbidden,
+		error: errors.New("Access denied, no role allows this operation")}
+	//ErrRequestAppMismatch - the app of the body of a request is not that of its path or app_id query parameter
+	ErrRequestAppMismatch = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("App of the request body does not match the app of the request")}
+)
+
+// Validate checks that binding has valid data for inserting into a store
+func (b *RoleBinding) Validate() error {
+	if strings.TrimSpace(b.Principal) == "" {
+		return ErrRoleBindingMissingPrincipal
+	}
+	if len(b.Principal) > MaxPrincipal {
+		return ErrRoleBindingTooLongPrincipal
+	}
+	if _, ok := rolePermissions[b.Role]; !ok {
+		return ErrRoleBindingInvalidRole
+	}
+	return nil
+}
+
+// Allows returns true if the binding allows the operations of permission on the resources
+// of app appID, or on resources of no app if appID is empty.
+func (b *RoleBinding) Allows(appID, permission string) bool {
+	return (b.AppID == "" || b.AppID == appID) && RoleAllows(b.Role, permission)
+}
+
+// RoleBindingFilter is a search criteria on role bindings, in creation order
+type RoleBindingFilter struct {
+	//AppID searches for the bindings on an app
+	AppID string // this is exact match
+	//Principal searches for the bindings of a principal
+	Principal string // this is exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// RoleBindingList is a container of role bindings returned by search, optionally indicating the next page cursor
+type RoleBindingList struct {
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Items      []*RoleBinding `json:"items"`
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// FreezeDelayDisabled as a scale policy freeze delay keeps idle containers running unpaused.
+	FreezeDelayDisabled int32 = -1
+
+	// MaxFreezeDelay is the longest freeze delay a scale policy may set, in milliseconds.
+	MaxFreezeDelay int32 = 3600 * 1000 // 1h
+
+	keepWarmTimeLayout = "15:04"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ScalePolicy tunes how the hot containers of a function scale down on the runners that
+// served it, it can be changed at any time without restarting the running containers.
+type ScalePolicy struct {
+	// FreezeDelay is the time in milliseconds an idle container runs before it is paused,
+	// 0 uses the runner default and -1 never pauses idle containers.
+	FreezeDelay int32 `json:"freeze_delay_ms,omitempty"`
+	// AdaptiveFreeze skips pausing idle containers while the function is called more often
+	// than the runner adaptive freeze rate, as pause/unpause cycles add latency to chatty
+	// functions.
+	AdaptiveFreeze bool `json:"adaptive_freeze,omitempty"`
+	// NeverScaleToZero keeps a hot container of the function alive once it has been called.
+	NeverScaleToZero bool `json:"never_scale_to_zero,omitempty"`
+	// KeepWarm lists the time windows during which hot containers are kept alive.
+	KeepWarm []KeepWarmWindow `json:"keep_warm,omitempty"`
+}
+
+// KeepWarmWindow keeps MinReady hot containers alive between Start and End (hh:mm) on
+// the given Days (sun, mon, ... sat, all days if empty) in Timezone (UTC if empty). A
+// window ending before it starts lasts until End on the next day.
+type KeepWarmWindow struct {
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+	MinReady uint64   `json:"min_ready"`
+}
+
+var _ APIError = ErrFnsInvalidScalePolicy("")
+
+// ErrFnsInvalidScalePolicy is returned for scale policies that fail validation
+type ErrFnsInvalidScalePolicy string
+
+func (e ErrFnsInvalidScalePolicy) Code() int     { return http.StatusBadRequest }
+func (e ErrFnsInvalidScalePolicy) Error() string { return string(e) }
+
+// IsEmpty returns true if the policy does not change any defaults.
+func (p *ScalePolicy) IsEmpty() bool {
+	return p == nil || (p.FreezeDelay == 0 && !p.AdaptiveFreeze && !p.NeverScaleToZero && len(p.KeepWarm) == 0)
+}
+
+// KeepsWarm returns true if the policy keeps hot containers alive regardless of calls.
+func (p *ScalePolicy) KeepsWarm() bool {
+	return p != nil && (p.NeverScaleToZero || len(p.KeepWarm) > 0)
+}
+
+// MinReady returns the number of hot containers the policy keeps alive at the given time.
+func (p *ScalePolicy) MinReady(now time.Time) uint64 {
+	if p == nil {
+		return 0
+	}
+
+	var minReady uint64
+	if p.NeverScaleToZero {
+		minReady = 1
+	}
+	for _, w := range p.KeepWarm {
+		if w.MinReady > minReady && w.isActive(now) {
+			minReady = w.MinReady
+		}
+	}
+	return minReady
+}
+
+// Validate validates all field values, returning the first error, if any.
+func (p *ScalePolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.FreezeDelay < FreezeDelayDisabled || p.FreezeDelay > MaxFreezeDelay {
+		return ErrFnsInvalidScalePolicy(fmt.Sprintf("freeze_delay_ms value is out of range, must be -1 or between 0 and %d", MaxFreezeDelay))
+	}
+
+	for i, w := range p.KeepWarm {
+		if err := w.validate(); err != nil {
+			return ErrFnsInvalidScalePolicy(fmt.Sprintf("invalid keep_warm window %d: %v", i, err))
+		}
+	}
+	return nil
+}
+
+// Equals returns true if both policies are the same, nil and empty policies are equal.
+func (p1 *ScalePolicy) Equals(p2 *ScalePolicy) bool {
+	if p1.IsEmpty() || p2.IsEmpty() {
+		return p1.IsEmpty() && p2.IsEmpty()
+	}
+
+	eq := true
+	eq = eq && p1.FreezeDelay == p2.FreezeDelay
+	eq = eq && p1.AdaptiveFreeze == p2.AdaptiveFreeze
+	eq = eq && p1.NeverScaleToZero == p2.NeverScaleToZero
+	eq = eq && len(p1.KeepWarm) == len(p2.KeepWarm)
+	for i := 0; eq && i < len(p1.KeepWarm); i++ {
+		eq = eq && p1.KeepWarm[i].equals(&p2.KeepWarm[i])
+	}
+	return eq
+}
+
+// Clone returns a deep copy of the policy.
+func (p *ScalePolicy) Clone() *ScalePolicy {
+	if p == nil {
+		return nil
+	}
+
+	clone := *p
+	if p.KeepWarm != nil {
+		clone.KeepWarm = make([]KeepWarmWindow, len(p.KeepWarm))
+		for i, w := range p.KeepWarm {
+			clone.KeepWarm[i] = w
+			clone.KeepWarm[i].Days = append([]string(nil), w.Days...)
+		}
+	}
+	return &clone
+}
+
+// Value implements sql.Valuer, returning a string
+func (p ScalePolicy) Value() (driver.Value, error) {
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(p)
+	return driver.Value(b.String()), err
+}
+
+// Scan implements sql.Scanner
+func (p *ScalePolicy) Scan(value interface{}) error {
+	if value == nil {
+		*p = ScalePolicy{}
+		return nil
+	}
+	bv, err := driver.String.ConvertValue(value)
+	if err == nil {
+		var b []byte
+		switch x := bv.(type) {
+		case []byte:
+			b = x
+		case string:
+			b = []byte(x)
+		}
+
+		if len(b) > 0 {
+			return json.Unmarshal(b, p)
+		}
+
+		*p = ScalePolicy{}
+		return nil
+	}
+
+	// otherwise, return an error
+	return fmt.Errorf("scale policy invalid db format: %T %T value, err: %v", value, bv, err)
+}
+
+func (w *KeepWarmWindow) validate() error {
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("unknown day %q", day)
+		}
+	}
+	if _, err := time.Parse(keepWarmTimeLayout, w.Start); err != nil {
+		return fmt.Errorf("start must be hh:mm")
+	}
+	if _, err := time.Parse(keepWarmTimeLayout, w.End); err != nil {
+		return fmt.Errorf("end must be hh:mm")
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	if w.MinReady < 1 || w.MinReady > MaxMinReady {
+		return fmt.Errorf("min_ready value is out of range, must be between 1 and %d", MaxMinReady)
+	}
+	return nil
+}
+
+func (w *KeepWarmWindow) equals(w2 *KeepWarmWindow) bool {
+	eq := true
+	eq = eq && w.Start == w2.Start
+	eq = eq && w.End == w2.End
+	eq = eq && w.Timezone == w2.Timezone
+	eq = eq && w.MinReady == w2.MinReady
+	eq = eq && len(w.Days) == len(w2.Days)
+	for i := 0; eq && i < len(w.Days); i++ {
+		eq = eq && w.Days[i] == w2.Days[i]
+	}
+	return eq
+}
+
+// isActive returns true if now falls into the window, invalid windows are never active.
+func (w *KeepWarmWindow) isActive(now time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse(keepWarmTimeLayout, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(keepWarmTimeLayout, w.End)
+	if err != nil {
+		return false
+	}
+
+	now = now.In(loc)
+	minute := now.Hour()*60 + now.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	day := now.Weekday()
+	if endMinute <= startMinute {
+		// overnight window, after midnight it belongs to the day it started on
+		if minute < endMinute {
+			return w.isDay((day + 6) % 7)
+		}
+		return minute >= startMinute && w.isDay(day)
+	}
+	return minute >= startMinute && minute < endMinute && w.isDay(day)
+}
+
+func (w *KeepWarmWindow) isDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScalePolicyValidate(t *testing.T) {
+	for i, test := range []struct {
+		policy *ScalePolicy
+		valid  bool
+	}{
+		{nil, true},
+		{&ScalePolicy{}, true},
+		{&ScalePolicy{FreezeDelay: FreezeDelayDisabled, NeverScaleToZero: true}, true},
+		{&ScalePolicy{FreezeDelay: -2}, false},
+		{&ScalePolicy{FreezeDelay: MaxFreezeDelay + 1}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Days: []string{"mon", "Fri"}, Start: "09:00", End: "17:30", MinReady: 2}}}, true},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "22:00", End: "06:00", Timezone: "UTC", MinReady: 1}}}, true},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Days: []string{"someday"}, Start: "09:00", End: "17:00", MinReady: 1}}}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "9am", End: "17:00", MinReady: 1}}}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "09:00", End: "25:00", MinReady: 1}}}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "09:00", End: "17:00", Timezone: "Nowhere/Special", MinReady: 1}}}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "09:00", End: "17:00"}}}, false},
+		{&ScalePolicy{KeepWarm: []KeepWarmWindow{{Start: "09:00", End: "17:00", MinReady: MaxMinReady + 1}}}, false},
+	} {
+		err := test.policy.Validate()
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid policy, got: %v", i, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("test %d: expected invalid policy", i)
+		}
+	}
+}
+
+func TestScalePolicyMinReady(t *testing.T) {
+	policy := &ScalePolicy{
+		KeepWarm: []KeepWarmWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", MinReady: 3},
+			{Days: []string{"fri"}, Start: "22:00", End: "02:00", MinReady: 1},
+		},
+	}
+
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	// 2024-01-01 is a monday
+	for i, test := range []struct {
+		now      string
+		minReady uint64
+	}{
+		{"2024-01-01T08:59:00Z", 0},
+		{"2024-01-01T09:00:00Z", 3},
+		{"2024-01-01T16:59:00Z", 3},
+		{"2024-01-01T17:00:00Z", 0},
+		{"2024-01-06T10:00:00Z", 0}, // saturday
+		{"2024-01-05T23:00:00Z", 1}, // friday night
+		{"2024-01-06T01:00:00Z", 1}, // still friday night
+		{"2024-01-06T02:00:00Z", 0},
+		{"2024-01-02T01:00:00Z", 0}, // monday night
+	} {
+		if n := policy.MinReady(at(test.now)); n != test.minReady {
+			t.Errorf("test %d: expected min ready %d at %s, got %d", i, test.minReady, test.now, n)
+		}
+	}
+
+	policy.NeverScaleToZero = true
+	if n := policy.MinReady(at("2024-01-06T10:00:00Z")); n != 1 {
+		t.Errorf("expected never scale to zero to keep a container, got %d", n)
+	}
+
+	var nilPolicy *ScalePolicy
+	if nilPolicy.MinReady(time.Now()) != 0 || nilPolicy.KeepsWarm() {
+		t.Error("nil policy should not keep containers warm")
+	}
+}
+
+func TestScalePolicyTimezone(t *testing.T) {
+	policy := &ScalePolicy{
+		KeepWarm: []KeepWarmWindow{
+			{Start: "09:00", End: "10:00", Timezone: "America/New_York", MinReady: 1},
+		},
+	}
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("no timezone database available")
+	}
+
+	// 14:30 UTC is 09:30 in New York in winter
+	now := time.Date(2024, 1, 10, 14, 30, 0, 0, time.UTC)
+	if n := policy.MinReady(now); n != 1 {
+		t.Errorf("expected window to be active in its timezone, got %d", n)
+	}
+	if n := policy.MinReady(now.Add(-5 * time.Hour)); n != 0 {
+		t.Errorf("expected window to be inactive in its timezone, got %d", n)
+	}
+}
+
+func TestScalePolicyEquals(t *testing.T) {
+	policy := &ScalePolicy{
+		FreezeDelay: 100,
+		KeepWarm: []KeepWarmWindow{
+			{Days: []string{"mon"}, Start: "09:00", End: "17:00", MinReady: 3},
+		},
+	}
+
+	clone := policy.Clone()
+	if !policy.Equals(clone) {
+		t.Fatal("policy should equal its clone")
+	}
+
+	clone.KeepWarm[0].Days[0] = "tue"
+	if policy.Equals(clone) || policy.KeepWarm[0].Days[0] != "mon" {
+		t.Fatal("clone should be a deep copy")
+	}
+
+	var nilPolicy *ScalePolicy
+	if !nilPolicy.Equals(&ScalePolicy{}) {
+		t.Fatal("nil policy should equal an empty policy")
+	}
+	if nilPolicy.Equals(policy) {
+		t.Fatal("nil policy should not equal a non empty policy")
+	}
+	if nilPolicy.Equals(&ScalePolicy{AdaptiveFreeze: true}) {
+		t.Fatal("nil policy should not equal an adaptive freeze policy")
+	}
+}
@@ -0,0 +1,249 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Statuses of a ScheduleRun
+const (
+	// ScheduleRunEnqueued is the status of runs whose call was enqueued, the call ID of the
+	// run then has the status of the invocation itself
+	ScheduleRunEnqueued = "enqueued"
+	// ScheduleRunError is the status of runs whose call could not be enqueued
+	ScheduleRunError = "error"
+)
+
+// Schedule invokes a function with a payload at the times of a cron expression, see ParseCron.
+type Schedule struct {
+	ID          string          `json:"id" db:"id"`
+	Name        string          `json:"name" db:"name"`
+	AppID       string          `json:"app_id" db:"app_id"`
+	FnID        string          `json:"fn_id" db:"fn_id"`
+	Cron        string          `json:"cron" db:"cron"`
+	Payload     string          `json:"payload,omitempty" db:"payload"`
+	NextRunAt   common.DateTime `json:"next_run_at,omitempty" db:"next_run_at"`
+	Annotations Annotations     `json:"annotations,omitempty" db:"annotations"`
+	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt   common.DateTime `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// ScheduleRun records an invocation of a schedule
+type ScheduleRun struct {
+	ID         string `json:"id" db:"id"`
+	ScheduleID string `json:"schedule_id" db:"schedule_id"`
+	AppID      string `json:"app_id" db:"app_id"`
+	FnID       string `json:"fn_id" db:"fn_id"`
+	CallID     string `json:"call_id,omitempty" db:"call_id"`
+	// ScheduledAt is the time the run was due at, CreatedAt the time it was enqueued at
+	ScheduledAt common.DateTime `json:"scheduled_at" db:"scheduled_at"`
+	CreatedAt   common.DateTime `json:"created_at" db:"created_at"`
+	Status      string          `json:"status" db:"status"`
+	Error       string          `json:"error,omitempty" db:"error"`
+}
+
+var (
+	//ErrDatastoreEmptySchedule - no schedule given to the datastore
+	ErrDatastoreEmptySchedule = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing Schedule"),
+	}
+	//ErrScheduleIDProvided indicates that a schedule ID was specified when it shouldn't have been
+	ErrScheduleIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for Schedule creation"),
+	}
+	//ErrScheduleIDMismatch indicates an ID was provided that did not match the ID of the corresponding operation/call
+	ErrScheduleIDMismatch = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID in path does not match ID in body"),
+	}
+	//ErrScheduleMissingName - name not specified on a schedule object
+	ErrScheduleMissingName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing name on Schedule")}
+	//ErrScheduleTooLongName - name exceeds maximum permitted name
+	ErrScheduleTooLongName = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Schedule name must be %v characters or less", MaxScheduleName)}
+	//ErrScheduleInvalidName - name does not comply with naming spec
+	ErrScheduleInvalidName = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid name for Schedule")}
+	//ErrScheduleMissingAppID - no app id specified on schedule creation
+	ErrScheduleMissingAppID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing App ID on Schedule")}
+	//ErrScheduleMissingFnID - no fn id specified on schedule creation
+	ErrScheduleMissingFnID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing Fn ID on Schedule")}
+	//ErrScheduleFnIDNotSameApp - specified Fn does not belong to the same app as the provided AppID
+	ErrScheduleFnIDNotSameApp = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid Fn ID - not owned by specified app")}
+	//ErrScheduleMissingCron - no cron expression specified on a schedule
+	ErrScheduleMissingCron = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing cron expression on Schedule")}
+	//ErrScheduleInvalidCron - the cron expression of a schedule does not parse or never matches
+	ErrScheduleInvalidCron = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid cron expression on Schedule")}
+	//ErrScheduleNotFound - schedule not found
+	ErrScheduleNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Schedule not found")}
+	//ErrScheduleExists - a schedule with the specified name already exists on the app
+	ErrScheduleExists = err{
+		code:  http.StatusConflict,
+		error: errors.New("Schedule already exists")}
+	//ErrScheduleNotDue - the schedule was removed or already advanced past the run
+	ErrScheduleNotDue = err{
+		code:  http.StatusConflict,
+		error: errors.New("Schedule is not due")}
+	//ErrScheduleRunMissingScheduleID - runs are listed per schedule
+	ErrScheduleRunMissingScheduleID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing Schedule ID")}
+)
+
+// Validate checks that schedule has valid data for inserting into a store
+func (s *Schedule) Validate() error {
+	if s.Name == "" {
+		return ErrScheduleMissingName
+	}
+
+	if s.AppID == "" {
+		return ErrScheduleMissingAppID
+	}
+
+	if len(s.Name) > MaxScheduleName {
+		return ErrScheduleTooLongName
+	}
+	for _, c := range s.Name {
+		if !(unicode.IsLetter(c) || unicode.IsNumber(c) || c == '_' || c == '-') {
+			return ErrScheduleInvalidName
+		}
+	}
+
+	if s.FnID == "" {
+		return ErrScheduleMissingFnID
+	}
+
+	if s.Cron == "" {
+		return ErrScheduleMissingCron
+	}
+	if _, err := s.NextRun(time.Now()); err != nil {
+		return err
+	}
+
+	return s.Annotations.Validate()
+}
+
+// NextRun returns the first time after t the schedule runs at
+func (s *Schedule) NextRun(t time.Time) (time.Time, error) {
+	expr, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, ErrScheduleInvalidCron
+	}
+	next := expr.Next(t)
+	if next.IsZero() {
+		return time.Time{}, ErrScheduleInvalidCron
+	}
+	return next, nil
+}
+
+// Equals compares two schedules for semantic equality it ignores timestamp fields but includes annotations
+func (s *Schedule) Equals(s2 *Schedule) bool {
+	eq := true
+	eq = eq && s.ID == s2.ID
+	eq = eq && s.Name == s2.Name
+	eq = eq && s.AppID == s2.AppID
+	eq = eq && s.FnID == s2.FnID
+	eq = eq && s.Cron == s2.Cron
+	eq = eq && s.Payload == s2.Payload
+	eq = eq && s.Annotations.Equals(s2.Annotations)
+
+	return eq
+}
+
+// Clone creates a deep copy of a schedule
+func (s *Schedule) Clone() *Schedule {
+	clone := new(Schedule)
+	*clone = *s // shallow copy
+	// annotations are immutable via their interface so can be shallow copied
+	return clone
+}
+
+// Update applies a change to a schedule, the next run is moved if the cron expression changed
+func (s *Schedule) Update(patch *Schedule) {
+
+	original := s.Clone()
+
+	if patch.FnID != "" {
+		s.FnID = patch.FnID
+	}
+
+	if patch.Name != "" {
+		s.Name = patch.Name
+	}
+
+	if patch.Cron != "" {
+		s.Cron = patch.Cron
+	}
+
+	if patch.Payload != "" {
+		s.Payload = patch.Payload
+	}
+
+	s.Annotations = s.Annotations.MergeChange(patch.Annotations)
+
+	if !s.Equals(original) {
+		s.UpdatedAt = common.DateTime(time.Now())
+	}
+	if s.Cron != original.Cron {
+		if next, err := s.NextRun(time.Now()); err == nil {
+			s.NextRunAt = common.DateTime(next)
+		}
+	}
+}
+
+// ScheduleFilter is a search criteria on schedules
+type ScheduleFilter struct {
+	//AppID searches for schedules in APP - mandatory
+	AppID string // this is exact match mandatory
+	//FnID searches for schedules belonging to a specific function
+	FnID string // this is exact match
+	//Name is the name of the schedule
+	Name string // exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// ScheduleList is a container of schedules returned by search, optionally indicating the next page cursor
+type ScheduleList struct {
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Items      []*Schedule `json:"items"`
+}
+
+// ScheduleRunFilter is a search criteria on the runs of a schedule, most recent first
+type ScheduleRunFilter struct {
+	//ScheduleID is the schedule of the runs - mandatory
+	ScheduleID string
+
+	Cursor  string
+	PerPage int
+}
+
+// ScheduleRunList is a container of schedule runs returned by search, optionally indicating the next page cursor
+type ScheduleRunList struct {
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Items      []*ScheduleRun `json:"items"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The types of the resources searched
+const (
+	SearchTypeApp = "app"
+	SearchTypeFn  = "fn"
+)
+
+// MaxSearchTerms is the largest number of terms of a search
+const MaxSearchTerms = 10
+
+var (
+	ErrMissingSearchQuery = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing search query, q must have a word to search for"),
+	}
+	ErrTooManySearchTerms = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Too many search terms, the maximum is %d", MaxSearchTerms),
+	}
+	ErrInvalidSearchType = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid search type, valid types are %s and %s", SearchTypeApp, SearchTypeFn),
+	}
+)
+
+// SearchResult is an app or fn matching a search
+type SearchResult struct {
+	// Type is the type of the resource, app or fn
+	Type string `json:"type" db:"resource_type"`
+	// ID is the id of the resource
+	ID string `json:"id" db:"resource_id"`
+	// AppID is the id of the app of the resource, its own for apps
+	AppID string `json:"app_id" db:"app_id"`
+	// Name is the name of the resource
+	Name string `json:"name" db:"name"`
+}
+
+// SearchFilter is a search of the apps and fns whose names, annotation keys and config keys
+// have words starting with each of its terms, in id order
+type SearchFilter struct {
+	Terms []string
+	Type  string // this is exact match
+	AppID string // this is exact match
+	// AppIDs restricts the results to the resources of these apps if not nil, to none if empty
+	AppIDs []string
+
+	Cursor  string
+	PerPage int
+}
+
+// SearchResultList is a container of search results, optionally indicating the next page cursor
+type SearchResultList struct {
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Items      []*SearchResult `json:"items"`
+}
+
+// ParseSearchQuery returns the terms of a search query, its words, see SearchWords
+func ParseSearchQuery(query string) ([]string, error) {
+	terms := SearchWords(query)
+	if len(terms) == 0 {
+		return nil, ErrMissingSearchQuery
+	}
+	if len(terms) > MaxSearchTerms {
+		return nil, ErrTooManySearchTerms
+	}
+	return terms, nil
+}
+
+// SearchWords returns the lower case words of s, the other characters than letters and
+// digits separate words
+func SearchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchText returns the text of a resource which searches match, the words of its name,
+// annotation keys and config keys separated by spaces. Search terms match the words they
+// prefix, whether the datastore matches them with full-text search or LIKE.
+func SearchText(name string, config Config, annotations Annotations) string {
+	var keys []string
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	words := SearchWords(name)
+	for _, k := range keys {
+		words = append(words, SearchWords(k)...)
+	}
+	return strings.Join(words, " ")
+}
+
+// MatchSearch returns true if text, see SearchText, has words starting with each of the
+// terms of a search
+func MatchSearch(terms []string, text string) bool {
+	words := strings.Fields(text)
+	for _, t := range terms {
+		matched := false
+		for _, w := range words {
+			if strings.HasPrefix(w, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchesApp returns true if the results of a search may be of app appID
+func (f *SearchFilter) SearchesApp(appID string) bool {
+	if f.AppID != "" && f.AppID != appID {
+		return false
+	}
+	if f.AppIDs == nil {
+		return true
+	}
+	for _, id := range f.AppIDs {
+		if id == appID {
+			return true
+		}
+	}
+	return false
+}
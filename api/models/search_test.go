@@ -0,0 +1,75 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	for i, test := range []struct {
+		query    string
+		expected []string
+		err      error
+	}{
+		{"payments", []string{"payments"}, nil},
+		{" My-App, v2 ", []string{"my", "app", "v2"}, nil},
+		{"%_*", nil, ErrMissingSearchQuery},
+		{"", nil, ErrMissingSearchQuery},
+		{strings.Repeat("a ", MaxSearchTerms+1), nil, ErrTooManySearchTerms},
+	} {
+		terms, err := ParseSearchQuery(test.query)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(terms, test.expected) {
+			t.Errorf("test %d: expected terms %v, got %v", i, test.expected, terms)
+		}
+	}
+}
+
+func TestMatchSearch(t *testing.T) {
+	annotations, err := EmptyAnnotations().With("team.Owner", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := SearchText("My-App", Config{"DB_URL": "x"}, annotations)
+	if text != "my app db url team owner" {
+		t.Fatalf("expected the words of the name and keys in lower case, got %q", text)
+	}
+
+	for i, test := range []struct {
+		terms []string
+		match bool
+	}{
+		{[]string{"my", "app"}, true},
+		{[]string{"owner", "db"}, true},
+		{[]string{"app", "other"}, false},
+		// terms match the words they prefix, not parts of words
+		{[]string{"own"}, true},
+		{[]string{"wner"}, false},
+	} {
+		if match := MatchSearch(test.terms, text); match != test.match {
+			t.Errorf("test %d: expected match %v, got %v", i, test.match, match)
+		}
+	}
+}
+
+func TestSearchesApp(t *testing.T) {
+	for i, test := range []struct {
+		filter   SearchFilter
+		appID    string
+		expected bool
+	}{
+		{SearchFilter{}, "app1", true},
+		{SearchFilter{AppID: "app2"}, "app1", false},
+		{SearchFilter{AppIDs: []string{}}, "app1", false},
+		{SearchFilter{AppIDs: []string{"app2", "app1"}}, "app1", true},
+		{SearchFilter{AppID: "app2", AppIDs: []string{"app1"}}, "app1", false},
+	} {
+		if searches := test.filter.SearchesApp(test.appID); searches != test.expected {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, searches)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// SharedPoolAnnotation is the app or fn annotation, true or false, opting the fns into sharing
+// hot containers. Fns of an app which opted in and run the same image with the same memory,
+// format and config draw from a common pool of hot containers, rather than each keeping its
+// own, which helps density for apps with many near identical fns. A fn annotation takes
+// precedence over the app one.
+//
+// The containers of a shared pool are not tied to a fn, so FN_FN_ID is not set in them.
+const SharedPoolAnnotation = "fnproject.io/sharedPool"
+
+var ErrInvalidSharedPool = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid shared pool annotation, must be true or false"),
+}
+
+// SharedPoolFromAnnotations returns whether annotations opt into a shared pool, false if unset.
+func SharedPoolFromAnnotations(annotations Annotations) (bool, error) {
+	raw, ok := annotations.Get(SharedPoolAnnotation)
+	if !ok {
+		return false, nil
+	}
+	var shared bool
+	if err := json.Unmarshal(raw, &shared); err != nil {
+		return false, ErrInvalidSharedPool
+	}
+	return shared, nil
+}
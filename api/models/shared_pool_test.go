@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestSharedPoolFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		value    interface{}
+		expected bool
+		valid    bool
+	}{
+		{nil, false, true},
+		{true, true, true},
+		{false, false, true},
+		{"true", false, false},
+		{1, false, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.value != nil {
+			annotations, err = annotations.With(SharedPoolAnnotation, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		shared, err := SharedPoolFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid shared pool, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidSharedPool {
+			t.Errorf("test %d: expected %v, got: %v", i, ErrInvalidSharedPool, err)
+		}
+		if shared != test.expected {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, shared)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+)
+
+const (
+	// AppMaxRequestSizeAnnotation is the app annotation holding the maximum size in bytes of the
+	// request body of calls of the app, lowering the limit of the runner.
+	AppMaxRequestSizeAnnotation = "fnproject.io/app/maxRequestSize"
+	// AppMaxResponseSizeAnnotation is the app annotation holding the maximum size in bytes of the
+	// response body of calls of the app, lowering the limit of the runner.
+	AppMaxResponseSizeAnnotation = "fnproject.io/app/maxResponseSize"
+	// AppMaxLogSizeAnnotation is the app annotation holding the maximum size in bytes of the
+	// log captured for each call of the app, overriding the runner default.
+	AppMaxLogSizeAnnotation = "fnproject.io/app/maxLogSize"
+)
+
+var ErrInvalidSizeLimit = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid size limit annotation, must be a positive number of bytes"),
+}
+
+// SizeLimitsFromAnnotations returns the maximum request and response sizes set in app
+// annotations, 0 if unset.
+func SizeLimitsFromAnnotations(annotations Annotations) (maxRequest, maxResponse uint64, err error) {
+	maxRequest, err = sizeLimit(annotations, AppMaxRequestSizeAnnotation)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxResponse, err = sizeLimit(annotations, AppMaxResponseSizeAnnotation)
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxRequest, maxResponse, nil
+}
+
+// MaxLogSizeFromAnnotations returns the maximum log size set in app annotations, 0 if unset.
+func MaxLogSizeFromAnnotations(annotations Annotations) (uint64, error) {
+	limit, err := sizeLimit(annotations, AppMaxLogSizeAnnotation)
+	if limit > math.MaxInt64 {
+		return 0, ErrInvalidSizeLimit
+	}
+	return limit, err
+}
+
+func sizeLimit(annotations Annotations, key string) (uint64, error) {
+	raw, ok := annotations.Get(key)
+	if !ok {
+		return 0, nil
+	}
+	var limit uint64
+	if err := json.Unmarshal(raw, &limit); err != nil || limit == 0 {
+		return 0, ErrInvalidSizeLimit
+	}
+	return limit, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestSizeLimitsFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		request  interface{}
+		response interface{}
+		expReq   uint64
+		expResp  uint64
+		valid    bool
+	}{
+		{nil, nil, 0, 0, true},
+		{1024, nil, 1024, 0, true},
+		{nil, 2048, 0, 2048, true},
+		{1024, 2048, 1024, 2048, true},
+		{0, nil, 0, 0, false},
+		{-1, nil, 0, 0, false},
+		{nil, "1MB", 0, 0, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.request != nil {
+			annotations, err = annotations.With(AppMaxRequestSizeAnnotation, test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if test.response != nil {
+			annotations, err = annotations.With(AppMaxResponseSizeAnnotation, test.response)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		maxReq, maxResp, err := SizeLimitsFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid size limits, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidSizeLimit {
+			t.Errorf("test %d: expected invalid size limit error, got: %v", i, err)
+		}
+		if maxReq != test.expReq || maxResp != test.expResp {
+			t.Errorf("test %d: expected size limits %d/%d, got %d/%d", i, test.expReq, test.expResp, maxReq, maxResp)
+		}
+	}
+}
+
+func TestMaxLogSizeFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		size     interface{}
+		expected uint64
+		valid    bool
+	}{
+		{nil, 0, true},
+		{4096, 4096, true},
+		{0, 0, false},
+		{"4KB", 0, false},
+	} {
+		annotations := EmptyAnnotations()
+		if test.size != nil {
+			var err error
+			annotations, err = annotations.With(AppMaxLogSizeAnnotation, test.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		size, err := MaxLogSizeFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected a valid log size, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidSizeLimit {
+			t.Errorf("test %d: expected invalid size limit error, got: %v", i, err)
+		}
+		if size != test.expected {
+			t.Errorf("test %d: expected log size %d, got %d", i, test.expected, size)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// FnSlotWaitTimeoutAnnotation is the fn annotation holding the time in milliseconds a call of
+// the fn may wait for a hot container to run it before it is rejected, overriding the runner
+// default.
+const FnSlotWaitTimeoutAnnotation = "fnproject.io/fn/slotWaitTimeoutMs"
+
+var ErrInvalidSlotWaitTimeout = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid slot wait timeout annotation, must be a positive number of milliseconds"),
+}
+
+// SlotWaitTimeoutFromAnnotations returns the slot wait timeout set in fn annotations, 0 if unset.
+func SlotWaitTimeoutFromAnnotations(annotations Annotations) (time.Duration, error) {
+	raw, ok := annotations.Get(FnSlotWaitTimeoutAnnotation)
+	if !ok {
+		return 0, nil
+	}
+	var msecs uint64
+	if err := json.Unmarshal(raw, &msecs); err != nil || msecs == 0 {
+		return 0, ErrInvalidSlotWaitTimeout
+	}
+	return time.Duration(msecs) * time.Millisecond, nil
+}
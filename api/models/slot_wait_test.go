@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlotWaitTimeoutFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		value    interface{}
+		expected time.Duration
+		valid    bool
+	}{
+		{nil, 0, true},
+		{250, 250 * time.Millisecond, true},
+		{0, 0, false},
+		{-1, 0, false},
+		{"1s", 0, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.value != nil {
+			annotations, err = annotations.With(FnSlotWaitTimeoutAnnotation, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		timeout, err := SlotWaitTimeoutFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid slot wait timeout, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidSlotWaitTimeout {
+			t.Errorf("test %d: expected %v, got: %v", i, ErrInvalidSlotWaitTimeout, err)
+		}
+		if timeout != test.expected {
+			t.Errorf("test %d: expected %v, got %v", i, test.expected, timeout)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// SyslogFormatAnnotation is the app annotation holding the format of the messages sent to the
+// syslog URL of the app, one of SyslogFormats. Messages are formatted as SyslogFormatRFC5424
+// if unset.
+const SyslogFormatAnnotation = "fnproject.io/app/syslogFormat"
+
+// Syslog message formats
+const (
+	SyslogFormatRFC3164 = "rfc3164"
+	SyslogFormatRFC5424 = "rfc5424"
+	// SyslogFormatRFC5424Micro is SyslogFormatRFC5424 with timestamps to the microsecond
+	SyslogFormatRFC5424Micro = "rfc5424micro"
+)
+
+// SyslogFormats are the formats of syslog messages
+var SyslogFormats = []string{SyslogFormatRFC3164, SyslogFormatRFC5424, SyslogFormatRFC5424Micro}
+
+var ErrInvalidSyslogFormat = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid syslog format annotation, must be one of rfc3164, rfc5424 or rfc5424micro"),
+}
+
+// SyslogFormatFromAnnotations returns the syslog format set in app annotations, empty if unset.
+func SyslogFormatFromAnnotations(annotations Annotations) (string, error) {
+	raw, ok := annotations.Get(SyslogFormatAnnotation)
+	if !ok {
+		return "", nil
+	}
+	var format string
+	if err := json.Unmarshal(raw, &format); err != nil {
+		return "", ErrInvalidSyslogFormat
+	}
+	for _, f := range SyslogFormats {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", ErrInvalidSyslogFormat
+}
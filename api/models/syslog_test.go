@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestSyslogFormatFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		exp        string
+		valid      bool
+	}{
+		{nil, "", true},
+		{"rfc3164", SyslogFormatRFC3164, true},
+		{"rfc5424micro", SyslogFormatRFC5424Micro, true},
+		{"RFC3164", "", false},
+		{"json", "", false},
+		{5424, "", false},
+	} {
+		annotations := EmptyAnnotations()
+		if test.annotation != nil {
+			var err error
+			annotations, err = annotations.With(SyslogFormatAnnotation, test.annotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		format, err := SyslogFormatFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid syslog format, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidSyslogFormat {
+			t.Errorf("test %d: expected invalid syslog format error, got: %v", i, err)
+		}
+		if format != test.exp {
+			t.Errorf("test %d: expected syslog format %q, got %q", i, test.exp, format)
+		}
+	}
+}
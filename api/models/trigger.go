@@ -14,6 +14,10 @@ import (
 // TriggerHTTPEndpointAnnotation is the annotation that exposes the HTTP trigger endpoint For want of a better place to put this it's here
 const TriggerHTTPEndpointAnnotation = "fnproject.io/trigger/httpEndpoint"
 
+// TriggerWebSocketAnnotation is the annotation set to true on HTTP triggers that accept WebSocket upgrades,
+// the connection is then proxied to a container of the function for its lifetime.
+const TriggerWebSocketAnnotation = "fnproject.io/trigger/websocket"
+
 // Trigger represents a binding between a Function and an external event source
 type Trigger struct {
 	ID          string          `json:"id" db:"id"`
@@ -178,6 +182,18 @@ func (t *Trigger) Validate() error {
 		return err
 	}
 
+	if _, err := CORSFromAnnotations(t.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := TriggerMethodsFromAnnotations(t.Annotations); err != nil {
+		return err
+	}
+
+	if _, err := TriggerHMACFromAnnotations(t.Annotations); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -224,6 +240,12 @@ type TriggerFilter struct {
 	FnID string // this is exact match
 	//Name is the name of the trigger
 	Name string // exact match
+	// NamePrefix lists the triggers whose name starts with it
+	NamePrefix string
+	// Annotations lists the triggers matching all of its key filters
+	Annotations []KeyFilter
+	// Order is the order of the list and its cursors, by name when zero
+	Order ListOrder
 
 	Cursor  string
 	PerPage int
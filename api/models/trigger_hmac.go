@@ -0,0 +1,183 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TriggerHMACAnnotation is the annotation of HTTP triggers whose requests must be signed with
+// an HMAC of their body, as webhooks are, e.g. {"header": "X-Hub-Signature-256", "prefix":
+// "sha256=", "secret_keys": ["WEBHOOK_SECRET"]}. The secrets are the values of config keys of
+// the fn or its app, so that they are not exposed with the trigger. Requests signed with any
+// of the secrets are accepted, so that a new secret can be added before the old one is removed.
+const TriggerHMACAnnotation = "fnproject.io/trigger/hmac"
+
+// Formats of the signatures of HMAC triggers
+const (
+	// HMACFormatHex signatures are the hex encoded HMAC of the body, after Prefix
+	HMACFormatHex = "hex"
+	// HMACFormatBase64 signatures are the base64 encoded HMAC of the body, after Prefix
+	HMACFormatBase64 = "base64"
+	// HMACFormatStripe signatures are t=timestamp,v1=signature[,v1=signature], signing the
+	// timestamp, a dot and the body, and are rejected once older than Tolerance
+	HMACFormatStripe = "stripe"
+)
+
+// DefaultHMACTolerance is the age in seconds of the timestamped signatures accepted when an
+// HMAC trigger doesn't set one
+const DefaultHMACTolerance = 300
+
+// TriggerHMAC is the configuration of the verification of the signatures of the requests of an
+// HTTP trigger
+type TriggerHMAC struct {
+	// Header carries the signature
+	Header string `json:"header"`
+	// Algorithm is the hash of the HMAC, sha1, sha256 (default) or sha512
+	Algorithm string `json:"algorithm,omitempty"`
+	// Format is HMACFormatHex (default), HMACFormatBase64 or HMACFormatStripe
+	Format string `json:"format,omitempty"`
+	// Prefix precedes the signature in the header, e.g. sha256=, signatures without it are rejected
+	Prefix string `json:"prefix,omitempty"`
+	// SecretKeys are the config keys of the secrets, of the fn or its app
+	SecretKeys []string `json:"secret_keys"`
+	// Tolerance is the age in seconds of timestamped signatures accepted, see DefaultHMACTolerance
+	Tolerance int64 `json:"tolerance,omitempty"`
+}
+
+var (
+	ErrInvalidTriggerHMAC = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid trigger HMAC, header and secret_keys are required, algorithm must be sha1, sha256 or sha512 and format hex, base64 or stripe"),
+	}
+	ErrTriggerSignatureInvalid = err{
+		code:  http.StatusUnauthorized,
+		error: errors.New("Missing or invalid request signature"),
+	}
+)
+
+// TriggerHMACFromAnnotations returns the HMAC configuration set in trigger annotations, nil if unset.
+func TriggerHMACFromAnnotations(annotations Annotations) (*TriggerHMAC, error) {
+	raw, ok := annotations.Get(TriggerHMACAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var h TriggerHMAC
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, ErrInvalidTriggerHMAC
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Validate checks the header, algorithm, format and secret keys of an HMAC configuration
+func (h *TriggerHMAC) Validate() error {
+	if h.Header == "" || len(h.SecretKeys) == 0 || h.Tolerance < 0 || h.hash() == nil {
+		return ErrInvalidTriggerHMAC
+	}
+	switch h.Format {
+	case "", HMACFormatHex, HMACFormatBase64, HMACFormatStripe:
+	default:
+		return ErrInvalidTriggerHMAC
+	}
+	for _, k := range h.SecretKeys {
+		if k == "" {
+			return ErrInvalidTriggerHMAC
+		}
+	}
+	return nil
+}
+
+func (h *TriggerHMAC) hash() func() hash.Hash {
+	switch h.Algorithm {
+	case "sha1":
+		return sha1.New
+	case "", "sha256":
+		return sha256.New
+	case "sha512":
+		return sha512.New
+	}
+	return nil
+}
+
+// Verify returns true if header carries a signature of body made with any of secrets, at now
+func (h *TriggerHMAC) Verify(secrets []string, header http.Header, body []byte, now time.Time) bool {
+	value := strings.TrimSpace(header.Get(h.Header))
+	if value == "" {
+		return false
+	}
+
+	var signatures [][]byte
+	signed := body
+	switch h.Format {
+	case HMACFormatStripe:
+		var timestamp string
+		for _, part := range strings.Split(value, ",") {
+			kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "t":
+				timestamp = kv[1]
+			case "v1":
+				if sig, err := hex.DecodeString(kv[1]); err == nil {
+					signatures = append(signatures, sig)
+				}
+			}
+		}
+		t, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		tolerance := h.Tolerance
+		if tolerance == 0 {
+			tolerance = DefaultHMACTolerance
+		}
+		if age := now.Unix() - t; age > tolerance || age < -tolerance {
+			return false
+		}
+		signed = append([]byte(timestamp+"."), body...)
+	case HMACFormatBase64:
+		if !strings.HasPrefix(value, h.Prefix) {
+			return false
+		}
+		if sig, err := base64.StdEncoding.DecodeString(value[len(h.Prefix):]); err == nil {
+			signatures = append(signatures, sig)
+		}
+	default:
+		if !strings.HasPrefix(value, h.Prefix) {
+			return false
+		}
+		if sig, err := hex.DecodeString(value[len(h.Prefix):]); err == nil {
+			signatures = append(signatures, sig)
+		}
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(h.hash(), []byte(secret))
+		mac.Write(signed)
+		expected := mac.Sum(nil)
+		for _, sig := range signatures {
+			if hmac.Equal(sig, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func sign(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func TestTriggerHMACFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		valid      bool
+	}{
+		{map[string]interface{}{"header": "X-Signature", "secret_keys": []string{"SECRET"}}, true},
+		{map[string]interface{}{"header": "X-Signature", "secret_keys": []string{"NEW", "OLD"}, "algorithm": "sha512", "format": "base64"}, true},
+		{map[string]interface{}{"header": "Stripe-Signature", "secret_keys": []string{"SECRET"}, "format": "stripe", "tolerance": 60}, true},
+		{map[string]interface{}{"secret_keys": []string{"SECRET"}}, false},
+		{map[string]interface{}{"header": "X-Signature"}, false},
+		{map[string]interface{}{"header": "X-Signature", "secret_keys": []string{""}}, false},
+		{map[string]interface{}{"header": "X-Signature", "secret_keys": []string{"SECRET"}, "algorithm": "md5"}, false},
+		{map[string]interface{}{"header": "X-Signature", "secret_keys": []string{"SECRET"}, "format": "jwt"}, false},
+		{"X-Signature", false},
+	} {
+		annotations, err := EmptyAnnotations().With(TriggerHMACAnnotation, test.annotation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := TriggerHMACFromAnnotations(annotations)
+		if test.valid && (err != nil || h == nil) {
+			t.Errorf("test %d: expected valid trigger HMAC, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidTriggerHMAC {
+			t.Errorf("test %d: expected invalid trigger HMAC error, got: %v", i, err)
+		}
+	}
+}
+
+func TestTriggerHMACVerify(t *testing.T) {
+	now := time.Now()
+	body := `{"event":"paid"}`
+	secrets := []string{"new", "old"}
+
+	for i, test := range []struct {
+		h        TriggerHMAC
+		header   string
+		expected bool
+	}{
+		{TriggerHMAC{Prefix: "sha256="}, "sha256=" + hex.EncodeToString(sign("new", body)), true},
+		// signatures of the previous secret are accepted while it is configured
+		{TriggerHMAC{Prefix: "sha256="}, "sha256=" + hex.EncodeToString(sign("old", body)), true},
+		{TriggerHMAC{Prefix: "sha256="}, "sha256=" + hex.EncodeToString(sign("other", body)), false},
+		{TriggerHMAC{Prefix: "sha256="}, "sha256=" + hex.EncodeToString(sign("new", body+" ")), false},
+		// the prefix is required
+		{TriggerHMAC{Prefix: "sha256="}, hex.EncodeToString(sign("new", body)), false},
+		{TriggerHMAC{Prefix: "sha256=", Format: HMACFormatBase64}, base64.StdEncoding.EncodeToString(sign("new", body)), false},
+		{TriggerHMAC{}, "", false},
+		{TriggerHMAC{Format: HMACFormatBase64}, base64.StdEncoding.EncodeToString(sign("new", body)), true},
+		{TriggerHMAC{Format: HMACFormatStripe},
+			fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), hex.EncodeToString(sign("other", "x")), hex.EncodeToString(sign("old", fmt.Sprintf("%d.%s", now.Unix(), body)))), true},
+		// timestamped signatures expire
+		{TriggerHMAC{Format: HMACFormatStripe},
+			fmt.Sprintf("t=%d,v1=%s", now.Unix()-600, hex.EncodeToString(sign("new", fmt.Sprintf("%d.%s", now.Unix()-600, body)))), false},
+		{TriggerHMAC{Format: HMACFormatStripe}, "v1=" + hex.EncodeToString(sign("new", body)), false},
+	} {
+		test.h.Header = "X-Signature"
+		header := http.Header{}
+		if test.header != "" {
+			header.Set("X-Signature", test.header)
+		}
+		if ok := test.h.Verify(secrets, header, []byte(body), now); ok != test.expected {
+			t.Errorf("test %d: expected signature valid %v, got %v", i, test.expected, ok)
+		}
+	}
+
+	// triggers whose secrets are not configured accept no signature
+	h := TriggerHMAC{Header: "X-Signature"}
+	header := http.Header{"X-Signature": {hex.EncodeToString(sign("", body))}}
+	if h.Verify([]string{""}, header, []byte(body), now) {
+		t.Error("expected signatures of an empty secret to be rejected")
+	}
+}
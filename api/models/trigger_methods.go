@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// TriggerMethodsAnnotation is the annotation of HTTP triggers listing the HTTP methods they
+// are called with, e.g. ["GET", "POST"]. The methods are the operations of the trigger in the
+// OpenAPI document of its app, requests with other methods still call the trigger.
+const TriggerMethodsAnnotation = "fnproject.io/trigger/methods"
+
+// triggerMethods are the methods triggers may list, in the order of OpenAPI documents
+var triggerMethods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch,
+}
+
+var ErrInvalidTriggerMethods = err{
+	code:  http.StatusBadRequest,
+	error: errors.New("Invalid trigger methods, must be a non-empty array of distinct HTTP methods in upper case"),
+}
+
+// TriggerMethodsFromAnnotations returns the methods set in trigger annotations, nil if unset.
+func TriggerMethodsFromAnnotations(annotations Annotations) ([]string, error) {
+	raw, ok := annotations.Get(TriggerMethodsAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var methods []string
+	if err := json.Unmarshal(raw, &methods); err != nil || len(methods) == 0 {
+		return nil, ErrInvalidTriggerMethods
+	}
+	seen := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		if seen[m] || !isTriggerMethod(m) {
+			return nil, ErrInvalidTriggerMethods
+		}
+		seen[m] = true
+	}
+	return methods, nil
+}
+
+func isTriggerMethod(method string) bool {
+	for _, m := range triggerMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestTriggerMethodsFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		valid      bool
+	}{
+		{[]string{"GET"}, true},
+		{[]string{"GET", "POST", "PATCH"}, true},
+		{[]string{}, false},
+		{[]string{"get"}, false},
+		{[]string{"GET", "GET"}, false},
+		{[]string{"CONNECT"}, false},
+		{"GET", false},
+	} {
+		annotations, err := EmptyAnnotations().With(TriggerMethodsAnnotation, test.annotation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		methods, err := TriggerMethodsFromAnnotations(annotations)
+		if test.valid && (err != nil || methods == nil) {
+			t.Errorf("test %d: expected valid trigger methods, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidTriggerMethods {
+			t.Errorf("test %d: expected invalid trigger methods error, got: %v", i, err)
+		}
+	}
+
+	methods, err := TriggerMethodsFromAnnotations(EmptyAnnotations())
+	if methods != nil || err != nil {
+		t.Fatalf("expected no trigger methods by default, got %v %v", methods, err)
+	}
+}
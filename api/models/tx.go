@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"sync"
+)
+
+type txScopeKey struct{}
+
+// TxScope collects the side effects of the operations made in a transaction of a datastore,
+// see Datastore.InTx, to run them once the transaction is committed
+type TxScope struct {
+	mu    sync.Mutex
+	funcs []func()
+}
+
+// WithTxScope returns a context carrying a new scope, for the datastores implementing InTx
+func WithTxScope(ctx context.Context) (context.Context, *TxScope) {
+	scope := new(TxScope)
+	return context.WithValue(ctx, txScopeKey{}, scope), scope
+}
+
+// InTransaction reports whether ctx is the context of operations made in a transaction
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txScopeKey{}).(*TxScope)
+	return ok
+}
+
+// Committed runs the functions deferred in the scope, in the order they were
+func (s *TxScope) Committed() {
+	s.mu.Lock()
+	funcs := s.funcs
+	s.funcs = nil
+	s.mu.Unlock()
+	for _, f := range funcs {
+		f()
+	}
+}
+
+// AfterCommit runs f once the transaction of ctx is committed, and never if it is rolled back.
+// Outside of transactions f runs now.
+func AfterCommit(ctx context.Context, f func()) {
+	scope, ok := ctx.Value(txScopeKey{}).(*TxScope)
+	if !ok {
+		f()
+		return
+	}
+	scope.mu.Lock()
+	scope.funcs = append(scope.funcs, f)
+	scope.mu.Unlock()
+}
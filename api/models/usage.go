@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+)
+
+// UsageQuotaAnnotation is the app annotation holding the daily and monthly quotas of the
+// usage of the app, e.g. {"daily": {"calls": 1000}, "monthly": {"gb_seconds": 3600}}. The
+// usage of apps is not limited if unset.
+const UsageQuotaAnnotation = "fnproject.io/app/usageQuota"
+
+// MBMillisecondsPerGBSecond converts compute usage, tracked in MB of memory of calls times
+// milliseconds of their duration, to GB-seconds.
+const MBMillisecondsPerGBSecond = 1024 * 1000
+
+// Formats of the periods usage is tracked over, in UTC
+const (
+	dailyUsagePeriod   = "2006-01-02"
+	monthlyUsagePeriod = "2006-01"
+)
+
+// UsageLimits are the most calls and GB-seconds of compute an app may use over a period,
+// a zero limit is unlimited.
+type UsageLimits struct {
+	Calls     int64   `json:"calls,omitempty"`
+	GBSeconds float64 `json:"gb_seconds,omitempty"`
+}
+
+// UsageQuota are the limits of the usage of an app per day and per month, both UTC.
+type UsageQuota struct {
+	Daily   *UsageLimits `json:"daily,omitempty"`
+	Monthly *UsageLimits `json:"monthly,omitempty"`
+}
+
+// AppUsage is the usage of an app over a period, a day (2006-01-02) or a month (2006-01).
+type AppUsage struct {
+	AppID  string `json:"app_id" db:"app_id"`
+	Period string `json:"period" db:"period"`
+	Calls  int64  `json:"calls" db:"calls"`
+	// MBMilliseconds is the compute usage, see GBSeconds
+	MBMilliseconds int64 `json:"-" db:"mb_ms"`
+	// GBSeconds is the compute usage, the sum of the memory of calls times their duration
+	GBSeconds float64 `json:"gb_seconds" db:"-"`
+	// Limits are the quota of the app over the period, if any
+	Limits *UsageLimits `json:"limits,omitempty" db:"-"`
+}
+
+// AppUsageReport is the usage of an app over the current day and month
+type AppUsageReport struct {
+	Daily   *AppUsage `json:"daily"`
+	Monthly *AppUsage `json:"monthly"`
+}
+
+var (
+	ErrInvalidUsageQuota = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Invalid usage quota, calls and gb_seconds must not be negative"),
+	}
+	ErrUsageQuotaExceeded = err{
+		code:  http.StatusTooManyRequests,
+		error: errors.New("Usage quota of this app exhausted for the current period"),
+	}
+	ErrMissingUsagePeriod = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing usage period"),
+	}
+)
+
+// UsageQuotaFromAnnotations returns the usage quota set in app annotations, nil if unset.
+func UsageQuotaFromAnnotations(annotations Annotations) (*UsageQuota, error) {
+	raw, ok := annotations.Get(UsageQuotaAnnotation)
+	if !ok {
+		return nil, nil
+	}
+	var quota UsageQuota
+	if err := json.Unmarshal(raw, &quota); err != nil {
+		return nil, ErrInvalidUsageQuota
+	}
+	for _, l := range []*UsageLimits{quota.Daily, quota.Monthly} {
+		if l != nil && (l.Calls < 0 || l.GBSeconds < 0 || math.IsNaN(l.GBSeconds)) {
+			return nil, ErrInvalidUsageQuota
+		}
+	}
+	return &quota, nil
+}
+
+// UsagePeriods returns the day and the month usage at t is tracked over
+func UsagePeriods(t time.Time) (daily, monthly string) {
+	t = t.UTC()
+	return t.Format(dailyUsagePeriod), t.Format(monthlyUsagePeriod)
+}
+
+// SetGBSeconds sets GBSeconds from MBMilliseconds
+func (u *AppUsage) SetGBSeconds() {
+	u.GBSeconds = float64(u.MBMilliseconds) / MBMillisecondsPerGBSecond
+}
+
+// Exhausts returns true if the usage reaches any of limits, so that no more calls are allowed
+func (u *AppUsage) Exhausts(limits *UsageLimits) bool {
+	if limits == nil {
+		return false
+	}
+	if limits.Calls > 0 && u.Calls >= limits.Calls {
+		return true
+	}
+	return limits.GBSeconds > 0 && float64(u.MBMilliseconds) >= limits.GBSeconds*MBMillisecondsPerGBSecond
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsageQuotaFromAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		annotation interface{}
+		expected   *UsageQuota
+		valid      bool
+	}{
+		{nil, nil, true},
+		{map[string]interface{}{"daily": map[string]interface{}{"calls": 100}}, &UsageQuota{Daily: &UsageLimits{Calls: 100}}, true},
+		{map[string]interface{}{"monthly": map[string]interface{}{"gb_seconds": 0.5}}, &UsageQuota{Monthly: &UsageLimits{GBSeconds: 0.5}}, true},
+		{map[string]interface{}{"daily": map[string]interface{}{"calls": -1}}, nil, false},
+		{map[string]interface{}{"monthly": map[string]interface{}{"gb_seconds": -1}}, nil, false},
+		{"1000 calls", nil, false},
+	} {
+		annotations := EmptyAnnotations()
+		var err error
+		if test.annotation != nil {
+			annotations, err = annotations.With(UsageQuotaAnnotation, test.annotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		quota, err := UsageQuotaFromAnnotations(annotations)
+		if test.valid && err != nil {
+			t.Errorf("test %d: expected valid usage quota, got: %v", i, err)
+		}
+		if !test.valid && err != ErrInvalidUsageQuota {
+			t.Errorf("test %d: expected invalid usage quota error, got: %v", i, err)
+		}
+		if (quota == nil) != (test.expected == nil) {
+			t.Errorf("test %d: expected usage quota %+v, got %+v", i, test.expected, quota)
+		}
+	}
+}
+
+func TestAppUsageExhausts(t *testing.T) {
+	u := &AppUsage{Calls: 10, MBMilliseconds: 2 * MBMillisecondsPerGBSecond}
+	if u.Exhausts(nil) || u.Exhausts(&UsageLimits{}) {
+		t.Error("expected usage not to exhaust unlimited quotas")
+	}
+	if !u.Exhausts(&UsageLimits{Calls: 10}) || u.Exhausts(&UsageLimits{Calls: 11}) {
+		t.Error("expected usage to exhaust the calls limit once reached")
+	}
+	if !u.Exhausts(&UsageLimits{GBSeconds: 2}) || u.Exhausts(&UsageLimits{GBSeconds: 2.5}) {
+		t.Error("expected usage to exhaust the gb_seconds limit once reached")
+	}
+
+	daily, monthly := UsagePeriods(time.Date(2018, 12, 31, 23, 30, 0, 0, time.FixedZone("", -3600)))
+	if daily != "2019-01-01" || monthly != "2019-01" {
+		t.Errorf("expected usage periods in UTC, got %s %s", daily, monthly)
+	}
+}
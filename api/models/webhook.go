@@ -0,0 +1,241 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/fnproject/fn/api/common"
+)
+
+// Types of the events of the management API webhooks are notified of
+const (
+	EventAppCreated     = "app.created"
+	EventAppUpdated     = "app.updated"
+	EventAppDeleted     = "app.deleted"
+	EventAppRestored    = "app.restored"
+	EventFnCreated      = "fn.created"
+	EventFnUpdated      = "fn.updated"
+	EventFnDeleted      = "fn.deleted"
+	EventFnRestored     = "fn.restored"
+	EventTriggerCreated = "trigger.created"
+	EventTriggerUpdated = "trigger.updated"
+	EventTriggerDeleted = "trigger.deleted"
+)
+
+// WebhookEventTypes are the types of events webhooks may subscribe to
+var WebhookEventTypes = []string{
+	EventAppCreated, EventAppUpdated, EventAppDeleted, EventAppRestored,
+	EventFnCreated, EventFnUpdated, EventFnDeleted, EventFnRestored,
+	EventTriggerCreated, EventTriggerUpdated, EventTriggerDeleted,
+}
+
+// Statuses of webhook deliveries
+const (
+	// WebhookDelivered deliveries were accepted by the webhook with a 2xx response
+	WebhookDelivered = "delivered"
+	// WebhookFailed deliveries were not accepted by the webhook in any of their attempts
+	WebhookFailed = "failed"
+)
+
+// WebhookSecretPrefix starts the secrets generated for webhooks
+const WebhookSecretPrefix = "whsec_"
+
+// bounds of the length of the secrets of webhooks
+const (
+	minWebhookSecret = 16
+	maxWebhookSecret = 256
+)
+
+// Webhook is a URL notified of the creation, update and deletion of apps, functions and
+// triggers. Events are posted as JSON, signed with the secret of the webhook.
+type Webhook struct {
+	ID  string `json:"id" db:"id"`
+	URL string `json:"url" db:"url"`
+	// Events are the types of events the webhook is notified of, all of them if empty
+	Events WebhookEvents `json:"events,omitempty" db:"events"`
+	// AppID restricts the webhook to the events of an app and of its functions and triggers.
+	// Webhooks restricted to an app are removed with it, so they aren't notified of its deletion.
+	AppID string `json:"app_id,omitempty" db:"app_id"`
+	// Secret signs the events with HMAC-SHA256, it is generated if not set on creation and
+	// only returned when the webhook is created.
+	Secret    string          `json:"secret,omitempty" db:"secret"`
+	CreatedAt common.DateTime `json:"created_at,omitempty" db:"created_at"`
+}
+
+// WebhookEvents are the types of events a webhook subscribes to
+type WebhookEvents []string
+
+// WebhookDelivery is the outcome of the delivery of an event to a webhook.
+type WebhookDelivery struct {
+	ID        string `json:"id" db:"id"`
+	WebhookID string `json:"webhook_id" db:"webhook_id"`
+	EventID   string `json:"event_id" db:"event_id"`
+	Event     string `json:"event" db:"event"`
+	// Status is delivered or failed
+	Status   string `json:"status" db:"status"`
+	Attempts int    `json:"attempts" db:"attempts"`
+	// StatusCode is the status of the response to the last attempt, 0 if there was none
+	StatusCode int `json:"status_code,omitempty" db:"status_code"`
+	// Error is why the last attempt failed
+	Error       string          `json:"error,omitempty" db:"error"`
+	CreatedAt   common.DateTime `json:"created_at,omitempty" db:"created_at"`
+	CompletedAt common.DateTime `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+var (
+	//ErrDatastoreEmptyWebhook - no webhook given to the datastore
+	ErrDatastoreEmptyWebhook = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing webhook"),
+	}
+	//ErrWebhookIDProvided indicates that a webhook ID was specified when it shouldn't have been
+	ErrWebhookIDProvided = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("ID cannot be provided for webhook creation"),
+	}
+	//ErrWebhookInvalidURL - url is not an absolute http or https URL
+	ErrWebhookInvalidURL = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid webhook URL, must be an http or https URL of %v characters or less", MaxWebhookURL)}
+	//ErrWebhookInvalidEvents - events are not types of events
+	ErrWebhookInvalidEvents = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid webhook events, must be some of %v", WebhookEventTypes)}
+	//ErrWebhookInvalidSecret - the secret is too short to sign events
+	ErrWebhookInvalidSecret = err{
+		code:  http.StatusBadRequest,
+		error: fmt.Errorf("Invalid webhook secret, must be between %v and %v characters", minWebhookSecret, maxWebhookSecret)}
+	//ErrWebhookNotFound - webhook not found
+	ErrWebhookNotFound = err{
+		code:  http.StatusNotFound,
+		error: errors.New("Webhook not found")}
+	//ErrWebhookDeliveryMissingWebhookID - deliveries are listed per webhook
+	ErrWebhookDeliveryMissingWebhookID = err{
+		code:  http.StatusBadRequest,
+		error: errors.New("Missing webhook ID")}
+)
+
+// NewWebhookSecret returns a new random secret for a webhook.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return WebhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Validate checks that webhook has valid data for inserting into a store
+func (w *Webhook) Validate() error {
+	if len(w.URL) > MaxWebhookURL {
+		return ErrWebhookInvalidURL
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookInvalidURL
+	}
+
+	seen := make(map[string]bool, len(w.Events))
+	for _, event := range w.Events {
+		if seen[event] || !validWebhookEvent(event) {
+			return ErrWebhookInvalidEvents
+		}
+		seen[event] = true
+	}
+
+	if len(w.Secret) < minWebhookSecret || len(w.Secret) > maxWebhookSecret {
+		return ErrWebhookInvalidSecret
+	}
+	return nil
+}
+
+func validWebhookEvent(event string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribes returns true if the webhook is notified of the events of type event of app appID
+func (w *Webhook) Subscribes(appID, event string) bool {
+	if w.AppID != "" && w.AppID != appID {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements sql.Valuer, returning a string
+func (e WebhookEvents) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal([]string(e))
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (e *WebhookEvents) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
+
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("webhook events invalid db format: %T %T value, err: %v", value, v, v)
+	}
+	if len(b) == 0 {
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(e))
+}
+
+// WebhookFilter is a search criteria on webhooks, in creation order
+type WebhookFilter struct {
+	//AppID searches for the webhooks restricted to an app
+	AppID string // this is exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// WebhookList is a container of webhooks returned by search, optionally indicating the next page cursor
+type WebhookList struct {
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Items      []*Webhook `json:"items"`
+}
+
+// WebhookDeliveryFilter is a search criteria on the deliveries of a webhook, most recent first
+type WebhookDeliveryFilter struct {
+	WebhookID string // this is exact match
+
+	Cursor  string
+	PerPage int
+}
+
+// WebhookDeliveryList is a container of webhook deliveries returned by search, optionally indicating the next page cursor
+type WebhookDeliveryList struct {
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Items      []*WebhookDelivery `json:"items"`
+}
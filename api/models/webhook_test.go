@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, WebhookSecretPrefix) {
+		t.Fatalf("expected secret to start with %q, got %q", WebhookSecretPrefix, secret)
+	}
+
+	for _, test := range []struct {
+		webhook Webhook
+		err     error
+	}{
+		{Webhook{URL: "https://hooks.example.com/fn", Secret: secret}, nil},
+		{Webhook{URL: "http://10.0.0.1:8080", Events: WebhookEvents{EventAppDeleted, EventTriggerUpdated}, Secret: secret}, nil},
+		{Webhook{URL: "", Secret: secret}, ErrWebhookInvalidURL},
+		{Webhook{URL: "/relative", Secret: secret}, ErrWebhookInvalidURL},
+		{Webhook{URL: "ws://hooks.example.com", Secret: secret}, ErrWebhookInvalidURL},
+		{Webhook{URL: "https://hooks.example.com/" + strings.Repeat("a", MaxWebhookURL), Secret: secret}, ErrWebhookInvalidURL},
+		{Webhook{URL: "https://hooks.example.com", Events: WebhookEvents{"app.invoked"}, Secret: secret}, ErrWebhookInvalidEvents},
+		{Webhook{URL: "https://hooks.example.com", Events: WebhookEvents{EventFnCreated, EventFnCreated}, Secret: secret}, ErrWebhookInvalidEvents},
+		{Webhook{URL: "https://hooks.example.com", Secret: "short"}, ErrWebhookInvalidSecret},
+		{Webhook{URL: "https://hooks.example.com", Secret: strings.Repeat("a", 257)}, ErrWebhookInvalidSecret},
+	} {
+		if err := test.webhook.Validate(); err != test.err {
+			t.Errorf("expected %v for %+v, got %v", test.err, test.webhook, err)
+		}
+	}
+}
+
+func TestWebhookSubscribes(t *testing.T) {
+	all := &Webhook{}
+	if !all.Subscribes("app1", EventFnCreated) || !all.Subscribes("app2", EventAppDeleted) {
+		t.Error("expected a webhook without app nor events to subscribe to everything")
+	}
+
+	scoped := &Webhook{AppID: "app1", Events: WebhookEvents{EventFnCreated, EventFnDeleted}}
+	if !scoped.Subscribes("app1", EventFnDeleted) {
+		t.Error("expected webhook to subscribe to the deletion of the functions of its app")
+	}
+	if scoped.Subscribes("app2", EventFnDeleted) {
+		t.Error("expected webhook not to subscribe to the events of other apps")
+	}
+	if scoped.Subscribes("app1", EventFnUpdated) {
+		t.Error("expected webhook not to subscribe to other events")
+	}
+}
+
+func TestWebhookEventsValueScan(t *testing.T) {
+	v, err := WebhookEvents(nil).Value()
+	if err != nil || v != "" {
+		t.Fatalf("expected no events to be stored empty, got %q %v", v, err)
+	}
+
+	v, err = WebhookEvents{EventAppCreated, EventTriggerDeleted}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events WebhookEvents
+	if err := events.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0] != EventAppCreated || events[1] != EventTriggerDeleted {
+		t.Fatalf("expected events to round trip, got %v", events)
+	}
+
+	if err := events.Scan(""); err != nil || events != nil {
+		t.Fatalf("expected empty events, got %v %v", events, err)
+	}
+	if err := events.Scan(42); err == nil {
+		t.Fatal("expected an error scanning an int")
+	}
+}
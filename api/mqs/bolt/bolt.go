@@ -365,6 +365,11 @@ func (mq *BoltDbMQ) Delete(ctx context.Context, job *models.Call) error {
 
 // Close shuts down the bolt db connection and
 // stops the goroutine associated with the ticker
+// Ping implements models.Pinger
+func (mq *BoltDbMQ) Ping(ctx context.Context) error {
+	return mq.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
 func (mq *BoltDbMQ) Close() error {
 	mq.ticker.Stop()
 	return mq.db.Close()
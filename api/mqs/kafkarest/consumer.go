@@ -0,0 +1,199 @@
+package kafkarest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/sirupsen/logrus"
+)
+
+type partition struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets track the records of a partition fetched and not yet done with
+type partitionOffsets struct {
+	pending map[int64]bool
+	// fetched is the offset of the last record fetched
+	fetched int64
+	// committed is the offset of the last record committed, -1 if none
+	committed int64
+}
+
+// consumer consumes a topic through a consumer instance of the proxy, created on first use
+// and again whenever the proxy loses it
+type consumer struct {
+	mq    *KafkaMQ
+	topic string
+
+	mu       sync.Mutex
+	instance string
+	// gen is incremented with each consumer instance, the records fetched by the ones before
+	// being fetched again by the new one
+	gen     int
+	records []*record
+	offsets map[partition]*partitionOffsets
+}
+
+func newConsumer(mq *KafkaMQ, topic string) *consumer {
+	return &consumer{mq: mq, topic: topic, offsets: make(map[partition]*partitionOffsets)}
+}
+
+func (c *consumer) instancePath() string {
+	return fmt.Sprintf("/consumers/%s/instances/%s", url.PathEscape(c.mq.group), url.PathEscape(c.instance))
+}
+
+func (c *consumer) generation() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// create creates the consumer instance and subscribes it to the topic, with c.mu held
+func (c *consumer) create(ctx context.Context) error {
+	name := c.topic + "-" + id.New().String()
+	req := map[string]string{
+		"name":               name,
+		"format":             "json",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}
+	if err := c.mq.do(ctx, http.MethodPost, "/consumers/"+url.PathEscape(c.mq.group), consumerContentType, req, nil); err != nil {
+		return err
+	}
+	c.instance = name
+
+	sub := struct {
+		Topics []string `json:"topics"`
+	}{[]string{c.topic}}
+	if err := c.mq.do(ctx, http.MethodPost, c.instancePath()+"/subscription", consumerContentType, sub, nil); err != nil {
+		c.reset()
+		return err
+	}
+	return nil
+}
+
+// reset forgets the consumer instance and the records it fetched, with c.mu held
+func (c *consumer) reset() {
+	c.instance = ""
+	c.gen++
+	c.records = nil
+	c.offsets = make(map[partition]*partitionOffsets)
+}
+
+// fetch fetches the records of the topic, with c.mu held
+func (c *consumer) fetch(ctx context.Context) error {
+	records, err := c.fetchRecords(ctx)
+	if perr, ok := err.(*proxyError); ok && perr.Code == errConsumerNotFound {
+		logrus.WithFields(logrus.Fields{"topic": c.topic, "instance": c.instance}).Warn("Kafka consumer instance lost, creating another")
+		c.reset()
+		records, err = c.fetchRecords(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		p := partition{rec.Topic, rec.Partition}
+		o, ok := c.offsets[p]
+		if !ok {
+			o = &partitionOffsets{pending: make(map[int64]bool), fetched: -1, committed: -1}
+			c.offsets[p] = o
+		}
+		o.pending[rec.Offset] = true
+		if rec.Offset > o.fetched {
+			o.fetched = rec.Offset
+		}
+	}
+	c.records = append(c.records, records...)
+	return nil
+}
+
+// fetchRecords fetches the records of the topic, creating the consumer instance if need be
+func (c *consumer) fetchRecords(ctx context.Context) ([]*record, error) {
+	if c.instance == "" {
+		if err := c.create(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var records []*record
+	path := fmt.Sprintf("%s/records?timeout=%d&max_bytes=%d", c.instancePath(), fetchTimeout.Nanoseconds()/1e6, fetchMaxBytes)
+	err := c.mq.do(ctx, http.MethodGet, path, contentType, nil, &records)
+	return records, err
+}
+
+// next returns the next record of the topic and the generation of the consumer instance
+// which fetched it, nil if there is none
+func (c *consumer) next(ctx context.Context) (*record, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.records) == 0 {
+		if err := c.fetch(ctx); err != nil {
+			return nil, 0, err
+		}
+		if len(c.records) == 0 {
+			return nil, 0, nil
+		}
+	}
+	rec := c.records[0]
+	c.records = c.records[1:]
+	return rec, c.gen, nil
+}
+
+// done marks the record fetched by the consumer instance of generation gen as done with, and
+// commits the offsets of its partition up to the first record still pending
+func (c *consumer) done(ctx context.Context, gen int, rec *record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		// the consumer instance is gone, the record is fetched again by the new one
+		return nil
+	}
+	p := partition{rec.Topic, rec.Partition}
+	o, ok := c.offsets[p]
+	if !ok {
+		return nil
+	}
+	delete(o.pending, rec.Offset)
+
+	commit := o.fetched
+	for offset := range o.pending {
+		if offset-1 < commit {
+			commit = offset - 1
+		}
+	}
+	if commit <= o.committed {
+		return nil
+	}
+
+	// the proxy commits the position after the offsets given
+	req := map[string][]map[string]interface{}{
+		"offsets": {{"topic": p.topic, "partition": p.partition, "offset": commit}},
+	}
+	if err := c.mq.do(ctx, http.MethodPost, c.instancePath()+"/offsets", consumerContentType, req, nil); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"topic": p.topic, "partition": p.partition, "offset": commit}).Warn("Failed to commit kafka offsets")
+		return err
+	}
+	o.committed = commit
+	return nil
+}
+
+// close deletes the consumer instance, if any
+func (c *consumer) close(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.instance == "" {
+		return nil
+	}
+	err := c.mq.do(ctx, http.MethodDelete, c.instancePath(), consumerContentType, nil, nil)
+	c.reset()
+	return err
+}
@@ -0,0 +1,348 @@
+package kafkarest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+type fakeInstance struct {
+	group  string
+	topics []string
+	// position is the offset of the next record fetched, by topic
+	position map[string]int
+}
+
+// fakeProxy is a proxy of topics of a single partition, consumed by consumer groups
+type fakeProxy struct {
+	mu        sync.Mutex
+	topics    map[string][]json.RawMessage
+	committed map[string]int
+	instances map[string]*fakeInstance
+}
+
+func newFakeProxy() (*fakeProxy, *httptest.Server) {
+	p := &fakeProxy{
+		topics:    make(map[string][]json.RawMessage),
+		committed: make(map[string]int),
+		instances: make(map[string]*fakeInstance),
+	}
+	return p, httptest.NewServer(p)
+}
+
+func (p *fakeProxy) committedOffset(group, topic string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.committed[group+"/"+topic]
+}
+
+// expire forgets every consumer instance, as the proxy does with instances left idle
+func (p *fakeProxy) expire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.instances = make(map[string]*fakeInstance)
+}
+
+func (p *fakeProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/topics":
+		w.Write([]byte(`[]`))
+
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == "topics":
+		var req struct {
+			Records []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rec := range req.Records {
+			p.topics[parts[1]] = append(p.topics[parts[1]], rec.Value)
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":0}]}`))
+
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == "consumers":
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req["auto.commit.enable"] != "false" {
+			http.Error(w, `{"error_code":42204,"message":"expected auto commit disabled"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		p.instances[req["name"]] = &fakeInstance{group: parts[1], position: make(map[string]int)}
+		fmt.Fprintf(w, `{"instance_id":%q}`, req["name"])
+
+	case len(parts) >= 4 && parts[0] == "consumers" && parts[2] == "instances":
+		inst, ok := p.instances[parts[3]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Consumer instance not found."}`))
+			return
+		}
+		p.serveInstance(w, r, parts[3], inst, parts[4:])
+
+	default:
+		http.Error(w, `{"error_code":40401,"message":"unexpected request"}`, http.StatusNotFound)
+	}
+}
+
+func (p *fakeProxy) serveInstance(w http.ResponseWriter, r *http.Request, name string, inst *fakeInstance, parts []string) {
+	switch {
+	case r.Method == http.MethodDelete && len(parts) == 0:
+		delete(p.instances, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPost && parts[0] == "subscription":
+		var req struct {
+			Topics []string `json:"topics"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		inst.topics = req.Topics
+		for _, topic := range req.Topics {
+			inst.position[topic] = p.committed[inst.group+"/"+topic]
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && parts[0] == "records":
+		var records []*record
+		for _, topic := range inst.topics {
+			for ; inst.position[topic] < len(p.topics[topic]); inst.position[topic]++ {
+				offset := inst.position[topic]
+				records = append(records, &record{Topic: topic, Value: p.topics[topic][offset], Offset: int64(offset)})
+			}
+		}
+		json.NewEncoder(w).Encode(records)
+
+	case r.Method == http.MethodPost && parts[0] == "offsets":
+		var req struct {
+			Offsets []struct {
+				Topic  string `json:"topic"`
+				Offset int    `json:"offset"`
+			} `json:"offsets"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, o := range req.Offsets {
+			p.committed[inst.group+"/"+o.Topic] = o.Offset + 1
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, `{"error_code":40401,"message":"unexpected request"}`, http.StatusNotFound)
+	}
+}
+
+func testCall(priority int32) *models.Call {
+	return &models.Call{ID: id.New().String(), FnID: id.New().String(), Priority: &priority}
+}
+
+func reserve(t *testing.T, mq *KafkaMQ) *models.Call {
+	call, err := mq.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return call
+}
+
+func TestKafkaMQ(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	mq := newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	defer mq.Close()
+
+	if err := mq.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected no call reserved, got %s", call.ID)
+	}
+
+	var calls []*models.Call
+	for _, priority := range []int32{0, 2, 1} {
+		call := testCall(priority)
+		if _, err := mq.Push(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, call)
+	}
+
+	// the most prioritized first
+	for i, expected := range []*models.Call{calls[1], calls[2], calls[0]} {
+		call := reserve(t, mq)
+		if call == nil || call.ID != expected.ID {
+			t.Fatalf("Test %d: expected call %s reserved, got %v", i, expected.ID, call)
+		}
+		if err := mq.Delete(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+		if err := mq.Delete(ctx, call); err == nil {
+			t.Fatalf("Test %d: expected a call deleted to no longer be reserved", i)
+		}
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected no call left, got %s", call.ID)
+	}
+
+	for priority := 0; priority <= maxPriority; priority++ {
+		topic := fmt.Sprintf("fn-calls-%d", priority)
+		if offset := p.committedOffset("fn", topic); offset != 1 {
+			t.Fatalf("expected the call of %s committed, got offset %d", topic, offset)
+		}
+	}
+}
+
+func TestKafkaMQCommitsCompleted(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newFakeProxy()
+	defer srv.Close()
+
+	mq := newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	first, second := testCall(0), testCall(0)
+	for _, call := range []*models.Call{first, second} {
+		if _, err := mq.Push(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reserve(t, mq)
+	if err := mq.Delete(ctx, reserve(t, mq)); err != nil {
+		t.Fatal(err)
+	}
+	// the first call still running, nothing is committed
+	if offset := p.committedOffset("fn", "fn-calls-0"); offset != 0 {
+		t.Fatalf("expected no offset committed before the first call completes, got %d", offset)
+	}
+	if err := mq.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server gone, another consumes its calls again
+	mq = newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	defer mq.Close()
+	for i, expected := range []*models.Call{first, second} {
+		call := reserve(t, mq)
+		if call == nil || call.ID != expected.ID {
+			t.Fatalf("Test %d: expected call %s consumed again, got %v", i, expected.ID, call)
+		}
+		if err := mq.Delete(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if offset := p.committedOffset("fn", "fn-calls-0"); offset != 2 {
+		t.Fatalf("expected both calls committed, got offset %d", offset)
+	}
+}
+
+func TestKafkaMQReservationTimeout(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	mq := newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	defer mq.Close()
+
+	pushed := testCall(1)
+	if _, err := mq.Push(ctx, pushed); err != nil {
+		t.Fatal(err)
+	}
+	reserved := reserve(t, mq)
+
+	mq.processTimeouts(ctx, time.Now())
+	if err := mq.Delete(ctx, reserved); err != nil {
+		t.Fatalf("expected the call still reserved, got %v", err)
+	}
+
+	if _, err := mq.Push(ctx, pushed); err != nil {
+		t.Fatal(err)
+	}
+	reserve(t, mq)
+	mq.processTimeouts(ctx, time.Now().Add(2*time.Minute))
+	if err := mq.Delete(ctx, pushed); err == nil {
+		t.Fatal("expected the call timed out to no longer be reserved")
+	}
+
+	// produced again, and committed
+	call := reserve(t, mq)
+	if call == nil || call.ID != pushed.ID {
+		t.Fatalf("expected the call timed out reserved again, got %v", call)
+	}
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if offset := p.committedOffset("fn", "fn-calls-1"); offset != 3 {
+		t.Fatalf("expected every record of the call committed, got offset %d", offset)
+	}
+}
+
+func TestKafkaMQDelayed(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	mq := newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	defer mq.Close()
+
+	pushed := testCall(2)
+	pushed.Delay = 10
+	if _, err := mq.Push(ctx, pushed); err != nil {
+		t.Fatal(err)
+	}
+
+	mq.processDelayed(ctx, time.Now())
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected the delayed call not reserved before its delay, got %s", call.ID)
+	}
+
+	mq.processDelayed(ctx, time.Now().Add(time.Minute))
+	call := reserve(t, mq)
+	if call == nil || call.ID != pushed.ID {
+		t.Fatalf("expected the delayed call reserved after its delay, got %v", call)
+	}
+	if offset := p.committedOffset("fn", "fn-calls-delayed"); offset != 1 {
+		t.Fatalf("expected the delayed call committed, got offset %d", offset)
+	}
+}
+
+func TestKafkaMQConsumerLost(t *testing.T) {
+	ctx := context.Background()
+	p, srv := newFakeProxy()
+	defer srv.Close()
+	mq := newKafkaMQ(srv.URL, "fn-calls", "fn", time.Minute)
+	defer mq.Close()
+
+	pushed := testCall(0)
+	if _, err := mq.Push(ctx, pushed); err != nil {
+		t.Fatal(err)
+	}
+	reserved := reserve(t, mq)
+
+	// the call is consumed again by a new consumer instance
+	p.expire()
+	call := reserve(t, mq)
+	if call == nil || call.ID != pushed.ID {
+		t.Fatalf("expected the call consumed again, got %v", call)
+	}
+	if err := mq.Delete(ctx, reserved); err != nil {
+		t.Fatal(err)
+	}
+}
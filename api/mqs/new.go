@@ -73,6 +73,14 @@ func (m *metricMQ) Delete(ctx context.Context, t *models.Call) error {
 	return m.mq.Delete(ctx, t)
 }
 
+// Ping implements models.Pinger, the message queues which cannot be pinged are reachable
+func (m *metricMQ) Ping(ctx context.Context) error {
+	if p, ok := m.mq.(models.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 // Close closes the underlying message queue
 func (m *metricMQ) Close() error {
 	return m.mq.Close()
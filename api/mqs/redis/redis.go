@@ -323,6 +323,22 @@ func (mq *RedisMQ) Delete(ctx context.Context, job *models.Call) error {
 	return err
 }
 
+// Ping implements models.Pinger
+func (mq *RedisMQ) Ping(ctx context.Context) error {
+	conn, err := mq.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timeout := time.Duration(0)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	_, err = redis.DoWithTimeout(conn, timeout, "PING")
+	return err
+}
+
 // Close shuts down the redis connection pool and
 // stops the goroutine associated with the ticker
 func (mq *RedisMQ) Close() error {
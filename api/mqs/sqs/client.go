@@ -0,0 +1,174 @@
+package sqs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// sqsClient is a client of the operations of the SQS query API the queue uses, the SQS
+// package of the SDK not being vendored. Its shapes follow those of the SDK.
+type sqsClient struct {
+	*client.Client
+}
+
+const (
+	serviceName = "sqs"
+	apiVersion  = "2012-11-05"
+)
+
+func newSQSClient(p client.ConfigProvider, cfgs ...*aws.Config) *sqsClient {
+	c := p.ClientConfig(serviceName, cfgs...)
+	svc := &sqsClient{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   serviceName,
+				ServiceID:     "SQS",
+				SigningName:   c.SigningName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    apiVersion,
+			},
+			c.Handlers,
+		),
+	}
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return svc
+}
+
+func (c *sqsClient) send(ctx context.Context, name string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{Name: name, HTTPMethod: "POST", HTTPPath: "/"}, input, output)
+	if output == nil {
+		req.Handlers.Unmarshal.Remove(query.UnmarshalHandler)
+		req.Handlers.Unmarshal.PushBackNamed(protocol.UnmarshalDiscardBodyHandler)
+	}
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+const (
+	// error codes of SQS
+	errCodeNonExistentQueue   = "AWS.SimpleQueueService.NonExistentQueue"
+	errCodeQueueAlreadyExists = "QueueAlreadyExists"
+)
+
+type createQueueInput struct {
+	_          struct{}           `type:"structure"`
+	Attributes map[string]*string `locationName:"Attribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	QueueName  *string            `type:"string" required:"true"`
+}
+
+type createQueueOutput struct {
+	_        struct{} `type:"structure"`
+	QueueUrl *string  `type:"string"`
+}
+
+func (c *sqsClient) CreateQueue(ctx context.Context, input *createQueueInput) (*createQueueOutput, error) {
+	output := &createQueueOutput{}
+	return output, c.send(ctx, "CreateQueue", input, output)
+}
+
+type getQueueUrlInput struct {
+	_         struct{} `type:"structure"`
+	QueueName *string  `type:"string" required:"true"`
+}
+
+type getQueueUrlOutput struct {
+	_        struct{} `type:"structure"`
+	QueueUrl *string  `type:"string"`
+}
+
+func (c *sqsClient) GetQueueUrl(ctx context.Context, input *getQueueUrlInput) (*getQueueUrlOutput, error) {
+	output := &getQueueUrlOutput{}
+	return output, c.send(ctx, "GetQueueUrl", input, output)
+}
+
+type getQueueAttributesInput struct {
+	_              struct{}  `type:"structure"`
+	AttributeNames []*string `locationNameList:"AttributeName" type:"list" flattened:"true"`
+	QueueUrl       *string   `type:"string" required:"true"`
+}
+
+type getQueueAttributesOutput struct {
+	_          struct{}           `type:"structure"`
+	Attributes map[string]*string `locationName:"Attribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+}
+
+func (c *sqsClient) GetQueueAttributes(ctx context.Context, input *getQueueAttributesInput) (*getQueueAttributesOutput, error) {
+	output := &getQueueAttributesOutput{}
+	return output, c.send(ctx, "GetQueueAttributes", input, output)
+}
+
+type messageAttributeValue struct {
+	_           struct{} `type:"structure"`
+	DataType    *string  `type:"string" required:"true"`
+	StringValue *string  `type:"string"`
+}
+
+type sendMessageInput struct {
+	_                 struct{}                          `type:"structure"`
+	DelaySeconds      *int64                            `type:"integer"`
+	MessageAttributes map[string]*messageAttributeValue `locationName:"MessageAttribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	MessageBody       *string                           `type:"string" required:"true"`
+	QueueUrl          *string                           `type:"string" required:"true"`
+}
+
+type sendMessageOutput struct {
+	_         struct{} `type:"structure"`
+	MessageId *string  `type:"string"`
+}
+
+func (c *sqsClient) SendMessage(ctx context.Context, input *sendMessageInput) (*sendMessageOutput, error) {
+	output := &sendMessageOutput{}
+	return output, c.send(ctx, "SendMessage", input, output)
+}
+
+type receiveMessageInput struct {
+	_                     struct{}  `type:"structure"`
+	AttributeNames        []*string `locationNameList:"AttributeName" type:"list" flattened:"true"`
+	MaxNumberOfMessages   *int64    `type:"integer"`
+	MessageAttributeNames []*string `locationNameList:"MessageAttributeName" type:"list" flattened:"true"`
+	QueueUrl              *string   `type:"string" required:"true"`
+	VisibilityTimeout     *int64    `type:"integer"`
+	WaitTimeSeconds       *int64    `type:"integer"`
+}
+
+type message struct {
+	_                 struct{}                          `type:"structure"`
+	Attributes        map[string]*string                `locationName:"Attribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	Body              *string                           `type:"string"`
+	MessageAttributes map[string]*messageAttributeValue `locationName:"MessageAttribute" locationNameKey:"Name" locationNameValue:"Value" type:"map" flattened:"true"`
+	MessageId         *string                           `type:"string"`
+	ReceiptHandle     *string                           `type:"string"`
+}
+
+type receiveMessageOutput struct {
+	_        struct{}   `type:"structure"`
+	Messages []*message `locationNameList:"Message" type:"list" flattened:"true"`
+}
+
+func (c *sqsClient) ReceiveMessage(ctx context.Context, input *receiveMessageInput) (*receiveMessageOutput, error) {
+	output := &receiveMessageOutput{}
+	return output, c.send(ctx, "ReceiveMessage", input, output)
+}
+
+type deleteMessageInput struct {
+	_             struct{} `type:"structure"`
+	QueueUrl      *string  `type:"string" required:"true"`
+	ReceiptHandle *string  `type:"string" required:"true"`
+}
+
+func (c *sqsClient) DeleteMessage(ctx context.Context, input *deleteMessageInput) error {
+	return c.send(ctx, "DeleteMessage", input, nil)
+}
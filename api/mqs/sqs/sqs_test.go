@@ -0,0 +1,366 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+)
+
+type fakeMessage struct {
+	id        string
+	body      string
+	notBefore string
+	delay     int
+	// receipt is set while the message is received, and invisible
+	receipt string
+}
+
+type fakeQueue struct {
+	name       string
+	attributes map[string]string
+	messages   []*fakeMessage
+}
+
+// fakeSQS serves the operations of the SQS query API the queue uses
+type fakeSQS struct {
+	mu     sync.Mutex
+	url    string
+	queues map[string]*fakeQueue
+}
+
+func newFakeSQS() (*fakeSQS, *httptest.Server) {
+	f := &fakeSQS{queues: make(map[string]*fakeQueue)}
+	srv := httptest.NewServer(f)
+	f.url = srv.URL
+	return f, srv
+}
+
+func (f *fakeSQS) queue(name string) *fakeQueue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queues[f.url+"/000000000000/"+name]
+}
+
+// expire makes the messages received visible again, as their visibility timeout elapses
+func (f *fakeSQS) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, q := range f.queues {
+		for _, m := range q.messages {
+			m.receipt = ""
+		}
+	}
+}
+
+// release makes the messages delayed visible, as their delay elapses
+func (f *fakeSQS) release() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, q := range f.queues {
+		for _, m := range q.messages {
+			m.delay = 0
+		}
+	}
+}
+
+func (f *fakeSQS) fail(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error><RequestId>1</RequestId></ErrorResponse>`, code, code)
+}
+
+func respond(w http.ResponseWriter, action, result string) {
+	fmt.Fprintf(w, `<%sResponse><%sResult>%s</%sResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></%sResponse>`, action, action, result, action, action)
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func formMap(form url.Values, prefix string) map[string]string {
+	res := make(map[string]string)
+	for i := 1; form.Get(fmt.Sprintf("%s.%d.Name", prefix, i)) != ""; i++ {
+		name := form.Get(fmt.Sprintf("%s.%d.Name", prefix, i))
+		value := form.Get(fmt.Sprintf("%s.%d.Value", prefix, i))
+		if value == "" {
+			value = form.Get(fmt.Sprintf("%s.%d.Value.StringValue", prefix, i))
+		}
+		res[name] = value
+	}
+	return res
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" || r.ParseForm() != nil {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	form := r.PostForm
+	action := form.Get("Action")
+	if action == "CreateQueue" || action == "GetQueueUrl" {
+		queueURL := f.url + "/000000000000/" + form.Get("QueueName")
+		q, ok := f.queues[queueURL]
+		switch {
+		case action == "GetQueueUrl" && !ok:
+			f.fail(w, errCodeNonExistentQueue)
+			return
+		case action == "CreateQueue" && !ok:
+			f.queues[queueURL] = &fakeQueue{name: form.Get("QueueName"), attributes: formMap(form, "Attribute")}
+		case action == "CreateQueue" && fmt.Sprint(q.attributes) != fmt.Sprint(formMap(form, "Attribute")):
+			f.fail(w, errCodeQueueAlreadyExists)
+			return
+		}
+		respond(w, action, "<QueueUrl>"+queueURL+"</QueueUrl>")
+		return
+	}
+
+	q, ok := f.queues[form.Get("QueueUrl")]
+	if !ok {
+		f.fail(w, errCodeNonExistentQueue)
+		return
+	}
+	switch action {
+	case "GetQueueAttributes":
+		result := "<Attribute><Name>QueueArn</Name><Value>arn:aws:sqs:us-east-1:000000000000:" + q.name + "</Value></Attribute>"
+		respond(w, action, result)
+
+	case "SendMessage":
+		m := &fakeMessage{id: id.New().String(), body: form.Get("MessageBody"), notBefore: formMap(form, "MessageAttribute")[notBeforeAttribute]}
+		m.delay, _ = strconv.Atoi(form.Get("DelaySeconds"))
+		q.messages = append(q.messages, m)
+		respond(w, action, "<MessageId>"+m.id+"</MessageId>")
+
+	case "ReceiveMessage":
+		for _, m := range q.messages {
+			if m.receipt != "" || m.delay > 0 {
+				continue
+			}
+			m.receipt = id.New().String()
+			result := "<Message><MessageId>" + m.id + "</MessageId><ReceiptHandle>" + m.receipt + "</ReceiptHandle><Body>" + escape(m.body) + "</Body>"
+			if m.notBefore != "" && form.Get("MessageAttributeName.1") == notBeforeAttribute {
+				result += "<MessageAttribute><Name>" + notBeforeAttribute + "</Name><Value><StringValue>" + m.notBefore + "</StringValue><DataType>Number</DataType></Value></MessageAttribute>"
+			}
+			respond(w, action, result+"</Message>")
+			return
+		}
+		respond(w, action, "")
+
+	case "DeleteMessage":
+		for i, m := range q.messages {
+			if m.receipt != "" && m.receipt == form.Get("ReceiptHandle") {
+				q.messages = append(q.messages[:i], q.messages[i+1:]...)
+				respond(w, action, "")
+				return
+			}
+		}
+		f.fail(w, "ReceiptHandleIsInvalid")
+
+	default:
+		f.fail(w, "InvalidAction")
+	}
+}
+
+func newMQ(t *testing.T, srv *httptest.Server, query string) *SQSMQ {
+	u, err := url.Parse("sqs://key:secret@" + strings.TrimPrefix(srv.URL, "http://") + "/us-east-1/fn-calls?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mq, err := sqsProvider(0).New(u)
+	if err != nil {
+		t.Fatalf("failed to create sqs queue: %v", err)
+	}
+	return mq.(*SQSMQ)
+}
+
+func testCall(priority int32) *models.Call {
+	return &models.Call{ID: id.New().String(), FnID: id.New().String(), Priority: &priority}
+}
+
+func reserve(t *testing.T, mq *SQSMQ) *models.Call {
+	call, err := mq.Reserve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return call
+}
+
+func TestSQSMQ(t *testing.T) {
+	ctx := context.Background()
+	_, srv := newFakeSQS()
+	defer srv.Close()
+	mq := newMQ(t, srv, "")
+	defer mq.Close()
+
+	if err := mq.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected no call reserved, got %s", call.ID)
+	}
+
+	var calls []*models.Call
+	for _, priority := range []int32{0, 2, 1} {
+		call := testCall(priority)
+		if _, err := mq.Push(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+		calls = append(calls, call)
+	}
+
+	// the most prioritized first
+	for i, expected := range []*models.Call{calls[1], calls[2], calls[0]} {
+		call := reserve(t, mq)
+		if call == nil || call.ID != expected.ID {
+			t.Fatalf("Test %d: expected call %s reserved, got %v", i, expected.ID, call)
+		}
+		if err := mq.Delete(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+		if err := mq.Delete(ctx, call); err == nil {
+			t.Fatalf("Test %d: expected a call deleted to no longer be reserved", i)
+		}
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected no call left, got %s", call.ID)
+	}
+}
+
+func TestSQSMQReservationTimeout(t *testing.T) {
+	ctx := context.Background()
+	f, srv := newFakeSQS()
+	defer srv.Close()
+	mq := newMQ(t, srv, "timeout=30")
+
+	if v := f.queue("fn-calls-1").attributes["VisibilityTimeout"]; v != "30" {
+		t.Fatalf("expected the queues created with the reservation timeout, got %q", v)
+	}
+
+	pushed := testCall(1)
+	if _, err := mq.Push(ctx, pushed); err != nil {
+		t.Fatal(err)
+	}
+	reserve(t, mq)
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected a call reserved not to be reserved again, got %s", call.ID)
+	}
+
+	f.expire()
+	call := reserve(t, mq)
+	if call == nil || call.ID != pushed.ID {
+		t.Fatalf("expected the call timed out reserved again, got %v", call)
+	}
+	if err := mq.Delete(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.queue("fn-calls-1").messages); n != 0 {
+		t.Fatalf("expected the call deleted, got %d messages", n)
+	}
+}
+
+func TestSQSMQDelayed(t *testing.T) {
+	ctx := context.Background()
+	f, srv := newFakeSQS()
+	defer srv.Close()
+	mq := newMQ(t, srv, "")
+
+	short, long := testCall(0), testCall(0)
+	short.Delay, long.Delay = 10, 3600
+	for _, call := range []*models.Call{short, long} {
+		if _, err := mq.Push(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected delayed calls not reserved, got %s", call.ID)
+	}
+	messages := f.queue("fn-calls-0").messages
+	if messages[0].delay != 10 || messages[0].notBefore != "" {
+		t.Fatalf("expected the short delay as the delay of the message, got %d", messages[0].delay)
+	}
+	if messages[1].delay != maxDelaySeconds || messages[1].notBefore == "" {
+		t.Fatalf("expected the long delay capped and held in an attribute, got %d", messages[1].delay)
+	}
+
+	// the short delay elapses, the long one is sent again
+	f.release()
+	call := reserve(t, mq)
+	if call == nil || call.ID != short.ID {
+		t.Fatalf("expected the call delayed shortly reserved, got %v", call)
+	}
+	if call := reserve(t, mq); call != nil {
+		t.Fatalf("expected the call delayed long not reserved, got %s", call.ID)
+	}
+	messages = f.queue("fn-calls-0").messages
+	if len(messages) != 2 || messages[1].delay != maxDelaySeconds || messages[1].notBefore == "" {
+		t.Fatalf("expected the call delayed long sent again, got %d messages", len(messages))
+	}
+
+	// once its delay elapsed
+	f.mu.Lock()
+	messages[1].notBefore = strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	f.mu.Unlock()
+	f.release()
+	call = reserve(t, mq)
+	if call == nil || call.ID != long.ID {
+		t.Fatalf("expected the call delayed long reserved, got %v", call)
+	}
+}
+
+func TestSQSMQDeadLetterQueue(t *testing.T) {
+	f, srv := newFakeSQS()
+	defer srv.Close()
+	newMQ(t, srv, "dlq=fn-dead&max_receives=3")
+
+	if f.queue("fn-dead") == nil {
+		t.Fatal("expected the dead letter queue created")
+	}
+	var policy map[string]string
+	if err := json.Unmarshal([]byte(f.queue("fn-calls-2").attributes["RedrivePolicy"]), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy["deadLetterTargetArn"] != "arn:aws:sqs:us-east-1:000000000000:fn-dead" || policy["maxReceiveCount"] != "3" {
+		t.Fatalf("expected the calls redriven to the dead letter queue, got %v", policy)
+	}
+
+	// queues which exist are used as they are
+	newMQ(t, srv, "timeout=10")
+	if _, ok := f.queue("fn-calls-0").attributes["RedrivePolicy"]; !ok {
+		t.Fatal("expected the queues which exist left as they are")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for i, rawurl := range []string{
+		"sqs://sqs/us-east-1",
+		"sqs:///us-east-1/",
+		"sqs:////fn-calls",
+		"sqs:///us-east-1/fn-calls?timeout=0",
+		"sqs:///us-east-1/fn-calls?timeout=forever",
+		"sqs:///us-east-1/fn-calls?max_receives=0",
+	} {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (sqsProvider(0)).New(u); err == nil {
+			t.Fatalf("Test %d: expected %s to be invalid", i, rawurl)
+		}
+	}
+}
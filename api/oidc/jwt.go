@@ -0,0 +1,252 @@
+package oidc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hashes of the supported algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// token is a parsed JSON Web Token, in compact serialization, its signature unverified
+type token struct {
+	header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	signingInput string
+	payload      []byte
+	signature    []byte
+}
+
+func parseToken(raw string) (*token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	tok := &token{signingInput: parts[0] + "." + parts[1]}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token header: %v", err)
+	}
+	if err := json.Unmarshal(header, &tok.header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %v", err)
+	}
+	if tok.payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("malformed token payload: %v", err)
+	}
+	if tok.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, fmt.Errorf("malformed token signature: %v", err)
+	}
+	return tok, nil
+}
+
+// algHashes are the hashes of the supported signing algorithms, "none" never is.
+var algHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// algCurves are the curves of the keys of the ECDSA algorithms
+var algCurves = map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+
+// verify checks the signature of the token with key
+func (t *token) verify(key *jsonWebKey) error {
+	hash, ok := algHashes[t.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported token alg %q", t.header.Alg)
+	}
+	h := hash.New()
+	h.Write([]byte(t.signingInput))
+	digest := h.Sum(nil)
+
+	var err error
+	switch pub := key.pub.(type) {
+	case *rsa.PublicKey:
+		if t.header.Alg[0] == 'P' {
+			err = rsa.VerifyPSS(pub, hash, digest, t.signature, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, t.signature)
+		}
+	case *ecdsa.PublicKey:
+		// the signature is r and s, each the size of the curve, big endian
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			err = errors.New("invalid token signature")
+		}
+	}
+	return err
+}
+
+// claims decodes the payload of the token
+func (t *token) claims() (*Claims, error) {
+	c := &Claims{}
+	d := json.NewDecoder(bytes.NewReader(t.payload))
+	d.UseNumber()
+	if err := d.Decode(&c.Raw); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %v", err)
+	}
+
+	c.Issuer, _ = c.Raw["iss"].(string)
+	c.Subject, _ = c.Raw["sub"].(string)
+	switch aud := c.Raw["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				c.Audience = append(c.Audience, s)
+			}
+		}
+	}
+	c.Expiry, _ = numericDate(c.Raw["exp"])
+	return c, nil
+}
+
+// numericDate returns the time of a NumericDate claim, seconds since the epoch
+func numericDate(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set
+type jsonWebKey struct {
+	kid string
+	alg string
+	pub crypto.PublicKey
+}
+
+func parseKey(raw json.RawMessage) (*jsonWebKey, error) {
+	var k struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		// RSA
+		N string `json:"n"`
+		E string `json:"e"`
+		// EC
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return nil, err
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("key %q is not a signing key", k.Kid)
+	}
+
+	key := &jsonWebKey{kid: k.Kid, alg: k.Alg}
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("key %q has an invalid exponent", k.Kid)
+		}
+		key.pub = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("key %q has unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("key %q is not on curve %q", k.Kid, k.Crv)
+		}
+		key.pub = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	default:
+		return nil, fmt.Errorf("key %q has unsupported type %q", k.Kid, k.Kty)
+	}
+	return key, nil
+}
+
+// fits returns true if a token signed with alg may be verified with the key
+func (k *jsonWebKey) fits(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch pub := k.pub.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return algCurves[alg] == pub.Curve.Params().Name
+	}
+	return false
+}
+
+// findKey returns the key for kid and alg, a token without kid may be verified with the
+// only key fitting its alg.
+func findKey(keys []*jsonWebKey, kid, alg string) *jsonWebKey {
+	var found *jsonWebKey
+	for _, k := range keys {
+		if !k.fits(alg) {
+			continue
+		}
+		if kid != "" {
+			if k.kid == kid {
+				return k
+			}
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = k
+	}
+	return found
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("malformed key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,232 @@
+// Package oidc verifies bearer tokens, JSON Web Tokens signed with one of the keys an
+// OpenID Connect issuer publishes in its JSON Web Key Set.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLeeway is the clock skew allowed on the exp and nbf claims of tokens
+	DefaultLeeway = time.Minute
+
+	// keys are fetched again after keysTTL, or when a token is signed with an unknown key
+	// but not more often than every keysMinRefresh, so that tokens with made up key ids
+	// can't flood the issuer.
+	keysTTL        = time.Hour
+	keysMinRefresh = 30 * time.Second
+
+	discoveryPath = "/.well-known/openid-configuration"
+)
+
+// Config of a Verifier
+type Config struct {
+	// Issuer is the URL of the issuer, the iss claim of tokens must be equal to it.
+	Issuer string
+	// JWKSURL is the URL of the key set of the issuer. It is discovered from the OpenID
+	// configuration of the issuer if empty.
+	JWKSURL string
+	// Audience, if set, must be one of the audiences of the aud claim of tokens.
+	Audience string
+	// Leeway is the clock skew allowed on the exp and nbf claims, DefaultLeeway if 0.
+	Leeway time.Duration
+	// Client fetches the configuration and keys of the issuer, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Claims are the claims of a verified token.
+type Claims struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	Expiry   time.Time
+	// Raw holds all the claims of the token, including the ones above.
+	Raw map[string]interface{}
+}
+
+// Verifier verifies tokens against the keys of an issuer. It is safe for concurrent use.
+type Verifier struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	jwksURL   string
+	keys      []*jsonWebKey
+	fetchedAt time.Time
+}
+
+// NewVerifier returns a verifier of the tokens of the issuer of cfg. The keys of the
+// issuer are only fetched once a token is verified, so that the issuer being unreachable
+// does not prevent the server from starting.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.Issuer == "" {
+		return nil, errors.New("missing oidc issuer")
+	}
+	if _, err := url.Parse(cfg.Issuer); err != nil {
+		return nil, fmt.Errorf("bad oidc issuer %q: %v", cfg.Issuer, err)
+	}
+	if cfg.Leeway == 0 {
+		cfg.Leeway = DefaultLeeway
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &Verifier{cfg: cfg, now: time.Now, jwksURL: cfg.JWKSURL}, nil
+}
+
+// Verify checks the signature and claims of the raw token, returning its claims if valid.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	tok, err := parseToken(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := v.key(ctx, tok.header.Kid, tok.header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := tok.verify(key); err != nil {
+		return nil, err
+	}
+
+	claims, err := tok.claims()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate checks the registered claims of a token with a valid signature
+func (v *Verifier) validate(c *Claims) error {
+	if c.Issuer != v.cfg.Issuer {
+		return fmt.Errorf("token issued by %q, expected %q", c.Issuer, v.cfg.Issuer)
+	}
+	if v.cfg.Audience != "" && !contains(c.Audience, v.cfg.Audience) {
+		return fmt.Errorf("token not issued for audience %q", v.cfg.Audience)
+	}
+
+	now := v.now()
+	if c.Expiry.IsZero() {
+		return errors.New("token has no expiry")
+	}
+	if now.After(c.Expiry.Add(v.cfg.Leeway)) {
+		return fmt.Errorf("token expired at %v", c.Expiry)
+	}
+	if nbf, ok := numericDate(c.Raw["nbf"]); ok && now.Add(v.cfg.Leeway).Before(nbf) {
+		return fmt.Errorf("token not valid before %v", nbf)
+	}
+	return nil
+}
+
+// key returns the key of the issuer a token was signed with
+func (v *Verifier) key(ctx context.Context, kid, alg string) (*jsonWebKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := findKey(v.keys, kid, alg)
+	stale := v.now().Sub(v.fetchedAt) > keysTTL
+	canRefresh := v.now().Sub(v.fetchedAt) > keysMinRefresh
+	if stale || (key == nil && canRefresh) {
+		keys, err := v.fetchKeys(ctx)
+		if err != nil {
+			if v.keys == nil {
+				return nil, err
+			}
+			// keep verifying with the keys we have until the issuer is back
+			logrus.WithError(err).WithFields(logrus.Fields{"issuer": v.cfg.Issuer}).Warn("failed to refresh oidc keys")
+		} else {
+			v.keys = keys
+		}
+		// failed fetches count too, to not retry them for every token
+		v.fetchedAt = v.now()
+		key = findKey(v.keys, kid, alg)
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("no key of the issuer for kid %q and alg %q", kid, alg)
+	}
+	return key, nil
+}
+
+// fetchKeys fetches the key set of the issuer, discovering its URL first if need be
+func (v *Verifier) fetchKeys(ctx context.Context) ([]*jsonWebKey, error) {
+	if v.jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.Issuer, "/")+discoveryPath, &discovery)
+		if err != nil {
+			return nil, err
+		}
+		if discovery.Issuer != v.cfg.Issuer {
+			return nil, fmt.Errorf("oidc configuration is of issuer %q, expected %q", discovery.Issuer, v.cfg.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("oidc configuration has no jwks_uri")
+		}
+		v.jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &set); err != nil {
+		return nil, err
+	}
+	keys := make([]*jsonWebKey, 0, len(set.Keys))
+	for _, raw := range set.Keys {
+		key, err := parseKey(raw)
+		if err != nil {
+			// an issuer may publish keys of types we don't support alongside the ones it signs with
+			logrus.WithError(err).WithFields(logrus.Fields{"issuer": v.cfg.Issuer}).Debug("skipping oidc key")
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable key in key set %s", v.jwksURL)
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, u string, dst interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.cfg.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status code %d", u, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("GET %s: %v", u, err)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
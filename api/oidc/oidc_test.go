@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/oidc/oidctest"
+)
+
+func TestVerify(t *testing.T) {
+	iss := oidctest.NewIssuer()
+	defer iss.Close()
+
+	v, err := NewVerifier(Config{Issuer: iss.URL, Audience: "fn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	claims := iss.Claims("alice")
+	claims["aud"] = []string{"other", "fn"}
+	claims["groups"] = []string{"admins"}
+	for _, raw := range []string{iss.Token(claims), iss.ECToken(claims)} {
+		c, err := v.Verify(ctx, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Subject != "alice" || c.Issuer != iss.URL || len(c.Audience) != 2 || c.Raw["groups"] == nil {
+			t.Fatalf("unexpected claims %+v", c)
+		}
+	}
+	if iss.KeyFetches() != 1 {
+		t.Fatalf("expected keys to be fetched once, got %d", iss.KeyFetches())
+	}
+
+	invalid := map[string]func(map[string]interface{}){
+		"expired":     func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no expiry":   func(c map[string]interface{}) { delete(c, "exp") },
+		"not yet":     func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"issuer":      func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" },
+		"no audience": func(c map[string]interface{}) { delete(c, "aud") },
+		"audience":    func(c map[string]interface{}) { c["aud"] = "other" },
+	}
+	for name, change := range invalid {
+		claims := iss.Claims("alice")
+		claims["aud"] = "fn"
+		change(claims)
+		if _, err := v.Verify(ctx, iss.Token(claims)); err == nil {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+
+	claims = iss.Claims("alice")
+	claims["aud"] = "fn"
+	parts := strings.Split(iss.Token(claims), ".")
+	claims["sub"] = "admin"
+	tampered := parts[0] + "." + strings.Split(iss.Token(claims), ".")[1] + "." + parts[2]
+	unsigned := "eyJhbGciOiJub25lIn0." + parts[1] + "."
+	for name, raw := range map[string]string{"tampered": tampered, "unsigned": unsigned, "malformed": "abc"} {
+		if _, err := v.Verify(ctx, raw); err == nil {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+
+	// unknown keys are not fetched again for every token
+	if iss.KeyFetches() != 1 {
+		t.Fatalf("expected keys to be fetched once, got %d", iss.KeyFetches())
+	}
+}
+
+func TestVerifyKeyRefresh(t *testing.T) {
+	iss := oidctest.NewIssuer()
+	defer iss.Close()
+
+	v, err := NewVerifier(Config{Issuer: iss.URL, JWKSURL: iss.URL + "/keys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	v.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, iss.Token(iss.Claims("alice"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys are kept while the issuer is down, until a fetch succeeds
+	iss.Close()
+	now = now.Add(keysTTL + time.Second)
+	claims := iss.Claims("alice")
+	claims["exp"] = now.Add(time.Hour).Unix()
+	if _, err := v.Verify(ctx, iss.Token(claims)); err != nil {
+		t.Fatal(err)
+	}
+	if iss.KeyFetches() != 1 {
+		t.Fatalf("expected keys to be fetched once, got %d", iss.KeyFetches())
+	}
+
+	if _, err := NewVerifier(Config{}); err == nil {
+		t.Fatal("expected a verifier without issuer to fail")
+	}
+}
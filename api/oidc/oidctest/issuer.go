@@ -0,0 +1,129 @@
+// Package oidctest provides an OpenID Connect issuer serving its configuration and keys
+// over HTTP, and signing tokens, to test the verification of tokens.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+)
+
+// Key ids of the keys of an Issuer
+const (
+	RSAKeyID = "rsa"
+	ECKeyID  = "ec"
+)
+
+// Issuer is an issuer of tokens, its URL is the URL of its server.
+type Issuer struct {
+	*httptest.Server
+
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	keyFetches int32
+}
+
+// NewIssuer starts an issuer with an RSA and an ECDSA P-256 key, it must be closed once done.
+func NewIssuer() *Issuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	iss := &Issuer{rsaKey: rsaKey, ecKey: ecKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   iss.URL,
+			"jwks_uri": iss.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&iss.keyFetches, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": RSAKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   encode(rsaKey.N.Bytes()),
+				"e":   encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+			}, {
+				"kty": "EC",
+				"kid": ECKeyID,
+				"crv": "P-256",
+				"x":   encode(ecKey.X.Bytes()),
+				"y":   encode(ecKey.Y.Bytes()),
+			}},
+		})
+	})
+	iss.Server = httptest.NewServer(mux)
+	return iss
+}
+
+// KeyFetches returns the number of times the keys of the issuer were fetched.
+func (iss *Issuer) KeyFetches() int {
+	return int(atomic.LoadInt32(&iss.keyFetches))
+}
+
+// Claims returns the claims of a token of the issuer for subject, expiring in an hour.
+func (iss *Issuer) Claims(subject string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss": iss.URL,
+		"sub": subject,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// Token returns a token with claims, signed with the RSA key of the issuer.
+func (iss *Issuer) Token(claims map[string]interface{}) string {
+	input := signingInput("RS256", RSAKeyID, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return input + "." + encode(sig)
+}
+
+// ECToken returns a token with claims, signed with the ECDSA key of the issuer.
+func (iss *Issuer) ECToken(claims map[string]interface{}) string {
+	input := signingInput("ES256", ECKeyID, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return input + "." + encode(sig)
+}
+
+func signingInput(alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": alg, "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	return encode(header) + "." + encode(payload)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,249 @@
+// Package openapi assembles OpenAPI documents describing the HTTP triggers of apps, so that
+// clients can be generated against deployed functions. Schemas are the JSON Schemas of
+// functions, hence documents follow OpenAPI 3.1 whose schemas are JSON Schemas.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Version is the version of the OpenAPI specification of the documents
+const Version = "3.1.0"
+
+// DefaultMethods are the methods documented for the HTTP triggers which list none, see
+// models.TriggerMethodsAnnotation
+var DefaultMethods = []string{"POST"}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// Info describes the API of a document
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is a base URL of the paths of a document
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+// Operation is a method of a path, the call of a function through an HTTP trigger
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// RequestBody describes the request bodies of an operation
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes the responses of an operation with a status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes a body of a content type
+type MediaType struct {
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// Components holds the schemas referenced by operations
+type Components struct {
+	Schemas map[string]json.RawMessage `json:"schemas"`
+}
+
+// errorSchema is the schema of the models.Error of calls rejected by their request schema
+const errorSchema = `{"type":"object","properties":{"message":{"type":"string"},"errors":{"type":"array","items":{"type":"object","properties":{"path":{"type":"string"},"message":{"type":"string"}}}}}}`
+
+// anyContent is the content of bodies without a schema
+var anyContent = map[string]MediaType{"*/*": {}}
+
+// Build returns the document of the HTTP triggers of app with the fns of the app, the paths of
+// triggers being relative to serverURLs. The version of the document is the time of the last
+// change of the app, its fns or its triggers.
+func Build(app *models.App, serverURLs []string, triggers []*models.Trigger, fns []*models.Fn) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: app.Name},
+		Paths:   make(map[string]PathItem),
+	}
+	for _, u := range serverURLs {
+		doc.Servers = append(doc.Servers, Server{URL: u})
+	}
+
+	updated := time.Time(app.UpdatedAt)
+	byID := make(map[string]*models.Fn, len(fns))
+	for _, fn := range fns {
+		byID[fn.ID] = fn
+	}
+	schemas := make(map[string]json.RawMessage)
+
+	for _, t := range triggers {
+		fn, ok := byID[t.FnID]
+		if t.Type != models.TriggerTypeHTTP || !ok {
+			continue
+		}
+		if u := time.Time(t.UpdatedAt); u.After(updated) {
+			updated = u
+		}
+		if u := time.Time(fn.UpdatedAt); u.After(updated) {
+			updated = u
+		}
+
+		requestRef, err := addSchema(schemas, fn.Name+".request", json.RawMessage(fn.RequestSchema))
+		if err != nil {
+			return nil, err
+		}
+		responseRef, err := addSchema(schemas, fn.Name+".response", json.RawMessage(fn.ResponseSchema))
+		if err != nil {
+			return nil, err
+		}
+
+		methods, err := models.TriggerMethodsFromAnnotations(t.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		if methods == nil {
+			methods = DefaultMethods
+		}
+
+		path := "/" + strings.TrimPrefix(t.Source, "/")
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		for _, m := range methods {
+			op := &Operation{
+				OperationID: t.Name,
+				Summary:     "Calls function " + fn.Name,
+				Tags:        []string{fn.Name},
+				Responses: map[string]*Response{
+					"200": {Description: "Response of the function", Content: content(responseRef)},
+				},
+			}
+			if len(methods) > 1 {
+				op.OperationID += "_" + strings.ToLower(m)
+			}
+			if hasBody(m) {
+				op.RequestBody = &RequestBody{Required: requestRef != nil, Content: content(requestRef)}
+			}
+			if requestRef != nil {
+				schemas["Error"] = json.RawMessage(errorSchema)
+				op.Responses["400"] = &Response{
+					Description: "The request body does not match the request schema of the function",
+					Content:     content(ref("Error")),
+				}
+			}
+			item[strings.ToLower(m)] = op
+		}
+	}
+
+	if len(schemas) > 0 {
+		doc.Components = &Components{Schemas: schemas}
+	}
+	doc.Info.Version = updated.UTC().Format(time.RFC3339)
+	return doc, nil
+}
+
+// addSchema adds the schema of a function to the components of a document, returning a
+// reference to it, nil if the schema is empty.
+func addSchema(schemas map[string]json.RawMessage, name string, schema json.RawMessage) (json.RawMessage, error) {
+	if models.RequestSchema(schema).IsEmpty() {
+		return nil, nil
+	}
+	name = componentName(name)
+	if _, ok := schemas[name]; ok {
+		return ref(name), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(schema))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(rebase(v, "#/components/schemas/"+name))
+	if err != nil {
+		return nil, err
+	}
+	schemas[name] = raw
+	return ref(name), nil
+}
+
+// rebase rewrites the local references of a schema moved to base
+func rebase(v interface{}, base string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range v {
+			switch k {
+			case "$ref":
+				if r, ok := sub.(string); ok && strings.HasPrefix(r, "#") {
+					v[k] = base + r[1:]
+				}
+			case "enum", "const", "default", "examples":
+				// values, not schemas
+			default:
+				v[k] = rebase(sub, base)
+			}
+		}
+	case []interface{}:
+		for i, sub := range v {
+			v[i] = rebase(sub, base)
+		}
+	}
+	return v
+}
+
+// componentName returns name with the characters not allowed in component names replaced
+func componentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func ref(name string) json.RawMessage {
+	raw, _ := json.Marshal(map[string]string{"$ref": "#/components/schemas/" + name})
+	return raw
+}
+
+func content(schema json.RawMessage) map[string]MediaType {
+	if schema == nil {
+		return anyContent
+	}
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// hasBody returns true if requests with method have a body
+func hasBody(method string) bool {
+	switch method {
+	case "GET", "HEAD", "DELETE", "OPTIONS":
+		return false
+	}
+	return true
+}
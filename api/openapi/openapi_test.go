@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestBuild(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp", UpdatedAt: common.DateTime(time.Unix(1000, 0))}
+	fns := []*models.Fn{
+		{
+			ID: "fn1", Name: "greet", UpdatedAt: common.DateTime(time.Unix(2000, 0)),
+			RequestSchema:  models.RequestSchema(`{"definitions": {"name": {"type": "string"}}, "properties": {"name": {"$ref": "#/definitions/name"}}, "enum": [{"$ref": "#"}]}`),
+			ResponseSchema: models.ResponseSchema(`{"type": "string"}`),
+		},
+		{ID: "fn2", Name: "echo"},
+	}
+	methods, _ := models.EmptyAnnotations().With(models.TriggerMethodsAnnotation, []string{"GET", "POST"})
+	triggers := []*models.Trigger{
+		{ID: "t1", Name: "greeting", FnID: "fn1", Type: models.TriggerTypeHTTP, Source: "/greet", Annotations: methods},
+		{ID: "t2", Name: "echo", FnID: "fn2", Type: models.TriggerTypeHTTP, Source: "echo"},
+		{ID: "t3", Name: "orphan", FnID: "missing", Type: models.TriggerTypeHTTP, Source: "/orphan"},
+	}
+
+	doc, err := Build(app, []string{"http://localhost:8080/t/myapp"}, triggers, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != Version || doc.Info.Title != "myapp" || doc.Info.Version != "1970-01-01T00:33:20Z" {
+		t.Fatalf("unexpected document info %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "http://localhost:8080/t/myapp" {
+		t.Fatalf("unexpected servers %+v", doc.Servers)
+	}
+	if len(doc.Paths) != 2 || doc.Paths["/greet"] == nil || doc.Paths["/echo"] == nil {
+		t.Fatalf("expected the paths of the triggers with a fn, got %+v", doc.Paths)
+	}
+
+	// triggers are documented with their methods and the schemas of their fn
+	get, post := doc.Paths["/greet"]["get"], doc.Paths["/greet"]["post"]
+	if get == nil || post == nil || len(doc.Paths["/greet"]) != 2 {
+		t.Fatalf("expected GET and POST operations, got %+v", doc.Paths["/greet"])
+	}
+	if get.OperationID != "greeting_get" || get.RequestBody != nil || get.Responses["400"] == nil {
+		t.Fatalf("unexpected GET operation %+v", get)
+	}
+	if post.OperationID != "greeting_post" || post.RequestBody == nil || !post.RequestBody.Required ||
+		string(post.RequestBody.Content["application/json"].Schema) != `{"$ref":"#/components/schemas/greet.request"}` {
+		t.Fatalf("unexpected POST operation %+v", post)
+	}
+	if string(post.Responses["200"].Content["application/json"].Schema) != `{"$ref":"#/components/schemas/greet.response"}` {
+		t.Fatalf("unexpected POST response %+v", post.Responses["200"])
+	}
+
+	// references of the schemas are moved along with them
+	request := doc.Components.Schemas["greet.request"]
+	expected := `{"definitions":{"name":{"type":"string"}},"enum":[{"$ref":"#"}],"properties":{"name":{"$ref":"#/components/schemas/greet.request/definitions/name"}}}`
+	if string(request) != expected {
+		t.Fatalf("expected request schema %s, got %s", expected, request)
+	}
+	if _, ok := doc.Components.Schemas["Error"]; !ok {
+		t.Fatal("expected the error schema of rejected calls")
+	}
+
+	// triggers without methods and fns without schemas take any body
+	echo := doc.Paths["/echo"]["post"]
+	if echo == nil || echo.OperationID != "echo" || echo.RequestBody == nil || echo.RequestBody.Required ||
+		echo.RequestBody.Content["*/*"].Schema != nil || echo.Responses["400"] != nil {
+		t.Fatalf("unexpected echo operation %+v", doc.Paths["/echo"])
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often full buckets are dropped from a MemoryStore, a bucket that
+// does not exist is full.
+const pruneInterval = time.Minute
+
+// MemoryStore keeps buckets in memory, limiting the calls of a single node.
+type MemoryStore struct {
+	now func() time.Time
+
+	lock       sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+}
+
+type bucket struct {
+	limit   Limit
+	tokens  float64
+	updated time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPruned) > pruneInterval {
+		s.prune(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst)}
+		s.buckets[key] = b
+	} else {
+		b.tokens = refill(limit, b.tokens, now.Sub(b.updated))
+	}
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(limit, allowed, b.tokens), nil
+}
+
+// Refund implements Store
+func (s *MemoryStore) Refund(ctx context.Context, key string, limit Limit) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		// the bucket is full
+		return nil
+	}
+	now := s.now()
+	b.tokens = math.Min(refill(limit, b.tokens, now.Sub(b.updated))+1, float64(limit.Burst))
+	b.updated = now
+	return nil
+}
+
+func (s *MemoryStore) prune(now time.Time) {
+	for key, b := range s.buckets {
+		if refill(b.limit, b.tokens, now.Sub(b.updated)) >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPruned = now
+}
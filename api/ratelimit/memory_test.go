@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+
+	take := func(key string, allowed bool, remaining int) *Result {
+		t.Helper()
+		res, err := s.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != allowed || res.Remaining != remaining || res.Limit != 3 {
+			t.Fatalf("expected allowed=%v remaining=%d, got %+v", allowed, remaining, res)
+		}
+		return res
+	}
+
+	// buckets start full and are drained by bursts
+	take("a", true, 2)
+	take("a", true, 1)
+	res := take("a", true, 0)
+	if res.Reset != 1500*time.Millisecond {
+		t.Fatalf("expected the bucket to be full again in 1.5s, got %v", res.Reset)
+	}
+	res = take("a", false, 0)
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected a token in 0.5s, got %v", res.RetryAfter)
+	}
+
+	// other keys have their own bucket
+	take("b", true, 2)
+
+	// buckets are refilled at the rate, up to the burst
+	now = now.Add(500 * time.Millisecond)
+	take("a", true, 0)
+	now = now.Add(time.Hour)
+	take("a", true, 2)
+
+	// refunded tokens are put back, up to the burst
+	now = now.Add(time.Hour)
+	take("c", true, 2)
+	take("c", true, 1)
+	if err := s.Refund(ctx, "c", limit); err != nil {
+		t.Fatal(err)
+	}
+	take("c", true, 1)
+	for i := 0; i < 3; i++ {
+		if err := s.Refund(ctx, "c", limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	take("c", true, 2)
+	if err := s.Refund(ctx, "d", limit); err != nil || s.buckets["d"] != nil {
+		t.Fatalf("expected refunds of missing buckets to leave them full, got %v", err)
+	}
+	now = now.Add(time.Hour)
+
+	// full buckets were pruned
+	if _, ok := s.buckets["b"]; ok || len(s.buckets) != 1 {
+		t.Fatalf("expected full buckets to be pruned, got %d buckets", len(s.buckets))
+	}
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(""); err != nil || s == nil {
+		t.Fatalf("expected a memory store, got %v %v", s, err)
+	}
+	if _, err := New("memcached://localhost"); err == nil {
+		t.Fatal("expected unsupported stores to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+// Package ratelimit limits the rate of calls with token buckets, kept in the memory of a
+// node or in a store shared by the nodes of a cluster.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+)
+
+// Limit of a bucket, refilled with Rate tokens per second up to Burst tokens. A call takes
+// a token, it is rejected if the bucket is empty.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result of taking a token from a bucket
+type Result struct {
+	// Allowed is true if a token was taken
+	Allowed bool
+	// Limit is the burst of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, if none was taken
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets
+type Store interface {
+	// Take takes a token from the bucket of key, created full with limit if it does not
+	// exist yet.
+	Take(ctx context.Context, key string, limit Limit) (*Result, error)
+	// Refund puts back a token taken from the bucket of key, by a call which was rejected
+	// by another limit.
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// New returns the store of a URL, the memory of the node if empty. redis://host:port/prefix
+// shares buckets between nodes through redis, with keys starting with prefix.
+func New(storeURL string) (Store, error) {
+	if storeURL == "" {
+		return NewMemoryStore(), nil
+	}
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedisStore(u)
+	}
+	return nil, fmt.Errorf("unsupported rate limit store %q", u.Scheme)
+}
+
+// refill returns the tokens of a bucket that had tokens elapsed ago, capped to its burst
+func refill(limit Limit, tokens float64, elapsed time.Duration) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * limit.Rate
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// result describes a bucket left with tokens
+func result(limit Limit, allowed bool, tokens float64) *Result {
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/common/redispool"
+	"github.com/garyburd/redigo/redis"
+)
+
+// takeScript takes a token from the bucket of KEYS[1] atomically. ARGV are the rate, the
+// burst and the current time in milliseconds. It returns 1 if a token was taken, and the
+// tokens left as a string since lua numbers are returned truncated to integers. Buckets
+// expire once full, a bucket that does not exist is full.
+var takeScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+elseif now > updated then
+	tokens = math.min(burst, tokens + (now - updated) / 1000 * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// refundScript puts a token back in the bucket of KEYS[1], with the same ARGV as takeScript.
+// Buckets which expired are full already.
+var refundScript = redis.NewScript(1, `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	return 0
+elseif now > updated then
+	tokens = tokens + (now - updated) / 1000 * rate
+end
+tokens = math.min(burst, tokens + 1)
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return 1
+`)
+
+// RedisStore keeps buckets in redis, limiting the calls of all the nodes sharing it. The
+// clocks of the nodes should be in sync.
+type RedisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore returns the store of a redis://host:port/prefix URL
+func NewRedisStore(u *url.URL) (*RedisStore, error) {
+	pool, err := redispool.New(u)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{pool: pool, prefix: redispool.Prefix(u)}, nil
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	values, err := redis.Values(takeScript.Do(conn, s.key(key), limit.Rate, limit.Burst, now))
+	if err != nil {
+		return nil, err
+	}
+	var allowed int
+	var left string
+	if _, err := redis.Scan(values, &allowed, &left); err != nil {
+		return nil, err
+	}
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return nil, err
+	}
+	return result(limit, allowed == 1, tokens), nil
+}
+
+// Refund implements Store
+func (s *RedisStore) Refund(ctx context.Context, key string, limit Limit) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	_, err := refundScript.Do(conn, s.key(key), limit.Rate, limit.Burst, now)
+	return err
+}
+
+func (s *RedisStore) key(key string) string {
+	return s.prefix + "ratelimit:" + key
+}
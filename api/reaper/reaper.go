@@ -0,0 +1,188 @@
+// Package reaper purges the apps and functions marked deleted once they have been deleted
+// for longer than their retention, until then they may be restored.
+//
+// Any number of nodes may run a Reaper against the same datastore, a datastore lease elects
+// the one that purges. Apps are purged along with their functions, triggers and every other
+// resource of theirs.
+package reaper
+
+import (
+	"context"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LeaseName is the name of the datastore lease held by the reaper that purges
+	LeaseName = "reaper"
+
+	// DefaultInterval is the default interval deleted resources are polled at
+	DefaultInterval = time.Minute
+	// DefaultLeaseTTL is the default time a reaper remains leader for after it last polled
+	DefaultLeaseTTL = 3 * time.Minute
+	// DefaultPageSize is the default number of apps and functions listed at once
+	DefaultPageSize = 100
+)
+
+// Reaper purges expired deleted resources, see the package documentation
+type Reaper struct {
+	ds        models.Datastore
+	holder    string
+	retention time.Duration
+	interval  time.Duration
+	leaseTTL  time.Duration
+	pageSize  int
+}
+
+// Option configures a Reaper
+type Option func(*Reaper)
+
+// WithInterval sets the interval deleted resources are polled at
+func WithInterval(d time.Duration) Option {
+	return func(r *Reaper) {
+		r.interval = d
+	}
+}
+
+// WithLeaseTTL sets the time a reaper remains leader for after it last polled, it must
+// exceed the poll interval
+func WithLeaseTTL(d time.Duration) Option {
+	return func(r *Reaper) {
+		r.leaseTTL = d
+	}
+}
+
+// WithPageSize sets the number of apps and functions listed at once
+func WithPageSize(n int) Option {
+	return func(r *Reaper) {
+		r.pageSize = n
+	}
+}
+
+// New creates a reaper purging the apps and functions of ds deleted for longer than retention
+func New(ds models.Datastore, retention time.Duration, opts ...Option) *Reaper {
+	r := &Reaper{
+		ds:        ds,
+		holder:    id.New().String(),
+		retention: retention,
+		interval:  DefaultInterval,
+		leaseTTL:  DefaultLeaseTTL,
+		pageSize:  DefaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run polls for expired deleted resources until ctx is done
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Tick(ctx, time.Now()); err != nil {
+			common.Logger(ctx).WithError(err).Error("error purging deleted resources")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick purges the resources deleted for longer than the retention at now if this reaper
+// holds the reaper lease
+func (r *Reaper) Tick(ctx context.Context, now time.Time) error {
+	leader, err := r.ds.AcquireLease(ctx, LeaseName, r.holder, r.leaseTTL)
+	if err != nil || !leader {
+		return err
+	}
+
+	expiry := now.Add(-r.retention)
+	if err := r.reapApps(ctx, expiry); err != nil {
+		return err
+	}
+	return r.reapFns(ctx, expiry)
+}
+
+// expired returns true if a resource was deleted before expiry
+func expired(deletedAt *common.DateTime, expiry time.Time) bool {
+	return deletedAt != nil && time.Time(*deletedAt).Before(expiry)
+}
+
+// reapApps purges the apps deleted before expiry, with their fns
+func (r *Reaper) reapApps(ctx context.Context, expiry time.Time) error {
+	filter := &models.AppFilter{Deleted: true, PerPage: r.pageSize}
+	for {
+		apps, err := r.ds.GetApps(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps.Items {
+			if !expired(app.DeletedAt, expiry) {
+				continue
+			}
+			err := r.ds.RemoveApp(ctx, app.ID)
+			if err != nil && err != models.ErrAppsNotFound {
+				common.Logger(ctx).WithError(err).WithField("app_id", app.ID).Error("error purging deleted app")
+			}
+		}
+		if apps.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
+
+// reapFns purges the fns of the live apps deleted before expiry, those of deleted apps are
+// purged with their app
+func (r *Reaper) reapFns(ctx context.Context, expiry time.Time) error {
+	filter := &models.AppFilter{PerPage: r.pageSize}
+	for {
+		apps, err := r.ds.GetApps(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps.Items {
+			if err := r.reapAppFns(ctx, app.ID, expiry); err != nil {
+				return err
+			}
+		}
+		if apps.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
+
+func (r *Reaper) reapAppFns(ctx context.Context, appID string, expiry time.Time) error {
+	filter := &models.FnFilter{AppID: appID, Deleted: true, PerPage: r.pageSize}
+	for {
+		fns, err := r.ds.GetFns(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns.Items {
+			if !expired(fn.DeletedAt, expiry) {
+				continue
+			}
+			err := r.ds.RemoveFn(ctx, fn.ID)
+			if err != nil && err != models.ErrFnsNotFound {
+				common.Logger(ctx).WithError(err).WithFields(logrus.Fields{
+					"app_id": appID, "fn_id": fn.ID,
+				}).Error("error purging deleted fn")
+			}
+		}
+		if fns.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = fns.NextCursor
+	}
+}
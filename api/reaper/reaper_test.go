@@ -0,0 +1,91 @@
+package reaper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func deletedAt(t time.Time) *common.DateTime {
+	d := common.DateTime(t)
+	return &d
+}
+
+func testResources(now time.Time) ([]*models.App, []*models.Fn) {
+	expired, recent := deletedAt(now.Add(-2*time.Hour)), deletedAt(now.Add(-time.Minute))
+	apps := []*models.App{
+		{ID: "live_app", Name: "live"},
+		{ID: "expired_app", Name: "expired", DeletedAt: expired},
+		{ID: "recent_app", Name: "recent", DeletedAt: recent},
+	}
+	fns := []*models.Fn{
+		{ID: "live_fn", AppID: "live_app", Name: "live"},
+		{ID: "expired_fn", AppID: "live_app", Name: "expired", DeletedAt: expired},
+		{ID: "recent_fn", AppID: "live_app", Name: "recent", DeletedAt: recent},
+		{ID: "expired_app_fn", AppID: "expired_app", Name: "fn", DeletedAt: expired},
+		{ID: "recent_app_fn", AppID: "recent_app", Name: "fn", DeletedAt: recent},
+	}
+	return apps, fns
+}
+
+func TestReaperPurgesExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	apps, fns := testResources(now)
+	ds := datastore.NewMockInit(apps, fns)
+	r := New(ds, time.Hour, WithPageSize(1))
+
+	if err := r.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := ds.GetApps(ctx, &models.AppFilter{Deleted: true, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted.Items) != 1 || deleted.Items[0].ID != "recent_app" {
+		t.Fatalf("expected only the recently deleted app to be kept, got %+v", deleted.Items)
+	}
+	if _, err := ds.RestoreApp(ctx, "recent_app"); err != nil {
+		t.Fatalf("expected the recently deleted app to be restorable, got %s", err)
+	}
+	if _, err := ds.GetFnByID(ctx, "recent_app_fn"); err != nil {
+		t.Fatalf("expected the fn of the recently deleted app to be kept, got %s", err)
+	}
+
+	if _, err := ds.GetDeletedFnByID(ctx, "expired_fn"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected the expired fn to be purged, got %v", err)
+	}
+	if _, err := ds.GetDeletedFnByID(ctx, "expired_app_fn"); err != models.ErrFnsNotFound {
+		t.Fatalf("expected the fn of the expired app to be purged, got %v", err)
+	}
+	if _, err := ds.GetDeletedFnByID(ctx, "recent_fn"); err != nil {
+		t.Fatalf("expected the recently deleted fn to be kept, got %s", err)
+	}
+	if _, err := ds.GetFnByID(ctx, "live_fn"); err != nil {
+		t.Fatalf("expected the live fn to be kept, got %s", err)
+	}
+}
+
+func TestReaperRequiresLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	apps, fns := testResources(now)
+	ds := datastore.NewMockInit(apps, fns)
+
+	if ok, err := ds.AcquireLease(ctx, LeaseName, "other", time.Minute); err != nil || !ok {
+		t.Fatalf("expected to acquire lease, got %v %v", ok, err)
+	}
+	if err := New(ds, time.Hour).Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetDeletedFnByID(ctx, "expired_fn"); err != nil {
+		t.Fatalf("expected reaper without the lease not to purge, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMemorySize is the default number of bytes of responses a MemoryStore keeps
+const DefaultMemorySize = 64 * 1024 * 1024
+
+// MemoryStore keeps responses in memory, up to a size after which the least recently used
+// responses are evicted.
+type MemoryStore struct {
+	maxSize int
+	now     func() time.Time
+
+	lock    sync.Mutex
+	size    int
+	lru     *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key       string
+	res       *Response
+	size      int
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore keeping up to maxSize bytes of responses
+func NewMemoryStore(maxSize int) *MemoryStore {
+	return &MemoryStore{
+		maxSize: maxSize,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Response, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	e := el.Value.(*entry)
+	if !s.now().Before(e.expiresAt) {
+		s.remove(el)
+		return nil, nil
+	}
+	s.lru.MoveToFront(el)
+	return e.res, nil
+}
+
+// Set implements Store
+func (s *MemoryStore) Set(ctx context.Context, key string, res *Response, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	s.set(key, res, ttl)
+	return nil
+}
+
+// set keeps a response, evicting the least recently used ones past the size. Responses larger
+// than the store are not kept.
+func (s *MemoryStore) set(key string, res *Response, ttl time.Duration) {
+	size := res.size()
+	if size > s.maxSize {
+		return
+	}
+	for s.size+size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+	s.entries[key] = s.lru.PushFront(&entry{key: key, res: res, size: size, expiresAt: s.now().Add(ttl)})
+	s.size += size
+}
+
+// Add implements Store
+func (s *MemoryStore) Add(ctx context.Context, key string, res *Response, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		if s.now().Before(el.Value.(*entry).expiresAt) {
+			return false, nil
+		}
+		s.remove(el)
+	}
+	s.set(key, res, ttl)
+	return true, nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*entry)
+	delete(s.entries, e.key)
+	s.size -= e.size
+}
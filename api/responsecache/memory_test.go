@@ -0,0 +1,102 @@
+package responsecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore(10)
+	s.now = func() time.Time { return now }
+
+	get := func(key string, body string) {
+		t.Helper()
+		res, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body == "" && res != nil {
+			t.Fatalf("expected no response for %s, got %q", key, res.Body)
+		}
+		if body != "" && (res == nil || string(res.Body) != body) {
+			t.Fatalf("expected response %q for %s, got %+v", body, key, res)
+		}
+	}
+	set := func(key string, body string, ttl time.Duration) {
+		t.Helper()
+		if err := s.Set(ctx, key, &Response{Status: 200, Body: []byte(body)}, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get("a", "")
+	set("a", "aaaa", time.Minute)
+	set("b", "bbbb", time.Second)
+	get("a", "aaaa")
+	get("b", "bbbb")
+
+	// responses expire after their ttl
+	now = now.Add(time.Second)
+	get("b", "")
+	if s.size != 4 {
+		t.Fatalf("expected expired responses to be removed, got size %d", s.size)
+	}
+
+	// the least recently used responses are evicted past the size
+	set("b", "bbbb", time.Minute)
+	get("a", "aaaa")
+	set("c", "cccc", time.Minute)
+	get("b", "")
+	get("a", "aaaa")
+	get("c", "cccc")
+
+	// responses larger than the store are not kept
+	set("d", "dddddddddddd", time.Minute)
+	get("d", "")
+	get("a", "aaaa")
+}
+
+func TestMemoryStoreAdd(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore(10)
+	s.now = func() time.Time { return now }
+
+	add := func(key string, expected bool) {
+		t.Helper()
+		added, err := s.Add(ctx, key, &Response{Body: []byte("x")}, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != expected {
+			t.Fatalf("expected added %v for %s, got %v", expected, key, added)
+		}
+	}
+
+	add("a", true)
+	add("a", false)
+
+	// expired responses are replaced
+	now = now.Add(time.Second)
+	add("a", true)
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if s.size != 0 {
+		t.Fatalf("expected deleted responses to be removed, got size %d", s.size)
+	}
+	add("a", true)
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(""); err != nil || s == nil {
+		t.Fatalf("expected a memory store, got %v %v", s, err)
+	}
+	if _, err := New("memcached://localhost"); err == nil {
+		t.Fatal("expected unsupported stores to be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package responsecache
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/fnproject/fn/api/common/redispool"
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisStore keeps responses in redis, shared by all the nodes using it. Responses expire
+// with their keys.
+type RedisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore returns the store of a redis://host:port/prefix URL
+func NewRedisStore(u *url.URL) (*RedisStore, error) {
+	pool, err := redispool.New(u)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{pool: pool, prefix: redispool.Prefix(u)}, nil
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) (*Response, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	b, err := redis.Bytes(conn.Do("GET", s.key(key)))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var res Response
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, res *Response, ttl time.Duration) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("SET", s.key(key), b, "PX", int64(ttl/time.Millisecond))
+	return err
+}
+
+// Add implements Store
+func (s *RedisStore) Add(ctx context.Context, key string, res *Response, ttl time.Duration) (bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return false, err
+	}
+	_, err = redis.String(conn.Do("SET", s.key(key), b, "PX", int64(ttl/time.Millisecond), "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", s.key(key))
+	return err
+}
+
+func (s *RedisStore) key(key string) string {
+	return s.prefix + "responsecache:" + key
+}
@@ -0,0 +1,60 @@
+// Package responsecache keeps the responses of functions, to answer the calls of idempotent
+// functions without running them, see models.FnResponseCacheAnnotation. Responses are kept
+// in the memory of a node or in a store shared by the nodes of a cluster.
+package responsecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Response is a cached response of a function
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// size is an estimate of the memory used by a response
+func (r *Response) size() int {
+	n := len(r.Body)
+	for k, vs := range r.Header {
+		n += len(k)
+		for _, v := range vs {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+// Store keeps responses
+type Store interface {
+	// Get returns the response of key, nil if there is none or it expired.
+	Get(ctx context.Context, key string) (*Response, error)
+	// Set keeps the response of key for ttl.
+	Set(ctx context.Context, key string, res *Response, ttl time.Duration) error
+	// Add keeps the response of key for ttl unless key has one, returning whether it was added.
+	Add(ctx context.Context, key string, res *Response, ttl time.Duration) (bool, error)
+	// Delete removes the response of key.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the store of a URL, the memory of the node if empty. redis://host:port/prefix
+// shares responses between nodes through redis, with keys starting with prefix.
+func New(storeURL string) (Store, error) {
+	if storeURL == "" {
+		return NewMemoryStore(DefaultMemorySize), nil
+	}
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedisStore(u)
+	}
+	return nil, fmt.Errorf("unsupported response cache store %q", u.Scheme)
+}
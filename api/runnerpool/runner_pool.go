@@ -24,6 +24,12 @@ type RunnerPool interface {
 	Shutdown(ctx context.Context) error
 }
 
+// RunnerLister is implemented by a RunnerPool which can list all of its runners
+// regardless of any call, eg. to aggregate their status.
+type RunnerLister interface {
+	ListRunners(ctx context.Context) ([]Runner, error)
+}
+
 // RunnerStatus is general information on Runner health as returned by Runner::Status() call
 type RunnerStatus struct {
 	ActiveRequestCount int32           // Number of active running requests on Runner
@@ -52,6 +58,20 @@ type Runner interface {
 	Address() string
 }
 
+// NodeStatusRunner is implemented by a Runner which can report the status of its node,
+// as served on the /status endpoint of the runner.
+type NodeStatusRunner interface {
+	NodeStatus(ctx context.Context) (*models.NodeStatus, error)
+}
+
+// LogTailRunner is implemented by a Runner which can stream the output of the containers of
+// a function on its node.
+type LogTailRunner interface {
+	// TailLogs sends the lines of the containers of fnID to lines until ctx is done or the
+	// stream fails, lines are dropped while lines is full.
+	TailLogs(ctx context.Context, fnID string, lines chan<- *models.LogLine) error
+}
+
 // RunnerCall provides access to the necessary details of request in order for it to be
 // processed by a RunnerPool
 type RunnerCall interface {
@@ -0,0 +1,191 @@
+// Package scheduler runs the cron schedules of functions by enqueueing async calls for them
+// when they are due.
+//
+// Any number of nodes may run a Scheduler against the same datastore, a datastore lease elects
+// the one that fires the due schedules. A run is only enqueued once its schedule was advanced
+// to its next time, so a run is never enqueued twice even if leadership changes hands while
+// it is being fired. Runs that were missed while no scheduler was running are fired once, the
+// schedule then continues from the current time.
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LeaseName is the name of the datastore lease held by the scheduler that fires schedules
+	LeaseName = "scheduler"
+
+	// ScheduleIDHeader is set on the requests of scheduled calls to the ID of their schedule
+	ScheduleIDHeader = "Fn-Schedule-Id"
+
+	// DefaultInterval is the default interval schedules are polled at
+	DefaultInterval = 10 * time.Second
+	// DefaultLeaseTTL is the default time a scheduler remains leader for after it last polled
+	DefaultLeaseTTL = 30 * time.Second
+	// DefaultBatchSize is the default maximum number of schedules fired per poll
+	DefaultBatchSize = 100
+)
+
+// Scheduler fires due schedules, see the package documentation
+type Scheduler struct {
+	ds        models.Datastore
+	mq        models.MessageQueue
+	holder    string
+	interval  time.Duration
+	leaseTTL  time.Duration
+	batchSize int
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// WithInterval sets the interval schedules are polled at
+func WithInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = d
+	}
+}
+
+// WithLeaseTTL sets the time a scheduler remains leader for after it last polled, it must
+// exceed the poll interval
+func WithLeaseTTL(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.leaseTTL = d
+	}
+}
+
+// WithBatchSize sets the maximum number of schedules fired per poll
+func WithBatchSize(n int) Option {
+	return func(s *Scheduler) {
+		s.batchSize = n
+	}
+}
+
+// New creates a scheduler enqueueing the calls of the schedules of ds on mq
+func New(ds models.Datastore, mq models.MessageQueue, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		ds:        ds,
+		mq:        mq,
+		holder:    id.New().String(),
+		interval:  DefaultInterval,
+		leaseTTL:  DefaultLeaseTTL,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run polls for due schedules until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(ctx, time.Now()); err != nil {
+			common.Logger(ctx).WithError(err).Error("error running schedules")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fires the schedules due at now if this scheduler holds the scheduler lease
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) error {
+	leader, err := s.ds.AcquireLease(ctx, LeaseName, s.holder, s.leaseTTL)
+	if err != nil || !leader {
+		return err
+	}
+
+	due, err := s.ds.GetDueSchedules(ctx, now, s.batchSize)
+	if err != nil {
+		return err
+	}
+	for _, sched := range due {
+		if err := s.fire(ctx, sched, now); err != nil {
+			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{
+				"schedule_id": sched.ID, "app_id": sched.AppID, "fn_id": sched.FnID,
+			}).Error("error firing schedule")
+		}
+	}
+	return nil
+}
+
+// fire claims the due run of sched by advancing it past now, then enqueues its call and
+// records the run
+func (s *Scheduler) fire(ctx context.Context, sched *models.Schedule, now time.Time) error {
+	next, err := sched.NextRun(now)
+	if err != nil {
+		return err
+	}
+
+	err = s.ds.AdvanceSchedule(ctx, sched.ID, time.Time(sched.NextRunAt), next)
+	if err == models.ErrScheduleNotDue {
+		return nil // fired elsewhere, or changed since it was read
+	}
+	if err != nil {
+		return err
+	}
+
+	run := &models.ScheduleRun{
+		ID:          id.New().String(),
+		ScheduleID:  sched.ID,
+		AppID:       sched.AppID,
+		FnID:        sched.FnID,
+		ScheduledAt: sched.NextRunAt,
+		CreatedAt:   common.DateTime(now),
+		Status:      models.ScheduleRunEnqueued,
+	}
+
+	callID, err := s.enqueue(ctx, sched)
+	if err != nil {
+		run.Status = models.ScheduleRunError
+		run.Error = err.Error()
+	}
+	run.CallID = callID
+
+	return s.ds.InsertScheduleRun(ctx, run)
+}
+
+// enqueue pushes an async call of the function of sched with its payload and returns its ID
+func (s *Scheduler) enqueue(ctx context.Context, sched *models.Schedule) (string, error) {
+	app, err := s.ds.GetAppByID(ctx, sched.AppID)
+	if err != nil {
+		return "", err
+	}
+	fn, err := s.ds.GetFnByID(ctx, sched.FnID)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/invoke/"+fn.ID, strings.NewReader(sched.Payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(ScheduleIDHeader, sched.ID)
+
+	call := agent.NewCallModel(app, fn, req)
+	call.Type = models.TypeAsync
+	call.Payload = sched.Payload
+	call.Status = "queued"
+
+	if _, err := s.mq.Push(ctx, call); err != nil {
+		return "", err
+	}
+	return call.ID, nil
+}
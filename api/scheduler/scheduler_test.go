@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+type recordingMQ struct {
+	mqs.Mock
+	pushed []*models.Call
+}
+
+func (mq *recordingMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	mq.pushed = append(mq.pushed, call)
+	return call, nil
+}
+
+func testSchedule(next time.Time) ([]*models.App, []*models.Fn, []*models.Schedule) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", AppID: app.ID, Name: "myfn", Image: "fnproject/fn-test-utils"}
+	sched := &models.Schedule{
+		ID:        "schedule_id",
+		Name:      "hourly",
+		AppID:     app.ID,
+		FnID:      fn.ID,
+		Cron:      "@hourly",
+		Payload:   `{"hello":"world"}`,
+		NextRunAt: common.DateTime(next),
+	}
+	return []*models.App{app}, []*models.Fn{fn}, []*models.Schedule{sched}
+}
+
+func TestSchedulerFiresDueSchedule(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	ds := datastore.NewMockInit(testSchedule(now.Add(-30 * time.Minute)))
+	mq := new(recordingMQ)
+	s := New(ds, mq)
+
+	if err := s.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mq.pushed) != 1 {
+		t.Fatalf("expected 1 call to be enqueued, got %d", len(mq.pushed))
+	}
+	call := mq.pushed[0]
+	if call.Type != models.TypeAsync || call.Payload != `{"hello":"world"}` || call.FnID != "fn_id" || call.Image != "fnproject/fn-test-utils" {
+		t.Fatalf("unexpected call enqueued %+v", call)
+	}
+	if call.Headers.Get(ScheduleIDHeader) != "schedule_id" {
+		t.Fatalf("expected schedule id header, got %v", call.Headers)
+	}
+
+	sched, err := ds.GetScheduleByID(ctx, "schedule_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := time.Time(sched.NextRunAt); !next.Equal(now.Add(30 * time.Minute)) {
+		t.Fatalf("expected schedule to run next at %v, got %v", now.Add(30*time.Minute), next)
+	}
+
+	runs, err := ds.GetScheduleRuns(ctx, &models.ScheduleRunFilter{ScheduleID: "schedule_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs.Items) != 1 || runs.Items[0].CallID != call.ID || runs.Items[0].Status != models.ScheduleRunEnqueued {
+		t.Fatalf("unexpected runs %+v", runs.Items)
+	}
+
+	// the schedule is not due again within the hour
+	if err := s.Tick(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(mq.pushed) != 1 {
+		t.Fatalf("expected schedule not to fire again, got %d calls", len(mq.pushed))
+	}
+}
+
+func TestSchedulerFiresMissedRunsOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	ds := datastore.NewMockInit(testSchedule(now.Add(-48 * time.Hour)))
+	mq := new(recordingMQ)
+	s := New(ds, mq)
+
+	if err := s.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Tick(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(mq.pushed) != 1 {
+		t.Fatalf("expected missed runs to fire once, got %d calls", len(mq.pushed))
+	}
+}
+
+func TestSchedulerRequiresLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	ds := datastore.NewMockInit(testSchedule(now.Add(-30 * time.Minute)))
+	leader, follower := new(recordingMQ), new(recordingMQ)
+
+	if ok, err := ds.AcquireLease(ctx, LeaseName, "other", time.Minute); err != nil || !ok {
+		t.Fatalf("expected to acquire lease, got %v %v", ok, err)
+	}
+	if err := New(ds, follower).Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(follower.pushed) != 0 {
+		t.Fatalf("expected scheduler without the lease not to fire, got %d calls", len(follower.pushed))
+	}
+
+	s := New(ds, leader)
+	s.holder = "other"
+	if err := s.Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(leader.pushed) != 1 {
+		t.Fatalf("expected lease holder to fire, got %d calls", len(leader.pushed))
+	}
+}
+
+// failingMQ is a queue failing to push calls
+type failingMQ struct {
+	mqs.Mock
+}
+
+func (mq *failingMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	return nil, errors.New("queue unavailable")
+}
+
+func TestSchedulerRecordsEnqueueErrors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	ds := datastore.NewMockInit(testSchedule(now.Add(-30 * time.Minute)))
+	if err := New(ds, new(failingMQ)).Tick(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := ds.GetScheduleRuns(ctx, &models.ScheduleRunFilter{ScheduleID: "schedule_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs.Items) != 1 || runs.Items[0].Status != models.ScheduleRunError || runs.Items[0].Error == "" {
+		t.Fatalf("expected an errored run, got %+v", runs.Items)
+	}
+}
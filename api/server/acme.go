@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// WithACME maps EnvACMEDomains, EnvACMECustomDomains, EnvACMEEmail, EnvACMECacheDir,
+// EnvACMEDirectoryURL and EnvACMEHTTPPort. The web server terminates TLS with certificates
+// obtained and renewed from the ACME directory, for the domains and the hostnames of the custom
+// domains of apps customDomains allows. Challenges are answered on the HTTP port, which
+// redirects other requests to HTTPS.
+func WithACME(domains, customDomains []string, email, cacheDir, directoryURL string, httpPort int) Option {
+	return func(ctx context.Context, s *Server) error {
+		if cacheDir == "" {
+			return errors.New("ACME requires a directory to keep certificates in")
+		}
+
+		hosts := acmeHosts(domains)
+		s.addHostnames(domains...)
+
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cacheDir),
+			HostPolicy: s.acmeHostPolicy(hosts, acmeHosts(customDomains)),
+			Email:      email,
+		}
+		if directoryURL != "" {
+			m.Client = &acme.Client{DirectoryURL: directoryURL}
+		}
+
+		s.acme = m
+		s.acmeHTTPAddr = fmt.Sprintf(":%d", httpPort)
+		s.svcConfigs[WebServer].TLSConfig = m.TLSConfig()
+		logrus.WithFields(logrus.Fields{"domains": domains, "custom_domains": customDomains, "cache_dir": cacheDir}).Info("TLS certificates managed with ACME")
+		return nil
+	}
+}
+
+// acmeHosts returns the set of the lowercase hostnames, or *.suffix patterns, of a list
+func acmeHosts(hostnames []string) map[string]bool {
+	hosts := make(map[string]bool, len(hostnames))
+	for _, h := range hostnames {
+		if h = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), "."); h != "" {
+			hosts[h] = true
+		}
+	}
+	return hosts
+}
+
+// acmeCustomDomainAllowed returns true if host is one of customDomains, or a subdomain of one
+// of their *.suffix patterns
+func acmeCustomDomainAllowed(customDomains map[string]bool, host string) bool {
+	if customDomains[host] {
+		return true
+	}
+	for i := strings.Index(host, "."); i > 0; i = strings.Index(host, ".") {
+		host = host[i+1:]
+		if customDomains["*."+host] {
+			return true
+		}
+	}
+	return false
+}
+
+// acmeHostPolicy allows certificates for hosts, and for the hostnames of custom domains
+// customDomains allows if the server routes them, so that certificates are only requested for
+// hostnames the server serves and its operators expect to.
+func (s *Server) acmeHostPolicy(hosts, customDomains map[string]bool) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		host = strings.ToLower(host)
+		if hosts[host] {
+			return nil
+		}
+		if net.ParseIP(host) != nil || !s.routesDomains() || s.reservedHostname(host) || !acmeCustomDomainAllowed(customDomains, host) {
+			return fmt.Errorf("acme: host %q not configured", host)
+		}
+
+		domains, err := s.lbReadAccess.GetDomainsByHostname(ctx, host)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithField("hostname", host).Error("failed to get domains of certificate host")
+			return err
+		}
+		if len(domains) == 0 {
+			return fmt.Errorf("acme: host %q has no domain", host)
+		}
+		return nil
+	}
+}
+
+// startACMEChallenges serves the HTTP challenges of the ACME directory, until the returned
+// func is called
+func (s *Server) startACMEChallenges(cancel context.CancelFunc) func() {
+	server := &http.Server{Addr: s.acmeHTTPAddr, Handler: s.acme.HTTPHandler(nil)}
+	logrus.WithField("addr", s.acmeHTTPAddr).Info("Fn serving ACME challenges")
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.WithError(err).Error("ACME challenge server error")
+			cancel()
+		}
+	}()
+
+	return func() {
+		if err := server.Shutdown(context.Background()); err != nil {
+			logrus.WithError(err).Error("ACME challenge server shutdown error")
+		}
+	}
+}
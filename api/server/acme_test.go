@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestACMEHostPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	for _, hostname := range []string{"app.example.com", "api.apps.example.com", "unlisted.example.org"} {
+		if _, err := ds.InsertDomain(context.Background(), &models.Domain{Hostname: hostname, AppID: "app_id"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{
+		nodeType:     ServerTypeFull,
+		lbReadAccess: agent.NewCachedDataAccess(ds),
+		svcConfigs:   map[string]*http.Server{WebServer: {}},
+	}
+	if err := WithACME([]string{" FN.example.com ", ""}, []string{"app.example.com", "*.apps.example.com", "fn.example.com"}, "ops@example.com", dir, "", 8080)(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.svcConfigs[WebServer].TLSConfig == nil || s.svcConfigs[WebServer].TLSConfig.GetCertificate == nil {
+		t.Fatal("expected the web server to get its certificates from ACME")
+	}
+	if s.acmeHTTPAddr != ":8080" {
+		t.Fatalf("expected the challenges served on :8080, got %s", s.acmeHTTPAddr)
+	}
+
+	for host, allowed := range map[string]bool{
+		"fn.example.com":    true,
+		"app.example.com":   true,
+		"other.example.com": false,
+		"10.0.0.1":          false,
+		// custom domains must be allowed too
+		"api.apps.example.com":   true,
+		"other.apps.example.com": false,
+		"unlisted.example.org":   false,
+	} {
+		if err := s.acme.HostPolicy(context.Background(), host); (err == nil) != allowed {
+			t.Errorf("expected %s allowed %v, got %v", host, allowed, err)
+		}
+	}
+
+	// custom domains only get certificates from nodes that route them
+	s.noHTTTPTriggerEndpoint = true
+	if err := s.acme.HostPolicy(context.Background(), "app.example.com"); err == nil {
+		t.Fatal("expected the custom domain not allowed without HTTP triggers")
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleAPIKeyCreate creates an API key, its secret is only ever returned in the response.
+func (s *Server) handleAPIKeyCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := &models.APIKey{}
+
+	err := c.BindJSON(key)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	// a key of no app is one of all apps
+	check := s.checkRequestApp(c, key.AppID, "")
+	if key.AppID == "" {
+		check = s.checkRequestAllApps(c)
+	}
+	if check != nil {
+		handleErrorResponse(c, check)
+		return
+	}
+
+	secret, err := models.NewAPIKeySecret()
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	key.Secret = ""
+	key.Hash = models.HashAPIKeySecret(secret)
+
+	keyCreated, err := s.datastore.InsertAPIKey(ctx, key)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	keyCreated.Secret = secret
+	c.JSON(http.StatusOK, keyCreated)
+}
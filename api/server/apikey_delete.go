@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleAPIKeyDelete revokes an API key, requests with its secret are rejected right away.
+func (s *Server) handleAPIKeyDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.datastore.RemoveAPIKey(ctx, c.Param(api.APIKeyID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAPIKeyList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.APIKeyFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.AppID = c.Query("app_id")
+
+	keys, err := s.datastore.GetAPIKeys(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	invoke "github.com/fnproject/fn/api/server/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testAdminAPIKey = "fnk_admin"
+
+func TestAPIKeys(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}, {ID: "other_id", Name: "other"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAPIKeys(testAdminAPIKey))
+
+	request := func(method, path, secret, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	request("GET", "/v2/apps", "", "", http.StatusUnauthorized, nil)
+	request("GET", "/v2/apps", "fnk_nope", "", http.StatusUnauthorized, nil)
+	request("GET", "/v2/apps", testAdminAPIKey, "", http.StatusOK, nil)
+
+	var appKey, invokeKey models.APIKey
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "deployer", "app_id": "app_id", "scope": "manage"}`, http.StatusOK, &appKey)
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "caller", "scope": "invoke"}`, http.StatusOK, &invokeKey)
+	if appKey.ID == "" || len(appKey.Secret) < 40 || appKey.Secret[:4] != models.APIKeyPrefix {
+		t.Fatalf("expected a key with its secret, got %+v", appKey)
+	}
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "bad", "scope": "admin"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "bad", "app_id": "nope", "scope": "invoke"}`, http.StatusNotFound, nil)
+
+	var list models.APIKeyList
+	request("GET", "/v2/apikeys", testAdminAPIKey, "", http.StatusOK, &list)
+	if len(list.Items) != 2 || list.Items[0].Secret != "" {
+		t.Fatalf("expected 2 keys without secret, got %+v", list.Items)
+	}
+
+	// keys restricted to an app only reach its resources
+	request("GET", "/v2/apps/app_id", appKey.Secret, "", http.StatusOK, nil)
+	request("GET", "/v2/apps/other_id", appKey.Secret, "", http.StatusForbidden, nil)
+	request("GET", "/v2/apps", appKey.Secret, "", http.StatusForbidden, nil)
+	var fn models.Fn
+	request("POST", "/v2/fns", appKey.Secret, `{"name": "myfn", "app_id": "app_id", "image": "fnproject/hello"}`, http.StatusOK, &fn)
+	request("GET", "/v2/fns/"+fn.ID, appKey.Secret, "", http.StatusOK, nil)
+	request("GET", "/v2/fns?app_id=app_id", appKey.Secret, "", http.StatusOK, nil)
+	request("POST", "/v2/fns", appKey.Secret, `{"name": "myfn", "app_id": "other_id", "image": "fnproject/hello"}`, http.StatusForbidden, nil)
+	request("GET", "/v2/apikeys?app_id=app_id", appKey.Secret, "", http.StatusForbidden, nil)
+	request("POST", "/v2/fns?app_id=app_id", appKey.Secret, `{"name": "otherfn", "app_id": "other_id", "image": "fnproject/hello"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/domains?app_id=app_id", appKey.Secret, `{"hostname": "other.example.com"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/domains?app_id=app_id", appKey.Secret, `{"hostname": "other.example.com", "app_id": "app_id"}`, http.StatusForbidden, nil)
+	request("POST", "/v2/webhooks?app_id=app_id", appKey.Secret, `{"url": "https://example.com/hook", "app_id": "other_id"}`, http.StatusBadRequest, nil)
+
+	// invoke keys can't manage
+	request("GET", "/v2/apps/app_id", invokeKey.Secret, "", http.StatusForbidden, nil)
+
+	// revoked keys are rejected right away
+	request("DELETE", "/v2/apikeys/"+appKey.ID, testAdminAPIKey, "", http.StatusNoContent, nil)
+	request("GET", "/v2/apps/app_id", appKey.Secret, "", http.StatusUnauthorized, nil)
+	request("DELETE", "/v2/apikeys/"+appKey.ID, testAdminAPIKey, "", http.StatusNotFound, nil)
+
+	t.Run("grpc invoke", func(t *testing.T) {
+		ctx := context.Background()
+		ds := datastore.NewMockInit(
+			[]*models.App{{ID: "app_id", Name: "myapp"}},
+			[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn", Image: "fnproject/hello"}},
+		)
+		s := &Server{lbReadAccess: agent.NewCachedDataAccess(ds), datastore: ds, apiKeys: true}
+
+		secret, _ := models.NewAPIKeySecret()
+		if _, err := ds.InsertAPIKey(ctx, &models.APIKey{Name: "other", AppID: "other_id", Scope: models.APIKeyScopeInvoke, Hash: models.HashAPIKeySecret(secret)}); err != models.ErrAppsNotFound {
+			t.Fatalf("expected keys of missing apps to be rejected, got %v", err)
+		}
+		if _, err := ds.InsertAPIKey(ctx, &models.APIKey{Name: "manager", Scope: models.APIKeyScopeManage, Hash: models.HashAPIKeySecret(secret)}); err != nil {
+			t.Fatal(err)
+		}
+
+		lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		invoke.RegisterInvokerServer(gs, &grpcInvoker{s: s})
+		go gs.Serve(lsnr)
+		defer gs.Stop()
+
+		conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		client := invoke.NewInvokerClient(conn)
+
+		_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "fn_id"})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("invocation without a key should be unauthenticated, got: %v", err)
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+		_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "nope"})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("authenticated invocation of a missing fn should not be found, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,459 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// applyPageSize is the page size used to list the fns and triggers of an applied app
+const applyPageSize = 100
+
+// pendingID stands in for the ids of objects yet to be created when validating a bundle
+const pendingID = "pending"
+
+// applyStep is a change to the datastore made to apply a bundle. Steps run in a transaction,
+// and may run again should it conflict with another.
+type applyStep func(ctx context.Context) error
+
+// applyPlan holds the changes turning the datastore into the state declared by a bundle.
+type applyPlan struct {
+	app       *models.App
+	changes   []*models.ApplyChange
+	steps     []applyStep
+	fnIDs     map[string]*string // fn ids by fn name, set as fns are created
+	fnCreates []applyStep
+	fnDeletes []applyStep
+	trDeletes []applyStep
+	trChanges []applyStep
+}
+
+// handleApply diffs a bundle declaring an app with its fns and their triggers, in JSON or
+// YAML, against the datastore and applies the changes, returning a report of them. The
+// changes are made in a transaction, so should one fail none is. With dry_run=true only the
+// report of the changes to make is returned.
+func (s *Server) handleApply(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	bundle, err := bindBundle(c.Request)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := bundle.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	plan, err := s.planApply(ctx, bundle)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	if !dryRun {
+		if err := plan.apply(ctx, s.datastore); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	report := &models.ApplyReport{App: plan.app, DryRun: dryRun}
+	for _, change := range plan.changes {
+		report.Changes = append(report.Changes, *change)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// bindBundle reads a bundle from a request body, in YAML if the request says so and in JSON
+// otherwise.
+func bindBundle(req *http.Request) (*models.ApplyBundle, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, models.ErrInvalidJSON
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		body, err = yamlToJSON(body)
+		if err != nil {
+			return nil, models.ErrInvalidJSON
+		}
+	}
+
+	var bundle models.ApplyBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		if models.IsAPIError(err) {
+			return nil, err
+		}
+		return nil, models.ErrInvalidJSON
+	}
+	return &bundle, nil
+}
+
+// yamlToJSON converts a YAML document to JSON, so that it decodes with the json tags of models
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// jsonValue turns the maps decoded from YAML, which may have keys of any type, into maps
+// which encode to JSON.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			val, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range v {
+			val, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = val
+		}
+	}
+	return v, nil
+}
+
+// planApply diffs a bundle against the datastore. Steps run in the order: app, fn creates
+// and updates, trigger deletes, trigger creates and updates, and finally fn deletes, so that
+// triggers may move between fns and reuse the sources of deleted triggers.
+func (s *Server) planApply(ctx context.Context, bundle *models.ApplyBundle) (*applyPlan, error) {
+	plan := &applyPlan{fnIDs: make(map[string]*string)}
+
+	desired := bundle.App.Clone()
+	existing, err := s.applyApp(ctx, desired.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.planApp(s.datastore, existing, desired); err != nil {
+		return nil, err
+	}
+
+	existingFns := make(map[string]*models.Fn)
+	existingTriggers := make(map[string]*models.Trigger)
+	if existing != nil {
+		fns, err := s.applyFns(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, fn := range fns {
+			existingFns[fn.Name] = fn
+		}
+		triggers, err := s.applyTriggers(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range triggers {
+			existingTriggers[t.Name] = t
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, fn := range bundle.Fns {
+		if err := plan.planFn(s.datastore, existingFns[fn.Name], fn.Fn.Clone()); err != nil {
+			return nil, err
+		}
+		for _, t := range fn.Triggers {
+			declared[t.Name] = true
+			if err := plan.planTrigger(s.datastore, existingTriggers[t.Name], t.Clone(), fn.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(existingTriggers) {
+		if !declared[name] {
+			plan.deleteTrigger(s.datastore, existingTriggers[name])
+		}
+	}
+	for _, name := range sortedKeys(existingFns) {
+		if _, ok := plan.fnIDs[name]; !ok {
+			plan.deleteFn(s.datastore, existingFns[name])
+		}
+	}
+
+	plan.steps = append(plan.steps, plan.fnCreates...)
+	plan.steps = append(plan.steps, plan.trDeletes...)
+	plan.steps = append(plan.steps, plan.trChanges...)
+	plan.steps = append(plan.steps, plan.fnDeletes...)
+	return plan, nil
+}
+
+// sortedKeys returns the keys of a map of fns or triggers by name, in order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*models.Fn:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*models.Trigger:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applyApp returns the app named name, nil if there is none.
+func (s *Server) applyApp(ctx context.Context, name string) (*models.App, error) {
+	appID, err := s.datastore.GetAppID(ctx, name)
+	if err == models.ErrAppsNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s.datastore.GetAppByID(ctx, appID)
+}
+
+// applyFns returns all fns of an app
+func (s *Server) applyFns(ctx context.Context, appID string) ([]*models.Fn, error) {
+	var fns []*models.Fn
+	filter := &models.FnFilter{AppID: appID, PerPage: applyPageSize}
+	for {
+		page, err := s.datastore.GetFns(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, page.Items...)
+		if page.NextCursor == "" {
+			return fns, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// applyTriggers returns all triggers of an app
+func (s *Server) applyTriggers(ctx context.Context, appID string) ([]*models.Trigger, error) {
+	var triggers []*models.Trigger
+	filter := &models.TriggerFilter{AppID: appID, PerPage: applyPageSize}
+	for {
+		page, err := s.datastore.GetTriggers(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, page.Items...)
+		if page.NextCursor == "" {
+			return triggers, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// addChange records a change of the report, steps update it once they ran
+func (p *applyPlan) addChange(kind, name, id, action, fn string) *models.ApplyChange {
+	change := &models.ApplyChange{Kind: kind, Name: name, ID: id, Action: action, Fn: fn}
+	p.changes = append(p.changes, change)
+	return change
+}
+
+func (p *applyPlan) planApp(ds models.Datastore, existing, desired *models.App) error {
+	if existing == nil {
+		desired.ID = ""
+		desired.CreatedAt, desired.UpdatedAt = common.DateTime{}, common.DateTime{}
+		if err := desired.Validate(); err != nil {
+			return err
+		}
+		p.app = desired
+		change := p.addChange(models.ApplyKindApp, desired.Name, "", models.ApplyCreate, "")
+		p.steps = append(p.steps, func(ctx context.Context) error {
+			app, err := ds.InsertApp(ctx, desired)
+			if err != nil {
+				return err
+			}
+			p.app, change.ID = app, app.ID
+			return nil
+		})
+		return nil
+	}
+
+	patch := existing.ReplacePatch(desired)
+	updated := existing.Clone()
+	updated.Update(patch)
+	p.app = updated
+	if updated.Equals(existing) {
+		p.addChange(models.ApplyKindApp, existing.Name, existing.ID, models.ApplyUnchanged, "")
+		return nil
+	}
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	p.addChange(models.ApplyKindApp, existing.Name, existing.ID, models.ApplyUpdate, "")
+	p.steps = append(p.steps, func(ctx context.Context) error {
+		app, err := ds.UpdateApp(ctx, patch)
+		if err != nil {
+			return err
+		}
+		p.app = app
+		return nil
+	})
+	return nil
+}
+
+// appID returns the id of the applied app, pendingID until it is created
+func (p *applyPlan) appID() string {
+	if p.app.ID == "" {
+		return pendingID
+	}
+	return p.app.ID
+}
+
+func (p *applyPlan) planFn(ds models.Datastore, existing, desired *models.Fn) error {
+	desired.ID = ""
+	desired.CreatedAt, desired.UpdatedAt = common.DateTime{}, common.DateTime{}
+	desired.AppID = p.appID()
+	desired.Canary = nil // canaries in progress are left alone
+	desired.SetDefaults()
+
+	if existing == nil {
+		if err := desired.Validate(); err != nil {
+			return err
+		}
+		fnID := new(string)
+		p.fnIDs[desired.Name] = fnID
+		change := p.addChange(models.ApplyKindFn, desired.Name, "", models.ApplyCreate, "")
+		p.fnCreates = append(p.fnCreates, func(ctx context.Context) error {
+			fn := desired.Clone()
+			fn.AppID = p.app.ID
+			fn, err := ds.InsertFn(ctx, fn)
+			if err != nil {
+				return err
+			}
+			*fnID, change.ID = fn.ID, fn.ID
+			return nil
+		})
+		return nil
+	}
+
+	fnID := existing.ID
+	p.fnIDs[desired.Name] = &fnID
+
+	patch := existing.ReplacePatch(desired)
+	updated := existing.Clone()
+	updated.Update(patch)
+	if updated.Equals(existing) {
+		p.addChange(models.ApplyKindFn, existing.Name, existing.ID, models.ApplyUnchanged, "")
+		return nil
+	}
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	p.addChange(models.ApplyKindFn, existing.Name, existing.ID, models.ApplyUpdate, "")
+	p.fnCreates = append(p.fnCreates, func(ctx context.Context) error {
+		_, err := ds.UpdateFn(ctx, patch)
+		return err
+	})
+	return nil
+}
+
+func (p *applyPlan) deleteFn(ds models.Datastore, existing *models.Fn) {
+	p.addChange(models.ApplyKindFn, existing.Name, existing.ID, models.ApplyDelete, "")
+	p.fnDeletes = append(p.fnDeletes, func(ctx context.Context) error {
+		return ds.RemoveFn(ctx, existing.ID)
+	})
+}
+
+func (p *applyPlan) planTrigger(ds models.Datastore, existing, desired *models.Trigger, fnName string) error {
+	fnID := p.fnIDs[fnName]
+	desired.ID = ""
+	desired.CreatedAt, desired.UpdatedAt = common.DateTime{}, common.DateTime{}
+	desired.AppID = p.appID()
+	desired.FnID = *fnID
+	if desired.FnID == "" {
+		desired.FnID = pendingID
+	}
+
+	if existing != nil && existing.Type != desired.Type {
+		// the type of a trigger cannot be updated, replace it
+		p.deleteTrigger(ds, existing)
+		existing = nil
+	}
+
+	if existing == nil {
+		if err := desired.Validate(); err != nil {
+			return err
+		}
+		change := p.addChange(models.ApplyKindTrigger, desired.Name, "", models.ApplyCreate, fnName)
+		p.trChanges = append(p.trChanges, func(ctx context.Context) error {
+			t := desired.Clone()
+			t.AppID, t.FnID = p.app.ID, *fnID
+			t, err := ds.InsertTrigger(ctx, t)
+			if err != nil {
+				return err
+			}
+			change.ID = t.ID
+			return nil
+		})
+		return nil
+	}
+
+	patch := existing.ReplacePatch(desired)
+	updated := existing.Clone()
+	updated.Update(patch)
+	if updated.Equals(existing) {
+		p.addChange(models.ApplyKindTrigger, existing.Name, existing.ID, models.ApplyUnchanged, fnName)
+		return nil
+	}
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	p.addChange(models.ApplyKindTrigger, existing.Name, existing.ID, models.ApplyUpdate, fnName)
+	p.trChanges = append(p.trChanges, func(ctx context.Context) error {
+		patch.FnID = *fnID
+		_, err := ds.UpdateTrigger(ctx, patch)
+		return err
+	})
+	return nil
+}
+
+func (p *applyPlan) deleteTrigger(ds models.Datastore, existing *models.Trigger) {
+	p.addChange(models.ApplyKindTrigger, existing.Name, existing.ID, models.ApplyDelete, "")
+	p.trDeletes = append(p.trDeletes, func(ctx context.Context) error {
+		return ds.RemoveTrigger(ctx, existing.ID)
+	})
+}
+
+// apply runs the steps of the plan in order, in a transaction of ds
+func (p *applyPlan) apply(ctx context.Context, ds models.Datastore) error {
+	return ds.InTx(ctx, func(ctx context.Context) error {
+		for _, step := range p.steps {
+			if err := step(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
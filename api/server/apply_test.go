@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+const applyRoute = "/v2/apply"
+
+const applyBundleYAML = `
+app:
+  name: myapp
+  config:
+    LEVEL: debug
+fns:
+  - name: hello
+    image: fnproject/hello
+    memory: 256
+    config:
+      GREETING: hi
+    triggers:
+      - name: hello
+        type: http
+        source: /hello
+  - name: bye
+    image: fnproject/bye
+    triggers:
+      - name: bye
+        type: http
+        source: /bye
+`
+
+func TestApply(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMock()
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	apply := func(contentType, query, body string, expectedCode int) *models.ApplyReport {
+		req := createRequest(t, "POST", applyRoute+query, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("expected status code %d, got %d: %s", expectedCode, rec.Code, rec.Body.String())
+		}
+		if expectedCode != http.StatusOK {
+			return nil
+		}
+		var report models.ApplyReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return &report
+	}
+	expectChanges := func(report *models.ApplyReport, expected ...string) {
+		var changes []string
+		for _, c := range report.Changes {
+			changes = append(changes, c.Kind+" "+c.Name+" "+c.Action)
+		}
+		if strings.Join(changes, ", ") != strings.Join(expected, ", ") {
+			t.Fatalf("expected changes %v, got %v", expected, changes)
+		}
+	}
+
+	report := apply("application/yaml", "", applyBundleYAML, http.StatusOK)
+	expectChanges(report,
+		"app myapp create",
+		"fn hello create", "trigger hello create",
+		"fn bye create", "trigger bye create")
+	if report.App.ID == "" || report.App.Config["LEVEL"] != "debug" {
+		t.Fatalf("unexpected app %+v", report.App)
+	}
+	appID := report.App.ID
+
+	ctx := context.Background()
+	fns, err := ds.GetFns(ctx, &models.FnFilter{AppID: appID})
+	if err != nil || len(fns.Items) != 2 {
+		t.Fatalf("expected 2 fns, got %v %v", fns, err)
+	}
+	triggers, err := ds.GetTriggers(ctx, &models.TriggerFilter{AppID: appID, Name: "hello"})
+	if err != nil || len(triggers.Items) != 1 || triggers.Items[0].FnID != report.Changes[1].ID {
+		t.Fatalf("expected trigger of fn hello, got %v %v", triggers, err)
+	}
+
+	// applying the same bundle changes nothing
+	report = apply("application/x-yaml", "", applyBundleYAML, http.StatusOK)
+	expectChanges(report,
+		"app myapp unchanged",
+		"fn hello unchanged", "trigger hello unchanged",
+		"fn bye unchanged", "trigger bye unchanged")
+
+	// removed fns and config keys are deleted, changed ones updated
+	report = apply("application/json", "", `{
+		"app": {"name": "myapp"},
+		"fns": [{
+			"name": "hello", "image": "fnproject/hello", "memory": 512,
+			"triggers": [{"name": "hello", "type": "http", "source": "/hi"}]
+		}]
+	}`, http.StatusOK)
+	expectChanges(report,
+		"app myapp update",
+		"fn hello update", "trigger hello update",
+		"trigger bye delete", "fn bye delete")
+	if len(report.App.Config) != 0 {
+		t.Fatalf("expected app config to be removed, got %v", report.App.Config)
+	}
+
+	hello, err := ds.GetFnByID(ctx, report.Changes[1].ID)
+	if err != nil || hello.Memory != 512 || len(hello.Config) != 0 {
+		t.Fatalf("unexpected fn %+v %v", hello, err)
+	}
+	helloTrigger := report.Changes[2].ID
+	if _, err := ds.GetTriggerByID(ctx, helloTrigger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetFnByID(ctx, report.Changes[4].ID); err != models.ErrFnsNotFound {
+		t.Fatalf("expected fn bye to be deleted, got %v", err)
+	}
+
+	// a dry run only reports the changes
+	report = apply("application/yaml", "?dry_run=true", applyBundleYAML, http.StatusOK)
+	if !report.DryRun {
+		t.Fatal("expected a dry run report")
+	}
+	expectChanges(report,
+		"app myapp update",
+		"fn hello update", "trigger hello update",
+		"fn bye create", "trigger bye create")
+	if fns, _ := ds.GetFns(ctx, &models.FnFilter{AppID: appID}); len(fns.Items) != 1 {
+		t.Fatalf("expected a dry run not to create fns, got %d fns", len(fns.Items))
+	}
+
+	// none of the changes are made if one fails
+	apply("application/yaml", "", `
+app:
+  name: myapp
+fns:
+  - name: hello
+    image: fnproject/hello
+    memory: 512
+    triggers:
+      - name: hello
+        type: http
+        source: /hi
+  - name: other
+    image: fnproject/other
+    triggers:
+      - name: other
+        type: http
+        source: /hi
+`, http.StatusConflict)
+	if fns, _ := ds.GetFns(ctx, &models.FnFilter{AppID: appID}); len(fns.Items) != 1 {
+		t.Fatalf("expected fn other to be removed on failure, got %d fns", len(fns.Items))
+	}
+
+	// objects deleted before a failure are kept, with their ids
+	apply("application/yaml", "", `
+app:
+  name: myapp
+fns:
+  - name: other
+    image: fnproject/other
+    triggers:
+      - name: other
+        type: http
+        source: /other
+      - name: another
+        type: http
+        source: /other
+`, http.StatusConflict)
+	if fn, err := ds.GetFnByID(ctx, hello.ID); err != nil || fn.Name != "hello" {
+		t.Fatalf("expected fn hello to be kept on failure, got %+v %v", fn, err)
+	}
+	if tr, err := ds.GetTriggerByID(ctx, helloTrigger); err != nil || tr.FnID != hello.ID {
+		t.Fatalf("expected trigger hello to be kept on failure, got %+v %v", tr, err)
+	}
+	if fns, _ := ds.GetFns(ctx, &models.FnFilter{AppID: appID}); len(fns.Items) != 1 {
+		t.Fatalf("expected fn other to be removed on failure, got %d fns", len(fns.Items))
+	}
+
+	// invalid bundles
+	apply("application/json", "", `{"fns": []}`, http.StatusBadRequest)
+	apply("application/json", "", `{"app": {"name": "myapp"}, "fns": [{"name": "a", "image": "a"}, {"name": "a", "image": "b"}]}`, http.StatusBadRequest)
+	apply("application/json", "", `{"app": {"name": "myapp"}, "fns": [{"name": "a"}]}`, http.StatusBadRequest)
+	apply("application/yaml", "", "app: [", http.StatusBadRequest)
+	if fns, _ := ds.GetFns(ctx, &models.FnFilter{AppID: appID}); len(fns.Items) != 1 {
+		t.Fatalf("expected invalid bundles to change nothing, got %d fns", len(fns.Items))
+	}
+}
@@ -7,10 +7,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// handleAppDelete deletes an app with its fns, they are only marked deleted until the
+// retention of deleted resources expires if one is set, see EnvDeleteRetention.
 func (s *Server) handleAppDelete(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	err := s.datastore.RemoveApp(ctx, c.Param(api.AppID))
+	var err error
+	if s.deleteRetention > 0 {
+		err = s.datastore.SoftDeleteApp(ctx, c.Param(api.AppID))
+	} else {
+		err = s.datastore.RemoveApp(ctx, c.Param(api.AppID))
+	}
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -18,3 +25,16 @@ func (s *Server) handleAppDelete(c *gin.Context) {
 
 	c.String(http.StatusNoContent, "")
 }
+
+// handleAppRestore restores an app marked deleted, along with the fns deleted with it.
+func (s *Server) handleAppRestore(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	app, err := s.datastore.RestoreApp(ctx, c.Param(api.AppID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
@@ -17,5 +17,6 @@ func (s *Server) handleAppGet(c *gin.Context) {
 		return
 	}
 
+	setETag(c, app)
 	c.JSON(http.StatusOK, app)
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
@@ -15,12 +16,32 @@ func (s *Server) handleAppList(c *gin.Context) {
 	filter.Cursor, filter.PerPage = pageParams(c)
 
 	filter.Name = c.Query("name")
+	filter.NamePrefix = c.Query("name_prefix")
+	filter.Deleted, _ = strconv.ParseBool(c.Query("deleted"))
+
+	var err error
+	filter.Annotations, err = models.ParseKeyFilters(c.QueryArray("annotation"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	filter.Config, err = models.ParseKeyFilters(c.QueryArray("config"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	filter.Order, err = models.ParseListOrder(c.Query("order_by"), c.Query("order"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 
 	apps, err := s.datastore.GetApps(ctx, filter)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
+	apps.Items = s.readableApps(c, apps.Items)
 
 	c.JSON(http.StatusOK, apps)
 }
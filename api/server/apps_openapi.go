@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleAppOpenAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	app, err := s.datastore.GetAppByID(ctx, c.Param(api.AppID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fns, err := s.applyFns(ctx, app.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	triggers, err := s.applyTriggers(ctx, app.ID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	servers, err := s.openAPIServers(c, app)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	doc, err := openapi.Build(app, servers, triggers, fns)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// openAPIServers returns the base URLs of the HTTP triggers of app, that of the trigger
+// annotator followed by those of the custom domains of the app.
+func (s *Server) openAPIServers(c *gin.Context, app *models.App) ([]string, error) {
+	var servers []string
+
+	// the annotator knows the base URL of triggers, e.g. behind a load balancer
+	t, err := s.triggerAnnotator.AnnotateTrigger(c, app, &models.Trigger{Type: models.TriggerTypeHTTP, Source: "/"})
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := t.Annotations.Get(models.TriggerHTTPEndpointAnnotation); ok {
+		var endpoint string
+		if err := json.Unmarshal(raw, &endpoint); err == nil {
+			servers = append(servers, strings.TrimSuffix(endpoint, "/"))
+		}
+	}
+
+	domains, err := s.appDomains(c.Request.Context(), app.ID)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	for _, d := range domains {
+		servers = append(servers, scheme+"://"+d.Hostname+d.PathPrefix)
+	}
+	return servers, nil
+}
+
+// appDomains returns all custom domains of an app
+func (s *Server) appDomains(ctx context.Context, appID string) ([]*models.Domain, error) {
+	var domains []*models.Domain
+	filter := &models.DomainFilter{AppID: appID, PerPage: applyPageSize}
+	for {
+		page, err := s.datastore.GetDomains(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, page.Items...)
+		if page.NextCursor == "" {
+			return domains, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
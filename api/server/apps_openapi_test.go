@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/openapi"
+)
+
+func TestAppOpenAPI(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit(
+		[]*models.App{{ID: "app_id", Name: "myapp"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn", RequestSchema: models.RequestSchema(`{"type": "object"}`)}},
+		[]*models.Trigger{{ID: "trigger_id", AppID: "app_id", FnID: "fn_id", Name: "mytrigger", Type: models.TriggerTypeHTTP, Source: "/hello"}},
+	)
+	if _, err := ds.InsertDomain(context.Background(), &models.Domain{Hostname: "api.example.com", PathPrefix: "/v1", AppID: "app_id"}); err != nil {
+		t.Fatal(err)
+	}
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	req := createRequest(t, "GET", "/v2/apps/app_id/openapi", nil)
+	req.Host = "fn.example.com"
+	_, rec := routerRequest2(t, srv.Router, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Servers) != 2 || doc.Servers[0].URL != "http://fn.example.com/t/myapp" || doc.Servers[1].URL != "http://api.example.com/v1" {
+		t.Fatalf("unexpected servers %+v", doc.Servers)
+	}
+	op := doc.Paths["/hello"]["post"]
+	if op == nil || op.OperationID != "mytrigger" || op.RequestBody == nil || doc.Components.Schemas["myfn.request"] == nil {
+		t.Fatalf("unexpected document %s", rec.Body.String())
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/apps/missing/openapi", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 for missing apps, got %d", rec.Code)
+	}
+}
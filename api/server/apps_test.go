@@ -166,8 +166,8 @@ func TestAppList(t *testing.T) {
 	ds := datastore.NewMockInit(
 		[]*models.App{
 			{Name: "myapp"},
-			{Name: "myapp2"},
-			{Name: "myapp3"},
+			{Name: "myapp2", Config: models.Config{"TIER": "prod"}},
+			{Name: "myapp3", Config: models.Config{"TIER": "dev"}},
 		},
 	)
 	fnl := logs.NewMock()
@@ -191,6 +191,12 @@ func TestAppList(t *testing.T) {
 		{"/v2/apps?per_page=1&cursor=" + a2b, "", http.StatusOK, nil, 1, a3b},
 		{"/v2/apps?per_page=100&cursor=" + a2b, "", http.StatusOK, nil, 1, ""}, // cursor is empty if per_page > len(results)
 		{"/v2/apps?per_page=1&cursor=" + a3b, "", http.StatusOK, nil, 0, ""},   // cursor could point to empty page
+		{"/v2/apps?name_prefix=myapp", "", http.StatusOK, nil, 3, ""},
+		{"/v2/apps?name_prefix=myapp2", "", http.StatusOK, nil, 1, ""},
+		{"/v2/apps?config=TIER", "", http.StatusOK, nil, 2, ""},
+		{"/v2/apps?config=TIER=prod&name_prefix=myapp", "", http.StatusOK, nil, 1, ""},
+		{"/v2/apps?config=TIER=prod&config=TIER=dev", "", http.StatusOK, nil, 0, ""},
+		{"/v2/apps?annotation=", "", http.StatusBadRequest, models.ErrInvalidKeyFilter, 0, ""},
 	} {
 		_, rec := routerRequest(t, srv.Router, "GET", test.path, nil)
 
@@ -38,5 +38,6 @@ func (s *Server) handleAppUpdate(c *gin.Context) {
 		return
 	}
 
+	setETag(c, app)
 	c.JSON(http.StatusOK, app)
 }
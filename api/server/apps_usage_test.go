@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestAppUsage(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.UsageQuotaAnnotation, models.UsageQuota{Daily: &models.UsageLimits{Calls: 100}})
+	ds := datastore.NewMockInit([]*models.App{app})
+	daily, monthly := models.UsagePeriods(time.Now())
+	ctx := context.Background()
+	for _, period := range []string{daily, monthly} {
+		if err := ds.AddAppUsage(ctx, &models.AppUsage{AppID: "app_id", Period: period, Calls: 3, MBMilliseconds: 1536000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/apps/app_id/usage", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report models.AppUsageReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Daily == nil || report.Daily.Period != daily || report.Daily.Calls != 3 || report.Daily.GBSeconds != 1.5 ||
+		report.Daily.Limits == nil || report.Daily.Limits.Calls != 100 {
+		t.Fatalf("unexpected daily usage %+v", report.Daily)
+	}
+	if report.Monthly == nil || report.Monthly.Period != monthly || report.Monthly.Calls != 3 || report.Monthly.Limits != nil {
+		t.Fatalf("unexpected monthly usage %+v", report.Monthly)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/apps/missing/usage", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status code 404 for missing apps, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleExport returns an archive of all apps, with their fns and triggers, and with their
+// calls too with calls=true, unless the call endpoints are disabled.
+func (s *Server) handleExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	withCalls, _ := strconv.ParseBool(c.Query("calls"))
+
+	archive := &models.Archive{Version: models.ArchiveVersion, CreatedAt: common.DateTime(time.Now())}
+	filter := &models.AppFilter{PerPage: applyPageSize}
+	for {
+		apps, err := s.datastore.GetApps(ctx, filter)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		for _, app := range apps.Items {
+			if err := s.exportApp(ctx, archive, app, withCalls && !s.noCallEndpoints); err != nil {
+				handleErrorResponse(c, err)
+				return
+			}
+		}
+		if apps.NextCursor == "" {
+			break
+		}
+		filter.Cursor = apps.NextCursor
+	}
+	c.JSON(http.StatusOK, archive)
+}
+
+// exportApp adds an app, its fns and triggers, and optionally the calls of its fns to archive
+func (s *Server) exportApp(ctx context.Context, archive *models.Archive, app *models.App, withCalls bool) error {
+	fns, err := s.applyFns(ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	triggers, err := s.applyTriggers(ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	archive.Apps = append(archive.Apps, app)
+	archive.Fns = append(archive.Fns, fns...)
+	archive.Triggers = append(archive.Triggers, triggers...)
+
+	if !withCalls {
+		return nil
+	}
+	for _, fn := range fns {
+		filter := &models.CallFilter{FnID: fn.ID, PerPage: applyPageSize}
+		for {
+			calls, err := s.logstore.GetCalls(ctx, filter)
+			if err != nil {
+				return err
+			}
+			archive.Calls = append(archive.Calls, calls.Items...)
+			if calls.NextCursor == "" {
+				break
+			}
+			filter.Cursor = calls.NextCursor
+		}
+	}
+	return nil
+}
+
+// handleImport imports an archive, creating its apps, fns and triggers, matched to existing
+// ones by name, in a single transaction. Existing resources are left as they are with
+// strategy=skip, the default, and replaced with strategy=overwrite. The calls of the archive
+// are imported once its resources are, the ones which exist already being skipped.
+func (s *Server) handleImport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	strategy := c.DefaultQuery("strategy", models.ImportSkip)
+	if strategy != models.ImportSkip && strategy != models.ImportOverwrite {
+		handleErrorResponse(c, models.ErrImportInvalidStrategy)
+		return
+	}
+
+	var archive models.Archive
+	if err := c.BindJSON(&archive); err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+	if err := archive.Validate(); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	var imp *archiveImport
+	err := s.datastore.InTx(ctx, func(ctx context.Context) error {
+		imp = &archiveImport{
+			ds:       s.datastore,
+			strategy: strategy,
+			appIDs:   make(map[string]string, len(archive.Apps)),
+			fnIDs:    make(map[string]string, len(archive.Fns)),
+		}
+		return imp.run(ctx, &archive)
+	})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	report := &models.ImportReport{Strategy: strategy, Changes: imp.changes}
+	if !s.noCallEndpoints {
+		for _, call := range archive.Calls {
+			call.AppID, call.FnID = imp.appIDs[call.AppID], imp.fnIDs[call.FnID]
+			if _, err := s.logstore.GetCall(ctx, call.FnID, call.ID); err == nil {
+				continue
+			}
+			if err := s.logstore.InsertCall(ctx, call); err != nil {
+				handleErrorResponse(c, err)
+				return
+			}
+			report.Calls++
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// archiveImport imports the resources of an archive, mapping their ids in the archive to the
+// ids of the resources imported
+type archiveImport struct {
+	ds       models.Datastore
+	strategy string
+	appIDs   map[string]string
+	fnIDs    map[string]string
+	changes  []models.ApplyChange
+}
+
+// run imports the apps, then the fns and then the triggers of archive
+func (imp *archiveImport) run(ctx context.Context, archive *models.Archive) error {
+	for _, app := range archive.Apps {
+		if err := imp.importApp(ctx, app.Clone()); err != nil {
+			return err
+		}
+	}
+	for _, fn := range archive.Fns {
+		if err := imp.importFn(ctx, fn.Clone()); err != nil {
+			return err
+		}
+	}
+	for _, t := range archive.Triggers {
+		if err := imp.importTrigger(ctx, t.Clone()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (imp *archiveImport) addChange(kind, name, id, action, fn string) {
+	imp.changes = append(imp.changes, models.ApplyChange{Kind: kind, Name: name, ID: id, Action: action, Fn: fn})
+}
+
+func (imp *archiveImport) importApp(ctx context.Context, app *models.App) error {
+	archivedID := app.ID
+	app.ID = ""
+	app.CreatedAt, app.UpdatedAt = common.DateTime{}, common.DateTime{}
+	app.DeletedAt = nil
+
+	appID, err := imp.ds.GetAppID(ctx, app.Name)
+	if err == models.ErrAppsNotFound {
+		created, err := imp.ds.InsertApp(ctx, app)
+		if err != nil {
+			return err
+		}
+		imp.appIDs[archivedID] = created.ID
+		imp.addChange(models.ApplyKindApp, app.Name, created.ID, models.ApplyCreate, "")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	imp.appIDs[archivedID] = appID
+
+	if imp.strategy == models.ImportSkip {
+		imp.addChange(models.ApplyKindApp, app.Name, appID, models.ImportSkipped, "")
+		return nil
+	}
+	existing, err := imp.ds.GetAppByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+	patch := existing.ReplacePatch(app)
+	updated := existing.Clone()
+	updated.Update(patch)
+	if updated.Equals(existing) {
+		imp.addChange(models.ApplyKindApp, app.Name, appID, models.ApplyUnchanged, "")
+		return nil
+	}
+	if _, err := imp.ds.UpdateApp(ctx, patch); err != nil {
+		return err
+	}
+	imp.addChange(models.ApplyKindApp, app.Name, appID, models.ApplyUpdate, "")
+	return nil
+}
+
+func (imp *archiveImport) importFn(ctx context.Context, fn *models.Fn) error {
+	archivedID := fn.ID
+	fn.ID = ""
+	fn.AppID = imp.appIDs[fn.AppID]
+	fn.CreatedAt, fn.UpdatedAt = common.DateTime{}, common.DateTime{}
+	fn.DeletedAt = nil
+	fn.Canary = nil // canaries are set with the canary endpoints
+	fn.SetDefaults()
+
+	fns, err := imp.ds.GetFns(ctx, &models.FnFilter{AppID: fn.AppID, Name: fn.Name, PerPage: 1})
+	if err != nil {
+		return err
+	}
+	if len(fns.Items) == 0 {
+		created, err := imp.ds.InsertFn(ctx, fn)
+		if err != nil {
+			return err
+		}
+		imp.fnIDs[archivedID] = created.ID
+		imp.addChange(models.ApplyKindFn, fn.Name, created.ID, models.ApplyCreate, "")
+		return nil
+	}
+	existing := fns.Items[0]
+	imp.fnIDs[archivedID] = existing.ID
+
+	if imp.strategy == models.ImportSkip {
+		imp.addChange(models.ApplyKindFn, fn.Name, existing.ID, models.ImportSkipped, "")
+		return nil
+	}
+	patch := existing.ReplacePatch(fn)
+	updated := existing.Clone()
+	updated.Update(patch)
+	if updated.Equals(existing) {
+		imp.addChange(models.ApplyKindFn, fn.Name, existing.ID, models.ApplyUnchanged, "")
+		return nil
+	}
+	if _, err := imp.ds.UpdateFn(ctx, patch); err != nil {
+		return err
+	}
+	imp.addChange(models.ApplyKindFn, fn.Name, existing.ID, models.ApplyUpdate, "")
+	return nil
+}
+
+func (imp *archiveImport) importTrigger(ctx context.Context, t *models.Trigger) error {
+	t.ID = ""
+	t.AppID, t.FnID = imp.appIDs[t.AppID], imp.fnIDs[t.FnID]
+	t.CreatedAt, t.UpdatedAt = common.DateTime{}, common.DateTime{}
+
+	triggers, err := imp.ds.GetTriggers(ctx, &models.TriggerFilter{AppID: t.AppID, Name: t.Name, PerPage: 1})
+	if err != nil {
+		return err
+	}
+	var existing *models.Trigger
+	if len(triggers.Items) > 0 {
+		existing = triggers.Items[0]
+	}
+	fnName := imp.fnName(ctx, t.FnID)
+
+	switch {
+	case existing != nil && imp.strategy == models.ImportSkip:
+		imp.addChange(models.ApplyKindTrigger, t.Name, existing.ID, models.ImportSkipped, fnName)
+		return nil
+	case existing != nil && existing.Type != t.Type:
+		// the type of a trigger cannot be updated, replace it
+		if err := imp.ds.RemoveTrigger(ctx, existing.ID); err != nil {
+			return err
+		}
+	case existing != nil:
+		patch := existing.ReplacePatch(t)
+		updated := existing.Clone()
+		updated.Update(patch)
+		if updated.Equals(existing) {
+			imp.addChange(models.ApplyKindTrigger, t.Name, existing.ID, models.ApplyUnchanged, fnName)
+			return nil
+		}
+		if _, err := imp.ds.UpdateTrigger(ctx, patch); err != nil {
+			return err
+		}
+		imp.addChange(models.ApplyKindTrigger, t.Name, existing.ID, models.ApplyUpdate, fnName)
+		return nil
+	}
+
+	created, err := imp.ds.InsertTrigger(ctx, t)
+	if err != nil {
+		return err
+	}
+	action := models.ApplyCreate
+	if existing != nil {
+		action = models.ApplyUpdate
+	}
+	imp.addChange(models.ApplyKindTrigger, t.Name, created.ID, action, fnName)
+	return nil
+}
+
+// fnName returns the name of the imported fn fnID, for the changes of its triggers
+func (imp *archiveImport) fnName(ctx context.Context, fnID string) string {
+	fn, err := imp.ds.GetFnByID(ctx, fnID)
+	if err != nil {
+		return ""
+	}
+	return fn.Name
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestExportImport(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+	ctx := context.Background()
+
+	request := func(srv *Server, method, path, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("expected status code %d, got %d: %s", expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expectChanges := func(report *models.ImportReport, expected ...string) {
+		var changes []string
+		for _, c := range report.Changes {
+			changes = append(changes, c.Kind+" "+c.Name+" "+c.Action)
+		}
+		if strings.Join(changes, ", ") != strings.Join(expected, ", ") {
+			t.Fatalf("expected changes %v, got %v", expected, changes)
+		}
+	}
+
+	// the source cluster, with an applied app and a call
+	srcLogs := logs.NewMock()
+	src := testServer(datastore.NewMock(), &mqs.Mock{}, srcLogs, nil, ServerTypeAPI)
+	req := createRequest(t, "POST", applyRoute, bytes.NewBufferString(applyBundleYAML))
+	req.Header.Set("Content-Type", "application/yaml")
+	if _, rec := routerRequest2(t, src.Router, req); rec.Code != http.StatusOK {
+		t.Fatalf("expected the bundle to be applied, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var archive models.Archive
+	request(src, "GET", "/v2/export", "", http.StatusOK, &archive)
+	if archive.Version != models.ArchiveVersion || len(archive.Apps) != 1 || len(archive.Fns) != 2 || len(archive.Triggers) != 2 || len(archive.Calls) != 0 {
+		t.Fatalf("unexpected archive %+v", archive)
+	}
+	call := &models.Call{ID: id.New().String(), AppID: archive.Apps[0].ID, FnID: archive.Fns[0].ID, Status: "success"}
+	if err := srcLogs.InsertCall(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	request(src, "GET", "/v2/export?calls=true", "", http.StatusOK, &archive)
+	if len(archive.Calls) != 1 || archive.Calls[0].ID != call.ID {
+		t.Fatalf("expected the call to be exported, got %+v", archive.Calls)
+	}
+
+	// the destination cluster, with an app of the same name
+	ds := datastore.NewMock()
+	dstLogs := logs.NewMock()
+	dst := testServer(ds, &mqs.Mock{}, dstLogs, nil, ServerTypeAPI)
+	existing, err := ds.InsertApp(ctx, &models.App{Name: "myapp", Config: models.Config{"LEVEL": "info"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report models.ImportReport
+	request(dst, "POST", "/v2/import", string(data), http.StatusOK, &report)
+	expectChanges(&report,
+		"app myapp skipped",
+		"fn bye create", "fn hello create",
+		"trigger bye create", "trigger hello create")
+	if report.Strategy != models.ImportSkip || report.Calls != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	app, err := ds.GetAppByID(ctx, existing.ID)
+	if err != nil || app.Config["LEVEL"] != "info" {
+		t.Fatalf("expected the existing app to be skipped, got %+v %v", app, err)
+	}
+	imported, err := dstLogs.GetCall(ctx, report.Changes[1].ID, call.ID)
+	if err != nil || imported.AppID != existing.ID {
+		t.Fatalf("expected the call to be imported into the existing app, got %+v %v", imported, err)
+	}
+
+	// importing again changes nothing, existing calls are skipped
+	request(dst, "POST", "/v2/import?strategy=skip", string(data), http.StatusOK, &report)
+	expectChanges(&report,
+		"app myapp skipped",
+		"fn bye skipped", "fn hello skipped",
+		"trigger bye skipped", "trigger hello skipped")
+	if report.Calls != 0 {
+		t.Fatalf("expected existing calls to be skipped, got %d", report.Calls)
+	}
+
+	// overwriting replaces the existing resources
+	archive.Fns[0].Memory = 512
+	data, err = json.Marshal(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request(dst, "POST", "/v2/import?strategy=overwrite", string(data), http.StatusOK, &report)
+	expectChanges(&report,
+		"app myapp update",
+		"fn bye update", "fn hello unchanged",
+		"trigger bye unchanged", "trigger hello unchanged")
+	app, err = ds.GetAppByID(ctx, existing.ID)
+	if err != nil || app.Config["LEVEL"] != "debug" {
+		t.Fatalf("expected the existing app to be overwritten, got %+v %v", app, err)
+	}
+	bye, err := ds.GetFnByID(ctx, report.Changes[1].ID)
+	if err != nil || bye.Memory != 512 {
+		t.Fatalf("expected the existing fn to be overwritten, got %+v %v", bye, err)
+	}
+
+	// nothing is imported if a resource fails
+	archive.Apps = append(archive.Apps, &models.App{ID: "other", Name: "other"})
+	archive.Fns = append(archive.Fns, &models.Fn{ID: "other", AppID: "other", Name: "other", Image: "fnproject/other"})
+	archive.Triggers = append(archive.Triggers,
+		&models.Trigger{AppID: "other", FnID: "other", Name: "other", Type: "http", Source: "/other"},
+		&models.Trigger{AppID: "other", FnID: "other", Name: "same", Type: "http", Source: "/other"})
+	data, err = json.Marshal(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request(dst, "POST", "/v2/import", string(data), http.StatusConflict, nil)
+	if _, err := ds.GetAppID(ctx, "other"); err != models.ErrAppsNotFound {
+		t.Fatalf("expected app other not to be imported, got %v", err)
+	}
+
+	// invalid imports
+	request(dst, "POST", "/v2/import?strategy=merge", `{"version": 1}`, http.StatusBadRequest, nil)
+	request(dst, "POST", "/v2/import", `{"version": 2}`, http.StatusBadRequest, nil)
+	request(dst, "POST", "/v2/import", `{"version": 1, "apps": [{"id": "a", "name": "a"}], "fns": [{"id": "f", "app_id": "b", "name": "f"}]}`, http.StatusBadRequest, nil)
+	request(dst, "POST", "/v2/import", `{"version": 1, "apps": [{"id": "a", "name": "a"}, {"id": "b", "name": "a"}]}`, http.StatusBadRequest, nil)
+	request(dst, "POST", "/v2/import", `not json`, http.StatusBadRequest, nil)
+}
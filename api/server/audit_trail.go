@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/audit/trail"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/gin-gonic/gin"
+)
+
+// WithAuditTrailSink appends the audit trail of the changes made through the management API
+// to sink, in place of the datastore. Use trail.MultiSink with trail.NewDatastoreSink to keep
+// the entries queryable through the API as well.
+func WithAuditTrailSink(sink trail.Sink) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.auditTrail = sink
+		return nil
+	}
+}
+
+// WithTrustedProxies maps EnvTrustedProxies
+func WithTrustedProxies(list string) Option {
+	return func(ctx context.Context, s *Server) error {
+		proxies, err := audit.ParseTrustedProxies(list)
+		if err != nil {
+			return err
+		}
+		s.trustedProxies = proxies
+		return nil
+	}
+}
+
+// dropCallerIdentity is the middleware removing the identity callers other than trusted
+// proxies claim from their requests, authenticate sets the identity it verifies.
+func (s *Server) dropCallerIdentity(c *gin.Context) {
+	if !s.trustedProxies.TrustsRequest(c.Request) {
+		c.Request.Header.Del(audit.CallerIdentityHeader)
+	}
+	c.Next()
+}
+
+// auditCaller is the middleware of the management API attributing the changes made by a
+// request to its caller, it runs once the request is authenticated. The actor is the
+// authenticated principal, or the identity a trusted proxy forwards when authentication is
+// disabled, and the address the one trusted proxies forward, if any, or the peer's.
+func (s *Server) auditCaller(c *gin.Context) {
+	ctx := c.Request.Context()
+	caller := audit.Caller{Address: s.trustedProxies.CallerAddress(c.Request)}
+	if id := fnext.GetIdentity(ctx); id != nil {
+		caller.Identity = id.Subject
+	} else {
+		// dropCallerIdentity removed the header unless a trusted proxy set it
+		caller.Identity = c.GetHeader(audit.CallerIdentityHeader)
+	}
+	c.Request = c.Request.WithContext(trail.WithCaller(ctx, caller))
+	c.Next()
+}
+
+// handleAuditEntryList queries the audit trail, most recent first, filtered by the app_id,
+// resource_type, resource_id, actor, from_time and to_time query parameters.
+func (s *Server) handleAuditEntryList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.AuditEntryFilter{
+		AppID:        c.Query("app_id"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		Actor:        c.Query("actor"),
+	}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	var err error
+	filter.FromTime, filter.ToTime, err = timeParams(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	entries, err := s.datastore.GetAuditEntries(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
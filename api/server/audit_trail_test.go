@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditTrail(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit()
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAPIKeys(testAdminAPIKey), WithTrustedProxies("10.0.0.0/8"))
+
+	remote := "10.0.0.1:5000"
+	request := func(method, path, secret, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var app models.App
+	request("POST", "/v2/apps", testAdminAPIKey, `{"name": "myapp"}`, http.StatusOK, &app)
+	request("PUT", "/v2/apps/"+app.ID, testAdminAPIKey, `{"config": {"LEVEL": "debug"}}`, http.StatusOK, nil)
+	var appKey models.APIKey
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "deployer", "app_id": "`+app.ID+`", "scope": "manage"}`, http.StatusOK, &appKey)
+	request("PUT", "/v2/apps/"+app.ID, appKey.Secret, `{"config": {"LEVEL": "info"}}`, http.StatusOK, nil)
+	// rejected requests change nothing
+	request("PUT", "/v2/apps/"+app.ID, "", `{"config": {"LEVEL": "warn"}}`, http.StatusUnauthorized, nil)
+
+	// the audit trail is only served to admins
+	request("GET", "/v2/audit", "", "", http.StatusUnauthorized, nil)
+	request("GET", "/v2/audit", appKey.Secret, "", http.StatusForbidden, nil)
+
+	var entries models.AuditEntryList
+	request("GET", "/v2/audit", testAdminAPIKey, "", http.StatusOK, &entries)
+	if len(entries.Items) != 4 {
+		t.Fatalf("expected an entry for each change, got %+v", entries.Items)
+	}
+	for _, entry := range entries.Items {
+		if entry.Address != "203.0.113.7" {
+			t.Errorf("expected the change to be made from 203.0.113.7, got %+v", entry)
+		}
+	}
+	latest := entries.Items[0]
+	if latest.Action != models.AuditActionUpdate || latest.ResourceType != models.AuditResourceApp || latest.Actor != "apikey/"+appKey.ID {
+		t.Fatalf("expected the update of the deployer key, got %+v", latest)
+	}
+	if d := latest.Diff["config"]; d.Old.(map[string]interface{})["LEVEL"] != "debug" || d.New.(map[string]interface{})["LEVEL"] != "info" {
+		t.Fatalf("expected the config to change from debug to info, got %+v", latest.Diff)
+	}
+	if key := entries.Items[1]; key.ResourceType != models.AuditResourceAPIKey || key.Diff["name"].New != "deployer" || key.Diff["secret"].New != nil {
+		t.Fatalf("expected the creation of the key without its secret, got %+v", key)
+	}
+
+	request("GET", "/v2/audit?actor=apikey/admin&resource_type=app", testAdminAPIKey, "", http.StatusOK, &entries)
+	if len(entries.Items) != 2 || entries.Items[1].Action != models.AuditActionCreate {
+		t.Fatalf("expected the creation and update of the app by the admin, got %+v", entries.Items)
+	}
+	request("GET", "/v2/audit?app_id="+app.ID+"&per_page=1", testAdminAPIKey, "", http.StatusOK, &entries)
+	if len(entries.Items) != 1 || entries.NextCursor == "" {
+		t.Fatalf("expected a page of entries, got %+v", entries)
+	}
+	request("GET", "/v2/audit?from_time=yesterday", testAdminAPIKey, "", http.StatusBadRequest, nil)
+
+	// only trusted proxies forward the address of the caller
+	remote = "198.51.100.1:5000"
+	request("PUT", "/v2/apps/"+app.ID, testAdminAPIKey, `{"config": {"LEVEL": "error"}}`, http.StatusOK, nil)
+	request("GET", "/v2/audit?per_page=1", testAdminAPIKey, "", http.StatusOK, &entries)
+	if len(entries.Items) != 1 || entries.Items[0].Address != "198.51.100.1" || entries.Items[0].Actor != "apikey/admin" {
+		t.Fatalf("expected the change to be made by the admin from 198.51.100.1, got %+v", entries.Items)
+	}
+}
+
+func TestDropCallerIdentity(t *testing.T) {
+	s := &Server{}
+	if err := WithTrustedProxies("10.0.0.0/8")(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(s.dropCallerIdentity)
+	engine.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.GetHeader(audit.CallerIdentityHeader)) })
+
+	for i, test := range []struct {
+		remote   string
+		expected string
+	}{
+		{"203.0.113.7:5000", ""},
+		{"10.0.0.1:5000", "alice"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remote
+		req.Header.Set(audit.CallerIdentityHeader, "alice")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Body.String() != test.expected {
+			t.Errorf("Test %d: expected identity %q, got %q", i, test.expected, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/oidc"
+	"github.com/fnproject/fn/fnext"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// apiKeyContextKey is the gin context key of the API key a request was authenticated with
+	apiKeyContextKey = "fn_api_key"
+	// appIDContextKey is the gin context key of the app of the resources of a request
+	appIDContextKey = "fn_request_app_id"
+
+	// adminAPIKeyID is the ID of the admin API key, see EnvAdminAPIKey
+	adminAPIKeyID = "admin"
+	// apiKeySubjectPrefix prefixes the ID of API keys in the subject of their identity, the
+	// tokens of the OIDC issuer with such subjects are rejected
+	apiKeySubjectPrefix = "apikey/"
+)
+
+// WithOIDC maps EnvOIDCIssuer, EnvOIDCJWKSURL and EnvOIDCAudience. Requests to the
+// management and invoke endpoints must then carry a bearer token of the issuer, the
+// identity of their caller is attached to their context, see fnext.GetIdentity.
+func WithOIDC(issuer, jwksURL, audience string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if issuer == "" {
+			return nil
+		}
+
+		v, err := oidc.NewVerifier(oidc.Config{
+			Issuer:   issuer,
+			JWKSURL:  jwksURL,
+			Audience: audience,
+		})
+		if err != nil {
+			return err
+		}
+		s.tokenVerifier = v
+		logrus.WithFields(logrus.Fields{"issuer": issuer, "audience": audience}).Info("authenticating requests with oidc bearer tokens")
+		return nil
+	}
+}
+
+// WithAPIKeys maps EnvAPIKeys and EnvAdminAPIKey. Requests to the management and invoke
+// endpoints must then carry the secret of an API key as bearer token, or a token of the
+// OIDC issuer if one is set too. The admin key, if set, is a manage key of all apps to
+// create the first API keys with.
+func WithAPIKeys(adminKey string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.apiKeys = true
+		if adminKey != "" {
+			s.adminAPIKeyHash = models.HashAPIKeySecret(adminKey)
+		}
+		logrus.Info("authenticating requests with api keys")
+		return nil
+	}
+}
+
+// authEnabled returns true if requests to the management and invoke endpoints must be authenticated
+func (s *Server) authEnabled() bool {
+	return s.tokenVerifier != nil || s.apiKeys
+}
+
+// authenticate returns the middleware authenticating the requests to endpoints of the class
+// of operations of scope, one of the API key scopes. Requests without valid credentials or
+// with an API key not allowing the operation are aborted.
+func (s *Server) authenticate(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		id, key, err := s.verifyBearerToken(ctx, c.GetHeader("Authorization"))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="fn"`)
+			handleErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+		if key != nil {
+			if err := s.authorizeAPIKey(c, key, scope); err != nil {
+				handleErrorResponse(c, err)
+				c.Abort()
+				return
+			}
+			c.Set(apiKeyContextKey, key)
+		}
+
+		// the verified subject replaces any identity claimed by the caller in audit records
+		c.Request.Header.Set(audit.CallerIdentityHeader, id.Subject)
+		c.Request = c.Request.WithContext(fnext.WithIdentity(ctx, id))
+		c.Next()
+	}
+}
+
+// requireUnrestrictedAPIKey rejects requests authenticated with API keys restricted to an app.
+func (s *Server) requireUnrestrictedAPIKey(c *gin.Context) {
+	if key, ok := c.Get(apiKeyContextKey); ok && key.(*models.APIKey).AppID != "" {
+		handleErrorResponse(c, models.ErrAPIKeyForbidden)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// verifyBearerToken returns the identity of the caller from the value of the Authorization
+// header of its request, and its API key if the token is the secret of one.
+func (s *Server) verifyBearerToken(ctx context.Context, authorization string) (*fnext.Identity, *models.APIKey, error) {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return nil, nil, models.ErrMissingBearerToken
+	}
+	token := strings.TrimSpace(authorization[len(prefix):])
+
+	if s.apiKeys && strings.HasPrefix(token, models.APIKeyPrefix) {
+		key, err := s.lookupAPIKey(ctx, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &fnext.Identity{
+			Subject: apiKeySubjectPrefix + key.ID,
+			Claims: map[string]interface{}{
+				"name":   key.Name,
+				"app_id": key.AppID,
+				"scope":  key.Scope,
+			},
+		}, key, nil
+	}
+
+	if s.tokenVerifier == nil {
+		return nil, nil, models.ErrInvalidBearerToken
+	}
+	claims, err := s.tokenVerifier.Verify(ctx, token)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Info("rejected bearer token")
+		return nil, nil, models.ErrInvalidBearerToken
+	}
+	if strings.HasPrefix(claims.Subject, apiKeySubjectPrefix) {
+		// would be taken for the principal of an API key in role bindings
+		common.Logger(ctx).WithField("subject", claims.Subject).Info("rejected bearer token with the subject of an api key")
+		return nil, nil, models.ErrInvalidBearerToken
+	}
+	return &fnext.Identity{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Claims:  claims.Raw,
+	}, nil, nil
+}
+
+// lookupAPIKey returns the API key of a secret
+func (s *Server) lookupAPIKey(ctx context.Context, secret string) (*models.APIKey, error) {
+	hash := models.HashAPIKeySecret(secret)
+	if s.adminAPIKeyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.adminAPIKeyHash)) == 1 {
+		return &models.APIKey{ID: adminAPIKeyID, Name: "admin", Scope: models.APIKeyScopeManage}, nil
+	}
+
+	key, err := s.datastore.GetAPIKeyByHash(ctx, hash)
+	if err == models.ErrAPIKeyNotFound {
+		return nil, models.ErrInvalidBearerToken
+	}
+	return key, err
+}
+
+// authorizeAPIKey checks that key allows the operation of the request, of the class of scope
+func (s *Server) authorizeAPIKey(c *gin.Context, key *models.APIKey, scope string) error {
+	if !key.AllowsScope(scope) {
+		return models.ErrAPIKeyForbidden
+	}
+	if key.AppID == "" {
+		return nil
+	}
+
+	appID, err := s.requestAppID(c)
+	if err != nil {
+		return err
+	}
+	if appID == "" || !key.AllowsApp(appID) {
+		return models.ErrAPIKeyForbidden
+	}
+	return nil
+}
+
+// requestAppID returns the app of the resources of a request, from its path, or else from
+// the app_id or fn_id of the body of writes and the app_id query parameter of reads. Writes
+// whose path, body and query parameter disagree are rejected. It is empty if the request is
+// not about the resources of a single app.
+func (s *Server) requestAppID(c *gin.Context) (string, error) {
+	if appID, ok := c.Get(appIDContextKey); ok {
+		return appID.(string), nil
+	}
+	appID, err := s.lookupRequestAppID(c)
+	if err != nil {
+		return "", err
+	}
+	c.Set(appIDContextKey, appID)
+	return appID, nil
+}
+
+func (s *Server) lookupRequestAppID(c *gin.Context) (string, error) {
+	appID, err := s.pathAppID(c)
+	if err != nil {
+		return "", err
+	}
+	queryAppID := c.Query("app_id")
+
+	if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut {
+		if appID != "" {
+			return appID, nil
+		}
+		return queryAppID, nil
+	}
+
+	// handlers act on the body of writes, their app is that of the body
+	bodyAppID, err := s.bodyAppID(c)
+	if err != nil {
+		return "", err
+	}
+	if appID != "" {
+		if (bodyAppID != "" && bodyAppID != appID) || (queryAppID != "" && queryAppID != appID) {
+			return "", models.ErrRequestAppMismatch
+		}
+		return appID, nil
+	}
+	if queryAppID != "" && queryAppID != bodyAppID {
+		return "", models.ErrRequestAppMismatch
+	}
+	return bodyAppID, nil
+}
+
+// pathAppID returns the app of the resource of the path of a request, empty if none
+func (s *Server) pathAppID(c *gin.Context) (string, error) {
+	ctx := c.Request.Context()
+
+	if appID := c.Param(api.AppID); appID != "" {
+		return appID, nil
+	}
+	if appName := c.Param(api.AppName); appName != "" {
+		return s.datastore.GetAppID(ctx, appName)
+	}
+	if fnID := c.Param(api.FnID); fnID != "" {
+		return s.fnAppID(ctx, fnID)
+	}
+	if triggerID := c.Param(api.TriggerID); triggerID != "" {
+		trigger, err := s.datastore.GetTriggerByID(ctx, triggerID)
+		if err != nil {
+			return "", err
+		}
+		return trigger.AppID, nil
+	}
+	if scheduleID := c.Param(api.ScheduleID); scheduleID != "" {
+		schedule, err := s.datastore.GetScheduleByID(ctx, scheduleID)
+		if err != nil {
+			return "", err
+		}
+		return schedule.AppID, nil
+	}
+	if bindingID := c.Param(api.RoleBindingID); bindingID != "" {
+		binding, err := s.datastore.GetRoleBindingByID(ctx, bindingID)
+		if err != nil {
+			return "", err
+		}
+		return binding.AppID, nil
+	}
+	if domainID := c.Param(api.DomainID); domainID != "" {
+		domain, err := s.datastore.GetDomainByID(ctx, domainID)
+		if err != nil {
+			return "", err
+		}
+		return domain.AppID, nil
+	}
+	if webhookID := c.Param(api.WebhookID); webhookID != "" {
+		webhook, err := s.datastore.GetWebhookByID(ctx, webhookID)
+		if err != nil {
+			return "", err
+		}
+		return webhook.AppID, nil
+	}
+	return "", nil
+}
+
+// bodyAppID returns the app of the app_id or fn_id of the body of a request, empty if it
+// has neither. Requests whose app_id and fn_id are of different apps are rejected.
+func (s *Server) bodyAppID(c *gin.Context) (string, error) {
+	// the body is read again by the handler
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var ref struct {
+		AppID string `json:"app_id"`
+		FnID  string `json:"fn_id"`
+	}
+	json.Unmarshal(body, &ref) // invalid bodies are reported by the handler
+	if ref.FnID == "" {
+		return ref.AppID, nil
+	}
+	fnAppID, err := s.fnAppID(c.Request.Context(), ref.FnID)
+	if err != nil {
+		return "", err
+	}
+	if ref.AppID != "" && ref.AppID != fnAppID {
+		return "", models.ErrRequestAppMismatch
+	}
+	return fnAppID, nil
+}
+
+// checkRequestApp rejects the operation of a handler on the resources of app appID, or of
+// the app of fn fnID, if its request was authorized on the resources of another app only.
+// Either may be empty if the operation leaves it as it is.
+func (s *Server) checkRequestApp(c *gin.Context, appID, fnID string) error {
+	authorized, ok := c.Get(appIDContextKey)
+	if !ok || authorized.(string) == "" {
+		// not authorized per app, or authorized on all apps
+		return nil
+	}
+	if appID != "" && appID != authorized.(string) {
+		return models.ErrRequestAppMismatch
+	}
+	if fnID != "" {
+		fnAppID, err := s.fnAppID(c.Request.Context(), fnID)
+		if err != nil {
+			return err
+		}
+		if fnAppID != authorized.(string) {
+			return models.ErrRequestAppMismatch
+		}
+	}
+	return nil
+}
+
+// checkRequestAllApps rejects the operation of a handler on the resources of all apps, if
+// its request was authorized on the resources of a single app only.
+func (s *Server) checkRequestAllApps(c *gin.Context) error {
+	if authorized, ok := c.Get(appIDContextKey); ok && authorized.(string) != "" {
+		return models.ErrRequestAppMismatch
+	}
+	return nil
+}
+
+// fnAppID returns the app of a fn, of a deleted one as well so that it may be restored.
+func (s *Server) fnAppID(ctx context.Context, fnID string) (string, error) {
+	fn, err := s.datastore.GetFnByID(ctx, fnID)
+	if err == models.ErrFnsNotFound {
+		fn, err = s.datastore.GetDeletedFnByID(ctx, fnID)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fn.AppID, nil
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/oidc"
+	"github.com/fnproject/fn/api/oidc/oidctest"
+	invoke "github.com/fnproject/fn/api/server/grpc"
+	"github.com/fnproject/fn/fnext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestOIDCAuthentication(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	iss := oidctest.NewIssuer()
+	defer iss.Close()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithOIDC(iss.URL, "", "fn"))
+
+	var identity *fnext.Identity
+	srv.AddAPIMiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity = fnext.GetIdentity(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	request := func(path, authorization string, expectedCode int) {
+		req := createRequest(t, "GET", path, bytes.NewBuffer(nil))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("expected status code %d, got %d: %s", expectedCode, rec.Code, rec.Body.String())
+		}
+		if expectedCode == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatal("expected a WWW-Authenticate header")
+		}
+	}
+
+	claims := iss.Claims("alice")
+	claims["aud"] = "fn"
+	request("/v2/apps", "Bearer "+iss.Token(claims), http.StatusOK)
+	if identity == nil || identity.Subject != "alice" || identity.Issuer != iss.URL {
+		t.Fatalf("expected the identity of alice, got %+v", identity)
+	}
+
+	identity = nil
+	request("/v2/apps", "", http.StatusUnauthorized)
+	request("/v2/apps", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized)
+	request("/v2/apps", "Bearer "+iss.Token(iss.Claims("alice")), http.StatusUnauthorized)
+	if identity != nil {
+		t.Fatal("expected unauthenticated requests not to reach api middlewares")
+	}
+
+	// the root of the server stays open to health checks
+	request("/", "", http.StatusOK)
+
+	t.Run("grpc invoke", func(t *testing.T) {
+		v, err := oidc.NewVerifier(oidc.Config{Issuer: iss.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Server{lbReadAccess: agent.NewCachedDataAccess(datastore.NewMock()), tokenVerifier: v}
+
+		lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		invoke.RegisterInvokerServer(gs, &grpcInvoker{s: s})
+		go gs.Serve(lsnr)
+		defer gs.Stop()
+
+		conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		client := invoke.NewInvokerClient(conn)
+		ctx := context.Background()
+
+		_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "nope"})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("invocation without a token should be unauthenticated, got: %v", err)
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+iss.Token(iss.Claims("alice")))
+		_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "nope"})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("authenticated invocation of a missing fn should not be found, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+)
+
+type backpressureAgent struct {
+	agent.Agent
+	bp models.Backpressure
+}
+
+func (a *backpressureAgent) Backpressure(agent.Call) (models.Backpressure, bool) {
+	return a.bp, true
+}
+
+func TestBackpressureErrorResponse(t *testing.T) {
+	bp := &models.Backpressure{QueueDepth: 7, RetryAfter: 4, InFlight: 10, MaxInFlight: 10}
+	s := &Server{agent: &backpressureAgent{bp: *bp}}
+
+	for _, test := range []struct {
+		err        error
+		status     int
+		retryAfter string
+		bp         *models.Backpressure
+	}{
+		{models.ErrCallTimeoutServerBusy, http.StatusServiceUnavailable, "4", bp},
+		{models.ErrTooManyRequests, http.StatusTooManyRequests, "4", bp},
+		{models.ErrAgentOverloaded, http.StatusServiceUnavailable, "4", bp},
+		{models.ErrAgentDraining, http.StatusServiceUnavailable, "", nil},
+		{models.ErrCallTimeout, http.StatusGatewayTimeout, "", nil},
+		{errors.New("boom"), http.StatusInternalServerError, "", nil},
+	} {
+		rec := httptest.NewRecorder()
+		HandleErrorResponse(context.Background(), rec, s.withBackpressure(nil, test.err))
+
+		if rec.Code != test.status {
+			t.Fatalf("%v: expected status %d, got %d", test.err, test.status, rec.Code)
+		}
+		if test.retryAfter != "" && rec.Header().Get("Retry-After") != test.retryAfter {
+			t.Fatalf("%v: expected Retry-After %s, got %q", test.err, test.retryAfter, rec.Header().Get("Retry-After"))
+		}
+
+		if test.bp != nil {
+			for header, value := range map[string]string{InFlightHeader: "10", MaxInFlightHeader: "10", QueueDepthHeader: "7"} {
+				if rec.Header().Get(header) != value {
+					t.Fatalf("%v: expected %s %s, got %q", test.err, header, value, rec.Header().Get(header))
+				}
+			}
+		} else if rec.Header().Get(InFlightHeader) != "" {
+			t.Fatalf("%v: expected no load headers", test.err)
+		}
+
+		var body models.Error
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if test.bp == nil {
+			if body.Backpressure != nil {
+				t.Fatalf("%v: expected no backpressure, got %+v", test.err, body.Backpressure)
+			}
+			continue
+		}
+		if body.Backpressure == nil || *body.Backpressure != *test.bp {
+			t.Fatalf("%v: expected backpressure %+v, got %+v", test.err, test.bp, body.Backpressure)
+		}
+	}
+}
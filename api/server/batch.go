@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleBatch returns the handler of the batches of changes to the resources of kind, one of
+// the apply kinds. The items are applied as their own requests would be, in order and in a
+// single datastore transaction, and the result of each item is returned. If an item fails
+// none is applied, and the response has the status of the error of the item.
+func (s *Server) handleBatch(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var batch models.Batch
+		err := c.BindJSON(&batch)
+		if err != nil {
+			if !models.IsAPIError(err) {
+				err = models.ErrInvalidJSON
+			}
+			handleErrorResponse(c, err)
+			return
+		}
+		if err := batch.Validate(kind); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+
+		for _, item := range batch.Items {
+			s.prepareBatchItem(item)
+		}
+
+		applied, err := s.datastore.ApplyBatch(ctx, batch.Items)
+		if e, ok := err.(*models.BatchError); ok {
+			result, status := failedBatchResult(c, len(batch.Items), e)
+			c.JSON(status, result)
+			return
+		}
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+
+		result := &models.BatchResult{Applied: true, Items: make([]*models.BatchItemResult, 0, len(applied))}
+		apps := make(map[string]*models.App)
+		for _, item := range applied {
+			result.Items = append(result.Items, s.batchItemResult(c, apps, item))
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// prepareBatchItem sets what the handlers of the requests of the item would before it is
+// applied
+func (s *Server) prepareBatchItem(item *models.BatchItem) {
+	switch item.Action {
+	case models.BatchCreate:
+		if item.Fn != nil {
+			item.Fn.Canary = nil // set with the canary endpoints
+			item.Fn.SetDefaults()
+		}
+	case models.BatchUpdate:
+		if item.Fn != nil {
+			item.Fn.Canary = nil
+		}
+	case models.BatchDelete:
+		item.SoftDelete = s.deleteRetention > 0 && item.Trigger == nil
+	}
+}
+
+// batchItemResult returns the result of an applied item, with created fns and triggers
+// annotated as their create handlers do. apps caches the apps of the annotated resources.
+func (s *Server) batchItemResult(c *gin.Context, apps map[string]*models.App, item *models.BatchItem) *models.BatchItemResult {
+	if item.Action == models.BatchDelete {
+		return &models.BatchItemResult{Status: http.StatusNoContent}
+	}
+
+	res := &models.BatchItemResult{Status: http.StatusOK, App: item.App, Fn: item.Fn, Trigger: item.Trigger}
+	if item.Action != models.BatchCreate || item.App != nil {
+		return res
+	}
+
+	log := common.Logger(c.Request.Context())
+	var appID string
+	if item.Fn != nil {
+		appID = item.Fn.AppID
+	} else {
+		appID = item.Trigger.AppID
+	}
+	app, ok := apps[appID]
+	if !ok {
+		var err error
+		app, err = s.datastore.GetAppByID(c.Request.Context(), appID)
+		if err != nil {
+			log.WithError(err).Debug("Failed to lookup app of batch item")
+			return res
+		}
+		apps[appID] = app
+	}
+
+	if item.Fn != nil {
+		fn, err := s.fnAnnotator.AnnotateFn(c, app, item.Fn)
+		if err != nil {
+			log.WithError(err).Debug("Failed to annotate fn")
+			return res
+		}
+		res.Fn = fn
+	} else {
+		trigger, err := s.triggerAnnotator.AnnotateTrigger(c, app, item.Trigger)
+		if err != nil {
+			log.WithError(err).Debug("Failed to annotate trigger")
+			return res
+		}
+		res.Trigger = trigger
+	}
+	return res
+}
+
+// failedBatchResult returns the result of a batch of n items which was not applied as the
+// item of e failed, and the status of the error of the item.
+func failedBatchResult(c *gin.Context, n int, e *models.BatchError) (*models.BatchResult, int) {
+	status, err := http.StatusInternalServerError, error(ErrInternalServerError)
+	if apiErr, ok := e.Err.(models.APIError); ok {
+		status, err = apiErr.Code(), apiErr
+	} else {
+		common.Logger(c.Request.Context()).WithError(e.Err).Error("internal server error applying batch")
+	}
+
+	result := &models.BatchResult{Items: make([]*models.BatchItemResult, n)}
+	for i := range result.Items {
+		if i == e.Index {
+			result.Items[i] = &models.BatchItemResult{Status: status, Error: simpleError(err)}
+		} else {
+			result.Items[i] = &models.BatchItemResult{Status: models.ErrBatchNotApplied.Code(), Error: simpleError(models.ErrBatchNotApplied)}
+		}
+	}
+	return result, status
+}
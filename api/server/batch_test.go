@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestBatch(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ds := datastore.NewMockInit([]*models.App{app})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithDeleteRetention(time.Hour))
+
+	request := func(path, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, "POST", path, bytes.NewBufferString(body))
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("POST %s: expected status code %d, got %d: %s", path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var result models.BatchResult
+	request("/v2/batch/fns", `{"items": [
+		{"action": "create", "fn": {"name": "fn1", "app_id": "app_id", "image": "fnproject/fn-test-utils"}},
+		{"action": "create", "fn": {"name": "fn2", "app_id": "app_id", "image": "fnproject/fn-test-utils"}}
+	]}`, http.StatusOK, &result)
+	if !result.Applied || len(result.Items) != 2 {
+		t.Fatalf("expected the batch to be applied, got %+v", result)
+	}
+	for _, item := range result.Items {
+		if item.Status != http.StatusOK || item.Fn == nil || item.Fn.ID == "" || item.Fn.Memory != models.DefaultMemory {
+			t.Fatalf("expected the fn to be created with defaults, got %+v", item)
+		}
+	}
+	fn1, fn2 := result.Items[0].Fn, result.Items[1].Fn
+
+	// a failed item leaves the other items unapplied
+	result = models.BatchResult{}
+	request("/v2/batch/triggers", `{"items": [
+		{"action": "create", "trigger": {"name": "t1", "app_id": "app_id", "fn_id": "`+fn1.ID+`", "type": "http", "source": "/t1"}},
+		{"action": "create", "trigger": {"name": "t2", "app_id": "app_id", "fn_id": "nonexistent", "type": "http", "source": "/t2"}}
+	]}`, http.StatusNotFound, &result)
+	if result.Applied || len(result.Items) != 2 {
+		t.Fatalf("expected the batch not to be applied, got %+v", result)
+	}
+	if item := result.Items[0]; item.Status != http.StatusFailedDependency || item.Trigger != nil {
+		t.Fatalf("expected the first item not to be applied, got %+v", item)
+	}
+	if item := result.Items[1]; item.Status != http.StatusNotFound || item.Error.Message != models.ErrFnsNotFound.Error() {
+		t.Fatalf("expected the second item to fail, got %+v", item)
+	}
+	triggers, err := ds.GetTriggers(context.Background(), &models.TriggerFilter{AppID: app.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(triggers.Items) != 0 {
+		t.Fatalf("expected no trigger to be created, got %+v", triggers.Items)
+	}
+
+	// deleted fns are only marked deleted while deleted resources are retained
+	result = models.BatchResult{}
+	request("/v2/batch/fns", `{"items": [
+		{"action": "update", "fn": {"id": "`+fn1.ID+`", "image": "fnproject/fn-test-utils:2"}},
+		{"action": "delete", "fn": {"id": "`+fn2.ID+`"}}
+	]}`, http.StatusOK, &result)
+	if item := result.Items[0]; item.Status != http.StatusOK || item.Fn.Image != "fnproject/fn-test-utils:2" {
+		t.Fatalf("expected the fn to be updated, got %+v", item)
+	}
+	if item := result.Items[1]; item.Status != http.StatusNoContent || item.Fn != nil {
+		t.Fatalf("expected the fn to be deleted, got %+v", item)
+	}
+	request("/v2/fns/"+fn2.ID+"/restore", "", http.StatusOK, nil)
+
+	for _, test := range []struct {
+		path, body string
+	}{
+		{"/v2/batch/apps", `{"items": []}`},
+		{"/v2/batch/apps", `{"items": [{"action": "upsert", "app": {"name": "app2"}}]}`},
+		{"/v2/batch/apps", `{"items": [{"action": "create", "fn": {"name": "fn3"}}]}`},
+		{"/v2/batch/triggers", `{"items": [{"action": "delete"}]}`},
+		{"/v2/batch/apps", `{"items": {}}`},
+	} {
+		request(test.path, test.body, http.StatusBadRequest, nil)
+	}
+}
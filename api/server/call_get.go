@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +31,14 @@ func (s *Server) handleCallGet(c *gin.Context) {
 	}
 
 	callObj, err := s.logstore.GetCall(ctx, fnID, callID)
+	if err == models.ErrCallNotFound {
+		// detached calls are only stored once they ended
+		if csp, ok := s.agent.(agent.CallStatusProvider); ok {
+			if detached, ok := csp.DetachedCall(fnID, callID); ok {
+				callObj, err = detached, nil
+			}
+		}
+	}
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
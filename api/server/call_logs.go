@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 
@@ -22,48 +23,99 @@ type callLogResponse struct {
 type callLog struct {
 	CallID string `json:"call_id" db:"id"`
 	Log    string `json:"log" db:"log"`
+	// Entries are those of structured logs, Log holds their lines
+	Entries []*models.LogEntry `json:"entries,omitempty" db:"-"`
 }
 
-func writeJSON(c *gin.Context, callID string, logReader io.Reader) {
-	var b bytes.Buffer
-	b.ReadFrom(logReader)
+func writeJSON(c *gin.Context, callID string, log *bytes.Buffer, entries []*models.LogEntry) {
 	c.JSON(http.StatusOK, &callLog{
-		CallID: callID,
-		Log:    b.String(),
+		CallID:  callID,
+		Log:     log.String(),
+		Entries: entries,
 	})
 }
 
+// writeEntries writes the entries of a log as newline delimited json
+func writeEntries(c *gin.Context, entries []*models.LogEntry) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+	}
+}
+
 func (s *Server) handleCallLogGet(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	fnID := c.Param(api.FnID)
 	callID := c.Param(api.CallID)
 
-	logReader, err := s.logstore.GetLog(ctx, fnID, callID)
+	filter, err := logFilterParams(c)
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
 	}
 
+	var log bytes.Buffer
+	var entries, jsonEntries []*models.LogEntry
+	if filter.IsZero() {
+		logReader, err := s.logstore.GetLog(ctx, fnID, callID)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if _, err := log.ReadFrom(logReader); err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		var structured bool
+		entries, structured, err = models.ParseLogEntries(bytes.NewReader(log.Bytes()))
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		if structured {
+			// the log is returned as the lines of its entries
+			jsonEntries = entries
+			log.Reset()
+			models.WriteLogText(&log, entries)
+		}
+	} else {
+		entries, err = s.logstore.SearchLog(ctx, fnID, callID, filter)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		jsonEntries = entries
+		models.WriteLogText(&log, entries)
+	}
+
 	mimeTypes, _ := c.Request.Header["Accept"]
 
 	if len(mimeTypes) == 0 {
-		writeJSON(c, callID, logReader)
+		writeJSON(c, callID, &log, jsonEntries)
 		return
 	}
 
 	for _, mimeType := range mimeTypes {
+		if strings.Contains(mimeType, "application/x-ndjson") {
+			writeEntries(c, entries)
+			return
+		}
 		if strings.Contains(mimeType, "application/json") {
-			writeJSON(c, callID, logReader)
+			writeJSON(c, callID, &log, jsonEntries)
 			return
 		}
 		if strings.Contains(mimeType, "text/plain") {
-			io.Copy(c.Writer, logReader)
+			io.Copy(c.Writer, &log)
 			return
 
 		}
 		if strings.Contains(mimeType, "*/*") {
-			writeJSON(c, callID, logReader)
+			writeJSON(c, callID, &log, jsonEntries)
 			return
 		}
 	}
@@ -72,3 +124,22 @@ func (s *Server) handleCallLogGet(c *gin.Context) {
 	handleErrorResponse(c, models.NewAPIError(http.StatusNotAcceptable,
 		errors.New("unable to respond within acceptable response content types")))
 }
+
+// logFilterParams returns the filter of the log entries set by the from_time, to_time,
+// match and severity query parameters
+func logFilterParams(c *gin.Context) (*models.LogFilter, error) {
+	fromTime, toTime, err := timeParams(c)
+	if err != nil {
+		return nil, err
+	}
+	filter := &models.LogFilter{
+		FromTime: fromTime,
+		ToTime:   toTime,
+		Match:    c.Query("match"),
+		Severity: c.Query("severity"),
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
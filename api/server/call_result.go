@@ -0,0 +1,27 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleCallResultGet writes the stored response payload of a detached call
+func (s *Server) handleCallResultGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fnID := c.Param(api.FnID)
+	callID := c.Param(api.CallID)
+
+	result, err := s.logstore.GetResult(ctx, fnID, callID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, result)
+}
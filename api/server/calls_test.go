@@ -1,10 +1,12 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
 )
 
 func TestCallGet(t *testing.T) {
@@ -84,6 +87,138 @@ func TestCallGet(t *testing.T) {
 	}
 }
 
+func TestCallResultGet(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	fn := &models.Fn{Name: "myfn", ID: "fn_id"}
+	call := &models.Call{FnID: fn.ID, ID: id.New().String()}
+
+	rnr, cancel := testRunner(t)
+	defer cancel()
+	ds := datastore.NewMockInit(
+		[]*models.Fn{fn},
+	)
+	fnl := logs.NewMock([]*models.Call{call})
+	if err := fnl.InsertResult(context.Background(), call, strings.NewReader("the result")); err != nil {
+		t.Fatal(err)
+	}
+	srv := testServer(ds, &mqs.Mock{}, fnl, rnr, ServerTypeFull)
+
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls/"+call.ID+"/result", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "the result" {
+		t.Fatalf("expected the stored result, got: %d %q", rec.Code, rec.Body.String())
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/calls/"+id.New().String()+"/result", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no result for an unknown call, got: %d", rec.Code)
+	}
+	resp := getErrorResponse(t, rec)
+	if !strings.Contains(resp.Message, models.ErrCallResultNotFound.Error()) {
+		t.Fatalf("expected error `%s`, got: %s", models.ErrCallResultNotFound.Error(), resp.Message)
+	}
+}
+
+func TestCallLogGetStructured(t *testing.T) {
+	call := &models.Call{FnID: "fn_id", ID: id.New().String()}
+	fnl := logs.NewMock([]*models.Call{call})
+	var log strings.Builder
+	for _, e := range []*models.LogEntry{
+		models.NewLogEntry(time.Now(), models.LogStreamStdout, []byte("hello")),
+		models.NewLogEntry(time.Now(), models.LogStreamStderr, []byte(`{"level":"warn"}`)),
+	} {
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Write(append(b, '\n'))
+	}
+	if err := fnl.InsertLog(context.Background(), call, strings.NewReader(log.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{logstore: fnl}
+	router := gin.New()
+	router.GET("/v2/fns/:fn_id/calls/:call_id/log", s.handleCallLogGet)
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v2/fns/fn_id/calls/"+call.ID+"/log", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d for %s, got %d: %s", http.StatusOK, accept, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	if text := get("text/plain").Body.String(); text != "hello\n{\"level\":\"warn\"}\n" {
+		t.Fatalf("expected the lines of the log, got %q", text)
+	}
+
+	var resp callLog
+	if err := json.NewDecoder(get("application/json").Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Log != "hello\n{\"level\":\"warn\"}\n" || len(resp.Entries) != 2 || resp.Entries[1].Fields["level"] != "warn" {
+		t.Fatalf("expected the lines and entries of the log, got %+v", resp)
+	}
+
+	lines := strings.Split(strings.TrimSpace(get("application/x-ndjson").Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected an entry per line, got %q", lines)
+	}
+	var entry models.LogEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry.Stream != models.LogStreamStdout || entry.Line != "hello" {
+		t.Fatalf("expected the stdout entry, got %+v %v", entry, err)
+	}
+}
+
+func TestCallLogSearch(t *testing.T) {
+	call := &models.Call{FnID: "fn_id", ID: id.New().String()}
+	fnl := logs.NewMock([]*models.Call{call})
+	if err := fnl.InsertLog(context.Background(), call, strings.NewReader("starting\nError: failed\ndone\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{logstore: fnl}
+	router := gin.New()
+	router.GET("/v2/fns/:fn_id/calls/:call_id/log", s.handleCallLogGet)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/fns/fn_id/calls/"+call.ID+"/log?"+query, nil))
+		return rec
+	}
+
+	rec := get("match=error")
+	var resp callLog
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || resp.Log != "Error: failed\n" || len(resp.Entries) != 1 {
+		t.Fatalf("expected the line matched, got %d %+v", rec.Code, resp)
+	}
+
+	// raw logs have no severity
+	resp = callLog{}
+	if err := json.NewDecoder(get("severity=debug").Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Log != "" || len(resp.Entries) != 0 {
+		t.Fatalf("expected no lines of a raw log to have a severity, got %+v", resp)
+	}
+
+	for _, query := range []string{"severity=loud", "from_time=yesterday"} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %d", query, rec.Code)
+		}
+	}
+}
+
 func TestCallList(t *testing.T) {
 	buf := setLogBuffer()
 	defer func() {
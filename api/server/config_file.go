@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"gopkg.in/yaml.v2"
+)
+
+// configFile sets the settings of a server from a YAML file of environment variables, see
+// EnvConfigFile. The variables of the environment of the server take precedence over it.
+type configFile struct {
+	path string
+	// variables set in the environment of the server at startup
+	environ map[string]bool
+	// variables set from the file as of its last load
+	set map[string]bool
+}
+
+// loadConfigFile sets the variables of the file at path which are not in the environment
+func loadConfigFile(path string) (*configFile, error) {
+	f := &configFile{path: path, environ: make(map[string]bool), set: make(map[string]bool)}
+	for _, kv := range os.Environ() {
+		f.environ[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+	return f, f.load()
+}
+
+// load reads the file again, setting its variables and unsetting the ones it no longer has. A
+// file which cannot be read changes no variable.
+func (f *configFile) load() error {
+	settings, err := readConfigFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	for key := range f.set {
+		if _, ok := settings[key]; !ok {
+			os.Unsetenv(key)
+			delete(f.set, key)
+		}
+	}
+	for key, value := range settings {
+		if f.environ[key] || f.environ[key+"_FILE"] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		f.set[key] = true
+	}
+	return nil
+}
+
+// readConfigFile returns the settings of a config file by environment variable. Keys are FN_
+// variables or their lower case names without FN_, other variables are rejected rather than
+// read as FN_ ones.
+func readConfigFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string, len(raw))
+	for name, value := range raw {
+		key := strings.ToUpper(name)
+		if !strings.HasPrefix(key, "FN_") {
+			// eg. DOCKER_HOST, which is not read from the file
+			if name != strings.ToLower(name) {
+				return nil, fmt.Errorf("invalid key %s in config file, only FN_ variables are read from it", name)
+			}
+			key = "FN_" + key
+		}
+		switch v := value.(type) {
+		case string:
+			settings[key] = v
+		case int, float64, bool:
+			settings[key] = fmt.Sprint(v)
+		case nil:
+			settings[key] = ""
+		default:
+			return nil, fmt.Errorf("invalid value of %s in config file, values must be strings, numbers or booleans", key)
+		}
+	}
+	return settings, nil
+}
+
+// reloadOnHangup reloads the settings of the server on SIGHUP, until ctx is done
+func (s *Server) reloadOnHangup(ctx context.Context) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-c:
+				s.reloadConfig(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reloadConfig reads the config file again, if any, and applies the settings which are safe
+// to change as the server runs, see EnvConfigFile. An invalid setting leaves it unchanged.
+func (s *Server) reloadConfig(ctx context.Context) {
+	log := common.Logger(ctx)
+	if s.configFile != nil {
+		if err := s.configFile.load(); err != nil {
+			log.WithError(err).WithField("path", s.configFile.path).Error("cannot reload config file, settings unchanged")
+			return
+		}
+	}
+	log.Info("Reloading settings")
+
+	common.SetLogLevel(getEnv(EnvLogLevel, DefaultLogLevel))
+
+	if limit, err := parseDefaultRateLimit(getEnv(EnvDefaultRateLimit, "")); err != nil {
+		log.WithError(err).Error("cannot reload default rate limit")
+	} else {
+		s.defaultRateLimit.Store(limit)
+	}
+
+	if setter, ok := s.agent.(agent.IdleTimeoutsSetter); ok {
+		cfg, err := agent.NewConfig()
+		if err != nil {
+			log.WithError(err).Error("cannot reload agent idle timeouts")
+		} else {
+			setter.SetIdleTimeouts(agent.IdleTimeouts{FreezeIdle: cfg.FreezeIdle, WebSocketIdle: cfg.WebSocketIdleTimeout})
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.yaml")
+
+	// the environment takes precedence over the file
+	os.Setenv("FN_TEST_FROM_ENV", "env")
+	defer os.Unsetenv("FN_TEST_FROM_ENV")
+	defer os.Unsetenv("FN_TEST_FROM_FILE")
+	defer os.Unsetenv("FN_TEST_REMOVED")
+
+	writeConfigFile(t, path, "FN_TEST_FROM_ENV: file\ntest_from_file: 10\nFN_TEST_REMOVED: true\n")
+	cf, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("expected config file to load, got %v", err)
+	}
+	for key, expected := range map[string]string{"FN_TEST_FROM_ENV": "env", "FN_TEST_FROM_FILE": "10", "FN_TEST_REMOVED": "true"} {
+		if v := os.Getenv(key); v != expected {
+			t.Fatalf("expected %s=%s, got %q", key, expected, v)
+		}
+	}
+
+	// settings no longer in the file are unset, an invalid file changes nothing
+	writeConfigFile(t, path, "test_from_file: 20\n")
+	if err := cf.load(); err != nil {
+		t.Fatalf("expected config file to reload, got %v", err)
+	}
+	if v, ok := os.LookupEnv("FN_TEST_REMOVED"); ok || os.Getenv("FN_TEST_FROM_FILE") != "20" {
+		t.Fatalf("expected the reloaded settings, got FN_TEST_REMOVED=%q FN_TEST_FROM_FILE=%q", v, os.Getenv("FN_TEST_FROM_FILE"))
+	}
+	writeConfigFile(t, path, "test_from_file: [1, 2]\n")
+	if err := cf.load(); err == nil {
+		t.Fatal("expected a list value to be rejected")
+	}
+	if v := os.Getenv("FN_TEST_FROM_FILE"); v != "20" {
+		t.Fatalf("expected the settings of the invalid file to be ignored, got %q", v)
+	}
+
+	// variables other than FN_ ones are not read as FN_ variables
+	writeConfigFile(t, path, "test_from_file: 30\nDOCKER_HOST: tcp://docker:2375\n")
+	if err := cf.load(); err == nil {
+		t.Fatal("expected a variable other than an FN_ one to be rejected")
+	}
+	if v, ok := os.LookupEnv("FN_DOCKER_HOST"); ok || os.Getenv("FN_TEST_FROM_FILE") != "20" {
+		t.Fatalf("expected the settings of the invalid file to be ignored, got FN_DOCKER_HOST=%q FN_TEST_FROM_FILE=%q", v, os.Getenv("FN_TEST_FROM_FILE"))
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fn-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fn.yaml")
+
+	defer os.Unsetenv(EnvLogLevel)
+	defer os.Unsetenv(EnvDefaultRateLimit)
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	writeConfigFile(t, path, "log_level: info\n")
+	cf, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{configFile: cf}
+
+	writeConfigFile(t, path, "log_level: debug\ndefault_rate_limit: '{\"rate\": 5, \"burst\": 10}'\n")
+	s.reloadConfig(context.Background())
+	if logrus.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("expected the log level to be reloaded, got %s", logrus.GetLevel())
+	}
+	limit, _ := s.defaultRateLimit.Load().(*models.RateLimit)
+	if limit == nil || limit.Rate != 5 || limit.Burst != 10 {
+		t.Fatalf("expected the default rate limit to be reloaded, got %+v", limit)
+	}
+
+	// an invalid limit keeps the current one
+	writeConfigFile(t, path, "log_level: debug\ndefault_rate_limit: '{\"rate\": -1}'\n")
+	s.reloadConfig(context.Background())
+	if limit, _ := s.defaultRateLimit.Load().(*models.RateLimit); limit == nil || limit.Rate != 5 {
+		t.Fatalf("expected the default rate limit to be kept, got %+v", limit)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+)
+
+type datastoreHooks []fnext.DatastoreHook
+
+var _ fnext.DatastoreHook = new(datastoreHooks)
+
+// AddDatastoreHook adds a DatastoreHook for the server to run inside the datastore operations
+// creating and updating apps, fns and triggers.
+func (s *Server) AddDatastoreHook(hook fnext.DatastoreHook) {
+	*s.datastoreHooks = append(*s.datastoreHooks, hook)
+}
+
+func (d *datastoreHooks) BeforeAppPersist(ctx context.Context, app *models.App, created bool) error {
+	for _, h := range *d {
+		err := h.BeforeAppPersist(ctx, app, created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *datastoreHooks) BeforeFnPersist(ctx context.Context, fn *models.Fn, created bool) error {
+	for _, h := range *d {
+		err := h.BeforeFnPersist(ctx, fn, created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *datastoreHooks) BeforeTriggerPersist(ctx context.Context, trigger *models.Trigger, created bool) error {
+	for _, h := range *d {
+		err := h.BeforeTriggerPersist(ctx, trigger, created)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleDeadLetterGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fnID := c.Param(api.FnID)
+
+	if fnID == "" {
+		handleErrorResponse(c, models.ErrFnsMissingID)
+		return
+	}
+
+	_, err := s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	callID := c.Param(api.CallID)
+	if callID == "" {
+		handleErrorResponse(c, models.ErrDatastoreEmptyCallID)
+		return
+	}
+
+	callObj, err := s.logstore.GetDeadLetter(ctx, fnID, callID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, callObj)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleDeadLetterReplay enqueues a dead lettered call again as a new call, with the
+// current retry policy of its fn, and removes it from the dead letters.
+func (s *Server) handleDeadLetterReplay(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fnID := c.Param(api.FnID)
+
+	if fnID == "" {
+		handleErrorResponse(c, models.ErrFnsMissingID)
+		return
+	}
+
+	fn, err := s.datastore.GetFnByID(ctx, fnID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	callID := c.Param(api.CallID)
+	if callID == "" {
+		handleErrorResponse(c, models.ErrDatastoreEmptyCallID)
+		return
+	}
+
+	if s.mq == nil {
+		handleErrorResponse(c, models.ErrAsyncUnsupported)
+		return
+	}
+
+	dead, err := s.logstore.GetDeadLetter(ctx, fnID, callID)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	replay := *dead
+	replay.ID = id.New().String()
+	replay.Attempt = 1
+	replay.RetryOf = dead.ID
+	replay.RetryPolicy = fn.RetryPolicy.Clone()
+	replay.Status = "queued"
+	replay.Error = ""
+	replay.Stats = nil
+	replay.Delay = 0
+	replay.CreatedAt = common.DateTime(time.Now())
+	replay.StartedAt = common.DateTime(time.Time{})
+	replay.CompletedAt = common.DateTime(time.Time{})
+
+	if _, err := s.mq.Push(ctx, &replay); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if err := s.logstore.RemoveDeadLetter(ctx, fnID, callID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, &replay)
+}
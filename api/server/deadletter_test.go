@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+type replayMQ struct {
+	mqs.Mock
+	pushed []*models.Call
+}
+
+func (mq *replayMQ) Push(ctx context.Context, call *models.Call) (*models.Call, error) {
+	mq.pushed = append(mq.pushed, call)
+	return call, nil
+}
+
+func TestDeadLetters(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	fn := &models.Fn{Name: "myfn", ID: "fn_id", RetryPolicy: &models.RetryPolicy{MaxAttempts: 5}}
+	dead := &models.Call{
+		FnID:        fn.ID,
+		ID:          id.New().String(),
+		Image:       "fnproject/fn-test-utils",
+		Type:        models.TypeAsync,
+		Payload:     `{"hello":"world"}`,
+		Status:      "error",
+		Error:       "boom",
+		Attempt:     3,
+		RetryPolicy: &models.RetryPolicy{MaxAttempts: 3},
+		CreatedAt:   common.DateTime(time.Now()),
+		CompletedAt: common.DateTime(time.Now()),
+	}
+
+	ds := datastore.NewMockInit([]*models.Fn{fn})
+	ls := logs.NewMock()
+	if err := ls.InsertDeadLetter(ctx, dead); err != nil {
+		t.Fatal(err)
+	}
+	mq := new(replayMQ)
+	srv := testServer(ds, mq, ls, nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{"GET", "/v2/fns/missing_fn/deadletters", http.StatusNotFound},
+		{"GET", "/v2/fns/fn_id/deadletters/" + id.New().String(), http.StatusNotFound},
+		{"POST", "/v2/fns/fn_id/deadletters/" + id.New().String() + "/replay", http.StatusNotFound},
+		{"GET", "/v2/fns/fn_id/deadletters/" + dead.ID, http.StatusOK},
+	} {
+		_, rec := routerRequest(t, srv.Router, test.method, test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: Expected status code to be %d but was %d", i, test.expectedCode, rec.Code)
+		}
+	}
+
+	_, rec := routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/deadletters", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected to list dead letters, got %d", rec.Code)
+	}
+	var list models.CallList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != dead.ID {
+		t.Fatalf("expected the dead letter to be listed, got %+v", list.Items)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "POST", "/v2/fns/fn_id/deadletters/"+dead.ID+"/replay", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected replay to be accepted, got %d", rec.Code)
+	}
+	if len(mq.pushed) != 1 {
+		t.Fatalf("expected the call to be enqueued, got %d", len(mq.pushed))
+	}
+	replay := mq.pushed[0]
+	if replay.ID == dead.ID || replay.RetryOf != dead.ID || replay.Attempt != 1 || replay.Status != "queued" || replay.Error != "" {
+		t.Fatalf("unexpected replayed call %+v", replay)
+	}
+	if replay.Payload != dead.Payload || !replay.RetryPolicy.Equals(fn.RetryPolicy) {
+		t.Fatalf("expected replay to keep the payload and use the fn retry policy, got %+v", replay)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", "/v2/fns/fn_id/deadletters/"+dead.ID, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected replayed dead letter to be removed, got %d", rec.Code)
+	}
+}
@@ -4,14 +4,27 @@
 package defaultexts
 
 import (
-	// import all datastore/log/mq modules for runtime config
+	// import all datastore/log/mq/audit modules for runtime config
 	_ "github.com/fnproject/fn/api/agent/drivers/docker"
+	_ "github.com/fnproject/fn/api/audit/file"
+	_ "github.com/fnproject/fn/api/audit/kafkarest"
+	_ "github.com/fnproject/fn/api/audit/webhook"
+	_ "github.com/fnproject/fn/api/datastore/cassandra"
+	_ "github.com/fnproject/fn/api/datastore/etcd"
+	_ "github.com/fnproject/fn/api/datastore/redis"
 	_ "github.com/fnproject/fn/api/datastore/sql"
 	_ "github.com/fnproject/fn/api/datastore/sql/mysql"
 	_ "github.com/fnproject/fn/api/datastore/sql/postgres"
 	_ "github.com/fnproject/fn/api/datastore/sql/sqlite"
+	_ "github.com/fnproject/fn/api/logs/azure"
+	_ "github.com/fnproject/fn/api/logs/elasticsearch"
+	_ "github.com/fnproject/fn/api/logs/gcs"
+	_ "github.com/fnproject/fn/api/logs/kafkarest"
+	_ "github.com/fnproject/fn/api/logs/loki"
 	_ "github.com/fnproject/fn/api/logs/s3"
 	_ "github.com/fnproject/fn/api/mqs/bolt"
+	_ "github.com/fnproject/fn/api/mqs/kafkarest"
 	_ "github.com/fnproject/fn/api/mqs/memory"
 	_ "github.com/fnproject/fn/api/mqs/redis"
+	_ "github.com/fnproject/fn/api/mqs/sqs"
 )
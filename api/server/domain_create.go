@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleDomainCreate maps a hostname, or the paths of a hostname under a prefix, to an app.
+func (s *Server) handleDomainCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	domain := &models.Domain{}
+
+	err := c.BindJSON(domain)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if s.reservedHostname(domain.Hostname) {
+		handleErrorResponse(c, models.ErrDomainHostnameReserved)
+		return
+	}
+
+	if err := s.checkRequestApp(c, domain.AppID, ""); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	domainCreated, err := s.datastore.InsertDomain(ctx, domain)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domainCreated)
+}
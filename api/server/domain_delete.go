@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleDomainDelete unmaps a domain, requests to it are routed normally once the caches of the
+// routing nodes expire.
+func (s *Server) handleDomainDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.datastore.RemoveDomain(ctx, c.Param(api.DomainID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleDomainList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.DomainFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.AppID = c.Query("app_id")
+	filter.Hostname = c.Query("hostname")
+
+	domains, err := s.datastore.GetDomains(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domains)
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+type ctxRequestURLKey string
+
+// domainReservedPaths are the paths of the endpoints of the server, which requests to custom
+// domains are not routed from, with the paths under them
+var domainReservedPaths = []string{
+	"/v2", "/version", "/health", "/ready", "/live", "/metrics", "/debug", "/networks", "/drain", "/status", "/shutdown",
+}
+
+// WithHostnames adds hostnames to the hostnames of the server, which may not be mapped to apps
+// as custom domains. The hostname of the public load balancer URL and the ACME domains are too.
+func WithHostnames(hostnames ...string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.addHostnames(hostnames...)
+		return nil
+	}
+}
+
+func (s *Server) addHostnames(hostnames ...string) {
+	if s.hostnames == nil {
+		s.hostnames = make(map[string]bool)
+	}
+	for _, h := range hostnames {
+		if h = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), "."); h != "" {
+			s.hostnames[h] = true
+		}
+	}
+}
+
+// reservedHostname returns true if hostname is one of the server, not to be routed to apps
+func (s *Server) reservedHostname(hostname string) bool {
+	return hostname == "localhost" || s.hostnames[hostname]
+}
+
+// reservedPath returns true if path is the path of an endpoint of the server
+func reservedPath(path string) bool {
+	for _, p := range domainReservedPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// domainRouter routes the requests to custom domains, see models.Domain, to the HTTP trigger
+// endpoint of their app by rewriting their path, so that they go through the same middleware
+// as the requests to /t. Requests to other hosts are passed through unchanged.
+func (s *Server) domainRouter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if path, ok := s.domainPath(req); ok {
+			ctx := context.WithValue(req.Context(), ctxRequestURLKey("url"), cloneURL(req.URL))
+			req = req.WithContext(ctx)
+			req.URL.Path = path
+			req.URL.RawPath = ""
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// routesDomains returns true if the server serves the HTTP triggers requests to custom domains
+// are routed to.
+func (s *Server) routesDomains() bool {
+	switch s.nodeType {
+	case ServerTypeFull, ServerTypeLB, ServerTypeRunner:
+		return !s.noHTTTPTriggerEndpoint && s.lbReadAccess != nil
+	}
+	return false
+}
+
+// domainPath returns the /t path of a request to a custom domain, false if the request is not
+// to a custom domain.
+func (s *Server) domainPath(req *http.Request) (string, bool) {
+	ctx := req.Context()
+	hostname := requestHostname(req)
+	if hostname == "" || net.ParseIP(hostname) != nil || s.reservedHostname(hostname) || reservedPath(req.URL.Path) {
+		return "", false
+	}
+	domains, err := s.lbReadAccess.GetDomainsByHostname(ctx, hostname)
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithField("hostname", hostname).Error("failed to get domains of request host")
+		return "", false
+	}
+
+	// the domain with the longest matching prefix wins
+	var domain *models.Domain
+	var source string
+	for _, d := range domains {
+		if rest, ok := d.Match(req.URL.Path); ok && (domain == nil || len(d.PathPrefix) > len(domain.PathPrefix)) {
+			domain, source = d, rest
+		}
+	}
+	if domain == nil {
+		return "", false
+	}
+	app, err := s.lbReadAccess.GetAppByID(ctx, domain.AppID)
+	if err != nil {
+		common.Logger(ctx).WithError(err).WithField("hostname", hostname).Error("failed to get app of domain")
+		return "", false
+	}
+	return "/t/" + app.Name + source, true
+}
+
+// requestHostname returns the lowercase hostname of the Host of a request, without port
+func requestHostname(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// requestURLFromContext returns the URL a request was made with if it was routed from a
+// custom domain, nil otherwise.
+func requestURLFromContext(ctx context.Context) *url.URL {
+	u, _ := ctx.Value(ctxRequestURLKey("url")).(*url.URL)
+	return u
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	cl := *u
+	if u.User != nil {
+		user := *u.User
+		cl.User = &user
+	}
+	return &cl
+}
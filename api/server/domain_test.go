@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDomains(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}, {ID: "other_id", Name: "other"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithHostnames("fn.example.com"))
+
+	request := func(method, path, body string, expectedCode int, v interface{}) {
+		_, rec := routerRequest(t, srv.Router, method, path, bytes.NewBufferString(body))
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var domain models.Domain
+	request("POST", "/v2/domains", `{"hostname": "api.example.com", "app_id": "app_id"}`, http.StatusOK, &domain)
+	if domain.ID == "" || domain.Hostname != "api.example.com" || domain.AppID != "app_id" {
+		t.Fatalf("unexpected domain %+v", domain)
+	}
+	request("POST", "/v2/domains", `{"hostname": "api.example.com", "path_prefix": "/v1", "app_id": "app_id"}`, http.StatusOK, nil)
+	request("POST", "/v2/domains", `{"hostname": "api.example.com", "app_id": "app_id"}`, http.StatusConflict, nil)
+	request("POST", "/v2/domains", `{"hostname": "api.example.com", "path_prefix": "/v2", "app_id": "other_id"}`, http.StatusConflict, nil)
+	request("POST", "/v2/domains", `{"hostname": "not a hostname", "app_id": "app_id"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/domains", `{"hostname": "www.example.com", "app_id": "missing"}`, http.StatusNotFound, nil)
+	// the hostnames of the server can't be mapped to apps
+	request("POST", "/v2/domains", `{"hostname": "fn.example.com", "app_id": "app_id"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/domains", `{"hostname": "localhost", "app_id": "app_id"}`, http.StatusBadRequest, nil)
+
+	var got models.Domain
+	request("GET", "/v2/domains/"+domain.ID, "", http.StatusOK, &got)
+	if got.ID != domain.ID || got.Hostname != domain.Hostname {
+		t.Fatalf("expected %+v, got %+v", domain, got)
+	}
+
+	var domains models.DomainList
+	request("GET", "/v2/domains?hostname=api.example.com", "", http.StatusOK, &domains)
+	if len(domains.Items) != 2 {
+		t.Fatalf("expected both domains of the hostname, got %+v", domains.Items)
+	}
+	request("GET", "/v2/domains?app_id=other_id", "", http.StatusOK, &domains)
+	if len(domains.Items) != 0 {
+		t.Fatalf("expected no domains of the other app, got %+v", domains.Items)
+	}
+
+	request("DELETE", "/v2/domains/"+domain.ID, "", http.StatusNoContent, nil)
+	request("GET", "/v2/domains/"+domain.ID, "", http.StatusNotFound, nil)
+	request("DELETE", "/v2/domains/"+domain.ID, "", http.StatusNotFound, nil)
+}
+
+func TestDomainRouter(t *testing.T) {
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}, {ID: "other_id", Name: "other"}})
+	s := &Server{lbReadAccess: agent.NewCachedDataAccess(ds), hostnames: map[string]bool{"fn.example.com": true}}
+	for _, domain := range []*models.Domain{
+		{Hostname: "api.example.com", AppID: "app_id"},
+		{Hostname: "fn.example.com", AppID: "app_id"},
+		{Hostname: "api.example.com", PathPrefix: "/v1", AppID: "app_id"},
+		{Hostname: "other.example.com", PathPrefix: "/other", AppID: "other_id"},
+	} {
+		if _, err := ds.InsertDomain(context.Background(), domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine := gin.New()
+	route := func(c *gin.Context) {
+		c.String(http.StatusOK, "%s %s %s", c.Param(api.AppName), c.Param(api.TriggerSource), reqURL(c.Request))
+	}
+	engine.Any("/t/:app_name", route)
+	engine.Any("/t/:app_name/*trigger_source", route)
+	engine.NoRoute(func(c *gin.Context) { c.String(http.StatusNotFound, c.Request.URL.Path) })
+	handler := s.domainRouter(engine)
+
+	for i, test := range []struct {
+		url      string
+		code     int
+		expected string
+	}{
+		{"http://api.example.com/hello?a=b", http.StatusOK, "myapp /hello http://api.example.com/hello?a=b"},
+		{"http://API.example.com:8080/", http.StatusOK, "myapp / http://API.example.com:8080/"},
+		// the longest prefix wins and is not part of the trigger source
+		{"http://api.example.com/v1/hello", http.StatusOK, "myapp /hello http://api.example.com/v1/hello"},
+		{"http://api.example.com/v1", http.StatusOK, "myapp / http://api.example.com/v1"},
+		{"http://api.example.com/v10", http.StatusOK, "myapp /v10 http://api.example.com/v10"},
+		{"http://other.example.com/other/hello", http.StatusOK, "other /hello http://other.example.com/other/hello"},
+		// paths outside of the prefixes and other hosts are not routed
+		{"http://other.example.com/hello", http.StatusNotFound, "/hello"},
+		{"http://localhost:8080/v2/apps", http.StatusNotFound, "/v2/apps"},
+		{"http://localhost:8080/t/myapp/hello", http.StatusOK, "myapp /hello http://localhost:8080/t/myapp/hello"},
+		// nor are the endpoints of the server and its hostnames
+		{"http://api.example.com/v2/apps", http.StatusNotFound, "/v2/apps"},
+		{"http://api.example.com/version", http.StatusNotFound, "/version"},
+		{"http://api.example.com/versions", http.StatusOK, "myapp /versions http://api.example.com/versions"},
+		{"http://fn.example.com/hello", http.StatusNotFound, "/hello"},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+		if rec.Code != test.code || rec.Body.String() != test.expected {
+			t.Errorf("test %d: expected %d %q, got %d %q", i, test.code, test.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
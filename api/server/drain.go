@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DrainHeader is set on the responses of calls rejected by a draining server, so that
+// load balancers can take the server out of rotation and retry calls elsewhere.
+const DrainHeader = "Fn-Draining"
+
+// What becomes of the calls in flight at the end of the drain timeout, see EnvDrainLongCalls
+const (
+	DrainLongCallsCancel = "cancel"
+	DrainLongCallsWait   = "wait"
+)
+
+// drainSetup adds /drain, which drains the agent and then shuts down the server. Only admins
+// may drain the server once requests are authenticated.
+func (s *Server) drainSetup(router *gin.Engine) {
+	ctx, start := context.WithCancel(context.Background())
+	s.extraCtxs = append(s.extraCtxs, ctx)
+
+	drain := router.Group("/drain")
+	if s.authEnabled() {
+		drain.Use(s.authenticate(models.APIKeyScopeManage), s.requireUnrestrictedAPIKey)
+		if s.rbac {
+			drain.Use(s.authorize(models.PermissionAdmin))
+		}
+	}
+	drain.POST("", handleDrain(start))
+}
+
+func handleDrain(start context.CancelFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start()
+		c.JSON(http.StatusAccepted, "draining")
+	}
+}
+
+// drainContext returns a context that expires after the drain timeout, if any
+func (s *Server) drainContext() (context.Context, context.CancelFunc) {
+	if s.drainTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.drainTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// isDraining returns true once the server started draining
+func (s *Server) isDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// drain reports the server draining, keeps taking calls for the drain delay, then stops the
+// agent from accepting new calls and waits for the calls in flight to complete. Calls still
+// in flight after the drain timeout are cancelled, unless long calls are waited for.
+func (s *Server) drain() {
+	atomic.StoreInt32(&s.draining, 1)
+	if s.drainDelay > 0 {
+		logrus.WithField("delay", s.drainDelay).Info("draining, taking calls until load balancers stop sending them")
+		time.Sleep(s.drainDelay)
+	}
+
+	drainer, ok := s.agent.(agent.Drainer)
+	if !ok {
+		return
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if s.drainLongCalls == DrainLongCallsWait {
+		// calls waited for end within their own timeout
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = s.drainContext()
+	}
+	defer cancel()
+
+	start := time.Now()
+	if err := drainer.Drain(ctx); err != nil {
+		logrus.WithError(err).Warn("agent drain timed out")
+		return
+	}
+	logrus.WithField("duration", time.Since(start)).Info("agent drained")
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDrainAdmin(t *testing.T) {
+	s := &Server{}
+	router := gin.New()
+	s.drainSetup(router)
+
+	if len(s.extraCtxs) != 1 || s.extraCtxs[0].Err() != nil {
+		t.Fatal("drain should not be started before it is requested")
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/drain", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if s.extraCtxs[0].Err() == nil {
+		t.Fatal("drain request should stop the server")
+	}
+}
+
+func TestDrainAuthentication(t *testing.T) {
+	s := &Server{apiKeys: true, adminAPIKeyHash: models.HashAPIKeySecret(testAdminAPIKey)}
+	router := gin.New()
+	s.drainSetup(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/drain", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnauthorized, rec.Code, rec.Body.String())
+	}
+	if s.extraCtxs[0].Err() != nil {
+		t.Fatal("unauthenticated drain request should not stop the server")
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/drain", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminAPIKey)
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if s.extraCtxs[0].Err() == nil {
+		t.Fatal("admin drain request should stop the server")
+	}
+}
+
+func TestDrainErrorResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HandleErrorResponse(context.Background(), rec, models.ErrAgentDraining)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if rec.Header().Get(DrainHeader) != "true" {
+		t.Fatalf("expected %s header, got %v", DrainHeader, rec.Header())
+	}
+}
+
+// drainAgent is an agent whose calls in flight take 100ms to complete
+type drainAgent struct {
+	agent.Agent
+	drained chan error
+}
+
+func (a *drainAgent) Drain(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		a.drained <- ctx.Err()
+		return ctx.Err()
+	case <-time.After(100 * time.Millisecond):
+		a.drained <- nil
+		return nil
+	}
+}
+
+func TestDrainLongCalls(t *testing.T) {
+	for _, test := range []struct {
+		policy   string
+		expected error
+	}{
+		{DrainLongCallsCancel, context.DeadlineExceeded},
+		{DrainLongCallsWait, nil},
+	} {
+		a := &drainAgent{drained: make(chan error, 1)}
+		s := &Server{agent: a}
+		for _, opt := range []Option{WithDrainTimeout(10 * time.Millisecond), WithDrainLongCalls(test.policy)} {
+			if err := opt(context.Background(), s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.drain()
+		if err := <-a.drained; err != test.expected {
+			t.Fatalf("%s: expected the drain to end with %v, got %v", test.policy, test.expected, err)
+		}
+	}
+
+	if err := WithDrainLongCalls("kill")(context.Background(), &Server{}); err == nil {
+		t.Fatal("expected an invalid policy to be rejected")
+	}
+}
+
+func TestDrainDelay(t *testing.T) {
+	s := &Server{drainDelay: 100 * time.Millisecond}
+	router := gin.New()
+	s.healthSetup(router)
+
+	done := make(chan struct{})
+	go func() {
+		s.drain()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// calls are still taken, but the node is no longer ready
+	select {
+	case <-done:
+		t.Fatal("expected the drain to wait for the drain delay")
+	default:
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), models.HealthDraining) {
+		t.Fatalf("expected a draining node not ready, got %d: %s", rec.Code, rec.Body.String())
+	}
+	<-done
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
@@ -16,6 +17,14 @@ import (
 // ErrInternalServerError returned when something exceptional happens.
 var ErrInternalServerError = errors.New("internal server error")
 
+// Headers describing the load of the server on the responses of calls it rejected, so that
+// load balancers and placers can spread calls to other servers, see models.Backpressure.
+const (
+	InFlightHeader    = "Fn-In-Flight"
+	MaxInFlightHeader = "Fn-Max-In-Flight"
+	QueueDepthHeader  = "Fn-Queue-Depth"
+)
+
 func simpleError(err error) *models.Error {
 	return &models.Error{Message: err.Error()}
 }
@@ -27,6 +36,14 @@ func handleErrorResponse(c *gin.Context, err error) {
 // HandleErrorResponse used to handle response errors in the same way.
 func HandleErrorResponse(ctx context.Context, w http.ResponseWriter, err error) {
 	log := common.Logger(ctx)
+
+	// backpressure only adds details to the response of the error it wraps
+	var bp *models.Backpressure
+	if e, ok := err.(*models.BackpressureError); ok {
+		bp = &e.Backpressure
+		err = e.APIError
+	}
+
 	if w, ok := err.(models.APIErrorWrapper); ok {
 		log = log.WithField("root_error", w.RootError())
 	}
@@ -46,6 +63,14 @@ func HandleErrorResponse(ctx context.Context, w http.ResponseWriter, err error)
 			// TODO: Determine a better delay value here (perhaps ask Agent). For now 15 secs with
 			// the hopes that fnlb will land this on a better server immediately.
 			w.Header().Set("Retry-After", "15")
+		} else if err == models.ErrAgentDraining {
+			w.Header().Set(DrainHeader, "true")
+		} else if err == models.ErrAppQuotaExceeded {
+			// app quotas free up as soon as one of the calls of the app in flight completes
+			w.Header().Set("Retry-After", "1")
+		}
+		if bp != nil {
+			setBackpressureHeaders(w.Header(), bp)
 		}
 		statuscode = e.Code()
 	} else {
@@ -53,15 +78,33 @@ func HandleErrorResponse(ctx context.Context, w http.ResponseWriter, err error)
 		statuscode = http.StatusInternalServerError
 		err = ErrInternalServerError
 	}
-	WriteError(ctx, w, statuscode, err)
+	body := simpleError(err)
+	body.Backpressure = bp
+	if e, ok := err.(*models.RequestValidationError); ok {
+		body.Errors = e.Errors
+	}
+	writeError(ctx, w, statuscode, body)
+}
+
+func setBackpressureHeaders(h http.Header, bp *models.Backpressure) {
+	h.Set("Retry-After", strconv.FormatInt(bp.RetryAfter, 10))
+	h.Set(InFlightHeader, strconv.FormatUint(bp.InFlight, 10))
+	if bp.MaxInFlight != 0 {
+		h.Set(MaxInFlightHeader, strconv.FormatUint(bp.MaxInFlight, 10))
+	}
+	h.Set(QueueDepthHeader, strconv.FormatUint(bp.QueueDepth, 10))
 }
 
 // WriteError easy way to do standard error response, but can set statuscode and error message easier than handleV1ErrorResponse
 func WriteError(ctx context.Context, w http.ResponseWriter, statuscode int, err error) {
+	writeError(ctx, w, statuscode, simpleError(err))
+}
+
+func writeError(ctx context.Context, w http.ResponseWriter, statuscode int, body *models.Error) {
 	log := common.Logger(ctx)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(statuscode)
-	err = json.NewEncoder(w).Encode(simpleError(err))
+	err := json.NewEncoder(w).Encode(body)
 	if err != nil {
 		log.WithError(err).Errorln("error encoding error json")
 	}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ifMatch makes the update or deletion of the app, fn or trigger of the request conditional on
+// its If-Match header, see models.WithIfMatch. Writes of a resource changed since it was
+// read fail with 412 rather than overwriting the change. "*" matches any ETag.
+func ifMatch(c *gin.Context) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return
+	}
+
+	etags := []string{}
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" {
+			return
+		}
+		if etag != "" {
+			etags = append(etags, etag)
+		}
+	}
+	c.Request = c.Request.WithContext(models.WithIfMatch(c.Request.Context(), etags))
+}
+
+// setETag sets the ETag header of the response of an app, fn or trigger as stored, before it
+// is annotated
+func setETag(c *gin.Context, resource interface{}) {
+	c.Header("ETag", models.ETag(resource))
+}
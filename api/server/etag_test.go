@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestIfMatch(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	fn := &models.Fn{ID: "fn_id", Name: "myfn", AppID: app.ID, Image: "fnproject/fn-test-utils"}
+	fn.SetDefaults()
+	trigger := &models.Trigger{ID: "trigger_id", Name: "mytrigger", AppID: app.ID, FnID: fn.ID, Type: "http", Source: "/mytrigger"}
+	ds := datastore.NewMockInit([]*models.App{app}, []*models.Fn{fn}, []*models.Trigger{trigger})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	request := func(method, path, body, ifMatch string, expectedCode int) string {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		return rec.Header().Get("ETag")
+	}
+
+	for _, path := range []string{"/v2/apps/app_id", "/v2/fns/fn_id", "/v2/triggers/trigger_id"} {
+		var body string
+		switch path {
+		case "/v2/apps/app_id":
+			body = `{"config": {"A": "1"}}`
+		case "/v2/fns/fn_id":
+			body = `{"image": "fnproject/fn-test-utils:2"}`
+		default:
+			body = `{"source": "/updated"}`
+		}
+
+		etag := request("GET", path, "", "", http.StatusOK)
+		if etag == "" {
+			t.Fatalf("GET %s: expected an ETag", path)
+		}
+		if again := request("GET", path, "", "", http.StatusOK); again != etag {
+			t.Fatalf("GET %s: expected a stable ETag, got %s then %s", path, etag, again)
+		}
+
+		request("PUT", path, body, `"stale"`, http.StatusPreconditionFailed)
+		if current := request("GET", path, "", "", http.StatusOK); current != etag {
+			t.Fatalf("PUT %s: expected a stale update not to be applied", path)
+		}
+
+		updated := request("PUT", path, body, `"stale", `+etag, http.StatusOK)
+		if current := request("GET", path, "", "", http.StatusOK); current == etag || current != updated {
+			t.Fatalf("PUT %s: expected the ETag of the update, got %s then %s", path, updated, current)
+		}
+
+		request("DELETE", path, "", etag, http.StatusPreconditionFailed)
+		request("PUT", path, body, "*", http.StatusOK)
+	}
+
+	request("DELETE", "/v2/triggers/trigger_id", "", request("GET", "/v2/triggers/trigger_id", "", "", http.StatusOK), http.StatusNoContent)
+	request("DELETE", "/v2/fns/fn_id", "", "", http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleFnCanarySet starts a canary of a fn, or changes the weight of its canary. The
+// candidate is an existing revision of the fn, or a new revision made of the image and
+// config of the fn updated with those of the request.
+func (s *Server) handleFnCanarySet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req models.FnCanary
+	err := c.BindJSON(&req)
+	if err != nil {
+		if !models.IsAPIError(err) {
+			err = models.ErrInvalidJSON
+		}
+		handleErrorResponse(c, err)
+		return
+	}
+	if req.IsEmpty() {
+		handleErrorResponse(c, models.ErrFnsInvalidCanaryWeight)
+		return
+	}
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	canary := &models.FnCanary{Weight: req.Weight}
+	switch {
+	case req.Revision > 0:
+		if req.Revision == fn.Revision {
+			handleErrorResponse(c, models.ErrFnsInvalidCanary("The candidate of a canary must not be the current revision"))
+			return
+		}
+		rev, err := s.datastore.GetFnRevision(ctx, fn.ID, req.Revision)
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+		canary.Revision, canary.Image, canary.Config = rev.Revision, rev.Image, rev.Config
+	case req.Image != "" || req.Config != nil:
+		candidate := fn.Clone()
+		candidate.Update(&models.Fn{Image: req.Image, Config: req.Config})
+		canary.Image, canary.Config = candidate.Image, candidate.Config
+	case !fn.Canary.IsEmpty():
+		canary.Revision, canary.Image, canary.Config = fn.Canary.Revision, fn.Canary.Image, fn.Canary.Config
+	default:
+		handleErrorResponse(c, models.ErrFnsNoCanary)
+		return
+	}
+
+	fnUpdated, err := s.datastore.UpdateFn(ctx, &models.Fn{ID: fn.ID, Canary: canary})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fnUpdated)
+}
+
+// handleFnCanaryPromote makes the candidate of the canary of a fn its current revision.
+func (s *Server) handleFnCanaryPromote(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if fn.Canary.IsEmpty() {
+		handleErrorResponse(c, models.ErrFnsNoCanary)
+		return
+	}
+
+	fnUpdated, err := s.datastore.UpdateFn(ctx, fn.PromotePatch())
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fnUpdated)
+}
+
+// handleFnCanaryAbort ends the canary of a fn, all of its calls go to its current revision.
+func (s *Server) handleFnCanaryAbort(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	if fn.Canary.IsEmpty() {
+		handleErrorResponse(c, models.ErrFnsNoCanary)
+		return
+	}
+
+	fnUpdated, err := s.datastore.UpdateFn(ctx, &models.Fn{ID: fn.ID, Canary: &models.FnCanary{}})
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fnUpdated)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestFnCanary(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	fn := &models.Fn{AppID: "app_id", Name: "myfn", Image: "fnproject/hello:1", Config: models.Config{"A": "1"}}
+	fn.SetDefaults()
+	fn, err := ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, path, body string, expectedCode int) *models.Fn {
+		_, rec := routerRequest(t, srv.Router, method, path, bytes.NewBufferString(body))
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if expectedCode != http.StatusOK {
+			return nil
+		}
+		var fn models.Fn
+		if err := json.NewDecoder(rec.Body).Decode(&fn); err != nil {
+			t.Fatal(err)
+		}
+		return &fn
+	}
+	canaryPath := "/v2/fns/" + fn.ID + "/canary"
+
+	request("POST", canaryPath+"/promote", "", http.StatusNotFound)
+	request("POST", canaryPath+"/abort", "", http.StatusNotFound)
+	request("PUT", canaryPath, `{"weight": 10}`, http.StatusNotFound)
+
+	// a new candidate is made of the image and config of the fn updated with those of the canary
+	updated := request("PUT", canaryPath, `{"weight": 10, "image": "fnproject/hello:2", "config": {"B": "2"}}`, http.StatusOK)
+	if updated.Revision != 1 || updated.Image != "fnproject/hello:1" {
+		t.Fatalf("expected the current revision to be kept, got %+v", updated)
+	}
+	expected := &models.FnCanary{Revision: 2, Weight: 10, Image: "fnproject/hello:2", Config: models.Config{"A": "1", "B": "2"}}
+	if !updated.Canary.Equals(expected) {
+		t.Fatalf("expected canary %+v, got %+v", expected, updated.Canary)
+	}
+
+	updated = request("PUT", canaryPath, `{"weight": 50}`, http.StatusOK)
+	if expected.Weight = 50; !updated.Canary.Equals(expected) {
+		t.Fatalf("expected canary %+v, got %+v", expected, updated.Canary)
+	}
+
+	// promoting makes the candidate the current revision
+	updated = request("POST", canaryPath+"/promote", "", http.StatusOK)
+	if updated.Revision != 2 || updated.Image != "fnproject/hello:2" || !updated.Config.Equals(expected.Config) || updated.Canary != nil {
+		t.Fatalf("unexpected promoted fn %+v", updated)
+	}
+
+	// an existing revision can be tried again, and aborted
+	updated = request("PUT", canaryPath, `{"weight": 5, "revision": 1}`, http.StatusOK)
+	expected = &models.FnCanary{Revision: 1, Weight: 5, Image: "fnproject/hello:1", Config: models.Config{"A": "1"}}
+	if !updated.Canary.Equals(expected) {
+		t.Fatalf("expected canary %+v, got %+v", expected, updated.Canary)
+	}
+	updated = request("POST", canaryPath+"/abort", "", http.StatusOK)
+	if updated.Revision != 2 || updated.Image != "fnproject/hello:2" || updated.Canary != nil {
+		t.Fatalf("unexpected aborted fn %+v", updated)
+	}
+	if revs, _ := ds.GetFnRevisions(ctx, &models.FnRevisionFilter{FnID: fn.ID}); len(revs.Items) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revs.Items))
+	}
+
+	request("PUT", canaryPath, `{"weight": 100, "image": "fnproject/hello:3"}`, http.StatusBadRequest)
+	request("PUT", canaryPath, `{"weight": 0, "image": "fnproject/hello:3"}`, http.StatusBadRequest)
+	request("PUT", canaryPath, `{"weight": 10, "revision": 2}`, http.StatusBadRequest)
+	request("PUT", canaryPath, `{"weight": 10, "revision": 3}`, http.StatusNotFound)
+	request("PUT", canaryPath, `{"weight":`, http.StatusBadRequest)
+	request("PUT", "/v2/fns/nope/canary", `{"weight": 10, "image": "fnproject/hello:3"}`, http.StatusNotFound)
+}
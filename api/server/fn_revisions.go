@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleFnRevisionList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fnID := c.Param(api.FnID)
+
+	// 404 for revisions of fns that don't exist, rather than an empty list
+	if _, err := s.datastore.GetFnByID(ctx, fnID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := &models.FnRevisionFilter{FnID: fnID}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	revisions, err := s.datastore.GetFnRevisions(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (s *Server) handleFnRevisionGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	revision, err := revisionParam(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	rev, err := s.datastore.GetFnRevision(ctx, c.Param(api.FnID), revision)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// handleFnRollback restores the image and config of a fn to those of one of its revisions.
+// This makes a new revision of the fn, unless it already has this image and config.
+func (s *Server) handleFnRollback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	revision, err := revisionParam(c)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	rev, err := s.datastore.GetFnRevision(ctx, fn.ID, revision)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fnUpdated, err := s.datastore.UpdateFn(ctx, fn.RollbackPatch(rev))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fnUpdated)
+}
+
+func revisionParam(c *gin.Context) (int64, error) {
+	revision, err := strconv.ParseInt(c.Param(api.FnRevision), 10, 64)
+	if err != nil || revision < 1 {
+		return 0, models.ErrFnRevisionInvalid
+	}
+	return revision, nil
+}
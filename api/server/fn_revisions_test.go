@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestFnRevisions(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ctx := context.Background()
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	fn := &models.Fn{AppID: "app_id", Name: "myfn", Image: "fnproject/hello:1", Config: models.Config{"A": "1"}}
+	fn.SetDefaults()
+	fn, err := ds.InsertFn(ctx, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, patch := range []*models.Fn{
+		{ID: fn.ID, Image: "fnproject/hello:2"},
+		{ID: fn.ID, Config: models.Config{"A": "", "B": "2"}},
+	} {
+		if _, err := ds.UpdateFn(ctx, patch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := func(method, path string, expectedCode int, v interface{}) {
+		_, rec := routerRequest(t, srv.Router, method, path, bytes.NewBuffer(nil))
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	revisionsPath := "/v2/fns/" + fn.ID + "/revisions"
+
+	var list models.FnRevisionList
+	request("GET", revisionsPath+"?per_page=2", http.StatusOK, &list)
+	if len(list.Items) != 2 || list.Items[0].Revision != 3 || list.Items[1].Revision != 2 || list.NextCursor == "" {
+		t.Fatalf("expected revisions 3 and 2, got %+v", list)
+	}
+
+	var rev models.FnRevision
+	request("GET", revisionsPath+"/1", http.StatusOK, &rev)
+	if rev.Image != "fnproject/hello:1" || rev.Config["A"] != "1" {
+		t.Fatalf("unexpected revision %+v", rev)
+	}
+
+	// rolling back restores the image and config as a new revision
+	var rolledBack models.Fn
+	request("POST", revisionsPath+"/1/rollback", http.StatusOK, &rolledBack)
+	if rolledBack.Revision != 4 || rolledBack.Image != "fnproject/hello:1" || !rolledBack.Config.Equals(models.Config{"A": "1"}) {
+		t.Fatalf("unexpected rolled back fn %+v", rolledBack)
+	}
+	request("GET", revisionsPath, http.StatusOK, &list)
+	if len(list.Items) != 4 {
+		t.Fatalf("expected 4 revisions, got %d", len(list.Items))
+	}
+
+	request("GET", revisionsPath+"/5", http.StatusNotFound, nil)
+	request("GET", revisionsPath+"/0", http.StatusBadRequest, nil)
+	request("POST", revisionsPath+"/abc/rollback", http.StatusBadRequest, nil)
+	request("POST", revisionsPath+"/5/rollback", http.StatusNotFound, nil)
+	request("GET", "/v2/fns/nope/revisions", http.StatusNotFound, nil)
+}
@@ -22,6 +22,12 @@ func (s *Server) handleFnCreate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkRequestApp(c, fn.AppID, ""); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fn.Canary = nil // set with the canary endpoints
 	fn.SetDefaults()
 	fnCreated, err := s.datastore.InsertFn(ctx, fn)
 	if err != nil {
@@ -1,18 +1,26 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/fnproject/fn/api"
 	"github.com/gin-gonic/gin"
 )
 
+// handleFnDelete deletes a fn, it is only marked deleted until the retention of deleted
+// resources expires if one is set, see EnvDeleteRetention.
 func (s *Server) handleFnDelete(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	fnID := c.Param(api.FnID)
 
-	err := s.datastore.RemoveFn(ctx, fnID)
+	var err error
+	if s.deleteRetention > 0 {
+		err = s.datastore.SoftDeleteFn(ctx, fnID)
+	} else {
+		err = s.datastore.RemoveFn(ctx, fnID)
+	}
 	if err != nil {
 		handleErrorResponse(c, err)
 		return
@@ -20,3 +28,28 @@ func (s *Server) handleFnDelete(c *gin.Context) {
 
 	c.String(http.StatusNoContent, "")
 }
+
+// handleFnRestore restores a fn marked deleted, its app must not be deleted.
+func (s *Server) handleFnRestore(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fn, err := s.datastore.RestoreFn(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	app, err := s.datastore.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		handleErrorResponse(c, fmt.Errorf("unexpected error - fn app not available: %s", err))
+		return
+	}
+
+	fn, err = s.fnAnnotator.AnnotateFn(c, app, fn)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fn)
+}
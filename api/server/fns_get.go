@@ -23,6 +23,7 @@ func (s *Server) handleFnGet(c *gin.Context) {
 		return
 	}
 
+	setETag(c, f)
 	f, err = s.fnAnnotator.AnnotateFn(c, app, f)
 	if err != nil {
 		handleErrorResponse(c, err)
@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
@@ -15,6 +16,25 @@ func (s *Server) handleFnList(c *gin.Context) {
 	filter.Cursor, filter.PerPage = pageParams(c)
 	filter.AppID = c.Query("app_id")
 	filter.Name = c.Query("name")
+	filter.NamePrefix = c.Query("name_prefix")
+	filter.Deleted, _ = strconv.ParseBool(c.Query("deleted"))
+
+	var err error
+	filter.Annotations, err = models.ParseKeyFilters(c.QueryArray("annotation"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	filter.Config, err = models.ParseKeyFilters(c.QueryArray("config"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	filter.Order, err = models.ParseListOrder(c.Query("order_by"), c.Query("order"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 
 	fns, err := s.datastore.GetFns(ctx, &filter)
 	if err != nil {
@@ -22,6 +42,12 @@ func (s *Server) handleFnList(c *gin.Context) {
 		return
 	}
 
+	// deleted fns cannot be invoked, nor may their app still exist
+	if filter.Deleted {
+		c.JSON(http.StatusOK, fns)
+		return
+	}
+
 	// Annotate the outbound fns
 
 	// this is fairly cludgy bit hard to do in datastore middleware confidently
@@ -99,10 +99,16 @@ func TestFnCreate(t *testing.T) {
 		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "timeout": 3601 }`, a.ID), http.StatusBadRequest, models.ErrFnsInvalidTimeout},
 		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "idle_timeout": 3601 }`, a.ID), http.StatusBadRequest, models.ErrFnsInvalidIdleTimeout},
 		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "memory": 100000000000000 }`, a.ID), http.StatusBadRequest, models.ErrInvalidMemory},
+		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "min_ready": 33 }`, a.ID), http.StatusBadRequest, models.ErrFnsInvalidMinReady},
+		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "min_ready": 2, "max_containers": 1 }`, a.ID), http.StatusBadRequest, models.ErrFnsInvalidMaxContainers},
+		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "scale_policy": { "freeze_delay_ms": -2 } }`, a.ID), http.StatusBadRequest, models.ErrFnsInvalidScalePolicy(fmt.Sprintf("freeze_delay_ms value is out of range, must be -1 or between 0 and %d", models.MaxFreezeDelay))},
+		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "a", "image": "fnproject/fn-test-utils", "annotations": { "fnproject.io/priority": "urgent" } }`, a.ID), http.StatusBadRequest, models.ErrInvalidPriority},
 
 		// success create & update
 		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "myfunc", "image": "fnproject/fn-test-utils" }`, a.ID), http.StatusOK, nil},
 		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "myfunc", "image": "fnproject/fn-test-utils" }`, a.ID), http.StatusConflict, models.ErrFnsExists},
+		// max_containers 0 does not limit the containers of the fn
+		{ds, ls, http.MethodPost, "/v2/fns", fmt.Sprintf(`{ "app_id": "%s", "name": "unbounded", "image": "fnproject/fn-test-utils", "min_ready": 2, "max_containers": 0 }`, a.ID), http.StatusOK, nil},
 	} {
 		test.run(t, i, buf)
 	}
@@ -21,6 +21,13 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkRequestApp(c, fn.AppID, ""); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	fn.Canary = nil // set with the canary endpoints
+
 	pathFnID := c.Param(api.FnID)
 
 	if fn.ID == "" {
@@ -37,5 +44,6 @@ func (s *Server) handleFnUpdate(c *gin.Context) {
 		return
 	}
 
+	setETag(c, fnUpdated)
 	c.JSON(http.StatusOK, fnUpdated)
 }
@@ -57,6 +57,7 @@ func optionalCorsWrap(r *gin.Engine) {
 		}
 
 		corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "HEAD", "DELETE"}
+		corsConfig.ExposeHeaders = []string{"ETag"}
 
 		logrus.Infof("CORS enabled for domains: %s", origins)
 
@@ -192,6 +193,8 @@ func panicWrap(c *gin.Context) {
 
 func loggerWrap(c *gin.Context) {
 	ctx, _ := common.LoggerWithFields(c.Request.Context(), extractFields(c))
+	// requests read their own writes, see common.WithWriteTracking
+	ctx = common.WithWriteTracking(ctx)
 
 	if appName := c.Param(api.AppName); appName != "" {
 		c.Set(api.AppName, appName)
@@ -0,0 +1,365 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: invoke.proto
+
+package invoke
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Header of an invocation or of its response
+type Header struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Header) Reset()         { *m = Header{} }
+func (m *Header) String() string { return proto.CompactTextString(m) }
+func (*Header) ProtoMessage()    {}
+func (*Header) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2156226f9a4f30f8, []int{0}
+}
+
+func (m *Header) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Header.Unmarshal(m, b)
+}
+func (m *Header) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Header.Marshal(b, m, deterministic)
+}
+func (m *Header) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Header.Merge(m, src)
+}
+func (m *Header) XXX_Size() int {
+	return xxx_messageInfo_Header.Size(m)
+}
+func (m *Header) XXX_DiscardUnknown() {
+	xxx_messageInfo_Header.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Header proto.InternalMessageInfo
+
+func (m *Header) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Header) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+// Invocation of a function. When streamed, the first message names the
+// function and holds the headers, the payload is the concatenation of the
+// body of all messages.
+type InvokeRequest struct {
+	FnId                 string    `protobuf:"bytes,1,opt,name=fn_id,json=fnId,proto3" json:"fn_id,omitempty"`
+	Headers              []*Header `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty"`
+	Body                 []byte    `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *InvokeRequest) Reset()         { *m = InvokeRequest{} }
+func (m *InvokeRequest) String() string { return proto.CompactTextString(m) }
+func (*InvokeRequest) ProtoMessage()    {}
+func (*InvokeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2156226f9a4f30f8, []int{1}
+}
+
+func (m *InvokeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InvokeRequest.Unmarshal(m, b)
+}
+func (m *InvokeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InvokeRequest.Marshal(b, m, deterministic)
+}
+func (m *InvokeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvokeRequest.Merge(m, src)
+}
+func (m *InvokeRequest) XXX_Size() int {
+	return xxx_messageInfo_InvokeRequest.Size(m)
+}
+func (m *InvokeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvokeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvokeRequest proto.InternalMessageInfo
+
+func (m *InvokeRequest) GetFnId() string {
+	if m != nil {
+		return m.FnId
+	}
+	return ""
+}
+
+func (m *InvokeRequest) GetHeaders() []*Header {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *InvokeRequest) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+// Response of a function, status is the HTTP status of the call
+type InvokeResponse struct {
+	CallId               string    `protobuf:"bytes,1,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	Status               int32     `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"`
+	Headers              []*Header `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty"`
+	Body                 []byte    `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *InvokeResponse) Reset()         { *m = InvokeResponse{} }
+func (m *InvokeResponse) String() string { return proto.CompactTextString(m) }
+func (*InvokeResponse) ProtoMessage()    {}
+func (*InvokeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2156226f9a4f30f8, []int{2}
+}
+
+func (m *InvokeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InvokeResponse.Unmarshal(m, b)
+}
+func (m *InvokeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InvokeResponse.Marshal(b, m, deterministic)
+}
+func (m *InvokeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvokeResponse.Merge(m, src)
+}
+func (m *InvokeResponse) XXX_Size() int {
+	return xxx_messageInfo_InvokeResponse.Size(m)
+}
+func (m *InvokeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvokeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvokeResponse proto.InternalMessageInfo
+
+func (m *InvokeResponse) GetCallId() string {
+	if m != nil {
+		return m.CallId
+	}
+	return ""
+}
+
+func (m *InvokeResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *InvokeResponse) GetHeaders() []*Header {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (m *InvokeResponse) GetBody() []byte {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Header)(nil), "invoke.Header")
+	proto.RegisterType((*InvokeRequest)(nil), "invoke.InvokeRequest")
+	proto.RegisterType((*InvokeResponse)(nil), "invoke.InvokeResponse")
+}
+
+func init() { proto.RegisterFile("invoke.proto", fileDescriptor_2156226f9a4f30f8) }
+
+var fileDescriptor_2156226f9a4f30f8 = []byte{
+	// 246 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0x31, 0x4f, 0xc3, 0x30,
+	0x14, 0x84, 0xe5, 0x26, 0x71, 0xc4, 0x23, 0x54, 0xe8, 0x01, 0x25, 0x62, 0x8a, 0x32, 0x79, 0xaa,
+	0x50, 0x19, 0x18, 0x59, 0xc9, 0x6a, 0x7e, 0x00, 0x72, 0xf0, 0xab, 0xa8, 0x1a, 0xe2, 0x12, 0x3b,
+	0x95, 0x3a, 0x31, 0xf0, 0xc7, 0x51, 0xed, 0x44, 0x01, 0x26, 0xd8, 0xee, 0x2e, 0xba, 0x77, 0x9f,
+	0x62, 0xc8, 0x36, 0xed, 0xde, 0x6c, 0x69, 0xb9, 0xeb, 0x8c, 0x33, 0xc8, 0x83, 0x2b, 0x6f, 0x81,
+	0x3f, 0x92, 0xd2, 0xd4, 0xe1, 0x39, 0x44, 0x5b, 0x3a, 0xe4, 0xac, 0x60, 0xe2, 0x44, 0x1e, 0x25,
+	0x5e, 0x42, 0xb2, 0x57, 0x4d, 0x4f, 0xf9, 0xcc, 0x67, 0xc1, 0x94, 0x35, 0x9c, 0x55, 0xbe, 0x2b,
+	0xe9, 0xbd, 0x27, 0xeb, 0xf0, 0x02, 0x92, 0x75, 0xfb, 0xbc, 0xd1, 0x43, 0x35, 0x5e, 0xb7, 0x95,
+	0x46, 0x01, 0xe9, 0xab, 0xbf, 0x6b, 0xf3, 0x59, 0x11, 0x89, 0xd3, 0xd5, 0x7c, 0x39, 0xec, 0x87,
+	0x39, 0x39, 0x7e, 0x46, 0x84, 0xb8, 0x36, 0xfa, 0x90, 0x47, 0x05, 0x13, 0x99, 0xf4, 0xba, 0xfc,
+	0x80, 0xf9, 0xb8, 0x61, 0x77, 0xa6, 0xb5, 0x84, 0xd7, 0x90, 0xbe, 0xa8, 0xa6, 0x99, 0x66, 0xf8,
+	0xd1, 0x56, 0x1a, 0x17, 0xc0, 0xad, 0x53, 0xae, 0xb7, 0x9e, 0x32, 0x91, 0x83, 0xfb, 0x0e, 0x10,
+	0xfd, 0x0d, 0x20, 0x9e, 0x00, 0x56, 0x9f, 0x0c, 0xd2, 0x40, 0xd0, 0xe1, 0x3d, 0xf0, 0x20, 0xf1,
+	0x6a, 0x3c, 0xf1, 0xe3, 0x07, 0xdc, 0x2c, 0x7e, 0xc7, 0x03, 0xf3, 0x03, 0x64, 0x21, 0x79, 0x72,
+	0x1d, 0xa9, 0xb7, 0x7f, 0xd6, 0x05, 0xab, 0xb9, 0x7f, 0xab, 0xbb, 0xaf, 0x01, 0x00, 0x27, 0xaf,
+	0xcf, 0xda, 0xbb, 0x01, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// InvokerClient is the client API for Invoker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type InvokerClient interface {
+	// Invoke a function with the payload of a single message
+	Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error)
+	// Invoke a function with a payload streamed in several messages
+	InvokeStream(ctx context.Context, opts ...grpc.CallOption) (Invoker_InvokeStreamClient, error)
+}
+
+type invokerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewInvokerClient(cc *grpc.ClientConn) InvokerClient {
+	return &invokerClient{cc}
+}
+
+func (c *invokerClient) Invoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error) {
+	out := new(InvokeResponse)
+	err := c.cc.Invoke(ctx, "/invoke.Invoker/Invoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *invokerClient) InvokeStream(ctx context.Context, opts ...grpc.CallOption) (Invoker_InvokeStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Invoker_serviceDesc.Streams[0], "/invoke.Invoker/InvokeStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &invokerInvokeStreamClient{stream}
+	return x, nil
+}
+
+type Invoker_InvokeStreamClient interface {
+	Send(*InvokeRequest) error
+	CloseAndRecv() (*InvokeResponse, error)
+	grpc.ClientStream
+}
+
+type invokerInvokeStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *invokerInvokeStreamClient) Send(m *InvokeRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *invokerInvokeStreamClient) CloseAndRecv() (*InvokeResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(InvokeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// InvokerServer is the server API for Invoker service.
+type InvokerServer interface {
+	// Invoke a function with the payload of a single message
+	Invoke(context.Context, *InvokeRequest) (*InvokeResponse, error)
+	// Invoke a function with a payload streamed in several messages
+	InvokeStream(Invoker_InvokeStreamServer) error
+}
+
+func RegisterInvokerServer(s *grpc.Server, srv InvokerServer) {
+	s.RegisterService(&_Invoker_serviceDesc, srv)
+}
+
+func _Invoker_Invoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvokerServer).Invoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/invoke.Invoker/Invoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvokerServer).Invoke(ctx, req.(*InvokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Invoker_InvokeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InvokerServer).InvokeStream(&invokerInvokeStreamServer{stream})
+}
+
+type Invoker_InvokeStreamServer interface {
+	SendAndClose(*InvokeResponse) error
+	Recv() (*InvokeRequest, error)
+	grpc.ServerStream
+}
+
+type invokerInvokeStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *invokerInvokeStreamServer) SendAndClose(m *InvokeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *invokerInvokeStreamServer) Recv() (*InvokeRequest, error) {
+	m := new(InvokeRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _Invoker_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "invoke.Invoker",
+	HandlerType: (*InvokerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Invoke",
+			Handler:    _Invoker_Invoke_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "InvokeStream",
+			Handler:       _Invoker_InvokeStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "invoke.proto",
+}
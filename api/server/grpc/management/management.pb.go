@@ -0,0 +1,1752 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: management.proto
+
+package management
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// StringValue wraps a string to tell an empty one from an unset field
+type StringValue struct {
+	Value                string   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StringValue) Reset()         { *m = StringValue{} }
+func (m *StringValue) String() string { return proto.CompactTextString(m) }
+func (*StringValue) ProtoMessage()    {}
+func (*StringValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{0}
+}
+
+func (m *StringValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StringValue.Unmarshal(m, b)
+}
+func (m *StringValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StringValue.Marshal(b, m, deterministic)
+}
+func (m *StringValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StringValue.Merge(m, src)
+}
+func (m *StringValue) XXX_Size() int {
+	return xxx_messageInfo_StringValue.Size(m)
+}
+func (m *StringValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_StringValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StringValue proto.InternalMessageInfo
+
+func (m *StringValue) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+// UInt64Value wraps an integer to tell zero from an unset field
+type UInt64Value struct {
+	Value                uint64   `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UInt64Value) Reset()         { *m = UInt64Value{} }
+func (m *UInt64Value) String() string { return proto.CompactTextString(m) }
+func (*UInt64Value) ProtoMessage()    {}
+func (*UInt64Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{1}
+}
+
+func (m *UInt64Value) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UInt64Value.Unmarshal(m, b)
+}
+func (m *UInt64Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UInt64Value.Marshal(b, m, deterministic)
+}
+func (m *UInt64Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UInt64Value.Merge(m, src)
+}
+func (m *UInt64Value) XXX_Size() int {
+	return xxx_messageInfo_UInt64Value.Size(m)
+}
+func (m *UInt64Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_UInt64Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UInt64Value proto.InternalMessageInfo
+
+func (m *UInt64Value) GetValue() uint64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+// App is an application, as in the REST API. Annotation values are JSON.
+type App struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Config               map[string]string    `protobuf:"bytes,3,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations          map[string][]byte    `protobuf:"bytes,4,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SyslogUrl            *StringValue         `protobuf:"bytes,5,opt,name=syslog_url,json=syslogUrl,proto3" json:"syslog_url,omitempty"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            *timestamp.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *App) Reset()         { *m = App{} }
+func (m *App) String() string { return proto.CompactTextString(m) }
+func (*App) ProtoMessage()    {}
+func (*App) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{2}
+}
+
+func (m *App) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_App.Unmarshal(m, b)
+}
+func (m *App) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_App.Marshal(b, m, deterministic)
+}
+func (m *App) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_App.Merge(m, src)
+}
+func (m *App) XXX_Size() int {
+	return xxx_messageInfo_App.Size(m)
+}
+func (m *App) XXX_DiscardUnknown() {
+	xxx_messageInfo_App.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_App proto.InternalMessageInfo
+
+func (m *App) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *App) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *App) GetConfig() map[string]string {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *App) GetAnnotations() map[string][]byte {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *App) GetSyslogUrl() *StringValue {
+	if m != nil {
+		return m.SyslogUrl
+	}
+	return nil
+}
+
+func (m *App) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *App) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+// Fn is a function, as in the REST API. Annotation values, policies, schemas
+// and the canary are JSON, the canary is only set with the REST API.
+type Fn struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	AppId                string               `protobuf:"bytes,3,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Image                string               `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
+	Memory               uint64               `protobuf:"varint,5,opt,name=memory,proto3" json:"memory,omitempty"`
+	Timeout              int32                `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	IdleTimeout          int32                `protobuf:"varint,7,opt,name=idle_timeout,json=idleTimeout,proto3" json:"idle_timeout,omitempty"`
+	MinReady             *UInt64Value         `protobuf:"bytes,8,opt,name=min_ready,json=minReady,proto3" json:"min_ready,omitempty"`
+	MaxContainers        *UInt64Value         `protobuf:"bytes,9,opt,name=max_containers,json=maxContainers,proto3" json:"max_containers,omitempty"`
+	ScalePolicy          []byte               `protobuf:"bytes,10,opt,name=scale_policy,json=scalePolicy,proto3" json:"scale_policy,omitempty"`
+	RetryPolicy          []byte               `protobuf:"bytes,11,opt,name=retry_policy,json=retryPolicy,proto3" json:"retry_policy,omitempty"`
+	RecyclePolicy        []byte               `protobuf:"bytes,12,opt,name=recycle_policy,json=recyclePolicy,proto3" json:"recycle_policy,omitempty"`
+	RequestSchema        []byte               `protobuf:"bytes,13,opt,name=request_schema,json=requestSchema,proto3" json:"request_schema,omitempty"`
+	ResponseSchema       []byte               `protobuf:"bytes,14,opt,name=response_schema,json=responseSchema,proto3" json:"response_schema,omitempty"`
+	Config               map[string]string    `protobuf:"bytes,15,rep,name=config,proto3" json:"config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Annotations          map[string][]byte    `protobuf:"bytes,16,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Revision             int64                `protobuf:"varint,17,opt,name=revision,proto3" json:"revision,omitempty"`
+	Canary               []byte               `protobuf:"bytes,18,opt,name=canary,proto3" json:"canary,omitempty"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,19,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            *timestamp.Timestamp `protobuf:"bytes,20,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Fn) Reset()         { *m = Fn{} }
+func (m *Fn) String() string { return proto.CompactTextString(m) }
+func (*Fn) ProtoMessage()    {}
+func (*Fn) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{3}
+}
+
+func (m *Fn) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Fn.Unmarshal(m, b)
+}
+func (m *Fn) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Fn.Marshal(b, m, deterministic)
+}
+func (m *Fn) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Fn.Merge(m, src)
+}
+func (m *Fn) XXX_Size() int {
+	return xxx_messageInfo_Fn.Size(m)
+}
+func (m *Fn) XXX_DiscardUnknown() {
+	xxx_messageInfo_Fn.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Fn proto.InternalMessageInfo
+
+func (m *Fn) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Fn) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Fn) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+func (m *Fn) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *Fn) GetMemory() uint64 {
+	if m != nil {
+		return m.Memory
+	}
+	return 0
+}
+
+func (m *Fn) GetTimeout() int32 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *Fn) GetIdleTimeout() int32 {
+	if m != nil {
+		return m.IdleTimeout
+	}
+	return 0
+}
+
+func (m *Fn) GetMinReady() *UInt64Value {
+	if m != nil {
+		return m.MinReady
+	}
+	return nil
+}
+
+func (m *Fn) GetMaxContainers() *UInt64Value {
+	if m != nil {
+		return m.MaxContainers
+	}
+	return nil
+}
+
+func (m *Fn) GetScalePolicy() []byte {
+	if m != nil {
+		return m.ScalePolicy
+	}
+	return nil
+}
+
+func (m *Fn) GetRetryPolicy() []byte {
+	if m != nil {
+		return m.RetryPolicy
+	}
+	return nil
+}
+
+func (m *Fn) GetRecyclePolicy() []byte {
+	if m != nil {
+		return m.RecyclePolicy
+	}
+	return nil
+}
+
+func (m *Fn) GetRequestSchema() []byte {
+	if m != nil {
+		return m.RequestSchema
+	}
+	return nil
+}
+
+func (m *Fn) GetResponseSchema() []byte {
+	if m != nil {
+		return m.ResponseSchema
+	}
+	return nil
+}
+
+func (m *Fn) GetConfig() map[string]string {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *Fn) GetAnnotations() map[string][]byte {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *Fn) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *Fn) GetCanary() []byte {
+	if m != nil {
+		return m.Canary
+	}
+	return nil
+}
+
+func (m *Fn) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Fn) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+// Trigger is a trigger, as in the REST API. Annotation values are JSON.
+type Trigger struct {
+	Id                   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	AppId                string               `protobuf:"bytes,3,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	FnId                 string               `protobuf:"bytes,4,opt,name=fn_id,json=fnId,proto3" json:"fn_id,omitempty"`
+	Type                 string               `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Source               string               `protobuf:"bytes,6,opt,name=source,proto3" json:"source,omitempty"`
+	Annotations          map[string][]byte    `protobuf:"bytes,7,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CreatedAt            *timestamp.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            *timestamp.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Trigger) Reset()         { *m = Trigger{} }
+func (m *Trigger) String() string { return proto.CompactTextString(m) }
+func (*Trigger) ProtoMessage()    {}
+func (*Trigger) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{4}
+}
+
+func (m *Trigger) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Trigger.Unmarshal(m, b)
+}
+func (m *Trigger) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Trigger.Marshal(b, m, deterministic)
+}
+func (m *Trigger) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Trigger.Merge(m, src)
+}
+func (m *Trigger) XXX_Size() int {
+	return xxx_messageInfo_Trigger.Size(m)
+}
+func (m *Trigger) XXX_DiscardUnknown() {
+	xxx_messageInfo_Trigger.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Trigger proto.InternalMessageInfo
+
+func (m *Trigger) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Trigger) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Trigger) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+func (m *Trigger) GetFnId() string {
+	if m != nil {
+		return m.FnId
+	}
+	return ""
+}
+
+func (m *Trigger) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Trigger) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *Trigger) GetAnnotations() map[string][]byte {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *Trigger) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *Trigger) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+type GetAppRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAppRequest) Reset()         { *m = GetAppRequest{} }
+func (m *GetAppRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAppRequest) ProtoMessage()    {}
+func (*GetAppRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{5}
+}
+
+func (m *GetAppRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAppRequest.Unmarshal(m, b)
+}
+func (m *GetAppRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAppRequest.Marshal(b, m, deterministic)
+}
+func (m *GetAppRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAppRequest.Merge(m, src)
+}
+func (m *GetAppRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAppRequest.Size(m)
+}
+func (m *GetAppRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAppRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAppRequest proto.InternalMessageInfo
+
+func (m *GetAppRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteAppRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteAppRequest) Reset()         { *m = DeleteAppRequest{} }
+func (m *DeleteAppRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteAppRequest) ProtoMessage()    {}
+func (*DeleteAppRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{6}
+}
+
+func (m *DeleteAppRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteAppRequest.Unmarshal(m, b)
+}
+func (m *DeleteAppRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteAppRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteAppRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteAppRequest.Merge(m, src)
+}
+func (m *DeleteAppRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteAppRequest.Size(m)
+}
+func (m *DeleteAppRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteAppRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteAppRequest proto.InternalMessageInfo
+
+func (m *DeleteAppRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListAppsRequest filters the apps listed by name or name prefix, from cursor.
+// annotations and config filter them on their keys, as key or key=value.
+// per_page is the number of apps read at once. order_by and order order them
+// by name, created_at or updated_at, asc or desc.
+type ListAppsRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Cursor               string   `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	PerPage              int32    `protobuf:"varint,3,opt,name=per_page,json=perPage,proto3" json:"per_page,omitempty"`
+	NamePrefix           string   `protobuf:"bytes,4,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	Annotations          []string `protobuf:"bytes,5,rep,name=annotations,proto3" json:"annotations,omitempty"`
+	Config               []string `protobuf:"bytes,6,rep,name=config,proto3" json:"config,omitempty"`
+	OrderBy              string   `protobuf:"bytes,7,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Order                string   `protobuf:"bytes,8,opt,name=order,proto3" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListAppsRequest) Reset()         { *m = ListAppsRequest{} }
+func (m *ListAppsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAppsRequest) ProtoMessage()    {}
+func (*ListAppsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{7}
+}
+
+func (m *ListAppsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListAppsRequest.Unmarshal(m, b)
+}
+func (m *ListAppsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListAppsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListAppsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListAppsRequest.Merge(m, src)
+}
+func (m *ListAppsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListAppsRequest.Size(m)
+}
+func (m *ListAppsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListAppsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListAppsRequest proto.InternalMessageInfo
+
+func (m *ListAppsRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ListAppsRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListAppsRequest) GetPerPage() int32 {
+	if m != nil {
+		return m.PerPage
+	}
+	return 0
+}
+
+func (m *ListAppsRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *ListAppsRequest) GetAnnotations() []string {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *ListAppsRequest) GetConfig() []string {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *ListAppsRequest) GetOrderBy() string {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ""
+}
+
+func (m *ListAppsRequest) GetOrder() string {
+	if m != nil {
+		return m.Order
+	}
+	return ""
+}
+
+type GetFnRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetFnRequest) Reset()         { *m = GetFnRequest{} }
+func (m *GetFnRequest) String() string { return proto.CompactTextString(m) }
+func (*GetFnRequest) ProtoMessage()    {}
+func (*GetFnRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{8}
+}
+
+func (m *GetFnRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetFnRequest.Unmarshal(m, b)
+}
+func (m *GetFnRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetFnRequest.Marshal(b, m, deterministic)
+}
+func (m *GetFnRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetFnRequest.Merge(m, src)
+}
+func (m *GetFnRequest) XXX_Size() int {
+	return xxx_messageInfo_GetFnRequest.Size(m)
+}
+func (m *GetFnRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetFnRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetFnRequest proto.InternalMessageInfo
+
+func (m *GetFnRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteFnRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteFnRequest) Reset()         { *m = DeleteFnRequest{} }
+func (m *DeleteFnRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteFnRequest) ProtoMessage()    {}
+func (*DeleteFnRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{9}
+}
+
+func (m *DeleteFnRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteFnRequest.Unmarshal(m, b)
+}
+func (m *DeleteFnRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteFnRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteFnRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteFnRequest.Merge(m, src)
+}
+func (m *DeleteFnRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteFnRequest.Size(m)
+}
+func (m *DeleteFnRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteFnRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteFnRequest proto.InternalMessageInfo
+
+func (m *DeleteFnRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListFnsRequest filters the fns listed by app and name or name prefix, from
+// cursor. annotations and config filter them on their keys, as key or
+// key=value. per_page is the number of fns read at once. order_by and order
+// order them by name, created_at or updated_at, asc or desc.
+type ListFnsRequest struct {
+	AppId                string   `protobuf:"bytes,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Cursor               string   `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	PerPage              int32    `protobuf:"varint,4,opt,name=per_page,json=perPage,proto3" json:"per_page,omitempty"`
+	NamePrefix           string   `protobuf:"bytes,5,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	Annotations          []string `protobuf:"bytes,6,rep,name=annotations,proto3" json:"annotations,omitempty"`
+	Config               []string `protobuf:"bytes,7,rep,name=config,proto3" json:"config,omitempty"`
+	OrderBy              string   `protobuf:"bytes,8,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Order                string   `protobuf:"bytes,9,opt,name=order,proto3" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFnsRequest) Reset()         { *m = ListFnsRequest{} }
+func (m *ListFnsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFnsRequest) ProtoMessage()    {}
+func (*ListFnsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{10}
+}
+
+func (m *ListFnsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFnsRequest.Unmarshal(m, b)
+}
+func (m *ListFnsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFnsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListFnsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFnsRequest.Merge(m, src)
+}
+func (m *ListFnsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFnsRequest.Size(m)
+}
+func (m *ListFnsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFnsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFnsRequest proto.InternalMessageInfo
+
+func (m *ListFnsRequest) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+func (m *ListFnsRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ListFnsRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListFnsRequest) GetPerPage() int32 {
+	if m != nil {
+		return m.PerPage
+	}
+	return 0
+}
+
+func (m *ListFnsRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *ListFnsRequest) GetAnnotations() []string {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *ListFnsRequest) GetConfig() []string {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *ListFnsRequest) GetOrderBy() string {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ""
+}
+
+func (m *ListFnsRequest) GetOrder() string {
+	if m != nil {
+		return m.Order
+	}
+	return ""
+}
+
+type GetTriggerRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetTriggerRequest) Reset()         { *m = GetTriggerRequest{} }
+func (m *GetTriggerRequest) String() string { return proto.CompactTextString(m) }
+func (*GetTriggerRequest) ProtoMessage()    {}
+func (*GetTriggerRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{11}
+}
+
+func (m *GetTriggerRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetTriggerRequest.Unmarshal(m, b)
+}
+func (m *GetTriggerRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetTriggerRequest.Marshal(b, m, deterministic)
+}
+func (m *GetTriggerRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetTriggerRequest.Merge(m, src)
+}
+func (m *GetTriggerRequest) XXX_Size() int {
+	return xxx_messageInfo_GetTriggerRequest.Size(m)
+}
+func (m *GetTriggerRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetTriggerRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetTriggerRequest proto.InternalMessageInfo
+
+func (m *GetTriggerRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteTriggerRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteTriggerRequest) Reset()         { *m = DeleteTriggerRequest{} }
+func (m *DeleteTriggerRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteTriggerRequest) ProtoMessage()    {}
+func (*DeleteTriggerRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{12}
+}
+
+func (m *DeleteTriggerRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteTriggerRequest.Unmarshal(m, b)
+}
+func (m *DeleteTriggerRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteTriggerRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteTriggerRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteTriggerRequest.Merge(m, src)
+}
+func (m *DeleteTriggerRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteTriggerRequest.Size(m)
+}
+func (m *DeleteTriggerRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteTriggerRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteTriggerRequest proto.InternalMessageInfo
+
+func (m *DeleteTriggerRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListTriggersRequest filters the triggers listed by app, fn and name or name
+// prefix, from cursor. annotations filters them on their keys, as key or
+// key=value. per_page is the number of triggers read at once. order_by and
+// order order them by name, created_at or updated_at, asc or desc.
+type ListTriggersRequest struct {
+	AppId                string   `protobuf:"bytes,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	FnId                 string   `protobuf:"bytes,2,opt,name=fn_id,json=fnId,proto3" json:"fn_id,omitempty"`
+	Name                 string   `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Cursor               string   `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	PerPage              int32    `protobuf:"varint,5,opt,name=per_page,json=perPage,proto3" json:"per_page,omitempty"`
+	NamePrefix           string   `protobuf:"bytes,6,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	Annotations          []string `protobuf:"bytes,7,rep,name=annotations,proto3" json:"annotations,omitempty"`
+	OrderBy              string   `protobuf:"bytes,8,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Order                string   `protobuf:"bytes,9,opt,name=order,proto3" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListTriggersRequest) Reset()         { *m = ListTriggersRequest{} }
+func (m *ListTriggersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListTriggersRequest) ProtoMessage()    {}
+func (*ListTriggersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edc174f991dc0a25, []int{13}
+}
+
+func (m *ListTriggersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListTriggersRequest.Unmarshal(m, b)
+}
+func (m *ListTriggersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListTriggersRequest.Marshal(b, m, deterministic)
+}
+func (m *ListTriggersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListTriggersRequest.Merge(m, src)
+}
+func (m *ListTriggersRequest) XXX_Size() int {
+	return xxx_messageInfo_ListTriggersRequest.Size(m)
+}
+func (m *ListTriggersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListTriggersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListTriggersRequest proto.InternalMessageInfo
+
+func (m *ListTriggersRequest) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetFnId() string {
+	if m != nil {
+		return m.FnId
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetPerPage() int32 {
+	if m != nil {
+		return m.PerPage
+	}
+	return 0
+}
+
+func (m *ListTriggersRequest) GetNamePrefix() string {
+	if m != nil {
+		return m.NamePrefix
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetAnnotations() []string {
+	if m != nil {
+		return m.Annotations
+	}
+	return nil
+}
+
+func (m *ListTriggersRequest) GetOrderBy() string {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ""
+}
+
+func (m *ListTriggersRequest) GetOrder() string {
+	if m != nil {
+		return m.Order
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*StringValue)(nil), "management.StringValue")
+	proto.RegisterType((*UInt64Value)(nil), "management.UInt64Value")
+	proto.RegisterType((*App)(nil), "management.App")
+	proto.RegisterMapType((map[string][]byte)(nil), "management.App.AnnotationsEntry")
+	proto.RegisterMapType((map[string]string)(nil), "management.App.ConfigEntry")
+	proto.RegisterType((*Fn)(nil), "management.Fn")
+	proto.RegisterMapType((map[string][]byte)(nil), "management.Fn.AnnotationsEntry")
+	proto.RegisterMapType((map[string]string)(nil), "management.Fn.ConfigEntry")
+	proto.RegisterType((*Trigger)(nil), "management.Trigger")
+	proto.RegisterMapType((map[string][]byte)(nil), "management.Trigger.AnnotationsEntry")
+	proto.RegisterType((*GetAppRequest)(nil), "management.GetAppRequest")
+	proto.RegisterType((*DeleteAppRequest)(nil), "management.DeleteAppRequest")
+	proto.RegisterType((*ListAppsRequest)(nil), "management.ListAppsRequest")
+	proto.RegisterType((*GetFnRequest)(nil), "management.GetFnRequest")
+	proto.RegisterType((*DeleteFnRequest)(nil), "management.DeleteFnRequest")
+	proto.RegisterType((*ListFnsRequest)(nil), "management.ListFnsRequest")
+	proto.RegisterType((*GetTriggerRequest)(nil), "management.GetTriggerRequest")
+	proto.RegisterType((*DeleteTriggerRequest)(nil), "management.DeleteTriggerRequest")
+	proto.RegisterType((*ListTriggersRequest)(nil), "management.ListTriggersRequest")
+}
+
+func init() { proto.RegisterFile("management.proto", fileDescriptor_edc174f991dc0a25) }
+
+var fileDescriptor_edc174f991dc0a25 = []byte{
+	// 1123 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x96, 0x4b, 0x6f, 0xdb, 0x46,
+	0x10, 0xc7, 0x41, 0x51, 0xa2, 0xc4, 0x91, 0x25, 0x3b, 0xeb, 0xd4, 0x65, 0x98, 0xb6, 0x56, 0x9c,
+	0x3e, 0x8c, 0x00, 0x51, 0x5c, 0xc7, 0x48, 0xeb, 0x1c, 0xd2, 0x2a, 0x6e, 0x64, 0x18, 0x68, 0x01,
+	0x83, 0xb1, 0x7b, 0x25, 0x68, 0x69, 0xad, 0x12, 0x15, 0x97, 0xdb, 0xe5, 0x2a, 0x30, 0xcf, 0xfd,
+	0x78, 0xfd, 0x1a, 0xbd, 0xf7, 0xdc, 0xa2, 0x87, 0x62, 0x1f, 0x94, 0x48, 0xea, 0x65, 0x27, 0xa7,
+	0xde, 0x38, 0xb3, 0xff, 0x59, 0xae, 0x7e, 0xb3, 0xf3, 0x17, 0x61, 0x2b, 0x0a, 0x48, 0x30, 0xc2,
+	0x11, 0x26, 0xbc, 0x4b, 0x59, 0xcc, 0x63, 0x04, 0xb3, 0x8c, 0xfb, 0x70, 0x14, 0xc7, 0xa3, 0x31,
+	0x7e, 0x26, 0x57, 0xae, 0x26, 0xd7, 0xcf, 0x70, 0x44, 0x79, 0xaa, 0x84, 0xee, 0x6e, 0x79, 0x91,
+	0x87, 0x11, 0x4e, 0x78, 0x10, 0x51, 0x25, 0xd8, 0x7b, 0x0c, 0xcd, 0xb7, 0x9c, 0x85, 0x64, 0xf4,
+	0x73, 0x30, 0x9e, 0x60, 0x74, 0x1f, 0x6a, 0xef, 0xc4, 0x83, 0x63, 0x74, 0x8c, 0x7d, 0xdb, 0x53,
+	0x81, 0x10, 0x5d, 0x9e, 0x11, 0xfe, 0xe2, 0x68, 0x81, 0xa8, 0x9a, 0x89, 0xfe, 0x30, 0xc1, 0xec,
+	0x51, 0x8a, 0xda, 0x50, 0x09, 0x87, 0xba, 0xbe, 0x12, 0x0e, 0x11, 0x82, 0x2a, 0x09, 0x22, 0xec,
+	0x54, 0x64, 0x46, 0x3e, 0xa3, 0xe7, 0x60, 0x0d, 0x62, 0x72, 0x1d, 0x8e, 0x1c, 0xb3, 0x63, 0xee,
+	0x37, 0x0f, 0x1f, 0x76, 0x73, 0x3f, 0xb1, 0x47, 0x69, 0xf7, 0x44, 0xae, 0xbe, 0x21, 0x9c, 0xa5,
+	0x9e, 0x96, 0xa2, 0xd7, 0xd0, 0x0c, 0x08, 0x89, 0x79, 0xc0, 0xc3, 0x98, 0x24, 0x4e, 0x55, 0x56,
+	0x76, 0xca, 0x95, 0xbd, 0x99, 0x44, 0x95, 0xe7, 0x8b, 0xd0, 0x0b, 0x80, 0x24, 0x4d, 0xc6, 0xf1,
+	0xc8, 0x9f, 0xb0, 0xb1, 0x53, 0xeb, 0x18, 0xfb, 0xcd, 0xc3, 0x8f, 0xf3, 0x5b, 0xe4, 0x60, 0x78,
+	0xb6, 0x92, 0x5e, 0xb2, 0x31, 0x3a, 0x06, 0x18, 0x30, 0x1c, 0x70, 0x3c, 0xf4, 0x03, 0xee, 0x58,
+	0xb2, 0xce, 0xed, 0x2a, 0xb8, 0xdd, 0x0c, 0x6e, 0xf7, 0x22, 0x83, 0xeb, 0xd9, 0x5a, 0xdd, 0xe3,
+	0xa2, 0x74, 0x42, 0x87, 0x59, 0x69, 0x7d, 0x7d, 0xa9, 0x56, 0xf7, 0xb8, 0x7b, 0x0c, 0xcd, 0x1c,
+	0x08, 0xb4, 0x05, 0xe6, 0xaf, 0x38, 0xd5, 0x68, 0xc5, 0xe3, 0xac, 0x13, 0x95, 0x5c, 0xbb, 0x5e,
+	0x56, 0xbe, 0x35, 0xdc, 0x57, 0xb0, 0x55, 0x26, 0xb1, 0xae, 0x7e, 0x23, 0x57, 0xbf, 0xf7, 0xb7,
+	0x05, 0x95, 0x3e, 0xb9, 0x55, 0x33, 0x3f, 0x02, 0x2b, 0xa0, 0xd4, 0x0f, 0x87, 0x8e, 0xa9, 0x4e,
+	0x11, 0x50, 0x7a, 0x36, 0x14, 0x7b, 0x87, 0x51, 0x30, 0xc2, 0x4e, 0x55, 0x65, 0x65, 0x80, 0x76,
+	0xc0, 0x8a, 0x70, 0x14, 0xb3, 0x54, 0xc2, 0xaf, 0x7a, 0x3a, 0x42, 0x0e, 0xd4, 0xc5, 0xd5, 0x8c,
+	0x27, 0x8a, 0x6e, 0xcd, 0xcb, 0x42, 0xf4, 0x08, 0x36, 0xc2, 0xe1, 0x18, 0xfb, 0xd9, 0x72, 0x5d,
+	0x2e, 0x37, 0x45, 0xee, 0x42, 0x4b, 0x8e, 0xc0, 0x8e, 0x42, 0xe2, 0x33, 0x1c, 0x0c, 0x53, 0xa7,
+	0x31, 0xdf, 0xd4, 0xdc, 0xe5, 0xf5, 0x1a, 0x51, 0x48, 0x3c, 0x21, 0x44, 0xaf, 0xa0, 0x1d, 0x05,
+	0x37, 0xfe, 0x20, 0x26, 0x3c, 0x08, 0x09, 0x66, 0x89, 0x63, 0xaf, 0x2e, 0x6d, 0x45, 0xc1, 0xcd,
+	0xc9, 0x54, 0x2d, 0x0e, 0x96, 0x0c, 0x82, 0x31, 0xf6, 0x69, 0x3c, 0x0e, 0x07, 0xa9, 0x03, 0x92,
+	0x61, 0x53, 0xe6, 0xce, 0x65, 0x4a, 0x48, 0x18, 0xe6, 0x2c, 0xcd, 0x24, 0x4d, 0x25, 0x91, 0x39,
+	0x2d, 0xf9, 0x02, 0xda, 0x0c, 0x0f, 0xd2, 0xc1, 0x6c, 0x9f, 0x0d, 0x29, 0x6a, 0xe9, 0x6c, 0x5e,
+	0xf6, 0xdb, 0x04, 0x27, 0xdc, 0x4f, 0x06, 0xbf, 0xe0, 0x28, 0x70, 0x5a, 0x99, 0x4c, 0x66, 0xdf,
+	0xca, 0x24, 0xfa, 0x0a, 0x36, 0x19, 0x4e, 0x68, 0x4c, 0x12, 0x9c, 0xe9, 0xda, 0x52, 0xd7, 0xce,
+	0xd2, 0x5a, 0x78, 0x38, 0x9d, 0xc0, 0x4d, 0x39, 0x47, 0x6e, 0xfe, 0x47, 0xf7, 0xc9, 0xc2, 0x01,
+	0xec, 0x15, 0x07, 0x70, 0x4b, 0x16, 0xee, 0x96, 0x0a, 0x57, 0xcf, 0x9f, 0x0b, 0x0d, 0x86, 0xdf,
+	0x85, 0x49, 0x18, 0x13, 0xe7, 0x5e, 0xc7, 0xd8, 0x37, 0xbd, 0x69, 0x2c, 0xae, 0xc6, 0x20, 0x20,
+	0x01, 0x4b, 0x1d, 0x24, 0x8f, 0xac, 0xa3, 0xd2, 0xec, 0x6d, 0xbf, 0xff, 0xec, 0xdd, 0xff, 0x9f,
+	0xcc, 0xde, 0xef, 0x26, 0xd4, 0x2f, 0x58, 0x38, 0x1a, 0x61, 0xf6, 0x21, 0x03, 0xb8, 0x0d, 0xb5,
+	0x6b, 0x22, 0xb2, 0x6a, 0x00, 0xab, 0xd7, 0xe4, 0x4c, 0xd6, 0xf3, 0x94, 0x62, 0x39, 0x7d, 0xb6,
+	0x27, 0x9f, 0x05, 0xf8, 0x24, 0x9e, 0xb0, 0x01, 0x96, 0xa3, 0x67, 0x7b, 0x3a, 0x42, 0xfd, 0x62,
+	0xbf, 0xeb, 0xb2, 0xdf, 0x9f, 0xe7, 0xfb, 0xad, 0x4f, 0xb9, 0xa6, 0xe9, 0xc5, 0x06, 0x36, 0xde,
+	0xbf, 0x81, 0xf6, 0x5d, 0x1a, 0xf8, 0xa1, 0x5d, 0xd8, 0x85, 0xd6, 0x29, 0xe6, 0x3d, 0x4a, 0x3d,
+	0x35, 0x61, 0xe5, 0x56, 0xec, 0xed, 0xc1, 0xd6, 0x0f, 0x78, 0x8c, 0x39, 0x5e, 0xa1, 0xf9, 0xd3,
+	0x80, 0xcd, 0x1f, 0xc3, 0x44, 0x6c, 0x93, 0x64, 0x9a, 0xac, 0x85, 0x46, 0xae, 0x85, 0xe2, 0xee,
+	0x4f, 0x58, 0x12, 0x33, 0xdd, 0x58, 0x1d, 0xa1, 0x07, 0xd0, 0xa0, 0x98, 0xf9, 0x54, 0xf8, 0xa8,
+	0xa9, 0x7c, 0x91, 0x62, 0x76, 0x2e, 0x9c, 0x74, 0x17, 0x9a, 0xa2, 0xd4, 0xa7, 0x0c, 0x5f, 0x87,
+	0x37, 0xba, 0xc9, 0x20, 0x52, 0xe7, 0x32, 0x83, 0x3a, 0xc5, 0xf6, 0xd5, 0x3a, 0xe6, 0xbe, 0x5d,
+	0x6c, 0xcc, 0xce, 0xd4, 0x04, 0x2c, 0xb9, 0xa8, 0x23, 0xf1, 0xd6, 0x98, 0x0d, 0x31, 0xf3, 0xaf,
+	0x52, 0x69, 0xb7, 0xb6, 0x57, 0x97, 0xf1, 0x6b, 0xc9, 0x4b, 0x3e, 0xca, 0x36, 0xda, 0x9e, 0x0a,
+	0xf6, 0x3e, 0x83, 0x8d, 0x53, 0xcc, 0xfb, 0x64, 0x19, 0x86, 0x47, 0xb0, 0xa9, 0x50, 0x2d, 0x97,
+	0xfc, 0x63, 0x40, 0x5b, 0x90, 0xea, 0x93, 0x29, 0xa8, 0xd9, 0xbd, 0x36, 0xf2, 0xf7, 0x7a, 0xd1,
+	0x08, 0xcc, 0xf8, 0x99, 0x4b, 0xf9, 0x55, 0x57, 0xf2, 0xab, 0xad, 0xe3, 0x67, 0xad, 0xe2, 0x57,
+	0x5f, 0xca, 0xaf, 0xb1, 0x84, 0x9f, 0x9d, 0xe7, 0xf7, 0x18, 0xee, 0x9d, 0x62, 0xae, 0xa7, 0x69,
+	0x19, 0xa1, 0x2f, 0xe1, 0xbe, 0x82, 0xb8, 0x46, 0xf7, 0xaf, 0x01, 0xdb, 0x82, 0xa4, 0x96, 0xad,
+	0xc3, 0x39, 0xb5, 0x89, 0x4a, 0xd1, 0x26, 0x24, 0x63, 0x73, 0x21, 0xe3, 0xea, 0x52, 0xc6, 0xb5,
+	0x95, 0x8c, 0xad, 0x75, 0x8c, 0xeb, 0xf3, 0x8c, 0xef, 0xca, 0xf2, 0xf0, 0x2f, 0x0b, 0xe0, 0xa7,
+	0xa9, 0x45, 0xa1, 0xa7, 0x60, 0x9f, 0x48, 0x3b, 0x11, 0xdf, 0xa6, 0x9b, 0xa5, 0xaf, 0x45, 0xb7,
+	0x9c, 0x40, 0x47, 0x60, 0xa9, 0xa9, 0x47, 0x0f, 0xf2, 0x4b, 0x05, 0x27, 0x98, 0xaf, 0x7a, 0x0a,
+	0xf6, 0xa5, 0x34, 0x9e, 0xdb, 0xbd, 0xa4, 0x07, 0xf6, 0xd4, 0x39, 0xd0, 0x27, 0xf9, 0xd5, 0xb2,
+	0xa1, 0xb8, 0x3b, 0x73, 0x66, 0xf7, 0x46, 0x7c, 0xde, 0xa3, 0x97, 0xd0, 0xc8, 0x7c, 0x05, 0x15,
+	0xbe, 0x9e, 0x4b, 0x6e, 0x33, 0xf7, 0xf2, 0x03, 0x03, 0x3d, 0x81, 0x86, 0x42, 0x22, 0x3e, 0xf0,
+	0x8a, 0x7f, 0xdf, 0x6e, 0x29, 0x46, 0x5f, 0x43, 0x4d, 0x4e, 0x36, 0x72, 0x4a, 0x38, 0xa6, 0x93,
+	0x3c, 0x57, 0xf2, 0x04, 0x1a, 0x0a, 0xc6, 0x2d, 0xb6, 0xff, 0x0e, 0x1a, 0x99, 0x31, 0x14, 0x7f,
+	0x46, 0xc9, 0x2e, 0x96, 0x72, 0xf8, 0x06, 0xea, 0xda, 0x35, 0x90, 0x5b, 0xc6, 0x30, 0xb3, 0x92,
+	0xf2, 0x7b, 0x0f, 0x0c, 0x74, 0x0c, 0x2d, 0x05, 0x21, 0xfb, 0xa7, 0xdd, 0x5e, 0xf0, 0xc7, 0xe6,
+	0x2e, 0x4a, 0xa2, 0xef, 0x01, 0x66, 0xd3, 0x8a, 0x3e, 0x2d, 0x81, 0x29, 0x4e, 0xe7, 0xe2, 0x1d,
+	0x8e, 0xa1, 0xa5, 0x10, 0xdd, 0xfd, 0xe5, 0x67, 0xd0, 0x2a, 0xb8, 0x00, 0xea, 0xcc, 0x63, 0x2b,
+	0x1d, 0x61, 0x19, 0xbb, 0x3e, 0x6c, 0xe4, 0x7d, 0x02, 0xed, 0x96, 0x01, 0x96, 0x1c, 0x64, 0xe1,
+	0x81, 0x0e, 0x8c, 0x2b, 0x4b, 0xee, 0xfb, 0xfc, 0xbf, 0x01, 0x00, 0xd9, 0x5c, 0xaf, 0x58, 0xa8,
+	0x0e, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ManagementClient is the client API for Management service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ManagementClient interface {
+	CreateApp(ctx context.Context, in *App, opts ...grpc.CallOption) (*App, error)
+	GetApp(ctx context.Context, in *GetAppRequest, opts ...grpc.CallOption) (*App, error)
+	UpdateApp(ctx context.Context, in *App, opts ...grpc.CallOption) (*App, error)
+	DeleteApp(ctx context.Context, in *DeleteAppRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (Management_ListAppsClient, error)
+	CreateFn(ctx context.Context, in *Fn, opts ...grpc.CallOption) (*Fn, error)
+	GetFn(ctx context.Context, in *GetFnRequest, opts ...grpc.CallOption) (*Fn, error)
+	UpdateFn(ctx context.Context, in *Fn, opts ...grpc.CallOption) (*Fn, error)
+	DeleteFn(ctx context.Context, in *DeleteFnRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListFns(ctx context.Context, in *ListFnsRequest, opts ...grpc.CallOption) (Management_ListFnsClient, error)
+	CreateTrigger(ctx context.Context, in *Trigger, opts ...grpc.CallOption) (*Trigger, error)
+	GetTrigger(ctx context.Context, in *GetTriggerRequest, opts ...grpc.CallOption) (*Trigger, error)
+	UpdateTrigger(ctx context.Context, in *Trigger, opts ...grpc.CallOption) (*Trigger, error)
+	DeleteTrigger(ctx context.Context, in *DeleteTriggerRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListTriggers(ctx context.Context, in *ListTriggersRequest, opts ...grpc.CallOption) (Management_ListTriggersClient, error)
+}
+
+type managementClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewManagementClient(cc *grpc.ClientConn) ManagementClient {
+	return &managementClient{cc}
+}
+
+func (c *managementClient) CreateApp(ctx context.Context, in *App, opts ...grpc.CallOption) (*App, error) {
+	out := new(App)
+	err := c.cc.Invoke(ctx, "/management.Management/CreateApp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) GetApp(ctx context.Context, in *GetAppRequest, opts ...grpc.CallOption) (*App, error) {
+	out := new(App)
+	err := c.cc.Invoke(ctx, "/management.Management/GetApp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) UpdateApp(ctx context.Context, in *App, opts ...grpc.CallOption) (*App, error) {
+	out := new(App)
+	err := c.cc.Invoke(ctx, "/management.Management/UpdateApp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) DeleteApp(ctx context.Context, in *DeleteAppRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/management.Management/DeleteApp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (Management_ListAppsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Management_serviceDesc.Streams[0], "/management.Management/ListApps", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managementListAppsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Management_ListAppsClient interface {
+	Recv() (*App, error)
+	grpc.ClientStream
+}
+
+type managementListAppsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managementListAppsClient) Recv() (*App, error) {
+	m := new(App)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managementClient) CreateFn(ctx context.Context, in *Fn, opts ...grpc.CallOption) (*Fn, error) {
+	out := new(Fn)
+	err := c.cc.Invoke(ctx, "/management.Management/CreateFn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) GetFn(ctx context.Context, in *GetFnRequest, opts ...grpc.CallOption) (*Fn, error) {
+	out := new(Fn)
+	err := c.cc.Invoke(ctx, "/management.Management/GetFn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) UpdateFn(ctx context.Context, in *Fn, opts ...grpc.CallOption) (*Fn, error) {
+	out := new(Fn)
+	err := c.cc.Invoke(ctx, "/management.Management/UpdateFn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) DeleteFn(ctx context.Context, in *DeleteFnRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/management.Management/DeleteFn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) ListFns(ctx context.Context, in *ListFnsRequest, opts ...grpc.CallOption) (Management_ListFnsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Management_serviceDesc.Streams[1], "/management.Management/ListFns", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managementListFnsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Management_ListFnsClient interface {
+	Recv() (*Fn, error)
+	grpc.ClientStream
+}
+
+type managementListFnsClient struct {
+	grpc.ClientStream
+}
+
+func (x *managementListFnsClient) Recv() (*Fn, error) {
+	m := new(Fn)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *managementClient) CreateTrigger(ctx context.Context, in *Trigger, opts ...grpc.CallOption) (*Trigger, error) {
+	out := new(Trigger)
+	err := c.cc.Invoke(ctx, "/management.Management/CreateTrigger", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) GetTrigger(ctx context.Context, in *GetTriggerRequest, opts ...grpc.CallOption) (*Trigger, error) {
+	out := new(Trigger)
+	err := c.cc.Invoke(ctx, "/management.Management/GetTrigger", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) UpdateTrigger(ctx context.Context, in *Trigger, opts ...grpc.CallOption) (*Trigger, error) {
+	out := new(Trigger)
+	err := c.cc.Invoke(ctx, "/management.Management/UpdateTrigger", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) DeleteTrigger(ctx context.Context, in *DeleteTriggerRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/management.Management/DeleteTrigger", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *managementClient) ListTriggers(ctx context.Context, in *ListTriggersRequest, opts ...grpc.CallOption) (Management_ListTriggersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Management_serviceDesc.Streams[2], "/management.Management/ListTriggers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &managementListTriggersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Management_ListTriggersClient interface {
+	Recv() (*Trigger, error)
+	grpc.ClientStream
+}
+
+type managementListTriggersClient struct {
+	grpc.ClientStream
+}
+
+func (x *managementListTriggersClient) Recv() (*Trigger, error) {
+	m := new(Trigger)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ManagementServer is the server API for Management service.
+type ManagementServer interface {
+	CreateApp(context.Context, *App) (*App, error)
+	GetApp(context.Context, *GetAppRequest) (*App, error)
+	UpdateApp(context.Context, *App) (*App, error)
+	DeleteApp(context.Context, *DeleteAppRequest) (*empty.Empty, error)
+	ListApps(*ListAppsRequest, Management_ListAppsServer) error
+	CreateFn(context.Context, *Fn) (*Fn, error)
+	GetFn(context.Context, *GetFnRequest) (*Fn, error)
+	UpdateFn(context.Context, *Fn) (*Fn, error)
+	DeleteFn(context.Context, *DeleteFnRequest) (*empty.Empty, error)
+	ListFns(*ListFnsRequest, Management_ListFnsServer) error
+	CreateTrigger(context.Context, *Trigger) (*Trigger, error)
+	GetTrigger(context.Context, *GetTriggerRequest) (*Trigger, error)
+	UpdateTrigger(context.Context, *Trigger) (*Trigger, error)
+	DeleteTrigger(context.Context, *DeleteTriggerRequest) (*empty.Empty, error)
+	ListTriggers(*ListTriggersRequest, Management_ListTriggersServer) error
+}
+
+func RegisterManagementServer(s *grpc.Server, srv ManagementServer) {
+	s.RegisterService(&_Management_serviceDesc, srv)
+}
+
+func _Management_CreateApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(App)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).CreateApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/CreateApp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).CreateApp(ctx, req.(*App))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_GetApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).GetApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/GetApp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).GetApp(ctx, req.(*GetAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_UpdateApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(App)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).UpdateApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/UpdateApp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).UpdateApp(ctx, req.(*App))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_DeleteApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).DeleteApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/DeleteApp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).DeleteApp(ctx, req.(*DeleteAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_ListApps_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListAppsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagementServer).ListApps(m, &managementListAppsServer{stream})
+}
+
+type Management_ListAppsServer interface {
+	Send(*App) error
+	grpc.ServerStream
+}
+
+type managementListAppsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managementListAppsServer) Send(m *App) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Management_CreateFn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Fn)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).CreateFn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/CreateFn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).CreateFn(ctx, req.(*Fn))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_GetFn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).GetFn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/GetFn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).GetFn(ctx, req.(*GetFnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_UpdateFn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Fn)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).UpdateFn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/UpdateFn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).UpdateFn(ctx, req.(*Fn))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_DeleteFn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).DeleteFn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/DeleteFn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).DeleteFn(ctx, req.(*DeleteFnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_ListFns_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListFnsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagementServer).ListFns(m, &managementListFnsServer{stream})
+}
+
+type Management_ListFnsServer interface {
+	Send(*Fn) error
+	grpc.ServerStream
+}
+
+type managementListFnsServer struct {
+	grpc.ServerStream
+}
+
+func (x *managementListFnsServer) Send(m *Fn) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Management_CreateTrigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Trigger)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).CreateTrigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/CreateTrigger",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).CreateTrigger(ctx, req.(*Trigger))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_GetTrigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTriggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).GetTrigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/GetTrigger",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).GetTrigger(ctx, req.(*GetTriggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_UpdateTrigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Trigger)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).UpdateTrigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/UpdateTrigger",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).UpdateTrigger(ctx, req.(*Trigger))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_DeleteTrigger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTriggerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ManagementServer).DeleteTrigger(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/management.Management/DeleteTrigger",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ManagementServer).DeleteTrigger(ctx, req.(*DeleteTriggerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Management_ListTriggers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListTriggersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ManagementServer).ListTriggers(m, &managementListTriggersServer{stream})
+}
+
+type Management_ListTriggersServer interface {
+	Send(*Trigger) error
+	grpc.ServerStream
+}
+
+type managementListTriggersServer struct {
+	grpc.ServerStream
+}
+
+func (x *managementListTriggersServer) Send(m *Trigger) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Management_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "management.Management",
+	HandlerType: (*ManagementServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateApp",
+			Handler:    _Management_CreateApp_Handler,
+		},
+		{
+			MethodName: "GetApp",
+			Handler:    _Management_GetApp_Handler,
+		},
+		{
+			MethodName: "UpdateApp",
+			Handler:    _Management_UpdateApp_Handler,
+		},
+		{
+			MethodName: "DeleteApp",
+			Handler:    _Management_DeleteApp_Handler,
+		},
+		{
+			MethodName: "CreateFn",
+			Handler:    _Management_CreateFn_Handler,
+		},
+		{
+			MethodName: "GetFn",
+			Handler:    _Management_GetFn_Handler,
+		},
+		{
+			MethodName: "UpdateFn",
+			Handler:    _Management_UpdateFn_Handler,
+		},
+		{
+			MethodName: "DeleteFn",
+			Handler:    _Management_DeleteFn_Handler,
+		},
+		{
+			MethodName: "CreateTrigger",
+			Handler:    _Management_CreateTrigger_Handler,
+		},
+		{
+			MethodName: "GetTrigger",
+			Handler:    _Management_GetTrigger_Handler,
+		},
+		{
+			MethodName: "UpdateTrigger",
+			Handler:    _Management_UpdateTrigger_Handler,
+		},
+		{
+			MethodName: "DeleteTrigger",
+			Handler:    _Management_DeleteTrigger_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListApps",
+			Handler:       _Management_ListApps_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListFns",
+			Handler:       _Management_ListFns_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListTriggers",
+			Handler:       _Management_ListTriggers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "management.proto",
+}
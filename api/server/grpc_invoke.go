@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	invoke "github.com/fnproject/fn/api/server/grpc"
+	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcInvoker implements invoke.InvokerServer, invocations go through the same path as
+// the HTTP invoke endpoint.
+type grpcInvoker struct {
+	s *Server
+}
+
+var _ invoke.InvokerServer = new(grpcInvoker)
+
+// implements http.ResponseWriter
+// grpcResponseWriter collects the response of a function to send it back in one message
+type grpcResponseWriter struct {
+	headers http.Header
+	status  int
+	body    bytes.Buffer
+}
+
+var _ http.ResponseWriter = new(grpcResponseWriter)
+
+func (w *grpcResponseWriter) Header() http.Header         { return w.headers }
+func (w *grpcResponseWriter) WriteHeader(code int)        { w.status = code }
+func (w *grpcResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// invokeStreamBody reads the payload of a streamed invocation, the body of the first
+// message is read before the ones received after it.
+type invokeStreamBody struct {
+	stream invoke.Invoker_InvokeStreamServer
+	buf    []byte
+}
+
+func (b *invokeStreamBody) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		msg, err := b.stream.Recv()
+		if err != nil {
+			return 0, err // io.EOF once the client closed the stream
+		}
+		b.buf = msg.Body
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+// Invoke implements invoke.InvokerServer
+func (g *grpcInvoker) Invoke(ctx context.Context, req *invoke.InvokeRequest) (*invoke.InvokeResponse, error) {
+	return g.invoke(ctx, req.FnId, req.Headers, bytes.NewReader(req.Body))
+}
+
+// InvokeStream implements invoke.InvokerServer
+func (g *grpcInvoker) InvokeStream(stream invoke.Invoker_InvokeStreamServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no invocation received")
+	} else if err != nil {
+		return err
+	}
+
+	resp, err := g.invoke(stream.Context(), first.FnId, first.Headers, &invokeStreamBody{stream: stream, buf: first.Body})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+func (g *grpcInvoker) invoke(ctx context.Context, fnID string, headers []*invoke.Header, body io.Reader) (*invoke.InvokeResponse, error) {
+	ctx, _ = common.LoggerWithFields(ctx, logrus.Fields{"fn_id": fnID})
+
+	// invocations carry their bearer token in the authorization metadata
+	var id *fnext.Identity
+	var key *models.APIKey
+	if g.s.authEnabled() {
+		var authorization string
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+			authorization = md.Get("authorization")[0]
+		}
+		var err error
+		id, key, err = g.s.verifyBearerToken(ctx, authorization)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		ctx = fnext.WithIdentity(ctx, id)
+	}
+
+	if fnID == "" {
+		return nil, grpcError(models.ErrFnsMissingID)
+	}
+	fn, err := g.s.lbReadAccess.GetFnByID(ctx, fnID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if key != nil && !(key.AllowsScope(models.APIKeyScopeInvoke) && key.AllowsApp(fn.AppID)) {
+		return nil, grpcError(models.ErrAPIKeyForbidden)
+	}
+	if g.s.rbac {
+		grants, err := g.s.principalGrants(ctx, id, key)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if !grants.allows(fn.AppID, models.PermissionInvoke) {
+			return nil, grpcError(models.ErrAccessDenied)
+		}
+	}
+	app, err := g.s.lbReadAccess.GetAppByID(ctx, fn.AppID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if _, err := g.s.takeRateLimits(ctx, app, key); err != nil {
+		return nil, grpcError(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/invoke/"+fnID, body)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	req = req.WithContext(ctx)
+	for _, h := range headers {
+		req.Header.Add(h.Key, h.Value)
+	}
+	// the identity is the verified one, not one claimed by the caller
+	req.Header.Del(audit.CallerIdentityHeader)
+	if id != nil {
+		req.Header.Set(audit.CallerIdentityHeader, id.Subject)
+	}
+
+	rw := &grpcResponseWriter{headers: make(http.Header), status: http.StatusOK}
+	err = g.s.fnInvoke(rw, req, app, fn, nil)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &invoke.InvokeResponse{
+		CallId: rw.headers.Get("Fn-Call-Id"),
+		Status: int32(rw.status),
+		Body:   rw.body.Bytes(),
+	}
+	for k, vs := range rw.headers {
+		for _, v := range vs {
+			resp.Headers = append(resp.Headers, &invoke.Header{Key: k, Value: v})
+		}
+	}
+	return resp, nil
+}
+
+// grpcError converts an error of an invocation to a gRPC status error, API errors are
+// given the gRPC code closest to their HTTP status code.
+func grpcError(err error) error {
+	e, ok := err.(models.APIError)
+	if !ok {
+		if err == context.Canceled {
+			return status.Error(codes.Canceled, err.Error())
+		}
+		return status.Error(codes.Internal, ErrInternalServerError.Error())
+	}
+	return status.Error(grpcCode(e.Code()), e.Error())
+}
+
+// grpcCode returns the gRPC code closest to an HTTP status code
+func grpcCode(statusCode int) codes.Code {
+	code := codes.Unknown
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		code = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	case http.StatusBadGateway:
+		code = codes.Aborted
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	case models.ErrClientCancel.Code():
+		code = codes.Canceled
+	}
+	return code
+}
+
+// startGRPCInvoke serves the gRPC invoke service on its port, the returned func stops it.
+func (s *Server) startGRPCInvoke(cancel context.CancelFunc) func() {
+	return s.startGRPC("invoke", s.grpcInvokeAddr, func(gs *grpc.Server) {
+		invoke.RegisterInvokerServer(gs, &grpcInvoker{s: s})
+	}, cancel)
+}
+
+// startGRPC serves the gRPC service registered by register on addr, with the TLS config of
+// the web server, the returned func stops it.
+func (s *Server) startGRPC(service, addr string, register func(*grpc.Server), cancel context.CancelFunc) func() {
+	var opts []grpc.ServerOption
+	if tlsCfg := s.svcConfigs[WebServer].TLSConfig; tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	gs := grpc.NewServer(opts...)
+	register(gs)
+
+	logrus.WithField("type", s.nodeType).Infof("Fn gRPC %s serving on `%v`", service, addr)
+	lsnr, err := net.Listen("tcp", addr)
+	if err != nil {
+		logrus.WithError(err).Errorf("grpc %s server error", service)
+		cancel()
+		return func() {}
+	}
+
+	go func() {
+		if err := gs.Serve(lsnr); err != nil {
+			logrus.WithError(err).Errorf("grpc %s server error", service)
+			cancel()
+		} else {
+			logrus.Infof("grpc %s server stopped", service)
+		}
+	}()
+	return gs.GracefulStop
+}
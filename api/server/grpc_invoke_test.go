@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	invoke "github.com/fnproject/fn/api/server/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCInvokeErrors(t *testing.T) {
+	s := &Server{lbReadAccess: agent.NewCachedDataAccess(datastore.NewMock())}
+
+	lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	invoke.RegisterInvokerServer(gs, &grpcInvoker{s: s})
+	go gs.Serve(lsnr)
+	defer gs.Stop()
+
+	conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := invoke.NewInvokerClient(conn)
+	ctx := context.Background()
+
+	_, err = client.Invoke(ctx, &invoke.InvokeRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invocation without a fn id should be invalid, got: %v", err)
+	}
+
+	_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "nope", Body: []byte("payload")})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("invocation of a missing fn should not be found, got: %v", err)
+	}
+
+	stream, err := client.InvokeStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&invoke.InvokeRequest{FnId: "nope"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("streamed invocation of a missing fn should not be found, got: %v", err)
+	}
+
+	for _, test := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{models.ErrAppQuotaExceeded, codes.ResourceExhausted},
+		{models.ErrAgentDraining, codes.Unavailable},
+		{models.ErrCallTimeout, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{io.ErrUnexpectedEOF, codes.Internal},
+	} {
+		if code := status.Code(grpcError(test.err)); code != test.code {
+			t.Errorf("expected code %v for %v, got %v", test.code, test.err, code)
+		}
+	}
+}
+
+// fakeInvokeStream is the server side of a streamed invocation
+type fakeInvokeStream struct {
+	invoke.Invoker_InvokeStreamServer
+	msgs []*invoke.InvokeRequest
+}
+
+func (f *fakeInvokeStream) Recv() (*invoke.InvokeRequest, error) {
+	if len(f.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := f.msgs[0]
+	f.msgs = f.msgs[1:]
+	return msg, nil
+}
+
+func TestInvokeStreamBody(t *testing.T) {
+	stream := &fakeInvokeStream{msgs: []*invoke.InvokeRequest{
+		{Body: []byte("lo ")},
+		{},
+		{Body: []byte("world")},
+	}}
+
+	body, err := ioutil.ReadAll(&invokeStreamBody{stream: stream, buf: []byte("hel")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "hello world" {
+		t.Fatalf("expected the payload of all messages, got: %q", body)
+	}
+}
@@ -0,0 +1,536 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	management "github.com/fnproject/fn/api/server/grpc/management"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcManager implements management.ManagementServer, calls are served by the REST endpoint
+// of the same operation through the router, so they go through the same middleware and
+// handlers as REST requests.
+type grpcManager struct {
+	s *Server
+}
+
+var _ management.ManagementServer = new(grpcManager)
+
+// grpcHeaders are the metadata of management calls passed to their endpoint as headers
+var grpcHeaders = []string{"Authorization", "If-Match"}
+
+// CreateApp implements management.ManagementServer
+func (g *grpcManager) CreateApp(ctx context.Context, req *management.App) (*management.App, error) {
+	app, err := appFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.App
+	if err := g.do(ctx, http.MethodPost, "/v2/apps", app, &res); err != nil {
+		return nil, err
+	}
+	return appToProto(&res), nil
+}
+
+// GetApp implements management.ManagementServer
+func (g *grpcManager) GetApp(ctx context.Context, req *management.GetAppRequest) (*management.App, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrAppsMissingID)
+	}
+	var res models.App
+	if err := g.do(ctx, http.MethodGet, "/v2/apps/"+url.PathEscape(req.Id), nil, &res); err != nil {
+		return nil, err
+	}
+	return appToProto(&res), nil
+}
+
+// UpdateApp implements management.ManagementServer
+func (g *grpcManager) UpdateApp(ctx context.Context, req *management.App) (*management.App, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrAppsMissingID)
+	}
+	app, err := appFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.App
+	if err := g.do(ctx, http.MethodPut, "/v2/apps/"+url.PathEscape(req.Id), app, &res); err != nil {
+		return nil, err
+	}
+	return appToProto(&res), nil
+}
+
+// DeleteApp implements management.ManagementServer
+func (g *grpcManager) DeleteApp(ctx context.Context, req *management.DeleteAppRequest) (*empty.Empty, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrAppsMissingID)
+	}
+	if err := g.do(ctx, http.MethodDelete, "/v2/apps/"+url.PathEscape(req.Id), nil, nil); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// ListApps implements management.ManagementServer
+func (g *grpcManager) ListApps(req *management.ListAppsRequest, stream management.Management_ListAppsServer) error {
+	query := listQuery(req.Cursor, req.PerPage, req.NamePrefix, req.Annotations, req.Config, req.OrderBy, req.Order)
+	if req.Name != "" {
+		query.Set("name", req.Name)
+	}
+	return g.list(stream.Context(), "/v2/apps", query, func(item json.RawMessage) error {
+		var app models.App
+		if err := json.Unmarshal(item, &app); err != nil {
+			return grpcError(err)
+		}
+		return stream.Send(appToProto(&app))
+	})
+}
+
+// CreateFn implements management.ManagementServer
+func (g *grpcManager) CreateFn(ctx context.Context, req *management.Fn) (*management.Fn, error) {
+	fn, err := fnFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.Fn
+	if err := g.do(ctx, http.MethodPost, "/v2/fns", fn, &res); err != nil {
+		return nil, err
+	}
+	return fnToProto(&res), nil
+}
+
+// GetFn implements management.ManagementServer
+func (g *grpcManager) GetFn(ctx context.Context, req *management.GetFnRequest) (*management.Fn, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrFnsMissingID)
+	}
+	var res models.Fn
+	if err := g.do(ctx, http.MethodGet, "/v2/fns/"+url.PathEscape(req.Id), nil, &res); err != nil {
+		return nil, err
+	}
+	return fnToProto(&res), nil
+}
+
+// UpdateFn implements management.ManagementServer
+func (g *grpcManager) UpdateFn(ctx context.Context, req *management.Fn) (*management.Fn, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrFnsMissingID)
+	}
+	fn, err := fnFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.Fn
+	if err := g.do(ctx, http.MethodPut, "/v2/fns/"+url.PathEscape(req.Id), fn, &res); err != nil {
+		return nil, err
+	}
+	return fnToProto(&res), nil
+}
+
+// DeleteFn implements management.ManagementServer
+func (g *grpcManager) DeleteFn(ctx context.Context, req *management.DeleteFnRequest) (*empty.Empty, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrFnsMissingID)
+	}
+	if err := g.do(ctx, http.MethodDelete, "/v2/fns/"+url.PathEscape(req.Id), nil, nil); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// ListFns implements management.ManagementServer
+func (g *grpcManager) ListFns(req *management.ListFnsRequest, stream management.Management_ListFnsServer) error {
+	query := listQuery(req.Cursor, req.PerPage, req.NamePrefix, req.Annotations, req.Config, req.OrderBy, req.Order)
+	if req.AppId != "" {
+		query.Set("app_id", req.AppId)
+	}
+	if req.Name != "" {
+		query.Set("name", req.Name)
+	}
+	return g.list(stream.Context(), "/v2/fns", query, func(item json.RawMessage) error {
+		var fn models.Fn
+		if err := json.Unmarshal(item, &fn); err != nil {
+			return grpcError(err)
+		}
+		return stream.Send(fnToProto(&fn))
+	})
+}
+
+// CreateTrigger implements management.ManagementServer
+func (g *grpcManager) CreateTrigger(ctx context.Context, req *management.Trigger) (*management.Trigger, error) {
+	trigger, err := triggerFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.Trigger
+	if err := g.do(ctx, http.MethodPost, "/v2/triggers", trigger, &res); err != nil {
+		return nil, err
+	}
+	return triggerToProto(&res), nil
+}
+
+// GetTrigger implements management.ManagementServer
+func (g *grpcManager) GetTrigger(ctx context.Context, req *management.GetTriggerRequest) (*management.Trigger, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrMissingID)
+	}
+	var res models.Trigger
+	if err := g.do(ctx, http.MethodGet, "/v2/triggers/"+url.PathEscape(req.Id), nil, &res); err != nil {
+		return nil, err
+	}
+	return triggerToProto(&res), nil
+}
+
+// UpdateTrigger implements management.ManagementServer
+func (g *grpcManager) UpdateTrigger(ctx context.Context, req *management.Trigger) (*management.Trigger, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrMissingID)
+	}
+	trigger, err := triggerFromProto(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var res models.Trigger
+	if err := g.do(ctx, http.MethodPut, "/v2/triggers/"+url.PathEscape(req.Id), trigger, &res); err != nil {
+		return nil, err
+	}
+	return triggerToProto(&res), nil
+}
+
+// DeleteTrigger implements management.ManagementServer
+func (g *grpcManager) DeleteTrigger(ctx context.Context, req *management.DeleteTriggerRequest) (*empty.Empty, error) {
+	if req.Id == "" {
+		return nil, grpcError(models.ErrMissingID)
+	}
+	if err := g.do(ctx, http.MethodDelete, "/v2/triggers/"+url.PathEscape(req.Id), nil, nil); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// ListTriggers implements management.ManagementServer
+func (g *grpcManager) ListTriggers(req *management.ListTriggersRequest, stream management.Management_ListTriggersServer) error {
+	query := listQuery(req.Cursor, req.PerPage, req.NamePrefix, req.Annotations, nil, req.OrderBy, req.Order)
+	for k, v := range map[string]string{"app_id": req.AppId, "fn_id": req.FnId, "name": req.Name} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	return g.list(stream.Context(), "/v2/triggers", query, func(item json.RawMessage) error {
+		var trigger models.Trigger
+		if err := json.Unmarshal(item, &trigger); err != nil {
+			return grpcError(err)
+		}
+		return stream.Send(triggerToProto(&trigger))
+	})
+}
+
+// do serves a call as a request to the REST endpoint of method and path with body in as JSON,
+// and decodes the JSON response into out. The ETag of the response is sent in the header
+// metadata of the call, and error responses are returned as gRPC status errors.
+func (g *grpcManager) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return grpcError(models.ErrInvalidJSON)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return grpcError(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, h := range grpcHeaders {
+			for _, v := range md.Get(h) {
+				req.Header.Add(h, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	rw := &grpcResponseWriter{headers: make(http.Header), status: http.StatusOK}
+	g.s.Router.ServeHTTP(rw, req)
+
+	if etag := rw.headers.Get("ETag"); etag != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs("etag", etag)); err != nil {
+			common.Logger(ctx).WithError(err).Debug("failed to set etag metadata")
+		}
+	}
+	if rw.status >= http.StatusMultipleChoices {
+		var e models.Error
+		json.Unmarshal(rw.body.Bytes(), &e) // errors without a body only have a status
+		if e.Message == "" {
+			e.Message = http.StatusText(rw.status)
+		}
+		return status.Error(grpcCode(rw.status), e.Message)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rw.body.Bytes(), out); err != nil {
+			return grpcError(err)
+		}
+	}
+	return nil
+}
+
+// list calls send with each item of the pages of the REST list endpoint path, from the
+// cursor of query until the last page.
+func (g *grpcManager) list(ctx context.Context, path string, query url.Values, send func(item json.RawMessage) error) error {
+	for {
+		var page struct {
+			Items      []json.RawMessage `json:"items"`
+			NextCursor string            `json:"next_cursor"`
+		}
+		if err := g.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &page); err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := send(item); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// listQuery returns the query of the first page of a list call
+func listQuery(cursor string, perPage int32, namePrefix string, annotations, config []string, orderBy, order string) url.Values {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if perPage > 0 {
+		query.Set("per_page", strconv.Itoa(int(perPage)))
+	}
+	if namePrefix != "" {
+		query.Set("name_prefix", namePrefix)
+	}
+	for _, a := range annotations {
+		query.Add("annotation", a)
+	}
+	for _, c := range config {
+		query.Add("config", c)
+	}
+	if orderBy != "" {
+		query.Set("order_by", orderBy)
+	}
+	if order != "" {
+		query.Set("order", order)
+	}
+	return query
+}
+
+func appToProto(app *models.App) *management.App {
+	res := &management.App{
+		Id:          app.ID,
+		Name:        app.Name,
+		Config:      app.Config,
+		Annotations: annotationsToProto(app.Annotations),
+		CreatedAt:   timestampProto(app.CreatedAt),
+		UpdatedAt:   timestampProto(app.UpdatedAt),
+	}
+	if app.SyslogURL != nil {
+		res.SyslogUrl = &management.StringValue{Value: *app.SyslogURL}
+	}
+	return res
+}
+
+func appFromProto(app *management.App) (*models.App, error) {
+	annotations, err := annotationsFromProto(app.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.App{
+		ID:          app.Id,
+		Name:        app.Name,
+		Config:      app.Config,
+		Annotations: annotations,
+	}
+	if app.SyslogUrl != nil {
+		res.SyslogURL = &app.SyslogUrl.Value
+	}
+	return res, nil
+}
+
+func fnToProto(fn *models.Fn) *management.Fn {
+	res := &management.Fn{
+		Id:             fn.ID,
+		Name:           fn.Name,
+		AppId:          fn.AppID,
+		Image:          fn.Image,
+		Memory:         fn.Memory,
+		Timeout:        fn.Timeout,
+		IdleTimeout:    fn.IdleTimeout,
+		ScalePolicy:    jsonProto(fn.ScalePolicy),
+		RetryPolicy:    jsonProto(fn.RetryPolicy),
+		RecyclePolicy:  jsonProto(fn.RecyclePolicy),
+		RequestSchema:  fn.RequestSchema,
+		ResponseSchema: fn.ResponseSchema,
+		Config:         fn.Config,
+		Annotations:    annotationsToProto(fn.Annotations),
+		Revision:       fn.Revision,
+		Canary:         jsonProto(fn.Canary),
+		CreatedAt:      timestampProto(fn.CreatedAt),
+		UpdatedAt:      timestampProto(fn.UpdatedAt),
+	}
+	if fn.MinReady != nil {
+		res.MinReady = &management.UInt64Value{Value: *fn.MinReady}
+	}
+	if fn.MaxContainers != nil {
+		res.MaxContainers = &management.UInt64Value{Value: *fn.MaxContainers}
+	}
+	return res
+}
+
+func fnFromProto(fn *management.Fn) (*models.Fn, error) {
+	annotations, err := annotationsFromProto(fn.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.Fn{
+		ID:             fn.Id,
+		Name:           fn.Name,
+		AppID:          fn.AppId,
+		Image:          fn.Image,
+		ResourceConfig: models.ResourceConfig{Memory: fn.Memory, Timeout: fn.Timeout, IdleTimeout: fn.IdleTimeout},
+		RequestSchema:  fn.RequestSchema,
+		ResponseSchema: fn.ResponseSchema,
+		Config:         fn.Config,
+		Annotations:    annotations,
+	}
+	if fn.MinReady != nil {
+		res.MinReady = &fn.MinReady.Value
+	}
+	if fn.MaxContainers != nil {
+		res.MaxContainers = &fn.MaxContainers.Value
+	}
+	for _, policy := range []struct {
+		value []byte
+		dst   interface{}
+	}{
+		{fn.ScalePolicy, &res.ScalePolicy},
+		{fn.RetryPolicy, &res.RetryPolicy},
+		{fn.RecyclePolicy, &res.RecyclePolicy},
+	} {
+		if len(policy.value) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(policy.value, policy.dst); err != nil {
+			return nil, models.ErrInvalidJSON
+		}
+	}
+	return res, nil
+}
+
+func triggerToProto(trigger *models.Trigger) *management.Trigger {
+	return &management.Trigger{
+		Id:          trigger.ID,
+		Name:        trigger.Name,
+		AppId:       trigger.AppID,
+		FnId:        trigger.FnID,
+		Type:        trigger.Type,
+		Source:      trigger.Source,
+		Annotations: annotationsToProto(trigger.Annotations),
+		CreatedAt:   timestampProto(trigger.CreatedAt),
+		UpdatedAt:   timestampProto(trigger.UpdatedAt),
+	}
+}
+
+func triggerFromProto(trigger *management.Trigger) (*models.Trigger, error) {
+	annotations, err := annotationsFromProto(trigger.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Trigger{
+		ID:          trigger.Id,
+		Name:        trigger.Name,
+		AppID:       trigger.AppId,
+		FnID:        trigger.FnId,
+		Type:        trigger.Type,
+		Source:      trigger.Source,
+		Annotations: annotations,
+	}, nil
+}
+
+// annotationsToProto returns the JSON values of annotations
+func annotationsToProto(annotations models.Annotations) map[string][]byte {
+	if len(annotations) == 0 {
+		return nil
+	}
+	res := make(map[string][]byte, len(annotations))
+	for k := range annotations {
+		res[k], _ = annotations.Get(k)
+	}
+	return res
+}
+
+// annotationsFromProto returns the annotations of JSON values
+func annotationsFromProto(values map[string][]byte) (models.Annotations, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	raw := make(map[string]json.RawMessage, len(values))
+	for k, v := range values {
+		raw[k] = v
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, models.ErrInvalidJSON
+	}
+	var annotations models.Annotations
+	if err := json.Unmarshal(b, &annotations); err != nil {
+		return nil, models.ErrInvalidJSON
+	}
+	return annotations, nil
+}
+
+// jsonProto returns the JSON of v, nil if v is nil
+func jsonProto(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return b
+}
+
+func timestampProto(t common.DateTime) *timestamp.Timestamp {
+	if time.Time(t).IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(time.Time(t))
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// startGRPCManagement serves the gRPC management service on its port, the returned func
+// stops it.
+func (s *Server) startGRPCManagement(cancel context.CancelFunc) func() {
+	return s.startGRPC("management", s.grpcManagementAddr, func(gs *grpc.Server) {
+		management.RegisterManagementServer(gs, &grpcManager{s: s})
+	}, cancel)
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	management "github.com/fnproject/fn/api/server/grpc/management"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCManagement(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	srv := testServer(datastore.NewMock(), &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	management.RegisterManagementServer(gs, &grpcManager{s: srv})
+	go gs.Serve(lsnr)
+	defer gs.Stop()
+
+	conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := management.NewManagementClient(conn)
+	ctx := context.Background()
+
+	app, err := client.CreateApp(ctx, &management.App{Name: "myapp", Config: map[string]string{"A": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Id == "" || app.Config["A"] != "1" || app.CreatedAt == nil {
+		t.Fatalf("expected the created app, got %+v", app)
+	}
+	if _, err := client.CreateApp(ctx, &management.App{Name: "myapp"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected a duplicate app to exist already, got %v", err)
+	}
+
+	var header metadata.MD
+	if _, err := client.GetApp(ctx, &management.GetAppRequest{Id: app.Id}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if len(header.Get("etag")) != 1 {
+		t.Fatalf("expected the ETag of the app, got %v", header)
+	}
+	etag := header.Get("etag")[0]
+
+	_, err = client.UpdateApp(metadata.AppendToOutgoingContext(ctx, "if-match", `"stale"`), &management.App{Id: app.Id, SyslogUrl: &management.StringValue{Value: "tcp://example.com:514"}})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected a stale update to fail its precondition, got %v", err)
+	}
+	app, err = client.UpdateApp(metadata.AppendToOutgoingContext(ctx, "if-match", etag), &management.App{Id: app.Id, SyslogUrl: &management.StringValue{Value: "tcp://example.com:514"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.SyslogUrl.GetValue() != "tcp://example.com:514" || app.Config["A"] != "1" {
+		t.Fatalf("expected the update to be merged into the app, got %+v", app)
+	}
+
+	for _, name := range []string{"fn1", "fn2", "fn3"} {
+		fn, err := client.CreateFn(ctx, &management.Fn{Name: name, AppId: app.Id, Image: "fnproject/fn-test-utils", Annotations: map[string][]byte{"a": []byte(`{"b":1}`)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fn.Memory != models.DefaultMemory || string(fn.Annotations["a"]) != `{"b":1}` {
+			t.Fatalf("expected the fn to be created with defaults, got %+v", fn)
+		}
+	}
+	if _, err := client.CreateFn(ctx, &management.Fn{Name: "fn4", AppId: app.Id, Image: "fnproject/fn-test-utils", RetryPolicy: []byte("{")}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected a fn with an invalid policy to be invalid, got %v", err)
+	}
+
+	// the streamed list spans the pages of the REST list
+	stream, err := client.ListFns(ctx, &management.ListFnsRequest{AppId: app.Id, PerPage: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fns []*management.Fn
+	for {
+		fn, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		fns = append(fns, fn)
+	}
+	if len(fns) != 3 || fns[0].Name != "fn1" || fns[2].Name != "fn3" {
+		t.Fatalf("expected the 3 fns of the app, got %+v", fns)
+	}
+
+	// filters are passed on to the REST list
+	stream, err = client.ListFns(ctx, &management.ListFnsRequest{AppId: app.Id, NamePrefix: "fn2", Annotations: []string{`a={"b":1}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn, err := stream.Recv(); err != nil || fn.Name != "fn2" {
+		t.Fatalf("expected the fn matching the filters, got %+v, %v", fn, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected only one fn to match the filters, got %v", err)
+	}
+	appStream, err := client.ListApps(ctx, &management.ListAppsRequest{Config: []string{"A=2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appStream.Recv(); err != io.EOF {
+		t.Fatalf("expected no app to match the filters, got %v", err)
+	}
+	appStream, err = client.ListApps(ctx, &management.ListAppsRequest{Config: []string{""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appStream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected an invalid filter to be invalid, got %v", err)
+	}
+
+	trigger, err := client.CreateTrigger(ctx, &management.Trigger{Name: "t1", AppId: app.Id, FnId: fns[0].Id, Type: "http", Source: "/t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteTrigger(ctx, &management.DeleteTriggerRequest{Id: trigger.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetTrigger(ctx, &management.GetTriggerRequest{Id: trigger.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected the deleted trigger not to be found, got %v", err)
+	}
+	if _, err := client.GetFn(ctx, &management.GetFnRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected a get without an id to be invalid, got %v", err)
+	}
+}
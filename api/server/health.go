@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Names of the dependencies of a node probed by /ready
+const (
+	DependencyDatastore = "datastore"
+	DependencyLogStore  = "logstore"
+	DependencyMQ        = "mq"
+	DependencyDocker    = "docker"
+)
+
+// defaultReadyTimeout is the time each dependency has to answer its probe if EnvReadyTimeout
+// is unset
+const defaultReadyTimeout = 2 * time.Second
+
+// WithReadyProbes maps EnvReadyTimeout and EnvReadyStrict, the timeout of the probes of the
+// dependencies of the node and whether the node is not ready once any of them fails.
+func WithReadyProbes(timeout time.Duration, strict bool) Option {
+	return func(ctx context.Context, s *Server) error {
+		if timeout <= 0 {
+			timeout = defaultReadyTimeout
+		}
+		s.readyTimeout = timeout
+		s.readyStrict = strict
+		return nil
+	}
+}
+
+// healthSetup adds /ready, which probes the dependencies of the node, and /live, which does not
+func (s *Server) healthSetup(router *gin.Engine) {
+	router.GET("/ready", s.handleReady)
+	router.GET("/live", handleLive)
+}
+
+// handleLive reports that the node serves requests. The dependencies are not probed, as
+// restarting the node would not bring them back.
+func handleLive(c *gin.Context) {
+	c.JSON(http.StatusOK, &models.NodeHealth{Status: models.HealthOK})
+}
+
+// handleReady probes the dependencies of the node, it is ready unless a required one is
+// unavailable. The datastore and the docker daemon are required, every dependency is when
+// readyStrict is set so that load balancers take the node out of rotation on any failure.
+// A draining node is not ready, without probing.
+func (s *Server) handleReady(c *gin.Context) {
+	if s.isDraining() {
+		c.JSON(http.StatusServiceUnavailable, &models.NodeHealth{Status: models.HealthDraining})
+		return
+	}
+
+	health := s.probeDependencies(c.Request.Context())
+	code := http.StatusOK
+	if health.Status == models.HealthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, health)
+}
+
+type dependencyProbe struct {
+	name     string
+	required bool
+	probe    func(ctx context.Context) error
+}
+
+// dependencyProbes returns the probes of the dependencies the node has
+func (s *Server) dependencyProbes() []dependencyProbe {
+	var probes []dependencyProbe
+	if s.datastore != nil {
+		probes = append(probes, dependencyProbe{DependencyDatastore, true, func(ctx context.Context) error {
+			_, err := s.datastore.GetApps(ctx, &models.AppFilter{PerPage: 1})
+			return err
+		}})
+	}
+	if s.logstore != nil {
+		probes = append(probes, dependencyProbe{DependencyLogStore, s.readyStrict, func(ctx context.Context) error {
+			// no call has this id, the store answers that its log does not exist
+			_, err := s.logstore.GetLog(ctx, "ready", "ready")
+			if err == models.ErrCallLogNotFound {
+				return nil
+			}
+			return err
+		}})
+	}
+	if p, ok := s.mq.(models.Pinger); ok {
+		probes = append(probes, dependencyProbe{DependencyMQ, s.readyStrict, p.Ping})
+	}
+	if p, ok := s.agent.(agent.DriverPinger); ok {
+		probes = append(probes, dependencyProbe{DependencyDocker, true, p.PingDriver})
+	}
+	return probes
+}
+
+// probeDependencies probes the dependencies of the node concurrently, each within the ready
+// timeout
+func (s *Server) probeDependencies(ctx context.Context) *models.NodeHealth {
+	probes := s.dependencyProbes()
+	health := &models.NodeHealth{Status: models.HealthOK, Dependencies: make([]*models.DependencyHealth, len(probes))}
+
+	timeout := s.readyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p dependencyProbe) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := p.probe(ctx)
+			dep := &models.DependencyHealth{Name: p.name, Status: models.HealthOK, Required: p.required}
+			if err != nil {
+				dep.Status = models.HealthUnavailable
+				common.Logger(ctx).WithError(err).WithFields(logrus.Fields{
+					"dependency":  p.name,
+					"required":    p.required,
+					"duration_ms": int64(time.Since(start) / time.Millisecond),
+				}).Warn("Dependency probe failed")
+			}
+			health.Dependencies[i] = dep
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, dep := range health.Dependencies {
+		if dep.Status == models.HealthOK {
+			continue
+		}
+		if dep.Required {
+			health.Status = models.HealthUnavailable
+			break
+		}
+		health.Status = models.HealthDegraded
+	}
+	return health
+}
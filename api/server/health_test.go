@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// pingMQ is a queue whose probes answer err, after delay
+type pingMQ struct {
+	models.MessageQueue
+	delay time.Duration
+	err   error
+}
+
+func (mq *pingMQ) Ping(ctx context.Context) error {
+	select {
+	case <-time.After(mq.delay):
+		return mq.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestReady(t *testing.T) {
+	for i, test := range []struct {
+		mq           *pingMQ
+		strict       bool
+		expectedCode int
+		expected     string
+	}{
+		{&pingMQ{}, false, http.StatusOK, models.HealthOK},
+		{&pingMQ{err: errors.New("down")}, false, http.StatusOK, models.HealthDegraded},
+		{&pingMQ{err: errors.New("down")}, true, http.StatusServiceUnavailable, models.HealthUnavailable},
+		{&pingMQ{delay: time.Minute}, true, http.StatusServiceUnavailable, models.HealthUnavailable},
+	} {
+		s := &Server{datastore: datastore.NewMock(), logstore: logs.NewMock(), mq: test.mq}
+		if err := WithReadyProbes(50*time.Millisecond, test.strict)(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+		router := gin.New()
+		s.healthSetup(router)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+		if rec.Code != test.expectedCode {
+			t.Errorf("test %d: expected status %d, got %d", i, test.expectedCode, rec.Code)
+		}
+		// why probes fail is logged, not disclosed
+		if strings.Contains(rec.Body.String(), "down") {
+			t.Errorf("test %d: expected no probe errors in the response, got %s", i, rec.Body.String())
+		}
+		var health models.NodeHealth
+		if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if health.Status != test.expected {
+			t.Errorf("test %d: expected node %s, got %s", i, test.expected, health.Status)
+		}
+		if len(health.Dependencies) != 3 {
+			t.Fatalf("test %d: expected the datastore, log store and mq probed, got %+v", i, health.Dependencies)
+		}
+		for _, dep := range health.Dependencies {
+			if dep.Name != DependencyMQ && dep.Status != models.HealthOK {
+				t.Errorf("test %d: expected %s ok, got %+v", i, dep.Name, dep)
+			}
+		}
+	}
+}
+
+func TestLive(t *testing.T) {
+	// the dependencies are not probed
+	s := &Server{mq: &pingMQ{err: errors.New("down")}}
+	router := gin.New()
+	s.healthSetup(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/live", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
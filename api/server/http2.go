@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// WithHTTP2 maps EnvH2C, EnvHTTP2MaxConcurrentStreams, EnvHTTP2StreamWindow and
+// EnvHTTP2ConnWindow. The web server speaks HTTP/2 with these settings to clients negotiating it
+// over TLS, and over cleartext if h2c is set. Zero settings keep the defaults of HTTP/2.
+func WithHTTP2(h2c bool, maxConcurrentStreams uint32, streamWindow, connWindow int32) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.http2 = &http2.Server{
+			MaxConcurrentStreams:         maxConcurrentStreams,
+			MaxUploadBufferPerStream:     streamWindow,
+			MaxUploadBufferPerConnection: connWindow,
+		}
+		s.h2c = h2c
+		return nil
+	}
+}
+
+// configureHTTP2 sets up the HTTP/2 settings of the web server, and wraps its handler to serve
+// HTTP/2 over cleartext if h2c is enabled. Bodies of requests are read as the calls stream them
+// to their container, so each stream's window only opens as fast as the function consumes it.
+func (s *Server) configureHTTP2(server *http.Server) error {
+	if s.http2 == nil {
+		return nil
+	}
+	// ConfigureServer would set up TLS on servers without it
+	if server.TLSConfig != nil {
+		if err := http2.ConfigureServer(server, s.http2); err != nil {
+			return err
+		}
+	}
+	if s.h2c {
+		logrus.Info("Fn serving HTTP/2 over cleartext (h2c)")
+		server.Handler = h2c.NewHandler(server.Handler, s.http2)
+	}
+	return nil
+}
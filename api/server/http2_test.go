@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestH2C(t *testing.T) {
+	s := &Server{}
+	if err := WithHTTP2(true, 10, 0, 0)(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	// echoes the body of requests, as read
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		w.Write([]byte(req.Proto + " " + string(b)))
+	})}
+	if err := s.configureHTTP2(server); err != nil {
+		t.Fatal(err)
+	}
+	if server.TLSConfig != nil {
+		t.Fatal("expected a server without TLS to stay without it")
+	}
+
+	ts := httptest.NewServer(server.Handler)
+	defer ts.Close()
+
+	// a client with prior knowledge of HTTP/2 over cleartext
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "HTTP/2.0 hello" {
+		t.Fatalf("expected the request served over HTTP/2, got %q", b)
+	}
+
+	// HTTP/1.1 is still served
+	resp, err = http.Post(ts.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ = ioutil.ReadAll(resp.Body)
+	if string(b) != "HTTP/1.1 hello" {
+		t.Fatalf("expected the request served over HTTP/1.1, got %q", b)
+	}
+}
@@ -171,9 +171,11 @@ func (s *Server) handleRunnerFinish(c *gin.Context) {
 		// note: Not returning err here since the job could have already finished successfully.
 	}
 
-	if err := s.logstore.InsertLog(ctx, &call, strings.NewReader(body.Log)); err != nil {
-		common.Logger(ctx).WithError(err).Error("error uploading log")
-		// note: Not returning err here since the job could have already finished successfully.
+	if call.LogUnavailable == "" {
+		if err := s.logstore.InsertLog(ctx, &call, strings.NewReader(body.Log)); err != nil {
+			common.Logger(ctx).WithError(err).Error("error uploading log")
+			// note: Not returning err here since the job could have already finished successfully.
+		}
 	}
 
 	// TODO open this up after we change messaging semantics.
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/responsecache"
+)
+
+const (
+	// IdempotencyKeyHeader is the header of the key clients set to invoke a function at most
+	// once, see EnvIdempotencyTTL
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses of calls answered with the outcome of an
+	// earlier call with the same idempotency key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is the time the outcomes of calls with an idempotency key are kept
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// idempotencyInProgressMargin is kept on top of the timeout of a function for the calls
+	// with an idempotency key in progress, so that calls of a node that dies are run again
+	idempotencyInProgressMargin = time.Minute
+)
+
+// WithIdempotencyTTL maps EnvIdempotencyTTL
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		if ttl <= 0 {
+			ttl = DefaultIdempotencyTTL
+		}
+		s.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// idempotentCall is a call of a function with an idempotency key, whose outcome is kept to
+// answer its retries
+type idempotentCall struct {
+	key string
+	// header holds the headers of the response set before the call, which are not kept
+	header http.Header
+	// ended is set once the call is over, its outcome kept or its key released
+	ended bool
+}
+
+// beginIdempotentCall returns the outcome of an earlier call of fn with the idempotency key of
+// req if there is one, or claims the key for the call otherwise. The call is nil if req has no
+// idempotency key. Another call with the key in progress is a conflict.
+func (s *Server) beginIdempotentCall(resp http.ResponseWriter, req *http.Request, fn *models.Fn) (*idempotentCall, *responsecache.Response, error) {
+	raw := req.Header.Get(IdempotencyKeyHeader)
+	if raw == "" {
+		return nil, nil, nil
+	}
+	key := common.SanitizeRequestID(raw)
+	if key == "" {
+		return nil, nil, models.ErrInvalidIdempotencyKey
+	}
+	key = "idempotency/" + fn.ID + "/" + key
+
+	ctx := req.Context()
+	timeout := fn.Timeout
+	if timeout == 0 {
+		timeout = models.DefaultTimeout
+	}
+	// responses without a status mark the calls in progress
+	inProgress := time.Duration(timeout)*time.Second + idempotencyInProgressMargin
+	added, err := s.responseCache.Add(ctx, key, &responsecache.Response{}, inProgress)
+	if err != nil {
+		// calls run their function if the store is unavailable
+		common.Logger(ctx).WithError(err).Error("failed to claim idempotency key")
+		return nil, nil, nil
+	}
+	if added {
+		return &idempotentCall{key: key, header: cloneHeader(resp.Header())}, nil, nil
+	}
+
+	res, err := s.responseCache.Get(ctx, key)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("failed to get idempotent call outcome")
+		return nil, nil, models.ErrIdempotentCallInProgress
+	}
+	if res == nil || res.Status == 0 {
+		return nil, nil, models.ErrIdempotentCallInProgress
+	}
+	return nil, res, nil
+}
+
+// endIdempotentCall keeps the outcome of a call for the retries with its idempotency key. The
+// outcome of calls that failed before their function ran is not kept, so that they can be retried.
+func (s *Server) endIdempotentCall(ctx context.Context, call *idempotentCall, header http.Header, status int, body []byte, err error) {
+	if err != nil && !models.IsFuncError(err) {
+		s.releaseIdempotentCall(ctx, call)
+		return
+	}
+	call.ended = true
+	if err != nil {
+		// the error response of the call, as it is written to the client
+		w := &syncResponseWriter{headers: cloneHeader(header), Buffer: new(bytes.Buffer)}
+		HandleErrorResponse(ctx, w, err)
+		header, status, body = w.headers, w.status, w.Bytes()
+	}
+
+	res := &responsecache.Response{
+		Status: status,
+		Header: make(http.Header, len(header)),
+		Body:   append([]byte(nil), body...),
+	}
+	for k, vs := range header {
+		if k == "Content-Length" || equalValues(call.header[k], vs) {
+			continue
+		}
+		res.Header[k] = vs
+	}
+	if err := s.responseCache.Set(ctx, call.key, res, s.idempotencyTTL); err != nil {
+		common.Logger(ctx).WithError(err).Error("failed to keep idempotent call outcome")
+	}
+}
+
+// releaseIdempotentCall releases the idempotency key of a call that did not end, so that it
+// can be retried, e.g. when the call fails before its function runs.
+func (s *Server) releaseIdempotentCall(ctx context.Context, call *idempotentCall) {
+	if call.ended {
+		return
+	}
+	call.ended = true
+	if err := s.responseCache.Delete(ctx, call.key); err != nil {
+		common.Logger(ctx).WithError(err).Error("failed to release idempotency key")
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/responsecache"
+)
+
+func TestIdempotentCall(t *testing.T) {
+	s := &Server{
+		responseCache:  responsecache.NewMemoryStore(responsecache.DefaultMemorySize),
+		idempotencyTTL: time.Hour,
+	}
+	fn := &models.Fn{ID: "fn_id"}
+	ctx := context.Background()
+
+	request := func(key string) *http.Request {
+		req := httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader("charge"))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		return req
+	}
+
+	// calls without a key are not tracked
+	if call, res, err := s.beginIdempotentCall(httptest.NewRecorder(), request(""), fn); call != nil || res != nil || err != nil {
+		t.Fatalf("expected calls without a key to run, got %v %v %v", call, res, err)
+	}
+	if _, _, err := s.beginIdempotentCall(httptest.NewRecorder(), request("bad key\n"), fn); err != models.ErrInvalidIdempotencyKey {
+		t.Fatalf("expected invalid keys to be rejected, got %v", err)
+	}
+
+	// the first call with a key runs its function
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Fn-Rate-Limit", "10")
+	call, res, err := s.beginIdempotentCall(rec, request("key-1"), fn)
+	if call == nil || res != nil || err != nil {
+		t.Fatalf("expected the call to run, got %v %v %v", call, res, err)
+	}
+
+	// retries conflict while it is in progress
+	if _, _, err := s.beginIdempotentCall(httptest.NewRecorder(), request("key-1"), fn); err != models.ErrIdempotentCallInProgress {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	// keys are scoped to their function
+	if call, _, _ := s.beginIdempotentCall(httptest.NewRecorder(), request("key-1"), &models.Fn{ID: "fn_id2"}); call == nil {
+		t.Fatal("expected the call of another function to run")
+	}
+
+	header := http.Header{
+		"Fn-Call-Id":    {"call_id"},
+		"Fn-Rate-Limit": {"10"},
+		"Content-Type":  {"application/json"},
+	}
+	s.endIdempotentCall(ctx, call, header, http.StatusOK, []byte(`{"charged":true}`), nil)
+
+	// retries are answered with the outcome of the call
+	call, res, err = s.beginIdempotentCall(httptest.NewRecorder(), request("key-1"), fn)
+	if call != nil || res == nil || err != nil {
+		t.Fatalf("expected the outcome of the call, got %v %v %v", call, res, err)
+	}
+	if res.Status != http.StatusOK || string(res.Body) != `{"charged":true}` ||
+		res.Header.Get("Fn-Call-Id") != "call_id" || res.Header.Get("Fn-Rate-Limit") != "" {
+		t.Fatalf("unexpected outcome %d %v %q", res.Status, res.Header, res.Body)
+	}
+
+	// errors of functions are outcomes
+	call, _, _ = s.beginIdempotentCall(httptest.NewRecorder(), request("key-2"), fn)
+	s.endIdempotentCall(ctx, call, http.Header{}, 0, nil, models.ErrCallTimeout)
+	_, res, _ = s.beginIdempotentCall(httptest.NewRecorder(), request("key-2"), fn)
+	if res == nil || res.Status != models.ErrCallTimeout.Code() || !strings.Contains(string(res.Body), models.ErrCallTimeout.Error()) {
+		t.Fatalf("expected the error of the function to be kept, got %+v", res)
+	}
+
+	// calls that failed before their function ran can be retried
+	call, _, _ = s.beginIdempotentCall(httptest.NewRecorder(), request("key-3"), fn)
+	s.endIdempotentCall(ctx, call, http.Header{}, 0, nil, models.ErrCallTimeoutServerBusy)
+	if call, res, err := s.beginIdempotentCall(httptest.NewRecorder(), request("key-3"), fn); call == nil || res != nil || err != nil {
+		t.Fatalf("expected the retry to run, got %v %v %v", call, res, err)
+	}
+}
+
+func TestIdempotentCachedCall(t *testing.T) {
+	s := &Server{
+		responseCache:  responsecache.NewMemoryStore(responsecache.DefaultMemorySize),
+		idempotencyTTL: time.Hour,
+	}
+	annotations, err := models.EmptyAnnotations().With(models.FnResponseCacheAnnotation, map[string]interface{}{"ttl": 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{ID: "fn_id", Revision: 1, Annotations: annotations}
+
+	request := func(key string) *http.Request {
+		req := httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader("hello"))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		return req
+	}
+
+	cached, _ := s.lookupResponse(httptest.NewRecorder(), request(""), fn)
+	if cached == nil {
+		t.Fatal("expected a miss")
+	}
+	s.storeResponse(context.Background(), cached, http.Header{"Content-Type": {"text/plain"}}, http.StatusOK, []byte("world"))
+
+	// calls answered from the cache don't keep their key claimed
+	rec := httptest.NewRecorder()
+	if err := s.fnInvoke(rec, request("key-1"), &models.App{}, fn, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "world" {
+		t.Fatalf("expected the cached response, got %d %q", rec.Code, rec.Body.String())
+	}
+	call, res, err := s.beginIdempotentCall(httptest.NewRecorder(), request("key-1"), fn)
+	if call != nil || err != nil || res == nil || res.Status != http.StatusOK || string(res.Body) != "world" {
+		t.Fatalf("expected the cached response as the outcome of the key, got %v %+v %v", call, res, err)
+	}
+}
@@ -1,8 +1,22 @@
 package server
 
-import "github.com/fnproject/fn/fnext"
+import (
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
+)
 
 // AddCallListener adds a listener that will be fired before and after a function is executed.
 func (s *Server) AddCallListener(listener fnext.CallListener) {
 	s.agent.AddCallListener(listener)
 }
+
+// AddContainerListener adds a listener that will be fired before a function container is created.
+func (s *Server) AddContainerListener(listener fnext.ContainerListener) {
+	adder, ok := s.agent.(drivers.ContainerListenerAdder)
+	if !ok {
+		logrus.Warn("agent does not run containers, ignoring container listener")
+		return
+	}
+	adder.AddContainerListener(listener)
+}
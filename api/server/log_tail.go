@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// tailHeartbeat is the interval comments are sent at on idle tails, for proxies not to time
+// them out
+const tailHeartbeat = 15 * time.Second
+
+var errLogTailNotSupported = models.NewAPIError(http.StatusNotImplemented, errors.New("Log tailing is not supported by this server"))
+
+// handleLogTail streams the output of the containers of a fn as server sent events, one
+// event with a json models.LogLine per line, until the client disconnects. The containers
+// of the agent of full nodes are tailed, API nodes tail those of the runners they are
+// configured with.
+func (s *Server) handleLogTail(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	fn, err := s.datastore.GetFnByID(ctx, c.Param(api.FnID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	tailer := s.logTailer
+	if tailer == nil {
+		tailer, _ = s.agent.(agent.LogTailer)
+	}
+	if tailer == nil {
+		handleErrorResponse(c, errLogTailNotSupported)
+		return
+	}
+
+	lines, cancel := tailer.TailLogs(fn.ID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(tailHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case line := <-lines:
+			b, err := json.Marshal(line)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", b); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// tailAgent is an agent tailing the lines sent on its lines
+type tailAgent struct {
+	agent.Agent
+	lines     chan *models.LogLine
+	cancelled chan struct{}
+}
+
+func (a *tailAgent) TailLogs(fnID string) (<-chan *models.LogLine, func()) {
+	return a.lines, func() { close(a.cancelled) }
+}
+
+func TestLogTail(t *testing.T) {
+	ds := datastore.NewMockInit(
+		[]*models.App{{ID: "app_id", Name: "myapp"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn"}})
+	a := &tailAgent{lines: make(chan *models.LogLine, 1), cancelled: make(chan struct{})}
+	s := &Server{datastore: ds, agent: a}
+
+	router := gin.New()
+	router.GET("/v2/fns/:fn_id/logs/tail", s.handleLogTail)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v2/fns/missing/logs/tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status %d for a missing fn, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/v2/fns/fn_id/logs/tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	a.lines <- &models.LogLine{FnID: "fn_id", CallID: "call_id", Line: "hello"}
+	event, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line models.LogLine
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &line); err != nil {
+		t.Fatalf("expected a json line event, got %q: %v", event, err)
+	}
+	if line.Line != "hello" || line.CallID != "call_id" {
+		t.Fatalf("expected the line tailed, got %+v", line)
+	}
+
+	resp.Body.Close()
+	<-a.cancelled
+}
+
+func TestLogTailNotSupported(t *testing.T) {
+	ds := datastore.NewMockInit(
+		[]*models.App{{ID: "app_id", Name: "myapp"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn"}})
+	s := &Server{datastore: ds}
+
+	router := gin.New()
+	router.GET("/v2/fns/:fn_id/logs/tail", s.handleLogTail)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v2/fns/fn_id/logs/tail", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotImplemented, rec.Code, rec.Body.String())
+	}
+}
+
+func TestLogTailRunners(t *testing.T) {
+	ds := datastore.NewMockInit(
+		[]*models.App{{ID: "app_id", Name: "myapp"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn"}})
+	// API nodes have no agent, they tail the runners of their load balancers
+	tailer := &tailAgent{lines: make(chan *models.LogLine, 1), cancelled: make(chan struct{})}
+	s := &Server{datastore: ds, logTailer: tailer}
+
+	router := gin.New()
+	router.GET("/v2/fns/:fn_id/logs/tail", s.handleLogTail)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v2/fns/fn_id/logs/tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tailer.lines <- &models.LogLine{FnID: "fn_id", CallID: "call_id", Line: "from a runner"}
+	event, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(event, "from a runner") {
+		t.Fatalf("expected the line of the runner, got %q", event)
+	}
+
+	resp.Body.Close()
+	<-tailer.cancelled
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/sirupsen/logrus"
+)
+
+// minReadyListener provisions the min ready hot containers of the fns created and updated
+// through the node, so that they are warm before the first call of the fns.
+type minReadyListener struct {
+	s           *Server
+	provisioner agent.Provisioner
+}
+
+var _ fnext.FnListener = new(minReadyListener)
+
+func (l *minReadyListener) BeforeFnCreate(ctx context.Context, fn *models.Fn) error { return nil }
+func (l *minReadyListener) BeforeFnUpdate(ctx context.Context, fn *models.Fn) error { return nil }
+func (l *minReadyListener) BeforeFnDelete(ctx context.Context, fnID string) error   { return nil }
+func (l *minReadyListener) AfterFnDelete(ctx context.Context, fnID string) error    { return nil }
+
+func (l *minReadyListener) AfterFnCreate(ctx context.Context, fn *models.Fn) error {
+	l.provision(ctx, fn)
+	return nil
+}
+
+func (l *minReadyListener) AfterFnUpdate(ctx context.Context, fn *models.Fn) error {
+	l.provision(ctx, fn)
+	return nil
+}
+
+// provision launches the min ready containers of fn once the change is committed
+func (l *minReadyListener) provision(ctx context.Context, fn *models.Fn) {
+	if fn.GetMinReady() == 0 {
+		return
+	}
+	models.AfterCommit(ctx, func() {
+		ctx := common.BackgroundContext(ctx)
+		app, err := l.s.datastore.GetAppByID(ctx, fn.AppID)
+		if err == nil {
+			err = l.provisioner.Provision(ctx, app, fn)
+		}
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithField("fn_id", fn.ID).Error("failed to provision min ready containers")
+		}
+	})
+}
+
+// provisionMinReady provisions the min ready hot containers of the fns of all apps, as the
+// node starts, so that they are warm before the first call of the fns.
+func (s *Server) provisionMinReady(ctx context.Context, provisioner agent.Provisioner) {
+	log := common.Logger(ctx)
+	filter := &models.AppFilter{PerPage: applyPageSize}
+	for {
+		apps, err := s.datastore.GetApps(ctx, filter)
+		if err != nil {
+			log.WithError(err).Error("failed to get apps to provision min ready containers")
+			return
+		}
+		for _, app := range apps.Items {
+			fns, err := s.applyFns(ctx, app.ID)
+			if err != nil {
+				log.WithError(err).WithField("app_id", app.ID).Error("failed to get fns to provision min ready containers")
+				continue
+			}
+			for _, fn := range fns {
+				if fn.GetMinReady() == 0 {
+					continue
+				}
+				if err := provisioner.Provision(ctx, app, fn); err != nil {
+					log.WithError(err).WithFields(logrus.Fields{"app_id": app.ID, "fn_id": fn.ID}).Error("failed to provision min ready containers")
+				}
+			}
+		}
+		if apps.NextCursor == "" {
+			return
+		}
+		filter.Cursor = apps.NextCursor
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/fnext"
+)
+
+// provisionerAgent records the fns it is asked to provision
+type provisionerAgent struct {
+	agent.Agent
+
+	mu  sync.Mutex
+	fns []string
+}
+
+func (a *provisionerAgent) AddCallListener(fnext.CallListener) {}
+
+func (a *provisionerAgent) Provision(ctx context.Context, app *models.App, fn *models.Fn) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fns = append(a.fns, fn.ID)
+	return nil
+}
+
+func (a *provisionerAgent) provisioned() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fns := append([]string(nil), a.fns...)
+	a.fns = nil
+	sort.Strings(fns)
+	return fns
+}
+
+func TestProvisionMinReady(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	minReady := uint64(1)
+	warm := &models.Fn{ID: "fn_id1", AppID: "app_id", Name: "warm", Image: "fnproject/fn-test-utils", MinReady: &minReady}
+	cold := &models.Fn{ID: "fn_id2", AppID: "app_id", Name: "cold", Image: "fnproject/fn-test-utils"}
+	warm.SetDefaults()
+	cold.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}}, []*models.Fn{warm, cold})
+	a := &provisionerAgent{}
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), a, ServerTypeFull)
+
+	// the fns with min ready containers are provisioned as the node starts
+	srv.provisionMinReady(context.Background(), a)
+	if fns := a.provisioned(); !reflect.DeepEqual(fns, []string{"fn_id1"}) {
+		t.Fatalf("expected the fn with min ready containers to be provisioned, got %v", fns)
+	}
+
+	request := func(method, path, body string) *models.Fn {
+		_, rec := routerRequest(t, srv.Router, method, path, bytes.NewBufferString(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status code 200, got %d: %s", method, path, rec.Code, rec.Body.String())
+		}
+		var fn models.Fn
+		if err := json.NewDecoder(rec.Body).Decode(&fn); err != nil {
+			t.Fatal(err)
+		}
+		return &fn
+	}
+
+	// and as they are created and updated
+	fn := request("POST", "/v2/fns", `{"app_id": "app_id", "name": "new", "image": "fnproject/fn-test-utils", "min_ready": 2}`)
+	if fns := a.provisioned(); !reflect.DeepEqual(fns, []string{fn.ID}) {
+		t.Fatalf("expected the created fn to be provisioned, got %v", fns)
+	}
+	request("PUT", "/v2/fns/fn_id2", `{"config": {"LEVEL": "debug"}}`)
+	if fns := a.provisioned(); len(fns) != 0 {
+		t.Fatalf("expected fns without min ready containers not to be provisioned, got %v", fns)
+	}
+	request("PUT", "/v2/fns/fn_id2", `{"min_ready": 1}`)
+	if fns := a.provisioned(); !reflect.DeepEqual(fns, []string{"fn_id2"}) {
+		t.Fatalf("expected the updated fn to be provisioned, got %v", fns)
+	}
+}
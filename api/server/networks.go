@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/fnproject/fn/api/agent/drivers"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// NetworkWrapper is the admin API representation of a network in the agent network pool
+type NetworkWrapper struct {
+	ID         string `json:"id"`
+	Containers uint64 `json:"containers"`
+}
+
+// networksSetup adds /networks, which lists and changes the networks of the agent network
+// pool. The pool is only served to admins once requests are authenticated.
+func (s *Server) networksSetup(router *gin.Engine, pool drivers.NetworkPool) {
+	engine := router.Group("/networks")
+	if s.authEnabled() {
+		engine.Use(s.authenticate(models.APIKeyScopeManage), s.requireUnrestrictedAPIKey)
+		if s.rbac {
+			engine.Use(s.authorize(models.PermissionAdmin))
+		}
+	}
+	engine.GET("", handleNetworkList(pool))
+	engine.PUT("/:network", handleNetworkAdd(pool))
+	engine.DELETE("/:network", handleNetworkRemove(pool))
+}
+
+func handleNetworkList(pool drivers.NetworkPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		networks := pool.Networks()
+
+		items := make([]NetworkWrapper, 0, len(networks))
+		for id, count := range networks {
+			items = append(items, NetworkWrapper{ID: id, Containers: count})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	}
+}
+
+func handleNetworkAdd(pool drivers.NetworkPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := pool.AddNetwork(c.Request.Context(), c.Param("network"))
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+
+		c.String(http.StatusNoContent, "")
+	}
+}
+
+func handleNetworkRemove(pool drivers.NetworkPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := pool.RemoveNetwork(c.Request.Context(), c.Param("network"))
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+
+		c.String(http.StatusNoContent, "")
+	}
+}
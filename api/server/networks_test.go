@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+type testNetworkPool struct {
+	networks map[string]uint64
+}
+
+func (p *testNetworkPool) Networks() map[string]uint64 { return p.networks }
+
+func (p *testNetworkPool) AddNetwork(ctx context.Context, id string) error {
+	if _, ok := p.networks[id]; ok {
+		return models.NewAPIError(http.StatusConflict, errors.New("network exists"))
+	}
+	p.networks[id] = 0
+	return nil
+}
+
+func (p *testNetworkPool) RemoveNetwork(ctx context.Context, id string) error {
+	if _, ok := p.networks[id]; !ok {
+		return models.NewAPIError(http.StatusNotFound, errors.New("network not found"))
+	}
+	delete(p.networks, id)
+	return nil
+}
+
+func TestNetworkPoolAdmin(t *testing.T) {
+	pool := &testNetworkPool{networks: map[string]uint64{"net1": 3}}
+	router := gin.New()
+	(&Server{}).networksSetup(router, pool)
+
+	for i, test := range []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{"PUT", "/networks/net2", http.StatusNoContent},
+		{"PUT", "/networks/net2", http.StatusConflict},
+		{"DELETE", "/networks/net1", http.StatusNoContent},
+		{"DELETE", "/networks/net1", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: %s %s expected status %d, got %d: %s", i, test.method, test.path, test.expectedCode, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/networks", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var resp struct {
+		Items []NetworkWrapper `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].ID != "net2" || resp.Items[0].Containers != 0 {
+		t.Fatalf("unexpected networks %+v", resp.Items)
+	}
+}
+
+func TestNetworkPoolAuthentication(t *testing.T) {
+	pool := &testNetworkPool{networks: map[string]uint64{"net1": 3}}
+	s := &Server{apiKeys: true, adminAPIKeyHash: models.HashAPIKeySecret(testAdminAPIKey)}
+	router := gin.New()
+	s.networksSetup(router, pool)
+
+	for i, test := range []struct {
+		method       string
+		path         string
+		secret       string
+		expectedCode int
+	}{
+		{"GET", "/networks", "", http.StatusUnauthorized},
+		{"PUT", "/networks/net2", "", http.StatusUnauthorized},
+		{"DELETE", "/networks/net1", "", http.StatusUnauthorized},
+		{"PUT", "/networks/net2", testAdminAPIKey, http.StatusNoContent},
+		{"DELETE", "/networks/net1", testAdminAPIKey, http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.secret != "" {
+			req.Header.Set("Authorization", "Bearer "+test.secret)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != test.expectedCode {
+			t.Fatalf("Test %d: %s %s expected status %d, got %d: %s", i, test.method, test.path, test.expectedCode, rec.Code, rec.Body.String())
+		}
+	}
+}
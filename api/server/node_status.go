@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// nodeStatusSetup adds /status, which reports the capacity, hot containers and image cache
+// of the agent. The status is only served to admins once requests are authenticated.
+func (s *Server) nodeStatusSetup(router *gin.Engine, sp agent.StatusProvider) {
+	status := router.Group("/status")
+	if s.authEnabled() {
+		status.Use(s.authenticate(models.APIKeyScopeManage), s.requireUnrestrictedAPIKey)
+		if s.rbac {
+			status.Use(s.authorize(models.PermissionAdmin))
+		}
+	}
+	status.GET("", handleNodeStatus(sp))
+}
+
+// handleNodeStatus returns the status of the agent, only listing the function of the
+// fn_id query parameter if set. Load balancers list the status of each of their runners.
+func handleNodeStatus(sp agent.StatusProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := sp.NodeStatus(c.Request.Context())
+		if status == nil {
+			handleErrorResponse(c, models.ErrNodeStatusUnavailable)
+			return
+		}
+
+		if fnID := c.Query("fn_id"); fnID != "" {
+			filterFnStatus(status, fnID)
+			for _, r := range status.Runners {
+				if r.Status != nil {
+					filterFnStatus(r.Status, fnID)
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// filterFnStatus drops the functions of a status but the one with the given id
+func filterFnStatus(status *models.NodeStatus, fnID string) {
+	fns := status.Functions
+	status.Functions = []*models.FnStatus{}
+	for _, fn := range fns {
+		if fn.FnID == fnID {
+			status.Functions = append(status.Functions, fn)
+		}
+	}
+}
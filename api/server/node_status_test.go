@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/fnext"
+	"github.com/gin-gonic/gin"
+)
+
+type statusAgent struct {
+	agent.Agent
+	status *models.NodeStatus
+}
+
+func (a *statusAgent) AddCallListener(fnext.CallListener) {}
+
+func (a *statusAgent) NodeStatus(context.Context) *models.NodeStatus {
+	if a.status == nil {
+		return nil
+	}
+	status := *a.status
+	return &status
+}
+
+func testNodeStatus() *models.NodeStatus {
+	return &models.NodeStatus{
+		InFlight:  2,
+		Resources: models.NodeResources{MemoryTotal: 1024, MemoryUsed: 256, MemoryFree: 768},
+		Functions: []*models.FnStatus{
+			{FnID: "fn1", Containers: map[string]uint64{"busy": 1}, Executing: 1},
+			{FnID: "fn2", Containers: map[string]uint64{"idle": 2}, QueueDepth: 1},
+		},
+		ImageCache: &models.ImageCacheStatus{MaxSize: 100, Images: []*models.CachedImageStatus{{ID: "img", Size: 10, InUse: true}}},
+	}
+}
+
+func TestNodeStatusAdmin(t *testing.T) {
+	sa := &statusAgent{status: testNodeStatus()}
+	router := gin.New()
+	(&Server{}).nodeStatusSetup(router, sa)
+
+	request := func(path string, expectedCode int) *models.NodeStatus {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != expectedCode {
+			t.Fatalf("%s: expected status %d, got %d: %s", path, expectedCode, rec.Code, rec.Body.String())
+		}
+		var status models.NodeStatus
+		if expectedCode == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &status
+	}
+
+	status := request("/status", http.StatusOK)
+	if status.InFlight != 2 || status.Resources.MemoryFree != 768 || len(status.Functions) != 2 ||
+		status.ImageCache == nil || len(status.ImageCache.Images) != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	status = request("/status?fn_id=fn2", http.StatusOK)
+	if len(status.Functions) != 1 || status.Functions[0].FnID != "fn2" || status.Functions[0].Containers["idle"] != 2 {
+		t.Fatalf("expected the status of fn2, got %+v", status.Functions)
+	}
+	status = request("/status?fn_id=nope", http.StatusOK)
+	if status.Functions == nil || len(status.Functions) != 0 {
+		t.Fatalf("expected no functions, got %+v", status.Functions)
+	}
+
+	sa.status = nil
+	request("/status", http.StatusNotImplemented)
+}
+
+func TestNodeStatusAuthentication(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), &statusAgent{status: testNodeStatus()}, ServerTypeFull, WithAPIKeys(testAdminAPIKey))
+
+	request := func(method, path, secret, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		_, rec := routerRequest2(t, srv.AdminRouter, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var appKey, invokeKey models.APIKey
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "deployer", "app_id": "app_id", "scope": "manage"}`, http.StatusOK, &appKey)
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "caller", "scope": "invoke"}`, http.StatusOK, &invokeKey)
+
+	request("GET", "/status", "", "", http.StatusUnauthorized, nil)
+	request("GET", "/status", appKey.Secret, "", http.StatusForbidden, nil)
+	request("GET", "/status", invokeKey.Secret, "", http.StatusForbidden, nil)
+
+	var status models.NodeStatus
+	request("GET", "/status", testAdminAPIKey, "", http.StatusOK, &status)
+	if len(status.Functions) != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
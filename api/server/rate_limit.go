@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers describing the rate limit of invocations, following the IETF RateLimit header fields
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// WithRateLimitStore maps EnvRateLimitStore, the store of the rate limits of invocations.
+func WithRateLimitStore(storeURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		store, err := ratelimit.New(storeURL)
+		if err != nil {
+			return err
+		}
+		s.rateLimits = store
+		return nil
+	}
+}
+
+// WithDefaultRateLimit maps EnvDefaultRateLimit, the rate limit of the apps without one of
+// their own, in the JSON form of the fnproject.io/app/rateLimit annotation.
+func WithDefaultRateLimit(limit string) Option {
+	return func(ctx context.Context, s *Server) error {
+		l, err := parseDefaultRateLimit(limit)
+		if err != nil {
+			return err
+		}
+		s.defaultRateLimit.Store(l)
+		return nil
+	}
+}
+
+func parseDefaultRateLimit(limit string) (*models.RateLimit, error) {
+	if limit == "" {
+		return nil, nil
+	}
+	var l models.RateLimit
+	if err := json.Unmarshal([]byte(limit), &l); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", EnvDefaultRateLimit, err)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", EnvDefaultRateLimit, err)
+	}
+	return &l, nil
+}
+
+// limitInvokeRate is the middleware of the invoke endpoints rejecting the calls over the
+// rate limit of their app or of their API key.
+func (s *Server) limitInvokeRate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// calls of apps or fns that don't exist are reported by the handlers
+	app, err := s.invokedApp(ctx, c)
+	if err != nil {
+		c.Next()
+		return
+	}
+	var key *models.APIKey
+	if k, ok := c.Get(apiKeyContextKey); ok {
+		key = k.(*models.APIKey)
+	}
+
+	res, err := s.takeRateLimits(ctx, app, key)
+	if res != nil {
+		setRateLimitHeaders(c.Writer.Header(), res)
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) invokedApp(ctx context.Context, c *gin.Context) (*models.App, error) {
+	appID := c.Param(api.AppID)
+	if appName := c.Param(api.AppName); appName != "" {
+		var err error
+		appID, err = s.lbReadAccess.GetAppID(ctx, appName)
+		if err != nil {
+			return nil, err
+		}
+	} else if fnID := c.Param(api.FnID); fnID != "" {
+		fn, err := s.lbReadAccess.GetFnByID(ctx, fnID)
+		if err != nil {
+			return nil, err
+		}
+		appID = fn.AppID
+	}
+	return s.lbReadAccess.GetAppByID(ctx, appID)
+}
+
+// takeRateLimits takes a call from the rate limit of the API key a call was invoked with and
+// from the rate limit of its app, returning models.ErrRateLimited if either is exceeded. A call
+// rejected by the limit of its app is refunded to the limit of its API key. The result is the
+// one of the most restrictive limit, nil if no limit applies.
+func (s *Server) takeRateLimits(ctx context.Context, app *models.App, key *models.APIKey) (*ratelimit.Result, error) {
+	var res *ratelimit.Result
+	take := func(bucket string, limit ratelimit.Limit) bool {
+		r, err := s.rateLimits.Take(ctx, bucket, limit)
+		if err != nil {
+			// calls are not rejected because the store of the limits is unavailable
+			common.Logger(ctx).WithError(err).Error("failed to take rate limit")
+			return true
+		}
+		if res == nil || !r.Allowed || (res.Allowed && r.Remaining < res.Remaining) {
+			res = r
+		}
+		return r.Allowed
+	}
+
+	var keyBucket string
+	var keyLimit ratelimit.Limit
+	if key != nil && key.RateLimit != nil {
+		keyBucket = "apikey/" + key.ID
+		keyLimit = ratelimit.Limit{Rate: key.RateLimit.Rate, Burst: key.RateLimit.BurstOrDefault()}
+		if !take(keyBucket, keyLimit) {
+			return res, models.ErrRateLimited
+		}
+	}
+	// invalid annotations are rejected when apps are changed
+	limit, _ := models.RateLimitFromAnnotations(app.Annotations)
+	if limit == nil {
+		limit, _ = s.defaultRateLimit.Load().(*models.RateLimit)
+	}
+	if limit != nil {
+		if !take("app/"+app.ID, ratelimit.Limit{Rate: limit.Rate, Burst: limit.BurstOrDefault()}) {
+			if keyBucket != "" {
+				if err := s.rateLimits.Refund(ctx, keyBucket, keyLimit); err != nil {
+					common.Logger(ctx).WithError(err).Error("failed to refund rate limit")
+				}
+			}
+			return res, models.ErrRateLimited
+		}
+	}
+	return res, nil
+}
+
+func setRateLimitHeaders(h http.Header, res *ratelimit.Result) {
+	h.Set(RateLimitLimitHeader, strconv.Itoa(res.Limit))
+	h.Set(RateLimitRemainingHeader, strconv.Itoa(res.Remaining))
+	h.Set(RateLimitResetHeader, strconv.FormatInt(ceilSeconds(res.Reset), 10))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimits(t *testing.T) {
+	limited := &models.App{ID: "app_id", Name: "myapp"}
+	limited.Annotations, _ = limited.Annotations.With(models.AppRateLimitAnnotation, models.RateLimit{Rate: 1, Burst: 2})
+	ds := datastore.NewMockInit(
+		[]*models.App{limited, {ID: "other_id", Name: "other"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn"}, {ID: "other_fn_id", AppID: "other_id", Name: "myfn"}},
+	)
+	s := &Server{lbReadAccess: agent.NewCachedDataAccess(ds), rateLimits: ratelimit.NewMemoryStore()}
+	key := &models.APIKey{ID: "key_id", RateLimit: &models.RateLimit{Rate: 0.1}}
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.Set(apiKeyContextKey, key)
+		}
+	})
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "") }
+	engine.POST("/invoke/:fn_id", s.limitInvokeRate, ok)
+	engine.POST("/t/:app_name", s.limitInvokeRate, ok)
+
+	call := func(path string, withKey bool, expectedCode int, remaining string) http.Header {
+		req := httptest.NewRequest("POST", path, nil)
+		if withKey {
+			req.Header.Set("Authorization", "Bearer fnk_key")
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s: expected status code %d, got %d: %s", path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get(RateLimitRemainingHeader) != remaining {
+			t.Fatalf("%s: expected %s %q, got %q", path, RateLimitRemainingHeader, remaining, rec.Header().Get(RateLimitRemainingHeader))
+		}
+		return rec.Header()
+	}
+
+	h := call("/invoke/fn_id", false, http.StatusOK, "1")
+	if h.Get(RateLimitLimitHeader) != "2" || h.Get(RateLimitResetHeader) != "1" {
+		t.Fatalf("expected rate limit headers of the app, got %v", h)
+	}
+	call("/invoke/fn_id", false, http.StatusOK, "0")
+	// triggers and fns of an app share its limit
+	h = call("/t/myapp", false, http.StatusTooManyRequests, "0")
+	if h.Get("Retry-After") != "1" {
+		t.Fatalf("expected Retry-After 1, got %q", h.Get("Retry-After"))
+	}
+	// calls rejected by the limit of their app are refunded to the limit of their API key
+	call("/t/myapp", true, http.StatusTooManyRequests, "0")
+
+	// apps without a limit are only limited by the limits of the API keys
+	call("/invoke/other_fn_id", false, http.StatusOK, "")
+	call("/invoke/other_fn_id", true, http.StatusOK, "0")
+	h = call("/invoke/other_fn_id", true, http.StatusTooManyRequests, "0")
+	if h.Get("Retry-After") != "10" || h.Get(RateLimitLimitHeader) != "1" {
+		t.Fatalf("expected the headers of the key limit, got %v", h)
+	}
+	call("/invoke/other_fn_id", false, http.StatusOK, "")
+
+	// the default limit applies to the apps without one
+	s.defaultRateLimit.Store(&models.RateLimit{Rate: 1})
+	call("/invoke/other_fn_id", false, http.StatusOK, "0")
+	call("/invoke/other_fn_id", false, http.StatusTooManyRequests, "0")
+
+	// calls of fns that don't exist are left to the handler
+	call("/invoke/nope", false, http.StatusOK, "")
+}
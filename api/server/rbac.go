@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// roleGrantsContextKey is the gin context key of the role bindings of the caller of a request
+const roleGrantsContextKey = "fn_role_grants"
+
+// WithRBAC maps EnvRBAC and EnvRBACAdmins. The operations of authenticated callers are then
+// only allowed by the roles bound to them, admins are bound the admin role on all apps to
+// bind the first roles with.
+func WithRBAC(admins []string) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.rbac = true
+		s.rbacAdmins = make(map[string]bool)
+		for _, a := range admins {
+			if a != "" {
+				s.rbacAdmins[a] = true
+			}
+		}
+		logrus.WithField("admins", admins).Info("authorizing requests with role bindings")
+		return nil
+	}
+}
+
+// roleGrants are the role bindings of a principal
+type roleGrants []*models.RoleBinding
+
+// allows returns true if a binding allows the operations of permission on the resources of
+// app appID, or on resources of no single app if appID is empty.
+func (g roleGrants) allows(appID, permission string) bool {
+	for _, b := range g {
+		if b.Allows(appID, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAny returns true if a binding allows the operations of permission on some app
+func (g roleGrants) allowsAny(permission string) bool {
+	for _, b := range g {
+		if models.RoleAllows(b.Role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// principalGrants returns the role bindings of the principal of identity id, authenticated
+// with API key key if not nil. The principal of API keys is taken from the key itself, and
+// no other identity may pass for one.
+func (s *Server) principalGrants(ctx context.Context, id *fnext.Identity, key *models.APIKey) (roleGrants, error) {
+	if id == nil || id.Subject == "" {
+		return nil, nil
+	}
+	principal := id.Subject
+	if key != nil {
+		principal = apiKeySubjectPrefix + key.ID
+		// the admin API key is the bootstrap key of API keys, so it is an admin too
+		if s.adminAPIKeyHash != "" && key.ID == adminAPIKeyID {
+			return roleGrants{{Principal: principal, Role: models.RoleAdmin}}, nil
+		}
+	} else if strings.HasPrefix(principal, apiKeySubjectPrefix) {
+		return nil, nil
+	}
+	if s.rbacAdmins[principal] {
+		return roleGrants{{Principal: principal, Role: models.RoleAdmin}}, nil
+	}
+
+	var grants roleGrants
+	filter := &models.RoleBindingFilter{Principal: principal, PerPage: 100}
+	for {
+		bindings, err := s.datastore.GetRoleBindings(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, bindings.Items...)
+		if bindings.NextCursor == "" {
+			return grants, nil
+		}
+		filter.Cursor = bindings.NextCursor
+	}
+}
+
+// requestGrants returns the role bindings of the caller of an authenticated request
+func (s *Server) requestGrants(c *gin.Context) (roleGrants, error) {
+	if grants, ok := c.Get(roleGrantsContextKey); ok {
+		return grants.(roleGrants), nil
+	}
+	var key *models.APIKey
+	if k, ok := c.Get(apiKeyContextKey); ok {
+		key = k.(*models.APIKey)
+	}
+	grants, err := s.principalGrants(c.Request.Context(), fnext.GetIdentity(c.Request.Context()), key)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(roleGrantsContextKey, grants)
+	return grants, nil
+}
+
+// authorize returns the middleware rejecting the requests the roles of their caller don't
+// allow the operations of permission for, on the app of the request.
+func (s *Server) authorize(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.checkAccess(c, permission); err != nil {
+			handleErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeAllApps returns the middleware rejecting the requests the roles of their caller
+// don't allow the operations of permission for on all apps, whatever the app of the request.
+func (s *Server) authorizeAllApps(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		grants, err := s.requestGrants(c)
+		if err == nil && !grants.allows("", permission) {
+			err = models.ErrAccessDenied
+		}
+		if err != nil {
+			handleErrorResponse(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeManage is the middleware of the management endpoints, reading requires the read
+// permission and anything else the write permission. Listing apps and searching across them
+// is allowed to callers that may read some app, the handlers only return those apps.
+func (s *Server) authorizeManage(c *gin.Context) {
+	permission := models.PermissionWrite
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		permission = models.PermissionRead
+	}
+
+	err := s.checkAccess(c, permission)
+	if err == models.ErrAccessDenied && permission == models.PermissionRead && (c.Request.URL.Path == "/v2/apps" || c.Request.URL.Path == "/v2/search") {
+		grants, _ := s.requestGrants(c)
+		if grants.allowsAny(models.PermissionRead) {
+			err = nil
+		}
+	}
+	if err != nil {
+		handleErrorResponse(c, err)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) checkAccess(c *gin.Context, permission string) error {
+	grants, err := s.requestGrants(c)
+	if err != nil {
+		return err
+	}
+	appID, err := s.requestAppID(c)
+	if err != nil {
+		return err
+	}
+	if !grants.allows(appID, permission) {
+		return models.ErrAccessDenied
+	}
+	return nil
+}
+
+// readableApps returns the apps of a list the caller of a request may read
+func (s *Server) readableApps(c *gin.Context, apps []*models.App) []*models.App {
+	grants, ok := c.Get(roleGrantsContextKey)
+	if !ok || grants.(roleGrants).allows("", models.PermissionRead) {
+		return apps
+	}
+
+	readable := []*models.App{}
+	for _, app := range apps {
+		if grants.(roleGrants).allows(app.ID, models.PermissionRead) {
+			readable = append(readable, app)
+		}
+	}
+	return readable
+}
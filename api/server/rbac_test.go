@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/oidc"
+	"github.com/fnproject/fn/api/oidc/oidctest"
+	invoke "github.com/fnproject/fn/api/server/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoleBasedAccessControl(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	iss := oidctest.NewIssuer()
+	defer iss.Close()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}, {ID: "other_id", Name: "other"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithOIDC(iss.URL, "", ""), WithRBAC([]string{"root"}))
+
+	request := func(method, path, subject, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+iss.Token(iss.Claims(subject)))
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s as %s: expected status code %d, got %d: %s", method, path, subject, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var readerBinding models.RoleBinding
+	request("POST", "/v2/rolebindings", "root", `{"principal": "alice", "role": "deployer", "app_id": "app_id"}`, http.StatusOK, nil)
+	request("POST", "/v2/rolebindings", "root", `{"principal": "bob", "role": "reader", "app_id": "app_id"}`, http.StatusOK, &readerBinding)
+	request("POST", "/v2/rolebindings", "root", `{"principal": "carol", "role": "invoker", "app_id": "app_id"}`, http.StatusOK, nil)
+	request("POST", "/v2/rolebindings", "root", `{"principal": "erin", "role": "admin", "app_id": "app_id"}`, http.StatusOK, nil)
+	request("POST", "/v2/rolebindings", "root", `{"principal": "bob", "role": "reader", "app_id": "app_id"}`, http.StatusConflict, nil)
+	request("POST", "/v2/rolebindings", "root", `{"principal": "bob", "role": "owner"}`, http.StatusBadRequest, nil)
+
+	// deployers read and change the resources of their apps only
+	request("GET", "/v2/apps/app_id", "alice", "", http.StatusOK, nil)
+	var fn models.Fn
+	request("POST", "/v2/fns", "alice", `{"name": "myfn", "app_id": "app_id", "image": "fnproject/hello"}`, http.StatusOK, &fn)
+	request("GET", "/v2/apps/other_id", "alice", "", http.StatusForbidden, nil)
+	request("POST", "/v2/fns", "alice", `{"name": "myfn", "app_id": "other_id", "image": "fnproject/hello"}`, http.StatusForbidden, nil)
+	request("POST", "/v2/apps", "alice", `{"name": "newapp"}`, http.StatusForbidden, nil)
+	request("POST", "/v2/rolebindings", "alice", `{"principal": "alice", "role": "admin", "app_id": "app_id"}`, http.StatusForbidden, nil)
+
+	var apps models.AppList
+	request("GET", "/v2/apps", "alice", "", http.StatusOK, &apps)
+	if len(apps.Items) != 1 || apps.Items[0].ID != "app_id" {
+		t.Fatalf("expected only the app of alice, got %+v", apps.Items)
+	}
+	request("GET", "/v2/apps", "root", "", http.StatusOK, &apps)
+	if len(apps.Items) != 2 {
+		t.Fatalf("expected all apps, got %+v", apps.Items)
+	}
+
+	// searches only find the resources of the apps of the caller, and page over them only
+	var results models.SearchResultList
+	request("GET", "/v2/search?q=other&per_page=1", "alice", "", http.StatusOK, &results)
+	if len(results.Items) != 0 || results.NextCursor != "" {
+		t.Fatalf("expected no result from the apps of others, got %+v", results)
+	}
+	request("GET", "/v2/search?q=my&per_page=1", "alice", "", http.StatusOK, &results)
+	if len(results.Items) != 1 || results.Items[0].AppID != "app_id" || results.NextCursor == "" {
+		t.Fatalf("expected a full page of the resources of alice, got %+v", results)
+	}
+	request("GET", "/v2/search?q=other", "root", "", http.StatusOK, &results)
+	if len(results.Items) != 1 || results.Items[0].ID != "other_id" {
+		t.Fatalf("expected the apps of others for admins, got %+v", results)
+	}
+
+	// readers only read, invokers only invoke and strangers do nothing
+	request("GET", "/v2/fns/"+fn.ID, "bob", "", http.StatusOK, nil)
+	request("PUT", "/v2/fns/"+fn.ID, "bob", `{"memory": 256}`, http.StatusForbidden, nil)
+	request("GET", "/v2/apps/app_id", "carol", "", http.StatusForbidden, nil)
+	request("GET", "/v2/apps", "dave", "", http.StatusForbidden, nil)
+
+	// admins of an app bind roles on it
+	request("POST", "/v2/rolebindings", "erin", `{"principal": "dave", "role": "reader", "app_id": "app_id"}`, http.StatusOK, nil)
+	request("POST", "/v2/rolebindings", "erin", `{"principal": "dave", "role": "reader"}`, http.StatusForbidden, nil)
+	// the app of writes is that of their body, which the query may not contradict
+	request("POST", "/v2/rolebindings?app_id=app_id", "erin", `{"principal": "erin", "role": "admin"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/rolebindings?app_id=app_id", "erin", `{"principal": "erin", "role": "admin", "app_id": "other_id"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/fns?app_id=app_id", "alice", `{"name": "otherfn", "app_id": "other_id", "image": "fnproject/hello"}`, http.StatusBadRequest, nil)
+	request("PUT", "/v2/fns/"+fn.ID, "alice", `{"app_id": "other_id"}`, http.StatusBadRequest, nil)
+	var otherFn models.Fn
+	request("POST", "/v2/fns", "root", `{"name": "otherfn", "app_id": "other_id", "image": "fnproject/hello"}`, http.StatusOK, &otherFn)
+	request("POST", "/v2/triggers", "alice", `{"name": "t", "app_id": "app_id", "fn_id": "`+otherFn.ID+`", "type": "http", "source": "/t"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/triggers", "alice", `{"name": "t", "fn_id": "`+otherFn.ID+`", "type": "http", "source": "/t"}`, http.StatusForbidden, nil)
+
+	var bindings models.RoleBindingList
+	request("GET", "/v2/rolebindings?app_id=app_id", "erin", "", http.StatusOK, &bindings)
+	if len(bindings.Items) != 5 {
+		t.Fatalf("expected 5 bindings on the app, got %+v", bindings.Items)
+	}
+	request("GET", "/v2/rolebindings", "erin", "", http.StatusForbidden, nil)
+	request("DELETE", "/v2/rolebindings/"+readerBinding.ID, "erin", "", http.StatusNoContent, nil)
+	request("GET", "/v2/fns/"+fn.ID, "bob", "", http.StatusForbidden, nil)
+
+	// hostnames are not of an app, admins of an app can't map them
+	request("POST", "/v2/domains", "erin", `{"hostname": "api.example.com", "app_id": "app_id"}`, http.StatusForbidden, nil)
+
+	// archives span apps, admins of an app can't export or import them
+	request("GET", "/v2/export?app_id=app_id", "erin", "", http.StatusForbidden, nil)
+	request("POST", "/v2/import?strategy=overwrite", "erin", `{"apps": []}`, http.StatusForbidden, nil)
+	request("GET", "/v2/export", "root", "", http.StatusOK, nil)
+
+	// tokens of the issuer can't pass for api keys, whose bindings are their own
+	var keyBinding models.RoleBinding
+	request("POST", "/v2/rolebindings", "root", `{"principal": "apikey/admin", "role": "admin"}`, http.StatusOK, &keyBinding)
+	request("GET", "/v2/apps/app_id", "apikey/admin", "", http.StatusUnauthorized, nil)
+	request("DELETE", "/v2/rolebindings/"+keyBinding.ID, "root", "", http.StatusNoContent, nil)
+
+	// bindings go with their app
+	request("DELETE", "/v2/apps/app_id", "root", "", http.StatusNoContent, nil)
+	request("GET", "/v2/rolebindings", "root", "", http.StatusOK, &bindings)
+	if len(bindings.Items) != 0 {
+		t.Fatalf("expected the bindings of the app to be removed, got %+v", bindings.Items)
+	}
+
+	t.Run("grpc invoke", func(t *testing.T) {
+		ctx := context.Background()
+		ds := datastore.NewMockInit(
+			[]*models.App{{ID: "app_id", Name: "myapp"}},
+			[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn", Image: "fnproject/hello"}},
+		)
+		if _, err := ds.InsertRoleBinding(ctx, &models.RoleBinding{Principal: "alice", Role: models.RoleDeployer, AppID: "app_id"}); err != nil {
+			t.Fatal(err)
+		}
+		v, err := oidc.NewVerifier(oidc.Config{Issuer: iss.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Server{lbReadAccess: agent.NewCachedDataAccess(ds), datastore: ds, tokenVerifier: v, rbac: true}
+
+		lsnr, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs := grpc.NewServer()
+		invoke.RegisterInvokerServer(gs, &grpcInvoker{s: s})
+		go gs.Serve(lsnr)
+		defer gs.Stop()
+
+		conn, err := grpc.Dial(lsnr.Addr().String(), grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		client := invoke.NewInvokerClient(conn)
+
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+iss.Token(iss.Claims("alice")))
+		_, err = client.Invoke(ctx, &invoke.InvokeRequest{FnId: "fn_id"})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("deployers should not invoke, got: %v", err)
+		}
+	})
+}
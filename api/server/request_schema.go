@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fnproject/fn/api/jsonschema"
+	"github.com/fnproject/fn/api/models"
+)
+
+// maxValidatedBodySize is the largest request body of the calls of functions with a request
+// schema, bodies are read and parsed before running the function.
+const maxValidatedBodySize = 6 * 1024 * 1024
+
+// compiledSchema is the compiled request schema of a function, along with its source
+type compiledSchema struct {
+	raw    string
+	schema *jsonschema.Schema
+}
+
+// validateRequest rejects the calls of fn whose request body does not match its request
+// schema, before they take a container, see models.Fn RequestSchema. The body is put back
+// for the call otherwise.
+func (s *Server) validateRequest(req *http.Request, fn *models.Fn) error {
+	if fn.RequestSchema.IsEmpty() {
+		return nil
+	}
+	schema, err := s.requestSchema(fn)
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxValidatedBodySize+1))
+	if err != nil {
+		return models.ErrInvalidPayload
+	}
+	if len(body) > maxValidatedBodySize {
+		return models.ErrRequestContentTooBig
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	errs := schema.Validate(body)
+	if len(errs) == 0 {
+		return nil
+	}
+	verr := &models.RequestValidationError{Errors: make([]models.ValidationError, len(errs))}
+	for i, e := range errs {
+		verr.Errors[i] = models.ValidationError{Path: e.Path, Message: e.Message}
+	}
+	return verr
+}
+
+// requestSchema returns the compiled request schema of fn, compiling it once per change
+func (s *Server) requestSchema(fn *models.Fn) (*jsonschema.Schema, error) {
+	if v, ok := s.requestSchemas.Get(fn.ID); ok {
+		if c := v.(*compiledSchema); c.raw == string(fn.RequestSchema) {
+			return c.schema, nil
+		}
+	}
+	schema, err := fn.RequestSchema.Compile()
+	if err != nil {
+		// schemas are validated when functions are changed
+		return nil, models.ErrFnsInvalidRequestSchema(err.Error())
+	}
+	s.requestSchemas.SetDefault(fn.ID, &compiledSchema{raw: string(fn.RequestSchema), schema: schema})
+	return schema, nil
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/patrickmn/go-cache"
+)
+
+func TestValidateRequest(t *testing.T) {
+	s := &Server{requestSchemas: cache.New(time.Minute, time.Minute)}
+	fn := &models.Fn{
+		ID:            "fn_id",
+		RequestSchema: models.RequestSchema(`{"type": "object", "properties": {"n": {"type": "integer"}}, "required": ["n"]}`),
+	}
+
+	// matching bodies are passed on to the call
+	req := httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(`{"n": 1}`))
+	if err := s.validateRequest(req, fn); err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != `{"n": 1}` {
+		t.Fatalf("expected the body to be read by the call, got %q", body)
+	}
+
+	// other bodies are rejected with their errors
+	req = httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(`{"n": "one", "m": {}}`))
+	err := s.validateRequest(req, fn)
+	verr, ok := err.(*models.RequestValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	expected := []models.ValidationError{{Path: "/n", Message: "expected integer, got string"}}
+	if !reflect.DeepEqual(verr.Errors, expected) {
+		t.Fatalf("expected errors %v, got %v", expected, verr.Errors)
+	}
+
+	rec := httptest.NewRecorder()
+	HandleErrorResponse(req.Context(), rec, err)
+	var body models.Error
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Message != models.ErrRequestSchemaMismatch.Error() || !reflect.DeepEqual(body.Errors, expected) {
+		t.Fatalf("unexpected error response %d %+v", rec.Code, body)
+	}
+
+	// the schema is compiled again once changed
+	fn.RequestSchema = models.RequestSchema(`{"type": "array"}`)
+	req = httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(`[]`))
+	if err := s.validateRequest(req, fn); err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+
+	// large bodies are rejected
+	req = httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(strings.Repeat(" ", maxValidatedBodySize+1)))
+	if err := s.validateRequest(req, fn); err != models.ErrRequestContentTooBig {
+		t.Fatalf("expected %v, got %v", models.ErrRequestContentTooBig, err)
+	}
+
+	// functions without a schema take any body
+	req = httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(`not json`))
+	if err := s.validateRequest(req, &models.Fn{ID: "other"}); err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+}
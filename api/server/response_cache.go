@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/responsecache"
+)
+
+// maxCachedBodySize is the largest request and response bodies of the calls answered from the
+// response cache, calls with larger bodies run their function.
+const maxCachedBodySize = 1024 * 1024
+
+// WithResponseCacheStore maps EnvResponseCacheStore, the store of the cached responses of functions.
+func WithResponseCacheStore(storeURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		store, err := responsecache.New(storeURL)
+		if err != nil {
+			return err
+		}
+		s.responseCache = store
+		return nil
+	}
+}
+
+// cachedCall is a call of a function with a response cache, see models.FnResponseCacheAnnotation
+type cachedCall struct {
+	key    string
+	config *models.ResponseCache
+	// header holds the headers of the response set before the call, which are not cached
+	header http.Header
+}
+
+// lookupResponse returns the cached response of a call of fn if there is one, or the call to
+// cache the response of otherwise. The call is nil if the responses of fn are not cached.
+func (s *Server) lookupResponse(resp http.ResponseWriter, req *http.Request, fn *models.Fn) (*cachedCall, *responsecache.Response) {
+	// invalid annotations are rejected when functions are changed
+	config, _ := models.ResponseCacheFromAnnotations(fn.Annotations)
+	if config == nil {
+		return nil, nil
+	}
+	key, ok := responseCacheKey(req, fn, config)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx := req.Context()
+	res, err := s.responseCache.Get(ctx, key)
+	if err != nil {
+		// calls run their function if the cache is unavailable
+		common.Logger(ctx).WithError(err).Error("failed to get cached response")
+	}
+	if res != nil {
+		return nil, res
+	}
+	return &cachedCall{key: key, config: config, header: cloneHeader(resp.Header())}, nil
+}
+
+// storeResponse caches the response of a call if its status is one of the cached ones
+func (s *Server) storeResponse(ctx context.Context, call *cachedCall, header http.Header, status int, body []byte) {
+	// the status of HTTP trigger responses is set by the function, see triggerResponseWriter
+	effective := status
+	if v := header.Get("Fn-Http-Status"); v != "" {
+		effective, _ = strconv.Atoi(v)
+	}
+	if !call.config.Caches(effective) || len(body) > maxCachedBodySize {
+		return
+	}
+
+	res := &responsecache.Response{
+		Status: status,
+		Header: make(http.Header, len(header)),
+		Body:   append([]byte(nil), body...),
+	}
+	for k, vs := range header {
+		if k == "Fn-Call-Id" || k == "Content-Length" || equalValues(call.header[k], vs) {
+			continue
+		}
+		res.Header[k] = append([]string(nil), vs...)
+	}
+	ttl := time.Duration(call.config.TTL) * time.Second
+	if err := s.responseCache.Set(ctx, call.key, res, ttl); err != nil {
+		common.Logger(ctx).WithError(err).Error("failed to cache response")
+	}
+}
+
+// writeCachedResponse writes a cached response as the function would have
+func writeCachedResponse(resp http.ResponseWriter, res *responsecache.Response) {
+	// the cached response is shared by the callers, and the header may be changed as it is written
+	for k, vs := range res.Header {
+		resp.Header()[k] = append([]string(nil), vs...)
+	}
+	resp.Header().Set("Content-Length", strconv.Itoa(len(res.Body)))
+	if res.Status > 0 {
+		resp.WriteHeader(res.Status)
+	}
+	resp.Write(res.Body)
+}
+
+// responseCacheKey returns the key of the responses of the calls of fn with the method, path,
+// query, vary headers and body of req, reading its body. Requests with large bodies are not
+// cached.
+func responseCacheKey(req *http.Request, fn *models.Fn, config *models.ResponseCache) (string, bool) {
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxCachedBodySize+1))
+	if err != nil || len(body) > maxCachedBodySize {
+		// the call reads the rest of the body, or the error
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		return "", false
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s\n", fn.Revision, req.Method, req.URL.Path, req.URL.RawQuery)
+	for _, name := range config.VaryHeaders {
+		fmt.Fprintf(h, "%s: %s\n", strings.ToLower(name), strings.Join(varyHeaderValues(req.Header, name), ","))
+	}
+	h.Write(body)
+	return fn.ID + "/" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// varyHeaderValues returns the values of a request header, the request headers of HTTP
+// triggers being prefixed by Fn-Http-H-.
+func varyHeaderValues(h http.Header, name string) []string {
+	if vs, ok := h[http.CanonicalHeaderKey("Fn-Http-H-"+name)]; ok {
+		return vs
+	}
+	return h[http.CanonicalHeaderKey(name)]
+}
+
+func cloneHeader(h http.Header) http.Header {
+	cl := make(http.Header, len(h))
+	for k, vs := range h {
+		cl[k] = append([]string(nil), vs...)
+	}
+	return cl
+}
+
+func equalValues(a, b []string) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/responsecache"
+)
+
+func TestResponseCache(t *testing.T) {
+	s := &Server{responseCache: responsecache.NewMemoryStore(responsecache.DefaultMemorySize)}
+	annotations, err := models.EmptyAnnotations().With(models.FnResponseCacheAnnotation, map[string]interface{}{
+		"ttl":          60,
+		"vary_headers": []string{"Accept"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := &models.Fn{ID: "fn_id", Revision: 1, Annotations: annotations}
+
+	request := func(body, accept string) *http.Request {
+		req := httptest.NewRequest("POST", "/invoke/fn_id?q=1", strings.NewReader(body))
+		req.Header.Set("Accept", accept)
+		return req
+	}
+
+	// the first call runs the function and caches its response
+	req := request("hello", "text/plain")
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Fn-Call-Id", "call_id")
+	call, res := s.lookupResponse(rec, req, fn)
+	if call == nil || res != nil {
+		t.Fatalf("expected a miss, got %v %v", call, res)
+	}
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != "hello" {
+		t.Fatalf("expected the body to be read by the call, got %q", body)
+	}
+	header := http.Header{
+		"Fn-Call-Id":     {"call_id"},
+		"Content-Type":   {"text/plain"},
+		"Content-Length": {"5"},
+	}
+	s.storeResponse(context.Background(), call, header, http.StatusOK, []byte("world"))
+
+	// calls with the same request are answered from the cache
+	rec = httptest.NewRecorder()
+	call, res = s.lookupResponse(rec, request("hello", "text/plain"), fn)
+	if call != nil || res == nil {
+		t.Fatalf("expected a hit, got %v %v", call, res)
+	}
+	writeCachedResponse(rec, res)
+	if rec.Code != http.StatusOK || rec.Body.String() != "world" ||
+		rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Fn-Call-Id") != "" {
+		t.Fatalf("unexpected cached response %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	// other bodies, vary headers and revisions are cached separately
+	for _, req := range []*http.Request{request("bye", "text/plain"), request("hello", "application/json")} {
+		if call, _ := s.lookupResponse(httptest.NewRecorder(), req, fn); call == nil {
+			t.Fatal("expected a miss")
+		}
+	}
+	fn2 := *fn
+	fn2.Revision = 2
+	if call, _ := s.lookupResponse(httptest.NewRecorder(), request("hello", "text/plain"), &fn2); call == nil {
+		t.Fatal("expected a miss for a new revision")
+	}
+
+	// errors of HTTP triggers are not cached
+	req = request("error", "text/plain")
+	call, _ = s.lookupResponse(httptest.NewRecorder(), req, fn)
+	s.storeResponse(context.Background(), call, http.Header{"Fn-Http-Status": {"500"}}, http.StatusOK, []byte("oops"))
+	if call, _ := s.lookupResponse(httptest.NewRecorder(), request("error", "text/plain"), fn); call == nil {
+		t.Fatal("expected errors not to be cached")
+	}
+
+	// functions without a response cache are not cached
+	if call, res := s.lookupResponse(httptest.NewRecorder(), request("hello", "text/plain"), &models.Fn{ID: "fn_id"}); call != nil || res != nil {
+		t.Fatal("expected responses not to be cached")
+	}
+}
+
+func TestResponseCacheLargeBody(t *testing.T) {
+	body := strings.Repeat("a", maxCachedBodySize+10)
+	req := httptest.NewRequest("POST", "/invoke/fn_id", strings.NewReader(body))
+	if _, ok := responseCacheKey(req, &models.Fn{ID: "fn_id"}, &models.ResponseCache{TTL: 1}); ok {
+		t.Fatal("expected large bodies not to be cached")
+	}
+	if read, _ := ioutil.ReadAll(req.Body); string(read) != body {
+		t.Fatalf("expected the whole body to be read by the call, got %d bytes", len(read))
+	}
+}
+
+func TestWriteCachedResponseCopiesHeader(t *testing.T) {
+	res := &responsecache.Response{
+		Status: http.StatusOK,
+		Header: http.Header{"X-Greeting": {"hello"}},
+		Body:   []byte("hi"),
+	}
+	rec := httptest.NewRecorder()
+	writeCachedResponse(rec, res)
+
+	rec.Header()["X-Greeting"][0] = "changed"
+	rec.Header().Add("X-Greeting", "more")
+	if len(res.Header["X-Greeting"]) != 1 || res.Header.Get("X-Greeting") != "hello" {
+		t.Fatalf("expected the cached header to be left alone, got %v", res.Header)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleRoleBindingCreate binds a role to a principal, on an app or on all apps.
+func (s *Server) handleRoleBindingCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	binding := &models.RoleBinding{}
+
+	err := c.BindJSON(binding)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	// a binding of no app is one of all apps
+	check := s.checkRequestApp(c, binding.AppID, "")
+	if binding.AppID == "" {
+		check = s.checkRequestAllApps(c)
+	}
+	if check != nil {
+		handleErrorResponse(c, check)
+		return
+	}
+
+	bindingCreated, err := s.datastore.InsertRoleBinding(ctx, binding)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bindingCreated)
+}
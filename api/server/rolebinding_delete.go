@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleRoleBindingDelete unbinds a role, requests of its principal are no longer allowed by it right away.
+func (s *Server) handleRoleBindingDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.datastore.RemoveRoleBinding(ctx, c.Param(api.RoleBindingID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleRoleBindingList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.RoleBindingFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.AppID = c.Query("app_id")
+	filter.Principal = c.Query("principal")
+
+	bindings, err := s.datastore.GetRoleBindings(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bindings)
+}
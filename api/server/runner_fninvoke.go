@@ -1,8 +1,11 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"sync"
@@ -11,6 +14,7 @@ import (
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/responsecache"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/tag"
@@ -43,6 +47,64 @@ func (s *syncResponseWriter) Header() http.Header  { return s.headers }
 func (s *syncResponseWriter) WriteHeader(code int) { s.status = code }
 func (s *syncResponseWriter) Status() int          { return s.status }
 
+// implements http.ResponseWriter
+// streamResponseWriter writes the response of functions that stream it straight to the
+// client, flushing every chunk the function writes. Once the status is written, errors
+// can no longer be reported to the client.
+type streamResponseWriter struct {
+	inner   http.ResponseWriter
+	status  int
+	written bool
+}
+
+var _ http.ResponseWriter = new(streamResponseWriter)
+
+func (s *streamResponseWriter) Header() http.Header { return s.inner.Header() }
+func (s *streamResponseWriter) Status() int         { return s.status }
+
+func (s *streamResponseWriter) WriteHeader(code int) {
+	if s.written {
+		return
+	}
+	s.written = true
+	s.status = code
+	s.inner.WriteHeader(code)
+}
+
+func (s *streamResponseWriter) Write(b []byte) (int, error) {
+	if !s.written {
+		s.WriteHeader(s.status)
+	}
+	n, err := s.inner.Write(b)
+	if f, ok := s.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+// Hijack takes over the client connection for WebSocket calls
+func (s *streamResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := s.inner.(http.Hijacker)
+	if !ok {
+		return nil, nil, models.ErrWebSocketUnsupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		s.written = true
+	}
+	return conn, rw, err
+}
+
+// isStreaming returns true if the function streams its response, see models.FnStreamingAnnotation
+func isStreaming(fn *models.Fn) bool {
+	raw, ok := fn.Annotations.Get(models.FnStreamingAnnotation)
+	if !ok {
+		return false
+	}
+	var streaming bool
+	return json.Unmarshal(raw, &streaming) == nil && streaming
+}
+
 // handleFnInvokeCall executes the function, for router handlers
 func (s *Server) handleFnInvokeCall(c *gin.Context) {
 	fnID := c.Param(api.FnID)
@@ -87,6 +149,10 @@ func (s *Server) ServeFnInvoke(c *gin.Context, app *models.App, fn *models.Fn) e
 }
 
 func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger) error {
+	if err := s.validateRequest(req, fn); err != nil {
+		return err
+	}
+
 	// TODO: we should get rid of the buffers, and stream back (saves memory (+splice), faster (splice), allows streaming, don't have to cap resp size)
 	// buffer the response before writing it out to client to prevent partials from trying to stream
 	buf := bufPool.Get().(*bytes.Buffer)
@@ -96,6 +162,8 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 	isDetached := req.Header.Get("Fn-Invoke-Type") == models.TypeDetached
 	if isDetached {
 		writer = agent.NewDetachedResponseWriter(resp.Header(), 202)
+	} else if isStreaming(fn) {
+		return s.fnInvokeStream(resp, req, app, fn, trig)
 	} else {
 		writer = &syncResponseWriter{
 			headers: resp.Header(),
@@ -103,6 +171,37 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 			Buffer:  buf,
 		}
 	}
+
+	idempotent, res, err := s.beginIdempotentCall(resp, req, fn)
+	if err != nil {
+		bufPool.Put(buf)
+		return err
+	}
+	if res != nil {
+		bufPool.Put(buf)
+		resp.Header().Set(IdempotentReplayedHeader, "true")
+		writeCachedResponse(resp, res)
+		return nil
+	}
+	if idempotent != nil {
+		// the key is released on the paths that don't keep an outcome, so that retries run
+		defer s.releaseIdempotentCall(req.Context(), idempotent)
+	}
+
+	var cached *cachedCall
+	if !isDetached {
+		var res *responsecache.Response
+		cached, res = s.lookupResponse(resp, req, fn)
+		if res != nil {
+			bufPool.Put(buf)
+			if idempotent != nil {
+				s.endIdempotentCall(req.Context(), idempotent, res.Header, res.Status, res.Body, nil)
+			}
+			writeCachedResponse(resp, res)
+			return nil
+		}
+	}
+
 	opts := getCallOptions(req, app, fn, trig, writer)
 
 	call, err := s.agent.GetCall(opts...)
@@ -114,8 +213,16 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 	writer.Header().Add("Fn-Call-Id", call.Model().ID)
 
 	err = s.agent.Submit(call)
+	if idempotent != nil {
+		s.endIdempotentCall(req.Context(), idempotent, writer.Header(), writer.Status(), buf.Bytes(), err)
+	}
 	if err != nil {
-		return err
+		return s.withBackpressure(call, err)
+	}
+
+	// before writing the response, HTTP trigger responses are rewritten as they are written
+	if cached != nil {
+		s.storeResponse(req.Context(), cached, writer.Header(), writer.Status(), buf.Bytes())
 	}
 
 	// because we can...
@@ -135,6 +242,55 @@ func (s *Server) fnInvoke(resp http.ResponseWriter, req *http.Request, app *mode
 	return nil
 }
 
+// fnInvokeStream executes a function that streams its response, writing the response to
+// the client as the function produces it.
+func (s *Server) fnInvokeStream(resp http.ResponseWriter, req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger, extra ...agent.CallOpt) error {
+	writer := &streamResponseWriter{inner: resp, status: 200}
+	opts := append(getCallOptions(req, app, fn, trig, writer), extra...)
+
+	call, err := s.agent.GetCall(opts...)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Add("Fn-Call-Id", call.Model().ID)
+
+	err = s.agent.Submit(call)
+	if err != nil && writer.written {
+		// the client already has part of the response, the error is recorded on the call
+		common.Logger(req.Context()).WithError(err).Info("streamed function response failed")
+		return nil
+	}
+	if err != nil {
+		return s.withBackpressure(call, err)
+	}
+	return nil
+}
+
+// withBackpressure attaches the backpressure of the agent to an error rejecting a call for
+// lack of capacity, so that clients and load balancers can back off.
+func (s *Server) withBackpressure(call agent.Call, err error) error {
+	apiErr, ok := err.(models.APIError)
+	if !ok || models.IsFuncError(err) || err == models.ErrAgentDraining || err == models.ErrUsageQuotaExceeded {
+		return err
+	}
+	switch apiErr.Code() {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		return err
+	}
+
+	bpp, ok := s.agent.(agent.BackpressureProvider)
+	if !ok {
+		return err
+	}
+	bp, ok := bpp.Backpressure(call)
+	if !ok {
+		return err
+	}
+	return models.NewBackpressureError(apiErr, bp)
+}
+
 func getCallOptions(req *http.Request, app *models.App, fn *models.Fn, trig *models.Trigger, rw http.ResponseWriter) []agent.CallOpt {
 	var opts []agent.CallOpt
 	opts = append(opts, agent.WithWriter(rw)) // XXX (reed): order matters [for now]
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -430,3 +431,45 @@ func TestInvokeRunnerMinimalConcurrentHotSync(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &streamResponseWriter{inner: rec, status: 200}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	if _, err := w.Write([]byte("data: one\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Flushed || rec.Body.String() != "data: one\n\n" {
+		t.Fatalf("chunk should be flushed to the client as it is written, got: %q", rec.Body.String())
+	}
+	w.WriteHeader(http.StatusBadGateway)
+	if rec.Code != http.StatusOK || w.Status() != http.StatusOK {
+		t.Fatalf("status should not change once the response is streaming, got: %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatal("headers set before the first chunk should be sent")
+	}
+
+	for i, test := range []struct {
+		value     interface{}
+		streaming bool
+	}{
+		{nil, false},
+		{true, true},
+		{false, false},
+		{"yes", false},
+	} {
+		fn := &models.Fn{Annotations: models.EmptyAnnotations()}
+		if test.value != nil {
+			var err error
+			fn.Annotations, err = fn.Annotations.With(models.FnStreamingAnnotation, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if isStreaming(fn) != test.streaming {
+			t.Errorf("test %d: expected streaming %v for annotation %v", i, test.streaming, test.value)
+		}
+	}
+}
@@ -1,13 +1,22 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/textproto"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/models"
 	"github.com/gin-gonic/gin"
 	"go.opencensus.io/tag"
@@ -75,6 +84,8 @@ func (s *Server) handleTriggerHTTPFunctionCall2(c *gin.Context) error {
 type triggerResponseWriter struct {
 	inner     http.ResponseWriter
 	committed bool
+	// CORS headers set by the server, replacing the ones of the function
+	headers http.Header
 }
 
 func (trw *triggerResponseWriter) Header() http.Header {
@@ -121,8 +132,18 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 		realHeaders.Del(k)
 	}
 	for k, vs := range gwHeaders {
+		if trw.headers != nil && strings.HasPrefix(k, "Access-Control-") {
+			continue
+		}
 		realHeaders[k] = vs
 	}
+	for k, vs := range trw.headers {
+		if k == "Vary" {
+			realHeaders[k] = append(realHeaders[k], vs...)
+		} else {
+			realHeaders[k] = vs
+		}
+	}
 
 	// XXX(reed): simplify / add tests for these behaviors...
 	finalStatus := 200
@@ -135,6 +156,23 @@ func (trw *triggerResponseWriter) WriteHeader(serviceStatus int) {
 	trw.inner.WriteHeader(finalStatus)
 }
 
+// Hijack takes over the client connection for WebSocket calls
+func (trw *triggerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := trw.inner.(http.Hijacker)
+	if !ok {
+		return nil, nil, models.ErrWebSocketUnsupported
+	}
+	trw.committed = true
+	return hj.Hijack()
+}
+
+// Flush sends any buffered data of streamed responses to the client
+func (trw *triggerResponseWriter) Flush() {
+	if f, ok := trw.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 var skipTriggerHeaders = map[string]bool{
 	"Connection":        true,
 	"Keep-Alive":        true,
@@ -145,24 +183,34 @@ var skipTriggerHeaders = map[string]bool{
 }
 
 func reqURL(req *http.Request) string {
-	if req.URL.Scheme == "" {
+	u := req.URL
+	// functions see the URL of requests routed from custom domains
+	if domainURL := requestURLFromContext(req.Context()); domainURL != nil {
+		u = domainURL
+	}
+	if u.Scheme == "" {
 		if req.TLS == nil {
-			req.URL.Scheme = "http"
+			u.Scheme = "http"
 		} else {
-			req.URL.Scheme = "https"
+			u.Scheme = "https"
 		}
 	}
-	if req.URL.Host == "" {
-		req.URL.Host = req.Host
+	if u.Host == "" {
+		u.Host = req.Host
 	}
-	return req.URL.String()
+	return u.String()
 }
 
 // ServeHTTPTrigger serves an HTTP trigger for a given app/fn/trigger based on the current request
 // This is exported to allow extensions to handle their own trigger naming and publishing
 func (s *Server) ServeHTTPTrigger(c *gin.Context, app *models.App, fn *models.Fn, trigger *models.Trigger) error {
-	// transpose trigger headers into the request
 	req := c.Request
+	if err := verifyTriggerSignature(req, app, fn, trigger); err != nil {
+		return err
+	}
+
+	// transpose trigger headers into the request
+	webSocket := isWebSocketTrigger(trigger) && isWebSocketUpgrade(req)
 	headers := make(http.Header, len(req.Header))
 	for k, vs := range req.Header {
 		// should be generally unnecessary but to be doubly sure.
@@ -170,8 +218,10 @@ func (s *Server) ServeHTTPTrigger(c *gin.Context, app *models.App, fn *models.Fn
 		if skipTriggerHeaders[k] {
 			continue
 		}
-		switch k {
-		case "Content-Type":
+		switch {
+		case k == "Content-Type":
+		case webSocket && strings.HasPrefix(k, "Sec-Websocket-"):
+			// the container completes the handshake
 		default:
 			k = fmt.Sprintf("Fn-Http-H-%s", k)
 		}
@@ -186,6 +236,94 @@ func (s *Server) ServeHTTPTrigger(c *gin.Context, app *models.App, fn *models.Fn
 
 	// trap the headers and rewrite them for http trigger
 	rw := &triggerResponseWriter{inner: c.Writer}
+	if h, ok := c.Get(corsHeadersContextKey); ok {
+		rw.headers = h.(http.Header)
+	}
 
+	if webSocket {
+		return s.fnInvokeStream(rw, req, app, fn, trigger, agent.WithWebSocket())
+	}
 	return s.fnInvoke(rw, req, app, fn, trigger)
 }
+
+// maxSignedBodySize is the largest request body of the calls of triggers with an HMAC
+// configuration, bodies are read to be verified before running the function.
+const maxSignedBodySize = 6 * 1024 * 1024
+
+// verifyTriggerSignature checks the signature of the body of a request to a trigger with an
+// HMAC configuration, see models.TriggerHMACAnnotation. The body is read to be verified and
+// then replaced, so that the function reads it as sent.
+func verifyTriggerSignature(req *http.Request, app *models.App, fn *models.Fn, trigger *models.Trigger) error {
+	// invalid annotations are rejected when triggers are changed, one that got stored
+	// anyway must not let unsigned requests through
+	h, err := models.TriggerHMACFromAnnotations(trigger.Annotations)
+	if err != nil {
+		common.Logger(req.Context()).WithError(err).WithField("trigger_id", trigger.ID).Error("rejecting request to trigger with invalid HMAC configuration")
+		return models.ErrTriggerSignatureInvalid
+	}
+	if h == nil {
+		return nil
+	}
+
+	// the body may not be larger than the calls of the app take either
+	limit := uint64(maxSignedBodySize)
+	if maxRequest, _, _ := models.SizeLimitsFromAnnotations(app.Annotations); maxRequest > 0 && maxRequest < limit {
+		limit = maxRequest
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(req.Body, int64(limit)+1))
+		req.Body.Close()
+		if err != nil {
+			return models.ErrInvalidPayload
+		}
+		if uint64(len(body)) > limit {
+			return models.ErrRequestContentTooBig
+		}
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	// the secrets of the fn take precedence over the ones of its app
+	secrets := make([]string, 0, len(h.SecretKeys))
+	for _, k := range h.SecretKeys {
+		secret, ok := fn.Config[k]
+		if !ok {
+			secret = app.Config[k]
+		}
+		secrets = append(secrets, secret)
+	}
+
+	if !h.Verify(secrets, req.Header, body, time.Now()) {
+		common.Logger(req.Context()).WithField("trigger_id", trigger.ID).Debug("rejecting request with invalid signature")
+		return models.ErrTriggerSignatureInvalid
+	}
+	return nil
+}
+
+// isWebSocketTrigger returns true if the trigger accepts WebSocket upgrades, see models.TriggerWebSocketAnnotation
+func isWebSocketTrigger(trigger *models.Trigger) bool {
+	raw, ok := trigger.Annotations.Get(models.TriggerWebSocketAnnotation)
+	if !ok {
+		return false
+	}
+	var webSocket bool
+	return json.Unmarshal(raw, &webSocket) == nil && webSocket
+}
+
+// isWebSocketUpgrade returns true if the request asks to upgrade the connection to a WebSocket
+func isWebSocketUpgrade(req *http.Request) bool {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range req.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
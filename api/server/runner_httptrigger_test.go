@@ -2,9 +2,13 @@ package server
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -16,6 +20,7 @@ import (
 	"github.com/fnproject/fn/api/logs"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
+	"github.com/gin-gonic/gin"
 	"reflect"
 )
 
@@ -553,3 +558,96 @@ func TestTriggerRunnerMinimalConcurrentHotSync(t *testing.T) {
 		}
 	}
 }
+
+func TestTriggerWebSocketUpgrade(t *testing.T) {
+	wsTrigger := &models.Trigger{Annotations: models.EmptyAnnotations()}
+	wsTrigger.Annotations, _ = wsTrigger.Annotations.With(models.TriggerWebSocketAnnotation, true)
+	if !isWebSocketTrigger(wsTrigger) {
+		t.Fatal("annotated trigger should accept websocket upgrades")
+	}
+	if isWebSocketTrigger(&models.Trigger{}) {
+		t.Fatal("triggers should not accept websocket upgrades by default")
+	}
+
+	for i, test := range []struct {
+		connection string
+		upgrade    string
+		expected   bool
+	}{
+		{"Upgrade", "websocket", true},
+		{"keep-alive, Upgrade", "WebSocket", true},
+		{"keep-alive", "websocket", false},
+		{"Upgrade", "h2c", false},
+		{"", "", false},
+	} {
+		req, _ := http.NewRequest("GET", "http://localhost/t/app/ws", nil)
+		req.Header.Set("Connection", test.connection)
+		req.Header.Set("Upgrade", test.upgrade)
+		if isWebSocketUpgrade(req) != test.expected {
+			t.Errorf("test %d: expected upgrade %v for connection %q upgrade %q", i, test.expected, test.connection, test.upgrade)
+		}
+	}
+}
+
+func TestTriggerSignature(t *testing.T) {
+	trigger := &models.Trigger{ID: "trigger_id", Type: models.TriggerTypeHTTP, Source: "/t"}
+	trigger.Annotations, _ = models.EmptyAnnotations().With(models.TriggerHMACAnnotation, map[string]interface{}{
+		"header":      "X-Hub-Signature-256",
+		"prefix":      "sha256=",
+		"secret_keys": []string{"WEBHOOK_SECRET"},
+	})
+	// the secret of the fn takes precedence over the one of its app
+	app := &models.App{Name: "app", Config: models.Config{"WEBHOOK_SECRET": "app-secret"}}
+	fn := &models.Fn{Config: models.Config{"WEBHOOK_SECRET": "fn-secret"}}
+	body := `{"action":"opened"}`
+
+	signed := func(secret string) *http.Request {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req, _ := http.NewRequest("POST", "http://localhost/t/app/t", strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return req
+	}
+
+	s := &Server{}
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = signed("app-secret")
+	if err := s.ServeHTTPTrigger(c, app, fn, trigger); err != models.ErrTriggerSignatureInvalid {
+		t.Fatalf("expected %v, got %v", models.ErrTriggerSignatureInvalid, err)
+	}
+
+	req := signed("fn-secret")
+	if err := verifyTriggerSignature(req, app, fn, trigger); err != nil {
+		t.Fatalf("expected the signature of the fn secret to be valid, got %v", err)
+	}
+	if b, _ := ioutil.ReadAll(req.Body); string(b) != body || req.ContentLength != int64(len(body)) {
+		t.Fatalf("expected the body to be readable once verified, got %q", b)
+	}
+
+	// bodies larger than the calls of the app take are not read
+	app.Annotations, _ = models.EmptyAnnotations().With(models.AppMaxRequestSizeAnnotation, len(body)-1)
+	if err := verifyTriggerSignature(signed("fn-secret"), app, fn, trigger); err != models.ErrRequestContentTooBig {
+		t.Fatalf("expected %v, got %v", models.ErrRequestContentTooBig, err)
+	}
+}
+
+func TestTriggerSignatureInvalidAnnotation(t *testing.T) {
+	app := &models.App{Name: "app", Config: models.Config{"WEBHOOK_SECRET": "app-secret"}}
+	fn := &models.Fn{}
+
+	for i, annotation := range []interface{}{
+		"not an object",
+		map[string]interface{}{"header": "X-Signature"},
+		map[string]interface{}{"header": "X-Signature", "secret_keys": []string{"WEBHOOK_SECRET"}, "algorithm": "md5"},
+	} {
+		trigger := &models.Trigger{ID: "trigger_id", Type: models.TriggerTypeHTTP, Source: "/t"}
+		trigger.Annotations, _ = models.EmptyAnnotations().With(models.TriggerHMACAnnotation, annotation)
+
+		// requests to a trigger with a malformed HMAC configuration are rejected, signed or not
+		req, _ := http.NewRequest("POST", "http://localhost/t/app/t", strings.NewReader("{}"))
+		if err := verifyTriggerSignature(req, app, fn, trigger); err != models.ErrTriggerSignatureInvalid {
+			t.Errorf("Test %d: expected %v, got %v", i, models.ErrTriggerSignatureInvalid, err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleScheduleCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	schedule := &models.Schedule{}
+
+	err := c.BindJSON(schedule)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if err := s.checkRequestApp(c, schedule.AppID, schedule.FnID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	scheduleCreated, err := s.datastore.InsertSchedule(ctx, schedule)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduleCreated)
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleScheduleGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	schedule, err := s.datastore.GetScheduleByID(ctx, c.Param(api.ScheduleID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
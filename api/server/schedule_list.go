@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleScheduleList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.ScheduleFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	filter.AppID = c.Query("app_id")
+	if filter.AppID == "" {
+		handleErrorResponse(c, models.ErrScheduleMissingAppID)
+		return
+	}
+
+	filter.FnID = c.Query("fn_id")
+	filter.Name = c.Query("name")
+
+	schedules, err := s.datastore.GetSchedules(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
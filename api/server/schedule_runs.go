@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleScheduleRunList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	scheduleID := c.Param(api.ScheduleID)
+
+	// 404 for runs of schedules that don't exist, rather than an empty list
+	if _, err := s.datastore.GetScheduleByID(ctx, scheduleID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := &models.ScheduleRunFilter{ScheduleID: scheduleID}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	runs, err := s.datastore.GetScheduleRuns(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
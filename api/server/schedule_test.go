@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+const scheduleRoute = "/v2/schedules"
+
+func TestScheduleCreate(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid"}
+	a2 := &models.App{ID: "appid2"}
+	fn := &models.Fn{ID: "fnid", AppID: a.ID}
+	fn.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{a, a2}, []*models.Fn{fn})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		body          string
+		expectedCode  int
+		expectedError error
+	}{
+		// errors
+		{``, http.StatusBadRequest, models.ErrInvalidJSON},
+		{`{}`, http.StatusNotFound, models.ErrAppsNotFound},
+		{`{"name": "nightly", "app_id": "appid"}`, http.StatusNotFound, models.ErrFnsNotFound},
+		{`{"app_id": "appid", "fn_id": "fnid"}`, http.StatusBadRequest, models.ErrScheduleMissingName},
+		{`{"name": "nightly", "app_id": "appid", "fn_id": "fnid"}`, http.StatusBadRequest, models.ErrScheduleMissingCron},
+		{`{"name": "nightly", "app_id": "appid", "fn_id": "fnid", "cron": "* * *"}`, http.StatusBadRequest, models.ErrScheduleInvalidCron},
+		{`{"name": "nightly", "app_id": "appid", "fn_id": "fnid", "cron": "0 0 30 2 *"}`, http.StatusBadRequest, models.ErrScheduleInvalidCron},
+		{`{"name": "&&%@!#$#@$", "app_id": "appid", "fn_id": "fnid", "cron": "@daily"}`, http.StatusBadRequest, models.ErrScheduleInvalidName},
+		{`{"id": "asdasca", "name": "nightly", "app_id": "appid", "fn_id": "fnid", "cron": "@daily"}`, http.StatusBadRequest, models.ErrScheduleIDProvided},
+		{`{"name": "nightly", "app_id": "appid2", "fn_id": "fnid", "cron": "@daily"}`, http.StatusBadRequest, models.ErrScheduleFnIDNotSameApp},
+
+		// success
+		{`{"name": "nightly", "app_id": "appid", "fn_id": "fnid", "cron": "@daily", "payload": "{}"}`, http.StatusOK, nil},
+
+		// repeated name
+		{`{"name": "nightly", "app_id": "appid", "fn_id": "fnid", "cron": "@daily"}`, http.StatusConflict, models.ErrScheduleExists},
+	} {
+		_, rec := routerRequest(t, srv.Router, "POST", scheduleRoute, bytes.NewBufferString(test.body))
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: Expected status code to be %d but was %d", i, test.expectedCode, rec.Code)
+		}
+
+		if test.expectedError != nil {
+			resp := getErrorResponse(t, rec)
+			if !strings.Contains(resp.Message, test.expectedError.Error()) {
+				t.Errorf("Test %d: Expected error message to have `%s` but got `%s`", i, test.expectedError.Error(), resp.Message)
+			}
+		}
+
+		if test.expectedCode == http.StatusOK {
+			var schedule models.Schedule
+			if err := json.NewDecoder(rec.Body).Decode(&schedule); err != nil {
+				t.Fatalf("Test %d: error decoding body for 'ok' json, it was a lie: %v", i, err)
+			}
+			if schedule.ID == "" {
+				t.Fatalf("Test %d: Missing ID", i)
+			}
+			if !time.Time(schedule.NextRunAt).After(time.Now()) {
+				t.Errorf("Test %d: expected next_run_at to be set in the future, it was %s", i, schedule.NextRunAt)
+			}
+
+			_, rec := routerRequest(t, srv.Router, "GET", scheduleRoute+"/"+schedule.ID, nil)
+			if rec.Code != http.StatusOK {
+				t.Errorf("Test %d: Expected to be able to GET schedule after successful POST: %d", i, rec.Code)
+			}
+		}
+	}
+}
+
+func TestScheduleUpdateDelete(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid"}
+	fn := &models.Fn{ID: "fnid", AppID: a.ID}
+	fn.SetDefaults()
+	sched := &models.Schedule{ID: "scheduleid", Name: "nightly", AppID: a.ID, FnID: fn.ID, Cron: "@daily"}
+	ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{fn}, []*models.Schedule{sched})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	path := scheduleRoute + "/scheduleid"
+
+	_, rec := routerRequest(t, srv.Router, "PUT", path, bytes.NewBufferString(`{"id": "other"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected mismatched id to fail, got %d", rec.Code)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "PUT", path, bytes.NewBufferString(`{"cron": "@hourly"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected update to succeed, got %d", rec.Code)
+	}
+	var updated models.Schedule
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Cron != "@hourly" || !time.Time(updated.NextRunAt).Before(time.Now().Add(time.Hour+time.Minute)) {
+		t.Fatalf("expected cron and next run to be updated, got %+v", updated)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", path+"/runs", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected to list runs, got %d", rec.Code)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "DELETE", path, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected delete to succeed, got %d", rec.Code)
+	}
+
+	for _, p := range []string{path, path + "/runs"} {
+		_, rec = routerRequest(t, srv.Router, "GET", p, nil)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected %s to be gone after delete, got %d", p, rec.Code)
+		}
+	}
+}
+
+func TestScheduleList(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	a := &models.App{ID: "appid"}
+	fn := &models.Fn{ID: "fnid", AppID: a.ID}
+	fn.SetDefaults()
+	ds := datastore.NewMockInit([]*models.App{a}, []*models.Fn{fn}, []*models.Schedule{
+		{ID: "s1", Name: "a", AppID: a.ID, FnID: fn.ID, Cron: "@daily"},
+		{ID: "s2", Name: "b", AppID: a.ID, FnID: fn.ID, Cron: "@hourly"},
+	})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, "GET", scheduleRoute, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected list without app_id to fail, got %d", rec.Code)
+	}
+
+	_, rec = routerRequest(t, srv.Router, "GET", scheduleRoute+"?app_id=appid&name=b", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected list to succeed, got %d", rec.Code)
+	}
+	var list models.ScheduleList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != "s2" {
+		t.Fatalf("expected schedule b, got %+v", list.Items)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleScheduleUpdate(c *gin.Context) {
+	schedule := &models.Schedule{}
+
+	err := c.BindJSON(schedule)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if err := s.checkRequestApp(c, schedule.AppID, schedule.FnID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	pathScheduleID := c.Param(api.ScheduleID)
+
+	if schedule.ID == "" {
+		schedule.ID = pathScheduleID
+	} else if pathScheduleID != schedule.ID {
+		handleErrorResponse(c, models.ErrScheduleIDMismatch)
+		return
+	}
+
+	ctx := c.Request.Context()
+	scheduleUpdated, err := s.datastore.UpdateSchedule(ctx, schedule)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduleUpdated)
+}
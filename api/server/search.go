@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleSearch searches the apps and fns whose names, annotation keys and config keys have
+// words starting with the words of the q query parameter, optionally of the type and app_id
+// query parameters.
+// Callers only find the resources of the apps they may read.
+func (s *Server) handleSearch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.SearchFilter{
+		Type:  c.Query("type"),
+		AppID: c.Query("app_id"),
+	}
+	if filter.Type != "" && filter.Type != models.SearchTypeApp && filter.Type != models.SearchTypeFn {
+		handleErrorResponse(c, models.ErrInvalidSearchType)
+		return
+	}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	var err error
+	filter.Terms, err = models.ParseSearchQuery(c.Query("q"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter.AppIDs = readableSearchApps(c)
+
+	results, err := s.datastore.Search(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// readableSearchApps returns the apps the caller of a request may read, to search only
+// theirs, or nil if it may read all apps. The search is scoped by the datastore so that pages
+// are full and their cursors don't follow the resources of other apps.
+func readableSearchApps(c *gin.Context) []string {
+	grants, ok := c.Get(roleGrantsContextKey)
+	if !ok || grants.(roleGrants).allows("", models.PermissionRead) {
+		return nil
+	}
+
+	appIDs := []string{}
+	for _, b := range grants.(roleGrants) {
+		if b.AppID != "" && models.RoleAllows(b.Role, models.PermissionRead) {
+			appIDs = append(appIDs, b.AppID)
+		}
+	}
+	return appIDs
+}
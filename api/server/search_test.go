@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestSearch(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	annotations, err := models.EmptyAnnotations().With("team.payments", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := datastore.NewMockInit(
+		[]*models.App{
+			{ID: "app_id1", Name: "payments"},
+			{ID: "app_id2", Name: "myapp", Config: models.Config{"PAYMENTS_URL": "http://example.com"}},
+		},
+		[]*models.Fn{
+			{ID: "fn_id1", Name: "charge", AppID: "app_id2", Annotations: annotations},
+			{ID: "fn_id2", Name: "refund", AppID: "app_id2"},
+		},
+	)
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	for i, test := range []struct {
+		path         string
+		expectedCode int
+		expectedIDs  []string
+	}{
+		{"/v2/search?q=payments", http.StatusOK, []string{"app_id1", "app_id2", "fn_id1"}},
+		{"/v2/search?q=PAY+url", http.StatusOK, []string{"app_id2"}},
+		{"/v2/search?q=payments&type=fn", http.StatusOK, []string{"fn_id1"}},
+		{"/v2/search?q=payments&app_id=app_id1", http.StatusOK, []string{"app_id1"}},
+		{"/v2/search?q=payments&per_page=1", http.StatusOK, []string{"app_id1"}},
+		{"/v2/search?q=nothing", http.StatusOK, []string{}},
+		{"/v2/search?q=+-+", http.StatusBadRequest, nil},
+		{"/v2/search?q=payments&type=trigger", http.StatusBadRequest, nil},
+	} {
+		_, rec := routerRequest(t, srv.Router, "GET", test.path, nil)
+		if rec.Code != test.expectedCode {
+			t.Fatalf("test %d: expected status code %d, got %d: %s", i, test.expectedCode, rec.Code, rec.Body.String())
+		}
+		if test.expectedIDs == nil {
+			continue
+		}
+
+		var results models.SearchResultList
+		if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+		if len(results.Items) != len(test.expectedIDs) {
+			t.Fatalf("test %d: expected results %v, got %+v", i, test.expectedIDs, results.Items)
+		}
+		for j, id := range test.expectedIDs {
+			if results.Items[j].ID != id {
+				t.Fatalf("test %d: expected results %v, got %+v", i, test.expectedIDs, results.Items)
+			}
+		}
+	}
+}
@@ -10,28 +10,47 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 	"unicode"
 
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/agent/hybrid"
+	"github.com/fnproject/fn/api/audit"
+	"github.com/fnproject/fn/api/audit/trail"
 	"github.com/fnproject/fn/api/common"
 	"github.com/fnproject/fn/api/datastore"
+	dscache "github.com/fnproject/fn/api/datastore/cache"
+	"github.com/fnproject/fn/api/datastore/encrypt"
+	"github.com/fnproject/fn/api/datastore/replica"
 	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/janitor"
 	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/logs/compress"
+	"github.com/fnproject/fn/api/logs/fanout"
 	"github.com/fnproject/fn/api/models"
 	"github.com/fnproject/fn/api/mqs"
+	"github.com/fnproject/fn/api/oidc"
+	"github.com/fnproject/fn/api/ratelimit"
+	"github.com/fnproject/fn/api/reaper"
+	"github.com/fnproject/fn/api/responsecache"
 	pool "github.com/fnproject/fn/api/runnerpool"
+	"github.com/fnproject/fn/api/scheduler"
+	"github.com/fnproject/fn/api/usage"
 	"github.com/fnproject/fn/api/version"
+	"github.com/fnproject/fn/api/webhooks"
 	"github.com/fnproject/fn/fnext"
 	"github.com/gin-gonic/gin"
 	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	"github.com/patrickmn/go-cache"
 	promclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"go.opencensus.io/exporter/jaeger"
@@ -40,6 +59,8 @@ import (
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/trace"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
 )
 
 const (
@@ -65,13 +86,42 @@ const (
 	EnvLogPrefix = "FN_LOG_PREFIX"
 
 	// EnvMQURL is a url to an MQ service:
-	// possible out-of-the-box schemes: { memory, redis, bolt }
+	// possible out-of-the-box schemes: { memory, redis, bolt, kafka-rest, sqs }
 	EnvMQURL = "FN_MQ_URL"
 
+	// EnvDeadLetterMQURL is a url to an MQ service async calls that exhausted the retry
+	// policy of their fn are pushed to, by default they are stored in the log store.
+	EnvDeadLetterMQURL = "FN_DEAD_LETTER_MQ_URL"
+
 	// EnvDBURL is a url to a db service:
-	// possible schemes: { postgres, sqlite3, mysql }
+	// possible schemes: { postgres, sqlite3, mysql, etcd, redis, cassandra, scylla }
+	// etcd, redis and cassandra store no logs, EnvLogDBURL must be set with them.
 	EnvDBURL = "FN_DB_URL"
 
+	// EnvDatastoreCacheTTL is the time in seconds the apps, fns and triggers looked up by
+	// invokes are cached for, 0 by default for no caching
+	EnvDatastoreCacheTTL = "FN_DS_CACHE_TTL"
+
+	// EnvDatastoreCacheBusURL is a redis://host:port/channel url the nodes caching the
+	// datastore publish its changes to, evicting them from the caches of each other. Without
+	// it, nodes see the changes of other nodes once their cache expires.
+	EnvDatastoreCacheBusURL = "FN_DS_CACHE_BUS_URL"
+
+	// EnvConfigEncryptionKeys is a comma separated list of the keys encrypting the config
+	// values of apps and fns in the datastore, each a file:///path or an awskms:key-id url.
+	// The first key encrypts, the others only decrypt the values encrypted before a rotation.
+	EnvConfigEncryptionKeys = "FN_CONFIG_ENCRYPTION_KEYS"
+
+	// EnvConfigEncryptionReencrypt re-encrypts in the background, when true, the config values
+	// of apps and fns not encrypted with the first of EnvConfigEncryptionKeys
+	EnvConfigEncryptionReencrypt = "FN_CONFIG_ENCRYPTION_REENCRYPT"
+
+	// EnvDatastoreReplicationInterval is the time in seconds the apps, fns, triggers and
+	// domains of the datastore, in the primary region, are replicated at to the memory of the
+	// nodes of a secondary region, for invokes to look them up in their region. Mutations are
+	// written through to the datastore. 0 by default, for the nodes of the primary region.
+	EnvDatastoreReplicationInterval = "FN_DS_REPLICATION_INTERVAL"
+
 	// EnvLogDBURL is a url to a log storage service:
 	// possible schemes: { postgres, sqlite3, mysql, s3 }
 	EnvLogDBURL = "FN_LOGSTORE_URL"
@@ -92,9 +142,63 @@ const (
 	// EnvPort is the port to listen on for fn http server.
 	EnvPort = "FN_PORT" // be careful, Gin expects this variable to be "port"
 
+	// EnvACMEDomains is a comma separated list of domains the web server terminates TLS for with
+	// certificates obtained and renewed via ACME (eg. Let's Encrypt). The hostnames of the custom
+	// domains of apps are served too if EnvACMECustomDomains allows them, and the node routes
+	// them. TLS is managed by ACME if set, the web server then usually listens on 443, see
+	// EnvPort.
+	EnvACMEDomains = "FN_ACME_DOMAINS"
+
+	// EnvACMECustomDomains is a comma separated list of the hostnames, or *.example.com patterns
+	// of their subdomains, of the custom domains of apps certificates are obtained for. None are
+	// by default, as anyone resolving a hostname to the server could otherwise have certificates
+	// requested for it.
+	EnvACMECustomDomains = "FN_ACME_CUSTOM_DOMAINS"
+
+	// EnvACMEEmail is the contact email of the ACME account, told about problems with
+	// certificates.
+	EnvACMEEmail = "FN_ACME_EMAIL"
+
+	// EnvACMECacheDir is the directory certificates and the ACME account key are kept in, across
+	// restarts. Defaults to data/acme.
+	EnvACMECacheDir = "FN_ACME_CACHE_DIR"
+
+	// EnvACMEDirectoryURL is the URL of the ACME directory, eg. the staging one of Let's Encrypt.
+	// Defaults to the production directory of Let's Encrypt.
+	EnvACMEDirectoryURL = "FN_ACME_DIRECTORY_URL"
+
+	// EnvACMEHTTPPort is the port the HTTP challenges of the ACME directory are served on, which
+	// must be reachable as port 80 of the domains. Other requests are redirected to HTTPS.
+	// Defaults to 80.
+	EnvACMEHTTPPort = "FN_ACME_HTTP_PORT"
+
+	// EnvH2C makes the web server speak HTTP/2 over cleartext to clients with prior knowledge
+	// or upgrading to it, for internal deployments without TLS. HTTP/2 is negotiated over TLS
+	// regardless.
+	EnvH2C = "FN_H2C"
+
+	// EnvHTTP2MaxConcurrentStreams is the number of streams, calls, an HTTP/2 client may have in
+	// flight at once on a connection. Defaults to 250.
+	EnvHTTP2MaxConcurrentStreams = "FN_HTTP2_MAX_CONCURRENT_STREAMS"
+
+	// EnvHTTP2StreamWindow is the flow control window in bytes of the request body of each HTTP/2
+	// stream, how much of it the client may send before the function reads it. Defaults to 1MB.
+	EnvHTTP2StreamWindow = "FN_HTTP2_STREAM_WINDOW_BYTES"
+
+	// EnvHTTP2ConnWindow is the flow control window in bytes of the request bodies of all the
+	// streams of an HTTP/2 connection. Defaults to 1MB.
+	EnvHTTP2ConnWindow = "FN_HTTP2_CONN_WINDOW_BYTES"
+
 	// EnvGRPCPort is the port to run the grpc server on for a pure-runner node.
 	EnvGRPCPort = "FN_GRPC_PORT"
 
+	// EnvGRPCInvokePort is the port to serve the gRPC invoke service on, 0 disables it.
+	EnvGRPCInvokePort = "FN_GRPC_INVOKE_PORT"
+
+	// EnvGRPCManagementPort is the port API nodes serve the gRPC management service on, 0
+	// disables it.
+	EnvGRPCManagementPort = "FN_GRPC_MANAGEMENT_PORT"
+
 	// EnvAPICORSOrigins is the list of CORS origins to allow.
 	EnvAPICORSOrigins = "FN_API_CORS_ORIGINS"
 
@@ -116,9 +220,149 @@ const (
 	// EnvLBPlacementAlg is the algorithm to place fn calls to fn runners in lb.[0w
 	EnvLBPlacementAlg = "FN_PLACER"
 
-	// EnvMaxRequestSize sets the limit in bytes for any API request's length.
+	// EnvMaxRequestSize sets the limit in bytes for any API request's length. The agent limits
+	// the request bodies of invocations to it too, apps may lower it, see agent.EnvMaxRequestSize.
 	EnvMaxRequestSize = "FN_MAX_REQUEST_SIZE"
 
+	// EnvRequestIDHeader is the header requests carry their correlation ID in. A valid ID
+	// supplied by the caller is adopted in logs, traces and the calls made by the request, one
+	// is generated otherwise, and it is sent back in the same header of the response.
+	// Defaults to X-Request-ID.
+	EnvRequestIDHeader = "FN_REQUEST_ID_HEADER"
+
+	// EnvDrainTimeout is the time in seconds calls in flight have to complete when the server
+	// is drained or shut down, calls are cancelled after it. 0 waits for calls to complete.
+	EnvDrainTimeout = "FN_DRAIN_TIMEOUT"
+
+	// EnvDrainDelay is the time in seconds the server keeps taking calls once it is drained or
+	// shut down, while /ready reports it draining, so that load balancers take it out of
+	// rotation before calls are rejected. Defaults to 0.
+	EnvDrainDelay = "FN_DRAIN_DELAY"
+
+	// EnvDrainLongCalls is what becomes of the calls still in flight at the end of
+	// EnvDrainTimeout: cancel, the default, cancels them and wait lets them run until their own
+	// timeout. Async calls being reserved from the queue are run, see agent.EnvDrainFinishAsync.
+	EnvDrainLongCalls = "FN_DRAIN_LONG_CALLS"
+
+	// EnvSchedulerInterval is the interval in seconds full and api nodes poll for due schedules
+	// at, 0 disables running schedules on the node.
+	EnvSchedulerInterval = "FN_SCHEDULER_INTERVAL"
+
+	// EnvDeleteRetention is the time in seconds deleted apps and functions are kept for, during
+	// which they may be listed and restored, before they are purged. 0 purges them on delete.
+	EnvDeleteRetention = "FN_DELETE_RETENTION"
+
+	// EnvLogRetentionInterval is the interval in seconds full and api nodes remove the calls
+	// expired by the log retention of their app at, see models.LogRetentionAnnotation. 0
+	// disables removing them on the node.
+	EnvLogRetentionInterval = "FN_LOG_RETENTION_INTERVAL"
+
+	// EnvLogRetentionDays is the number of days the calls of apps without a log retention
+	// annotation are kept for. 0, the default, keeps them.
+	EnvLogRetentionDays = "FN_LOG_RETENTION_DAYS"
+
+	// EnvLogCompression is the algorithm call logs are compressed with before they are
+	// stored, none, the default, gzip or zstd. Logs stored uncompressed or with another
+	// algorithm are still read once set.
+	EnvLogCompression = "FN_LOG_COMPRESSION"
+
+	// EnvLogCompressionLevel is the level call logs are compressed at, 1 to 9 for gzip and 1
+	// to 22 for zstd. 0, the default, is the default level of the algorithm.
+	EnvLogCompressionLevel = "FN_LOG_COMPRESSION_LEVEL"
+
+	// EnvLogSecondaryURLs are the comma separated URLs of log stores the calls, logs and
+	// results are written to as well as the one of EnvLogDBURL, the primary, e.g. to archive
+	// logs. They are written in the background and never read.
+	EnvLogSecondaryURLs = "FN_LOG_SECONDARY_URLS"
+
+	// EnvLogSecondaryQueueSize is the number of writes queued to each secondary log store,
+	// beyond which the writes to the store are dropped. Defaults to fanout.DefaultQueueSize.
+	EnvLogSecondaryQueueSize = "FN_LOG_SECONDARY_QUEUE_SIZE"
+
+	// EnvOIDCIssuer is the URL of the OpenID Connect issuer of the bearer tokens requests to
+	// the management and invoke endpoints must carry. Requests are not authenticated if unset.
+	EnvOIDCIssuer = "FN_OIDC_ISSUER"
+
+	// EnvOIDCJWKSURL is the URL of the keys tokens are signed with, discovered from the
+	// issuer if unset.
+	EnvOIDCJWKSURL = "FN_OIDC_JWKS_URL"
+
+	// EnvOIDCAudience is the audience tokens must be issued for, any if unset.
+	EnvOIDCAudience = "FN_OIDC_AUDIENCE"
+
+	// EnvAPIKeys enables API keys when true, requests to the management and invoke endpoints
+	// must then carry the secret of an API key, or a token of the OIDC issuer if set. API keys
+	// are kept in the datastore, so they are only supported on full and api nodes.
+	EnvAPIKeys = "FN_API_KEYS"
+
+	// EnvAdminAPIKey is a secret accepted as an API key to manage all apps, to create the first
+	// API keys with when no OIDC issuer is set. It must start with "fnk_".
+	EnvAdminAPIKey = "FN_ADMIN_API_KEY"
+
+	// EnvRBAC enables role based access control when true, the operations of authenticated
+	// callers are then only allowed by the roles bound to them on apps. Role bindings are kept
+	// in the datastore, so they are only supported on full and api nodes.
+	EnvRBAC = "FN_RBAC"
+
+	// EnvRBACAdmins is a comma separated list of principals bound the admin role on all apps,
+	// to bind the first roles with. The admin API key is always an admin.
+	EnvRBACAdmins = "FN_RBAC_ADMINS"
+
+	// EnvTrustedProxies is a comma separated list of the IP addresses and CIDR networks of the
+	// proxies in front of fn. The X-Forwarded-For and Fn-Caller-Identity headers of their
+	// requests are recorded in audit records and the audit trail, those of other callers are
+	// ignored and dropped. The agent reads it too, see agent.EnvTrustedProxies.
+	EnvTrustedProxies = "FN_TRUSTED_PROXIES"
+
+	// EnvRateLimitStore is the URL of the store of the rate limits of invocations, set in the
+	// fnproject.io/app/rateLimit annotation of apps and on API keys. The limits are enforced by
+	// each node on its own if unset, redis://host:port/prefix enforces them across nodes.
+	EnvRateLimitStore = "FN_RATE_LIMIT_STORE"
+
+	// EnvDefaultRateLimit is the rate limit of the invocations of the apps without one of
+	// their own in the fnproject.io/app/rateLimit annotation, in the same JSON form. Apps are
+	// only limited by their annotation if unset. It is reloaded on SIGHUP.
+	EnvDefaultRateLimit = "FN_DEFAULT_RATE_LIMIT"
+
+	// EnvConfigFile is the path of a YAML file of settings, as an alternative to environment
+	// variables. Its keys are the names of the FN_ environment variables, FN_LOG_LEVEL or
+	// log_level, with scalar values. Other variables, eg. DOCKER_HOST, are not read from the
+	// file and their keys are rejected, they are set in the environment. A setting is taken
+	// from, in order of precedence, its environment variable, its _FILE environment variable,
+	// the config file and its default.
+	// On SIGHUP the file is read again and the settings which are safe to change as the server
+	// runs are applied: EnvLogLevel, EnvDefaultRateLimit and the idle timeouts of the agent,
+	// agent.EnvFreezeIdle and agent.EnvWebSocketIdleTimeout. Others change on restart.
+	EnvConfigFile = "FN_CONFIG_FILE"
+
+	// EnvReadyTimeout is the time in seconds each dependency of the node, the datastore, log
+	// store, MQ and docker daemon, has to answer the probes of /ready. Defaults to 2.
+	EnvReadyTimeout = "FN_READY_TIMEOUT"
+
+	// EnvReadyStrict makes /ready report the node unavailable once any of its dependencies
+	// fails, so that load balancers drain it. Only the datastore and the docker daemon are
+	// required if unset, the failure of others reports the node degraded but ready.
+	EnvReadyStrict = "FN_READY_STRICT"
+
+	// EnvResponseCacheStore is the URL of the store of the responses of the functions with the
+	// fnproject.io/fn/responseCache annotation. Each node caches responses in its memory if
+	// unset, redis://host:port/prefix shares them across nodes.
+	EnvResponseCacheStore = "FN_RESPONSE_CACHE_STORE"
+
+	// EnvIdempotencyTTL is the time in seconds the outcomes of calls with an Idempotency-Key
+	// header are kept, in the store of EnvResponseCacheStore. Retries of a call with the key of
+	// an earlier call of the function are answered with its outcome instead of running it again,
+	// and conflict while it is in progress. Calls of functions streaming their response are not
+	// covered. Defaults to 24 hours. The memory store is of each node and evicts the least
+	// recently used outcomes once full, so keys are only honoured across nodes, and for the
+	// whole TTL, with a shared redis store.
+	EnvIdempotencyTTL = "FN_IDEMPOTENCY_TTL"
+
+	// EnvWebhookPrivateNetworks allows webhooks on loopback, link-local and private addresses
+	// when true, for receivers in the network of fn. Webhooks are only delivered to public
+	// addresses if unset.
+	EnvWebhookPrivateNetworks = "FN_WEBHOOK_PRIVATE_NETWORKS"
+
 	// DefaultLogFormat is text
 	DefaultLogFormat = "text"
 
@@ -128,6 +372,9 @@ const (
 	// DefaultLogDest is stderr
 	DefaultLogDest = "stderr"
 
+	// DefaultRequestIDHeader is X-Request-ID
+	DefaultRequestIDHeader = "X-Request-ID"
+
 	// DefaultPort is 8080
 	DefaultPort = 8080
 
@@ -192,6 +439,8 @@ type Server struct {
 
 	agent     agent.Agent
 	datastore models.Datastore
+	// logTailer tails the logs of fns when set, rather than the agent
+	logTailer agent.LogTailer
 	mq        models.MessageQueue
 	logstore  models.LogStore
 	nodeType  NodeType
@@ -209,6 +458,7 @@ type Server struct {
 	noFnInvokeEndpoint     bool
 	noCallEndpoints        bool
 	appListeners           *appListeners
+	datastoreHooks         *datastoreHooks
 	fnListeners            *fnListeners
 	triggerListeners       *triggerListeners
 	rootMiddlewares        []fnext.Middleware
@@ -219,6 +469,107 @@ type Server struct {
 
 	// Extensions can append to this list of contexts so that cancellations are properly handled.
 	extraCtxs []context.Context
+
+	// time calls in flight have to complete on shutdown, see EnvDrainTimeout
+	drainTimeout time.Duration
+
+	// time calls are still taken once a drain starts, see EnvDrainDelay
+	drainDelay time.Duration
+
+	// DrainLongCallsCancel or DrainLongCallsWait, see EnvDrainLongCalls
+	drainLongCalls string
+
+	// set to 1 once the server starts draining
+	draining int32
+
+	// manager of the certificates of the web server and address of its HTTP challenges, see
+	// EnvACMEDomains
+	acme         *autocert.Manager
+	acmeHTTPAddr string
+
+	// hostnames of the server, which custom domains may not map, see WithHostnames
+	hostnames map[string]bool
+
+	// settings of HTTP/2 on the web server and whether it is served over cleartext, see EnvH2C
+	http2 *http2.Server
+	h2c   bool
+
+	// address of the gRPC invoke service, see EnvGRPCInvokePort
+	grpcInvokeAddr string
+
+	// address of the gRPC management service, see EnvGRPCManagementPort
+	grpcManagementAddr string
+
+	// interval schedules are polled at, see EnvSchedulerInterval
+	schedulerInterval time.Duration
+
+	// time deleted apps and fns are kept for, see EnvDeleteRetention
+	deleteRetention time.Duration
+
+	// interval expired calls are removed at, see EnvLogRetentionInterval
+	logRetentionInterval time.Duration
+	// log retention of the apps without a log retention annotation, see EnvLogRetentionDays
+	logRetention *models.LogRetention
+
+	// algorithm and level logs are compressed with, see EnvLogCompression
+	logCompression      string
+	logCompressionLevel int
+
+	// log stores written to as well as the logstore, see EnvLogSecondaryURLs
+	logSecondaries        []models.LogStore
+	logSecondaryQueueSize int
+
+	// queue dead lettered calls are pushed to, see EnvDeadLetterMQURL
+	deadLetterMQ models.MessageQueue
+
+	// verifier of the bearer tokens of requests, see EnvOIDCIssuer
+	tokenVerifier *oidc.Verifier
+
+	// API keys authenticate requests, see EnvAPIKeys and EnvAdminAPIKey
+	apiKeys         bool
+	adminAPIKeyHash string
+
+	// role bindings authorize requests, see EnvRBAC and EnvRBACAdmins
+	rbac       bool
+	rbacAdmins map[string]bool
+
+	// proxies whose forwarded callers are recorded, see EnvTrustedProxies
+	trustedProxies audit.TrustedProxies
+
+	// store of the rate limits of invocations, see EnvRateLimitStore
+	rateLimits ratelimit.Store
+
+	// *models.RateLimit of the apps without one of their own, see EnvDefaultRateLimit
+	defaultRateLimit atomic.Value
+
+	// file the settings of the server are read from and reloaded, see EnvConfigFile
+	configFile *configFile
+
+	// timeout of the probes of the dependencies of the node and whether all of them are
+	// required for it to be ready, see EnvReadyTimeout and EnvReadyStrict
+	readyTimeout time.Duration
+	readyStrict  bool
+
+	// store of the cached responses of functions, see EnvResponseCacheStore
+	responseCache responsecache.Store
+
+	// time the outcomes of calls with an idempotency key are kept, see EnvIdempotencyTTL
+	idempotencyTTL time.Duration
+
+	// compiled request schemas of functions by fn id, see validateRequest
+	requestSchemas *cache.Cache
+
+	// tracker of the usage of apps enforcing their quotas, nil without a datastore
+	usage *usage.Tracker
+
+	// dispatcher of management events to webhooks, nil unless the node serves the management API
+	webhooks *webhooks.Dispatcher
+
+	// whether webhooks may be delivered to private addresses, see EnvWebhookPrivateNetworks
+	webhookPrivateNetworks bool
+
+	// sink of the audit trail of the management API, the datastore unless WithAuditTrailSink
+	auditTrail trail.Sink
 }
 
 func nodeTypeFromString(value string) NodeType {
@@ -238,6 +589,18 @@ func nodeTypeFromString(value string) NodeType {
 
 // NewFromEnv creates a new Functions server based on env vars.
 func NewFromEnv(ctx context.Context, opts ...Option) *Server {
+	// the settings of the config file are set in the environment, before they are read
+	if path := getEnv(EnvConfigFile, ""); path != "" {
+		cf, err := loadConfigFile(path)
+		if err != nil {
+			logrus.WithError(err).WithField("path", path).Fatal("cannot load config file")
+		}
+		opts = append(opts, func(ctx context.Context, s *Server) error {
+			s.configFile = cf
+			return nil
+		})
+	}
+
 	curDir := pwd()
 	var defaultDB, defaultMQ string
 	nodeType := nodeTypeFromString(getEnv(EnvNodeType, "")) // default to full
@@ -251,7 +614,16 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 		defaultMQ = fmt.Sprintf("bolt://%s/data/fn.mq", curDir)
 	}
 	opts = append(opts, WithWebPort(getEnvInt(EnvPort, DefaultPort)))
+	if domains := getEnv(EnvACMEDomains, ""); domains != "" {
+		opts = append(opts, WithACME(strings.Split(domains, ","), strings.Split(getEnv(EnvACMECustomDomains, ""), ","), getEnv(EnvACMEEmail, ""),
+			getEnv(EnvACMECacheDir, filepath.Join(curDir, "data", "acme")), getEnv(EnvACMEDirectoryURL, ""), getEnvInt(EnvACMEHTTPPort, 80)))
+	}
+	h2c, _ := strconv.ParseBool(getEnv(EnvH2C, "false"))
+	opts = append(opts, WithHTTP2(h2c, uint32(getEnvInt(EnvHTTP2MaxConcurrentStreams, 0)),
+		int32(getEnvInt(EnvHTTP2StreamWindow, 0)), int32(getEnvInt(EnvHTTP2ConnWindow, 0))))
 	opts = append(opts, WithGRPCPort(getEnvInt(EnvGRPCPort, DefaultGRPCPort)))
+	opts = append(opts, WithGRPCInvokePort(getEnvInt(EnvGRPCInvokePort, 0)))
+	opts = append(opts, WithGRPCManagementPort(getEnvInt(EnvGRPCManagementPort, 0)))
 	opts = append(opts, WithLogFormat(getEnv(EnvLogFormat, DefaultLogFormat)))
 	opts = append(opts, WithLogLevel(getEnv(EnvLogLevel, DefaultLogLevel)))
 	opts = append(opts, WithLogDest(getEnv(EnvLogDest, DefaultLogDest), getEnv(EnvLogPrefix, "")))
@@ -259,16 +631,56 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	opts = append(opts, WithJaeger(getEnv(EnvJaegerURL, "")))
 	opts = append(opts, WithPrometheus()) // TODO option to turn this off?
 	opts = append(opts, WithDBURL(getEnv(EnvDBURL, defaultDB)))
+	if keys := getEnv(EnvConfigEncryptionKeys, ""); keys != "" {
+		reencrypt, _ := strconv.ParseBool(getEnv(EnvConfigEncryptionReencrypt, "false"))
+		opts = append(opts, WithConfigEncryption(strings.Split(keys, ","), reencrypt))
+	}
+	opts = append(opts, WithDatastoreCache(time.Duration(getEnvInt(EnvDatastoreCacheTTL, 0))*time.Second, getEnv(EnvDatastoreCacheBusURL, "")))
+	opts = append(opts, WithDatastoreReplication(time.Duration(getEnvInt(EnvDatastoreReplicationInterval, 0))*time.Second))
 	opts = append(opts, WithMQURL(getEnv(EnvMQURL, defaultMQ)))
+	opts = append(opts, WithDeadLetterMQURL(getEnv(EnvDeadLetterMQURL, "")))
+	opts = append(opts, WithLogCompression(getEnv(EnvLogCompression, compress.None), getEnvInt(EnvLogCompressionLevel, 0)))
+	if urls := getEnv(EnvLogSecondaryURLs, ""); urls != "" {
+		opts = append(opts, WithLogSecondaryURLs(getEnvInt(EnvLogSecondaryQueueSize, 0), strings.Split(urls, ",")...))
+	}
 	opts = append(opts, WithLogURL(getEnv(EnvLogDBURL, "")))
 	opts = append(opts, WithRunnerURL(getEnv(EnvRunnerURL, "")))
 	opts = append(opts, WithType(nodeType))
 
 	opts = append(opts, LimitRequestBody(int64(getEnvInt(EnvMaxRequestSize, 0))))
+	opts = append(opts, WithRIDProvider(&RIDProvider{
+		HeaderName:   getEnv(EnvRequestIDHeader, DefaultRequestIDHeader),
+		RIDGenerator: common.FnRequestID,
+	}))
+	opts = append(opts, WithDrainTimeout(time.Duration(getEnvInt(EnvDrainTimeout, 0))*time.Second))
+	opts = append(opts, WithDrainDelay(time.Duration(getEnvInt(EnvDrainDelay, 0))*time.Second))
+	opts = append(opts, WithDrainLongCalls(getEnv(EnvDrainLongCalls, DrainLongCallsCancel)))
+	opts = append(opts, WithSchedulerInterval(time.Duration(getEnvInt(EnvSchedulerInterval, 10))*time.Second))
+	opts = append(opts, WithDeleteRetention(time.Duration(getEnvInt(EnvDeleteRetention, 0))*time.Second))
+	opts = append(opts, WithLogRetention(time.Duration(getEnvInt(EnvLogRetentionInterval, 3600))*time.Second, getEnvInt(EnvLogRetentionDays, 0)))
+	opts = append(opts, WithOIDC(getEnv(EnvOIDCIssuer, ""), getEnv(EnvOIDCJWKSURL, ""), getEnv(EnvOIDCAudience, "")))
+	if apiKeys, _ := strconv.ParseBool(getEnv(EnvAPIKeys, "false")); apiKeys {
+		opts = append(opts, WithAPIKeys(getEnv(EnvAdminAPIKey, "")))
+	}
+	if rbac, _ := strconv.ParseBool(getEnv(EnvRBAC, "false")); rbac {
+		opts = append(opts, WithRBAC(strings.Split(getEnv(EnvRBACAdmins, ""), ",")))
+	}
+	opts = append(opts, WithTrustedProxies(getEnv(EnvTrustedProxies, "")))
+	opts = append(opts, WithRateLimitStore(getEnv(EnvRateLimitStore, "")))
+	opts = append(opts, WithDefaultRateLimit(getEnv(EnvDefaultRateLimit, "")))
+	readyStrict, _ := strconv.ParseBool(getEnv(EnvReadyStrict, "false"))
+	opts = append(opts, WithReadyProbes(time.Duration(getEnvInt(EnvReadyTimeout, 2))*time.Second, readyStrict))
+	opts = append(opts, WithResponseCacheStore(getEnv(EnvResponseCacheStore, "")))
+	opts = append(opts, WithIdempotencyTTL(time.Duration(getEnvInt(EnvIdempotencyTTL, 0))*time.Second))
+	webhookPrivateNetworks, _ := strconv.ParseBool(getEnv(EnvWebhookPrivateNetworks, "false"))
+	opts = append(opts, WithWebhookPrivateNetworks(webhookPrivateNetworks))
 
 	publicLBURL := getEnv(EnvPublicLoadBalancerURL, "")
 	if publicLBURL != "" {
 		logrus.Infof("using LB Base URL: '%s'", publicLBURL)
+		if u, err := url.Parse(publicLBURL); err == nil {
+			opts = append(opts, WithHostnames(u.Hostname()))
+		}
 		opts = append(opts, WithTriggerAnnotator(NewStaticURLTriggerAnnotator(publicLBURL)))
 		opts = append(opts, WithFnAnnotator(NewStaticURLFnAnnotator(publicLBURL)))
 	} else {
@@ -283,6 +695,10 @@ func NewFromEnv(ctx context.Context, opts ...Option) *Server {
 	} else {
 		// NOTE: ensures logstore is set or there will be troubles
 		opts = append(opts, WithLogstoreFromDatastore())
+		if getEnv(EnvRunnerAddresses, "") != "" {
+			// the logs of fns are tailed on the runners their load balancers run them on
+			opts = append(opts, WithRunnerLogTailer())
+		}
 	}
 
 	return New(ctx, opts...)
@@ -316,6 +732,28 @@ func WithGRPCPort(port int) Option {
 	}
 }
 
+// WithGRPCInvokePort maps EnvGRPCInvokePort
+func WithGRPCInvokePort(port int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.grpcInvokeAddr = ""
+		if port > 0 {
+			s.grpcInvokeAddr = fmt.Sprintf(":%d", port)
+		}
+		return nil
+	}
+}
+
+// WithGRPCManagementPort maps EnvGRPCManagementPort
+func WithGRPCManagementPort(port int) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.grpcManagementAddr = ""
+		if port > 0 {
+			s.grpcManagementAddr = fmt.Sprintf(":%d", port)
+		}
+		return nil
+	}
+}
+
 // WithLogFormat maps EnvLogFormat
 func WithLogFormat(format string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -355,6 +793,84 @@ func WithDBURL(dbURL string) Option {
 	}
 }
 
+// WithConfigEncryption encrypts the config values of apps and fns in the datastore with the
+// keys of keyURLs, see EnvConfigEncryptionKeys, re-encrypting the values not encrypted with
+// the first key in the background if reencrypt
+func WithConfigEncryption(keyURLs []string, reencrypt bool) Option {
+	return func(ctx context.Context, s *Server) error {
+		if len(keyURLs) == 0 || s.datastore == nil {
+			return nil
+		}
+		keys := make([]encrypt.Key, 0, len(keyURLs))
+		for _, u := range keyURLs {
+			key, err := encrypt.NewKey(strings.TrimSpace(u))
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		ds, err := encrypt.Wrap(s.datastore, keys)
+		if err != nil {
+			return err
+		}
+		s.datastore = ds
+		s.lbReadAccess = agent.NewCachedDataAccess(s.datastore)
+
+		if r, ok := ds.(interface {
+			Reencrypt(context.Context) (int, int, error)
+		}); ok && reencrypt {
+			go func() {
+				log := common.Logger(ctx)
+				apps, fns, err := r.Reencrypt(ctx)
+				if err != nil {
+					log.WithError(err).Error("cannot re-encrypt config values")
+					return
+				}
+				log.WithFields(logrus.Fields{"apps": apps, "fns": fns}).Info("re-encrypted config values")
+			}()
+		}
+		return nil
+	}
+}
+
+// WithDatastoreCache caches the lookups of the datastore for ttl, publishing its changes on
+// the bus of busURL, see EnvDatastoreCacheBusURL
+func WithDatastoreCache(ttl time.Duration, busURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if ttl <= 0 || s.datastore == nil {
+			return nil
+		}
+		var bus dscache.Bus
+		if busURL != "" {
+			var err error
+			bus, err = dscache.NewBus(busURL)
+			if err != nil {
+				return err
+			}
+		}
+		ds, err := dscache.Wrap(ctx, s.datastore, ttl, bus)
+		if err != nil {
+			return err
+		}
+		s.datastore = ds
+		s.lbReadAccess = agent.NewCachedDataAccess(s.datastore)
+		return nil
+	}
+}
+
+// WithDatastoreReplication replicates the lookups of invokes of the datastore, in a primary
+// region, every interval, see EnvDatastoreReplicationInterval
+func WithDatastoreReplication(interval time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		if interval <= 0 || s.datastore == nil {
+			return nil
+		}
+		s.datastore = replica.Wrap(ctx, s.datastore, interval)
+		s.lbReadAccess = agent.NewCachedDataAccess(s.datastore)
+		return nil
+	}
+}
+
 // WithMQURL maps EnvMQURL
 func WithMQURL(mqURL string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -370,6 +886,20 @@ func WithMQURL(mqURL string) Option {
 	}
 }
 
+// WithDeadLetterMQURL maps EnvDeadLetterMQURL
+func WithDeadLetterMQURL(mqURL string) Option {
+	return func(ctx context.Context, s *Server) error {
+		if mqURL != "" {
+			mq, err := mqs.New(mqURL)
+			if err != nil {
+				return err
+			}
+			s.deadLetterMQ = mq
+		}
+		return nil
+	}
+}
+
 // WithLogURL maps EnvLogURL
 func WithLogURL(logstoreURL string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -378,8 +908,52 @@ func WithLogURL(logstoreURL string) Option {
 			if err != nil {
 				return err
 			}
-			s.logstore = logDB
+			return s.setLogstore(logDB)
+		}
+		return nil
+	}
+}
+
+// setLogstore sets the logstore to ls, compressed and written to the secondary log stores as
+// configured
+func (s *Server) setLogstore(ls models.LogStore) error {
+	ls, err := compress.New(ls, s.logCompression, s.logCompressionLevel)
+	if err != nil {
+		return err
+	}
+	s.logstore = fanout.New(ls, s.logSecondaries, s.logSecondaryQueueSize)
+	return nil
+}
+
+// WithLogCompression maps EnvLogCompression and EnvLogCompressionLevel. It must be provided
+// before the logstore is set, by WithLogURL or WithLogstoreFromDatastore. Only the logstore
+// is compressed, not the secondary log stores.
+func WithLogCompression(algorithm string, level int) Option {
+	return func(ctx context.Context, s *Server) error {
+		if _, err := compress.New(nil, algorithm, level); err != nil {
+			return err
+		}
+		s.logCompression = algorithm
+		s.logCompressionLevel = level
+		return nil
+	}
+}
+
+// WithLogSecondaryURLs maps EnvLogSecondaryURLs and EnvLogSecondaryQueueSize. Like
+// WithLogCompression, it must be provided before the logstore is set.
+func WithLogSecondaryURLs(queueSize int, urls ...string) Option {
+	return func(ctx context.Context, s *Server) error {
+		for _, u := range urls {
+			if u = strings.TrimSpace(u); u == "" {
+				continue
+			}
+			ls, err := logs.New(ctx, u)
+			if err != nil {
+				return err
+			}
+			s.logSecondaries = append(s.logSecondaries, ls)
 		}
+		s.logSecondaryQueueSize = queueSize
 		return nil
 	}
 }
@@ -407,6 +981,68 @@ func WithType(t NodeType) Option {
 	}
 }
 
+// WithDrainTimeout maps EnvDrainTimeout
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.drainTimeout = timeout
+		return nil
+	}
+}
+
+// WithDrainDelay maps EnvDrainDelay
+func WithDrainDelay(delay time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.drainDelay = delay
+		return nil
+	}
+}
+
+// WithDrainLongCalls maps EnvDrainLongCalls
+func WithDrainLongCalls(policy string) Option {
+	return func(ctx context.Context, s *Server) error {
+		switch policy {
+		case "", DrainLongCallsCancel:
+			s.drainLongCalls = DrainLongCallsCancel
+		case DrainLongCallsWait:
+			s.drainLongCalls = DrainLongCallsWait
+		default:
+			return fmt.Errorf("invalid %s %q, calls in flight are either cancelled (%s) or waited for (%s)",
+				EnvDrainLongCalls, policy, DrainLongCallsCancel, DrainLongCallsWait)
+		}
+		return nil
+	}
+}
+
+// WithSchedulerInterval maps EnvSchedulerInterval
+func WithSchedulerInterval(interval time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.schedulerInterval = interval
+		return nil
+	}
+}
+
+// WithDeleteRetention maps EnvDeleteRetention
+func WithDeleteRetention(retention time.Duration) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.deleteRetention = retention
+		return nil
+	}
+}
+
+// WithLogRetention maps EnvLogRetentionInterval and EnvLogRetentionDays
+func WithLogRetention(interval time.Duration, days int) Option {
+	return func(ctx context.Context, s *Server) error {
+		if days < 0 {
+			return fmt.Errorf("invalid log retention of %d days", days)
+		}
+		s.logRetentionInterval = interval
+		if days > 0 {
+			s.logRetention = &models.LogRetention{Days: days}
+		}
+		return nil
+	}
+}
+
 // WithTLS configures a service with a provided TLS configuration
 func WithTLS(service string, tlsCfg *tls.Config) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -459,6 +1095,26 @@ func WithAgent(agent agent.Agent) Option {
 	}
 }
 
+// WithLogTailer sets what tails the logs of fns, instead of the agent
+func WithLogTailer(tailer agent.LogTailer) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.logTailer = tailer
+		return nil
+	}
+}
+
+// WithRunnerLogTailer tails the logs of fns on the runners of EnvRunnerAddresses
+func WithRunnerLogTailer() Option {
+	return func(ctx context.Context, s *Server) error {
+		runnerPool, err := s.defaultRunnerPool()
+		if err != nil {
+			return err
+		}
+		s.logTailer = agent.NewRunnerLogTailer(runnerPool)
+		return nil
+	}
+}
+
 func (s *Server) defaultRunnerPool() (pool.RunnerPool, error) {
 	runnerAddresses := getEnv(EnvRunnerAddresses, "")
 	if runnerAddresses == "" {
@@ -476,7 +1132,9 @@ func WithLogstoreFromDatastore() Option {
 		}
 		if s.logstore == nil {
 			if ls, ok := s.datastore.(models.LogStore); ok {
-				s.logstore = ls
+				if err := s.setLogstore(ls); err != nil {
+					return err
+				}
 			} else {
 				return errors.New("datastore must implement logstore interface")
 			}
@@ -498,7 +1156,11 @@ func WithFullAgent() Option {
 		if s.datastore == nil || s.logstore == nil || s.mq == nil {
 			return errors.New("full nodes must configure FN_DB_URL, FN_LOG_URL, FN_MQ_URL")
 		}
-		da := agent.NewDirectCallDataAccess(s.logstore, s.mq)
+		var daOpts []agent.DirectDataAccessOption
+		if s.deadLetterMQ != nil {
+			daOpts = append(daOpts, agent.WithDeadLetterQueue(s.deadLetterMQ))
+		}
+		da := agent.NewDirectCallDataAccess(s.logstore, s.mq, daOpts...)
 		dq := agent.NewDirectDequeueAccess(s.mq)
 		s.agent = agent.New(da, agent.WithAsync(dq))
 		return nil
@@ -635,9 +1297,10 @@ func New(ctx context.Context, opts ...Option) *Server {
 	log := common.Logger(ctx)
 	engine := gin.New()
 	s := &Server{
-		Router:      engine,
-		AdminRouter: engine,
-		lbEnqueue:   agent.NewUnsupportedAsyncEnqueueAccess(),
+		Router:         engine,
+		AdminRouter:    engine,
+		lbEnqueue:      agent.NewUnsupportedAsyncEnqueueAccess(),
+		requestSchemas: cache.New(5*time.Minute, 10*time.Minute),
 		svcConfigs: map[string]*http.Server{
 			WebServer:   &http.Server{},
 			AdminServer: &http.Server{},
@@ -659,6 +1322,15 @@ func New(ctx context.Context, opts ...Option) *Server {
 	if s.svcConfigs[WebServer].Addr == "" {
 		s.svcConfigs[WebServer].Addr = fmt.Sprintf(":%d", DefaultPort)
 	}
+	if s.rateLimits == nil {
+		s.rateLimits = ratelimit.NewMemoryStore()
+	}
+	if s.responseCache == nil {
+		s.responseCache = responsecache.NewMemoryStore(responsecache.DefaultMemorySize)
+	}
+	if s.idempotencyTTL == 0 {
+		s.idempotencyTTL = DefaultIdempotencyTTL
+	}
 	if s.svcConfigs[AdminServer].Addr == "" {
 		s.svcConfigs[AdminServer].Addr = fmt.Sprintf(":%d", DefaultPort)
 	}
@@ -708,6 +1380,15 @@ func New(ctx context.Context, opts ...Option) *Server {
 		log.Fatal("unknown server type %d", s.nodeType)
 
 	}
+	if s.apiKeys && s.datastore == nil {
+		log.Fatalf("Invalid configuration for server type %s, API keys need a datastore", s.nodeType)
+	}
+	if s.rbac && s.datastore == nil {
+		log.Fatalf("Invalid configuration for server type %s, role bindings need a datastore", s.nodeType)
+	}
+	if s.rbac && !s.authEnabled() {
+		log.Fatal("Invalid configuration, role bindings need OIDC or API keys to authenticate requests")
+	}
 
 	setMachineID()
 	s.Router.Use(loggerWrap, traceWrap) // TODO should be opts
@@ -721,12 +1402,40 @@ func New(ctx context.Context, opts ...Option) *Server {
 	s.appListeners = new(appListeners)
 	s.fnListeners = new(fnListeners)
 	s.triggerListeners = new(triggerListeners)
+	s.datastoreHooks = new(datastoreHooks)
+	if p, ok := s.agent.(agent.Provisioner); ok && s.nodeType == ServerTypeFull {
+		s.AddFnListener(&minReadyListener{s: s, provisioner: p})
+	}
 
 	// TODO it's not clear that this is always correct as the read store  won't  get wrapping
 	s.datastore = datastore.Wrap(s.datastore)
+	s.datastore = fnext.NewHookedDatastore(s.datastore, s.datastoreHooks)
 	s.datastore = fnext.NewDatastore(s.datastore, s.appListeners, s.fnListeners, s.triggerListeners)
+	if s.nodeType == ServerTypeFull || s.nodeType == ServerTypeAPI {
+		var webhookOpts []webhooks.Option
+		if s.webhookPrivateNetworks {
+			webhookOpts = append(webhookOpts, webhooks.WithPrivateNetworks())
+		}
+		s.webhooks = webhooks.New(s.datastore, webhookOpts...)
+		s.datastore = webhooks.Wrap(s.datastore, s.webhooks)
+		if s.auditTrail == nil {
+			s.auditTrail = trail.NewDatastoreSink(s.datastore)
+		}
+		s.datastore = trail.Wrap(s.datastore, s.auditTrail)
+	}
 	s.logstore = logs.Wrap(s.logstore)
 
+	if s.datastore != nil {
+		var apps usage.AppReader = s.datastore
+		if s.lbReadAccess != nil {
+			apps = s.lbReadAccess
+		}
+		s.usage = usage.New(s.datastore, apps)
+		if s.agent != nil {
+			s.AddCallListener(s.usage)
+		}
+	}
+
 	return s
 }
 
@@ -800,6 +1509,14 @@ func WithoutCallEndpoints() Option {
 	}
 }
 
+// WithWebhookPrivateNetworks maps EnvWebhookPrivateNetworks
+func WithWebhookPrivateNetworks(allow bool) Option {
+	return func(ctx context.Context, s *Server) error {
+		s.webhookPrivateNetworks = allow
+		return nil
+	}
+}
+
 // WithJaeger maps EnvJaegerURL
 func WithJaeger(jaegerURL string) Option {
 	return func(ctx context.Context, s *Server) error {
@@ -1003,10 +1720,18 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 	logrus.WithField("type", s.nodeType).Infof("Fn serving on `%v`", s.svcConfigs[WebServer].Addr)
 
 	installChildReaper()
+	s.reloadOnHangup(ctx)
 
 	server := s.svcConfigs[WebServer]
 	if server.Handler == nil {
-		server.Handler = &ochttp.Handler{Handler: s.Router}
+		var handler http.Handler = s.Router
+		if s.routesDomains() {
+			handler = s.domainRouter(handler)
+		}
+		server.Handler = &ochttp.Handler{Handler: handler}
+	}
+	if err := s.configureHTTP2(server); err != nil {
+		logrus.WithError(err).Fatal("cannot configure HTTP/2")
 	}
 
 	go func() {
@@ -1053,6 +1778,65 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		}()
 	}
 
+	stopGRPCInvoke := func() {}
+	if s.grpcInvokeAddr != "" && s.agent != nil && !s.noFnInvokeEndpoint {
+		stopGRPCInvoke = s.startGRPCInvoke(cancel)
+	}
+	stopACMEChallenges := func() {}
+	if s.acme != nil {
+		stopACMEChallenges = s.startACMEChallenges(cancel)
+	}
+	stopGRPCManagement := func() {}
+	if s.grpcManagementAddr != "" && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeAPI) {
+		stopGRPCManagement = s.startGRPCManagement(cancel)
+	}
+
+	schedCtx, stopScheduler := context.WithCancel(ctx)
+	defer stopScheduler()
+	if s.schedulerInterval > 0 && s.datastore != nil && s.mq != nil && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeAPI) {
+		sched := scheduler.New(s.datastore, s.mq,
+			scheduler.WithInterval(s.schedulerInterval),
+			scheduler.WithLeaseTTL(3*s.schedulerInterval))
+		go sched.Run(schedCtx)
+	}
+
+	reaperCtx, stopReaper := context.WithCancel(ctx)
+	defer stopReaper()
+	if s.deleteRetention > 0 && s.datastore != nil && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeAPI) {
+		go reaper.New(s.datastore, s.deleteRetention).Run(reaperCtx)
+	}
+
+	janitorCtx, stopJanitor := context.WithCancel(ctx)
+	defer stopJanitor()
+	if s.logRetentionInterval > 0 && s.datastore != nil && s.logstore != nil && (s.nodeType == ServerTypeFull || s.nodeType == ServerTypeAPI) {
+		go janitor.New(s.datastore, s.logstore,
+			janitor.WithDefaultRetention(s.logRetention),
+			janitor.WithInterval(s.logRetentionInterval),
+			janitor.WithLeaseTTL(3*s.logRetentionInterval)).Run(janitorCtx)
+	}
+
+	hooksCtx, stopHooks := context.WithCancel(ctx)
+	defer stopHooks()
+	if s.webhooks != nil {
+		go s.webhooks.Run(hooksCtx)
+	}
+	if p, ok := s.agent.(agent.Provisioner); ok && s.nodeType == ServerTypeFull && s.datastore != nil {
+		go s.provisionMinReady(hooksCtx, p)
+	}
+
+	// usage is flushed one last time once the agent finished its calls
+	usageCtx, stopUsage := context.WithCancel(ctx)
+	defer stopUsage()
+	usageDone := make(chan struct{})
+	if s.usage != nil && s.agent != nil {
+		go func() {
+			defer close(usageDone)
+			s.usage.Run(usageCtx)
+		}()
+	} else {
+		close(usageDone)
+	}
+
 	// listening for signals or listener errors or cancellations on all registered contexts.
 	s.extraCtxs = append(s.extraCtxs, ctx)
 	cases := make([]reflect.SelectCase, len(s.extraCtxs))
@@ -1071,10 +1855,17 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 		}).Debug("Stopping because of closed channel from done context.")
 	}
 
-	// TODO: do not wait forever during graceful shutdown (add graceful shutdown timeout)
-	if err := server.Shutdown(context.Background()); err != nil {
+	// stop taking calls while still serving requests, so that callers are told to go elsewhere
+	s.drain()
+
+	shutCtx, shutCancel := s.drainContext()
+	defer shutCancel()
+	if err := server.Shutdown(shutCtx); err != nil {
 		logrus.WithError(err).Error("server shutdown error")
 	}
+	stopGRPCInvoke()
+	stopGRPCManagement()
+	stopACMEChallenges()
 
 	if s.agent != nil {
 		err := s.agent.Close() // after we stop taking requests, wait for all tasks to finish
@@ -1082,6 +1873,8 @@ func (s *Server) startGears(ctx context.Context, cancel context.CancelFunc) {
 			logrus.WithError(err).Error("Fail to close the agent")
 		}
 	}
+	stopUsage()
+	<-usageDone
 }
 
 func (s *Server) goneResponse(c *gin.Context) {
@@ -1092,10 +1885,12 @@ func (s *Server) bindHandlers(ctx context.Context) {
 	engine := s.Router
 	admin := s.AdminRouter
 	// now for extensible middleware
+	engine.Use(s.dropCallerIdentity)
 	engine.Use(s.rootMiddlewareWrapper())
 
 	engine.GET("/", handlePing)
 	admin.GET("/version", handleVersion)
+	s.healthSetup(admin)
 
 	// TODO: move under v1 ?
 	if s.promExporter != nil {
@@ -1104,42 +1899,152 @@ func (s *Server) bindHandlers(ctx context.Context) {
 
 	profilerSetup(admin, "/debug")
 
+	if np, ok := s.agent.(agent.NetworkPoolProvider); ok && np.NetworkPool() != nil {
+		s.networksSetup(admin, np.NetworkPool())
+	}
+
+	if _, ok := s.agent.(agent.Drainer); ok {
+		s.drainSetup(admin)
+	}
+
+	if sp, ok := s.agent.(agent.StatusProvider); ok {
+		s.nodeStatusSetup(admin, sp)
+	}
+
 	// Pure runners don't have any route, they have grpc
 	switch s.nodeType {
 
 	case ServerTypeFull, ServerTypeAPI:
 		cleanv2 := engine.Group("/v2")
 		v2 := cleanv2.Group("")
+		if s.authEnabled() {
+			v2.Use(s.authenticate(models.APIKeyScopeManage))
+		}
+		v2.Use(s.auditCaller)
+		if s.rbac {
+			v2.Use(s.authorizeManage)
+		}
 		v2.Use(s.apiMiddlewareWrapper())
 
 		{
 			v2.GET("/apps", s.handleAppList)
 			v2.POST("/apps", s.handleAppCreate)
 			v2.GET("/apps/:app_id", s.handleAppGet)
-			v2.PUT("/apps/:app_id", s.handleAppUpdate)
-			v2.DELETE("/apps/:app_id", s.handleAppDelete)
+			v2.PUT("/apps/:app_id", ifMatch, s.handleAppUpdate)
+			v2.DELETE("/apps/:app_id", ifMatch, s.handleAppDelete)
+			v2.POST("/apps/:app_id/restore", s.handleAppRestore)
+			v2.GET("/apps/:app_id/usage", s.handleAppUsage)
+			v2.GET("/apps/:app_id/openapi", s.handleAppOpenAPI)
 
 			v2.GET("/fns", s.handleFnList)
 			v2.POST("/fns", s.handleFnCreate)
 			v2.GET("/fns/:fn_id", s.handleFnGet)
-			v2.PUT("/fns/:fn_id", s.handleFnUpdate)
-			v2.DELETE("/fns/:fn_id", s.handleFnDelete)
+			v2.PUT("/fns/:fn_id", ifMatch, s.handleFnUpdate)
+			v2.DELETE("/fns/:fn_id", ifMatch, s.handleFnDelete)
+			v2.POST("/fns/:fn_id/restore", s.handleFnRestore)
+			v2.GET("/fns/:fn_id/revisions", s.handleFnRevisionList)
+			v2.GET("/fns/:fn_id/revisions/:revision", s.handleFnRevisionGet)
+			v2.POST("/fns/:fn_id/revisions/:revision/rollback", s.handleFnRollback)
+			v2.PUT("/fns/:fn_id/canary", s.handleFnCanarySet)
+			v2.POST("/fns/:fn_id/canary/promote", s.handleFnCanaryPromote)
+			v2.POST("/fns/:fn_id/canary/abort", s.handleFnCanaryAbort)
 
 			v2.GET("/triggers", s.handleTriggerList)
 			v2.POST("/triggers", s.handleTriggerCreate)
 			v2.GET("/triggers/:trigger_id", s.handleTriggerGet)
-			v2.PUT("/triggers/:trigger_id", s.handleTriggerUpdate)
-			v2.DELETE("/triggers/:trigger_id", s.handleTriggerDelete)
+			v2.PUT("/triggers/:trigger_id", ifMatch, s.handleTriggerUpdate)
+			v2.DELETE("/triggers/:trigger_id", ifMatch, s.handleTriggerDelete)
+
+			v2.POST("/apply", s.handleApply)
+
+			v2.GET("/search", s.handleSearch)
+
+			// batches may span apps
+			batch := v2.Group("/batch")
+			batch.Use(s.requireUnrestrictedAPIKey)
+			batch.POST("/apps", s.handleBatch(models.ApplyKindApp))
+			batch.POST("/fns", s.handleBatch(models.ApplyKindFn))
+			batch.POST("/triggers", s.handleBatch(models.ApplyKindTrigger))
+
+			// archives span apps, and carry their configs
+			archive := v2.Group("")
+			archive.Use(s.requireUnrestrictedAPIKey)
+			if s.rbac {
+				archive.Use(s.authorizeAllApps(models.PermissionAdmin))
+			}
+			archive.GET("/export", s.handleExport)
+			archive.POST("/import", s.handleImport)
+
+			v2.GET("/schedules", s.handleScheduleList)
+			v2.POST("/schedules", s.handleScheduleCreate)
+			v2.GET("/schedules/:schedule_id", s.handleScheduleGet)
+			v2.PUT("/schedules/:schedule_id", s.handleScheduleUpdate)
+			v2.DELETE("/schedules/:schedule_id", s.handleScheduleDelete)
+			v2.GET("/schedules/:schedule_id/runs", s.handleScheduleRunList)
+
+			// hostnames are not of a single app, only admins of all apps may map them
+			domainCreate := v2.Group("")
+			domainCreate.Use(s.requireUnrestrictedAPIKey)
+			if s.rbac {
+				domainCreate.Use(s.authorizeAllApps(models.PermissionAdmin))
+			}
+			domainCreate.POST("/domains", s.handleDomainCreate)
+
+			v2.GET("/domains", s.handleDomainList)
+			v2.GET("/domains/:domain_id", s.handleDomainGet)
+			v2.DELETE("/domains/:domain_id", s.handleDomainDelete)
+
+			v2.GET("/webhooks", s.handleWebhookList)
+			v2.POST("/webhooks", s.handleWebhookCreate)
+			v2.GET("/webhooks/:webhook_id", s.handleWebhookGet)
+			v2.DELETE("/webhooks/:webhook_id", s.handleWebhookDelete)
+			v2.GET("/webhooks/:webhook_id/deliveries", s.handleWebhookDeliveryList)
+
+			// keys restricted to an app may not manage keys
+			apiKeys := v2.Group("/apikeys")
+			apiKeys.Use(s.requireUnrestrictedAPIKey)
+			if s.rbac {
+				apiKeys.Use(s.authorize(models.PermissionAdmin))
+			}
+			apiKeys.GET("", s.handleAPIKeyList)
+			apiKeys.POST("", s.handleAPIKeyCreate)
+			apiKeys.DELETE("/:key_id", s.handleAPIKeyDelete)
+
+			roleBindings := v2.Group("/rolebindings")
+			if s.rbac {
+				roleBindings.Use(s.authorize(models.PermissionAdmin))
+			}
+			roleBindings.GET("", s.handleRoleBindingList)
+			roleBindings.POST("", s.handleRoleBindingCreate)
+			roleBindings.DELETE("/:binding_id", s.handleRoleBindingDelete)
+
+			// the audit trail spans all apps
+			auditTrail := v2.Group("/audit")
+			auditTrail.Use(s.requireUnrestrictedAPIKey)
+			if s.rbac {
+				auditTrail.Use(s.authorize(models.PermissionAdmin))
+			}
+			auditTrail.GET("", s.handleAuditEntryList)
 		}
 
 		if !s.noCallEndpoints {
 			v2.GET("/fns/:fn_id/calls", s.handleCallList)
 			v2.GET("/fns/:fn_id/calls/:call_id", s.handleCallGet)
 			v2.GET("/fns/:fn_id/calls/:call_id/log", s.handleCallLogGet)
+			v2.GET("/fns/:fn_id/calls/:call_id/result", s.handleCallResultGet)
+			v2.GET("/fns/:fn_id/deadletters", s.handleDeadLetterList)
+			v2.GET("/fns/:fn_id/deadletters/:call_id", s.handleDeadLetterGet)
+			v2.POST("/fns/:fn_id/deadletters/:call_id/replay", s.handleDeadLetterReplay)
+			v2.GET("/fns/:fn_id/logs/tail", s.handleLogTail)
 		} else {
 			v2.GET("/fns/:fn_id/calls", s.goneResponse)
 			v2.GET("/fns/:fn_id/calls/:call_id", s.goneResponse)
 			v2.GET("/fns/:fn_id/calls/:call_id/log", s.goneResponse)
+			v2.GET("/fns/:fn_id/calls/:call_id/result", s.goneResponse)
+			v2.GET("/fns/:fn_id/deadletters", s.goneResponse)
+			v2.GET("/fns/:fn_id/deadletters/:call_id", s.goneResponse)
+			v2.POST("/fns/:fn_id/deadletters/:call_id/replay", s.goneResponse)
+			v2.GET("/fns/:fn_id/logs/tail", s.goneResponse)
 		}
 
 		if !s.noHybridAPI { // Hybrid API - this should only be enabled on API servers
@@ -1163,12 +2068,27 @@ func (s *Server) bindHandlers(ctx context.Context) {
 	case ServerTypeFull, ServerTypeLB, ServerTypeRunner:
 		if !s.noHTTTPTriggerEndpoint {
 			lbTriggerGroup := engine.Group("/t")
+			lbTriggerGroup.Use(s.triggerCORS)
+			if s.authEnabled() {
+				lbTriggerGroup.Use(s.authenticate(models.APIKeyScopeInvoke))
+			}
+			if s.rbac {
+				lbTriggerGroup.Use(s.authorize(models.PermissionInvoke))
+			}
+			lbTriggerGroup.Use(s.limitInvokeRate)
 			lbTriggerGroup.Any("/:app_name", s.handleHTTPTriggerCall)
 			lbTriggerGroup.Any("/:app_name/*trigger_source", s.handleHTTPTriggerCall)
 		}
 
 		if !s.noFnInvokeEndpoint {
 			lbFnInvokeGroup := engine.Group("/invoke")
+			if s.authEnabled() {
+				lbFnInvokeGroup.Use(s.authenticate(models.APIKeyScopeInvoke))
+			}
+			if s.rbac {
+				lbFnInvokeGroup.Use(s.authorize(models.PermissionInvoke))
+			}
+			lbFnInvokeGroup.Use(s.limitInvokeRate)
 			lbFnInvokeGroup.POST("/:fn_id", s.handleFnInvokeCall)
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/fnproject/fn/api/common"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
 )
 
 // Option is a func that allows configuring a Server
@@ -35,7 +36,10 @@ func withRIDProvider(ridp *RIDProvider) func(c *gin.Context) {
 		// We set the rid in the common logger so it is always logged when the common logger is used
 		l := common.Logger(ctx).WithFields(logrus.Fields{common.RequestIDContextKey: rid})
 		ctx = common.WithLogger(ctx, l)
+		// and on the span of the request, so that traces line up with logs
+		trace.FromContext(ctx).AddAttributes(trace.StringAttribute(common.RequestIDContextKey, rid))
 		c.Request = c.Request.WithContext(ctx)
+		c.Header(ridp.HeaderName, rid)
 		c.Next()
 	}
 }
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRIDProvider(t *testing.T) {
+	router := gin.New()
+	router.Use(withRIDProvider(&RIDProvider{HeaderName: DefaultRequestIDHeader, RIDGenerator: common.FnRequestID}))
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, common.RequestIDFromContext(c.Request.Context()))
+	})
+
+	for _, test := range []struct {
+		header  string
+		adopted bool
+	}{
+		{"", false},
+		{"caller-req-1", true},
+		{"forged\nline", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			req.Header.Set(DefaultRequestIDHeader, test.header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		rid := rec.Body.String()
+		if test.adopted != (rid == test.header) || rid == "" {
+			t.Errorf("expected %q adopted %v, got %q", test.header, test.adopted, rid)
+		}
+		if rec.Header().Get(DefaultRequestIDHeader) != rid {
+			t.Errorf("expected the request id %q sent back, got %q", rid, rec.Header().Get(DefaultRequestIDHeader))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestSoftDelete(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit()
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI, WithAPIKeys(testAdminAPIKey), WithDeleteRetention(time.Hour))
+
+	request := func(method, path, secret, body string, expectedCode int, v interface{}) {
+		req := createRequest(t, method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		_, rec := routerRequest2(t, srv.Router, req)
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var app models.App
+	request("POST", "/v2/apps", testAdminAPIKey, `{"name": "myapp"}`, http.StatusOK, &app)
+	var fn models.Fn
+	request("POST", "/v2/fns", testAdminAPIKey, `{"name": "myfn", "app_id": "`+app.ID+`", "image": "fnproject/fn-test-utils"}`, http.StatusOK, &fn)
+	var appKey models.APIKey
+	request("POST", "/v2/apikeys", testAdminAPIKey, `{"name": "deployer", "app_id": "`+app.ID+`", "scope": "manage"}`, http.StatusOK, &appKey)
+
+	// deleted fns are hidden until they are restored
+	request("DELETE", "/v2/fns/"+fn.ID, appKey.Secret, "", http.StatusNoContent, nil)
+	request("GET", "/v2/fns/"+fn.ID, appKey.Secret, "", http.StatusNotFound, nil)
+	request("DELETE", "/v2/fns/"+fn.ID, appKey.Secret, "", http.StatusNotFound, nil)
+	var fns models.FnList
+	request("GET", "/v2/fns?app_id="+app.ID, appKey.Secret, "", http.StatusOK, &fns)
+	if len(fns.Items) != 0 {
+		t.Fatalf("expected the deleted fn not to be listed, got %+v", fns.Items)
+	}
+	request("GET", "/v2/fns?app_id="+app.ID+"&deleted=true", appKey.Secret, "", http.StatusOK, &fns)
+	if len(fns.Items) != 1 || fns.Items[0].ID != fn.ID || fns.Items[0].DeletedAt == nil {
+		t.Fatalf("expected the deleted fn to be listed as deleted, got %+v", fns.Items)
+	}
+	var restored models.Fn
+	request("POST", "/v2/fns/"+fn.ID+"/restore", appKey.Secret, "", http.StatusOK, &restored)
+	if restored.ID != fn.ID || restored.DeletedAt != nil {
+		t.Fatalf("expected the fn to be restored, got %+v", restored)
+	}
+	request("POST", "/v2/fns/"+fn.ID+"/restore", appKey.Secret, "", http.StatusNotFound, nil)
+
+	// deleted apps are restored with their fns
+	request("DELETE", "/v2/apps/"+app.ID, testAdminAPIKey, "", http.StatusNoContent, nil)
+	request("GET", "/v2/apps/"+app.ID, testAdminAPIKey, "", http.StatusNotFound, nil)
+	request("POST", "/v2/apps", testAdminAPIKey, `{"name": "myapp"}`, http.StatusConflict, nil)
+	request("POST", "/v2/fns/"+fn.ID+"/restore", testAdminAPIKey, "", http.StatusNotFound, nil)
+	var apps models.AppList
+	request("GET", "/v2/apps?deleted=true", testAdminAPIKey, "", http.StatusOK, &apps)
+	if len(apps.Items) != 1 || apps.Items[0].ID != app.ID || apps.Items[0].DeletedAt == nil {
+		t.Fatalf("expected the deleted app to be listed as deleted, got %+v", apps.Items)
+	}
+	var restoredApp models.App
+	request("POST", "/v2/apps/"+app.ID+"/restore", testAdminAPIKey, "", http.StatusOK, &restoredApp)
+	if restoredApp.ID != app.ID || restoredApp.DeletedAt != nil {
+		t.Fatalf("expected the app to be restored, got %+v", restoredApp)
+	}
+	request("GET", "/v2/fns/"+fn.ID, testAdminAPIKey, "", http.StatusOK, nil)
+}
+
+func TestDeleteWithoutRetention(t *testing.T) {
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	ds := datastore.NewMockInit([]*models.App{app})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	_, rec := routerRequest(t, srv.Router, "DELETE", "/v2/apps/"+app.ID, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status code %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	_, rec = routerRequest(t, srv.Router, "POST", "/v2/apps/"+app.ID+"/restore", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected apps deleted without retention not to be restored, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// corsHeadersContextKey holds the CORS headers of the response of an HTTP trigger call
+const corsHeadersContextKey = "fn_cors_headers"
+
+// request headers browsers may send in cross-origin requests without a preflight allowing them
+var corsSafelistedHeaders = map[string]bool{
+	"Accept":           true,
+	"Accept-Language":  true,
+	"Content-Language": true,
+	"Content-Type":     true,
+}
+
+// triggerCORS is the first middleware of the HTTP trigger endpoints. For triggers with a CORS
+// configuration, see models.TriggerCORSAnnotation, it answers preflight requests without
+// calling the function and sets the CORS headers of the responses of cross-origin calls.
+func (s *Server) triggerCORS(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		c.Next()
+		return
+	}
+	// calls of apps or triggers that don't exist are reported by the handler
+	trigger, err := s.requestTrigger(c.Request.Context(), c)
+	if err != nil {
+		c.Next()
+		return
+	}
+	// invalid annotations are rejected when triggers are changed
+	cors, _ := models.CORSFromAnnotations(trigger.Annotations)
+	if cors == nil {
+		c.Next()
+		return
+	}
+
+	h := c.Writer.Header()
+	if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+		setCORSPreflightHeaders(h, cors, c.Request)
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	headers := corsHeaders(cors, origin)
+	for k, vs := range headers {
+		h[k] = vs
+	}
+	c.Set(corsHeadersContextKey, headers)
+	c.Next()
+}
+
+func (s *Server) requestTrigger(ctx context.Context, c *gin.Context) (*models.Trigger, error) {
+	appID, err := s.lbReadAccess.GetAppID(ctx, c.Param(api.AppName))
+	if err != nil {
+		return nil, err
+	}
+	source := c.Param(api.TriggerSource)
+	if source == "" {
+		source = "/"
+	}
+	return s.lbReadAccess.GetTriggerBySource(ctx, appID, models.TriggerTypeHTTP, source)
+}
+
+// corsHeaders returns the CORS headers of the responses to origin, only Vary if the origin
+// isn't allowed.
+func corsHeaders(cors *models.CORS, origin string) http.Header {
+	h := http.Header{}
+	h.Set("Vary", "Origin")
+	if !cors.AllowsOrigin(origin) {
+		return h
+	}
+	if cors.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	} else if cors.AllowsOrigin("*") {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if len(cors.ExposeHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+	}
+	return h
+}
+
+// setCORSPreflightHeaders allows the method and the headers requested by a preflight request
+// if the configuration allows them all, browsers then reject the request otherwise.
+func setCORSPreflightHeaders(h http.Header, cors *models.CORS, req *http.Request) {
+	h.Set("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	origin := req.Header.Get("Origin")
+	method := req.Header.Get("Access-Control-Request-Method")
+	if !cors.AllowsOrigin(origin) || !cors.AllowsMethod(method) {
+		return
+	}
+	var headers []string
+	for _, v := range req.Header["Access-Control-Request-Headers"] {
+		for _, header := range strings.Split(v, ",") {
+			header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+			if header == "" {
+				continue
+			}
+			if !corsSafelistedHeaders[header] && !cors.AllowsHeader(header) {
+				return
+			}
+			headers = append(headers, header)
+		}
+	}
+
+	for k, vs := range corsHeaders(cors, origin) {
+		if k != "Vary" && k != "Access-Control-Expose-Headers" {
+			h[k] = vs
+		}
+	}
+	h.Set("Access-Control-Allow-Methods", method)
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if cors.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	}
+}
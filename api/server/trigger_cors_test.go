@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnproject/fn/api/agent"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTriggerCORS(t *testing.T) {
+	withCORS := &models.Trigger{ID: "trigger_id", AppID: "app_id", FnID: "fn_id", Name: "cors", Type: models.TriggerTypeHTTP, Source: "/cors"}
+	withCORS.Annotations, _ = withCORS.Annotations.With(models.TriggerCORSAnnotation, models.CORS{
+		AllowOrigins:  []string{"https://example.com"},
+		AllowMethods:  []string{"GET", "PUT"},
+		AllowHeaders:  []string{"X-Token"},
+		ExposeHeaders: []string{"X-Result"},
+		MaxAge:        600,
+	})
+	ds := datastore.NewMockInit(
+		[]*models.App{{ID: "app_id", Name: "myapp"}},
+		[]*models.Fn{{ID: "fn_id", AppID: "app_id", Name: "myfn"}},
+		[]*models.Trigger{withCORS, {ID: "other_id", AppID: "app_id", FnID: "fn_id", Name: "other", Type: models.TriggerTypeHTTP, Source: "/other"}},
+	)
+	s := &Server{lbReadAccess: agent.NewCachedDataAccess(ds)}
+
+	called := 0
+	engine := gin.New()
+	engine.Any("/t/:app_name/*trigger_source", s.triggerCORS, func(c *gin.Context) {
+		called++
+		rw := &triggerResponseWriter{inner: c.Writer}
+		if h, ok := c.Get(corsHeadersContextKey); ok {
+			rw.headers = h.(http.Header)
+		}
+		rw.Header().Set("Fn-Http-H-Access-Control-Allow-Origin", "*")
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	call := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// preflight requests are answered without calling the function
+	rec := call("OPTIONS", "/t/myapp/cors", map[string]string{
+		"Origin":                         "https://example.com",
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "x-token, content-type",
+	})
+	h := rec.Header()
+	if rec.Code != http.StatusNoContent || called != 0 {
+		t.Fatalf("expected preflight to be answered by the server, got %d, %d calls", rec.Code, called)
+	}
+	if h.Get("Access-Control-Allow-Origin") != "https://example.com" || h.Get("Access-Control-Allow-Methods") != "PUT" ||
+		h.Get("Access-Control-Allow-Headers") != "X-Token, Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("unexpected preflight headers %v", h)
+	}
+
+	for i, headers := range []map[string]string{
+		{"Origin": "https://evil.com", "Access-Control-Request-Method": "GET"},
+		{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"},
+		{"Origin": "https://example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Other"},
+	} {
+		rec = call("OPTIONS", "/t/myapp/cors", headers)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Fatalf("test %d: expected preflight to be denied, got %d %v", i, rec.Code, rec.Header())
+		}
+	}
+
+	// responses to allowed origins carry the CORS headers of the trigger, not the function's
+	rec = call("GET", "/t/myapp/cors", map[string]string{"Origin": "https://example.com"})
+	h = rec.Header()
+	if called != 1 || h.Get("Access-Control-Allow-Origin") != "https://example.com" ||
+		h.Get("Access-Control-Expose-Headers") != "X-Result" || h.Get("Vary") != "Origin" {
+		t.Fatalf("unexpected response headers %v", h)
+	}
+	rec = call("GET", "/t/myapp/cors", map[string]string{"Origin": "https://evil.com"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected no CORS headers for other origins, got %v", rec.Header())
+	}
+
+	// triggers without CORS configuration leave preflight requests to the function
+	rec = call("OPTIONS", "/t/myapp/other", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"})
+	if called != 3 || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected the function to answer the preflight, got %v", rec.Header())
+	}
+}
@@ -24,6 +24,11 @@ func (s *Server) handleTriggerCreate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkRequestApp(c, trigger.AppID, trigger.FnID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	triggerCreated, err := s.datastore.InsertTrigger(ctx, trigger)
 	if err != nil {
 		handleErrorResponse(c, err)
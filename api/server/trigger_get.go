@@ -24,6 +24,7 @@ func (s *Server) handleTriggerGet(c *gin.Context) {
 		return
 	}
 
+	setETag(c, trigger)
 	trigger, err = s.triggerAnnotator.AnnotateTrigger(c, app, trigger)
 	if err != nil {
 		handleErrorResponse(c, err)
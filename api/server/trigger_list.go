@@ -23,6 +23,19 @@ func (s *Server) handleTriggerList(c *gin.Context) {
 
 	filter.FnID = c.Query("fn_id")
 	filter.Name = c.Query("name")
+	filter.NamePrefix = c.Query("name_prefix")
+
+	var err error
+	filter.Annotations, err = models.ParseKeyFilters(c.QueryArray("annotation"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+	filter.Order, err = models.ParseListOrder(c.Query("order_by"), c.Query("order"))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
 
 	triggers, err := s.datastore.GetTriggers(ctx, filter)
 	if err != nil {
@@ -21,6 +21,11 @@ func (s *Server) handleTriggerUpdate(c *gin.Context) {
 		return
 	}
 
+	if err := s.checkRequestApp(c, trigger.AppID, trigger.FnID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
 	pathTriggerID := c.Param(api.TriggerID)
 
 	if trigger.ID == "" {
@@ -38,5 +43,6 @@ func (s *Server) handleTriggerUpdate(c *gin.Context) {
 		return
 	}
 
+	setETag(c, triggerUpdated)
 	c.JSON(http.StatusOK, triggerUpdated)
 }
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// handleWebhookCreate registers a webhook, a secret is generated unless one is given. The
+// secret is only ever returned in the response.
+func (s *Server) handleWebhookCreate(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhook := &models.Webhook{}
+
+	err := c.BindJSON(webhook)
+	if err != nil {
+		if models.IsAPIError(err) {
+			handleErrorResponse(c, err)
+		} else {
+			handleErrorResponse(c, models.ErrInvalidJSON)
+		}
+		return
+	}
+
+	if err := s.checkRequestApp(c, webhook.AppID, ""); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret, err = models.NewWebhookSecret()
+		if err != nil {
+			handleErrorResponse(c, err)
+			return
+		}
+	}
+
+	webhookCreated, err := s.datastore.InsertWebhook(ctx, webhook)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookCreated)
+}
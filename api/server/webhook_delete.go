@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+// handleWebhookDelete removes a webhook and its delivery history, deliveries in progress are
+// not recorded.
+func (s *Server) handleWebhookDelete(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.datastore.RemoveWebhook(ctx, c.Param(api.WebhookID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.String(http.StatusNoContent, "")
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleWebhookDeliveryList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	webhookID := c.Param(api.WebhookID)
+
+	// 404 for deliveries of webhooks that don't exist, rather than an empty list
+	if _, err := s.datastore.GetWebhookByID(ctx, webhookID); err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	filter := &models.WebhookDeliveryFilter{WebhookID: webhookID}
+	filter.Cursor, filter.PerPage = pageParams(c)
+
+	deliveries, err := s.datastore.GetWebhookDeliveries(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
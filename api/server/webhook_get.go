@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleWebhookGet(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	webhook, err := s.datastore.GetWebhookByID(ctx, c.Param(api.WebhookID))
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
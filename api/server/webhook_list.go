@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fnproject/fn/api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleWebhookList(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := &models.WebhookFilter{}
+	filter.Cursor, filter.PerPage = pageParams(c)
+	filter.AppID = c.Query("app_id")
+
+	webhooks, err := s.datastore.GetWebhooks(ctx, filter)
+	if err != nil {
+		handleErrorResponse(c, err)
+		return
+	}
+
+	for _, webhook := range webhooks.Items {
+		webhook.Secret = ""
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/logs"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/api/mqs"
+)
+
+func TestWebhooks(t *testing.T) {
+	buf := setLogBuffer()
+	defer func() {
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}()
+
+	ds := datastore.NewMockInit([]*models.App{{ID: "app_id", Name: "myapp"}})
+	srv := testServer(ds, &mqs.Mock{}, logs.NewMock(), nil, ServerTypeAPI)
+
+	request := func(method, path, body string, expectedCode int, v interface{}) {
+		_, rec := routerRequest(t, srv.Router, method, path, bytes.NewBufferString(body))
+		if rec.Code != expectedCode {
+			t.Fatalf("%s %s: expected status code %d, got %d: %s", method, path, expectedCode, rec.Code, rec.Body.String())
+		}
+		if v != nil {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var webhook models.Webhook
+	request("POST", "/v2/webhooks", `{"url": "https://hooks.example.com/fn", "events": ["fn.created", "fn.deleted"]}`, http.StatusOK, &webhook)
+	if webhook.ID == "" || webhook.URL != "https://hooks.example.com/fn" || len(webhook.Events) != 2 {
+		t.Fatalf("unexpected webhook %+v", webhook)
+	}
+	if !strings.HasPrefix(webhook.Secret, models.WebhookSecretPrefix) {
+		t.Fatalf("expected a generated secret, got %q", webhook.Secret)
+	}
+
+	var scoped models.Webhook
+	request("POST", "/v2/webhooks", `{"url": "http://hooks.example.com/app", "app_id": "app_id", "secret": "0123456789abcdef"}`, http.StatusOK, &scoped)
+	if scoped.Secret != "0123456789abcdef" || scoped.AppID != "app_id" {
+		t.Fatalf("unexpected webhook %+v", scoped)
+	}
+	request("POST", "/v2/webhooks", `{"url": "hooks.example.com"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/webhooks", `{"url": "https://hooks.example.com", "events": ["fn.invoked"]}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/webhooks", `{"url": "https://hooks.example.com", "secret": "short"}`, http.StatusBadRequest, nil)
+	request("POST", "/v2/webhooks", `{"url": "https://hooks.example.com", "app_id": "missing"}`, http.StatusNotFound, nil)
+
+	// the secret is only returned on creation
+	var got models.Webhook
+	request("GET", "/v2/webhooks/"+webhook.ID, "", http.StatusOK, &got)
+	if got.ID != webhook.ID || got.URL != webhook.URL || got.Secret != "" {
+		t.Fatalf("expected %+v without secret, got %+v", webhook, got)
+	}
+
+	var webhooks models.WebhookList
+	request("GET", "/v2/webhooks", "", http.StatusOK, &webhooks)
+	if len(webhooks.Items) != 2 {
+		t.Fatalf("expected both webhooks, got %+v", webhooks.Items)
+	}
+	for _, w := range webhooks.Items {
+		if w.Secret != "" {
+			t.Fatalf("expected no secret, got %+v", w)
+		}
+	}
+	request("GET", "/v2/webhooks?app_id=app_id", "", http.StatusOK, &webhooks)
+	if len(webhooks.Items) != 1 || webhooks.Items[0].ID != scoped.ID {
+		t.Fatalf("expected the webhook of the app, got %+v", webhooks.Items)
+	}
+
+	delivery := &models.WebhookDelivery{
+		ID:          id.New().String(),
+		WebhookID:   webhook.ID,
+		EventID:     id.New().String(),
+		Event:       models.EventFnCreated,
+		Status:      models.WebhookDelivered,
+		Attempts:    1,
+		StatusCode:  http.StatusOK,
+		CreatedAt:   common.DateTime(time.Now()),
+		CompletedAt: common.DateTime(time.Now()),
+	}
+	if err := ds.InsertWebhookDelivery(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+	var deliveries models.WebhookDeliveryList
+	request("GET", "/v2/webhooks/"+webhook.ID+"/deliveries", "", http.StatusOK, &deliveries)
+	if len(deliveries.Items) != 1 || deliveries.Items[0].ID != delivery.ID || deliveries.Items[0].Status != models.WebhookDelivered {
+		t.Fatalf("expected the delivery, got %+v", deliveries.Items)
+	}
+
+	request("DELETE", "/v2/webhooks/"+webhook.ID, "", http.StatusNoContent, nil)
+	request("GET", "/v2/webhooks/"+webhook.ID, "", http.StatusNotFound, nil)
+	request("GET", "/v2/webhooks/"+webhook.ID+"/deliveries", "", http.StatusNotFound, nil)
+	request("DELETE", "/v2/webhooks/"+webhook.ID, "", http.StatusNotFound, nil)
+
+	// webhooks restricted to an app are removed with it
+	request("DELETE", "/v2/apps/app_id", "", http.StatusNoContent, nil)
+	request("GET", "/v2/webhooks/"+scoped.ID, "", http.StatusNotFound, nil)
+}
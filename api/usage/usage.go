@@ -0,0 +1,261 @@
+// Package usage tracks the calls and the compute usage of apps, and enforces the daily and
+// monthly quotas set in their fnproject.io/app/usageQuota annotation.
+//
+// A Tracker is a call listener of an agent. The usage of the calls completed on the node is
+// added up in memory and flushed to the datastore at an interval, so the usage of the other
+// nodes sharing the datastore is known up to that interval late and apps may exceed their
+// quotas by the calls made in the meantime. The quotas of apps and their usage in the
+// datastore are cached for the same interval, so that calls don't read the datastore, and
+// changes to quotas apply up to that interval late.
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+	"github.com/fnproject/fn/fnext"
+	"github.com/patrickmn/go-cache"
+)
+
+// DefaultFlushInterval is the default interval usage is flushed to the datastore at
+const DefaultFlushInterval = 10 * time.Second
+
+// AppReader gets the apps of calls
+type AppReader interface {
+	GetAppByID(ctx context.Context, appID string) (*models.App, error)
+}
+
+// Tracker tracks and enforces the usage of apps, see the package documentation
+type Tracker struct {
+	ds       models.Datastore
+	apps     AppReader
+	interval time.Duration
+	now      func() time.Time
+
+	quotas *cache.Cache // *models.UsageQuota by app id, nil without quota
+	stored *cache.Cache // models.AppUsage in the datastore by app id and period
+
+	lock     sync.Mutex // protects pending and flushing
+	pending  map[pendingKey]*models.AppUsage
+	flushing map[pendingKey]*models.AppUsage // usage being added to the datastore
+}
+
+type pendingKey struct {
+	appID  string
+	period string
+}
+
+func (k pendingKey) String() string {
+	return k.appID + "/" + k.period
+}
+
+var _ fnext.CallListener = &Tracker{}
+
+// Option configures a Tracker
+type Option func(*Tracker)
+
+// WithFlushInterval sets the interval usage is flushed to the datastore at
+func WithFlushInterval(d time.Duration) Option {
+	return func(t *Tracker) {
+		t.interval = d
+	}
+}
+
+// New creates a tracker keeping usage in ds, apps is used to get the quotas of apps
+func New(ds models.Datastore, apps AppReader, opts ...Option) *Tracker {
+	t := &Tracker{
+		ds:       ds,
+		apps:     apps,
+		interval: DefaultFlushInterval,
+		now:      time.Now,
+		pending:  make(map[pendingKey]*models.AppUsage),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.quotas = cache.New(t.interval, 10*t.interval)
+	t.stored = cache.New(t.interval, 10*t.interval)
+	return t
+}
+
+// BeforeCall rejects the calls of apps that exhausted their quota with
+// models.ErrUsageQuotaExceeded. Calls are not rejected if their usage can't be read.
+func (t *Tracker) BeforeCall(ctx context.Context, call *models.Call) error {
+	quota, err := t.quota(ctx, call.AppID)
+	if err != nil {
+		common.Logger(ctx).WithError(err).Error("failed to get app to check its usage quota")
+		return nil
+	}
+	if quota == nil {
+		return nil
+	}
+
+	daily, monthly := models.UsagePeriods(t.now())
+	for _, q := range []struct {
+		period string
+		limits *models.UsageLimits
+	}{{daily, quota.Daily}, {monthly, quota.Monthly}} {
+		if q.limits == nil {
+			continue
+		}
+		usage, err := t.Usage(ctx, call.AppID, q.period)
+		if err != nil {
+			common.Logger(ctx).WithError(err).Error("failed to get app usage to check its quota")
+			return nil
+		}
+		if usage.Exhausts(q.limits) {
+			return models.ErrUsageQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// quota returns the quota of an app, nil if it has none
+func (t *Tracker) quota(ctx context.Context, appID string) (*models.UsageQuota, error) {
+	if v, ok := t.quotas.Get(appID); ok {
+		return v.(*models.UsageQuota), nil
+	}
+	app, err := t.apps.GetAppByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	// invalid annotations are rejected when apps are changed
+	quota, _ := models.UsageQuotaFromAnnotations(app.Annotations)
+	t.quotas.Set(appID, quota, cache.DefaultExpiration)
+	return quota, nil
+}
+
+// AfterCall adds the usage of a call to the usage of its app
+func (t *Tracker) AfterCall(ctx context.Context, call *models.Call) error {
+	var mbMs int64
+	started, completed := time.Time(call.StartedAt), time.Time(call.CompletedAt)
+	if !started.IsZero() && completed.After(started) {
+		mbMs = int64(call.Memory) * int64(completed.Sub(started)/time.Millisecond)
+	}
+
+	daily, monthly := models.UsagePeriods(t.now())
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, period := range []string{daily, monthly} {
+		k := pendingKey{call.AppID, period}
+		usage, ok := t.pending[k]
+		if !ok {
+			usage = &models.AppUsage{AppID: call.AppID, Period: period}
+			t.pending[k] = usage
+		}
+		usage.Calls++
+		usage.MBMilliseconds += mbMs
+	}
+	return nil
+}
+
+// Usage returns the usage of an app over a period, including the usage not flushed yet
+func (t *Tracker) Usage(ctx context.Context, appID, period string) (*models.AppUsage, error) {
+	k := pendingKey{appID, period}
+	if usage, ok := t.addPending(k, nil); ok {
+		return usage, nil
+	}
+
+	stored, err := t.ds.GetAppUsage(ctx, appID, period)
+	if err != nil {
+		return nil, err
+	}
+	usage, _ := t.addPending(k, stored)
+	return usage, nil
+}
+
+// addPending returns the usage of the datastore, stored or else the cached one, with the
+// usage not flushed yet added. The cache is read under the lock so that the usage Flush adds
+// to the datastore is counted once.
+func (t *Tracker) addPending(k pendingKey, stored *models.AppUsage) (*models.AppUsage, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var usage models.AppUsage
+	if stored != nil {
+		usage = *stored
+		t.stored.Set(k.String(), usage, cache.DefaultExpiration)
+	} else if v, ok := t.stored.Get(k.String()); ok {
+		usage = v.(models.AppUsage)
+	} else {
+		return nil, false
+	}
+
+	for _, p := range []*models.AppUsage{t.pending[k], t.flushing[k]} {
+		if p != nil {
+			usage.Calls += p.Calls
+			usage.MBMilliseconds += p.MBMilliseconds
+		}
+	}
+	usage.SetGBSeconds()
+	return &usage, true
+}
+
+// Run flushes usage at the interval of the tracker until ctx is done, flushing it one last
+// time before returning.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.Flush(common.BackgroundContext(ctx))
+			return
+		case <-ticker.C:
+			t.Flush(ctx)
+		}
+	}
+}
+
+// Flush adds the usage tracked since the last flush to the datastore. Usage that fails to
+// be added is merged back into the pending usage to be flushed again, and is counted by
+// Usage meanwhile.
+func (t *Tracker) Flush(ctx context.Context) {
+	t.lock.Lock()
+	t.flushing = t.pending
+	t.pending = make(map[pendingKey]*models.AppUsage)
+	flushing := t.flushing
+	t.lock.Unlock()
+
+	for k, usage := range flushing {
+		err := t.ds.AddAppUsage(ctx, usage)
+
+		t.lock.Lock()
+		delete(t.flushing, k)
+		if err != nil {
+			common.Logger(ctx).WithError(err).WithField("app_id", k.appID).Error("failed to flush app usage")
+			if p, ok := t.pending[k]; ok {
+				p.Calls += usage.Calls
+				p.MBMilliseconds += usage.MBMilliseconds
+			} else {
+				t.pending[k] = usage
+			}
+		} else {
+			// the usage in the datastore now includes it
+			t.stored.Delete(k.String())
+		}
+		t.lock.Unlock()
+	}
+}
+
+// Report returns the usage of app over the current day and month, with the limits of its quota
+func (t *Tracker) Report(ctx context.Context, app *models.App) (*models.AppUsageReport, error) {
+	daily, monthly := models.UsagePeriods(t.now())
+	report := &models.AppUsageReport{}
+	var err error
+	if report.Daily, err = t.Usage(ctx, app.ID, daily); err != nil {
+		return nil, err
+	}
+	if report.Monthly, err = t.Usage(ctx, app.ID, monthly); err != nil {
+		return nil, err
+	}
+	if quota, _ := models.UsageQuotaFromAnnotations(app.Annotations); quota != nil {
+		report.Daily.Limits = quota.Daily
+		report.Monthly.Limits = quota.Monthly
+	}
+	return report, nil
+}
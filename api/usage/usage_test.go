@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.UsageQuotaAnnotation, models.UsageQuota{
+		Daily:   &models.UsageLimits{Calls: 3},
+		Monthly: &models.UsageLimits{GBSeconds: 10},
+	})
+	ds := datastore.NewMockInit([]*models.App{app, {ID: "other_id", Name: "other"}})
+
+	now := time.Date(2018, 3, 31, 23, 0, 0, 0, time.UTC)
+	tr := New(ds, ds)
+	tr.now = func() time.Time { return now }
+
+	call := func(appID string, memory uint64, d time.Duration) error {
+		c := &models.Call{AppID: appID, Memory: memory}
+		if err := tr.BeforeCall(ctx, c); err != nil {
+			return err
+		}
+		c.StartedAt = common.DateTime(now)
+		c.CompletedAt = common.DateTime(now.Add(d))
+		return tr.AfterCall(ctx, c)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := call("app_id", 128, time.Second); err != nil {
+			t.Fatalf("expected call %d to be allowed, got %v", i, err)
+		}
+	}
+	if err := call("app_id", 128, time.Second); err != models.ErrUsageQuotaExceeded {
+		t.Fatalf("expected the daily quota to be exhausted, got %v", err)
+	}
+	// apps without quota are not limited
+	for i := 0; i < 5; i++ {
+		if err := call("other_id", 128, time.Second); err != nil {
+			t.Fatalf("expected calls of apps without quota to be allowed, got %v", err)
+		}
+	}
+
+	usage, err := tr.Usage(ctx, "app_id", "2018-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Calls != 3 || usage.GBSeconds != 0.375 {
+		t.Fatalf("expected pending usage to be counted, got %+v", usage)
+	}
+
+	tr.Flush(ctx)
+	usage, err = ds.GetAppUsage(ctx, "app_id", "2018-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Calls != 3 || usage.MBMilliseconds != 3*128*1000 {
+		t.Fatalf("expected usage to be flushed, got %+v", usage)
+	}
+	usage, err = tr.Usage(ctx, "other_id", "2018-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Calls != 5 {
+		t.Fatalf("expected flushed usage not to be counted twice, got %+v", usage)
+	}
+
+	// the next day, and month, quotas are reset until the monthly one is exhausted
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := call("app_id", 1024, 5*time.Second); err != nil {
+			t.Fatalf("expected quotas to be reset, got %v", err)
+		}
+	}
+	if err := call("app_id", 128, time.Second); err != models.ErrUsageQuotaExceeded {
+		t.Fatalf("expected the monthly quota to be exhausted, got %v", err)
+	}
+}
+
+// flakyStore counts the reads of apps and fails to add usage while failing is set
+type flakyStore struct {
+	models.Datastore
+	appReads int
+	failing  bool
+}
+
+func (s *flakyStore) GetAppByID(ctx context.Context, appID string) (*models.App, error) {
+	s.appReads++
+	return s.Datastore.GetAppByID(ctx, appID)
+}
+
+func (s *flakyStore) AddAppUsage(ctx context.Context, usage *models.AppUsage) error {
+	if s.failing {
+		return errors.New("datastore unavailable")
+	}
+	return s.Datastore.AddAppUsage(ctx, usage)
+}
+
+func TestTrackerFlushFailed(t *testing.T) {
+	ctx := context.Background()
+	app := &models.App{ID: "app_id", Name: "myapp"}
+	app.Annotations, _ = app.Annotations.With(models.UsageQuotaAnnotation, models.UsageQuota{
+		Daily: &models.UsageLimits{Calls: 4},
+	})
+	ds := &flakyStore{Datastore: datastore.NewMockInit([]*models.App{app})}
+
+	now := time.Date(2018, 3, 31, 23, 0, 0, 0, time.UTC)
+	tr := New(ds, ds)
+	tr.now = func() time.Time { return now }
+
+	call := func() error {
+		c := &models.Call{AppID: "app_id", Memory: 128}
+		if err := tr.BeforeCall(ctx, c); err != nil {
+			return err
+		}
+		return tr.AfterCall(ctx, c)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := call(); err != nil {
+			t.Fatalf("expected call %d to be allowed, got %v", i, err)
+		}
+	}
+	if ds.appReads != 1 {
+		t.Fatalf("expected the quota of the app to be read once, got %d reads", ds.appReads)
+	}
+
+	ds.failing = true
+	tr.Flush(ctx)
+	if err := call(); err != nil {
+		t.Fatalf("expected call to be allowed, got %v", err)
+	}
+	usage, err := tr.Usage(ctx, "app_id", "2018-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Calls != 3 {
+		t.Fatalf("expected the usage that failed to flush to be kept, got %+v", usage)
+	}
+
+	ds.failing = false
+	tr.Flush(ctx)
+	stored, err := ds.GetAppUsage(ctx, "app_id", "2018-03-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Calls != 3 {
+		t.Fatalf("expected the usage to be flushed again, got %+v", stored)
+	}
+	if err := call(); err != nil {
+		t.Fatalf("expected call to be allowed, got %v", err)
+	}
+	if err := call(); err != models.ErrUsageQuotaExceeded {
+		t.Fatalf("expected flushed usage to be counted once, got %v", err)
+	}
+}
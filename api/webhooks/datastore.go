@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// Wrap returns a datastore that notifies d of the apps, functions and triggers created,
+// updated and deleted through ds.
+func Wrap(ds models.Datastore, d *Dispatcher) models.Datastore {
+	return &notifyingds{Datastore: ds, d: d}
+}
+
+type notifyingds struct {
+	models.Datastore
+	d *Dispatcher
+}
+
+func (n *notifyingds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := n.Datastore.InsertApp(ctx, app)
+	if err == nil {
+		n.d.Notify(ctx, models.EventAppCreated, app.ID, app)
+	}
+	return app, err
+}
+
+func (n *notifyingds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	app, err := n.Datastore.UpdateApp(ctx, app)
+	if err == nil {
+		n.d.Notify(ctx, models.EventAppUpdated, app.ID, app)
+	}
+	return app, err
+}
+
+func (n *notifyingds) RemoveApp(ctx context.Context, appID string) error {
+	// the event carries the app as it was before it was deleted
+	app, _ := n.Datastore.GetAppByID(ctx, appID)
+	err := n.Datastore.RemoveApp(ctx, appID)
+	if err == nil && app != nil {
+		n.d.Notify(ctx, models.EventAppDeleted, app.ID, app)
+	}
+	return err
+}
+
+func (n *notifyingds) SoftDeleteApp(ctx context.Context, appID string) error {
+	app, _ := n.Datastore.GetAppByID(ctx, appID)
+	err := n.Datastore.SoftDeleteApp(ctx, appID)
+	if err == nil && app != nil {
+		n.d.Notify(ctx, models.EventAppDeleted, app.ID, app)
+	}
+	return err
+}
+
+func (n *notifyingds) RestoreApp(ctx context.Context, appID string) (*models.App, error) {
+	app, err := n.Datastore.RestoreApp(ctx, appID)
+	if err == nil {
+		n.d.Notify(ctx, models.EventAppRestored, app.ID, app)
+	}
+	return app, err
+}
+
+func (n *notifyingds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := n.Datastore.InsertFn(ctx, fn)
+	if err == nil {
+		n.d.Notify(ctx, models.EventFnCreated, fn.AppID, fn)
+	}
+	return fn, err
+}
+
+func (n *notifyingds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	fn, err := n.Datastore.UpdateFn(ctx, fn)
+	if err == nil {
+		n.d.Notify(ctx, models.EventFnUpdated, fn.AppID, fn)
+	}
+	return fn, err
+}
+
+func (n *notifyingds) RemoveFn(ctx context.Context, fnID string) error {
+	fn, _ := n.Datastore.GetFnByID(ctx, fnID)
+	err := n.Datastore.RemoveFn(ctx, fnID)
+	if err == nil && fn != nil {
+		n.d.Notify(ctx, models.EventFnDeleted, fn.AppID, fn)
+	}
+	return err
+}
+
+func (n *notifyingds) SoftDeleteFn(ctx context.Context, fnID string) error {
+	fn, _ := n.Datastore.GetFnByID(ctx, fnID)
+	if fn != nil {
+		fn = fn.Clone() // the event carries the fn as it was before it was deleted
+	}
+	err := n.Datastore.SoftDeleteFn(ctx, fnID)
+	if err == nil && fn != nil {
+		n.d.Notify(ctx, models.EventFnDeleted, fn.AppID, fn)
+	}
+	return err
+}
+
+func (n *notifyingds) RestoreFn(ctx context.Context, fnID string) (*models.Fn, error) {
+	fn, err := n.Datastore.RestoreFn(ctx, fnID)
+	if err == nil {
+		n.d.Notify(ctx, models.EventFnRestored, fn.AppID, fn)
+	}
+	return fn, err
+}
+
+func (n *notifyingds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := n.Datastore.InsertTrigger(ctx, trigger)
+	if err == nil {
+		n.d.Notify(ctx, models.EventTriggerCreated, trigger.AppID, trigger)
+	}
+	return trigger, err
+}
+
+func (n *notifyingds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	trigger, err := n.Datastore.UpdateTrigger(ctx, trigger)
+	if err == nil {
+		n.d.Notify(ctx, models.EventTriggerUpdated, trigger.AppID, trigger)
+	}
+	return trigger, err
+}
+
+func (n *notifyingds) RemoveTrigger(ctx context.Context, triggerID string) error {
+	trigger, _ := n.Datastore.GetTriggerByID(ctx, triggerID)
+	err := n.Datastore.RemoveTrigger(ctx, triggerID)
+	if err == nil && trigger != nil {
+		n.d.Notify(ctx, models.EventTriggerDeleted, trigger.AppID, trigger)
+	}
+	return err
+}
+
+// ApplyBatch notifies the changes of a batch once it was applied, in the order of its items
+func (n *notifyingds) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	applied, err := n.Datastore.ApplyBatch(ctx, items)
+	if err != nil {
+		return applied, err
+	}
+	for _, item := range applied {
+		switch {
+		case item.App != nil:
+			n.d.Notify(ctx, batchEvent(item.Action, models.EventAppCreated, models.EventAppUpdated, models.EventAppDeleted), item.App.ID, item.App)
+		case item.Fn != nil:
+			n.d.Notify(ctx, batchEvent(item.Action, models.EventFnCreated, models.EventFnUpdated, models.EventFnDeleted), item.Fn.AppID, item.Fn)
+		case item.Trigger != nil:
+			n.d.Notify(ctx, batchEvent(item.Action, models.EventTriggerCreated, models.EventTriggerUpdated, models.EventTriggerDeleted), item.Trigger.AppID, item.Trigger)
+		}
+	}
+	return applied, nil
+}
+
+// batchEvent returns the event of the action of a batch item, among those of its resource
+func batchEvent(action, created, updated, deleted string) string {
+	switch action {
+	case models.BatchCreate:
+		return created
+	case models.BatchUpdate:
+		return updated
+	}
+	return deleted
+}
@@ -0,0 +1,347 @@
+// Package webhooks notifies the webhooks registered by operators of the creation, update and
+// deletion of apps, functions and triggers, eg. to keep an external audit trail or to
+// invalidate caches.
+//
+// A Dispatcher is fed the events of the management API by a datastore wrapping the one of
+// the server, see Wrap. Each event is posted as JSON to the webhooks subscribed to it, signed
+// with the secret of the webhook, and retried with an exponential backoff until a webhook
+// accepts it with a 2xx response or the attempts are exhausted. The outcome of every delivery
+// is recorded in the datastore. Events are queued in memory, so events pending when a server
+// stops are lost.
+//
+// Webhooks are only delivered to public addresses: the addresses their hosts resolve to are
+// checked as they are dialed, and loopback, link-local, private and unspecified addresses are
+// refused unless WithPrivateNetworks, so that webhooks can't reach the internal services of
+// the network of fn.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/id"
+	"github.com/fnproject/fn/api/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers of the requests delivering events to webhooks
+const (
+	// EventHeader is the type of the event, eg. fn.updated
+	EventHeader = "Fn-Webhook-Event"
+	// DeliveryHeader is the ID of the delivery, the same for every attempt
+	DeliveryHeader = "Fn-Webhook-Delivery"
+	// TimestampHeader is the unix time the attempt was signed at
+	TimestampHeader = "Fn-Webhook-Timestamp"
+	// SignatureHeader is the signature of the attempt, see Sign
+	SignatureHeader = "Fn-Webhook-Signature"
+)
+
+// Defaults of the deliveries of a Dispatcher
+const (
+	DefaultAttempts   = 5
+	DefaultBackoff    = time.Second
+	DefaultMaxBackoff = time.Minute
+	DefaultTimeout    = 10 * time.Second
+	// DefaultQueueSize is the number of events queued before new events are dropped
+	DefaultQueueSize = 1024
+)
+
+// Event is posted to webhooks when an app, a function or a trigger is created, updated or
+// deleted, and when a deleted app or function is restored. Data is the resource as of the
+// event, as returned by the management API.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	AppID     string          `json:"app_id"`
+	CreatedAt common.DateTime `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the signature of a delivery of body at timestamp with the secret of a webhook,
+// the hex encoded HMAC-SHA256 of the timestamp, a dot and the body, prefixed with sha256=.
+// Receivers recompute it to authenticate deliveries, and should reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers events to webhooks, see the package documentation
+type Dispatcher struct {
+	ds         models.Datastore
+	client     *http.Client
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	private    bool
+	now        func() time.Time
+
+	queue chan *Event
+	wg    sync.WaitGroup // deliveries in progress
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithAttempts sets the number of times an event is posted to a webhook before giving up
+func WithAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.attempts = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a delivery, doubled for each retry up
+// to max
+func WithBackoff(backoff, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+		d.maxBackoff = max
+	}
+}
+
+// WithPrivateNetworks allows webhooks on loopback, link-local and private addresses, for
+// deployments whose receivers are internal
+func WithPrivateNetworks() Option {
+	return func(d *Dispatcher) {
+		d.private = true
+	}
+}
+
+// New creates a dispatcher of events to the webhooks of ds, recording deliveries in ds
+func New(ds models.Datastore, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		ds:         ds,
+		attempts:   DefaultAttempts,
+		backoff:    DefaultBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		now:        time.Now,
+		queue:      make(chan *Event, DefaultQueueSize),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.attempts < 1 {
+		d.attempts = 1
+	}
+
+	dialer := &net.Dialer{Timeout: DefaultTimeout, KeepAlive: 30 * time.Second}
+	if !d.private {
+		dialer.Control = dialPublic
+	}
+	d.client = &http.Client{
+		Timeout: DefaultTimeout,
+		// no proxy, which would dial the webhooks on behalf of fn
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: DefaultTimeout,
+		},
+	}
+	return d
+}
+
+// dialPublic refuses the connections to the addresses webhooks must not reach, it checks the
+// address a host resolved to as it is dialed so that names can't be rebound to them.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// publicIP returns whether ip is a unicast address outside of the loopback, link-local and
+// private ranges
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsPrivate() && !ip.IsUnspecified()
+}
+
+// Notify queues an event of type typ of a resource of app appID, to be delivered by Run.
+// The event is dropped if the queue is full. The events of changes made in a transaction
+// are queued once it is committed.
+func (d *Dispatcher) Notify(ctx context.Context, typ, appID string, data interface{}) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"event": typ, "app_id": appID})
+
+	// the resource may change once it is returned to the caller
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.WithError(err).Error("failed to encode webhook event")
+		return
+	}
+
+	event := &Event{
+		ID:        id.New().String(),
+		Type:      typ,
+		AppID:     appID,
+		CreatedAt: common.DateTime(d.now()),
+		Data:      raw,
+	}
+	models.AfterCommit(ctx, func() {
+		select {
+		case d.queue <- event:
+		default:
+			log.Error("webhook queue is full, dropping event")
+		}
+	})
+}
+
+// Run delivers the queued events until ctx is done, then waits for the deliveries in
+// progress. Deliveries waiting to be retried give up once ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer d.wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.queue:
+			d.dispatch(ctx, event)
+		}
+	}
+}
+
+// dispatch starts the deliveries of an event to the webhooks subscribed to it
+func (d *Dispatcher) dispatch(ctx context.Context, event *Event) {
+	log := common.Logger(ctx).WithFields(logrus.Fields{"event": event.Type, "event_id": event.ID})
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("failed to encode webhook event")
+		return
+	}
+
+	filter := &models.WebhookFilter{PerPage: 100}
+	for {
+		webhooks, err := d.ds.GetWebhooks(ctx, filter)
+		if err != nil {
+			log.WithError(err).Error("failed to get webhooks to deliver event")
+			return
+		}
+		for _, webhook := range webhooks.Items {
+			if webhook.Subscribes(event.AppID, event.Type) {
+				d.wg.Add(1)
+				go func(webhook *models.Webhook) {
+					defer d.wg.Done()
+					d.deliver(ctx, webhook, event, body)
+				}(webhook)
+			}
+		}
+		if webhooks.NextCursor == "" {
+			return
+		}
+		filter.Cursor = webhooks.NextCursor
+	}
+}
+
+// deliver posts an event to a webhook until it is accepted or the attempts are exhausted,
+// and records the delivery.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *models.Webhook, event *Event, body []byte) {
+	delivery := &models.WebhookDelivery{
+		ID:        id.New().String(),
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		Event:     event.Type,
+		Status:    models.WebhookFailed,
+		CreatedAt: event.CreatedAt,
+	}
+
+	backoff := d.backoff
+	for delivery.Attempts < d.attempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				delivery.Error = ctx.Err().Error()
+			case <-time.After(backoff):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if backoff *= 2; backoff > d.maxBackoff {
+				backoff = d.maxBackoff
+			}
+		}
+
+		delivery.Attempts++
+		code, err := d.post(ctx, webhook, delivery.ID, event.Type, body)
+		delivery.StatusCode = code
+		if err == nil {
+			delivery.Status = models.WebhookDelivered
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+	}
+	delivery.CompletedAt = common.DateTime(d.now())
+
+	log := common.Logger(ctx).WithFields(logrus.Fields{"webhook_id": webhook.ID, "event": event.Type, "attempts": delivery.Attempts})
+	if delivery.Status == models.WebhookFailed {
+		log.WithField("error", delivery.Error).Warn("failed to deliver event to webhook")
+	}
+
+	// deliveries given up on shutdown are still recorded
+	err := d.ds.InsertWebhookDelivery(common.BackgroundContext(ctx), delivery)
+	if err != nil && err != models.ErrWebhookNotFound {
+		log.WithError(err).Error("failed to record webhook delivery")
+	}
+}
+
+// post sends one attempt of a delivery, it returns the status code of the response if any
+// and an error unless the status is 2xx.
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, deliveryID, typ string, body []byte) (int, error) {
+	// webhooks are validated as they are created, unless inserted in the datastore directly
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return 0, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return 0, fmt.Errorf("webhook URL scheme %q is not http or https", u.Scheme)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, typ)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body so that the connection is reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,315 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/models"
+)
+
+const testSecret = "whsec_0123456789abcdef"
+
+// receiver records the events posted to it, responding with the statuses in order, then 200
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	events   []*Event
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("failed to read delivery: %v", err)
+		return
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		r.t.Errorf("invalid timestamp: %v", err)
+	}
+	if sig := req.Header.Get(SignatureHeader); sig != Sign(testSecret, timestamp, body) {
+		r.t.Errorf("invalid signature %q", sig)
+	}
+	if req.Header.Get(DeliveryHeader) == "" {
+		r.t.Error("expected a delivery ID")
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		r.t.Errorf("invalid event: %v", err)
+	}
+	if req.Header.Get(EventHeader) != event.Type {
+		r.t.Errorf("expected event header %q, got %q", event.Type, req.Header.Get(EventHeader))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	r.events = append(r.events, &event)
+}
+
+func (r *receiver) received() []*Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Event(nil), r.events...)
+}
+
+// waitDeliveries waits for n deliveries to be recorded for a webhook
+func waitDeliveries(t *testing.T, ds models.Datastore, webhookID string, n int) []*models.WebhookDelivery {
+	ctx := context.Background()
+	for i := 0; i < 200; i++ {
+		deliveries, err := ds.GetWebhookDeliveries(ctx, &models.WebhookDeliveryFilter{WebhookID: webhookID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries.Items) >= n {
+			return deliveries.Items
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d deliveries to %s", n, webhookID)
+	return nil
+}
+
+func setup(t *testing.T, opts ...Option) (models.Datastore, func()) {
+	ds := datastore.NewMock()
+	// the receivers of the tests listen on loopback
+	d := New(ds, append([]Option{WithBackoff(time.Millisecond, 5*time.Millisecond), WithPrivateNetworks()}, opts...)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	return Wrap(ds, d), func() {
+		cancel()
+		<-done
+	}
+}
+
+func givenWebhook(t *testing.T, ds models.Datastore, url, appID string, events ...string) *models.Webhook {
+	webhook, err := ds.InsertWebhook(context.Background(), &models.Webhook{URL: url, AppID: appID, Events: events, Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+func testFn(appID, name string) *models.Fn {
+	fn := &models.Fn{AppID: appID, Name: name, Image: "fnproject/fn-test-utils"}
+	fn.SetDefaults()
+	return fn
+}
+
+func TestDeliverEvents(t *testing.T) {
+	ds, stop := setup(t)
+	defer stop()
+	ctx := context.Background()
+
+	r := &receiver{t: t}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	webhook := givenWebhook(t, ds, srv.URL, "")
+
+	app, err := ds.InsertApp(ctx, &models.App{Name: "myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := ds.InsertFn(ctx, testFn(app.ID, "myfn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.RemoveFn(ctx, fn.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := waitDeliveries(t, ds, webhook.ID, 3)
+	for _, delivery := range deliveries {
+		if delivery.Status != models.WebhookDelivered || delivery.Attempts != 1 || delivery.StatusCode != http.StatusOK {
+			t.Errorf("expected a delivery on the first attempt, got %+v", delivery)
+		}
+	}
+
+	events := make(map[string]*Event)
+	for _, event := range r.received() {
+		events[event.Type] = event
+		if event.AppID != app.ID {
+			t.Errorf("expected event of app %s, got %+v", app.ID, event)
+		}
+	}
+	for _, typ := range []string{models.EventAppCreated, models.EventFnCreated, models.EventFnDeleted} {
+		if events[typ] == nil {
+			t.Fatalf("expected a %s event, got %v", typ, events)
+		}
+	}
+	var deleted models.Fn
+	if err := json.Unmarshal(events[models.EventFnDeleted].Data, &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != fn.ID || deleted.Name != fn.Name {
+		t.Errorf("expected the deleted function, got %+v", deleted)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	ds, stop := setup(t, WithAttempts(3))
+	defer stop()
+	ctx := context.Background()
+
+	r := &receiver{t: t, statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	webhook := givenWebhook(t, ds, srv.URL, "")
+
+	if _, err := ds.InsertApp(ctx, &models.App{Name: "myapp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := waitDeliveries(t, ds, webhook.ID, 1)[0]
+	if delivery.Status != models.WebhookDelivered || delivery.Attempts != 3 || delivery.Error != "" {
+		t.Errorf("expected a delivery on the third attempt, got %+v", delivery)
+	}
+	if len(r.received()) != 1 {
+		t.Errorf("expected one event, got %d", len(r.received()))
+	}
+}
+
+func TestDeliverFailed(t *testing.T) {
+	ds, stop := setup(t, WithAttempts(2))
+	defer stop()
+	ctx := context.Background()
+
+	r := &receiver{t: t, statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	webhook := givenWebhook(t, ds, srv.URL, "")
+
+	if _, err := ds.InsertApp(ctx, &models.App{Name: "myapp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := waitDeliveries(t, ds, webhook.ID, 1)[0]
+	if delivery.Status != models.WebhookFailed || delivery.Attempts != 2 || delivery.StatusCode != http.StatusBadGateway || delivery.Error == "" {
+		t.Errorf("expected a failed delivery after two attempts, got %+v", delivery)
+	}
+}
+
+func TestDeliverSubscriptions(t *testing.T) {
+	ds, stop := setup(t)
+	defer stop()
+	ctx := context.Background()
+
+	app1, err := ds.InsertApp(ctx, &models.App{Name: "app1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app2, err := ds.InsertApp(ctx, &models.App{Name: "app2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped, all := &receiver{t: t}, &receiver{t: t}
+	scopedSrv, allSrv := httptest.NewServer(scoped), httptest.NewServer(all)
+	defer scopedSrv.Close()
+	defer allSrv.Close()
+	scopedHook := givenWebhook(t, ds, scopedSrv.URL, app1.ID, models.EventFnCreated)
+	allHook := givenWebhook(t, ds, allSrv.URL, "", models.EventFnCreated)
+
+	if _, err := ds.InsertFn(ctx, testFn(app2.ID, "other")); err != nil {
+		t.Fatal(err)
+	}
+	fn, err := ds.InsertFn(ctx, testFn(app1.ID, "myfn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.UpdateApp(ctx, &models.App{ID: app1.ID, Config: models.Config{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitDeliveries(t, ds, allHook.ID, 2)
+	waitDeliveries(t, ds, scopedHook.ID, 1)
+	// the last event is delivered to nobody, wait for the others to settle
+	time.Sleep(50 * time.Millisecond)
+
+	events := scoped.received()
+	if len(events) != 1 || events[0].Type != models.EventFnCreated {
+		t.Fatalf("expected only the creation of a function of app1, got %d events", len(events))
+	}
+	var created models.Fn
+	if err := json.Unmarshal(events[0].Data, &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != fn.ID {
+		t.Errorf("expected function %s, got %s", fn.ID, created.ID)
+	}
+	if n := len(all.received()); n != 2 {
+		t.Errorf("expected the creation of both functions, got %d events", n)
+	}
+}
+
+func TestDeliverPublicOnly(t *testing.T) {
+	d := New(datastore.NewMock())
+	ctx := context.Background()
+
+	r := &receiver{t: t}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for i, test := range []struct {
+		url      string
+		expected string
+	}{
+		{srv.URL, "is not public"},
+		{strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), "is not public"},
+		{"ftp://example.com/hook", "is not http or https"},
+		{"file:///etc/passwd", "is not http or https"},
+	} {
+		_, err := d.post(ctx, &models.Webhook{URL: test.url, Secret: testSecret}, "delivery", models.EventAppCreated, []byte("{}"))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Test %d: expected an error containing %q, got %v", i, test.expected, err)
+		}
+	}
+	if n := len(r.received()); n != 0 {
+		t.Errorf("expected no event to reach the receiver, got %d", n)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for i, test := range []struct {
+		ip     string
+		public bool
+	}{
+		{"203.0.113.7", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	} {
+		if public := publicIP(net.ParseIP(test.ip)); public != test.public {
+			t.Errorf("Test %d: expected %s public %v, got %v", i, test.ip, test.public, public)
+		}
+	}
+}
@@ -5,6 +5,14 @@ import (
 
 	"github.com/fnproject/fn/api/agent"
 	"github.com/fnproject/fn/api/agent/drivers/docker"
+	"github.com/fnproject/fn/api/datastore"
+	"github.com/fnproject/fn/api/datastore/sql"
+	"github.com/fnproject/fn/api/logs/azure"
+	"github.com/fnproject/fn/api/logs/elasticsearch"
+	"github.com/fnproject/fn/api/logs/fanout"
+	"github.com/fnproject/fn/api/logs/gcs"
+	"github.com/fnproject/fn/api/logs/kafkarest"
+	"github.com/fnproject/fn/api/logs/loki"
 	"github.com/fnproject/fn/api/logs/s3"
 	"github.com/fnproject/fn/api/server"
 	// The trace package is imported in several places by different dependencies and if we don't import explicity here it is
@@ -50,6 +58,22 @@ func registerViews() {
 
 	// Register s3 log views
 	s3.RegisterViews(keys, latencyDist)
+	// Register gcs log views
+	gcs.RegisterViews(keys, latencyDist)
+	// Register azure blob log views
+	azure.RegisterViews(keys, latencyDist)
+	// Register elasticsearch log views
+	elasticsearch.RegisterViews(keys, latencyDist)
+	// Register kafka log views
+	kafkarest.RegisterViews(keys, latencyDist)
+	// Register loki log views
+	loki.RegisterViews(keys, latencyDist)
+	// Register secondary log store views
+	fanout.RegisterViews(keys)
+
+	// Register datastore views
+	datastore.RegisterViews(keys, latencyDist)
+	sql.RegisterViews(keys)
 
 	server.RegisterAPIViews(keys, latencyDist)
 }
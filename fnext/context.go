@@ -14,4 +14,6 @@ var (
 	MiddlewareControllerKey = contextKey("middleware_controller")
 	// AppNameKey
 	AppNameKey = contextKey("app_name")
+	// IdentityKey is a context key. The identity of the caller of a request is a *Identity value.
+	IdentityKey = contextKey("identity")
 )
@@ -200,3 +200,100 @@ func (e *extds) RemoveFn(ctx context.Context, fnID string) error {
 	}
 	return nil
 }
+
+// ApplyBatch calls the listeners of each item before the batch is applied, and after it was
+// applied, as a batch is applied at once.
+func (e *extds) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	for _, item := range items {
+		if err := e.beforeBatchItem(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := e.Datastore.ApplyBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range applied {
+		if err := e.afterBatchItem(ctx, item); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+func (e *extds) beforeBatchItem(ctx context.Context, item *models.BatchItem) error {
+	// deletes only call listeners when apps and fns are removed, as SoftDeleteApp does
+	if item.Action == models.BatchDelete && item.SoftDelete {
+		return nil
+	}
+
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.al.BeforeAppCreate(ctx, item.App)
+		case models.BatchUpdate:
+			return e.al.BeforeAppUpdate(ctx, item.App)
+		case models.BatchDelete:
+			return e.al.BeforeAppDelete(ctx, item.App)
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.fl.BeforeFnCreate(ctx, item.Fn)
+		case models.BatchUpdate:
+			return e.fl.BeforeFnUpdate(ctx, item.Fn)
+		case models.BatchDelete:
+			return e.fl.BeforeFnDelete(ctx, item.Fn.ID)
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.tl.BeforeTriggerCreate(ctx, item.Trigger)
+		case models.BatchUpdate:
+			return e.tl.BeforeTriggerUpdate(ctx, item.Trigger)
+		case models.BatchDelete:
+			return e.tl.BeforeTriggerDelete(ctx, item.Trigger.ID)
+		}
+	}
+	return nil
+}
+
+func (e *extds) afterBatchItem(ctx context.Context, item *models.BatchItem) error {
+	if item.Action == models.BatchDelete && item.SoftDelete {
+		return nil
+	}
+
+	switch {
+	case item.App != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.al.AfterAppCreate(ctx, item.App)
+		case models.BatchUpdate:
+			return e.al.AfterAppUpdate(ctx, item.App)
+		case models.BatchDelete:
+			return e.al.AfterAppDelete(ctx, item.App)
+		}
+	case item.Fn != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.fl.AfterFnCreate(ctx, item.Fn)
+		case models.BatchUpdate:
+			return e.fl.AfterFnUpdate(ctx, item.Fn)
+		case models.BatchDelete:
+			return e.fl.AfterFnDelete(ctx, item.Fn.ID)
+		}
+	case item.Trigger != nil:
+		switch item.Action {
+		case models.BatchCreate:
+			return e.tl.AfterTriggerCreate(ctx, item.Trigger)
+		case models.BatchUpdate:
+			return e.tl.AfterTriggerUpdate(ctx, item.Trigger)
+		case models.BatchDelete:
+			return e.tl.AfterTriggerDelete(ctx, item.Trigger.ID)
+		}
+	}
+	return nil
+}
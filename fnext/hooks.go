@@ -0,0 +1,123 @@
+package fnext
+
+import (
+	"context"
+
+	"github.com/fnproject/fn/api/models"
+)
+
+// DatastoreHook is an interface used to inject custom code inside the datastore operations
+// creating and updating apps, fns and triggers, once the resource is validated and before it
+// is persisted, in the transaction of the operation. Hooks may change the resource, eg to
+// enforce naming conventions or inject default annotations, or fail the operation with an
+// error, nothing being persisted then. The datastore calls made with the context of a hook
+// are part of the transaction, and models.AfterCommit defers the changes to external systems
+// until it is committed. Hooks may run more than once if the transaction is retried, and must
+// not change the IDs of resources or the names of the apps updated.
+type DatastoreHook interface {
+	// BeforeAppPersist called with the app created, or updated with its changes applied
+	BeforeAppPersist(ctx context.Context, app *models.App, created bool) error
+	// BeforeFnPersist called with the fn created, or updated with its changes applied
+	BeforeFnPersist(ctx context.Context, fn *models.Fn, created bool) error
+	// BeforeTriggerPersist called with the trigger created, or updated with its changes applied
+	BeforeTriggerPersist(ctx context.Context, trigger *models.Trigger, created bool) error
+}
+
+// NewHookedDatastore returns a Datastore that wraps the provided Datastore, running hook
+// inside the operations creating and updating apps, fns and triggers.
+func NewHookedDatastore(ds models.Datastore, hook DatastoreHook) models.Datastore {
+	return &hookds{
+		Datastore: ds,
+		hook:      hook,
+	}
+}
+
+type hookds struct {
+	models.Datastore
+	hook DatastoreHook
+}
+
+func (h *hookds) persist(ctx context.Context, resource interface{}, created bool) error {
+	switch r := resource.(type) {
+	case *models.App:
+		return h.hook.BeforeAppPersist(ctx, r, created)
+	case *models.Fn:
+		return h.hook.BeforeFnPersist(ctx, r, created)
+	case *models.Trigger:
+		return h.hook.BeforeTriggerPersist(ctx, r, created)
+	}
+	return nil
+}
+
+// inTx runs f in a transaction, with the context running the hook
+func (h *hookds) inTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return h.Datastore.InTx(ctx, func(ctx context.Context) error {
+		return f(models.WithPersistHook(ctx, h.persist))
+	})
+}
+
+func (h *hookds) InsertApp(ctx context.Context, app *models.App) (*models.App, error) {
+	var res *models.App
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.InsertApp(ctx, app)
+		return err
+	})
+	return res, err
+}
+
+func (h *hookds) UpdateApp(ctx context.Context, app *models.App) (*models.App, error) {
+	var res *models.App
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.UpdateApp(ctx, app)
+		return err
+	})
+	return res, err
+}
+
+func (h *hookds) InsertFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	var res *models.Fn
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.InsertFn(ctx, fn)
+		return err
+	})
+	return res, err
+}
+
+func (h *hookds) UpdateFn(ctx context.Context, fn *models.Fn) (*models.Fn, error) {
+	var res *models.Fn
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.UpdateFn(ctx, fn)
+		return err
+	})
+	return res, err
+}
+
+func (h *hookds) InsertTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	var res *models.Trigger
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.InsertTrigger(ctx, trigger)
+		return err
+	})
+	return res, err
+}
+
+func (h *hookds) UpdateTrigger(ctx context.Context, trigger *models.Trigger) (*models.Trigger, error) {
+	var res *models.Trigger
+	err := h.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = h.Datastore.UpdateTrigger(ctx, trigger)
+		return err
+	})
+	return res, err
+}
+
+// ApplyBatch runs the hook on the items creating and updating apps, fns and triggers, in the
+// transaction of the batch
+func (h *hookds) ApplyBatch(ctx context.Context, items []*models.BatchItem) ([]*models.BatchItem, error) {
+	return h.Datastore.ApplyBatch(models.WithPersistHook(ctx, h.persist), items)
+}
@@ -0,0 +1,26 @@
+package fnext
+
+import "context"
+
+// Identity is the verified identity of the caller of a request, attached to the context of
+// the request when the server authenticates requests.
+type Identity struct {
+	// Issuer of the token the identity was verified with
+	Issuer string
+	// Subject is the caller in the issuer, eg. a user or service account id
+	Subject string
+	// Claims are all the claims of the token, eg. groups or scopes to authorize requests with
+	Claims map[string]interface{}
+}
+
+// WithIdentity returns a context with the identity of the caller of a request.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, IdentityKey, id)
+}
+
+// GetIdentity returns the identity of the caller of the request of ctx, nil if the request
+// was not authenticated.
+func GetIdentity(ctx context.Context) *Identity {
+	id, _ := ctx.Value(IdentityKey).(*Identity)
+	return id
+}
@@ -73,6 +73,16 @@ type TriggerListener interface {
 	AfterTriggerDelete(ctx context.Context, triggerId string) error
 }
 
+// ContainerListener enables callbacks around the creation of function containers.
+type ContainerListener interface {
+	// BeforeContainerCreate called after the driver has configured a container and
+	// before the container is created. opts holds the driver specific container
+	// options and may be modified, for the docker driver it is a
+	// *docker.CreateContainerOptions from github.com/fsouza/go-dockerclient.
+	// Returning an error fails the container launch.
+	BeforeContainerCreate(ctx context.Context, opts interface{}) error
+}
+
 // CallListener enables callbacks around Call events.
 type CallListener interface {
 	// BeforeCall called before a function is executed
@@ -20,6 +20,8 @@ type ExtServer interface {
 	AddAppListener(listener AppListener)
 	// AddCallListener adds a listener that will be invoked around any call invocations.
 	AddCallListener(listener CallListener)
+	// AddContainerListener adds a listener that will be invoked before function containers are created.
+	AddContainerListener(listener ContainerListener)
 
 	// AddAPIMiddleware add middleware
 	AddAPIMiddleware(m Middleware)
@@ -6,6 +6,7 @@ require (
 	github.com/aws/aws-sdk-go v1.15.57
 	github.com/boltdb/bolt v0.0.0-20170907202052-fa5367d20c99
 	github.com/containerd/continuity v0.0.0-20181003075958-be9bd761db19 // indirect
+	github.com/coreos/etcd v3.3.10+incompatible
 	github.com/coreos/go-semver v0.2.1-0.20180108230905-e214231b295a
 	github.com/dchest/siphash v1.2.0
 	github.com/docker/docker v0.7.3-0.20180904180028-c3e32938430e // indirect
@@ -17,11 +18,15 @@ require (
 	github.com/gin-contrib/sse v0.0.0-20170109093832-22d885f9ecc7 // indirect
 	github.com/gin-gonic/gin v1.3.0
 	github.com/go-sql-driver/mysql v1.4.0
+	github.com/gocql/gocql v1.0.0
 	github.com/golang/protobuf v1.2.1-0.20190109072247-347cf4a86c1c
+	github.com/golang/snappy v0.0.3 // indirect
 	github.com/google/btree v0.0.0-20180813153112-4030bb1f1f0c
 	github.com/grpc-ecosystem/go-grpc-middleware v1.0.0
+	github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/json-iterator/go v1.1.5 // indirect
+	github.com/klauspost/compress v1.11.13
 	github.com/leanovate/gopter v0.2.2
 	github.com/lib/pq v1.0.0
 	github.com/mattn/go-isatty v0.0.4 // indirect
@@ -34,11 +39,14 @@ require (
 	github.com/sirupsen/logrus v1.1.1
 	github.com/ugorji/go/codec v0.0.0-20181022190402-e5e69e061d4f // indirect
 	go.opencensus.io v0.19.0
+	golang.org/x/crypto v0.0.0-20181015023909-0c41d7ab0a0e
 	golang.org/x/net v0.0.0-20181217023233-e147a9138326
 	golang.org/x/sys v0.0.0-20181218192612-074acd46bca6
 	golang.org/x/time v0.0.0-20180412165947-fbb02b2291d2
 	google.golang.org/grpc v1.17.0
 	gopkg.in/go-playground/validator.v8 v8.18.2 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
 
 replace (